@@ -0,0 +1,86 @@
+package app
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/backup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/daemon"
+)
+
+func init() { //nolint:gochecknoinits // init is ok here
+	backupCmd.AddCommand(backupExportCmd, backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+var (
+	backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Export or restore the application database (not the PowerDNS data)",
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err = config.ReadConfig(configPath)
+
+			return err
+		},
+	}
+
+	backupExportCmd = &cobra.Command{
+		Use:   "export FILE",
+		Short: "Write users, roles, groups, settings, zone ownership and the audit trail to a JSON archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				return err
+			}
+
+			counts, err := backup.Export(daemon.OpenDB(&cfg), f)
+			if err != nil {
+				_ = f.Close()
+				return err
+			}
+
+			if err = f.Close(); err != nil {
+				return err
+			}
+
+			printCounts(cmd, "exported", counts)
+
+			return nil
+		},
+	}
+
+	backupRestoreCmd = &cobra.Command{
+		Use:   "restore FILE",
+		Short: "Replace the application database with the contents of a JSON archive",
+		Long: `Replace the application database with the contents of a JSON archive
+written by "backup export" or the Backup & Restore admin page. Every table
+contained in the archive is emptied first. Restart running instances afterwards.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			counts, err := backup.Restore(daemon.OpenDB(&cfg), f)
+			if err != nil {
+				return err
+			}
+
+			printCounts(cmd, "restored", counts)
+
+			return nil
+		},
+	}
+)
+
+// printCounts prints the number of rows per table.
+func printCounts(cmd *cobra.Command, verb string, counts []backup.TableCount) {
+	for _, c := range counts {
+		cmd.Printf("%s %6d rows of %s\n", verb, c.Rows, c.Table)
+	}
+}
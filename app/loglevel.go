@@ -0,0 +1,166 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	appsettingshandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/app"
+)
+
+// apiKeyEnv is the environment variable holding the API key of loglevel.
+const apiKeyEnv = "GPA_KEY"
+
+var errNoAPIKey = errors.New("an API key is required: set " + apiKeyEnv + " or pass --api-key")
+
+var (
+	logLevelURL      string
+	logLevelAPIKey   string
+	logLevelSampling uint32
+	logLevelReset    bool
+
+	logLevelCmd = &cobra.Command{
+		Use:   "loglevel [LEVEL]",
+		Short: "Show or change the log level and debug log sampling of a running instance",
+		Long: `Show or change the log level (trace, debug, info, warn or error) and the debug
+log sampling of a running instance without a restart, like Settings ->
+Application. The change is saved as an override and reaches the other
+instances through the cache sync. --reset returns to the config file values.
+
+The instance is reached at --url, by default [webserver] url of the config,
+with an API key of a user holding the admin.settings permission, read from
+--api-key or the ` + apiKeyEnv + ` environment variable.`,
+		Example: `  go-powerdns-admin loglevel
+  go-powerdns-admin loglevel debug --sampling 100
+  go-powerdns-admin loglevel --reset`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if logLevelAPIKey == "" {
+				logLevelAPIKey = os.Getenv(apiKeyEnv)
+			}
+
+			if logLevelAPIKey == "" {
+				return errNoAPIKey
+			}
+
+			if logLevelURL == "" {
+				if cfg, err = config.ReadConfig(configPath); err != nil {
+					return err
+				}
+
+				logLevelURL = cfg.Webserver.URL
+			}
+
+			var change *appsettingshandler.LoggingChange
+
+			if len(args) == 1 || logLevelReset || cmd.Flags().Changed("sampling") {
+				change = &appsettingshandler.LoggingChange{Reset: logLevelReset}
+
+				if len(args) == 1 {
+					change.Level = &args[0]
+				}
+
+				if cmd.Flags().Changed("sampling") {
+					change.Sampling = &logLevelSampling
+				}
+			}
+
+			got, err := requestLogging(cmd.Context(), strings.TrimRight(logLevelURL, "/"), logLevelAPIKey, change)
+			if err != nil {
+				return err
+			}
+
+			sampling := "off"
+			if got.Sampling > 1 {
+				sampling = fmt.Sprintf("1 in %d debug and trace messages", got.Sampling)
+			}
+
+			cmd.Printf("level:    %s (config file: %s)\n", got.Level, got.ConfigLevel)
+			cmd.Printf("sampling: %s (config file: %d)\n", sampling, got.ConfigSampling)
+
+			if got.Overridden {
+				cmd.Println("overridden under Settings -> Application; --reset returns to the config file")
+			}
+
+			return nil
+		},
+	}
+)
+
+func init() { //nolint:gochecknoinits // init is ok here
+	logLevelCmd.Flags().StringVar(&logLevelURL, "url", "", "base URL of the instance (default: [webserver] url)")
+	logLevelCmd.Flags().StringVar(&logLevelAPIKey, "api-key", "", "API key (default: $"+apiKeyEnv+")")
+	logLevelCmd.Flags().Uint32Var(&logLevelSampling, "sampling", 0,
+		"keep only the first of every n debug and trace messages, 0 keeps all")
+	logLevelCmd.Flags().BoolVar(&logLevelReset, "reset", false, "drop the overrides and use the config file values")
+
+	rootCmd.AddCommand(logLevelCmd)
+}
+
+// requestLogging reads the logging endpoint at baseURL, or posts change to it
+// when not nil.
+func requestLogging(
+	ctx context.Context,
+	baseURL, apiKey string,
+	change *appsettingshandler.LoggingChange,
+) (*appsettingshandler.Logging, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	method, body := http.MethodGet, io.Reader(nil)
+
+	if change != nil {
+		b, err := json.Marshal(change)
+		if err != nil {
+			return nil, err
+		}
+
+		method, body = http.MethodPost, bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+appsettingshandler.APIPath, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr handler.APIError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Message != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, apiErr.Message)
+		}
+
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	var got appsettingshandler.Logging
+	if err = json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		return nil, err
+	}
+
+	return &got, nil
+}
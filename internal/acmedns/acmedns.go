@@ -0,0 +1,347 @@
+// Package acmedns obtains and renews the TLS certificate of the web service
+// with the ACME dns-01 challenge. The challenge TXT records are published in
+// the PowerDNS zones managed by the application, so the service needs neither
+// port 80 nor a public address, and wildcard certificates are possible.
+//
+// Certificates and the account key are stored in the ACME cache directory
+// under names of their own, so the directory can be shared with the http-01
+// manager of autocert.
+package acmedns
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+)
+
+const (
+	// checkInterval is how often the certificate is checked for renewal.
+	checkInterval = 12 * time.Hour
+
+	// retryInterval is the wait after a failed issuance.
+	retryInterval = 15 * time.Minute
+
+	// renewBefore is the remaining validity at which a certificate is renewed.
+	renewBefore = 30 * 24 * time.Hour
+
+	// issueTimeout bounds one issuance, including DNS propagation.
+	issueTimeout = 10 * time.Minute
+
+	// challengeLabel is prepended to a domain for its dns-01 TXT record.
+	challengeLabel = "_acme-challenge."
+
+	accountKeyName = "dns01+account.key"
+	certSuffix     = "+dns01"
+)
+
+var (
+	// ErrNoCertificate is returned for TLS handshakes before the first
+	// certificate has been issued.
+	ErrNoCertificate = errors.New("acmedns: no certificate issued yet")
+
+	errNoDNSChallenge = errors.New("acmedns: CA offers no dns-01 challenge")
+)
+
+// Solver publishes and removes the TXT records of dns-01 challenges.
+type Solver interface {
+	// Present publishes value as TXT record at fqdn and returns when the
+	// record is served.
+	Present(ctx context.Context, fqdn, value string) error
+	// CleanUp removes the TXT record at fqdn.
+	CleanUp(ctx context.Context, fqdn string) error
+}
+
+// Manager keeps a certificate for one domain, which may be a wildcard.
+type Manager struct {
+	domain       string
+	email        string
+	directoryURL string
+	cache        autocert.Cache
+	solver       Solver
+	now          func() time.Time
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// New returns a manager for the ACME settings of w, a validated config.
+func New(w *config.Webserver, solver Solver) *Manager {
+	return &Manager{
+		domain:       w.ACMEDomain,
+		email:        w.ACMEEmail,
+		directoryURL: w.ACMEDirectoryURL,
+		cache:        autocert.DirCache(w.ACMECacheDir),
+		solver:       solver,
+		now:          time.Now,
+	}
+}
+
+// GetCertificate returns the current certificate; use it as
+// tls.Config.GetCertificate.
+func (m *Manager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.cert == nil {
+		return nil, ErrNoCertificate
+	}
+
+	return m.cert, nil
+}
+
+// Run loads the cached certificate, issues one when it is missing or due for
+// renewal and checks again every 12 hours until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	if err := m.load(ctx); err != nil && !errors.Is(err, autocert.ErrCacheMiss) {
+		log.Warn().Err(err).Str("domain", m.domain).Msg("acmedns: cached certificate unusable")
+	}
+
+	jobs.Scheduled(jobs.CertRenewal, checkInterval)
+
+	for {
+		wait := checkInterval
+
+		if m.renewalDue() {
+			err := jobs.Run(jobs.CertRenewal, func() error { return m.renew(ctx) })
+			if err != nil {
+				log.Error().Err(err).Str("domain", m.domain).Msg("acmedns: certificate issuance failed")
+
+				wait = retryInterval
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// renewalDue reports whether there is no certificate or it expires soon.
+func (m *Manager) renewalDue() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cert == nil || m.cert.Leaf.NotAfter.Sub(m.now()) < renewBefore
+}
+
+// load reads the certificate of the domain from the cache.
+func (m *Manager) load(ctx context.Context) error {
+	data, err := m.cache.Get(ctx, m.domain+certSuffix)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return err
+	}
+
+	if err = cert.Leaf.VerifyHostname(strings.Replace(m.domain, "*", "x", 1)); err != nil {
+		return err
+	}
+
+	m.setCert(&cert)
+
+	return nil
+}
+
+func (m *Manager) setCert(cert *tls.Certificate) {
+	m.mu.Lock()
+	m.cert = cert
+	m.mu.Unlock()
+
+	log.Info().Str("domain", m.domain).Time("expires", cert.Leaf.NotAfter).Msg("acmedns: certificate loaded")
+}
+
+// renew issues a new certificate, stores it in the cache and serves it.
+func (m *Manager) renew(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, issueTimeout)
+	defer cancel()
+
+	accountKey, err := m.accountKey(ctx)
+	if err != nil {
+		return fmt.Errorf("account key: %w", err)
+	}
+
+	client := &acme.Client{Key: accountKey, DirectoryURL: m.directoryURL}
+
+	_, err = client.Register(ctx, &acme.Account{Contact: []string{"mailto:" + m.email}}, acme.AcceptTOS)
+	if err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("register account: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(m.domain))
+	if err != nil {
+		return fmt.Errorf("create order: %w", err)
+	}
+
+	for _, url := range order.AuthzURLs {
+		if err = m.authorize(ctx, client, url); err != nil {
+			return err
+		}
+	}
+
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return fmt.Errorf("wait for order: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{m.domain}}, key)
+	if err != nil {
+		return err
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("finalize order: %w", err)
+	}
+
+	data, err := bundle(key, chain)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return err
+	}
+
+	if err = m.cache.Put(ctx, m.domain+certSuffix, data); err != nil {
+		log.Warn().Err(err).Str("domain", m.domain).Msg("acmedns: failed to cache certificate")
+	}
+
+	m.setCert(&cert)
+
+	return nil
+}
+
+// authorize completes the dns-01 challenge of the authorization at url. The
+// TXT record is removed again whatever the outcome.
+func (m *Manager) authorize(ctx context.Context, client *acme.Client, url string) error {
+	authz, err := client.GetAuthorization(ctx, url)
+	if err != nil {
+		return fmt.Errorf("get authorization: %w", err)
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+
+	for _, c := range authz.Challenges {
+		if c.Type == config.ACMEChallengeDNS01 {
+			challenge = c
+			break
+		}
+	}
+
+	if challenge == nil {
+		return errNoDNSChallenge
+	}
+
+	value, err := client.DNS01ChallengeRecord(challenge.Token)
+	if err != nil {
+		return err
+	}
+
+	// For a wildcard the identifier is the domain without "*.".
+	fqdn := challengeLabel + authz.Identifier.Value
+
+	defer func() {
+		// The context of a timed out issuance cannot be used for the cleanup.
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+
+		if err := m.solver.CleanUp(cleanupCtx, fqdn); err != nil {
+			log.Warn().Err(err).Str("name", fqdn).Msg("acmedns: failed to remove challenge record")
+		}
+	}()
+
+	if err = m.solver.Present(ctx, fqdn, value); err != nil {
+		return fmt.Errorf("publish challenge record: %w", err)
+	}
+
+	if _, err = client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("accept challenge: %w", err)
+	}
+
+	if _, err = client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("wait for authorization of %s: %w", authz.Identifier.Value, err)
+	}
+
+	return nil
+}
+
+// accountKey returns the cached ACME account key, creating it on first use.
+func (m *Manager) accountKey(ctx context.Context) (crypto.Signer, error) {
+	data, err := m.cache.Get(ctx, accountKeyName)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("invalid PEM data")
+		}
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	if !errors.Is(err, autocert.ErrCacheMiss) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.cache.Put(ctx, accountKeyName, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// bundle returns key and the DER certificates of chain as PEM, the layout
+// autocert uses for its cache entries.
+func bundle(key *ecdsa.PrivateKey, chain [][]byte) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+	for _, c := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c})...)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,143 @@
+package acmedns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+func newTestManager(t *testing.T, domain string) *Manager {
+	t.Helper()
+
+	return New(&config.Webserver{
+		ACMEDomain:   domain,
+		ACMEEmail:    "admin@example.com",
+		ACMECacheDir: t.TempDir(),
+	}, PowerDNSSolver{})
+}
+
+// cacheCert stores a self-signed certificate for names, valid until notAfter,
+// as the cached certificate of m.
+func cacheCert(t *testing.T, m *Manager, notAfter time.Time, names ...string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    notAfter.AddDate(0, -3, 0),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bundle(key, [][]byte{der})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.cache.Put(context.Background(), m.domain+certSuffix, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_LoadAndRenewalDue(t *testing.T) {
+	m := newTestManager(t, "*.example.com")
+
+	if _, err := m.GetCertificate(nil); !errors.Is(err, ErrNoCertificate) {
+		t.Fatalf("GetCertificate() before issuance error = %v, want ErrNoCertificate", err)
+	}
+
+	if err := m.load(context.Background()); !errors.Is(err, autocert.ErrCacheMiss) {
+		t.Fatalf("load() on empty cache error = %v, want ErrCacheMiss", err)
+	}
+
+	if !m.renewalDue() {
+		t.Error("renewalDue() without certificate = false")
+	}
+
+	cacheCert(t, m, time.Now().AddDate(0, 2, 0), "*.example.com")
+
+	if err := m.load(context.Background()); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	if cert, err := m.GetCertificate(nil); err != nil || cert.Leaf.DNSNames[0] != "*.example.com" {
+		t.Errorf("GetCertificate() = %v, %v", cert, err)
+	}
+
+	if m.renewalDue() {
+		t.Error("renewalDue() two months before expiry = true")
+	}
+
+	m.now = func() time.Time { return time.Now().AddDate(0, 1, 15) }
+
+	if !m.renewalDue() {
+		t.Error("renewalDue() two weeks before expiry = false")
+	}
+}
+
+func TestManager_LoadRejectsOtherDomain(t *testing.T) {
+	m := newTestManager(t, "pdns.example.com")
+	cacheCert(t, m, time.Now().AddDate(0, 2, 0), "old.example.com")
+
+	if err := m.load(context.Background()); err == nil {
+		t.Error("load() accepted a certificate for another domain")
+	}
+}
+
+func TestManager_AccountKeyIsReused(t *testing.T) {
+	m := newTestManager(t, "pdns.example.com")
+
+	first, err := m.accountKey(context.Background())
+	if err != nil {
+		t.Fatalf("accountKey() error = %v", err)
+	}
+
+	second, err := m.accountKey(context.Background())
+	if err != nil {
+		t.Fatalf("accountKey() error = %v", err)
+	}
+
+	if !first.(*ecdsa.PrivateKey).Equal(second) {
+		t.Error("accountKey() created a new key instead of loading the cached one")
+	}
+}
+
+func TestZoneFor(t *testing.T) {
+	zones := []string{"example.com.", "sub.example.com.", "example.org."}
+
+	for _, tt := range []struct {
+		fqdn string
+		want string
+	}{
+		{"_acme-challenge.example.com", "example.com."},
+		{"_acme-challenge.pdns.sub.example.com.", "sub.example.com."},
+		{"_acme-challenge.Sub.Example.COM", "sub.example.com."},
+		{"_acme-challenge.notexample.com", ""},
+		{"_acme-challenge.example.net", ""},
+	} {
+		if got := zoneFor(tt.fqdn, zones); got != tt.want {
+			t.Errorf("zoneFor(%q) = %q, want %q", tt.fqdn, got, tt.want)
+		}
+	}
+}
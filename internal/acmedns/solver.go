@@ -0,0 +1,166 @@
+package acmedns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// challengeTTL is the TTL of challenge records, short so that resolvers
+	// of the CA do not keep an old value between attempts.
+	challengeTTL = 60
+
+	// propagationTimeout bounds the wait for PowerDNS to serve a record.
+	propagationTimeout = 2 * time.Minute
+
+	// pollInterval is the wait between DNS queries for a new record.
+	pollInterval = 5 * time.Second
+
+	// queryTimeout bounds a single DNS query.
+	queryTimeout = 5 * time.Second
+)
+
+// errNoZone is returned when no managed zone contains a challenge name.
+var errNoZone = errors.New("no managed zone contains the name")
+
+// PowerDNSSolver publishes challenge records through the shared PowerDNS
+// engine, in the managed zone closest to the challenge name.
+type PowerDNSSolver struct{}
+
+// Present replaces the TXT RRset at fqdn with value and waits until PowerDNS
+// answers with it. When PowerDNS does not answer DNS queries on the API host,
+// the wait times out with a warning and the CA is asked to validate anyway.
+func (PowerDNSSolver) Present(ctx context.Context, fqdn, value string) error {
+	if powerdns.Engine.Client == nil {
+		return powerdns.ErrClientNotInitialized
+	}
+
+	zone, err := managedZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+
+	err = powerdns.Engine.Records.Change(ctx, zone, fqdn, pdnsapi.RRTypeTXT, challengeTTL,
+		[]string{dnsclient.Quote(value)})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("zone", zone).Str("name", fqdn).Msg("acmedns: challenge record published")
+
+	if err = waitForTXT(ctx, powerdns.Engine.DNSServer(), fqdn, value); err != nil {
+		log.Warn().Err(err).Str("name", fqdn).Msg("acmedns: challenge record not seen on PowerDNS")
+	}
+
+	return ctx.Err()
+}
+
+// CleanUp deletes the TXT RRset at fqdn.
+func (PowerDNSSolver) CleanUp(ctx context.Context, fqdn string) error {
+	if powerdns.Engine.Client == nil {
+		return powerdns.ErrClientNotInitialized
+	}
+
+	zone, err := managedZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+
+	return powerdns.Engine.Records.Delete(ctx, zone, fqdn, pdnsapi.RRTypeTXT)
+}
+
+// managedZone returns the managed zone fqdn belongs to.
+func managedZone(ctx context.Context, fqdn string) (string, error) {
+	zones, err := zoneindex.Default.List(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(zones))
+	for i := range zones {
+		if zones[i].Name != nil {
+			names = append(names, *zones[i].Name)
+		}
+	}
+
+	zone := zoneFor(fqdn, names)
+	if zone == "" {
+		return "", fmt.Errorf("%w: %s", errNoZone, fqdn)
+	}
+
+	return zone, nil
+}
+
+// zoneFor returns the longest of zones that is fqdn or one of its parents,
+// or "" when there is none. Names are compared case-insensitively, with or
+// without the trailing dot.
+func zoneFor(fqdn string, zones []string) string {
+	name := canonical(fqdn)
+
+	var best string
+
+	for _, zone := range zones {
+		z := canonical(zone)
+		if (name == z || strings.HasSuffix(name, "."+z)) && len(z) > len(canonical(best)) {
+			best = zone
+		}
+	}
+
+	return best
+}
+
+func canonical(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// waitForTXT polls server until it answers fqdn with a TXT record of value.
+func waitForTXT(ctx context.Context, server, fqdn, value string) error {
+	if server == "" {
+		return powerdns.ErrClientNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, propagationTimeout)
+	defer cancel()
+
+	for {
+		if hasTXT(ctx, server, fqdn, value) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// hasTXT reports whether server answers fqdn with a TXT record of value.
+func hasTXT(ctx context.Context, server, fqdn, value string) bool {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	resp, err := dnsclient.Exchange(ctx, server, dnsclient.Query{Name: fqdn, Type: dnsmessage.TypeTXT})
+	if err != nil {
+		return false
+	}
+
+	for _, answer := range resp.Message.Answers {
+		if txt, ok := answer.Body.(*dnsmessage.TXTResource); ok && strings.Join(txt.TXT, "") == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,11 +3,13 @@ package activitylog
 
 import (
 	"encoding/json"
+	"strconv"
 
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
 )
 
 // Action constants define the supported audit event types.
@@ -21,12 +23,54 @@ const (
 	ActionRecordChanged      = "record_changed"
 	ActionRecordUndone       = "record_undone"
 	ActionZoneDeletedUndone  = "zone_deleted_undone"
+	ActionPasswordResetSent  = "password_reset_requested"
+	ActionPasswordReset      = "password_reset"
+	ActionZoneRequested      = "zone_requested"
+	ActionZoneRequestRejected = "zone_request_rejected"
+	ActionUserDeactivated    = "user_deactivated"
+	ActionZoneRetrieved      = "zone_axfr_retrieved"
+	ActionZoneNotified       = "zone_notified"
+	ActionRecordScheduled    = "record_scheduled"
+	ActionRecordScheduleApplied  = "record_schedule_applied"
+	ActionRecordScheduleCanceled = "record_schedule_canceled"
+	ActionZoneClaimed          = "zone_claimed"
+	ActionZoneClaimVerified    = "zone_claim_verified"
+	ActionZoneClaimApproved    = "zone_claim_approved"
+	ActionZoneClaimRejected    = "zone_claim_rejected"
+	ActionZoneOwnershipRevoked = "zone_ownership_revoked"
+	ActionZoneDeletionScheduled = "zone_deletion_scheduled"
+	ActionZoneDeletionRestored  = "zone_deletion_restored"
+	ActionAPIKeyCreated         = "api_key_created"
+	ActionAPIKeyRevoked         = "api_key_revoked"
+	ActionMaintenanceEnabled    = "maintenance_enabled"
+	ActionMaintenanceDisabled   = "maintenance_disabled"
+	ActionSetupAccountCreated   = "setup_account_created"
+	ActionSetupCompleted        = "setup_completed"
+	ActionUserRegistered        = "user_registered"
+	ActionUserApproved          = "user_approved"
+	ActionUserDenied            = "user_denied"
+	ActionUserOffboarded        = "user_offboarded"
+	ActionAuthSettingsChanged   = "auth_settings_changed"
+	ActionBackupExported        = "backup_exported"
+	ActionBackupRestored        = "backup_restored"
+	ActionSnapshotTaken         = "snapshot_taken"
+	ActionSnapshotZoneRestored  = "snapshot_zone_restored"
+	ActionChangeRequested       = "change_requested"
+	ActionChangeApproved        = "change_approved"
+	ActionChangeRejected        = "change_rejected"
+	ActionViewRecordChanged     = "view_record_changed"
+	ActionLabelsChanged         = "labels_changed"
+	ActionExpirySet             = "expiry_set"
+	ActionExpiryCleared         = "expiry_cleared"
+	ActionNameserverSetApplied  = "nameserver_set_applied"
 )
 
 // ResourceType constants categorize the resource affected by an action.
 const (
-	ResourceTypeAuth = "auth"
-	ResourceTypeZone = "zone"
+	ResourceTypeAuth   = "auth"
+	ResourceTypeZone   = "zone"
+	ResourceTypeUser   = "user"
+	ResourceTypeSystem = "system"
 )
 
 // Entry holds all fields needed to record an activity log event.
@@ -39,9 +83,14 @@ type Entry struct {
 	ResourceName string
 	Details      any
 	IPAddress    string
+	// AuthMethod and APIKeyID tell how the user authenticated; see
+	// auth.Attribute.
+	AuthMethod string
+	APIKeyID   *uint64
 }
 
-// Record creates a new ActivityLog entry in the database.
+// Record creates a new ActivityLog entry in the database and publishes it as
+// eventbus.TopicActivity.
 func Record(e *Entry) {
 	detailsJSON := ""
 
@@ -60,10 +109,16 @@ func Record(e *Entry) {
 		ResourceName: e.ResourceName,
 		Details:      detailsJSON,
 		IPAddress:    e.IPAddress,
+		AuthMethod:   e.AuthMethod,
+		APIKeyID:     e.APIKeyID,
 	}
 
 	if err := e.DB.Create(entry).Error; err != nil {
 		log.Error().Err(err).Str("action", e.Action).Str("username", e.Username).
 			Msg("failed to record activity log entry")
+
+		return
 	}
+
+	eventbus.Default.Publish(eventbus.TopicActivity, strconv.FormatUint(entry.ID, 10))
 }
@@ -1,5 +1,10 @@
 package activitylog
 
+import (
+	"maps"
+	"slices"
+)
+
 // FieldDiff represents a single changed setting with its old and new value.
 type FieldDiff struct {
 	Field string `json:"field"`
@@ -35,6 +40,64 @@ type RecordsDiff struct {
 	Records []RecordEntryDiff `json:"records"`
 }
 
+// ViewRecordsDiff is stored with view_record_changed activity entries: the
+// RRset changes of the variant of a zone on a PowerDNS view.
+type ViewRecordsDiff struct {
+	// View is the name of the view.
+	View string `json:"view"`
+	RecordsDiff
+}
+
+// LabelsDiff is stored with labels_changed activity entries: the labels of
+// the zone, or of one of its RRsets, before and after the change.
+type LabelsDiff struct {
+	// Name and Type identify the RRset; both are empty for the zone.
+	Name string            `json:"name,omitempty"`
+	Type string            `json:"type,omitempty"`
+	Old  map[string]string `json:"old,omitempty"`
+	New  map[string]string `json:"new,omitempty"`
+}
+
+// Changes returns the labels that were added, changed or removed, sorted by
+// key. Old is empty for an added label and New for a removed one.
+func (d *LabelsDiff) Changes() []FieldDiff {
+	keys := slices.Collect(maps.Keys(d.Old))
+	for key := range d.New {
+		if _, ok := d.Old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	var out []FieldDiff
+
+	for _, key := range keys {
+		oldValue, hadOld := d.Old[key]
+		newValue, hasNew := d.New[key]
+
+		if hadOld && hasNew && oldValue == newValue {
+			continue
+		}
+
+		out = append(out, FieldDiff{Field: key, Old: oldValue, New: newValue})
+	}
+
+	return out
+}
+
+// ExpiryDetails is stored with expiry_set and expiry_cleared activity
+// entries: the expiry date of the zone, or of one of its RRsets, before and
+// after the change, as YYYY-MM-DD.
+type ExpiryDetails struct {
+	// Name and Type identify the RRset; both are empty for the zone.
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+	Note string `json:"note,omitempty"`
+}
+
 // RecordUndoneDetails is stored with record_undone activity entries.
 type RecordUndoneDetails struct {
 	// OriginalID is the ID of the record_changed entry that was reversed.
@@ -0,0 +1,64 @@
+package activitylog
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/logger"
+)
+
+// Stream writes the entries published on bus as eventbus.TopicActivity to w,
+// e.g. to feed a SIEM. Entries replayed from other replicas are skipped;
+// every replica streams the entries it recorded.
+func Stream(bus *eventbus.Bus, db *gorm.DB, w *logger.AuditWriter) {
+	bus.Subscribe(eventbus.TopicActivity, func(e eventbus.Event) {
+		if e.Remote {
+			return
+		}
+
+		id, err := strconv.ParseUint(e.Key, 10, 64)
+		if err != nil {
+			return
+		}
+
+		var entry models.ActivityLog
+		if err = db.First(&entry, id).Error; err != nil {
+			log.Warn().Err(err).Uint64("id", id).Msg("audit: failed to load activity log entry")
+			return
+		}
+
+		w.Write(AuditEvent(&entry))
+	})
+}
+
+// AuditEvent returns entry as an event of the audit stream.
+func AuditEvent(entry *models.ActivityLog) *logger.AuditEvent {
+	e := &logger.AuditEvent{
+		ID:           entry.ID,
+		Time:         entry.CreatedAt,
+		User:         entry.Username,
+		UserID:       entry.UserID,
+		Action:       entry.Action,
+		Outcome:      logger.AuditOutcomeSuccess,
+		ResourceType: entry.ResourceType,
+		ResourceName: entry.ResourceName,
+		IPAddress:    entry.IPAddress,
+		AuthMethod:   entry.AuthMethod,
+		APIKeyID:     entry.APIKeyID,
+	}
+
+	if entry.Action == ActionLoginFailed {
+		e.Outcome = logger.AuditOutcomeFailure
+	}
+
+	if entry.Details != "" && json.Valid([]byte(entry.Details)) {
+		e.Details = json.RawMessage(entry.Details)
+	}
+
+	return e
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/uniuri"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so keys are easy to recognize in
+	// configuration files and secret scanners.
+	APIKeyPrefix = "gpa_"
+
+	// APIKeyHeader is the request header carrying an API key. Keys are also
+	// accepted as "Authorization: Bearer gpa_...".
+	APIKeyHeader = "X-API-Key"
+
+	// apiKeyLen is the length of the random part of a key (~238 bits of entropy).
+	apiKeyLen = 40
+
+	// apiKeyShownLen is how much of a key is stored in clear to tell keys apart.
+	apiKeyShownLen = len(APIKeyPrefix) + 6
+
+	// apiKeyTouchInterval throttles the last-used updates of busy keys.
+	apiKeyTouchInterval = time.Minute
+)
+
+// hashAPIKey returns the hex-encoded SHA-256 hash stored for token.
+func hashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPermissions returns the permissions key is limited to, or nil when
+// it carries all permissions of its user.
+func APIKeyPermissions(key *models.APIKey) []string {
+	if key.Permissions == "" {
+		return nil
+	}
+
+	return strings.Fields(key.Permissions)
+}
+
+// APIKeyScope narrows what an API key may do below the rights of its user,
+// e.g. for an external-dns deployment that should only manage A and TXT
+// records of one zone. Empty fields leave that aspect unrestricted.
+type APIKeyScope struct {
+	// Permissions limits the key to these permissions of the user.
+	Permissions []string
+	// Zones limits the key to the zones matching one of these zone patterns.
+	Zones []string
+	// RecordTypes limits record changes made with the key to these types.
+	RecordTypes []string
+}
+
+// APIKeyZones returns the zone patterns key is limited to, or nil when it
+// may access all zones of its user. Stored patterns that no longer parse are
+// skipped, leaving the key with fewer zones rather than more.
+func APIKeyZones(key *models.APIKey) []ZonePattern {
+	texts := key.ZonePatternList()
+	if len(texts) == 0 {
+		return nil
+	}
+
+	patterns := make([]ZonePattern, 0, len(texts))
+
+	for _, text := range texts {
+		pattern, err := ParseZonePattern(text)
+		if err != nil {
+			log.Warn().Err(err).Uint64("api_key_id", key.ID).Msg("skipping stored zone pattern of api key")
+			continue
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns
+}
+
+// CreateAPIKey issues an API key for userID. scope limits the key to a subset
+// of the user's own permissions, zones and record types. A nil expiresAt
+// never expires. The plain token is returned once; only its hash is stored.
+func (s *Service) CreateAPIKey(userID uint64, name string, scope APIKeyScope,
+	expiresAt *time.Time,
+) (string, *models.APIKey, error) {
+	if len(scope.Permissions) > 0 {
+		granted, err := s.GetUserPermissions(userID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		for _, perm := range scope.Permissions {
+			if !slices.Contains(granted, perm) {
+				return "", nil, fmt.Errorf("%w: %s", ErrAPIKeyPermission, perm)
+			}
+		}
+	}
+
+	zones := make([]string, 0, len(scope.Zones))
+
+	for _, text := range scope.Zones {
+		pattern, err := ParseZonePattern(text)
+		if err != nil {
+			return "", nil, err
+		}
+
+		zones = append(zones, pattern.String())
+	}
+
+	token := APIKeyPrefix + uniuri.NewLen(apiKeyLen)
+	key := &models.APIKey{
+		UserID:      userID,
+		Name:        name,
+		Prefix:      token[:apiKeyShownLen],
+		TokenHash:   hashAPIKey(token),
+		Permissions: strings.Join(scope.Permissions, " "),
+		Zones:       strings.Join(zones, "\n"),
+		RecordTypes: models.NormalizeRecordTypes(strings.Join(scope.RecordTypes, ",")),
+		ExpiresAt:   expiresAt,
+	}
+
+	if err := s.db.Create(key).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to store api key: %w", err)
+	}
+
+	return token, key, nil
+}
+
+// ListAPIKeys returns the API keys of userID, newest first, including expired
+// and revoked ones.
+func (s *Service) ListAPIKeys(userID uint64) ([]models.APIKey, error) {
+	var keys []models.APIKey
+
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes the API key id of userID. Revoking a revoked key is a
+// no-op.
+func (s *Service) RevokeAPIKey(userID, id uint64) (*models.APIKey, error) {
+	var key models.APIKey
+
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key: %w", err)
+	}
+
+	if key.RevokedAt != nil {
+		return &key, nil
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&key).Update("revoked_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	key.RevokedAt = &now
+
+	return &key, nil
+}
+
+// apiKeyResolver authenticates requests carrying an API key in the
+// X-API-Key header or as a bearer token starting with APIKeyPrefix.
+type apiKeyResolver struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// Resolve implements Resolver.
+func (r *apiKeyResolver) Resolve(c fiber.Ctx) (*Principal, error) {
+	token := c.Get(APIKeyHeader)
+	if token == "" {
+		if bearer := bearerToken(c); strings.HasPrefix(bearer, APIKeyPrefix) {
+			token = bearer
+		}
+	}
+
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	if r.db == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := r.now()
+
+	var key models.APIKey
+
+	err := r.db.Preload("User").
+		Where("token_hash = ? AND revoked_at IS NULL", hashAPIKey(token)).
+		First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key: %w", err)
+	}
+
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !key.User.Active {
+		return nil, ErrUserAccountDisabled
+	}
+
+	// Record the use at most once a minute, so busy keys do not write on
+	// every request.
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		r.db.Model(&models.APIKey{}).Where(whereID, key.ID).Update("last_used_at", now)
+	}
+
+	id := key.ID
+
+	return &Principal{
+		User:        key.User,
+		Method:      MethodAPIKey,
+		APIKeyID:    &id,
+		Scopes:      APIKeyPermissions(&key),
+		Zones:       APIKeyZones(&key),
+		RecordTypes: key.RecordTypeList(),
+	}, nil
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or "".
+func bearerToken(c fiber.Ctx) string {
+	scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func apiKeyFixture(t *testing.T) (*gorm.DB, *Service, models.User) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.Permission{}, &models.RolePermission{}, &models.User{},
+		&models.Group{}, &models.GroupMapping{}, &models.UserGroup{}, &models.APIKey{},
+	))
+
+	role := models.Role{Name: "user"}
+	require.NoError(t, db.Create(&role).Error)
+
+	for _, name := range []string{PermZoneList, PermZoneCreate} {
+		perm := models.Permission{Name: name, Resource: "zone", Action: name}
+		require.NoError(t, db.Create(&perm).Error)
+		require.NoError(t, db.Create(&models.RolePermission{RoleID: role.ID, PermissionID: perm.ID}).Error)
+	}
+
+	user := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: role.ID, Active: true}
+	require.NoError(t, db.Create(&user).Error)
+
+	return db, NewService(db), user
+}
+
+// apiKeyApp serves a zone list and a zone create route behind Authenticate.
+func apiKeyApp(s *Service) *fiber.App {
+	app := fiber.New()
+	app.Use(Authenticate(s))
+	ok := func(c fiber.Ctx) error { return c.SendString(PrincipalFrom(c).Method) }
+	app.Get("/zones", RequirePermission(s, PermZoneList), ok)
+	app.Post("/zones", RequirePermission(s, PermZoneCreate), ok)
+	app.Get("/keys", RequireSession(), ok)
+
+	return app
+}
+
+func apiKeyRequest(t *testing.T, app *fiber.App, method, path string, header ...string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, http.NoBody)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestAPIKey_Authenticates(t *testing.T) {
+	db, s, user := apiKeyFixture(t)
+	app := apiKeyApp(s)
+
+	token, key, err := s.CreateAPIKey(user.ID, "ci", APIKeyScope{}, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, APIKeyPrefix))
+	assert.Equal(t, token[:apiKeyShownLen], key.Prefix)
+	assert.NotContains(t, key.TokenHash, token)
+
+	assert.Equal(t, fiber.StatusOK, apiKeyRequest(t, app, fiber.MethodGet, "/zones", APIKeyHeader, token))
+	assert.Equal(t, fiber.StatusOK, apiKeyRequest(t, app, fiber.MethodPost, "/zones", "Authorization", "Bearer "+token))
+	assert.Equal(t, fiber.StatusUnauthorized,
+		apiKeyRequest(t, app, fiber.MethodGet, "/zones", APIKeyHeader, APIKeyPrefix+"unknown"))
+	assert.Equal(t, fiber.StatusUnauthorized, apiKeyRequest(t, app, fiber.MethodGet, "/zones"))
+
+	// Keys cannot reach session-only pages.
+	assert.Equal(t, fiber.StatusForbidden, apiKeyRequest(t, app, fiber.MethodGet, "/keys", APIKeyHeader, token))
+
+	var stored models.APIKey
+	require.NoError(t, db.First(&stored, key.ID).Error)
+	assert.NotNil(t, stored.LastUsedAt)
+}
+
+func TestAPIKey_Scopes(t *testing.T) {
+	_, s, user := apiKeyFixture(t)
+	app := apiKeyApp(s)
+
+	token, _, err := s.CreateAPIKey(user.ID, "read-only", APIKeyScope{Permissions: []string{PermZoneList}}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, fiber.StatusOK, apiKeyRequest(t, app, fiber.MethodGet, "/zones", APIKeyHeader, token))
+	assert.Equal(t, fiber.StatusForbidden, apiKeyRequest(t, app, fiber.MethodPost, "/zones", APIKeyHeader, token))
+
+	p := &Principal{User: user, Scopes: []string{PermZoneList}}
+	perms, err := s.PrincipalPermissions(p)
+	require.NoError(t, err)
+	assert.Equal(t, []string{PermZoneList}, perms)
+
+	// A key cannot carry permissions its user lacks.
+	_, _, err = s.CreateAPIKey(user.ID, "admin", APIKeyScope{Permissions: []string{PermAdminUsers}}, nil)
+	require.ErrorIs(t, err, ErrAPIKeyPermission)
+}
+
+func TestAPIKey_RevokedAndExpired(t *testing.T) {
+	db, s, user := apiKeyFixture(t)
+	app := apiKeyApp(s)
+
+	revoked, key, err := s.CreateAPIKey(user.ID, "old", APIKeyScope{}, nil)
+	require.NoError(t, err)
+
+	_, err = s.RevokeAPIKey(user.ID, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, apiKeyRequest(t, app, fiber.MethodGet, "/zones", APIKeyHeader, revoked))
+
+	_, err = s.RevokeAPIKey(user.ID+1, key.ID)
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	past := time.Now().Add(-time.Hour)
+	expired, _, err := s.CreateAPIKey(user.ID, "expired", APIKeyScope{}, &past)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, apiKeyRequest(t, app, fiber.MethodGet, "/zones", APIKeyHeader, expired))
+
+	active, _, err := s.CreateAPIKey(user.ID, "active", APIKeyScope{}, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&user).Update("active", false).Error)
+	assert.Equal(t, fiber.StatusUnauthorized, apiKeyRequest(t, app, fiber.MethodGet, "/zones", APIKeyHeader, active))
+
+	keys, err := s.ListAPIKeys(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
+
+func TestAPIKey_ZonesAndRecordTypes(t *testing.T) {
+	_, s, user := apiKeyFixture(t)
+
+	token, key, err := s.CreateAPIKey(user.ID, "external-dns", APIKeyScope{
+		Zones:       []string{"K8s.Example.com", "*.apps.example.com."},
+		RecordTypes: []string{"txt", "A", "a"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "k8s.example.com.\n*.apps.example.com.", key.Zones)
+	assert.Equal(t, "A,TXT", key.RecordTypes)
+
+	app := fiber.New()
+	app.Use(Authenticate(s))
+	app.Get("/zones/:zone", func(c fiber.Ctx) error {
+		if !CanAccessZone(c, nil, c.Params("zone")) {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	get := func(zone string) int {
+		return apiKeyRequest(t, app, fiber.MethodGet, "/zones/"+zone, APIKeyHeader, token)
+	}
+
+	assert.Equal(t, fiber.StatusOK, get("k8s.example.com."))
+	assert.Equal(t, fiber.StatusOK, get("eu.apps.example.com."))
+	assert.Equal(t, fiber.StatusForbidden, get("example.com."))
+
+	p := &Principal{User: user, RecordTypes: key.RecordTypeList()}
+	assert.Equal(t, map[string]bool{"A": true, "TXT": true}, p.LimitRecordTypes(nil))
+	assert.Equal(t, map[string]bool{"A": true}, p.LimitRecordTypes(map[string]bool{"A": true, "MX": true}))
+	assert.Nil(t, (&Principal{User: user}).LimitRecordTypes(nil))
+
+	_, _, err = s.CreateAPIKey(user.ID, "bad", APIKeyScope{Zones: []string{"*"}}, nil)
+	require.ErrorIs(t, err, ErrInvalidZonePattern)
+}
@@ -16,6 +16,14 @@ var (
 	// ErrUserAccountDisabled is returned when attempting to authenticate a disabled user account.
 	ErrUserAccountDisabled = errors.New("user account is disabled")
 
+	// ErrUserPendingApproval is returned when a self-registered account that
+	// has not been approved yet tries to log in with the right password.
+	ErrUserPendingApproval = errors.New("user account is awaiting approval")
+
+	// ErrRegistrationNotPending is returned when a registration is approved or
+	// denied that is not pending (any longer).
+	ErrRegistrationNotPending = errors.New("registration is not pending")
+
 	// ErrInvalidPassword is returned when the provided password is incorrect during authentication.
 	ErrInvalidPassword = errors.New("invalid password")
 
@@ -25,4 +33,31 @@ var (
 	// ErrMultipleUsersFound is returned when a query expected one user but found multiple.
 	// This typically indicates a misconfigured LDAP filter or duplicate entries.
 	ErrMultipleUsersFound = errors.New("multiple users found")
+
+	// ErrPasswordPolicy is returned when a new password does not meet the
+	// password policy.
+	ErrPasswordPolicy = errors.New("password does not meet the password policy")
+
+	// ErrPasswordUnchanged is returned when a password is changed to itself.
+	ErrPasswordUnchanged = errors.New("new password must differ from the current password")
+
+	// ErrInvalidResetToken is returned when a password reset token is unknown,
+	// already used or expired.
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+	// ErrNoCredentials is returned by a Resolver when the request carries no
+	// credentials of its kind, so the next resolver should be tried.
+	ErrNoCredentials = errors.New("no credentials")
+
+	// ErrInvalidCredentials is returned by a Resolver when the request carries
+	// credentials of its kind that are unknown, expired or revoked.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrAPIKeyNotFound is returned when an API key does not exist or belongs
+	// to another user.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// ErrAPIKeyPermission is returned when an API key is to be limited to a
+	// permission its user does not have.
+	ErrAPIKeyPermission = errors.New("api key permission not granted to the user")
 )
@@ -14,6 +14,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 )
 
@@ -66,6 +67,33 @@ type LDAPConfig struct {
 	SearchAttributes []string
 }
 
+// NewLDAPConfig builds the provider configuration from the application's
+// [auth.ldap] settings.
+func NewLDAPConfig(c *config.LDAPAuth) *LDAPConfig {
+	return &LDAPConfig{
+		Enabled:          c.Enabled,
+		Host:             c.Host,
+		Port:             c.Port,
+		UseSSL:           c.UseSSL,
+		UseTLS:           c.UseTLS,
+		SkipVerify:       c.SkipVerify,
+		BindDN:           c.BindDN,
+		BindPassword:     c.BindPassword,
+		BaseDN:           c.BaseDN,
+		UserFilter:       c.UserFilter,
+		GroupBaseDN:      c.GroupBaseDN,
+		GroupFilter:      c.GroupFilter,
+		GroupMemberAttr:  c.GroupMemberAttr,
+		UsernameAttr:     c.UsernameAttr,
+		EmailAttr:        c.EmailAttr,
+		FirstNameAttr:    c.FirstNameAttr,
+		LastNameAttr:     c.LastNameAttr,
+		GroupNameAttr:    c.GroupNameAttr,
+		Timeout:          c.Timeout,
+		SearchAttributes: c.SearchAttrs,
+	}
+}
+
 // LDAPProvider handles LDAP authentication.
 type LDAPProvider struct {
 	config *LDAPConfig
@@ -161,6 +189,35 @@ func (p *LDAPProvider) Connect() (*ldap.Conn, error) {
 
 // Authenticate authenticates a user against LDAP and returns the user and their groups.
 func (p *LDAPProvider) Authenticate(username, password string) (*models.User, []string, error) {
+	userEntry, groups, err := p.verify(username, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	email := userEntry.GetAttributeValue(p.config.EmailAttr)
+	firstName := userEntry.GetAttributeValue(p.config.FirstNameAttr)
+	lastName := userEntry.GetAttributeValue(p.config.LastNameAttr)
+
+	user, errUpsert := p.upsertLDAPUser(username, userEntry.DN, email, firstName, lastName)
+	if errUpsert != nil {
+		return nil, nil, errUpsert
+	}
+
+	return user, groups, nil
+}
+
+// TestLogin checks username and password against the directory like
+// Authenticate, but neither creates nor updates the user. It returns the
+// directory groups of the user and is used to try out unsaved settings.
+func (p *LDAPProvider) TestLogin(username, password string) ([]string, error) {
+	_, groups, err := p.verify(username, password)
+
+	return groups, err
+}
+
+// verify looks up username, binds as the user with password and returns the
+// user's entry and groups.
+func (p *LDAPProvider) verify(username, password string) (*ldap.Entry, []string, error) {
 	conn, err := p.Connect()
 	if err != nil {
 		return nil, nil, err
@@ -181,31 +238,52 @@ func (p *LDAPProvider) Authenticate(username, password string) (*models.User, []
 		return nil, nil, errSearch
 	}
 
-	userDN := userEntry.DN
-
-	if errAuthAsUser := p.authenticateAsUser(conn, userDN, password); errAuthAsUser != nil {
+	if errAuthAsUser := p.authenticateAsUser(conn, userEntry.DN, password); errAuthAsUser != nil {
 		return nil, nil, errAuthAsUser
 	}
 
-	email := userEntry.GetAttributeValue(p.config.EmailAttr)
-	firstName := userEntry.GetAttributeValue(p.config.FirstNameAttr)
-	lastName := userEntry.GetAttributeValue(p.config.LastNameAttr)
-
 	if errRebind := p.rebindServiceForGroups(conn); errRebind != nil {
 		return nil, nil, errRebind
 	}
 
-	groups, errUserGroup := p.getUserGroups(conn, userDN)
+	groups, errUserGroup := p.getUserGroups(conn, userEntry.DN)
 	if errUserGroup != nil {
 		return nil, nil, fmt.Errorf("failed to get user groups: %w", errUserGroup)
 	}
 
-	user, errUpsert := p.upsertLDAPUser(username, userDN, email, firstName, lastName)
-	if errUpsert != nil {
-		return nil, nil, errUpsert
+	return userEntry, groups, nil
+}
+
+// LookupUserGroups returns the directory groups of username without
+// authenticating as the user. It binds with the service account and is used to
+// preview a group sync.
+func (p *LDAPProvider) LookupUserGroups(username string) ([]string, error) {
+	conn, err := p.Connect()
+	if err != nil {
+		return nil, err
 	}
 
-	return user, groups, nil
+	defer func() {
+		if errClose := conn.Close(); errClose != nil {
+			log.Warn().Err(errClose).Msg("failed to close LDAP connection")
+		}
+	}()
+
+	if errBindService := p.bindServiceForSearch(conn); errBindService != nil {
+		return nil, errBindService
+	}
+
+	userEntry, errSearch := p.searchUserEntry(conn, username)
+	if errSearch != nil {
+		return nil, errSearch
+	}
+
+	groups, errUserGroup := p.getUserGroups(conn, userEntry.DN)
+	if errUserGroup != nil {
+		return nil, fmt.Errorf("failed to get user groups: %w", errUserGroup)
+	}
+
+	return groups, nil
 }
 
 // bindServiceForSearch binds with the configured service account (if provided)
@@ -304,6 +382,8 @@ func (p *LDAPProvider) upsertLDAPUser(username, userDN, email, firstName, lastNa
 			Username:    username,
 			Email:       email,
 			DisplayName: displayName,
+			FirstName:   firstName,
+			LastName:    lastName,
 			AuthSource:  models.AuthSourceLDAP,
 			ExternalID:  userDN,
 			RoleID:      viewerRole.ID,
@@ -325,6 +405,8 @@ func (p *LDAPProvider) upsertLDAPUser(username, userDN, email, firstName, lastNa
 	// Update existing user
 	user.Email = email
 	user.DisplayName = displayName
+	user.FirstName = firstName
+	user.LastName = lastName
 	user.UpdatedAt = time.Now()
 
 	if err = p.db.Save(&user).Error; err != nil {
@@ -7,12 +7,14 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 )
 
 // LocalProvider handles local database authentication.
 type LocalProvider struct {
-	db *gorm.DB
+	db     *gorm.DB
+	policy config.PasswordPolicy
 }
 
 const (
@@ -44,6 +46,15 @@ func (p *LocalProvider) Authenticate(username, password string) (*models.User, e
 		return nil, fmt.Errorf("failed to query user: %w", err)
 	}
 
+	// A pending registration only learns its state with the right password.
+	if user.Pending {
+		if !user.VerifyPassword(password) {
+			return nil, ErrInvalidPassword
+		}
+
+		return nil, ErrUserPendingApproval
+	}
+
 	// Check if user is active
 	if !user.Active {
 		return nil, ErrUserAccountDisabled
@@ -78,8 +89,13 @@ func (p *LocalProvider) CreateUser(
 		return nil, fmt.Errorf("failed to check existing user: %w", err)
 	}
 
+	if err := p.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword := models.HashPassword(password)
+	now := time.Now()
 
 	// Create user
 	user := models.User{
@@ -90,8 +106,9 @@ func (p *LocalProvider) CreateUser(
 		DisplayName: displayName,
 		RoleID:      roleID,
 		AuthSource: models.AuthSourceLocal,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		PasswordChangedAt: &now,
 	}
 
 	if err := p.db.Create(&user).Error; err != nil {
@@ -115,7 +132,9 @@ func (p *LocalProvider) UpdateUser(userID uint64, email, displayName string, rol
 		Updates(updates).Error
 }
 
-// ChangePassword changes a user's password.
+// ChangePassword changes a user's password. The new password must meet the
+// password policy and differ from the old one; a pending forced change is
+// cleared.
 func (p *LocalProvider) ChangePassword(userID uint64, oldPassword, newPassword string) error {
 	var user models.User
 	if err := p.db.Where(whereIDAndAuthSource, userID, models.AuthSourceLocal).
@@ -128,22 +147,40 @@ func (p *LocalProvider) ChangePassword(userID uint64, oldPassword, newPassword s
 		return ErrInvalidOldPassword
 	}
 
-	// Hash new password
-	hashedPassword := models.HashPassword(newPassword)
+	if err := p.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	if user.VerifyPassword(newPassword) {
+		return ErrPasswordUnchanged
+	}
 
 	// Update password
 	return p.db.Model(&models.User{}).
 		Where(whereID, userID).
-		Update("password", hashedPassword).Error
+		Updates(passwordUpdates(newPassword, false)).Error
 }
 
-// ResetPassword resets a user's password (admin function).
+// ResetPassword resets a user's password (admin function). The user has to
+// choose a new password at the next login.
 func (p *LocalProvider) ResetPassword(userID uint64, newPassword string) error {
-	hashedPassword := models.HashPassword(newPassword)
+	if err := p.ValidatePassword(newPassword); err != nil {
+		return err
+	}
 
 	return p.db.Model(&models.User{}).
 		Where(whereIDAndAuthSource, userID, models.AuthSourceLocal).
-		Update("password", hashedPassword).Error
+		Updates(passwordUpdates(newPassword, true)).Error
+}
+
+// passwordUpdates returns the columns to update when the password is set to
+// password; mustChange asks for another change at the next login.
+func passwordUpdates(password string, mustChange bool) map[string]any {
+	return map[string]any{
+		"password":             models.HashPassword(password),
+		"password_changed_at":  time.Now(),
+		"must_change_password": mustChange,
+	}
 }
 
 // ActivateUser activates a user account.
@@ -3,130 +3,58 @@ package auth
 import (
 	"github.com/gofiber/fiber/v3"
 	"github.com/rs/zerolog/log"
-
-	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
 )
 
 // RequirePermission creates Fiber middleware that requires a specific permission.
 func RequirePermission(authService *Service, permission string) fiber.Handler {
-	return func(c fiber.Ctx) error {
-		// Get session cookie
-		sessionID := c.Cookies("session")
-		if sessionID == "" {
-			log.Error().Msg("No session cookie found")
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
-		}
-
-		// Read session data
-		sessionData := new(session.Data)
-		if err := sessionData.Read(sessionID); err != nil {
-			log.Error().Err(err).Msg("Failed to read session")
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
-		}
-
-		// Check if the session is valid
-		if sessionData.User.ID == 0 {
-			log.Error().Msg("Invalid session data")
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
-		}
-
-		// Check if the user has permission
-		hasPermission, err := authService.HasPermission(sessionData.User.ID, permission)
-		if err != nil {
-			log.Error().Err(err).Uint64("user_id", sessionData.User.ID).Str("permission", permission).
-				Msg("Failed to check permission")
-
-			return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
-		}
-
-		if !hasPermission {
-			log.Warn().Uint64("user_id", sessionData.User.ID).Str("permission", permission).
-				Msg("User lacks required permission")
-
-			return c.Status(fiber.StatusForbidden).SendString("Forbidden: You don't have permission to access this resource")
-		}
+	noteGuard(permission)
 
-		// User has permission, proceed
-		return c.Next()
-	}
+	return requirePermissions(authService, []string{permission}, func(p *Principal, _ ...string) (bool, error) {
+		return authService.PrincipalHasPermission(p, permission)
+	})
 }
 
 // RequireAnyPermission creates Fiber middleware that requires at least one of the given permissions.
-func RequireAnyPermission(authService *Service, permissions ...string) fiber.Handler { //nolint:dupl // ok for now
-	return func(c fiber.Ctx) error {
-		// Get session cookie
-		sessionID := c.Cookies("session")
-		if sessionID == "" {
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
-		}
-
-		// Read session data
-		sessionData := new(session.Data)
-		if err := sessionData.Read(sessionID); err != nil {
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
-		}
-
-		if sessionData.User.ID == 0 {
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
-		}
-
-		// Check if user has any of the permissions
-		hasPermission, err := authService.HasAnyPermission(sessionData.User.ID, permissions)
-		if err != nil {
-			log.Error().Err(err).Uint64("user_id", sessionData.User.ID).Strs("permissions", permissions).
-				Msg("Failed to check permissions")
+func RequireAnyPermission(authService *Service, permissions ...string) fiber.Handler {
+	noteGuard(permissions...)
 
-			return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
-		}
-
-		if !hasPermission {
-			log.Warn().Uint64("user_id", sessionData.User.ID).Strs("permissions", permissions).
-				Msg("User lacks required permissions")
-
-			return c.Status(fiber.StatusForbidden).SendString("Forbidden: You don't have permission to access this resource")
-		}
-
-		// User has at least one permission, proceed
-		return c.Next()
-	}
+	return requirePermissions(authService, permissions, authService.PrincipalHasAnyPermission)
 }
 
 // RequireAllPermissions creates Fiber middleware that requires all the given permissions.
-func RequireAllPermissions(authService *Service, permissions ...string) fiber.Handler { //nolint:dupl // ok for now
-	return func(c fiber.Ctx) error {
-		// Get session cookie
-		sessionID := c.Cookies("session")
-		if sessionID == "" {
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
-		}
+func RequireAllPermissions(authService *Service, permissions ...string) fiber.Handler {
+	noteGuard(permissions...)
 
-		// Read session data
-		sessionData := new(session.Data)
-		if err := sessionData.Read(sessionID); err != nil {
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
-		}
+	return requirePermissions(authService, permissions, authService.PrincipalHasAllPermissions)
+}
 
-		if sessionData.User.ID == 0 {
-			return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized")
+// requirePermissions is the middleware behind the Require*Permission
+// constructors: it lets the request pass when check accepts its principal.
+func requirePermissions(authService *Service, permissions []string,
+	check func(p *Principal, permissions ...string) (bool, error),
+) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		p := principal(c, authService)
+		if p == nil {
+			log.Error().Str("path", c.Path()).Msg("No authenticated principal")
+			return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
 		}
 
-		// Check if user has all permissions
-		hasPermissions, err := authService.HasAllPermissions(sessionData.User.ID, permissions)
+		allowed, err := check(p, permissions...)
 		if err != nil {
-			log.Error().Err(err).Uint64("user_id", sessionData.User.ID).Strs("permissions", permissions).
+			log.Error().Err(err).Uint64("user_id", p.User.ID).Strs("permissions", permissions).
 				Msg("Failed to check permissions")
 
-			return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+			return fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
 		}
 
-		if !hasPermissions {
-			log.Warn().Uint64("user_id", sessionData.User.ID).Strs("permissions", permissions).
+		if !allowed {
+			log.Warn().Uint64("user_id", p.User.ID).Str("method", p.Method).Strs("permissions", permissions).
 				Msg("User lacks required permissions")
 
-			return c.Status(fiber.StatusForbidden).SendString("Forbidden: You don't have permission to access this resource")
+			return fiber.NewError(fiber.StatusForbidden, "Forbidden: You don't have permission to access this resource")
 		}
 
-		// User has all permissions, proceed
 		return c.Next()
 	}
 }
@@ -143,21 +71,12 @@ func RequireAuthenticated() fiber.Handler {
 // HasPermissionInContext checks if the current user in the Fiber context has a permission.
 // Useful for conditional rendering in handlers.
 func HasPermissionInContext(c fiber.Ctx, authService *Service, permission string) bool {
-	sessionID := c.Cookies("session")
-	if sessionID == "" {
+	p := principal(c, authService)
+	if p == nil {
 		return false
 	}
 
-	sessionData := new(session.Data)
-	if err := sessionData.Read(sessionID); err != nil {
-		return false
-	}
-
-	if sessionData.User.ID == 0 {
-		return false
-	}
-
-	hasPermission, err := authService.HasPermission(sessionData.User.ID, permission)
+	hasPermission, err := authService.PrincipalHasPermission(p, permission)
 	if err != nil {
 		return false
 	}
@@ -167,21 +86,12 @@ func HasPermissionInContext(c fiber.Ctx, authService *Service, permission string
 
 // GetUserPermissionsFromContext retrieves all permissions for the current user.
 func GetUserPermissionsFromContext(c fiber.Ctx, authService *Service) ([]string, error) {
-	sessionID := c.Cookies("session")
-	if sessionID == "" {
-		return nil, nil
-	}
-
-	sessionData := new(session.Data)
-	if err := sessionData.Read(sessionID); err != nil {
-		return nil, err
-	}
-
-	if sessionData.User.ID == 0 {
+	p := principal(c, authService)
+	if p == nil {
 		return nil, nil
 	}
 
-	return authService.GetUserPermissions(sessionData.User.ID)
+	return authService.PrincipalPermissions(p)
 }
 
 // AddPermissionsToLocals is a Fiber middleware that adds user permissions to fiber.Locals.
@@ -193,23 +103,14 @@ func AddPermissionsToLocals(authService *Service) fiber.Handler {
 		// Always provide a safe default so templates can call hasPermission unconditionally.
 		c.Locals("hasPermission", noPermission)
 
-		sessionID := c.Cookies("session")
-		if sessionID == "" {
-			return c.Next()
-		}
-
-		sessionData := new(session.Data)
-		if err := sessionData.Read(sessionID); err != nil {
-			return c.Next()
-		}
-
-		if sessionData.User.ID == 0 {
+		p := PrincipalFrom(c)
+		if p == nil {
 			return c.Next()
 		}
 
-		permissions, err := authService.GetUserPermissions(sessionData.User.ID)
+		permissions, err := authService.PrincipalPermissions(p)
 		if err != nil {
-			log.Error().Err(err).Uint64("user_id", sessionData.User.ID).
+			log.Error().Err(err).Uint64("user_id", p.User.ID).
 				Msg("Failed to get user permissions")
 
 			return c.Next()
@@ -218,7 +119,7 @@ func AddPermissionsToLocals(authService *Service) fiber.Handler {
 		// Add permissions to locals for template access
 		c.Locals("permissions", permissions)
 		c.Locals("hasPermission", func(perm string) bool {
-			if has, errHas := authService.HasPermission(sessionData.User.ID, perm); errHas == nil {
+			if has, errHas := authService.PrincipalHasPermission(p, perm); errHas == nil {
 				return has
 			}
 
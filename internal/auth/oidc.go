@@ -13,6 +13,7 @@ import (
 	"golang.org/x/oauth2"
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 )
 
@@ -37,6 +38,20 @@ type OIDCConfig struct {
 	GroupsClaim string
 }
 
+// NewOIDCConfig builds the provider configuration from the application's
+// [auth.oidc] settings.
+func NewOIDCConfig(c *config.OIDCAuth) *OIDCConfig {
+	return &OIDCConfig{
+		Enabled:      c.Enabled,
+		ProviderURL:  c.ProviderURL,
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  c.RedirectURL,
+		Scopes:       c.Scopes,
+		GroupsClaim:  c.GroupsClaim,
+	}
+}
+
 // OIDCProvider handles OIDC authentication.
 type OIDCProvider struct {
 	config   *OIDCConfig
@@ -156,6 +171,8 @@ func (p *OIDCProvider) HandleCallback(ctx context.Context, code string) (*models
 			Username:    claims.Email,
 			Email:       claims.Email,
 			DisplayName: claims.Name,
+			FirstName:   claims.GivenName,
+			LastName:    claims.FamilyName,
 			AuthSource:  models.AuthSourceOIDC,
 			ExternalID:  claims.Sub,
 			RoleID:      viewerRole.ID,
@@ -172,6 +189,8 @@ func (p *OIDCProvider) HandleCallback(ctx context.Context, code string) (*models
 		// Update existing user
 		user.Email = claims.Email
 		user.DisplayName = claims.Name
+		user.FirstName = claims.GivenName
+		user.LastName = claims.FamilyName
 		user.UpdatedAt = time.Now()
 
 		if err = p.db.Save(&user).Error; err != nil {
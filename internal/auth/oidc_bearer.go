@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// bearerResolver authenticates requests carrying an ID token of the OIDC
+// provider as bearer token. Only users who signed in through the web login
+// before are accepted; bearer tokens never create accounts.
+type bearerResolver struct {
+	provider *OIDCProvider
+	db       *gorm.DB
+}
+
+// Resolve implements Resolver.
+func (r *bearerResolver) Resolve(c fiber.Ctx) (*Principal, error) {
+	token := bearerToken(c)
+	if token == "" || strings.HasPrefix(token, APIKeyPrefix) {
+		return nil, ErrNoCredentials
+	}
+
+	idToken, err := r.provider.VerifyToken(c.Context(), token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	var user models.User
+
+	err = r.db.Where("external_id = ? AND auth_source = ?", idToken.Subject, models.AuthSourceOIDC).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	if !user.Active {
+		return nil, ErrUserAccountDisabled
+	}
+
+	return &Principal{User: user, Method: MethodOIDCBearer}, nil
+}
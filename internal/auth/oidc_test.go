@@ -281,6 +281,7 @@ func TestHandleCallback(t *testing.T) {
 				assert.Equal(t, "test@example.com", u.Username)
 				assert.Equal(t, "test@example.com", u.Email)
 				assert.Equal(t, "Test User", u.DisplayName)
+				assert.Equal(t, "Test", u.FirstName)
 				assert.Equal(t, models.AuthSourceOIDC, u.AuthSource)
 				assert.Equal(t, "user123", u.ExternalID)
 				assert.True(t, u.Active)
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// SetPasswordPolicy sets the rules new passwords must follow and when they
+// expire. Without a policy any non-empty password is accepted and never
+// expires.
+func (p *LocalProvider) SetPasswordPolicy(policy config.PasswordPolicy) {
+	p.policy = policy
+}
+
+// PasswordPolicy returns the password policy of p.
+func (p *LocalProvider) PasswordPolicy() config.PasswordPolicy {
+	return p.policy
+}
+
+// ValidatePassword checks password against the password policy. The error
+// wraps ErrPasswordPolicy and names the requirements.
+func (p *LocalProvider) ValidatePassword(password string) error {
+	policy := p.policy
+
+	ok := password != "" && utf8.RuneCountInString(password) >= policy.MinLength
+
+	var upper, lower, digit, symbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+
+	ok = ok &&
+		(upper || !policy.RequireUpper) &&
+		(lower || !policy.RequireLower) &&
+		(digit || !policy.RequireDigit) &&
+		(symbol || !policy.RequireSymbol)
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPasswordPolicy, p.PasswordRequirements())
+	}
+
+	return nil
+}
+
+// PasswordRequirements describes the password policy for forms, e.g.
+// "at least 12 characters, including an uppercase letter and a digit".
+func (p *LocalProvider) PasswordRequirements() string {
+	policy := p.policy
+
+	minLength := max(policy.MinLength, 1)
+
+	out := "at least " + strconv.Itoa(minLength) + " character"
+	if minLength != 1 {
+		out += "s"
+	}
+
+	var classes []string
+	if policy.RequireUpper {
+		classes = append(classes, "an uppercase letter")
+	}
+
+	if policy.RequireLower {
+		classes = append(classes, "a lowercase letter")
+	}
+
+	if policy.RequireDigit {
+		classes = append(classes, "a digit")
+	}
+
+	if policy.RequireSymbol {
+		classes = append(classes, "a symbol")
+	}
+
+	switch len(classes) {
+	case 0:
+		return out
+	case 1:
+		return out + ", including " + classes[0]
+	default:
+		return out + ", including " + strings.Join(classes[:len(classes)-1], ", ") + " and " + classes[len(classes)-1]
+	}
+}
+
+// PasswordChangeRequired reports whether user has to choose a new password
+// before continuing: an administrator asked for it, or the password is older
+// than the maximum age of the policy. It only applies to local accounts.
+func (p *LocalProvider) PasswordChangeRequired(user *models.User) bool {
+	if user.AuthSource != models.AuthSourceLocal {
+		return false
+	}
+
+	if user.MustChangePassword {
+		return true
+	}
+
+	if p.policy.MaxAge <= 0 {
+		return false
+	}
+
+	changed := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changed = *user.PasswordChangedAt
+	}
+
+	return time.Since(changed) > p.policy.MaxAge
+}
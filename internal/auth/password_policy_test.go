@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestValidatePassword(t *testing.T) {
+	p := NewLocalProvider(nil)
+	p.SetPasswordPolicy(config.PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	})
+
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{"Secret-pass-1", true},
+		{"Sec-1", false},         // too short
+		{"secret-pass-1", false}, // no uppercase letter
+		{"Secret-pass-", false},  // no digit
+		{"Secretpass12", false},  // no symbol
+		{"Äpfel und 12", true},   // a space counts as a symbol
+	}
+
+	for _, tt := range tests {
+		err := p.ValidatePassword(tt.password)
+		if tt.ok {
+			assert.NoError(t, err, tt.password)
+		} else {
+			assert.ErrorIs(t, err, ErrPasswordPolicy, tt.password)
+		}
+	}
+
+	assert.Equal(t, "at least 10 characters, including an uppercase letter, a digit and a symbol",
+		p.PasswordRequirements())
+}
+
+func TestValidatePassword_NoPolicy(t *testing.T) {
+	p := NewLocalProvider(nil)
+
+	require.NoError(t, p.ValidatePassword("x"))
+	require.ErrorIs(t, p.ValidatePassword(""), ErrPasswordPolicy)
+	assert.Equal(t, "at least 1 character", p.PasswordRequirements())
+}
+
+func TestPasswordChangeRequired(t *testing.T) {
+	p := NewLocalProvider(nil)
+	p.SetPasswordPolicy(config.PasswordPolicy{MaxAge: 90 * 24 * time.Hour})
+
+	recent := time.Now().Add(-24 * time.Hour)
+	old := time.Now().Add(-100 * 24 * time.Hour)
+
+	assert.False(t, p.PasswordChangeRequired(&models.User{AuthSource: models.AuthSourceLocal, PasswordChangedAt: &recent}))
+	assert.True(t, p.PasswordChangeRequired(&models.User{AuthSource: models.AuthSourceLocal, PasswordChangedAt: &old}))
+	assert.True(t, p.PasswordChangeRequired(&models.User{AuthSource: models.AuthSourceLocal, CreatedAt: old}))
+	assert.True(t, p.PasswordChangeRequired(&models.User{
+		AuthSource: models.AuthSourceLocal, PasswordChangedAt: &recent, MustChangePassword: true,
+	}))
+	assert.False(t, p.PasswordChangeRequired(&models.User{AuthSource: models.AuthSourceLDAP, MustChangePassword: true}))
+}
+
+func TestChangePassword_ClearsForcedChange(t *testing.T) {
+	db, p, user := resetFixture(t)
+	p.SetPasswordPolicy(config.PasswordPolicy{MinLength: 8})
+
+	require.NoError(t, p.ResetPassword(user.ID, "admin-chosen"))
+
+	got, err := p.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, got.MustChangePassword)
+
+	require.ErrorIs(t, p.ChangePassword(user.ID, "admin-chosen", "short"), ErrPasswordPolicy)
+	require.ErrorIs(t, p.ChangePassword(user.ID, "admin-chosen", "admin-chosen"), ErrPasswordUnchanged)
+	require.NoError(t, p.ChangePassword(user.ID, "admin-chosen", "my-own-password"))
+
+	require.NoError(t, db.First(got, user.ID).Error)
+	assert.False(t, got.MustChangePassword)
+	assert.NotNil(t, got.PasswordChangedAt)
+	assert.True(t, got.VerifyPassword("my-own-password"))
+}
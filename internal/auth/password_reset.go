@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/uniuri"
+)
+
+// resetTokenLen is the length of a password reset token (~190 bits of entropy).
+const resetTokenLen = 32
+
+// hashResetToken returns the hex-encoded SHA-256 hash stored for token.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreatePasswordResetToken issues a reset token for the active local user with
+// the given username or email address, valid for ttl. Earlier unused tokens of
+// the user are invalidated. The plain token is returned once; only its hash is
+// stored.
+func (p *LocalProvider) CreatePasswordResetToken(login string, ttl time.Duration) (string, *models.User, error) {
+	var users []models.User
+	if err := p.db.Where("(username = ? OR email = ?) AND auth_source = ? AND active = ?",
+		login, login, models.AuthSourceLocal, true).
+		Limit(2).Find(&users).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	switch {
+	case len(users) == 0:
+		return "", nil, ErrUserNotFound
+	case len(users) > 1:
+		return "", nil, ErrMultipleUsersFound
+	}
+
+	user := users[0]
+	token := uniuri.NewLen(resetTokenLen)
+
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).
+			Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+		}
+
+		if err := tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashResetToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to store reset token: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &user, nil
+}
+
+// ValidatePasswordResetToken returns the user a reset token belongs to, or
+// ErrInvalidResetToken when the token is unknown, used or expired.
+func (p *LocalProvider) ValidatePasswordResetToken(token string) (*models.User, error) {
+	rt, err := p.findResetToken(p.db, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rt.User, nil
+}
+
+// ResetPasswordWithToken sets a new password for the user of token and marks
+// the token used, so it cannot be redeemed twice. The password must meet the
+// password policy.
+func (p *LocalProvider) ResetPasswordWithToken(token, newPassword string) (*models.User, error) {
+	if err := p.ValidatePassword(newPassword); err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		rt, err := p.findResetToken(tx, token)
+		if err != nil {
+			return err
+		}
+
+		// Conditional update: of two concurrent redemptions only one wins.
+		res := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", rt.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return fmt.Errorf("failed to redeem reset token: %w", res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where(whereID, rt.UserID).
+			Updates(passwordUpdates(newPassword, false)).Error; err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		// Other outstanding links of the user are void once the password changed.
+		if err := tx.Where("user_id = ? AND used_at IS NULL", rt.UserID).
+			Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+		}
+
+		user = &rt.User
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// findResetToken loads a valid token with its user.
+func (p *LocalProvider) findResetToken(db *gorm.DB, token string) (*models.PasswordResetToken, error) {
+	if token == "" {
+		return nil, ErrInvalidResetToken
+	}
+
+	var rt models.PasswordResetToken
+
+	err := db.Preload("User").
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashResetToken(token), time.Now()).
+		First(&rt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidResetToken
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reset token: %w", err)
+	}
+
+	if !rt.User.Active || rt.User.AuthSource != models.AuthSourceLocal {
+		return nil, ErrInvalidResetToken
+	}
+
+	return &rt, nil
+}
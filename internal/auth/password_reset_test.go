@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func resetFixture(t *testing.T) (*gorm.DB, *LocalProvider, *models.User) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Role{}, &models.User{}, &models.PasswordResetToken{}))
+
+	p := NewLocalProvider(db)
+
+	user, err := p.CreateUser("alice", "alice@example.com", "old-password", "Alice", 0)
+	require.NoError(t, err)
+
+	return db, p, user
+}
+
+func TestPasswordReset_RoundTrip(t *testing.T) {
+	db, p, user := resetFixture(t)
+
+	token, got, err := p.CreatePasswordResetToken("alice@example.com", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+	assert.Len(t, token, resetTokenLen)
+
+	var stored models.PasswordResetToken
+	require.NoError(t, db.First(&stored).Error)
+	assert.Equal(t, hashResetToken(token), stored.TokenHash)
+
+	valid, err := p.ValidatePasswordResetToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", valid.Username)
+
+	_, err = p.ResetPasswordWithToken(token, "new-password")
+	require.NoError(t, err)
+
+	_, err = p.Authenticate("alice", "new-password")
+	require.NoError(t, err)
+
+	// Single use.
+	_, err = p.ResetPasswordWithToken(token, "again-password")
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+
+	_, err = p.ValidatePasswordResetToken(token)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestPasswordReset_Invalidation(t *testing.T) {
+	db, p, user := resetFixture(t)
+
+	first, _, err := p.CreatePasswordResetToken("alice", time.Hour)
+	require.NoError(t, err)
+
+	second, _, err := p.CreatePasswordResetToken("alice", time.Hour)
+	require.NoError(t, err)
+
+	_, err = p.ValidatePasswordResetToken(first)
+	require.ErrorIs(t, err, ErrInvalidResetToken, "a new token replaces the previous one")
+
+	_, err = p.ValidatePasswordResetToken(second)
+	require.NoError(t, err)
+
+	// Expired.
+	require.NoError(t, db.Model(&models.PasswordResetToken{}).Where("user_id = ?", user.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	_, err = p.ValidatePasswordResetToken(second)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+
+	// Unknown users, external users and empty tokens.
+	_, _, err = p.CreatePasswordResetToken("nobody", time.Hour)
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).
+		Update("auth_source", models.AuthSourceLDAP).Error)
+
+	_, _, err = p.CreatePasswordResetToken("alice", time.Hour)
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = p.ValidatePasswordResetToken("")
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+}
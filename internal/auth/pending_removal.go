@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// ListPendingGroupRemovals returns the removals queued by group sync under the
+// approval policy, oldest first, with user and group loaded.
+func (s *Service) ListPendingGroupRemovals() ([]models.PendingGroupRemoval, error) {
+	var pending []models.PendingGroupRemoval
+	if err := s.db.Joins("User").Joins("Group").
+		Order("pending_group_removals.created_at ASC").
+		Find(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to load pending group removals: %w", err)
+	}
+
+	return pending, nil
+}
+
+// ApprovePendingGroupRemoval removes the queued group membership.
+func (s *Service) ApprovePendingGroupRemoval(id uint64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var pending models.PendingGroupRemoval
+		if err := tx.First(&pending, id).Error; err != nil {
+			return fmt.Errorf("failed to load pending group removal: %w", err)
+		}
+
+		if err := tx.Where("user_id = ? AND group_id = ?", pending.UserID, pending.GroupID).
+			Delete(&models.UserGroup{}).Error; err != nil {
+			return fmt.Errorf("failed to remove group membership: %w", err)
+		}
+
+		if err := tx.Delete(&pending).Error; err != nil {
+			return fmt.Errorf("failed to delete pending group removal: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RejectPendingGroupRemoval keeps the queued group membership and marks it
+// manual, so later syncs leave it alone.
+func (s *Service) RejectPendingGroupRemoval(id uint64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var pending models.PendingGroupRemoval
+		if err := tx.First(&pending, id).Error; err != nil {
+			return fmt.Errorf("failed to load pending group removal: %w", err)
+		}
+
+		if err := tx.Model(&models.UserGroup{}).
+			Where("user_id = ? AND group_id = ?", pending.UserID, pending.GroupID).
+			Update("manual", true).Error; err != nil {
+			return fmt.Errorf("failed to protect group membership: %w", err)
+		}
+
+		if err := tx.Delete(&pending).Error; err != nil {
+			return fmt.Errorf("failed to delete pending group removal: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// syncFixture creates a user who is an OIDC member of "stale" (synced) and
+// "pinned" (manual), and a local member of "local".
+func syncFixture(t *testing.T) (*gorm.DB, uint64, map[string]uint) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.User{}, &models.Group{}, &models.UserGroup{}, &models.PendingGroupRemoval{},
+	))
+
+	role := models.Role{Name: "viewer"}
+	require.NoError(t, db.Create(&role).Error)
+
+	user := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: role.ID}
+	require.NoError(t, db.Create(&user).Error)
+
+	groups := map[string]uint{}
+
+	for _, g := range []struct {
+		name   string
+		source models.GroupSource
+		manual bool
+	}{
+		{"stale", models.GroupSourceOIDC, false},
+		{"pinned", models.GroupSourceOIDC, true},
+		{"local", models.GroupSourceLocal, false},
+	} {
+		group := models.Group{Name: g.name, ExternalID: g.name, Source: g.source}
+		require.NoError(t, db.Create(&group).Error)
+		require.NoError(t, db.Create(&models.UserGroup{UserID: user.ID, GroupID: group.ID, Manual: g.manual}).Error)
+
+		groups[g.name] = group.ID
+	}
+
+	return db, user.ID, groups
+}
+
+func memberNames(t *testing.T, db *gorm.DB, userID uint64) []string {
+	t.Helper()
+
+	var names []string
+	require.NoError(t, db.Table("groups").
+		Joins("JOIN user_groups ON user_groups.group_id = groups.id").
+		Where("user_groups.user_id = ?", userID).
+		Order("groups.name").
+		Pluck("groups.name", &names).Error)
+
+	return names
+}
+
+func TestSyncUserGroups_Policies(t *testing.T) {
+	tests := []struct {
+		policy  string
+		members []string
+		pending int64
+	}{
+		{config.GroupSyncPolicyFull, []string{"fresh", "local", "pinned"}, 0},
+		{config.GroupSyncPolicyAdditive, []string{"fresh", "local", "pinned", "stale"}, 0},
+		{config.GroupSyncPolicyApproval, []string{"fresh", "local", "pinned", "stale"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			db, userID, _ := syncFixture(t)
+
+			svc := NewService(db)
+			svc.SetGroupSyncPolicy(tt.policy)
+
+			require.NoError(t, svc.SyncUserGroups(userID, []string{"fresh", "fresh"}, models.GroupSourceOIDC))
+			assert.Equal(t, tt.members, memberNames(t, db, userID))
+
+			// A second sync neither fails on existing memberships nor queues twice.
+			require.NoError(t, svc.SyncUserGroups(userID, []string{"fresh"}, models.GroupSourceOIDC))
+
+			var pending int64
+			db.Model(&models.PendingGroupRemoval{}).Count(&pending)
+			assert.Equal(t, tt.pending, pending)
+		})
+	}
+}
+
+func TestSyncUserGroups_ReportedAgainClearsPending(t *testing.T) {
+	db, userID, _ := syncFixture(t)
+
+	svc := NewService(db)
+	svc.SetGroupSyncPolicy(config.GroupSyncPolicyApproval)
+
+	require.NoError(t, svc.SyncUserGroups(userID, nil, models.GroupSourceOIDC))
+	require.NoError(t, svc.SyncUserGroups(userID, []string{"stale"}, models.GroupSourceOIDC))
+
+	var pending int64
+	db.Model(&models.PendingGroupRemoval{}).Count(&pending)
+	assert.Zero(t, pending)
+	assert.Equal(t, []string{"local", "pinned", "stale"}, memberNames(t, db, userID))
+}
+
+func TestPendingGroupRemoval_ApproveReject(t *testing.T) {
+	db, userID, groups := syncFixture(t)
+
+	svc := NewService(db)
+	svc.SetGroupSyncPolicy(config.GroupSyncPolicyApproval)
+
+	require.NoError(t, svc.SyncUserGroups(userID, nil, models.GroupSourceOIDC))
+
+	pending, err := svc.ListPendingGroupRemovals()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "jdoe", pending[0].User.Username)
+	assert.Equal(t, "stale", pending[0].Group.Name)
+
+	require.NoError(t, svc.RejectPendingGroupRemoval(pending[0].ID))
+	assert.Equal(t, []string{"local", "pinned", "stale"}, memberNames(t, db, userID))
+
+	var ug models.UserGroup
+	require.NoError(t, db.Where("user_id = ? AND group_id = ?", userID, groups["stale"]).First(&ug).Error)
+	assert.True(t, ug.Manual, "rejected removal protects the membership")
+
+	// Manual now: a further sync queues nothing.
+	require.NoError(t, svc.SyncUserGroups(userID, nil, models.GroupSourceOIDC))
+
+	pending, err = svc.ListPendingGroupRemovals()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Approval removes the membership.
+	require.NoError(t, db.Model(&models.UserGroup{}).Where("user_id = ? AND group_id = ?", userID, groups["stale"]).
+		Update("manual", false).Error)
+	require.NoError(t, svc.SyncUserGroups(userID, nil, models.GroupSourceOIDC))
+
+	pending, err = svc.ListPendingGroupRemovals()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	require.NoError(t, svc.ApprovePendingGroupRemoval(pending[0].ID))
+	assert.Equal(t, []string{"local", "pinned"}, memberNames(t, db, userID))
+
+	pending, err = svc.ListPendingGroupRemovals()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	require.Error(t, svc.ApprovePendingGroupRemoval(9999))
+}
@@ -17,6 +17,38 @@ const (
 	PermZoneDelete = "zone.delete"
 	// PermZoneList allows listing all DNS zones.
 	PermZoneList = "zone.list"
+	// PermZoneRequest allows requesting new zones for administrator approval.
+	PermZoneRequest = "zone.request"
+	// PermZoneClaim allows claiming existing zones by proving control of the domain.
+	PermZoneClaim = "zone.claim"
+	// PermZoneMetadata allows managing zone metadata such as ALSO-NOTIFY and
+	// the AXFR access lists.
+	PermZoneMetadata = "zone.metadata"
+	// PermZoneLUA allows editing LUA records, which run code on the PowerDNS
+	// server for every query.
+	PermZoneLUA = "zone.lua"
+	// PermZoneTTLOverride allows setting TTLs outside the TTL policies.
+	PermZoneTTLOverride = "zone.ttl_override"
+	// PermZonePolicyOverride allows changing records that the name policies
+	// block.
+	PermZonePolicyOverride = "zone.policy_override"
+	// PermZonePropose puts record changes under review: without
+	// PermZoneApprove they are stored as change requests instead of applied.
+	PermZonePropose = "zone.propose"
+	// PermZoneApprove allows approving or rejecting proposed record changes.
+	PermZoneApprove = "zone.approve"
+
+	// PermToolsQuery allows sending DNS queries to any server with the query
+	// tester.
+	PermToolsQuery = "tools.query"
+
+	// PermProfileAPIKeys allows issuing personal API keys for scripts and
+	// automation.
+	PermProfileAPIKeys = "profile.api_keys"
+
+	// PermTenantUsage allows viewing the quotas of the own tenant and how much
+	// of them it uses.
+	PermTenantUsage = "tenant.usage"
 
 	// PermAdminSettings allows managing application-wide settings.
 	PermAdminSettings = "admin.settings"
@@ -46,4 +78,62 @@ const (
 	PermAdminTTLPresets = "admin.ttl.presets"
 	// PermAdminBranding allows managing branding (product name, logo, favicon).
 	PermAdminBranding = "admin.branding"
+	// PermAdminZoneRequests allows reviewing (approving or rejecting) zone requests.
+	PermAdminZoneRequests = "admin.zone.requests"
+	// PermAdminZoneClaims allows reviewing zone ownership claims and revoking grants.
+	PermAdminZoneClaims = "admin.zone.claims"
+	// PermAdminSystem allows viewing the system information page.
+	PermAdminSystem = "admin.system"
+	// PermAdminMaintenance allows toggling maintenance mode and using the
+	// application while it is enabled.
+	PermAdminMaintenance = "admin.maintenance"
+	// PermAdminBackup allows exporting and restoring the application database.
+	PermAdminBackup = "admin.backup"
+	// PermAdminSnapshots allows browsing scheduled snapshots and restoring
+	// zones or application data from them.
+	PermAdminSnapshots = "admin.snapshots"
+	// PermAdminIntegrations allows managing the chat notification integrations.
+	PermAdminIntegrations = "admin.integrations"
+	// PermAdminTSIGKeys allows managing the TSIG keys of dynamic updates.
+	PermAdminTSIGKeys = "admin.tsig_keys"
+	// PermAdminDHCPImports allows managing the DHCP lease imports.
+	PermAdminDHCPImports = "admin.dhcp_imports"
+	// PermAdminNameserverSets allows managing nameserver sets, assigning
+	// them to zones and migrating zones between them.
+	PermAdminNameserverSets = "admin.nameserver_sets"
+	// PermAdminTenants allows managing tenants, their users, groups and
+	// zones. Only users outside any tenant can hold it.
+	PermAdminTenants = "admin.tenants"
 )
+
+// RoleTenantAdmin is the role of tenant administrators. It holds every
+// permission users of a tenant can hold and, like the admin role, reaches all
+// zones of the tenant whatever tags and groups grant.
+const RoleTenantAdmin = "tenant_admin"
+
+// tenantPermissions are the permissions users of a tenant can hold. The
+// others act on what all tenants share, like the settings, tags, TSIG keys
+// or the PowerDNS server itself, and no role grants them to users of a tenant.
+var tenantPermissions = map[string]bool{
+	PermDashboardView:     true,
+	PermZoneCreate:        true,
+	PermZoneRead:          true,
+	PermZoneUpdate:        true,
+	PermZoneDelete:        true,
+	PermZoneList:          true,
+	PermZoneRequest:       true,
+	PermZoneMetadata:      true,
+	PermZonePropose:       true,
+	PermZoneApprove:       true,
+	PermProfileAPIKeys:    true,
+	PermTenantUsage:       true,
+	PermAdminUsers:        true,
+	PermAdminGroups:       true,
+	PermAdminActivityLog:  true,
+	PermAdminIntegrations: true,
+}
+
+// TenantPermission reports whether users of a tenant can hold permission.
+func TenantPermission(permission string) bool {
+	return tenantPermissions[permission]
+}
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
+)
+
+// Authentication methods of a Principal.
+const (
+	// MethodSession is a browser session started at the login page.
+	MethodSession = "session"
+	// MethodAPIKey is a personal API key.
+	MethodAPIKey = "api_key"
+	// MethodOIDCBearer is an ID token of the OIDC provider sent as bearer token.
+	MethodOIDCBearer = "oidc_bearer"
+	// MethodTSIG is a TSIG key signing a dynamic update (see internal/dnsupdate).
+	// It only appears in the activity log; such requests have no Principal.
+	MethodTSIG = "tsig"
+	// MethodDHCPImport is a DHCP lease import (see
+	// internal/integrations/dhcplease). It only appears in the activity log.
+	MethodDHCPImport = "dhcp_import"
+)
+
+// localsPrincipal is the fiber.Locals key holding the request's *Principal.
+const localsPrincipal = "Principal"
+
+// Principal is the authenticated identity behind a request, however it
+// authenticated. Handlers and the activity log read it instead of the session.
+type Principal struct {
+	// User is the user the request acts as.
+	User models.User
+	// Method is one of the Method* constants.
+	Method string
+	// APIKeyID is the API key that authenticated the request, if any.
+	APIKeyID *uint64
+	// Scopes limits the request to these permissions of the user; nil allows
+	// all of them.
+	Scopes []string
+	// Zones limits the request to the zones of the user matching one of
+	// these patterns; nil allows all of them.
+	Zones []ZonePattern
+	// RecordTypes limits the record changes of the request to these types;
+	// nil allows all the user may edit.
+	RecordTypes []string
+	// TOTPPending is set for sessions whose second factor is still due.
+	TOTPPending bool
+	// PasswordChange is set for sessions that must change the password of
+	// the user before doing anything else.
+	PasswordChange bool
+}
+
+// allows reports whether the scopes of p admit permission. The user must
+// still hold it.
+func (p *Principal) allows(permission string) bool {
+	return p.Scopes == nil || slices.Contains(p.Scopes, permission)
+}
+
+// allowsZone reports whether the zones of p admit zone. The user must still
+// have access to it. A nil p admits every zone.
+func (p *Principal) allowsZone(zone string) bool {
+	if p == nil || p.Zones == nil {
+		return true
+	}
+
+	for _, pattern := range p.Zones {
+		if pattern.Match(zone) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LimitRecordTypes narrows editable, the record types the user may edit or
+// nil for all, to the record types of p.
+func (p *Principal) LimitRecordTypes(editable map[string]bool) map[string]bool {
+	if p == nil || p.RecordTypes == nil {
+		return editable
+	}
+
+	limited := make(map[string]bool, len(p.RecordTypes))
+
+	for _, t := range p.RecordTypes {
+		if editable == nil || editable[t] {
+			limited[t] = true
+		}
+	}
+
+	return limited
+}
+
+// Resolver authenticates a request from one kind of credentials. It returns
+// ErrNoCredentials when the request carries none of its kind.
+type Resolver interface {
+	Resolve(c fiber.Ctx) (*Principal, error)
+}
+
+// sessionResolver authenticates requests by the session cookie.
+type sessionResolver struct{}
+
+// Resolve implements Resolver.
+func (sessionResolver) Resolve(c fiber.Ctx) (*Principal, error) {
+	sessionID := c.Cookies("session")
+	if sessionID == "" {
+		return nil, ErrNoCredentials
+	}
+
+	// An unknown or expired session is treated like no session at all, so
+	// the user is sent to the login page.
+	sessionData := new(session.Data)
+	if err := sessionData.Read(sessionID); err != nil || sessionData.User.ID == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	// Every request counts as activity for the idle timeout.
+	if err := sessionData.Renew(sessionID); err != nil {
+		if errors.Is(err, session.ErrExpired) {
+			return nil, ErrNoCredentials
+		}
+
+		log.Warn().Err(err).Uint64("user_id", sessionData.User.ID).Msg("failed to renew session")
+	}
+
+	return &Principal{
+		User:           sessionData.User,
+		Method:         MethodSession,
+		TOTPPending:    sessionData.TOTPPending,
+		PasswordChange: sessionData.PasswordChange,
+	}, nil
+}
+
+// SetOIDCProvider makes the service accept ID tokens of the provider returned
+// by provider as bearer tokens. It is called per request, so the provider may
+// follow settings changes; it returns nil while bearer tokens are not
+// accepted. A nil func turns bearer authentication off again.
+func (s *Service) SetOIDCProvider(provider func() *OIDCProvider) {
+	s.oidcBearer = provider
+}
+
+// resolvers returns the resolver chain in order: header credentials take
+// precedence over the session cookie.
+func (s *Service) resolvers() []Resolver {
+	if s == nil {
+		return []Resolver{sessionResolver{}}
+	}
+
+	chain := []Resolver{&apiKeyResolver{db: s.db, now: time.Now}}
+	if s.oidcBearer != nil {
+		if provider := s.oidcBearer(); provider != nil {
+			chain = append(chain, &bearerResolver{provider: provider, db: s.db})
+		}
+	}
+
+	return append(chain, sessionResolver{})
+}
+
+// Resolve authenticates the request with the first resolver that finds
+// credentials. It returns ErrNoCredentials for anonymous requests.
+func (s *Service) Resolve(c fiber.Ctx) (*Principal, error) {
+	for _, r := range s.resolvers() {
+		p, err := r.Resolve(c)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		return p, err
+	}
+
+	return nil, ErrNoCredentials
+}
+
+// Authenticate is a Fiber middleware that resolves the principal of each
+// request and stores it in fiber.Locals, together with the user as
+// "CurrentUser". Anonymous requests pass through; whether they may see the
+// page is up to the auth middleware of the web package. Requests with bad
+// header credentials are rejected, rather than served anonymously.
+func Authenticate(authService *Service) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		p, err := authService.Resolve(c)
+		if errors.Is(err, ErrNoCredentials) {
+			return c.Next()
+		}
+
+		if err != nil {
+			log.Warn().Err(err).Str("path", c.Path()).Msg("Rejected request credentials")
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+
+			return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+		}
+
+		c.Locals(localsPrincipal, p)
+		c.Locals("CurrentUser", p.User)
+
+		return c.Next()
+	}
+}
+
+// PrincipalFrom returns the principal Authenticate stored for the request,
+// or nil.
+func PrincipalFrom(c fiber.Ctx) *Principal {
+	p, _ := c.Locals(localsPrincipal).(*Principal)
+	return p
+}
+
+// principal returns the principal of the request, resolving it when
+// Authenticate did not run. It returns nil for anonymous requests.
+func principal(c fiber.Ctx, authService *Service) *Principal {
+	if p := PrincipalFrom(c); p != nil {
+		return p
+	}
+
+	p, err := authService.Resolve(c)
+	if err != nil {
+		return nil
+	}
+
+	return p
+}
+
+// RequireSession rejects requests not authenticated by a browser session,
+// e.g. so an API key cannot issue further keys.
+func RequireSession() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		p := PrincipalFrom(c)
+		if p == nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+		}
+
+		if p.Method != MethodSession {
+			return fiber.NewError(fiber.StatusForbidden, "Forbidden: this page requires a browser session")
+		}
+
+		return c.Next()
+	}
+}
+
+// Actor returns the ID and name of the user behind the request for the
+// activity log. The ID is nil for anonymous requests.
+func Actor(c fiber.Ctx) (*uint64, string) {
+	p := PrincipalFrom(c)
+	if p == nil {
+		return nil, ""
+	}
+
+	id := p.User.ID
+
+	return &id, p.User.Username
+}
+
+// Attribute completes e with the principal of the request: the user when e
+// names none, the authentication method, the API key and the client address.
+func Attribute(c fiber.Ctx, e *activitylog.Entry) *activitylog.Entry {
+	if e.IPAddress == "" {
+		e.IPAddress = c.IP()
+	}
+
+	p := PrincipalFrom(c)
+	if p == nil {
+		return e
+	}
+
+	if e.UserID == nil && e.Username == "" {
+		e.UserID, e.Username = Actor(c)
+	}
+
+	e.AuthMethod = p.Method
+	e.APIKeyID = p.APIKeyID
+
+	return e
+}
+
+// PrincipalHasPermission reports whether p holds permission: its user must
+// have it and its scopes must admit it.
+func (s *Service) PrincipalHasPermission(p *Principal, permission string) (bool, error) {
+	if !p.allows(permission) {
+		return false, nil
+	}
+
+	return s.HasPermission(p.User.ID, permission)
+}
+
+// PrincipalHasAnyPermission reports whether p holds at least one of
+// permissions.
+func (s *Service) PrincipalHasAnyPermission(p *Principal, permissions ...string) (bool, error) {
+	var allowed []string
+
+	for _, perm := range permissions {
+		if p.allows(perm) {
+			allowed = append(allowed, perm)
+		}
+	}
+
+	if len(allowed) == 0 {
+		return false, nil
+	}
+
+	return s.HasAnyPermission(p.User.ID, allowed)
+}
+
+// PrincipalHasAllPermissions reports whether p holds all of permissions.
+func (s *Service) PrincipalHasAllPermissions(p *Principal, permissions ...string) (bool, error) {
+	for _, perm := range permissions {
+		if !p.allows(perm) {
+			return false, nil
+		}
+	}
+
+	return s.HasAllPermissions(p.User.ID, permissions)
+}
+
+// PrincipalPermissions returns the permissions p holds: those of its user
+// admitted by its scopes.
+func (s *Service) PrincipalPermissions(p *Principal) ([]string, error) {
+	permissions, err := s.GetUserPermissions(p.User.ID)
+	if err != nil || p.Scopes == nil {
+		return permissions, err
+	}
+
+	return slices.DeleteFunc(permissions, func(perm string) bool { return !p.allows(perm) }), nil
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "sync"
+
+// ProviderCache holds a provider built from settings of a given version and
+// builds it again once the version changes. A failed build is kept as well,
+// so a broken provider is not retried on every request. The zero value is
+// ready to use and it is safe for concurrent use.
+type ProviderCache[T any] struct {
+	mu      sync.Mutex
+	built   bool
+	version uint64
+	value   T
+	err     error
+}
+
+// Get returns the provider of version, calling build when it has not been
+// built yet.
+func (c *ProviderCache[T]) Get(version uint64, build func() (T, error)) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.built || c.version != version {
+		c.value, c.err = build()
+		c.built, c.version = true, version
+	}
+
+	return c.value, c.err
+}
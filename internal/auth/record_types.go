@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// GetEditableRecordTypes returns the set of DNS record types the user may
+// create, edit or delete.
+//
+// Returns nil when editing is unrestricted: the user has the admin role, or at
+// least one of their roles (direct or via group mappings) has no record type
+// restriction. Returns a non-nil set (possibly empty) otherwise; it is the union
+// of the record types of all the user's roles.
+func (s *Service) GetEditableRecordTypes(userID uint64) (map[string]bool, error) {
+	var user models.User
+	if err := s.db.Preload("Role").First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("record types: load user: %w", err)
+	}
+
+	roles := []models.Role{user.Role}
+
+	var groupRoles []models.Role
+	if err := s.db.Table("roles").
+		Select("DISTINCT roles.*").
+		Joins("JOIN group_mappings ON group_mappings.role_id = roles.id").
+		Joins("JOIN user_groups ON user_groups.group_id = group_mappings.group_id").
+		Where("user_groups.user_id = ?", userID).
+		Scan(&groupRoles).Error; err != nil {
+		return nil, fmt.Errorf("record types: load group roles: %w", err)
+	}
+
+	roles = append(roles, groupRoles...)
+
+	allowed := make(map[string]bool)
+
+	for i := range roles {
+		if roles[i].Name == "admin" {
+			return nil, nil //nolint:nilnil // nil map intentionally signals unrestricted access
+		}
+
+		types := roles[i].RecordTypeList()
+		if len(types) == 0 {
+			return nil, nil //nolint:nilnil // nil map intentionally signals unrestricted access
+		}
+
+		for _, t := range types {
+			allowed[t] = true
+		}
+	}
+
+	return allowed, nil
+}
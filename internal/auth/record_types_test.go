@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func newRecordTypesTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.User{}, &models.Group{}, &models.GroupMapping{}, &models.UserGroup{},
+	))
+
+	return db
+}
+
+func TestGetEditableRecordTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		userRole   models.Role
+		groupRoles []models.Role
+		want       map[string]bool
+	}{
+		{
+			name:     "unrestricted role",
+			userRole: models.Role{Name: "user"},
+			want:     nil,
+		},
+		{
+			name:     "admin ignores restriction",
+			userRole: models.Role{Name: "admin", RecordTypes: "A"},
+			want:     nil,
+		},
+		{
+			name:     "restricted role",
+			userRole: models.Role{Name: "web", RecordTypes: "A,AAAA,CNAME,TXT"},
+			want:     map[string]bool{"A": true, "AAAA": true, "CNAME": true, "TXT": true},
+		},
+		{
+			name:       "union with group role",
+			userRole:   models.Role{Name: "web", RecordTypes: "A,AAAA"},
+			groupRoles: []models.Role{{Name: "mail", RecordTypes: "MX,TXT"}},
+			want:       map[string]bool{"A": true, "AAAA": true, "MX": true, "TXT": true},
+		},
+		{
+			name:       "unrestricted group role lifts restriction",
+			userRole:   models.Role{Name: "web", RecordTypes: "A"},
+			groupRoles: []models.Role{{Name: "ops"}},
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newRecordTypesTestDB(t)
+
+			require.NoError(t, db.Create(&tt.userRole).Error)
+
+			user := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: tt.userRole.ID}
+			require.NoError(t, db.Create(&user).Error)
+
+			for i := range tt.groupRoles {
+				require.NoError(t, db.Create(&tt.groupRoles[i]).Error)
+
+				name := tt.groupRoles[i].Name
+				group := models.Group{Name: name, ExternalID: name, Source: models.GroupSourceLocal}
+				require.NoError(t, db.Create(&group).Error)
+				require.NoError(t, db.Create(&models.GroupMapping{GroupID: group.ID, RoleID: tt.groupRoles[i].ID}).Error)
+				require.NoError(t, db.Create(&models.UserGroup{UserID: user.ID, GroupID: group.ID}).Error)
+			}
+
+			got, err := NewService(db).GetEditableRecordTypes(user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
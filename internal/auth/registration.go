@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+const wherePendingID = "id = ? AND pending = ?"
+
+// Register creates the local account of a self-registration. It stays
+// inactive and pending until an administrator approves it; roleID is the role
+// suggested for the approval.
+func (p *LocalProvider) Register(username, email, password, displayName string, roleID uint) (*models.User, error) {
+	var existing int64
+	if err := p.db.Model(&models.User{}).
+		Where("username = ? OR email = ?", username, email).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check existing user: %w", err)
+	}
+
+	if existing > 0 {
+		return nil, ErrUserNameOrEmailExists
+	}
+
+	if err := p.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := models.User{
+		Username:          username,
+		Email:             email,
+		DisplayName:       displayName,
+		Password:          models.HashPassword(password),
+		PasswordChangedAt: &now,
+		Pending:           true,
+		RoleID:            roleID,
+		AuthSource:        models.AuthSourceLocal,
+	}
+
+	if err := p.db.Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// PendingRegistrations returns the accounts awaiting approval by an
+// administrator of the tenant tenantID, oldest first. Administrators of a
+// tenant only review the registrations of their tenant; the provider, nil,
+// reviews all of them.
+func (p *LocalProvider) PendingRegistrations(tenantID *uint) ([]models.User, error) {
+	var users []models.User
+	if err := p.db.Scopes(reviewable(tenantID)).Preload("Role").Where("pending = ?", true).
+		Order("created_at ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ApproveRegistration activates the pending account userID with roleID on
+// behalf of an administrator of the tenant tenantID. An administrator of a
+// tenant approves the account into that tenant.
+func (p *LocalProvider) ApproveRegistration(userID uint64, roleID uint, tenantID *uint) (*models.User, error) {
+	updates := map[string]any{
+		"pending": false,
+		"active":  true,
+		"role_id": roleID,
+	}
+
+	if tenantID != nil {
+		updates["tenant_id"] = *tenantID
+	}
+
+	res := p.db.Model(&models.User{}).
+		Scopes(reviewable(tenantID)).
+		Where(wherePendingID, userID, true).
+		Updates(updates)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to approve registration: %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, ErrRegistrationNotPending
+	}
+
+	return p.GetUserByID(userID)
+}
+
+// DenyRegistration deletes the pending account userID on behalf of an
+// administrator of the tenant tenantID, so the username and email address can
+// be registered again. It returns the deleted account.
+func (p *LocalProvider) DenyRegistration(userID uint64, tenantID *uint) (*models.User, error) {
+	var user models.User
+
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(reviewable(tenantID)).Where(wherePendingID, userID, true).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRegistrationNotPending
+			}
+
+			return err
+		}
+
+		res := tx.Where(wherePendingID, userID, true).Delete(&models.User{})
+		if res.Error != nil {
+			return fmt.Errorf("failed to deny registration: %w", res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return ErrRegistrationNotPending
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// reviewable limits a query on pending accounts to those administrators of
+// the tenant tenantID review: the accounts of their tenant, or all of them
+// for the provider.
+func reviewable(tenantID *uint) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if tenantID == nil {
+			return tx
+		}
+
+		return tx.Where("tenant_id = ?", *tenantID)
+	}
+}
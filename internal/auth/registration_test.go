@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestRegistration_Approve(t *testing.T) {
+	db, p, _ := resetFixture(t)
+
+	user := models.Role{Name: "user"}
+	operator := models.Role{Name: "operator"}
+	require.NoError(t, db.Create(&user).Error)
+	require.NoError(t, db.Create(&operator).Error)
+
+	_, err := p.Register("alice", "new@example.com", "secret-pass", "", user.ID)
+	require.ErrorIs(t, err, ErrUserNameOrEmailExists)
+
+	bob, err := p.Register("bob", "bob@example.com", "secret-pass", "Bob", user.ID)
+	require.NoError(t, err)
+	assert.True(t, bob.Pending)
+	assert.False(t, bob.Active)
+
+	_, err = p.Authenticate("bob", "wrong-pass")
+	require.ErrorIs(t, err, ErrInvalidPassword)
+	_, err = p.Authenticate("bob", "secret-pass")
+	require.ErrorIs(t, err, ErrUserPendingApproval)
+
+	pending, err := p.PendingRegistrations(nil)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "user", pending[0].Role.Name)
+
+	approved, err := p.ApproveRegistration(bob.ID, operator.ID, nil)
+	require.NoError(t, err)
+	assert.True(t, approved.Active)
+	assert.False(t, approved.Pending)
+	assert.Equal(t, operator.ID, approved.RoleID)
+
+	_, err = p.Authenticate("bob", "secret-pass")
+	require.NoError(t, err)
+
+	_, err = p.ApproveRegistration(bob.ID, user.ID, nil)
+	require.ErrorIs(t, err, ErrRegistrationNotPending)
+	_, err = p.DenyRegistration(bob.ID, nil)
+	require.ErrorIs(t, err, ErrRegistrationNotPending)
+}
+
+func TestRegistration_Deny(t *testing.T) {
+	db, p, alice := resetFixture(t)
+
+	carol, err := p.Register("carol", "carol@example.com", "secret-pass", "", 0)
+	require.NoError(t, err)
+
+	denied, err := p.DenyRegistration(carol.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "carol", denied.Username)
+
+	var count int64
+	require.NoError(t, db.Model(&models.User{}).Where("username = ?", "carol").Count(&count).Error)
+	assert.Zero(t, count)
+
+	// Existing accounts are not part of the queue.
+	_, err = p.DenyRegistration(alice.ID, nil)
+	require.ErrorIs(t, err, ErrRegistrationNotPending)
+
+	_, err = p.Register("carol", "carol@example.com", "secret-pass", "", 0)
+	require.NoError(t, err)
+}
+
+func TestRegistration_TenantReviewers(t *testing.T) {
+	db, p, _ := resetFixture(t)
+	require.NoError(t, db.AutoMigrate(&models.Tenant{}))
+
+	acme, globex := models.Tenant{Name: "acme"}, models.Tenant{Name: "globex"}
+	require.NoError(t, db.Create(&acme).Error)
+	require.NoError(t, db.Create(&globex).Error)
+
+	dave, err := p.Register("dave", "dave@example.com", "secret-pass", "", 0)
+	require.NoError(t, err)
+
+	erin, err := p.Register("erin", "erin@example.com", "secret-pass", "", 0)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(erin).Update("tenant_id", acme.ID).Error)
+
+	pending, err := p.PendingRegistrations(&acme.ID)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "erin", pending[0].Username)
+
+	all, err := p.PendingRegistrations(nil)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	// Registrations outside the tenant of the reviewer are not theirs to
+	// review.
+	_, err = p.ApproveRegistration(dave.ID, 0, &acme.ID)
+	require.ErrorIs(t, err, ErrRegistrationNotPending)
+	_, err = p.DenyRegistration(dave.ID, &acme.ID)
+	require.ErrorIs(t, err, ErrRegistrationNotPending)
+	_, err = p.ApproveRegistration(erin.ID, 0, &globex.ID)
+	require.ErrorIs(t, err, ErrRegistrationNotPending)
+
+	approved, err := p.ApproveRegistration(erin.ID, 0, &acme.ID)
+	require.NoError(t, err)
+	require.NotNil(t, approved.TenantID)
+	assert.Equal(t, acme.ID, *approved.TenantID)
+}
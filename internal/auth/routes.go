@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GuardedRoute is a route protected by one of the Require*Permission
+// middlewares.
+type GuardedRoute struct {
+	Method string
+	Path   string
+}
+
+// routeRegistry maps permissions to the routes they guard. It is filled while
+// the handlers register their routes: the Require*Permission constructors run
+// as arguments of app.Get/app.Post/..., i.e. right before the route is added,
+// and note their permissions; the OnRoute hook then assigns the noted
+// permissions to the new route.
+var routeRegistry = struct {
+	mu      sync.Mutex
+	pending []string
+	routes  map[string][]GuardedRoute
+}{routes: make(map[string][]GuardedRoute)}
+
+// noteGuard remembers permissions for the route registered next.
+func noteGuard(permissions ...string) {
+	routeRegistry.mu.Lock()
+	routeRegistry.pending = append(routeRegistry.pending, permissions...)
+	routeRegistry.mu.Unlock()
+}
+
+// RegisterRouteHooks records which permission guards which route of app.
+// Call it before the handlers register their routes.
+func RegisterRouteHooks(app *fiber.App) {
+	app.Hooks().OnRoute(recordRoute)
+}
+
+// recordRoute is the OnRoute hook of RegisterRouteHooks.
+func recordRoute(r fiber.Route) error {
+	routeRegistry.mu.Lock()
+	defer routeRegistry.mu.Unlock()
+
+	pending := routeRegistry.pending
+	routeRegistry.pending = nil
+
+	route := GuardedRoute{Method: r.Method, Path: r.Path}
+
+	for _, perm := range pending {
+		if !containsRoute(routeRegistry.routes[perm], route) {
+			routeRegistry.routes[perm] = append(routeRegistry.routes[perm], route)
+		}
+	}
+
+	return nil
+}
+
+// PermissionRoutes returns the routes guarded by each permission, sorted by
+// path and method.
+func PermissionRoutes() map[string][]GuardedRoute {
+	routeRegistry.mu.Lock()
+	defer routeRegistry.mu.Unlock()
+
+	result := make(map[string][]GuardedRoute, len(routeRegistry.routes))
+
+	for perm, routes := range routeRegistry.routes {
+		sorted := append([]GuardedRoute(nil), routes...)
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].Path != sorted[j].Path {
+				return sorted[i].Path < sorted[j].Path
+			}
+
+			return sorted[i].Method < sorted[j].Method
+		})
+
+		result[perm] = sorted
+	}
+
+	return result
+}
+
+func containsRoute(routes []GuardedRoute, route GuardedRoute) bool {
+	for _, r := range routes {
+		if r == route {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionRoutes(t *testing.T) {
+	app := fiber.New()
+	RegisterRouteHooks(app)
+
+	ok := func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+
+	app.Get("/routes-test/b", RequirePermission(nil, "routes.test.read"), ok)
+	app.Get("/routes-test/a", RequirePermission(nil, "routes.test.read"), ok)
+	app.Post("/routes-test/a", RequireAnyPermission(nil, "routes.test.read", "routes.test.write"), ok)
+	app.Get("/routes-test/open", ok)
+
+	routes := PermissionRoutes()
+
+	assert.Equal(t, []GuardedRoute{
+		{Method: fiber.MethodGet, Path: "/routes-test/a"},
+		{Method: fiber.MethodPost, Path: "/routes-test/a"},
+		{Method: fiber.MethodGet, Path: "/routes-test/b"},
+	}, routes["routes.test.read"])
+	assert.Equal(t, []GuardedRoute{{Method: fiber.MethodPost, Path: "/routes-test/a"}}, routes["routes.test.write"])
+}
@@ -2,26 +2,64 @@ package auth
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/cache"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 )
 
 // Service provides authentication and authorization functionality.
 type Service struct {
 	db *gorm.DB
+	// syncPolicy is the config.GroupSyncPolicy* applied by SyncUserGroups.
+	syncPolicy string
+	// oidcBearer returns the provider verifying OIDC bearer tokens; nil, or
+	// returning nil, when they are not accepted.
+	oidcBearer func() *OIDCProvider
 }
 
 // NewService creates a new auth service.
 func NewService(db *gorm.DB) *Service {
-	return &Service{db: db}
+	return &Service{db: db, syncPolicy: config.GroupSyncPolicyFull}
+}
+
+// SetGroupSyncPolicy sets how SyncUserGroups handles memberships the identity
+// provider no longer reports. An empty policy selects the full sync.
+func (s *Service) SetGroupSyncPolicy(policy string) {
+	if policy == "" {
+		policy = config.GroupSyncPolicyFull
+	}
+
+	s.syncPolicy = policy
 }
 
 // HasPermission checks if a user has a specific permission.
 // This works by checking if the user's role has the permission assigned,
 // or if any of the user's groups map to roles with that permission.
+// When the object cache is enabled the check uses the user's cached
+// permission set instead. Users of a tenant only hold the permissions
+// TenantPermission allows, whatever their roles grant.
 func (s *Service) HasPermission(userID uint64, permission string) (bool, error) {
+	if cache.Default().Enabled() {
+		permissions, err := s.GetUserPermissions(userID)
+		if err != nil {
+			return false, err
+		}
+
+		return slices.Contains(permissions, permission), nil
+	}
+
+	if !TenantPermission(permission) {
+		inTenant, err := s.inTenant(userID)
+		if err != nil || inTenant {
+			return false, err
+		}
+	}
+
 	var count int64
 
 	// Check permissions from user's direct role
@@ -58,18 +96,12 @@ func (s *Service) HasAnyPermission(userID uint64, permissions []string) (bool, e
 		return false, nil
 	}
 
-	for _, perm := range permissions {
-		has, err := s.HasPermission(userID, perm)
-		if err != nil {
-			return false, err
-		}
-
-		if has {
-			return true, nil
-		}
+	granted, err := s.grantedPermissions(userID, permissions)
+	if err != nil {
+		return false, err
 	}
 
-	return false, nil
+	return len(granted) > 0, nil
 }
 
 // HasAllPermissions checks if a user has all of the given permissions.
@@ -78,13 +110,13 @@ func (s *Service) HasAllPermissions(userID uint64, permissions []string) (bool,
 		return true, nil
 	}
 
-	for _, perm := range permissions {
-		has, err := s.HasPermission(userID, perm)
-		if err != nil {
-			return false, err
-		}
+	granted, err := s.grantedPermissions(userID, permissions)
+	if err != nil {
+		return false, err
+	}
 
-		if !has {
+	for _, perm := range permissions {
+		if !slices.Contains(granted, perm) {
 			return false, nil
 		}
 	}
@@ -92,8 +124,64 @@ func (s *Service) HasAllPermissions(userID uint64, permissions []string) (bool,
 	return true, nil
 }
 
+// grantedPermissions returns which of permissions the user has, through the
+// direct role or a group, in a single query; with the object cache enabled the
+// cached permission set is used instead.
+func (s *Service) grantedPermissions(userID uint64, permissions []string) ([]string, error) {
+	if cache.Default().Enabled() {
+		all, err := s.GetUserPermissions(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		return slices.DeleteFunc(all, func(perm string) bool { return !slices.Contains(permissions, perm) }), nil
+	}
+
+	direct := s.db.Table("users").Select("role_id").Where("id = ?", userID)
+
+	viaGroup := s.db.Table("group_mappings").
+		Select("group_mappings.role_id").
+		Joins("JOIN user_groups ON user_groups.group_id = group_mappings.group_id").
+		Where("user_groups.user_id = ?", userID)
+
+	var granted []string
+
+	err := s.db.Table("permissions").
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("permissions.name IN ?", permissions).
+		Where("role_permissions.role_id IN (?) OR role_permissions.role_id IN (?)", direct, viaGroup).
+		Pluck("permissions.name", &granted).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+
+	return s.capTenant(userID, granted)
+}
+
 // GetUserPermissions retrieves all permissions for a user (from direct role and groups).
+// The result is cached under the permissions tag when the object cache is
+// enabled.
 func (s *Service) GetUserPermissions(userID uint64) ([]string, error) {
+	store := cache.Default()
+	key := "perms:" + strconv.FormatUint(userID, 10)
+
+	if cached, ok := store.Get(key); ok {
+		return slices.Clone(cached.([]string)), nil
+	}
+
+	permissions, err := s.loadUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	store.Set(key, slices.Clone(permissions), cache.TTL(), cache.TagPermissions)
+
+	return permissions, nil
+}
+
+// loadUserPermissions queries the permissions of a user's role and groups.
+func (s *Service) loadUserPermissions(userID uint64) ([]string, error) {
 	var permissions []string
 
 	// Get permissions from user's direct role
@@ -137,7 +225,88 @@ func (s *Service) GetUserPermissions(userID uint64) ([]string, error) {
 		result = append(result, perm)
 	}
 
-	return result, nil
+	return s.capTenant(userID, result)
+}
+
+// inTenant reports whether the user belongs to a tenant.
+func (s *Service) inTenant(userID uint64) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.User{}).
+		Where("id = ? AND tenant_id IS NOT NULL", userID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check tenant: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// capTenant drops from permissions those users of a tenant cannot
+// hold when the user belongs to one.
+func (s *Service) capTenant(userID uint64, permissions []string) ([]string, error) {
+	if !slices.ContainsFunc(permissions, func(perm string) bool { return !TenantPermission(perm) }) {
+		return permissions, nil
+	}
+
+	inTenant, err := s.inTenant(userID)
+	if err != nil || !inTenant {
+		return permissions, err
+	}
+
+	return slices.DeleteFunc(permissions, func(perm string) bool { return !TenantPermission(perm) }), nil
+}
+
+// GrantableRoles limits a query on roles to those administrators of the
+// tenant tenantID may give users: every role for the provider, nil, and for a
+// tenant the roles granting only permissions TenantPermission allows.
+func GrantableRoles(tenantID *uint) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if tenantID == nil {
+			return tx
+		}
+
+		allowed := make([]string, 0, len(tenantPermissions))
+		for perm := range tenantPermissions {
+			allowed = append(allowed, perm)
+		}
+
+		granting := tx.Session(&gorm.Session{NewDB: true}).
+			Table("role_permissions").
+			Select("role_permissions.role_id").
+			Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+			Where("permissions.name NOT IN ?", allowed)
+
+		return tx.Where("roles.id NOT IN (?)", granting)
+	}
+}
+
+// GetUsersWithPermission returns the active users granted permission, either
+// through their own role or through the role of one of their groups.
+func (s *Service) GetUsersWithPermission(permission string) ([]models.User, error) {
+	direct := s.db.Table("users").
+		Select("users.id").
+		Joins("JOIN role_permissions ON role_permissions.role_id = users.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("permissions.name = ?", permission)
+
+	viaGroup := s.db.Table("user_groups").
+		Select("user_groups.user_id").
+		Joins("JOIN group_mappings ON group_mappings.group_id = user_groups.group_id").
+		Joins("JOIN role_permissions ON role_permissions.role_id = group_mappings.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("permissions.name = ?", permission)
+
+	tx := s.db.Where("active = ? AND (id IN (?) OR id IN (?))", true, direct, viaGroup)
+	if !TenantPermission(permission) {
+		tx = tx.Where("tenant_id IS NULL")
+	}
+
+	var users []models.User
+	if err := tx.Order("username").
+		Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get users with permission: %w", err)
+	}
+
+	return users, nil
 }
 
 // GetUserGroups retrieves all groups a user belongs to.
@@ -157,11 +326,16 @@ func (s *Service) GetUserGroups(userID uint64) ([]models.Group, error) {
 
 // SyncUserGroups synchronizes a user's groups with external groups.
 // This is called after OIDC or LDAP authentication to update group memberships.
+//
+// Memberships of the source the identity provider no longer reports are
+// handled according to the group sync policy: removed (full), kept (additive)
+// or queued as PendingGroupRemoval for an administrator (approval). Manual
+// memberships are never removed.
 func (s *Service) SyncUserGroups(userID uint64, externalGroups []string, source models.GroupSource) error {
 	// Start a transaction
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// Get or create groups for external groups
-		var groupIDs []uint
+		target := make(map[uint]bool, len(externalGroups))
 
 		for _, externalGroup := range externalGroups {
 			var group models.Group
@@ -176,18 +350,49 @@ func (s *Service) SyncUserGroups(userID uint64, externalGroups []string, source
 				return fmt.Errorf("failed to create/get group %s: %w", externalGroup, err)
 			}
 
-			groupIDs = append(groupIDs, group.ID)
+			target[group.ID] = true
 		}
 
-		// Remove old group memberships for this source
+		var current []models.UserGroup
 		if err := tx.Where("user_id = ?", userID).
 			Where("group_id IN (SELECT id FROM groups WHERE source = ?)", source).
-			Delete(&models.UserGroup{}).Error; err != nil {
-			return fmt.Errorf("failed to remove old group memberships: %w", err)
+			Find(&current).Error; err != nil {
+			return fmt.Errorf("failed to load group memberships: %w", err)
+		}
+
+		member := make(map[uint]bool, len(current))
+
+		for _, ug := range current {
+			member[ug.GroupID] = true
+
+			if target[ug.GroupID] || ug.Manual {
+				continue
+			}
+
+			if err := s.removeSyncedMembership(tx, ug); err != nil {
+				return err
+			}
+		}
+
+		if len(target) > 0 {
+			ids := make([]uint, 0, len(target))
+			for id := range target {
+				ids = append(ids, id)
+			}
+
+			// The provider reports these groups again; nothing left to approve.
+			if err := tx.Where("user_id = ? AND group_id IN ?", userID, ids).
+				Delete(&models.PendingGroupRemoval{}).Error; err != nil {
+				return fmt.Errorf("failed to clear pending group removals: %w", err)
+			}
 		}
 
 		// Add new group memberships
-		for _, groupID := range groupIDs {
+		for groupID := range target {
+			if member[groupID] {
+				continue
+			}
+
 			if err := tx.Create(&models.UserGroup{
 				UserID:  userID,
 				GroupID: groupID,
@@ -200,6 +405,29 @@ func (s *Service) SyncUserGroups(userID uint64, externalGroups []string, source
 	})
 }
 
+// removeSyncedMembership applies the group sync policy to a membership the
+// identity provider no longer reports.
+func (s *Service) removeSyncedMembership(tx *gorm.DB, ug models.UserGroup) error {
+	switch s.syncPolicy {
+	case config.GroupSyncPolicyAdditive:
+		return nil
+	case config.GroupSyncPolicyApproval:
+		pending := models.PendingGroupRemoval{UserID: ug.UserID, GroupID: ug.GroupID}
+		if err := tx.Where(&pending).FirstOrCreate(&pending).Error; err != nil {
+			return fmt.Errorf("failed to queue group removal: %w", err)
+		}
+
+		return nil
+	default:
+		if err := tx.Where("user_id = ? AND group_id = ?", ug.UserID, ug.GroupID).
+			Delete(&models.UserGroup{}).Error; err != nil {
+			return fmt.Errorf("failed to remove old group membership: %w", err)
+		}
+
+		return nil
+	}
+}
+
 // AssignRoleToUser assigns a role to a user (for local users).
 func (s *Service) AssignRoleToUser(userID uint64, roleID uint) error {
 	return s.db.Model(&models.User{}).
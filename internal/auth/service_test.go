@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/querylog"
+)
+
+func TestHasAnyAllPermissions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Permission{}, &models.Role{}, &models.RolePermission{}, &models.User{},
+		&models.Group{}, &models.UserGroup{}, &models.GroupMapping{},
+	))
+
+	perms := map[string]uint{}
+
+	for _, name := range []string{"zone.read", "zone.update", "zone.delete"} {
+		p := models.Permission{Name: name, Resource: "zone", Action: name[len("zone."):]}
+		require.NoError(t, db.Create(&p).Error)
+
+		perms[name] = p.ID
+	}
+
+	viewer := models.Role{Name: "viewer"}
+	editor := models.Role{Name: "editor"}
+	require.NoError(t, db.Create(&viewer).Error)
+	require.NoError(t, db.Create(&editor).Error)
+	require.NoError(t, db.Create(&models.RolePermission{RoleID: viewer.ID, PermissionID: perms["zone.read"]}).Error)
+	require.NoError(t, db.Create(&models.RolePermission{RoleID: editor.ID, PermissionID: perms["zone.update"]}).Error)
+
+	user := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: viewer.ID}
+	require.NoError(t, db.Create(&user).Error)
+
+	group := models.Group{Name: "editors", ExternalID: "editors", Source: models.GroupSourceLocal}
+	require.NoError(t, db.Create(&group).Error)
+	require.NoError(t, db.Create(&models.UserGroup{UserID: user.ID, GroupID: group.ID}).Error)
+	require.NoError(t, db.Create(&models.GroupMapping{GroupID: group.ID, RoleID: editor.ID}).Error)
+
+	queries := querylog.New(time.Hour)
+	require.NoError(t, db.Use(queries))
+
+	s := NewService(db)
+
+	tests := []struct {
+		permissions []string
+		any, all    bool
+	}{
+		{[]string{"zone.read"}, true, true},
+		{[]string{"zone.read", "zone.update"}, true, true},
+		{[]string{"zone.update", "zone.delete"}, true, false},
+		{[]string{"zone.delete", "admin.users"}, false, false},
+	}
+
+	for _, tt := range tests {
+		got, err := s.HasAnyPermission(user.ID, tt.permissions)
+		require.NoError(t, err)
+		assert.Equal(t, tt.any, got, "any %v", tt.permissions)
+
+		got, err = s.HasAllPermissions(user.ID, tt.permissions)
+		require.NoError(t, err)
+		assert.Equal(t, tt.all, got, "all %v", tt.permissions)
+	}
+
+	// Each check resolves its permissions in one query, however many it asks for.
+	var count int64
+	for _, st := range queries.Statements(querylog.ByCount) {
+		count += st.Count
+	}
+
+	assert.EqualValues(t, 2*len(tests), count)
+}
+
+func TestTenantUsersHoldOnlyTenantPermissions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Permission{}, &models.Role{}, &models.RolePermission{}, &models.Tenant{}, &models.User{},
+		&models.Group{}, &models.UserGroup{}, &models.GroupMapping{},
+	))
+
+	admin := models.Role{Name: "admin"}
+	require.NoError(t, db.Create(&admin).Error)
+
+	for _, name := range []string{PermAdminUsers, PermAdminSettings, PermAdminTenants} {
+		p := models.Permission{Name: name, Resource: "admin", Action: name[len("admin."):]}
+		require.NoError(t, db.Create(&p).Error)
+		require.NoError(t, db.Create(&models.RolePermission{RoleID: admin.ID, PermissionID: p.ID}).Error)
+	}
+
+	acme := models.Tenant{Name: "acme"}
+	require.NoError(t, db.Create(&acme).Error)
+
+	provider := models.User{Username: "root", Email: "root@example.com", RoleID: admin.ID, Active: true}
+	customer := models.User{Username: "acme-admin", Email: "admin@acme.example", RoleID: admin.ID,
+		TenantID: &acme.ID, Active: true}
+	require.NoError(t, db.Create(&provider).Error)
+	require.NoError(t, db.Create(&customer).Error)
+
+	s := NewService(db)
+
+	for _, tt := range []struct {
+		user       models.User
+		permission string
+		want       bool
+	}{
+		{provider, PermAdminSettings, true},
+		{provider, PermAdminTenants, true},
+		{customer, PermAdminUsers, true},
+		{customer, PermAdminSettings, false},
+		{customer, PermAdminTenants, false},
+	} {
+		got, err := s.HasPermission(tt.user.ID, tt.permission)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%s %s", tt.user.Username, tt.permission)
+	}
+
+	granted, err := s.GetUserPermissions(customer.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{PermAdminUsers}, granted)
+
+	all, err := s.HasAllPermissions(customer.ID, []string{PermAdminUsers, PermAdminSettings})
+	require.NoError(t, err)
+	assert.False(t, all)
+
+	users, err := s.GetUsersWithPermission(PermAdminSettings)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, provider.ID, users[0].ID)
+}
+
+func TestGrantableRoles(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.RolePermission{}))
+
+	grant := func(role *models.Role, names ...string) {
+		require.NoError(t, db.Create(role).Error)
+
+		for _, name := range names {
+			var p models.Permission
+			require.NoError(t, db.Where(models.Permission{Name: name}).FirstOrCreate(&p).Error)
+			require.NoError(t, db.Create(&models.RolePermission{RoleID: role.ID, PermissionID: p.ID}).Error)
+		}
+	}
+
+	admin, operator, empty := models.Role{Name: "admin"}, models.Role{Name: "operator"}, models.Role{Name: "viewer"}
+	grant(&admin, PermAdminUsers, PermAdminSettings)
+	grant(&operator, PermAdminUsers, PermZoneUpdate)
+	grant(&empty)
+
+	names := func(tenantID *uint) []string {
+		var roles []string
+		require.NoError(t, db.Model(&models.Role{}).Scopes(GrantableRoles(tenantID)).Order("name").
+			Pluck("name", &roles).Error)
+
+		return roles
+	}
+
+	acme := uint(1)
+
+	assert.Equal(t, []string{"admin", "operator", "viewer"}, names(nil))
+	assert.Equal(t, []string{"operator", "viewer"}, names(&acme))
+}
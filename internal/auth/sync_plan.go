@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// SyncPlan describes what SyncUserGroups would change for one user.
+type SyncPlan struct {
+	// NewGroups are external groups without a local group; the sync would create them.
+	NewGroups []string
+	// Added are the groups the user would join, including new ones.
+	Added []string
+	// Removed are the groups of the same source the user would leave.
+	Removed []string
+	// PendingRemoval are the groups whose removal would be queued for
+	// approval; the user stays a member until an administrator approves.
+	PendingRemoval []string
+	// Kept are the groups no longer reported that the user stays in, because
+	// the membership is manual or the policy is additive.
+	Kept []string
+	// RolesGained and RolesLost are the roles the user would gain or lose
+	// through group mappings. The user's direct role is taken into account.
+	RolesGained []string
+	RolesLost   []string
+}
+
+// HasChanges reports whether the sync would change anything for the user.
+func (p *SyncPlan) HasChanges() bool {
+	return len(p.NewGroups) > 0 || len(p.Added) > 0 || len(p.Removed) > 0 || len(p.PendingRemoval) > 0
+}
+
+// PlanUserGroupSync computes what SyncUserGroups(userID, externalGroups,
+// source) would change under the configured group sync policy, without
+// writing anything.
+func (s *Service) PlanUserGroupSync(
+	userID uint64, externalGroups []string, source models.GroupSource,
+) (*SyncPlan, error) {
+	var user models.User
+	if err := s.db.Preload("Role").First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("sync plan: load user: %w", err)
+	}
+
+	current, err := s.GetUserGroups(userID)
+	if err != nil {
+		return nil, fmt.Errorf("sync plan: %w", err)
+	}
+
+	var memberships []models.UserGroup
+	if err := s.db.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		return nil, fmt.Errorf("sync plan: load memberships: %w", err)
+	}
+
+	manual := make(map[uint]bool, len(memberships))
+	for _, ug := range memberships {
+		manual[ug.GroupID] = ug.Manual
+	}
+
+	plan := &SyncPlan{}
+
+	// Resolve the external groups to the local groups the sync would use.
+	var (
+		target   = make(map[uint]models.Group)
+		seenName = make(map[string]bool)
+	)
+
+	for _, externalGroup := range externalGroups {
+		if seenName[externalGroup] {
+			continue
+		}
+
+		seenName[externalGroup] = true
+
+		var groups []models.Group
+		if err := s.db.Where("external_id = ? AND source = ?", externalGroup, source).
+			Limit(1).Find(&groups).Error; err != nil {
+			return nil, fmt.Errorf("sync plan: load group %s: %w", externalGroup, err)
+		}
+
+		if len(groups) == 0 {
+			plan.NewGroups = append(plan.NewGroups, externalGroup)
+			plan.Added = append(plan.Added, externalGroup)
+
+			continue
+		}
+
+		target[groups[0].ID] = groups[0]
+	}
+
+	// Groups of the user after the sync: other sources are kept untouched.
+	currentIDs := make(map[uint]bool, len(current))
+	after := make([]uint, 0, len(current)+len(target))
+
+	for _, g := range current {
+		currentIDs[g.ID] = true
+
+		if g.Source != source {
+			after = append(after, g.ID)
+			continue
+		}
+
+		if _, keep := target[g.ID]; keep {
+			continue
+		}
+
+		switch {
+		case manual[g.ID] || s.syncPolicy == config.GroupSyncPolicyAdditive:
+			plan.Kept = append(plan.Kept, g.Name)
+			after = append(after, g.ID)
+		case s.syncPolicy == config.GroupSyncPolicyApproval:
+			plan.PendingRemoval = append(plan.PendingRemoval, g.Name)
+			after = append(after, g.ID)
+		default:
+			plan.Removed = append(plan.Removed, g.Name)
+		}
+	}
+
+	for id, g := range target {
+		after = append(after, id)
+
+		if !currentIDs[id] {
+			plan.Added = append(plan.Added, g.Name)
+		}
+	}
+
+	before := make([]uint, 0, len(current))
+	for _, g := range current {
+		before = append(before, g.ID)
+	}
+
+	rolesBefore, err := s.mappedRoleNames(before)
+	if err != nil {
+		return nil, err
+	}
+
+	rolesAfter, err := s.mappedRoleNames(after)
+	if err != nil {
+		return nil, err
+	}
+
+	// The direct role is held regardless of group membership.
+	rolesBefore[user.Role.Name] = true
+	rolesAfter[user.Role.Name] = true
+
+	for name := range rolesAfter {
+		if !rolesBefore[name] {
+			plan.RolesGained = append(plan.RolesGained, name)
+		}
+	}
+
+	for name := range rolesBefore {
+		if !rolesAfter[name] {
+			plan.RolesLost = append(plan.RolesLost, name)
+		}
+	}
+
+	for _, list := range [][]string{
+		plan.NewGroups, plan.Added, plan.Removed, plan.PendingRemoval, plan.Kept, plan.RolesGained, plan.RolesLost,
+	} {
+		sort.Strings(list)
+	}
+
+	return plan, nil
+}
+
+// mappedRoleNames returns the names of the roles mapped to groupIDs.
+func (s *Service) mappedRoleNames(groupIDs []uint) (map[string]bool, error) {
+	names := make(map[string]bool)
+	if len(groupIDs) == 0 {
+		return names, nil
+	}
+
+	var roleNames []string
+	if err := s.db.Table("roles").
+		Joins("JOIN group_mappings ON group_mappings.role_id = roles.id").
+		Where("group_mappings.group_id IN ?", groupIDs).
+		Distinct().
+		Pluck("roles.name", &roleNames).Error; err != nil {
+		return nil, fmt.Errorf("sync plan: load mapped roles: %w", err)
+	}
+
+	for _, n := range roleNames {
+		names[n] = true
+	}
+
+	return names, nil
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestPlanUserGroupSync(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.User{}, &models.Group{}, &models.GroupMapping{}, &models.UserGroup{},
+	))
+
+	roles := map[string]*models.Role{}
+	for _, name := range []string{"viewer", "ops", "legacy", "extra"} {
+		r := &models.Role{Name: name}
+		require.NoError(t, db.Create(r).Error)
+		roles[name] = r
+	}
+
+	user := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: roles["viewer"].ID}
+	require.NoError(t, db.Create(&user).Error)
+
+	mkGroup := func(name string, source models.GroupSource, role string, member bool) {
+		g := models.Group{Name: name, ExternalID: name, Source: source}
+		require.NoError(t, db.Create(&g).Error)
+
+		if role != "" {
+			require.NoError(t, db.Create(&models.GroupMapping{GroupID: g.ID, RoleID: roles[role].ID}).Error)
+		}
+
+		if member {
+			require.NoError(t, db.Create(&models.UserGroup{UserID: user.ID, GroupID: g.ID}).Error)
+		}
+	}
+
+	mkGroup("ops", models.GroupSourceOIDC, "ops", false)
+	mkGroup("old", models.GroupSourceOIDC, "legacy", true)
+	mkGroup("kept", models.GroupSourceOIDC, "", true)
+	mkGroup("local", models.GroupSourceLocal, "extra", true)
+
+	plan, err := NewService(db).PlanUserGroupSync(user.ID,
+		[]string{"ops", "brand-new", "kept", "ops"}, models.GroupSourceOIDC)
+	require.NoError(t, err)
+
+	assert.True(t, plan.HasChanges())
+	assert.Equal(t, []string{"brand-new"}, plan.NewGroups)
+	assert.Equal(t, []string{"brand-new", "ops"}, plan.Added)
+	assert.Equal(t, []string{"old"}, plan.Removed)
+	assert.Equal(t, []string{"ops"}, plan.RolesGained)
+	assert.Equal(t, []string{"legacy"}, plan.RolesLost)
+
+	var groups, memberships int64
+	db.Model(&models.Group{}).Count(&groups)
+	db.Model(&models.UserGroup{}).Count(&memberships)
+	assert.Equal(t, int64(4), groups, "plan must not create groups")
+	assert.Equal(t, int64(3), memberships, "plan must not change memberships")
+
+	// Syncing the current groups again changes nothing.
+	plan, err = NewService(db).PlanUserGroupSync(user.ID, []string{"old", "kept"}, models.GroupSourceOIDC)
+	require.NoError(t, err)
+	assert.False(t, plan.HasChanges())
+	assert.Empty(t, plan.RolesGained)
+	assert.Empty(t, plan.RolesLost)
+
+	// Under the approval policy "old" stays until approved, and a manual
+	// membership is never removed.
+	require.NoError(t, db.Model(&models.UserGroup{}).
+		Where("user_id = ? AND group_id = (SELECT id FROM groups WHERE name = ?)", user.ID, "kept").
+		Update("manual", true).Error)
+
+	svc := NewService(db)
+	svc.SetGroupSyncPolicy(config.GroupSyncPolicyApproval)
+
+	plan, err = svc.PlanUserGroupSync(user.ID, []string{"ops"}, models.GroupSourceOIDC)
+	require.NoError(t, err)
+	assert.True(t, plan.HasChanges())
+	assert.Empty(t, plan.Removed)
+	assert.Equal(t, []string{"old"}, plan.PendingRemoval)
+	assert.Equal(t, []string{"kept"}, plan.Kept)
+	assert.Equal(t, []string{"ops"}, plan.RolesGained)
+	assert.Empty(t, plan.RolesLost)
+}
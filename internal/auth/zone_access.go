@@ -2,25 +2,135 @@ package auth
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 )
 
-// GetAccessibleZoneIDs returns the set of zone IDs the user may access.
+// ZoneAccess is the resolved set of zones a restricted user may access.
+// A nil *ZoneAccess means access is unrestricted.
+type ZoneAccess struct {
+	// Direct holds zones carrying one of the user's tags.
+	Direct map[string]bool
+	// Inheritable holds zones carrying one of the user's tags with inheritance
+	// enabled; the grant also covers their sub-zones.
+	Inheritable map[string]bool
+	// Blocked holds zones that do not inherit tags from their parent zones.
+	Blocked map[string]bool
+	// Patterns holds the zone patterns granted through the user's roles and
+	// groups.
+	Patterns []ZonePattern
+	// Tenant holds the zones of the user's tenant. When set, no other zone is
+	// allowed, whatever the grants above say.
+	Tenant map[string]bool
+}
+
+// Allows reports whether the user may access zone.
+func (a *ZoneAccess) Allows(zone string) bool {
+	_, ok := a.GrantedBy(zone)
+	return ok
+}
+
+// GrantedBy returns the zone whose tag grants access to zone: zone itself for
+// a direct grant or a matching pattern, or the nearest parent zone with
+// an inheriting grant.
+func (a *ZoneAccess) GrantedBy(zone string) (string, bool) {
+	if a != nil && a.Tenant != nil && !a.Tenant[zone] {
+		return "", false
+	}
+
+	if a == nil || a.Direct[zone] {
+		return zone, true
+	}
+
+	for _, pattern := range a.Patterns {
+		if pattern.Match(zone) {
+			return zone, true
+		}
+	}
+
+	for _, parent := range ParentZones(zone, a.Blocked) {
+		if a.Inheritable[parent] {
+			return parent, true
+		}
+	}
+
+	return "", false
+}
+
+// ParentZones returns the parent zones whose inheriting tags apply to zone,
+// nearest first ("dev.example.com." → "example.com.", "com."). The walk stops
+// at a zone in blocked: a blocked zone inherits nothing from above, so neither
+// do its sub-zones through it.
+func ParentZones(zone string, blocked map[string]bool) []string {
+	var parents []string
+
+	for cur := zone; !blocked[cur]; {
+		_, rest, ok := strings.Cut(cur, ".")
+		if !ok || rest == "" || rest == "." {
+			break
+		}
+
+		parents = append(parents, rest)
+		cur = rest
+	}
+
+	return parents
+}
+
+// GetZoneAccess resolves which zones the user may access.
 //
 // Returns nil when access is unrestricted (admin role, or the user/groups have
-// no tag assignments at all — backward-compatible default).
-// Returns a non-nil map (possibly empty) when tag restrictions are in effect;
-// only zones whose ID appears in the map are accessible.
-func (s *Service) GetAccessibleZoneIDs(userID uint64) (map[string]bool, error) {
-	// Admin role always has unrestricted access.
+// no tag assignments and the groups no zone patterns at all — backward-compatible
+// default).
+// Returns a non-nil *ZoneAccess when restrictions are in effect; only zones it
+// Allows are accessible. Zones the user owns through an approved claim are
+// always allowed. Users of a tenant are limited to the zones of their tenant,
+// the admin role included; without other grants, and always with the
+// RoleTenantAdmin role, they access all of them.
+func (s *Service) GetZoneAccess(userID uint64) (*ZoneAccess, error) {
 	var user models.User
 	if err := s.db.Preload("Role").First(&user, userID).Error; err != nil {
 		return nil, fmt.Errorf("zone access: load user: %w", err)
 	}
 
-	if user.Role.Name == "admin" {
-		return nil, nil //nolint:nilnil // nil map intentionally signals unrestricted access
+	access, err := s.grantedZoneAccess(&user)
+	if err != nil || user.TenantID == nil {
+		return access, err
+	}
+
+	var zones []string
+	if err = s.db.Model(&models.ZoneTenant{}).Where("tenant_id = ?", *user.TenantID).
+		Pluck("zone_name", &zones).Error; err != nil {
+		return nil, fmt.Errorf("zone access: load tenant zones: %w", err)
+	}
+
+	tenantZones := make(map[string]bool, len(zones))
+	for _, zone := range zones {
+		tenantZones[zone] = true
+	}
+
+	if access == nil {
+		return &ZoneAccess{Direct: tenantZones, Tenant: tenantZones}, nil
+	}
+
+	access.Tenant = tenantZones
+
+	return access, nil
+}
+
+// grantedZoneAccess resolves the zones the grants of the user allow; see
+// GetZoneAccess.
+func (s *Service) grantedZoneAccess(user *models.User) (*ZoneAccess, error) {
+	userID := user.ID
+
+	// Admin role always has unrestricted access, tenant admins within their
+	// tenant.
+	if user.Role.Name == "admin" || (user.Role.Name == RoleTenantAdmin && user.TenantID != nil) {
+		return nil, nil //nolint:nilnil // nil access intentionally signals unrestricted access
 	}
 
 	// Count direct user-tag and group-tag assignments.
@@ -37,9 +147,14 @@ func (s *Service) GetAccessibleZoneIDs(userID uint64) (map[string]bool, error) {
 		return nil, fmt.Errorf("zone access: count group tags: %w", err)
 	}
 
+	patterns, err := s.zonePatterns(user)
+	if err != nil {
+		return nil, err
+	}
+
 	// No assignments at all → unrestricted (backward compatible).
-	if directCount == 0 && groupCount == 0 {
-		return nil, nil //nolint:nilnil // nil map intentionally signals unrestricted access
+	if directCount == 0 && groupCount == 0 && len(patterns) == 0 {
+		return nil, nil //nolint:nilnil // nil access intentionally signals unrestricted access
 	}
 
 	// Collect all tag IDs the user has access to.
@@ -69,9 +184,21 @@ func (s *Service) GetAccessibleZoneIDs(userID uint64) (map[string]bool, error) {
 		tagSet[r.TagID] = struct{}{}
 	}
 
+	// Zones granted through an approved ownership claim.
+	var owned []string
+	if err := s.db.Model(&models.ZoneOwnership{}).Where("user_id = ?", userID).
+		Pluck("zone_name", &owned).Error; err != nil {
+		return nil, fmt.Errorf("zone access: load zone ownerships: %w", err)
+	}
+
 	if len(tagSet) == 0 {
-		// Has assignments but none resolved — deny everything.
-		return map[string]bool{}, nil
+		// No tags resolved — deny everything but owned and pattern zones.
+		access := &ZoneAccess{Direct: make(map[string]bool, len(owned)), Patterns: patterns}
+		for _, zone := range owned {
+			access.Direct[zone] = true
+		}
+
+		return access, nil
 	}
 
 	tagIDs := make([]uint, 0, len(tagSet))
@@ -85,10 +212,151 @@ func (s *Service) GetAccessibleZoneIDs(userID uint64) (map[string]bool, error) {
 		return nil, fmt.Errorf("zone access: load zone tags: %w", err)
 	}
 
-	accessible := make(map[string]bool, len(zoneTags))
+	access := &ZoneAccess{
+		Direct:      make(map[string]bool, len(zoneTags)),
+		Inheritable: make(map[string]bool),
+		Blocked:     make(map[string]bool),
+		Patterns:    patterns,
+	}
+
+	for _, zone := range owned {
+		access.Direct[zone] = true
+	}
+
 	for i := range zoneTags {
-		accessible[zoneTags[i].ZoneID] = true
+		access.Direct[zoneTags[i].ZoneID] = true
+
+		if zoneTags[i].Inherit {
+			access.Inheritable[zoneTags[i].ZoneID] = true
+		}
+	}
+
+	if len(access.Inheritable) == 0 {
+		return access, nil
+	}
+
+	var blocked []string
+	if err := s.db.Model(&models.ZoneAccessOption{}).
+		Where("block_inheritance = ?", true).
+		Pluck("zone_id", &blocked).Error; err != nil {
+		return nil, fmt.Errorf("zone access: load zone options: %w", err)
+	}
+
+	for _, zone := range blocked {
+		access.Blocked[zone] = true
+	}
+
+	return access, nil
+}
+
+// zonePatterns returns the zone patterns granted to the user by the user's
+// role, the user's groups and the roles mapped to those groups. Invalid stored
+// patterns are skipped.
+func (s *Service) zonePatterns(user *models.User) ([]ZonePattern, error) {
+	var texts []string
+	if err := s.db.Table("group_zones").
+		Joins("JOIN user_groups ON user_groups.group_id = group_zones.group_id").
+		Where("user_groups.user_id = ?", user.ID).
+		Pluck("group_zones.pattern", &texts).Error; err != nil {
+		return nil, fmt.Errorf("zone access: load group zones: %w", err)
+	}
+
+	var groupRoles []models.Role
+	if err := s.db.Model(&models.Role{}).
+		Joins("JOIN group_mappings ON group_mappings.role_id = roles.id").
+		Joins("JOIN user_groups ON user_groups.group_id = group_mappings.group_id").
+		Where("user_groups.user_id = ? AND roles.zones <> ''", user.ID).
+		Find(&groupRoles).Error; err != nil {
+		return nil, fmt.Errorf("zone access: load group roles: %w", err)
+	}
+
+	texts = append(texts, user.Role.ZonePatternList()...)
+	for i := range groupRoles {
+		texts = append(texts, groupRoles[i].ZonePatternList()...)
+	}
+
+	var patterns []ZonePattern
+
+	seen := make(map[string]bool, len(texts))
+
+	for _, text := range texts {
+		if seen[text] {
+			continue
+		}
+
+		seen[text] = true
+
+		pattern, err := ParseZonePattern(text)
+		if err != nil {
+			log.Warn().Err(err).Uint64("user_id", user.ID).Msg("zone access: skipping stored zone pattern")
+			continue
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// CanAccessZone reports whether the current user of the request may access
+// zone. It is the check behind the pages and endpoints of a single zone and
+// fails closed when the access cannot be resolved. A nil authService allows
+// every zone the API key of the request is not limited from.
+func CanAccessZone(c fiber.Ctx, authService *Service, zone string) bool {
+	allowed, err := ZoneFilter(c, authService)
+	if err != nil {
+		return false
+	}
+
+	return allowed == nil || allowed(zone)
+}
+
+// ZoneFilter returns the check of CanAccessZone for lists of zones, or nil
+// when the request may access all zones. Besides the zone access of the user
+// it applies the zones the API key of the request is limited to.
+func ZoneFilter(c fiber.Ctx, authService *Service) (func(string) bool, error) {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return func(string) bool { return false }, nil
+	}
+
+	var access *ZoneAccess
+
+	if authService != nil {
+		var err error
+
+		access, err = authService.GetZoneAccess(user.ID)
+		if err != nil {
+			log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load zone access")
+			return nil, err
+		}
+	}
+
+	p := PrincipalFrom(c)
+	if access == nil && (p == nil || p.Zones == nil) {
+		return nil, nil //nolint:nilnil // a nil filter intentionally signals unrestricted access
+	}
+
+	return func(zone string) bool {
+		return access.Allows(zone) && p.allowsZone(zone)
+	}, nil
+}
+
+// GetUserTags returns the tags assigned to the user directly or through one of
+// the user's groups, ordered by name.
+func (s *Service) GetUserTags(userID uint64) ([]models.Tag, error) {
+	direct := s.db.Table("user_tags").Select("tag_id").Where("user_id = ?", userID)
+	viaGroup := s.db.Table("group_tags").
+		Select("group_tags.tag_id").
+		Joins("JOIN user_groups ON user_groups.group_id = group_tags.group_id").
+		Where("user_groups.user_id = ?", userID)
+
+	var tags []models.Tag
+	if err := s.db.Where("id IN (?) OR id IN (?)", direct, viaGroup).
+		Order("name").
+		Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("zone access: load tags: %w", err)
 	}
 
-	return accessible, nil
+	return tags, nil
 }
@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestParentZones(t *testing.T) {
+	assert.Equal(t, []string{"example.com.", "com."}, ParentZones("dev.example.com.", nil))
+	assert.Empty(t, ParentZones("com.", nil))
+	assert.Empty(t, ParentZones("dev.example.com.", map[string]bool{"dev.example.com.": true}))
+	assert.Equal(t, []string{"b.example.com."},
+		ParentZones("a.b.example.com.", map[string]bool{"b.example.com.": true}))
+}
+
+func TestGetZoneAccessInheritance(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.User{}, &models.Group{}, &models.UserGroup{}, &models.GroupMapping{},
+		&models.Tag{}, &models.ZoneTag{}, &models.UserTag{}, &models.GroupTag{}, &models.ZoneAccessOption{},
+		&models.ZoneOwnership{}, &models.GroupZone{},
+	))
+
+	role := models.Role{Name: "user"}
+	require.NoError(t, db.Create(&role).Error)
+
+	user := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: role.ID}
+	require.NoError(t, db.Create(&user).Error)
+
+	team := models.Tag{Name: "team"}
+	other := models.Tag{Name: "other"}
+	require.NoError(t, db.Create(&team).Error)
+	require.NoError(t, db.Create(&other).Error)
+	require.NoError(t, db.Create(&models.UserTag{UserID: user.ID, TagID: team.ID}).Error)
+
+	require.NoError(t, db.Create(&models.ZoneTag{ZoneID: "example.com.", TagID: team.ID, Inherit: true}).Error)
+	require.NoError(t, db.Create(&models.ZoneTag{ZoneID: "example.org.", TagID: team.ID}).Error)
+	require.NoError(t, db.Create(&models.ZoneTag{ZoneID: "example.net.", TagID: other.ID, Inherit: true}).Error)
+	require.NoError(t, db.Create(&models.ZoneAccessOption{ZoneID: "private.example.com.", BlockInheritance: true}).Error)
+	require.NoError(t, db.Create(&models.ZoneOwnership{ZoneName: "owned.example.net.", UserID: user.ID}).Error)
+
+	access, err := NewService(db).GetZoneAccess(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, access)
+
+	tests := []struct {
+		zone string
+		from string
+		ok   bool
+	}{
+		{"example.com.", "example.com.", true},
+		{"dev.example.com.", "example.com.", true},
+		{"a.b.example.com.", "example.com.", true},
+		{"private.example.com.", "", false},
+		{"x.private.example.com.", "", false},
+		{"example.org.", "example.org.", true},
+		{"dev.example.org.", "", false},
+		{"dev.example.net.", "", false},
+		{"owned.example.net.", "owned.example.net.", true},
+		{"sub.owned.example.net.", "", false},
+	}
+
+	for _, tt := range tests {
+		from, ok := access.GrantedBy(tt.zone)
+		assert.Equal(t, tt.ok, ok, tt.zone)
+		assert.Equal(t, tt.from, from, tt.zone)
+	}
+
+	var unrestricted *ZoneAccess
+	assert.True(t, unrestricted.Allows("anything.example."))
+}
+
+func TestGetZoneAccessGroupZones(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.User{}, &models.Group{}, &models.UserGroup{}, &models.GroupMapping{},
+		&models.Tag{}, &models.ZoneTag{}, &models.UserTag{}, &models.GroupTag{}, &models.ZoneAccessOption{},
+		&models.ZoneOwnership{}, &models.GroupZone{},
+	))
+
+	role := models.Role{Name: "user"}
+	require.NoError(t, db.Create(&role).Error)
+
+	member := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: role.ID}
+	outsider := models.User{Username: "other", Email: "other@example.com", RoleID: role.ID}
+	require.NoError(t, db.Create(&member).Error)
+	require.NoError(t, db.Create(&outsider).Error)
+
+	team := models.Group{Name: "team-x", Source: models.GroupSourceOIDC}
+	require.NoError(t, db.Create(&team).Error)
+	require.NoError(t, db.Create(&models.UserGroup{UserID: member.ID, GroupID: team.ID}).Error)
+	require.NoError(t, db.Create(&models.GroupZone{GroupID: team.ID, Pattern: "*.team-x.example.com."}).Error)
+	require.NoError(t, db.Create(&models.GroupZone{GroupID: team.ID, Pattern: "team-x.example.org."}).Error)
+
+	svc := NewService(db)
+
+	// Group zones alone restrict the member to the matching zones.
+	access, err := svc.GetZoneAccess(member.ID)
+	require.NoError(t, err)
+	require.NotNil(t, access)
+
+	assert.True(t, access.Allows("dev.team-x.example.com."))
+	assert.True(t, access.Allows("a.dev.team-x.example.com."))
+	assert.True(t, access.Allows("team-x.example.org."))
+	assert.False(t, access.Allows("team-x.example.com."))
+	assert.False(t, access.Allows("example.com."))
+
+	// Users outside the group keep the unrestricted default.
+	access, err = svc.GetZoneAccess(outsider.ID)
+	require.NoError(t, err)
+	assert.Nil(t, access)
+
+	// Patterns add to the zones granted through tags.
+	tag := models.Tag{Name: "shared"}
+	require.NoError(t, db.Create(&tag).Error)
+	require.NoError(t, db.Create(&models.GroupTag{GroupID: team.ID, TagID: tag.ID}).Error)
+	require.NoError(t, db.Create(&models.ZoneTag{ZoneID: "shared.example.net.", TagID: tag.ID}).Error)
+
+	access, err = svc.GetZoneAccess(member.ID)
+	require.NoError(t, err)
+	assert.True(t, access.Allows("shared.example.net."))
+	assert.True(t, access.Allows("dev.team-x.example.com."))
+	assert.False(t, access.Allows("example.net."))
+}
+
+func TestGetZoneAccessRoleZones(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.User{}, &models.Group{}, &models.UserGroup{}, &models.GroupMapping{},
+		&models.Tag{}, &models.ZoneTag{}, &models.UserTag{}, &models.GroupTag{}, &models.ZoneAccessOption{},
+		&models.ZoneOwnership{}, &models.GroupZone{},
+	))
+
+	customer := models.Role{Name: "customer123", Zones: "*.customer123.com.\ncustomer123.com."}
+	reverse := models.Role{Name: "noc", Zones: "10.20.*.in-addr.arpa."}
+	require.NoError(t, db.Create(&customer).Error)
+	require.NoError(t, db.Create(&reverse).Error)
+
+	user := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: customer.ID}
+	require.NoError(t, db.Create(&user).Error)
+
+	// The role of a group adds its patterns too.
+	noc := models.Group{Name: "noc"}
+	require.NoError(t, db.Create(&noc).Error)
+	require.NoError(t, db.Create(&models.GroupMapping{GroupID: noc.ID, RoleID: reverse.ID}).Error)
+	require.NoError(t, db.Create(&models.UserGroup{UserID: user.ID, GroupID: noc.ID}).Error)
+
+	access, err := NewService(db).GetZoneAccess(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, access)
+
+	assert.True(t, access.Allows("customer123.com."))
+	assert.True(t, access.Allows("www.customer123.com."))
+	assert.True(t, access.Allows("10.20.30.in-addr.arpa."))
+	assert.False(t, access.Allows("10.21.30.in-addr.arpa."))
+	assert.False(t, access.Allows("customer124.com."))
+}
+
+func TestGetUserTags(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.User{}, &models.Group{}, &models.UserGroup{},
+		&models.Tag{}, &models.UserTag{}, &models.GroupTag{},
+	))
+
+	role := models.Role{Name: "user"}
+	require.NoError(t, db.Create(&role).Error)
+
+	user := models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: role.ID}
+	require.NoError(t, db.Create(&user).Error)
+
+	group := models.Group{Name: "ops"}
+	require.NoError(t, db.Create(&group).Error)
+	require.NoError(t, db.Create(&models.UserGroup{UserID: user.ID, GroupID: group.ID}).Error)
+
+	web := models.Tag{Name: "web"}
+	ops := models.Tag{Name: "ops"}
+	unrelated := models.Tag{Name: "unrelated"}
+	require.NoError(t, db.Create(&web).Error)
+	require.NoError(t, db.Create(&ops).Error)
+	require.NoError(t, db.Create(&unrelated).Error)
+	require.NoError(t, db.Create(&models.UserTag{UserID: user.ID, TagID: web.ID}).Error)
+	require.NoError(t, db.Create(&models.GroupTag{GroupID: group.ID, TagID: ops.ID}).Error)
+	require.NoError(t, db.Create(&models.GroupTag{GroupID: group.ID, TagID: web.ID}).Error)
+
+	tags, err := NewService(db).GetUserTags(user.ID)
+	require.NoError(t, err)
+
+	names := make([]string, len(tags))
+	for i := range tags {
+		names[i] = tags[i].Name
+	}
+
+	assert.Equal(t, []string{"ops", "web"}, names)
+}
+
+func TestGetZoneAccessTenant(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Role{}, &models.Tenant{}, &models.User{}, &models.Group{}, &models.UserGroup{}, &models.GroupMapping{},
+		&models.Tag{}, &models.ZoneTag{}, &models.UserTag{}, &models.GroupTag{}, &models.ZoneAccessOption{},
+		&models.ZoneOwnership{}, &models.GroupZone{}, &models.ZoneTenant{},
+	))
+
+	admin := models.Role{Name: "admin"}
+	role := models.Role{Name: "user"}
+	tenantAdminRole := models.Role{Name: RoleTenantAdmin}
+	require.NoError(t, db.Create(&admin).Error)
+	require.NoError(t, db.Create(&role).Error)
+	require.NoError(t, db.Create(&tenantAdminRole).Error)
+
+	acme := models.Tenant{Name: "acme"}
+	require.NoError(t, db.Create(&acme).Error)
+	require.NoError(t, db.Create(&models.ZoneTenant{ZoneName: "acme.example.", TenantID: acme.ID}).Error)
+	require.NoError(t, db.Create(&models.ZoneTenant{ZoneName: "shop.example.", TenantID: acme.ID}).Error)
+
+	tenantAdmin := models.User{Username: "acme-admin", Email: "admin@acme.example", RoleID: admin.ID, TenantID: &acme.ID}
+	member := models.User{Username: "acme-dev", Email: "dev@acme.example", RoleID: role.ID, TenantID: &acme.ID}
+	manager := models.User{Username: "acme-manager", Email: "manager@acme.example", RoleID: tenantAdminRole.ID,
+		TenantID: &acme.ID}
+	outsider := models.User{Username: "provider-manager", Email: "manager@provider.example",
+		RoleID: tenantAdminRole.ID}
+	require.NoError(t, db.Create(&tenantAdmin).Error)
+	require.NoError(t, db.Create(&member).Error)
+	require.NoError(t, db.Create(&manager).Error)
+	require.NoError(t, db.Create(&outsider).Error)
+
+	// A tag granting a zone outside the tenant does not reach beyond it.
+	team := models.Tag{Name: "team"}
+	require.NoError(t, db.Create(&team).Error)
+	require.NoError(t, db.Create(&models.UserTag{UserID: member.ID, TagID: team.ID}).Error)
+	require.NoError(t, db.Create(&models.UserTag{UserID: manager.ID, TagID: team.ID}).Error)
+	require.NoError(t, db.Create(&models.UserTag{UserID: outsider.ID, TagID: team.ID}).Error)
+	require.NoError(t, db.Create(&models.ZoneTag{ZoneID: "shop.example.", TagID: team.ID}).Error)
+	require.NoError(t, db.Create(&models.ZoneTag{ZoneID: "provider.example.", TagID: team.ID}).Error)
+
+	s := NewService(db)
+
+	access, err := s.GetZoneAccess(tenantAdmin.ID)
+	require.NoError(t, err)
+	assert.True(t, access.Allows("acme.example."))
+	assert.True(t, access.Allows("shop.example."))
+	assert.False(t, access.Allows("provider.example."))
+
+	access, err = s.GetZoneAccess(member.ID)
+	require.NoError(t, err)
+	assert.False(t, access.Allows("acme.example."))
+	assert.True(t, access.Allows("shop.example."))
+	assert.False(t, access.Allows("provider.example."))
+
+	// Tenant admins reach every zone of their tenant whatever their tags say;
+	// outside a tenant the role grants nothing special.
+	access, err = s.GetZoneAccess(manager.ID)
+	require.NoError(t, err)
+	assert.True(t, access.Allows("acme.example."))
+	assert.True(t, access.Allows("shop.example."))
+	assert.False(t, access.Allows("provider.example."))
+
+	access, err = s.GetZoneAccess(outsider.ID)
+	require.NoError(t, err)
+	assert.False(t, access.Allows("acme.example."))
+	assert.True(t, access.Allows("provider.example."))
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// zoneRegexPrefix marks a zone pattern as a regular expression.
+const zoneRegexPrefix = "~"
+
+// ErrInvalidZonePattern is returned for a zone pattern that cannot be parsed.
+var ErrInvalidZonePattern = errors.New("invalid zone pattern")
+
+// ZonePattern is a zone access rule attached to a role or group. It is one of:
+//
+//   - a zone name, "team-x.example.com.", matching that zone only;
+//   - a wildcard: a leading "*" label matches one or more labels
+//     ("*.customer123.com." matches every zone below customer123.com.), a "*"
+//     label elsewhere exactly one ("10.20.*.in-addr.arpa."), and a "*" within
+//     a label any characters but a dot ("team-*.example.com.");
+//   - "~" followed by a regular expression, matched case-insensitively against
+//     the whole fully qualified zone name ("~team-[0-9]+\.example\.com\.").
+type ZonePattern struct {
+	text string
+	re   *regexp.Regexp
+}
+
+// ParseZonePattern parses and canonicalizes a zone pattern: zone names and
+// wildcards are lower-cased and made fully qualified.
+func ParseZonePattern(text string) (ZonePattern, error) {
+	text = strings.TrimSpace(text)
+
+	if expr, ok := strings.CutPrefix(text, zoneRegexPrefix); ok {
+		re, err := regexp.Compile(`^(?i:` + expr + `)$`)
+		if expr == "" || err != nil {
+			return ZonePattern{}, fmt.Errorf("%w: %q", ErrInvalidZonePattern, text)
+		}
+
+		return ZonePattern{text: text, re: re}, nil
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(text, "."))
+	if name == "" {
+		return ZonePattern{}, fmt.Errorf("%w: %q", ErrInvalidZonePattern, text)
+	}
+
+	var expr strings.Builder
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if label == "" || len(label) > 63 || strings.Trim(label, "abcdefghijklmnopqrstuvwxyz0123456789-_*") != "" {
+			return ZonePattern{}, fmt.Errorf("%w: %q", ErrInvalidZonePattern, text)
+		}
+
+		switch {
+		case label == "*" && i == 0 && len(labels) > 1:
+			expr.WriteString(`(?:[^.]+\.)+`)
+			continue
+		case label == "*":
+			expr.WriteString(`[^.]+`)
+		default:
+			for j, part := range strings.Split(label, "*") {
+				if j > 0 {
+					expr.WriteString(`[^.]*`)
+				}
+
+				expr.WriteString(regexp.QuoteMeta(part))
+			}
+		}
+
+		expr.WriteString(`\.`)
+	}
+
+	if strings.Trim(name, "*.") == "" {
+		return ZonePattern{}, fmt.Errorf("%w: %q matches every zone", ErrInvalidZonePattern, text)
+	}
+
+	return ZonePattern{text: name + ".", re: regexp.MustCompile(`^(?i:` + expr.String() + `)$`)}, nil
+}
+
+// ParseZonePatterns parses a list of zone patterns, one per line, e.g. from a
+// form textarea. It returns the canonical patterns
+// without duplicates, or an error naming every invalid entry.
+func ParseZonePatterns(text string) ([]string, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == '\n' || r == '\r'
+	})
+
+	var (
+		patterns []string
+		errs     []error
+	)
+
+	seen := make(map[string]bool, len(fields))
+
+	for _, field := range fields {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+
+		pattern, err := ParseZonePattern(field)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if !seen[pattern.text] {
+			seen[pattern.text] = true
+			patterns = append(patterns, pattern.text)
+		}
+	}
+
+	return patterns, errors.Join(errs...)
+}
+
+// String returns the canonical form of the pattern.
+func (p ZonePattern) String() string {
+	return p.text
+}
+
+// Match reports whether the fully qualified zone name matches the pattern.
+func (p ZonePattern) Match(zone string) bool {
+	return p.re != nil && p.re.MatchString(zone)
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseZonePattern(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		match []string
+		miss  []string
+	}{
+		{
+			in: "Team-X.Example.com", want: "team-x.example.com.",
+			match: []string{"team-x.example.com.", "TEAM-X.example.com."},
+			miss:  []string{"dev.team-x.example.com."},
+		},
+		{
+			in: " *.customer123.com. ", want: "*.customer123.com.",
+			match: []string{"www.customer123.com.", "a.b.customer123.com."},
+			miss:  []string{"customer123.com.", "badcustomer123.com."},
+		},
+		{
+			in: "10.20.*.in-addr.arpa", want: "10.20.*.in-addr.arpa.",
+			match: []string{"10.20.30.in-addr.arpa."},
+			miss:  []string{"10.20.in-addr.arpa.", "10.20.30.40.in-addr.arpa."},
+		},
+		{
+			in: "team-*.example.com", want: "team-*.example.com.",
+			match: []string{"team-.example.com.", "team-x.example.com."},
+			miss:  []string{"a.team-x.example.com.", "teamx.example.com."},
+		},
+		{
+			in: `~team-[0-9]+\.example\.com\.`, want: `~team-[0-9]+\.example\.com\.`,
+			match: []string{"team-42.example.com.", "Team-7.example.com."},
+			miss:  []string{"team-x.example.com.", "a.team-42.example.com."},
+		},
+	}
+
+	for _, tt := range tests {
+		p, err := ParseZonePattern(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, p.String(), tt.in)
+
+		for _, zone := range tt.match {
+			assert.True(t, p.Match(zone), "%s should match %s", tt.in, zone)
+		}
+
+		for _, zone := range tt.miss {
+			assert.False(t, p.Match(zone), "%s should not match %s", tt.in, zone)
+		}
+	}
+
+	for _, in := range []string{"", ".", "*", "*.", "*.*", "a..example.com", "team x.example.com", "~", "~team-(", "ex/ample.com"} {
+		_, err := ParseZonePattern(in)
+		assert.ErrorIs(t, err, ErrInvalidZonePattern, in)
+	}
+}
+
+func TestParseZonePatterns(t *testing.T) {
+	got, err := ParseZonePatterns("example.com\r\n\n*.Example.com.\nexample.com.\n~a{1,3}\\.com\\.\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com.", "*.example.com.", `~a{1,3}\.com\.`}, got)
+
+	_, err = ParseZonePatterns("example.com\nbad pattern\n~(")
+	require.ErrorIs(t, err, ErrInvalidZonePattern)
+	assert.Contains(t, err.Error(), "bad pattern")
+	assert.Contains(t, err.Error(), "~(")
+}
@@ -0,0 +1,147 @@
+// Package avatar renders small user avatars for the UI. The default provider
+// generates an initials badge locally as an SVG data: URI, so no request ever
+// leaves the browser; Gravatar can be enabled explicitly and is suppressed again
+// by the DisableExternal privacy switch.
+package avatar
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash/fnv"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+const (
+	// ProviderInitials renders a locally generated initials badge.
+	ProviderInitials = "initials"
+	// ProviderGravatar loads the avatar from gravatar.com.
+	ProviderGravatar = "gravatar"
+	// ProviderNone disables avatars; templates fall back to a generic icon.
+	ProviderNone = "none"
+
+	gravatarOrigin = "https://www.gravatar.com"
+	gravatarSize   = 64
+)
+
+// palette holds background colors for initials badges; a name always maps to
+// the same color.
+var palette = []string{
+	"#0d6efd", "#6610f2", "#6f42c1", "#d63384", "#dc3545",
+	"#fd7e14", "#198754", "#20c997", "#0dcaf0", "#6c757d",
+}
+
+// Provider resolves the image URL for a user avatar.
+type Provider interface {
+	// URL returns the avatar image URL for a user, or "" when no avatar should
+	// be shown. name is the display name, email may be empty.
+	URL(name, email string) string
+	// Origin returns the external origin images are loaded from (for the
+	// Content-Security-Policy), or "" when avatars are served inline.
+	Origin() string
+}
+
+// New returns the provider selected by the configuration. Unknown or empty
+// provider names fall back to initials.
+func New(cfg config.Avatar) Provider {
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderNone:
+		return None{}
+	case ProviderGravatar:
+		if cfg.DisableExternal {
+			return Initials{}
+		}
+
+		return Gravatar{}
+	default:
+		return Initials{}
+	}
+}
+
+// None is a Provider that never returns an avatar.
+type None struct{}
+
+// URL implements Provider.
+func (None) URL(string, string) string { return "" }
+
+// Origin implements Provider.
+func (None) Origin() string { return "" }
+
+// Initials is a Provider that renders the user's initials on a colored circle
+// as an inline SVG data: URI.
+type Initials struct{}
+
+// URL implements Provider.
+func (Initials) URL(name, _ string) string {
+	initials := html.EscapeString(initialsOf(name))
+
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="64" height="64" viewBox="0 0 64 64">` +
+		`<circle cx="32" cy="32" r="32" fill="` + colorFor(name) + `"/>` +
+		`<text x="50%" y="50%" dy=".35em" text-anchor="middle" fill="#fff" ` +
+		`font-family="sans-serif" font-size="26" font-weight="600">` + initials + `</text></svg>`
+
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg))
+}
+
+// Origin implements Provider.
+func (Initials) Origin() string { return "" }
+
+// Gravatar is a Provider that loads avatars from gravatar.com. Users without an
+// email address get an initials badge instead.
+type Gravatar struct{}
+
+// URL implements Provider.
+func (Gravatar) URL(name, email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return Initials{}.URL(name, "")
+	}
+
+	sum := sha256.Sum256([]byte(email))
+
+	q := url.Values{}
+	q.Set("s", strconv.Itoa(gravatarSize))
+	q.Set("d", "identicon")
+
+	return gravatarOrigin + "/avatar/" + hex.EncodeToString(sum[:]) + "?" + q.Encode()
+}
+
+// Origin implements Provider.
+func (Gravatar) Origin() string { return gravatarOrigin }
+
+// initialsOf returns up to two upper-case initials: the first letters of the
+// first and last word, or the first letter of a single word. For email-style
+// usernames only the local part is considered.
+func initialsOf(name string) string {
+	name, _, _ = strings.Cut(name, "@")
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '.' || r == '_' || r == '-'
+	})
+	if len(words) == 0 {
+		return "?"
+	}
+
+	first := []rune(words[0])[:1]
+	if len(words) == 1 {
+		return strings.ToUpper(string(first))
+	}
+
+	last := []rune(words[len(words)-1])[:1]
+
+	return strings.ToUpper(string(first) + string(last))
+}
+
+// colorFor picks a stable palette color for name.
+func colorFor(name string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.ToLower(name)))
+
+	return palette[h.Sum32()%uint32(len(palette))]
+}
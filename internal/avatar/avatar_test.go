@@ -0,0 +1,97 @@
+package avatar
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+func TestInitialsOf(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Jane Doe", "JD"},
+		{"Jane Q. Public", "JP"},
+		{"admin", "A"},
+		{"jane.doe@example.com", "JD"},
+		{"émile zola", "ÉZ"},
+		{"  ", "?"},
+		{"", "?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := initialsOf(tt.name); got != tt.want {
+				t.Errorf("initialsOf(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitials_URL(t *testing.T) {
+	got := Initials{}.URL("Jane Doe", "jane@example.com")
+
+	const prefix = "data:image/svg+xml;base64,"
+	if !strings.HasPrefix(got, prefix) {
+		t.Fatalf("URL() = %q, want data: URI", got)
+	}
+
+	svg, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(got, prefix))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if !strings.Contains(string(svg), ">JD</text>") {
+		t.Errorf("svg does not contain initials: %s", svg)
+	}
+
+	if got != (Initials{}).URL("Jane Doe", "") {
+		t.Error("URL() is not stable for the same name")
+	}
+}
+
+func TestGravatar_URL(t *testing.T) {
+	sum := sha256.Sum256([]byte("jane@example.com"))
+	want := gravatarOrigin + "/avatar/" + hex.EncodeToString(sum[:]) + "?d=identicon&s=64"
+
+	if got := (Gravatar{}).URL("Jane Doe", "  Jane@Example.com "); got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+
+	if fallback := (Gravatar{}).URL("Jane Doe", ""); !strings.HasPrefix(fallback, "data:") {
+		t.Errorf("URL() without email = %q, want initials fallback", fallback)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.Avatar
+		wantOrigin string
+		wantEmpty  bool
+	}{
+		{name: "default", cfg: config.Avatar{}},
+		{name: "gravatar", cfg: config.Avatar{Provider: "gravatar"}, wantOrigin: gravatarOrigin},
+		{name: "gravatar disabled by privacy switch", cfg: config.Avatar{Provider: "Gravatar", DisableExternal: true}},
+		{name: "none", cfg: config.Avatar{Provider: "none"}, wantEmpty: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(tt.cfg)
+
+			if got := p.Origin(); got != tt.wantOrigin {
+				t.Errorf("Origin() = %q, want %q", got, tt.wantOrigin)
+			}
+
+			if got := p.URL("Jane Doe", "jane@example.com"); (got == "") != tt.wantEmpty {
+				t.Errorf("URL() = %q, wantEmpty %v", got, tt.wantEmpty)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+// Package backup exports the application database (users, roles, groups,
+// settings, zone ownership, audit trail, ...) to a portable JSON archive and
+// restores it. The archive does not depend on the database engine, so it can
+// also move an installation from e.g. SQLite to PostgreSQL. PowerDNS data is
+// not part of it; back up the PowerDNS backend separately.
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/version"
+)
+
+const (
+	// Format identifies backup archives.
+	Format = "gopowerdns-admin-backup"
+	// Version is the archive version written by Export. Restore accepts
+	// archives up to this version.
+	Version = 1
+
+	// batchSize is the number of rows inserted per statement on restore.
+	batchSize = 200
+)
+
+var (
+	// ErrInvalidArchive is returned by Restore for input that is not a backup
+	// archive.
+	ErrInvalidArchive = errors.New("not a GoPowerDNS-Admin backup archive")
+	// ErrUnsupportedVersion is returned by Restore for archives written by a
+	// newer release.
+	ErrUnsupportedVersion = errors.New("unsupported backup archive version")
+)
+
+// Archive is the JSON document written by Export.
+type Archive struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	AppVersion string    `json:"app_version"`
+	// Tables holds the rows of each table, keyed by table name.
+	Tables map[string]json.RawMessage `json:"tables"`
+}
+
+// TableCount is the number of rows exported or restored for a table.
+type TableCount struct {
+	Table string
+	Rows  int
+}
+
+// table exports and restores the rows of one model.
+type table struct {
+	model any
+	dump  func(db *gorm.DB) (json.RawMessage, int, error)
+	load  func(tx *gorm.DB, data json.RawMessage) (int, error)
+}
+
+// tables lists the backed up models, referenced tables first. Password reset
+// tokens and relayed bus events are short-lived and left out.
+var tables = []table{
+	tableOf[models.Role](),
+	tableOf[models.Permission](),
+	tableOf[models.RolePermission](),
+	tableOf[models.User](),
+	tableOf[models.APIKey](),
+	tableOf[models.Setting](),
+	tableOf[models.Integration](),
+	tableOf[models.TSIGKey](),
+	tableOf[models.DHCPImport](),
+	tableOf[models.DHCPImportRecord](),
+	tableOf[models.PDNSView](),
+	tableOf[models.Group](),
+	tableOf[models.GroupMapping](),
+	tableOf[models.GroupZone](),
+	tableOf[models.UserGroup](),
+	tableOf[models.PendingGroupRemoval](),
+	tableOf[models.Tag](),
+	tableOf[models.ZoneTag](),
+	tableOf[models.ZoneAccessOption](),
+	tableOf[models.Label](),
+	tableOf[models.UserTag](),
+	tableOf[models.GroupTag](),
+	tableOf[models.ZoneRequest](),
+	tableOf[models.ZoneClaim](),
+	tableOf[models.ZoneOwnership](),
+	tableOf[models.RecordSchedule](),
+	tableOf[models.Expiry](),
+	tableOf[models.ChangeRequest](),
+	tableOf[models.ZoneDeletion](),
+	tableOf[models.ZoneFavorite](),
+	tableOf[models.ZoneVisit](),
+	tableOf[models.DashboardView](),
+	tableOf[models.ActivityLog](),
+	tableOf[models.LoginEvent](),
+}
+
+// Models returns the backed up models, referenced tables first.
+func Models() []any {
+	models := make([]any, 0, len(tables))
+	for _, t := range tables {
+		models = append(models, t.model)
+	}
+
+	return models
+}
+
+func tableOf[T any]() table {
+	return table{model: new(T), dump: dump[T], load: load[T]}
+}
+
+// Export writes all application tables to w as one JSON archive. The rows
+// are read in a single transaction, so the archive is consistent.
+func Export(db *gorm.DB, w io.Writer) ([]TableCount, error) {
+	archive := Archive{
+		Format:     Format,
+		Version:    Version,
+		CreatedAt:  time.Now().UTC(),
+		AppVersion: version.Get(),
+		Tables:     make(map[string]json.RawMessage, len(tables)),
+	}
+
+	counts := make([]TableCount, 0, len(tables))
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range tables {
+			name, err := tableName(tx, t.model)
+			if err != nil {
+				return err
+			}
+
+			rows, n, err := t.dump(tx)
+			if err != nil {
+				return fmt.Errorf("export %s: %w", name, err)
+			}
+
+			archive.Tables[name] = rows
+			counts = append(counts, TableCount{Table: name, Rows: n})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, json.NewEncoder(w).Encode(&archive)
+}
+
+// Restore replaces the application tables with the rows of the archive read
+// from r, in a single transaction. Tables the archive does not contain, e.g.
+// because it was written by an older release, are left untouched. Afterwards
+// the change of every setting is published on the event bus.
+func Restore(db *gorm.DB, r io.Reader) ([]TableCount, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil || archive.Format != Format {
+		return nil, ErrInvalidArchive
+	}
+
+	if archive.Version < 1 || archive.Version > Version {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, archive.Version)
+	}
+
+	// Settings that are removed by the restore must be reported as well.
+	changed := settingNames(db)
+
+	counts := make([]TableCount, 0, len(tables))
+	known := make(map[string]bool, len(tables))
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Rows are deleted in reverse order, so no row is referenced anymore
+		// when it is deleted.
+		for _, t := range slices.Backward(tables) {
+			name, err := tableName(tx, t.model)
+			if err != nil {
+				return err
+			}
+
+			known[name] = true
+
+			if _, ok := archive.Tables[name]; !ok {
+				continue
+			}
+
+			if err = tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(t.model).Error; err != nil {
+				return fmt.Errorf("clear %s: %w", name, err)
+			}
+		}
+
+		for _, t := range tables {
+			name, _ := tableName(tx, t.model)
+
+			data, ok := archive.Tables[name]
+			if !ok {
+				continue
+			}
+
+			n, err := t.load(tx, data)
+			if err != nil {
+				return fmt.Errorf("restore %s: %w", name, err)
+			}
+
+			if err = resetSequence(tx, t.model); err != nil {
+				return fmt.Errorf("reset sequence of %s: %w", name, err)
+			}
+
+			counts = append(counts, TableCount{Table: name, Rows: n})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range archive.Tables {
+		if !known[name] {
+			log.Warn().Str("table", name).Msg("backup: skipped unknown table in archive")
+		}
+	}
+
+	changed = append(changed, settingNames(db)...)
+	slices.Sort(changed)
+
+	for _, name := range slices.Compact(changed) {
+		eventbus.Default.Publish(eventbus.TopicSetting, name)
+	}
+
+	return counts, nil
+}
+
+// dump returns the rows of T as a JSON array, ordered by primary key.
+// Association fields are left out; their rows are part of their own table.
+func dump[T any](db *gorm.DB) (json.RawMessage, int, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, 0, err
+	}
+
+	var rows []T
+	if err := db.Order(strings.Join(stmt.Schema.PrimaryFieldDBNames, ", ")).Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(stmt.Schema.Relationships.Relations) == 0 {
+		return data, len(rows), nil
+	}
+
+	var objects []map[string]json.RawMessage
+	if err = json.Unmarshal(data, &objects); err != nil {
+		return nil, 0, err
+	}
+
+	for _, object := range objects {
+		for field := range stmt.Schema.Relationships.Relations {
+			delete(object, field)
+		}
+	}
+
+	data, err = json.Marshal(objects)
+
+	return data, len(rows), err
+}
+
+// load inserts the rows of the JSON array data into the table of T.
+func load[T any](tx *gorm.DB, data json.RawMessage) (int, error) {
+	var rows []T
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return 0, err
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	if err := tx.Omit(clause.Associations).CreateInBatches(rows, batchSize).Error; err != nil {
+		return 0, err
+	}
+
+	return len(rows), nil
+}
+
+// resetSequence moves the ID sequence of a PostgreSQL table past the
+// restored IDs. Other databases derive the next ID from the stored rows.
+func resetSequence(tx *gorm.DB, model any) error {
+	if tx.Name() != "postgres" {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil || !field.AutoIncrement {
+		return nil
+	}
+
+	return tx.Exec(
+		"SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX("+field.DBName+"), 0) + 1, false) FROM "+
+			stmt.Schema.Table,
+		stmt.Schema.Table, field.DBName,
+	).Error
+}
+
+// tableName returns the table name of model.
+func tableName(db *gorm.DB, model any) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+
+	return stmt.Schema.Table, nil
+}
+
+// settingNames returns the names of the stored settings.
+func settingNames(db *gorm.DB) []string {
+	var names []string
+	if err := db.Model(&models.Setting{}).Pluck("name", &names).Error; err != nil {
+		log.Warn().Err(err).Msg("backup: failed to list settings")
+	}
+
+	return names
+}
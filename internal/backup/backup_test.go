@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+func newTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name)), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(Models()...); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func create(t *testing.T, db *gorm.DB, rows ...any) {
+	t.Helper()
+
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExportRestore(t *testing.T) {
+	src := newTestDB(t, "src.db")
+
+	role := &models.Role{Name: "admin", IsSystem: true}
+	create(t, src, role)
+
+	changed := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	user := &models.User{Username: "alice", Email: "alice@example.com", RoleID: role.ID, PasswordChangedAt: &changed}
+	create(t, src, user,
+		&models.Setting{Name: "branding", Value: []byte{0x00, '{', 0xff}},
+		&models.ZoneTag{ZoneID: "example.com.", TagID: 7},
+		&models.ActivityLog{UserID: &user.ID, Username: "alice", Action: "login"},
+	)
+
+	var archive bytes.Buffer
+
+	if _, err := Export(src, &archive); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if strings.Contains(archive.String(), `"Role":`) {
+		t.Error("archive contains association fields")
+	}
+
+	dst := newTestDB(t, "dst.db")
+	create(t, dst, &models.Role{Name: "stale"}, &models.Setting{Name: "stale", Value: []byte("x")})
+
+	var published []string
+
+	eventbus.Default.Subscribe(eventbus.TopicSetting, func(e eventbus.Event) { published = append(published, e.Key) })
+
+	counts, err := Restore(dst, &archive)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	if len(counts) != len(tables) {
+		t.Errorf("Restore() restored %d tables, want %d", len(counts), len(tables))
+	}
+
+	var roles []models.Role
+	dst.Find(&roles)
+
+	if len(roles) != 1 || roles[0].Name != "admin" || roles[0].ID != role.ID {
+		t.Errorf("roles = %+v, want only the restored admin role", roles)
+	}
+
+	var got models.User
+	if err = dst.First(&got, user.ID).Error; err != nil {
+		t.Fatalf("restored user: %v", err)
+	}
+
+	if got.Username != "alice" || got.RoleID != role.ID || !got.PasswordChangedAt.Equal(changed) {
+		t.Errorf("restored user = %+v", got)
+	}
+
+	var s models.Setting
+
+	err = dst.Where("name = ?", "branding").First(&s).Error
+	if err != nil || !bytes.Equal(s.Value, []byte{0x00, '{', 0xff}) {
+		t.Errorf("restored setting = %v, %v", s.Value, err)
+	}
+
+	var n int64
+	dst.Model(&models.ActivityLog{}).Where("user_id = ?", user.ID).Count(&n)
+
+	if n != 1 {
+		t.Errorf("restored %d activity log entries, want 1", n)
+	}
+
+	if strings.Join(published, ",") != "branding,stale" {
+		t.Errorf("published setting changes %v, want the restored and the removed setting", published)
+	}
+
+	// New rows get IDs after the restored ones.
+	next := &models.Role{Name: "viewer"}
+	create(t, dst, next)
+
+	if next.ID <= role.ID {
+		t.Errorf("new role ID %d, want > %d", next.ID, role.ID)
+	}
+}
+
+func TestRestore_RejectsInvalidArchives(t *testing.T) {
+	db := newTestDB(t, "app.db")
+
+	for _, tt := range []struct {
+		input string
+		want  error
+	}{
+		{"not json", ErrInvalidArchive},
+		{`{"format":"something-else","version":1}`, ErrInvalidArchive},
+		{`{"format":"gopowerdns-admin-backup","version":99}`, ErrUnsupportedVersion},
+	} {
+		if _, err := Restore(db, strings.NewReader(tt.input)); !errors.Is(err, tt.want) {
+			t.Errorf("Restore(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestRestore_KeepsTablesMissingFromArchive(t *testing.T) {
+	db := newTestDB(t, "app.db")
+	create(t, db, &models.Setting{Name: "keep", Value: []byte("1")})
+
+	archive := `{"format":"gopowerdns-admin-backup","version":1,"tables":{"roles":[{"ID":3,"Name":"admin"}]}}`
+
+	if _, err := Restore(db, strings.NewReader(archive)); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	var n int64
+	db.Model(&models.Setting{}).Count(&n)
+
+	if n != 1 {
+		t.Errorf("settings after restore = %d, want the untouched 1", n)
+	}
+}
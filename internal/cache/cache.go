@@ -0,0 +1,203 @@
+// Package cache provides the application object cache. Entries carry tags so
+// that a write in one subsystem can drop every entry derived from the changed
+// data, e.g. all cached permission sets when a role changes.
+//
+// The cache is disabled (see Nop) until the daemon installs a store with
+// SetDefault, which keeps tests and tools that open their own databases from
+// sharing cached values.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Tags used by the built-in cache users.
+const (
+	// TagSettings marks values derived from the settings table.
+	TagSettings = "settings"
+	// TagPermissions marks values derived from users, roles, groups, their
+	// mappings and tenants.
+	TagPermissions = "permissions"
+)
+
+// Store is a tagged key/value cache. Values are stored as is and must not be
+// modified after Set or Get.
+type Store interface {
+	// Get returns the value stored under key, if present and not expired.
+	Get(key string) (any, bool)
+	// Set stores value under key for ttl with the given tags.
+	Set(key string, value any, ttl time.Duration, tags ...string)
+	// Delete removes key.
+	Delete(key string)
+	// InvalidateTags removes every entry carrying one of tags.
+	InvalidateTags(tags ...string)
+	// Enabled reports whether values are cached at all.
+	Enabled() bool
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore Store = Nop{}
+	defaultTTL   time.Duration
+)
+
+// SetDefault installs the store used by Default and the TTL returned by TTL.
+func SetDefault(store Store, ttl time.Duration) {
+	defaultMu.Lock()
+	defaultStore = store
+	defaultTTL = ttl
+	defaultMu.Unlock()
+}
+
+// Default returns the shared store.
+func Default() Store {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultStore
+}
+
+// TTL returns the configured lifetime of cache entries.
+func TTL() time.Duration {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultTTL
+}
+
+// Nop is a Store that caches nothing.
+type Nop struct{}
+
+// Get implements Store.
+func (Nop) Get(string) (any, bool) { return nil, false }
+
+// Set implements Store.
+func (Nop) Set(string, any, time.Duration, ...string) {}
+
+// Delete implements Store.
+func (Nop) Delete(string) {}
+
+// InvalidateTags implements Store.
+func (Nop) InvalidateTags(...string) {}
+
+// Enabled implements Store.
+func (Nop) Enabled() bool { return false }
+
+type entry struct {
+	value   any
+	expires time.Time
+	tags    []string
+}
+
+// Memory is an in-process Store.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	// tagged maps a tag to the keys carrying it.
+	tagged map[string]map[string]struct{}
+	now    func() time.Time
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		entries: map[string]entry{},
+		tagged:  map[string]map[string]struct{}{},
+		now:     time.Now,
+	}
+}
+
+// Get implements Store.
+func (m *Memory) Get(key string) (any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !m.now().Before(e.expires) {
+		m.deleteLocked(key)
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// Set implements Store.
+func (m *Memory) Set(key string, value any, ttl time.Duration, tags ...string) {
+	if ttl <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteLocked(key)
+
+	m.entries[key] = entry{value: value, expires: m.now().Add(ttl), tags: tags}
+
+	for _, tag := range tags {
+		keys, ok := m.tagged[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			m.tagged[tag] = keys
+		}
+
+		keys[key] = struct{}{}
+	}
+}
+
+// Delete implements Store.
+func (m *Memory) Delete(key string) {
+	m.mu.Lock()
+	m.deleteLocked(key)
+	m.mu.Unlock()
+}
+
+// InvalidateTags implements Store.
+func (m *Memory) InvalidateTags(tags ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range m.tagged[tag] {
+			m.deleteLocked(key)
+		}
+
+		delete(m.tagged, tag)
+	}
+}
+
+// Enabled implements Store.
+func (m *Memory) Enabled() bool { return true }
+
+// Len returns the number of stored entries, including expired ones not yet
+// evicted.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.entries)
+}
+
+func (m *Memory) deleteLocked(key string) {
+	e, ok := m.entries[key]
+	if !ok {
+		return
+	}
+
+	delete(m.entries, key)
+
+	for _, tag := range e.tags {
+		if keys, ok := m.tagged[tag]; ok {
+			delete(keys, key)
+
+			if len(keys) == 0 {
+				delete(m.tagged, tag)
+			}
+		}
+	}
+}
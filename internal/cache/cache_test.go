@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	m.Set("a", 1, time.Minute)
+
+	if v, ok := m.Get("a"); !ok || v.(int) != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+
+	now = now.Add(time.Minute)
+
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("Get(a) returned an expired entry")
+	}
+
+	if m.Len() != 0 {
+		t.Errorf("Len() = %d after expiry, want 0", m.Len())
+	}
+
+	m.Set("b", 2, 0)
+
+	if _, ok := m.Get("b"); ok {
+		t.Error("Set with zero TTL stored the entry")
+	}
+}
+
+func TestMemoryInvalidateTags(t *testing.T) {
+	m := NewMemory()
+	m.Set("setting:a", "a", time.Minute, TagSettings)
+	m.Set("perms:1", "p1", time.Minute, TagPermissions)
+	m.Set("perms:2", "p2", time.Minute, TagPermissions)
+	m.Set("both", "x", time.Minute, TagSettings, TagPermissions)
+
+	m.InvalidateTags(TagPermissions)
+
+	for _, key := range []string{"perms:1", "perms:2", "both"} {
+		if _, ok := m.Get(key); ok {
+			t.Errorf("Get(%s) found an invalidated entry", key)
+		}
+	}
+
+	if _, ok := m.Get("setting:a"); !ok {
+		t.Error("InvalidateTags removed an entry with another tag")
+	}
+
+	// The removed "both" entry must no longer be tracked under settings.
+	m.Set("both", "y", time.Minute)
+	m.InvalidateTags(TagSettings)
+
+	if _, ok := m.Get("both"); !ok {
+		t.Error("re-set entry without tags was invalidated by a stale tag")
+	}
+}
+
+func TestNopCachesNothing(t *testing.T) {
+	var s Store = Nop{}
+	s.Set("a", 1, time.Minute, TagSettings)
+
+	if _, ok := s.Get("a"); ok || s.Enabled() {
+		t.Error("Nop store cached a value")
+	}
+}
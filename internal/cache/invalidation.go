@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+// tableTopics maps the tables the cached values are derived from to the bus
+// topic published after they are written.
+var tableTopics = map[string]string{
+	"settings":         eventbus.TopicSettings,
+	"users":            eventbus.TopicPermissions,
+	"roles":            eventbus.TopicPermissions,
+	"permissions":      eventbus.TopicPermissions,
+	"role_permissions": eventbus.TopicPermissions,
+	"groups":           eventbus.TopicPermissions,
+	"group_mappings":   eventbus.TopicPermissions,
+	"user_groups":      eventbus.TopicPermissions,
+	"tenants":          eventbus.TopicPermissions,
+}
+
+// topicTags maps bus topics to the cache tags they invalidate.
+var topicTags = map[string]string{
+	eventbus.TopicSettings:    TagSettings,
+	eventbus.TopicPermissions: TagPermissions,
+}
+
+// RegisterInvalidation publishes a bus event after every successful create,
+// update or delete on a table cached values are derived from, and subscribes
+// the default store to those events so that local and remote writes drop the
+// affected entries.
+//
+// Writes inside an explicit transaction publish when the statement finishes,
+// before the transaction commits; a value read in between is cached for at
+// most the configured TTL.
+func RegisterInvalidation(db *gorm.DB, bus *eventbus.Bus) error {
+	publish := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement == nil {
+			return
+		}
+
+		if topic, ok := tableTopics[tx.Statement.Table]; ok {
+			bus.Publish(topic, "")
+		}
+	}
+
+	const name = "cache:invalidate"
+
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().After("gorm:commit_or_rollback_transaction").Register(name, publish); err != nil {
+		return err
+	}
+
+	if err := callbacks.Update().After("gorm:commit_or_rollback_transaction").Register(name, publish); err != nil {
+		return err
+	}
+
+	if err := callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register(name, publish); err != nil {
+		return err
+	}
+
+	for topic, tag := range topicTags {
+		bus.Subscribe(topic, func(eventbus.Event) {
+			Default().InvalidateTags(tag)
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+func TestRegisterInvalidation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&models.Setting{}, &models.Role{}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemory()
+	SetDefault(store, time.Minute)
+	t.Cleanup(func() { SetDefault(Nop{}, 0) })
+
+	bus := eventbus.New()
+	if err := RegisterInvalidation(db, bus); err != nil {
+		t.Fatal(err)
+	}
+
+	fill := func() {
+		store.Set("setting:a", "a", time.Minute, TagSettings)
+		store.Set("perms:1", "p", time.Minute, TagPermissions)
+	}
+
+	fill()
+
+	if err := db.Create(&models.Setting{Name: "a", Value: []byte("1")}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.Get("setting:a"); ok {
+		t.Error("settings entry survived a settings insert")
+	}
+
+	if _, ok := store.Get("perms:1"); !ok {
+		t.Error("permissions entry dropped by a settings insert")
+	}
+
+	fill()
+
+	if err := db.Create(&models.Role{Name: "ops"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.Get("perms:1"); ok {
+		t.Error("permissions entry survived a role insert")
+	}
+
+	fill()
+
+	// Failed writes do not invalidate.
+	_ = db.Create(&models.Role{Name: "ops"}).Error
+
+	if _, ok := store.Get("perms:1"); !ok {
+		t.Error("permissions entry dropped by a failed insert")
+	}
+}
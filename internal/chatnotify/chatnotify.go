@@ -0,0 +1,231 @@
+// Package chatnotify posts zone and record changes, failed zone health
+// checks and PowerDNS servers going down or recovering to Slack, Mattermost and Microsoft Teams channels through incoming
+// webhooks. The channels are models.Integration rows managed under
+// Admin → Integrations; each selects its events and may override the message
+// templates.
+package chatnotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// Events a channel can subscribe to.
+const (
+	// EventZone is a zone that was created, changed, deleted or restored.
+	EventZone = "zone"
+	// EventRecord is a change of the records of a zone.
+	EventRecord = "record"
+	// EventHealth is a zone whose health checks started failing.
+	EventHealth = "health"
+	// EventServer is a PowerDNS server that went down or recovered. It is
+	// sent to the channels subscribed to EventHealth, whatever their zones.
+	EventServer = "server"
+)
+
+// Default message templates, used when an integration leaves its template
+// empty.
+const (
+	DefaultZoneTemplate   = "{{.User}} {{.Summary}} {{.Zone}}{{if .URL}}\n{{.URL}}{{end}}"
+	DefaultRecordTemplate = "{{.User}} {{.Summary}} {{.Zone}}{{range .Changes}}\n• {{.}}{{end}}" +
+		"{{if .URL}}\n{{.URL}}{{end}}"
+	DefaultHealthTemplate = "Health checks of {{.Zone}} failing: {{.Errors}} error(s), {{.Warnings}} warning(s)" +
+		"{{if .URL}}\n{{.URL}}{{end}}"
+	DefaultServerTemplate = "{{if .Down}}{{.Server}} is down after {{.Failures}} failed check(s): {{.Error}}" +
+		"{{else}}{{.Server}} is up again{{end}}"
+)
+
+const (
+	// sendTimeout bounds one webhook request.
+	sendTimeout = 10 * time.Second
+
+	// maxResponse is how much of an error response is kept for the message.
+	maxResponse = 200
+
+	// botName is the sender shown by Mattermost.
+	botName = "GoPowerDNS-Admin"
+)
+
+var (
+	// ErrUnknownKind is returned for an integration of an unsupported kind.
+	ErrUnknownKind = errors.New("unknown integration kind")
+	// ErrDelivery is returned when a webhook rejects a message.
+	ErrDelivery = errors.New("webhook rejected the message")
+)
+
+// Message is the data of a message template.
+type Message struct {
+	// Event is EventZone, EventRecord, EventHealth or EventServer.
+	Event string
+	// Action is the activity log action, e.g. "zone_created"; empty for
+	// EventHealth and EventServer.
+	Action string
+	Zone   string
+	// User is the user who made the change.
+	User string
+	// Summary describes the change, e.g. "created zone" or
+	// "changed 2 record(s) in".
+	Summary string
+	// Changes lists the changed records, e.g. "added www.example.com. A".
+	Changes []string
+	// Errors and Warnings count the failing health checks.
+	Errors   int
+	Warnings int
+	// Server names the server of EventServer, e.g. "PowerDNS view internal".
+	// Down tells whether it went down or recovered; Failures counts the
+	// failed checks and Error is why the last one failed.
+	Server   string
+	Down     bool
+	Failures int
+	Error    string
+	// URL links to the zone; empty without [webserver] url.
+	URL string
+}
+
+// DefaultTemplate returns the default template of event.
+func DefaultTemplate(event string) string {
+	switch event {
+	case EventRecord:
+		return DefaultRecordTemplate
+	case EventHealth:
+		return DefaultHealthTemplate
+	case EventServer:
+		return DefaultServerTemplate
+	default:
+		return DefaultZoneTemplate
+	}
+}
+
+// Sample returns an example message of event, e.g. to check a template or
+// to send a test message.
+func Sample(event string) *Message {
+	if event == EventServer {
+		return &Message{Event: event, Server: "PowerDNS", Down: true, Failures: 3, Error: "connection refused"}
+	}
+
+	m := &Message{Event: event, Zone: "example.com.", User: "admin", URL: "https://dns.example.com/zone/edit/example.com."}
+
+	switch event {
+	case EventRecord:
+		m.Action = "record_changed"
+		m.Summary = "changed 2 record(s) in"
+		m.Changes = []string{"added www.example.com. A", "deleted old.example.com. CNAME"}
+	case EventHealth:
+		m.Errors, m.Warnings = 1, 2
+	default:
+		m.Action = "zone_created"
+		m.Summary = "created zone"
+	}
+
+	return m
+}
+
+// CheckTemplate reports whether src is a valid template of event.
+func CheckTemplate(event, src string) error {
+	_, err := Render(event, src, Sample(event))
+
+	return err
+}
+
+// Render returns the text of m with the template src, or the default
+// template of event when src is empty.
+func Render(event, src string, m *Message) (string, error) {
+	if strings.TrimSpace(src) == "" {
+		src = DefaultTemplate(event)
+	}
+
+	tpl, err := template.New(event).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err = tpl.Execute(&b, m); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// textPayload is the message of a Slack or Mattermost webhook.
+type textPayload struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Payload returns the webhook request body posting text in the format of
+// kind. channel overrides the channel of Slack and Mattermost webhooks.
+func Payload(kind models.IntegrationKind, channel, text string) ([]byte, error) {
+	switch kind {
+	case models.IntegrationSlack:
+		return json.Marshal(textPayload{Text: text, Channel: channel})
+	case models.IntegrationMattermost:
+		return json.Marshal(textPayload{Text: text, Channel: channel, Username: botName})
+	case models.IntegrationTeams:
+		// Teams workflow webhooks take an Adaptive Card.
+		return json.Marshal(map[string]any{
+			"type": "message",
+			"attachments": []any{map[string]any{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"type":    "AdaptiveCard",
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"version": "1.4",
+					"body":    []any{map[string]any{"type": "TextBlock", "text": text, "wrap": true}},
+				},
+			}},
+		})
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+	}
+}
+
+// Send posts text to the webhook of in.
+func Send(ctx context.Context, client *http.Client, in *models.Integration, text string) error {
+	body, err := Payload(in.Kind, in.Channel, text)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL holds the webhook credentials; keep it out of the error.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+
+		return fmt.Errorf("%w: %s: %s", ErrDelivery, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
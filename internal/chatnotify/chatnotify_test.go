@@ -0,0 +1,245 @@
+package chatnotify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestRender(t *testing.T) {
+	m := Sample(EventRecord)
+
+	got, err := Render(EventRecord, "", m)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	want := "admin changed 2 record(s) in example.com.\n" +
+		"• added www.example.com. A\n• deleted old.example.com. CNAME\n" +
+		"https://dns.example.com/zone/edit/example.com."
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	if got, _ = Render(EventZone, "{{.Zone}} by {{.User}}", Sample(EventZone)); got != "example.com. by admin" {
+		t.Errorf("Render(custom) = %q", got)
+	}
+
+	if got, _ = Render(EventServer, "", Sample(EventServer)); got != "PowerDNS is down after 3 failed check(s): connection refused" {
+		t.Errorf("Render(server) = %q", got)
+	}
+
+	for _, src := range []string{"{{.Zone", "{{.Unknown}}"} {
+		if err = CheckTemplate(EventZone, src); err == nil {
+			t.Errorf("CheckTemplate(%q) = nil, want an error", src)
+		}
+	}
+}
+
+func TestPayload(t *testing.T) {
+	for _, tt := range []struct {
+		kind models.IntegrationKind
+		want string
+	}{
+		{models.IntegrationSlack, `{"text":"hi"}`},
+		{models.IntegrationMattermost, `{"text":"hi","username":"GoPowerDNS-Admin"}`},
+	} {
+		got, err := Payload(tt.kind, "", "hi")
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Payload(%s) = %s, %v, want %s", tt.kind, got, err, tt.want)
+		}
+	}
+
+	got, err := Payload(models.IntegrationTeams, "ignored", "hi")
+	if err != nil || !strings.Contains(string(got), `"text":"hi"`) || !strings.Contains(string(got), "AdaptiveCard") {
+		t.Errorf("Payload(teams) = %s, %v", got, err)
+	}
+
+	if _, err = Payload("irc", "", "hi"); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("Payload(irc) error = %v, want ErrUnknownKind", err)
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	in := &models.Integration{Enabled: true, RecordEvents: true, Zones: "example.com example.org."}
+
+	for _, tt := range []struct {
+		event, zone string
+		want        bool
+	}{
+		{EventRecord, "example.com.", true},
+		{EventRecord, "dev.Example.com.", true},
+		{EventRecord, "badexample.com.", false},
+		{EventRecord, "example.net.", false},
+		{EventZone, "example.com.", false},
+		{EventServer, "", false},
+	} {
+		if got := Subscribed(in, tt.event, tt.zone); got != tt.want {
+			t.Errorf("Subscribed(%s, %s) = %v, want %v", tt.event, tt.zone, got, tt.want)
+		}
+	}
+
+	in.HealthEvents = true
+	if !Subscribed(in, EventServer, "") {
+		t.Error("server events filtered by zone")
+	}
+
+	in.Enabled = false
+	if Subscribed(in, EventRecord, "example.com.") {
+		t.Error("disabled integration subscribed")
+	}
+}
+
+func TestActivityMessage(t *testing.T) {
+	details, _ := json.Marshal(activitylog.RecordsDiff{Records: []activitylog.RecordEntryDiff{
+		{Name: "www.example.com.", Type: "A", Action: "added"},
+	}})
+
+	m := activityMessage(&models.ActivityLog{
+		Action:       activitylog.ActionRecordChanged,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: "example.com",
+		Username:     "alice",
+		Details:      string(details),
+	})
+	if m == nil || m.Event != EventRecord || m.Zone != "example.com." || len(m.Changes) != 1 ||
+		m.Summary != "changed 1 record(s) in" {
+		t.Errorf("activityMessage(record_changed) = %+v", m)
+	}
+
+	m = activityMessage(&models.ActivityLog{
+		Action: activitylog.ActionZoneDeleted, ResourceType: activitylog.ResourceTypeZone, ResourceName: "example.com.",
+	})
+	if m == nil || m.Event != EventZone || m.User != systemUser {
+		t.Errorf("activityMessage(zone_deleted) = %+v", m)
+	}
+
+	if m = activityMessage(&models.ActivityLog{Action: activitylog.ActionLogin, ResourceType: "auth"}); m != nil {
+		t.Errorf("activityMessage(login) = %+v, want nil", m)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	var bodies []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+
+		if r.URL.Path == "/broken" {
+			http.Error(w, "invalid_token", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(&models.Tenant{}, &models.ZoneTenant{}, &models.Integration{}); err != nil {
+		t.Fatal(err)
+	}
+
+	integrations := []models.Integration{
+		{Name: "ok", Kind: models.IntegrationSlack, WebhookURL: srv.URL + "/ok", Enabled: true, ZoneEvents: true,
+			ZoneTemplate: "{{.Zone}} {{.Action}}"},
+		{Name: "broken", Kind: models.IntegrationMattermost, WebhookURL: srv.URL + "/broken", Enabled: true,
+			ZoneEvents: true},
+		{Name: "records", Kind: models.IntegrationSlack, WebhookURL: srv.URL + "/records", Enabled: true, RecordEvents: true},
+	}
+	if err = db.Create(&integrations).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	n := New(db, nil)
+
+	err = n.Deliver(t.Context(), &Message{Event: EventZone, Action: "zone_created", Zone: "example.com."})
+	if !errors.Is(err, ErrDelivery) {
+		t.Errorf("Deliver() error = %v, want ErrDelivery", err)
+	}
+
+	if len(bodies) != 2 || bodies[0] != `{"text":"example.com. zone_created"}` {
+		t.Errorf("posted %q", bodies)
+	}
+
+	var got []models.Integration
+	db.Order("id").Find(&got)
+
+	if got[0].LastSentAt == nil || got[0].LastError != "" {
+		t.Errorf("ok integration = %+v", got[0])
+	}
+
+	if !strings.Contains(got[1].LastError, "403") || strings.Contains(got[1].LastError, srv.URL) {
+		t.Errorf("broken integration LastError = %q", got[1].LastError)
+	}
+
+	if got[2].LastSentAt != nil {
+		t.Errorf("records integration received a zone event")
+	}
+}
+
+func TestDeliver_Tenants(t *testing.T) {
+	var paths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(&models.Tenant{}, &models.ZoneTenant{}, &models.Integration{}); err != nil {
+		t.Fatal(err)
+	}
+
+	acme, globex := models.Tenant{Name: "acme"}, models.Tenant{Name: "globex"}
+	db.Create(&acme)
+	db.Create(&globex)
+	db.Create(&models.ZoneTenant{ZoneName: "acme.example.", TenantID: acme.ID})
+
+	for _, in := range []models.Integration{
+		{Name: "provider", WebhookURL: srv.URL + "/provider", TenantID: nil},
+		{Name: "acme", WebhookURL: srv.URL + "/acme", TenantID: &acme.ID},
+		{Name: "globex", WebhookURL: srv.URL + "/globex", TenantID: &globex.ID},
+	} {
+		in.Kind, in.Enabled, in.ZoneEvents, in.HealthEvents = models.IntegrationSlack, true, true, true
+		if err = db.Create(&in).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n := New(db, nil)
+
+	for _, tt := range []struct {
+		m    *Message
+		want string
+	}{
+		{&Message{Event: EventZone, Action: "zone_created", Zone: "acme.example."}, "/provider /acme"},
+		{&Message{Event: EventZone, Action: "zone_created", Zone: "example.com."}, "/provider"},
+		{&Message{Event: EventServer, Server: "pdns-1", Down: true}, "/provider"},
+	} {
+		paths = nil
+
+		if err = n.Deliver(t.Context(), tt.m); err != nil {
+			t.Fatalf("Deliver() error = %v", err)
+		}
+
+		if got := strings.Join(paths, " "); got != tt.want {
+			t.Errorf("%s %s%s posted to %q, want %q", tt.m.Event, tt.m.Zone, tt.m.Server, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package chatnotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/servermonitor"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonestats"
+)
+
+const (
+	// maxChanges is the number of changed records listed in a message.
+	maxChanges = 10
+
+	// maxErrorLength fits models.Integration.LastError.
+	maxErrorLength = 500
+
+	// systemUser is the user of changes made by the application itself,
+	// e.g. scheduled record changes.
+	systemUser = "system"
+)
+
+// zoneSummaries describes the zone actions sent as EventZone.
+var zoneSummaries = map[string]string{
+	activitylog.ActionZoneCreated:           "created zone",
+	activitylog.ActionZoneUpdated:           "changed the settings of zone",
+	activitylog.ActionZoneDeleted:           "deleted zone",
+	activitylog.ActionZoneDeletedUndone:     "restored the deleted zone",
+	activitylog.ActionZoneDeletionScheduled: "scheduled the deletion of zone",
+	activitylog.ActionZoneDeletionRestored:  "canceled the deletion of zone",
+	activitylog.ActionSnapshotZoneRestored:  "restored a snapshot of zone",
+}
+
+// Notifier sends the messages of the configured integrations.
+type Notifier struct {
+	db     *gorm.DB
+	client *http.Client
+	// link returns the URL of a zone, or "" when unknown.
+	link func(zone string) string
+}
+
+// New returns a Notifier reading the integrations from db. link returns the
+// URL of the zone page, or "" when the external URL is not configured.
+func New(db *gorm.DB, link func(zone string) string) *Notifier {
+	return &Notifier{db: db, client: &http.Client{}, link: link}
+}
+
+// Follow sends messages for the activity log entries, zone health and server
+// health events published on bus. Events replayed from other replicas are ignored; the
+// replica that published them sends the messages.
+func (n *Notifier) Follow(bus *eventbus.Bus) {
+	bus.Subscribe(eventbus.TopicActivity, n.onActivity)
+	bus.Subscribe(eventbus.TopicZoneHealth, n.onZoneHealth)
+	bus.Subscribe(eventbus.TopicServerHealth, n.onServerHealth)
+}
+
+func (n *Notifier) onActivity(e eventbus.Event) {
+	if e.Remote {
+		return
+	}
+
+	id, err := strconv.ParseUint(e.Key, 10, 64)
+	if err != nil {
+		return
+	}
+
+	var entry models.ActivityLog
+	if err = n.db.First(&entry, id).Error; err != nil {
+		log.Warn().Err(err).Uint64("id", id).Msg("chatnotify: failed to load activity log entry")
+		return
+	}
+
+	m := activityMessage(&entry)
+	if m == nil {
+		return
+	}
+
+	n.send(m)
+}
+
+func (n *Notifier) onZoneHealth(e eventbus.Event) {
+	if e.Remote || e.Key == "" {
+		return
+	}
+
+	m := &Message{Event: EventHealth, Zone: e.Key}
+
+	if entry, ok := zonestats.Default.Entry(e.Key); ok {
+		m.Errors, m.Warnings = entry.Errors, entry.Warnings
+	}
+
+	n.send(m)
+}
+
+func (n *Notifier) onServerHealth(e eventbus.Event) {
+	if e.Remote {
+		return
+	}
+
+	status, ok := servermonitor.Default.Status(e.Key)
+	if !ok {
+		return
+	}
+
+	n.send(&Message{
+		Event:    EventServer,
+		Server:   status.Label(),
+		Down:     status.Down,
+		Failures: status.Failures,
+		Error:    status.Error,
+	})
+}
+
+// send delivers m in the background, so that a slow webhook does not delay
+// the request that made the change.
+func (n *Notifier) send(m *Message) {
+	if n.link != nil && m.Zone != "" {
+		m.URL = n.link(m.Zone)
+	}
+
+	jobs.Go(jobs.ChatNotify, func() error {
+		return n.Deliver(context.Background(), m)
+	})
+}
+
+// Deliver posts m to every enabled integration subscribed to its event and
+// zone, and stores the outcome with each integration. Integrations of a
+// tenant only get the messages of the zones of their tenant.
+func (n *Notifier) Deliver(ctx context.Context, m *Message) error {
+	var integrations []models.Integration
+	if err := n.db.Where("enabled = ?", true).Find(&integrations).Error; err != nil {
+		log.Error().Err(err).Msg("chatnotify: failed to load integrations")
+		return err
+	}
+
+	var owner *uint
+
+	if m.Zone != "" {
+		var err error
+		if owner, err = tenant.OfZone(n.db, m.Zone); err != nil {
+			log.Error().Err(err).Str("zone", m.Zone).Msg("chatnotify: failed to load the tenant of the zone")
+			return err
+		}
+	}
+
+	var errs []error
+
+	for i := range integrations {
+		in := &integrations[i]
+		if !Subscribed(in, m.Event, m.Zone) || !reaches(in, m.Event, owner) {
+			continue
+		}
+
+		err := n.deliverTo(ctx, in, m)
+		if err != nil {
+			log.Warn().Err(err).Str("integration", in.Name).Str("zone", m.Zone).Msg("chatnotify: failed to send message")
+
+			errs = append(errs, fmt.Errorf("%s: %w", in.Name, err))
+		}
+
+		n.saveResult(in, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// SendTest posts an example message of the first event in subscribes to, or
+// EventZone, and stores the outcome with in.
+func (n *Notifier) SendTest(ctx context.Context, in *models.Integration) error {
+	event := EventZone
+
+	switch {
+	case in.ZoneEvents:
+	case in.RecordEvents:
+		event = EventRecord
+	case in.HealthEvents:
+		event = EventHealth
+	}
+
+	err := n.deliverTo(ctx, in, Sample(event))
+	n.saveResult(in, err)
+
+	return err
+}
+
+func (n *Notifier) deliverTo(ctx context.Context, in *models.Integration, m *Message) error {
+	text, err := Render(m.Event, templateOf(in, m.Event), m)
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+
+	return Send(ctx, n.client, in, text)
+}
+
+// saveResult stores the outcome of a delivery without touching UpdatedAt.
+func (n *Notifier) saveResult(in *models.Integration, err error) {
+	now := time.Now()
+	msg := ""
+
+	if err != nil {
+		msg = err.Error()
+		if len(msg) > maxErrorLength {
+			msg = msg[:maxErrorLength]
+		}
+	}
+
+	if dbErr := n.db.Model(&models.Integration{}).Where("id = ?", in.ID).
+		UpdateColumns(map[string]any{"last_sent_at": now, "last_error": msg}).Error; dbErr != nil {
+		log.Warn().Err(dbErr).Str("integration", in.Name).Msg("chatnotify: failed to store delivery result")
+	}
+}
+
+// Subscribed reports whether in sends the messages of event for zone. The
+// zones of in do not filter EventServer.
+func Subscribed(in *models.Integration, event, zone string) bool {
+	switch {
+	case !in.Enabled:
+		return false
+	case event == EventZone && !in.ZoneEvents,
+		event == EventRecord && !in.RecordEvents,
+		(event == EventHealth || event == EventServer) && !in.HealthEvents:
+		return false
+	case event == EventServer:
+		return true
+	}
+
+	filter := strings.Fields(in.Zones)
+	if len(filter) == 0 {
+		return true
+	}
+
+	zone = canonical(zone)
+
+	for _, f := range filter {
+		f = canonical(f)
+		if zone == f || strings.HasSuffix(zone, "."+f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reaches reports whether in may get the messages of event for a zone of the
+// tenant owner. Integrations of the provider get every message; those of a
+// tenant neither the messages of other zones nor those about the servers all
+// tenants share.
+func reaches(in *models.Integration, event string, owner *uint) bool {
+	if in.TenantID == nil {
+		return true
+	}
+
+	return event != EventServer && owner != nil && *owner == *in.TenantID
+}
+
+// templateOf returns the template of event configured in in; EventServer
+// always uses DefaultServerTemplate.
+func templateOf(in *models.Integration, event string) string {
+	switch event {
+	case EventRecord:
+		return in.RecordTemplate
+	case EventHealth:
+		return in.HealthTemplate
+	case EventServer:
+		return ""
+	default:
+		return in.ZoneTemplate
+	}
+}
+
+// activityMessage returns the message of an activity log entry, or nil for
+// entries that are not sent.
+func activityMessage(entry *models.ActivityLog) *Message {
+	if entry.ResourceType != activitylog.ResourceTypeZone || entry.ResourceName == "" {
+		return nil
+	}
+
+	m := &Message{Action: entry.Action, Zone: canonical(entry.ResourceName), User: entry.Username}
+	if m.User == "" {
+		m.User = systemUser
+	}
+
+	if summary, ok := zoneSummaries[entry.Action]; ok {
+		m.Event, m.Summary = EventZone, summary
+		return m
+	}
+
+	m.Event = EventRecord
+
+	switch entry.Action {
+	case activitylog.ActionRecordChanged:
+		var total int
+
+		m.Changes, total = recordChanges(entry.Details)
+		m.Summary = fmt.Sprintf("changed %d record(s) in", total)
+	case activitylog.ActionRecordUndone:
+		m.Summary = "undid a record change in"
+	case activitylog.ActionRecordScheduleApplied:
+		m.Summary = "applied a scheduled record change in"
+	case activitylog.ActionChangeRequested:
+		m.Summary = "proposed a record change for approval in"
+	default:
+		return nil
+	}
+
+	return m
+}
+
+// recordChanges lists the changes of activitylog.RecordsDiff details, up to
+// maxChanges, and returns their number.
+func recordChanges(details string) ([]string, int) {
+	var diff activitylog.RecordsDiff
+	if err := json.Unmarshal([]byte(details), &diff); err != nil {
+		return nil, 0
+	}
+
+	changes := make([]string, 0, min(len(diff.Records), maxChanges+1))
+
+	for i, r := range diff.Records {
+		if i == maxChanges {
+			changes = append(changes, fmt.Sprintf("and %d more", len(diff.Records)-maxChanges))
+			break
+		}
+
+		changes = append(changes, r.Action+" "+r.Name+" "+r.Type)
+	}
+
+	return changes, len(diff.Records)
+}
+
+func canonical(zone string) string {
+	zone = strings.ToLower(strings.TrimSpace(zone))
+	if zone != "" && !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+
+	return zone
+}
@@ -4,10 +4,17 @@ package config
 import (
 	"bytes"
 	"encoding/json"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
 )
 
 // EnvPrefix is the prefix for environment variable overrides.
@@ -33,24 +40,7 @@ func ReadConfig(path string) (Config, error) {
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
-	var mainConfig, overlayFile string
-
-	if strings.HasSuffix(path, ".toml") {
-		// A specific overlay file was given; derive main.toml from its grandparent dir.
-		// e.g. "etc/local/dev.toml" → main = "etc/main.toml", overlay = "etc/local/dev.toml"
-		overlayFile = path
-		parts := strings.Split(strings.TrimSuffix(path, ".toml"), "/")
-
-		baseDir := strings.Join(parts[:len(parts)-2], "/")
-		if baseDir == "" {
-			baseDir = "."
-		}
-
-		mainConfig = baseDir + "/main.toml"
-	} else {
-		dir := strings.TrimRight(path, "/")
-		mainConfig = dir + "/main.toml"
-	}
+	mainConfig, overlayFile := configFiles(path)
 
 	v.SetConfigFile(mainConfig)
 
@@ -71,6 +61,8 @@ func ReadConfig(path string) (Config, error) {
 		return Config{}, errors.Wrap(err, "failed to unmarshal config")
 	}
 
+	c.Path = path
+
 	// Bridge log.level → Log.LogLevel (logger.Log uses a different field name).
 	if c.Log.LogLevel == "" {
 		c.Log.LogLevel = v.GetString("log.level")
@@ -93,6 +85,25 @@ func ReadConfig(path string) (Config, error) {
 	return c, nil
 }
 
+// configFiles returns the main config file and the optional overlay file for
+// a ReadConfig path.
+func configFiles(path string) (mainConfig, overlayFile string) {
+	if !strings.HasSuffix(path, ".toml") {
+		return strings.TrimRight(path, "/") + "/main.toml", ""
+	}
+
+	// A specific overlay file was given; derive main.toml from its grandparent dir.
+	// e.g. "etc/local/dev.toml" → main = "etc/main.toml", overlay = "etc/local/dev.toml"
+	parts := strings.Split(strings.TrimSuffix(path, ".toml"), "/")
+
+	baseDir := strings.Join(parts[:len(parts)-2], "/")
+	if baseDir == "" {
+		baseDir = "."
+	}
+
+	return baseDir + "/main.toml", path
+}
+
 // DumpConfigJSON serializes the config as an indented JSON string.
 func DumpConfigJSON(c *Config) (string, error) {
 	var buffer bytes.Buffer
@@ -111,13 +122,86 @@ const (
 	placeholderSecret  = "change_this_to_a_random_string"
 	minCookieKeyLength = 32
 	minArgon2SaltLen   = 16
+
+	defaultInactiveDays = 90
+
+	defaultExpiryNotifyBefore = 7 * 24 * time.Hour
+
+	defaultZoneIndexInterval = time.Minute
+	minZoneIndexInterval     = 5 * time.Second
+
+	defaultCacheTTL      = time.Minute
+	minCacheSyncInterval = time.Second
+
+	defaultMetricsPath = "/metrics"
+
+	defaultConfigWatchInterval = 30 * time.Second
+	minConfigWatchInterval     = time.Second
+
+	defaultPDNSClientTimeout          = 10 * time.Second
+	defaultPDNSClientRetries          = 2
+	maxPDNSClientRetries              = 10
+	defaultPDNSClientBreakerThreshold = 5
+	defaultPDNSClientBreakerCooldown  = 30 * time.Second
+	minPDNSClientBreakerCooldown      = time.Second
+
+	defaultRateLimitRate       = 10
+	defaultRateLimitBurst      = 50
+	defaultRateLimitRouteBurst = 10
+
+	defaultDNSCheckTimeout    = 3 * time.Second
+	defaultDelegationInterval = 6 * time.Hour
+
+	defaultDNSUpdateFudge = 5 * time.Minute
+
+	defaultLoginHistoryRetentionDays = 90
+
+	defaultSnapshotsKeep = 14
+
+	defaultMonitorInterval      = time.Minute
+	minMonitorInterval          = 10 * time.Second
+	defaultMonitorThreshold     = 3
+	defaultMonitorRetentionDays = 30
+
+	defaultZoneSnapshotsKeep       = 20
+	defaultZoneSnapshotsLargePatch = 10
+
+	defaultRecordTrashRetention = 30 * 24 * time.Hour
+	minRecordTrashRetention     = time.Hour
+
+	defaultUsageInterval = 5 * time.Minute
+	minUsageInterval     = time.Minute
+
+	defaultHSTSMaxAge        = 365 * 24 * 60 * 60
+	defaultReferrerPolicy    = "strict-origin-when-cross-origin"
+	defaultPermissionsPolicy = "camera=(), microphone=(), geolocation=(), payment=(), usb=()"
 )
 
+// defaultRateLimitRoutes are the route limits used when none are configured:
+// the JSON API, the dashboard, which queries PowerDNS and the database, and
+// the zone health report, which fetches every zone, the resolution check,
+// which sends a query per record and resolver, and the delegation check,
+// which queries the nameservers of every delegated subdomain.
+var defaultRateLimitRoutes = []RateLimitRoute{
+	{Path: "/api/", Rate: 2, Burst: 20},
+	{Path: "/dashboard", Rate: 0.5, Burst: 10},
+	{Path: "/zone/health", Rate: 0.1, Burst: 3},
+	{Path: "/zone/verify", Rate: 0.1, Burst: 3},
+	{Path: "/zone/delegation-check", Rate: 0.1, Burst: 3},
+}
+
+// cspDirectiveName matches Content-Security-Policy directive names.
+var cspDirectiveName = regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`)
+
+// defaultDNSCheckResolvers are the public resolvers of Cloudflare, Google and
+// Quad9.
+var defaultDNSCheckResolvers = []string{"1.1.1.1:53", "8.8.8.8:53", "9.9.9.9:53"}
+
 // validate checks the minimal required config fields.
 func validate(c *Config) error {
 	const invalidErrMessage = "invalid config"
 
-	if c.Webserver.Port == 0 {
+	if c.Webserver.Port == 0 && len(c.Webserver.Listeners) == 0 {
 		return errors.Wrap(ErrWebServerPortCanNotBeZero, invalidErrMessage)
 	}
 
@@ -141,6 +225,10 @@ func validate(c *Config) error {
 		return errors.Wrap(err, invalidErrMessage)
 	}
 
+	if err := validateSession(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
 	certSet := c.Webserver.TLSCertFile != ""
 	keySet := c.Webserver.TLSKeyFile != ""
 
@@ -152,10 +240,102 @@ func validate(c *Config) error {
 		return errors.Wrap(err, invalidErrMessage)
 	}
 
+	if err := validateListeners(&c.Webserver); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
 	if err := validateReverseProxy(c); err != nil {
 		return errors.Wrap(err, invalidErrMessage)
 	}
 
+	if err := validateSecurityHeaders(&c.Webserver.SecurityHeaders); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateAvatar(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if c.Mail.Enabled() && c.Mail.Port == 0 {
+		return errors.Wrap(ErrMailMissingPort, invalidErrMessage)
+	}
+
+	if err := validateZoneRequest(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateInactiveUsers(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateExpiry(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateZoneIndex(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if c.ZoneDeletion.GracePeriod < 0 {
+		return errors.Wrap(ErrZoneDeletionNegativeGracePeriod, invalidErrMessage)
+	}
+
+	if err := validateCache(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateMetrics(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateConfigWatch(c); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validatePDNSClient(&c.PDNSClient); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateRateLimit(&c.RateLimit); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateDNSCheck(&c.DNSCheck); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateDNSUpdate(&c.DNSUpdate); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateLoginHistory(&c.LoginHistory); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateSnapshots(&c.Snapshots); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateMonitor(&c.Monitor); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateZoneSnapshots(&c.ZoneSnapshots); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateRecordTrash(&c.RecordTrash); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := validateUsage(&c.Usage); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
+	if err := c.Log.Audit.Validate(); err != nil {
+		return errors.Wrap(err, invalidErrMessage)
+	}
+
 	return nil
 }
 
@@ -180,6 +360,18 @@ func validateACME(c *Config) error {
 		return ErrACMEMissingCacheDir
 	}
 
+	switch c.Webserver.ACMEChallenge {
+	case "":
+		c.Webserver.ACMEChallenge = ACMEChallengeHTTP01
+	case ACMEChallengeHTTP01, ACMEChallengeDNS01:
+	default:
+		return ErrACMEUnknownChallenge
+	}
+
+	if strings.HasPrefix(c.Webserver.ACMEDomain, "*.") && c.Webserver.ACMEChallenge != ACMEChallengeDNS01 {
+		return ErrACMEWildcardHTTP01
+	}
+
 	return nil
 }
 
@@ -213,41 +405,119 @@ func validateDB(c *Config) error {
 	return nil
 }
 
+// Validate checks that an enabled OIDC provider has everything needed to
+// start the login flow.
+func (o *OIDCAuth) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+
+	switch {
+	case o.ProviderURL == "":
+		return ErrOIDCMissingProviderURL
+	case o.ClientID == "":
+		return ErrOIDCMissingClientID
+	case o.ClientSecret == "":
+		return ErrOIDCMissingClientSecret
+	case o.RedirectURL == "":
+		return ErrOIDCMissingRedirectURL
+	}
+
+	return nil
+}
+
+// Validate checks that an enabled LDAP provider has a server and a search
+// base.
+func (l *LDAPAuth) Validate() error {
+	if !l.Enabled {
+		return nil
+	}
+
+	switch {
+	case l.Host == "":
+		return ErrLDAPMissingHost
+	case l.Port == 0:
+		return ErrLDAPMissingPort
+	case l.BaseDN == "":
+		return ErrLDAPMissingBaseDN
+	}
+
+	return nil
+}
+
+// validateSession normalizes webserver.session.backend to one of the
+// SessionBackend* constants.
+func validateSession(c *Config) error {
+	s := &c.Webserver.Session
+
+	switch backend := strings.ToLower(strings.TrimSpace(s.Backend)); backend {
+	case "", SessionBackendDatabase:
+		s.Backend = SessionBackendDatabase
+	case SessionBackendRedis, SessionBackendMemory:
+		s.Backend = backend
+	case "mysql", "postgres", "sqlite":
+		if backend != strings.ToLower(c.DB.GormEngine) {
+			return ErrSessionInvalidBackend
+		}
+
+		s.Backend = SessionBackendDatabase
+	default:
+		return ErrSessionInvalidBackend
+	}
+
+	if s.Backend == SessionBackendRedis && s.Redis.Database < 0 {
+		return ErrSessionRedisInvalidDatabase
+	}
+
+	if s.IdleTimeout < 0 {
+		return ErrSessionNegativeIdleTimeout
+	}
+
+	return nil
+}
+
 func validateAuth(c *Config) error {
 	if !c.Auth.LocalDB.Enabled && !c.Auth.OIDC.Enabled && !c.Auth.LDAP.Enabled {
 		return ErrNoAuthProviderEnabled
 	}
 
-	if c.Auth.OIDC.Enabled {
-		if c.Auth.OIDC.ProviderURL == "" {
-			return ErrOIDCMissingProviderURL
-		}
+	if err := c.Auth.OIDC.Validate(); err != nil {
+		return err
+	}
 
-		if c.Auth.OIDC.ClientID == "" {
-			return ErrOIDCMissingClientID
-		}
+	if err := c.Auth.LDAP.Validate(); err != nil {
+		return err
+	}
 
-		if c.Auth.OIDC.ClientSecret == "" {
-			return ErrOIDCMissingClientSecret
+	if c.Auth.LocalDB.PasswordReset {
+		if !c.Mail.Enabled() || c.Mail.From == "" {
+			return ErrPasswordResetWithoutMail
 		}
 
-		if c.Auth.OIDC.RedirectURL == "" {
-			return ErrOIDCMissingRedirectURL
+		if c.Auth.LocalDB.ResetTokenTTL <= 0 {
+			c.Auth.LocalDB.ResetTokenTTL = DefaultResetTokenTTL
 		}
 	}
 
-	if c.Auth.LDAP.Enabled {
-		if c.Auth.LDAP.Host == "" {
-			return ErrLDAPMissingHost
-		}
+	policy := &c.Auth.LocalDB.PasswordPolicy
+	if policy.MinLength < 0 || policy.MaxAge < 0 {
+		return ErrInvalidPasswordPolicy
+	}
 
-		if c.Auth.LDAP.Port == 0 {
-			return ErrLDAPMissingPort
-		}
+	if policy.MinLength == 0 {
+		policy.MinLength = DefaultPasswordMinLength
+	}
 
-		if c.Auth.LDAP.BaseDN == "" {
-			return ErrLDAPMissingBaseDN
-		}
+	if c.Auth.LocalDB.RegistrationRole == "" {
+		c.Auth.LocalDB.RegistrationRole = DefaultRegistrationRole
+	}
+
+	switch c.Auth.GroupSync.Policy {
+	case "":
+		c.Auth.GroupSync.Policy = GroupSyncPolicyFull
+	case GroupSyncPolicyFull, GroupSyncPolicyAdditive, GroupSyncPolicyApproval:
+	default:
+		return ErrGroupSyncUnknownPolicy
 	}
 
 	return nil
@@ -258,15 +528,441 @@ func (w *Webserver) TLSEnabled() bool {
 	return w.TLSCertFile != "" && w.TLSKeyFile != ""
 }
 
+// Listen returns the listeners of the web service: BindAddress:Port, serving
+// HTTPS when a certificate or ACME is configured, followed by Listeners.
+func (w *Webserver) Listen() []Listener {
+	listeners := make([]Listener, 0, len(w.Listeners)+1)
+
+	if w.Port != 0 {
+		listeners = append(listeners, Listener{
+			Address: net.JoinHostPort(w.BindAddress, strconv.Itoa(w.Port)),
+			TLS:     w.TLSEnabled() || w.ACMEEnabled,
+		})
+	}
+
+	return append(listeners, w.Listeners...)
+}
+
+// validateListeners checks the addresses of the additional listeners and that
+// HTTPS listeners have a certificate to serve.
+func validateListeners(w *Webserver) error {
+	for _, l := range w.Listeners {
+		if path, ok := strings.CutPrefix(l.Address, UnixSocketPrefix); ok {
+			if path == "" {
+				return errors.Wrap(ErrListenerInvalidAddress, l.Address)
+			}
+		} else if _, port, err := net.SplitHostPort(l.Address); err != nil || port == "" {
+			return errors.Wrap(ErrListenerInvalidAddress, l.Address)
+		}
+
+		if l.TLS && !w.TLSEnabled() && !w.ACMEEnabled {
+			return errors.Wrap(ErrListenerTLSWithoutCertificate, l.Address)
+		}
+	}
+
+	return nil
+}
+
 func validateReverseProxy(c *Config) error {
 	rp := c.Webserver.ReverseProxy
 	if !rp.Enabled {
 		return nil
 	}
 
-	if len(rp.TrustedIPs) == 0 {
+	// Connections over a Unix socket are trusted without an IP to match.
+	unixSocket := slices.ContainsFunc(c.Webserver.Listeners, func(l Listener) bool {
+		return strings.HasPrefix(l.Address, UnixSocketPrefix)
+	})
+
+	if len(rp.TrustedIPs) == 0 && !unixSocket {
 		return ErrReverseProxyMissingTrustedIPs
 	}
 
 	return nil
 }
+
+// validateSecurityHeaders fills in the defaults and checks the CSP directive
+// names, so that a typo does not silently weaken the policy.
+func validateSecurityHeaders(h *SecurityHeaders) error {
+	switch h.FrameOptions = strings.ToUpper(strings.TrimSpace(h.FrameOptions)); h.FrameOptions {
+	case "":
+		h.FrameOptions = FrameOptionsDeny
+	case FrameOptionsDeny, FrameOptionsSameOrigin:
+	default:
+		return ErrSecurityHeadersInvalidFrameOptions
+	}
+
+	switch {
+	case h.HSTSMaxAge == 0:
+		h.HSTSMaxAge = defaultHSTSMaxAge
+	case h.HSTSMaxAge < -1:
+		return ErrSecurityHeadersInvalidHSTSMaxAge
+	}
+
+	if h.ReferrerPolicy == "" {
+		h.ReferrerPolicy = defaultReferrerPolicy
+	}
+
+	if h.PermissionsPolicy == "" {
+		h.PermissionsPolicy = defaultPermissionsPolicy
+	}
+
+	for name, value := range h.CSP {
+		if !cspDirectiveName.MatchString(name) || strings.ContainsAny(value, ";,\r\n") {
+			return errors.Wrapf(ErrSecurityHeadersInvalidCSP, "%q", name)
+		}
+	}
+
+	return nil
+}
+
+func validateAvatar(c *Config) error {
+	switch strings.ToLower(c.Avatar.Provider) {
+	case "", "initials", "gravatar", "none":
+		return nil
+	default:
+		return ErrAvatarUnknownProvider
+	}
+}
+
+func validateZoneRequest(c *Config) error {
+	switch c.ZoneRequest.Kind {
+	case "":
+		c.ZoneRequest.Kind = "Native"
+	case "Native", "Master":
+	default:
+		return ErrZoneRequestUnknownKind
+	}
+
+	switch c.ZoneRequest.SOAEditAPI {
+	case "":
+		c.ZoneRequest.SOAEditAPI = "DEFAULT"
+	case "DEFAULT", "INCREASE", "EPOCH", "OFF":
+	default:
+		return ErrZoneRequestUnknownSOAEditAPI
+	}
+
+	return nil
+}
+
+func validateInactiveUsers(c *Config) error {
+	switch {
+	case c.InactiveUsers.Days < 0:
+		return ErrInactiveUsersInvalidDays
+	case c.InactiveUsers.Days == 0:
+		c.InactiveUsers.Days = defaultInactiveDays
+	}
+
+	if c.InactiveUsers.Interval != 0 && c.InactiveUsers.Interval < time.Hour {
+		return ErrInactiveUsersShortInterval
+	}
+
+	return nil
+}
+
+func validateExpiry(c *Config) error {
+	switch {
+	case c.Expiry.NotifyBefore < 0:
+		return ErrExpiryNegativeNotifyBefore
+	case c.Expiry.NotifyBefore == 0:
+		c.Expiry.NotifyBefore = defaultExpiryNotifyBefore
+	}
+
+	return nil
+}
+
+func validateZoneIndex(c *Config) error {
+	switch {
+	case c.ZoneIndex.Interval == 0:
+		c.ZoneIndex.Interval = defaultZoneIndexInterval
+	case c.ZoneIndex.Interval < minZoneIndexInterval:
+		return ErrZoneIndexShortInterval
+	}
+
+	return nil
+}
+
+func validateCache(c *Config) error {
+	switch {
+	case c.Cache.TTL < 0:
+		return ErrCacheNegativeTTL
+	case c.Cache.TTL == 0:
+		c.Cache.TTL = defaultCacheTTL
+	}
+
+	if c.Cache.SyncInterval != 0 && c.Cache.SyncInterval < minCacheSyncInterval {
+		return ErrCacheShortSyncInterval
+	}
+
+	return nil
+}
+
+func validateMetrics(c *Config) error {
+	if c.Metrics.Path == "" {
+		c.Metrics.Path = defaultMetricsPath
+	}
+
+	if !strings.HasPrefix(c.Metrics.Path, "/") {
+		return ErrMetricsInvalidPath
+	}
+
+	return nil
+}
+
+func validateConfigWatch(c *Config) error {
+	switch {
+	case c.ConfigWatch.Interval == 0:
+		c.ConfigWatch.Interval = defaultConfigWatchInterval
+	case c.ConfigWatch.Interval < minConfigWatchInterval:
+		return ErrConfigWatchShortInterval
+	}
+
+	return nil
+}
+
+// validatePDNSClient applies the PowerDNS client defaults. Retries and
+// BreakerThreshold use -1 to disable, since 0 selects the default.
+func validatePDNSClient(p *PDNSClient) error {
+	switch {
+	case p.Timeout == 0:
+		p.Timeout = defaultPDNSClientTimeout
+	case p.Timeout < 0:
+		return ErrPDNSClientNegativeTimeout
+	}
+
+	switch {
+	case p.Retries == 0:
+		p.Retries = defaultPDNSClientRetries
+	case p.Retries == -1:
+		p.Retries = 0
+	case p.Retries < 0 || p.Retries > maxPDNSClientRetries:
+		return ErrPDNSClientInvalidRetries
+	}
+
+	switch {
+	case p.BreakerThreshold == 0:
+		p.BreakerThreshold = defaultPDNSClientBreakerThreshold
+	case p.BreakerThreshold == -1:
+		p.BreakerThreshold = 0
+	case p.BreakerThreshold < 0:
+		return ErrPDNSClientInvalidBreakerThreshold
+	}
+
+	switch {
+	case p.BreakerCooldown == 0:
+		p.BreakerCooldown = defaultPDNSClientBreakerCooldown
+	case p.BreakerCooldown < minPDNSClientBreakerCooldown:
+		return ErrPDNSClientShortBreakerCooldown
+	}
+
+	return nil
+}
+
+func validateRateLimit(r *RateLimit) error {
+	switch {
+	case r.Rate == 0:
+		r.Rate = defaultRateLimitRate
+	case r.Rate < 0:
+		return ErrRateLimitNegativeRate
+	}
+
+	switch {
+	case r.Burst == 0:
+		r.Burst = defaultRateLimitBurst
+	case r.Burst < 0:
+		return ErrRateLimitNegativeBurst
+	}
+
+	if len(r.Routes) == 0 {
+		r.Routes = slices.Clone(defaultRateLimitRoutes)
+	}
+
+	for i := range r.Routes {
+		route := &r.Routes[i]
+
+		if !strings.HasPrefix(route.Path, "/") {
+			return ErrRateLimitInvalidRoutePath
+		}
+
+		if route.Rate <= 0 || route.Burst < 0 {
+			return ErrRateLimitInvalidRouteRate
+		}
+
+		if route.Burst == 0 {
+			route.Burst = defaultRateLimitRouteBurst
+		}
+	}
+
+	return nil
+}
+
+// validateDNSUpdate checks the listen address of the dynamic update gateway
+// and fills in the default fudge.
+func validateDNSUpdate(d *DNSUpdate) error {
+	switch {
+	case d.Fudge == 0:
+		d.Fudge = defaultDNSUpdateFudge
+	case d.Fudge < 0:
+		return ErrDNSUpdateNegativeFudge
+	}
+
+	if d.Listen == "" {
+		return nil
+	}
+
+	if _, port, err := net.SplitHostPort(d.Listen); err != nil || port == "" {
+		return errors.Wrapf(ErrDNSUpdateInvalidListen, "%q", d.Listen)
+	}
+
+	return nil
+}
+
+// validateDNSCheck fills in the default resolvers, timeout and delegation
+// interval and adds the default port to servers given without one.
+func validateDNSCheck(d *DNSCheck) error {
+	switch {
+	case d.Timeout == 0:
+		d.Timeout = defaultDNSCheckTimeout
+	case d.Timeout < 0:
+		return ErrDNSCheckNegativeTimeout
+	}
+
+	if d.DelegationInterval == 0 {
+		d.DelegationInterval = defaultDelegationInterval
+	}
+
+	if d.Authoritative != "" {
+		server, err := dnsclient.Server(d.Authoritative)
+		if err != nil {
+			return errors.Wrapf(ErrDNSCheckInvalidResolver, "%q", d.Authoritative)
+		}
+
+		d.Authoritative = server
+	}
+
+	if len(d.Resolvers) == 0 {
+		d.Resolvers = slices.Clone(defaultDNSCheckResolvers)
+		return nil
+	}
+
+	for i, resolver := range d.Resolvers {
+		server, err := dnsclient.Server(resolver)
+		if err != nil {
+			return errors.Wrapf(ErrDNSCheckInvalidResolver, "%q", resolver)
+		}
+
+		d.Resolvers[i] = server
+	}
+
+	return nil
+}
+
+// validateLoginHistory fills in the default retention.
+func validateLoginHistory(l *LoginHistory) error {
+	switch {
+	case l.RetentionDays == 0:
+		l.RetentionDays = defaultLoginHistoryRetentionDays
+	case l.RetentionDays < -1:
+		return ErrLoginHistoryInvalidRetention
+	}
+
+	l.CountryHeader = strings.TrimSpace(l.CountryHeader)
+
+	return nil
+}
+
+// validateSnapshots checks the snapshot storage and fills in the default
+// retention.
+func validateSnapshots(s *Snapshots) error {
+	switch {
+	case s.Directory != "" && s.S3.Bucket != "":
+		return ErrSnapshotsTwoTargets
+	case s.S3.Bucket != "" && s.S3.Endpoint == "":
+		return ErrSnapshotsS3MissingEndpoint
+	case s.Interval != 0 && !s.Enabled():
+		return ErrSnapshotsMissingTarget
+	case s.Interval < 0 || (s.Interval > 0 && s.Interval < time.Hour):
+		return ErrSnapshotsShortInterval
+	case s.Keep < -1 || s.MaxAge < 0:
+		return ErrSnapshotsInvalidRetention
+	case s.Keep == 0:
+		s.Keep = defaultSnapshotsKeep
+	}
+
+	s.S3.Prefix = strings.Trim(s.S3.Prefix, "/")
+
+	return nil
+}
+
+// validateMonitor fills in the default interval, threshold and retention.
+func validateMonitor(m *Monitor) error {
+	switch {
+	case m.Interval == 0:
+		m.Interval = defaultMonitorInterval
+	case m.Interval > 0 && m.Interval < minMonitorInterval:
+		return ErrMonitorShortInterval
+	}
+
+	switch {
+	case m.Threshold == 0:
+		m.Threshold = defaultMonitorThreshold
+	case m.Threshold < 0:
+		return ErrMonitorInvalidThreshold
+	}
+
+	switch {
+	case m.RetentionDays == 0:
+		m.RetentionDays = defaultMonitorRetentionDays
+	case m.RetentionDays < 0:
+		return ErrMonitorInvalidRetention
+	}
+
+	return nil
+}
+
+// validateZoneSnapshots fills in the default retention and patch size.
+func validateZoneSnapshots(z *ZoneSnapshots) error {
+	switch {
+	case z.Keep == 0:
+		z.Keep = defaultZoneSnapshotsKeep
+	case z.Keep < -1:
+		return ErrZoneSnapshotsInvalidKeep
+	}
+
+	switch {
+	case z.LargePatch == 0:
+		z.LargePatch = defaultZoneSnapshotsLargePatch
+	case z.LargePatch < -1:
+		return ErrZoneSnapshotsInvalidLargePatch
+	}
+
+	return nil
+}
+
+// validateRecordTrash fills in the default retention.
+func validateRecordTrash(r *RecordTrash) error {
+	switch {
+	case r.Retention == 0:
+		r.Retention = defaultRecordTrashRetention
+	case r.Retention > 0 && r.Retention < minRecordTrashRetention:
+		return ErrRecordTrashShortRetention
+	}
+
+	return nil
+}
+
+// validateUsage fills in the default interval and checks the webhook URL.
+func validateUsage(u *Usage) error {
+	switch {
+	case u.Interval == 0:
+		u.Interval = defaultUsageInterval
+	case u.Interval < minUsageInterval:
+		return ErrUsageShortInterval
+	}
+
+	if u.WebhookURL != "" && !strings.HasPrefix(u.WebhookURL, "https://") && !strings.HasPrefix(u.WebhookURL, "http://") {
+		return ErrUsageInvalidWebhookURL
+	}
+
+	return nil
+}
@@ -4,8 +4,12 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/logger"
 )
 
 func TestReadConfig(t *testing.T) {
@@ -170,6 +174,47 @@ func TestConfigValidation(t *testing.T) {
 			}(),
 			wantErr: ErrACMEMissingCacheDir,
 		},
+		{
+			name: "ACME wildcard with dns-01",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.ACMEEnabled = true
+				c.Webserver.ACMEDomain = "*.example.com"
+				c.Webserver.ACMEEmail = "admin@example.com"
+				c.Webserver.ACMECacheDir = "/tmp/acme"
+				c.Webserver.ACMEChallenge = ACMEChallengeDNS01
+
+				return c
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "ACME wildcard needs dns-01",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.ACMEEnabled = true
+				c.Webserver.ACMEDomain = "*.example.com"
+				c.Webserver.ACMEEmail = "admin@example.com"
+				c.Webserver.ACMECacheDir = "/tmp/acme"
+
+				return c
+			}(),
+			wantErr: ErrACMEWildcardHTTP01,
+		},
+		{
+			name: "ACME unknown challenge",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.ACMEEnabled = true
+				c.Webserver.ACMEDomain = "pdns.example.com"
+				c.Webserver.ACMEEmail = "admin@example.com"
+				c.Webserver.ACMECacheDir = "/tmp/acme"
+				c.Webserver.ACMEChallenge = "tls-alpn-01"
+
+				return c
+			}(),
+			wantErr: ErrACMEUnknownChallenge,
+		},
 		{
 			name: "missing port",
 			config: func() Config {
@@ -180,6 +225,48 @@ func TestConfigValidation(t *testing.T) {
 			}(),
 			wantErr: ErrWebServerPortCanNotBeZero,
 		},
+		{
+			name: "unix socket listener without port",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.Port = 0
+				c.Webserver.Listeners = []Listener{{Address: "unix:/run/gopowerdns-admin.sock"}}
+
+				return c
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "listener without port",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.Listeners = []Listener{{Address: "127.0.0.1"}}
+
+				return c
+			}(),
+			wantErr: ErrListenerInvalidAddress,
+		},
+		{
+			name: "HTTPS listener without certificate",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.Listeners = []Listener{{Address: ":8443", TLS: true}}
+
+				return c
+			}(),
+			wantErr: ErrListenerTLSWithoutCertificate,
+		},
+		{
+			name: "reverse proxy on Unix socket without trusted IPs",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.ReverseProxy.Enabled = true
+				c.Webserver.Listeners = []Listener{{Address: "unix:/run/gopowerdns-admin.sock"}}
+
+				return c
+			}(),
+			wantErr: nil,
+		},
 		{
 			name: "missing URL",
 			config: func() Config {
@@ -353,6 +440,481 @@ func TestConfigValidation(t *testing.T) {
 			}(),
 			wantErr: nil,
 		},
+		{
+			name: "gravatar avatar provider",
+			config: func() Config {
+				c := validBase()
+				c.Avatar.Provider = "gravatar"
+
+				return c
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "unknown avatar provider",
+			config: func() Config {
+				c := validBase()
+				c.Avatar.Provider = "libravatar"
+
+				return c
+			}(),
+			wantErr: ErrAvatarUnknownProvider,
+		},
+		{
+			name: "additive group sync policy",
+			config: func() Config {
+				c := validBase()
+				c.Auth.GroupSync.Policy = GroupSyncPolicyAdditive
+
+				return c
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "unknown group sync policy",
+			config: func() Config {
+				c := validBase()
+				c.Auth.GroupSync.Policy = "merge"
+
+				return c
+			}(),
+			wantErr: ErrGroupSyncUnknownPolicy,
+		},
+		{
+			name: "password reset with mail",
+			config: func() Config {
+				c := validBase()
+				c.Auth.LocalDB.PasswordReset = true
+				c.Mail = Mail{Host: "smtp.example.com", Port: 587, From: "dns@example.com"}
+
+				return c
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "password reset without mail",
+			config: func() Config {
+				c := validBase()
+				c.Auth.LocalDB.PasswordReset = true
+
+				return c
+			}(),
+			wantErr: ErrPasswordResetWithoutMail,
+		},
+		{
+			name: "password policy with negative max age",
+			config: func() Config {
+				c := validBase()
+				c.Auth.LocalDB.PasswordPolicy.MaxAge = -time.Hour
+
+				return c
+			}(),
+			wantErr: ErrInvalidPasswordPolicy,
+		},
+		{
+			name: "mail host without port",
+			config: func() Config {
+				c := validBase()
+				c.Mail.Host = "smtp.example.com"
+
+				return c
+			}(),
+			wantErr: ErrMailMissingPort,
+		},
+		{
+			name: "zone request template with Master kind",
+			config: func() Config {
+				c := validBase()
+				c.ZoneRequest.Kind = "Master"
+
+				return c
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "zone request template with Slave kind",
+			config: func() Config {
+				c := validBase()
+				c.ZoneRequest.Kind = "Slave"
+
+				return c
+			}(),
+			wantErr: ErrZoneRequestUnknownKind,
+		},
+		{
+			name: "inactive users with negative days",
+			config: func() Config {
+				c := validBase()
+				c.InactiveUsers.Days = -1
+
+				return c
+			}(),
+			wantErr: ErrInactiveUsersInvalidDays,
+		},
+		{
+			name: "inactive users with short interval",
+			config: func() Config {
+				c := validBase()
+				c.InactiveUsers.Interval = time.Minute
+
+				return c
+			}(),
+			wantErr: ErrInactiveUsersShortInterval,
+		},
+		{
+			name: "expiry with negative notify before",
+			config: func() Config {
+				c := validBase()
+				c.Expiry.NotifyBefore = -time.Hour
+
+				return c
+			}(),
+			wantErr: ErrExpiryNegativeNotifyBefore,
+		},
+		{
+			name: "zone deletion with negative grace period",
+			config: func() Config {
+				c := validBase()
+				c.ZoneDeletion.GracePeriod = -time.Hour
+
+				return c
+			}(),
+			wantErr: ErrZoneDeletionNegativeGracePeriod,
+		},
+		{
+			name: "zone index with short interval",
+			config: func() Config {
+				c := validBase()
+				c.ZoneIndex.Interval = time.Second
+
+				return c
+			}(),
+			wantErr: ErrZoneIndexShortInterval,
+		},
+		{
+			name: "cache with negative ttl",
+			config: func() Config {
+				c := validBase()
+				c.Cache.TTL = -time.Second
+
+				return c
+			}(),
+			wantErr: ErrCacheNegativeTTL,
+		},
+		{
+			name: "cache with short sync interval",
+			config: func() Config {
+				c := validBase()
+				c.Cache.SyncInterval = 100 * time.Millisecond
+
+				return c
+			}(),
+			wantErr: ErrCacheShortSyncInterval,
+		},
+		{
+			name: "metrics path without leading slash",
+			config: func() Config {
+				c := validBase()
+				c.Metrics.Path = "metrics"
+
+				return c
+			}(),
+			wantErr: ErrMetricsInvalidPath,
+		},
+		{
+			name: "config watch with short interval",
+			config: func() Config {
+				c := validBase()
+				c.ConfigWatch.Interval = time.Millisecond
+
+				return c
+			}(),
+			wantErr: ErrConfigWatchShortInterval,
+		},
+		{
+			name: "pdns client with negative timeout",
+			config: func() Config {
+				c := validBase()
+				c.PDNSClient.Timeout = -time.Second
+
+				return c
+			}(),
+			wantErr: ErrPDNSClientNegativeTimeout,
+		},
+		{
+			name: "pdns client with too many retries",
+			config: func() Config {
+				c := validBase()
+				c.PDNSClient.Retries = 11
+
+				return c
+			}(),
+			wantErr: ErrPDNSClientInvalidRetries,
+		},
+		{
+			name: "pdns client with invalid breaker threshold",
+			config: func() Config {
+				c := validBase()
+				c.PDNSClient.BreakerThreshold = -2
+
+				return c
+			}(),
+			wantErr: ErrPDNSClientInvalidBreakerThreshold,
+		},
+		{
+			name: "pdns client with short breaker cooldown",
+			config: func() Config {
+				c := validBase()
+				c.PDNSClient.BreakerCooldown = time.Millisecond
+
+				return c
+			}(),
+			wantErr: ErrPDNSClientShortBreakerCooldown,
+		},
+		{
+			name: "rate limit with negative rate",
+			config: func() Config {
+				c := validBase()
+				c.RateLimit.Rate = -1
+
+				return c
+			}(),
+			wantErr: ErrRateLimitNegativeRate,
+		},
+		{
+			name: "rate limit route without leading slash",
+			config: func() Config {
+				c := validBase()
+				c.RateLimit.Routes = []RateLimitRoute{{Path: "api/", Rate: 1}}
+
+				return c
+			}(),
+			wantErr: ErrRateLimitInvalidRoutePath,
+		},
+		{
+			name: "rate limit route without rate",
+			config: func() Config {
+				c := validBase()
+				c.RateLimit.Routes = []RateLimitRoute{{Path: "/api/"}}
+
+				return c
+			}(),
+			wantErr: ErrRateLimitInvalidRouteRate,
+		},
+		{
+			name: "negative dns check timeout",
+			config: func() Config {
+				c := validBase()
+				c.DNSCheck.Timeout = -time.Second
+
+				return c
+			}(),
+			wantErr: ErrDNSCheckNegativeTimeout,
+		},
+		{
+			name: "dns update listen without port",
+			config: func() Config {
+				c := validBase()
+				c.DNSUpdate.Listen = "192.0.2.1"
+
+				return c
+			}(),
+			wantErr: ErrDNSUpdateInvalidListen,
+		},
+		{
+			name: "unknown session backend",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.Session.Backend = "memcached"
+
+				return c
+			}(),
+			wantErr: ErrSessionInvalidBackend,
+		},
+		{
+			name: "session backend of another database engine",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.Session.Backend = "mysql"
+
+				return c
+			}(),
+			wantErr: ErrSessionInvalidBackend,
+		},
+		{
+			name: "negative redis session database",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.Session.Backend = "redis"
+				c.Webserver.Session.Redis.Database = -1
+
+				return c
+			}(),
+			wantErr: ErrSessionRedisInvalidDatabase,
+		},
+		{
+			name: "negative session idle timeout",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.Session.IdleTimeout = -time.Minute
+
+				return c
+			}(),
+			wantErr: ErrSessionNegativeIdleTimeout,
+		},
+		{
+			name: "invalid login history retention",
+			config: func() Config {
+				c := validBase()
+				c.LoginHistory.RetentionDays = -2
+
+				return c
+			}(),
+			wantErr: ErrLoginHistoryInvalidRetention,
+		},
+		{
+			name: "valid snapshots to s3",
+			config: func() Config {
+				c := validBase()
+				c.Snapshots.Interval = 24 * time.Hour
+				c.Snapshots.S3 = SnapshotsS3{Endpoint: "s3.example.com", Bucket: "backups"}
+
+				return c
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "snapshots to directory and s3",
+			config: func() Config {
+				c := validBase()
+				c.Snapshots.Directory = "/var/backups/go-pdns"
+				c.Snapshots.S3 = SnapshotsS3{Endpoint: "s3.example.com", Bucket: "backups"}
+
+				return c
+			}(),
+			wantErr: ErrSnapshotsTwoTargets,
+		},
+		{
+			name: "snapshot interval without storage",
+			config: func() Config {
+				c := validBase()
+				c.Snapshots.Interval = 24 * time.Hour
+
+				return c
+			}(),
+			wantErr: ErrSnapshotsMissingTarget,
+		},
+		{
+			name: "short snapshot interval",
+			config: func() Config {
+				c := validBase()
+				c.Snapshots.Directory = "/var/backups/go-pdns"
+				c.Snapshots.Interval = time.Minute
+
+				return c
+			}(),
+			wantErr: ErrSnapshotsShortInterval,
+		},
+		{
+			name: "audit syslog without port",
+			config: func() Config {
+				c := validBase()
+				c.Log.Audit.Syslog.Address = "siem.example.com"
+
+				return c
+			}(),
+			wantErr: logger.ErrAuditAddress,
+		},
+		{
+			name: "unknown audit format",
+			config: func() Config {
+				c := validBase()
+				c.Log.Audit.File = "/var/log/go-pdns/audit.log"
+				c.Log.Audit.Format = "leef"
+
+				return c
+			}(),
+			wantErr: logger.ErrAuditFormat,
+		},
+		{
+			name: "invalid frame options",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.SecurityHeaders.FrameOptions = "ALLOW-FROM https://example.com"
+
+				return c
+			}(),
+			wantErr: ErrSecurityHeadersInvalidFrameOptions,
+		},
+		{
+			name: "csp entry with several directives",
+			config: func() Config {
+				c := validBase()
+				c.Webserver.SecurityHeaders.CSP = map[string]string{"img-src": "'self'; script-src *"}
+
+				return c
+			}(),
+			wantErr: ErrSecurityHeadersInvalidCSP,
+		},
+		{
+			name: "invalid dns check resolver",
+			config: func() Config {
+				c := validBase()
+				c.DNSCheck.Resolvers = []string{"192.0.2.53", ":53"}
+
+				return c
+			}(),
+			wantErr: ErrDNSCheckInvalidResolver,
+		},
+		{
+			name: "short monitor interval",
+			config: func() Config {
+				c := validBase()
+				c.Monitor.Interval = time.Second
+
+				return c
+			}(),
+			wantErr: ErrMonitorShortInterval,
+		},
+		{
+			name: "disabled monitor",
+			config: func() Config {
+				c := validBase()
+				c.Monitor.Interval = -1
+
+				return c
+			}(),
+		},
+		{
+			name: "invalid zone snapshots keep",
+			config: func() Config {
+				c := validBase()
+				c.ZoneSnapshots.Keep = -2
+
+				return c
+			}(),
+			wantErr: ErrZoneSnapshotsInvalidKeep,
+		},
+		{
+			name: "short record trash retention",
+			config: func() Config {
+				c := validBase()
+				c.RecordTrash.Retention = time.Minute
+
+				return c
+			}(),
+			wantErr: ErrRecordTrashShortRetention,
+		},
+		{
+			name: "disabled zone snapshots",
+			config: func() Config {
+				c := validBase()
+				c.ZoneSnapshots.Keep = -1
+				c.ZoneSnapshots.LargePatch = -1
+
+				return c
+			}(),
+		},
 	}
 
 	for _, tt := range tests {
@@ -378,6 +940,179 @@ func TestConfigValidation(t *testing.T) {
 	}
 }
 
+func TestValidatePDNSClientDefaults(t *testing.T) {
+	var p PDNSClient
+	if err := validatePDNSClient(&p); err != nil {
+		t.Fatalf("validatePDNSClient() error = %v", err)
+	}
+
+	want := PDNSClient{
+		Timeout:          defaultPDNSClientTimeout,
+		Retries:          defaultPDNSClientRetries,
+		BreakerThreshold: defaultPDNSClientBreakerThreshold,
+		BreakerCooldown:  defaultPDNSClientBreakerCooldown,
+	}
+	if p != want {
+		t.Errorf("defaults = %+v, want %+v", p, want)
+	}
+
+	p = PDNSClient{Retries: -1, BreakerThreshold: -1}
+	if err := validatePDNSClient(&p); err != nil {
+		t.Fatalf("validatePDNSClient() error = %v", err)
+	}
+
+	if p.Retries != 0 || p.BreakerThreshold != 0 {
+		t.Errorf("-1 did not disable retries and breaker: %+v", p)
+	}
+}
+
+func TestValidateRateLimitDefaults(t *testing.T) {
+	var r RateLimit
+	if err := validateRateLimit(&r); err != nil {
+		t.Fatalf("validateRateLimit() error = %v", err)
+	}
+
+	if r.Rate != defaultRateLimitRate || r.Burst != defaultRateLimitBurst {
+		t.Errorf("defaults = %+v", r)
+	}
+
+	if len(r.Routes) != len(defaultRateLimitRoutes) {
+		t.Errorf("default routes = %+v, want %+v", r.Routes, defaultRateLimitRoutes)
+	}
+
+	r = RateLimit{Routes: []RateLimitRoute{{Path: "/zone/", Rate: 1}}}
+	if err := validateRateLimit(&r); err != nil {
+		t.Fatalf("validateRateLimit() error = %v", err)
+	}
+
+	if len(r.Routes) != 1 || r.Routes[0].Burst != defaultRateLimitRouteBurst {
+		t.Errorf("configured routes = %+v", r.Routes)
+	}
+}
+
+func TestValidateDNSCheckDefaults(t *testing.T) {
+	var d DNSCheck
+	if err := validateDNSCheck(&d); err != nil {
+		t.Fatalf("validateDNSCheck() error = %v", err)
+	}
+
+	if d.Timeout != defaultDNSCheckTimeout || !slices.Equal(d.Resolvers, defaultDNSCheckResolvers) ||
+		d.DelegationInterval != defaultDelegationInterval {
+		t.Errorf("defaults = %+v", d)
+	}
+
+	d = DNSCheck{
+		Resolvers:     []string{"192.0.2.53", "2001:db8::53", "dns.example.net:5353"},
+		Authoritative: "pdns.example.net",
+	}
+	if err := validateDNSCheck(&d); err != nil {
+		t.Fatalf("validateDNSCheck() error = %v", err)
+	}
+
+	want := []string{"192.0.2.53:53", "[2001:db8::53]:53", "dns.example.net:5353"}
+	if !slices.Equal(d.Resolvers, want) {
+		t.Errorf("resolvers = %v, want %v", d.Resolvers, want)
+	}
+
+	if d.Authoritative != "pdns.example.net:53" {
+		t.Errorf("authoritative = %q", d.Authoritative)
+	}
+}
+
+func TestValidateMonitorDefaults(t *testing.T) {
+	var m Monitor
+	if err := validateMonitor(&m); err != nil {
+		t.Fatalf("validateMonitor() error = %v", err)
+	}
+
+	want := Monitor{
+		Interval:      defaultMonitorInterval,
+		Threshold:     defaultMonitorThreshold,
+		RetentionDays: defaultMonitorRetentionDays,
+	}
+	if m != want {
+		t.Errorf("defaults = %+v, want %+v", m, want)
+	}
+
+	m = Monitor{Threshold: -1}
+	if err := validateMonitor(&m); !errors.Is(err, ErrMonitorInvalidThreshold) {
+		t.Errorf("validateMonitor() error = %v, want %v", err, ErrMonitorInvalidThreshold)
+	}
+}
+
+func TestValidateZoneSnapshotsDefaults(t *testing.T) {
+	var z ZoneSnapshots
+	if err := validateZoneSnapshots(&z); err != nil {
+		t.Fatalf("validateZoneSnapshots() error = %v", err)
+	}
+
+	want := ZoneSnapshots{Keep: defaultZoneSnapshotsKeep, LargePatch: defaultZoneSnapshotsLargePatch}
+	if z != want || !z.Enabled() {
+		t.Errorf("defaults = %+v, want %+v", z, want)
+	}
+
+	z = ZoneSnapshots{LargePatch: -3}
+	if err := validateZoneSnapshots(&z); !errors.Is(err, ErrZoneSnapshotsInvalidLargePatch) {
+		t.Errorf("validateZoneSnapshots() error = %v, want %v", err, ErrZoneSnapshotsInvalidLargePatch)
+	}
+}
+
+func TestValidateRecordTrashDefaults(t *testing.T) {
+	var r RecordTrash
+	if err := validateRecordTrash(&r); err != nil {
+		t.Fatalf("validateRecordTrash() error = %v", err)
+	}
+
+	if r.Retention != defaultRecordTrashRetention || !r.Enabled() {
+		t.Errorf("retention = %s, want %s", r.Retention, defaultRecordTrashRetention)
+	}
+
+	r = RecordTrash{Retention: -1}
+	if err := validateRecordTrash(&r); err != nil || r.Enabled() {
+		t.Errorf("validateRecordTrash() of a disabled trash = %v, enabled %v", err, r.Enabled())
+	}
+}
+
+func TestValidateUsage(t *testing.T) {
+	var u Usage
+	if err := validateUsage(&u); err != nil || u.Interval != defaultUsageInterval {
+		t.Errorf("validateUsage() = %v, interval %s, want %s", err, u.Interval, defaultUsageInterval)
+	}
+
+	u = Usage{Interval: time.Second}
+	if err := validateUsage(&u); !errors.Is(err, ErrUsageShortInterval) {
+		t.Errorf("validateUsage() of a 1s interval error = %v, want %v", err, ErrUsageShortInterval)
+	}
+
+	u = Usage{WebhookURL: "billing.example.com/hooks/dns"}
+	if err := validateUsage(&u); !errors.Is(err, ErrUsageInvalidWebhookURL) {
+		t.Errorf("validateUsage() of a URL without scheme error = %v, want %v", err, ErrUsageInvalidWebhookURL)
+	}
+}
+
+func TestValidateSessionBackend(t *testing.T) {
+	tests := map[string]string{
+		"":         SessionBackendDatabase,
+		"SQLite":   SessionBackendDatabase,
+		"database": SessionBackendDatabase,
+		" Redis ":  SessionBackendRedis,
+		"memory":   SessionBackendMemory,
+	}
+
+	for backend, want := range tests {
+		c := validBase()
+		c.Webserver.Session.Backend = backend
+
+		if err := validate(&c); err != nil {
+			t.Fatalf("validate(%q) error = %v", backend, err)
+		}
+
+		if c.Webserver.Session.Backend != want {
+			t.Errorf("backend %q = %q, want %q", backend, c.Webserver.Session.Backend, want)
+		}
+	}
+}
+
 func TestTLSEnabled(t *testing.T) {
 	if (&Webserver{}).TLSEnabled() {
 		t.Error("expected TLSEnabled=false when both fields are empty")
@@ -396,6 +1131,26 @@ func TestTLSEnabled(t *testing.T) {
 	}
 }
 
+func TestWebserverListen(t *testing.T) {
+	w := Webserver{
+		BindAddress: "::1",
+		Port:        8443,
+		TLSCertFile: "cert.pem",
+		TLSKeyFile:  "key.pem",
+		Listeners:   []Listener{{Address: "unix:/run/gopowerdns-admin.sock"}},
+	}
+
+	want := []Listener{{Address: "[::1]:8443", TLS: true}, {Address: "unix:/run/gopowerdns-admin.sock"}}
+	if got := w.Listen(); !slices.Equal(got, want) {
+		t.Errorf("Listen() = %v, want %v", got, want)
+	}
+
+	w.Port = 0
+	if got := w.Listen(); !slices.Equal(got, want[1:]) {
+		t.Errorf("Listen() without port = %v, want %v", got, want[1:])
+	}
+}
+
 func TestBrandingResolve(t *testing.T) {
 	// Empty branding falls back to the title and the bundled logo for all images.
 	got := Branding{}.Resolve("My Title")
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // DB holds the database configuration settings.
 type DB struct {
 	Extras     string
@@ -9,4 +11,7 @@ type DB struct {
 	Password   string
 	Name       string
 	GormEngine string
+	// SlowQuery is the duration from which a query is listed as slow under
+	// Admin → System Information → Database Queries (default 200ms).
+	SlowQuery time.Duration
 }
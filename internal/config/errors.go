@@ -8,8 +8,19 @@ var (
 	// ErrEmptyURL error if config webserver.URL is empty.
 	ErrEmptyURL = errors.New("toml config webserver.url can not be empty")
 
-	// ErrWebServerPortCanNotBeZero error if config webserver listening port is 0.
-	ErrWebServerPortCanNotBeZero = errors.New("toml config webserver.port listening port can not be 0")
+	// ErrWebServerPortCanNotBeZero error if config webserver listening port is 0
+	// and no other listener is configured.
+	ErrWebServerPortCanNotBeZero = errors.New(
+		"toml config webserver.port listening port can not be 0 without webserver.listeners")
+
+	// ErrListenerInvalidAddress is returned for a listener address that is
+	// neither host:port nor unix:/path.
+	ErrListenerInvalidAddress = errors.New("webserver.listeners address must be host:port or unix:/path")
+
+	// ErrListenerTLSWithoutCertificate is returned for an HTTPS listener when
+	// neither a certificate nor ACME is configured.
+	ErrListenerTLSWithoutCertificate = errors.New(
+		"webserver.listeners with tls = true need tlscertfile/tlskeyfile or acmeenabled")
 
 	// ErrPlaceholderCookieKey is returned when CookieEncryptionKey still holds
 	// the default placeholder value.
@@ -75,9 +86,159 @@ var (
 	// ErrACMEMissingCacheDir is returned when ACME is enabled but no cache dir is set.
 	ErrACMEMissingCacheDir = errors.New("webserver.acmecachedir is required when acmeenabled is true")
 
+	// ErrACMEUnknownChallenge is returned for an ACME challenge type other than
+	// http-01 or dns-01.
+	ErrACMEUnknownChallenge = errors.New("webserver.acmechallenge must be http-01 or dns-01")
+
+	// ErrACMEWildcardHTTP01 is returned for a wildcard ACME domain without the
+	// dns-01 challenge, the only one a CA accepts for wildcards.
+	ErrACMEWildcardHTTP01 = errors.New("webserver.acmedomain may only be a wildcard with acmechallenge dns-01")
+
 	// ErrReverseProxyMissingTrustedIPs is returned when reverse proxy is enabled
 	// but no trusted IP addresses are configured.
 	ErrReverseProxyMissingTrustedIPs = errors.New(
 		"webserver.reverseproxy.trustedips must not be empty when reverseproxy.enabled is true",
 	)
+
+	// ErrAvatarUnknownProvider is returned when avatar.provider is not one of
+	// the supported values.
+	ErrAvatarUnknownProvider = errors.New("avatar.provider must be one of initials, gravatar or none")
+
+	// ErrGroupSyncUnknownPolicy is returned when auth.groupsync.policy is not
+	// one of the supported values.
+	ErrGroupSyncUnknownPolicy = errors.New("auth.groupsync.policy must be one of full, additive or approval")
+
+	// ErrPasswordResetWithoutMail is returned when password reset is enabled
+	// but no SMTP server or sender address is configured.
+	ErrPasswordResetWithoutMail = errors.New("auth.localdb.password_reset requires mail.host and mail.from")
+
+	// ErrInvalidPasswordPolicy is returned when auth.localdb.password_policy
+	// has a negative min_length or max_age.
+	ErrInvalidPasswordPolicy = errors.New("auth.localdb.password_policy min_length and max_age must not be negative")
+
+	// ErrMailMissingPort is returned when mail.host is set but mail.port is zero.
+	ErrMailMissingPort = errors.New("mail.port is required when mail.host is set")
+
+	// ErrZoneRequestUnknownKind is returned when zonerequest.kind is neither
+	// Native nor Master.
+	ErrZoneRequestUnknownKind = errors.New("zonerequest.kind must be Native or Master")
+
+	// ErrZoneRequestUnknownSOAEditAPI is returned when zonerequest.soaeditapi is
+	// not a supported SOA-EDIT-API value.
+	ErrZoneRequestUnknownSOAEditAPI = errors.New(
+		"zonerequest.soaeditapi must be one of DEFAULT, INCREASE, EPOCH or OFF",
+	)
+
+	// ErrInactiveUsersInvalidDays is returned when inactiveusers.days is negative.
+	ErrInactiveUsersInvalidDays = errors.New("inactiveusers.days must not be negative")
+
+	// ErrInactiveUsersShortInterval is returned when inactiveusers.interval is
+	// set to less than one hour.
+	ErrInactiveUsersShortInterval = errors.New("inactiveusers.interval must be 0 (disabled) or at least 1h")
+
+	// ErrExpiryNegativeNotifyBefore is returned when expiry.notifybefore is negative.
+	ErrExpiryNegativeNotifyBefore = errors.New("expiry.notifybefore must not be negative")
+
+	// ErrZoneIndexShortInterval is returned when zoneindex.interval is below 5s.
+	ErrZoneIndexShortInterval = errors.New("zoneindex.interval must be 0 (default) or at least 5s")
+	// ErrZoneDeletionNegativeGracePeriod is returned when zonedeletion.graceperiod is negative.
+	ErrZoneDeletionNegativeGracePeriod = errors.New("zonedeletion.graceperiod must not be negative")
+	// ErrCacheNegativeTTL is returned when cache.ttl is negative.
+	ErrCacheNegativeTTL = errors.New("cache.ttl must not be negative")
+	// ErrCacheShortSyncInterval is returned when cache.syncinterval is below 1s.
+	ErrCacheShortSyncInterval = errors.New("cache.syncinterval must be 0 (disabled) or at least 1s")
+	// ErrConfigWatchShortInterval is returned when configwatch.interval is below 1s.
+	ErrConfigWatchShortInterval = errors.New("configwatch.interval must be 0 (default) or at least 1s")
+	// ErrPDNSClientNegativeTimeout is returned when pdnsclient.timeout is negative.
+	ErrPDNSClientNegativeTimeout = errors.New("pdnsclient.timeout must not be negative")
+	// ErrPDNSClientInvalidRetries is returned when pdnsclient.retries is not
+	// -1 (disabled), 0 (default) or between 1 and 10.
+	ErrPDNSClientInvalidRetries = errors.New("pdnsclient.retries must be -1 (disabled), 0 (default) or at most 10")
+	// ErrPDNSClientInvalidBreakerThreshold is returned when
+	// pdnsclient.breakerthreshold is below -1.
+	ErrPDNSClientInvalidBreakerThreshold = errors.New("pdnsclient.breakerthreshold must be -1 (disabled), 0 (default) or positive")
+	// ErrPDNSClientShortBreakerCooldown is returned when
+	// pdnsclient.breakercooldown is below 1s.
+	ErrPDNSClientShortBreakerCooldown = errors.New("pdnsclient.breakercooldown must be 0 (default) or at least 1s")
+	// ErrRateLimitNegativeRate is returned when ratelimit.rate is negative.
+	ErrRateLimitNegativeRate = errors.New("ratelimit.rate must not be negative")
+	// ErrRateLimitNegativeBurst is returned when ratelimit.burst is negative.
+	ErrRateLimitNegativeBurst = errors.New("ratelimit.burst must not be negative")
+	// ErrRateLimitInvalidRoutePath is returned when a ratelimit.routes path
+	// does not start with "/".
+	ErrRateLimitInvalidRoutePath = errors.New("ratelimit.routes path must start with /")
+	// ErrRateLimitInvalidRouteRate is returned when a ratelimit.routes rate is
+	// not positive or its burst is negative.
+	ErrRateLimitInvalidRouteRate = errors.New("ratelimit.routes rate must be positive and burst not negative")
+	// ErrDNSCheckNegativeTimeout is returned when dnscheck.timeout is negative.
+	ErrDNSCheckNegativeTimeout = errors.New("dnscheck.timeout must not be negative")
+	// ErrDNSCheckInvalidResolver is returned when a dnscheck.resolvers entry or
+	// dnscheck.authoritative is not a host or host:port.
+	ErrDNSCheckInvalidResolver = errors.New("dnscheck servers must be host or host:port")
+	// ErrDNSUpdateInvalidListen is returned when dnsupdate.listen is not a
+	// host:port address.
+	ErrDNSUpdateInvalidListen = errors.New("dnsupdate.listen must be host:port or :port")
+	// ErrDNSUpdateNegativeFudge is returned when dnsupdate.fudge is negative.
+	ErrDNSUpdateNegativeFudge = errors.New("dnsupdate.fudge must not be negative")
+	// ErrSessionInvalidBackend is returned when webserver.session.backend is
+	// not database, redis or memory, or names another engine than db.gormengine.
+	ErrSessionInvalidBackend = errors.New("webserver.session.backend must be database, redis or memory")
+	// ErrSessionRedisInvalidDatabase is returned when
+	// webserver.session.redis.database is negative.
+	ErrSessionRedisInvalidDatabase = errors.New("webserver.session.redis.database must not be negative")
+	// ErrSessionNegativeIdleTimeout is returned when
+	// webserver.session.idletimeout is negative.
+	ErrSessionNegativeIdleTimeout = errors.New("webserver.session.idletimeout must not be negative")
+	// ErrLoginHistoryInvalidRetention is returned when
+	// loginhistory.retentiondays is negative other than -1.
+	ErrLoginHistoryInvalidRetention = errors.New("loginhistory.retentiondays must be positive or -1")
+	// ErrSecurityHeadersInvalidFrameOptions is returned when
+	// webserver.securityheaders.frameoptions is not DENY or SAMEORIGIN.
+	ErrSecurityHeadersInvalidFrameOptions = errors.New("webserver.securityheaders.frameoptions must be DENY or SAMEORIGIN")
+	// ErrSecurityHeadersInvalidHSTSMaxAge is returned when
+	// webserver.securityheaders.hstsmaxage is negative other than -1.
+	ErrSecurityHeadersInvalidHSTSMaxAge = errors.New("webserver.securityheaders.hstsmaxage must be positive or -1")
+	// ErrSecurityHeadersInvalidCSP is returned for a
+	// webserver.securityheaders.csp entry that is not a single directive.
+	ErrSecurityHeadersInvalidCSP = errors.New("webserver.securityheaders.csp entries must be single directives")
+	// ErrMetricsInvalidPath is returned when metrics.path does not start with "/".
+	ErrMetricsInvalidPath = errors.New("metrics.path must start with /")
+	// ErrSnapshotsTwoTargets is returned when both snapshots.directory and
+	// snapshots.s3.bucket are set.
+	ErrSnapshotsTwoTargets = errors.New("set either snapshots.directory or snapshots.s3.bucket, not both")
+	// ErrSnapshotsS3MissingEndpoint is returned when snapshots.s3.bucket is set
+	// without snapshots.s3.endpoint.
+	ErrSnapshotsS3MissingEndpoint = errors.New("snapshots.s3.endpoint is required with snapshots.s3.bucket")
+	// ErrSnapshotsMissingTarget is returned when snapshots.interval is set
+	// without a snapshot storage.
+	ErrSnapshotsMissingTarget = errors.New("snapshots.interval requires snapshots.directory or snapshots.s3.bucket")
+	// ErrSnapshotsShortInterval is returned when snapshots.interval is negative
+	// or shorter than 1h.
+	ErrSnapshotsShortInterval = errors.New("snapshots.interval must be 0 (disabled) or at least 1h")
+	// ErrSnapshotsInvalidRetention is returned when snapshots.keep is negative
+	// other than -1 or snapshots.maxage is negative.
+	ErrSnapshotsInvalidRetention = errors.New("snapshots.keep must be positive or -1 and snapshots.maxage not negative")
+	// ErrMonitorShortInterval is returned when monitor.interval is shorter
+	// than 10s.
+	ErrMonitorShortInterval = errors.New("monitor.interval must be negative (disabled) or at least 10s")
+	// ErrMonitorInvalidThreshold is returned when monitor.threshold is negative.
+	ErrMonitorInvalidThreshold = errors.New("monitor.threshold must be positive")
+	// ErrMonitorInvalidRetention is returned when monitor.retentiondays is
+	// negative.
+	ErrMonitorInvalidRetention = errors.New("monitor.retentiondays must be positive")
+	// ErrZoneSnapshotsInvalidKeep is returned when zonesnapshots.keep is
+	// negative other than -1.
+	ErrZoneSnapshotsInvalidKeep = errors.New("zonesnapshots.keep must be positive or -1")
+	// ErrZoneSnapshotsInvalidLargePatch is returned when
+	// zonesnapshots.largepatch is negative other than -1.
+	ErrZoneSnapshotsInvalidLargePatch = errors.New("zonesnapshots.largepatch must be positive or -1")
+	// ErrRecordTrashShortRetention is returned when recordtrash.retention is
+	// shorter than 1h.
+	ErrRecordTrashShortRetention = errors.New("recordtrash.retention must be negative (disabled) or at least 1h")
+	// ErrUsageShortInterval is returned when usage.interval is shorter than
+	// 1m.
+	ErrUsageShortInterval = errors.New("usage.interval must be at least 1m")
+	// ErrUsageInvalidWebhookURL is returned when usage.webhookurl is not an
+	// http or https URL.
+	ErrUsageInvalidWebhookURL = errors.New("usage.webhookurl must be an http:// or https:// URL")
 )
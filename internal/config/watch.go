@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fileState identifies a version of a config file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch checks the config files read from path every interval until ctx is
+// canceled and calls onChange with the re-read config after they changed. A
+// config that fails to read or validate is logged and skipped; the previous
+// one stays in effect.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func(Config)) {
+	mainConfig, overlayFile := configFiles(path)
+
+	files := []string{mainConfig}
+	if overlayFile != "" {
+		files = append(files, overlayFile)
+	}
+
+	last := statFiles(files)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := statFiles(files)
+			if equalStates(current, last) {
+				continue
+			}
+
+			last = current
+
+			c, err := ReadConfig(path)
+			if err != nil {
+				log.Error().Err(err).Str("path", path).Msg("config: changed config is invalid; keeping the current settings")
+				continue
+			}
+
+			log.Info().Str("path", path).Msg("config: reloaded changed config files")
+			onChange(c)
+		}
+	}
+}
+
+func statFiles(files []string) []fileState {
+	states := make([]fileState, len(files))
+
+	for i, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			states[i] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+
+	return states
+}
+
+func equalStates(a, b []fileState) bool {
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+
+	return true
+}
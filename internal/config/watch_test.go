@@ -0,0 +1,53 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchReloadsChangedConfig(t *testing.T) {
+	projectRoot, err := filepath.Abs("../../")
+	if err != nil {
+		t.Fatalf("failed to get project root: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectRoot, "etc", "main.toml"))
+	if err != nil {
+		t.Fatalf("failed to read main.toml: %v", err)
+	}
+
+	dir := t.TempDir()
+	mainFile := filepath.Join(dir, "main.toml")
+
+	if err = os.WriteFile(mainFile, data, 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan Config, 1)
+
+	go Watch(ctx, dir+"/", 10*time.Millisecond, func(c Config) { changed <- c })
+
+	// Let the watcher record the initial state before changing the file.
+	time.Sleep(50 * time.Millisecond)
+
+	updated := strings.Replace(string(data), `Level = "info"`, `Level = "debug"`, 1)
+	if err = os.WriteFile(mainFile, []byte(updated), 0o600); err != nil {
+		t.Fatalf("failed to rewrite config: %v", err)
+	}
+
+	select {
+	case c := <-changed:
+		if got := c.RuntimeSettings().LogLevel; got != "debug" {
+			t.Errorf("LogLevel = %q, want %q", got, "debug")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch did not report the changed config")
+	}
+}
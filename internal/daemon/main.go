@@ -1,10 +1,6 @@
 package daemon
 
 import (
-	"fmt"
-
-	sessionmysql "github.com/gofiber/storage/mysql/v2"
-	sessionpostgres "github.com/gofiber/storage/postgres/v3"
 	"github.com/rs/zerolog/log"
 	gormsqlite "github.com/glebarez/sqlite"
 	gormmysql "gorm.io/driver/mysql"
@@ -14,8 +10,10 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/dsn"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/querylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/logger"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/loginhistory"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
-	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/storage/sqlitestorage"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
 )
@@ -26,11 +24,9 @@ type Daemon struct {
 	webService web.Service
 }
 
-// Start starts the Daemon's web service on the configured port.
+// Start starts the Daemon's web service on the configured listeners.
 func (d *Daemon) Start() error {
-	addr := fmt.Sprintf(":%d", d.cfg.Webserver.Port)
-
-	return d.webService.Start(addr)
+	return d.webService.Start(d.cfg.Webserver.Listen())
 }
 
 // New creates a new Daemon instance with the provided configuration.
@@ -40,31 +36,23 @@ func New(cfg *config.Config) *Daemon {
 		return nil
 	}
 
-	db, sessionStorage := openDB(cfg)
+	// Sample debug messages as configured under [log] or Settings ->
+	// Application; installed before any goroutine logs.
+	logger.InstallSampler()
 
-	if err := db.AutoMigrate(
-		&models.User{},
-		&models.Setting{},
-		&models.Role{},
-		&models.Permission{},
-		&models.RolePermission{},
-		&models.Group{},
-		&models.GroupMapping{},
-		&models.UserGroup{},
-		&models.ActivityLog{},
-		&models.Tag{},
-		&models.ZoneTag{},
-		&models.UserTag{},
-		&models.GroupTag{},
-	); err != nil {
-		log.Fatal().Err(err).Msg("failed to migrate database")
-	}
+	db := OpenDB(cfg)
 
 	seed(cfg, db)
 
-	session.Init(sessionStorage)
+	session.Init(openSessionStorage(cfg))
 
 	// Initialize PowerDNS client
+	powerdns.Configure(cfg.PDNSClient)
+	powerdns.ForceReadOnly(cfg.PDNS.ReadOnly)
+
+	// Retention and country header of the login history
+	loginhistory.Configure(cfg.LoginHistory)
+
 	if err := powerdns.Open(db); err != nil {
 		log.Warn().Err(err).Msg("failed to initialize PowerDNS client - server configuration features will be unavailable")
 		log.Info().Msg("PowerDNS client will be available after configuring server settings")
@@ -86,20 +74,75 @@ func New(cfg *config.Config) *Daemon {
 	}
 }
 
-// openDB opens the GORM database and session storage based on cfg.DB.GormEngine.
-// Supported values: "mysql" (default), "postgres".
-func openDB(cfg *config.Config) (*gorm.DB, session.StorageBackend) {
-	driver := cfg.DB.GormEngine
-	if driver == "" {
-		driver = "mysql"
+// OpenDB opens the application database configured in cfg and migrates its
+// schema. It exits the process when either fails.
+func OpenDB(cfg *config.Config) *gorm.DB {
+	db := openDB(cfg)
+
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.PasswordResetToken{},
+		&models.APIKey{},
+		&models.Setting{},
+		&models.Role{},
+		&models.Permission{},
+		&models.RolePermission{},
+		&models.Group{},
+		&models.GroupMapping{},
+		&models.UserGroup{},
+		&models.PendingGroupRemoval{},
+		&models.ActivityLog{},
+		&models.LoginEvent{},
+		&models.Tag{},
+		&models.ZoneTag{},
+		&models.ZoneAccessOption{},
+		&models.ZoneRequest{},
+		&models.ChangeRequest{},
+		&models.ZoneClaim{},
+		&models.ZoneOwnership{},
+		&models.RecordSchedule{},
+		&models.ZoneDeletion{},
+		&models.ZoneSnapshot{},
+		&models.DeletedRRset{},
+		&models.UserTag{},
+		&models.GroupTag{},
+		&models.GroupZone{},
+		&models.BusEvent{},
+		&models.ZoneFavorite{},
+		&models.ZoneVisit{},
+		&models.DashboardView{},
+		&models.Integration{},
+		&models.TSIGKey{},
+		&models.NameserverSet{},
+		&models.ZoneNameserverSet{},
+		&models.Tenant{},
+		&models.ZoneTenant{},
+		&models.TenantUsage{},
+		&models.UsageMonth{},
+		&models.DHCPImport{},
+		&models.DHCPImportRecord{},
+		&models.DelegationCheck{},
+		&models.PDNSView{},
+		&models.Label{},
+		&models.Expiry{},
+		&models.ServerCheck{},
+	); err != nil {
+		log.Fatal().Err(err).Msg("failed to migrate database")
 	}
 
+	return db
+}
+
+// openDB opens the GORM database based on cfg.DB.GormEngine.
+// Supported values: "mysql" (default), "postgres", "sqlite".
+func openDB(cfg *config.Config) *gorm.DB {
 	var (
-		db             *gorm.DB
-		err            error
-		sessionStorage session.StorageBackend
+		db  *gorm.DB
+		err error
 	)
 
+	driver := dbDriver(cfg)
+
 	switch driver {
 	case "sqlite":
 		log.Info().Msg("using SQLite database driver")
@@ -109,11 +152,6 @@ func openDB(cfg *config.Config) (*gorm.DB, session.StorageBackend) {
 			log.Fatal().Err(err).Msg("failed to connect database")
 		}
 
-		sessionStorage, err = sqlitestorage.New(dsn.CreateSQLite(cfg) + "-sessions.db")
-		if err != nil {
-			log.Fatal().Err(err).Msg("failed to open session storage")
-		}
-
 	case "postgres":
 		log.Info().Msg("using PostgreSQL database driver")
 
@@ -122,11 +160,6 @@ func openDB(cfg *config.Config) (*gorm.DB, session.StorageBackend) {
 			log.Fatal().Err(err).Msg("failed to connect database")
 		}
 
-		sessionStorage = sessionpostgres.New(sessionpostgres.Config{
-			ConnectionURI: dsn.CreatePostgresURL(cfg),
-			Table:         "sessions",
-		})
-
 	default:
 		if driver != "mysql" {
 			log.Warn().Str("driver", driver).Msg("unknown database driver, falling back to mysql")
@@ -138,12 +171,23 @@ func openDB(cfg *config.Config) (*gorm.DB, session.StorageBackend) {
 		if err != nil {
 			log.Fatal().Err(err).Msg("failed to connect database")
 		}
+	}
+
+	// Measure every query for the metrics and the slow query list.
+	querylog.Default.SetThreshold(cfg.DB.SlowQuery)
+
+	if err = db.Use(querylog.Default); err != nil {
+		log.Error().Err(err).Msg("failed to register the query log")
+	}
+
+	return db
+}
 
-		sessionStorage = sessionmysql.New(sessionmysql.Config{
-			ConnectionURI: dsn.Create(cfg),
-			Table:         "sessions",
-		})
+// dbDriver returns cfg.DB.GormEngine, "mysql" when unset.
+func dbDriver(cfg *config.Config) string {
+	if cfg.DB.GormEngine == "" {
+		return "mysql"
 	}
 
-	return db, sessionStorage
+	return cfg.DB.GormEngine
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/pdnsserver"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
@@ -24,8 +25,11 @@ func seed(cfg *config.Config, db *gorm.DB) {
 	// Seed role-permission mappings
 	seedRolePermissions(db)
 
-	// Seed default admin user
-	seedUsers(db)
+	// Seed the default admin user in demo mode; other installations create
+	// it in the setup wizard.
+	if cfg.Demo {
+		seedUsers(db)
+	}
 
 	// Seed zone record settings from config (only if not already set)
 	seedZoneRecordSettings(cfg, db)
@@ -60,6 +64,11 @@ func seedRoles(db *gorm.DB) {
 			Description: "Read-only access to zones and dashboards",
 			IsSystem:    true,
 		},
+		{
+			Name:        auth.RoleTenantAdmin,
+			Description: "Manages the users, groups, zones and integrations of a tenant",
+			IsSystem:    true,
+		},
 	}
 
 	for _, role := range roles {
@@ -118,6 +127,78 @@ func seedPermissions(db *gorm.DB) {
 			Action:      "list",
 			Description: "List DNS zones",
 		},
+		{
+			Name:        "zone.request",
+			Resource:    "zone",
+			Action:      "request",
+			Description: "Request new DNS zones for administrator approval",
+		},
+		{
+			Name:        "zone.claim",
+			Resource:    "zone",
+			Action:      "claim",
+			Description: "Claim existing DNS zones by proving control of the domain",
+		},
+		{
+			Name:        "zone.metadata",
+			Resource:    "zone",
+			Action:      "metadata",
+			Description: "Manage zone metadata (ALSO-NOTIFY, AXFR access, SOA-EDIT)",
+		},
+		{
+			Name:        "zone.lua",
+			Resource:    "zone",
+			Action:      "lua",
+			Description: "Edit LUA records, which run code on the PowerDNS server",
+		},
+		{
+			Name:        "zone.ttl_override",
+			Resource:    "zone",
+			Action:      "ttl_override",
+			Description: "Set record TTLs outside the TTL policies",
+		},
+		{
+			Name:        "zone.policy_override",
+			Resource:    "zone",
+			Action:      "policy_override",
+			Description: "Create, change or delete records that the name policies block",
+		},
+		{
+			Name:        "zone.propose",
+			Resource:    "zone",
+			Action:      "propose",
+			Description: "Propose record changes that are applied once approved",
+		},
+		{
+			Name:        "zone.approve",
+			Resource:    "zone",
+			Action:      "approve",
+			Description: "Approve or reject proposed record changes",
+		},
+
+		// Tools permissions
+		{
+			Name:        "tools.query",
+			Resource:    "tools",
+			Action:      "query",
+			Description: "Send DNS queries to PowerDNS or any other server with the query tester",
+		},
+
+		// Profile permissions
+		{
+			Name:        "profile.api_keys",
+			Resource:    "profile",
+			Action:      "api_keys",
+			Description: "Issue personal API keys",
+		},
+
+		// Tenant permissions
+		{
+			Name:        "tenant.usage",
+			Resource:    "tenant",
+			Action:      "usage",
+			Description: "View the quotas and usage of the own tenant",
+		},
 
 		// Admin permissions
 		{
@@ -204,6 +285,72 @@ func seedPermissions(db *gorm.DB) {
 			Action:      "branding",
 			Description: "Manage branding (product name, logo, favicon)",
 		},
+		{
+			Name:        "admin.zone.requests",
+			Resource:    "admin",
+			Action:      "zone.requests",
+			Description: "Approve or reject zone requests",
+		},
+		{
+			Name:        "admin.zone.claims",
+			Resource:    "admin",
+			Action:      "zone.claims",
+			Description: "Approve or reject zone ownership claims",
+		},
+		{
+			Name:        "admin.system",
+			Resource:    "admin",
+			Action:      "system",
+			Description: "View system information (versions, runtime, configuration summary)",
+		},
+		{
+			Name:        "admin.maintenance",
+			Resource:    "admin",
+			Action:      "maintenance",
+			Description: "Toggle maintenance mode and keep access while it is enabled",
+		},
+		{
+			Name:        "admin.backup",
+			Resource:    "admin",
+			Action:      "backup",
+			Description: "Export and restore the application database",
+		},
+		{
+			Name:        "admin.snapshots",
+			Resource:    "admin",
+			Action:      "snapshots",
+			Description: "Browse snapshots and restore zones or application data from them",
+		},
+		{
+			Name:        "admin.integrations",
+			Resource:    "admin",
+			Action:      "integrations",
+			Description: "Manage Slack, Mattermost and Teams notification integrations",
+		},
+		{
+			Name:        "admin.tsig_keys",
+			Resource:    "admin",
+			Action:      "tsig_keys",
+			Description: "Manage the TSIG keys of RFC 2136 dynamic updates",
+		},
+		{
+			Name:        "admin.dhcp_imports",
+			Resource:    "admin",
+			Action:      "dhcp_imports",
+			Description: "Manage the imports of DHCP leases into A, AAAA and PTR records",
+		},
+		{
+			Name:        "admin.nameserver_sets",
+			Resource:    "admin",
+			Action:      "nameserver_sets",
+			Description: "Manage nameserver sets and migrate zones between them",
+		},
+		{
+			Name:        "admin.tenants",
+			Resource:    "admin",
+			Action:      "tenants",
+			Description: "Manage tenants and assign zones to them",
+		},
 	}
 
 	for _, perm := range permissions {
@@ -223,10 +370,11 @@ func seedPermissions(db *gorm.DB) {
 // seedRolePermissions creates role-permission mappings.
 func seedRolePermissions(db *gorm.DB) {
 	// Get roles
-	var adminRole, userRole, viewerRole models.Role
+	var adminRole, userRole, viewerRole, tenantAdminRole models.Role
 	db.Where(models.WhereNameIs, "admin").First(&adminRole)
 	db.Where(models.WhereNameIs, "user").First(&userRole)
 	db.Where(models.WhereNameIs, "viewer").First(&viewerRole)
+	db.Where(models.WhereNameIs, auth.RoleTenantAdmin).First(&tenantAdminRole)
 
 	// Get all permissions
 	var allPermissions []models.Permission
@@ -254,6 +402,9 @@ func seedRolePermissions(db *gorm.DB) {
 		"zone.update",
 		"zone.delete",
 		"zone.list",
+		"zone.request",
+		"zone.claim",
+		"profile.api_keys",
 		"admin.activity.log",
 	}
 	assignPermissionsToRole(db, userRole.ID, userPermissions)
@@ -263,11 +414,21 @@ func seedRolePermissions(db *gorm.DB) {
 		"dashboard.view",
 		"zone.read",
 		"zone.list",
+		"zone.request",
 		"admin.server.config",
 		"admin.activity.log",
 	}
 	assignPermissionsToRole(db, viewerRole.ID, viewerPermissions)
 
+	// Tenant admin gets every permission users of a tenant can hold
+	var tenantPermissions []string
+	for _, perm := range allPermissions {
+		if auth.TenantPermission(perm.Name) {
+			tenantPermissions = append(tenantPermissions, perm.Name)
+		}
+	}
+	assignPermissionsToRole(db, tenantAdminRole.ID, tenantPermissions)
+
 	log.Info().Msg("Role-permission mappings created")
 }
 
@@ -378,7 +539,8 @@ var defaultRecordSettings = config.Record{
 	"LUA": {
 		Forward: false, Reverse: false,
 		Description: "LUA Record",
-		Help:        "Lua record (PowerDNS-specific).",
+		Help: `Format: type "lua code" (e.g., A "ifportup(443, {'192.0.2.1', '192.0.2.2'})").` +
+			" Requires enable-lua-records in PowerDNS.",
 	},
 	"MX": {
 		Forward: true, Reverse: false,
@@ -616,7 +778,7 @@ func seedPDNSServer(cfg *config.Config, db *gorm.DB) {
 	log.Info().Str("url", cfg.PDNS.APIServerURL).Msg("seeded PowerDNS server settings from config")
 }
 
-// seedUsers creates the default admin user.
+// seedUsers creates the default admin user of the demo.
 func seedUsers(db *gorm.DB) {
 	var count int64
 	db.Model(&models.User{}).Count(&count)
@@ -626,22 +788,24 @@ func seedUsers(db *gorm.DB) {
 		var adminRole models.Role
 		db.Where(models.WhereNameIs, "admin").First(&adminRole)
 
-		// Create default admin user
+		// Create default admin user. The well-known password has to be
+		// changed at the first login, which demo mode skips since it keeps
+		// passwords fixed.
 		user := &models.User{
-			Username:    "admin",
-			Email:       "admin@localhost",
-			Password:    models.HashPassword("changeme"),
-			Active:      true,
-			RoleID:      adminRole.ID,
-			AuthSource:  models.AuthSourceLocal,
-			DisplayName: "System Administrator",
+			Username:           "admin",
+			Email:              "admin@localhost",
+			Password:           models.HashPassword("changeme"),
+			MustChangePassword: true,
+			Active:             true,
+			RoleID:             adminRole.ID,
+			AuthSource:         models.AuthSourceLocal,
+			DisplayName:        "System Administrator",
 		}
 
 		if err := db.Create(user).Error; err != nil {
 			log.Error().Err(err).Msg("Failed to create default admin user")
 		} else {
-			log.Info().Msg("Created default admin user (username: admin, password: changeme)")
-			log.Warn().Msg("SECURITY WARNING: Please change the default admin password immediately!")
+			log.Info().Msg("Created demo admin user (username: admin, password: changeme)")
 		}
 	}
 }
@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"time"
+
+	sessionmysql "github.com/gofiber/storage/mysql/v2"
+	sessionpostgres "github.com/gofiber/storage/postgres/v3"
+	sessionredis "github.com/gofiber/storage/redis/v3"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/dsn"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/storage/memorystorage"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/storage/sqlitestorage"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
+)
+
+// sessionTable is the table holding sessions in the application database.
+const sessionTable = "sessions"
+
+// openSessionStorage opens the session storage selected by
+// cfg.Webserver.Session.Backend.
+func openSessionStorage(cfg *config.Config) session.StorageBackend {
+	switch cfg.Webserver.Session.Backend {
+	case config.SessionBackendRedis:
+		r := cfg.Webserver.Session.Redis
+
+		redisCfg := sessionredis.Config{
+			URL:      r.URL,
+			Host:     r.Host,
+			Port:     r.Port,
+			Username: r.Username,
+			Password: r.Password,
+			Database: r.Database,
+		}
+		if r.TLS {
+			redisCfg.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+
+		log.Info().Msg("storing sessions in Redis")
+
+		// New connects right away and panics when Redis is unreachable.
+		return redisSessionStorage{sessionredis.New(redisCfg)}
+
+	case config.SessionBackendMemory:
+		log.Warn().Msg("storing sessions in memory: they are lost on restart and not shared between replicas")
+
+		return memorystorage.New()
+	}
+
+	switch dbDriver(cfg) {
+	case "sqlite":
+		storage, err := sqlitestorage.New(dsn.CreateSQLite(cfg) + "-sessions.db")
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to open session storage")
+		}
+
+		return storage
+
+	case "postgres":
+		return postgresSessionStorage{sessionpostgres.New(sessionpostgres.Config{
+			ConnectionURI: dsn.CreatePostgresURL(cfg),
+			Table:         sessionTable,
+		})}
+
+	default:
+		return mysqlSessionStorage{sessionmysql.New(sessionmysql.Config{
+			ConnectionURI: dsn.Create(cfg),
+			Table:         sessionTable,
+		})}
+	}
+}
+
+// redisSessionStorage adds session.GetDeleter to the Redis storage using
+// GETDEL.
+type redisSessionStorage struct {
+	*sessionredis.Storage
+}
+
+// GetDelete removes the entry for key and returns its value, or nil if it is
+// missing.
+func (s redisSessionStorage) GetDelete(key string) ([]byte, error) {
+	val, err := s.Conn().GetDel(context.Background(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	return val, err
+}
+
+// postgresSessionStorage adds session.GetDeleter to the PostgreSQL storage
+// using DELETE ... RETURNING.
+type postgresSessionStorage struct {
+	*sessionpostgres.Storage
+}
+
+// GetDelete removes the entry for key and returns its value, or nil if it is
+// missing or expired.
+func (s postgresSessionStorage) GetDelete(key string) ([]byte, error) {
+	var (
+		val    []byte
+		expiry int64
+	)
+
+	// The storage creates its table in the public schema.
+	table := pgx.Identifier{"public", sessionTable}.Sanitize()
+
+	err := s.Conn().QueryRow(context.Background(),
+		"DELETE FROM "+table+" WHERE k = $1 RETURNING v, e", key).Scan(&val, &expiry)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if expiry != 0 && expiry <= time.Now().Unix() {
+		return nil, nil
+	}
+
+	return val, nil
+}
+
+// mysqlSessionStorage adds session.GetDeleter to the MySQL storage. MySQL has
+// no DELETE ... RETURNING, so the entry is read first and only returned by
+// the caller whose delete removed the row.
+type mysqlSessionStorage struct {
+	*sessionmysql.Storage
+}
+
+// GetDelete removes the entry for key and returns its value, or nil if it is
+// missing, expired or removed concurrently.
+func (s mysqlSessionStorage) GetDelete(key string) ([]byte, error) {
+	val, err := s.Get(key)
+	if err != nil || val == nil {
+		return nil, err
+	}
+
+	var res sql.Result
+
+	res, err = s.Conn().ExecContext(context.Background(), "DELETE FROM "+sessionTable+" WHERE k = ?", key)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return nil, err
+	}
+
+	return val, nil
+}
@@ -0,0 +1,212 @@
+// Package appsettings provides the runtime application settings: the
+// config.RuntimeSettings of the TOML config with the overrides an
+// administrator saved in the database applied on top. A Store keeps the
+// resolved settings in memory; it is updated when the overrides are saved on
+// any replica and when the config files change.
+package appsettings
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+// SettingKey is the key under which the overrides are stored.
+const SettingKey = "app"
+
+// Overrides holds the persisted overrides. A nil field uses the value from the
+// config files.
+type Overrides struct {
+	LogLevel           *string        `json:"log_level,omitempty"`
+	LogSampling        *uint32        `json:"log_sampling,omitempty"`
+	SessionExpiry      *time.Duration `json:"session_expiry,omitempty"`
+	SessionIdleTimeout *time.Duration `json:"session_idle_timeout,omitempty"`
+	LocalLogin         *bool          `json:"local_login,omitempty"`
+	PasswordReset      *bool          `json:"password_reset,omitempty"`
+	ResetTokenTTL      *time.Duration `json:"reset_token_ttl,omitempty"`
+}
+
+// Apply returns base with the overrides applied.
+func (o *Overrides) Apply(base config.RuntimeSettings) config.RuntimeSettings {
+	out := base
+
+	if o.LogLevel != nil {
+		out.LogLevel = *o.LogLevel
+	}
+
+	if o.LogSampling != nil {
+		out.LogSampling = *o.LogSampling
+	}
+
+	if o.SessionExpiry != nil {
+		out.SessionExpiry = *o.SessionExpiry
+	}
+
+	if o.SessionIdleTimeout != nil {
+		out.SessionIdleTimeout = *o.SessionIdleTimeout
+	}
+
+	if o.LocalLogin != nil {
+		out.LocalLogin = *o.LocalLogin
+	}
+
+	if o.PasswordReset != nil {
+		out.PasswordReset = *o.PasswordReset
+	}
+
+	if o.ResetTokenTTL != nil {
+		out.ResetTokenTTL = *o.ResetTokenTTL
+	}
+
+	return out
+}
+
+// Load reads the overrides from the database. It returns
+// setting.ErrSettingNotFound when nothing has been saved yet.
+func Load(db *gorm.DB) (*Overrides, error) {
+	var out Overrides
+	if err := setting.GetJSON(db, SettingKey, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Save persists the overrides to the database (upsert).
+func (o *Overrides) Save(db *gorm.DB) error {
+	return setting.SetJSON(db, SettingKey, o)
+}
+
+// Store caches the resolved runtime settings. It is safe for concurrent use.
+type Store struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	base      config.RuntimeSettings
+	overrides Overrides
+	hooks     []func(config.RuntimeSettings)
+
+	current atomic.Pointer[config.RuntimeSettings]
+}
+
+// defaultStore is the store returned by Current.
+var defaultStore atomic.Pointer[Store]
+
+// NewStore creates a Store for base, the runtime settings of the config
+// files, and loads the overrides from the database. A non-nil error indicates
+// the initial load failed; the returned Store is still usable and uses base.
+func NewStore(db *gorm.DB, base config.RuntimeSettings) (*Store, error) {
+	st := &Store{db: db, base: base}
+	st.current.Store(&base)
+
+	err := st.Reload()
+
+	return st, err
+}
+
+// SetDefault makes st the store read by Current.
+func SetDefault(st *Store) {
+	defaultStore.Store(st)
+}
+
+// Current returns the runtime settings of the default store, or those of cfg
+// when no store is installed (e.g. in tests).
+func Current(cfg *config.Config) config.RuntimeSettings {
+	if st := defaultStore.Load(); st != nil {
+		return st.Settings()
+	}
+
+	return cfg.RuntimeSettings()
+}
+
+// Settings returns the resolved runtime settings.
+func (st *Store) Settings() config.RuntimeSettings {
+	return *st.current.Load()
+}
+
+// Base returns the runtime settings of the config files.
+func (st *Store) Base() config.RuntimeSettings {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.base
+}
+
+// Overrides returns a copy of the cached overrides.
+func (st *Store) Overrides() Overrides {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.overrides
+}
+
+// OnChange registers fn to be called with the resolved settings whenever they
+// are recomputed.
+func (st *Store) OnChange(fn func(config.RuntimeSettings)) {
+	st.mu.Lock()
+	st.hooks = append(st.hooks, fn)
+	st.mu.Unlock()
+}
+
+// Reload refreshes the overrides from the database. A missing setting is not
+// an error: no overrides apply.
+func (st *Store) Reload() error {
+	o, err := Load(st.db)
+	if err != nil {
+		if !errors.Is(err, setting.ErrSettingNotFound) {
+			return err
+		}
+
+		o = &Overrides{}
+	}
+
+	st.mu.Lock()
+	st.overrides = *o
+	st.mu.Unlock()
+
+	st.resolve()
+
+	return nil
+}
+
+// Follow reloads the overrides whenever they are written, locally or on
+// another replica.
+func (st *Store) Follow(bus *eventbus.Bus) {
+	setting.OnChange(bus, SettingKey, func(string) {
+		if err := st.Reload(); err != nil {
+			log.Warn().Err(err).Msg("appsettings: failed to reload overrides")
+		}
+	})
+}
+
+// SetBase replaces the runtime settings of the config files, e.g. after they
+// changed on disk.
+func (st *Store) SetBase(base config.RuntimeSettings) {
+	st.mu.Lock()
+	st.base = base
+	st.mu.Unlock()
+
+	st.resolve()
+}
+
+func (st *Store) resolve() {
+	st.mu.Lock()
+	resolved := st.overrides.Apply(st.base)
+	hooks := slices.Clone(st.hooks)
+	st.mu.Unlock()
+
+	st.current.Store(&resolved)
+
+	for _, fn := range hooks {
+		fn(resolved)
+	}
+}
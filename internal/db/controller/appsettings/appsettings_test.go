@@ -0,0 +1,112 @@
+package appsettings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Setting{}))
+
+	return db
+}
+
+func baseSettings() config.RuntimeSettings {
+	return config.RuntimeSettings{
+		LogLevel:      "info",
+		SessionExpiry: 12 * time.Hour,
+		LocalLogin:    true,
+		PasswordReset: false,
+		ResetTokenTTL: time.Hour,
+	}
+}
+
+func TestStore_NoOverridesUsesBase(t *testing.T) {
+	db := setupTestDB(t)
+
+	st, err := NewStore(db, baseSettings())
+	require.NoError(t, err)
+	require.Equal(t, baseSettings(), st.Settings())
+	require.Equal(t, Overrides{}, st.Overrides())
+}
+
+func TestStore_OverridesApplyOverBase(t *testing.T) {
+	db := setupTestDB(t)
+
+	level := "debug"
+	expiry := 30 * time.Minute
+	reset := true
+	require.NoError(t, (&Overrides{LogLevel: &level, SessionExpiry: &expiry, PasswordReset: &reset}).Save(db))
+
+	st, err := NewStore(db, baseSettings())
+	require.NoError(t, err)
+
+	got := st.Settings()
+	require.Equal(t, "debug", got.LogLevel)
+	require.Equal(t, 30*time.Minute, got.SessionExpiry)
+	require.True(t, got.PasswordReset)
+	require.True(t, got.LocalLogin, "fields without override follow the base")
+	require.Equal(t, time.Hour, got.ResetTokenTTL)
+
+	// A config file change only affects fields that are not overridden.
+	base := baseSettings()
+	base.LogLevel = "warn"
+	base.ResetTokenTTL = 2 * time.Hour
+	st.SetBase(base)
+
+	got = st.Settings()
+	require.Equal(t, "debug", got.LogLevel)
+	require.Equal(t, 2*time.Hour, got.ResetTokenTTL)
+	require.Equal(t, base, st.Base())
+}
+
+func TestStore_OnChangeAndFollow(t *testing.T) {
+	db := setupTestDB(t)
+
+	st, err := NewStore(db, baseSettings())
+	require.NoError(t, err)
+
+	var seen []string
+
+	st.OnChange(func(rs config.RuntimeSettings) { seen = append(seen, rs.LogLevel) })
+
+	bus := eventbus.New()
+	st.Follow(bus)
+
+	level := "trace"
+	require.NoError(t, (&Overrides{LogLevel: &level}).Save(db))
+
+	// Without a publish the store still serves the old value.
+	require.Equal(t, "info", st.Settings().LogLevel)
+
+	bus.Publish(eventbus.TopicSetting, SettingKey)
+	require.Equal(t, "trace", st.Settings().LogLevel)
+	require.Equal(t, []string{"trace"}, seen)
+}
+
+func TestCurrent_FallsBackToConfig(t *testing.T) {
+	prev := defaultStore.Swap(nil)
+	t.Cleanup(func() { defaultStore.Store(prev) })
+
+	var cfg config.Config
+
+	cfg.Webserver.Session.ExpiryTime = 3 * time.Hour
+	require.Equal(t, 3*time.Hour, Current(&cfg).SessionExpiry)
+
+	st, err := NewStore(setupTestDB(t), baseSettings())
+	require.NoError(t, err)
+	SetDefault(st)
+	require.Equal(t, 12*time.Hour, Current(&cfg).SessionExpiry)
+}
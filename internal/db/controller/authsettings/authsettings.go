@@ -0,0 +1,197 @@
+// Package authsettings provides the LDAP and OIDC provider settings: the
+// [auth.ldap] and [auth.oidc] sections of the TOML config, each replaced by
+// the provider an administrator saved in the database, if any. A Store keeps
+// the resolved settings in memory; it is updated when they are saved on any
+// replica and when the config files change.
+package authsettings
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+// SettingKey is the key under which the overrides are stored.
+const SettingKey = "auth"
+
+// Settings are the provider settings in effect. Version changes whenever the
+// providers do, so that anything built from them can be cached until then.
+type Settings struct {
+	LDAP    config.LDAPAuth
+	OIDC    config.OIDCAuth
+	Version uint64
+}
+
+// FromConfig returns the provider settings of the config files.
+func FromConfig(cfg *config.Config) Settings {
+	return Settings{LDAP: cfg.Auth.LDAP, OIDC: cfg.Auth.OIDC}
+}
+
+// Overrides holds the persisted providers. A nil provider uses the section of
+// the config files.
+type Overrides struct {
+	LDAP *config.LDAPAuth `json:"ldap,omitempty"`
+	OIDC *config.OIDCAuth `json:"oidc,omitempty"`
+}
+
+// Apply returns base with the overrides applied.
+func (o *Overrides) Apply(base Settings) Settings {
+	out := base
+
+	if o.LDAP != nil {
+		out.LDAP = *o.LDAP
+	}
+
+	if o.OIDC != nil {
+		out.OIDC = *o.OIDC
+	}
+
+	return out
+}
+
+// Load reads the overrides from the database. It returns
+// setting.ErrSettingNotFound when nothing has been saved yet.
+func Load(db *gorm.DB) (*Overrides, error) {
+	var out Overrides
+	if err := setting.GetJSON(db, SettingKey, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Save persists the overrides to the database (upsert).
+func (o *Overrides) Save(db *gorm.DB) error {
+	return setting.SetJSON(db, SettingKey, o)
+}
+
+// Store caches the resolved provider settings. It is safe for concurrent use.
+type Store struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	base      Settings
+	overrides Overrides
+
+	current atomic.Pointer[Settings]
+}
+
+// defaultStore is the store returned by Current.
+var defaultStore atomic.Pointer[Store]
+
+// NewStore creates a Store for base, the provider settings of the config
+// files, and loads the overrides from the database. A non-nil error indicates
+// the initial load failed; the returned Store is still usable and uses base.
+func NewStore(db *gorm.DB, base Settings) (*Store, error) {
+	base.Version = 1
+
+	st := &Store{db: db, base: base}
+	st.current.Store(&base)
+
+	err := st.Reload()
+
+	return st, err
+}
+
+// SetDefault makes st the store read by Current.
+func SetDefault(st *Store) {
+	defaultStore.Store(st)
+}
+
+// Current returns the provider settings of the default store, or those of cfg
+// when no store is installed (e.g. in tests).
+func Current(cfg *config.Config) Settings {
+	if st := defaultStore.Load(); st != nil {
+		return st.Settings()
+	}
+
+	return FromConfig(cfg)
+}
+
+// Settings returns the resolved provider settings.
+func (st *Store) Settings() Settings {
+	return *st.current.Load()
+}
+
+// Base returns the provider settings of the config files.
+func (st *Store) Base() Settings {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.base
+}
+
+// Overrides returns a copy of the cached overrides.
+func (st *Store) Overrides() Overrides {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.overrides
+}
+
+// Reload refreshes the overrides from the database. A missing setting is not
+// an error: no overrides apply.
+func (st *Store) Reload() error {
+	o, err := Load(st.db)
+	if err != nil {
+		if !errors.Is(err, setting.ErrSettingNotFound) {
+			return err
+		}
+
+		o = &Overrides{}
+	}
+
+	st.mu.Lock()
+	st.overrides = *o
+	st.mu.Unlock()
+
+	st.resolve()
+
+	return nil
+}
+
+// Follow reloads the overrides whenever they are written, locally or on
+// another replica.
+func (st *Store) Follow(bus *eventbus.Bus) {
+	setting.OnChange(bus, SettingKey, func(string) {
+		if err := st.Reload(); err != nil {
+			log.Warn().Err(err).Msg("authsettings: failed to reload overrides")
+		}
+	})
+}
+
+// SetBase replaces the provider settings of the config files, e.g. after they
+// changed on disk.
+func (st *Store) SetBase(base Settings) {
+	st.mu.Lock()
+	st.base = base
+	st.mu.Unlock()
+
+	st.resolve()
+}
+
+// resolve applies the overrides to the base. The version is only bumped when
+// a provider actually changed, as every write to the settings table triggers
+// a reload.
+func (st *Store) resolve() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	resolved := st.overrides.Apply(st.base)
+	previous := st.current.Load()
+
+	resolved.Version = previous.Version
+	if !reflect.DeepEqual(resolved.LDAP, previous.LDAP) || !reflect.DeepEqual(resolved.OIDC, previous.OIDC) {
+		resolved.Version++
+	}
+
+	st.current.Store(&resolved)
+}
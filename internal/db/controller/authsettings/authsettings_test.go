@@ -0,0 +1,110 @@
+package authsettings
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Setting{}))
+
+	return db
+}
+
+func baseSettings() Settings {
+	return Settings{
+		LDAP: config.LDAPAuth{Enabled: true, Host: "ldap.example.com", Port: 389, BaseDN: "dc=example,dc=com"},
+		OIDC: config.OIDCAuth{ProviderURL: "https://idp.example.com"},
+	}
+}
+
+func TestStore_NoOverridesUsesBase(t *testing.T) {
+	st, err := NewStore(setupTestDB(t), baseSettings())
+	require.NoError(t, err)
+
+	got := st.Settings()
+	require.Equal(t, baseSettings().LDAP, got.LDAP)
+	require.Equal(t, baseSettings().OIDC, got.OIDC)
+	require.Equal(t, Overrides{}, st.Overrides())
+}
+
+func TestStore_OverridesReplaceProviders(t *testing.T) {
+	db := setupTestDB(t)
+
+	oidc := config.OIDCAuth{
+		Enabled: true, ProviderURL: "https://sso.example.org", ClientID: "admin",
+		ClientSecret: "secret", RedirectURL: "https://dns.example.org/auth/oidc/callback",
+		Scopes: []string{"openid", "groups"},
+	}
+	require.NoError(t, (&Overrides{OIDC: &oidc}).Save(db))
+
+	st, err := NewStore(db, baseSettings())
+	require.NoError(t, err)
+
+	got := st.Settings()
+	require.Equal(t, oidc, got.OIDC)
+	require.Equal(t, "ldap.example.com", got.LDAP.Host, "providers without override follow the base")
+
+	// A config file change only affects providers that are not overridden.
+	base := baseSettings()
+	base.LDAP.Host = "ldap2.example.com"
+	base.OIDC.ProviderURL = "https://other.example.com"
+	st.SetBase(base)
+
+	got = st.Settings()
+	require.Equal(t, "ldap2.example.com", got.LDAP.Host)
+	require.Equal(t, "https://sso.example.org", got.OIDC.ProviderURL)
+}
+
+func TestStore_VersionFollowsChanges(t *testing.T) {
+	db := setupTestDB(t)
+
+	st, err := NewStore(db, baseSettings())
+	require.NoError(t, err)
+
+	bus := eventbus.New()
+	st.Follow(bus)
+
+	version := st.Settings().Version
+
+	// Unrelated settings writes leave the version alone.
+	bus.Publish(eventbus.TopicSetting, SettingKey)
+	require.Equal(t, version, st.Settings().Version)
+
+	ldap := baseSettings().LDAP
+	ldap.BindDN = "cn=admin,dc=example,dc=com"
+	require.NoError(t, (&Overrides{LDAP: &ldap}).Save(db))
+
+	// Without a publish the store still serves the old value.
+	require.Empty(t, st.Settings().LDAP.BindDN)
+
+	bus.Publish(eventbus.TopicSetting, SettingKey)
+	require.Equal(t, "cn=admin,dc=example,dc=com", st.Settings().LDAP.BindDN)
+	require.Equal(t, version+1, st.Settings().Version)
+}
+
+func TestCurrent_FallsBackToConfig(t *testing.T) {
+	prev := defaultStore.Swap(nil)
+	t.Cleanup(func() { defaultStore.Store(prev) })
+
+	var cfg config.Config
+
+	cfg.Auth.LDAP.Host = "config.example.com"
+	require.Equal(t, "config.example.com", Current(&cfg).LDAP.Host)
+
+	st, err := NewStore(setupTestDB(t), baseSettings())
+	require.NoError(t, err)
+	SetDefault(st)
+	require.Equal(t, "ldap.example.com", Current(&cfg).LDAP.Host)
+}
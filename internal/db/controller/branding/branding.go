@@ -7,7 +7,6 @@ package branding
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"sync/atomic"
 
@@ -72,13 +71,8 @@ type Settings struct {
 // Load reads the branding settings from the database. It returns
 // setting.ErrSettingNotFound when nothing has been saved yet.
 func Load(db *gorm.DB) (*Settings, error) {
-	s, err := setting.Get(db, SettingKey)
-	if err != nil {
-		return nil, err
-	}
-
 	var out Settings
-	if err := json.Unmarshal(s.Value, &out); err != nil {
+	if err := setting.GetJSON(db, SettingKey, &out); err != nil {
 		return nil, err
 	}
 
@@ -87,14 +81,7 @@ func Load(db *gorm.DB) (*Settings, error) {
 
 // Save persists the branding settings to the database (upsert).
 func (s *Settings) Save(db *gorm.DB) error {
-	data, err := json.Marshal(s)
-	if err != nil {
-		return err
-	}
-
-	_, err = setting.Set(db, SettingKey, data)
-
-	return err
+	return setting.SetJSON(db, SettingKey, s)
 }
 
 // Store caches branding settings in memory and resolves them against the static
@@ -0,0 +1,144 @@
+// Package maintenance provides the maintenance mode state: while it is enabled
+// only users with the admin.maintenance permission can use the application,
+// everyone else gets a maintenance page. The state is stored in the settings
+// table so that it applies to every replica; a Store keeps it in memory and
+// reloads it whenever the settings change.
+package maintenance
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+const (
+	// SettingKey is the key under which the state is stored.
+	SettingKey = "maintenance"
+
+	// MaxMessageLength is the longest accepted maintenance message.
+	MaxMessageLength = 500
+)
+
+// DefaultMessage is shown on the maintenance page when no message was set.
+const DefaultMessage = "The application is undergoing maintenance. Please try again later."
+
+// State is the persisted maintenance mode state.
+type State struct {
+	Enabled bool `json:"enabled"`
+	// Message is shown to the users locked out; empty shows DefaultMessage.
+	Message string `json:"message,omitempty"`
+	// Since and By record when and by whom maintenance mode was enabled.
+	Since time.Time `json:"since,omitzero"`
+	By    string    `json:"by,omitempty"`
+}
+
+// DisplayMessage returns the message shown on the maintenance page.
+func (s State) DisplayMessage() string {
+	if s.Message == "" {
+		return DefaultMessage
+	}
+
+	return s.Message
+}
+
+// Load reads the state from the database. It returns
+// setting.ErrSettingNotFound when maintenance mode was never toggled.
+func Load(db *gorm.DB) (*State, error) {
+	var out State
+	if err := setting.GetJSON(db, SettingKey, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Save persists the state to the database (upsert).
+func (s *State) Save(db *gorm.DB) error {
+	return setting.SetJSON(db, SettingKey, s)
+}
+
+// Store caches the maintenance state. It is safe for concurrent use.
+type Store struct {
+	db      *gorm.DB
+	current atomic.Pointer[State]
+}
+
+// defaultStore is the store read by Current.
+var defaultStore atomic.Pointer[Store]
+
+// NewStore creates a Store and loads the state from the database. A non-nil
+// error indicates the initial load failed; the returned Store is still usable
+// and reports maintenance mode as off.
+func NewStore(db *gorm.DB) (*Store, error) {
+	st := &Store{db: db}
+	st.current.Store(&State{})
+
+	err := st.Reload()
+
+	return st, err
+}
+
+// SetDefault makes st the store read by Current.
+func SetDefault(st *Store) {
+	defaultStore.Store(st)
+}
+
+// Current returns the state of the default store, or the zero State (off)
+// when no store is installed (e.g. in tests).
+func Current() State {
+	if st := defaultStore.Load(); st != nil {
+		return st.State()
+	}
+
+	return State{}
+}
+
+// State returns the cached state.
+func (st *Store) State() State {
+	return *st.current.Load()
+}
+
+// Set persists state and makes it current.
+func (st *Store) Set(state *State) error {
+	if err := state.Save(st.db); err != nil {
+		return err
+	}
+
+	saved := *state
+	st.current.Store(&saved)
+
+	return nil
+}
+
+// Reload refreshes the state from the database. A missing setting is not an
+// error: maintenance mode is off.
+func (st *Store) Reload() error {
+	s, err := Load(st.db)
+	if err != nil {
+		if !errors.Is(err, setting.ErrSettingNotFound) {
+			return err
+		}
+
+		s = &State{}
+	}
+
+	st.current.Store(s)
+
+	return nil
+}
+
+// Follow reloads the state whenever it is written, locally or on another
+// replica.
+func (st *Store) Follow(bus *eventbus.Bus) {
+	setting.OnChange(bus, SettingKey, func(string) {
+		if err := st.Reload(); err != nil {
+			log.Warn().Err(err).Msg("maintenance: failed to reload state")
+		}
+	})
+}
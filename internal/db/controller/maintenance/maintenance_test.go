@@ -0,0 +1,74 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Setting{}))
+
+	return db
+}
+
+func TestStore_OffWithoutSetting(t *testing.T) {
+	st, err := NewStore(setupTestDB(t))
+	require.NoError(t, err)
+	require.Equal(t, State{}, st.State())
+}
+
+func TestStore_SetAndLoad(t *testing.T) {
+	db := setupTestDB(t)
+
+	st, err := NewStore(db)
+	require.NoError(t, err)
+
+	since := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, st.Set(&State{Enabled: true, Message: "Upgrading PowerDNS", Since: since, By: "alice"}))
+	require.True(t, st.State().Enabled)
+
+	loaded, err := Load(db)
+	require.NoError(t, err)
+	require.Equal(t, "Upgrading PowerDNS", loaded.DisplayMessage())
+	require.True(t, loaded.Since.Equal(since))
+	require.Equal(t, "alice", loaded.By)
+
+	require.NoError(t, st.Set(&State{}))
+	require.False(t, st.State().Enabled)
+	require.Equal(t, DefaultMessage, st.State().DisplayMessage())
+}
+
+func TestStore_Follow(t *testing.T) {
+	db := setupTestDB(t)
+
+	st, err := NewStore(db)
+	require.NoError(t, err)
+
+	bus := eventbus.New()
+	st.Follow(bus)
+
+	// Saved by another replica.
+	require.NoError(t, (&State{Enabled: true}).Save(db))
+	require.False(t, st.State().Enabled)
+
+	bus.Publish(eventbus.TopicSetting, SettingKey)
+	require.True(t, st.State().Enabled)
+}
+
+func TestCurrent_OffWithoutStore(t *testing.T) {
+	prev := defaultStore.Swap(nil)
+	t.Cleanup(func() { defaultStore.Store(prev) })
+
+	require.False(t, Current().Enabled)
+}
@@ -1,8 +1,6 @@
 package pdnsserver
 
 import (
-	"encoding/json"
-
 	"gorm.io/gorm"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
@@ -19,31 +17,22 @@ type (
 		APIServerURL string `form:"api_server_url" json:"apiServerUrl" validate:"required,url"`
 		APIKey       string `form:"api_key"        json:"apiKey"       validate:"required,min=8"`
 		VHost        string `form:"vhost"          json:"vhost"        validate:"required"`
+		// LogAPITraffic logs every PowerDNS API request and response at
+		// debug level, with secrets redacted.
+		LogAPITraffic bool `form:"log_api_traffic" json:"logApiTraffic"`
+		// ReadOnly rejects every change to the zones of the server, e.g.
+		// while it is migrated or when it is only inspected.
+		ReadOnly bool `form:"read_only" json:"readOnly"`
 	}
 )
 
 // Load loads the PDNS server settings from the database.
 func (p *Settings) Load(db *gorm.DB) error {
-	// Retrieve the setting from the database
-	s, err := setting.Get(db, SettingKeyPDNSServer)
-	if err != nil {
-		return err
-	}
-
-	// Unmarshal the JSON blob into the struct
-	return json.Unmarshal(s.Value, p)
+	return setting.GetJSON(db, SettingKeyPDNSServer, p)
 }
 
 // Save saves the PDNS server settings to the database.
 func (p *Settings) Save(db *gorm.DB) error {
-	// Marshal the struct to JSON
-	data, err := json.Marshal(p) //nolint:gosec // APIKey is intentionally persisted to the DB
-	if err != nil {
-		return err
-	}
-
-	// Save or update the setting in the database
-	_, err = setting.Set(db, SettingKeyPDNSServer, data)
-
-	return err
+	// APIKey is intentionally persisted to the DB.
+	return setting.SetJSON(db, SettingKeyPDNSServer, p)
 }
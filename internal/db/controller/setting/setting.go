@@ -1,4 +1,5 @@
-// Package setting provides CRUD operations for managing application settings.
+// Package setting provides CRUD operations for managing application settings,
+// typed accessors for single values and notifications when a setting changes.
 package setting
 
 import (
@@ -6,11 +7,14 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/cache"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
 )
 
 const (
 	nameQueryPattern = "name = ?"
+	cacheKeyPrefix   = "setting:"
 )
 
 var (
@@ -24,7 +28,26 @@ var (
 	ErrDBNil = errors.New("database connection is nil")
 )
 
-// Get retrieves a setting by its name.
+// OnChange registers fn on bus to be called with the name after the setting
+// name was created, updated or deleted through this package, on this replica
+// or, while the relay of eventbus.Default runs, on another one. Writes are
+// published on eventbus.Default; the cached value is already invalidated when
+// fn runs.
+func OnChange(bus *eventbus.Bus, name string, fn func(name string)) {
+	bus.Subscribe(eventbus.TopicSetting, func(e eventbus.Event) {
+		if e.Key == name {
+			fn(name)
+		}
+	})
+}
+
+// notify announces a write of the setting name.
+func notify(name string) {
+	eventbus.Default.Publish(eventbus.TopicSetting, name)
+}
+
+// Get retrieves a setting by its name. Settings are served from the object
+// cache when it is enabled; writes to the settings table invalidate it.
 func Get(db *gorm.DB, name string) (*models.Setting, error) {
 	if db == nil {
 		return nil, ErrDBNil
@@ -34,6 +57,14 @@ func Get(db *gorm.DB, name string) (*models.Setting, error) {
 		return nil, ErrSettingNameEmpty
 	}
 
+	store := cache.Default()
+	if cached, ok := store.Get(cacheKeyPrefix + name); ok {
+		setting := cached.(models.Setting)
+		setting.Value = append([]byte(nil), setting.Value...)
+
+		return &setting, nil
+	}
+
 	var setting models.Setting
 
 	result := db.Where(nameQueryPattern, name).First(&setting)
@@ -45,6 +76,10 @@ func Get(db *gorm.DB, name string) (*models.Setting, error) {
 		return nil, result.Error
 	}
 
+	cached := setting
+	cached.Value = append([]byte(nil), setting.Value...)
+	store.Set(cacheKeyPrefix+name, cached, cache.TTL(), cache.TagSettings)
+
 	return &setting, nil
 }
 
@@ -116,6 +151,8 @@ func Create(db *gorm.DB, name string, value []byte) (*models.Setting, error) {
 		return nil, result.Error
 	}
 
+	notify(name)
+
 	return setting, nil
 }
 
@@ -150,6 +187,8 @@ func Set(db *gorm.DB, name string, value []byte) (*models.Setting, error) {
 		return nil, result.Error
 	}
 
+	notify(setting.Name)
+
 	return &setting, nil
 }
 
@@ -177,6 +216,8 @@ func Update(db *gorm.DB, id uint64, value []byte) (*models.Setting, error) {
 		return nil, result.Error
 	}
 
+	notify(setting.Name)
+
 	return &setting, nil
 }
 
@@ -208,13 +249,16 @@ func UpdateByName(db *gorm.DB, name string, value []byte) (*models.Setting, erro
 		return nil, result.Error
 	}
 
+	notify(setting.Name)
+
 	return &setting, nil
 }
 
 // Delete deletes a setting by ID.
 func Delete(db *gorm.DB, id uint64) error {
-	if db == nil {
-		return ErrDBNil
+	setting, err := GetByID(db, id)
+	if err != nil {
+		return err
 	}
 
 	result := db.Delete(&models.Setting{}, id)
@@ -226,6 +270,8 @@ func Delete(db *gorm.DB, id uint64) error {
 		return ErrSettingNotFound
 	}
 
+	notify(setting.Name)
+
 	return nil
 }
 
@@ -248,5 +294,7 @@ func DeleteByName(db *gorm.DB, name string) error {
 		return ErrSettingNotFound
 	}
 
+	notify(name)
+
 	return nil
 }
@@ -0,0 +1,108 @@
+package setting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// ErrSettingInvalidValue is returned when a stored value cannot be decoded as
+// the requested type.
+var ErrSettingInvalidValue = errors.New("invalid setting value")
+
+// GetString returns the value of the setting name, or def when it is not set.
+func GetString(db *gorm.DB, name, def string) (string, error) {
+	s, err := Get(db, name)
+	if err != nil {
+		if errors.Is(err, ErrSettingNotFound) {
+			return def, nil
+		}
+
+		return def, err
+	}
+
+	return string(s.Value), nil
+}
+
+// GetInt returns the value of the setting name as an integer, or def when it
+// is not set.
+func GetInt(db *gorm.DB, name string, def int) (int, error) {
+	s, err := GetString(db, name, strconv.Itoa(def))
+	if err != nil {
+		return def, err
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return def, invalidValue(name, err)
+	}
+
+	return v, nil
+}
+
+// GetBool returns the value of the setting name as a boolean, or def when it
+// is not set.
+func GetBool(db *gorm.DB, name string, def bool) (bool, error) {
+	s, err := GetString(db, name, strconv.FormatBool(def))
+	if err != nil {
+		return def, err
+	}
+
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return def, invalidValue(name, err)
+	}
+
+	return v, nil
+}
+
+// GetJSON decodes the JSON value of the setting name into v. It returns
+// ErrSettingNotFound, leaving v unchanged, when the setting is not set.
+func GetJSON(db *gorm.DB, name string, v any) error {
+	s, err := Get(db, name)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(s.Value, v); err != nil {
+		return invalidValue(name, err)
+	}
+
+	return nil
+}
+
+// SetString stores value as the setting name.
+func SetString(db *gorm.DB, name, value string) error {
+	_, err := Set(db, name, []byte(value))
+
+	return err
+}
+
+// SetInt stores value as the setting name.
+func SetInt(db *gorm.DB, name string, value int) error {
+	return SetString(db, name, strconv.Itoa(value))
+}
+
+// SetBool stores value as the setting name.
+func SetBool(db *gorm.DB, name string, value bool) error {
+	return SetString(db, name, strconv.FormatBool(value))
+}
+
+// SetJSON stores the JSON encoding of v as the setting name.
+func SetJSON(db *gorm.DB, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = Set(db, name, data)
+
+	return err
+}
+
+func invalidValue(name string, err error) error {
+	return fmt.Errorf("%w %q: %w", ErrSettingInvalidValue, name, err)
+}
@@ -0,0 +1,73 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+func TestTypedAccessors(t *testing.T) {
+	db := setupTestDB(t)
+
+	s, err := GetString(db, "motd", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", s, "default for a missing setting")
+
+	require.NoError(t, SetString(db, "motd", "maintenance tonight"))
+	s, err = GetString(db, "motd", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "maintenance tonight", s)
+
+	n, err := GetInt(db, "limit", 7)
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+
+	require.NoError(t, SetInt(db, "limit", 42))
+	n, err = GetInt(db, "limit", 7)
+	require.NoError(t, err)
+	assert.Equal(t, 42, n)
+
+	require.NoError(t, SetBool(db, "enabled", true))
+	b, err := GetBool(db, "enabled", false)
+	require.NoError(t, err)
+	assert.True(t, b)
+
+	_, err = GetInt(db, "motd", 7)
+	require.ErrorIs(t, err, ErrSettingInvalidValue)
+
+	type presets struct {
+		TTLs []int `json:"ttls"`
+	}
+
+	var p presets
+	require.ErrorIs(t, GetJSON(db, "presets", &p), ErrSettingNotFound)
+
+	require.NoError(t, SetJSON(db, "presets", presets{TTLs: []int{60, 3600}}))
+	require.NoError(t, GetJSON(db, "presets", &p))
+	assert.Equal(t, []int{60, 3600}, p.TTLs)
+
+	require.ErrorIs(t, GetJSON(db, "motd", &p), ErrSettingInvalidValue)
+}
+
+func TestOnChange(t *testing.T) {
+	db := setupTestDB(t)
+
+	var changed []string
+
+	OnChange(eventbus.Default, "notify-test", func(name string) { changed = append(changed, name) })
+
+	require.NoError(t, SetString(db, "notify-test", "a"))
+	require.NoError(t, SetString(db, "notify-test", "b"))
+	require.NoError(t, SetString(db, "notify-other", "c"))
+
+	var s models.Setting
+	require.NoError(t, db.Where(nameQueryPattern, "notify-test").First(&s).Error)
+	require.NoError(t, Delete(db, s.ID))
+
+	assert.Equal(t, []string{"notify-test", "notify-test", "notify-test"}, changed,
+		"create, update and delete notify; other settings do not")
+}
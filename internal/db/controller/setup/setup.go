@@ -0,0 +1,158 @@
+// Package setup tracks the first-run setup wizard. Setup is pending on a fresh
+// installation, i.e. while no user exists, until the administrator finishes or
+// skips the wizard. Installations that already have users when this state was
+// introduced are never asked to run it.
+//
+// Until the first account exists the wizard is guarded by a setup token that
+// is written to the log at startup, so that nobody who merely reaches the web
+// server can claim the administrator account.
+package setup
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+// SettingKey is the key under which the state is stored.
+const SettingKey = "setup"
+
+// State is the persisted setup state.
+type State struct {
+	Completed   bool      `json:"completed"`
+	CompletedAt time.Time `json:"completed_at,omitzero"`
+	// Token guards the creation of the first account; it is cleared when
+	// setup completes.
+	Token string `json:"token,omitempty"`
+}
+
+// Load reads the state from the database. It returns
+// setting.ErrSettingNotFound when setup was never started.
+func Load(db *gorm.DB) (*State, error) {
+	var out State
+	if err := setting.GetJSON(db, SettingKey, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Save persists the state to the database (upsert).
+func (s *State) Save(db *gorm.DB) error {
+	return setting.SetJSON(db, SettingKey, s)
+}
+
+// Store caches whether setup is pending. It is safe for concurrent use.
+type Store struct {
+	db      *gorm.DB
+	pending atomic.Bool
+}
+
+// NewStore creates a Store and loads the state from the database. A non-nil
+// error indicates the initial load failed; the returned Store then reports
+// setup as done.
+func NewStore(db *gorm.DB) (*Store, error) {
+	st := &Store{db: db}
+	err := st.Reload()
+
+	return st, err
+}
+
+// Pending reports whether the setup wizard still has to run.
+func (st *Store) Pending() bool {
+	return st.pending.Load()
+}
+
+// Reload refreshes the state from the database. Without a saved state, setup
+// is pending when there are no users yet.
+func (st *Store) Reload() error {
+	s, err := Load(st.db)
+	if err == nil {
+		st.pending.Store(!s.Completed)
+		return nil
+	}
+
+	if !errors.Is(err, setting.ErrSettingNotFound) {
+		return err
+	}
+
+	exists, err := st.HasUsers()
+	if err != nil {
+		return err
+	}
+
+	st.pending.Store(!exists)
+
+	return nil
+}
+
+// HasUsers reports whether any user exists, i.e. whether the administrator
+// account has been created.
+func (st *Store) HasUsers() (bool, error) {
+	var count int64
+	if err := st.db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Token returns the setup token, creating it on first use.
+func (st *Store) Token() (string, error) {
+	s, err := Load(st.db)
+	if err != nil && !errors.Is(err, setting.ErrSettingNotFound) {
+		return "", err
+	}
+
+	if s != nil && s.Token != "" {
+		return s.Token, nil
+	}
+
+	s = &State{Token: rand.Text()}
+	if err := s.Save(st.db); err != nil {
+		return "", err
+	}
+
+	return s.Token, nil
+}
+
+// CheckToken reports whether token is the setup token.
+func (st *Store) CheckToken(token string) bool {
+	s, err := Load(st.db)
+	if err != nil || s.Token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// Complete marks setup as done and discards the token.
+func (st *Store) Complete() error {
+	s := &State{Completed: true, CompletedAt: time.Now().UTC()}
+	if err := s.Save(st.db); err != nil {
+		return err
+	}
+
+	st.pending.Store(false)
+
+	return nil
+}
+
+// Follow reloads the state whenever it is written, locally or on another
+// replica.
+func (st *Store) Follow(bus *eventbus.Bus) {
+	setting.OnChange(bus, SettingKey, func(string) {
+		if err := st.Reload(); err != nil {
+			log.Warn().Err(err).Msg("setup: failed to reload state")
+		}
+	})
+}
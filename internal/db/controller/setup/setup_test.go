@@ -0,0 +1,100 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Setting{}, &models.Role{}, &models.User{}))
+
+	return db
+}
+
+func createUser(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	role := models.Role{Name: "admin"}
+	require.NoError(t, db.Create(&role).Error)
+	require.NoError(t, db.Create(&models.User{
+		Username: "alice",
+		Email:    "alice@example.com",
+		RoleID:   role.ID,
+	}).Error)
+}
+
+func TestStore_PendingWithoutUsers(t *testing.T) {
+	st, err := NewStore(setupTestDB(t))
+	require.NoError(t, err)
+	require.True(t, st.Pending())
+}
+
+func TestStore_NotPendingForExistingInstallation(t *testing.T) {
+	db := setupTestDB(t)
+	createUser(t, db)
+
+	st, err := NewStore(db)
+	require.NoError(t, err)
+	require.False(t, st.Pending())
+}
+
+func TestStore_TokenAndComplete(t *testing.T) {
+	db := setupTestDB(t)
+
+	st, err := NewStore(db)
+	require.NoError(t, err)
+
+	token, err := st.Token()
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	again, err := st.Token()
+	require.NoError(t, err)
+	require.Equal(t, token, again)
+
+	require.True(t, st.CheckToken(token))
+	require.False(t, st.CheckToken(""))
+	require.False(t, st.CheckToken("wrong"))
+
+	// The account is created before setup completes; it stays pending.
+	createUser(t, db)
+	require.NoError(t, st.Reload())
+	require.True(t, st.Pending())
+
+	require.NoError(t, st.Complete())
+	require.False(t, st.Pending())
+	require.False(t, st.CheckToken(token))
+
+	loaded, err := Load(db)
+	require.NoError(t, err)
+	require.True(t, loaded.Completed)
+	require.False(t, loaded.CompletedAt.IsZero())
+	require.Empty(t, loaded.Token)
+}
+
+func TestStore_Follow(t *testing.T) {
+	db := setupTestDB(t)
+
+	st, err := NewStore(db)
+	require.NoError(t, err)
+
+	bus := eventbus.New()
+	st.Follow(bus)
+
+	// Completed on another replica.
+	require.NoError(t, (&State{Completed: true}).Save(db))
+	require.True(t, st.Pending())
+
+	bus.Publish(eventbus.TopicSetting, SettingKey)
+	require.False(t, st.Pending())
+}
@@ -0,0 +1,68 @@
+// Package like builds case-insensitive substring searches that work on every
+// supported database. PostgreSQL compares case-sensitively with LIKE and
+// MySQL and SQLite have no ILIKE, so the predicate depends on the dialect.
+package like
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// escaper escapes the LIKE wildcards with "!", which unlike a backslash needs
+// no quoting in MySQL.
+var escaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// Pattern returns the LIKE pattern matching values that contain query, with
+// its wildcards escaped and in lower case.
+func Pattern(query string) string {
+	return "%" + escaper.Replace(strings.ToLower(query)) + "%"
+}
+
+// PrefixPattern returns the LIKE pattern matching values that start with
+// query, with its wildcards escaped and in lower case.
+func PrefixPattern(query string) string {
+	return escaper.Replace(strings.ToLower(query)) + "%"
+}
+
+// Predicate returns the condition matching rows where any of columns matches
+// one Pattern argument per column on the given dialect, as named by
+// gorm.Dialector.Name.
+func Predicate(dialect string, columns ...string) string {
+	conds := make([]string, len(columns))
+
+	for i, column := range columns {
+		if dialect == "postgres" {
+			conds[i] = column + " ILIKE ? ESCAPE '!'"
+		} else {
+			conds[i] = "LOWER(" + column + ") LIKE ? ESCAPE '!'"
+		}
+	}
+
+	return strings.Join(conds, " OR ")
+}
+
+// Contains returns a scope keeping the rows where any of columns contains
+// query, ignoring case.
+func Contains(query string, columns ...string) func(*gorm.DB) *gorm.DB {
+	return match(Pattern(query), columns)
+}
+
+// StartsWith returns a scope keeping the rows where any of columns starts
+// with query, ignoring case.
+func StartsWith(query string, columns ...string) func(*gorm.DB) *gorm.DB {
+	return match(PrefixPattern(query), columns)
+}
+
+// match returns a scope keeping the rows where any of columns matches
+// pattern.
+func match(pattern string, columns []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		args := make([]any, len(columns))
+		for i := range args {
+			args[i] = pattern
+		}
+
+		return db.Where(Predicate(db.Dialector.Name(), columns...), args...)
+	}
+}
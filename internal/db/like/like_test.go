@@ -0,0 +1,100 @@
+package like_test
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
+)
+
+func TestPattern(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"Admin", "%admin%"},
+		{"50%_off!", "%50!%!_off!!%"},
+	} {
+		if got := like.Pattern(tt.in); got != tt.want {
+			t.Errorf("Pattern(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPredicate(t *testing.T) {
+	for _, tt := range []struct{ dialect, want string }{
+		{"postgres", "name ILIKE ? ESCAPE '!' OR email ILIKE ? ESCAPE '!'"},
+		{"mysql", "LOWER(name) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!'"},
+		{"sqlite", "LOWER(name) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!'"},
+	} {
+		if got := like.Predicate(tt.dialect, "name", "email"); got != tt.want {
+			t.Errorf("Predicate(%q) = %q, want %q", tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	type entry struct {
+		ID    uint
+		Name  string
+		Email string
+	}
+
+	if err = db.AutoMigrate(&entry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []entry{
+		{Name: "Alice", Email: "alice@example.com"},
+		{Name: "bob", Email: "BOB@Example.org"},
+		{Name: "carol_50%", Email: "carol@example.net"},
+	} {
+		if err = db.Create(&e).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  int64
+	}{
+		{"ALICE", 1},
+		{"example.ORG", 1},
+		{"example", 3},
+		{"_", 1},
+		{"50%", 1},
+		{"%", 1},
+	} {
+		var count int64
+		if err = db.Model(&entry{}).Scopes(like.Contains(tt.query, "name", "email")).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if count != tt.want {
+			t.Errorf("Contains(%q) matched %d rows, want %d", tt.query, count, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  int64
+	}{
+		{"AL", 1},
+		{"example", 0},
+		{"carol_", 1},
+		{"c%", 0},
+	} {
+		var count int64
+		if err = db.Model(&entry{}).Scopes(like.StartsWith(tt.query, "name", "email")).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if count != tt.want {
+			t.Errorf("StartsWith(%q) matched %d rows, want %d", tt.query, count, tt.want)
+		}
+	}
+}
@@ -23,6 +23,11 @@ type ActivityLog struct {
 	Details string `gorm:"type:text"`
 	// IPAddress is the client IP address at the time of the action.
 	IPAddress string `gorm:"size:45"`
+	// AuthMethod is how the user authenticated the request (session, api_key
+	// or oidc_bearer). Empty for entries written by background jobs.
+	AuthMethod string `gorm:"size:20"`
+	// APIKeyID is the API key that authenticated the request, if any.
+	APIKeyID *uint64 `gorm:"index"`
 	// CreatedAt is the timestamp when the event occurred.
 	CreatedAt time.Time `gorm:"index"`
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey is a long-lived token a user issues to let scripts and automation
+// act on their behalf without a browser session. Only the SHA-256 hash of the
+// token is stored; the token itself is shown once, when the key is created.
+type APIKey struct {
+	// ID is the unique identifier for the key.
+	ID uint64 `gorm:"primaryKey"`
+	// UserID is the user the key acts as.
+	UserID uint64 `gorm:"not null;index"`
+	// User is the associated user; keys are removed together with the user.
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// Name describes what the key is used for.
+	Name string `gorm:"size:100;not null"`
+	// Prefix is the start of the token, shown to tell keys apart.
+	Prefix string `gorm:"size:16;not null"`
+	// TokenHash is the hex-encoded SHA-256 hash of the token.
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+	// Permissions is the space-separated list of permissions the key is
+	// limited to. Empty means all permissions of the user.
+	Permissions string `gorm:"type:text"`
+	// Zones limits the key to the zones of its user matching one of these
+	// patterns, one per line (see auth.ZonePattern). Empty means all zones of
+	// the user.
+	Zones string `gorm:"size:2048"`
+	// RecordTypes limits the record changes made with the key to these
+	// record types, as a comma-separated list (e.g. "A,CNAME,TXT"). Empty
+	// means all record types the user may edit.
+	RecordTypes string `gorm:"size:255"`
+	// ExpiresAt is when the key stops being valid (nil for no expiry).
+	ExpiresAt *time.Time
+	// LastUsedAt is when the key last authenticated a request.
+	LastUsedAt *time.Time
+	// RevokedAt is when the user revoked the key (nil while active).
+	RevokedAt *time.Time
+	// CreatedAt is the timestamp when the key was issued (managed by GORM).
+	CreatedAt time.Time
+}
+
+// TableName specifies the database table name for the APIKey model.
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// RecordTypeList returns the record types the key is limited to, or nil when
+// it may change every record type its user may edit.
+func (k *APIKey) RecordTypeList() []string {
+	return splitRecordTypes(k.RecordTypes)
+}
+
+// ZonePatternList returns the zone patterns the key is limited to, or nil
+// when it may access all zones of its user.
+func (k *APIKey) ZonePatternList() []string {
+	return strings.Fields(k.Zones)
+}
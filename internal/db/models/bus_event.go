@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// BusEvent is an event relayed between application replicas through the
+// shared database, such as a cache invalidation. Rows are pruned after a
+// short retention period.
+type BusEvent struct {
+	// ID orders the events; replicas remember the last ID they processed.
+	ID uint64 `gorm:"primaryKey"`
+	// Origin identifies the replica that published the event.
+	Origin string `gorm:"size:64;not null"`
+	// Topic is the event topic, e.g. "cache.settings".
+	Topic string `gorm:"size:64;not null"`
+	// Key is the optional subject of the event, e.g. a zone name.
+	Key string `gorm:"size:255"`
+	// CreatedAt is when the event was published.
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// ChangeRequestStatus is the review state of a ChangeRequest.
+type ChangeRequestStatus string
+
+const (
+	// ChangeRequestPending is a change waiting for review.
+	ChangeRequestPending ChangeRequestStatus = "pending"
+	// ChangeRequestApproved is a change that has been applied.
+	ChangeRequestApproved ChangeRequestStatus = "approved"
+	// ChangeRequestRejected is a change an approver declined.
+	ChangeRequestRejected ChangeRequestStatus = "rejected"
+)
+
+// ChangeRequest is a record update proposed by a user with the zone.propose
+// permission. It is applied once a user with the zone.approve permission
+// approves it, or discarded when rejected.
+type ChangeRequest struct {
+	// ID is the unique identifier for the change request.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot (e.g. "example.com.").
+	ZoneName string `gorm:"size:255;not null;index"`
+	// Changes is the proposed records update as JSON, in the format the zone
+	// editor sends.
+	Changes string `gorm:"type:text;not null"`
+	// Summary is a short description of the changes, e.g. "www A, mail MX".
+	Summary string `gorm:"type:text"`
+	// RequesterID is the user who proposed the change.
+	RequesterID uint64 `gorm:"not null;index"`
+	// Requester is the associated user; change requests are removed together with the user.
+	Requester User `gorm:"foreignKey:RequesterID;constraint:OnDelete:CASCADE"`
+	// Status is the review state.
+	Status ChangeRequestStatus `gorm:"type:varchar(20);not null;default:'pending';index"`
+	// ReviewerID is the user who approved or rejected the change.
+	ReviewerID *uint64
+	// Reviewer is the associated user.
+	Reviewer *User `gorm:"foreignKey:ReviewerID;constraint:OnDelete:SET NULL"`
+	// ReviewComment is the reviewer's note to the requester.
+	ReviewComment string `gorm:"type:text"`
+	// ReviewedAt is when the change was approved or rejected.
+	ReviewedAt *time.Time
+	// CreatedAt is the timestamp when the change was proposed (managed by GORM).
+	CreatedAt time.Time
+	// UpdatedAt is the timestamp when the change request was last updated (managed by GORM).
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for the ChangeRequest model.
+func (ChangeRequest) TableName() string {
+	return "change_requests"
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// DashboardView is a named dashboard filter a user saved, e.g. "Slave zones
+// by serial".
+type DashboardView struct {
+	// ID is the unique identifier for the view.
+	ID uint64 `gorm:"primaryKey"`
+	// UserID is the user who saved the view.
+	UserID uint64 `gorm:"not null;uniqueIndex:idx_dashboard_views_user_name"`
+	// User is the associated user; views are removed together with the user.
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// Name is the label shown in the list of saved views, unique per user.
+	Name string `gorm:"size:100;not null;uniqueIndex:idx_dashboard_views_user_name"`
+	// Query is the URL query of the dashboard with the tab, search, kind,
+	// sort and page size of the view.
+	Query string `gorm:"size:1000;not null"`
+	// CreatedAt is the timestamp when the view was first saved (managed by GORM).
+	CreatedAt time.Time
+	// UpdatedAt is the timestamp when the view was last saved (managed by GORM).
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for the DashboardView model.
+func (DashboardView) TableName() string {
+	return "dashboard_views"
+}
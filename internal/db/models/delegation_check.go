@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DelegationStatus is the outcome of a DelegationCheck.
+type DelegationStatus string
+
+const (
+	// DelegationOK means every nameserver answered authoritatively.
+	DelegationOK DelegationStatus = "ok"
+	// DelegationPartial means some nameservers are lame or unreachable.
+	DelegationPartial DelegationStatus = "partial"
+	// DelegationLame means no nameserver answered authoritatively.
+	DelegationLame DelegationStatus = "lame"
+)
+
+// DelegationCheck is the last check of a delegation: an NS RRset below the
+// apex of a zone, delegating the subdomain Name to other nameservers (see
+// internal/delegation).
+type DelegationCheck struct {
+	// ID is the unique identifier for the check.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical name of the parent zone with trailing dot.
+	ZoneName string `gorm:"size:255;not null;uniqueIndex:idx_delegation_checks_zone_name"`
+	// Name is the canonical name of the delegated subdomain.
+	Name string `gorm:"size:255;not null;uniqueIndex:idx_delegation_checks_zone_name"`
+	// Nameservers are the targets of the NS RRset, one per line.
+	Nameservers string `gorm:"type:text"`
+	// Status is the outcome of the check.
+	Status DelegationStatus `gorm:"type:varchar(20);not null;index"`
+	// Servers is the JSON encoded []delegation.Server with the answer of each
+	// nameserver.
+	Servers   string    `gorm:"type:text"`
+	CheckedAt time.Time `gorm:"not null;index"`
+}
+
+// TableName overrides the default GORM table name.
+func (DelegationCheck) TableName() string { return "delegation_checks" }
+
+// NameserverList returns the nameservers of the delegation.
+func (d *DelegationCheck) NameserverList() []string {
+	return strings.Fields(d.Nameservers)
+}
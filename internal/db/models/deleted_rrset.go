@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// DeletedRRset is an RRset deleted from a zone, kept in the trash of the zone
+// until ExpiresAt so that it can be restored.
+type DeletedRRset struct {
+	// ID is the unique identifier for the trash entry.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;index"`
+	// Name is the owner name of the RRset with trailing dot.
+	Name string `gorm:"size:255;not null"`
+	// Type is the record type, e.g. "A".
+	Type string `gorm:"size:20;not null"`
+	// TTL is the TTL of the RRset.
+	TTL uint32 `gorm:"not null;default:0"`
+	// Records is the JSON encoded list of records, including disabled ones.
+	Records string `gorm:"type:text;not null"`
+	// Comments is the JSON encoded list of comments of the RRset.
+	Comments string `gorm:"type:text"`
+	// DeletedByID is the user who deleted the RRset (nil once deleted or for
+	// background jobs).
+	DeletedByID *uint64
+	// DeletedBy is the associated user.
+	DeletedBy *User `gorm:"foreignKey:DeletedByID;constraint:OnDelete:SET NULL"`
+	// ExpiresAt is when the entry is purged from the trash.
+	ExpiresAt time.Time `gorm:"not null;index"`
+	// CreatedAt is the timestamp when the RRset was deleted (managed by GORM).
+	CreatedAt time.Time
+}
+
+// TableName specifies the database table name for the DeletedRRset model.
+func (DeletedRRset) TableName() string {
+	return "deleted_rrsets"
+}
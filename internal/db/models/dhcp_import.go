@@ -0,0 +1,101 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DHCPImportKind is the DHCP server a DHCPImport reads leases from.
+type DHCPImportKind string
+
+const (
+	// DHCPImportKea reads leases from the Kea Control Agent API.
+	DHCPImportKea DHCPImportKind = "kea"
+	// DHCPImportISC reads leases from an ISC dhcpd lease file.
+	DHCPImportISC DHCPImportKind = "isc"
+)
+
+// DHCPConflictPolicy decides what happens when a lease would change records
+// that an import does not manage.
+type DHCPConflictPolicy string
+
+const (
+	// DHCPConflictSkip leaves the existing records and reports the conflict.
+	DHCPConflictSkip DHCPConflictPolicy = "skip"
+	// DHCPConflictOverwrite replaces the existing records with those of the
+	// lease and reports the conflict.
+	DHCPConflictOverwrite DHCPConflictPolicy = "overwrite"
+)
+
+// DHCPImport periodically reads the active leases of a DHCP server and keeps
+// A, AAAA and PTR records of the leased addresses (see
+// internal/integrations/dhcplease).
+type DHCPImport struct {
+	// ID is the unique identifier for the import.
+	ID uint `gorm:"primaryKey"`
+	// Name identifies the import in the admin UI and the activity log.
+	Name string         `gorm:"unique;size:100;not null"`
+	Kind DHCPImportKind `gorm:"size:20;not null"`
+	// URL is the Kea Control Agent URL, e.g. "http://kea:8000/".
+	URL string `gorm:"size:512"`
+	// Username and Password authenticate at the Control Agent when set. The
+	// password is never shown again after saving.
+	Username string `gorm:"size:255"`
+	Password string `gorm:"size:255"`
+	// IPv6 also reads the DHCPv6 leases of Kea.
+	IPv6 bool `gorm:"not null;default:false"`
+	// LeaseFile is the path of the ISC dhcpd lease file.
+	LeaseFile string `gorm:"size:512"`
+	// ForwardZone receives the A and AAAA records, named after the first label
+	// of the client hostname.
+	ForwardZone string `gorm:"size:255;not null"`
+	// ReverseZones receive the PTR records, one zone per line. Addresses
+	// outside of them get no PTR record.
+	ReverseZones string `gorm:"type:text"`
+	// TTL is the TTL of new records.
+	TTL uint32 `gorm:"not null;default:300"`
+	// IntervalMinutes is the time between two imports.
+	IntervalMinutes uint               `gorm:"not null;default:5"`
+	ConflictPolicy  DHCPConflictPolicy `gorm:"size:20;not null;default:skip"`
+	Enabled         bool               `gorm:"not null;default:false"`
+	// LastRunAt and LastError describe the last import; LastError is empty
+	// after a success. LastReport is the JSON reconciliation report of it.
+	LastRunAt  *time.Time
+	LastError  string `gorm:"size:500"`
+	LastReport string `gorm:"type:text"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (DHCPImport) TableName() string { return "dhcp_imports" }
+
+// ReverseZoneList returns the reverse zones of the import.
+func (d *DHCPImport) ReverseZoneList() []string {
+	return strings.Fields(d.ReverseZones)
+}
+
+// Interval returns the time between two imports.
+func (d *DHCPImport) Interval() time.Duration {
+	return time.Duration(d.IntervalMinutes) * time.Minute
+}
+
+// DHCPImportRecord is a record created by a DHCPImport. Records of a name
+// that the import does not manage are conflicts, and managed records whose
+// lease ended are deleted.
+type DHCPImportRecord struct {
+	ID       uint   `gorm:"primaryKey"`
+	ImportID uint   `gorm:"index;not null"`
+	ZoneName string `gorm:"size:255;not null"`
+	// Name is the fully qualified record name.
+	Name    string `gorm:"size:255;not null"`
+	Type    string `gorm:"size:10;not null"`
+	Content string `gorm:"size:255;not null"`
+	// Hostname is the client hostname of the lease.
+	Hostname  string `gorm:"size:255"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (DHCPImportRecord) TableName() string { return "dhcp_import_records" }
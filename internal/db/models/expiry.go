@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Expiry is the date a zone, or one of its RRsets, is due for review or
+// removal, e.g. a temporary CNAME for a campaign. Like labels, expiry dates
+// are kept by the application only; nothing changes in PowerDNS when they
+// pass.
+type Expiry struct {
+	// ID is the unique identifier for the expiry.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;uniqueIndex:idx_expiries_target"`
+	// Name and Type identify the RRset; both are empty for the zone itself.
+	Name string `gorm:"size:255;not null;default:'';uniqueIndex:idx_expiries_target"`
+	Type string `gorm:"size:20;not null;default:'';uniqueIndex:idx_expiries_target"`
+	// ExpiresAt is the date the entry is due.
+	ExpiresAt time.Time `gorm:"not null;index"`
+	// Note says why the entry expires, e.g. "spring campaign".
+	Note string `gorm:"size:255;not null;default:''"`
+	// CreatedByID is the user who set the date (nil once deleted).
+	CreatedByID *uint64
+	// CreatedBy is the associated user.
+	CreatedBy *User `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL"`
+	// NotifiedAt is when the reminder was sent; nil until then.
+	NotifiedAt *time.Time
+	// CreatedAt is the timestamp when the expiry was created (managed by GORM).
+	CreatedAt time.Time
+	// UpdatedAt is the timestamp when the expiry was last updated (managed by GORM).
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for the Expiry model.
+func (Expiry) TableName() string {
+	return "expiries"
+}
+
+// IsZone reports whether the expiry is the one of the zone itself.
+func (e *Expiry) IsZone() bool {
+	return e.Name == "" && e.Type == ""
+}
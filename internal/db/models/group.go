@@ -30,6 +30,11 @@ type Group struct {
 	Source GroupSource `gorm:"type:varchar(20);not null;uniqueIndex:idx_source_external"`
 	// Description provides a human-readable explanation of the group's purpose.
 	Description string `gorm:"size:255"`
+	// TenantID is the tenant the group belongs to; nil for groups of the
+	// provider.
+	TenantID *uint `gorm:"index"`
+	// Tenant is the associated tenant; a tenant with groups cannot be deleted.
+	Tenant *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:RESTRICT"`
 	// CreatedAt is the timestamp when the group was created (managed by GORM).
 	CreatedAt time.Time
 	// UpdatedAt is the timestamp when the group was last updated (managed by GORM).
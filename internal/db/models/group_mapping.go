@@ -31,3 +31,23 @@ type GroupMapping struct {
 func (GroupMapping) TableName() string {
 	return "group_mappings"
 }
+
+// GroupZone grants all members of a group access to the zones matching
+// Pattern, in addition to the role of the group's mapping. Pattern is a zone
+// name, a wildcard such as "*.team-x.example.com." or a "~" regular
+// expression (see auth.ZonePattern).
+type GroupZone struct {
+	// GroupID is the ID of the group the zones are granted to.
+	GroupID uint `gorm:"primaryKey;column:group_id"`
+	// Pattern is the canonical zone pattern.
+	Pattern string `gorm:"primaryKey;size:255"`
+	// Group is the associated group; its zone grants are removed with it (CASCADE).
+	Group Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	// CreatedAt is the timestamp when the grant was created (managed by GORM).
+	CreatedAt time.Time
+}
+
+// TableName specifies the database table name for the GroupZone model.
+func (GroupZone) TableName() string {
+	return "group_zones"
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// IntegrationKind is the chat service an Integration posts to.
+type IntegrationKind string
+
+const (
+	// IntegrationSlack posts to a Slack incoming webhook.
+	IntegrationSlack IntegrationKind = "slack"
+	// IntegrationMattermost posts to a Mattermost incoming webhook.
+	IntegrationMattermost IntegrationKind = "mattermost"
+	// IntegrationTeams posts to a Microsoft Teams workflow webhook.
+	IntegrationTeams IntegrationKind = "teams"
+)
+
+// Integration posts zone and record changes and failed health checks to a
+// chat channel through an incoming webhook.
+type Integration struct {
+	// ID is the unique identifier for the integration.
+	ID uint `gorm:"primaryKey"`
+	// Name identifies the integration in the admin UI.
+	Name string          `gorm:"unique;size:100;not null"`
+	Kind IntegrationKind `gorm:"size:20;not null"`
+	// WebhookURL is the incoming webhook of the channel. It contains the
+	// credentials of the webhook and is never shown again after saving.
+	WebhookURL string `gorm:"size:1024;not null"`
+	// Channel overrides the channel of a Slack or Mattermost webhook.
+	Channel string `gorm:"size:100"`
+	// Zones limits the messages to these zones and their sub-zones,
+	// separated by spaces. Empty sends the messages of all zones.
+	Zones   string `gorm:"type:text"`
+	Enabled bool   `gorm:"not null;default:false"`
+	// TenantID is the tenant the integration belongs to; nil for integrations
+	// of the provider. Integrations of a tenant only get the messages of the
+	// zones of their tenant.
+	TenantID *uint `gorm:"index"`
+	// Tenant is the associated tenant; its integrations go with it.
+	Tenant *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE"`
+	// ZoneEvents, RecordEvents and HealthEvents select the messages sent.
+	ZoneEvents   bool `gorm:"not null;default:false"`
+	RecordEvents bool `gorm:"not null;default:false"`
+	HealthEvents bool `gorm:"not null;default:false"`
+	// ZoneTemplate, RecordTemplate and HealthTemplate are the text/template
+	// sources of the messages; empty uses the default.
+	ZoneTemplate   string `gorm:"type:text"`
+	RecordTemplate string `gorm:"type:text"`
+	HealthTemplate string `gorm:"type:text"`
+	// LastSentAt and LastError describe the last delivery; LastError is
+	// empty after a success.
+	LastSentAt *time.Time
+	LastError  string `gorm:"size:500"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (Integration) TableName() string { return "integrations" }
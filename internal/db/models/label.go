@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Label is a key/value pair attached to a zone or to one of its RRsets, e.g.
+// owner=web-team. Labels are kept by the application only; PowerDNS never
+// sees them. Unlike Tag, a label grants no access.
+type Label struct {
+	// ID is the unique identifier for the label.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;uniqueIndex:idx_labels_target_key"`
+	// Name and Type identify the RRset the label is attached to; both are
+	// empty for a label of the zone itself.
+	Name string `gorm:"size:255;not null;default:'';uniqueIndex:idx_labels_target_key"`
+	Type string `gorm:"size:20;not null;default:'';uniqueIndex:idx_labels_target_key"`
+	// Key is the lower-case label key.
+	Key string `gorm:"size:63;not null;uniqueIndex:idx_labels_target_key;index"`
+	// Value is the label value; it may be empty.
+	Value string `gorm:"size:255;not null;default:''"`
+	// CreatedAt and UpdatedAt are managed by GORM.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for the Label model.
+func (Label) TableName() string {
+	return "labels"
+}
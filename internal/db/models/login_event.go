@@ -0,0 +1,51 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// LoginEvent records one sign-in attempt, successful or not, for the login
+// history of the user and the admin report. Unlike the activity log it keeps
+// the user agent and country of the client and flags suspicious logins.
+type LoginEvent struct {
+	// ID is the unique identifier for the event.
+	ID uint64 `gorm:"primaryKey;autoIncrement"`
+	// UserID is the user who signed in. Nil for failed attempts on unknown
+	// usernames.
+	UserID *uint64 `gorm:"index"`
+	// Username is the name that was entered, kept even if the user is deleted.
+	Username string `gorm:"size:100;not null;index"`
+	// Provider is how the user authenticated: local, ldap, oidc or totp.
+	Provider string `gorm:"size:20;not null"`
+	// Success tells whether the attempt signed the user in.
+	Success bool `gorm:"not null;index"`
+	// Reason is why a failed attempt was rejected.
+	Reason string `gorm:"size:255"`
+	// IPAddress is the client IP address.
+	IPAddress string `gorm:"size:45"`
+	// UserAgent is the User-Agent header of the client.
+	UserAgent string `gorm:"size:512"`
+	// Country is the ISO 3166-1 alpha-2 code of the client, if known.
+	Country string `gorm:"size:2"`
+	// Flags is the space-separated list of reasons a successful login looks
+	// suspicious, e.g. "new_country". Empty for ordinary logins.
+	Flags string `gorm:"size:255"`
+	// CreatedAt is the time of the attempt.
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// TableName specifies the database table name for the LoginEvent model.
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
+
+// FlagList returns Flags as a slice.
+func (e *LoginEvent) FlagList() []string {
+	return strings.Fields(e.Flags)
+}
+
+// Suspicious reports whether the login was flagged.
+func (e *LoginEvent) Suspicious() bool {
+	return e.Flags != ""
+}
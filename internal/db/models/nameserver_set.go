@@ -0,0 +1,56 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// NameserverSet is a named list of nameservers managed by administrators
+// (e.g. "anycast-eu"). The apex NS RRset of the zones assigned to the set
+// lists exactly these nameservers.
+type NameserverSet struct {
+	// ID is the unique identifier for the set.
+	ID uint `gorm:"primaryKey"`
+	// Name is the unique name of the set, e.g. "anycast-eu".
+	Name string `gorm:"unique;size:64;not null"`
+	// Description tells administrators what the set is for.
+	Description string `gorm:"size:255"`
+	// Nameservers are the lower-case, fully qualified nameserver names, one
+	// per line (e.g. "ns1.example.net.").
+	Nameservers string `gorm:"type:text;not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (NameserverSet) TableName() string { return "nameserver_sets" }
+
+// NameserverList returns the nameservers of the set.
+func (s *NameserverSet) NameserverList() []string {
+	return strings.Fields(s.Nameservers)
+}
+
+// ZoneNameserverSet assigns a zone to a nameserver set and records whether
+// the nameservers of the set were applied to the zone.
+type ZoneNameserverSet struct {
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"primaryKey;size:255"`
+	// SetID is the nameserver set of the zone.
+	SetID uint `gorm:"not null;index"`
+	// Set is the associated nameserver set.
+	Set *NameserverSet `gorm:"foreignKey:SetID;constraint:OnDelete:CASCADE"`
+	// AppliedAt is when the apex NS RRset last matched the set, or nil while
+	// the set is still to be applied.
+	AppliedAt *time.Time
+	// Error is the reason the last attempt to apply the set failed.
+	Error     string `gorm:"size:1024"`
+	UpdatedAt time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (ZoneNameserverSet) TableName() string { return "zone_nameserver_sets" }
+
+// Pending reports whether the set is still to be applied to the zone.
+func (z *ZoneNameserverSet) Pending() bool {
+	return z.AppliedAt == nil && z.Error == ""
+}
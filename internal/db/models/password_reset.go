@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a single-use token emailed to a local user who forgot
+// their password. Only the SHA-256 hash of the token is stored, so a database
+// leak does not expose usable reset links.
+type PasswordResetToken struct {
+	// ID is the unique identifier for the token.
+	ID uint64 `gorm:"primaryKey"`
+	// UserID is the user whose password the token may reset.
+	UserID uint64 `gorm:"not null;index"`
+	// User is the associated user; tokens are removed together with the user.
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// TokenHash is the hex-encoded SHA-256 hash of the token.
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+	// ExpiresAt is when the token stops being valid.
+	ExpiresAt time.Time `gorm:"not null"`
+	// UsedAt is when the token was redeemed (nil while unused).
+	UsedAt *time.Time
+	// CreatedAt is the timestamp when the token was issued (managed by GORM).
+	CreatedAt time.Time
+}
+
+// TableName specifies the database table name for the PasswordResetToken model.
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
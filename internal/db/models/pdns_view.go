@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PDNSView is a further PowerDNS server serving its own variant of zones of
+// the primary server, e.g. the internal view of a split-horizon setup (see
+// internal/splithorizon).
+type PDNSView struct {
+	// ID is the unique identifier for the view.
+	ID uint `gorm:"primaryKey"`
+	// Name identifies the view in the zone editor, e.g. "internal".
+	Name string `gorm:"unique;size:100;not null"`
+	// Description tells users which clients the view serves.
+	Description string `gorm:"size:255"`
+	// APIServerURL, APIKey and VHost connect to the PowerDNS API of the
+	// view like the settings of the primary server. The API key is never
+	// shown again after saving.
+	APIServerURL string `gorm:"size:512;not null"`
+	APIKey       string `gorm:"size:255;not null"`
+	VHost        string `gorm:"size:255;not null;default:localhost"`
+	// ReadOnly rejects every change to the zones of the view.
+	ReadOnly  bool `gorm:"not null;default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (PDNSView) TableName() string { return "pdns_views" }
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// RecordScheduleAction is the change a RecordSchedule applies.
+type RecordScheduleAction string
+
+const (
+	// RecordScheduleEnable enables the record.
+	RecordScheduleEnable RecordScheduleAction = "enable"
+	// RecordScheduleDisable disables the record.
+	RecordScheduleDisable RecordScheduleAction = "disable"
+)
+
+// RecordScheduleStatus is the state of a RecordSchedule.
+type RecordScheduleStatus string
+
+const (
+	// RecordSchedulePending is a schedule waiting for its time.
+	RecordSchedulePending RecordScheduleStatus = "pending"
+	// RecordScheduleRunning is a schedule claimed by a worker.
+	RecordScheduleRunning RecordScheduleStatus = "running"
+	// RecordScheduleDone is a schedule that was applied.
+	RecordScheduleDone RecordScheduleStatus = "done"
+	// RecordScheduleFailed is a schedule that could not be applied.
+	RecordScheduleFailed RecordScheduleStatus = "failed"
+	// RecordScheduleCanceled is a schedule a user canceled before it ran.
+	RecordScheduleCanceled RecordScheduleStatus = "canceled"
+)
+
+// RecordSchedule enables or disables a single record at a point in time. The
+// record is identified by zone, name, type and content.
+type RecordSchedule struct {
+	// ID is the unique identifier for the schedule.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;index"`
+	// Name is the canonical record name with trailing dot.
+	Name string `gorm:"size:255;not null"`
+	// Type is the record type (A, AAAA, …).
+	Type string `gorm:"size:20;not null"`
+	// Content is the record content as stored in PowerDNS.
+	Content string `gorm:"type:text;not null"`
+	// Action is the change to apply.
+	Action RecordScheduleAction `gorm:"type:varchar(20);not null"`
+	// RunAt is when the change is due.
+	RunAt time.Time `gorm:"not null;index"`
+	// Status is the state of the schedule.
+	Status RecordScheduleStatus `gorm:"type:varchar(20);not null;default:'pending';index"`
+	// Error is the reason a failed schedule could not be applied.
+	Error string `gorm:"type:text"`
+	// CreatedByID is the user who created the schedule (nil once deleted).
+	CreatedByID *uint64
+	// CreatedBy is the associated user.
+	CreatedBy *User `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL"`
+	// AppliedAt is when the schedule was applied or failed.
+	AppliedAt *time.Time
+	// CreatedAt is the timestamp when the schedule was created (managed by GORM).
+	CreatedAt time.Time
+	// UpdatedAt is the timestamp when the schedule was last updated (managed by GORM).
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for the RecordSchedule model.
+func (RecordSchedule) TableName() string {
+	return "record_schedules"
+}
+
+// Disabled reports whether the schedule disables the record.
+func (s *RecordSchedule) Disabled() bool {
+	return s.Action == RecordScheduleDisable
+}
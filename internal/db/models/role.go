@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"slices"
+	"strings"
+	"time"
+)
 
 // Role represents a role in the role-based access control (RBAC) system.
 // Roles are collections of permissions that can be assigned to users or mapped from groups.
@@ -14,6 +18,14 @@ type Role struct {
 	Description string `gorm:"size:255"`
 	// IsSystem indicates if this is a system role that cannot be deleted.
 	IsSystem bool `gorm:"default:false"`
+	// RecordTypes optionally restricts which DNS record types members of this
+	// role may create, edit or delete, as a comma-separated list (e.g.
+	// "A,AAAA,CNAME,TXT"). Empty means all record types are allowed.
+	RecordTypes string `gorm:"size:255"`
+	// Zones optionally restricts members of this role to the zones matching
+	// one of these patterns, one per line (see auth.ZonePattern). Empty means
+	// the role adds no restriction.
+	Zones string `gorm:"size:2048"`
 	// CreatedAt is the timestamp when the role was created (managed by GORM).
 	CreatedAt time.Time
 	// UpdatedAt is the timestamp when the role was last updated (managed by GORM).
@@ -25,3 +37,53 @@ type Role struct {
 func (Role) TableName() string {
 	return "roles"
 }
+
+// RecordTypeList returns the record types this role is restricted to, or nil
+// when the role may edit every record type.
+func (r *Role) RecordTypeList() []string {
+	return splitRecordTypes(r.RecordTypes)
+}
+
+// ZonePatternList returns the zone patterns of the role, or nil when the role
+// does not restrict zones.
+func (r *Role) ZonePatternList() []string {
+	return strings.Fields(r.Zones)
+}
+
+// NormalizeRecordTypes cleans a user-supplied record type list: entries are
+// split on commas and whitespace, upper-cased, de-duplicated and sorted.
+// Entries that are not plain alphanumeric tokens are dropped.
+func NormalizeRecordTypes(raw string) string {
+	return strings.Join(splitRecordTypes(raw), ",")
+}
+
+func splitRecordTypes(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+
+	var types []string
+
+	for _, f := range fields {
+		f = strings.ToUpper(f)
+		if !isRecordTypeToken(f) || slices.Contains(types, f) {
+			continue
+		}
+
+		types = append(types, f)
+	}
+
+	slices.Sort(types)
+
+	return types
+}
+
+func isRecordTypeToken(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestNormalizeRecordTypes(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"", ""},
+		{"a, aaaa ,CNAME", "A,AAAA,CNAME"},
+		{"TXT TXT\ttxt", "TXT"},
+		{"mx,,ns", "MX,NS"},
+		{"A, <script>, TYPE65534", "A,TYPE65534"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeRecordTypes(tt.raw); got != tt.want {
+			t.Errorf("NormalizeRecordTypes(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+
+	if got := (&Role{}).RecordTypeList(); got != nil {
+		t.Errorf("RecordTypeList() on unrestricted role = %v, want nil", got)
+	}
+}
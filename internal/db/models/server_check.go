@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ServerCheck is an availability check of the PowerDNS API of the primary
+// server or of a view (see internal/servermonitor).
+type ServerCheck struct {
+	// ID is the unique identifier for the check.
+	ID uint64 `gorm:"primaryKey"`
+	// Server is the name of the checked view, empty for the primary server.
+	Server string `gorm:"size:100;not null;index:idx_server_checks_server"`
+	// OK reports whether the API answered.
+	OK bool `gorm:"not null"`
+	// LatencyMS is how long the API took to answer or fail, in milliseconds.
+	LatencyMS int64 `gorm:"not null"`
+	// Error is why the check failed.
+	Error     string    `gorm:"size:500"`
+	CheckedAt time.Time `gorm:"not null;index;index:idx_server_checks_server"`
+}
+
+// TableName overrides the default GORM table name.
+func (ServerCheck) TableName() string { return "server_checks" }
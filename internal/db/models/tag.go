@@ -16,9 +16,13 @@ type Tag struct {
 func (Tag) TableName() string { return "tags" }
 
 // ZoneTag links a PowerDNS zone (by its canonical name, e.g. "example.com.") to a tag.
+// When Inherit is set the tag also applies to every sub-zone of ZoneID
+// (e.g. "dev.example.com."), including zones created later, unless the sub-zone
+// blocks inheritance via ZoneAccessOption.
 type ZoneTag struct {
 	ZoneID    string    `gorm:"primaryKey;column:zone_id;size:255"`
 	TagID     uint      `gorm:"primaryKey;column:tag_id"`
+	Inherit   bool      `gorm:"not null;default:false"`
 	Tag       Tag       `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE"`
 	CreatedAt time.Time
 }
@@ -26,6 +30,17 @@ type ZoneTag struct {
 // TableName overrides the default GORM table name.
 func (ZoneTag) TableName() string { return "zone_tags" }
 
+// ZoneAccessOption holds per-zone access control options.
+type ZoneAccessOption struct {
+	ZoneID string `gorm:"primaryKey;column:zone_id;size:255"`
+	// BlockInheritance stops the zone from inheriting tags of its parent zones.
+	BlockInheritance bool `gorm:"not null;default:false"`
+	UpdatedAt        time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (ZoneAccessOption) TableName() string { return "zone_access_options" }
+
 // UserTag grants a user access to all zones carrying a specific tag.
 type UserTag struct {
 	UserID    uint64    `gorm:"primaryKey;column:user_id"`
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Tenant is an account of a hosting provider's customer. Users, groups and
+// zones of a tenant are only visible to that tenant; users outside any tenant
+// belong to the provider. API keys belong to the tenant of their user.
+type Tenant struct {
+	// ID is the unique identifier for the tenant.
+	ID uint `gorm:"primaryKey"`
+	// Name is the unique name of the tenant, e.g. "acme".
+	Name string `gorm:"unique;size:64;not null"`
+	// Description tells administrators who the tenant is.
+	Description string `gorm:"size:255"`
+	// MaxZones is the number of zones the tenant may have; 0 is unlimited.
+	MaxZones int `gorm:"not null;default:0"`
+	// MaxRecordsPerZone is the number of records each zone of the tenant may
+	// have; 0 is unlimited.
+	MaxRecordsPerZone int `gorm:"not null;default:0"`
+	// MaxAPIRequests is the number of requests per minute the API keys of the
+	// tenant may send together; 0 is unlimited.
+	MaxAPIRequests int `gorm:"not null;default:0"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (Tenant) TableName() string { return "tenants" }
+
+// ZoneTenant assigns a zone to a tenant. Zones without an assignment belong
+// to the provider.
+type ZoneTenant struct {
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"primaryKey;size:255"`
+	// TenantID is the tenant the zone belongs to.
+	TenantID  uint `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (ZoneTenant) TableName() string { return "zone_tenants" }
+
+// TenantUsage is the usage of a tenant in a calendar month (UTC), kept for
+// the billing of the provider.
+type TenantUsage struct {
+	ID       uint64 `gorm:"primaryKey"`
+	TenantID uint   `gorm:"not null;uniqueIndex:idx_tenant_usages_month"`
+	// TenantName is the name of the tenant when the usage was last updated,
+	// kept for tenants deleted since.
+	TenantName string `gorm:"size:64;not null"`
+	// Month is the month of the usage, e.g. "2026-10".
+	Month string `gorm:"size:7;not null;uniqueIndex:idx_tenant_usages_month"`
+	// Zones and Records are the most zones and records the tenant had in the
+	// month.
+	Zones   int `gorm:"not null;default:0"`
+	Records int `gorm:"not null;default:0"`
+	// APIRequests is the number of API requests of the tenant in the month.
+	APIRequests int64 `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (TenantUsage) TableName() string { return "tenant_usages" }
+
+// UsageMonth is the usage of the whole installation in a calendar month
+// (UTC) and the delivery of the summary of the month.
+type UsageMonth struct {
+	// Month is the month, e.g. "2026-10".
+	Month string `gorm:"primaryKey;size:7"`
+	// Queries is the number of queries PowerDNS answered in the month.
+	// PowerDNS counts queries per server, not per zone.
+	Queries int64 `gorm:"not null;default:0"`
+	// QueryCounter is the PowerDNS query counter when the queries were last
+	// counted.
+	QueryCounter int64 `gorm:"not null;default:0"`
+	// SentAt is when the summary of the month was posted to the webhook.
+	SentAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (UsageMonth) TableName() string { return "usage_months" }
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// TSIGKey is a shared secret that signs RFC 2136 dynamic updates sent to the
+// update gateway (see internal/dnsupdate), e.g. by a DHCP server. The key may
+// change the record types it lists in the zones matching its zone patterns.
+type TSIGKey struct {
+	// ID is the unique identifier for the key.
+	ID uint `gorm:"primaryKey"`
+	// Name is the key name clients sign with, lower-case and fully qualified
+	// (e.g. "dhcp-updater.").
+	Name string `gorm:"unique;size:255;not null"`
+	// Algorithm is the TSIG algorithm name, e.g. "hmac-sha256.".
+	Algorithm string `gorm:"size:64;not null"`
+	// Secret is the base64-encoded shared secret. It is never shown again
+	// after saving.
+	Secret string `gorm:"size:255;not null"`
+	// Zones limits the key to the zones matching one of these patterns, one
+	// per line (see auth.ZonePattern). Empty means all zones.
+	Zones string `gorm:"size:2048"`
+	// RecordTypes are the record types the key may change, as a
+	// comma-separated list (e.g. "A,PTR,DHCID").
+	RecordTypes string `gorm:"size:255;not null"`
+	Enabled     bool   `gorm:"not null;default:false"`
+	// LastUsedAt is when the key last signed an accepted update.
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// TableName overrides the default GORM table name.
+func (TSIGKey) TableName() string { return "tsig_keys" }
+
+// AlgorithmName returns the algorithm without the trailing dot, as key
+// statements of BIND and ISC dhcpd spell it.
+func (k *TSIGKey) AlgorithmName() string {
+	return strings.TrimSuffix(k.Algorithm, ".")
+}
+
+// RecordTypeList returns the record types the key may change.
+func (k *TSIGKey) RecordTypeList() []string {
+	return splitRecordTypes(k.RecordTypes)
+}
+
+// ZonePatternList returns the zone patterns of the key, or nil when it may
+// update all zones.
+func (k *TSIGKey) ZonePatternList() []string {
+	return strings.Fields(k.Zones)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/alexedwards/argon2id"
@@ -28,18 +29,37 @@ type User struct {
 	ID uint64 `gorm:"primaryKey"`
 	// Active indicates whether the user account is active and can log in.
 	Active bool
+	// Pending marks a self-registered account that awaits approval by an
+	// administrator; it stays inactive until then.
+	Pending bool `gorm:"not null;default:false"`
 	// Username is the unique username for login.
 	Username string `gorm:"unique;size:100;not null"`
 	// Email is the user's email address.
 	Email string `gorm:"size:255;not null"`
 	// Password is the Argon2id hashed password (only used for local authentication).
 	Password string `gorm:"size:255"`
-	// DisplayName is the user's display name.
+	// MustChangePassword forces the user to choose a new password at the next
+	// login, e.g. after an administrator set it (local authentication only).
+	MustChangePassword bool `gorm:"not null;default:false"`
+	// PasswordChangedAt is when the password was last set; nil for passwords
+	// set before it was tracked, whose age is counted from CreatedAt.
+	PasswordChangedAt *time.Time
+	// DisplayName is the user's display name. When empty, FullName composes one
+	// from FirstName and LastName.
 	DisplayName string `gorm:"size:255"`
+	// FirstName is the user's given name (synchronized from LDAP/OIDC when available).
+	FirstName string `gorm:"size:100"`
+	// LastName is the user's family name (synchronized from LDAP/OIDC when available).
+	LastName string `gorm:"size:100"`
 	// RoleID is the ID of the role assigned to this user.
 	RoleID uint `gorm:"column:role_id;not null"`
 	// Role is the associated role (enforced with a foreign key constraint).
 	Role Role `gorm:"foreignKey:RoleID;references:ID;constraint:OnDelete:RESTRICT,OnUpdate:CASCADE"`
+	// TenantID is the tenant the user belongs to; nil for users of the
+	// provider.
+	TenantID *uint `gorm:"index"`
+	// Tenant is the associated tenant; a tenant with users cannot be deleted.
+	Tenant *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:RESTRICT"`
 	// AuthSource indicates how this user authenticates (local, oidc, or ldap).
 	AuthSource AuthSource `gorm:"type:varchar(20);not null;default:'local'"`
 	// ExternalID is the external identifier for OIDC (sub claim) or LDAP (DN) users.
@@ -50,12 +70,17 @@ type User struct {
 	TOTPEnabled bool
 	// TOTPRequired indicates an admin has mandated TOTP for this user.
 	TOTPRequired bool
+	// ServiceAccount marks technical accounts (e.g. used by automation) that
+	// are exempt from the inactive user cleanup.
+	ServiceAccount bool `gorm:"not null;default:false"`
 	// DashboardPageSize is the user's preferred number of items per page on the dashboard (0 = use default).
 	DashboardPageSize int `gorm:"default:0"`
 	// ZoneEditPageSize is the user's preferred number of records per page on the zone edit page (0 = use default).
 	ZoneEditPageSize int `gorm:"default:0"`
 	// ActivityLogPageSize is the user's preferred number of entries per page on the admin activity log (0 = use default).
 	ActivityLogPageSize int `gorm:"default:0"`
+	// Locale is the user's preferred BCP 47 locale for number and date formatting (empty = default).
+	Locale string `gorm:"size:16"`
 	// CreatedAt is the timestamp when the user was created (managed by GORM).
 	CreatedAt time.Time
 	// UpdatedAt is the timestamp when the user was last updated (managed by GORM).
@@ -64,6 +89,21 @@ type User struct {
 	DeletedAt *time.Time
 }
 
+// FullName returns the name shown for the user in the UI: the explicit
+// DisplayName when set, otherwise first and last name joined by a space,
+// otherwise the username.
+func (u User) FullName() string { //nolint:gocritic // value receiver so templates can call it on a User copy
+	if name := strings.TrimSpace(u.DisplayName); name != "" {
+		return name
+	}
+
+	if name := strings.TrimSpace(strings.TrimSpace(u.FirstName) + " " + strings.TrimSpace(u.LastName)); name != "" {
+		return name
+	}
+
+	return u.Username
+}
+
 // HashPassword hashes a plaintext password using the Argon2id algorithm.
 // This function should be used when creating or updating local user passwords.
 // It uses the default Argon2id parameters for secure password hashing.
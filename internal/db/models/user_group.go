@@ -17,6 +17,10 @@ type UserGroup struct {
 	// Group is the associated group (loaded via foreign key).
 	// When a group is deleted, all user memberships in that group are automatically removed (CASCADE).
 	Group Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	// Manual marks a membership added by an administrator. Group sync never
+	// removes manual memberships, even when the identity provider stops
+	// reporting the group.
+	Manual bool `gorm:"not null;default:false"`
 	// CreatedAt is the timestamp when the user was added to the group (managed by GORM).
 	CreatedAt time.Time
 }
@@ -26,3 +30,23 @@ type UserGroup struct {
 func (UserGroup) TableName() string {
 	return "user_groups"
 }
+
+// PendingGroupRemoval is a group membership removal that a sync under the
+// "approval" policy queued instead of applying. An administrator approves
+// (removes the membership) or rejects it (keeps the membership as manual).
+type PendingGroupRemoval struct {
+	ID      uint64 `gorm:"primaryKey"`
+	UserID  uint64 `gorm:"not null;uniqueIndex:idx_pending_user_group"`
+	GroupID uint   `gorm:"not null;uniqueIndex:idx_pending_user_group"`
+	// User and Group are the membership to remove; pending removals are
+	// dropped together with the user or group.
+	User  User  `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Group Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	// CreatedAt is when the sync first queued the removal.
+	CreatedAt time.Time
+}
+
+// TableName specifies the database table name for the PendingGroupRemoval model.
+func (PendingGroupRemoval) TableName() string {
+	return "pending_group_removals"
+}
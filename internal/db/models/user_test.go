@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestUser_FullName(t *testing.T) {
+	tests := []struct {
+		name string
+		user User
+		want string
+	}{
+		{
+			name: "display name wins",
+			user: User{Username: "jdoe", DisplayName: "Jane D.", FirstName: "Jane", LastName: "Doe"},
+			want: "Jane D.",
+		},
+		{name: "first and last", user: User{Username: "jdoe", FirstName: "Jane", LastName: "Doe"}, want: "Jane Doe"},
+		{name: "first only", user: User{Username: "jdoe", FirstName: " Jane "}, want: "Jane"},
+		{name: "last only", user: User{Username: "jdoe", LastName: "Doe"}, want: "Doe"},
+		{name: "blank display name", user: User{Username: "jdoe", DisplayName: "  "}, want: "jdoe"},
+		{name: "username fallback", user: User{Username: "jdoe"}, want: "jdoe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.FullName(); got != tt.want {
+				t.Errorf("FullName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// ZoneClaimMethod is how a claimant proves control of a zone.
+type ZoneClaimMethod string
+
+const (
+	// ZoneClaimTXT is proven by publishing the token in a TXT record.
+	ZoneClaimTXT ZoneClaimMethod = "txt"
+	// ZoneClaimEmail is proven by following the link mailed to hostmaster@<zone>.
+	ZoneClaimEmail ZoneClaimMethod = "email"
+)
+
+// ZoneClaimStatus is the state of a ZoneClaim.
+type ZoneClaimStatus string
+
+const (
+	// ZoneClaimPending is a claim waiting for the claimant to prove control.
+	ZoneClaimPending ZoneClaimStatus = "pending"
+	// ZoneClaimVerified is a proven claim waiting for review.
+	ZoneClaimVerified ZoneClaimStatus = "verified"
+	// ZoneClaimApproved is a claim whose ownership grant has been created.
+	ZoneClaimApproved ZoneClaimStatus = "approved"
+	// ZoneClaimRejected is a claim an administrator declined.
+	ZoneClaimRejected ZoneClaimStatus = "rejected"
+	// ZoneClaimRevoked is an approved claim whose grant was removed again.
+	ZoneClaimRevoked ZoneClaimStatus = "revoked"
+)
+
+// ZoneClaim is a user's claim to an existing zone. The claimant proves control
+// of the domain with a TXT record or through the hostmaster mailbox; an
+// administrator then approves the claim, which creates a ZoneOwnership.
+type ZoneClaim struct {
+	// ID is the unique identifier for the claim.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot (e.g. "example.com.").
+	ZoneName string `gorm:"size:255;not null;index"`
+	// RequesterID is the user who claims the zone.
+	RequesterID uint64 `gorm:"not null;index"`
+	// Requester is the associated user; claims are removed together with the user.
+	Requester User `gorm:"foreignKey:RequesterID;constraint:OnDelete:CASCADE"`
+	// Method is how control of the zone is proven.
+	Method ZoneClaimMethod `gorm:"type:varchar(10);not null"`
+	// Token is the challenge the claimant publishes or receives by email.
+	Token string `gorm:"size:64;not null"`
+	// Status is the state of the claim.
+	Status ZoneClaimStatus `gorm:"type:varchar(20);not null;default:'pending';index"`
+	// VerifiedAt is when control of the zone was proven.
+	VerifiedAt *time.Time
+	// ReviewerID is the administrator who approved or rejected the claim.
+	ReviewerID *uint64
+	// Reviewer is the associated administrator.
+	Reviewer *User `gorm:"foreignKey:ReviewerID;constraint:OnDelete:SET NULL"`
+	// ReviewComment is the reviewer's note to the claimant.
+	ReviewComment string `gorm:"type:text"`
+	// ReviewedAt is when the claim was approved or rejected.
+	ReviewedAt *time.Time
+	// CreatedAt is the timestamp when the claim was submitted (managed by GORM).
+	CreatedAt time.Time
+	// UpdatedAt is the timestamp when the claim was last updated (managed by GORM).
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for the ZoneClaim model.
+func (ZoneClaim) TableName() string {
+	return "zone_claims"
+}
+
+// Open reports whether the claim still awaits verification or review.
+func (c *ZoneClaim) Open() bool {
+	return c.Status == ZoneClaimPending || c.Status == ZoneClaimVerified
+}
+
+// ZoneOwnership grants a user access to a zone independent of tags. It is
+// created when an administrator approves a ZoneClaim.
+type ZoneOwnership struct {
+	// ID is the unique identifier for the grant.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;uniqueIndex:idx_zone_ownership"`
+	// UserID is the user the zone is granted to.
+	UserID uint64 `gorm:"not null;uniqueIndex:idx_zone_ownership;index"`
+	// User is the associated user; grants are removed together with the user.
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// ClaimID is the claim the grant was created from.
+	ClaimID *uint64
+	// CreatedAt is the timestamp when the grant was created (managed by GORM).
+	CreatedAt time.Time
+}
+
+// TableName specifies the database table name for the ZoneOwnership model.
+func (ZoneOwnership) TableName() string {
+	return "zone_ownerships"
+}
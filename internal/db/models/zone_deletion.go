@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// ZoneDeletionStatus is the state of a ZoneDeletion.
+type ZoneDeletionStatus string
+
+const (
+	// ZoneDeletionPending is a deletion waiting for its grace period to end.
+	ZoneDeletionPending ZoneDeletionStatus = "pending"
+	// ZoneDeletionRunning is a deletion claimed by a worker.
+	ZoneDeletionRunning ZoneDeletionStatus = "running"
+	// ZoneDeletionPurged is a deletion whose zone was removed from PowerDNS.
+	ZoneDeletionPurged ZoneDeletionStatus = "purged"
+	// ZoneDeletionRestored is a deletion a user reverted before the purge.
+	ZoneDeletionRestored ZoneDeletionStatus = "restored"
+	// ZoneDeletionFailed is a deletion whose purge failed.
+	ZoneDeletionFailed ZoneDeletionStatus = "failed"
+)
+
+// ZoneDeletion is a zone deleted in soft-delete mode. The zone stays in
+// PowerDNS, frozen, until PurgeAt; Snapshot holds its content at deletion.
+type ZoneDeletion struct {
+	// ID is the unique identifier for the deletion.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;index"`
+	// Snapshot is the JSON encoded activitylog.ZoneSnapshot of the zone.
+	Snapshot string `gorm:"type:text"`
+	// PurgeAt is when the zone is removed from PowerDNS.
+	PurgeAt time.Time `gorm:"not null;index"`
+	// Status is the state of the deletion.
+	Status ZoneDeletionStatus `gorm:"type:varchar(20);not null;default:'pending';index"`
+	// Error is the reason a failed purge could not be applied.
+	Error string `gorm:"type:text"`
+	// DeletedByID is the user who deleted the zone (nil once deleted).
+	DeletedByID *uint64
+	// DeletedBy is the associated user.
+	DeletedBy *User `gorm:"foreignKey:DeletedByID;constraint:OnDelete:SET NULL"`
+	// ClosedAt is when the zone was purged or restored, or the purge failed.
+	ClosedAt *time.Time
+	// CreatedAt is the timestamp when the zone was deleted (managed by GORM).
+	CreatedAt time.Time
+	// UpdatedAt is the timestamp when the deletion was last updated (managed by GORM).
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for the ZoneDeletion model.
+func (ZoneDeletion) TableName() string {
+	return "zone_deletions"
+}
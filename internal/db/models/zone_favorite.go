@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ZoneFavorite is a zone a user starred to find it quickly on the dashboard.
+type ZoneFavorite struct {
+	// ID is the unique identifier for the favorite.
+	ID uint64 `gorm:"primaryKey"`
+	// UserID is the user who starred the zone.
+	UserID uint64 `gorm:"not null;uniqueIndex:idx_zone_favorites_user_zone"`
+	// User is the associated user; favorites are removed together with the user.
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;uniqueIndex:idx_zone_favorites_user_zone"`
+	// CreatedAt is the timestamp when the zone was starred (managed by GORM).
+	CreatedAt time.Time
+}
+
+// TableName specifies the database table name for the ZoneFavorite model.
+func (ZoneFavorite) TableName() string {
+	return "zone_favorites"
+}
+
+// ZoneVisit is the last time a user opened a zone in the zone editor. Only
+// the most recent visits of each user are kept.
+type ZoneVisit struct {
+	// ID is the unique identifier for the visit.
+	ID uint64 `gorm:"primaryKey"`
+	// UserID is the user who opened the zone.
+	UserID uint64 `gorm:"not null;uniqueIndex:idx_zone_visits_user_zone"`
+	// User is the associated user; visits are removed together with the user.
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;uniqueIndex:idx_zone_visits_user_zone"`
+	// VisitedAt is when the user last opened the zone.
+	VisitedAt time.Time `gorm:"not null;index"`
+}
+
+// TableName specifies the database table name for the ZoneVisit model.
+func (ZoneVisit) TableName() string {
+	return "zone_visits"
+}
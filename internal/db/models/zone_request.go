@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ZoneRequestStatus is the review state of a ZoneRequest.
+type ZoneRequestStatus string
+
+const (
+	// ZoneRequestPending is a request waiting for review.
+	ZoneRequestPending ZoneRequestStatus = "pending"
+	// ZoneRequestApproved is a request whose zone has been created.
+	ZoneRequestApproved ZoneRequestStatus = "approved"
+	// ZoneRequestRejected is a request an administrator declined.
+	ZoneRequestRejected ZoneRequestStatus = "rejected"
+)
+
+// ZoneRequest is a zone a user without the zone.create permission asked for.
+// An administrator approves it, which creates the zone and tags it with the
+// requester's team tag, or rejects it.
+type ZoneRequest struct {
+	// ID is the unique identifier for the request.
+	ID uint64 `gorm:"primaryKey"`
+	// Name is the canonical zone name with trailing dot (e.g. "example.com.").
+	Name string `gorm:"size:255;not null;index"`
+	// Purpose is the requester's justification.
+	Purpose string `gorm:"type:text;not null"`
+	// Nameservers are the desired NS hostnames, newline-separated (empty = template default).
+	Nameservers string `gorm:"type:text"`
+	// RequesterID is the user who submitted the request.
+	RequesterID uint64 `gorm:"not null;index"`
+	// Requester is the associated user; requests are removed together with the user.
+	Requester User `gorm:"foreignKey:RequesterID;constraint:OnDelete:CASCADE"`
+	// TagID is the team tag the zone is assigned to on approval (nil = none).
+	TagID *uint
+	// Tag is the associated team tag.
+	Tag *Tag `gorm:"foreignKey:TagID;constraint:OnDelete:SET NULL"`
+	// Status is the review state.
+	Status ZoneRequestStatus `gorm:"type:varchar(20);not null;default:'pending';index"`
+	// ReviewerID is the administrator who approved or rejected the request.
+	ReviewerID *uint64
+	// Reviewer is the associated administrator.
+	Reviewer *User `gorm:"foreignKey:ReviewerID;constraint:OnDelete:SET NULL"`
+	// ReviewComment is the reviewer's note to the requester.
+	ReviewComment string `gorm:"type:text"`
+	// ReviewedAt is when the request was approved or rejected.
+	ReviewedAt *time.Time
+	// CreatedAt is the timestamp when the request was submitted (managed by GORM).
+	CreatedAt time.Time
+	// UpdatedAt is the timestamp when the request was last updated (managed by GORM).
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for the ZoneRequest model.
+func (ZoneRequest) TableName() string {
+	return "zone_requests"
+}
+
+// NameserverList returns the requested nameservers as a slice.
+func (r *ZoneRequest) NameserverList() []string {
+	return strings.Fields(r.Nameservers)
+}
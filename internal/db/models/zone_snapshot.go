@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ZoneSnapshot is the content of a zone saved before it was deleted or
+// changed in bulk, from which the zone can be restored.
+type ZoneSnapshot struct {
+	// ID is the unique identifier for the snapshot.
+	ID uint64 `gorm:"primaryKey"`
+	// ZoneName is the canonical zone name with trailing dot.
+	ZoneName string `gorm:"size:255;not null;index"`
+	// Reason is the operation the snapshot was taken before, e.g. "delete".
+	Reason string `gorm:"size:20;not null"`
+	// RRsetCount is the number of RRsets in the snapshot.
+	RRsetCount int `gorm:"not null;default:0"`
+	// Snapshot is the JSON encoded activitylog.ZoneSnapshot of the zone.
+	Snapshot string `gorm:"type:text;not null"`
+	// CreatedByID is the user whose operation took the snapshot (nil once
+	// deleted or for background jobs).
+	CreatedByID *uint64
+	// CreatedBy is the associated user.
+	CreatedBy *User `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL"`
+	// CreatedAt is the timestamp when the snapshot was taken (managed by GORM).
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// TableName specifies the database table name for the ZoneSnapshot model.
+func (ZoneSnapshot) TableName() string {
+	return "zone_snapshots"
+}
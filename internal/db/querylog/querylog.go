@@ -0,0 +1,348 @@
+// Package querylog is a GORM plugin that measures the queries of the
+// application database. Every query is recorded in the
+// db_query_duration_seconds histogram; queries slower than a threshold are
+// kept in a rolling list, and identical statements are counted so that N+1
+// patterns, e.g. one permission query per zone of a list, stand out. Both are
+// shown under Admin → System Information → Database Queries.
+package querylog
+
+import (
+	"cmp"
+	"errors"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+const (
+	// DefaultThreshold is the duration from which a query is slow.
+	DefaultThreshold = 200 * time.Millisecond
+
+	// maxSlow is the number of slow queries kept.
+	maxSlow = 100
+
+	// maxStatements is the number of distinct statements counted; further
+	// statements are not counted until Reset.
+	maxStatements = 500
+
+	// maxSQL is the length at which statements are cut.
+	maxSQL = 2000
+
+	startKey = "querylog:start"
+)
+
+var duration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_query_duration_seconds",
+	Help:    "Duration of application database queries, by operation and table.",
+	Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"operation", "table"})
+
+var (
+	// placeholders matches the numbered placeholders of PostgreSQL.
+	placeholders = regexp.MustCompile(`\$\d+`)
+	// placeholderLists matches IN lists, whose length varies with the data.
+	placeholderLists = regexp.MustCompile(`\(\?(?:\s*,\s*\?)+\)`)
+)
+
+// Query is a slow query.
+type Query struct {
+	Time      time.Time
+	Duration  time.Duration
+	Operation string
+	Table     string
+	// SQL is the statement with placeholders; the values are not kept.
+	SQL   string
+	Rows  int64
+	Error string
+	// Caller is the application function that ran the query.
+	Caller    string
+	RequestID string
+}
+
+// Statement counts the runs of one statement.
+type Statement struct {
+	SQL   string
+	Count int64
+	Total time.Duration
+	Max   time.Duration
+	// Caller is the application function of the first run.
+	Caller string
+}
+
+// Average returns the mean duration of the runs.
+func (s Statement) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+
+	return s.Total / time.Duration(s.Count)
+}
+
+// Recorder is the GORM plugin. It is safe for concurrent use.
+type Recorder struct {
+	mu         sync.Mutex
+	threshold  time.Duration
+	since      time.Time
+	slow       []Query // ring buffer, next is the oldest once full
+	next       int
+	statements map[string]*Statement
+}
+
+// Default is the recorder of the application database.
+var Default = New(DefaultThreshold)
+
+// New returns a recorder keeping the queries slower than threshold.
+func New(threshold time.Duration) *Recorder {
+	return &Recorder{threshold: threshold, since: time.Now(), statements: map[string]*Statement{}}
+}
+
+// SetThreshold changes the duration from which a query is slow; 0 keeps
+// DefaultThreshold.
+func (r *Recorder) SetThreshold(threshold time.Duration) {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	r.mu.Lock()
+	r.threshold = threshold
+	r.mu.Unlock()
+}
+
+// Threshold returns the duration from which a query is slow.
+func (r *Recorder) Threshold() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.threshold
+}
+
+// Since returns when the recorder started or was last reset.
+func (r *Recorder) Since() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.since
+}
+
+// Name implements gorm.Plugin.
+func (r *Recorder) Name() string { return "querylog" }
+
+// Initialize implements gorm.Plugin by registering callbacks around every
+// kind of query.
+func (r *Recorder) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("querylog:before_create", before),
+		cb.Create().After("gorm:create").Register("querylog:after_create", r.after("create")),
+		cb.Query().Before("gorm:query").Register("querylog:before_query", before),
+		cb.Query().After("gorm:query").Register("querylog:after_query", r.after("query")),
+		cb.Update().Before("gorm:update").Register("querylog:before_update", before),
+		cb.Update().After("gorm:update").Register("querylog:after_update", r.after("update")),
+		cb.Delete().Before("gorm:delete").Register("querylog:before_delete", before),
+		cb.Delete().After("gorm:delete").Register("querylog:after_delete", r.after("delete")),
+		cb.Row().Before("gorm:row").Register("querylog:before_row", before),
+		cb.Row().After("gorm:row").Register("querylog:after_row", r.after("row")),
+		cb.Raw().Before("gorm:raw").Register("querylog:before_raw", before),
+		cb.Raw().After("gorm:raw").Register("querylog:after_raw", r.after("raw")),
+	)
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (r *Recorder) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		// Subqueries are built in a dry run; only the outer query runs.
+		if db.DryRun {
+			return
+		}
+
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+
+		start, ok := v.(time.Time)
+		if !ok || db.Statement.SQL.Len() == 0 {
+			return
+		}
+
+		q := Query{
+			Time:      start,
+			Duration:  time.Since(start),
+			Operation: operation,
+			Table:     db.Statement.Table,
+			SQL:       db.Statement.SQL.String(),
+			Rows:      db.RowsAffected,
+		}
+
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			q.Error = db.Error.Error()
+		}
+
+		if ctx := db.Statement.Context; ctx != nil {
+			q.RequestID = requestid.FromContext(ctx)
+		}
+
+		r.Record(&q)
+	}
+}
+
+// Record counts q and keeps it when it is slow. Caller is filled in when
+// empty and needed.
+func (r *Recorder) Record(q *Query) {
+	table := q.Table
+	if table == "" {
+		table = "-"
+	}
+
+	duration.WithLabelValues(q.Operation, table).Observe(q.Duration.Seconds())
+
+	sql := Fingerprint(q.SQL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, ok := r.statements[sql]
+	if !ok && len(r.statements) < maxStatements {
+		if q.Caller == "" {
+			q.Caller = caller()
+		}
+
+		st = &Statement{SQL: sql, Caller: q.Caller}
+		r.statements[sql] = st
+	}
+
+	if st != nil {
+		st.Count++
+		st.Total += q.Duration
+		st.Max = max(st.Max, q.Duration)
+	}
+
+	if q.Duration < r.threshold {
+		return
+	}
+
+	if q.Caller == "" {
+		q.Caller = caller()
+	}
+
+	q.SQL = truncate(q.SQL)
+
+	if len(r.slow) < maxSlow {
+		r.slow = append(r.slow, *q)
+		return
+	}
+
+	r.slow[r.next] = *q
+	r.next = (r.next + 1) % maxSlow
+}
+
+// Slow returns the kept slow queries, newest first.
+func (r *Recorder) Slow() []Query {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Query, 0, len(r.slow))
+	out = append(out, r.slow[r.next:]...)
+	out = append(out, r.slow[:r.next]...)
+	slices.Reverse(out)
+
+	return out
+}
+
+// Statements returns the counted statements sorted by order, e.g. ByCount.
+func (r *Recorder) Statements(order func(a, b *Statement) int) []Statement {
+	r.mu.Lock()
+
+	list := make([]*Statement, 0, len(r.statements))
+	for _, st := range r.statements {
+		list = append(list, st)
+	}
+
+	slices.SortFunc(list, order)
+
+	out := make([]Statement, len(list))
+	for i, st := range list {
+		out[i] = *st
+	}
+
+	r.mu.Unlock()
+
+	return out
+}
+
+// ByCount orders statements by descending count.
+func ByCount(a, b *Statement) int {
+	return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.SQL, b.SQL))
+}
+
+// ByTotal orders statements by descending total duration.
+func ByTotal(a, b *Statement) int {
+	return cmp.Or(cmp.Compare(b.Total, a.Total), strings.Compare(a.SQL, b.SQL))
+}
+
+// Reset drops the slow queries and statement counts.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.slow, r.next = nil, 0
+	r.statements = map[string]*Statement{}
+	r.since = time.Now()
+	r.mu.Unlock()
+}
+
+// Fingerprint returns sql with the placeholders of IN lists collapsed, so
+// that the runs of a statement with lists of different lengths are counted
+// together.
+func Fingerprint(sql string) string {
+	sql = placeholders.ReplaceAllString(sql, "?")
+	sql = placeholderLists.ReplaceAllString(sql, "(?...)")
+
+	return truncate(sql)
+}
+
+func truncate(sql string) string {
+	if len(sql) > maxSQL {
+		return sql[:maxSQL] + "…"
+	}
+
+	return sql
+}
+
+// caller returns the first function on the stack outside of GORM, the
+// database drivers and this package.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	for {
+		frame, more := frames.Next()
+
+		if !strings.Contains(frame.Function, "gorm.io/") && !strings.Contains(frame.Function, "/querylog.") &&
+			!strings.Contains(frame.Function, "glebarez/") && !strings.Contains(frame.Function, "database/sql.") {
+			short := frame.File
+			if i := strings.Index(short, "/internal/"); i >= 0 {
+				short = short[i+1:]
+			}
+
+			return frame.Function[strings.LastIndex(frame.Function, "/")+1:] + " (" + short + ":" +
+				strconv.Itoa(frame.Line) + ")"
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package querylog_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/querylog"
+)
+
+func TestFingerprint(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"SELECT * FROM users WHERE id = $1", "SELECT * FROM users WHERE id = ?"},
+		{"SELECT * FROM roles WHERE id IN ($1,$2,$3)", "SELECT * FROM roles WHERE id IN (?...)"},
+		{"SELECT * FROM roles WHERE id IN (?, ?)", "SELECT * FROM roles WHERE id IN (?...)"},
+		{"SELECT * FROM roles WHERE id IN (?)", "SELECT * FROM roles WHERE id IN (?)"},
+	} {
+		if got := querylog.Fingerprint(tt.in); got != tt.want {
+			t.Errorf("Fingerprint(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	r := querylog.New(10 * time.Millisecond)
+
+	for i := range 150 {
+		r.Record(&querylog.Query{
+			Time:      time.Unix(int64(i), 0),
+			Duration:  time.Duration(i) * time.Millisecond,
+			Operation: "query",
+			SQL:       "SELECT 1",
+			Caller:    "test",
+		})
+	}
+
+	r.Record(&querylog.Query{Operation: "query", SQL: "SELECT 2 WHERE a IN (?,?)", Caller: "test"})
+	r.Record(&querylog.Query{Operation: "query", SQL: "SELECT 2 WHERE a IN (?,?,?)", Caller: "test"})
+
+	slow := r.Slow()
+	if len(slow) != 100 {
+		t.Fatalf("len(Slow()) = %d, want 100", len(slow))
+	}
+
+	if slow[0].Time.Unix() != 149 || slow[99].Time.Unix() != 50 {
+		t.Errorf("Slow() runs from %d to %d, want 149 to 50", slow[0].Time.Unix(), slow[99].Time.Unix())
+	}
+
+	st := r.Statements(querylog.ByCount)
+	if len(st) != 2 || st[0].SQL != "SELECT 1" || st[0].Count != 150 || st[1].Count != 2 {
+		t.Fatalf("Statements(ByCount) = %+v", st)
+	}
+
+	if st[0].Max != 149*time.Millisecond {
+		t.Errorf("Max = %v, want 149ms", st[0].Max)
+	}
+
+	r.Reset()
+
+	if len(r.Slow()) != 0 || len(r.Statements(querylog.ByTotal)) != 0 {
+		t.Error("Reset() kept queries")
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	type item struct {
+		ID   uint
+		Name string
+	}
+
+	if err = db.AutoMigrate(&item{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := querylog.New(time.Hour)
+	if err = db.Use(r); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+
+	for i := range 3 {
+		if err = db.Create(&item{Name: strings.Repeat("x", i)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for id := 1; id <= 3; id++ {
+		var it item
+		if err = db.First(&it, id).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := r.Statements(querylog.ByCount)
+	if len(st) != 2 || st[0].Count != 3 || st[1].Count != 3 {
+		t.Fatalf("Statements() = %+v, want 2 statements run 3 times", st)
+	}
+
+	for _, s := range st {
+		if !strings.Contains(s.Caller, "TestPlugin") {
+			t.Errorf("Caller = %q, want the test function", s.Caller)
+		}
+	}
+
+	r.SetThreshold(time.Nanosecond)
+	r.Reset()
+
+	// The subquery is only built, not run, and is not recorded.
+	if err = db.Where("id IN (?)", db.Model(&item{}).Select("id").Where("name = ?", "x")).
+		Find(&[]item{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if slow := r.Slow(); len(slow) != 1 || slow[0].Table != "items" || slow[0].Operation != "query" {
+		t.Errorf("Slow() = %+v", slow)
+	}
+}
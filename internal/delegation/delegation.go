@@ -0,0 +1,388 @@
+// Package delegation checks the delegations of zones: NS RRsets below the
+// apex that hand a subdomain to other nameservers. Each nameserver is asked
+// for the SOA of the subdomain without recursion; a delegation is lame when
+// its nameservers do not answer authoritatively for it, so resolvers fail or
+// fall back to the other nameservers.
+package delegation
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+)
+
+// ServerStatus is the outcome of checking one nameserver of a delegation.
+type ServerStatus string
+
+const (
+	// ServerOK means the nameserver answered authoritatively.
+	ServerOK ServerStatus = "ok"
+
+	// ServerLame means the nameserver answered, but not authoritatively for
+	// the subdomain, e.g. with REFUSED or a referral.
+	ServerLame ServerStatus = "lame"
+
+	// ServerUnreachable means no address of the nameserver answered.
+	ServerUnreachable ServerStatus = "unreachable"
+
+	// ServerUnresolved means the nameserver has no address: no glue in the
+	// zone and no A or AAAA record at the resolvers.
+	ServerUnresolved ServerStatus = "unresolved"
+)
+
+const (
+	// workers is the number of nameservers checked at a time.
+	workers = 8
+
+	// MaxDelegations is the number of delegations of a zone checked in one
+	// run; the others are left out.
+	MaxDelegations = 100
+)
+
+// Delegation is an NS RRset below the apex of a zone.
+type Delegation struct {
+	// Zone is the parent zone.
+	Zone string
+	// Name is the delegated subdomain.
+	Name string
+	// Nameservers are the enabled targets of the NS RRset, sorted.
+	Nameservers []string
+	// Glue holds the addresses the zone has for nameservers within it.
+	Glue map[string][]netip.Addr
+}
+
+// Address is the answer of one address of a nameserver.
+type Address struct {
+	Address string        `json:"address"`
+	Status  ServerStatus  `json:"status"`
+	RCode   string        `json:"rcode,omitempty"`
+	Latency time.Duration `json:"latency,omitempty"`
+	// Error is why the query failed or why the answer is lame.
+	Error string `json:"error,omitempty"`
+}
+
+// Server is the check of one nameserver of a delegation.
+type Server struct {
+	Nameserver string       `json:"nameserver"`
+	Status     ServerStatus `json:"status"`
+	// Glue reports whether the addresses came from the parent zone.
+	Glue      bool      `json:"glue,omitempty"`
+	Addresses []Address `json:"addresses,omitempty"`
+}
+
+// Result is the check of a delegation.
+type Result struct {
+	Delegation
+	Status  models.DelegationStatus
+	Servers []Server
+}
+
+// Status returns the status of a delegation from the status of its
+// nameservers.
+func Status(servers []Server) models.DelegationStatus {
+	ok := 0
+
+	for i := range servers {
+		if servers[i].Status == ServerOK {
+			ok++
+		}
+	}
+
+	switch ok {
+	case len(servers):
+		return models.DelegationOK
+	case 0:
+		return models.DelegationLame
+	default:
+		return models.DelegationPartial
+	}
+}
+
+// Delegations returns the delegations of z, sorted by name. Disabled records
+// are ignored, as PowerDNS does not serve them.
+func Delegations(z *pdnsapi.Zone) []Delegation {
+	if z == nil {
+		return nil
+	}
+
+	apex := strings.ToLower(pdnsapi.StringValue(z.Name))
+	addrs := map[string][]netip.Addr{}
+
+	var delegations []Delegation
+
+	for i := range z.RRsets {
+		rrSet := &z.RRsets[i]
+		if rrSet.Name == nil || rrSet.Type == nil {
+			continue
+		}
+
+		name := strings.ToLower(*rrSet.Name)
+
+		switch *rrSet.Type {
+		case pdnsapi.RRTypeA, pdnsapi.RRTypeAAAA:
+			for _, content := range enabled(rrSet) {
+				if addr, err := netip.ParseAddr(content); err == nil {
+					addrs[name] = append(addrs[name], addr)
+				}
+			}
+		case pdnsapi.RRTypeNS:
+			if name == apex {
+				continue
+			}
+
+			var nameservers []string
+			for _, content := range enabled(rrSet) {
+				nameservers = append(nameservers, fqdn(content))
+			}
+
+			if len(nameservers) > 0 {
+				slices.Sort(nameservers)
+				delegations = append(delegations, Delegation{
+					Zone: apex, Name: name, Nameservers: slices.Compact(nameservers),
+				})
+			}
+		}
+	}
+
+	for i := range delegations {
+		d := &delegations[i]
+		d.Glue = map[string][]netip.Addr{}
+
+		for _, ns := range d.Nameservers {
+			if glue := addrs[ns]; len(glue) > 0 && (ns == apex || strings.HasSuffix(ns, "."+apex)) {
+				d.Glue[ns] = glue
+			}
+		}
+	}
+
+	slices.SortFunc(delegations, func(a, b Delegation) int { return strings.Compare(a.Name, b.Name) })
+
+	return delegations
+}
+
+// exchangeFunc sends a query; it is dnsclient.Exchange outside of tests.
+type exchangeFunc func(ctx context.Context, server string, q dnsclient.Query) (*dnsclient.Response, error)
+
+// Checker checks delegations, looking up nameservers without glue at a set
+// of resolvers.
+type Checker struct {
+	// Resolvers are host:port addresses.
+	Resolvers []string
+	// Timeout bounds each query.
+	Timeout time.Duration
+
+	exchange exchangeFunc
+}
+
+// New returns a Checker resolving nameservers at resolvers, each query
+// limited to timeout.
+func New(resolvers []string, timeout time.Duration) *Checker {
+	return &Checker{Resolvers: resolvers, Timeout: timeout, exchange: dnsclient.Exchange}
+}
+
+// Check checks the delegations, up to MaxDelegations, and returns their
+// results in the same order.
+func (c *Checker) Check(ctx context.Context, delegations []Delegation) []Result {
+	if len(delegations) > MaxDelegations {
+		delegations = delegations[:MaxDelegations]
+	}
+
+	results := make([]Result, len(delegations))
+
+	type job struct{ result, server int }
+
+	var jobs []job
+
+	for i := range delegations {
+		results[i] = Result{Delegation: delegations[i], Servers: make([]Server, len(delegations[i].Nameservers))}
+		for k := range delegations[i].Nameservers {
+			jobs = append(jobs, job{i, k})
+		}
+	}
+
+	next := make(chan job)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Go(func() {
+			for j := range next {
+				r := &results[j.result]
+				r.Servers[j.server] = c.checkServer(ctx, &r.Delegation, r.Nameservers[j.server])
+			}
+		})
+	}
+
+	for _, j := range jobs {
+		next <- j
+	}
+
+	close(next)
+	wg.Wait()
+
+	for i := range results {
+		results[i].Status = Status(results[i].Servers)
+	}
+
+	return results
+}
+
+// checkServer asks every address of nameserver for the SOA of the
+// delegated name. The nameserver is fine when any address answers
+// authoritatively, as a host may lack IPv6 connectivity.
+func (c *Checker) checkServer(ctx context.Context, d *Delegation, nameserver string) Server {
+	server := Server{Nameserver: nameserver}
+
+	addrs, glue := d.Glue[nameserver], true
+	if len(addrs) == 0 {
+		addrs, glue = c.resolve(ctx, nameserver), false
+	}
+
+	server.Glue = glue && len(addrs) > 0
+
+	if len(addrs) == 0 {
+		server.Status = ServerUnresolved
+		return server
+	}
+
+	server.Status = ServerUnreachable
+
+	for _, addr := range addrs {
+		a := c.query(ctx, addr, d.Name)
+		server.Addresses = append(server.Addresses, a)
+
+		switch {
+		case a.Status == ServerOK:
+			server.Status = ServerOK
+		case a.Status == ServerLame && server.Status == ServerUnreachable:
+			server.Status = ServerLame
+		}
+	}
+
+	return server
+}
+
+// query asks addr for the SOA of name without recursion.
+func (c *Checker) query(ctx context.Context, addr netip.Addr, name string) Address {
+	a := Address{Address: addr.String()}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	resp, err := c.exchange(ctx, netip.AddrPortFrom(addr, 53).String(),
+		dnsclient.Query{Name: name, Type: dnsmessage.TypeSOA})
+	if err != nil {
+		a.Status = ServerUnreachable
+		a.Error = err.Error()
+
+		return a
+	}
+
+	a.Latency = resp.RTT
+	a.RCode = dnsclient.RCodeName(resp.Message.RCode)
+
+	switch {
+	case resp.Message.RCode != dnsmessage.RCodeSuccess && resp.Message.RCode != dnsmessage.RCodeNameError:
+		a.Status = ServerLame
+		a.Error = "answered " + a.RCode
+	case !resp.Message.Authoritative:
+		a.Status = ServerLame
+		a.Error = "not authoritative for " + name
+	case !hasSOA(&resp.Message, name):
+		a.Status = ServerLame
+		a.Error = "no SOA record for " + name
+	default:
+		a.Status = ServerOK
+	}
+
+	return a
+}
+
+// hasSOA reports whether msg answers with the SOA of name.
+func hasSOA(msg *dnsmessage.Message, name string) bool {
+	for i := range msg.Answers {
+		h := &msg.Answers[i].Header
+		if h.Type == dnsmessage.TypeSOA && strings.EqualFold(h.Name.String(), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve returns the addresses of host at the first resolver answering.
+func (c *Checker) resolve(ctx context.Context, host string) []netip.Addr {
+	for _, resolver := range c.Resolvers {
+		addrs, ok := c.lookup(ctx, resolver, host)
+		if ok {
+			return addrs
+		}
+	}
+
+	return nil
+}
+
+// lookup asks resolver for the A and AAAA records of host; ok is false when
+// neither query got an answer.
+func (c *Checker) lookup(ctx context.Context, resolver, host string) (addrs []netip.Addr, ok bool) {
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		qctx, cancel := context.WithTimeout(ctx, c.Timeout)
+		resp, err := c.exchange(qctx, resolver, dnsclient.Query{Name: host, Type: qtype, Recursion: true})
+
+		cancel()
+
+		if err != nil || (resp.Message.RCode != dnsmessage.RCodeSuccess &&
+			resp.Message.RCode != dnsmessage.RCodeNameError) {
+			continue
+		}
+
+		ok = true
+
+		for i := range resp.Message.Answers {
+			rr := &resp.Message.Answers[i]
+			if rr.Header.Type != qtype {
+				continue
+			}
+
+			if addr, err := netip.ParseAddr(dnsclient.Data(rr)); err == nil {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	slices.SortFunc(addrs, netip.Addr.Compare)
+
+	return slices.Compact(addrs), ok
+}
+
+// enabled returns the contents of the enabled records of rrSet.
+func enabled(rrSet *pdnsapi.RRset) []string {
+	var contents []string
+
+	for _, rec := range rrSet.Records {
+		if !pdnsapi.BoolValue(rec.Disabled) {
+			contents = append(contents, pdnsapi.StringValue(rec.Content))
+		}
+	}
+
+	return contents
+}
+
+// fqdn returns name lower-case and fully qualified.
+func fqdn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name
+}
@@ -0,0 +1,190 @@
+package delegation
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+)
+
+func rrSet(name string, rrType pdnsapi.RRType, contents ...string) pdnsapi.RRset {
+	records := make([]pdnsapi.Record, 0, len(contents))
+	for _, content := range contents {
+		records = append(records, pdnsapi.Record{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)})
+	}
+
+	return pdnsapi.RRset{Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(rrType), Records: records}
+}
+
+func TestDelegations(t *testing.T) {
+	disabled := rrSet("off.example.com.", pdnsapi.RRTypeNS, "ns.example.net.")
+	disabled.Records[0].Disabled = pdnsapi.Bool(true)
+
+	z := &pdnsapi.Zone{Name: pdnsapi.String("example.com."), RRsets: []pdnsapi.RRset{
+		rrSet("example.com.", pdnsapi.RRTypeNS, "ns1.example.com."),
+		rrSet("ns1.example.com.", pdnsapi.RRTypeA, "192.0.2.1"),
+		rrSet("sub.example.com.", pdnsapi.RRTypeNS, "ns.example.net.", "NS.Sub.example.com", "ns.sub.example.com."),
+		rrSet("ns.sub.example.com.", pdnsapi.RRTypeA, "192.0.2.53"),
+		rrSet("ns.sub.example.com.", pdnsapi.RRTypeAAAA, "2001:db8::53"),
+		rrSet("ns.example.net.", pdnsapi.RRTypeA, "192.0.2.99"),
+		rrSet("a.example.com.", pdnsapi.RRTypeNS, "ns.example.net."),
+		disabled,
+	}}
+
+	got := Delegations(z)
+
+	want := []Delegation{
+		{Zone: "example.com.", Name: "a.example.com.", Nameservers: []string{"ns.example.net."},
+			Glue: map[string][]netip.Addr{}},
+		{Zone: "example.com.", Name: "sub.example.com.",
+			Nameservers: []string{"ns.example.net.", "ns.sub.example.com."},
+			Glue: map[string][]netip.Addr{"ns.sub.example.com.": {
+				netip.MustParseAddr("192.0.2.53"), netip.MustParseAddr("2001:db8::53"),
+			}}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Delegations() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		servers []ServerStatus
+		want    models.DelegationStatus
+	}{
+		{[]ServerStatus{ServerOK, ServerOK}, models.DelegationOK},
+		{[]ServerStatus{ServerOK, ServerUnreachable}, models.DelegationPartial},
+		{[]ServerStatus{ServerLame, ServerUnresolved}, models.DelegationLame},
+	}
+
+	for _, tt := range tests {
+		servers := make([]Server, 0, len(tt.servers))
+		for _, s := range tt.servers {
+			servers = append(servers, Server{Status: s})
+		}
+
+		if got := Status(servers); got != tt.want {
+			t.Errorf("Status(%v) = %q, want %q", tt.servers, got, tt.want)
+		}
+	}
+}
+
+// fakeExchange answers like the nameservers of the test delegations:
+// 192.0.2.1 is authoritative, 192.0.2.2 answers with a referral, 192.0.2.3
+// refuses and 192.0.2.4 is down. The resolver knows ns.example.net. only.
+func fakeExchange(_ context.Context, server string, q dnsclient.Query) (*dnsclient.Response, error) {
+	resp := &dnsclient.Response{Server: server, RTT: 5 * time.Millisecond}
+	resp.Message.Response = true
+
+	switch server {
+	case "resolver:53":
+		if q.Name == "ns.example.net." && q.Type == dnsmessage.TypeA {
+			resp.Message.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(q.Name), Type: dnsmessage.TypeA},
+				Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+			}}
+		} else if q.Name != "ns.example.net." {
+			resp.Message.RCode = dnsmessage.RCodeNameError
+		}
+	case "192.0.2.1:53":
+		resp.Message.Authoritative = true
+		resp.Message.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(q.Name), Type: dnsmessage.TypeSOA},
+			Body: &dnsmessage.SOAResource{
+				NS: dnsmessage.MustNewName("ns.example.net."), MBox: dnsmessage.MustNewName("hostmaster.example.net."),
+			},
+		}}
+	case "192.0.2.2:53":
+	case "192.0.2.3:53":
+		resp.Message.RCode = dnsmessage.RCodeRefused
+	default:
+		return nil, errors.New("i/o timeout")
+	}
+
+	return resp, nil
+}
+
+func TestCheck(t *testing.T) {
+	addrs := func(ips ...string) []netip.Addr {
+		var out []netip.Addr
+		for _, ip := range ips {
+			out = append(out, netip.MustParseAddr(ip))
+		}
+
+		return out
+	}
+
+	delegations := []Delegation{
+		{Name: "ok.example.com.", Nameservers: []string{"ns.example.net.", "ns.ok.example.com."},
+			Glue: map[string][]netip.Addr{"ns.ok.example.com.": addrs("192.0.2.4", "192.0.2.1")}},
+		{Name: "partial.example.com.", Nameservers: []string{"ns.example.net.", "ns.partial.example.com."},
+			Glue: map[string][]netip.Addr{"ns.partial.example.com.": addrs("192.0.2.2")}},
+		{Name: "lame.example.com.", Nameservers: []string{"nowhere.example.net.", "ns.lame.example.com."},
+			Glue: map[string][]netip.Addr{"ns.lame.example.com.": addrs("192.0.2.3", "192.0.2.4")}},
+	}
+
+	c := &Checker{Resolvers: []string{"resolver:53"}, Timeout: time.Second, exchange: fakeExchange}
+
+	results := c.Check(context.Background(), delegations)
+
+	type server struct {
+		status ServerStatus
+		glue   bool
+	}
+
+	want := []struct {
+		status  models.DelegationStatus
+		servers []server
+	}{
+		{models.DelegationOK, []server{{ServerOK, false}, {ServerOK, true}}},
+		{models.DelegationPartial, []server{{ServerOK, false}, {ServerLame, true}}},
+		{models.DelegationLame, []server{{ServerUnresolved, false}, {ServerLame, true}}},
+	}
+
+	for i, r := range results {
+		if r.Name != delegations[i].Name || r.Status != want[i].status {
+			t.Errorf("result %d = %s %q, want %s %q", i, r.Name, r.Status, delegations[i].Name, want[i].status)
+		}
+
+		var got []server
+		for _, s := range r.Servers {
+			got = append(got, server{s.Status, s.Glue})
+		}
+
+		if !reflect.DeepEqual(got, want[i].servers) {
+			t.Errorf("servers of %s = %+v, want %+v", r.Name, got, want[i].servers)
+		}
+	}
+
+	lame := results[2].Servers[1].Addresses
+	if len(lame) != 2 || lame[0].RCode != "REFUSED" || lame[0].Error != "answered REFUSED" ||
+		lame[1].Status != ServerUnreachable {
+		t.Errorf("addresses of ns.lame.example.com. = %+v", lame)
+	}
+
+	if got := results[1].Servers[1].Addresses[0].Error; got != "not authoritative for partial.example.com." {
+		t.Errorf("error of the referral = %q", got)
+	}
+}
+
+func TestCheckLimitsDelegations(t *testing.T) {
+	delegations := make([]Delegation, MaxDelegations+5)
+	for i := range delegations {
+		delegations[i] = Delegation{Name: "sub.example.com.", Nameservers: []string{"ns.example.net."}}
+	}
+
+	c := &Checker{Resolvers: []string{"resolver:53"}, Timeout: time.Second, exchange: fakeExchange}
+
+	if got := len(c.Check(context.Background(), delegations)); got != MaxDelegations {
+		t.Errorf("Check() returned %d results, want %d", got, MaxDelegations)
+	}
+}
@@ -0,0 +1,266 @@
+package delegation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+const (
+	// checkInterval is how often zones due for a re-check are looked for.
+	checkInterval = 10 * time.Minute
+
+	// zoneTimeout bounds fetching and checking the delegations of one zone.
+	zoneTimeout = 2 * time.Minute
+)
+
+// Save stores the results of checking the delegations of zone at now.
+// Results cover the delegations of names, or all delegations of the zone
+// when names is nil; the stored checks of those names without a result are
+// removed, as the names are no longer delegated.
+func Save(db *gorm.DB, zone string, names []string, results []Result, now time.Time) error {
+	checks := make([]models.DelegationCheck, 0, len(results))
+	checked := make([]string, 0, len(results))
+
+	for i := range results {
+		r := &results[i]
+
+		servers, err := json.Marshal(r.Servers)
+		if err != nil {
+			return err
+		}
+
+		checks = append(checks, models.DelegationCheck{
+			ZoneName:    zone,
+			Name:        r.Name,
+			Nameservers: strings.Join(r.Nameservers, "\n"),
+			Status:      r.Status,
+			Servers:     string(servers),
+			CheckedAt:   now,
+		})
+		checked = append(checked, r.Name)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		q := tx.Where("zone_name = ?", zone)
+		if names != nil {
+			q = q.Where("name IN ?", names)
+		}
+
+		if len(checked) > 0 {
+			q = q.Where("name NOT IN ?", checked)
+		}
+
+		if err := q.Delete(&models.DelegationCheck{}).Error; err != nil {
+			return err
+		}
+
+		if len(checks) == 0 {
+			return nil
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "zone_name"}, {Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"nameservers", "status", "servers", "checked_at"}),
+		}).Create(&checks).Error
+	})
+}
+
+// Servers returns the nameserver results stored with check.
+func Servers(check *models.DelegationCheck) []Server {
+	var servers []Server
+	if json.Unmarshal([]byte(check.Servers), &servers) != nil {
+		return nil
+	}
+
+	return servers
+}
+
+// Runner checks delegations when their NS RRsets change and checks the
+// stored delegations again at the configured interval.
+type Runner struct {
+	db       *gorm.DB
+	checker  *Checker
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewRunner returns a Runner storing its checks in db and querying the
+// resolvers of cfg.
+func NewRunner(db *gorm.DB, cfg *config.DNSCheck) *Runner {
+	return &Runner{
+		db:       db,
+		checker:  New(cfg.Resolvers, cfg.Timeout),
+		interval: cfg.DelegationInterval,
+		now:      time.Now,
+	}
+}
+
+// Run checks the zones whose delegations were last checked more than the
+// interval ago, every checkInterval until ctx is canceled. It returns at once
+// when the re-check is disabled.
+func (r *Runner) Run(ctx context.Context) {
+	if r.interval < 0 {
+		return
+	}
+
+	jobs.Scheduled(jobs.DelegationChecks, checkInterval)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.DelegationChecks, func() error { return r.runOnce(ctx) })
+		}
+	}
+}
+
+// runOnce checks every delegation of the zones due for a re-check.
+func (r *Runner) runOnce(ctx context.Context) error {
+	var zones []string
+
+	err := r.db.Model(&models.DelegationCheck{}).
+		Where("checked_at <= ?", r.now().Add(-r.interval)).
+		Distinct("zone_name").Order("zone_name").Pluck("zone_name", &zones).Error
+	if err != nil {
+		log.Error().Err(err).Msg("delegation: failed to load zones due for a check")
+		return err
+	}
+
+	var errs []error
+
+	for _, zone := range zones {
+		if err := r.CheckZone(ctx, zone, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// CheckZone fetches zone from PowerDNS, checks its delegations and stores
+// the results (see Save). With names, only the delegations of those names are
+// checked. The checks of zones that no longer exist are deleted.
+func (r *Runner) CheckZone(ctx context.Context, zone string, names []string) error {
+	if powerdns.Engine.Client == nil {
+		return powerdns.ErrClientNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, zoneTimeout)
+	defer cancel()
+
+	z, err := powerdns.Engine.Zones.Get(ctx, zone)
+	if err != nil {
+		var apiErr *pdnsapi.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return r.db.Where("zone_name = ?", zone).Delete(&models.DelegationCheck{}).Error
+		}
+
+		log.Warn().Err(err).Str("zone_name", zone).Msg("delegation: failed to fetch zone")
+
+		return err
+	}
+
+	delegations := Delegations(z)
+
+	if names != nil {
+		delegations = slices.DeleteFunc(delegations, func(d Delegation) bool { return !slices.Contains(names, d.Name) })
+	}
+
+	results := r.checker.Check(ctx, delegations)
+
+	for i := range results {
+		if results[i].Status != models.DelegationOK {
+			log.Warn().Str("zone_name", zone).Str("name", results[i].Name).
+				Str("status", string(results[i].Status)).Msg("delegation: lame delegation")
+		}
+	}
+
+	if err = Save(r.db, zone, names, results, r.now()); err != nil {
+		log.Error().Err(err).Str("zone_name", zone).Msg("delegation: failed to store checks")
+		return err
+	}
+
+	return nil
+}
+
+// Follow checks the delegations whose NS RRsets were added, changed or
+// deleted, as recorded in the activity log entries published on bus. Events
+// replayed from other replicas are ignored; the replica that published them
+// checks.
+func (r *Runner) Follow(bus *eventbus.Bus) {
+	bus.Subscribe(eventbus.TopicActivity, r.onActivity)
+}
+
+func (r *Runner) onActivity(e eventbus.Event) {
+	if e.Remote {
+		return
+	}
+
+	id, err := strconv.ParseUint(e.Key, 10, 64)
+	if err != nil {
+		return
+	}
+
+	var entry models.ActivityLog
+	if err = r.db.First(&entry, id).Error; err != nil {
+		log.Warn().Err(err).Uint64("id", id).Msg("delegation: failed to load activity log entry")
+		return
+	}
+
+	zone, names := changedDelegations(&entry)
+	if len(names) == 0 {
+		return
+	}
+
+	jobs.Go(jobs.DelegationChecks, func() error {
+		return r.CheckZone(context.Background(), zone, names)
+	})
+}
+
+// changedDelegations returns the zone of entry and the names below its apex
+// whose NS RRset entry changed.
+func changedDelegations(entry *models.ActivityLog) (string, []string) {
+	if entry.Action != activitylog.ActionRecordChanged || entry.ResourceType != activitylog.ResourceTypeZone {
+		return "", nil
+	}
+
+	var diff activitylog.RecordsDiff
+	if json.Unmarshal([]byte(entry.Details), &diff) != nil {
+		return "", nil
+	}
+
+	zone := fqdn(entry.ResourceName)
+
+	var names []string
+
+	for _, rec := range diff.Records {
+		name := fqdn(rec.Name)
+		if rec.Type == "NS" && name != zone && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return zone, names
+}
@@ -0,0 +1,149 @@
+package delegation
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.DelegationCheck{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func result(name string, status models.DelegationStatus, nameservers ...string) Result {
+	servers := make([]Server, 0, len(nameservers))
+	for _, ns := range nameservers {
+		servers = append(servers, Server{Nameserver: ns, Status: ServerOK})
+	}
+
+	return Result{Delegation: Delegation{Name: name, Nameservers: nameservers}, Status: status, Servers: servers}
+}
+
+// stored returns the names and statuses of the checks of zone.
+func stored(t *testing.T, db *gorm.DB, zone string) map[string]models.DelegationStatus {
+	t.Helper()
+
+	var checks []models.DelegationCheck
+	if err := db.Where("zone_name = ?", zone).Find(&checks).Error; err != nil {
+		t.Fatalf("failed to load checks: %v", err)
+	}
+
+	got := map[string]models.DelegationStatus{}
+	for _, c := range checks {
+		got[c.Name] = c.Status
+	}
+
+	return got
+}
+
+func TestSave(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	err := Save(db, "example.com.", nil, []Result{
+		result("a.example.com.", models.DelegationOK, "ns1.example.net.", "ns2.example.net."),
+		result("b.example.com.", models.DelegationLame, "ns.example.net."),
+		result("c.example.com.", models.DelegationOK, "ns.example.net."),
+	}, now)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if err = Save(db, "example.org.", nil, []Result{result("x.example.org.", models.DelegationOK)}, now); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Checking b again updates it; c, also checked by name, is no longer
+	// delegated; a was not checked and stays.
+	later := now.Add(time.Hour)
+
+	err = Save(db, "example.com.", []string{"b.example.com.", "c.example.com."}, []Result{
+		result("b.example.com.", models.DelegationPartial, "ns.example.net."),
+	}, later)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	want := map[string]models.DelegationStatus{
+		"a.example.com.": models.DelegationOK,
+		"b.example.com.": models.DelegationPartial,
+	}
+	if got := stored(t, db, "example.com."); !reflect.DeepEqual(got, want) {
+		t.Errorf("checks = %v, want %v", got, want)
+	}
+
+	var check models.DelegationCheck
+	db.Where("name = ?", "a.example.com.").First(&check)
+
+	if got := check.NameserverList(); !reflect.DeepEqual(got, []string{"ns1.example.net.", "ns2.example.net."}) {
+		t.Errorf("nameservers = %v", got)
+	}
+
+	if servers := Servers(&check); len(servers) != 2 || servers[1].Nameserver != "ns2.example.net." {
+		t.Errorf("Servers() = %+v", servers)
+	}
+
+	var updated models.DelegationCheck
+	db.Where("name = ?", "b.example.com.").First(&updated)
+
+	if !updated.CheckedAt.Equal(later) {
+		t.Errorf("checked at = %v, want %v", updated.CheckedAt, later)
+	}
+
+	// A full check without delegations removes the checks of the zone only.
+	if err = Save(db, "example.com.", nil, nil, later); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if got := stored(t, db, "example.com."); len(got) != 0 {
+		t.Errorf("checks after removing all delegations = %v", got)
+	}
+
+	if got := stored(t, db, "example.org."); len(got) != 1 {
+		t.Errorf("checks of example.org. = %v, want 1", got)
+	}
+}
+
+func TestChangedDelegations(t *testing.T) {
+	details := `{"records":[` +
+		`{"name":"sub.example.com","type":"NS","action":"added"},` +
+		`{"name":"example.com.","type":"NS","action":"modified"},` +
+		`{"name":"www.example.com.","type":"A","action":"added"},` +
+		`{"name":"old.example.com.","type":"NS","action":"deleted"},` +
+		`{"name":"sub.example.com.","type":"NS","action":"modified"}]}`
+
+	entry := &models.ActivityLog{
+		Action:       activitylog.ActionRecordChanged,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: "example.com.",
+		Details:      details,
+	}
+
+	zone, names := changedDelegations(entry)
+	if zone != "example.com." || !reflect.DeepEqual(names, []string{"sub.example.com.", "old.example.com."}) {
+		t.Errorf("changedDelegations() = %q %v", zone, names)
+	}
+
+	entry.Action = activitylog.ActionZoneUpdated
+
+	if _, names = changedDelegations(entry); names != nil {
+		t.Errorf("changedDelegations() of a zone update = %v, want none", names)
+	}
+}
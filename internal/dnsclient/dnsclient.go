@@ -0,0 +1,232 @@
+// Package dnsclient sends single DNS queries to a given server and returns the
+// parsed response. Queries go over UDP with EDNS(0) and are retried over TCP
+// when the answer is truncated.
+package dnsclient
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// DefaultPort is the port used for servers given without one.
+	DefaultPort = "53"
+
+	// udpPayloadSize is the EDNS(0) UDP payload size advertised, the size
+	// recommended by DNS Flag Day 2020.
+	udpPayloadSize = 1232
+
+	// maxMessageSize is the largest DNS message.
+	maxMessageSize = 65535
+)
+
+var (
+	errIDMismatch = errors.New("response ID does not match the query")
+	errNotAnswer  = errors.New("response does not answer the query")
+)
+
+// Query is a DNS question with the query options.
+type Query struct {
+	Name string
+	Type dnsmessage.Type
+	// Recursion sets the RD flag, asking a resolver to resolve the name.
+	Recursion bool
+	// DNSSEC sets the DO flag, asking for DNSSEC records.
+	DNSSEC bool
+}
+
+// Response is the answer of a server.
+type Response struct {
+	Message  dnsmessage.Message
+	Server   string
+	Protocol string
+	RTT      time.Duration
+	Size     int
+}
+
+// Server returns server with DefaultPort appended when it has no port. IPv6
+// addresses may be given with or without brackets.
+func Server(server string) (string, error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return "", errors.New("empty server address")
+	}
+
+	if host, port, err := net.SplitHostPort(server); err == nil {
+		if host == "" || port == "" {
+			return "", fmt.Errorf("invalid server address %q", server)
+		}
+
+		return server, nil
+	}
+
+	return net.JoinHostPort(strings.Trim(server, "[]"), DefaultPort), nil
+}
+
+// Exchange sends q to server (host:port) and returns its response. The
+// deadline of ctx bounds the whole exchange, including a TCP retry.
+func Exchange(ctx context.Context, server string, q Query) (*Response, error) {
+	name := q.Name
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name %q: %w", q.Name, err)
+	}
+
+	question := dnsmessage.Question{Name: qname, Type: q.Type, Class: dnsmessage.ClassINET}
+
+	query, id, err := pack(question, q)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := exchange(ctx, "udp", server, query, id, question)
+	if err == nil && resp.Message.Truncated {
+		resp, err = exchange(ctx, "tcp", server, query, id, question)
+	}
+
+	return resp, err
+}
+
+// pack builds the query message for question.
+func pack(question dnsmessage.Question, q Query) ([]byte, uint16, error) {
+	id := uint16(rand.N(1 << 16)) //nolint:gosec // query IDs need no cryptographic randomness here
+
+	b := dnsmessage.NewBuilder(make([]byte, 2, 512), dnsmessage.Header{ID: id, RecursionDesired: q.Recursion})
+	b.EnableCompression()
+
+	if err := b.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := b.Question(question); err != nil {
+		return nil, 0, err
+	}
+
+	if err := b.StartAdditionals(); err != nil {
+		return nil, 0, err
+	}
+
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(udpPayloadSize, dnsmessage.RCodeSuccess, q.DNSSEC); err != nil {
+		return nil, 0, err
+	}
+
+	if err := b.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, 0, err
+	}
+
+	msg, err := b.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The first two bytes hold the length prefix used over TCP.
+	binary.BigEndian.PutUint16(msg, uint16(len(msg)-2)) //nolint:gosec // a query is far below 64 KiB
+
+	return msg, id, nil
+}
+
+// exchange sends query over network and reads the matching response.
+func exchange(ctx context.Context, network, server string, query []byte, id uint16,
+	question dnsmessage.Question) (*Response, error) {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	start := time.Now()
+
+	var raw []byte
+
+	if network == "tcp" {
+		raw, err = exchangeTCP(conn, query)
+	} else {
+		raw, err = exchangeUDP(conn, query[2:], id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &Response{Server: server, Protocol: network, RTT: time.Since(start), Size: len(raw)}
+
+	if err := resp.Message.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+
+	if resp.Message.ID != id {
+		return nil, errIDMismatch
+	}
+
+	if len(resp.Message.Questions) > 0 && !sameQuestion(resp.Message.Questions[0], question) {
+		return nil, errNotAnswer
+	}
+
+	return resp, nil
+}
+
+// exchangeUDP writes query and returns the first datagram with its ID.
+// Datagrams with other IDs, e.g. late answers to earlier queries, are skipped.
+func exchangeUDP(conn net.Conn, query []byte, id uint16) ([]byte, error) {
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, maxMessageSize)
+
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+// exchangeTCP writes the length-prefixed query and reads the response.
+func exchangeTCP(conn net.Conn, query []byte) ([]byte, error) {
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// sameQuestion reports whether a and b ask the same, ignoring the case of the
+// name.
+func sameQuestion(a, b dnsmessage.Question) bool {
+	return a.Type == b.Type && a.Class == b.Class && strings.EqualFold(a.Name.String(), b.Name.String())
+}
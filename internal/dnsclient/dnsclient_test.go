@@ -0,0 +1,196 @@
+package dnsclient
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// answer builds the response to query with one A record. truncated sets the
+// TC flag and leaves the answer out.
+func answer(t *testing.T, query []byte, truncated bool) []byte {
+	t.Helper()
+
+	var q dnsmessage.Message
+	if err := q.Unpack(query); err != nil {
+		t.Errorf("server: invalid query: %v", err)
+		return nil
+	}
+
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: q.ID, Response: true, Authoritative: true, Truncated: truncated},
+		Questions: q.Questions,
+	}
+
+	if !truncated {
+		resp.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+			Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+		}}
+	}
+
+	raw, err := resp.Pack()
+	if err != nil {
+		t.Errorf("server: pack: %v", err)
+	}
+
+	return raw
+}
+
+// serve answers UDP queries truncated and TCP queries in full, on the same
+// port.
+func serve(t *testing.T) string {
+	t.Helper()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		_ = udp.Close()
+		t.Skipf("cannot listen on TCP: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = udp.Close()
+		_ = tcp.Close()
+	})
+
+	go func() {
+		buf := make([]byte, maxMessageSize)
+
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			_, _ = udp.WriteTo(answer(t, buf[:n], true), addr)
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					raw := answer(t, query, false)
+					_, _ = conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(raw))))
+					_, _ = conn.Write(raw)
+				}
+			}
+
+			_ = conn.Close()
+		}
+	}()
+
+	return udp.LocalAddr().String()
+}
+
+func TestExchangeFallsBackToTCP(t *testing.T) {
+	server := serve(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := Exchange(ctx, server, Query{Name: "www.example.com", Type: dnsmessage.TypeA})
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	if resp.Protocol != "tcp" {
+		t.Errorf("Protocol = %q, want tcp", resp.Protocol)
+	}
+
+	if len(resp.Message.Answers) != 1 || Data(&resp.Message.Answers[0]) != "192.0.2.1" {
+		t.Fatalf("Answers = %+v, want one A 192.0.2.1", resp.Message.Answers)
+	}
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"192.0.2.53", "192.0.2.53:53", false},
+		{"192.0.2.53:5353", "192.0.2.53:5353", false},
+		{"2001:db8::53", "[2001:db8::53]:53", false},
+		{"[2001:db8::53]", "[2001:db8::53]:53", false},
+		{"[2001:db8::53]:5353", "[2001:db8::53]:5353", false},
+		{"dns.example.net", "dns.example.net:53", false},
+		{"", "", true},
+		{":53", "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := Server(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("Server(%q) = %q, %v; want %q, error %v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for in, want := range map[string]dnsmessage.Type{
+		"a": dnsmessage.TypeA, "CAA": TypeCAA, "TYPE65534": 65534, "any": dnsmessage.TypeALL,
+	} {
+		if got, err := ParseType(in); err != nil || got != want {
+			t.Errorf("ParseType(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseType("BOGUS"); err == nil {
+		t.Error("ParseType(BOGUS) succeeded")
+	}
+
+	if got := TypeName(65534); got != "TYPE65534" {
+		t.Errorf("TypeName(65534) = %q", got)
+	}
+}
+
+func TestData(t *testing.T) {
+	tests := []struct {
+		body dnsmessage.ResourceBody
+		want string
+	}{
+		{&dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}, "2001:db8::1"},
+		{&dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.example.com.")}, "10 mail.example.com."},
+		{&dnsmessage.TXTResource{TXT: []string{`say "hi"`, "x"}}, `"say \"hi\"" "x"`},
+		{&dnsmessage.UnknownResource{Type: TypeCAA, Data: append([]byte{0, 5}, "issueletsencrypt.org"...)}, `0 issue "letsencrypt.org"`},
+		{&dnsmessage.UnknownResource{Type: 65534, Data: []byte{1, 2}}, `\# 2 0102`},
+	}
+
+	for _, tc := range tests {
+		if got := Data(&dnsmessage.Resource{Body: tc.body}); got != tc.want {
+			t.Errorf("Data(%T) = %q, want %q", tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	for in, want := range map[string]string{
+		`"v=spf1 -all"`:        "v=spf1 -all",
+		`"a" "b"`:              "ab",
+		`"say \"hi\"" "\\"`:    `say "hi"\`,
+		`"caf\195\169"`:        "café",
+		`unquoted`:             "unquoted",
+		Quote("tab\there\x00"): "tab\there\x00",
+	} {
+		if got := Unquote(in); got != want {
+			t.Errorf("Unquote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
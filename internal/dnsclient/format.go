@@ -0,0 +1,215 @@
+package dnsclient
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Record types without a dnsmessage constant.
+const (
+	TypeDS     dnsmessage.Type = 43
+	TypeDHCID  dnsmessage.Type = 49
+	TypeRRSIG  dnsmessage.Type = 46
+	TypeNSEC   dnsmessage.Type = 47
+	TypeDNSKEY dnsmessage.Type = 48
+	TypeTLSA   dnsmessage.Type = 52
+	TypeCAA    dnsmessage.Type = 257
+)
+
+// typeNames maps the record types known by name to their mnemonic.
+var typeNames = map[dnsmessage.Type]string{
+	dnsmessage.TypeA:     "A",
+	dnsmessage.TypeNS:    "NS",
+	dnsmessage.TypeCNAME: "CNAME",
+	dnsmessage.TypeSOA:   "SOA",
+	dnsmessage.TypePTR:   "PTR",
+	dnsmessage.TypeMX:    "MX",
+	dnsmessage.TypeTXT:   "TXT",
+	dnsmessage.TypeAAAA:  "AAAA",
+	dnsmessage.TypeSRV:   "SRV",
+	dnsmessage.TypeOPT:   "OPT",
+	dnsmessage.TypeSVCB:  "SVCB",
+	dnsmessage.TypeHTTPS: "HTTPS",
+	dnsmessage.TypeHINFO: "HINFO",
+	dnsmessage.TypeALL:   "ANY",
+	TypeDS:               "DS",
+	TypeDHCID:            "DHCID",
+	TypeRRSIG:            "RRSIG",
+	TypeNSEC:             "NSEC",
+	TypeDNSKEY:           "DNSKEY",
+	TypeTLSA:             "TLSA",
+	TypeCAA:              "CAA",
+}
+
+// TypeName returns the mnemonic of t, or TYPEn for types without one.
+func TypeName(t dnsmessage.Type) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+// ParseType parses a record type mnemonic or its TYPEn form.
+func ParseType(s string) (dnsmessage.Type, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	for t, name := range typeNames {
+		if name == s {
+			return t, nil
+		}
+	}
+
+	if n, err := strconv.ParseUint(strings.TrimPrefix(s, "TYPE"), 10, 16); err == nil && strings.HasPrefix(s, "TYPE") {
+		return dnsmessage.Type(n), nil
+	}
+
+	return 0, fmt.Errorf("unknown record type %q", s)
+}
+
+// RCodeName returns the mnemonic of a response code, e.g. NXDOMAIN.
+func RCodeName(rcode dnsmessage.RCode) string {
+	switch rcode {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	default:
+		return "RCODE" + strconv.Itoa(int(rcode))
+	}
+}
+
+// Data returns the record data of r in presentation format, as PowerDNS
+// shows it: names are fully qualified and TXT strings quoted. Types the
+// package cannot decode use the RFC 3597 \# form.
+func Data(r *dnsmessage.Resource) string {
+	switch body := r.Body.(type) {
+	case *dnsmessage.AResource:
+		return netip.AddrFrom4(body.A).String()
+	case *dnsmessage.AAAAResource:
+		return netip.AddrFrom16(body.AAAA).String()
+	case *dnsmessage.CNAMEResource:
+		return body.CNAME.String()
+	case *dnsmessage.NSResource:
+		return body.NS.String()
+	case *dnsmessage.PTRResource:
+		return body.PTR.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", body.Pref, body.MX.String())
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", body.Priority, body.Weight, body.Port, body.Target.String())
+	case *dnsmessage.SOAResource:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", body.NS.String(), body.MBox.String(),
+			body.Serial, body.Refresh, body.Retry, body.Expire, body.MinTTL)
+	case *dnsmessage.TXTResource:
+		quoted := make([]string, len(body.TXT))
+		for i, s := range body.TXT {
+			quoted[i] = Quote(s)
+		}
+
+		return strings.Join(quoted, " ")
+	case *dnsmessage.OPTResource:
+		return fmt.Sprintf("udp=%d do=%t", r.Header.Class, r.Header.DNSSECAllowed())
+	case *dnsmessage.UnknownResource:
+		return unknownData(body)
+	default:
+		return strings.TrimPrefix(fmt.Sprintf("%#v", r.Body), "&")
+	}
+}
+
+// unknownData formats the record types dnsmessage does not decode.
+func unknownData(body *dnsmessage.UnknownResource) string {
+	d := body.Data
+
+	switch {
+	case body.Type == TypeCAA && len(d) >= 2 && len(d) >= 2+int(d[1]):
+		tagLen := int(d[1])
+		return fmt.Sprintf("%d %s %s", d[0], d[2:2+tagLen], Quote(string(d[2+tagLen:])))
+	case body.Type == TypeDHCID:
+		return base64.StdEncoding.EncodeToString(d)
+	case body.Type == TypeDS && len(d) > 4:
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(d), d[2], d[3], strings.ToUpper(hex.EncodeToString(d[4:])))
+	default:
+		return fmt.Sprintf(`\# %d %s`, len(d), hex.EncodeToString(d))
+	}
+}
+
+// Quote returns s as a DNS character string: in double quotes, with quotes
+// and backslashes escaped and non-printable bytes as \DDD.
+func Quote(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	for i := range len(s) {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, `\%03d`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// Unquote returns the concatenated character strings of a TXT record in
+// presentation format, the inverse of joining the Quote of each string.
+// Unquoted words are taken as they are.
+func Unquote(s string) string {
+	var b strings.Builder
+
+	inQuotes := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == '\\' && i+3 < len(s) && isDigits(s[i+1:i+4]):
+			n, _ := strconv.Atoi(s[i+1 : i+4])
+			b.WriteByte(byte(n)) //nolint:gosec // \DDD escapes are at most 255 in valid data
+			i += 3
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case !inQuotes && (c == ' ' || c == '\t'):
+			// Separator between character strings.
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+func isDigits(s string) bool {
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
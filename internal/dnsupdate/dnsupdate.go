@@ -0,0 +1,504 @@
+// Package dnsupdate is a gateway for RFC 2136 dynamic updates. It accepts DNS
+// UPDATE messages signed with a TSIG key managed under Admin → TSIG Keys,
+// checks them against the zones and record types of the key, applies them
+// through the PowerDNS API and records them in the activity log. Clients that
+// only speak DNS, such as DHCP servers, so keep working without access to the
+// PowerDNS API or its key.
+package dnsupdate
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/dns/dnsmessage"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// opUpdate is the UPDATE opcode.
+	opUpdate dnsmessage.OpCode = 5
+
+	// maxUDPSize is the largest update read from a UDP datagram.
+	maxUDPSize = 65535
+
+	// updateTimeout bounds the PowerDNS requests of one update.
+	updateTimeout = 10 * time.Second
+
+	// tcpIdleTimeout closes TCP connections without a further update.
+	tcpIdleTimeout = 30 * time.Second
+)
+
+// Server answers dynamic updates.
+type Server struct {
+	db     *gorm.DB
+	listen string
+	fudge  time.Duration
+	now    func() time.Time
+}
+
+// New returns a Server for the [dnsupdate] settings cfg.
+func New(cfg *config.DNSUpdate, db *gorm.DB) *Server {
+	return &Server{db: db, listen: cfg.Listen, fudge: cfg.Fudge, now: time.Now}
+}
+
+// Run serves updates over UDP and TCP on the listen address until ctx is
+// done. It returns an error when the address cannot be bound.
+func (s *Server) Run(ctx context.Context) error {
+	pc, err := net.ListenPacket("udp", s.listen)
+	if err != nil {
+		return fmt.Errorf("dnsupdate: %w", err)
+	}
+
+	ln, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		_ = pc.Close()
+		return fmt.Errorf("dnsupdate: %w", err)
+	}
+
+	log.Info().Str("listen", s.listen).Msg("dnsupdate: accepting dynamic updates")
+
+	go s.serveUDP(ctx, pc)
+	go s.serveTCP(ctx, ln)
+
+	<-ctx.Done()
+
+	_ = pc.Close()
+	_ = ln.Close()
+
+	return nil
+}
+
+// serveUDP answers the datagrams of pc one after the other, so updates are
+// applied in the order they arrive.
+func (s *Server) serveUDP(ctx context.Context, pc net.PacketConn) {
+	buf := make([]byte, maxUDPSize)
+
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Error().Err(err).Msg("dnsupdate: failed to read UDP message")
+			}
+
+			return
+		}
+
+		if resp := s.Handle(ctx, buf[:n], hostOf(addr)); resp != nil {
+			_, _ = pc.WriteTo(resp, addr)
+		}
+	}
+}
+
+// serveTCP accepts the connections of ln.
+func (s *Server) serveTCP(ctx context.Context, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Error().Err(err).Msg("dnsupdate: failed to accept TCP connection")
+			}
+
+			return
+		}
+
+		go s.serveConn(ctx, conn)
+	}
+}
+
+// serveConn answers the length-prefixed messages of a TCP connection.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		_ = conn.SetDeadline(s.now().Add(tcpIdleTimeout))
+
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+
+		msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+
+		resp := s.Handle(ctx, msg, hostOf(conn.RemoteAddr()))
+		if resp == nil {
+			return
+		}
+
+		//nolint:gosec // responses are far below 64 KiB
+		if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...)); err != nil {
+			return
+		}
+	}
+}
+
+// hostOf returns the IP address of addr.
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
+
+// Handle answers the DNS message msg from the client address client. It
+// returns nil for messages not worth an answer, e.g. responses.
+func (s *Server) Handle(ctx context.Context, msg []byte, client string) []byte {
+	var p dnsmessage.Parser
+
+	header, err := p.Start(msg)
+	if err != nil || header.Response {
+		return nil
+	}
+
+	if header.OpCode != opUpdate {
+		return s.reply(header, nil, dnsmessage.RCodeNotImplemented, nil)
+	}
+
+	signed, rec, err := splitTSIG(msg)
+	if err != nil {
+		return s.reply(header, nil, dnsmessage.RCodeFormatError, nil)
+	}
+
+	if rec == nil {
+		log.Warn().Str("client", client).Msg("dnsupdate: refused unsigned update")
+		return s.reply(header, nil, dnsmessage.RCodeRefused, nil)
+	}
+
+	key, secret, tsigErr := s.lookupKey(rec)
+	if tsigErr == 0 {
+		tsigErr = rec.verify(secret, signed, s.now(), s.fudge)
+	}
+
+	if tsigErr != 0 {
+		log.Warn().Str("client", client).Str("key", rec.KeyName).Uint16("tsig_error", tsigErr).
+			Msg("dnsupdate: rejected update signature")
+
+		return s.reply(header, nil, rcodeNotAuth, &signing{request: rec, secret: secret, err: tsigErr})
+	}
+
+	zone, rcode := s.update(ctx, &p, key, client)
+
+	return s.reply(header, zone, rcode, &signing{request: rec, secret: secret})
+}
+
+// lookupKey returns the enabled key rec is signed with and its secret, or
+// the TSIG error BADKEY.
+func (s *Server) lookupKey(rec *tsig) (*models.TSIGKey, []byte, uint16) {
+	var key models.TSIGKey
+
+	err := s.db.Where("name = ? AND enabled = ?", rec.KeyName, true).First(&key).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Error().Err(err).Str("key", rec.KeyName).Msg("dnsupdate: failed to load TSIG key")
+		}
+
+		return nil, nil, tsigBadKey
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(key.Secret)
+	if err != nil || key.Algorithm != rec.Algorithm {
+		return nil, nil, tsigBadKey
+	}
+
+	return &key, secret, 0
+}
+
+// update applies the update section of the message p is positioned in, after
+// its header. It returns the zone section and the response code.
+func (s *Server) update(ctx context.Context, p *dnsmessage.Parser, key *models.TSIGKey,
+	client string,
+) (*dnsmessage.Question, dnsmessage.RCode) {
+	questions, err := p.AllQuestions()
+	if err != nil || len(questions) != 1 || questions[0].Type != dnsmessage.TypeSOA ||
+		questions[0].Class != dnsmessage.ClassINET {
+		return nil, dnsmessage.RCodeFormatError
+	}
+
+	question := &questions[0]
+	zoneName := strings.ToLower(question.Name.String())
+	logger := log.With().Str("client", client).Str("key", key.Name).Str("zone_name", zoneName).Logger()
+
+	prereqs, err := readSection(p, p.AnswerHeader, p.SkipAnswer)
+	if err != nil {
+		return question, dnsmessage.RCodeFormatError
+	}
+
+	updates, err := readSection(p, p.AuthorityHeader, p.SkipAuthority)
+	if err != nil {
+		return question, dnsmessage.RCodeFormatError
+	}
+
+	if !keyAllowsZone(key, zoneName) {
+		logger.Warn().Msg("dnsupdate: refused update of a zone the key is not limited to")
+		return question, dnsmessage.RCodeRefused
+	}
+
+	if powerdns.Engine.Client == nil {
+		logger.Error().Msg(powerdns.ErrMsgClientNotInitialized)
+		return question, dnsmessage.RCodeServerFailure
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		var pdnsErr *pdnsapi.Error
+		if errors.As(err, &pdnsErr) &&
+			(pdnsErr.StatusCode == http.StatusNotFound || pdnsErr.StatusCode == http.StatusUnprocessableEntity) {
+			return question, rcodeNotAuth
+		}
+
+		logger.Error().Err(err).Msg("dnsupdate: failed to fetch zone")
+
+		return question, dnsmessage.RCodeServerFailure
+	}
+
+	if deletion, err := zonedeletion.Pending(s.db, zoneName); err != nil || deletion != nil {
+		return question, dnsmessage.RCodeRefused
+	}
+
+	before := newZoneState(zone)
+	if rcode := checkPrerequisites(before, zoneName, prereqs); rcode != dnsmessage.RCodeSuccess {
+		return question, rcode
+	}
+
+	allowed := key.RecordTypeList()
+	if rcode := prescan(zoneName, updates, allowed); rcode != dnsmessage.RCodeSuccess {
+		logger.Warn().Str("rcode", dnsclient.RCodeName(rcode)).Msg("dnsupdate: refused update")
+		return question, rcode
+	}
+
+	after := newZoneState(zone)
+	applyUpdates(after, zoneName, updates, allowed)
+
+	patch, diff := changedRRsets(before, after)
+	if len(patch) == 0 {
+		return question, dnsmessage.RCodeSuccess
+	}
+
+	if err = powerdns.Engine.Records.Patch(ctx, zoneName, &pdnsapi.RRsets{Sets: patch}); err != nil {
+		logger.Error().Err(err).Msg("dnsupdate: failed to update records")
+		return question, dnsmessage.RCodeServerFailure
+	}
+
+	logger.Info().Int("changes_count", len(patch)).Msg("dnsupdate: zone records updated")
+
+	// The serial changed; keep the zone lists current.
+	zoneindex.Default.RefreshZone(ctx, zoneName)
+
+	now := s.now()
+	s.db.Model(key).Update("last_used_at", now)
+
+	activitylog.Record(&activitylog.Entry{
+		DB:           s.db,
+		Username:     strings.TrimSuffix(key.Name, "."),
+		Action:       activitylog.ActionRecordChanged,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      diff,
+		IPAddress:    client,
+		AuthMethod:   auth.MethodTSIG,
+	})
+
+	return question, dnsmessage.RCodeSuccess
+}
+
+// keyAllowsZone reports whether zone matches one of the zone patterns of
+// key; a key without patterns may update every zone. Patterns that no longer
+// parse match nothing.
+func keyAllowsZone(key *models.TSIGKey, zone string) bool {
+	texts := key.ZonePatternList()
+	if len(texts) == 0 {
+		return true
+	}
+
+	for _, text := range texts {
+		if pattern, err := auth.ParseZonePattern(text); err == nil && pattern.Match(zone) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readSection reads the records of the section whose header and skip
+// functions are given.
+func readSection(p *dnsmessage.Parser, header func() (dnsmessage.ResourceHeader, error),
+	skip func() error,
+) ([]record, error) {
+	var records []record
+
+	for {
+		h, err := header()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		rr := record{Name: strings.ToLower(h.Name.String()), Type: h.Type, Class: h.Class, TTL: h.TTL}
+
+		if h.Length == 0 {
+			err = skip()
+		} else {
+			rr.Content, err = readData(p, h)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, rr)
+	}
+}
+
+// readData reads the RDATA of the record whose header p just read, in the
+// presentation format of PowerDNS.
+func readData(p *dnsmessage.Parser, h dnsmessage.ResourceHeader) (string, error) {
+	var (
+		body dnsmessage.ResourceBody
+		err  error
+	)
+
+	switch h.Type {
+	case dnsmessage.TypeA:
+		var r dnsmessage.AResource
+		r, err = p.AResource()
+		body = &r
+	case dnsmessage.TypeAAAA:
+		var r dnsmessage.AAAAResource
+		r, err = p.AAAAResource()
+		body = &r
+	case dnsmessage.TypeCNAME:
+		var r dnsmessage.CNAMEResource
+		r, err = p.CNAMEResource()
+		body = &r
+	case dnsmessage.TypeNS:
+		var r dnsmessage.NSResource
+		r, err = p.NSResource()
+		body = &r
+	case dnsmessage.TypePTR:
+		var r dnsmessage.PTRResource
+		r, err = p.PTRResource()
+		body = &r
+	case dnsmessage.TypeMX:
+		var r dnsmessage.MXResource
+		r, err = p.MXResource()
+		body = &r
+	case dnsmessage.TypeSRV:
+		var r dnsmessage.SRVResource
+		r, err = p.SRVResource()
+		body = &r
+	case dnsmessage.TypeTXT:
+		var r dnsmessage.TXTResource
+		r, err = p.TXTResource()
+		body = &r
+	default:
+		var r dnsmessage.UnknownResource
+		r, err = p.UnknownResource()
+		body = &r
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return dnsclient.Data(&dnsmessage.Resource{Header: h, Body: body}), nil
+}
+
+// signing signs a response with the key of the request.
+type signing struct {
+	request *tsig
+	secret  []byte
+	// err is the TSIG error of the request; BADKEY and BADSIG answers are
+	// not signed.
+	err uint16
+}
+
+// reply builds the response to the request with header, echoing its zone
+// section.
+func (s *Server) reply(header dnsmessage.Header, zone *dnsmessage.Question, rcode dnsmessage.RCode,
+	sig *signing,
+) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:       header.ID,
+		Response: true,
+		OpCode:   header.OpCode,
+		RCode:    rcode,
+	})
+
+	if zone != nil {
+		_ = b.StartQuestions()
+		_ = b.Question(*zone)
+	}
+
+	msg, err := b.Finish()
+	if err != nil || sig == nil {
+		return msg
+	}
+
+	rec := &tsig{
+		KeyName:    sig.request.KeyName,
+		Algorithm:  sig.request.Algorithm,
+		TimeSigned: uint64(s.now().Unix()), //nolint:gosec // the clock is after 1970
+		Fudge:      sig.request.Fudge,
+		OriginalID: header.ID,
+		Error:      sig.err,
+	}
+
+	switch sig.err {
+	case 0:
+		rec.MAC = rec.mac(sig.secret, msg, sig.request.MAC)
+	case tsigBadTime:
+		// Tell the client the time of the server (RFC 8945, 5.2.3).
+		rec.OtherData = appendTime(nil, rec.TimeSigned)
+		rec.TimeSigned = sig.request.TimeSigned
+		rec.MAC = rec.mac(sig.secret, msg, sig.request.MAC)
+	default:
+		rec.TimeSigned = sig.request.TimeSigned
+	}
+
+	return appendTSIG(msg, rec)
+}
+
+// appendTSIG appends rec to the additional section of msg.
+func appendTSIG(msg []byte, rec *tsig) []byte {
+	rdata := rec.rdata()
+
+	msg = appendName(msg, rec.KeyName)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(typeTSIG))
+	msg = binary.BigEndian.AppendUint16(msg, uint16(classANY))
+	msg = binary.BigEndian.AppendUint32(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(rdata))) //nolint:gosec // TSIG RDATA is below 64 KiB
+	msg = append(msg, rdata...)
+
+	binary.BigEndian.PutUint16(msg[10:], binary.BigEndian.Uint16(msg[10:])+1)
+
+	return msg
+}
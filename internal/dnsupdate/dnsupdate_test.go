@@ -0,0 +1,345 @@
+package dnsupdate
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"golang.org/x/net/dns/dnsmessage"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/pdnsserver"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+const testZone = `{"name":"example.com.","kind":"Native","rrsets":[` +
+	`{"name":"example.com.","type":"SOA","ttl":3600,"records":[{"content":` +
+	`"ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600","disabled":false}],"comments":[]},` +
+	`{"name":"example.com.","type":"NS","ttl":3600,"records":[{"content":"ns1.example.com.","disabled":false}],` +
+	`"comments":[]},` +
+	`{"name":"old.example.com.","type":"A","ttl":300,"records":[{"content":"192.0.2.5","disabled":false}],` +
+	`"comments":[]}]}`
+
+// fakePowerDNS serves example.com. and keeps the bodies of the PATCH
+// requests.
+type fakePowerDNS struct {
+	mu      sync.Mutex
+	patches []string
+}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /api/v1/servers/localhost":
+		_, _ = io.WriteString(w, `{"id":"localhost","version":"4.9.0"}`)
+	case "GET /api/v1/servers/localhost/zones/example.com.":
+		_, _ = io.WriteString(w, testZone)
+	case "PATCH /api/v1/servers/localhost/zones/example.com.":
+		body, _ := io.ReadAll(r.Body)
+
+		f.mu.Lock()
+		f.patches = append(f.patches, string(body))
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"Not Found"}`)
+	}
+}
+
+var (
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+	testNow    = time.Unix(1_700_000_000, 0)
+)
+
+type gatewayFixture struct {
+	server *Server
+	db     *gorm.DB
+	pdns   *fakePowerDNS
+}
+
+// newGatewayFixture serves updates signed with the key dhcp-updater., which
+// may change A and PTR records of example.com.
+func newGatewayFixture(t *testing.T) *gatewayFixture {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Setting{}, &models.TSIGKey{}, &models.ZoneDeletion{},
+		&models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	db.Create(&models.TSIGKey{
+		Name:        "dhcp-updater.",
+		Algorithm:   AlgorithmHMACSHA256,
+		Secret:      "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		Zones:       "example.com",
+		RecordTypes: "A,PTR",
+		Enabled:     true,
+	})
+
+	f := &gatewayFixture{db: db, pdns: &fakePowerDNS{}}
+
+	srv := httptest.NewServer(f.pdns)
+	t.Cleanup(srv.Close)
+
+	settings := &pdnsserver.Settings{APIServerURL: srv.URL, APIKey: "secret", VHost: "localhost"}
+	if err = settings.Save(db); err != nil {
+		t.Fatalf("failed to save PowerDNS settings: %v", err)
+	}
+
+	prev := powerdns.Engine
+	t.Cleanup(func() { powerdns.Engine = prev })
+
+	if err = powerdns.Open(db); err != nil {
+		t.Fatalf("failed to open PowerDNS client: %v", err)
+	}
+
+	f.server = New(&config.DNSUpdate{Fudge: 5 * time.Minute}, db)
+	f.server.now = func() time.Time { return testNow }
+
+	return f
+}
+
+// update is an update of the zone with the given prerequisites and updates.
+type update struct {
+	zone    string
+	prereqs []dnsmessage.Resource
+	updates []dnsmessage.Resource
+}
+
+// message builds the unsigned UPDATE message of u.
+func (u *update) message(t *testing.T) []byte {
+	t.Helper()
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 4711, OpCode: opUpdate})
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{
+		Name: dnsmessage.MustNewName(u.zone), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET,
+	})
+
+	_ = b.StartAnswers()
+	for _, rr := range u.prereqs {
+		addResource(t, &b, rr)
+	}
+
+	_ = b.StartAuthorities()
+	for _, rr := range u.updates {
+		addResource(t, &b, rr)
+	}
+
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	return msg
+}
+
+func addResource(t *testing.T, b *dnsmessage.Builder, rr dnsmessage.Resource) {
+	t.Helper()
+
+	var err error
+
+	switch body := rr.Body.(type) {
+	case *dnsmessage.AResource:
+		err = b.AResource(rr.Header, *body)
+	case *dnsmessage.MXResource:
+		err = b.MXResource(rr.Header, *body)
+	case *dnsmessage.UnknownResource:
+		// The builder takes the type of unknown records from their body.
+		body.Type = rr.Header.Type
+		err = b.UnknownResource(rr.Header, *body)
+	}
+
+	if err != nil {
+		t.Fatalf("failed to add %v: %v", rr.Header, err)
+	}
+}
+
+// header returns a record header; class is ClassINET for additions.
+func header(name string, rtype dnsmessage.Type, class dnsmessage.Class, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: rtype, Class: class, TTL: ttl}
+}
+
+// send signs msg with the key dhcp-updater. and secret and returns the
+// response code, the TSIG record of the response and whether its MAC is
+// valid.
+func (f *gatewayFixture) send(t *testing.T, msg, secret []byte) (dnsmessage.RCode, *tsig, bool) {
+	t.Helper()
+
+	signedMsg, rec := sign(t, msg, "dhcp-updater.", AlgorithmHMACSHA256, secret, testNow)
+
+	resp := f.server.Handle(context.Background(), signedMsg, "192.0.2.100")
+	if resp == nil {
+		t.Fatal("Handle() = nil, want a response")
+	}
+
+	h, err := new(dnsmessage.Parser).Start(resp)
+	if err != nil || !h.Response || h.ID != 4711 {
+		t.Fatalf("response header = %+v, %v", h, err)
+	}
+
+	signed, respTSIG, err := splitTSIG(resp)
+	if err != nil {
+		t.Fatalf("splitTSIG(response) error = %v", err)
+	}
+
+	valid := respTSIG != nil && bytes.Equal(respTSIG.MAC, respTSIG.mac(secret, signed, rec.MAC))
+
+	return h.RCode, respTSIG, valid
+}
+
+func TestHandle_AppliesUpdate(t *testing.T) {
+	f := newGatewayFixture(t)
+
+	msg := (&update{
+		zone: "example.com.",
+		prereqs: []dnsmessage.Resource{
+			{Header: header("host1.example.com.", dnsmessage.TypeALL, classNONE, 0),
+				Body: &dnsmessage.UnknownResource{}},
+		},
+		updates: []dnsmessage.Resource{
+			{Header: header("host1.example.com.", dnsmessage.TypeA, dnsmessage.ClassINET, 600),
+				Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}}},
+			{Header: header("old.example.com.", dnsmessage.TypeA, classANY, 0),
+				Body: &dnsmessage.UnknownResource{}},
+		},
+	}).message(t)
+
+	rcode, rec, valid := f.send(t, msg, testSecret)
+	if rcode != dnsmessage.RCodeSuccess || !valid {
+		t.Fatalf("response = %v, TSIG %+v valid %v; want NOERROR, signed", rcode, rec, valid)
+	}
+
+	if len(f.pdns.patches) != 1 {
+		t.Fatalf("%d patches sent to PowerDNS, want 1", len(f.pdns.patches))
+	}
+
+	patch := f.pdns.patches[0]
+	for _, want := range []string{
+		`"host1.example.com."`, `"192.0.2.10"`, `"REPLACE"`, `"old.example.com."`, `"DELETE"`,
+	} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("patch %s lacks %s", patch, want)
+		}
+	}
+
+	var entry models.ActivityLog
+	if err := f.db.Where("action = ?", activitylog.ActionRecordChanged).First(&entry).Error; err != nil {
+		t.Fatalf("no record change in the activity log: %v", err)
+	}
+
+	if entry.Username != "dhcp-updater" || entry.AuthMethod != auth.MethodTSIG ||
+		entry.IPAddress != "192.0.2.100" || !strings.Contains(entry.Details, "host1.example.com.") {
+		t.Errorf("activity = %+v", entry)
+	}
+
+	var key models.TSIGKey
+	if f.db.First(&key); key.LastUsedAt == nil {
+		t.Error("LastUsedAt not set")
+	}
+}
+
+func TestHandle_Rejects(t *testing.T) {
+	addA := dnsmessage.Resource{
+		Header: header("host1.example.com.", dnsmessage.TypeA, dnsmessage.ClassINET, 600),
+		Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}},
+	}
+
+	tests := []struct {
+		name   string
+		update update
+		secret []byte
+		want   dnsmessage.RCode
+		// tsigErr is the TSIG error of the response.
+		tsigErr uint16
+	}{
+		{
+			name:    "wrong secret",
+			update:  update{zone: "example.com.", updates: []dnsmessage.Resource{addA}},
+			secret:  []byte("wrong"),
+			want:    rcodeNotAuth,
+			tsigErr: tsigBadSig,
+		},
+		{
+			name: "record type not allowed",
+			update: update{zone: "example.com.", updates: []dnsmessage.Resource{{
+				Header: header("example.com.", dnsmessage.TypeMX, dnsmessage.ClassINET, 600),
+				Body:   &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.example.com.")},
+			}}},
+			want: dnsmessage.RCodeRefused,
+		},
+		{
+			name:   "zone not allowed",
+			update: update{zone: "example.org.", updates: []dnsmessage.Resource{addA}},
+			want:   dnsmessage.RCodeRefused,
+		},
+		{
+			name: "prerequisite fails",
+			update: update{
+				zone: "example.com.",
+				prereqs: []dnsmessage.Resource{{
+					Header: header("old.example.com.", dnsmessage.TypeALL, classNONE, 0),
+					Body:   &dnsmessage.UnknownResource{},
+				}},
+				updates: []dnsmessage.Resource{addA},
+			},
+			want: rcodeYXDomain,
+		},
+	}
+
+	for _, tt := range tests {
+		f := newGatewayFixture(t)
+
+		secret := tt.secret
+		if secret == nil {
+			secret = testSecret
+		}
+
+		rcode, rec, valid := f.send(t, tt.update.message(t), secret)
+		if rcode != tt.want {
+			t.Errorf("%s: rcode = %v, want %v", tt.name, rcode, tt.want)
+		}
+
+		if rec == nil || rec.Error != tt.tsigErr {
+			t.Errorf("%s: response TSIG = %+v, want error %d", tt.name, rec, tt.tsigErr)
+		}
+
+		if tt.tsigErr == 0 && !valid {
+			t.Errorf("%s: response is not signed", tt.name)
+		}
+
+		if len(f.pdns.patches) != 0 {
+			t.Errorf("%s: patches sent to PowerDNS: %v", tt.name, f.pdns.patches)
+		}
+	}
+}
+
+func TestHandle_Unsigned(t *testing.T) {
+	f := newGatewayFixture(t)
+
+	resp := f.server.Handle(context.Background(), (&update{zone: "example.com."}).message(t), "192.0.2.100")
+
+	h, err := new(dnsmessage.Parser).Start(resp)
+	if err != nil || h.RCode != dnsmessage.RCodeRefused {
+		t.Errorf("response = %+v, %v; want REFUSED", h, err)
+	}
+}
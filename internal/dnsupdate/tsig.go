@@ -0,0 +1,325 @@
+package dnsupdate
+
+import (
+	"crypto/hmac"
+	"crypto/md5"  //nolint:gosec // HMAC-MD5 is still the default TSIG algorithm of ISC dhcpd
+	"crypto/sha1" //nolint:gosec // HMAC-SHA1 is a TSIG algorithm of RFC 4635
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TSIG algorithm names (RFC 8945, section 6).
+const (
+	AlgorithmHMACMD5    = "hmac-md5.sig-alg.reg.int."
+	AlgorithmHMACSHA1   = "hmac-sha1."
+	AlgorithmHMACSHA224 = "hmac-sha224."
+	AlgorithmHMACSHA256 = "hmac-sha256."
+	AlgorithmHMACSHA384 = "hmac-sha384."
+	AlgorithmHMACSHA512 = "hmac-sha512."
+)
+
+// Algorithms are the TSIG algorithms keys may use, strongest last.
+var Algorithms = []string{
+	AlgorithmHMACMD5, AlgorithmHMACSHA1, AlgorithmHMACSHA224,
+	AlgorithmHMACSHA256, AlgorithmHMACSHA384, AlgorithmHMACSHA512,
+}
+
+// algorithmHashes maps the TSIG algorithms to their hash functions.
+var algorithmHashes = map[string]func() hash.Hash{
+	AlgorithmHMACMD5:    md5.New,
+	AlgorithmHMACSHA1:   sha1.New,
+	AlgorithmHMACSHA224: sha256.New224,
+	AlgorithmHMACSHA256: sha256.New,
+	AlgorithmHMACSHA384: sha512.New384,
+	AlgorithmHMACSHA512: sha512.New,
+}
+
+// typeTSIG is the TSIG meta record type.
+const typeTSIG dnsmessage.Type = 250
+
+// classANY is the class of TSIG records and of deletions in an update.
+const classANY dnsmessage.Class = 255
+
+// TSIG error codes of RFC 8945, section 5.3.
+const (
+	tsigBadSig  uint16 = 16
+	tsigBadKey  uint16 = 17
+	tsigBadTime uint16 = 18
+)
+
+var (
+	errTSIGFormat   = errors.New("malformed TSIG record")
+	errTSIGTooShort = errors.New("message too short")
+)
+
+// tsig is the TSIG record of a message.
+type tsig struct {
+	// KeyName and Algorithm are lower-case and fully qualified.
+	KeyName    string
+	Algorithm  string
+	TimeSigned uint64
+	Fudge      uint16
+	MAC        []byte
+	OriginalID uint16
+	Error      uint16
+	OtherData  []byte
+}
+
+// splitTSIG separates the TSIG record from msg. It returns the message as it
+// was signed, with the TSIG record removed from the additional section, and
+// the record; rec is nil for unsigned messages.
+func splitTSIG(msg []byte) (signed []byte, rec *tsig, err error) {
+	start, err := lastRecordOffset(msg)
+	if err != nil || start < 0 {
+		return msg, nil, err
+	}
+
+	name, off, err := readName(msg, start)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if off+10 > len(msg) || dnsmessage.Type(binary.BigEndian.Uint16(msg[off:])) != typeTSIG {
+		return msg, nil, nil
+	}
+
+	rdLen := int(binary.BigEndian.Uint16(msg[off+8:]))
+	if off+10+rdLen != len(msg) {
+		return nil, nil, errTSIGFormat
+	}
+
+	rec, err = parseTSIG(msg[off+10:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rec.KeyName = name
+
+	signed = slices.Clone(msg[:start])
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(msg[10:])-1)
+
+	return signed, rec, nil
+}
+
+// parseTSIG parses the RDATA of a TSIG record.
+func parseTSIG(rdata []byte) (*tsig, error) {
+	algorithm, off, err := readName(rdata, 0)
+	if err != nil {
+		return nil, errTSIGFormat
+	}
+
+	if off+10 > len(rdata) {
+		return nil, errTSIGFormat
+	}
+
+	rec := &tsig{
+		Algorithm:  algorithm,
+		TimeSigned: uint64(binary.BigEndian.Uint16(rdata[off:]))<<32 | uint64(binary.BigEndian.Uint32(rdata[off+2:])),
+		Fudge:      binary.BigEndian.Uint16(rdata[off+6:]),
+	}
+
+	macLen := int(binary.BigEndian.Uint16(rdata[off+8:]))
+	off += 10
+
+	if off+macLen+6 > len(rdata) {
+		return nil, errTSIGFormat
+	}
+
+	rec.MAC = rdata[off : off+macLen]
+	off += macLen
+
+	rec.OriginalID = binary.BigEndian.Uint16(rdata[off:])
+	rec.Error = binary.BigEndian.Uint16(rdata[off+2:])
+	otherLen := int(binary.BigEndian.Uint16(rdata[off+4:]))
+	off += 6
+
+	if off+otherLen != len(rdata) {
+		return nil, errTSIGFormat
+	}
+
+	rec.OtherData = rdata[off:]
+
+	return rec, nil
+}
+
+// rdata returns the RDATA of rec.
+func (rec *tsig) rdata() []byte {
+	b := appendName(nil, rec.Algorithm)
+	b = appendTime(b, rec.TimeSigned)
+	b = binary.BigEndian.AppendUint16(b, rec.Fudge)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rec.MAC))) //nolint:gosec // MACs are at most 64 bytes
+	b = append(b, rec.MAC...)
+	b = binary.BigEndian.AppendUint16(b, rec.OriginalID)
+	b = binary.BigEndian.AppendUint16(b, rec.Error)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rec.OtherData))) //nolint:gosec // at most 6 bytes
+
+	return append(b, rec.OtherData...)
+}
+
+// mac computes the MAC of the signed message msg with the variables of rec.
+// requestMAC is the MAC of the request when msg is a response, else nil.
+func (rec *tsig) mac(secret, msg, requestMAC []byte) []byte {
+	h := hmac.New(algorithmHashes[rec.Algorithm], secret)
+
+	if requestMAC != nil {
+		_ = binary.Write(h, binary.BigEndian, uint16(len(requestMAC))) //nolint:gosec // MACs are at most 64 bytes
+		h.Write(requestMAC)
+	}
+
+	// The ID of the signed message is the original ID (RFC 8945, 4.3.3).
+	signed := slices.Clone(msg)
+	binary.BigEndian.PutUint16(signed, rec.OriginalID)
+	h.Write(signed)
+
+	// Names enter the MAC in canonical, lower-case form (RFC 8945, 4.3.3).
+	vars := appendName(nil, strings.ToLower(rec.KeyName))
+	vars = binary.BigEndian.AppendUint16(vars, uint16(classANY))
+	vars = binary.BigEndian.AppendUint32(vars, 0)
+	vars = appendName(vars, strings.ToLower(rec.Algorithm))
+	vars = appendTime(vars, rec.TimeSigned)
+	vars = binary.BigEndian.AppendUint16(vars, rec.Fudge)
+	vars = binary.BigEndian.AppendUint16(vars, rec.Error)
+	vars = binary.BigEndian.AppendUint16(vars, uint16(len(rec.OtherData))) //nolint:gosec // at most 6 bytes
+	vars = append(vars, rec.OtherData...)
+	h.Write(vars)
+
+	return h.Sum(nil)
+}
+
+// verify checks the MAC and the time of rec, a TSIG record of msg signed with
+// secret, at now. It returns the TSIG error code, or 0 when rec is valid.
+func (rec *tsig) verify(secret, msg []byte, now time.Time, fudge time.Duration) uint16 {
+	if _, ok := algorithmHashes[rec.Algorithm]; !ok {
+		return tsigBadKey
+	}
+
+	if !hmac.Equal(rec.MAC, rec.mac(secret, msg, nil)) {
+		return tsigBadSig
+	}
+
+	signed := time.Unix(int64(rec.TimeSigned), 0) //nolint:gosec // 48-bit times fit into int64
+	if d := now.Sub(signed); d > fudge || d < -fudge {
+		return tsigBadTime
+	}
+
+	return 0
+}
+
+// appendTime appends the 48-bit time t.
+func appendTime(b []byte, t uint64) []byte {
+	return append(b, byte(t>>40), byte(t>>32), byte(t>>24), byte(t>>16), byte(t>>8), byte(t))
+}
+
+// appendName appends the uncompressed wire form of the fully qualified name.
+func appendName(b []byte, name string) []byte {
+	for label := range strings.SplitSeq(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+
+	return append(b, 0)
+}
+
+// readName reads the name at off of msg, following compression pointers. It
+// returns the name lower-case and fully qualified, and the offset after it.
+func readName(msg []byte, off int) (string, int, error) {
+	var (
+		labels []string
+		end    = -1
+	)
+
+	for hops := 0; ; hops++ {
+		if off >= len(msg) || hops > 127 {
+			return "", 0, errTSIGTooShort
+		}
+
+		n := int(msg[off])
+
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+
+			return strings.ToLower(strings.Join(labels, ".")) + ".", end, nil
+		case n&0xC0 == 0xC0:
+			if off+2 > len(msg) {
+				return "", 0, errTSIGTooShort
+			}
+
+			if end < 0 {
+				end = off + 2
+			}
+
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errTSIGTooShort
+			}
+
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+// lastRecordOffset returns the offset of the last record of the additional
+// section of msg, or -1 when the section is empty.
+func lastRecordOffset(msg []byte) (int, error) {
+	if len(msg) < 12 {
+		return 0, errTSIGTooShort
+	}
+
+	counts := [4]int{}
+	for i := range counts {
+		counts[i] = int(binary.BigEndian.Uint16(msg[4+2*i:]))
+	}
+
+	if counts[3] == 0 {
+		return -1, nil
+	}
+
+	off := 12
+
+	for range counts[0] {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return 0, err
+		}
+
+		off = next + 4
+	}
+
+	records := counts[1] + counts[2] + counts[3]
+
+	for i := range records {
+		if i == records-1 {
+			return off, nil
+		}
+
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return 0, err
+		}
+
+		if next+10 > len(msg) {
+			return 0, errTSIGTooShort
+		}
+
+		off = next + 10 + int(binary.BigEndian.Uint16(msg[next+8:]))
+	}
+
+	return 0, errTSIGFormat
+}
@@ -0,0 +1,88 @@
+package dnsupdate
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// sign appends a TSIG record of the key name signed with secret at now.
+func sign(t *testing.T, msg []byte, name, algorithm string, secret []byte, now time.Time) ([]byte, *tsig) {
+	t.Helper()
+
+	rec := &tsig{
+		KeyName:    name,
+		Algorithm:  algorithm,
+		TimeSigned: uint64(now.Unix()), //nolint:gosec // test times are after 1970
+		Fudge:      300,
+		OriginalID: uint16(msg[0])<<8 | uint16(msg[1]),
+	}
+	rec.MAC = rec.mac(secret, msg, nil)
+
+	return appendTSIG(msg, rec), rec
+}
+
+func TestSplitTSIG_RoundTrip(t *testing.T) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 4711, OpCode: opUpdate})
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{
+		Name: dnsmessage.MustNewName("example.com."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET,
+	})
+
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	secret := []byte("0123456789abcdef")
+	now := time.Unix(1_700_000_000, 0)
+
+	for _, algorithm := range Algorithms {
+		signedMsg, sent := sign(t, bytes.Clone(msg), "Dhcp-Updater.", algorithm, secret, now)
+
+		signed, rec, err := splitTSIG(signedMsg)
+		if err != nil || rec == nil {
+			t.Fatalf("%s: splitTSIG() = %v, %v", algorithm, rec, err)
+		}
+
+		if !bytes.Equal(signed, msg) {
+			t.Errorf("%s: signed message differs from the original", algorithm)
+		}
+
+		if rec.KeyName != "dhcp-updater." || rec.Algorithm != algorithm || !bytes.Equal(rec.MAC, sent.MAC) {
+			t.Errorf("%s: record = %+v", algorithm, rec)
+		}
+
+		if code := rec.verify(secret, signed, now.Add(time.Minute), 5*time.Minute); code != 0 {
+			t.Errorf("%s: verify() = %d, want 0", algorithm, code)
+		}
+
+		if code := rec.verify([]byte("wrong"), signed, now, 5*time.Minute); code != tsigBadSig {
+			t.Errorf("%s: verify(wrong secret) = %d, want BADSIG", algorithm, code)
+		}
+
+		if code := rec.verify(secret, signed, now.Add(time.Hour), 5*time.Minute); code != tsigBadTime {
+			t.Errorf("%s: verify(an hour later) = %d, want BADTIME", algorithm, code)
+		}
+	}
+}
+
+func TestSplitTSIG_Unsigned(t *testing.T) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 1, OpCode: opUpdate})
+
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	signed, rec, err := splitTSIG(msg)
+	if err != nil || rec != nil || !bytes.Equal(signed, msg) {
+		t.Errorf("splitTSIG() = %v, %+v, %v, want the message unchanged", signed, rec, err)
+	}
+
+	if _, _, err = splitTSIG(msg[:5]); err == nil {
+		t.Error("splitTSIG(truncated) error = nil")
+	}
+}
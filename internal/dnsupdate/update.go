@@ -0,0 +1,390 @@
+package dnsupdate
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+)
+
+// Response codes of RFC 2136 without a dnsmessage constant.
+const (
+	rcodeYXDomain dnsmessage.RCode = 6
+	rcodeYXRRSet  dnsmessage.RCode = 7
+	rcodeNXRRSet  dnsmessage.RCode = 8
+	rcodeNotAuth  dnsmessage.RCode = 9
+	rcodeNotZone  dnsmessage.RCode = 10
+)
+
+// classNONE marks the deletion of single records in an update.
+const classNONE dnsmessage.Class = 254
+
+// record is a record of the prerequisite or update section.
+type record struct {
+	// Name is lower-case and fully qualified.
+	Name  string
+	Type  dnsmessage.Type
+	Class dnsmessage.Class
+	TTL   uint32
+	// Content is the record data as PowerDNS shows it, "" for empty RDATA.
+	Content string
+}
+
+// rrKey identifies an RRset of a zone.
+type rrKey struct {
+	name, rtype string
+}
+
+// rrset is an RRset of a zone while an update is applied.
+type rrset struct {
+	TTL      uint32
+	Records  []pdnsapi.Record
+	Comments []pdnsapi.Comment
+}
+
+// contains reports whether the RRset has a record with content.
+func (r *rrset) contains(rtype, content string) bool {
+	return slices.ContainsFunc(r.Records, func(rec pdnsapi.Record) bool {
+		return sameContent(rtype, pdnsapi.StringValue(rec.Content), content)
+	})
+}
+
+// zoneState holds the RRsets of a zone by name and type.
+type zoneState map[rrKey]*rrset
+
+// newZoneState copies the RRsets of zone.
+func newZoneState(zone *pdnsapi.Zone) zoneState {
+	state := make(zoneState, len(zone.RRsets))
+
+	for i := range zone.RRsets {
+		rr := &zone.RRsets[i]
+		if rr.Name == nil || rr.Type == nil {
+			continue
+		}
+
+		state[rrKey{strings.ToLower(*rr.Name), string(*rr.Type)}] = &rrset{
+			TTL:      pdnsapi.Uint32Value(rr.TTL),
+			Records:  slices.Clone(rr.Records),
+			Comments: rr.Comments,
+		}
+	}
+
+	return state
+}
+
+// types returns the record types of the RRsets at name.
+func (z zoneState) types(name string) []string {
+	var types []string
+
+	for key := range z {
+		if key.name == name {
+			types = append(types, key.rtype)
+		}
+	}
+
+	return types
+}
+
+// sameContent compares record contents; all but TXT data ignore case.
+func sameContent(rtype, a, b string) bool {
+	if rtype == "TXT" {
+		return a == b
+	}
+
+	return strings.EqualFold(a, b)
+}
+
+// inZone reports whether name is zone or below it.
+func inZone(name, zone string) bool {
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// isMetaType reports whether t only exists in queries, like ANY or AXFR.
+func isMetaType(t dnsmessage.Type) bool {
+	return t >= 251 && t <= 255
+}
+
+// checkPrerequisites evaluates the prerequisite section against the zone
+// (RFC 2136, section 3.2).
+func checkPrerequisites(state zoneState, zone string, prereqs []record) dnsmessage.RCode {
+	values := map[rrKey][]string{}
+
+	for _, rr := range prereqs {
+		if rr.TTL != 0 {
+			return dnsmessage.RCodeFormatError
+		}
+
+		if !inZone(rr.Name, zone) {
+			return rcodeNotZone
+		}
+
+		rtype := dnsclient.TypeName(rr.Type)
+
+		switch rr.Class {
+		case classANY, classNONE:
+			if rr.Content != "" {
+				return dnsmessage.RCodeFormatError
+			}
+
+			inUse := len(state.types(rr.Name)) > 0
+			if rr.Type != dnsmessage.TypeALL {
+				_, inUse = state[rrKey{rr.Name, rtype}]
+			}
+
+			if code := prerequisiteCode(rr, inUse); code != dnsmessage.RCodeSuccess {
+				return code
+			}
+		case dnsmessage.ClassINET:
+			key := rrKey{rr.Name, rtype}
+			values[key] = append(values[key], rr.Content)
+		default:
+			return dnsmessage.RCodeFormatError
+		}
+	}
+
+	// The RRsets given by value must match the zone exactly.
+	for key, contents := range values {
+		current, ok := state[key]
+		if !ok || len(current.Records) != len(contents) {
+			return rcodeNXRRSet
+		}
+
+		for _, content := range contents {
+			if !current.contains(key.rtype, content) {
+				return rcodeNXRRSet
+			}
+		}
+	}
+
+	return dnsmessage.RCodeSuccess
+}
+
+// prerequisiteCode is the result of a "name/RRset (not) in use"
+// prerequisite, given whether the name or RRset is in use.
+func prerequisiteCode(rr record, inUse bool) dnsmessage.RCode {
+	switch {
+	case rr.Class == classANY && !inUse && rr.Type == dnsmessage.TypeALL:
+		return dnsmessage.RCodeNameError
+	case rr.Class == classANY && !inUse:
+		return rcodeNXRRSet
+	case rr.Class == classNONE && inUse && rr.Type == dnsmessage.TypeALL:
+		return rcodeYXDomain
+	case rr.Class == classNONE && inUse:
+		return rcodeYXRRSet
+	default:
+		return dnsmessage.RCodeSuccess
+	}
+}
+
+// prescan checks the update section before anything is applied (RFC 2136,
+// section 3.4.1): records must be in the zone and well-formed, and the key
+// must allow their record types. SOA records are managed by PowerDNS and
+// refused.
+func prescan(zone string, updates []record, allowed []string) dnsmessage.RCode {
+	for _, rr := range updates {
+		if !inZone(rr.Name, zone) {
+			return rcodeNotZone
+		}
+
+		switch rr.Class {
+		case dnsmessage.ClassINET:
+			if isMetaType(rr.Type) || rr.Content == "" {
+				return dnsmessage.RCodeFormatError
+			}
+		case classANY:
+			if rr.TTL != 0 || rr.Content != "" || (isMetaType(rr.Type) && rr.Type != dnsmessage.TypeALL) {
+				return dnsmessage.RCodeFormatError
+			}
+		case classNONE:
+			if rr.TTL != 0 || isMetaType(rr.Type) {
+				return dnsmessage.RCodeFormatError
+			}
+		default:
+			return dnsmessage.RCodeFormatError
+		}
+
+		if rr.Type == dnsmessage.TypeALL {
+			continue
+		}
+
+		if rr.Type == dnsmessage.TypeSOA || !slices.Contains(allowed, dnsclient.TypeName(rr.Type)) {
+			return dnsmessage.RCodeRefused
+		}
+	}
+
+	return dnsmessage.RCodeSuccess
+}
+
+// applyUpdates applies the prescanned update section to state (RFC 2136,
+// section 3.4.2). Deleting all RRsets of a name only deletes the record types
+// in allowed; the SOA and NS records of the apex are never deleted.
+func applyUpdates(state zoneState, zone string, updates []record, allowed []string) {
+	for _, rr := range updates {
+		rtype := dnsclient.TypeName(rr.Type)
+		key := rrKey{rr.Name, rtype}
+		apexNS := rr.Name == zone && (rtype == "NS" || rtype == "SOA")
+
+		switch {
+		case rr.Class == dnsmessage.ClassINET:
+			addRecord(state, key, rr)
+		case rr.Class == classANY && rr.Type == dnsmessage.TypeALL:
+			for _, t := range state.types(rr.Name) {
+				if slices.Contains(allowed, t) && (rr.Name != zone || (t != "NS" && t != "SOA")) {
+					delete(state, rrKey{rr.Name, t})
+				}
+			}
+		case rr.Class == classANY && !apexNS:
+			delete(state, key)
+		case rr.Class == classNONE:
+			removeRecord(state, key, rr.Content, apexNS)
+		}
+	}
+}
+
+// addRecord adds rr to its RRset. A CNAME replaces the CNAME of its name and
+// is ignored next to other data, as other data is next to a CNAME.
+func addRecord(state zoneState, key rrKey, rr record) {
+	types := state.types(rr.Name)
+	if key.rtype == "CNAME" && slices.ContainsFunc(types, func(t string) bool { return t != "CNAME" }) ||
+		key.rtype != "CNAME" && slices.Contains(types, "CNAME") {
+		return
+	}
+
+	current, ok := state[key]
+	if !ok || key.rtype == "CNAME" {
+		var comments []pdnsapi.Comment
+		if ok {
+			comments = current.Comments
+		}
+
+		current = &rrset{Comments: comments}
+		state[key] = current
+	}
+
+	current.TTL = rr.TTL
+
+	if !current.contains(key.rtype, rr.Content) {
+		current.Records = append(current.Records, pdnsapi.Record{
+			Content:  pdnsapi.String(rr.Content),
+			Disabled: pdnsapi.Bool(false),
+		})
+	}
+}
+
+// removeRecord deletes the record with content from its RRset. The last NS
+// record of the apex is kept.
+func removeRecord(state zoneState, key rrKey, content string, apexNS bool) {
+	current, ok := state[key]
+	if !ok || key.rtype == "SOA" {
+		return
+	}
+
+	records := slices.DeleteFunc(slices.Clone(current.Records), func(rec pdnsapi.Record) bool {
+		return sameContent(key.rtype, pdnsapi.StringValue(rec.Content), content)
+	})
+
+	switch {
+	case len(records) == 0 && apexNS:
+		return
+	case len(records) == 0:
+		delete(state, key)
+	default:
+		current.Records = records
+	}
+}
+
+// changedRRsets compares the RRsets of a zone before and after an update. It
+// returns the PowerDNS patch and the diff for the activity log, sorted by name
+// and type.
+func changedRRsets(before, after zoneState) ([]pdnsapi.RRset, *activitylog.RecordsDiff) {
+	keys := make([]rrKey, 0, len(after))
+
+	for key, set := range after {
+		if old, ok := before[key]; !ok || !sameRRset(key.rtype, old, set) {
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+
+		return keys[i].rtype < keys[j].rtype
+	})
+
+	patch := make([]pdnsapi.RRset, 0, len(keys))
+	diff := &activitylog.RecordsDiff{}
+
+	for _, key := range keys {
+		old, hadOld := before[key]
+		set, ok := after[key]
+
+		entry := activitylog.RecordEntryDiff{Name: key.name, Type: key.rtype}
+		rr := pdnsapi.RRset{Name: pdnsapi.String(key.name), Type: pdnsapi.RRTypePtr(pdnsapi.RRType(key.rtype))}
+
+		if hadOld {
+			entry.OldTTL = old.TTL
+			entry.Old = contents(old)
+		}
+
+		switch {
+		case !ok:
+			entry.Action = "deleted"
+			rr.ChangeType = pdnsapi.ChangeTypePtr(pdnsapi.ChangeTypeDelete)
+		default:
+			entry.Action = "modified"
+			if !hadOld {
+				entry.Action = "added"
+			}
+
+			entry.NewTTL = set.TTL
+			entry.New = contents(set)
+			rr.ChangeType = pdnsapi.ChangeTypePtr(pdnsapi.ChangeTypeReplace)
+			rr.TTL = pdnsapi.Uint32(set.TTL)
+			rr.Records = set.Records
+			rr.Comments = set.Comments
+		}
+
+		patch = append(patch, rr)
+		diff.Records = append(diff.Records, entry)
+	}
+
+	return patch, diff
+}
+
+// sameRRset reports whether a and b have the same TTL and records.
+func sameRRset(rtype string, a, b *rrset) bool {
+	if a.TTL != b.TTL || len(a.Records) != len(b.Records) {
+		return false
+	}
+
+	for _, rec := range b.Records {
+		if !a.contains(rtype, pdnsapi.StringValue(rec.Content)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// contents returns the record contents of set.
+func contents(set *rrset) []string {
+	out := make([]string, len(set.Records))
+	for i, rec := range set.Records {
+		out[i] = pdnsapi.StringValue(rec.Content)
+	}
+
+	return out
+}
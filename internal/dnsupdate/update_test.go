@@ -0,0 +1,184 @@
+package dnsupdate
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const zoneName = "example.com."
+
+// testState returns the RRsets of example.com. with an apex SOA and NS, an A
+// record at host and a CNAME at www.
+func testState() zoneState {
+	set := func(ttl uint32, contents ...string) *rrset {
+		r := &rrset{TTL: ttl}
+		for _, content := range contents {
+			r.Records = append(r.Records, pdnsapi.Record{Content: pdnsapi.String(content)})
+		}
+
+		return r
+	}
+
+	return zoneState{
+		{zoneName, "SOA"}:              set(3600, "ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"),
+		{zoneName, "NS"}:               set(3600, "ns1.example.com."),
+		{"host.example.com.", "A"}:     set(300, "192.0.2.1"),
+		{"host.example.com.", "TXT"}:   set(300, `"owner"`),
+		{"www.example.com.", "CNAME"}:  set(300, "host.example.com."),
+		{"mail.example.com.", "MX"}:    set(300, "10 host.example.com."),
+		{"host6.example.com.", "AAAA"}: set(300, "2001:db8::1"),
+	}
+}
+
+func TestCheckPrerequisites(t *testing.T) {
+	tests := []struct {
+		name    string
+		prereqs []record
+		want    dnsmessage.RCode
+	}{
+		{"name in use", []record{{Name: "host.example.com.", Type: dnsmessage.TypeALL, Class: classANY}},
+			dnsmessage.RCodeSuccess},
+		{"name not in use", []record{{Name: "new.example.com.", Type: dnsmessage.TypeALL, Class: classANY}},
+			dnsmessage.RCodeNameError},
+		{"name must not exist", []record{{Name: "host.example.com.", Type: dnsmessage.TypeALL, Class: classNONE}},
+			rcodeYXDomain},
+		{"rrset exists", []record{{Name: "host.example.com.", Type: dnsmessage.TypeA, Class: classANY}},
+			dnsmessage.RCodeSuccess},
+		{"rrset missing", []record{{Name: "host.example.com.", Type: dnsmessage.TypeAAAA, Class: classANY}},
+			rcodeNXRRSet},
+		{"rrset must not exist", []record{{Name: "host.example.com.", Type: dnsmessage.TypeA, Class: classNONE}},
+			rcodeYXRRSet},
+		{"rrset value matches", []record{{
+			Name: "host.example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, Content: "192.0.2.1",
+		}}, dnsmessage.RCodeSuccess},
+		{"rrset value differs", []record{{
+			Name: "host.example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, Content: "192.0.2.9",
+		}}, rcodeNXRRSet},
+		{"outside the zone", []record{{Name: "host.example.org.", Type: dnsmessage.TypeALL, Class: classANY}},
+			rcodeNotZone},
+		{"ttl set", []record{{Name: "host.example.com.", Type: dnsmessage.TypeALL, Class: classANY, TTL: 1}},
+			dnsmessage.RCodeFormatError},
+	}
+
+	for _, tt := range tests {
+		if got := checkPrerequisites(testState(), zoneName, tt.prereqs); got != tt.want {
+			t.Errorf("%s: checkPrerequisites() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrescan(t *testing.T) {
+	allowed := []string{"A", "AAAA", "PTR", "TXT"}
+
+	tests := []struct {
+		name   string
+		update record
+		want   dnsmessage.RCode
+	}{
+		{"add allowed", record{Name: "a.example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET,
+			TTL: 300, Content: "192.0.2.2"}, dnsmessage.RCodeSuccess},
+		{"delete name", record{Name: "a.example.com.", Type: dnsmessage.TypeALL, Class: classANY},
+			dnsmessage.RCodeSuccess},
+		{"type not allowed", record{Name: "a.example.com.", Type: dnsmessage.TypeMX, Class: dnsmessage.ClassINET,
+			TTL: 300, Content: "10 a.example.com."}, dnsmessage.RCodeRefused},
+		{"soa", record{Name: zoneName, Type: dnsmessage.TypeSOA, Class: classANY}, dnsmessage.RCodeRefused},
+		{"outside the zone", record{Name: "a.example.org.", Type: dnsmessage.TypeA, Class: classANY},
+			rcodeNotZone},
+		{"add without data", record{Name: "a.example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+			dnsmessage.RCodeFormatError},
+		{"delete rrset with ttl", record{Name: "a.example.com.", Type: dnsmessage.TypeA, Class: classANY, TTL: 5},
+			dnsmessage.RCodeFormatError},
+		{"meta type", record{Name: "a.example.com.", Type: dnsmessage.TypeAXFR, Class: classNONE},
+			dnsmessage.RCodeFormatError},
+	}
+
+	for _, tt := range tests {
+		if got := prescan(zoneName, []record{tt.update}, allowed); got != tt.want {
+			t.Errorf("%s: prescan() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyUpdates(t *testing.T) {
+	allowed := []string{"A", "AAAA", "CNAME", "NS", "TXT"}
+
+	tests := []struct {
+		name    string
+		updates []record
+		// want are the changed RRsets as "name type action contents".
+		want []string
+	}{
+		{
+			name: "add to rrset",
+			updates: []record{{Name: "host.example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET,
+				TTL: 600, Content: "192.0.2.2"}},
+			want: []string{"host.example.com. A modified [192.0.2.1 192.0.2.2]"},
+		},
+		{
+			name: "add existing record",
+			updates: []record{{Name: "host.example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET,
+				TTL: 300, Content: "192.0.2.1"}},
+		},
+		{
+			name: "new name",
+			updates: []record{{Name: "new.example.com.", Type: dnsmessage.TypeAAAA, Class: dnsmessage.ClassINET,
+				TTL: 300, Content: "2001:db8::2"}},
+			want: []string{"new.example.com. AAAA added [2001:db8::2]"},
+		},
+		{
+			name: "delete name keeps other types",
+			updates: []record{{Name: "host.example.com.", Type: dnsmessage.TypeALL, Class: classANY},
+				{Name: "mail.example.com.", Type: dnsmessage.TypeALL, Class: classANY}},
+			want: []string{"host.example.com. A deleted []", "host.example.com. TXT deleted []"},
+		},
+		{
+			name: "delete record",
+			updates: []record{{Name: "host6.example.com.", Type: dnsmessage.TypeAAAA, Class: classNONE,
+				Content: "2001:DB8::1"}},
+			want: []string{"host6.example.com. AAAA deleted []"},
+		},
+		{
+			name: "last apex ns is kept",
+			updates: []record{{Name: zoneName, Type: dnsmessage.TypeNS, Class: classNONE,
+				Content: "ns1.example.com."}},
+		},
+		{
+			name:    "apex ns rrset is kept",
+			updates: []record{{Name: zoneName, Type: dnsmessage.TypeNS, Class: classANY}},
+		},
+		{
+			name: "no data next to a cname",
+			updates: []record{{Name: "www.example.com.", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET,
+				TTL: 300, Content: "192.0.2.3"}},
+		},
+		{
+			name: "cname replaces cname",
+			updates: []record{{Name: "www.example.com.", Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET,
+				TTL: 300, Content: "host6.example.com."}},
+			want: []string{"www.example.com. CNAME modified [host6.example.com.]"},
+		},
+	}
+
+	for _, tt := range tests {
+		before, after := testState(), testState()
+		applyUpdates(after, zoneName, tt.updates, allowed)
+
+		patch, diff := changedRRsets(before, after)
+		if len(patch) != len(diff.Records) {
+			t.Fatalf("%s: %d RRsets patched, %d in the diff", tt.name, len(patch), len(diff.Records))
+		}
+
+		var got []string
+		for _, entry := range diff.Records {
+			got = append(got, entry.Name+" "+entry.Type+" "+entry.Action+" "+fmt.Sprint(entry.New))
+		}
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: changes = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+// Package dnsverify resolves the records of a zone through recursive resolvers
+// and compares the answers with the records configured in PowerDNS. Answers
+// that differ point to changes that have not propagated yet, to a delegation
+// that sends resolvers to other name servers, or to a hijacked zone.
+package dnsverify
+
+import (
+	"cmp"
+	"context"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+)
+
+// Status is the outcome of comparing the answer of a resolver with the
+// configured records.
+type Status string
+
+const (
+	// StatusMatch means the resolver returned the configured records.
+	StatusMatch Status = "match"
+
+	// StatusDiffers means the resolver returned other records.
+	StatusDiffers Status = "differs"
+
+	// StatusMissing means the name or type does not exist for the resolver.
+	StatusMissing Status = "missing"
+
+	// StatusError means the query failed, e.g. timed out or got SERVFAIL.
+	StatusError Status = "error"
+)
+
+const (
+	// workers is the number of queries sent at a time.
+	workers = 8
+
+	// MaxRRsets is the number of RRsets checked in one run; the others are
+	// reported as skipped.
+	MaxRRsets = 300
+)
+
+// types are the record types that are checked, with their query type. Types
+// PowerDNS computes itself, such as LUA and ALIAS, are left out.
+var types = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"TXT":   dnsmessage.TypeTXT,
+	"SRV":   dnsmessage.TypeSRV,
+	"PTR":   dnsmessage.TypePTR,
+	"CAA":   dnsclient.TypeCAA,
+	"SOA":   dnsmessage.TypeSOA,
+}
+
+// Answer is what one resolver returned for an RRset.
+type Answer struct {
+	Resolver string
+	Status   Status
+	// Records are the records returned, in presentation format.
+	Records []string
+	// TTL is the smallest remaining TTL of the records, i.e. how long the
+	// resolver keeps serving them.
+	TTL     uint32
+	RCode   string
+	Latency time.Duration
+	// Error is why the query failed.
+	Error string
+}
+
+// Result is the check of one RRset.
+type Result struct {
+	Name string
+	Type string
+	// Expected are the enabled records in PowerDNS.
+	Expected []string
+	// Answers holds one answer per resolver, in resolver order.
+	Answers []Answer
+}
+
+// OK reports whether every resolver returned the configured records.
+func (r *Result) OK() bool {
+	for i := range r.Answers {
+		if r.Answers[i].Status != StatusMatch {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Report is the result of checking a zone.
+type Report struct {
+	Results []Result
+	// Skipped counts the RRsets over MaxRRsets that were not checked.
+	Skipped int
+}
+
+// exchangeFunc sends a query; it is dnsclient.Exchange outside of tests.
+type exchangeFunc func(ctx context.Context, server string, q dnsclient.Query) (*dnsclient.Response, error)
+
+// Verifier checks zones against a set of resolvers.
+type Verifier struct {
+	// Resolvers are host:port addresses.
+	Resolvers []string
+	// Timeout bounds each query.
+	Timeout time.Duration
+
+	exchange exchangeFunc
+}
+
+// New returns a Verifier querying resolvers, each query limited to timeout.
+func New(resolvers []string, timeout time.Duration) *Verifier {
+	return &Verifier{Resolvers: resolvers, Timeout: timeout, exchange: dnsclient.Exchange}
+}
+
+// Verify queries every resolver for the checkable RRsets of z and compares
+// the answers. Results are sorted by name and type.
+func (v *Verifier) Verify(ctx context.Context, z *pdnsapi.Zone) Report {
+	results := rrSets(z)
+
+	var report Report
+
+	if len(results) > MaxRRsets {
+		report.Skipped = len(results) - MaxRRsets
+		results = results[:MaxRRsets]
+	}
+
+	for i := range results {
+		results[i].Answers = make([]Answer, len(v.Resolvers))
+	}
+
+	type job struct{ result, resolver int }
+
+	next := make(chan job)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Go(func() {
+			for j := range next {
+				r := &results[j.result]
+				r.Answers[j.resolver] = v.query(ctx, v.Resolvers[j.resolver], r)
+			}
+		})
+	}
+
+	for i := range results {
+		for k := range v.Resolvers {
+			next <- job{i, k}
+		}
+	}
+
+	close(next)
+	wg.Wait()
+
+	report.Results = results
+
+	return report
+}
+
+// query asks resolver for the RRset of r and compares the answer.
+func (v *Verifier) query(ctx context.Context, resolver string, r *Result) Answer {
+	answer := Answer{Resolver: resolver}
+
+	ctx, cancel := context.WithTimeout(ctx, v.Timeout)
+	defer cancel()
+
+	qtype := types[r.Type]
+
+	resp, err := v.exchange(ctx, resolver, dnsclient.Query{Name: r.Name, Type: qtype, Recursion: true})
+	if err != nil {
+		answer.Status = StatusError
+		answer.Error = err.Error()
+
+		return answer
+	}
+
+	answer.Latency = resp.RTT
+	answer.RCode = dnsclient.RCodeName(resp.Message.RCode)
+
+	switch resp.Message.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		answer.Status = StatusMissing
+		return answer
+	default:
+		answer.Status = StatusError
+		return answer
+	}
+
+	var aliases []string
+
+	for i := range resp.Message.Answers {
+		rr := &resp.Message.Answers[i]
+		if !strings.EqualFold(rr.Header.Name.String(), r.Name) {
+			continue
+		}
+
+		switch rr.Header.Type {
+		case qtype:
+			answer.Records = append(answer.Records, dnsclient.Data(rr))
+
+			if len(answer.Records) == 1 || rr.Header.TTL < answer.TTL {
+				answer.TTL = rr.Header.TTL
+			}
+		case dnsmessage.TypeCNAME:
+			aliases = append(aliases, "CNAME "+dnsclient.Data(rr))
+		}
+	}
+
+	switch {
+	case len(answer.Records) == 0 && len(aliases) > 0:
+		// The name is an alias for the resolver but not in PowerDNS.
+		answer.Records = aliases
+		answer.Status = StatusDiffers
+	case len(answer.Records) == 0:
+		answer.Status = StatusMissing
+	case sameRecords(r.Type, r.Expected, answer.Records):
+		answer.Status = StatusMatch
+	default:
+		answer.Status = StatusDiffers
+	}
+
+	return answer
+}
+
+// rrSets returns a Result without answers for each checkable RRset of z, by
+// name and type. Wildcards cannot be queried and names below a delegation
+// are answered by other servers, so both are left out; the NS RRset of the
+// delegation itself is checked.
+func rrSets(z *pdnsapi.Zone) []Result {
+	if z == nil {
+		return nil
+	}
+
+	apex := strings.ToLower(pdnsapi.StringValue(z.Name))
+
+	var (
+		results []Result
+		cuts    []string
+	)
+
+	for i := range z.RRsets {
+		rrSet := &z.RRsets[i]
+		if rrSet.Name == nil || rrSet.Type == nil {
+			continue
+		}
+
+		name := strings.ToLower(*rrSet.Name)
+		rrType := string(*rrSet.Type)
+
+		if _, ok := types[rrType]; !ok || strings.HasPrefix(name, "*.") {
+			continue
+		}
+
+		var expected []string
+
+		for _, rec := range rrSet.Records {
+			if !pdnsapi.BoolValue(rec.Disabled) {
+				expected = append(expected, pdnsapi.StringValue(rec.Content))
+			}
+		}
+
+		if len(expected) == 0 {
+			continue
+		}
+
+		if rrType == "NS" && name != apex {
+			cuts = append(cuts, name)
+		}
+
+		results = append(results, Result{Name: name, Type: rrType, Expected: expected})
+	}
+
+	results = slices.DeleteFunc(results, func(r Result) bool {
+		return slices.ContainsFunc(cuts, func(cut string) bool {
+			return strings.HasSuffix(r.Name, "."+cut) || (r.Name == cut && r.Type != "NS")
+		})
+	})
+
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.Type, b.Type))
+	})
+
+	return results
+}
+
+// sameRecords reports whether the configured and the returned records of a
+// type are the same set.
+func sameRecords(rrType string, expected, got []string) bool {
+	a := normalizeAll(rrType, expected)
+	b := normalizeAll(rrType, got)
+
+	return slices.Equal(a, b)
+}
+
+// normalizeAll returns the sorted, de-duplicated normal forms of records.
+func normalizeAll(rrType string, records []string) []string {
+	out := make([]string, 0, len(records))
+	for _, content := range records {
+		out = append(out, normalize(rrType, content))
+	}
+
+	slices.Sort(out)
+
+	return slices.Compact(out)
+}
+
+// normalize returns content in a form that compares equal for the same
+// record, whether it was written in PowerDNS or returned by a resolver:
+// addresses in canonical form, names lower-cased and fully qualified and TXT
+// strings joined. SOA records compare by serial only, since resolvers may
+// return the SOA with another MNAME or RNAME formatting.
+func normalize(rrType, content string) string {
+	content = strings.TrimSpace(content)
+
+	switch rrType {
+	case "A", "AAAA":
+		if addr, err := netip.ParseAddr(content); err == nil {
+			return addr.String()
+		}
+	case "TXT":
+		return dnsclient.Unquote(content)
+	case "CAA":
+		fields := strings.SplitN(content, " ", 3)
+		if len(fields) == 3 {
+			return fields[0] + " " + strings.ToLower(fields[1]) + " " + dnsclient.Unquote(fields[2])
+		}
+	case "SOA":
+		if fields := strings.Fields(content); len(fields) >= 3 {
+			if serial, err := strconv.ParseUint(fields[2], 10, 32); err == nil {
+				return strconv.FormatUint(serial, 10)
+			}
+		}
+	default:
+		fields := strings.Fields(strings.ToLower(content))
+		if n := len(fields); n > 0 && !strings.HasSuffix(fields[n-1], ".") {
+			fields[n-1] += "."
+		}
+
+		return strings.Join(fields, " ")
+	}
+
+	return content
+}
@@ -0,0 +1,141 @@
+package dnsverify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+)
+
+func rrSet(name string, rrType pdnsapi.RRType, contents ...string) pdnsapi.RRset {
+	records := make([]pdnsapi.Record, 0, len(contents))
+	for _, content := range contents {
+		records = append(records, pdnsapi.Record{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)})
+	}
+
+	return pdnsapi.RRset{Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(rrType), Records: records}
+}
+
+func TestRRSets(t *testing.T) {
+	disabled := rrSet("off.example.com.", pdnsapi.RRTypeA, "192.0.2.9")
+	disabled.Records[0].Disabled = pdnsapi.Bool(true)
+
+	z := &pdnsapi.Zone{Name: pdnsapi.String("example.com."), RRsets: []pdnsapi.RRset{
+		rrSet("www.example.com.", pdnsapi.RRTypeA, "192.0.2.1"),
+		rrSet("example.com.", pdnsapi.RRTypeNS, "ns1.example.com."),
+		rrSet("*.example.com.", pdnsapi.RRTypeA, "192.0.2.2"),
+		rrSet("lua.example.com.", pdnsapi.RRTypeLUA, `A "ifportup(443, {'192.0.2.3'})"`),
+		rrSet("sub.example.com.", pdnsapi.RRTypeNS, "ns.sub.example.com."),
+		rrSet("ns.sub.example.com.", pdnsapi.RRTypeA, "192.0.2.53"),
+		disabled,
+	}}
+
+	var got []string
+	for _, r := range rrSets(z) {
+		got = append(got, r.Name+" "+r.Type)
+	}
+
+	want := []string{"example.com. NS", "sub.example.com. NS", "www.example.com. A"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rrSets() = %v, want %v", got, want)
+	}
+}
+
+// fakeExchange answers from records by resolver, name and type. A missing
+// entry is NXDOMAIN; the resolver "down" fails every query.
+func fakeExchange(records map[string][]dnsmessage.Resource) exchangeFunc {
+	return func(_ context.Context, server string, q dnsclient.Query) (*dnsclient.Response, error) {
+		if server == "down" {
+			return nil, errors.New("i/o timeout")
+		}
+
+		resp := &dnsclient.Response{Server: server, RTT: 12 * time.Millisecond}
+		resp.Message.Response = true
+
+		answers, ok := records[server+" "+q.Name+" "+dnsclient.TypeName(q.Type)]
+		if !ok {
+			resp.Message.RCode = dnsmessage.RCodeNameError
+		}
+
+		resp.Message.Answers = answers
+
+		return resp, nil
+	}
+}
+
+func a(name string, ttl uint32, ip byte) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, ip}},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	z := &pdnsapi.Zone{Name: pdnsapi.String("example.com."), RRsets: []pdnsapi.RRset{
+		rrSet("www.example.com.", pdnsapi.RRTypeA, "192.0.2.1", "192.0.2.2"),
+		rrSet("example.com.", pdnsapi.RRTypeTXT, `"v=spf1 " "-all"`),
+	}}
+
+	txt := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example.com."), Type: dnsmessage.TypeTXT, TTL: 300},
+		Body:   &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}},
+	}
+
+	v := New([]string{"good", "stale", "down"}, time.Second)
+	v.exchange = fakeExchange(map[string][]dnsmessage.Resource{
+		"good www.example.com. A":  {a("www.example.com.", 300, 2), a("www.example.com.", 200, 1)},
+		"good example.com. TXT":    {txt},
+		"stale www.example.com. A": {a("WWW.example.com.", 3000, 1)},
+		"stale example.com. TXT":   {},
+	})
+
+	report := v.Verify(context.Background(), z)
+	if len(report.Results) != 2 || report.Skipped != 0 {
+		t.Fatalf("Verify() = %+v", report)
+	}
+
+	var got []string
+	for _, r := range report.Results {
+		for _, answer := range r.Answers {
+			got = append(got, r.Name+" "+answer.Resolver+" "+string(answer.Status))
+		}
+	}
+
+	want := []string{
+		"example.com. good match", "example.com. stale missing", "example.com. down error",
+		"www.example.com. good match", "www.example.com. stale differs", "www.example.com. down error",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+
+	if www := report.Results[1]; www.Answers[0].TTL != 200 || www.Answers[1].TTL != 3000 || www.OK() {
+		t.Errorf("www answers = %+v", www.Answers)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		rrType, a, b string
+	}{
+		{"AAAA", "2001:DB8:0::1", "2001:db8::1"},
+		{"CNAME", "Target.Example.com", "target.example.com."},
+		{"MX", "10 Mail.example.com.", "10 mail.example.com."},
+		{"TXT", `"a" "b"`, `"ab"`},
+		{"CAA", `0 ISSUE "letsencrypt.org"`, `0 issue "letsencrypt.org"`},
+		{"SOA", "ns1.example.com. hostmaster.example.com. 2026101701 10800 3600 604800 3600",
+			"a.example.net. dns.example.net. 2026101701 1 1 1 1"},
+	}
+
+	for _, tc := range tests {
+		if na, nb := normalize(tc.rrType, tc.a), normalize(tc.rrType, tc.b); na != nb {
+			t.Errorf("normalize(%s, %q) = %q, normalize(%q) = %q", tc.rrType, tc.a, na, tc.b, nb)
+		}
+	}
+}
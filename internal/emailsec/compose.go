@@ -0,0 +1,255 @@
+package emailsec
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SPFForm describes the servers an SPF record permits.
+type SPFForm struct {
+	// MX and A permit the mail exchangers and the addresses of the apex.
+	MX bool `json:"mx"`
+	A  bool `json:"a"`
+	// IP4 and IP6 are addresses or prefixes of other servers.
+	IP4 []string `json:"ip4"`
+	IP6 []string `json:"ip6"`
+	// Includes are the domains of mail providers whose SPF records apply.
+	Includes []string `json:"includes"`
+	// All is the qualifier of the final all mechanism: "-" to fail other
+	// servers, "~" to soft-fail them or "?" for a neutral result.
+	All string `json:"all"`
+}
+
+// DMARCForm describes a DMARC policy.
+type DMARCForm struct {
+	// Policy and SubdomainPolicy are none, quarantine or reject; an empty
+	// SubdomainPolicy applies Policy to subdomains.
+	Policy          string `json:"policy"`
+	SubdomainPolicy string `json:"subdomain_policy"`
+	// Percent is the share of failing mail the policy applies to.
+	Percent int `json:"percent"`
+	// RUA and RUF are the addresses of aggregate and failure reports.
+	RUA []string `json:"rua"`
+	RUF []string `json:"ruf"`
+	// ADKIM and ASPF are the alignment modes, r (relaxed) or s (strict).
+	ADKIM string `json:"adkim"`
+	ASPF  string `json:"aspf"`
+}
+
+// DKIMForm describes the public DKIM key of a selector.
+type DKIMForm struct {
+	Selector string `json:"selector"`
+	KeyType  string `json:"key_type"`
+	// PublicKey is the key as base64, or as a PEM block.
+	PublicKey string `json:"public_key"`
+	// Testing sets t=y, which asks receivers not to act on failures.
+	Testing bool `json:"testing"`
+}
+
+var (
+	errSPFQualifier = errors.New("choose how to treat mail from other servers")
+	errDMARCPolicy  = errors.New("the policy must be none, quarantine or reject")
+	errDMARCPercent = errors.New("the percentage must be from 0 to 100")
+	errDMARCAlign   = errors.New("the alignment must be r or s")
+	errSelector     = errors.New("the selector must consist of letters, digits, hyphens and underscores, " +
+		"with dots between labels")
+	errNoPublicKey = errors.New("enter the public key")
+)
+
+// ComposeSPF returns the text of the SPF record described by f.
+func ComposeSPF(f *SPFForm) (string, error) {
+	if !strings.Contains("-~?", f.All) || len(f.All) != 1 {
+		return "", errSPFQualifier
+	}
+
+	terms := []string{"v=spf1"}
+
+	if f.MX {
+		terms = append(terms, "mx")
+	}
+
+	if f.A {
+		terms = append(terms, "a")
+	}
+
+	for _, list := range []struct {
+		mechanism string
+		values    []string
+	}{{"ip4", f.IP4}, {"ip6", f.IP6}, {"include", f.Includes}} {
+		for _, value := range list.values {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+
+			term := list.mechanism + ":" + value
+			if _, err := parseSPFTerm(term); err != nil {
+				return "", err
+			}
+
+			terms = append(terms, term)
+		}
+	}
+
+	terms = append(terms, f.All+"all")
+
+	return strings.Join(terms, " "), nil
+}
+
+// ComposeDMARC returns the text of the DMARC record described by f. Tags
+// with their default value are left out.
+func ComposeDMARC(f *DMARCForm) (string, error) {
+	policy, subdomainPolicy := strings.ToLower(f.Policy), strings.ToLower(f.SubdomainPolicy)
+	if !dmarcPolicies[policy] || (subdomainPolicy != "" && !dmarcPolicies[subdomainPolicy]) {
+		return "", errDMARCPolicy
+	}
+
+	if f.Percent < 0 || f.Percent > 100 {
+		return "", errDMARCPercent
+	}
+
+	tags := []string{"v=DMARC1", "p=" + policy}
+
+	if subdomainPolicy != "" && subdomainPolicy != policy {
+		tags = append(tags, "sp="+subdomainPolicy)
+	}
+
+	if f.Percent != 100 {
+		tags = append(tags, "pct="+strconv.Itoa(f.Percent))
+	}
+
+	for _, list := range []struct {
+		tag       string
+		addresses []string
+	}{{"rua", f.RUA}, {"ruf", f.RUF}} {
+		uris, err := mailtoList(list.addresses)
+		if err != nil {
+			return "", err
+		}
+
+		if uris != "" {
+			tags = append(tags, list.tag+"="+uris)
+		}
+	}
+
+	for _, align := range []struct{ tag, mode string }{{"adkim", f.ADKIM}, {"aspf", f.ASPF}} {
+		switch mode := strings.ToLower(align.mode); mode {
+		case "", "r":
+		case "s":
+			tags = append(tags, align.tag+"=s")
+		default:
+			return "", errDMARCAlign
+		}
+	}
+
+	return strings.Join(tags, "; "), nil
+}
+
+// mailtoList joins addresses as mailto: URIs for a rua or ruf tag.
+func mailtoList(addresses []string) (string, error) {
+	uris := make([]string, 0, len(addresses))
+
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if len(address) >= len("mailto:") && strings.EqualFold(address[:len("mailto:")], "mailto:") {
+			address = address[len("mailto:"):]
+		}
+
+		if address == "" {
+			continue
+		}
+
+		if local, domain, ok := strings.Cut(address, "@"); !ok || local == "" || domain == "" ||
+			strings.ContainsAny(address, " ,;!") {
+			return "", fmt.Errorf("invalid report address %q", address)
+		}
+
+		uris = append(uris, "mailto:"+address)
+	}
+
+	return strings.Join(uris, ","), nil
+}
+
+// ComposeDKIM returns the text of the DKIM record described by f.
+func ComposeDKIM(f *DKIMForm) (string, error) {
+	if !ValidSelector(f.Selector) {
+		return "", errSelector
+	}
+
+	keyType := strings.ToLower(strings.TrimSpace(f.KeyType))
+	if keyType == "" {
+		keyType = KeyTypeRSA
+	}
+
+	key := strings.TrimSpace(f.PublicKey)
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		key = base64.StdEncoding.EncodeToString(block.Bytes)
+	}
+
+	key = strings.Join(strings.Fields(key), "")
+	if key == "" {
+		return "", errNoPublicKey
+	}
+
+	if keyType == KeyTypeEd25519 {
+		key = rawEd25519Key(key)
+	}
+
+	if _, err := parseKey(keyType, key); err != nil {
+		return "", err
+	}
+
+	tags := []string{"v=DKIM1", "k=" + keyType}
+	if f.Testing {
+		tags = append(tags, "t=y")
+	}
+
+	tags = append(tags, "p="+key)
+
+	return strings.Join(tags, "; "), nil
+}
+
+// rawEd25519Key returns the bare 32-byte key DKIM publishes for an Ed25519
+// key given as a SubjectPublicKeyInfo, as OpenSSL writes it, and key itself
+// otherwise.
+func rawEd25519Key(key string) string {
+	der, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return key
+	}
+
+	if pub, err := x509.ParsePKIXPublicKey(der); err == nil {
+		if edKey, ok := pub.(ed25519.PublicKey); ok {
+			return base64.StdEncoding.EncodeToString(edKey)
+		}
+	}
+
+	return key
+}
+
+// ValidSelector reports whether selector is a valid DKIM selector: one or
+// more labels of letters, digits, hyphens and underscores.
+func ValidSelector(selector string) bool {
+	if selector == "" {
+		return false
+	}
+
+	for _, label := range strings.Split(selector, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
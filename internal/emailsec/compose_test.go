@@ -0,0 +1,94 @@
+package emailsec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func TestComposeSPF(t *testing.T) {
+	got, err := ComposeSPF(&SPFForm{MX: true, IP4: []string{"192.0.2.1", " "}, IP6: []string{"2001:db8::/32"},
+		Includes: []string{"_spf.google.com"}, All: "~"})
+	if want := "v=spf1 mx ip4:192.0.2.1 ip6:2001:db8::/32 include:_spf.google.com ~all"; err != nil || got != want {
+		t.Errorf("ComposeSPF() = %q, %v, want %q", got, err, want)
+	}
+
+	for _, f := range []SPFForm{
+		{All: "+"},
+		{All: ""},
+		{IP4: []string{"2001:db8::1"}, All: "-"},
+	} {
+		if got, err := ComposeSPF(&f); err == nil {
+			t.Errorf("ComposeSPF(%+v) = %q, want an error", f, got)
+		}
+	}
+}
+
+func TestComposeDMARC(t *testing.T) {
+	got, err := ComposeDMARC(&DMARCForm{Policy: "quarantine", SubdomainPolicy: "reject", Percent: 100,
+		RUA: []string{"dmarc@example.com", "mailto:reports@example.net"}, ADKIM: "s", ASPF: "r"})
+	if want := "v=DMARC1; p=quarantine; sp=reject; rua=mailto:dmarc@example.com,mailto:reports@example.net; adkim=s"; err != nil ||
+		got != want {
+		t.Errorf("ComposeDMARC() = %q, %v, want %q", got, err, want)
+	}
+
+	got, err = ComposeDMARC(&DMARCForm{Policy: "none", SubdomainPolicy: "none", Percent: 25})
+	if want := "v=DMARC1; p=none; pct=25"; err != nil || got != want {
+		t.Errorf("ComposeDMARC() = %q, %v, want %q", got, err, want)
+	}
+
+	for _, f := range []DMARCForm{
+		{Policy: "block", Percent: 100},
+		{Policy: "reject", Percent: 101},
+		{Policy: "reject", Percent: 100, RUA: []string{"dmarc"}},
+		{Policy: "reject", Percent: 100, ASPF: "x"},
+	} {
+		if got, err := ComposeDMARC(&f); err == nil {
+			t.Errorf("ComposeDMARC(%+v) = %q, want an error", f, got)
+		}
+	}
+}
+
+func TestComposeDKIM(t *testing.T) {
+	key := rsaKey(t, 2048)
+	pemKey := "-----BEGIN PUBLIC KEY-----\n" + key[:64] + "\n" + key[64:] + "\n-----END PUBLIC KEY-----\n"
+
+	for _, publicKey := range []string{key, key[:100] + "\n " + key[100:], pemKey} {
+		got, err := ComposeDKIM(&DKIMForm{Selector: "mail2026", PublicKey: publicKey, Testing: true})
+		if want := "v=DKIM1; k=rsa; t=y; p=" + key; err != nil || got != want {
+			t.Errorf("ComposeDKIM() = %q, %v, want %q", got, err, want)
+		}
+	}
+
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(edKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	edPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	got, err := ComposeDKIM(&DKIMForm{Selector: "ed", KeyType: "ed25519", PublicKey: edPEM})
+	if want := "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(edKey); err != nil || got != want {
+		t.Errorf("ComposeDKIM(ed25519) = %q, %v, want %q", got, err, want)
+	}
+
+	for _, f := range []DKIMForm{
+		{Selector: "bad selector", PublicKey: key},
+		{Selector: "s1"},
+		{Selector: "s1", PublicKey: "bm90IGEga2V5"},
+		{Selector: "s1", KeyType: "dsa", PublicKey: key},
+	} {
+		if got, err := ComposeDKIM(&f); err == nil || strings.HasPrefix(got, "v=") {
+			t.Errorf("ComposeDKIM(%+v) = %q, want an error", f, got)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package emailsec
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// KeyTypeRSA is the default DKIM key type.
+	KeyTypeRSA = "rsa"
+
+	// KeyTypeEd25519 is the DKIM key type of RFC 8463.
+	KeyTypeEd25519 = "ed25519"
+)
+
+const (
+	// minRSABits is the smallest RSA key receivers accept, see RFC 8301.
+	minRSABits = 1024
+
+	// goodRSABits is the RSA key size RFC 8301 recommends.
+	goodRSABits = 2048
+
+	// ed25519KeySize is the length of an Ed25519 public key in bytes.
+	ed25519KeySize = 32
+)
+
+var (
+	errKeyEncoding = errors.New("the public key is not valid base64")
+	errKeyRSA      = errors.New("the public key is not an RSA key")
+	errKeyEd25519  = errors.New("the public key is not a 32-byte Ed25519 key")
+	errKeyType     = errors.New("the key type must be rsa or ed25519")
+)
+
+// parseKey decodes the base64 public key of a DKIM record of keyType and
+// returns its size in bits.
+func parseKey(keyType, key string) (int, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(key), ""))
+	if err != nil {
+		return 0, errKeyEncoding
+	}
+
+	switch keyType {
+	case KeyTypeRSA:
+		if pub, err := x509.ParsePKIXPublicKey(der); err == nil {
+			if rsaKey, ok := pub.(*rsa.PublicKey); ok {
+				return rsaKey.N.BitLen(), nil
+			}
+
+			return 0, errKeyRSA
+		}
+
+		// Some generators publish the bare PKCS #1 key.
+		rsaKey, err := x509.ParsePKCS1PublicKey(der)
+		if err != nil {
+			return 0, errKeyRSA
+		}
+
+		return rsaKey.N.BitLen(), nil
+	case KeyTypeEd25519:
+		if len(der) != ed25519KeySize {
+			return 0, errKeyEd25519
+		}
+
+		return ed25519KeySize * 8, nil
+	default:
+		return 0, errKeyType
+	}
+}
+
+// checkDKIM evaluates the DKIM keys of the selectors below _domainkey of the
+// zone apex, sorted by selector.
+func (c *zone) checkDKIM() []DKIM {
+	suffix := "._domainkey." + c.name
+
+	names := map[string]bool{}
+
+	for name := range c.txt {
+		names[name] = true
+	}
+
+	for name := range c.cname {
+		names[name] = true
+	}
+
+	keys := []DKIM{}
+
+	for name := range names {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+
+		dkim := DKIM{Selector: strings.TrimSuffix(name, suffix), Name: name, Issues: []Issue{}}
+
+		if target, ok := c.cname[name]; ok {
+			dkim.Target = target
+		} else {
+			c.checkKey(&dkim)
+		}
+
+		keys = append(keys, dkim)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Selector < keys[j].Selector })
+
+	return keys
+}
+
+// checkKey evaluates the TXT record of the DKIM selector of dkim.
+func (c *zone) checkKey(dkim *DKIM) {
+	records := c.txt[dkim.Name]
+	if len(records) > 1 {
+		fail(&dkim.Issues, fmt.Sprintf("the selector has %d TXT records; receivers may use either", len(records)))
+		return
+	}
+
+	dkim.Record = &records[0]
+
+	tags, duplicates := parseTags(records[0].Text)
+
+	for _, tag := range duplicates {
+		fail(&dkim.Issues, "the "+tag+" tag is set more than once")
+	}
+
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		fail(&dkim.Issues, fmt.Sprintf("invalid version %q; use DKIM1", v))
+	}
+
+	dkim.KeyType = KeyTypeRSA
+	if k, ok := tags["k"]; ok {
+		dkim.KeyType = strings.ToLower(k)
+	}
+
+	if flags, ok := tags["t"]; ok && strings.Contains(flags, "y") {
+		warn(&dkim.Issues, "the key is in test mode (t=y), so receivers treat signed mail like unsigned mail")
+	}
+
+	key, ok := tags["p"]
+
+	switch {
+	case !ok:
+		fail(&dkim.Issues, "the p tag with the public key is missing")
+		return
+	case key == "":
+		warn(&dkim.Issues, "the key is revoked: its p tag is empty")
+		return
+	}
+
+	bits, err := parseKey(dkim.KeyType, key)
+	if err != nil {
+		fail(&dkim.Issues, err.Error())
+		return
+	}
+
+	dkim.KeyBits = bits
+	if dkim.KeyType != KeyTypeRSA {
+		return
+	}
+
+	switch {
+	case bits < minRSABits:
+		fail(&dkim.Issues, fmt.Sprintf("the %d-bit RSA key is too short; receivers ignore keys below %d bits",
+			bits, minRSABits))
+	case bits < goodRSABits:
+		warn(&dkim.Issues, fmt.Sprintf("the %d-bit RSA key is weak; use at least %d bits", bits, goodRSABits))
+	}
+}
@@ -0,0 +1,102 @@
+package emailsec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dmarcPolicies are the values of the p and sp tags.
+var dmarcPolicies = map[string]bool{"none": true, "quarantine": true, "reject": true}
+
+// checkDMARC evaluates the DMARC record at _dmarc of the zone apex.
+func (c *zone) checkDMARC() DMARC {
+	dmarc := DMARC{Name: "_dmarc." + c.name, Tags: map[string]string{}, Issues: []Issue{}}
+
+	if target, ok := c.cname[dmarc.Name]; ok {
+		dmarc.Target = target
+		return dmarc
+	}
+
+	records := c.tagged(dmarc.Name, "v=DMARC1")
+
+	switch len(records) {
+	case 0:
+		warn(&dmarc.Issues, "the zone has no DMARC record, so receivers decide on their own what to do with mail failing SPF and DKIM")
+		return dmarc
+	case 1:
+	default:
+		fail(&dmarc.Issues, fmt.Sprintf("there are %d DMARC records; receivers ignore them all", len(records)))
+		return dmarc
+	}
+
+	dmarc.Record = &records[0]
+
+	tags, duplicates := parseTags(records[0].Text)
+	dmarc.Tags = tags
+
+	for _, tag := range duplicates {
+		fail(&dmarc.Issues, "the "+tag+" tag is set more than once")
+	}
+
+	checkDMARCTags(tags, &dmarc.Issues)
+
+	return dmarc
+}
+
+// checkDMARCTags reports invalid tags and policies that do not protect the
+// domain yet.
+func checkDMARCTags(tags map[string]string, issues *[]Issue) {
+	switch p, ok := tags["p"]; {
+	case !ok:
+		fail(issues, "the p tag with the policy is missing")
+	case !dmarcPolicies[strings.ToLower(p)]:
+		fail(issues, fmt.Sprintf("invalid policy %q; use none, quarantine or reject", p))
+	case strings.EqualFold(p, "none"):
+		warn(issues, "the policy none only monitors: mail failing DMARC is still delivered")
+	}
+
+	if sp, ok := tags["sp"]; ok && !dmarcPolicies[strings.ToLower(sp)] {
+		fail(issues, fmt.Sprintf("invalid subdomain policy %q; use none, quarantine or reject", sp))
+	}
+
+	if pct, ok := tags["pct"]; ok {
+		switch n, err := strconv.Atoi(pct); {
+		case err != nil || n < 0 || n > 100:
+			fail(issues, fmt.Sprintf("invalid percentage %q; use a number from 0 to 100", pct))
+		case n < 100:
+			warn(issues, fmt.Sprintf("the policy applies to %d%% of the mail failing DMARC only", n))
+		}
+	}
+
+	if rua := tags["rua"]; rua == "" {
+		warn(issues, "no rua address receives aggregate reports, so you cannot tell who sends mail for the zone")
+	}
+
+	for _, tag := range []string{"rua", "ruf"} {
+		for _, uri := range splitList(tags[tag]) {
+			if !strings.HasPrefix(strings.ToLower(uri), "mailto:") {
+				fail(issues, fmt.Sprintf("the %s address %q must start with mailto:", tag, uri))
+			}
+		}
+	}
+
+	for _, tag := range []string{"adkim", "aspf"} {
+		if mode, ok := tags[tag]; ok && !strings.EqualFold(mode, "r") && !strings.EqualFold(mode, "s") {
+			fail(issues, fmt.Sprintf("invalid %s alignment %q; use r or s", tag, mode))
+		}
+	}
+}
+
+// splitList splits a comma-separated tag value, dropping empty entries.
+func splitList(value string) []string {
+	var out []string
+
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,230 @@
+// Package emailsec evaluates the email security records of a zone: the SPF
+// record of the apex with the DNS lookups it needs, the DMARC policy at
+// _dmarc and the DKIM keys below _domainkey. It only looks at the zone's own
+// records and ignores disabled ones, which PowerDNS does not serve; includes
+// of other domains are not resolved.
+package emailsec
+
+import (
+	"slices"
+	"strings"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonecheck"
+)
+
+const (
+	// SeverityError marks records receivers reject or ignore.
+	SeverityError = zonecheck.SeverityError
+
+	// SeverityWarning marks records that work but protect less than they
+	// could.
+	SeverityWarning = zonecheck.SeverityWarning
+)
+
+// Issue is a problem found in an email security record.
+type Issue struct {
+	Severity zonecheck.Severity `json:"severity"`
+	Message  string             `json:"message"`
+}
+
+// Record is a TXT record the report is about.
+type Record struct {
+	// Content is the record as PowerDNS stores it, quoted.
+	Content string `json:"content"`
+	// Text is the concatenated character strings of Content.
+	Text string `json:"text"`
+}
+
+// SPF is the evaluation of the SPF record of the zone apex.
+type SPF struct {
+	Name string `json:"name"`
+	// Record is the SPF record; nil when there is none or more than one.
+	Record *Record `json:"record"`
+	// Lookups counts the DNS lookups of the record and of the includes in the
+	// zone; each include of another domain counts as one.
+	Lookups int `json:"lookups"`
+	// External lists the includes and redirects to other domains, whose own
+	// lookups are not counted.
+	External []string `json:"external"`
+	Issues   []Issue  `json:"issues"`
+}
+
+// DMARC is the evaluation of the DMARC record at _dmarc.
+type DMARC struct {
+	Name   string  `json:"name"`
+	Record *Record `json:"record"`
+	// Target is set instead of Record when _dmarc is a CNAME, which is not
+	// followed.
+	Target string `json:"target,omitempty"`
+	// Tags are the tags of the record by lower-cased name.
+	Tags   map[string]string `json:"tags"`
+	Issues []Issue           `json:"issues"`
+}
+
+// DKIM is the evaluation of a DKIM key below _domainkey.
+type DKIM struct {
+	Selector string  `json:"selector"`
+	Name     string  `json:"name"`
+	Record   *Record `json:"record"`
+	// Target is set instead of Record when the selector is a CNAME, usually
+	// to a key the mail provider rotates.
+	Target  string  `json:"target,omitempty"`
+	KeyType string  `json:"key_type,omitempty"`
+	KeyBits int     `json:"key_bits,omitempty"`
+	Issues  []Issue `json:"issues"`
+}
+
+// Report is the result of checking a zone.
+type Report struct {
+	SPF   SPF    `json:"spf"`
+	DMARC DMARC  `json:"dmarc"`
+	DKIM  []DKIM `json:"dkim"`
+	// Errors and Warnings count the issues of all records.
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+}
+
+// zone holds the enabled TXT, SPF and CNAME records of a zone by lower-cased
+// name.
+type zone struct {
+	name  string
+	txt   map[string][]Record
+	spf   map[string]bool
+	cname map[string]string
+}
+
+// Check evaluates the email security records of z.
+func Check(z *pdnsapi.Zone) Report {
+	if z == nil {
+		return Report{}
+	}
+
+	c := &zone{
+		name:  strings.ToLower(pdnsapi.StringValue(z.Name)),
+		txt:   map[string][]Record{},
+		spf:   map[string]bool{},
+		cname: map[string]string{},
+	}
+
+	for i := range z.RRsets {
+		c.add(&z.RRsets[i])
+	}
+
+	report := Report{
+		SPF:   c.checkSPF(),
+		DMARC: c.checkDMARC(),
+		DKIM:  c.checkDKIM(),
+	}
+
+	count := func(issues []Issue) {
+		for _, issue := range issues {
+			if issue.Severity == SeverityError {
+				report.Errors++
+			} else {
+				report.Warnings++
+			}
+		}
+	}
+
+	count(report.SPF.Issues)
+	count(report.DMARC.Issues)
+
+	for i := range report.DKIM {
+		count(report.DKIM[i].Issues)
+	}
+
+	return report
+}
+
+// add indexes the enabled records of rrSet.
+func (c *zone) add(rrSet *pdnsapi.RRset) {
+	if rrSet.Name == nil || rrSet.Type == nil {
+		return
+	}
+
+	name := strings.ToLower(*rrSet.Name)
+
+	for _, rec := range rrSet.Records {
+		if pdnsapi.BoolValue(rec.Disabled) {
+			continue
+		}
+
+		content := strings.TrimSpace(pdnsapi.StringValue(rec.Content))
+
+		switch *rrSet.Type {
+		case pdnsapi.RRTypeTXT:
+			c.txt[name] = append(c.txt[name], Record{Content: content, Text: dnsclient.Unquote(content)})
+		case pdnsapi.RRTypeSPF:
+			c.spf[name] = true
+		case pdnsapi.RRTypeCNAME:
+			c.cname[name] = strings.ToLower(content)
+		}
+	}
+}
+
+// inZone reports whether name is the zone apex or below it.
+func (c *zone) inZone(name string) bool {
+	return name == c.name || strings.HasSuffix(name, "."+c.name)
+}
+
+// tagged returns the TXT records of name whose text starts with prefix, a
+// version tag such as "v=spf1", case-insensitively.
+func (c *zone) tagged(name, prefix string) []Record {
+	var out []Record
+
+	for _, rec := range c.txt[name] {
+		if hasVersion(rec.Text, prefix) {
+			out = append(out, rec)
+		}
+	}
+
+	return out
+}
+
+// hasVersion reports whether text starts with the version tag prefix,
+// followed by the end of the text, a space or a semicolon.
+func hasVersion(text, prefix string) bool {
+	if len(text) < len(prefix) || !strings.EqualFold(text[:len(prefix)], prefix) {
+		return false
+	}
+
+	rest := text[len(prefix):]
+
+	return rest == "" || rest[0] == ' ' || rest[0] == ';'
+}
+
+// parseTags splits a DMARC or DKIM record into its tags by lower-cased name.
+// It returns the names of tags that are listed twice.
+func parseTags(text string) (map[string]string, []string) {
+	tags := map[string]string{}
+
+	var duplicates []string
+
+	for _, part := range strings.Split(text, ";") {
+		key, value, _ := strings.Cut(part, "=")
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+
+		if _, ok := tags[key]; ok && !slices.Contains(duplicates, key) {
+			duplicates = append(duplicates, key)
+		}
+
+		tags[key] = strings.TrimSpace(value)
+	}
+
+	return tags, duplicates
+}
+
+func warn(issues *[]Issue, msg string) {
+	*issues = append(*issues, Issue{Severity: SeverityWarning, Message: msg})
+}
+
+func fail(issues *[]Issue, msg string) {
+	*issues = append(*issues, Issue{Severity: SeverityError, Message: msg})
+}
@@ -0,0 +1,234 @@
+package emailsec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func rrSet(name string, rrType pdnsapi.RRType, contents ...string) pdnsapi.RRset {
+	records := make([]pdnsapi.Record, 0, len(contents))
+	for _, content := range contents {
+		records = append(records, pdnsapi.Record{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)})
+	}
+
+	return pdnsapi.RRset{Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(rrType), Records: records}
+}
+
+func testZone(rrSets ...pdnsapi.RRset) *pdnsapi.Zone {
+	return &pdnsapi.Zone{Name: pdnsapi.String("example.com."), RRsets: rrSets}
+}
+
+// messages returns the messages of issues.
+func messages(issues []Issue) []string {
+	out := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		out = append(out, string(issue.Severity)+": "+issue.Message)
+	}
+
+	return out
+}
+
+func rsaKey(t *testing.T, bits int) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func TestCheckSPF(t *testing.T) {
+	tests := []struct {
+		name     string
+		rrSets   []pdnsapi.RRset
+		lookups  int
+		external []string
+		want     []string
+	}{
+		{
+			name: "missing",
+			want: []string{"warning: the zone has no SPF record, so receivers cannot tell which servers may send its mail"},
+		},
+		{
+			name:   "good",
+			rrSets: []pdnsapi.RRset{rrSet("example.com.", pdnsapi.RRTypeTXT, `"v=spf1 mx ip4:192.0.2.0/24 -all"`)},
+			want:   []string{}, lookups: 1,
+		},
+		{
+			name: "two records",
+			rrSets: []pdnsapi.RRset{rrSet("example.com.", pdnsapi.RRTypeTXT,
+				`"v=spf1 mx -all"`, `"v=spf1 a -all"`, `"google-site-verification=abc"`)},
+			want: []string{"error: the zone has 2 SPF records; receivers fail the check when there is more than one"},
+		},
+		{
+			name: "syntax",
+			rrSets: []pdnsapi.RRset{rrSet("example.com.", pdnsapi.RRTypeTXT,
+				`"v=spf1 ip4:192.0.2.300 include: ptr mx:mail.example.com/24 foo +all a"`)},
+			lookups: 3,
+			want: []string{
+				`error: invalid ip4 address "192.0.2.300"`,
+				"error: the include mechanism needs a domain: include:",
+				`error: unknown mechanism "foo"`,
+				"warning: the ptr mechanism is slow and unreliable; RFC 7208 advises against it",
+				"warning: the mechanisms after all are never evaluated",
+				"error: +all permits every server to send mail for the zone",
+			},
+		},
+		{
+			name: "lookups",
+			rrSets: []pdnsapi.RRset{
+				rrSet("example.com.", pdnsapi.RRTypeTXT,
+					`"v=spf1 include:_spf.example.com include:_spf.google.com include:spf.protection.outlook.com" " ~all"`),
+				rrSet("_spf.example.com.", pdnsapi.RRTypeTXT,
+					`"v=spf1 a mx a:a.example.com a:b.example.com a:c.example.com a:d.example.com a:e.example.com ?all"`),
+				rrSet("example.com.", pdnsapi.RRTypeSPF, `"v=spf1 -all"`),
+			},
+			lookups:  10,
+			external: []string{"_spf.google.com", "spf.protection.outlook.com"},
+			want:     []string{"warning: the SPF record type is obsolete; receivers only read the TXT record"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spf := Check(testZone(tt.rrSets...)).SPF
+
+			if got := messages(spf.Issues); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %q, want %q", got, tt.want)
+			}
+
+			if spf.Lookups != tt.lookups {
+				t.Errorf("lookups = %d, want %d", spf.Lookups, tt.lookups)
+			}
+
+			if len(tt.external) > 0 && !reflect.DeepEqual(spf.External, tt.external) {
+				t.Errorf("external = %v, want %v", spf.External, tt.external)
+			}
+		})
+	}
+
+	spf := Check(testZone(rrSet("example.com.", pdnsapi.RRTypeTXT,
+		`"v=spf1 a a a a a a a a a a a -all"`))).SPF
+	if got := messages(spf.Issues); len(got) != 1 || got[0] != "error: the record needs 11 DNS lookups, more than the 10 receivers allow" {
+		t.Errorf("too many lookups: issues = %q", got)
+	}
+}
+
+func TestCheckDMARC(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "good",
+			content: `"v=DMARC1; p=reject; rua=mailto:dmarc@example.com"`,
+			want:    []string{},
+		},
+		{
+			name:    "monitoring",
+			content: `"v=DMARC1;p=none;pct=50"`,
+			want: []string{
+				"warning: the policy none only monitors: mail failing DMARC is still delivered",
+				"warning: the policy applies to 50% of the mail failing DMARC only",
+				"warning: no rua address receives aggregate reports, so you cannot tell who sends mail for the zone",
+			},
+		},
+		{
+			name:    "invalid",
+			content: `"v=DMARC1; p=block; sp=quarantine; pct=120; rua=dmarc@example.com; adkim=x; p=none"`,
+			want: []string{
+				"error: the p tag is set more than once",
+				"warning: the policy none only monitors: mail failing DMARC is still delivered",
+				`error: invalid percentage "120"; use a number from 0 to 100`,
+				`error: the rua address "dmarc@example.com" must start with mailto:`,
+				`error: invalid adkim alignment "x"; use r or s`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dmarc := Check(testZone(rrSet("_dmarc.example.com.", pdnsapi.RRTypeTXT, tt.content))).DMARC
+
+			if got := messages(dmarc.Issues); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if dmarc := Check(testZone()).DMARC; dmarc.Record != nil || len(dmarc.Issues) != 1 {
+		t.Errorf("missing DMARC record: %+v", dmarc)
+	}
+
+	dmarc := Check(testZone(rrSet("_dmarc.example.com.", pdnsapi.RRTypeCNAME, "_dmarc.example.net."))).DMARC
+	if dmarc.Target != "_dmarc.example.net." || len(dmarc.Issues) != 0 {
+		t.Errorf("DMARC CNAME: %+v", dmarc)
+	}
+}
+
+func TestCheckDKIM(t *testing.T) {
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	report := Check(testZone(
+		rrSet("s2048._domainkey.example.com.", pdnsapi.RRTypeTXT, `"v=DKIM1; k=rsa; p=`+rsaKey(t, 2048)+`"`),
+		rrSet("s1024._domainkey.example.com.", pdnsapi.RRTypeTXT, `"v=DKIM1; t=y; p=`+rsaKey(t, 1024)+`"`),
+		rrSet("ed._domainkey.example.com.", pdnsapi.RRTypeTXT,
+			`"v=DKIM1; k=ed25519; p=`+base64.StdEncoding.EncodeToString(edKey)+`"`),
+		rrSet("old._domainkey.example.com.", pdnsapi.RRTypeTXT, `"v=DKIM1; p="`),
+		rrSet("bad._domainkey.example.com.", pdnsapi.RRTypeTXT, `"v=DKIM1; p=not-base64!"`),
+		rrSet("selector1._domainkey.example.com.", pdnsapi.RRTypeCNAME, "selector1-example-com._domainkey.example.onmicrosoft.com."),
+		rrSet("_domainkey.example.com.", pdnsapi.RRTypeTXT, `"o=~"`),
+	))
+
+	got := make([]string, 0, len(report.DKIM))
+	for _, dkim := range report.DKIM {
+		got = append(got, dkim.Selector+" "+dkim.KeyType+" "+strings.Join(messages(dkim.Issues), "|"))
+	}
+
+	want := []string{
+		"bad rsa error: the public key is not valid base64",
+		"ed ed25519 ",
+		"old rsa warning: the key is revoked: its p tag is empty",
+		"s1024 rsa warning: the key is in test mode (t=y), so receivers treat signed mail like unsigned mail|" +
+			"warning: the 1024-bit RSA key is weak; use at least 2048 bits",
+		"s2048 rsa ",
+		"selector1  ",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DKIM = %q, want %q", got, want)
+	}
+
+	if report.DKIM[4].KeyBits != 2048 || report.DKIM[5].Target == "" {
+		t.Errorf("DKIM = %+v", report.DKIM)
+	}
+}
+
+func TestCheckDisabled(t *testing.T) {
+	set := rrSet("example.com.", pdnsapi.RRTypeTXT, `"v=spf1 -all"`)
+	set.Records[0].Disabled = pdnsapi.Bool(true)
+
+	report := Check(testZone(set))
+	if report.SPF.Record != nil || report.Warnings != 2 || report.Errors != 0 {
+		t.Errorf("Check() = %+v, want the disabled record ignored", report)
+	}
+}
@@ -0,0 +1,232 @@
+package emailsec
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// MaxLookups is the number of DNS lookups an SPF check may need before
+// receivers fail it, see RFC 7208 section 4.6.4.
+const MaxLookups = 10
+
+// spfTerm is a mechanism or modifier of an SPF record.
+type spfTerm struct {
+	qualifier byte
+	// name is the lower-cased mechanism or modifier name.
+	name     string
+	value    string
+	modifier bool
+}
+
+// lookupMechanisms are the mechanisms that need a DNS lookup.
+var lookupMechanisms = map[string]bool{"a": true, "mx": true, "ptr": true, "exists": true, "include": true}
+
+// parseSPF splits the text of an SPF record into its terms and reports
+// syntax errors and risky terms.
+func parseSPF(text string) ([]spfTerm, []Issue) {
+	issues := []Issue{}
+
+	fields := strings.Fields(text)
+	if len(fields) > 0 {
+		fields = fields[1:]
+	}
+
+	terms := make([]spfTerm, 0, len(fields))
+
+	for _, field := range fields {
+		term, err := parseSPFTerm(field)
+		if err != nil {
+			fail(&issues, err.Error())
+			continue
+		}
+
+		terms = append(terms, term)
+	}
+
+	checkSPFTerms(terms, &issues)
+
+	return terms, issues
+}
+
+// parseSPFTerm parses and validates a single term.
+func parseSPFTerm(field string) (spfTerm, error) {
+	eq := strings.IndexByte(field, '=')
+	if eq > 0 && !strings.ContainsAny(field[:eq], ":/") {
+		return spfTerm{name: strings.ToLower(field[:eq]), value: field[eq+1:], modifier: true}, nil
+	}
+
+	term, mechanism := spfTerm{qualifier: '+'}, field
+	if strings.IndexByte("+-~?", field[0]) >= 0 {
+		term.qualifier, mechanism = field[0], field[1:]
+	}
+
+	end := strings.IndexAny(mechanism, ":/")
+	if end < 0 {
+		end = len(mechanism)
+	}
+
+	term.name, term.value = strings.ToLower(mechanism[:end]), mechanism[end:]
+	arg := strings.TrimPrefix(term.value, ":")
+
+	switch term.name {
+	case "all":
+		if term.value != "" {
+			return term, fmt.Errorf("the all mechanism takes no argument: %s", field)
+		}
+	case "include", "exists":
+		if !strings.HasPrefix(term.value, ":") || arg == "" {
+			return term, fmt.Errorf("the %s mechanism needs a domain: %s", term.name, field)
+		}
+	case "a", "mx", "ptr":
+		// The domain and, except for ptr, the prefix lengths are optional.
+	case "ip4", "ip6":
+		if !validIP(arg, term.name == "ip4") {
+			return term, fmt.Errorf("invalid %s address %q", term.name, arg)
+		}
+	default:
+		return term, fmt.Errorf("unknown mechanism %q", field)
+	}
+
+	term.value = arg
+
+	return term, nil
+}
+
+// validIP reports whether s is an IPv4, or with v4 unset an IPv6, address
+// or prefix.
+func validIP(s string, v4 bool) bool {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Addr().Is4() == v4
+	}
+
+	addr, err := netip.ParseAddr(s)
+
+	return err == nil && addr.Is4() == v4
+}
+
+// checkSPFTerms reports terms that make the record weaker than intended.
+func checkSPFTerms(terms []spfTerm, issues *[]Issue) {
+	all, redirects := -1, 0
+
+	for i, term := range terms {
+		switch {
+		case term.modifier:
+			if term.name == "redirect" {
+				redirects++
+			}
+		case term.name == "all":
+			if all < 0 {
+				all = i
+			}
+		case term.name == "ptr":
+			warn(issues, "the ptr mechanism is slow and unreliable; RFC 7208 advises against it")
+		}
+	}
+
+	if redirects > 1 {
+		fail(issues, "the redirect modifier is set more than once")
+	}
+
+	if all < 0 {
+		if redirects == 0 {
+			warn(issues, "the record does not end with an all mechanism, so mail from other servers is neither passed nor failed")
+		}
+
+		return
+	}
+
+	if redirects > 0 {
+		warn(issues, "the redirect modifier is ignored because the record has an all mechanism")
+	}
+
+	for _, term := range terms[all+1:] {
+		if !term.modifier {
+			warn(issues, "the mechanisms after all are never evaluated")
+			break
+		}
+	}
+
+	switch terms[all].qualifier {
+	case '+':
+		fail(issues, "+all permits every server to send mail for the zone")
+	case '?':
+		warn(issues, "?all gives mail from other servers a neutral result; use ~all or -all")
+	}
+}
+
+// checkSPF evaluates the SPF record of the zone apex.
+func (c *zone) checkSPF() SPF {
+	spf := SPF{Name: c.name, External: []string{}, Issues: []Issue{}}
+
+	if c.spf[c.name] {
+		warn(&spf.Issues, "the SPF record type is obsolete; receivers only read the TXT record")
+	}
+
+	records := c.tagged(c.name, "v=spf1")
+
+	switch len(records) {
+	case 0:
+		warn(&spf.Issues, "the zone has no SPF record, so receivers cannot tell which servers may send its mail")
+		return spf
+	case 1:
+	default:
+		fail(&spf.Issues, fmt.Sprintf("the zone has %d SPF records; receivers fail the check when there is more than one",
+			len(records)))
+
+		return spf
+	}
+
+	spf.Record = &records[0]
+
+	terms, issues := parseSPF(records[0].Text)
+	spf.Issues = append(spf.Issues, issues...)
+	spf.Lookups = c.countLookups(terms, map[string]bool{c.name: true}, &spf.External)
+
+	if spf.Lookups > MaxLookups {
+		fail(&spf.Issues, fmt.Sprintf("the record needs %d DNS lookups, more than the %d receivers allow",
+			spf.Lookups, MaxLookups))
+	}
+
+	return spf
+}
+
+// countLookups counts the DNS lookups of terms, following includes and
+// redirects to SPF records in the zone. visited holds the names followed
+// already; other domains are added to external.
+func (c *zone) countLookups(terms []spfTerm, visited map[string]bool, external *[]string) int {
+	hasAll := slices.ContainsFunc(terms, func(t spfTerm) bool { return !t.modifier && t.name == "all" })
+	lookups := 0
+
+	for _, term := range terms {
+		redirect := term.modifier && term.name == "redirect" && !hasAll
+		if !redirect && (term.modifier || !lookupMechanisms[term.name]) {
+			continue
+		}
+
+		lookups++
+
+		if term.name != "include" && !redirect {
+			continue
+		}
+
+		target := strings.ToLower(strings.TrimSuffix(term.value, ".")) + "."
+
+		switch {
+		case strings.Contains(target, "%") || !c.inZone(target):
+			if !slices.Contains(*external, term.value) {
+				*external = append(*external, term.value)
+			}
+		case !visited[target]:
+			visited[target] = true
+
+			if records := c.tagged(target, "v=spf1"); len(records) == 1 {
+				nested, _ := parseSPF(records[0].Text)
+				lookups += c.countLookups(nested, visited, external)
+			}
+		}
+	}
+
+	return lookups
+}
@@ -0,0 +1,187 @@
+// Package eventbus is a small publish/subscribe bus for events that other
+// subsystems, and other replicas of the application, must react to, such as
+// cache invalidations.
+//
+// Events are delivered to local subscribers synchronously. When a relay is
+// running (see Bus.Relay) every event is also written to the bus_events table
+// and replayed on the other replicas polling it, so replicas sharing a
+// MySQL or PostgreSQL database stay consistent without an extra service.
+package eventbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// Topics published by the application.
+const (
+	// TopicSettings is published after the settings table changed.
+	TopicSettings = "cache.settings"
+	// TopicPermissions is published after users, roles, groups or their
+	// mappings changed.
+	TopicPermissions = "cache.permissions"
+	// TopicZone is published after a zone was created, changed or deleted.
+	// The key is the zone name.
+	TopicZone = "zone"
+	// TopicSetting is published after a setting was written through the
+	// setting controller. The key is the setting name.
+	TopicSetting = "setting"
+	// TopicActivity is published after an activity log entry was recorded.
+	// The key is the ID of the entry.
+	TopicActivity = "activity"
+	// TopicZoneHealth is published when the health checks of a zone start
+	// failing. The key is the zone name.
+	TopicZoneHealth = "zone.health"
+	// TopicServerHealth is published when a PowerDNS server goes down or
+	// recovers. The key is the name of the view, empty for the primary
+	// server.
+	TopicServerHealth = "server.health"
+)
+
+// retention is how long relayed events are kept before pruning.
+const retention = time.Hour
+
+// Event is a published event.
+type Event struct {
+	Topic string
+	Key   string
+	// Remote is set for events replayed from another replica.
+	Remote bool
+}
+
+// Handler handles an event.
+type Handler func(Event)
+
+// Bus dispatches events to subscribers.
+type Bus struct {
+	origin string
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	db       *gorm.DB
+}
+
+// Default is the application bus.
+var Default = New()
+
+// New returns a bus with a random replica origin.
+func New() *Bus {
+	return &Bus{origin: newOrigin(), handlers: map[string][]Handler{}}
+}
+
+// Subscribe registers h for topic.
+func (b *Bus) Subscribe(topic string, h Handler) {
+	b.mu.Lock()
+	b.handlers[topic] = append(b.handlers[topic], h)
+	b.mu.Unlock()
+}
+
+// Publish delivers an event to the local subscribers and, while a relay is
+// running, to the other replicas.
+func (b *Bus) Publish(topic, key string) {
+	b.dispatch(Event{Topic: topic, Key: key})
+
+	b.mu.RLock()
+	db := b.db
+	b.mu.RUnlock()
+
+	if db == nil {
+		return
+	}
+
+	if err := db.Create(&models.BusEvent{Origin: b.origin, Topic: topic, Key: key}).Error; err != nil {
+		log.Warn().Err(err).Str("topic", topic).Msg("eventbus: failed to relay event")
+	}
+}
+
+// Relay replays the events published by other replicas every interval until
+// ctx is canceled. Events published before Relay started are skipped.
+func (b *Bus) Relay(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	var lastID uint64
+	if err := db.Model(&models.BusEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&lastID).Error; err != nil {
+		log.Error().Err(err).Msg("eventbus: relay disabled")
+		return
+	}
+
+	b.mu.Lock()
+	b.db = db
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.db = nil
+		b.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastPrune := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lastID = b.poll(db, lastID)
+
+			if time.Since(lastPrune) > retention {
+				b.prune(db)
+				lastPrune = time.Now()
+			}
+		}
+	}
+}
+
+// poll dispatches the events of other replicas newer than lastID and returns
+// the new last ID.
+func (b *Bus) poll(db *gorm.DB, lastID uint64) uint64 {
+	var events []models.BusEvent
+	if err := db.Where("id > ?", lastID).Order("id").Find(&events).Error; err != nil {
+		log.Warn().Err(err).Msg("eventbus: failed to poll events")
+		return lastID
+	}
+
+	for i := range events {
+		lastID = events[i].ID
+
+		if events[i].Origin == b.origin {
+			continue
+		}
+
+		b.dispatch(Event{Topic: events[i].Topic, Key: events[i].Key, Remote: true})
+	}
+
+	return lastID
+}
+
+func (b *Bus) prune(db *gorm.DB) {
+	if err := db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.BusEvent{}).Error; err != nil {
+		log.Warn().Err(err).Msg("eventbus: failed to prune events")
+	}
+}
+
+func (b *Bus) dispatch(e Event) {
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers[e.Topic]...)
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+func newOrigin() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,67 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestPublishLocal(t *testing.T) {
+	bus := New()
+
+	var got []Event
+
+	bus.Subscribe(TopicZone, func(e Event) { got = append(got, e) })
+	bus.Publish(TopicZone, "example.org.")
+	bus.Publish(TopicSettings, "")
+
+	if len(got) != 1 || got[0].Key != "example.org." || got[0].Remote {
+		t.Fatalf("got %+v, want one local zone event", got)
+	}
+}
+
+func TestRelayBetweenReplicas(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&models.BusEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := New(), New()
+	a.db, b.db = db, db
+
+	var gotA, gotB []Event
+
+	a.Subscribe(TopicZone, func(e Event) { gotA = append(gotA, e) })
+	b.Subscribe(TopicZone, func(e Event) { gotB = append(gotB, e) })
+
+	a.Publish(TopicZone, "example.org.")
+
+	lastA := a.poll(db, 0)
+	lastB := b.poll(db, 0)
+
+	if len(gotA) != 1 || gotA[0].Remote {
+		t.Errorf("publisher got %+v, want only its local event", gotA)
+	}
+
+	if len(gotB) != 1 || !gotB[0].Remote || gotB[0].Key != "example.org." {
+		t.Errorf("replica got %+v, want one remote zone event", gotB)
+	}
+
+	if lastA != lastB || lastA == 0 {
+		t.Errorf("poll returned last IDs %d and %d", lastA, lastB)
+	}
+
+	// Already processed events are not replayed.
+	b.poll(db, lastB)
+
+	if len(gotB) != 1 {
+		t.Errorf("replica replayed events: %+v", gotB)
+	}
+}
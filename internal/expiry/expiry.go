@@ -0,0 +1,160 @@
+// Package expiry keeps the expiry dates users set on zones and RRsets, e.g.
+// for a temporary CNAME of a campaign, and reminds their owners ahead of
+// them. An expiry date is a reminder only: nothing is changed in PowerDNS
+// when it passes.
+package expiry
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// MaxNoteLength bounds the note of an expiry.
+const MaxNoteLength = 255
+
+// targetQuery selects the expiry of a zone or RRset.
+const targetQuery = "zone_name = ? AND name = ? AND type = ?"
+
+// Zone returns the expiries of zoneName, of the zone itself and of its
+// RRsets, earliest first.
+func Zone(db *gorm.DB, zoneName string) ([]models.Expiry, error) {
+	var expiries []models.Expiry
+
+	err := db.Preload("CreatedBy").
+		Where("zone_name = ?", zoneName).
+		Order("expires_at, id").
+		Find(&expiries).Error
+
+	return expiries, err
+}
+
+// Upcoming returns up to limit expiries due before the given time, overdue
+// ones included, of the zones allow accepts, earliest first.
+func Upcoming(db *gorm.DB, before time.Time, allow func(zoneName string) bool, limit int) ([]models.Expiry, error) {
+	var expiries []models.Expiry
+
+	err := db.Where("expires_at <= ?", before).
+		Order("expires_at, id").
+		Find(&expiries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	expiries = slices.DeleteFunc(expiries, func(e models.Expiry) bool { return !allow(e.ZoneName) })
+	if len(expiries) > limit {
+		expiries = expiries[:limit]
+	}
+
+	return expiries, nil
+}
+
+// Set sets the expiry of the zone, or of the RRset name/rrType when they are
+// not empty, and returns the one it replaces, nil when there was none. A new
+// date sends the reminder again.
+func Set(db *gorm.DB, e *models.Expiry) (*models.Expiry, error) {
+	var old *models.Expiry
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Expiry
+
+		err := tx.Where(targetQuery, e.ZoneName, e.Name, e.Type).First(&existing).Error
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(e).Error
+		case err != nil:
+			return err
+		}
+
+		previous := existing
+		old = &previous
+
+		e.ID, e.CreatedAt = existing.ID, existing.CreatedAt
+		if existing.ExpiresAt.Equal(e.ExpiresAt) {
+			e.NotifiedAt = existing.NotifiedAt
+		}
+
+		return tx.Save(e).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return old, nil
+}
+
+// Clear removes the expiry of the zone, or of the RRset name/rrType, and
+// returns it, nil when there was none.
+func Clear(db *gorm.DB, zoneName, name, rrType string) (*models.Expiry, error) {
+	var existing models.Expiry
+
+	err := db.Where(targetQuery, zoneName, name, rrType).First(&existing).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, nil //nolint:nilnil // no expiry is not an error
+	case err != nil:
+		return nil, err
+	}
+
+	if err = db.Delete(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	return &existing, nil
+}
+
+// due returns up to limit expiries before the given time whose owners were
+// not reminded yet, earliest first.
+func due(db *gorm.DB, before time.Time, limit int) ([]models.Expiry, error) {
+	var expiries []models.Expiry
+
+	err := db.Preload("CreatedBy").
+		Where("notified_at IS NULL AND expires_at <= ?", before).
+		Order("expires_at, id").
+		Limit(limit).
+		Find(&expiries).Error
+
+	return expiries, err
+}
+
+// claim marks the reminder of an expiry as sent. It reports false when
+// another instance claimed it first or the expiry was removed.
+func claim(db *gorm.DB, id uint64, now time.Time) (bool, error) {
+	res := db.Model(&models.Expiry{}).
+		Where("id = ? AND notified_at IS NULL", id).
+		Update("notified_at", now)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+// owners returns the email addresses of the owners of e: the user who set it
+// and the users the zone was granted to through a claim.
+func owners(db *gorm.DB, e *models.Expiry) ([]string, error) {
+	var users []models.User
+
+	err := db.Where("active = ? AND email <> ''", true).
+		Where("id IN (?)", db.Model(&models.ZoneOwnership{}).Select("user_id").Where("zone_name = ?", e.ZoneName)).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if e.CreatedBy != nil && e.CreatedBy.Active && e.CreatedBy.Email != "" {
+		users = append(users, *e.CreatedBy)
+	}
+
+	emails := make([]string, 0, len(users))
+	for i := range users {
+		emails = append(emails, users[i].Email)
+	}
+
+	slices.Sort(emails)
+
+	return slices.Compact(emails), nil
+}
@@ -0,0 +1,190 @@
+package expiry
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+var now = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+type stubMailer struct {
+	sent map[string]string
+}
+
+func (m *stubMailer) Send(to, _, body string) error {
+	m.sent[to] = body
+	return nil
+}
+
+// newTestDB seeds the users "alice", who set the expiries, "bob", who owns
+// example.com. through a claim, and the inactive "carol", who owns it too.
+func newTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.ZoneOwnership{}, &models.Expiry{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	role := models.Role{Name: "user"}
+	db.Create(&role)
+
+	users := []models.User{
+		{Username: "alice", Email: "alice@example.com", RoleID: role.ID, Active: true},
+		{Username: "bob", Email: "bob@example.com", RoleID: role.ID, Active: true},
+		{Username: "carol", Email: "carol@example.com", RoleID: role.ID},
+	}
+
+	for i := range users {
+		if err = db.Create(&users[i]).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	db.Create(&models.ZoneOwnership{ZoneName: "example.com.", UserID: users[1].ID})
+	db.Create(&models.ZoneOwnership{ZoneName: "example.com.", UserID: users[2].ID})
+
+	return db, &users[0]
+}
+
+func day(offset int) time.Time {
+	return now.Truncate(24*time.Hour).AddDate(0, 0, offset)
+}
+
+func TestSetAndClear(t *testing.T) {
+	db, alice := newTestDB(t)
+
+	old, err := Set(db, &models.Expiry{ZoneName: "example.com.", Name: "promo.example.com.", Type: "CNAME",
+		ExpiresAt: day(3), Note: "campaign", CreatedByID: &alice.ID})
+	if err != nil || old != nil {
+		t.Fatalf("Set() = %v, %v, want nil, nil", old, err)
+	}
+
+	if ok, _ := claim(db, 1, now); !ok {
+		t.Fatal("claim() = false, want true")
+	}
+
+	// The same date keeps the reminder sent, a new one sends it again.
+	old, err = Set(db, &models.Expiry{ZoneName: "example.com.", Name: "promo.example.com.", Type: "CNAME",
+		ExpiresAt: day(3), Note: "spring campaign"})
+	if err != nil || old == nil || old.Note != "campaign" {
+		t.Fatalf("Set() = %+v, %v", old, err)
+	}
+
+	expiries, _ := Zone(db, "example.com.")
+	if len(expiries) != 1 || expiries[0].NotifiedAt == nil || expiries[0].Note != "spring campaign" {
+		t.Fatalf("Zone() = %+v, want one notified expiry", expiries)
+	}
+
+	if _, err = Set(db, &models.Expiry{ZoneName: "example.com.", Name: "promo.example.com.", Type: "CNAME",
+		ExpiresAt: day(10)}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	expiries, _ = Zone(db, "example.com.")
+	if len(expiries) != 1 || expiries[0].NotifiedAt != nil {
+		t.Fatalf("Zone() = %+v, want one expiry not notified", expiries)
+	}
+
+	old, err = Clear(db, "example.com.", "promo.example.com.", "CNAME")
+	if err != nil || old == nil || !old.ExpiresAt.Equal(day(10)) {
+		t.Fatalf("Clear() = %+v, %v", old, err)
+	}
+
+	if old, err = Clear(db, "example.com.", "promo.example.com.", "CNAME"); err != nil || old != nil {
+		t.Fatalf("Clear() again = %+v, %v, want nil, nil", old, err)
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	db, _ := newTestDB(t)
+
+	for _, e := range []models.Expiry{
+		{ZoneName: "example.com.", ExpiresAt: day(-1)},
+		{ZoneName: "example.com.", Name: "www.example.com.", Type: "A", ExpiresAt: day(5)},
+		{ZoneName: "example.com.", Name: "old.example.com.", Type: "A", ExpiresAt: day(30)},
+		{ZoneName: "hidden.example.", ExpiresAt: day(1)},
+	} {
+		if _, err := Set(db, &e); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	got, err := Upcoming(db, day(7), func(zone string) bool { return zone == "example.com." }, 10)
+	if err != nil {
+		t.Fatalf("Upcoming() error = %v", err)
+	}
+
+	names := make([]string, 0, len(got))
+	for i := range got {
+		names = append(names, got[i].ZoneName+got[i].Name)
+	}
+
+	if want := []string{"example.com.", "example.com.www.example.com."}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Upcoming() = %v, want %v", names, want)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	db, alice := newTestDB(t)
+
+	for _, e := range []models.Expiry{
+		{ZoneName: "example.com.", Name: "promo.example.com.", Type: "CNAME", ExpiresAt: day(2), Note: "campaign", CreatedByID: &alice.ID},
+		{ZoneName: "example.com.", ExpiresAt: day(30)},
+		{ZoneName: "other.example.", ExpiresAt: day(-2), CreatedByID: &alice.ID},
+	} {
+		if _, err := Set(db, &e); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	m := &stubMailer{sent: map[string]string{}}
+	r := &Runner{db: db, notifyBefore: 7 * 24 * time.Hour, mailer: m, brand: "Test", url: "https://dns.example.org",
+		now: func() time.Time { return now }}
+
+	if err := r.runOnce(); err != nil {
+		t.Fatalf("runOnce() error = %v", err)
+	}
+
+	if len(m.sent) != 2 {
+		t.Fatalf("sent to %v, want alice and bob", m.sent)
+	}
+
+	alices := m.sent["alice@example.com"]
+	for _, want := range []string{
+		"CNAME promo.example.com., expires 2026-10-19",
+		"campaign",
+		"zone other.example., expired 2026-10-15",
+		"https://dns.example.org/zone/edit/example.com.",
+	} {
+		if !strings.Contains(alices, want) {
+			t.Errorf("reminder of alice does not contain %q:\n%s", want, alices)
+		}
+	}
+
+	if bobs := m.sent["bob@example.com"]; strings.Contains(bobs, "other.example.") || !strings.Contains(bobs, "promo.example.com.") {
+		t.Errorf("reminder of bob = %q, want the expiry of example.com. only", bobs)
+	}
+
+	// Each reminder is sent once.
+	m.sent = map[string]string{}
+
+	if err := r.runOnce(); err != nil {
+		t.Fatalf("runOnce() error = %v", err)
+	}
+
+	if len(m.sent) != 0 {
+		t.Errorf("second run sent %v, want nothing", m.sent)
+	}
+}
@@ -0,0 +1,164 @@
+package expiry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/mailer"
+)
+
+const (
+	// checkInterval is how often expiries are checked for reminders.
+	checkInterval = time.Hour
+
+	// batchSize is the number of reminders sent per check.
+	batchSize = 500
+
+	// zoneEditPath is the path of the zone editor; the zone name follows.
+	zoneEditPath = "/zone/edit/"
+)
+
+// Runner reminds the owners of expiries in the background.
+type Runner struct {
+	db           *gorm.DB
+	notifyBefore time.Duration
+	mailer       mailer.Sender
+	brand        string
+	url          string
+	now          func() time.Time
+}
+
+// NewRunner builds a Runner from cfg. Reminders are only mailed when an SMTP
+// server is configured.
+func NewRunner(cfg *config.Config, db *gorm.DB) *Runner {
+	r := &Runner{
+		db:           db,
+		notifyBefore: cfg.Expiry.NotifyBefore,
+		brand:        cfg.Branding.Resolve(cfg.Title).Name,
+		url:          strings.TrimRight(cfg.Webserver.URL, "/"),
+		now:          time.Now,
+	}
+
+	if cfg.Mail.Enabled() {
+		r.mailer = mailer.New(&cfg.Mail)
+	}
+
+	return r
+}
+
+// Run sends the due reminders every checkInterval until ctx is canceled. It
+// returns immediately when no SMTP server is configured.
+func (r *Runner) Run(ctx context.Context) {
+	if r.mailer == nil {
+		log.Debug().Msg("expiry: reminders disabled, no SMTP server configured")
+		return
+	}
+
+	jobs.Scheduled(jobs.ExpiryReminders, checkInterval)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.ExpiryReminders, r.runOnce)
+		}
+	}
+}
+
+// runOnce mails one reminder per owner listing the expiries due within the
+// notify period. Each expiry is claimed first, so several instances sharing a
+// database remind only once.
+func (r *Runner) runOnce() error {
+	now := r.now()
+
+	expiries, err := due(r.db, now.Add(r.notifyBefore), batchSize)
+	if err != nil {
+		log.Error().Err(err).Msg("expiry: failed to load due expiries")
+		return err
+	}
+
+	byOwner := map[string][]models.Expiry{}
+
+	for i := range expiries {
+		e := &expiries[i]
+
+		ok, err := claim(r.db, e.ID, now)
+		if err != nil {
+			log.Error().Err(err).Uint64("expiry_id", e.ID).Msg("expiry: failed to claim expiry")
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		emails, err := owners(r.db, e)
+		if err != nil {
+			log.Error().Err(err).Uint64("expiry_id", e.ID).Msg("expiry: failed to load owners")
+			return err
+		}
+
+		if len(emails) == 0 {
+			log.Info().Str("zone_name", e.ZoneName).Str("name", e.Name).Str("type", e.Type).
+				Msg("expiry: no owner with an email address to remind")
+		}
+
+		for _, to := range emails {
+			byOwner[to] = append(byOwner[to], *e)
+		}
+	}
+
+	for to, owned := range byOwner {
+		subject, body := r.reminderMessage(owned, now)
+		if err := r.mailer.Send(to, subject, body); err != nil {
+			log.Error().Err(err).Str("to", to).Msg("expiry: failed to send reminder")
+		}
+	}
+
+	return nil
+}
+
+// reminderMessage returns the subject and body of the reminder of expiries.
+func (r *Runner) reminderMessage(expiries []models.Expiry, now time.Time) (string, string) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "The following %d entries expire soon or have expired:\n\n", len(expiries))
+
+	for i := range expiries {
+		e := &expiries[i]
+
+		what := "zone " + e.ZoneName
+		if !e.IsZone() {
+			what = e.Type + " " + e.Name
+		}
+
+		state := "expires"
+		if e.ExpiresAt.Before(now.UTC().Truncate(24 * time.Hour)) {
+			state = "expired"
+		}
+
+		fmt.Fprintf(&b, "  %s, %s %s\n", what, state, e.ExpiresAt.UTC().Format(time.DateOnly))
+
+		if e.Note != "" {
+			fmt.Fprintf(&b, "    %s\n", e.Note)
+		}
+
+		fmt.Fprintf(&b, "    %s\n", r.url+zoneEditPath+e.ZoneName)
+	}
+
+	b.WriteString("\nReview the entries, then remove them or set a new expiry date in the zone editor.\n")
+
+	return fmt.Sprintf("%s: %d entries expiring", r.brand, len(expiries)), b.String()
+}
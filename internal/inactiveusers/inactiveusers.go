@@ -0,0 +1,179 @@
+// Package inactiveusers finds user accounts that have not logged in for a
+// configurable period and deactivates them, either on request of an
+// administrator or periodically in the background. The last login is taken
+// from the activity log; admins and service accounts are never affected.
+package inactiveusers
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// adminRoleName is the role whose members are never deactivated.
+const adminRoleName = "admin"
+
+// Candidate is an active user who has not logged in since the cutoff.
+type Candidate struct {
+	User models.User
+	// LastLogin is nil when the user never logged in; the account creation
+	// time is compared with the cutoff instead.
+	LastLogin *time.Time
+}
+
+// Since returns the time the inactivity is measured from.
+func (c *Candidate) Since() time.Time {
+	if c.LastLogin != nil {
+		return *c.LastLogin
+	}
+
+	return c.User.CreatedAt
+}
+
+// Cutoff returns the point in time before which a user counts as inactive.
+func Cutoff(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
+
+// Find returns the active users whose last login, or creation when they never
+// logged in, is before cutoff, longest inactive first. Admins and service
+// accounts are excluded.
+func Find(db *gorm.DB, cutoff time.Time) ([]Candidate, error) {
+	query := db.Preload("Role").Where("active = ? AND service_account = ?", true, false)
+
+	var adminRole models.Role
+
+	err := db.Where(models.WhereNameIs, adminRoleName).First(&adminRole).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if adminRole.ID != 0 {
+		query = query.Where("role_id <> ?", adminRole.ID)
+	}
+
+	var users []models.User
+	if err = query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	logins, err := lastLogins(db)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(users))
+
+	for i := range users {
+		c := Candidate{User: users[i]}
+		if t, ok := logins[users[i].ID]; ok {
+			c.LastLogin = &t
+		}
+
+		if c.Since().Before(cutoff) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Since().Before(candidates[j].Since())
+	})
+
+	return candidates, nil
+}
+
+// lastLogins returns the time of the latest successful login per user ID.
+// It picks the login entry with the highest ID per user: activity log IDs
+// grow with time, and unlike MAX(created_at) this scans into time.Time on
+// every supported database.
+func lastLogins(db *gorm.DB) (map[uint64]time.Time, error) {
+	latestIDs := db.Model(&models.ActivityLog{}).
+		Select("MAX(id)").
+		Where("action = ? AND user_id IS NOT NULL", activitylog.ActionLogin).
+		Group("user_id")
+
+	var logins []models.ActivityLog
+	if err := db.Select("user_id", "created_at").Where("id IN (?)", latestIDs).Find(&logins).Error; err != nil {
+		return nil, err
+	}
+
+	latest := make(map[uint64]time.Time, len(logins))
+	for i := range logins {
+		latest[*logins[i].UserID] = logins[i].CreatedAt
+	}
+
+	return latest, nil
+}
+
+// Select returns the candidates whose user ID is in ids. Callers pass the
+// user's selection through it so only actual candidates are deactivated.
+func Select(candidates []Candidate, ids []uint64) []Candidate {
+	wanted := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var selected []Candidate
+
+	for i := range candidates {
+		if wanted[candidates[i].User.ID] {
+			selected = append(selected, candidates[i])
+		}
+	}
+
+	return selected
+}
+
+// Deactivate deactivates the candidates and records one activity log entry
+// per user on behalf of actor (nil for the scheduled run).
+func Deactivate(db *gorm.DB, candidates []Candidate, actor *models.User, ipAddress string) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(candidates))
+	for i := range candidates {
+		ids[i] = candidates[i].User.ID
+	}
+
+	if err := db.Model(&models.User{}).
+		Where("id IN ? AND active = ?", ids, true).
+		Update("active", false).Error; err != nil {
+		return err
+	}
+
+	var (
+		actorID   *uint64
+		actorName = "system"
+	)
+
+	if actor != nil {
+		id := actor.ID
+		actorID, actorName = &id, actor.Username
+	}
+
+	for i := range candidates {
+		details := map[string]any{"reason": "inactive", "automatic": actor == nil}
+		if candidates[i].LastLogin != nil {
+			details["last_login"] = candidates[i].LastLogin.UTC().Format(time.RFC3339)
+		}
+
+		activitylog.Record(&activitylog.Entry{
+			DB:           db,
+			UserID:       actorID,
+			Username:     actorName,
+			Action:       activitylog.ActionUserDeactivated,
+			ResourceType: activitylog.ResourceTypeUser,
+			ResourceName: candidates[i].User.Username,
+			Details:      details,
+			IPAddress:    ipAddress,
+		})
+	}
+
+	return nil
+}
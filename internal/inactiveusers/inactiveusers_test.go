@@ -0,0 +1,192 @@
+package inactiveusers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type stubMailer struct {
+	to      []string
+	subject string
+	body    string
+}
+
+func (m *stubMailer) Send(to, subject, body string) error {
+	m.to = append(m.to, to)
+	m.subject, m.body = subject, body
+
+	return nil
+}
+
+// newTestDB seeds users created a year ago: "stale" logged in 100 days ago,
+// "recent" 10 days ago, "never" not at all, plus an admin, a service account
+// and an already inactive user who never logged in either.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	userRole := models.Role{Name: "user"}
+	adminRole := models.Role{Name: adminRoleName}
+	db.Create(&userRole)
+	db.Create(&adminRole)
+
+	created := now.AddDate(-1, 0, 0)
+	users := []models.User{
+		{Username: "stale", Email: "stale@example.com", RoleID: userRole.ID, Active: true},
+		{Username: "recent", Email: "recent@example.com", RoleID: userRole.ID, Active: true},
+		{Username: "never", Email: "never@example.com", RoleID: userRole.ID, Active: true},
+		{Username: "root", Email: "root@example.com", RoleID: adminRole.ID, Active: true},
+		{Username: "ci-bot", Email: "ci@example.com", RoleID: userRole.ID, Active: true, ServiceAccount: true},
+		{Username: "gone", Email: "gone@example.com", RoleID: userRole.ID},
+	}
+
+	for i := range users {
+		users[i].CreatedAt = created
+		if err = db.Create(&users[i]).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	login := func(u *models.User, at time.Time) {
+		id := u.ID
+		db.Create(&models.ActivityLog{UserID: &id, Username: u.Username, Action: activitylog.ActionLogin, CreatedAt: at})
+	}
+
+	login(&users[0], now.AddDate(0, 0, -200))
+	login(&users[0], now.AddDate(0, 0, -100))
+	login(&users[1], now.AddDate(0, 0, -10))
+	login(&users[3], now.AddDate(0, 0, -300))
+
+	return db
+}
+
+func usernames(candidates []Candidate) []string {
+	names := make([]string, len(candidates))
+	for i := range candidates {
+		names[i] = candidates[i].User.Username
+	}
+
+	return names
+}
+
+func TestFind(t *testing.T) {
+	db := newTestDB(t)
+
+	candidates, err := Find(db, Cutoff(now, 90))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	// Longest inactive first: "never" counts from its creation a year ago.
+	if got := strings.Join(usernames(candidates), ","); got != "never,stale" {
+		t.Fatalf("candidates = %s, want never,stale", got)
+	}
+
+	if candidates[0].LastLogin != nil {
+		t.Errorf("never.LastLogin = %v, want nil", candidates[0].LastLogin)
+	}
+
+	if last := candidates[1].LastLogin; last == nil || !last.Equal(now.AddDate(0, 0, -100)) {
+		t.Errorf("stale.LastLogin = %v, want latest login", last)
+	}
+
+	candidates, err = Find(db, Cutoff(now, 150))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	if got := strings.Join(usernames(candidates), ","); got != "never" {
+		t.Errorf("candidates after 150 days = %s, want never", got)
+	}
+}
+
+func TestDeactivate_SelectedCandidates(t *testing.T) {
+	db := newTestDB(t)
+
+	candidates, err := Find(db, Cutoff(now, 90))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	var root models.User
+	db.Where("username = ?", "root").First(&root)
+
+	// The admin is not a candidate, so selecting it has no effect.
+	selected := Select(candidates, []uint64{candidates[1].User.ID, root.ID})
+	if err = Deactivate(db, selected, &root, "127.0.0.1"); err != nil {
+		t.Fatalf("Deactivate() error = %v", err)
+	}
+
+	var active []models.User
+	db.Where("active = ?", true).Order("username").Find(&active)
+
+	names := make([]string, len(active))
+	for i := range active {
+		names[i] = active[i].Username
+	}
+
+	if got := strings.Join(names, ","); got != "ci-bot,never,recent,root" {
+		t.Errorf("active users = %s", got)
+	}
+
+	var entry models.ActivityLog
+	if err = db.Where("action = ?", activitylog.ActionUserDeactivated).First(&entry).Error; err != nil {
+		t.Fatalf("no activity log entry: %v", err)
+	}
+
+	if entry.ResourceName != "stale" || entry.Username != "root" {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestRunner_AutoDeactivateAndReport(t *testing.T) {
+	db := newTestDB(t)
+	mail := &stubMailer{}
+
+	r := &Runner{
+		cfg: config.InactiveUsers{
+			Days: 90, Interval: 24 * time.Hour, AutoDeactivate: true, Report: []string{"ops@example.com"},
+		},
+		db:     db,
+		mailer: mail,
+		brand:  "DNS",
+		url:    "https://dns.example.com",
+		now:    func() time.Time { return now },
+	}
+
+	r.runOnce()
+
+	var inactive int64
+	db.Model(&models.User{}).Where("username IN ? AND active = ?", []string{"stale", "never"}, false).Count(&inactive)
+
+	if inactive != 2 {
+		t.Errorf("deactivated %d users, want 2", inactive)
+	}
+
+	if len(mail.to) != 1 || mail.to[0] != "ops@example.com" {
+		t.Fatalf("report recipients = %v", mail.to)
+	}
+
+	if mail.subject != "DNS: 2 inactive users deactivated" || !strings.Contains(mail.body, "stale <stale@example.com>") ||
+		!strings.Contains(mail.body, "https://dns.example.com"+ReportPath) {
+		t.Errorf("report = %q\n%s", mail.subject, mail.body)
+	}
+}
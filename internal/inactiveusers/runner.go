@@ -0,0 +1,165 @@
+package inactiveusers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/mailer"
+)
+
+// ReportPath is the admin page linked from the report email.
+const ReportPath = "/admin/user/inactive"
+
+// Runner re-runs the inactive user check at the configured interval.
+type Runner struct {
+	cfg    config.InactiveUsers
+	db     *gorm.DB
+	mailer mailer.Sender
+	brand  string
+	url    string
+	now    func() time.Time
+}
+
+// NewRunner builds a Runner from cfg. Reports are only mailed when an SMTP
+// server is configured.
+func NewRunner(cfg *config.Config, db *gorm.DB) *Runner {
+	r := &Runner{
+		cfg:   cfg.InactiveUsers,
+		db:    db,
+		brand: cfg.Branding.Resolve(cfg.Title).Name,
+		url:   strings.TrimRight(cfg.Webserver.URL, "/"),
+		now:   time.Now,
+	}
+
+	if cfg.Mail.Enabled() {
+		r.mailer = mailer.New(&cfg.Mail)
+	}
+
+	return r
+}
+
+// Run checks for inactive users every interval until ctx is canceled. It
+// returns immediately when no interval is configured. The first check runs
+// one interval after startup, so a restart never deactivates accounts on its
+// own.
+func (r *Runner) Run(ctx context.Context) {
+	if r.cfg.Interval <= 0 {
+		log.Debug().Msg("inactiveusers: scheduled check disabled by config")
+		return
+	}
+
+	jobs.Scheduled(jobs.InactiveUsers, r.cfg.Interval)
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.InactiveUsers, r.runOnce)
+		}
+	}
+}
+
+// runOnce finds the inactive users, deactivates them when configured and
+// mails the report. Errors are logged; lookup and deactivation errors are
+// also returned.
+func (r *Runner) runOnce() error {
+	candidates, err := Find(r.db, Cutoff(r.now(), r.cfg.Days))
+	if err != nil {
+		log.Error().Err(err).Msg("inactiveusers: failed to find inactive users")
+		return err
+	}
+
+	if len(candidates) == 0 {
+		log.Debug().Msg("inactiveusers: no inactive users")
+		return nil
+	}
+
+	deactivated := false
+
+	if r.cfg.AutoDeactivate {
+		if err = Deactivate(r.db, candidates, nil, ""); err != nil {
+			log.Error().Err(err).Msg("inactiveusers: failed to deactivate inactive users")
+		} else {
+			deactivated = true
+		}
+	}
+
+	log.Info().Int("users", len(candidates)).Bool("deactivated", deactivated).
+		Msg("inactiveusers: found inactive users")
+
+	r.report(candidates, deactivated)
+
+	return err
+}
+
+// report mails the result of a run to the configured recipients, or to every
+// user holding admin.users when none are configured.
+func (r *Runner) report(candidates []Candidate, deactivated bool) {
+	if r.mailer == nil {
+		return
+	}
+
+	recipients := r.cfg.Report
+	if len(recipients) == 0 {
+		admins, err := auth.NewService(r.db).GetUsersWithPermission(auth.PermAdminUsers)
+		if err != nil {
+			log.Error().Err(err).Msg("inactiveusers: failed to load report recipients")
+			return
+		}
+
+		for i := range admins {
+			if admins[i].Email != "" {
+				recipients = append(recipients, admins[i].Email)
+			}
+		}
+	}
+
+	subject, body := r.reportMessage(candidates, deactivated)
+
+	for _, to := range recipients {
+		if err := r.mailer.Send(to, subject, body); err != nil {
+			log.Error().Err(err).Str("to", to).Msg("inactiveusers: failed to send report")
+		}
+	}
+}
+
+func (r *Runner) reportMessage(candidates []Candidate, deactivated bool) (string, string) {
+	var b strings.Builder
+
+	if deactivated {
+		fmt.Fprintf(&b, "The following %d users had not logged in for %d days and were deactivated:\n\n",
+			len(candidates), r.cfg.Days)
+	} else {
+		fmt.Fprintf(&b, "The following %d users have not logged in for %d days:\n\n", len(candidates), r.cfg.Days)
+	}
+
+	for i := range candidates {
+		last := "never"
+		if candidates[i].LastLogin != nil {
+			last = candidates[i].LastLogin.UTC().Format("2006-01-02")
+		}
+
+		fmt.Fprintf(&b, "  %s <%s>, last login: %s\n", candidates[i].User.Username, candidates[i].User.Email, last)
+	}
+
+	fmt.Fprintf(&b, "\nReview inactive users at:\n\n%s\n", r.url+ReportPath)
+
+	subject := fmt.Sprintf("%s: %d inactive users", r.brand, len(candidates))
+	if deactivated {
+		subject = fmt.Sprintf("%s: %d inactive users deactivated", r.brand, len(candidates))
+	}
+
+	return subject, b.String()
+}
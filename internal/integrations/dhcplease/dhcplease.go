@@ -0,0 +1,417 @@
+// Package dhcplease imports the leases of DHCP servers into zones. Each
+// import configured under Admin → DHCP Imports periodically reads the active
+// leases of a Kea server (through its Control Agent API) or an ISC dhcpd lease
+// file, and keeps an A or AAAA record per client hostname in the forward zone
+// and a PTR record per address in the matching reverse zone. Records of a
+// name that the import did not create are conflicts, skipped or overwritten
+// by the policy of the import, and every run stores a reconciliation report.
+package dhcplease
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// Report is the reconciliation report of one import run.
+type Report struct {
+	StartedAt time.Time `json:"started_at"`
+	// Leases is the number of active leases read from the DHCP server.
+	Leases int `json:"leases"`
+	// Added, Removed and Unchanged count records.
+	Added     int        `json:"added"`
+	Removed   int        `json:"removed"`
+	Unchanged int        `json:"unchanged"`
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+	Skipped   []Skipped  `json:"skipped,omitempty"`
+}
+
+// Conflict is a record of a lease whose name already has records that the
+// import does not manage.
+type Conflict struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	// Existing are the records found at the name.
+	Existing []string `json:"existing"`
+	// Overwritten reports whether the records of the lease replaced them.
+	Overwritten bool   `json:"overwritten"`
+	Reason      string `json:"reason"`
+}
+
+// Skipped is a lease that got no records.
+type Skipped struct {
+	Hostname string `json:"hostname"`
+	Address  string `json:"address"`
+	Reason   string `json:"reason"`
+}
+
+// rrKey identifies an RRset.
+type rrKey struct {
+	zone, name, rtype string
+}
+
+// wanted is a record the leases call for.
+type wanted struct {
+	content  string
+	hostname string
+	expires  time.Time
+}
+
+// plan is the outcome of reconcile.
+type plan struct {
+	// patches and diffs hold the RRset changes by zone.
+	patches map[string][]pdnsapi.RRset
+	diffs   map[string]*activitylog.RecordsDiff
+	// records are the records the import manages once the patches are applied.
+	records []models.DHCPImportRecord
+	report  *Report
+}
+
+// reconcile compares the records the leases call for with the zones, which
+// hold the forward and reverse zones of imp by name, and the records the
+// import managed so far.
+func reconcile(imp *models.DHCPImport, leases []Lease, zones map[string]*pdnsapi.Zone,
+	managed []models.DHCPImportRecord, now time.Time,
+) *plan {
+	p := &plan{
+		patches: map[string][]pdnsapi.RRset{},
+		diffs:   map[string]*activitylog.RecordsDiff{},
+		report:  &Report{StartedAt: now, Leases: len(leases)},
+	}
+
+	want := p.wantedRecords(imp, leases)
+
+	owned := map[rrKey][]string{}
+
+	for _, rec := range managed {
+		if _, ok := zones[rec.ZoneName]; ok {
+			key := rrKey{rec.ZoneName, rec.Name, rec.Type}
+			owned[key] = append(owned[key], rec.Content)
+		}
+	}
+
+	keys := make([]rrKey, 0, len(want)+len(owned))
+	for key := range want {
+		keys = append(keys, key)
+	}
+
+	for key := range owned {
+		if _, ok := want[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.zone != b.zone {
+			return a.zone < b.zone
+		}
+
+		if a.name != b.name {
+			return a.name < b.name
+		}
+
+		return a.rtype < b.rtype
+	})
+
+	for _, key := range keys {
+		p.reconcileRRset(imp, zones[key.zone], key, want[key], owned[key])
+	}
+
+	return p
+}
+
+// wantedRecords returns the records the leases call for. Of several leases
+// of a hostname and address family, the one ending last wins.
+func (p *plan) wantedRecords(imp *models.DHCPImport, leases []Lease) map[rrKey][]wanted {
+	forwardZone := fqdn(imp.ForwardZone)
+	reverseZones := make([]string, 0, len(imp.ReverseZoneList()))
+
+	for _, zone := range imp.ReverseZoneList() {
+		reverseZones = append(reverseZones, fqdn(zone))
+	}
+
+	sorted := slices.Clone(leases)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Expires.After(sorted[j].Expires) })
+
+	want := map[rrKey][]wanted{}
+
+	for _, l := range sorted {
+		label := hostLabel(l.Hostname)
+		if label == "" {
+			reason := "no hostname"
+			if l.Hostname != "" {
+				reason = "hostname is not a valid DNS label"
+			}
+
+			p.skip(l, reason)
+
+			continue
+		}
+
+		name := label + "." + forwardZone
+		rtype := "A"
+
+		if l.Address.Is6() && !l.Address.Is4In6() {
+			rtype = "AAAA"
+		}
+
+		key := rrKey{forwardZone, name, rtype}
+		if len(want[key]) > 0 {
+			p.skip(l, "hostname also leased to "+want[key][0].content)
+			continue
+		}
+
+		want[key] = []wanted{{content: l.Address.Unmap().String(), hostname: l.Hostname, expires: l.Expires}}
+
+		ptrName := reverseName(l.Address)
+		if zone := longestZone(ptrName, reverseZones); zone != "" {
+			want[rrKey{zone, ptrName, "PTR"}] = []wanted{{content: name, hostname: l.Hostname, expires: l.Expires}}
+		}
+	}
+
+	return want
+}
+
+// skip reports a lease without records.
+func (p *plan) skip(l Lease, reason string) {
+	p.report.Skipped = append(p.report.Skipped, Skipped{
+		Hostname: l.Hostname,
+		Address:  l.Address.Unmap().String(),
+		Reason:   reason,
+	})
+}
+
+// reconcileRRset plans the RRset of key in zone, given the records the leases
+// want there and the records the import created there before.
+func (p *plan) reconcileRRset(imp *models.DHCPImport, zone *pdnsapi.Zone, key rrKey, want []wanted,
+	owned []string,
+) {
+	current, ttl, comments := rrset(zone, key.name, key.rtype)
+
+	var foreign []string
+
+	for _, content := range current {
+		if !containsFold(owned, content) {
+			foreign = append(foreign, content)
+		}
+	}
+
+	wantContents := make([]string, len(want))
+	for i, w := range want {
+		wantContents[i] = w.content
+	}
+
+	next := append(slices.Clone(foreign), wantContents...)
+	keep := want
+
+	var cname []string
+	if key.rtype != "PTR" {
+		cname, _, _ = rrset(zone, key.name, "CNAME")
+	}
+
+	switch {
+	case len(want) > 0 && len(cname) > 0:
+		p.conflict(key, want[0].content, cname, false, "name has a CNAME record")
+		next, keep = current, p.stillOwned(owned, current)
+	case len(want) > 0 && len(foreign) > 0 && imp.ConflictPolicy == models.DHCPConflictOverwrite:
+		p.conflict(key, want[0].content, foreign, true, "name has records not created by the import")
+		next = wantContents
+	case len(want) > 0 && len(foreign) > 0:
+		p.conflict(key, want[0].content, foreign, false, "name has records not created by the import")
+		next, keep = current, p.stillOwned(owned, current)
+	}
+
+	for _, w := range keep {
+		p.records = append(p.records, models.DHCPImportRecord{
+			ImportID:  imp.ID,
+			ZoneName:  key.zone,
+			Name:      key.name,
+			Type:      key.rtype,
+			Content:   w.content,
+			Hostname:  w.hostname,
+			ExpiresAt: w.expires,
+		})
+	}
+
+	added, removed := difference(next, current), difference(current, next)
+	p.report.Added += len(added)
+	p.report.Removed += len(removed)
+	p.report.Unchanged += len(keep) - len(added)
+
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	rr := pdnsapi.RRset{Name: pdnsapi.String(key.name), Type: pdnsapi.RRTypePtr(pdnsapi.RRType(key.rtype))}
+	entry := activitylog.RecordEntryDiff{Name: key.name, Type: key.rtype, Old: current, New: next}
+
+	switch {
+	case len(next) == 0:
+		rr.ChangeType = pdnsapi.ChangeTypePtr(pdnsapi.ChangeTypeDelete)
+		entry.Action, entry.OldTTL = "deleted", ttl
+	default:
+		if len(current) == 0 {
+			ttl = imp.TTL
+			entry.Action = "added"
+		} else {
+			entry.Action, entry.OldTTL = "modified", ttl
+		}
+
+		entry.NewTTL = ttl
+		rr.ChangeType = pdnsapi.ChangeTypePtr(pdnsapi.ChangeTypeReplace)
+		rr.TTL = pdnsapi.Uint32(ttl)
+		rr.Comments = comments
+
+		for _, content := range next {
+			rr.Records = append(rr.Records, pdnsapi.Record{
+				Content:  pdnsapi.String(content),
+				Disabled: pdnsapi.Bool(false),
+			})
+		}
+	}
+
+	p.patches[key.zone] = append(p.patches[key.zone], rr)
+
+	if p.diffs[key.zone] == nil {
+		p.diffs[key.zone] = &activitylog.RecordsDiff{}
+	}
+
+	p.diffs[key.zone].Records = append(p.diffs[key.zone].Records, entry)
+}
+
+// stillOwned returns the records the import created before that are still
+// in current; the import keeps managing them while a conflict lasts.
+func (p *plan) stillOwned(owned, current []string) []wanted {
+	var keep []wanted
+
+	for _, content := range owned {
+		if containsFold(current, content) {
+			keep = append(keep, wanted{content: content})
+		}
+	}
+
+	return keep
+}
+
+// conflict reports a conflict of the record content at key.
+func (p *plan) conflict(key rrKey, content string, existing []string, overwritten bool, reason string) {
+	p.report.Conflicts = append(p.report.Conflicts, Conflict{
+		Name:        key.name,
+		Type:        key.rtype,
+		Content:     content,
+		Existing:    existing,
+		Overwritten: overwritten,
+		Reason:      reason,
+	})
+}
+
+// rrset returns the contents, TTL and comments of the RRset name/rtype of
+// zone.
+func rrset(zone *pdnsapi.Zone, name, rtype string) ([]string, uint32, []pdnsapi.Comment) {
+	for i := range zone.RRsets {
+		rr := &zone.RRsets[i]
+		if rr.Name == nil || rr.Type == nil || !strings.EqualFold(*rr.Name, name) || string(*rr.Type) != rtype {
+			continue
+		}
+
+		contents := make([]string, 0, len(rr.Records))
+		for _, rec := range rr.Records {
+			contents = append(contents, pdnsapi.StringValue(rec.Content))
+		}
+
+		return contents, pdnsapi.Uint32Value(rr.TTL), rr.Comments
+	}
+
+	return nil, 0, nil
+}
+
+// difference returns the contents of a that are not in b.
+func difference(a, b []string) []string {
+	var out []string
+
+	for _, content := range a {
+		if !containsFold(b, content) {
+			out = append(out, content)
+		}
+	}
+
+	return out
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+// hostLabel returns the first label of the client hostname, lower-case, or
+// "" when it is not a valid DNS label.
+func hostLabel(hostname string) string {
+	label, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(hostname)), ".")
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return ""
+	}
+
+	for _, r := range label {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return ""
+		}
+	}
+
+	return label
+}
+
+// reverseName returns the PTR name of addr.
+func reverseName(addr netip.Addr) string {
+	addr = addr.Unmap()
+
+	var b strings.Builder
+
+	if addr.Is4() {
+		ip := addr.As4()
+		fmt.Fprintf(&b, "%d.%d.%d.%d.in-addr.arpa.", ip[3], ip[2], ip[1], ip[0])
+
+		return b.String()
+	}
+
+	ip := addr.As16()
+	for i := len(ip) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%x.%x.", ip[i]&0x0f, ip[i]>>4)
+	}
+
+	b.WriteString("ip6.arpa.")
+
+	return b.String()
+}
+
+// longestZone returns the most specific of zones that name belongs to, or "".
+func longestZone(name string, zones []string) string {
+	best := ""
+
+	for _, zone := range zones {
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(best) {
+			best = zone
+		}
+	}
+
+	return best
+}
+
+// fqdn returns name lower-case and fully qualified.
+func fqdn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name
+}
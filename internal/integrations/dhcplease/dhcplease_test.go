@@ -0,0 +1,291 @@
+package dhcplease
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+const (
+	testForward = "dhcp.example.com."
+	testReverse = "2.0.192.in-addr.arpa."
+)
+
+func testImport(policy models.DHCPConflictPolicy) *models.DHCPImport {
+	return &models.DHCPImport{
+		ID:             1,
+		Name:           "office",
+		Kind:           models.DHCPImportKea,
+		ForwardZone:    "dhcp.example.com",
+		ReverseZones:   "0.192.in-addr.arpa\n2.0.192.in-addr.arpa.",
+		TTL:            300,
+		ConflictPolicy: policy,
+	}
+}
+
+// testZone returns an empty zone with the given RRsets, each written as
+// name, type and contents.
+func testZone(name string, rrsets ...[]string) *pdnsapi.Zone {
+	zone := &pdnsapi.Zone{Name: pdnsapi.String(name)}
+
+	for _, rr := range rrsets {
+		set := pdnsapi.RRset{
+			Name: pdnsapi.String(rr[0]),
+			Type: pdnsapi.RRTypePtr(pdnsapi.RRType(rr[1])),
+			TTL:  pdnsapi.Uint32(3600),
+		}
+
+		for _, content := range rr[2:] {
+			set.Records = append(set.Records, pdnsapi.Record{Content: pdnsapi.String(content)})
+		}
+
+		zone.RRsets = append(zone.RRsets, set)
+	}
+
+	return zone
+}
+
+func testZones(forward, reverse *pdnsapi.Zone) map[string]*pdnsapi.Zone {
+	return map[string]*pdnsapi.Zone{
+		testForward:           forward,
+		"0.192.in-addr.arpa.": testZone("0.192.in-addr.arpa."),
+		testReverse:           reverse,
+	}
+}
+
+func lease(addr, hostname string, expires time.Duration) Lease {
+	return Lease{Address: netip.MustParseAddr(addr), Hostname: hostname, Expires: testNow.Add(expires)}
+}
+
+// patch returns the change type, TTL and contents of the patch of name/rtype
+// in zone, or ok false without one.
+func patch(p *plan, zone, name, rtype string) (changeType string, ttl uint32, contents []string, ok bool) {
+	for _, rr := range p.patches[zone] {
+		if *rr.Name != name || string(*rr.Type) != rtype {
+			continue
+		}
+
+		for _, rec := range rr.Records {
+			contents = append(contents, *rec.Content)
+		}
+
+		return string(*rr.ChangeType), pdnsapi.Uint32Value(rr.TTL), contents, true
+	}
+
+	return "", 0, nil, false
+}
+
+func TestReconcileAddsRecords(t *testing.T) {
+	leases := []Lease{
+		lease("192.0.2.10", "Laptop-42.corp.example", time.Hour),
+		lease("198.51.100.7", "printer", time.Hour),
+		lease("2001:db8::10", "laptop-42", time.Hour),
+	}
+
+	p := reconcile(testImport(models.DHCPConflictSkip), leases,
+		testZones(testZone(testForward), testZone(testReverse)), nil, testNow)
+
+	changeType, ttl, contents, ok := patch(p, testForward, "laptop-42."+testForward, "A")
+	if !ok || changeType != "REPLACE" || ttl != 300 || !slices.Equal(contents, []string{"192.0.2.10"}) {
+		t.Errorf("A patch = %s %d %v %v, want REPLACE 300 [192.0.2.10]", changeType, ttl, contents, ok)
+	}
+
+	if _, _, contents, ok = patch(p, testForward, "laptop-42."+testForward, "AAAA"); !ok ||
+		!slices.Equal(contents, []string{"2001:db8::10"}) {
+		t.Errorf("AAAA patch = %v %v, want [2001:db8::10]", contents, ok)
+	}
+
+	// The PTR goes to the most specific reverse zone.
+	if _, _, contents, ok = patch(p, testReverse, "10."+testReverse, "PTR"); !ok ||
+		!slices.Equal(contents, []string{"laptop-42." + testForward}) {
+		t.Errorf("PTR patch = %v %v, want [laptop-42.%s]", contents, ok, testForward)
+	}
+
+	if len(p.patches["0.192.in-addr.arpa."]) != 0 {
+		t.Errorf("patches of the less specific reverse zone = %d, want 0", len(p.patches["0.192.in-addr.arpa."]))
+	}
+
+	// 198.51.100.7 is outside the reverse zones and only gets an A record.
+	if _, _, _, ok = patch(p, testForward, "printer."+testForward, "A"); !ok {
+		t.Error("printer has no A patch")
+	}
+
+	if p.report.Added != 4 || p.report.Removed != 0 || len(p.report.Conflicts) != 0 {
+		t.Errorf("report = %+v, want 4 added", p.report)
+	}
+
+	if len(p.records) != 4 {
+		t.Errorf("managed records = %d, want 4", len(p.records))
+	}
+
+	if p.diffs[testForward] == nil || len(p.diffs[testForward].Records) != 3 {
+		t.Errorf("diff of %s = %+v, want 3 records", testForward, p.diffs[testForward])
+	}
+}
+
+func TestReconcileUnchangedAndExpired(t *testing.T) {
+	forward := testZone(testForward,
+		[]string{"laptop-42." + testForward, "A", "192.0.2.10"},
+		[]string{"gone." + testForward, "A", "192.0.2.11"})
+	reverse := testZone(testReverse,
+		[]string{"10." + testReverse, "PTR", "laptop-42." + testForward},
+		[]string{"11." + testReverse, "PTR", "gone." + testForward})
+
+	managed := []models.DHCPImportRecord{
+		{ZoneName: testForward, Name: "laptop-42." + testForward, Type: "A", Content: "192.0.2.10"},
+		{ZoneName: testReverse, Name: "10." + testReverse, Type: "PTR", Content: "laptop-42." + testForward},
+		{ZoneName: testForward, Name: "gone." + testForward, Type: "A", Content: "192.0.2.11"},
+		{ZoneName: testReverse, Name: "11." + testReverse, Type: "PTR", Content: "gone." + testForward},
+	}
+
+	p := reconcile(testImport(models.DHCPConflictSkip), []Lease{lease("192.0.2.10", "laptop-42", time.Hour)},
+		testZones(forward, reverse), managed, testNow)
+
+	if _, _, _, ok := patch(p, testForward, "laptop-42."+testForward, "A"); ok {
+		t.Error("unchanged A record is patched")
+	}
+
+	if changeType, _, _, ok := patch(p, testForward, "gone."+testForward, "A"); !ok || changeType != "DELETE" {
+		t.Errorf("expired A patch = %s %v, want DELETE", changeType, ok)
+	}
+
+	if changeType, _, _, ok := patch(p, testReverse, "11."+testReverse, "PTR"); !ok || changeType != "DELETE" {
+		t.Errorf("expired PTR patch = %s %v, want DELETE", changeType, ok)
+	}
+
+	if p.report.Added != 0 || p.report.Removed != 2 || p.report.Unchanged != 2 {
+		t.Errorf("report = %+v, want 2 removed and 2 unchanged", p.report)
+	}
+
+	if len(p.records) != 2 {
+		t.Errorf("managed records = %d, want 2", len(p.records))
+	}
+}
+
+func TestReconcileAddressChange(t *testing.T) {
+	forward := testZone(testForward, []string{"laptop-42." + testForward, "A", "192.0.2.10"})
+	managed := []models.DHCPImportRecord{
+		{ZoneName: testForward, Name: "laptop-42." + testForward, Type: "A", Content: "192.0.2.10"},
+	}
+
+	p := reconcile(testImport(models.DHCPConflictSkip), []Lease{lease("192.0.2.20", "laptop-42", time.Hour)},
+		testZones(forward, testZone(testReverse)), managed, testNow)
+
+	changeType, ttl, contents, ok := patch(p, testForward, "laptop-42."+testForward, "A")
+	if !ok || changeType != "REPLACE" || ttl != 3600 || !slices.Equal(contents, []string{"192.0.2.20"}) {
+		t.Errorf("A patch = %s %d %v %v, want REPLACE 3600 [192.0.2.20]", changeType, ttl, contents, ok)
+	}
+
+	if len(p.report.Conflicts) != 0 {
+		t.Errorf("conflicts = %+v, want none", p.report.Conflicts)
+	}
+}
+
+func TestReconcileConflicts(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      models.DHCPConflictPolicy
+		rrset       []string
+		wantPatch   bool
+		overwritten bool
+		wantManaged int
+	}{
+		{
+			name:        "skip keeps foreign records",
+			policy:      models.DHCPConflictSkip,
+			rrset:       []string{"laptop-42." + testForward, "A", "192.0.2.99"},
+			wantManaged: 1, // only the PTR record
+		},
+		{
+			name:        "overwrite replaces foreign records",
+			policy:      models.DHCPConflictOverwrite,
+			rrset:       []string{"laptop-42." + testForward, "A", "192.0.2.99"},
+			wantPatch:   true,
+			overwritten: true,
+			wantManaged: 2,
+		},
+		{
+			name:        "CNAME is never overwritten",
+			policy:      models.DHCPConflictOverwrite,
+			rrset:       []string{"laptop-42." + testForward, "CNAME", "www.example.com."},
+			wantManaged: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := reconcile(testImport(tt.policy), []Lease{lease("192.0.2.10", "laptop-42", time.Hour)},
+				testZones(testZone(testForward, tt.rrset), testZone(testReverse)), nil, testNow)
+
+			_, _, contents, ok := patch(p, testForward, "laptop-42."+testForward, "A")
+			if ok != tt.wantPatch {
+				t.Fatalf("A patched = %v, want %v", ok, tt.wantPatch)
+			}
+
+			if ok && !slices.Equal(contents, []string{"192.0.2.10"}) {
+				t.Errorf("A patch contents = %v, want [192.0.2.10]", contents)
+			}
+
+			if len(p.report.Conflicts) != 1 {
+				t.Fatalf("conflicts = %+v, want 1", p.report.Conflicts)
+			}
+
+			c := p.report.Conflicts[0]
+			if c.Content != "192.0.2.10" || c.Overwritten != tt.overwritten || !slices.Equal(c.Existing, tt.rrset[2:]) {
+				t.Errorf("conflict = %+v", c)
+			}
+
+			if len(p.records) != tt.wantManaged {
+				t.Errorf("managed records = %d, want %d", len(p.records), tt.wantManaged)
+			}
+		})
+	}
+}
+
+func TestReconcileSkippedLeases(t *testing.T) {
+	leases := []Lease{
+		lease("192.0.2.10", "laptop-42", time.Hour),
+		lease("192.0.2.11", "laptop-42", 2*time.Hour),
+		lease("192.0.2.12", "", time.Hour),
+		lease("192.0.2.13", "bad_name", time.Hour),
+	}
+
+	p := reconcile(testImport(models.DHCPConflictSkip), leases,
+		testZones(testZone(testForward), testZone(testReverse)), nil, testNow)
+
+	// The lease ending last gets the name.
+	if _, _, contents, _ := patch(p, testForward, "laptop-42."+testForward, "A"); !slices.Equal(contents,
+		[]string{"192.0.2.11"}) {
+		t.Errorf("A patch contents = %v, want [192.0.2.11]", contents)
+	}
+
+	want := []Skipped{
+		{Hostname: "laptop-42", Address: "192.0.2.10", Reason: "hostname also leased to 192.0.2.11"},
+		{Hostname: "", Address: "192.0.2.12", Reason: "no hostname"},
+		{Hostname: "bad_name", Address: "192.0.2.13", Reason: "hostname is not a valid DNS label"},
+	}
+	if !slices.Equal(p.report.Skipped, want) {
+		t.Errorf("skipped = %+v, want %+v", p.report.Skipped, want)
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.10":        "10.2.0.192.in-addr.arpa.",
+		"::ffff:192.0.2.10": "10.2.0.192.in-addr.arpa.",
+		"2001:db8::1":       "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+	}
+
+	for addr, want := range tests {
+		if got := reverseName(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("reverseName(%s) = %s, want %s", addr, got, want)
+		}
+	}
+}
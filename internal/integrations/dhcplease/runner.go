@@ -0,0 +1,265 @@
+package dhcplease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// checkInterval is how often due imports are looked for.
+	checkInterval = time.Minute
+
+	// importTimeout bounds the requests of one import run.
+	importTimeout = 2 * time.Minute
+)
+
+// httpClient reads the leases of Kea servers.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Runner runs the enabled imports at their interval.
+type Runner struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewRunner returns a Runner for the imports in db.
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{db: db, now: time.Now}
+}
+
+// Run runs due imports every checkInterval until ctx is canceled.
+func (r *Runner) Run(ctx context.Context) {
+	jobs.Scheduled(jobs.DHCPImports, checkInterval)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.DHCPImports, func() error { return r.runOnce(ctx) })
+		}
+	}
+}
+
+// runOnce runs the enabled imports whose interval has passed. Each import is
+// claimed first, so several instances sharing a database run it only once.
+func (r *Runner) runOnce(ctx context.Context) error {
+	var imports []models.DHCPImport
+	if err := r.db.Where("enabled = ?", true).Order("id").Find(&imports).Error; err != nil {
+		log.Error().Err(err).Msg("dhcplease: failed to load imports")
+		return err
+	}
+
+	now := r.now()
+
+	var errs []error
+
+	for i := range imports {
+		imp := &imports[i]
+		if imp.LastRunAt != nil && now.Before(imp.LastRunAt.Add(imp.Interval())) {
+			continue
+		}
+
+		ok, err := claim(r.db, imp, now)
+		if err != nil {
+			log.Error().Err(err).Str("import", imp.Name).Msg("dhcplease: failed to claim import")
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if _, err = Import(ctx, r.db, imp, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// claim sets the last run of imp to now unless another instance ran it
+// within its interval.
+func claim(db *gorm.DB, imp *models.DHCPImport, now time.Time) (bool, error) {
+	res := db.Model(&models.DHCPImport{}).
+		Where("id = ? AND (last_run_at IS NULL OR last_run_at <= ?)", imp.ID, now.Add(-imp.Interval())).
+		Update("last_run_at", now)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+// Import runs imp once at now: it reads the leases, applies the record
+// changes through the PowerDNS API and stores the report and error of the
+// run with the import.
+func Import(ctx context.Context, db *gorm.DB, imp *models.DHCPImport, now time.Time) (*Report, error) {
+	ctx, cancel := context.WithTimeout(ctx, importTimeout)
+	defer cancel()
+
+	report, err := importLeases(ctx, db, imp, now)
+
+	updates := map[string]any{"last_run_at": now, "last_error": ""}
+
+	if err != nil {
+		updates["last_error"] = truncate(err.Error(), 500)
+
+		log.Error().Err(err).Str("import", imp.Name).Msg("dhcplease: import failed")
+	}
+
+	if report != nil {
+		data, _ := json.Marshal(report)
+		updates["last_report"] = string(data)
+
+		log.Info().Str("import", imp.Name).Int("leases", report.Leases).Int("added", report.Added).
+			Int("removed", report.Removed).Int("conflicts", len(report.Conflicts)).Msg("dhcplease: import done")
+	}
+
+	if dbErr := db.Model(imp).Updates(updates).Error; dbErr != nil {
+		log.Error().Err(dbErr).Str("import", imp.Name).Msg("dhcplease: failed to store import result")
+	}
+
+	return report, err
+}
+
+// importLeases reads the leases of imp, reconciles them with its zones and
+// applies the changes. Zones whose patch fails keep their managed records, so
+// the next run tries again; the error joins those failures.
+func importLeases(ctx context.Context, db *gorm.DB, imp *models.DHCPImport, now time.Time) (*Report, error) {
+	if powerdns.Engine.Client == nil {
+		return nil, powerdns.ErrClientNotInitialized
+	}
+
+	leases, err := fetch(ctx, httpClient, imp, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leases: %w", err)
+	}
+
+	zones := map[string]*pdnsapi.Zone{}
+
+	for _, name := range append([]string{imp.ForwardZone}, imp.ReverseZoneList()...) {
+		name = fqdn(name)
+
+		zone, err := powerdns.Engine.Zones.Get(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch zone %s: %w", name, err)
+		}
+
+		zones[name] = zone
+	}
+
+	var managed []models.DHCPImportRecord
+	if err = db.Where("import_id = ?", imp.ID).Find(&managed).Error; err != nil {
+		return nil, err
+	}
+
+	p := reconcile(imp, leases, zones, managed, now)
+
+	zoneNames := make([]string, 0, len(p.patches))
+	for name := range p.patches {
+		zoneNames = append(zoneNames, name)
+	}
+
+	sort.Strings(zoneNames)
+
+	failed := map[string]bool{}
+
+	var errs []error
+
+	for _, name := range zoneNames {
+		if err = powerdns.Engine.Records.Patch(ctx, name, &pdnsapi.RRsets{Sets: p.patches[name]}); err != nil {
+			failed[name] = true
+			errs = append(errs, fmt.Errorf("failed to update zone %s: %w", name, err))
+
+			continue
+		}
+
+		zoneindex.Default.RefreshZone(ctx, name)
+
+		activitylog.Record(&activitylog.Entry{
+			DB:           db,
+			Username:     imp.Name,
+			Action:       activitylog.ActionRecordChanged,
+			ResourceType: activitylog.ResourceTypeZone,
+			ResourceName: name,
+			Details:      p.diffs[name],
+			AuthMethod:   auth.MethodDHCPImport,
+		})
+	}
+
+	records := make([]models.DHCPImportRecord, 0, len(p.records))
+
+	for _, rec := range p.records {
+		if !failed[rec.ZoneName] {
+			records = append(records, rec)
+		}
+	}
+
+	for _, rec := range managed {
+		if failed[rec.ZoneName] {
+			rec.ID = 0
+			records = append(records, rec)
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("import_id = ?", imp.ID).Delete(&models.DHCPImportRecord{}).Error; err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			return nil
+		}
+
+		return tx.CreateInBatches(records, 100).Error
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to store managed records: %w", err))
+	}
+
+	return p.report, errors.Join(errs...)
+}
+
+// truncate shortens s to at most n bytes.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return s[:n]
+}
+
+// LastReport returns the report of the last run of imp, or nil before the
+// first run.
+func LastReport(imp *models.DHCPImport) *Report {
+	if imp.LastReport == "" {
+		return nil
+	}
+
+	var report Report
+	if json.Unmarshal([]byte(imp.LastReport), &report) != nil {
+		return nil
+	}
+
+	return &report
+}
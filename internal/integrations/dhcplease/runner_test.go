@@ -0,0 +1,208 @@
+package dhcplease
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/pdnsserver"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+// fakePowerDNS serves an empty dhcp.example.com. and 2.0.192.in-addr.arpa.
+// and keeps the bodies of the PATCH requests by zone.
+type fakePowerDNS struct {
+	mu      sync.Mutex
+	patches map[string][]string
+}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	zone := strings.TrimPrefix(r.URL.Path, "/api/v1/servers/localhost/zones/")
+
+	switch {
+	case r.URL.Path == "/api/v1/servers/localhost":
+		_, _ = io.WriteString(w, `{"id":"localhost","version":"4.9.0"}`)
+	case zone != testForward && zone != testReverse:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"Not Found"}`)
+	case r.Method == http.MethodGet:
+		_, _ = io.WriteString(w, `{"name":"`+zone+`","kind":"Native","rrsets":[]}`)
+	case r.Method == http.MethodPatch:
+		body, _ := io.ReadAll(r.Body)
+
+		f.mu.Lock()
+		f.patches[zone] = append(f.patches[zone], string(body))
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// newImportFixture returns a database with an enabled Kea import whose
+// Control Agent serves the lease of laptop-42, and the fake PowerDNS.
+func newImportFixture(t *testing.T) (*gorm.DB, *models.DHCPImport, *fakePowerDNS) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Setting{}, &models.DHCPImport{}, &models.DHCPImportRecord{},
+		&models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	pdns := &fakePowerDNS{patches: map[string][]string{}}
+
+	pdnsSrv := httptest.NewServer(pdns)
+	t.Cleanup(pdnsSrv.Close)
+
+	settings := &pdnsserver.Settings{APIServerURL: pdnsSrv.URL, APIKey: "secret", VHost: "localhost"}
+	if err = settings.Save(db); err != nil {
+		t.Fatalf("failed to save PowerDNS settings: %v", err)
+	}
+
+	prev := powerdns.Engine
+	t.Cleanup(func() { powerdns.Engine = prev })
+
+	if err = powerdns.Open(db); err != nil {
+		t.Fatalf("failed to open PowerDNS client: %v", err)
+	}
+
+	kea := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `[{"result":0,"arguments":{"leases":[`+
+			`{"ip-address":"192.0.2.10","hostname":"laptop-42","cltt":1699999000,"valid-lft":3600,"state":0}]}}]`)
+	}))
+	t.Cleanup(kea.Close)
+
+	imp := testImport(models.DHCPConflictSkip)
+	imp.ReverseZones = testReverse
+	imp.URL = kea.URL
+	imp.IntervalMinutes = 5
+	imp.Enabled = true
+
+	if err = db.Create(imp).Error; err != nil {
+		t.Fatalf("failed to create import: %v", err)
+	}
+
+	return db, imp, pdns
+}
+
+func TestImport(t *testing.T) {
+	db, imp, pdns := newImportFixture(t)
+
+	report, err := Import(context.Background(), db, imp, testNow)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	if report.Leases != 1 || report.Added != 2 {
+		t.Errorf("report = %+v, want 1 lease and 2 records added", report)
+	}
+
+	if len(pdns.patches[testForward]) != 1 || !strings.Contains(pdns.patches[testForward][0], `"192.0.2.10"`) {
+		t.Errorf("patches of %s = %v", testForward, pdns.patches[testForward])
+	}
+
+	if len(pdns.patches[testReverse]) != 1 ||
+		!strings.Contains(pdns.patches[testReverse][0], `"laptop-42.dhcp.example.com."`) {
+		t.Errorf("patches of %s = %v", testReverse, pdns.patches[testReverse])
+	}
+
+	var records []models.DHCPImportRecord
+	db.Order("type").Find(&records)
+
+	if len(records) != 2 || records[0].Type != "A" || records[0].Hostname != "laptop-42" ||
+		records[1].Type != "PTR" {
+		t.Errorf("managed records = %+v", records)
+	}
+
+	var stored models.DHCPImport
+	db.First(&stored, imp.ID)
+
+	if stored.LastRunAt == nil || !stored.LastRunAt.Equal(testNow) || stored.LastError != "" {
+		t.Errorf("last run = %v %q, want %v without error", stored.LastRunAt, stored.LastError, testNow)
+	}
+
+	if last := LastReport(&stored); last == nil || last.Added != 2 {
+		t.Errorf("LastReport = %+v, want 2 added", last)
+	}
+
+	var entries []models.ActivityLog
+	db.Find(&entries)
+
+	if len(entries) != 2 || entries[0].Action != string(activitylog.ActionRecordChanged) ||
+		entries[0].Username != "office" || entries[0].AuthMethod != auth.MethodDHCPImport {
+		t.Errorf("activity log = %+v, want 2 record changes by office", entries)
+	}
+}
+
+func TestImportStoresError(t *testing.T) {
+	db, imp, _ := newImportFixture(t)
+
+	imp.ForwardZone = "missing.example.com."
+
+	if _, err := Import(context.Background(), db, imp, testNow); err == nil {
+		t.Fatal("Import of a missing zone succeeded")
+	}
+
+	var stored models.DHCPImport
+	db.First(&stored, imp.ID)
+
+	if !strings.Contains(stored.LastError, "missing.example.com.") {
+		t.Errorf("last error = %q, want the missing zone", stored.LastError)
+	}
+}
+
+func TestRunOnceHonoursInterval(t *testing.T) {
+	db, _, pdns := newImportFixture(t)
+
+	now := testNow
+	r := &Runner{db: db, now: func() time.Time { return now }}
+
+	for _, step := range []time.Duration{0, time.Minute, 3 * time.Minute} {
+		now = now.Add(step)
+
+		if err := r.runOnce(context.Background()); err != nil {
+			t.Fatalf("runOnce: %v", err)
+		}
+	}
+
+	// The first run added the records; the next ones, within the interval,
+	// did not run.
+	if len(pdns.patches[testForward]) != 1 {
+		t.Errorf("patches of %s = %d, want 1", testForward, len(pdns.patches[testForward]))
+	}
+
+	var stored models.DHCPImport
+	db.First(&stored)
+
+	if !stored.LastRunAt.Equal(testNow) {
+		t.Errorf("last run = %v, want %v", stored.LastRunAt, testNow)
+	}
+
+	now = testNow.Add(5 * time.Minute)
+	if err := r.runOnce(context.Background()); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+
+	db.First(&stored)
+
+	if !stored.LastRunAt.Equal(now) {
+		t.Errorf("last run after the interval = %v, want %v", stored.LastRunAt, now)
+	}
+}
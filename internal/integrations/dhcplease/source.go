@@ -0,0 +1,259 @@
+package dhcplease
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+const (
+	// keaResultSuccess and keaResultEmpty are the result codes of Kea
+	// commands that returned leases or found none.
+	keaResultSuccess = 0
+	keaResultEmpty   = 3
+
+	// keaStateDefault is the state of leases in use.
+	keaStateDefault = 0
+
+	// maxResponseSize bounds the Control Agent responses and lease files read.
+	maxResponseSize = 64 << 20
+)
+
+var (
+	// ErrUnknownKind is returned for imports of an unknown DHCP server kind.
+	ErrUnknownKind = errors.New("unknown DHCP server kind")
+
+	errKeaResponse = errors.New("unexpected Kea response")
+)
+
+// Lease is an active lease with the client hostname.
+type Lease struct {
+	Address netip.Addr
+	// Hostname is the hostname the client sent, possibly fully qualified or
+	// empty.
+	Hostname string
+	Expires  time.Time
+}
+
+// fetch returns the active leases of the DHCP server of imp at now.
+func fetch(ctx context.Context, client *http.Client, imp *models.DHCPImport, now time.Time) ([]Lease, error) {
+	switch imp.Kind {
+	case models.DHCPImportKea:
+		leases, err := fetchKea(ctx, client, imp, "lease4-get-all", "dhcp4", now)
+		if err != nil || !imp.IPv6 {
+			return leases, err
+		}
+
+		leases6, err := fetchKea(ctx, client, imp, "lease6-get-all", "dhcp6", now)
+
+		return append(leases, leases6...), err
+	case models.DHCPImportISC:
+		f, err := os.Open(imp.LeaseFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return parseISCLeases(io.LimitReader(f, maxResponseSize), now)
+	default:
+		return nil, ErrUnknownKind
+	}
+}
+
+// keaLease is a lease in the response of lease4-get-all and lease6-get-all.
+type keaLease struct {
+	IPAddress string `json:"ip-address"`
+	Hostname  string `json:"hostname"`
+	CLTT      int64  `json:"cltt"`
+	ValidLft  int64  `json:"valid-lft"`
+	State     int    `json:"state"`
+}
+
+// keaResponse is the answer of one server to a Control Agent command.
+type keaResponse struct {
+	Result    int    `json:"result"`
+	Text      string `json:"text"`
+	Arguments struct {
+		Leases []keaLease `json:"leases"`
+	} `json:"arguments"`
+}
+
+// fetchKea sends command to service through the Control Agent of imp and
+// returns the leases in use at now.
+func fetchKea(ctx context.Context, client *http.Client, imp *models.DHCPImport, command, service string,
+	now time.Time,
+) ([]Lease, error) {
+	body, err := json.Marshal(map[string]any{"command": command, "service": []string{service}})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, imp.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if imp.Username != "" {
+		req.SetBasicAuth(imp.Username, imp.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s returned HTTP %d", errKeaResponse, command, resp.StatusCode)
+	}
+
+	var answers []keaResponse
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&answers); err != nil {
+		return nil, fmt.Errorf("%w: %w", errKeaResponse, err)
+	}
+
+	if len(answers) != 1 {
+		return nil, fmt.Errorf("%w: %d answers to %s", errKeaResponse, len(answers), command)
+	}
+
+	switch answers[0].Result {
+	case keaResultSuccess:
+	case keaResultEmpty:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: %s: %s", errKeaResponse, command, answers[0].Text)
+	}
+
+	leases := make([]Lease, 0, len(answers[0].Arguments.Leases))
+
+	for _, l := range answers[0].Arguments.Leases {
+		addr, err := netip.ParseAddr(l.IPAddress)
+		expires := time.Unix(l.CLTT+l.ValidLft, 0)
+
+		if err != nil || l.State != keaStateDefault || !expires.After(now) {
+			continue
+		}
+
+		leases = append(leases, Lease{Address: addr, Hostname: l.Hostname, Expires: expires})
+	}
+
+	return leases, nil
+}
+
+// parseISCLeases reads the IPv4 leases of an ISC dhcpd lease file and
+// returns those active at now. The file is a journal: a later entry of an
+// address replaces the earlier ones.
+func parseISCLeases(r io.Reader, now time.Time) ([]Lease, error) {
+	var (
+		byAddr  = map[netip.Addr]*Lease{}
+		order   []netip.Addr
+		current *Lease
+		active  bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if current == nil {
+			fields := strings.Fields(line)
+			if len(fields) == 3 && fields[0] == "lease" && fields[2] == "{" {
+				if addr, err := netip.ParseAddr(fields[1]); err == nil {
+					current, active = &Lease{Address: addr}, false
+				}
+			}
+
+			continue
+		}
+
+		if line == "}" {
+			if _, ok := byAddr[current.Address]; !ok {
+				order = append(order, current.Address)
+			}
+
+			if !active {
+				current.Expires = time.Time{}
+			}
+
+			byAddr[current.Address] = current
+			current = nil
+
+			continue
+		}
+
+		statement := strings.TrimSuffix(line, ";")
+
+		switch {
+		case statement == "binding state active":
+			active = true
+		case strings.HasPrefix(statement, "ends "):
+			current.Expires = parseISCTime(strings.TrimPrefix(statement, "ends "))
+		case strings.HasPrefix(statement, "client-hostname "):
+			current.Hostname, _ = strconv.Unquote(strings.TrimPrefix(statement, "client-hostname "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var leases []Lease
+
+	for _, addr := range order {
+		if l := byAddr[addr]; l.Expires.After(now) {
+			leases = append(leases, *l)
+		}
+	}
+
+	return leases, nil
+}
+
+// farFuture stands for the end of leases that never expire.
+var farFuture = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// parseISCTime parses the end of a lease: "never", "epoch <seconds>" or
+// "<weekday> <yyyy/mm/dd> <hh:mm:ss>" in UTC. Unparsable times return the
+// zero time, so the lease counts as expired.
+func parseISCTime(s string) time.Time {
+	fields := strings.Fields(s)
+
+	switch {
+	case len(fields) == 1 && fields[0] == "never":
+		return farFuture
+	case len(fields) >= 2 && fields[0] == "epoch":
+		secs, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return time.Time{}
+		}
+
+		return time.Unix(secs, 0)
+	case len(fields) >= 3:
+		t, err := time.Parse("2006/01/02 15:04:05", fields[1]+" "+fields[2])
+		if err != nil {
+			return time.Time{}
+		}
+
+		return t
+	default:
+		return time.Time{}
+	}
+}
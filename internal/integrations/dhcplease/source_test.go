@@ -0,0 +1,123 @@
+package dhcplease
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+const iscLeases = `# The format of this file is documented in the dhcpd.leases(5) manual page.
+authoring-byte-order little-endian;
+
+lease 192.0.2.10 {
+  starts 2 2023/11/14 21:00:00;
+  ends 2 2023/11/14 21:30:00;
+  binding state active;
+  client-hostname "old-name";
+}
+lease 192.0.2.11 {
+  ends 2 2023/11/14 23:00:00;
+  binding state free;
+  client-hostname "released";
+}
+lease 192.0.2.12 {
+  ends never;
+  binding state active;
+  client-hostname "printer";
+}
+lease 192.0.2.13 {
+  ends epoch 1699990000;
+  binding state active;
+  client-hostname "expired";
+}
+lease 192.0.2.10 {
+  starts 2 2023/11/14 22:00:00;
+  ends 2 2023/11/14 23:00:00;
+  binding state active;
+  client-hostname "laptop-42";
+}
+`
+
+func TestParseISCLeases(t *testing.T) {
+	leases, err := parseISCLeases(strings.NewReader(iscLeases), testNow)
+	if err != nil {
+		t.Fatalf("parseISCLeases: %v", err)
+	}
+
+	if len(leases) != 2 {
+		t.Fatalf("leases = %+v, want 2", leases)
+	}
+
+	// The later entry of 192.0.2.10 replaces the earlier one.
+	if got := leases[0]; got.Address.String() != "192.0.2.10" || got.Hostname != "laptop-42" ||
+		!got.Expires.Equal(time.Date(2023, 11, 14, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("leases[0] = %+v", got)
+	}
+
+	if got := leases[1]; got.Address.String() != "192.0.2.12" || got.Hostname != "printer" ||
+		!got.Expires.Equal(farFuture) {
+		t.Errorf("leases[1] = %+v", got)
+	}
+}
+
+func TestFetchKea(t *testing.T) {
+	var commands []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "kea" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Command string   `json:"command"`
+			Service []string `json:"service"`
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		commands = append(commands, req.Command+" "+strings.Join(req.Service, ","))
+
+		if req.Command == "lease6-get-all" {
+			_, _ = io.WriteString(w, `[{"result":3,"text":"0 IPv6 lease(s) found."}]`)
+			return
+		}
+
+		// 192.0.2.11 is declined (state 1) and 192.0.2.12 has expired.
+		_, _ = io.WriteString(w, `[{"result":0,"text":"3 IPv4 lease(s) found.","arguments":{"leases":[`+
+			`{"ip-address":"192.0.2.10","hostname":"laptop-42.","cltt":1699999000,"valid-lft":3600,"state":0},`+
+			`{"ip-address":"192.0.2.11","hostname":"declined","cltt":1699999000,"valid-lft":3600,"state":1},`+
+			`{"ip-address":"192.0.2.12","hostname":"expired","cltt":1699990000,"valid-lft":3600,"state":0}]}}]`)
+	}))
+	t.Cleanup(srv.Close)
+
+	imp := &models.DHCPImport{
+		Kind: models.DHCPImportKea, URL: srv.URL, Username: "kea", Password: "secret", IPv6: true,
+	}
+
+	leases, err := fetch(context.Background(), srv.Client(), imp, testNow)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	if want := []string{"lease4-get-all dhcp4", "lease6-get-all dhcp6"}; strings.Join(commands, ";") !=
+		strings.Join(want, ";") {
+		t.Errorf("commands = %v, want %v", commands, want)
+	}
+
+	if len(leases) != 1 || leases[0].Address.String() != "192.0.2.10" || leases[0].Hostname != "laptop-42." ||
+		!leases[0].Expires.Equal(time.Unix(1699999000+3600, 0)) {
+		t.Errorf("leases = %+v, want 192.0.2.10 only", leases)
+	}
+
+	imp.Password = "wrong"
+	if _, err = fetch(context.Background(), srv.Client(), imp, testNow); err == nil {
+		t.Error("fetch with a wrong password succeeded")
+	}
+}
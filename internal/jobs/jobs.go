@@ -0,0 +1,124 @@
+// Package jobs instruments background work with Prometheus metrics: the
+// scheduled runners (zone index refresh, update and inactive user checks) and
+// the fire-and-forget tasks such as notification mails.
+//
+// The series are meant for alerting. A scheduled job is stuck or failing when
+//
+//	time() - background_job_last_success_timestamp_seconds
+//	  > 2 * background_job_interval_seconds
+//
+// and work is piling up when background_job_queue_depth keeps growing.
+package jobs
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Job names used by the application.
+const (
+	ZoneIndexRefresh = "zoneindex_refresh"
+	UpdateCheck      = "update_check"
+	InactiveUsers    = "inactive_users"
+	Mail             = "mail"
+	RecordSchedules  = "record_schedules"
+	ZoneBatch        = "zone_batch"
+	ZoneDeletions    = "zone_deletions"
+	ZoneStats        = "zone_stats"
+	CertRenewal      = "cert_renewal"
+	Snapshots        = "snapshots"
+	ChatNotify       = "chat_notify"
+	DHCPImports      = "dhcp_imports"
+	DelegationChecks = "delegation_checks"
+	ExpiryReminders  = "expiry_reminders"
+	ServerChecks     = "server_checks"
+	RecordTrash      = "record_trash"
+	NameserverSets   = "nameserver_sets"
+	TenantUsage      = "tenant_usage"
+)
+
+// Result label values.
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "background_job_queue_depth",
+		Help: "Number of background jobs started but not yet finished, by job.",
+	}, []string{"job"})
+
+	duration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "background_job_duration_seconds",
+		Help:    "Duration of background job runs, by job and result.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+	}, []string{"job", "result"})
+
+	failures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "background_job_failures_total",
+		Help: "Number of failed background job runs, by job.",
+	}, []string{"job"})
+
+	lastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "background_job_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful run, by job.",
+	}, []string{"job"})
+
+	interval = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "background_job_interval_seconds",
+		Help: "Configured interval of scheduled jobs, by job.",
+	}, []string{"job"})
+)
+
+// Scheduled records that job runs every every. Call it when the scheduler
+// starts; the interval is exported for staleness alerts.
+func Scheduled(job string, every time.Duration) {
+	interval.WithLabelValues(job).Set(every.Seconds())
+	// Initialize the series so a job that never succeeds still shows up.
+	failures.WithLabelValues(job)
+}
+
+// Run runs fn as job and records its duration and result.
+func Run(job string, fn func() error) error {
+	gauge := queueDepth.WithLabelValues(job)
+	gauge.Inc()
+	defer gauge.Dec()
+
+	return observe(job, fn)
+}
+
+// Go runs fn as job in a new goroutine. The job counts towards the queue depth
+// from the call until fn returns. fn logs its own errors; they are only
+// counted here.
+func Go(job string, fn func() error) {
+	gauge := queueDepth.WithLabelValues(job)
+	gauge.Inc()
+
+	go func() {
+		defer gauge.Dec()
+
+		_ = observe(job, fn)
+	}()
+}
+
+// observe runs fn and records its duration and result.
+func observe(job string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+
+	result := resultSuccess
+	if err != nil {
+		result = resultFailure
+
+		failures.WithLabelValues(job).Inc()
+	} else {
+		lastSuccess.WithLabelValues(job).SetToCurrentTime()
+	}
+
+	duration.WithLabelValues(job, result).Observe(time.Since(start).Seconds())
+
+	return err
+}
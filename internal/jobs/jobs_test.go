@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRunRecordsResult(t *testing.T) {
+	const job = "test_run"
+
+	if err := Run(job, func() error { return nil }); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got := testutil.ToFloat64(lastSuccess.WithLabelValues(job)); got == 0 {
+		t.Error("last success timestamp not set")
+	}
+
+	errFailed := errors.New("failed")
+	if err := Run(job, func() error { return errFailed }); !errors.Is(err, errFailed) {
+		t.Fatalf("Run() error = %v, want %v", err, errFailed)
+	}
+
+	if got := testutil.ToFloat64(failures.WithLabelValues(job)); got != 1 {
+		t.Errorf("failures = %v, want 1", got)
+	}
+
+	if got := testutil.CollectAndCount(duration, "background_job_duration_seconds"); got < 2 {
+		t.Errorf("duration series = %d, want success and failure", got)
+	}
+}
+
+func TestQueueDepth(t *testing.T) {
+	const job = "test_queue"
+
+	release := make(chan struct{})
+
+	Go(job, func() error {
+		<-release
+		return nil
+	})
+
+	if got := testutil.ToFloat64(queueDepth.WithLabelValues(job)); got != 1 {
+		t.Errorf("queue depth while running = %v, want 1", got)
+	}
+
+	_ = Run(job, func() error {
+		if got := testutil.ToFloat64(queueDepth.WithLabelValues(job)); got != 2 {
+			t.Errorf("queue depth with two jobs = %v, want 2", got)
+		}
+
+		close(release)
+
+		return nil
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(queueDepth.WithLabelValues(job)) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("queue depth did not drop to 0")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
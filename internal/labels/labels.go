@@ -0,0 +1,269 @@
+// Package labels keeps the key/value labels of zones and RRsets, such as
+// owner=web-team or ticket=JIRA-123, and selects zones and RRsets by them.
+// Labels are stored by the application only and never sent to PowerDNS.
+package labels
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+const (
+	// MaxKeyLength and MaxValueLength bound the length of keys and values.
+	MaxKeyLength   = 63
+	MaxValueLength = 255
+
+	// MaxLabels bounds the labels of one zone or RRset.
+	MaxLabels = 50
+)
+
+// keyRegex matches a lower-case label key.
+var keyRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]*[a-z0-9])?$`)
+
+// Labels are the labels of a zone or RRset, values by key.
+type Labels map[string]string
+
+// Target is what labels are attached to: an RRset, or the zone itself when
+// Name and Type are empty.
+type Target struct {
+	// Name is the canonical name of the RRset with trailing dot.
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// IsZone reports whether t is the zone itself.
+func (t Target) IsZone() bool { return t.Name == "" && t.Type == "" }
+
+// Parse reads labels written as key=value and separated by commas or line
+// breaks, e.g. "owner=web-team, ticket=JIRA-123". A key without = gets an
+// empty value. Keys are lower-cased.
+func Parse(text string) (Labels, error) {
+	l := Labels{}
+
+	for item := range strings.FieldsFuncSeq(text, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(item, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if _, dup := l[key]; dup {
+			return nil, fmt.Errorf("label %q is given twice", key)
+		}
+
+		l[key] = strings.TrimSpace(value)
+	}
+
+	return Normalize(l)
+}
+
+// Normalize returns l with lower-cased keys and trimmed values, or an error
+// naming the first invalid label.
+func Normalize(l map[string]string) (Labels, error) {
+	out := make(Labels, len(l))
+
+	for key, value := range l {
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch {
+		case len(key) > MaxKeyLength || !keyRegex.MatchString(key):
+			return nil, fmt.Errorf("label key %q must be at most %d letters, digits, '.', '_', '/' or '-', "+
+				"starting and ending with a letter or digit", key, MaxKeyLength)
+		case len(value) > MaxValueLength:
+			return nil, fmt.Errorf("the value of label %q is longer than %d characters", key, MaxValueLength)
+		case strings.ContainsFunc(value, func(r rune) bool { return r == ',' || unicode.IsControl(r) }):
+			return nil, fmt.Errorf("the value of label %q must not contain commas or control characters", key)
+		}
+
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("label %q is given twice", key)
+		}
+
+		out[key] = value
+	}
+
+	if len(out) > MaxLabels {
+		return nil, fmt.Errorf("at most %d labels are allowed", MaxLabels)
+	}
+
+	return out, nil
+}
+
+// Keys returns the keys of l, sorted.
+func (l Labels) Keys() []string {
+	return slices.Sorted(maps.Keys(l))
+}
+
+// String returns the labels as key=value, sorted by key and separated by
+// commas, the form Parse reads. Labels with an empty value are just the key.
+func (l Labels) String() string {
+	items := make([]string, 0, len(l))
+
+	for _, key := range l.Keys() {
+		if l[key] == "" {
+			items = append(items, key)
+		} else {
+			items = append(items, key+"="+l[key])
+		}
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// Selector selects zones or RRsets by one label: by key alone, or by key and
+// value.
+type Selector struct {
+	Key   string
+	Value string
+	// AnyValue is set when the selector names the key alone.
+	AnyValue bool
+}
+
+// ParseSelector reads a selector written as key or key=value. An empty
+// string is the zero Selector, which matches everything.
+func ParseSelector(text string) (Selector, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Selector{}, nil
+	}
+
+	key, value, hasValue := strings.Cut(text, "=")
+
+	l, err := Normalize(Labels{key: value})
+	if err != nil {
+		return Selector{}, err
+	}
+
+	key = strings.ToLower(strings.TrimSpace(key))
+
+	return Selector{Key: key, Value: l[key], AnyValue: !hasValue}, nil
+}
+
+// IsZero reports whether s selects everything.
+func (s Selector) IsZero() bool { return s.Key == "" }
+
+// Matches reports whether l has the label of s.
+func (s Selector) Matches(l Labels) bool {
+	if s.IsZero() {
+		return true
+	}
+
+	value, ok := l[s.Key]
+
+	return ok && (s.AnyValue || value == s.Value)
+}
+
+// String returns s in the form ParseSelector reads.
+func (s Selector) String() string {
+	if s.AnyValue || s.IsZero() {
+		return s.Key
+	}
+
+	return s.Key + "=" + s.Value
+}
+
+// Zone returns the labels of the zone zoneName itself.
+func Zone(db *gorm.DB, zoneName string) (Labels, error) {
+	var rows []models.Label
+	if err := db.Where("zone_name = ? AND name = '' AND type = ''", zoneName).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	l := make(Labels, len(rows))
+	for _, row := range rows {
+		l[row.Key] = row.Value
+	}
+
+	return l, nil
+}
+
+// Zones returns the labels of all zones that have any, by zone name.
+func Zones(db *gorm.DB) (map[string]Labels, error) {
+	var rows []models.Label
+	if err := db.Where("name = '' AND type = ''").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := map[string]Labels{}
+
+	for _, row := range rows {
+		if out[row.ZoneName] == nil {
+			out[row.ZoneName] = Labels{}
+		}
+
+		out[row.ZoneName][row.Key] = row.Value
+	}
+
+	return out, nil
+}
+
+// Records returns the labels of the RRsets of zoneName that have any.
+func Records(db *gorm.DB, zoneName string) (map[Target]Labels, error) {
+	var rows []models.Label
+	if err := db.Where("zone_name = ? AND name <> ''", zoneName).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := map[Target]Labels{}
+
+	for _, row := range rows {
+		t := Target{Name: row.Name, Type: row.Type}
+		if out[t] == nil {
+			out[t] = Labels{}
+		}
+
+		out[t][row.Key] = row.Value
+	}
+
+	return out, nil
+}
+
+// Set replaces the labels of target in zoneName with l and returns the labels
+// it had before. An empty l removes all labels of target.
+func Set(db *gorm.DB, zoneName string, target Target, l Labels) (Labels, error) {
+	old := Labels{}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		where := tx.Where("zone_name = ? AND name = ? AND type = ?", zoneName, target.Name, target.Type)
+
+		var rows []models.Label
+		if err := where.Find(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			old[row.Key] = row.Value
+		}
+
+		err := tx.Where("zone_name = ? AND name = ? AND type = ?", zoneName, target.Name, target.Type).
+			Delete(&models.Label{}).Error
+		if err != nil || len(l) == 0 {
+			return err
+		}
+
+		rows = make([]models.Label, 0, len(l))
+		for _, key := range l.Keys() {
+			rows = append(rows, models.Label{
+				ZoneName: zoneName, Name: target.Name, Type: target.Type, Key: key, Value: l[key],
+			})
+		}
+
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return old, nil
+}
@@ -0,0 +1,124 @@
+package labels
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Label{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse("Owner = web-team,\nticket=JIRA-123\r\n\nlegacy")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := Labels{"owner": "web-team", "ticket": "JIRA-123", "legacy": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %v, want %v", got, want)
+	}
+
+	if s := got.String(); s != "legacy, owner=web-team, ticket=JIRA-123" {
+		t.Errorf("String = %q", s)
+	}
+
+	if again, err := Parse(got.String()); err != nil || !reflect.DeepEqual(again, got) {
+		t.Errorf("Parse(String()) = %v, %v", again, err)
+	}
+
+	for _, bad := range []string{"owner=a\nOWNER=b", "-owner=x", "has space=x", "=x", "owner=a\tb"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSelector(t *testing.T) {
+	l := Labels{"owner": "web-team", "legacy": ""}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"owner", true},
+		{"Owner=web-team", true},
+		{"owner=db-team", false},
+		{"legacy=", true},
+		{"ticket", false},
+	}
+
+	for _, tt := range tests {
+		s, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q): %v", tt.selector, err)
+		}
+
+		if got := s.Matches(l); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+
+	if _, err := ParseSelector("bad key"); err == nil {
+		t.Error("ParseSelector(bad key) succeeded")
+	}
+}
+
+func TestStore(t *testing.T) {
+	db := newTestDB(t)
+
+	www := Target{Name: "www.example.com.", Type: "A"}
+
+	if _, err := Set(db, "example.com.", Target{}, Labels{"owner": "web-team"}); err != nil {
+		t.Fatalf("Set zone: %v", err)
+	}
+
+	if _, err := Set(db, "example.com.", www, Labels{"ticket": "JIRA-1"}); err != nil {
+		t.Fatalf("Set record: %v", err)
+	}
+
+	old, err := Set(db, "example.com.", www, Labels{"ticket": "JIRA-2", "env": "prod"})
+	if err != nil || !reflect.DeepEqual(old, Labels{"ticket": "JIRA-1"}) {
+		t.Fatalf("Set record again = %v, %v", old, err)
+	}
+
+	if zone, err := Zone(db, "example.com."); err != nil || !reflect.DeepEqual(zone, Labels{"owner": "web-team"}) {
+		t.Errorf("Zone = %v, %v", zone, err)
+	}
+
+	records, err := Records(db, "example.com.")
+	if err != nil || !reflect.DeepEqual(records, map[Target]Labels{www: {"ticket": "JIRA-2", "env": "prod"}}) {
+		t.Errorf("Records = %v, %v", records, err)
+	}
+
+	zones, err := Zones(db)
+	if err != nil || !reflect.DeepEqual(zones, map[string]Labels{"example.com.": {"owner": "web-team"}}) {
+		t.Errorf("Zones = %v, %v", zones, err)
+	}
+
+	if _, err = Set(db, "example.com.", www, nil); err != nil {
+		t.Fatalf("Set(nil): %v", err)
+	}
+
+	if records, _ = Records(db, "example.com."); len(records) != 0 {
+		t.Errorf("Records after clearing = %v", records)
+	}
+}
@@ -0,0 +1,405 @@
+package logger
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Audit formats.
+const (
+	// AuditFormatJSON writes one JSON object per event.
+	AuditFormatJSON = "json"
+	// AuditFormatCEF writes ArcSight Common Event Format lines.
+	AuditFormatCEF = "cef"
+)
+
+// Audit outcomes.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+const (
+	auditVendor        = "GoPowerDNS-Admin"
+	auditDefaultTag    = "gopowerdns-admin"
+	auditDefaultSize   = 100
+	auditQueueSize     = 1024
+	auditSyslogTimeout = 5 * time.Second
+
+	// syslog severities of successful and failed actions.
+	syslogNotice  = 5
+	syslogWarning = 4
+)
+
+// syslogFacilities maps the facility names accepted in AuditSyslog.Facility
+// to their codes.
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "daemon": 3, "auth": 4, "syslog": 5, "authpriv": 10,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogFacility returns the code of a syslog facility name; "" is authpriv.
+func SyslogFacility(name string) (int, bool) {
+	if name == "" {
+		name = "authpriv"
+	}
+
+	code, ok := syslogFacilities[strings.ToLower(name)]
+
+	return code, ok
+}
+
+// AuditEvent is one entry of the audit stream.
+type AuditEvent struct {
+	ID           uint64    `json:"id"`
+	Time         time.Time `json:"time"`
+	User         string    `json:"user"`
+	UserID       *uint64   `json:"user_id,omitempty"`
+	Action       string    `json:"action"`
+	Outcome      string    `json:"outcome"`
+	ResourceType string    `json:"resource_type,omitempty"`
+	ResourceName string    `json:"resource_name,omitempty"`
+	IPAddress    string    `json:"ip,omitempty"`
+	AuthMethod   string    `json:"auth_method,omitempty"`
+	APIKeyID     *uint64   `json:"api_key_id,omitempty"`
+	// Details is the JSON context of the entry. It is only written in the
+	// JSON format.
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+// AuditWriter writes audit events to the destinations of an Audit config.
+// Events are written in the background in the order they were passed to
+// Write.
+type AuditWriter struct {
+	format   string
+	version  string
+	file     io.WriteCloser
+	syslog   *syslogSender
+	queue    chan *AuditEvent
+	done     chan struct{}
+	closeOne sync.Once
+}
+
+// NewAuditWriter opens the audit file of cfg and returns a writer for it and
+// the syslog endpoint. version is reported as the product version in CEF.
+// The syslog endpoint is connected on the first event and reconnected after
+// errors, so that an unreachable SIEM does not prevent the start.
+func NewAuditWriter(cfg *Audit, version string) (*AuditWriter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	w := &AuditWriter{
+		format:  strings.ToLower(cfg.Format),
+		version: version,
+		queue:   make(chan *AuditEvent, auditQueueSize),
+		done:    make(chan struct{}),
+	}
+
+	if w.format == "" {
+		w.format = AuditFormatJSON
+	}
+
+	if cfg.Syslog.Address != "" {
+		w.syslog = newSyslogSender(&cfg.Syslog)
+	}
+
+	if cfg.File != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o750); err != nil {
+			return nil, err
+		}
+
+		size := cfg.MaxSize
+		if size == 0 {
+			size = auditDefaultSize
+		}
+
+		w.file = &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    size,
+			MaxAge:     cfg.MaxAge,
+			MaxBackups: cfg.MaxBackups,
+		}
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+// Validate checks the format and the syslog endpoint of a.
+func (a *Audit) Validate() error {
+	switch strings.ToLower(a.Format) {
+	case "", AuditFormatJSON, AuditFormatCEF:
+	default:
+		return fmt.Errorf("%w: %q", ErrAuditFormat, a.Format)
+	}
+
+	if a.Syslog.Address == "" {
+		return nil
+	}
+
+	switch strings.ToLower(a.Syslog.Network) {
+	case "", "udp", "tcp", "tls":
+	default:
+		return fmt.Errorf("%w: %q", ErrAuditNetwork, a.Syslog.Network)
+	}
+
+	if _, _, err := net.SplitHostPort(a.Syslog.Address); err != nil {
+		return fmt.Errorf("%w: %q", ErrAuditAddress, a.Syslog.Address)
+	}
+
+	if _, ok := SyslogFacility(a.Syslog.Facility); !ok {
+		return fmt.Errorf("%w: %q", ErrAuditFacility, a.Syslog.Facility)
+	}
+
+	return nil
+}
+
+// Write queues e. When the destinations cannot keep up and the queue is
+// full, e is dropped and a warning logged.
+func (w *AuditWriter) Write(e *AuditEvent) {
+	select {
+	case w.queue <- e:
+	default:
+		log.Warn().Uint64("id", e.ID).Str("action", e.Action).Msg("audit: queue full, event dropped")
+	}
+}
+
+// Close writes the queued events and closes the destinations. Write must not
+// be called afterwards.
+func (w *AuditWriter) Close() {
+	w.closeOne.Do(func() {
+		close(w.queue)
+		<-w.done
+
+		if w.file != nil {
+			_ = w.file.Close()
+		}
+
+		if w.syslog != nil {
+			w.syslog.close()
+		}
+	})
+}
+
+func (w *AuditWriter) run() {
+	defer close(w.done)
+
+	for e := range w.queue {
+		line, err := w.line(e)
+		if err != nil {
+			log.Warn().Err(err).Uint64("id", e.ID).Msg("audit: failed to format event")
+			continue
+		}
+
+		if w.file != nil {
+			if _, err = io.WriteString(w.file, line+"\n"); err != nil {
+				log.Warn().Err(err).Uint64("id", e.ID).Msg("audit: failed to write event to file")
+			}
+		}
+
+		if w.syslog != nil {
+			if err = w.syslog.send(e, line); err != nil {
+				log.Warn().Err(err).Uint64("id", e.ID).Msg("audit: failed to send event to syslog")
+			}
+		}
+	}
+}
+
+func (w *AuditWriter) line(e *AuditEvent) (string, error) {
+	if w.format == AuditFormatCEF {
+		return FormatCEF(e, w.version), nil
+	}
+
+	b, err := json.Marshal(e)
+
+	return string(b), err
+}
+
+// FormatCEF returns e as a CEF line. The resource type, resource name and
+// authentication method are the custom strings cs1 to cs3, the API key is
+// cn1.
+func FormatCEF(e *AuditEvent, version string) string {
+	severity := 3
+	if e.Outcome == AuditOutcomeFailure {
+		severity = 5
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|",
+		cefHeader(auditVendor), cefHeader(auditVendor), cefHeader(version),
+		cefHeader(e.Action), cefHeader(strings.ReplaceAll(e.Action, "_", " ")), severity)
+
+	ext := []string{
+		"rt", strconv.FormatInt(e.Time.UnixMilli(), 10),
+		"externalId", strconv.FormatUint(e.ID, 10),
+		"act", e.Action,
+		"outcome", e.Outcome,
+		"suser", e.User,
+	}
+
+	if e.UserID != nil {
+		ext = append(ext, "suid", strconv.FormatUint(*e.UserID, 10))
+	}
+
+	apiKeyID := ""
+	if e.APIKeyID != nil {
+		apiKeyID = strconv.FormatUint(*e.APIKeyID, 10)
+	}
+
+	ext = append(ext, "src", e.IPAddress)
+
+	for _, custom := range []struct{ key, label, value string }{
+		{"cs1", "resourceType", e.ResourceType},
+		{"cs2", "resourceName", e.ResourceName},
+		{"cs3", "authMethod", e.AuthMethod},
+		{"cn1", "apiKeyId", apiKeyID},
+	} {
+		if custom.value != "" {
+			ext = append(ext, custom.key+"Label", custom.label, custom.key, custom.value)
+		}
+	}
+
+	sep := ""
+
+	for i := 0; i < len(ext); i += 2 {
+		if ext[i+1] == "" {
+			continue
+		}
+
+		b.WriteString(sep + ext[i] + "=" + cefExtension(ext[i+1]))
+		sep = " "
+	}
+
+	return b.String()
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefHeader(s string) string { return cefHeaderEscaper.Replace(s) }
+
+func cefExtension(s string) string { return cefExtensionEscaper.Replace(s) }
+
+// syslogSender sends RFC 5424 messages to a remote syslog endpoint.
+type syslogSender struct {
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string
+	conn     net.Conn
+}
+
+// newSyslogSender returns the sender of a validated cfg.
+func newSyslogSender(cfg *AuditSyslog) *syslogSender {
+	facility, _ := SyslogFacility(cfg.Facility)
+
+	s := &syslogSender{
+		network:  strings.ToLower(cfg.Network),
+		address:  cfg.Address,
+		facility: facility,
+		tag:      cfg.Tag,
+	}
+
+	if s.network == "" {
+		s.network = "udp"
+	}
+
+	if s.tag == "" {
+		s.tag = auditDefaultTag
+	}
+
+	if s.hostname, _ = os.Hostname(); s.hostname == "" {
+		s.hostname = "-"
+	}
+
+	return s
+}
+
+// send sends line as the message of e, reconnecting once if the connection
+// failed.
+func (s *syslogSender) send(e *AuditEvent, line string) error {
+	severity := syslogNotice
+	if e.Outcome == AuditOutcomeFailure {
+		severity = syslogWarning
+	}
+
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		s.facility*8+severity, e.Time.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		s.hostname, s.tag, os.Getpid(), msgID(e.Action), line)
+	if s.network != "udp" {
+		msg += "\n"
+	}
+
+	var err error
+
+	for range 2 {
+		if s.conn == nil {
+			if s.conn, err = s.dial(); err != nil {
+				continue
+			}
+		}
+
+		_ = s.conn.SetWriteDeadline(time.Now().Add(auditSyslogTimeout))
+
+		if _, err = io.WriteString(s.conn, msg); err == nil {
+			return nil
+		}
+
+		s.close()
+	}
+
+	return err
+}
+
+func (s *syslogSender) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: auditSyslogTimeout}
+
+	if s.network == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", s.address, &tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	return dialer.Dial(s.network, s.address)
+}
+
+func (s *syslogSender) close() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// msgID returns action as an RFC 5424 MSGID: at most 32 printable characters.
+func msgID(action string) string {
+	if action == "" {
+		return "-"
+	}
+
+	action = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+
+		return r
+	}, action)
+
+	return action[:min(len(action), 32)]
+}
@@ -0,0 +1,132 @@
+package logger_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/logger"
+)
+
+func auditEvent() *logger.AuditEvent {
+	userID := uint64(7)
+
+	return &logger.AuditEvent{
+		ID:           42,
+		Time:         time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		User:         "alice",
+		UserID:       &userID,
+		Action:       "record_changed",
+		Outcome:      logger.AuditOutcomeSuccess,
+		ResourceType: "zone",
+		ResourceName: "example.com.",
+		IPAddress:    "192.0.2.10",
+		AuthMethod:   "session",
+		Details:      json.RawMessage(`{"records":[]}`),
+	}
+}
+
+func TestFormatCEF(t *testing.T) {
+	e := auditEvent()
+	e.ResourceName = "a=b\nc"
+
+	got := logger.FormatCEF(e, "v1.2|3")
+	want := `CEF:0|GoPowerDNS-Admin|GoPowerDNS-Admin|v1.2\|3|record_changed|record changed|3|` +
+		`rt=1772366400000 externalId=42 act=record_changed outcome=success suser=alice suid=7 src=192.0.2.10 ` +
+		`cs1Label=resourceType cs1=zone cs2Label=resourceName cs2=a\=b\nc cs3Label=authMethod cs3=session`
+
+	if got != want {
+		t.Errorf("FormatCEF() =\n%s\nwant\n%s", got, want)
+	}
+
+	e.Outcome = logger.AuditOutcomeFailure
+	if got = logger.FormatCEF(e, ""); !strings.Contains(got, "|record changed|5|") {
+		t.Errorf("FormatCEF(failure) = %s, want severity 5", got)
+	}
+}
+
+func TestAuditWriterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+
+	w, err := logger.NewAuditWriter(&logger.Audit{File: path}, "dev")
+	if err != nil {
+		t.Fatalf("NewAuditWriter() error = %v", err)
+	}
+
+	w.Write(auditEvent())
+	w.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err = json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("audit line %q is not JSON: %v", b, err)
+	}
+
+	if got["action"] != "record_changed" || got["user"] != "alice" || got["details"] == nil {
+		t.Errorf("audit line = %s", b)
+	}
+}
+
+func TestAuditWriterSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := logger.NewAuditWriter(&logger.Audit{
+		Format: logger.AuditFormatCEF,
+		Syslog: logger.AuditSyslog{Address: conn.LocalAddr().String(), Facility: "local4"},
+	}, "dev")
+	if err != nil {
+		t.Fatalf("NewAuditWriter() error = %v", err)
+	}
+
+	e := auditEvent()
+	e.Outcome = logger.AuditOutcomeFailure
+
+	w.Write(e)
+	w.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 4096)
+
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// local4 (20) * 8 + warning (4)
+	got := string(buf[:n])
+	if !strings.HasPrefix(got, "<164>1 2026-03-01T12:00:00.000000Z ") ||
+		!strings.Contains(got, " gopowerdns-admin ") || !strings.Contains(got, " record_changed - CEF:0|") {
+		t.Errorf("syslog message = %q", got)
+	}
+}
+
+func TestAuditValidate(t *testing.T) {
+	for _, tt := range []struct {
+		cfg  logger.Audit
+		want error
+	}{
+		{logger.Audit{File: "audit.log"}, nil},
+		{logger.Audit{Format: "leef"}, logger.ErrAuditFormat},
+		{logger.Audit{Syslog: logger.AuditSyslog{Address: "siem.example.com"}}, logger.ErrAuditAddress},
+		{logger.Audit{Syslog: logger.AuditSyslog{Address: "siem:514", Network: "quic"}}, logger.ErrAuditNetwork},
+		{logger.Audit{Syslog: logger.AuditSyslog{Address: "siem:514", Facility: "mail"}}, logger.ErrAuditFacility},
+	} {
+		if err := tt.cfg.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("Validate(%+v) = %v, want %v", tt.cfg, err, tt.want)
+		}
+	}
+}
@@ -12,6 +12,18 @@ var (
 
 	// ErrServiceNameIsEmpty is returned if Log.ServiceName was not defined.
 	ErrServiceNameIsEmpty = errors.New("config Log.ServiceName can not be empty")
+
+	// ErrAuditFormat is returned if log.audit.format is neither json nor cef.
+	ErrAuditFormat = errors.New("log.audit.format must be json or cef")
+
+	// ErrAuditNetwork is returned if log.audit.syslog.network is not udp, tcp or tls.
+	ErrAuditNetwork = errors.New("log.audit.syslog.network must be udp, tcp or tls")
+
+	// ErrAuditAddress is returned if log.audit.syslog.address is not host:port.
+	ErrAuditAddress = errors.New("log.audit.syslog.address must be host:port")
+
+	// ErrAuditFacility is returned for an unknown log.audit.syslog.facility.
+	ErrAuditFacility = errors.New("log.audit.syslog.facility is not a known syslog facility")
 )
 
 // ErrorHandler implements a custom error handler.
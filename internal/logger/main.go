@@ -103,6 +103,9 @@ func Init(cfg *Log) error {
 		log.Logger = zerolog.New(mw).Hook(ph).With().Timestamp().Logger()
 	}
 
+	InstallSampler()
+	SetSampling(cfg.Sampling)
+
 	return nil
 }
 
@@ -192,3 +195,15 @@ func NewConsoleWriter(cfg *Log) io.Writer {
 
 	return &lw
 }
+
+// SetLevel changes the global log level at runtime.
+func SetLevel(level string) error {
+	logLevel, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("loglevel %s is not supported", level))
+	}
+
+	zerolog.SetGlobalLevel(logLevel)
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// sampler samples the debug and trace messages of the global logger; see
+// SetSampling.
+var sampler = &levelSampler{}
+
+// levelSampler keeps the first of every n debug and trace messages and all
+// messages of higher levels.
+type levelSampler struct {
+	n       atomic.Uint32
+	counter atomic.Uint32
+}
+
+// Sample implements zerolog.Sampler.
+func (s *levelSampler) Sample(l zerolog.Level) bool {
+	n := s.n.Load()
+	if n <= 1 || l > zerolog.DebugLevel {
+		return true
+	}
+
+	return s.counter.Add(1)%n == 1
+}
+
+// InstallSampler makes the global logger apply the sampling set with
+// SetSampling. Call it before other goroutines log; Init installs it.
+func InstallSampler() {
+	log.Logger = log.Logger.Sample(sampler)
+}
+
+// SetSampling keeps only the first of every n debug and trace messages, so
+// that debug logging can be turned on under production load. 0 and 1 keep
+// all messages.
+func SetSampling(n uint32) {
+	sampler.n.Store(n)
+	sampler.counter.Store(0)
+}
+
+// Sampling returns the sampling set with SetSampling.
+func Sampling() uint32 {
+	return sampler.n.Load()
+}
@@ -0,0 +1,54 @@
+package logger_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/logger"
+)
+
+func TestSetSampling(t *testing.T) {
+	var buf bytes.Buffer
+
+	level, global := zerolog.GlobalLevel(), log.Logger
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+
+	t.Cleanup(func() {
+		zerolog.SetGlobalLevel(level)
+		logger.SetSampling(0)
+
+		log.Logger = global
+	})
+
+	log.Logger = zerolog.New(&buf)
+	logger.InstallSampler()
+	logger.SetSampling(3)
+
+	for range 6 {
+		log.Debug().Msg("debug")
+		log.Info().Msg("info")
+	}
+
+	if got := strings.Count(buf.String(), `"level":"debug"`); got != 2 {
+		t.Errorf("kept %d of 6 debug messages, want 2", got)
+	}
+
+	if got := strings.Count(buf.String(), `"level":"info"`); got != 6 {
+		t.Errorf("kept %d of 6 info messages, want 6", got)
+	}
+
+	buf.Reset()
+	logger.SetSampling(0)
+
+	for range 6 {
+		log.Debug().Msg("debug")
+	}
+
+	if got := strings.Count(buf.String(), `"level":"debug"`); got != 6 {
+		t.Errorf("kept %d of 6 debug messages without sampling, want 6", got)
+	}
+}
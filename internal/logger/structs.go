@@ -69,6 +69,10 @@ type Log struct {
 	LogLevel string // info, warn, error.
 	LogEnv   string
 
+	// Sampling keeps only the first of every Sampling debug and trace
+	// messages; 0 and 1 keep all.
+	Sampling uint32
+
 	// EnableAccessLogToConsole if true any feed service having a webservice, will start to log to console.
 	// Does not overrule flag Console.Enabled!
 	// If Console.Enabled is false, still no access log output to the console will be shown.
@@ -90,4 +94,37 @@ type Log struct {
 
 	// DataDog
 	DataDog DataDog
+
+	// Audit streams the activity log to a SIEM.
+	Audit Audit `toml:"audit"`
+}
+
+// Audit configures the audit stream: every activity log entry is written as a
+// JSON or CEF line to File and/or sent to a remote syslog endpoint.
+type Audit struct {
+	// Format is "json" (default) or "cef".
+	Format string `toml:"format"`
+
+	// File is the path of the audit file, rotated by size like the log files.
+	File       string `toml:"file"`
+	MaxSize    int    `toml:"maxsize"` // megabytes, default 100
+	MaxBackups int    `toml:"maxbackups"`
+	MaxAge     int    `toml:"maxage"` // days
+
+	Syslog AuditSyslog `toml:"syslog"`
+}
+
+// Enabled reports whether the audit stream has a destination.
+func (a *Audit) Enabled() bool {
+	return a.File != "" || a.Syslog.Address != ""
+}
+
+// AuditSyslog configures the remote syslog endpoint of the audit stream.
+// Messages are sent in RFC 5424 format; over TCP and TLS they are separated
+// by newlines.
+type AuditSyslog struct {
+	Network  string `toml:"network"`  // udp (default), tcp or tls
+	Address  string `toml:"address"`  // host:port
+	Facility string `toml:"facility"` // default authpriv
+	Tag      string `toml:"tag"`      // APP-NAME, default gopowerdns-admin
 }
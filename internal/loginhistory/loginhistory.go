@@ -0,0 +1,284 @@
+// Package loginhistory records sign-in attempts in the login_events table for
+// the login history under Profile → Security and the admin report. A
+// successful login is flagged as suspicious when it comes from a country the
+// user never signed in from before or follows a burst of failed attempts.
+// Events older than the configured retention are removed as new ones arrive.
+package loginhistory
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// Providers an attempt is recorded for.
+const (
+	ProviderLocal = "local"
+	ProviderLDAP  = "ldap"
+	ProviderOIDC  = "oidc"
+	ProviderTOTP  = "totp"
+)
+
+// Flags of suspicious logins.
+const (
+	// FlagNewCountry marks a login from a country the user has not signed in
+	// from before.
+	FlagNewCountry = "new_country"
+	// FlagAfterFailures marks a login that follows failureThreshold or more
+	// failed attempts on the same username within failureWindow.
+	FlagAfterFailures = "after_failures"
+)
+
+const (
+	failureWindow    = time.Hour
+	failureThreshold = 5
+
+	// purgeInterval is how often Record removes expired events.
+	purgeInterval = time.Hour
+
+	// maxUserAgentLen is the longest user agent stored (see models.LoginEvent).
+	maxUserAgentLen = 512
+)
+
+var (
+	// settings holds the config set by Configure.
+	settings atomic.Pointer[config.LoginHistory]
+	// lastPurge is the Unix time expired events were last removed.
+	lastPurge atomic.Int64
+)
+
+// Configure sets the retention and the country header.
+func Configure(cfg config.LoginHistory) {
+	settings.Store(&cfg)
+}
+
+// Attempt is a sign-in attempt to record.
+type Attempt struct {
+	DB *gorm.DB
+	// UserID is nil when the username is unknown.
+	UserID   *uint64
+	Username string
+	Provider string
+	Success  bool
+	// Reason is why a failed attempt was rejected.
+	Reason    string
+	IPAddress string
+	UserAgent string
+	Country   string
+}
+
+// FromRequest returns an attempt carrying the client details of c.
+func FromRequest(c fiber.Ctx, db *gorm.DB) *Attempt {
+	return &Attempt{
+		DB:        db,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Country:   country(c),
+	}
+}
+
+// Succeeded sets the user of a successful attempt and returns a.
+func (a *Attempt) Succeeded(user *models.User, provider string) *Attempt {
+	userID := user.ID
+	a.UserID = &userID
+	a.Username = user.Username
+	a.Provider = provider
+	a.Success = true
+
+	return a
+}
+
+// Failed sets the username and reason of a failed attempt and returns a.
+func (a *Attempt) Failed(username, provider string, reason error) *Attempt {
+	a.Username = username
+	a.Provider = provider
+	a.Reason = reason.Error()
+
+	return a
+}
+
+// country returns the country code the trusted proxy in front of the
+// application set in the configured header, or "" when unknown.
+func country(c fiber.Ctx) string {
+	cfg := settings.Load()
+	if cfg == nil || cfg.CountryHeader == "" || !c.IsProxyTrusted() {
+		return ""
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(c.Get(cfg.CountryHeader)))
+
+	// "XX" is the common marker for an unknown country.
+	if len(code) != 2 || code == "XX" {
+		return ""
+	}
+
+	return code
+}
+
+// Record stores the attempt and flags it when suspicious. Errors are logged,
+// not returned: a failure to record must not prevent the login.
+func Record(a *Attempt) {
+	now := time.Now()
+
+	event := models.LoginEvent{
+		UserID:    a.UserID,
+		Username:  a.Username,
+		Provider:  a.Provider,
+		Success:   a.Success,
+		Reason:    a.Reason,
+		IPAddress: a.IPAddress,
+		UserAgent: truncate(a.UserAgent, maxUserAgentLen),
+		Country:   a.Country,
+		CreatedAt: now,
+	}
+
+	if a.Success {
+		flags, err := Flags(a.DB, &event)
+		if err != nil {
+			log.Warn().Err(err).Str("username", a.Username).Msg("loginhistory: failed to check login")
+		}
+
+		event.Flags = strings.Join(flags, " ")
+	}
+
+	if err := a.DB.Create(&event).Error; err != nil {
+		log.Error().Err(err).Str("username", a.Username).Msg("loginhistory: failed to record login")
+		return
+	}
+
+	if event.Suspicious() {
+		log.Warn().Str("username", event.Username).Str("ip", event.IPAddress).Str("country", event.Country).
+			Strs("flags", event.FlagList()).Msg("suspicious login")
+	}
+
+	maybePurge(a.DB, now)
+}
+
+// Flags returns the reasons the successful login e, not yet stored, looks
+// suspicious.
+func Flags(db *gorm.DB, e *models.LoginEvent) ([]string, error) {
+	var flags []string
+
+	if e.UserID != nil && e.Country != "" {
+		var known, fromCountry int64
+
+		err := db.Model(&models.LoginEvent{}).
+			Where("user_id = ? AND success = ? AND country <> ''", *e.UserID, true).Count(&known).Error
+		if err != nil {
+			return nil, err
+		}
+
+		if known > 0 {
+			err = db.Model(&models.LoginEvent{}).
+				Where("user_id = ? AND success = ? AND country = ?", *e.UserID, true, e.Country).
+				Count(&fromCountry).Error
+			if err != nil {
+				return nil, err
+			}
+
+			if fromCountry == 0 {
+				flags = append(flags, FlagNewCountry)
+			}
+		}
+	}
+
+	var failures int64
+
+	err := db.Model(&models.LoginEvent{}).
+		Where("username = ? AND success = ? AND created_at > ?", e.Username, false, e.CreatedAt.Add(-failureWindow)).
+		Count(&failures).Error
+	if err != nil {
+		return flags, err
+	}
+
+	if failures >= failureThreshold {
+		flags = append(flags, FlagAfterFailures)
+	}
+
+	return flags, nil
+}
+
+// ForUser returns the latest limit events of the user, newest first.
+func ForUser(db *gorm.DB, userID uint64, limit int) ([]models.LoginEvent, error) {
+	var events []models.LoginEvent
+
+	err := db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error
+
+	return events, err
+}
+
+// Filter narrows the admin report.
+type Filter struct {
+	// Username matches part of the username.
+	Username string
+	// Failed limits the report to failed attempts.
+	Failed bool
+	// Suspicious limits the report to flagged logins.
+	Suspicious bool
+}
+
+// Query returns the events matching f, for counting and paging.
+func Query(db *gorm.DB, f *Filter) *gorm.DB {
+	tx := db.Model(&models.LoginEvent{})
+
+	if f.Username != "" {
+		tx = tx.Scopes(like.Contains(f.Username, "username"))
+	}
+
+	if f.Failed {
+		tx = tx.Where("success = ?", false)
+	}
+
+	if f.Suspicious {
+		tx = tx.Where("flags <> ''")
+	}
+
+	return tx
+}
+
+// Purge removes the events created before cutoff and returns their number.
+func Purge(db *gorm.DB, cutoff time.Time) (int64, error) {
+	result := db.Where("created_at < ?", cutoff).Delete(&models.LoginEvent{})
+
+	return result.RowsAffected, result.Error
+}
+
+// maybePurge removes expired events at most once per purgeInterval.
+func maybePurge(db *gorm.DB, now time.Time) {
+	cfg := settings.Load()
+	if cfg == nil || cfg.RetentionDays < 0 {
+		return
+	}
+
+	last := lastPurge.Load()
+	if now.Unix()-last < int64(purgeInterval.Seconds()) || !lastPurge.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+
+	n, err := Purge(db, now.AddDate(0, 0, -cfg.RetentionDays))
+	if err != nil {
+		log.Warn().Err(err).Msg("loginhistory: failed to remove expired events")
+		return
+	}
+
+	if n > 0 {
+		log.Debug().Int64("count", n).Msg("loginhistory: removed expired events")
+	}
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return strings.ToValidUTF8(s[:n], "")
+}
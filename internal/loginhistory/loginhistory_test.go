@@ -0,0 +1,162 @@
+package loginhistory
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.LoginEvent{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+// login records a successful login of alice from country and returns it.
+func login(t *testing.T, db *gorm.DB, country string) models.LoginEvent {
+	t.Helper()
+
+	user := models.User{ID: 7, Username: "alice"}
+	Record((&Attempt{DB: db, Country: country}).Succeeded(&user, ProviderLocal))
+
+	var e models.LoginEvent
+	if err := db.Order("id DESC").First(&e).Error; err != nil {
+		t.Fatalf("login not recorded: %v", err)
+	}
+
+	return e
+}
+
+func TestRecord_FlagsNewCountry(t *testing.T) {
+	db := newTestDB(t)
+
+	// Without earlier countries there is nothing to compare with.
+	if e := login(t, db, "DE"); e.Suspicious() {
+		t.Errorf("first login flagged %v", e.FlagList())
+	}
+
+	if e := login(t, db, "DE"); e.Suspicious() {
+		t.Errorf("login from a known country flagged %v", e.FlagList())
+	}
+
+	if e := login(t, db, "BR"); !slices.Equal(e.FlagList(), []string{FlagNewCountry}) {
+		t.Errorf("login from a new country flags = %v, want [%s]", e.FlagList(), FlagNewCountry)
+	}
+
+	if e := login(t, db, ""); e.Suspicious() {
+		t.Errorf("login without a country flagged %v", e.FlagList())
+	}
+}
+
+func TestRecord_FlagsLoginAfterFailures(t *testing.T) {
+	db := newTestDB(t)
+
+	for range failureThreshold - 1 {
+		Record((&Attempt{DB: db}).Failed("alice", ProviderLocal, errors.New("invalid credentials")))
+	}
+
+	if e := login(t, db, ""); e.Suspicious() {
+		t.Errorf("login after %d failures flagged %v", failureThreshold-1, e.FlagList())
+	}
+
+	Record((&Attempt{DB: db}).Failed("alice", ProviderLocal, errors.New("invalid credentials")))
+
+	if e := login(t, db, ""); !slices.Equal(e.FlagList(), []string{FlagAfterFailures}) {
+		t.Errorf("flags = %v, want [%s]", e.FlagList(), FlagAfterFailures)
+	}
+
+	var failed int64
+	Query(db, &Filter{Username: "lic", Failed: true}).Count(&failed)
+
+	if failed != failureThreshold {
+		t.Errorf("failed attempts = %d, want %d", failed, failureThreshold)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	db := newTestDB(t)
+
+	old := models.LoginEvent{Username: "alice", Provider: ProviderLocal, CreatedAt: time.Now().AddDate(0, 0, -100)}
+	db.Create(&old)
+	login(t, db, "")
+
+	n, err := Purge(db, time.Now().AddDate(0, 0, -90))
+	if err != nil || n != 1 {
+		t.Fatalf("Purge() = %d, %v; want 1", n, err)
+	}
+
+	events, err := ForUser(db, 7, 10)
+	if err != nil || len(events) != 1 {
+		t.Errorf("ForUser() = %d events, %v; want 1", len(events), err)
+	}
+}
+
+func TestFromRequest_CountryFromTrustedProxy(t *testing.T) {
+	Configure(config.LoginHistory{CountryHeader: "CF-IPCountry"})
+	t.Cleanup(func() { Configure(config.LoginHistory{}) })
+
+	for _, tt := range []struct {
+		name  string
+		app   *fiber.App
+		value string
+		want  string
+	}{
+		{"untrusted client", fiber.New(), "de", ""},
+		{"trusted proxy", fiber.New(fiber.Config{
+			TrustProxy: true, TrustProxyConfig: fiber.TrustProxyConfig{Proxies: []string{"0.0.0.0/0"}},
+		}), "de", "DE"},
+		{"unknown country", fiber.New(fiber.Config{
+			TrustProxy: true, TrustProxyConfig: fiber.TrustProxyConfig{Proxies: []string{"0.0.0.0/0"}},
+		}), "XX", ""},
+	} {
+		var got *Attempt
+
+		tt.app.Get("/", func(c fiber.Ctx) error {
+			got = FromRequest(c, nil)
+			return nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.Header.Set("CF-IPCountry", tt.value)
+
+		resp, err := tt.app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.name, err)
+		}
+
+		_ = resp.Body.Close()
+
+		if got.Country != tt.want {
+			t.Errorf("%s: country = %q, want %q", tt.name, got.Country, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("abcdé", 5); got != "abcd" {
+		t.Errorf("truncate() = %q, want abcd", got)
+	}
+
+	if got := truncate("abc", 5); got != "abc" {
+		t.Errorf("truncate() = %q, want abc", got)
+	}
+}
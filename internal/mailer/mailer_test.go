@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessage(t *testing.T) {
+	date := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	msg := string(Message("dns@example.com", "alice@example.com\r\nBcc: evil@example.com",
+		"Réinitialiser", "line one\nline two", date))
+
+	head, body, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no header/body separator in %q", msg)
+	}
+
+	for _, want := range []string{
+		"From: dns@example.com\r\n",
+		"To: alice@example.comBcc: evil@example.com\r\n",
+		"Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n",
+		"Date: Fri, 02 Jan 2026 03:04:05 +0000\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+	} {
+		if !strings.Contains(head+"\r\n", want) {
+			t.Errorf("header missing %q in:\n%s", want, head)
+		}
+	}
+
+	if strings.Contains(head, "\nBcc:") {
+		t.Error("newline in recipient must not start a new header")
+	}
+
+	if body != "line one\r\nline two" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestMessage_PlainSubjectNotEncoded(t *testing.T) {
+	msg := string(Message("a@example.com", "b@example.com", "Password reset", "", time.Now()))
+
+	if !strings.Contains(msg, "Subject: Password reset\r\n") {
+		t.Errorf("plain ASCII subject should not be encoded:\n%s", msg)
+	}
+}
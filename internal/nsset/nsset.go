@@ -0,0 +1,366 @@
+// Package nsset manages nameserver sets: named lists of nameservers that
+// administrators assign to zones. The apex NS RRset of an assigned zone is
+// replaced with the nameservers of its set when the zone is assigned and
+// whenever the set changes. Moving many zones to another set runs in the
+// background; the outcome is stored per zone.
+package nsset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// defaultTTL is the TTL of an apex NS RRset created by a set.
+	defaultTTL = 3600
+
+	// applyTimeout bounds the PowerDNS requests applying a set to one zone.
+	applyTimeout = 30 * time.Second
+)
+
+var (
+	// ErrNoNameservers is returned when a set names no nameserver.
+	ErrNoNameservers = errors.New("name at least one nameserver")
+	// ErrInUse is returned when deleting a set that zones are assigned to.
+	ErrInUse = errors.New("nameserver set is assigned to zones")
+	// ErrSecondary is returned when applying a set to a secondary zone, whose
+	// records are transferred from its primaries.
+	ErrSecondary = errors.New("secondary zones get their NS records from their primaries")
+)
+
+// hostnameRegex matches a lower-case hostname with trailing dot.
+var hostnameRegex = regexp.MustCompile(`^([a-z0-9_]([a-z0-9-]{0,61}[a-z0-9])?\.)+$`)
+
+// Parse splits a list of nameservers separated by commas or white space and
+// returns them lower-cased with a trailing dot, without duplicates.
+func Parse(list string) ([]string, error) {
+	var nameservers []string
+
+	for field := range strings.FieldsFuncSeq(strings.ToLower(list), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		ns := field
+		if !strings.HasSuffix(ns, ".") {
+			ns += "."
+		}
+
+		if len(ns) > 254 || !hostnameRegex.MatchString(ns) {
+			return nil, fmt.Errorf("nameserver %q is not a valid hostname", field)
+		}
+
+		if !slices.Contains(nameservers, ns) {
+			nameservers = append(nameservers, ns)
+		}
+	}
+
+	if len(nameservers) == 0 {
+		return nil, ErrNoNameservers
+	}
+
+	return nameservers, nil
+}
+
+// List returns the nameserver sets ordered by name.
+func List(db *gorm.DB) ([]models.NameserverSet, error) {
+	var sets []models.NameserverSet
+
+	err := db.Order("name").Find(&sets).Error
+
+	return sets, err
+}
+
+// Get returns the nameserver set id.
+func Get(db *gorm.DB, id uint) (*models.NameserverSet, error) {
+	var set models.NameserverSet
+	if err := db.First(&set, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+// ZoneCounts returns the number of zones assigned to each set by set ID.
+func ZoneCounts(db *gorm.DB) (map[uint]int64, error) {
+	var rows []struct {
+		SetID uint
+		Count int64
+	}
+
+	err := db.Model(&models.ZoneNameserverSet{}).
+		Select("set_id, COUNT(*) AS count").
+		Group("set_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, r := range rows {
+		counts[r.SetID] = r.Count
+	}
+
+	return counts, nil
+}
+
+// Zones returns the zones assigned to the set setID ordered by name.
+func Zones(db *gorm.DB, setID uint) ([]models.ZoneNameserverSet, error) {
+	var zones []models.ZoneNameserverSet
+
+	err := db.Where("set_id = ?", setID).Order("zone_name").Find(&zones).Error
+
+	return zones, err
+}
+
+// Assigned returns the assignment of zone with its set, or nil when the zone
+// has no set.
+func Assigned(db *gorm.DB, zone string) (*models.ZoneNameserverSet, error) {
+	var assignment models.ZoneNameserverSet
+
+	err := db.Preload("Set").Where("zone_name = ?", zone).First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &assignment, nil
+}
+
+// Assign assigns zone to the set setID, to be applied by ApplyZone. A setID
+// of 0 removes the assignment and leaves the NS records as they are.
+func Assign(db *gorm.DB, zone string, setID uint) error {
+	if setID == 0 {
+		return db.Where("zone_name = ?", zone).Delete(&models.ZoneNameserverSet{}).Error
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "zone_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"set_id", "applied_at", "error", "updated_at"}),
+	}).Create(&models.ZoneNameserverSet{ZoneName: zone, SetID: setID}).Error
+}
+
+// MarkPending marks the set setID to be applied again to all its zones, e.g.
+// after its nameservers changed.
+func MarkPending(db *gorm.DB, setID uint) error {
+	return db.Model(&models.ZoneNameserverSet{}).
+		Where("set_id = ?", setID).
+		Updates(map[string]any{"applied_at": nil, "error": ""}).Error
+}
+
+// Migrate assigns the zones of the set fromID to the set toID, to be applied
+// by ApplyPending. It returns the number of zones moved.
+func Migrate(db *gorm.DB, fromID, toID uint) (int64, error) {
+	res := db.Model(&models.ZoneNameserverSet{}).
+		Where("set_id = ?", fromID).
+		Updates(map[string]any{"set_id": toID, "applied_at": nil, "error": ""})
+
+	return res.RowsAffected, res.Error
+}
+
+// Delete deletes set, or returns ErrInUse while zones are assigned to it.
+func Delete(db *gorm.DB, set *models.NameserverSet) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var zones int64
+		if err := tx.Model(&models.ZoneNameserverSet{}).Where("set_id = ?", set.ID).Count(&zones).Error; err != nil {
+			return err
+		}
+
+		if zones > 0 {
+			return ErrInUse
+		}
+
+		return tx.Delete(set).Error
+	})
+}
+
+// Apply makes the apex NS RRset of zone list exactly nameservers. The TTL and
+// comments of an existing RRset are kept. It reports whether the RRset was
+// changed; an RRset already listing the nameservers is left alone.
+func Apply(ctx context.Context, zone string, nameservers []string) (bool, error) {
+	if powerdns.Engine.Client == nil {
+		return false, powerdns.ErrClientNotInitialized
+	}
+
+	current, err := powerdns.Engine.Zones.Get(ctx, zone)
+	if err != nil {
+		return false, err
+	}
+
+	if current.Kind != nil && *current.Kind == pdnsapi.SlaveZoneKind {
+		return false, ErrSecondary
+	}
+
+	rrSet := apexNS(current, zone)
+
+	ttl := uint32(defaultTTL)
+	changeType := pdnsapi.ChangeTypeReplace
+	patch := pdnsapi.RRset{
+		Name:       pdnsapi.String(zone),
+		Type:       pdnsapi.RRTypePtr(pdnsapi.RRTypeNS),
+		TTL:        &ttl,
+		ChangeType: &changeType,
+		Records:    make([]pdnsapi.Record, len(nameservers)),
+	}
+
+	if rrSet != nil {
+		if sameNameservers(rrSet, nameservers) {
+			return false, nil
+		}
+
+		if rrSet.TTL != nil {
+			patch.TTL = rrSet.TTL
+		}
+
+		patch.Comments = rrSet.Comments
+	}
+
+	for i, ns := range nameservers {
+		patch.Records[i] = pdnsapi.Record{Content: pdnsapi.String(ns), Disabled: pdnsapi.Bool(false)}
+	}
+
+	if err = powerdns.Engine.Records.Patch(ctx, zone, &pdnsapi.RRsets{Sets: []pdnsapi.RRset{patch}}); err != nil {
+		return false, err
+	}
+
+	zoneindex.Default.RefreshZone(ctx, zone)
+
+	return true, nil
+}
+
+// ApplyZone applies the set assigned to zone and stores the outcome with the
+// assignment. A change of the NS records is recorded in the activity log with
+// the user, IP and authentication of actor. The assignment of a zone that no
+// longer exists in PowerDNS is removed.
+func ApplyZone(ctx context.Context, db *gorm.DB, zone string, actor activitylog.Entry) error {
+	assignment, err := Assigned(db, zone)
+	if err != nil || assignment == nil || assignment.Set == nil {
+		return err
+	}
+
+	applyCtx, cancel := context.WithTimeout(ctx, applyTimeout)
+	defer cancel()
+
+	changed, applyErr := Apply(applyCtx, zone, assignment.Set.NameserverList())
+
+	var pdnsErr *pdnsapi.Error
+	if errors.As(applyErr, &pdnsErr) && pdnsErr.StatusCode == http.StatusNotFound {
+		log.Info().Str("zone_name", zone).Msg("nsset: zone no longer exists, removing its nameserver set")
+
+		return Assign(db, zone, 0)
+	}
+
+	updates := map[string]any{"applied_at": time.Now(), "error": ""}
+	if applyErr != nil {
+		updates = map[string]any{"applied_at": nil, "error": applyErr.Error()}
+
+		log.Error().Err(applyErr).Str("zone_name", zone).Str("nameserver_set", assignment.Set.Name).
+			Msg("nsset: failed to apply nameserver set")
+	}
+
+	if err = db.Model(assignment).Updates(updates).Error; err != nil {
+		log.Error().Err(err).Str("zone_name", zone).Msg("nsset: failed to store the outcome of applying the nameserver set")
+	}
+
+	if applyErr != nil {
+		return applyErr
+	}
+
+	if changed {
+		actor.DB = db
+		actor.Action = activitylog.ActionNameserverSetApplied
+		actor.ResourceType = activitylog.ResourceTypeZone
+		actor.ResourceName = zone
+		actor.Details = map[string]any{
+			"nameserver_set": assignment.Set.Name,
+			"nameservers":    assignment.Set.NameserverList(),
+		}
+
+		activitylog.Record(&actor)
+	}
+
+	return nil
+}
+
+// ApplyPending applies the set setID to those of its zones where it is
+// pending or failed before, one zone after another. The returned error joins
+// the errors of the zones that failed.
+func ApplyPending(ctx context.Context, db *gorm.DB, setID uint, actor activitylog.Entry) error {
+	var zones []string
+
+	err := db.Model(&models.ZoneNameserverSet{}).
+		Where("set_id = ? AND (applied_at IS NULL OR error <> '')", setID).
+		Order("zone_name").
+		Pluck("zone_name", &zones).Error
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, zone := range zones {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err = ApplyZone(ctx, db, zone, actor); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", zone, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Start runs ApplyPending for the set setID in the background.
+func Start(db *gorm.DB, setID uint, actor activitylog.Entry) {
+	jobs.Go(jobs.NameserverSets, func() error {
+		return ApplyPending(context.Background(), db, setID, actor)
+	})
+}
+
+// apexNS returns the NS RRset at the apex of zone, or nil.
+func apexNS(zone *pdnsapi.Zone, name string) *pdnsapi.RRset {
+	for i := range zone.RRsets {
+		rrSet := &zone.RRsets[i]
+		if rrSet.Type != nil && *rrSet.Type == pdnsapi.RRTypeNS && strings.EqualFold(pdnsapi.StringValue(rrSet.Name), name) {
+			return rrSet
+		}
+	}
+
+	return nil
+}
+
+// sameNameservers reports whether rrSet lists exactly nameservers, all
+// enabled, in any order.
+func sameNameservers(rrSet *pdnsapi.RRset, nameservers []string) bool {
+	if len(rrSet.Records) != len(nameservers) {
+		return false
+	}
+
+	for _, rec := range rrSet.Records {
+		if pdnsapi.BoolValue(rec.Disabled) ||
+			!slices.Contains(nameservers, strings.ToLower(pdnsapi.StringValue(rec.Content))) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,221 @@
+package nsset
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/pdnsserver"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+// fakePowerDNS serves example.com. with an apex NS RRset listing
+// ns1.example.net. and ns2.example.net., the secondary zone secondary.example.
+// and nothing else, and keeps the bodies of the PATCH requests.
+type fakePowerDNS struct {
+	mu      sync.Mutex
+	patches []string
+}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	zone := strings.TrimPrefix(r.URL.Path, "/api/v1/servers/localhost/zones/")
+
+	switch {
+	case r.Method == http.MethodGet && zone == "example.com.":
+		_, _ = io.WriteString(w, `{"name":"example.com.","kind":"Native","rrsets":[`+
+			`{"name":"example.com.","type":"NS","ttl":86400,"records":[`+
+			`{"content":"ns2.example.net.","disabled":false},{"content":"ns1.example.net.","disabled":false}],`+
+			`"comments":[{"content":"registrar","account":"admin","modified_at":1}]}]}`)
+	case r.Method == http.MethodGet && zone == "secondary.example.":
+		_, _ = io.WriteString(w, `{"name":"secondary.example.","kind":"Slave","rrsets":[]}`)
+	case r.Method == http.MethodGet:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"Not Found"}`)
+	default:
+		body, _ := io.ReadAll(r.Body)
+
+		f.mu.Lock()
+		f.patches = append(f.patches, string(body))
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Setting{}, &models.User{}, &models.ActivityLog{},
+		&models.NameserverSet{}, &models.ZoneNameserverSet{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func openFakePowerDNS(t *testing.T, db *gorm.DB) *fakePowerDNS {
+	t.Helper()
+
+	pdns := &fakePowerDNS{}
+
+	srv := httptest.NewServer(pdns)
+	t.Cleanup(srv.Close)
+
+	settings := &pdnsserver.Settings{APIServerURL: srv.URL, APIKey: "secret", VHost: "localhost"}
+	if err := settings.Save(db); err != nil {
+		t.Fatalf("failed to save PowerDNS settings: %v", err)
+	}
+
+	prev := powerdns.Engine
+	t.Cleanup(func() { powerdns.Engine = prev })
+
+	if err := powerdns.Open(db); err != nil {
+		t.Fatalf("failed to open PowerDNS client: %v", err)
+	}
+
+	return pdns
+}
+
+func createSet(t *testing.T, db *gorm.DB, name string, nameservers ...string) *models.NameserverSet {
+	t.Helper()
+
+	set := &models.NameserverSet{Name: name, Nameservers: strings.Join(nameservers, "\n")}
+	if err := db.Create(set).Error; err != nil {
+		t.Fatalf("failed to create set: %v", err)
+	}
+
+	return set
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse("NS1.example.net, ns2.example.net.\nns1.example.net")
+	if err != nil || strings.Join(got, " ") != "ns1.example.net. ns2.example.net." {
+		t.Errorf("Parse() = %v, %v", got, err)
+	}
+
+	if _, err = Parse(" \n"); !errors.Is(err, ErrNoNameservers) {
+		t.Errorf("Parse(empty) error = %v, want ErrNoNameservers", err)
+	}
+
+	if _, err = Parse("ns1.example.net bad_host-.example"); err == nil {
+		t.Error("Parse(invalid) error = nil")
+	}
+}
+
+func TestApply(t *testing.T) {
+	db := newTestDB(t)
+	pdns := openFakePowerDNS(t, db)
+	ctx := context.Background()
+
+	changed, err := Apply(ctx, "example.com.", []string{"ns1.example.net.", "ns2.example.net."})
+	if err != nil || changed {
+		t.Errorf("Apply(same nameservers) = %v, %v, want unchanged", changed, err)
+	}
+
+	changed, err = Apply(ctx, "example.com.", []string{"a.anycast.example.", "b.anycast.example."})
+	if err != nil || !changed {
+		t.Fatalf("Apply() = %v, %v, want changed", changed, err)
+	}
+
+	if len(pdns.patches) != 1 {
+		t.Fatalf("patches = %d, want 1", len(pdns.patches))
+	}
+
+	patch := pdns.patches[0]
+	for _, want := range []string{`"changetype":"REPLACE"`, `"ttl":86400`, `"a.anycast.example."`, `"registrar"`} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("patch %s does not contain %s", patch, want)
+		}
+	}
+
+	if _, err = Apply(ctx, "secondary.example.", []string{"ns1.example.net."}); !errors.Is(err, ErrSecondary) {
+		t.Errorf("Apply(secondary) error = %v, want ErrSecondary", err)
+	}
+}
+
+func TestMigrateAndApplyPending(t *testing.T) {
+	db := newTestDB(t)
+	pdns := openFakePowerDNS(t, db)
+
+	legacy := createSet(t, db, "legacy", "ns1.example.net.", "ns2.example.net.")
+	anycast := createSet(t, db, "anycast-eu", "a.anycast.example.", "b.anycast.example.")
+
+	for _, zone := range []string{"example.com.", "secondary.example.", "gone.example."} {
+		if err := Assign(db, zone, legacy.ID); err != nil {
+			t.Fatalf("Assign(%s) error = %v", zone, err)
+		}
+	}
+
+	if err := Delete(db, legacy); !errors.Is(err, ErrInUse) {
+		t.Errorf("Delete(assigned set) error = %v, want ErrInUse", err)
+	}
+
+	moved, err := Migrate(db, legacy.ID, anycast.ID)
+	if err != nil || moved != 3 {
+		t.Fatalf("Migrate() = %d, %v, want 3 zones", moved, err)
+	}
+
+	actor := activitylog.Entry{Username: "admin"}
+	if err = ApplyPending(context.Background(), db, anycast.ID, actor); !errors.Is(err, ErrSecondary) {
+		t.Errorf("ApplyPending() error = %v, want the secondary zone to fail", err)
+	}
+
+	if len(pdns.patches) != 1 {
+		t.Errorf("patches = %d, want 1", len(pdns.patches))
+	}
+
+	zones, err := Zones(db, anycast.ID)
+	if err != nil || len(zones) != 2 {
+		t.Fatalf("Zones() = %+v, %v, want the zone that no longer exists removed", zones, err)
+	}
+
+	if z := zones[0]; z.ZoneName != "example.com." || z.AppliedAt == nil || z.Error != "" {
+		t.Errorf("zones[0] = %+v, want example.com. applied", z)
+	}
+
+	if z := zones[1]; z.ZoneName != "secondary.example." || z.AppliedAt != nil || z.Error == "" {
+		t.Errorf("zones[1] = %+v, want secondary.example. failed", z)
+	}
+
+	var entries int64
+
+	db.Model(&models.ActivityLog{}).Where("action = ?", activitylog.ActionNameserverSetApplied).Count(&entries)
+
+	if entries != 1 {
+		t.Errorf("activity entries = %d, want 1", entries)
+	}
+
+	counts, err := ZoneCounts(db)
+	if err != nil || counts[anycast.ID] != 2 || counts[legacy.ID] != 0 {
+		t.Errorf("ZoneCounts() = %v, %v", counts, err)
+	}
+
+	if err = Delete(db, legacy); err != nil {
+		t.Errorf("Delete(unused set) error = %v", err)
+	}
+
+	if err = Assign(db, "example.com.", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if assignment, _ := Assigned(db, "example.com."); assignment != nil {
+		t.Errorf("Assigned() after unassign = %+v, want nil", assignment)
+	}
+}
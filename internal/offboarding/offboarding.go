@@ -0,0 +1,232 @@
+// Package offboarding takes stock of what a user owns — zone grants, API keys
+// and pending record schedules — before the account is deactivated or
+// deleted, optionally hands it over to another user, and reports what
+// happened to each item.
+package offboarding
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// Mode is what happens to the account.
+type Mode string
+
+const (
+	// ModeDeactivate keeps the account but disables it.
+	ModeDeactivate Mode = "deactivate"
+	// ModeDelete removes the account.
+	ModeDelete Mode = "delete"
+)
+
+// ErrTransferToSelf is returned when the resources would be handed to the
+// user being offboarded.
+var ErrTransferToSelf = errors.New("cannot transfer resources to the offboarded user")
+
+// ErrTransferTargetInactive is returned when the resources would be handed to
+// an inactive user.
+var ErrTransferTargetInactive = errors.New("cannot transfer resources to an inactive user")
+
+// Resources are the things linked to a user that outlive a login.
+type Resources struct {
+	// Zones are the zones granted to the user directly.
+	Zones []models.ZoneOwnership
+	// APIKeys are the keys that still authenticate, i.e. neither revoked nor
+	// expired.
+	APIKeys []models.APIKey
+	// Schedules are the record schedules the user created that have not run
+	// yet.
+	Schedules []models.RecordSchedule
+}
+
+// Empty reports whether the user owns nothing.
+func (r *Resources) Empty() bool {
+	return len(r.Zones) == 0 && len(r.APIKeys) == 0 && len(r.Schedules) == 0
+}
+
+// Collect returns the resources owned by userID.
+func Collect(db *gorm.DB, userID uint64) (*Resources, error) {
+	var r Resources
+
+	if err := db.Where("user_id = ?", userID).Order("zone_name ASC").Find(&r.Zones).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)",
+		userID, time.Now()).Order("name ASC").Find(&r.APIKeys).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("created_by_id = ? AND status = ?", userID, models.RecordSchedulePending).
+		Order("run_at ASC").Find(&r.Schedules).Error; err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Report summarizes an offboarding. Without a transfer target, zone grants
+// are kept on deactivation and removed on deletion, API keys are revoked, and
+// pending schedules still run.
+type Report struct {
+	Username   string
+	Mode       Mode
+	TransferTo string
+	Zones      []string
+	APIKeys    []string
+	Schedules  []models.RecordSchedule
+	ActorName  string
+	At         time.Time
+}
+
+// Transferred reports whether the resources were handed over.
+func (r *Report) Transferred() bool {
+	return r.TransferTo != ""
+}
+
+// Offboard deactivates or deletes user in one transaction. With a non-nil
+// target the resources of user are transferred to it first. The result is
+// recorded in the activity log on behalf of actor.
+func Offboard(db *gorm.DB, user *models.User, mode Mode, target, actor *models.User, ipAddress string) (*Report, error) {
+	if target != nil {
+		if target.ID == user.ID {
+			return nil, ErrTransferToSelf
+		}
+
+		if !target.Active {
+			return nil, ErrTransferTargetInactive
+		}
+	}
+
+	report := &Report{Username: user.Username, Mode: mode, At: time.Now()}
+	if target != nil {
+		report.TransferTo = target.Username
+	}
+
+	if actor != nil {
+		report.ActorName = actor.Username
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		res, err := Collect(tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		for i := range res.Zones {
+			report.Zones = append(report.Zones, res.Zones[i].ZoneName)
+		}
+
+		for i := range res.APIKeys {
+			report.APIKeys = append(report.APIKeys, res.APIKeys[i].Name)
+		}
+
+		report.Schedules = res.Schedules
+
+		if target != nil {
+			err = transfer(tx, user.ID, target.ID)
+		} else {
+			err = release(tx, user.ID, mode)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if mode == ModeDelete {
+			return tx.Delete(&models.User{}, user.ID).Error
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).Update("active", false).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record(db, report, actor, ipAddress)
+
+	return report, nil
+}
+
+// transfer hands the zone grants, API keys and pending schedules of fromID to
+// toID. Grants toID already has are dropped instead of duplicated.
+func transfer(tx *gorm.DB, fromID, toID uint64) error {
+	owned := tx.Model(&models.ZoneOwnership{}).Select("zone_name").Where("user_id = ?", toID)
+
+	if err := tx.Where("user_id = ? AND zone_name IN (?)", fromID, owned).
+		Delete(&models.ZoneOwnership{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.ZoneOwnership{}).Where("user_id = ?", fromID).
+		Update("user_id", toID).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", fromID).
+		Update("user_id", toID).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.RecordSchedule{}).
+		Where("created_by_id = ? AND status = ?", fromID, models.RecordSchedulePending).
+		Update("created_by_id", toID).Error
+}
+
+// release cleans up the resources of userID without a new owner. The schema
+// cascades on deletion, but not every database enforces foreign keys, so the
+// rows are removed explicitly.
+func release(tx *gorm.DB, userID uint64, mode Mode) error {
+	if mode == ModeDeactivate {
+		return tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.ZoneOwnership{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.APIKey{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.RecordSchedule{}).Where("created_by_id = ?", userID).
+		Update("created_by_id", nil).Error
+}
+
+// record writes the activity log entry of report.
+func record(db *gorm.DB, report *Report, actor *models.User, ipAddress string) {
+	var actorID *uint64
+
+	actorName := "system"
+	if actor != nil {
+		id := actor.ID
+		actorID, actorName = &id, actor.Username
+	}
+
+	details := map[string]any{
+		"mode":      string(report.Mode),
+		"zones":     report.Zones,
+		"api_keys":  report.APIKeys,
+		"schedules": len(report.Schedules),
+	}
+	if report.Transferred() {
+		details["transfer_to"] = report.TransferTo
+	}
+
+	activitylog.Record(&activitylog.Entry{
+		DB:           db,
+		UserID:       actorID,
+		Username:     actorName,
+		Action:       activitylog.ActionUserOffboarded,
+		ResourceType: activitylog.ResourceTypeUser,
+		ResourceName: report.Username,
+		Details:      details,
+		IPAddress:    ipAddress,
+	})
+}
@@ -0,0 +1,162 @@
+package offboarding
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// newTestDB seeds "leaver" with two zone grants, an active, a revoked and an
+// expired API key and a pending and a finished schedule, and "heir", who
+// already has one of the two zones.
+func newTestDB(t *testing.T) (db *gorm.DB, leaver, heir models.User) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.ActivityLog{},
+		&models.ZoneOwnership{}, &models.APIKey{}, &models.RecordSchedule{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	leaver = models.User{Username: "leaver", Email: "leaver@example.com", Active: true}
+	heir = models.User{Username: "heir", Email: "heir@example.com", Active: true}
+	db.Create(&leaver)
+	db.Create(&heir)
+
+	past := time.Now().Add(-time.Hour)
+	rows := []any{
+		&models.ZoneOwnership{ZoneName: "a.example.", UserID: leaver.ID},
+		&models.ZoneOwnership{ZoneName: "b.example.", UserID: leaver.ID},
+		&models.ZoneOwnership{ZoneName: "b.example.", UserID: heir.ID},
+		&models.APIKey{UserID: leaver.ID, Name: "ci", Prefix: "p1", TokenHash: "h1"},
+		&models.APIKey{UserID: leaver.ID, Name: "old", Prefix: "p2", TokenHash: "h2", RevokedAt: &past},
+		&models.APIKey{UserID: leaver.ID, Name: "expired", Prefix: "p3", TokenHash: "h3", ExpiresAt: &past},
+		&models.RecordSchedule{ZoneName: "a.example.", Name: "www.a.example.", Type: "A", Content: "192.0.2.1",
+			Action: models.RecordScheduleDisable, RunAt: time.Now().Add(time.Hour),
+			Status: models.RecordSchedulePending, CreatedByID: &leaver.ID},
+		&models.RecordSchedule{ZoneName: "a.example.", Name: "old.a.example.", Type: "A", Content: "192.0.2.2",
+			Action: models.RecordScheduleDisable, RunAt: past,
+			Status: models.RecordScheduleDone, CreatedByID: &leaver.ID},
+	}
+
+	for _, row := range rows {
+		if err = db.Create(row).Error; err != nil {
+			t.Fatalf("failed to seed: %v", err)
+		}
+	}
+
+	return db, leaver, heir
+}
+
+func TestCollect(t *testing.T) {
+	db, leaver, _ := newTestDB(t)
+
+	r, err := Collect(db, leaver.ID)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if len(r.Zones) != 2 || len(r.APIKeys) != 1 || len(r.Schedules) != 1 {
+		t.Fatalf("Collect() = %d zones, %d keys, %d schedules; want 2, 1, 1",
+			len(r.Zones), len(r.APIKeys), len(r.Schedules))
+	}
+
+	if r.APIKeys[0].Name != "ci" {
+		t.Errorf("API key = %q, want ci", r.APIKeys[0].Name)
+	}
+}
+
+func TestOffboard_DeactivateWithTransfer(t *testing.T) {
+	db, leaver, heir := newTestDB(t)
+
+	report, err := Offboard(db, &leaver, ModeDeactivate, &heir, nil, "")
+	if err != nil {
+		t.Fatalf("Offboard() error = %v", err)
+	}
+
+	if !report.Transferred() || len(report.Zones) != 2 || len(report.APIKeys) != 1 || len(report.Schedules) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	var zones []string
+	db.Model(&models.ZoneOwnership{}).Where("user_id = ?", heir.ID).Order("zone_name").Pluck("zone_name", &zones)
+
+	if len(zones) != 2 || zones[0] != "a.example." || zones[1] != "b.example." {
+		t.Errorf("heir zones = %v", zones)
+	}
+
+	if r, _ := Collect(db, leaver.ID); !r.Empty() {
+		t.Errorf("leaver still owns %+v", r)
+	}
+
+	if r, _ := Collect(db, heir.ID); len(r.APIKeys) != 1 || len(r.Schedules) != 1 {
+		t.Errorf("heir owns %d keys and %d schedules, want 1 and 1", len(r.APIKeys), len(r.Schedules))
+	}
+
+	var got models.User
+	db.First(&got, leaver.ID)
+
+	if got.Active {
+		t.Error("leaver is still active")
+	}
+
+	var entry models.ActivityLog
+	if err = db.Where("action = ?", "user_offboarded").First(&entry).Error; err != nil {
+		t.Fatalf("activity log entry missing: %v", err)
+	}
+
+	if entry.Username != "system" || entry.ResourceName != "leaver" {
+		t.Errorf("entry = %s on %s", entry.Username, entry.ResourceName)
+	}
+}
+
+func TestOffboard_WithoutTransfer(t *testing.T) {
+	db, leaver, heir := newTestDB(t)
+
+	if _, err := Offboard(db, &leaver, ModeDeactivate, &leaver, nil, ""); !errors.Is(err, ErrTransferToSelf) {
+		t.Fatalf("transfer to self: error = %v, want ErrTransferToSelf", err)
+	}
+
+	if _, err := Offboard(db, &leaver, ModeDeactivate, nil, nil, ""); err != nil {
+		t.Fatalf("deactivate: error = %v", err)
+	}
+
+	// Deactivation revokes the keys but keeps the grants and schedules.
+	r, _ := Collect(db, leaver.ID)
+	if len(r.Zones) != 2 || len(r.APIKeys) != 0 || len(r.Schedules) != 1 {
+		t.Fatalf("after deactivate: %d zones, %d keys, %d schedules; want 2, 0, 1",
+			len(r.Zones), len(r.APIKeys), len(r.Schedules))
+	}
+
+	if _, err := Offboard(db, &leaver, ModeDelete, nil, nil, ""); err != nil {
+		t.Fatalf("delete: error = %v", err)
+	}
+
+	var keys, grants int64
+	db.Model(&models.APIKey{}).Count(&keys)
+	db.Model(&models.ZoneOwnership{}).Count(&grants)
+
+	if keys != 0 || grants != 1 {
+		t.Errorf("after delete: %d keys, %d grants; want 0 and heir's 1", keys, grants)
+	}
+
+	var schedule models.RecordSchedule
+	db.Where("status = ?", models.RecordSchedulePending).First(&schedule)
+
+	if schedule.CreatedByID != nil {
+		t.Errorf("pending schedule still created by %d", *schedule.CreatedByID)
+	}
+
+	if r, _ := Collect(db, heir.ID); len(r.Zones) != 1 {
+		t.Errorf("heir zones = %d, want 1", len(r.Zones))
+	}
+}
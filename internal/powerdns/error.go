@@ -15,20 +15,39 @@ const (
 	// ErrMsgServerUnreachable is the user-facing message when the PowerDNS server cannot be reached.
 	ErrMsgServerUnreachable = "PowerDNS server is unreachable. Please check that the server is running" +
 		" and the configured address is correct."
+
+	// ErrMsgUnavailable is the error message while the circuit breaker rejects requests.
+	ErrMsgUnavailable = "PowerDNS unavailable"
+
+	// ErrMsgReadOnly is the error message of a change to a read-only server.
+	ErrMsgReadOnly = "PowerDNS is in read-only mode"
 )
 
 var (
 	// ErrClientNotInitialized is returned when the PowerDNS client is not initialized.
 	ErrClientNotInitialized = errors.New(ErrMsgClientNotInitialized)
+
+	// ErrUnavailable is returned without contacting PowerDNS while the circuit
+	// breaker is open after repeated failures.
+	ErrUnavailable = errors.New(ErrMsgUnavailable)
+
+	// ErrReadOnly is returned without contacting PowerDNS for a change to a
+	// server in read-only mode.
+	ErrReadOnly = errors.New(ErrMsgReadOnly)
 )
 
 // IsServerUnreachable reports whether err indicates a network-level failure
-// reaching the PowerDNS server (connection refused, timeout, DNS failure, etc.).
+// reaching the PowerDNS server (connection refused, timeout, DNS failure, etc.)
+// or a request rejected because the circuit breaker is open.
 func IsServerUnreachable(err error) bool {
 	if err == nil {
 		return false
 	}
 
+	if errors.Is(err, ErrUnavailable) {
+		return true
+	}
+
 	var netErr net.Error
 	if errors.As(err, &netErr) {
 		return true
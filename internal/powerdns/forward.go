@@ -0,0 +1,62 @@
+package powerdns
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Response is a PowerDNS API response as received from the server.
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Forward sends a request with method and body, which may be nil, for
+// pathFragment below the server's vhost, or for the server itself when
+// pathFragment is empty. Unlike the client library it returns the response
+// of any status unchanged, for passing it on to API clients.
+func (e engine) Forward(ctx context.Context, method, pathFragment string, query url.Values,
+	body []byte,
+) (*Response, error) {
+	if e.Client == nil || e.httpClient == nil {
+		return nil, ErrClientNotInitialized
+	}
+
+	apiURL, err := e.serverURL(pathFragment, query)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-API-Key", e.apiKey)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        data,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package powerdns
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestForward(t *testing.T) {
+	e := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		if r.Method != http.MethodPatch || r.URL.Path != "/api/v1/servers/localhost/zones/example.com." ||
+			r.Header.Get("X-API-Key") != "secret" || string(body) != `{"rrsets":[]}` {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"error":"boom"}`))
+	})
+
+	resp, err := e.Forward(t.Context(), http.MethodPatch, "zones/example.com.", nil, []byte(`{"rrsets":[]}`))
+	if err != nil {
+		t.Fatalf("Forward() error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusUnprocessableEntity || resp.ContentType != "application/json" ||
+		string(resp.Body) != `{"error":"boom"}` {
+		t.Errorf("Forward() = %+v", resp)
+	}
+}
+
+func TestForward_Server(t *testing.T) {
+	e := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/servers/localhost" || r.URL.Query().Get("x") != "1" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"id":"localhost"}`))
+	})
+
+	resp, err := e.Forward(t.Context(), http.MethodGet, "", url.Values{"x": {"1"}}, nil)
+	if err != nil {
+		t.Fatalf("Forward() error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != `{"id":"localhost"}` {
+		t.Errorf("Forward() = %+v", resp)
+	}
+}
@@ -0,0 +1,117 @@
+package powerdns
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+const (
+	// maxLoggedBody is the number of request and response body bytes logged.
+	maxLoggedBody = 4096
+
+	redacted = "[REDACTED]"
+)
+
+// apiLogging enables logging of PowerDNS API traffic; see SetAPILogging.
+var apiLogging atomic.Bool
+
+// secretFieldRe matches JSON string members holding secrets, such as the key
+// of a TSIG key.
+var secretFieldRe = regexp.MustCompile(`("(?i:key|api_?key|password|secret)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// SetAPILogging turns logging of PowerDNS API requests and responses on or
+// off. Entries are written at debug level.
+func SetAPILogging(enabled bool) {
+	apiLogging.Store(enabled)
+}
+
+// APILogging reports whether PowerDNS API traffic is logged.
+func APILogging() bool {
+	return apiLogging.Load()
+}
+
+// loggingTransport forwards the request ID of the request context to
+// PowerDNS and logs PowerDNS API requests and responses while API logging is
+// enabled.
+type loggingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := requestid.FromContext(req.Context()); id != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(requestid.Header, id)
+	}
+
+	if !apiLogging.Load() {
+		return t.base.RoundTrip(req)
+	}
+
+	apiKey := req.Header.Get("X-API-Key")
+
+	var reqBody []byte
+
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start)
+
+	event := requestid.Logger(req.Context()).Debug().
+		Str("method", req.Method).
+		Str("path", req.URL.RequestURI()).
+		Dur("latency", latency).
+		Str("request_body", redactBody(reqBody, apiKey))
+
+	if err != nil {
+		event.Err(err).Msg("PowerDNS API request failed")
+		return resp, err
+	}
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if readErr != nil {
+		event.Err(readErr).Int("status", resp.StatusCode).Msg("PowerDNS API response could not be read")
+		return nil, readErr
+	}
+
+	event.Int("status", resp.StatusCode).
+		Str("response_body", redactBody(respBody, apiKey)).
+		Msg("PowerDNS API request")
+
+	return resp, nil
+}
+
+// redactBody returns body with secrets and apiKey masked, truncated to
+// maxLoggedBody bytes.
+func redactBody(body []byte, apiKey string) string {
+	s := secretFieldRe.ReplaceAllString(string(body), `${1}"`+redacted+`"`)
+
+	if apiKey != "" {
+		s = strings.ReplaceAll(s, apiKey, redacted)
+	}
+
+	if len(s) > maxLoggedBody {
+		s = s[:maxLoggedBody] + "…(truncated)"
+	}
+
+	return s
+}
@@ -0,0 +1,125 @@
+package powerdns
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+func TestRedactBody(t *testing.T) {
+	body := []byte(`{"name":"xfr.","algorithm":"hmac-sha256","key":"c2VjcmV0"} error for api key s3cr3t-key`)
+
+	got := redactBody(body, "s3cr3t-key")
+
+	if strings.Contains(got, "c2VjcmV0") || strings.Contains(got, "s3cr3t-key") {
+		t.Errorf("secret not redacted: %s", got)
+	}
+
+	if !strings.Contains(got, `"name":"xfr."`) {
+		t.Errorf("non-secret field redacted: %s", got)
+	}
+
+	if got := redactBody(bytes.Repeat([]byte("a"), maxLoggedBody+10), ""); !strings.HasSuffix(got, "(truncated)") {
+		t.Errorf("long body not truncated")
+	}
+}
+
+func TestLoggingTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write(append([]byte("echo:"), b...))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+
+	prevLogger, prevLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&out)
+
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+
+	defer func() {
+		log.Logger = prevLogger
+		zerolog.SetGlobalLevel(prevLevel)
+		SetAPILogging(false)
+	}()
+
+	client := &http.Client{Transport: loggingTransport{base: http.DefaultTransport}}
+
+	send := func() string {
+		req, _ := http.NewRequest(http.MethodPatch, srv.URL+"/api/v1/servers/localhost/zones/example.com.",
+			strings.NewReader(`{"rrsets":[]}`))
+		req.Header.Set("X-API-Key", "s3cr3t-key")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		b, _ := io.ReadAll(resp.Body)
+
+		return string(b)
+	}
+
+	if got := send(); got != `echo:{"rrsets":[]}` || out.Len() != 0 {
+		t.Fatalf("disabled logging: body %q, log %q", got, out.String())
+	}
+
+	SetAPILogging(true)
+
+	if got := send(); got != `echo:{"rrsets":[]}` {
+		t.Fatalf("response body not preserved: %q", got)
+	}
+
+	for _, want := range []string{`"method":"PATCH"`, `"status":422`, `"path":"/api/v1/servers/localhost/zones/example.com."`,
+		`"response_body":"echo:{\"rrsets\":[]}"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("log %s missing %s", out.String(), want)
+		}
+	}
+
+	if strings.Contains(out.String(), "s3cr3t-key") {
+		t.Errorf("API key logged: %s", out.String())
+	}
+}
+
+func TestLoggingTransportForwardsRequestID(t *testing.T) {
+	var got string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(requestid.Header)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: loggingTransport{base: http.DefaultTransport}}
+
+	ctx := requestid.NewContext(context.Background(), "req-7")
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, http.NoBody)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	if got != "req-7" {
+		t.Errorf("PowerDNS saw %s %q, want req-7", requestid.Header, got)
+	}
+
+	if req.Header.Get(requestid.Header) != "" {
+		t.Error("caller's request was modified")
+	}
+}
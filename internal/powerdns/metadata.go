@@ -0,0 +1,138 @@
+package powerdns
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+// ManagedMetadataKinds are the zone metadata kinds managed on the zone edit
+// page, in display order. Other kinds are left untouched.
+var ManagedMetadataKinds = []powerdns.MetadataKind{
+	powerdns.MetadataAlsoNotify,
+	powerdns.MetadataAllowAXFRFrom,
+	powerdns.MetadataTSIGAllowAXFR,
+	powerdns.MetadataSOAEdit,
+}
+
+// Metadata holds the values of the managed metadata kinds of a zone. A kind
+// without values is not set on the zone.
+type Metadata map[powerdns.MetadataKind][]string
+
+// Get returns the values of kind.
+func (m Metadata) Get(kind powerdns.MetadataKind) []string {
+	return m[kind]
+}
+
+// String returns the values of kind joined by ", ".
+func (m Metadata) String(kind powerdns.MetadataKind) string {
+	return strings.Join(m[kind], ", ")
+}
+
+// ZoneMetadata returns the managed metadata of zone.
+func (e engine) ZoneMetadata(ctx context.Context, zone string) (Metadata, error) {
+	if e.Client == nil {
+		return nil, ErrClientNotInitialized
+	}
+
+	list, err := e.Metadata.List(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	md := make(Metadata, len(ManagedMetadataKinds))
+
+	for i := range list {
+		if list[i].Kind == nil || !isManagedKind(*list[i].Kind) || len(list[i].Metadata) == 0 {
+			continue
+		}
+
+		md[*list[i].Kind] = list[i].Metadata
+	}
+
+	return md, nil
+}
+
+// SetZoneMetadata writes the managed kinds whose values differ between current
+// and next. Kinds without values in next are deleted from the zone. It returns
+// the kinds that were changed, in display order, up to the first error.
+func (e engine) SetZoneMetadata(ctx context.Context, zone string, current, next Metadata) (
+	[]powerdns.MetadataKind, error,
+) {
+	if e.Client == nil {
+		return nil, ErrClientNotInitialized
+	}
+
+	var changed []powerdns.MetadataKind
+
+	for _, kind := range ManagedMetadataKinds {
+		if equalValues(current[kind], next[kind]) {
+			continue
+		}
+
+		var err error
+		if len(next[kind]) == 0 {
+			err = e.Metadata.Delete(ctx, zone, kind)
+		} else {
+			_, err = e.Metadata.Set(ctx, zone, kind, next[kind])
+		}
+
+		if err != nil {
+			return changed, err
+		}
+
+		changed = append(changed, kind)
+	}
+
+	return changed, nil
+}
+
+// TSIGKeyNames returns the names of the TSIG keys known to PowerDNS, sorted.
+func (e engine) TSIGKeyNames(ctx context.Context) ([]string, error) {
+	if e.Client == nil {
+		return nil, ErrClientNotInitialized
+	}
+
+	keys, err := e.TSIGKeys.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(keys))
+
+	for i := range keys {
+		if keys[i].Name != nil {
+			names = append(names, *keys[i].Name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func isManagedKind(kind powerdns.MetadataKind) bool {
+	for _, k := range ManagedMetadataKinds {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -2,6 +2,9 @@ package powerdns
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/joeig/go-powerdns/v3"
@@ -13,10 +16,18 @@ import (
 
 const (
 	defaultTimeout = 30 * time.Second
+
+	// probeTimeout bounds a connection test with unsaved settings.
+	probeTimeout = 10 * time.Second
 )
 
 type engine struct {
 	*powerdns.Client
+
+	// apiKey and httpClient serve the requests the client library does not
+	// cover; see getJSON.
+	apiKey     string
+	httpClient *http.Client
 }
 
 // Engine represents the PowerDNS client engine.
@@ -41,6 +52,63 @@ func (e engine) Test() error {
 	return nil
 }
 
+// Ping checks that the PowerDNS API answers by reading the server of the
+// virtual host. Unlike Test it does not list the zones, so it is cheap enough
+// to run periodically; it goes through the circuit breaker like any request.
+func (e engine) Ping(ctx context.Context) error {
+	if e.Client == nil {
+		return ErrClientNotInitialized
+	}
+
+	_, err := e.Servers.Get(ctx, e.VHost)
+
+	return err
+}
+
+// Probe checks that the PowerDNS API answers with settings, without touching
+// Engine, and returns the server it reports for the virtual host.
+func Probe(ctx context.Context, settings *pdnsserver.Settings) (*powerdns.Server, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	return NewClient(settings).Servers.Get(ctx, settings.VHost)
+}
+
+// NewClient returns a client of the PowerDNS API of settings apart from
+// Engine, e.g. for a view. Its requests are logged like those of Engine and
+// limited to defaultTimeout, but not retried. Changes fail with ErrReadOnly
+// when settings or [pdns] readonly make the server read-only.
+func NewClient(settings *pdnsserver.Settings) *powerdns.Client {
+	readOnly := settings.ReadOnly
+
+	return powerdns.New(settings.APIServerURL, settings.VHost,
+		powerdns.WithAPIKey(settings.APIKey),
+		powerdns.WithHTTPClient(&http.Client{
+			Transport: readOnlyTransport{
+				base:     loggingTransport{base: http.DefaultTransport},
+				readOnly: func() bool { return readOnly || forcedReadOnly.Load() },
+			},
+			Timeout: defaultTimeout,
+		}),
+	)
+}
+
+// DNSServer returns the address PowerDNS is assumed to answer DNS queries on:
+// the host of the API URL on port 53. It returns "" when the client is not
+// initialized.
+func (e engine) DNSServer() string {
+	if e.Client == nil {
+		return ""
+	}
+
+	apiURL, err := url.Parse(e.BaseURL)
+	if err != nil || apiURL.Hostname() == "" {
+		return ""
+	}
+
+	return net.JoinHostPort(apiURL.Hostname(), "53")
+}
+
 // Open initializes the PowerDNS client using settings from the database.
 func Open(db *gorm.DB) error {
 	// Initialize PowerDNS client
@@ -50,8 +118,24 @@ func Open(db *gorm.DB) error {
 		return err
 	}
 
+	SetAPILogging(settings.LogAPITraffic)
+	engineReadOnly.Store(settings.ReadOnly)
+
+	transport := newResilientTransport(loggingTransport{base: http.DefaultTransport})
+	circuit.Store(transport.breaker)
+	circuitOpen.Set(0)
+
+	httpClient := &http.Client{Transport: readOnlyTransport{base: transport, readOnly: ReadOnly}}
+
 	// create new PowerDNS client
-	Engine.Client = powerdns.New(settings.APIServerURL, settings.VHost, powerdns.WithAPIKey(settings.APIKey))
+	Engine = engine{
+		Client: powerdns.New(settings.APIServerURL, settings.VHost,
+			powerdns.WithAPIKey(settings.APIKey),
+			powerdns.WithHTTPClient(httpClient),
+		),
+		apiKey:     settings.APIKey,
+		httpClient: httpClient,
+	}
 
 	return nil
 }
@@ -0,0 +1,53 @@
+package powerdns
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+var (
+	// forcedReadOnly is set by [pdns] readonly and applies to every server.
+	forcedReadOnly atomic.Bool
+
+	// engineReadOnly is the read-only setting of the primary server, applied
+	// by Open.
+	engineReadOnly atomic.Bool
+)
+
+// ForceReadOnly puts every PowerDNS server in read-only mode, regardless of
+// its settings, e.g. for [pdns] readonly.
+func ForceReadOnly(on bool) {
+	forcedReadOnly.Store(on)
+}
+
+// ReadOnly reports whether changes to the primary server are rejected.
+func ReadOnly() bool {
+	return forcedReadOnly.Load() || engineReadOnly.Load()
+}
+
+// ReadOnlyForced reports whether every server is read-only by configuration,
+// so the settings cannot turn it off.
+func ReadOnlyForced() bool {
+	return forcedReadOnly.Load()
+}
+
+// readOnlyTransport rejects every request but GET and HEAD with ErrReadOnly
+// while readOnly reports true, before it reaches PowerDNS or the circuit
+// breaker.
+type readOnlyTransport struct {
+	base     http.RoundTripper
+	readOnly func() bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t readOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead && t.readOnly() {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+
+		return nil, ErrReadOnly
+	}
+
+	return t.base.RoundTrip(req)
+}
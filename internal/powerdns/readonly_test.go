@@ -0,0 +1,48 @@
+package powerdns
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestReadOnlyTransport(t *testing.T) {
+	srv, calls := flakyServer(t, 0, http.StatusOK)
+
+	readOnly := true
+	tr := readOnlyTransport{base: http.DefaultTransport, readOnly: func() bool { return readOnly }}
+
+	if resp, err := send(t, tr, http.MethodGet, srv.URL); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET in read-only mode: %v", err)
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if _, err := send(t, tr, method, srv.URL); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s in read-only mode: error = %v, want ErrReadOnly", method, err)
+		}
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("%d requests reached the server, want only the GET", calls.Load())
+	}
+
+	readOnly = false
+
+	if _, err := send(t, tr, http.MethodPatch, srv.URL); err != nil {
+		t.Errorf("PATCH without read-only mode: error = %v", err)
+	}
+}
+
+func TestForceReadOnly(t *testing.T) {
+	t.Cleanup(func() { ForceReadOnly(false) })
+
+	if ReadOnly() {
+		t.Fatal("ReadOnly() = true by default")
+	}
+
+	ForceReadOnly(true)
+
+	if !ReadOnly() || !ReadOnlyForced() {
+		t.Error("ReadOnly() or ReadOnlyForced() = false after ForceReadOnly(true)")
+	}
+}
@@ -0,0 +1,320 @@
+package powerdns
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+const (
+	// retryBaseDelay and retryMaxDelay bound the exponential backoff between
+	// retries; the actual delay is drawn at random below the bound.
+	retryBaseDelay = 100 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+
+	// maxDrainedBody is the number of bytes read from a discarded response
+	// so its connection can be reused.
+	maxDrainedBody = 4096
+)
+
+var (
+	// clientPolicy is the policy applied by Open; see Configure.
+	clientPolicy atomic.Pointer[config.PDNSClient]
+
+	// circuit is the breaker of the current client; see Unavailable.
+	circuit atomic.Pointer[breaker]
+
+	circuitOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "powerdns_api_circuit_open",
+		Help: "1 while the PowerDNS API circuit breaker is open, 0 otherwise.",
+	})
+
+	retries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "powerdns_api_retries_total",
+		Help: "Number of retried PowerDNS API requests.",
+	})
+)
+
+// Configure sets the timeout, retry and circuit breaker policy of the
+// PowerDNS client. It takes effect with the next Open.
+func Configure(cfg config.PDNSClient) {
+	clientPolicy.Store(&cfg)
+}
+
+// Unavailable reports whether the circuit breaker is open, i.e. PowerDNS
+// failed repeatedly and has not answered a probe request since.
+func Unavailable() bool {
+	b := circuit.Load()
+
+	return b != nil && b.isOpen()
+}
+
+// resilientTransport bounds each PowerDNS API request attempt by a timeout,
+// retries idempotent requests on transient failures and rejects requests
+// while the circuit breaker is open.
+type resilientTransport struct {
+	base    http.RoundTripper
+	policy  config.PDNSClient
+	breaker *breaker
+}
+
+// newResilientTransport returns a resilientTransport around base using the
+// configured policy. Without a policy requests are passed through unchanged.
+func newResilientTransport(base http.RoundTripper) resilientTransport {
+	var policy config.PDNSClient
+	if p := clientPolicy.Load(); p != nil {
+		policy = *p
+	}
+
+	return resilientTransport{
+		base:    base,
+		policy:  policy,
+		breaker: &breaker{threshold: policy.BreakerThreshold, cooldown: policy.BreakerCooldown},
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.allow(time.Now()); err != nil {
+		return nil, err
+	}
+
+	attempts := 1
+	if idempotent(req) {
+		attempts += t.policy.Retries
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	for attempt := 1; ; attempt++ {
+		resp, err = t.attempt(req)
+		if attempt == attempts || !transient(resp, err) {
+			break
+		}
+
+		if !sleep(req.Context(), backoff(attempt)) {
+			break
+		}
+
+		if resp != nil {
+			discard(resp)
+		}
+
+		retries.Inc()
+		requestid.Logger(req.Context()).Debug().Str("method", req.Method).Str("path", req.URL.RequestURI()).
+			Int("attempt", attempt+1).Msg("retrying PowerDNS API request")
+	}
+
+	switch {
+	case req.Context().Err() != nil:
+		// The caller gave up; that says nothing about PowerDNS.
+		t.breaker.release()
+	case transient(resp, err):
+		t.breaker.failure(time.Now())
+	default:
+		t.breaker.success()
+	}
+
+	return resp, err
+}
+
+// attempt sends req once, bounded by the per-attempt timeout. The timeout
+// also covers reading the response body.
+func (t resilientTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.policy.Timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.Timeout)
+
+	resp, err := t.base.RoundTrip(req.Clone(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// idempotent reports whether req may safely be sent again.
+func idempotent(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// transient reports whether a request outcome indicates that PowerDNS is
+// unreachable or overloaded rather than that the request was wrong.
+func transient(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// backoff returns the delay after failed attempt n (n >= 1): a random
+// duration below retryBaseDelay·2^(n-1), capped at retryMaxDelay.
+func backoff(n int) time.Duration {
+	d := retryMaxDelay
+	if n < 6 {
+		d = min(retryBaseDelay<<(n-1), retryMaxDelay)
+	}
+
+	return rand.N(d) + time.Millisecond
+}
+
+// sleep waits for d and reports whether ctx is still alive.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// discard drains and closes a response that is replaced by a retry.
+func discard(resp *http.Response) {
+	_, _ = io.CopyN(io.Discard, resp.Body, maxDrainedBody)
+	_ = resp.Body.Close()
+}
+
+// cancelOnClose releases the per-attempt context once the body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer.
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
+
+// breaker is a circuit breaker. It opens after threshold consecutive
+// failures, rejects requests for cooldown and then lets a single probe
+// through: a successful probe closes it, a failed one opens it again.
+// A zero threshold disables it.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+}
+
+// allow returns ErrUnavailable while the breaker rejects requests.
+func (b *breaker) allow(now time.Time) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+
+	if b.probing || now.Sub(b.openedAt) < b.cooldown {
+		return ErrUnavailable
+	}
+
+	b.probing = true
+
+	return nil
+}
+
+// success records a request PowerDNS answered.
+func (b *breaker) success() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.open {
+		circuitOpen.Set(0)
+	}
+
+	b.failures = 0
+	b.open = false
+	b.probing = false
+}
+
+// failure records a request PowerDNS did not answer.
+func (b *breaker) failure(now time.Time) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.open || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = now
+
+		circuitOpen.Set(1)
+	}
+}
+
+// release records a request that ended without a verdict, so another probe
+// may be sent.
+func (b *breaker) release() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// isOpen reports whether the breaker is open. It stays open after the
+// cooldown until a probe succeeds.
+func (b *breaker) isOpen() bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.open
+}
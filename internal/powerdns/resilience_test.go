@@ -0,0 +1,209 @@
+package powerdns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+// flakyServer answers the first failures requests with status and every
+// later one with 200.
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+
+		_, _ = w.Write([]byte("[]"))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func newTestTransport(policy config.PDNSClient) resilientTransport {
+	return resilientTransport{
+		base:    http.DefaultTransport,
+		policy:  policy,
+		breaker: &breaker{threshold: policy.BreakerThreshold, cooldown: policy.BreakerCooldown},
+	}
+}
+
+func send(t *testing.T, tr http.RoundTripper, method, url string) (*http.Response, error) {
+	t.Helper()
+
+	req, _ := http.NewRequestWithContext(context.Background(), method, url, http.NoBody)
+
+	resp, err := tr.RoundTrip(req)
+	if resp != nil {
+		t.Cleanup(func() { _ = resp.Body.Close() })
+	}
+
+	return resp, err
+}
+
+func TestResilientTransportRetriesGet(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+	tr := newTestTransport(config.PDNSClient{Timeout: time.Second, Retries: 2})
+
+	resp, err := send(t, tr, http.MethodGet, srv.URL)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("status = %d after %d calls, want 200 after 3", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestResilientTransportDoesNotRetryWrites(t *testing.T) {
+	for _, method := range []string{http.MethodPost, http.MethodPatch, http.MethodDelete} {
+		srv, calls := flakyServer(t, 1, http.StatusBadGateway)
+		tr := newTestTransport(config.PDNSClient{Timeout: time.Second, Retries: 2})
+
+		resp, err := send(t, tr, method, srv.URL)
+		if err != nil {
+			t.Fatalf("%s: RoundTrip() error = %v", method, err)
+		}
+
+		if resp.StatusCode != http.StatusBadGateway || calls.Load() != 1 {
+			t.Errorf("%s: status = %d after %d calls, want 502 after 1", method, resp.StatusCode, calls.Load())
+		}
+	}
+}
+
+func TestResilientTransportTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	tr := newTestTransport(config.PDNSClient{Timeout: 50 * time.Millisecond})
+
+	start := time.Now()
+
+	if _, err := send(t, tr, http.MethodGet, srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RoundTrip() error = %v, want deadline exceeded", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s despite a 50ms timeout", elapsed)
+	}
+}
+
+func TestResilientTransportCircuitBreaker(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+	tr := newTestTransport(config.PDNSClient{Timeout: time.Second, BreakerThreshold: 2, BreakerCooldown: time.Hour})
+
+	for range 2 {
+		if _, err := send(t, tr, http.MethodGet, srv.URL); err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+	}
+
+	if !tr.breaker.isOpen() {
+		t.Fatal("breaker closed after reaching the threshold")
+	}
+
+	if _, err := send(t, tr, http.MethodGet, srv.URL); !errors.Is(err, ErrUnavailable) || !IsServerUnreachable(err) {
+		t.Fatalf("RoundTrip() error = %v, want ErrUnavailable", err)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("open breaker sent a request: %d calls", calls.Load())
+	}
+
+	// After the cooldown a single probe is let through; its success closes
+	// the breaker.
+	tr.breaker.openedAt = time.Now().Add(-2 * time.Hour)
+
+	resp, err := send(t, tr, http.MethodGet, srv.URL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("probe: resp = %v, err = %v", resp, err)
+	}
+
+	if tr.breaker.isOpen() {
+		t.Error("breaker still open after a successful probe")
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := &breaker{threshold: 1, cooldown: time.Minute}
+	now := time.Now()
+
+	b.failure(now)
+
+	if err := b.allow(now.Add(time.Second)); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("allow() during cooldown = %v, want ErrUnavailable", err)
+	}
+
+	if err := b.allow(now.Add(2 * time.Minute)); err != nil {
+		t.Fatalf("allow() after cooldown = %v, want probe", err)
+	}
+
+	if err := b.allow(now.Add(2 * time.Minute)); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("allow() during probe = %v, want ErrUnavailable", err)
+	}
+
+	// A failed probe restarts the cooldown.
+	b.failure(now.Add(2 * time.Minute))
+
+	if err := b.allow(now.Add(150 * time.Second)); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("allow() after failed probe = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := &breaker{}
+
+	for range 10 {
+		b.failure(time.Now())
+	}
+
+	if err := b.allow(time.Now()); err != nil || b.isOpen() {
+		t.Errorf("disabled breaker rejected a request: %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for n := 1; n <= 20; n++ {
+		if d := backoff(n); d <= 0 || d > retryMaxDelay+time.Millisecond {
+			t.Errorf("backoff(%d) = %s", n, d)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	tests := map[string]bool{
+		http.MethodGet:    true,
+		http.MethodHead:   true,
+		http.MethodPost:   false,
+		http.MethodPatch:  false,
+		http.MethodPut:    false,
+		http.MethodDelete: false,
+	}
+
+	for method, want := range tests {
+		req, _ := http.NewRequestWithContext(context.Background(), method, "http://pdns/", http.NoBody)
+		if got := idempotent(req); got != want {
+			t.Errorf("idempotent(%s) = %v, want %v", method, got, want)
+		}
+	}
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://pdns/", strings.NewReader("x"))
+	if idempotent(req) {
+		t.Error("GET with a body is idempotent")
+	}
+}
@@ -0,0 +1,169 @@
+package powerdns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+// TransferStatus is the replication state of a zone.
+type TransferStatus struct {
+	Kind           string
+	Serial         uint32
+	NotifiedSerial uint32
+	EditedSerial   uint32
+	Masters        []string
+	// LastCheck is when a secondary zone last checked its primary for a new
+	// serial; zero when it never did or for other zone kinds.
+	LastCheck time.Time
+}
+
+// transferStatusJSON is the subset of the zone JSON describing replication.
+// The client library does not decode last_check.
+type transferStatusJSON struct {
+	Name           string   `json:"name"`
+	Kind           string   `json:"kind"`
+	Serial         uint32   `json:"serial"`
+	NotifiedSerial uint32   `json:"notified_serial"`
+	EditedSerial   uint32   `json:"edited_serial"`
+	Masters        []string `json:"masters"`
+	LastCheck      int64    `json:"last_check"`
+}
+
+func (z *transferStatusJSON) status() TransferStatus {
+	status := TransferStatus{
+		Kind:           z.Kind,
+		Serial:         z.Serial,
+		NotifiedSerial: z.NotifiedSerial,
+		EditedSerial:   z.EditedSerial,
+		Masters:        z.Masters,
+	}
+
+	if z.LastCheck > 0 {
+		status.LastCheck = time.Unix(z.LastCheck, 0)
+	}
+
+	return status
+}
+
+// ZoneTransferStatus returns the replication state of zone.
+func (e engine) ZoneTransferStatus(ctx context.Context, zone string) (TransferStatus, error) {
+	var z transferStatusJSON
+	if err := e.getJSON(ctx, "zones/"+canonicalZone(zone), url.Values{"rrsets": {"false"}}, &z); err != nil {
+		return TransferStatus{}, err
+	}
+
+	return z.status(), nil
+}
+
+// ZoneTransferStatuses returns the replication state of all zones by
+// canonical zone name.
+func (e engine) ZoneTransferStatuses(ctx context.Context) (map[string]TransferStatus, error) {
+	var zones []transferStatusJSON
+	if err := e.getJSON(ctx, "zones", nil, &zones); err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]TransferStatus, len(zones))
+	for i := range zones {
+		statuses[canonicalZone(zones[i].Name)] = zones[i].status()
+	}
+
+	return statuses, nil
+}
+
+// RetrieveZone asks PowerDNS to retrieve a secondary zone from its primary
+// (AXFR) and returns the server's result message.
+func (e engine) RetrieveZone(ctx context.Context, zone string) (string, error) {
+	if e.Client == nil {
+		return "", ErrClientNotInitialized
+	}
+
+	result, err := e.Zones.AxfrRetrieve(ctx, zone)
+	if err != nil {
+		return "", err
+	}
+
+	return powerdns.StringValue(result.Result), nil
+}
+
+// NotifyZone asks PowerDNS to send a NOTIFY for zone to its secondaries and
+// returns the server's result message.
+func (e engine) NotifyZone(ctx context.Context, zone string) (string, error) {
+	if e.Client == nil {
+		return "", ErrClientNotInitialized
+	}
+
+	result, err := e.Zones.Notify(ctx, zone)
+	if err != nil {
+		return "", err
+	}
+
+	return powerdns.StringValue(result.Result), nil
+}
+
+// getJSON sends a GET request for pathFragment below the server's vhost and
+// decodes the JSON response into v.
+func (e engine) getJSON(ctx context.Context, pathFragment string, query url.Values, v any) error {
+	if e.Client == nil || e.httpClient == nil {
+		return ErrClientNotInitialized
+	}
+
+	apiURL, err := e.serverURL(pathFragment, query)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-API-Key", e.apiKey)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &powerdns.Error{StatusCode: resp.StatusCode, Status: resp.Status}
+		if json.NewDecoder(resp.Body).Decode(apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+
+		return apiErr
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// serverURL returns the URL of pathFragment below the server's vhost, or of
+// the server itself when pathFragment is empty.
+func (e engine) serverURL(pathFragment string, query url.Values) (string, error) {
+	apiURL, err := url.Parse(e.BaseURL)
+	if err != nil {
+		return "", err
+	}
+
+	apiURL.Path = "/api/v1/servers/" + e.VHost
+	if pathFragment != "" {
+		apiURL.Path += "/" + pathFragment
+	}
+
+	apiURL.RawQuery = query.Encode()
+
+	return apiURL.String(), nil
+}
+
+// canonicalZone returns zone with a single trailing dot.
+func canonicalZone(zone string) string {
+	return strings.TrimSuffix(zone, ".") + "."
+}
@@ -0,0 +1,69 @@
+package powerdns
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joeig/go-powerdns/v3"
+)
+
+func newTestEngine(t *testing.T, h http.HandlerFunc) engine {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	return engine{
+		Client:     powerdns.New(srv.URL, "localhost", powerdns.WithAPIKey("secret")),
+		apiKey:     "secret",
+		httpClient: srv.Client(),
+	}
+}
+
+func TestZoneTransferStatus(t *testing.T) {
+	e := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/servers/localhost/zones/example.com." || r.URL.Query().Get("rrsets") != "false" ||
+			r.Header.Get("X-API-Key") != "secret" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"example.com.","kind":"Slave","serial":2024010102,` +
+			`"notified_serial":2024010101,"masters":["192.0.2.1"],"last_check":1704100000}`))
+	})
+
+	status, err := e.ZoneTransferStatus(t.Context(), "example.com")
+	if err != nil {
+		t.Fatalf("ZoneTransferStatus() error: %v", err)
+	}
+
+	if status.Kind != "Slave" || status.Serial != 2024010102 || status.NotifiedSerial != 2024010101 ||
+		!status.LastCheck.Equal(time.Unix(1704100000, 0)) || len(status.Masters) != 1 {
+		t.Errorf("ZoneTransferStatus() = %+v", status)
+	}
+}
+
+func TestZoneTransferStatuses_Error(t *testing.T) {
+	e := newTestEngine(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"error":"boom"}`))
+	})
+
+	_, err := e.ZoneTransferStatuses(t.Context())
+
+	var apiErr *powerdns.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Message != "boom" {
+		t.Errorf("ZoneTransferStatuses() error = %v", err)
+	}
+}
+
+func TestZoneTransferStatus_NotInitialized(t *testing.T) {
+	if _, err := (engine{}).ZoneTransferStatus(t.Context(), "example.com."); !errors.Is(err, ErrClientNotInitialized) {
+		t.Errorf("error = %v, want ErrClientNotInitialized", err)
+	}
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	l := New(2, 3)
+	now := time.Unix(1_700_000_000, 0)
+
+	for i := range 3 {
+		if ok, _ := l.Allow("a", now); !ok {
+			t.Fatalf("request %d within burst rejected", i+1)
+		}
+	}
+
+	ok, wait := l.Allow("a", now)
+	if ok {
+		t.Fatal("request over burst allowed")
+	}
+
+	if wait != 500*time.Millisecond {
+		t.Errorf("wait = %v, want 500ms", wait)
+	}
+
+	// Other keys have their own bucket.
+	if ok, _ := l.Allow("b", now); !ok {
+		t.Error("request of another key rejected")
+	}
+
+	// Two tokens per second refill.
+	now = now.Add(time.Second)
+	for i := range 2 {
+		if ok, _ := l.Allow("a", now); !ok {
+			t.Fatalf("refilled request %d rejected", i+1)
+		}
+	}
+
+	if ok, _ := l.Allow("a", now); ok {
+		t.Error("request over refill allowed")
+	}
+
+	// The bucket never holds more than burst tokens.
+	now = now.Add(time.Hour)
+	for range 3 {
+		l.Allow("a", now)
+	}
+
+	if ok, _ := l.Allow("a", now); ok {
+		t.Error("bucket refilled over burst")
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	// One token per minute.
+	l := New(1.0/60, 2)
+	now := time.Unix(1_700_000_000, 0)
+
+	l.Allow("idle", now)
+	l.Allow("busy", now)
+	l.Allow("busy", now)
+
+	// A minute later "idle" is full again and dropped, "busy" is not.
+	l.Allow("other", now.Add(sweepInterval))
+
+	if got := l.Len(); got != 2 {
+		t.Errorf("Len() after sweep = %d, want 2", got)
+	}
+
+	if ok, _ := l.Allow("busy", now.Add(sweepInterval)); !ok {
+		t.Error("refilled token of kept bucket rejected")
+	}
+
+	if ok, _ := l.Allow("busy", now.Add(sweepInterval)); ok {
+		t.Error("kept bucket lost its state")
+	}
+}
+
+func TestLimiterRefund(t *testing.T) {
+	l := New(0.001, 2)
+	now := time.Unix(1_700_000_000, 0)
+
+	l.Allow("a", now)
+	l.Allow("a", now)
+	l.Refund("a")
+
+	if ok, _ := l.Allow("a", now); !ok {
+		t.Fatal("refunded token rejected")
+	}
+
+	if ok, _ := l.Allow("a", now); ok {
+		t.Error("request over burst allowed after refund")
+	}
+
+	// A refund never fills the bucket over burst.
+	l.Refund("b")
+	l.Allow("c", now)
+	l.Refund("c")
+	l.Refund("c")
+
+	for range 2 {
+		l.Allow("c", now)
+	}
+
+	if ok, _ := l.Allow("c", now); ok {
+		t.Error("bucket refunded over burst")
+	}
+}
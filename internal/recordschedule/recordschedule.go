@@ -0,0 +1,148 @@
+// Package recordschedule enables and disables DNS records at a scheduled
+// time. Schedules are stored in the database and applied through the
+// PowerDNS API by a background Runner.
+package recordschedule
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+var (
+	// ErrRecordNotFound is returned when the scheduled record is not in the zone.
+	ErrRecordNotFound = errors.New("record not found in zone")
+	// ErrNotPending is returned when canceling a schedule that is not pending.
+	ErrNotPending = errors.New("schedule is not pending")
+)
+
+// Pending returns the pending schedules of zoneName, earliest first.
+func Pending(db *gorm.DB, zoneName string) ([]models.RecordSchedule, error) {
+	var schedules []models.RecordSchedule
+
+	err := db.Preload("CreatedBy").
+		Where("zone_name = ? AND status = ?", zoneName, models.RecordSchedulePending).
+		Order("run_at, id").
+		Find(&schedules).Error
+
+	return schedules, err
+}
+
+// Cancel cancels the pending schedule id of zoneName.
+func Cancel(db *gorm.DB, zoneName string, id uint64) (*models.RecordSchedule, error) {
+	var schedule models.RecordSchedule
+	if err := db.Where("id = ? AND zone_name = ?", id, zoneName).First(&schedule).Error; err != nil {
+		return nil, err
+	}
+
+	res := db.Model(&models.RecordSchedule{}).
+		Where("id = ? AND status = ?", id, models.RecordSchedulePending).
+		Update("status", models.RecordScheduleCanceled)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, ErrNotPending
+	}
+
+	schedule.Status = models.RecordScheduleCanceled
+
+	return &schedule, nil
+}
+
+// Find returns the RRset of zone holding the record name/rrType with content.
+func Find(zone *pdnsapi.Zone, name, rrType, content string) (*pdnsapi.RRset, bool) {
+	for i := range zone.RRsets {
+		rrSet := &zone.RRsets[i]
+		if rrSet.Name == nil || rrSet.Type == nil ||
+			!strings.EqualFold(*rrSet.Name, name) || string(*rrSet.Type) != rrType {
+			continue
+		}
+
+		for _, rec := range rrSet.Records {
+			if pdnsapi.StringValue(rec.Content) == content {
+				return rrSet, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Apply enables or disables the record of s in PowerDNS. The other records
+// and the comments of the RRset are left as they are.
+func Apply(ctx context.Context, s *models.RecordSchedule) error {
+	if powerdns.Engine.Client == nil {
+		return powerdns.ErrClientNotInitialized
+	}
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, s.ZoneName)
+	if err != nil {
+		return err
+	}
+
+	rrSet, ok := Find(zone, s.Name, s.Type, s.Content)
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	patch := withRecordDisabled(rrSet, s.Content, s.Disabled())
+
+	return powerdns.Engine.Records.Patch(ctx, s.ZoneName, &pdnsapi.RRsets{Sets: []pdnsapi.RRset{patch}})
+}
+
+// withRecordDisabled returns a REPLACE of rrSet in which the record with
+// content is disabled or enabled.
+func withRecordDisabled(rrSet *pdnsapi.RRset, content string, disabled bool) pdnsapi.RRset {
+	records := make([]pdnsapi.Record, len(rrSet.Records))
+
+	for i, rec := range rrSet.Records {
+		records[i] = pdnsapi.Record{Content: rec.Content, Disabled: rec.Disabled}
+		if pdnsapi.StringValue(rec.Content) == content {
+			records[i].Disabled = pdnsapi.Bool(disabled)
+		}
+	}
+
+	changeType := pdnsapi.ChangeTypeReplace
+
+	return pdnsapi.RRset{
+		Name:       rrSet.Name,
+		Type:       rrSet.Type,
+		TTL:        rrSet.TTL,
+		ChangeType: &changeType,
+		Records:    records,
+		Comments:   rrSet.Comments,
+	}
+}
+
+// due returns up to limit pending schedules whose time has come, earliest
+// first.
+func due(db *gorm.DB, now time.Time, limit int) ([]models.RecordSchedule, error) {
+	var schedules []models.RecordSchedule
+
+	err := db.Preload("CreatedBy").
+		Where("status = ? AND run_at <= ?", models.RecordSchedulePending, now).
+		Order("run_at, id").
+		Limit(limit).
+		Find(&schedules).Error
+
+	return schedules, err
+}
+
+// claim marks a pending schedule as running. It reports false when another
+// instance claimed or a user canceled it first.
+func claim(db *gorm.DB, id uint64) (bool, error) {
+	res := db.Model(&models.RecordSchedule{}).
+		Where("id = ? AND status = ?", id, models.RecordSchedulePending).
+		Update("status", models.RecordScheduleRunning)
+
+	return res.RowsAffected == 1, res.Error
+}
@@ -0,0 +1,150 @@
+package recordschedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.RecordSchedule{}, &models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func newSchedule(t *testing.T, db *gorm.DB, runAt time.Time) *models.RecordSchedule {
+	t.Helper()
+
+	s := &models.RecordSchedule{
+		ZoneName: "example.com.", Name: "www.example.com.", Type: "A", Content: "192.0.2.1",
+		Action: models.RecordScheduleDisable, RunAt: runAt, Status: models.RecordSchedulePending,
+	}
+	if err := db.Create(s).Error; err != nil {
+		t.Fatalf("failed to create schedule: %v", err)
+	}
+
+	return s
+}
+
+func TestPendingAndCancel(t *testing.T) {
+	db := newTestDB(t)
+	later := newSchedule(t, db, now.Add(2*time.Hour))
+	sooner := newSchedule(t, db, now.Add(time.Hour))
+
+	pending, err := Pending(db, "example.com.")
+	if err != nil || len(pending) != 2 || pending[0].ID != sooner.ID {
+		t.Fatalf("Pending() = %+v, %v", pending, err)
+	}
+
+	if _, err = Cancel(db, "other.com.", later.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Cancel() in another zone error = %v, want not found", err)
+	}
+
+	if _, err = Cancel(db, "example.com.", later.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	if _, err = Cancel(db, "example.com.", later.ID); !errors.Is(err, ErrNotPending) {
+		t.Errorf("second Cancel() error = %v, want ErrNotPending", err)
+	}
+
+	if pending, _ = Pending(db, "example.com."); len(pending) != 1 {
+		t.Errorf("Pending() after cancel = %d schedules, want 1", len(pending))
+	}
+}
+
+func TestWithRecordDisabled(t *testing.T) {
+	ttl := uint32(300)
+	rrSet := &pdnsapi.RRset{
+		Name: pdnsapi.String("www.example.com."), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeA), TTL: &ttl,
+		Records: []pdnsapi.Record{
+			{Content: pdnsapi.String("192.0.2.1"), Disabled: pdnsapi.Bool(false)},
+			{Content: pdnsapi.String("192.0.2.2"), Disabled: pdnsapi.Bool(true)},
+		},
+		Comments: []pdnsapi.Comment{{Content: pdnsapi.String("web")}},
+	}
+
+	zone := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{*rrSet}}
+	if _, ok := Find(zone, "WWW.example.com.", "A", "192.0.2.1"); !ok {
+		t.Fatal("Find() did not find the record")
+	}
+
+	if _, ok := Find(zone, "www.example.com.", "A", "192.0.2.9"); ok {
+		t.Error("Find() found a record with other content")
+	}
+
+	patch := withRecordDisabled(rrSet, "192.0.2.1", true)
+
+	if *patch.ChangeType != pdnsapi.ChangeTypeReplace || len(patch.Comments) != 1 || *patch.TTL != 300 {
+		t.Errorf("patch = %+v", patch)
+	}
+
+	if !*patch.Records[0].Disabled || !*patch.Records[1].Disabled {
+		t.Errorf("records = %v, %v; want both disabled", *patch.Records[0].Disabled, *patch.Records[1].Disabled)
+	}
+
+	if *rrSet.Records[0].Disabled {
+		t.Error("withRecordDisabled() modified the current RRset")
+	}
+}
+
+func TestRunOnceRecordsFailure(t *testing.T) {
+	db := newTestDB(t)
+	dueSchedule := newSchedule(t, db, now.Add(-time.Minute))
+	future := newSchedule(t, db, now.Add(time.Hour))
+
+	prev := powerdns.Engine.Client
+	powerdns.Engine.Client = nil
+
+	t.Cleanup(func() { powerdns.Engine.Client = prev })
+
+	r := &Runner{db: db, now: func() time.Time { return now }}
+
+	if err := r.runOnce(context.Background()); !errors.Is(err, powerdns.ErrClientNotInitialized) {
+		t.Fatalf("runOnce() error = %v, want ErrClientNotInitialized", err)
+	}
+
+	var got models.RecordSchedule
+
+	db.First(&got, dueSchedule.ID)
+
+	if got.Status != models.RecordScheduleFailed || got.Error == "" || got.AppliedAt == nil {
+		t.Errorf("due schedule = %+v, want failed", got)
+	}
+
+	var pending models.RecordSchedule
+
+	db.First(&pending, future.ID)
+
+	if pending.Status != models.RecordSchedulePending {
+		t.Errorf("future schedule status = %s, want pending", pending.Status)
+	}
+
+	var entries int64
+
+	db.Model(&models.ActivityLog{}).Where("action = ?", activitylog.ActionRecordScheduleApplied).Count(&entries)
+
+	if entries != 1 {
+		t.Errorf("activity entries = %d, want 1", entries)
+	}
+}
@@ -0,0 +1,149 @@
+package recordschedule
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// checkInterval is how often due schedules are looked for. Schedules
+	// are applied up to this late.
+	checkInterval = 30 * time.Second
+
+	// batchSize is the number of due schedules applied per check.
+	batchSize = 100
+
+	// applyTimeout bounds the PowerDNS requests of one schedule.
+	applyTimeout = 30 * time.Second
+)
+
+// Runner applies due schedules in the background.
+type Runner struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewRunner returns a Runner for the schedules in db.
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{db: db, now: time.Now}
+}
+
+// Run applies due schedules every checkInterval until ctx is canceled.
+func (r *Runner) Run(ctx context.Context) {
+	jobs.Scheduled(jobs.RecordSchedules, checkInterval)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.RecordSchedules, func() error { return r.runOnce(ctx) })
+		}
+	}
+}
+
+// runOnce applies the due schedules. Each schedule is claimed first, so
+// several instances sharing a database apply it only once. The returned error
+// joins the errors of the schedules that failed.
+func (r *Runner) runOnce(ctx context.Context) error {
+	schedules, err := due(r.db, r.now(), batchSize)
+	if err != nil {
+		log.Error().Err(err).Msg("recordschedule: failed to load due schedules")
+		return err
+	}
+
+	var errs []error
+
+	for i := range schedules {
+		s := &schedules[i]
+
+		ok, err := claim(r.db, s.ID)
+		if err != nil {
+			log.Error().Err(err).Uint64("schedule_id", s.ID).Msg("recordschedule: failed to claim schedule")
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err = r.apply(ctx, s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// apply applies a claimed schedule, stores the outcome and records it in the
+// activity log on behalf of the user who created the schedule.
+func (r *Runner) apply(ctx context.Context, s *models.RecordSchedule) error {
+	applyCtx, cancel := context.WithTimeout(ctx, applyTimeout)
+	defer cancel()
+
+	applyErr := Apply(applyCtx, s)
+
+	now := r.now()
+	updates := map[string]any{"status": models.RecordScheduleDone, "applied_at": now, "error": ""}
+
+	if applyErr != nil {
+		updates["status"] = models.RecordScheduleFailed
+		updates["error"] = applyErr.Error()
+
+		log.Error().Err(applyErr).Uint64("schedule_id", s.ID).Str("zone_name", s.ZoneName).
+			Str("record", s.Name).Str("type", s.Type).Msg("recordschedule: failed to apply schedule")
+	} else {
+		log.Info().Uint64("schedule_id", s.ID).Str("zone_name", s.ZoneName).Str("record", s.Name).
+			Str("type", s.Type).Str("action", string(s.Action)).Msg("recordschedule: schedule applied")
+
+		zoneindex.Default.RefreshZone(applyCtx, s.ZoneName)
+	}
+
+	if err := r.db.Model(s).Updates(updates).Error; err != nil {
+		log.Error().Err(err).Uint64("schedule_id", s.ID).Msg("recordschedule: failed to store schedule result")
+	}
+
+	username := "system"
+	if s.CreatedBy != nil {
+		username = s.CreatedBy.Username
+	}
+
+	details := map[string]any{
+		"schedule_id": s.ID,
+		"name":        s.Name,
+		"type":        s.Type,
+		"content":     s.Content,
+		"action":      s.Action,
+		"run_at":      s.RunAt.UTC().Format(time.RFC3339),
+	}
+	if applyErr != nil {
+		details["error"] = applyErr.Error()
+	}
+
+	activitylog.Record(&activitylog.Entry{
+		DB:           r.db,
+		UserID:       s.CreatedByID,
+		Username:     username,
+		Action:       activitylog.ActionRecordScheduleApplied,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: s.ZoneName,
+		Details:      details,
+	})
+
+	return applyErr
+}
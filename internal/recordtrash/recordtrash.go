@@ -0,0 +1,200 @@
+// Package recordtrash keeps the RRsets deleted from a zone in the trash of
+// the zone for the retention configured by [recordtrash], from which they can
+// be restored. Expired entries are purged by the Runner.
+package recordtrash
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// ErrExists is returned by Restore when the zone holds an RRset of the same
+// name and type again.
+var ErrExists = errors.New("the RRset exists again; edit it in the zone editor instead")
+
+// Deleted returns the RRsets of current that the DELETE changes in rrSets
+// remove. RRsets current does not hold are left out.
+func Deleted(current *pdnsapi.Zone, rrSets []pdnsapi.RRset) []pdnsapi.RRset {
+	if current == nil {
+		return nil
+	}
+
+	deleted := make(map[string]bool)
+
+	for _, rr := range rrSets {
+		if rr.ChangeType != nil && *rr.ChangeType == pdnsapi.ChangeTypeDelete && rr.Name != nil && rr.Type != nil {
+			deleted[key(*rr.Name, string(*rr.Type))] = true
+		}
+	}
+
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	var out []pdnsapi.RRset
+
+	for _, rr := range current.RRsets {
+		if rr.Name != nil && rr.Type != nil && len(rr.Records) > 0 && deleted[key(*rr.Name, string(*rr.Type))] {
+			out = append(out, rr)
+		}
+	}
+
+	return out
+}
+
+// Add puts rrSets, deleted from zoneName by userID at now, into the trash of
+// the zone. It stores nothing when the trash is disabled.
+func Add(
+	db *gorm.DB, cfg config.RecordTrash, zoneName string, rrSets []pdnsapi.RRset, userID *uint64, now time.Time,
+) error {
+	if !cfg.Enabled() || len(rrSets) == 0 {
+		return nil
+	}
+
+	entries := make([]models.DeletedRRset, 0, len(rrSets))
+
+	for _, rr := range rrSets {
+		records, err := json.Marshal(rr.Records)
+		if err != nil {
+			return err
+		}
+
+		comments, err := json.Marshal(rr.Comments)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, models.DeletedRRset{
+			ZoneName:    zoneName,
+			Name:        pdnsapi.StringValue(rr.Name),
+			Type:        string(*rr.Type),
+			TTL:         pdnsapi.Uint32Value(rr.TTL),
+			Records:     string(records),
+			Comments:    string(comments),
+			DeletedByID: userID,
+			ExpiresAt:   now.Add(cfg.Retention),
+		})
+	}
+
+	return db.Create(&entries).Error
+}
+
+// List returns the unexpired entries in the trash of zoneName, newest first.
+func List(db *gorm.DB, zoneName string, now time.Time) ([]models.DeletedRRset, error) {
+	var entries []models.DeletedRRset
+
+	err := db.Preload("DeletedBy").
+		Where("zone_name = ? AND expires_at > ?", zoneName, now).
+		Order("id DESC").
+		Find(&entries).Error
+
+	return entries, err
+}
+
+// Count returns the number of unexpired entries in the trash of zoneName.
+func Count(db *gorm.DB, zoneName string, now time.Time) (int64, error) {
+	var n int64
+
+	err := db.Model(&models.DeletedRRset{}).
+		Where("zone_name = ? AND expires_at > ?", zoneName, now).
+		Count(&n).Error
+
+	return n, err
+}
+
+// Get returns the unexpired entry id in the trash of zoneName.
+func Get(db *gorm.DB, zoneName string, id uint64, now time.Time) (*models.DeletedRRset, error) {
+	var entry models.DeletedRRset
+
+	err := db.Where("zone_name = ? AND expires_at > ?", zoneName, now).First(&entry, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// Remove deletes entry id from the trash, once it was restored.
+func Remove(db *gorm.DB, id uint64) error {
+	return db.Delete(&models.DeletedRRset{}, id).Error
+}
+
+// Purge deletes the entries that expired by now and returns their number.
+func Purge(db *gorm.DB, now time.Time) (int64, error) {
+	res := db.Where("expires_at <= ?", now).Delete(&models.DeletedRRset{})
+
+	return res.RowsAffected, res.Error
+}
+
+// Restore returns the change that puts entry back into current, the zone
+// it was deleted from, with its records and comments. It returns ErrExists
+// when current holds an RRset of the same name and type again, so that a
+// restore never overwrites records added since.
+func Restore(current *pdnsapi.Zone, entry *models.DeletedRRset) (pdnsapi.RRset, error) {
+	k := key(entry.Name, entry.Type)
+
+	for _, rr := range current.RRsets {
+		if rr.Name != nil && rr.Type != nil && len(rr.Records) > 0 && key(*rr.Name, string(*rr.Type)) == k {
+			return pdnsapi.RRset{}, ErrExists
+		}
+	}
+
+	var records []pdnsapi.Record
+	if err := json.Unmarshal([]byte(entry.Records), &records); err != nil {
+		return pdnsapi.RRset{}, err
+	}
+
+	var comments []pdnsapi.Comment
+	if entry.Comments != "" {
+		if err := json.Unmarshal([]byte(entry.Comments), &comments); err != nil {
+			return pdnsapi.RRset{}, err
+		}
+	}
+
+	name := entry.Name
+	rrType := pdnsapi.RRType(entry.Type)
+	ttl := entry.TTL
+	replace := pdnsapi.ChangeTypeReplace
+
+	return pdnsapi.RRset{
+		Name:       &name,
+		Type:       &rrType,
+		TTL:        &ttl,
+		ChangeType: &replace,
+		Records:    records,
+		Comments:   comments,
+	}, nil
+}
+
+// Records decodes the record contents of entry for display, disabled ones
+// included.
+func Records(entry *models.DeletedRRset) []string {
+	var records []pdnsapi.Record
+	if err := json.Unmarshal([]byte(entry.Records), &records); err != nil {
+		return nil
+	}
+
+	contents := make([]string, 0, len(records))
+	for _, r := range records {
+		contents = append(contents, pdnsapi.StringValue(r.Content))
+	}
+
+	return contents
+}
+
+func key(name, rrType string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name + "/" + strings.ToUpper(rrType)
+}
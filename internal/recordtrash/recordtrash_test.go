@@ -0,0 +1,141 @@
+package recordtrash
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+const testZone = "example.com."
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.User{}, &models.DeletedRRset{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return db
+}
+
+func rrset(name string, rrType pdnsapi.RRType, content string) pdnsapi.RRset {
+	ttl := uint32(300)
+	disabled := false
+
+	return pdnsapi.RRset{
+		Name:    &name,
+		Type:    &rrType,
+		TTL:     &ttl,
+		Records: []pdnsapi.Record{{Content: &content, Disabled: &disabled}},
+	}
+}
+
+func deleteChange(name string, rrType pdnsapi.RRType) pdnsapi.RRset {
+	remove := pdnsapi.ChangeTypeDelete
+
+	return pdnsapi.RRset{Name: &name, Type: &rrType, ChangeType: &remove}
+}
+
+func TestDeleted(t *testing.T) {
+	current := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		rrset("www.example.com.", pdnsapi.RRTypeA, "192.0.2.2"),
+		rrset("mail.example.com.", pdnsapi.RRTypeA, "192.0.2.25"),
+	}}
+
+	replace := rrset("mail.example.com.", pdnsapi.RRTypeA, "192.0.2.26")
+	changeType := pdnsapi.ChangeTypeReplace
+	replace.ChangeType = &changeType
+
+	got := Deleted(current, []pdnsapi.RRset{
+		deleteChange("WWW.example.com.", pdnsapi.RRTypeA),
+		deleteChange("gone.example.com.", pdnsapi.RRTypeA),
+		replace,
+	})
+
+	if len(got) != 1 || *got[0].Name != "www.example.com." {
+		t.Fatalf("Deleted() = %+v, want the www A RRset", got)
+	}
+}
+
+func TestAddListRestore(t *testing.T) {
+	db := newTestDB(t)
+	cfg := config.RecordTrash{Retention: 24 * time.Hour}
+	now := time.Now()
+
+	www := rrset("www.example.com.", pdnsapi.RRTypeA, "192.0.2.2")
+	www.Comments = []pdnsapi.Comment{{Content: pdnsapi.String("web server")}}
+
+	if err := Add(db, cfg, testZone, []pdnsapi.RRset{www}, nil, now); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	entries, err := List(db, testZone, now)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List() = %v, %v, want 1 entry", entries, err)
+	}
+
+	if n, _ := Count(db, testZone, now); n != 1 {
+		t.Errorf("Count() = %d, want 1", n)
+	}
+
+	entry, err := Get(db, testZone, entries[0].ID, now)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if _, err = Get(db, "other.com.", entries[0].ID, now); err == nil {
+		t.Error("Get() of another zone's entry succeeded")
+	}
+
+	change, err := Restore(&pdnsapi.Zone{}, entry)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	if *change.ChangeType != pdnsapi.ChangeTypeReplace || *change.TTL != 300 ||
+		len(change.Records) != 1 || *change.Records[0].Content != "192.0.2.2" ||
+		len(change.Comments) != 1 || *change.Comments[0].Content != "web server" {
+		t.Errorf("Restore() = %+v", change)
+	}
+
+	current := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{rrset("www.example.com.", pdnsapi.RRTypeA, "192.0.2.3")}}
+	if _, err = Restore(current, entry); !errors.Is(err, ErrExists) {
+		t.Errorf("Restore() over an existing RRset error = %v, want ErrExists", err)
+	}
+
+	// Expired entries are hidden and purged.
+	later := now.Add(25 * time.Hour)
+	if entries, _ = List(db, testZone, later); len(entries) != 0 {
+		t.Errorf("List() after expiry = %d entries, want 0", len(entries))
+	}
+
+	if n, err := Purge(db, later); err != nil || n != 1 {
+		t.Errorf("Purge() = %d, %v, want 1", n, err)
+	}
+}
+
+func TestAddDisabled(t *testing.T) {
+	db := newTestDB(t)
+
+	err := Add(db, config.RecordTrash{Retention: -1}, testZone,
+		[]pdnsapi.RRset{rrset("www.example.com.", pdnsapi.RRTypeA, "192.0.2.2")}, nil, time.Now())
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if n, _ := Count(db, testZone, time.Now()); n != 0 {
+		t.Errorf("Count() = %d, want 0 with the trash disabled", n)
+	}
+}
@@ -0,0 +1,57 @@
+package recordtrash
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+)
+
+// purgeInterval is how often expired entries are purged. List and Get leave
+// them out already; they stay in the database for up to this long.
+const purgeInterval = time.Hour
+
+// Runner purges the expired entries of the trash.
+type Runner struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewRunner returns a Runner for the trash in db.
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{db: db, now: time.Now}
+}
+
+// Run purges expired entries every purgeInterval until ctx is canceled.
+func (r *Runner) Run(ctx context.Context) {
+	jobs.Scheduled(jobs.RecordTrash, purgeInterval)
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.RecordTrash, r.runOnce)
+		}
+	}
+}
+
+func (r *Runner) runOnce() error {
+	n, err := Purge(r.db, r.now())
+	if err != nil {
+		log.Error().Err(err).Msg("recordtrash: failed to purge expired entries")
+		return err
+	}
+
+	if n > 0 {
+		log.Info().Int64("purged", n).Msg("recordtrash: purged expired entries")
+	}
+
+	return nil
+}
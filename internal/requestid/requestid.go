@@ -0,0 +1,77 @@
+// Package requestid carries the ID of the HTTP request being served through
+// a context.Context, together with a logger that adds it to every entry, so
+// that the log lines of one request (including the PowerDNS API calls it
+// makes) can be found by ID.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Header is the HTTP header carrying the request ID, both from clients and
+	// proxies and to PowerDNS.
+	Header = "X-Request-ID"
+
+	// Field is the log field holding the request ID.
+	Field = "request_id"
+
+	// maxLen is the longest accepted client-supplied request ID.
+	maxLen = 128
+)
+
+type ctxKey struct{}
+
+// New returns a new random request ID.
+func New() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
+
+// Valid reports whether id can be used as request ID: 1 to 128 visible ASCII
+// characters.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewContext returns a copy of ctx carrying id and a logger that adds it to
+// every entry.
+func NewContext(ctx context.Context, id string) context.Context {
+	logger := log.Logger.With().Str(Field, id).Logger()
+
+	return logger.WithContext(context.WithValue(ctx, ctxKey{}, id))
+}
+
+// FromContext returns the request ID of ctx, or "" when it has none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+
+	return id
+}
+
+// Logger returns the logger of ctx, or the global logger when ctx carries no
+// request ID.
+func Logger(ctx context.Context) *zerolog.Logger {
+	if FromContext(ctx) == "" {
+		return &log.Logger
+	}
+
+	return zerolog.Ctx(ctx)
+}
@@ -0,0 +1,62 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestValid(t *testing.T) {
+	tests := map[string]bool{
+		"":                       false,
+		"abc-123":                true,
+		"trace/1.2:3":            true,
+		"with space":             false,
+		"tab\there":              false,
+		"ünïcode":                false,
+		strings.Repeat("a", 128): true,
+		strings.Repeat("a", 129): false,
+	}
+
+	for id, want := range tests {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, got, want)
+		}
+	}
+
+	if id := New(); !Valid(id) || len(id) != 32 {
+		t.Errorf("New() = %q, want 32 hex characters", id)
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	var out bytes.Buffer
+
+	prev := log.Logger
+	log.Logger = zerolog.New(&out)
+
+	t.Cleanup(func() { log.Logger = prev })
+
+	if FromContext(context.Background()) != "" {
+		t.Fatal("FromContext() of a plain context is not empty")
+	}
+
+	if Logger(context.Background()) != &log.Logger {
+		t.Fatal("Logger() of a plain context is not the global logger")
+	}
+
+	ctx := NewContext(context.Background(), "req-1")
+	if got := FromContext(ctx); got != "req-1" {
+		t.Fatalf("FromContext() = %q, want req-1", got)
+	}
+
+	Logger(ctx).Info().Msg("hello")
+
+	if !strings.Contains(out.String(), `"request_id":"req-1"`) {
+		t.Errorf("log line lacks the request ID: %s", out.String())
+	}
+}
@@ -0,0 +1,69 @@
+package servermonitor
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// Availability sums up the checks of a server in a period.
+type Availability struct {
+	Server string
+	Checks int64
+	OK     int64
+	// AvgLatencyMS is the average latency of the successful checks.
+	AvgLatencyMS float64
+}
+
+// Ratio returns the share of successful checks, 1 without any check.
+func (a Availability) Ratio() float64 {
+	if a.Checks == 0 {
+		return 1
+	}
+
+	return float64(a.OK) / float64(a.Checks)
+}
+
+// Percent returns Ratio as a percentage, or "–" without any check.
+func (a Availability) Percent() string {
+	if a.Checks == 0 {
+		return "–"
+	}
+
+	return fmt.Sprintf("%.2f%%", a.Ratio()*100)
+}
+
+// Availabilities returns the availability of each server checked since
+// since, by server.
+func Availabilities(db *gorm.DB, since time.Time) (map[string]Availability, error) {
+	var rows []Availability
+
+	err := db.Model(&models.ServerCheck{}).
+		Select("server, COUNT(*) AS checks, " +
+			"SUM(CASE WHEN ok THEN 1 ELSE 0 END) AS ok, " +
+			"COALESCE(AVG(CASE WHEN ok THEN latency_ms END), 0) AS avg_latency_ms").
+		Where("checked_at >= ?", since).
+		Group("server").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byServer := make(map[string]Availability, len(rows))
+	for _, row := range rows {
+		byServer[row.Server] = row
+	}
+
+	return byServer, nil
+}
+
+// Failures returns the last limit failed checks, newest first.
+func Failures(db *gorm.DB, limit int) ([]models.ServerCheck, error) {
+	var checks []models.ServerCheck
+
+	err := db.Where("ok = ?", false).Order("checked_at DESC").Limit(limit).Find(&checks).Error
+
+	return checks, err
+}
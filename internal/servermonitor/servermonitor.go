@@ -0,0 +1,308 @@
+// Package servermonitor checks periodically that the PowerDNS API of the
+// primary server and of each view answers.
+//
+// Every check is stored as a models.ServerCheck, from which the availability
+// under Admin → System Information → PowerDNS Availability is computed. The
+// current status of each server is kept in memory for the header of every
+// page. A server failing the configured number of consecutive checks is down:
+// this is logged and published as eventbus.TopicServerHealth, so the chat
+// integrations with health events are alerted, and again when it recovers.
+package servermonitor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/splithorizon"
+)
+
+const (
+	// Primary is the Server of the primary PowerDNS server.
+	Primary = ""
+
+	// checkTimeout bounds the check of one server.
+	checkTimeout = 10 * time.Second
+
+	// maxErrorLength fits models.ServerCheck.Error.
+	maxErrorLength = 500
+)
+
+// Checker checks that the API of view answers, or of the primary server when
+// view is nil.
+type Checker func(ctx context.Context, view *models.PDNSView) error
+
+// check pings the primary server through the shared engine, so that a
+// successful check also closes its circuit breaker, and probes the API of a
+// view with a client of its own.
+func check(ctx context.Context, view *models.PDNSView) error {
+	if view == nil {
+		ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+		defer cancel()
+
+		return powerdns.Engine.Ping(ctx)
+	}
+
+	_, err := powerdns.Probe(ctx, splithorizon.Settings(view))
+
+	return err
+}
+
+// Status is the current status of a server.
+type Status struct {
+	// Server is the name of the view, or Primary.
+	Server string
+	// Down is set once the threshold of consecutive failures is reached and
+	// cleared by the next successful check.
+	Down bool
+	// Failures counts the consecutive failed checks.
+	Failures int
+	// Error is why the last check failed; empty when it succeeded.
+	Error     string
+	LatencyMS int64
+	CheckedAt time.Time
+	// Since is when the server went down or came back up; zero while it has
+	// been up since the monitor started.
+	Since time.Time
+}
+
+// Label names the server in messages and pages.
+func (s Status) Label() string {
+	return Label(s.Server)
+}
+
+// Label names server in messages and pages.
+func Label(server string) string {
+	if server == Primary {
+		return "PowerDNS"
+	}
+
+	return "PowerDNS view " + server
+}
+
+// Monitor checks the servers and keeps their status.
+type Monitor struct {
+	check Checker
+	now   func() time.Time
+
+	mu       sync.RWMutex
+	db       *gorm.DB
+	cfg      config.Monitor
+	bus      *eventbus.Bus
+	statuses map[string]Status
+}
+
+// Default is the monitor shared by the web handlers.
+var Default = New(check)
+
+// New returns a monitor checking the servers with check.
+func New(check Checker) *Monitor {
+	return &Monitor{check: check, now: time.Now, statuses: map[string]Status{}}
+}
+
+// Configure sets the database the views are read from and the checks are
+// stored in, and the interval, threshold and retention of cfg.
+func (m *Monitor) Configure(db *gorm.DB, cfg config.Monitor) {
+	m.mu.Lock()
+	m.db, m.cfg = db, cfg
+	m.mu.Unlock()
+}
+
+// PublishTo publishes eventbus.TopicServerHealth on bus, keyed by the
+// server, when a server goes down or recovers. Each replica checks on its
+// own, so every replica publishes the event locally.
+func (m *Monitor) PublishTo(bus *eventbus.Bus) {
+	m.mu.Lock()
+	m.bus = bus
+	m.mu.Unlock()
+}
+
+// Status returns the status of server.
+func (m *Monitor) Status(server string) (Status, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.statuses[server]
+
+	return s, ok
+}
+
+// Statuses returns the status of every checked server, the primary server
+// first and the views by name.
+func (m *Monitor) Statuses() []Status {
+	m.mu.RLock()
+	statuses := make([]Status, 0, len(m.statuses))
+
+	for _, s := range m.statuses {
+		statuses = append(statuses, s)
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(statuses, func(a, b Status) int { return strings.Compare(a.Server, b.Server) })
+
+	return statuses
+}
+
+// Down returns the status of the servers that are down.
+func (m *Monitor) Down() []Status {
+	return slices.DeleteFunc(m.Statuses(), func(s Status) bool { return !s.Down })
+}
+
+// Run checks the servers at once and then every interval until ctx is
+// canceled. It returns at once when the monitor is disabled or not
+// configured.
+func (m *Monitor) Run(ctx context.Context) {
+	m.mu.RLock()
+	db, interval := m.db, m.cfg.Interval
+	m.mu.RUnlock()
+
+	if db == nil || interval <= 0 {
+		return
+	}
+
+	jobs.Scheduled(jobs.ServerChecks, interval)
+
+	_ = jobs.Run(jobs.ServerChecks, func() error { return m.CheckAll(ctx) })
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.ServerChecks, func() error { return m.CheckAll(ctx) })
+		}
+	}
+}
+
+// CheckAll checks the primary server and every view, stores the checks,
+// updates the statuses and deletes the checks older than the retention.
+func (m *Monitor) CheckAll(ctx context.Context) error {
+	m.mu.RLock()
+	db, cfg := m.db, m.cfg
+	m.mu.RUnlock()
+
+	var views []models.PDNSView
+	if err := db.Order("name").Find(&views).Error; err != nil {
+		log.Error().Err(err).Msg("servermonitor: failed to load views")
+		return err
+	}
+
+	checks := make([]models.ServerCheck, 0, len(views)+1)
+	checks = append(checks, m.checkServer(ctx, Primary, nil))
+
+	for i := range views {
+		checks = append(checks, m.checkServer(ctx, views[i].Name, &views[i]))
+	}
+
+	m.update(checks, cfg.Threshold)
+
+	var errs []error
+
+	if err := db.Create(&checks).Error; err != nil {
+		log.Error().Err(err).Msg("servermonitor: failed to store checks")
+
+		errs = append(errs, err)
+	}
+
+	cutoff := m.now().AddDate(0, 0, -cfg.RetentionDays)
+	if err := db.Where("checked_at < ?", cutoff).Delete(&models.ServerCheck{}).Error; err != nil {
+		log.Error().Err(err).Msg("servermonitor: failed to delete old checks")
+
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkServer checks server, the name of view, and returns the check.
+func (m *Monitor) checkServer(ctx context.Context, server string, view *models.PDNSView) models.ServerCheck {
+	start := m.now()
+	err := m.check(ctx, view)
+
+	c := models.ServerCheck{
+		Server:    server,
+		OK:        err == nil,
+		LatencyMS: m.now().Sub(start).Milliseconds(),
+		CheckedAt: start,
+	}
+
+	if err != nil {
+		c.Error = err.Error()
+		if len(c.Error) > maxErrorLength {
+			c.Error = c.Error[:maxErrorLength]
+		}
+
+		log.Debug().Err(err).Str("server", Label(server)).Msg("servermonitor: check failed")
+	}
+
+	return c
+}
+
+// update applies checks to the statuses, dropping those of deleted views,
+// and reports the servers that went down or recovered.
+func (m *Monitor) update(checks []models.ServerCheck, threshold int) {
+	statuses := make(map[string]Status, len(checks))
+
+	var changed []Status
+
+	m.mu.Lock()
+
+	for i := range checks {
+		c := &checks[i]
+		s := m.statuses[c.Server]
+
+		s.Server, s.Error, s.LatencyMS, s.CheckedAt = c.Server, c.Error, c.LatencyMS, c.CheckedAt
+
+		if c.OK {
+			s.Failures = 0
+
+			if s.Down {
+				s.Down, s.Since = false, c.CheckedAt
+				changed = append(changed, s)
+			}
+		} else {
+			s.Failures++
+
+			if !s.Down && s.Failures >= threshold {
+				s.Down, s.Since = true, c.CheckedAt
+				changed = append(changed, s)
+			}
+		}
+
+		statuses[c.Server] = s
+	}
+
+	m.statuses = statuses
+	bus := m.bus
+
+	m.mu.Unlock()
+
+	for i := range changed {
+		s := &changed[i]
+
+		if s.Down {
+			log.Warn().Str("server", s.Label()).Int("failures", s.Failures).Str("error", s.Error).
+				Msg("servermonitor: server is down")
+		} else {
+			log.Info().Str("server", s.Label()).Msg("servermonitor: server recovered")
+		}
+
+		if bus != nil {
+			bus.Publish(eventbus.TopicServerHealth, s.Server)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package servermonitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.PDNSView{}, &models.ServerCheck{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func TestCheckAll(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Create(&models.PDNSView{Name: "internal", APIServerURL: "http://192.0.2.1:8081", APIKey: "k"}).Error; err != nil {
+		t.Fatalf("failed to create view: %v", err)
+	}
+
+	// The view fails the first three checks, the primary server never.
+	failing := 3
+	m := New(func(_ context.Context, view *models.PDNSView) error {
+		if view == nil || failing == 0 {
+			return nil
+		}
+
+		return errors.New("connection refused")
+	})
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.Configure(db, config.Monitor{Interval: time.Minute, Threshold: 2, RetentionDays: 1})
+
+	bus := eventbus.New()
+	m.PublishTo(bus)
+
+	var events []string
+
+	bus.Subscribe(eventbus.TopicServerHealth, func(e eventbus.Event) { events = append(events, e.Key) })
+
+	// An old check is deleted after the retention.
+	db.Create(&models.ServerCheck{Server: Primary, OK: true, CheckedAt: now.Add(-48 * time.Hour)})
+
+	for i := range 4 {
+		if i == 3 {
+			failing = 0
+		}
+
+		if err := m.CheckAll(context.Background()); err != nil {
+			t.Fatalf("CheckAll() error = %v", err)
+		}
+
+		down := len(m.Down()) > 0
+		if want := i == 1 || i == 2; down != want {
+			t.Errorf("check %d: down = %v, want %v", i+1, down, want)
+		}
+
+		now = now.Add(time.Minute)
+	}
+
+	if len(events) != 2 || events[0] != "internal" {
+		t.Errorf("events = %q, want the view going down and recovering", events)
+	}
+
+	var old int64
+	if db.Model(&models.ServerCheck{}).Where("checked_at < ?", now.Add(-24*time.Hour)).Count(&old); old != 0 {
+		t.Errorf("%d checks older than the retention kept", old)
+	}
+
+	statuses := m.Statuses()
+	if len(statuses) != 2 || statuses[0].Server != Primary || statuses[1].Failures != 0 || statuses[1].Error != "" {
+		t.Errorf("Statuses() = %+v", statuses)
+	}
+
+	availability, err := Availabilities(db, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Availabilities() error = %v", err)
+	}
+
+	if a := availability["internal"]; a.Checks != 4 || a.OK != 1 || a.Ratio() != 0.25 {
+		t.Errorf("availability of the view = %+v", a)
+	}
+
+	if a := availability[Primary]; a.Checks != 4 || a.Ratio() != 1 {
+		t.Errorf("availability of the primary server = %+v", a)
+	}
+
+	failures, err := Failures(db, 10)
+	if err != nil || len(failures) != 3 || failures[0].Server != "internal" || failures[0].Error != "connection refused" {
+		t.Errorf("Failures() = %+v, %v", failures, err)
+	}
+
+	// Checks of a deleted view are no longer reported.
+	db.Where("name = ?", "internal").Delete(&models.PDNSView{})
+
+	if err = m.CheckAll(context.Background()); err != nil {
+		t.Fatalf("CheckAll() error = %v", err)
+	}
+
+	if _, ok := m.Status("internal"); ok {
+		t.Error("status of the deleted view kept")
+	}
+}
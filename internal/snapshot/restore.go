@@ -0,0 +1,182 @@
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+// ErrInvalidZoneFile is returned for a zone file line that cannot be parsed.
+var ErrInvalidZoneFile = errors.New("invalid zone file")
+
+// ParseZoneFile parses a zone in the format exported by PowerDNS: one record
+// per line with owner name, TTL, class, type and content. Records of the same
+// name and type form one RRset with the TTL of the first one.
+func ParseZoneFile(r io.Reader) ([]pdnsapi.RRset, error) {
+	var (
+		rrsets []pdnsapi.RRset
+		index  = map[string]int{}
+		line   int
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") || strings.HasPrefix(text, "$") {
+			continue
+		}
+
+		name, ttl, rrType, content, err := parseRecord(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidZoneFile, line, err)
+		}
+
+		key := name + "/" + rrType
+
+		i, ok := index[key]
+		if !ok {
+			t := pdnsapi.RRType(rrType)
+			i = len(rrsets)
+			index[key] = i
+			rrsets = append(rrsets, pdnsapi.RRset{Name: &name, Type: &t, TTL: &ttl})
+		}
+
+		disabled := false
+		rrsets[i].Records = append(rrsets[i].Records, pdnsapi.Record{Content: &content, Disabled: &disabled})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rrsets, nil
+}
+
+// parseRecord splits a zone file line into its fields. The content keeps its
+// inner spacing, e.g. of TXT strings.
+func parseRecord(text string) (name string, ttl uint32, rrType, content string, err error) {
+	fields := make([]string, 0, 4)
+	rest := text
+
+	for range 4 {
+		rest = strings.TrimLeft(rest, " \t")
+
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return "", 0, "", "", errors.New("too few fields")
+		}
+
+		fields = append(fields, rest[:end])
+		rest = rest[end:]
+	}
+
+	content = strings.TrimSpace(rest)
+	if content == "" {
+		return "", 0, "", "", errors.New("missing content")
+	}
+
+	if !strings.EqualFold(fields[2], "IN") {
+		return "", 0, "", "", fmt.Errorf("unsupported class %q", fields[2])
+	}
+
+	t, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return "", 0, "", "", fmt.Errorf("invalid TTL %q", fields[1])
+	}
+
+	name = strings.ToLower(fields[0])
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name, uint32(t), strings.ToUpper(fields[3]), content, nil
+}
+
+// RestoreZone makes zone contain exactly rrsets: RRsets of the snapshot
+// replace the current ones and RRsets not in the snapshot are deleted. A zone
+// that no longer exists is created as a Native zone. Disabled records and
+// comments are not part of the snapshot.
+func RestoreZone(ctx context.Context, zone string, rrsets []pdnsapi.RRset) error {
+	if powerdns.Engine.Client == nil {
+		return powerdns.ErrClientNotInitialized
+	}
+
+	current, err := powerdns.Engine.Zones.Get(ctx, zone)
+
+	var pdnsErr *pdnsapi.Error
+	if errors.As(err, &pdnsErr) && pdnsErr.StatusCode == http.StatusNotFound {
+		kind := pdnsapi.NativeZoneKind
+
+		// No ChangeType: zone creation does not use changetype in rrsets.
+		if _, err = powerdns.Engine.Zones.Add(ctx, &pdnsapi.Zone{Name: &zone, Kind: &kind, RRsets: rrsets}); err != nil {
+			return fmt.Errorf("create zone: %w", err)
+		}
+
+		zoneindex.Default.RefreshZone(ctx, zone)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	changes := restoreChanges(current.RRsets, rrsets)
+
+	if err = powerdns.Engine.Records.Patch(ctx, zone, &pdnsapi.RRsets{Sets: changes}); err != nil {
+		return err
+	}
+
+	zoneindex.Default.RefreshZone(ctx, zone)
+
+	return nil
+}
+
+// restoreChanges returns the changes that turn the RRsets current into
+// snapshot.
+func restoreChanges(current, snapshot []pdnsapi.RRset) []pdnsapi.RRset {
+	keep := make(map[string]bool, len(snapshot))
+	changes := make([]pdnsapi.RRset, 0, len(snapshot))
+
+	for _, rr := range snapshot {
+		keep[rrsetKey(&rr)] = true
+
+		replace := pdnsapi.ChangeTypeReplace
+		rr.ChangeType = &replace
+		changes = append(changes, rr)
+	}
+
+	for _, rr := range current {
+		if keep[rrsetKey(&rr)] {
+			continue
+		}
+
+		remove := pdnsapi.ChangeTypeDelete
+		changes = append(changes, pdnsapi.RRset{Name: rr.Name, Type: rr.Type, ChangeType: &remove})
+	}
+
+	return changes
+}
+
+func rrsetKey(rr *pdnsapi.RRset) string {
+	var rrType pdnsapi.RRType
+	if rr.Type != nil {
+		rrType = *rr.Type
+	}
+
+	return strings.ToLower(pdnsapi.StringValue(rr.Name)) + "/" + string(rrType)
+}
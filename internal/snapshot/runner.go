@@ -0,0 +1,131 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+)
+
+// checkInterval is how often the runner checks whether the current interval
+// has a snapshot yet. Checking, instead of waiting a full interval, keeps
+// the schedule across restarts and lets several instances share a storage.
+const checkInterval = 10 * time.Minute
+
+// Runner takes a snapshot every configured interval and applies the
+// retention policy.
+type Runner struct {
+	cfg   config.Snapshots
+	db    *gorm.DB
+	store Store
+	now   func() time.Time
+}
+
+// NewRunner returns a Runner writing the snapshots of db to store.
+func NewRunner(cfg *config.Snapshots, db *gorm.DB, store Store) *Runner {
+	return &Runner{cfg: *cfg, db: db, store: store, now: time.Now}
+}
+
+// Run takes the snapshots until ctx is canceled. It returns immediately when
+// no interval is configured.
+func (r *Runner) Run(ctx context.Context) {
+	if r.cfg.Interval <= 0 || r.store == nil {
+		log.Debug().Msg("snapshot: scheduled snapshots disabled by config")
+		return
+	}
+
+	jobs.Scheduled(jobs.Snapshots, r.cfg.Interval)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		_ = jobs.Run(jobs.Snapshots, func() error { return r.runOnce(ctx) })
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce takes a snapshot unless one was taken in the current interval,
+// counted from midnight UTC, and then removes the expired ones.
+func (r *Runner) runOnce(ctx context.Context) error {
+	objects, err := r.store.List(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("snapshot: failed to list snapshots")
+		return err
+	}
+
+	now := r.now()
+
+	if len(objects) > 0 {
+		if taken, ok := nameTime(objects[0].Name); ok && !taken.Before(now.UTC().Truncate(r.cfg.Interval)) {
+			return nil
+		}
+	}
+
+	name := Name(now)
+
+	manifest, err := Take(ctx, r.db, r.store, name)
+	if manifest == nil {
+		log.Error().Err(err).Str("snapshot", name).Msg("snapshot: failed to take snapshot")
+		return err
+	}
+
+	log.Info().Err(err).Str("snapshot", name).Int("zones", len(manifest.Zones)).
+		Int("failed_zones", len(manifest.Errors)).Msg("snapshot taken")
+
+	return errors.Join(err, Prune(ctx, r.store, &r.cfg, now))
+}
+
+// Prune removes the snapshots beyond the newest cfg.Keep and those older than
+// cfg.MaxAge. The newest snapshot is always kept.
+func Prune(ctx context.Context, store Store, cfg *config.Snapshots, now time.Time) error {
+	objects, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for i, o := range objects {
+		if i == 0 {
+			continue
+		}
+
+		taken, ok := nameTime(o.Name)
+		expired := cfg.MaxAge > 0 && ok && now.Sub(taken) > cfg.MaxAge
+
+		if (cfg.Keep > 0 && i >= cfg.Keep) || expired {
+			if err = store.Delete(ctx, o.Name); err != nil {
+				log.Error().Err(err).Str("snapshot", o.Name).Msg("snapshot: failed to remove expired snapshot")
+
+				errs = append(errs, err)
+
+				continue
+			}
+
+			log.Info().Str("snapshot", o.Name).Msg("snapshot: removed expired snapshot")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// nameTime returns the time a snapshot was taken, read from its name.
+func nameTime(name string) (time.Time, bool) {
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, namePrefix), nameSuffix)
+
+	t, err := time.Parse(nameLayout, stamp)
+
+	return t, err == nil
+}
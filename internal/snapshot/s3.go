@@ -0,0 +1,109 @@
+package snapshot
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+// s3Store keeps snapshots in an S3-compatible bucket.
+type s3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func newS3Store(cfg *config.SnapshotsS3) (*s3Store, error) {
+	creds := credentials.NewEnvAWS()
+	if cfg.AccessKey != "" {
+		creds = credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, "")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !cfg.Insecure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Store{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+// key returns the object key of name.
+func (s *s3Store) key(name string) string {
+	return path.Join(s.prefix, name)
+}
+
+func (s *s3Store) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.key(name), r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+
+	return err
+}
+
+func (s *s3Store) List(ctx context.Context) ([]Object, error) {
+	prefix := ""
+	if s.prefix != "" {
+		prefix = s.prefix + "/"
+	}
+
+	var objects []Object
+
+	for info := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+
+		name := strings.TrimPrefix(info.Key, prefix)
+		if !validName(name) {
+			continue
+		}
+
+		objects = append(objects, Object{Name: name, Size: info.Size, ModTime: info.LastModified})
+	}
+
+	sortNewestFirst(objects)
+
+	return objects, nil
+}
+
+func (s *s3Store) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !validName(name) {
+		return nil, ErrNotFound
+	}
+
+	obj, err := s.client.GetObject(ctx, s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// GetObject does not request the object yet; Stat reports a missing one.
+	if _, err = obj.Stat(); err != nil {
+		_ = obj.Close()
+
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, name string) error {
+	if !validName(name) {
+		return ErrNotFound
+	}
+
+	return s.client.RemoveObject(ctx, s.bucket, s.key(name), minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,271 @@
+// Package snapshot takes scheduled snapshots of all zones, exported from
+// PowerDNS in BIND format, together with an application database archive
+// (see internal/backup), and stores them in a local directory or an
+// S3-compatible bucket with a retention policy. Snapshots can be browsed
+// under Admin → Snapshots, which restores single zones or the application
+// data from them.
+//
+// A snapshot is a gzip-compressed tar archive:
+//
+//	app.json                application database archive
+//	zones/example.com.zone  zone in BIND format, one per zone
+//	manifest.json           time, version and zones of the snapshot
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/backup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/version"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// ManifestEntry is the archive entry of the manifest.
+	ManifestEntry = "manifest.json"
+	// AppEntry is the archive entry of the application database archive.
+	AppEntry = "app.json"
+
+	contentType = "application/gzip"
+
+	nameLayout = "20060102-150405"
+	namePrefix = "snapshot-"
+	nameSuffix = ".tar.gz"
+
+	// exportTimeout bounds the export of one zone.
+	exportTimeout = 30 * time.Second
+)
+
+// ErrEntryNotFound is returned when a snapshot does not contain an entry.
+var ErrEntryNotFound = errors.New("entry not found in snapshot")
+
+var nameRe = regexp.MustCompile(`^` + namePrefix + `\d{8}-\d{6}` + regexp.QuoteMeta(nameSuffix) + `$`)
+
+// Manifest describes a snapshot.
+type Manifest struct {
+	CreatedAt  time.Time `json:"created_at"`
+	AppVersion string    `json:"app_version"`
+	// Zones lists the zones in the snapshot.
+	Zones []string `json:"zones"`
+	// Errors holds the zones that could not be exported, with the error.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Name returns the name of a snapshot taken at t.
+func Name(t time.Time) string {
+	return namePrefix + t.UTC().Format(nameLayout) + nameSuffix
+}
+
+// validName reports whether name is the name of a snapshot. Other objects in
+// the storage are ignored and never read or deleted.
+func validName(name string) bool {
+	return nameRe.MatchString(name)
+}
+
+// ZoneEntry returns the archive entry of zone.
+func ZoneEntry(zone string) string {
+	return "zones/" + strings.TrimSuffix(zone, ".") + ".zone"
+}
+
+// Take writes a snapshot named name to store. Zones that fail to export are
+// listed in the manifest and reported in the returned error, but do not stop
+// the snapshot; it is only discarded when the application data cannot be
+// exported or the archive cannot be stored.
+func Take(ctx context.Context, db *gorm.DB, store Store, name string) (*Manifest, error) {
+	if powerdns.Engine.Client == nil {
+		return nil, powerdns.ErrClientNotInitialized
+	}
+
+	list, err := zoneindex.Default.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list zones: %w", err)
+	}
+
+	zones := make([]string, 0, len(list))
+	for i := range list {
+		zones = append(zones, pdnsapi.StringValue(list[i].Name))
+	}
+
+	return take(ctx, db, store, name, zones, exportZone)
+}
+
+// take writes a snapshot of zones, exported with export, to store.
+func take(
+	ctx context.Context,
+	db *gorm.DB,
+	store Store,
+	name string,
+	zones []string,
+	export func(ctx context.Context, zone string) ([]byte, error),
+) (*Manifest, error) {
+	tmp, err := os.CreateTemp("", "go-pdns-snapshot-*")
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	manifest := &Manifest{CreatedAt: time.Now().UTC(), AppVersion: version.Get()}
+
+	var app strings.Builder
+	if _, err = backup.Export(db, &app); err != nil {
+		return nil, fmt.Errorf("export application data: %w", err)
+	}
+
+	// Zones are written as they are exported; the manifest follows them.
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+
+	if err = writeEntry(tw, AppEntry, []byte(app.String()), manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	var zoneErrs []error
+
+	for _, zone := range zones {
+		data, err := export(ctx, zone)
+		if err != nil {
+			log.Warn().Err(err).Str("zone", zone).Msg("snapshot: failed to export zone")
+
+			if manifest.Errors == nil {
+				manifest.Errors = map[string]string{}
+			}
+
+			manifest.Errors[zone] = err.Error()
+			zoneErrs = append(zoneErrs, fmt.Errorf("%s: %w", zone, err))
+
+			continue
+		}
+
+		if err = writeEntry(tw, ZoneEntry(zone), data, manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		manifest.Zones = append(manifest.Zones, zone)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeEntry(tw, ManifestEntry, data, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err = gz.Close(); err != nil {
+		return nil, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if err = store.Put(ctx, name, tmp, size); err != nil {
+		return nil, fmt.Errorf("store %s: %w", name, err)
+	}
+
+	return manifest, errors.Join(zoneErrs...)
+}
+
+// exportZone returns zone in BIND format.
+func exportZone(ctx context.Context, zone string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	data, err := powerdns.Engine.Zones.Export(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(data), nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+
+	_, err := tw.Write(data)
+
+	return err
+}
+
+// ReadManifest returns the manifest of the snapshot name.
+func ReadManifest(ctx context.Context, store Store, name string) (*Manifest, error) {
+	var manifest Manifest
+
+	err := ReadEntry(ctx, store, name, ManifestEntry, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&manifest)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// ReadEntry calls fn with the content of entry in the snapshot name. It
+// returns ErrNotFound for a missing snapshot and ErrEntryNotFound for a
+// missing entry.
+func ReadEntry(ctx context.Context, store Store, name, entry string, fn func(io.Reader) error) error {
+	rc, err := store.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+
+	tr := tar.NewReader(gz)
+
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return ErrEntryNotFound
+		}
+
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+
+		if h.Name == entry {
+			return fn(tr)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/backup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+const exampleZone = "example.com.\t3600\tIN\tSOA\tns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600\n" +
+	"example.com.\t3600\tIN\tNS\tns1.example.com.\n" +
+	"example.com.\t3600\tIN\tNS\tns2.example.com.\n" +
+	"www.example.com.\t300\tIN\tA\t192.0.2.1\n" +
+	"example.com.\t300\tIN\tTXT\t\"v=spf1  -all\"\n"
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "app.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(backup.Models()...); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestTakeAndRead(t *testing.T) {
+	ctx := context.Background()
+	store := &dirStore{dir: t.TempDir()}
+	name := Name(time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC))
+
+	export := func(_ context.Context, zone string) ([]byte, error) {
+		if zone == "broken.example." {
+			return nil, errors.New("boom")
+		}
+
+		return []byte(exampleZone), nil
+	}
+
+	manifest, err := take(ctx, newTestDB(t), store, name, []string{"example.com.", "broken.example."}, export)
+	if manifest == nil {
+		t.Fatalf("take() error = %v", err)
+	}
+
+	if err == nil || manifest.Errors["broken.example."] != "boom" {
+		t.Errorf("take() error = %v, manifest errors %v; want the failed zone reported", err, manifest.Errors)
+	}
+
+	objects, err := store.List(ctx)
+	if err != nil || len(objects) != 1 || objects[0].Name != "snapshot-20261017-020000.tar.gz" {
+		t.Fatalf("List() = %v, %v", objects, err)
+	}
+
+	read, err := ReadManifest(ctx, store, name)
+	if err != nil || strings.Join(read.Zones, ",") != "example.com." {
+		t.Fatalf("ReadManifest() = %+v, %v", read, err)
+	}
+
+	var zone bytes.Buffer
+
+	err = ReadEntry(ctx, store, name, ZoneEntry("example.com."), func(r io.Reader) error {
+		_, err := io.Copy(&zone, r)
+		return err
+	})
+	if err != nil || zone.String() != exampleZone {
+		t.Errorf("ReadEntry(zone) = %q, %v", zone.String(), err)
+	}
+
+	err = ReadEntry(ctx, store, name, AppEntry, func(r io.Reader) error {
+		_, err := backup.Restore(newTestDB(t), r)
+		return err
+	})
+	if err != nil {
+		t.Errorf("restoring the application data of the snapshot: %v", err)
+	}
+
+	if err = ReadEntry(ctx, store, name, ZoneEntry("missing."), nil); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("ReadEntry(missing zone) error = %v, want ErrEntryNotFound", err)
+	}
+
+	if _, err = ReadManifest(ctx, store, Name(time.Now())); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReadManifest(missing snapshot) error = %v, want ErrNotFound", err)
+	}
+
+	if _, err = store.Open(ctx, "../app.db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open(path outside the store) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	store := &dirStore{dir: t.TempDir()}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	for days := range 5 {
+		name := Name(now.AddDate(0, 0, -days))
+		if err := store.Put(ctx, name, strings.NewReader("x"), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func() string {
+		objects, _ := store.List(ctx)
+
+		var s []string
+		for _, o := range objects {
+			s = append(s, strings.TrimSuffix(strings.TrimPrefix(o.Name, namePrefix), nameSuffix))
+		}
+
+		return strings.Join(s, " ")
+	}
+
+	if err := Prune(ctx, store, &config.Snapshots{Keep: 4, MaxAge: 60 * time.Hour}, now); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	if got, want := names(), "20261017-120000 20261016-120000 20261015-120000"; got != want {
+		t.Errorf("after Prune() = %q, want %q", got, want)
+	}
+
+	// The newest snapshot is kept regardless of its age.
+	if err := Prune(ctx, store, &config.Snapshots{Keep: -1, MaxAge: time.Hour}, now.AddDate(1, 0, 0)); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	if got, want := names(), "20261017-120000"; got != want {
+		t.Errorf("after Prune() = %q, want %q", got, want)
+	}
+}
+
+func TestRunner_SkipsIntervalWithSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := &dirStore{dir: t.TempDir()}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	if err := store.Put(ctx, Name(now.Add(-2*time.Hour)), strings.NewReader("x"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner(&config.Snapshots{Interval: 24 * time.Hour, Keep: 3}, nil, store)
+	r.now = func() time.Time { return now }
+
+	// Taking a snapshot would fail without a PowerDNS client.
+	if err := r.runOnce(ctx); err != nil {
+		t.Errorf("runOnce() error = %v, want the snapshot of today to be kept", err)
+	}
+}
+
+func TestParseZoneFile(t *testing.T) {
+	rrsets, err := ParseZoneFile(strings.NewReader("$ORIGIN .\n; comment\n" + exampleZone))
+	if err != nil {
+		t.Fatalf("ParseZoneFile() error = %v", err)
+	}
+
+	if len(rrsets) != 4 {
+		t.Fatalf("ParseZoneFile() returned %d RRsets, want 4", len(rrsets))
+	}
+
+	if ns := rrsets[1]; *ns.Type != pdnsapi.RRTypeNS || len(ns.Records) != 2 || *ns.TTL != 3600 {
+		t.Errorf("NS RRset = %+v", ns)
+	}
+
+	if txt := *rrsets[3].Records[0].Content; txt != `"v=spf1  -all"` {
+		t.Errorf("TXT content = %q, want the inner spacing kept", txt)
+	}
+
+	for _, bad := range []string{"www.example.com. 300 IN A", "www.example.com. x IN A 192.0.2.1", "a. 1 CH TXT x"} {
+		if _, err = ParseZoneFile(strings.NewReader(bad)); !errors.Is(err, ErrInvalidZoneFile) {
+			t.Errorf("ParseZoneFile(%q) error = %v, want ErrInvalidZoneFile", bad, err)
+		}
+	}
+}
+
+func TestRestoreChanges(t *testing.T) {
+	snapshot, err := ParseZoneFile(strings.NewReader(exampleZone))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, rrType := "new.example.com.", pdnsapi.RRTypeA
+	current := append([]pdnsapi.RRset{{Name: &name, Type: &rrType}}, snapshot[:2]...)
+
+	var got []string
+
+	for _, c := range restoreChanges(current, snapshot) {
+		got = append(got, string(*c.ChangeType)+" "+rrsetKey(&c))
+	}
+
+	want := "REPLACE example.com./SOA,REPLACE example.com./NS,REPLACE www.example.com./A," +
+		"REPLACE example.com./TXT,DELETE new.example.com./A"
+	if strings.Join(got, ",") != want {
+		t.Errorf("restoreChanges() = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+var (
+	// ErrNotFound is returned for a snapshot that does not exist.
+	ErrNotFound = errors.New("snapshot not found")
+	// ErrNotConfigured is returned by NewStore when no snapshot storage is
+	// configured.
+	ErrNotConfigured = errors.New("no snapshot storage configured")
+)
+
+// Object is a stored snapshot.
+type Object struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Store keeps snapshot archives.
+type Store interface {
+	// Put stores the size bytes read from r as name.
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// List returns the stored snapshots, newest first.
+	List(ctx context.Context) ([]Object, error)
+	// Open returns the content of name, or ErrNotFound.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// Delete removes name.
+	Delete(ctx context.Context, name string) error
+}
+
+// NewStore returns the store configured in cfg.
+func NewStore(cfg *config.Snapshots) (Store, error) {
+	switch {
+	case cfg.S3.Bucket != "":
+		return newS3Store(&cfg.S3)
+	case cfg.Directory != "":
+		return &dirStore{dir: cfg.Directory}, nil
+	default:
+		return nil, ErrNotConfigured
+	}
+}
+
+// sortNewestFirst orders objects by name, which starts with the time the
+// snapshot was taken, newest first.
+func sortNewestFirst(objects []Object) {
+	slices.SortFunc(objects, func(a, b Object) int { return strings.Compare(b.Name, a.Name) })
+}
+
+// dirStore keeps snapshots in a local directory.
+type dirStore struct {
+	dir string
+}
+
+func (d *dirStore) Put(_ context.Context, name string, r io.Reader, _ int64) error {
+	if err := os.MkdirAll(d.dir, 0o750); err != nil {
+		return err
+	}
+
+	// Written under a temporary name, so that a partial file is never listed.
+	f, err := os.CreateTemp(d.dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(f, r); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(d.dir, name))
+}
+
+func (d *dirStore) List(_ context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(d.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]Object, 0, len(entries))
+
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !validName(e.Name()) {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		objects = append(objects, Object{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+
+	sortNewestFirst(objects)
+
+	return objects, nil
+}
+
+func (d *dirStore) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	if !validName(name) {
+		return nil, ErrNotFound
+	}
+
+	f, err := os.Open(filepath.Join(d.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+func (d *dirStore) Delete(_ context.Context, name string) error {
+	if !validName(name) {
+		return ErrNotFound
+	}
+
+	return os.Remove(filepath.Join(d.dir, name))
+}
@@ -0,0 +1,330 @@
+// Package splithorizon compares the variants of a zone in split-horizon
+// setups: the zone on the primary PowerDNS server and its copy on a view, a
+// further PowerDNS server answering other clients, e.g. the internal
+// network. RRsets are compared as sets of records with their TTL, and either
+// side can be made to match the other.
+package splithorizon
+
+import (
+	"slices"
+	"strings"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/pdnsserver"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+// State is how the variants of an RRset compare.
+type State string
+
+const (
+	// StateSame means both variants have the same records and TTL.
+	StateSame State = "same"
+	// StateDiffers means the variants have other records or another TTL.
+	StateDiffers State = "differs"
+	// StatePrimaryOnly means only the primary server has the RRset.
+	StatePrimaryOnly State = "primary"
+	// StateViewOnly means only the view has the RRset.
+	StateViewOnly State = "view"
+)
+
+// Side names a variant of a zone.
+type Side string
+
+const (
+	// SidePrimary is the zone on the primary PowerDNS server.
+	SidePrimary Side = "primary"
+	// SideView is the zone on the view.
+	SideView Side = "view"
+)
+
+// Other returns the opposite side.
+func (s Side) Other() Side {
+	if s == SidePrimary {
+		return SideView
+	}
+
+	return SidePrimary
+}
+
+// Record is a record of an RRset variant.
+type Record struct {
+	Content  string
+	Disabled bool
+}
+
+// RRset is the variant of an RRset on one side.
+type RRset struct {
+	TTL uint32
+	// Records are sorted by content.
+	Records []Record
+}
+
+// Equal reports whether r and o have the same TTL and records.
+func (r *RRset) Equal(o *RRset) bool {
+	return r.TTL == o.TTL && slices.Equal(r.Records, o.Records)
+}
+
+// Contents returns the contents of the records, disabled ones included.
+func (r *RRset) Contents() []string {
+	if r == nil {
+		return nil
+	}
+
+	contents := make([]string, 0, len(r.Records))
+	for _, rec := range r.Records {
+		contents = append(contents, rec.Content)
+	}
+
+	return contents
+}
+
+// Row compares the variants of an RRset; Primary or View is nil when the
+// side does not have it.
+type Row struct {
+	Name    string
+	Type    string
+	Primary *RRset
+	View    *RRset
+	State   State
+}
+
+// Side returns the variant of side.
+func (r *Row) Side(side Side) *RRset {
+	if side == SidePrimary {
+		return r.Primary
+	}
+
+	return r.View
+}
+
+// Summary counts the rows by state.
+type Summary struct {
+	Same        int
+	Differs     int
+	PrimaryOnly int
+	ViewOnly    int
+}
+
+// Summarize counts rows by state.
+func Summarize(rows []Row) Summary {
+	var s Summary
+
+	for i := range rows {
+		switch rows[i].State {
+		case StateSame:
+			s.Same++
+		case StateDiffers:
+			s.Differs++
+		case StatePrimaryOnly:
+			s.PrimaryOnly++
+		case StateViewOnly:
+			s.ViewOnly++
+		}
+	}
+
+	return s
+}
+
+// managedTypes are left out of the comparison: each server keeps its own SOA
+// serial and signs the zone with its own keys.
+var managedTypes = map[string]bool{
+	"SOA":        true,
+	"RRSIG":      true,
+	"NSEC":       true,
+	"NSEC3":      true,
+	"NSEC3PARAM": true,
+	"DNSKEY":     true,
+}
+
+// Comparable reports whether RRsets of rrType are compared and may be copied.
+// PowerDNS-internal types such as TYPE65534 are not.
+func Comparable(rrType string) bool {
+	return !managedTypes[rrType] && !strings.HasPrefix(rrType, "TYPE")
+}
+
+// Diff compares the RRsets of the primary zone and its variant on a view,
+// sorted by name and type.
+func Diff(primary, view *pdnsapi.Zone) []Row {
+	rows := map[[2]string]*Row{}
+
+	add := func(z *pdnsapi.Zone, side Side) {
+		if z == nil {
+			return
+		}
+
+		for i := range z.RRsets {
+			name, rrType, rrSet, ok := variant(&z.RRsets[i])
+			if !ok {
+				continue
+			}
+
+			key := [2]string{name, rrType}
+
+			row := rows[key]
+			if row == nil {
+				row = &Row{Name: name, Type: rrType}
+				rows[key] = row
+			}
+
+			if side == SidePrimary {
+				row.Primary = rrSet
+			} else {
+				row.View = rrSet
+			}
+		}
+	}
+
+	add(primary, SidePrimary)
+	add(view, SideView)
+
+	out := make([]Row, 0, len(rows))
+
+	for _, row := range rows {
+		switch {
+		case row.View == nil:
+			row.State = StatePrimaryOnly
+		case row.Primary == nil:
+			row.State = StateViewOnly
+		case row.Primary.Equal(row.View):
+			row.State = StateSame
+		default:
+			row.State = StateDiffers
+		}
+
+		out = append(out, *row)
+	}
+
+	slices.SortFunc(out, func(a, b Row) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Type, b.Type)
+	})
+
+	return out
+}
+
+// Find returns the row of name and rrType, or nil.
+func Find(rows []Row, name, rrType string) *Row {
+	for i := range rows {
+		if rows[i].Name == name && rows[i].Type == rrType {
+			return &rows[i]
+		}
+	}
+
+	return nil
+}
+
+// variant converts a PowerDNS RRset; ok is false for RRsets without records
+// and those that are not Comparable.
+func variant(rrSet *pdnsapi.RRset) (name, rrType string, v *RRset, ok bool) {
+	if rrSet.Name == nil || rrSet.Type == nil || len(rrSet.Records) == 0 {
+		return "", "", nil, false
+	}
+
+	rrType = string(*rrSet.Type)
+	if !Comparable(rrType) {
+		return "", "", nil, false
+	}
+
+	v = &RRset{TTL: pdnsapi.Uint32Value(rrSet.TTL)}
+	for _, rec := range rrSet.Records {
+		v.Records = append(v.Records, Record{
+			Content:  pdnsapi.StringValue(rec.Content),
+			Disabled: pdnsapi.BoolValue(rec.Disabled),
+		})
+	}
+
+	sortRecords(v.Records)
+
+	return strings.ToLower(*rrSet.Name), rrType, v, true
+}
+
+// ParseRecords returns the enabled records of text, one per line. Blank
+// lines and repeated records are left out.
+func ParseRecords(text string) []Record {
+	var records []Record
+
+	for line := range strings.Lines(text) {
+		if content := strings.TrimSpace(line); content != "" {
+			records = append(records, Record{Content: content})
+		}
+	}
+
+	sortRecords(records)
+
+	return slices.Compact(records)
+}
+
+func sortRecords(records []Record) {
+	slices.SortFunc(records, func(a, b Record) int { return strings.Compare(a.Content, b.Content) })
+}
+
+// Patch returns the PowerDNS patch setting the RRset of name and rrType to
+// rrSet, or deleting it when rrSet is nil.
+func Patch(name, rrType string, rrSet *RRset) pdnsapi.RRset {
+	patch := pdnsapi.RRset{
+		Name: pdnsapi.String(name),
+		Type: pdnsapi.RRTypePtr(pdnsapi.RRType(rrType)),
+	}
+
+	if rrSet == nil {
+		patch.ChangeType = pdnsapi.ChangeTypePtr(pdnsapi.ChangeTypeDelete)
+		return patch
+	}
+
+	patch.ChangeType = pdnsapi.ChangeTypePtr(pdnsapi.ChangeTypeReplace)
+	patch.TTL = pdnsapi.Uint32(rrSet.TTL)
+
+	patch.Records = make([]pdnsapi.Record, 0, len(rrSet.Records))
+	for _, rec := range rrSet.Records {
+		patch.Records = append(patch.Records, pdnsapi.Record{
+			Content:  pdnsapi.String(rec.Content),
+			Disabled: pdnsapi.Bool(rec.Disabled),
+		})
+	}
+
+	return patch
+}
+
+// Change describes setting the RRset of name and rrType from before to
+// after for the activity log; either may be nil.
+func Change(name, rrType string, before, after *RRset) activitylog.RecordEntryDiff {
+	change := activitylog.RecordEntryDiff{Name: name, Type: rrType, Old: before.Contents(), New: after.Contents()}
+
+	switch {
+	case before == nil:
+		change.Action = "added"
+	case after == nil:
+		change.Action = "deleted"
+	default:
+		change.Action = "modified"
+	}
+
+	if before != nil {
+		change.OldTTL = before.TTL
+	}
+
+	if after != nil {
+		change.NewTTL = after.TTL
+	}
+
+	return change
+}
+
+// Settings returns the connection settings of view.
+func Settings(view *models.PDNSView) *pdnsserver.Settings {
+	return &pdnsserver.Settings{
+		APIServerURL: view.APIServerURL, APIKey: view.APIKey, VHost: view.VHost, ReadOnly: view.ReadOnly,
+	}
+}
+
+// Client returns a client of the PowerDNS API of view.
+func Client(view *models.PDNSView) *pdnsapi.Client {
+	return powerdns.NewClient(Settings(view))
+}
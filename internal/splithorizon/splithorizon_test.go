@@ -0,0 +1,146 @@
+package splithorizon
+
+import (
+	"reflect"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func rrSet(name string, rrType pdnsapi.RRType, ttl uint32, contents ...string) pdnsapi.RRset {
+	records := make([]pdnsapi.Record, 0, len(contents))
+	for _, content := range contents {
+		records = append(records, pdnsapi.Record{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)})
+	}
+
+	return pdnsapi.RRset{
+		Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(rrType), TTL: pdnsapi.Uint32(ttl), Records: records,
+	}
+}
+
+func TestDiff(t *testing.T) {
+	primary := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		rrSet("example.com.", pdnsapi.RRTypeSOA, 3600, "ns1.example.com. admin.example.com. 1 3600 600 604800 300"),
+		rrSet("example.com.", pdnsapi.RRTypeNS, 3600, "ns1.example.com.", "ns2.example.com."),
+		rrSet("www.example.com.", pdnsapi.RRTypeA, 300, "192.0.2.1"),
+		rrSet("mail.example.com.", pdnsapi.RRTypeA, 300, "192.0.2.25"),
+		rrSet("ttl.example.com.", pdnsapi.RRTypeTXT, 300, `"x"`),
+	}}
+	view := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		rrSet("example.com.", pdnsapi.RRTypeSOA, 3600, "ns1.example.com. admin.example.com. 7 3600 600 604800 300"),
+		rrSet("Example.com.", pdnsapi.RRTypeNS, 3600, "ns2.example.com.", "ns1.example.com."),
+		rrSet("www.example.com.", pdnsapi.RRTypeA, 300, "10.0.0.1"),
+		rrSet("intranet.example.com.", pdnsapi.RRTypeA, 300, "10.0.0.2"),
+		rrSet("ttl.example.com.", pdnsapi.RRTypeTXT, 60, `"x"`),
+		rrSet("empty.example.com.", pdnsapi.RRTypeA, 300),
+	}}
+
+	rows := Diff(primary, view)
+
+	want := []struct {
+		name, rrType string
+		state        State
+	}{
+		{"example.com.", "NS", StateSame},
+		{"intranet.example.com.", "A", StateViewOnly},
+		{"mail.example.com.", "A", StatePrimaryOnly},
+		{"ttl.example.com.", "TXT", StateDiffers},
+		{"www.example.com.", "A", StateDiffers},
+	}
+
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+
+	for i, w := range want {
+		if rows[i].Name != w.name || rows[i].Type != w.rrType || rows[i].State != w.state {
+			t.Errorf("row %d = %s %s %s, want %s %s %s",
+				i, rows[i].Name, rows[i].Type, rows[i].State, w.name, w.rrType, w.state)
+		}
+	}
+
+	if got := Summarize(rows); got != (Summary{Same: 1, Differs: 2, PrimaryOnly: 1, ViewOnly: 1}) {
+		t.Errorf("Summarize = %+v", got)
+	}
+
+	if row := Find(rows, "mail.example.com.", "A"); row == nil || row.Side(SideView) != nil {
+		t.Errorf("Find(mail A) = %+v", row)
+	}
+
+	if row := Find(rows, "example.com.", "SOA"); row != nil {
+		t.Errorf("Find(SOA) = %+v, want nil", row)
+	}
+}
+
+func TestDiffMissingView(t *testing.T) {
+	primary := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{rrSet("www.example.com.", pdnsapi.RRTypeA, 300, "192.0.2.1")}}
+
+	rows := Diff(primary, nil)
+	if len(rows) != 1 || rows[0].State != StatePrimaryOnly {
+		t.Errorf("Diff(primary, nil) = %+v", rows)
+	}
+}
+
+func TestComparable(t *testing.T) {
+	for rrType, want := range map[string]bool{
+		"A": true, "TXT": true, "SOA": false, "DNSKEY": false, "RRSIG": false, "TYPE65534": false,
+	} {
+		if got := Comparable(rrType); got != want {
+			t.Errorf("Comparable(%q) = %v, want %v", rrType, got, want)
+		}
+	}
+}
+
+func TestParseRecords(t *testing.T) {
+	got := ParseRecords("  192.0.2.2\n\n192.0.2.1\r\n192.0.2.2\n")
+	want := []Record{{Content: "192.0.2.1"}, {Content: "192.0.2.2"}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRecords = %+v, want %+v", got, want)
+	}
+
+	if got := ParseRecords(" \n"); len(got) != 0 {
+		t.Errorf("ParseRecords(blank) = %+v, want none", got)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	patch := Patch("www.example.com.", "A", &RRset{TTL: 60, Records: []Record{{Content: "192.0.2.1"}}})
+
+	if *patch.ChangeType != pdnsapi.ChangeTypeReplace || *patch.TTL != 60 || len(patch.Records) != 1 ||
+		*patch.Records[0].Content != "192.0.2.1" || *patch.Records[0].Disabled {
+		t.Errorf("Patch = %+v", patch)
+	}
+
+	patch = Patch("www.example.com.", "A", nil)
+	if *patch.ChangeType != pdnsapi.ChangeTypeDelete || patch.Records != nil {
+		t.Errorf("Patch(nil) = %+v", patch)
+	}
+}
+
+func TestChange(t *testing.T) {
+	before := &RRset{TTL: 300, Records: []Record{{Content: "192.0.2.1"}}}
+	after := &RRset{TTL: 60, Records: []Record{{Content: "10.0.0.1"}}}
+
+	tests := []struct {
+		before, after *RRset
+		action        string
+	}{
+		{nil, after, "added"},
+		{before, nil, "deleted"},
+		{before, after, "modified"},
+	}
+
+	for _, tt := range tests {
+		change := Change("www.example.com.", "A", tt.before, tt.after)
+		if change.Action != tt.action {
+			t.Errorf("Change action = %q, want %q", change.Action, tt.action)
+		}
+	}
+
+	change := Change("www.example.com.", "A", before, after)
+	if change.OldTTL != 300 || change.NewTTL != 60 ||
+		!reflect.DeepEqual(change.Old, []string{"192.0.2.1"}) || !reflect.DeepEqual(change.New, []string{"10.0.0.1"}) {
+		t.Errorf("Change = %+v", change)
+	}
+}
@@ -0,0 +1,112 @@
+// Package memorystorage provides a session.StorageBackend that keeps entries
+// in the process memory. Entries are lost on restart and not shared between
+// replicas, so it is meant for development.
+package memorystorage
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of writes between removals of expired entries.
+const sweepEvery = 1000
+
+type entry struct {
+	value  []byte
+	expiry time.Time
+}
+
+// expired reports whether the entry has expired at now.
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiry.IsZero() && now.After(e.expiry)
+}
+
+// Storage is a session.StorageBackend backed by a map. It is safe for
+// concurrent use.
+type Storage struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	writes  int
+}
+
+// New returns an empty Storage.
+func New() *Storage {
+	return &Storage{entries: make(map[string]entry)}
+}
+
+// Get returns the value for key, or nil if it is missing or expired.
+func (s *Storage) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	if e.expired(time.Now()) {
+		delete(s.entries, key)
+		return nil, nil
+	}
+
+	return append([]byte(nil), e.value...), nil
+}
+
+// Set stores value under key with the given expiration duration.
+// A zero exp means the entry never expires.
+func (s *Storage) Set(key string, val []byte, exp time.Duration) error {
+	e := entry{value: append([]byte(nil), val...)}
+	if exp > 0 {
+		e.expiry = time.Now().Add(exp)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = e
+
+	// Expired entries are only dropped when read; sweep now and then so that
+	// abandoned sessions do not pile up.
+	if s.writes++; s.writes >= sweepEvery {
+		s.writes = 0
+		now := time.Now()
+
+		for k, e := range s.entries {
+			if e.expired(now) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the entry for key.
+func (s *Storage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+// GetDelete removes the entry for key and returns its value, or nil if it is
+// missing or expired.
+func (s *Storage) GetDelete(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	delete(s.entries, key)
+
+	if e.expired(time.Now()) {
+		return nil, nil
+	}
+
+	return e.value, nil
+}
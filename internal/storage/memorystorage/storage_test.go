@@ -0,0 +1,70 @@
+package memorystorage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStorage(t *testing.T) {
+	s := New()
+
+	if err := s.Set("a", []byte("one"), 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if err := s.Set("b", []byte("two"), -time.Second); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	s.entries["old"] = entry{value: []byte("x"), expiry: time.Now().Add(-time.Second)}
+
+	if got, _ := s.Get("a"); string(got) != "one" {
+		t.Errorf("Get(a) = %q, want one", got)
+	}
+
+	if got, _ := s.Get("b"); string(got) != "two" {
+		t.Errorf("Get(b) = %q, want two (no expiry)", got)
+	}
+
+	if got, _ := s.Get("old"); got != nil {
+		t.Errorf("Get(old) = %q, want nil for an expired entry", got)
+	}
+
+	if _, ok := s.entries["old"]; ok {
+		t.Error("expired entry was not removed on read")
+	}
+
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if got, _ := s.Get("a"); got != nil {
+		t.Errorf("Get(a) after Delete = %q, want nil", got)
+	}
+}
+
+func TestStorage_GetDelete(t *testing.T) {
+	s := New()
+
+	if err := s.Set("a", []byte("one"), 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	s.entries["old"] = entry{value: []byte("x"), expiry: time.Now().Add(-time.Second)}
+
+	if got, _ := s.GetDelete("a"); string(got) != "one" {
+		t.Errorf("GetDelete(a) = %q, want one", got)
+	}
+
+	if got, _ := s.GetDelete("a"); got != nil {
+		t.Errorf("second GetDelete(a) = %q, want nil", got)
+	}
+
+	if got, _ := s.GetDelete("old"); got != nil {
+		t.Errorf("GetDelete(old) = %q, want nil for an expired entry", got)
+	}
+
+	if _, ok := s.entries["old"]; ok {
+		t.Error("expired entry was not removed")
+	}
+}
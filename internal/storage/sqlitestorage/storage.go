@@ -86,3 +86,31 @@ func (s *Storage) Delete(key string) error {
 
 	return err
 }
+
+// GetDelete removes the entry for key and returns its value, or nil if it is
+// missing or expired. Of several concurrent calls only the one whose delete
+// removes the row returns the value.
+func (s *Storage) GetDelete(key string) ([]byte, error) {
+	var value []byte
+
+	var expiry int64
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`DELETE FROM sessions WHERE "key" = ? RETURNING value, expiry`, key,
+	).Scan(&value, &expiry)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if expiry != 0 && time.Now().UnixNano() > expiry {
+		return nil, nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,110 @@
+package tenant
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/ratelimit"
+)
+
+// API enforces the API request quotas of the tenants of this replica.
+var API = NewAPILimiter()
+
+// APILimiter holds a token bucket per tenant that refills at the API request
+// quota of the tenant per minute, and counts the requests of each tenant in
+// the current minute and since the counts were last taken for the monthly
+// usage.
+type APILimiter struct {
+	mu sync.Mutex
+	// limiters are the limiters by requests per minute; tenants with the
+	// same quota share a limiter, each with its own bucket.
+	limiters map[int]*ratelimit.Limiter
+	windows  map[uint]window
+	counts   map[uint]int64
+}
+
+// window counts the requests of a tenant in the minute starting at start.
+type window struct {
+	start    time.Time
+	requests int
+}
+
+// NewAPILimiter returns an APILimiter without requests.
+func NewAPILimiter() *APILimiter {
+	return &APILimiter{
+		limiters: make(map[int]*ratelimit.Limiter),
+		windows:  make(map[uint]window),
+		counts:   make(map[uint]int64),
+	}
+}
+
+// Allow takes a token from the bucket of the tenant id, which allows
+// perMinute requests per minute, at now; perMinute 0 allows every request.
+// When the bucket is empty it returns false and how long the tenant has to
+// wait for the next token. Allowed requests are counted.
+func (l *APILimiter) Allow(id uint, perMinute int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if perMinute > 0 {
+		limiter, found := l.limiters[perMinute]
+		if !found {
+			limiter = ratelimit.New(float64(perMinute)/60, perMinute)
+			l.limiters[perMinute] = limiter
+		}
+
+		if ok, wait := limiter.Allow(strconv.FormatUint(uint64(id), 10), now); !ok {
+			return false, wait
+		}
+	}
+
+	start := now.Truncate(time.Minute)
+
+	w := l.windows[id]
+	if !w.start.Equal(start) {
+		w = window{start: start}
+	}
+
+	w.requests++
+	l.windows[id] = w
+	l.counts[id]++
+
+	return true, 0
+}
+
+// Requests returns the number of requests of the tenant id allowed in the
+// minute of now.
+func (l *APILimiter) Requests(id uint, now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if w := l.windows[id]; w.start.Equal(now.Truncate(time.Minute)) {
+		return w.requests
+	}
+
+	return 0
+}
+
+// TakeCounts returns the number of requests of each tenant allowed since the
+// last call and starts counting anew.
+func (l *APILimiter) TakeCounts() map[uint]int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counts := l.counts
+	l.counts = make(map[uint]int64, len(counts))
+
+	return counts
+}
+
+// Restore adds counts taken with TakeCounts back, e.g. after they failed to
+// be saved.
+func (l *APILimiter) Restore(counts map[uint]int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for id, n := range counts {
+		l.counts[id] += n
+	}
+}
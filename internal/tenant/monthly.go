@@ -0,0 +1,243 @@
+package tenant
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// EventMonthly is the event of the monthly usage summaries posted to the
+// webhook of [usage].
+const EventMonthly = "tenant_usage.monthly"
+
+// Summary is the usage of the tenants in a month, as exported and posted to
+// the webhook.
+type Summary struct {
+	Event string `json:"event"`
+	// Month is the month, e.g. "2026-10".
+	Month string `json:"month"`
+	// Queries is the number of queries PowerDNS answered in the month, for
+	// the zones of all tenants and of the provider together.
+	Queries int64           `json:"queries"`
+	Tenants []TenantSummary `json:"tenants"`
+}
+
+// TenantSummary is the usage of a tenant in a month.
+type TenantSummary struct {
+	TenantID uint   `json:"tenant_id"`
+	Tenant   string `json:"tenant"`
+	// Zones and Records are the most zones and records the tenant had in the
+	// month.
+	Zones       int   `json:"zones"`
+	Records     int   `json:"records"`
+	APIRequests int64 `json:"api_requests"`
+}
+
+// Month returns the month of t in UTC, e.g. "2026-10".
+func Month(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// ValidMonth reports whether month is a month as returned by Month.
+func ValidMonth(month string) bool {
+	_, err := time.Parse("2006-01", month)
+
+	return err == nil && len(month) == len("2006-01")
+}
+
+// Months returns the months with usage, newest first.
+func Months(db *gorm.DB) ([]models.UsageMonth, error) {
+	var months []models.UsageMonth
+
+	err := db.Order("month DESC").Find(&months).Error
+
+	return months, err
+}
+
+// MonthlyUsage returns the usage of the tenant id in month; a month without
+// usage yet returns zero counts.
+func MonthlyUsage(db *gorm.DB, id uint, month string) (*models.TenantUsage, error) {
+	usage := &models.TenantUsage{TenantID: id, Month: month}
+
+	err := db.Where("tenant_id = ? AND month = ?", id, month).First(usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return usage, nil
+	}
+
+	return usage, err
+}
+
+// MonthlySummary returns the usage of the tenants in month, by tenant name.
+func MonthlySummary(db *gorm.DB, month string) (*Summary, error) {
+	s := &Summary{Event: EventMonthly, Month: month, Tenants: []TenantSummary{}}
+
+	var m models.UsageMonth
+
+	err := db.Where("month = ?", month).First(&m).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	s.Queries = m.Queries
+
+	var usages []models.TenantUsage
+	if err = db.Where("month = ?", month).Order("tenant_name, tenant_id").Find(&usages).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range usages {
+		u := &usages[i]
+		s.Tenants = append(s.Tenants, TenantSummary{
+			TenantID:    u.TenantID,
+			Tenant:      u.TenantName,
+			Zones:       u.Zones,
+			Records:     u.Records,
+			APIRequests: u.APIRequests,
+		})
+	}
+
+	return s, nil
+}
+
+// WriteCSV writes the tenants of s as CSV with a header row. The queries of
+// the month are not counted per tenant and only part of the JSON.
+func (s *Summary) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"month", "tenant_id", "tenant", "zones", "records", "api_requests"}); err != nil {
+		return err
+	}
+
+	for i := range s.Tenants {
+		t := &s.Tenants[i]
+
+		err := cw.Write([]string{
+			s.Month,
+			strconv.FormatUint(uint64(t.TenantID), 10),
+			t.Tenant,
+			strconv.Itoa(t.Zones),
+			strconv.Itoa(t.Records),
+			strconv.FormatInt(t.APIRequests, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// RecordUsage adds requests API requests to the usage of t in month and
+// raises its zones and records to those given when they are more.
+func RecordUsage(db *gorm.DB, t *models.Tenant, month string, zones, records int, requests int64) error {
+	usage := models.TenantUsage{TenantID: t.ID, Month: month}
+
+	err := db.Where("tenant_id = ? AND month = ?", t.ID, month).
+		Attrs(models.TenantUsage{TenantName: t.Name}).
+		FirstOrCreate(&usage).Error
+	if err != nil {
+		return err
+	}
+
+	// Add and raise in the database, so the replicas sharing it each add
+	// their own requests.
+	err = db.Model(&models.TenantUsage{}).Where("id = ?", usage.ID).Updates(map[string]any{
+		"api_requests": gorm.Expr("api_requests + ?", requests),
+		"tenant_name":  t.Name,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	if err = db.Model(&models.TenantUsage{}).Where("id = ? AND zones < ?", usage.ID, zones).
+		Update("zones", zones).Error; err != nil {
+		return err
+	}
+
+	return db.Model(&models.TenantUsage{}).Where("id = ? AND records < ?", usage.ID, records).
+		Update("records", records).Error
+}
+
+// CountQueries adds the queries PowerDNS answered since the last call to
+// month, given counter, the current query counter of PowerDNS. The first
+// call only keeps the counter; a counter lower than the last one means
+// PowerDNS restarted, and all its queries are added. Replicas counting at
+// the same time add the queries once.
+func CountQueries(db *gorm.DB, month string, counter int64) error {
+	m, err := usageMonth(db, month)
+	if err != nil {
+		return err
+	}
+
+	added := counter - m.QueryCounter
+
+	switch {
+	case m.QueryCounter == 0:
+		added = 0
+	case added < 0:
+		added = counter
+	}
+
+	return db.Model(&models.UsageMonth{}).
+		Where("month = ? AND query_counter = ?", month, m.QueryCounter).
+		Updates(map[string]any{
+			"queries":       gorm.Expr("queries + ?", added),
+			"query_counter": counter,
+		}).Error
+}
+
+// usageMonth returns month, creating it with the query counter of the month
+// before so that the queries across the turn of the month are counted.
+func usageMonth(db *gorm.DB, month string) (*models.UsageMonth, error) {
+	var m models.UsageMonth
+
+	err := db.Where("month = ?", month).First(&m).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return &m, err
+	}
+
+	var last models.UsageMonth
+	if err = db.Where("month < ?", month).Order("month DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, err
+	}
+
+	m = models.UsageMonth{Month: month, QueryCounter: last.QueryCounter}
+	if err = db.Where("month = ?", month).Attrs(m).FirstOrCreate(&m).Error; err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// unsent returns the months before month whose summary was not posted yet,
+// oldest first.
+func unsent(db *gorm.DB, month string) ([]models.UsageMonth, error) {
+	var months []models.UsageMonth
+
+	err := db.Where("month < ? AND sent_at IS NULL", month).Order("month").Find(&months).Error
+
+	return months, err
+}
+
+// claimMonth marks the summary of month as sent. It reports false when
+// another replica claimed it first.
+func claimMonth(db *gorm.DB, month string, now time.Time) (bool, error) {
+	res := db.Model(&models.UsageMonth{}).
+		Where("month = ? AND sent_at IS NULL", month).
+		Update("sent_at", now)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+// releaseMonth marks the summary of month as not sent, to retry it.
+func releaseMonth(db *gorm.DB, month string) error {
+	return db.Model(&models.UsageMonth{}).Where("month = ?", month).Update("sent_at", nil).Error
+}
@@ -0,0 +1,200 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestRecordUsage(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+
+	if err := RecordUsage(db, acme, "2026-10", 3, 40, 10); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+
+	if err := RecordUsage(db, acme, "2026-10", 2, 50, 5); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+
+	got, err := MonthlyUsage(db, acme.ID, "2026-10")
+	if err != nil || got.Zones != 3 || got.Records != 50 || got.APIRequests != 15 || got.TenantName != "acme" {
+		t.Errorf("MonthlyUsage() = %+v, %v, want 3 zones, 50 records and 15 requests", got, err)
+	}
+
+	if got, err = MonthlyUsage(db, acme.ID, "2026-11"); err != nil || got.ID != 0 || got.APIRequests != 0 {
+		t.Errorf("MonthlyUsage() of a month without usage = %+v, %v", got, err)
+	}
+}
+
+func TestCountQueries(t *testing.T) {
+	db := newTestDB(t)
+
+	steps := []struct {
+		month   string
+		counter int64
+		want    int64
+	}{
+		{"2026-10", 1000, 0},   // the first count only keeps the counter
+		{"2026-10", 1500, 500}, // +500
+		{"2026-10", 200, 700},  // PowerDNS restarted: +200
+		{"2026-11", 260, 60},   // the next month continues from the last counter
+	}
+
+	for _, step := range steps {
+		if err := CountQueries(db, step.month, step.counter); err != nil {
+			t.Fatalf("CountQueries(%s, %d) error = %v", step.month, step.counter, err)
+		}
+
+		var m models.UsageMonth
+		if err := db.First(&m, "month = ?", step.month).Error; err != nil || m.Queries != step.want {
+			t.Errorf("queries of %s after counter %d = %d, %v, want %d", step.month, step.counter, m.Queries, err, step.want)
+		}
+	}
+}
+
+func TestMonthlySummary(t *testing.T) {
+	db := newTestDB(t)
+	globex := createTenant(t, db, "globex")
+	acme := createTenant(t, db, "acme")
+
+	_ = RecordUsage(db, globex, "2026-10", 1, 5, 7)
+	_ = RecordUsage(db, acme, "2026-10", 2, 30, 100)
+	_ = RecordUsage(db, acme, "2026-09", 9, 9, 9)
+
+	s, err := MonthlySummary(db, "2026-10")
+	if err != nil {
+		t.Fatalf("MonthlySummary() error = %v", err)
+	}
+
+	if s.Event != EventMonthly || len(s.Tenants) != 2 || s.Tenants[0].Tenant != "acme" || s.Tenants[1].APIRequests != 7 {
+		t.Errorf("MonthlySummary() = %+v", s)
+	}
+
+	var b strings.Builder
+	if err = s.WriteCSV(&b); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "month,tenant_id,tenant,zones,records,api_requests\n" +
+		"2026-10,2,acme,2,30,100\n" +
+		"2026-10,1,globex,1,5,7\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestValidMonth(t *testing.T) {
+	for month, want := range map[string]bool{"2026-10": true, "2026-1": false, "2026-13": false, "october": false} {
+		if got := ValidMonth(month); got != want {
+			t.Errorf("ValidMonth(%q) = %v, want %v", month, got, want)
+		}
+	}
+}
+
+func TestRunnerPostsPastMonths(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+
+	type delivery struct {
+		event, signature string
+		summary          Summary
+	}
+
+	deliveries := make(chan delivery, 4)
+	var fail atomic.Bool
+	fail.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		d := delivery{event: r.Header.Get(EventHeader), signature: r.Header.Get(SignatureHeader)}
+		_ = json.Unmarshal(body, &d.summary)
+
+		if d.signature != Sign(body, "s3cret") {
+			t.Errorf("signature %q does not match the body", d.signature)
+		}
+
+		deliveries <- d
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 10, 31, 23, 58, 0, 0, time.UTC)
+	r := &Runner{
+		cfg:     &config.Usage{Interval: time.Minute, WebhookURL: server.URL, WebhookSecret: "s3cret"},
+		db:      db,
+		client:  server.Client(),
+		queries: func(context.Context) (int64, error) { return 100, nil },
+		now:     func() time.Time { return now },
+	}
+
+	API.Restore(map[uint]int64{acme.ID: 42})
+
+	if err := r.runOnce(context.Background()); err != nil {
+		t.Fatalf("runOnce() in October error = %v", err)
+	}
+
+	now = now.Add(5 * time.Minute)
+
+	if err := r.runOnce(context.Background()); err == nil {
+		t.Fatal("runOnce() with a failing webhook did not fail")
+	}
+
+	fail.Store(false)
+
+	if err := r.runOnce(context.Background()); err != nil {
+		t.Fatalf("runOnce() in November error = %v", err)
+	}
+
+	select {
+	case d := <-deliveries:
+		if d.event != EventMonthly || d.summary.Month != "2026-10" || len(d.summary.Tenants) != 1 ||
+			d.summary.Tenants[0].APIRequests != 42 {
+			t.Errorf("delivered %+v", d)
+		}
+	default:
+		t.Fatal("the summary of October was not posted")
+	}
+
+	if err := r.runOnce(context.Background()); err != nil {
+		t.Fatalf("runOnce() after posting error = %v", err)
+	}
+
+	if len(deliveries) != 0 {
+		t.Error("the summary of October was posted twice")
+	}
+}
+
+func TestSumStatistics(t *testing.T) {
+	stat := func(name string, value any) pdnsapi.Statistic {
+		return pdnsapi.Statistic{Name: &name, Value: value}
+	}
+
+	statistics := []pdnsapi.Statistic{
+		stat("udp-queries", "1200"),
+		stat("tcp-queries", "34"),
+		stat("uptime", "9999"),
+		stat("response-sizes", []any{}),
+	}
+
+	if got := sumStatistics(statistics, queryStatistics); got != 1234 {
+		t.Errorf("sumStatistics() = %d, want 1234", got)
+	}
+}
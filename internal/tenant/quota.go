@@ -0,0 +1,135 @@
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/cache"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// ErrQuota is returned, wrapped with the exceeded limit, for changes that
+// would take a tenant over one of its quotas.
+var ErrQuota = errors.New("quota exceeded")
+
+// Quota returns the tenant id with its quotas. With the object cache enabled
+// the tenant is cached until a tenant, user or group changes.
+func Quota(db *gorm.DB, id uint) (*models.Tenant, error) {
+	store := cache.Default()
+	key := "tenant:" + strconv.FormatUint(uint64(id), 10)
+
+	if cached, ok := store.Get(key); ok {
+		t := cached.(models.Tenant)
+		return &t, nil
+	}
+
+	t, err := Get(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	store.Set(key, *t, cache.TTL(), cache.TagPermissions)
+
+	return t, nil
+}
+
+// CheckZones returns an ErrQuota error when adding zones would take the
+// tenant tenantID over its zone quota. The provider, nil, has no quota.
+func CheckZones(db *gorm.DB, tenantID *uint, adding int) error {
+	if tenantID == nil {
+		return nil
+	}
+
+	t, err := Quota(db, *tenantID)
+	if err != nil || t.MaxZones == 0 {
+		return err
+	}
+
+	var count int64
+	if err = db.Model(&models.ZoneTenant{}).Where("tenant_id = ?", t.ID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count+int64(adding) > int64(t.MaxZones) {
+		return fmt.Errorf("%w: tenant %s may have at most %d zones and has %d", ErrQuota, t.Name, t.MaxZones, count)
+	}
+
+	return nil
+}
+
+// CheckRecords returns an ErrQuota error when a change taking zone from
+// before to after records takes it over the record quota of its tenant.
+// Changes that do not add records always pass, so a zone over a lowered
+// quota can still be cleaned up. Zones of the provider have no quota.
+func CheckRecords(db *gorm.DB, zone string, before, after int) error {
+	if after <= before {
+		return nil
+	}
+
+	tenantID, err := OfZone(db, zone)
+	if err != nil || tenantID == nil {
+		return err
+	}
+
+	t, err := Quota(db, *tenantID)
+	if err != nil || t.MaxRecordsPerZone == 0 {
+		return err
+	}
+
+	if after > t.MaxRecordsPerZone {
+		return fmt.Errorf("%w: zones of tenant %s may have at most %d records, %s would have %d",
+			ErrQuota, t.Name, t.MaxRecordsPerZone, zone, after)
+	}
+
+	return nil
+}
+
+// RecordCount returns the number of records in rrSets.
+func RecordCount(rrSets []pdnsapi.RRset) int {
+	n := 0
+	for i := range rrSets {
+		n += len(rrSets[i].Records)
+	}
+
+	return n
+}
+
+// PatchedRecords returns the number of records of current before and after
+// applying patch, a PowerDNS API patch of REPLACE and DELETE changes.
+func PatchedRecords(current *pdnsapi.Zone, patch []pdnsapi.RRset) (before, after int) {
+	existing := make(map[string]int, len(current.RRsets))
+
+	for i := range current.RRsets {
+		rr := &current.RRsets[i]
+		if rr.Name == nil || rr.Type == nil {
+			continue
+		}
+
+		existing[*rr.Name+" "+string(*rr.Type)] += len(rr.Records)
+		before += len(rr.Records)
+	}
+
+	after = before
+
+	for i := range patch {
+		rr := &patch[i]
+		if rr.Name == nil || rr.Type == nil {
+			continue
+		}
+
+		key := *rr.Name + " " + string(*rr.Type)
+		after -= existing[key]
+		existing[key] = 0
+
+		if rr.ChangeType == nil || *rr.ChangeType != pdnsapi.ChangeTypeDelete {
+			after += len(rr.Records)
+			existing[key] = len(rr.Records)
+		}
+	}
+
+	return before, after
+}
@@ -0,0 +1,147 @@
+package tenant
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func TestCheckZones(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+
+	if err := CheckZones(db, &acme.ID, 5); err != nil {
+		t.Errorf("CheckZones() without a quota error = %v", err)
+	}
+
+	db.Model(acme).Update("max_zones", 2)
+
+	if err := AssignZone(db, "example.com.", &acme.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckZones(db, &acme.ID, 1); err != nil {
+		t.Errorf("CheckZones(second zone) error = %v", err)
+	}
+
+	if err := CheckZones(db, &acme.ID, 2); !errors.Is(err, ErrQuota) {
+		t.Errorf("CheckZones(third zone) error = %v, want ErrQuota", err)
+	}
+
+	if err := CheckZones(db, nil, 100); err != nil {
+		t.Errorf("CheckZones(provider) error = %v", err)
+	}
+}
+
+func TestCheckRecords(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+	db.Model(acme).Update("max_records_per_zone", 10)
+
+	if err := AssignZone(db, "example.com.", &acme.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		zone          string
+		before, after int
+		wantErr       bool
+	}{
+		{"within quota", "example.com.", 5, 10, false},
+		{"over quota", "example.com.", 5, 11, true},
+		{"shrinking over quota", "example.com.", 20, 15, false},
+		{"provider zone", "example.org.", 5, 500, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRecords(db, tt.zone, tt.before, tt.after)
+			if errors.Is(err, ErrQuota) != tt.wantErr || (err != nil && !tt.wantErr) {
+				t.Errorf("CheckRecords() error = %v, want quota error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPatchedRecords(t *testing.T) {
+	rrSet := func(name string, typ pdnsapi.RRType, records int, change pdnsapi.ChangeType) pdnsapi.RRset {
+		rr := pdnsapi.RRset{Name: &name, Type: &typ, Records: make([]pdnsapi.Record, records)}
+		if change != "" {
+			rr.ChangeType = &change
+		}
+
+		return rr
+	}
+
+	current := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		rrSet("example.com.", pdnsapi.RRTypeSOA, 1, ""),
+		rrSet("example.com.", pdnsapi.RRTypeNS, 2, ""),
+		rrSet("www.example.com.", pdnsapi.RRTypeA, 2, ""),
+	}}
+
+	before, after := PatchedRecords(current, []pdnsapi.RRset{
+		rrSet("www.example.com.", pdnsapi.RRTypeA, 3, pdnsapi.ChangeTypeReplace),
+		rrSet("example.com.", pdnsapi.RRTypeNS, 0, pdnsapi.ChangeTypeDelete),
+		rrSet("mail.example.com.", pdnsapi.RRTypeMX, 1, pdnsapi.ChangeTypeReplace),
+	})
+
+	if before != 5 || after != 5 {
+		t.Errorf("PatchedRecords() = %d, %d, want 5, 5", before, after)
+	}
+
+	if got := RecordCount(current.RRsets); got != 5 {
+		t.Errorf("RecordCount() = %d, want 5", got)
+	}
+}
+
+func TestAPILimiter(t *testing.T) {
+	limiter := NewAPILimiter()
+	now := time.Date(2026, 10, 17, 12, 0, 30, 0, time.UTC)
+
+	for i := range 3 {
+		if ok, _ := limiter.Allow(1, 3, now); !ok {
+			t.Fatalf("request %d rejected within the quota", i+1)
+		}
+	}
+
+	if ok, wait := limiter.Allow(1, 3, now); ok || wait <= 0 {
+		t.Errorf("Allow() over the quota = %v, %v, want false and a wait", ok, wait)
+	}
+
+	if ok, _ := limiter.Allow(2, 3, now); !ok {
+		t.Error("another tenant shares the bucket of the first")
+	}
+
+	if ok, _ := limiter.Allow(1, 0, now); !ok {
+		t.Error("Allow() without a quota rejected the request")
+	}
+
+	if got := limiter.Requests(1, now); got != 4 {
+		t.Errorf("Requests() = %d, want 4", got)
+	}
+
+	if got := limiter.Requests(1, now.Add(time.Minute)); got != 0 {
+		t.Errorf("Requests() in the next minute = %d, want 0", got)
+	}
+}
+
+func TestUsageOf(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+
+	if err := AssignZone(db, "example.com.", &acme.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := UsageOf(db, acme, time.Now())
+	if err != nil {
+		t.Fatalf("UsageOf() error = %v", err)
+	}
+
+	if len(usage.Zones) != 1 || usage.Zones[0].Name != "example.com." || usage.Zones[0].Scanned {
+		t.Errorf("UsageOf().Zones = %+v, want example.com. not scanned", usage.Zones)
+	}
+}
@@ -0,0 +1,273 @@
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+const (
+	// sendTimeout bounds one webhook request.
+	sendTimeout = 30 * time.Second
+
+	// maxResponse is how much of an error response is kept for the log.
+	maxResponse = 200
+
+	// SignatureHeader carries the HMAC-SHA256 of the body of a webhook
+	// request, keyed with [usage] webhooksecret, as "sha256=<hex>".
+	SignatureHeader = "X-Signature-256"
+	// EventHeader carries the event of a webhook request.
+	EventHeader = "X-Event"
+)
+
+// ErrDelivery is returned when the webhook rejects a summary.
+var ErrDelivery = errors.New("webhook rejected the usage summary")
+
+// queryStatistics are the PowerDNS counters of the queries it answered.
+var queryStatistics = []string{"udp-queries", "tcp-queries"}
+
+// Runner records the usage of the tenants every [usage] interval and posts
+// the summaries of past months to the webhook.
+type Runner struct {
+	cfg    *config.Usage
+	db     *gorm.DB
+	client *http.Client
+	// queries returns the query counter of PowerDNS.
+	queries func(ctx context.Context) (int64, error)
+	now     func() time.Time
+}
+
+// NewRunner returns a Runner for the tenants in db.
+func NewRunner(cfg *config.Usage, db *gorm.DB) *Runner {
+	return &Runner{cfg: cfg, db: db, client: &http.Client{}, queries: powerDNSQueries, now: time.Now}
+}
+
+// Run records the usage every interval until ctx is canceled.
+func (r *Runner) Run(ctx context.Context) {
+	jobs.Scheduled(jobs.TenantUsage, r.cfg.Interval)
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.TenantUsage, func() error { return r.runOnce(ctx) })
+		}
+	}
+}
+
+func (r *Runner) runOnce(ctx context.Context) error {
+	now := r.now()
+	month := Month(now)
+
+	if err := r.recordUsage(month, now); err != nil {
+		log.Error().Err(err).Msg("tenant usage: failed to record the usage of tenants")
+		return err
+	}
+
+	if counter, err := r.queries(ctx); err != nil {
+		log.Warn().Err(err).Msg("tenant usage: failed to read the query statistics of PowerDNS")
+	} else if err = CountQueries(r.db, month, counter); err != nil {
+		log.Error().Err(err).Msg("tenant usage: failed to count the queries")
+		return err
+	}
+
+	return r.sendSummaries(ctx, month, now)
+}
+
+// recordUsage adds the API requests counted since the last run to month and
+// raises the zones and records of each tenant.
+func (r *Runner) recordUsage(month string, now time.Time) error {
+	if _, err := usageMonth(r.db, month); err != nil {
+		return err
+	}
+
+	counts := API.TakeCounts()
+	defer API.Restore(counts)
+
+	tenants, err := List(r.db)
+	if err != nil {
+		return err
+	}
+
+	for i := range tenants {
+		t := &tenants[i]
+
+		usage, err := UsageOf(r.db, t, now)
+		if err != nil {
+			return err
+		}
+
+		if err = RecordUsage(r.db, t, month, len(usage.Zones), usage.Records, counts[t.ID]); err != nil {
+			return err
+		}
+
+		delete(counts, t.ID)
+	}
+
+	// The rest are requests of tenants deleted since.
+	clear(counts)
+
+	return nil
+}
+
+// sendSummaries posts the summaries of the months before month that were not
+// posted yet. A failed summary is retried on the next run.
+func (r *Runner) sendSummaries(ctx context.Context, month string, now time.Time) error {
+	if r.cfg.WebhookURL == "" {
+		return nil
+	}
+
+	months, err := unsent(r.db, month)
+	if err != nil {
+		return err
+	}
+
+	for i := range months {
+		m := months[i].Month
+
+		claimed, err := claimMonth(r.db, m, now)
+		if err != nil {
+			return err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		summary, err := MonthlySummary(r.db, m)
+		if err == nil {
+			err = Post(ctx, r.client, r.cfg, summary)
+		}
+
+		if err != nil {
+			log.Error().Err(err).Str("month", m).Msg("tenant usage: failed to post the monthly summary")
+
+			if errRelease := releaseMonth(r.db, m); errRelease != nil {
+				log.Error().Err(errRelease).Str("month", m).Msg("tenant usage: failed to retry the monthly summary")
+			}
+
+			return err
+		}
+
+		log.Info().Str("month", m).Int("tenants", len(summary.Tenants)).Msg("tenant usage: posted the monthly summary")
+	}
+
+	return nil
+}
+
+// Sign returns the value of SignatureHeader for body and secret.
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Post posts s as JSON to the webhook of cfg, signed when cfg has a secret.
+func Post(ctx context.Context, client *http.Client, cfg *config.Usage, s *Summary) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, s.Event)
+
+	if cfg.WebhookSecret != "" {
+		req.Header.Set(SignatureHeader, Sign(body, cfg.WebhookSecret))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may hold credentials; keep it out of the error.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+
+		return fmt.Errorf("%w: %s: %s", ErrDelivery, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// powerDNSQueries returns the number of queries PowerDNS answered since it
+// started.
+func powerDNSQueries(ctx context.Context) (int64, error) {
+	if powerdns.Engine.Client == nil {
+		return 0, powerdns.ErrClientNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	statistics, err := powerdns.Engine.Statistics.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return sumStatistics(statistics, queryStatistics), nil
+}
+
+// sumStatistics returns the sum of the counters names of statistics.
+func sumStatistics(statistics []pdnsapi.Statistic, names []string) int64 {
+	var sum int64
+
+	for i := range statistics {
+		st := &statistics[i]
+		if st.Name == nil {
+			continue
+		}
+
+		for _, name := range names {
+			if *st.Name != name {
+				continue
+			}
+
+			if value, ok := st.Value.(string); ok {
+				n, _ := strconv.ParseInt(value, 10, 64)
+				sum += n
+			}
+		}
+	}
+
+	return sum
+}
@@ -0,0 +1,268 @@
+// Package tenant manages tenants: accounts of a hosting provider's customers
+// that own users, groups and zones. Administrators of a tenant only see the
+// resources of their tenant; users outside any tenant belong to the provider
+// and see everything their permissions allow.
+package tenant
+
+import (
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+var (
+	// ErrInUse is returned when deleting a tenant that still has users,
+	// groups or zones.
+	ErrInUse = errors.New("tenant still has users, groups or zones")
+	// ErrUnknown is returned for a tenant that does not exist.
+	ErrUnknown = errors.New("unknown tenant")
+)
+
+// Counts are the number of resources of a tenant.
+type Counts struct {
+	Users  int64
+	Groups int64
+	Zones  int64
+}
+
+// List returns the tenants ordered by name.
+func List(db *gorm.DB) ([]models.Tenant, error) {
+	var tenants []models.Tenant
+
+	err := db.Order("name").Find(&tenants).Error
+
+	return tenants, err
+}
+
+// Get returns the tenant id.
+func Get(db *gorm.DB, id uint) (*models.Tenant, error) {
+	var t models.Tenant
+	if err := db.First(&t, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Name returns the name of the tenant id, or "" when id is nil or the tenant
+// does not exist.
+func Name(db *gorm.DB, id *uint) string {
+	if id == nil {
+		return ""
+	}
+
+	var name string
+	db.Model(&models.Tenant{}).Where("id = ?", *id).Pluck("name", &name)
+
+	return name
+}
+
+// CountAll returns the resource counts of every tenant by tenant ID.
+func CountAll(db *gorm.DB) (map[uint]Counts, error) {
+	type row struct {
+		TenantID uint
+		Count    int64
+	}
+
+	counts := make(map[uint]Counts)
+
+	for _, src := range []struct {
+		model any
+		add   func(c *Counts, n int64)
+	}{
+		{&models.User{}, func(c *Counts, n int64) { c.Users = n }},
+		{&models.Group{}, func(c *Counts, n int64) { c.Groups = n }},
+		{&models.ZoneTenant{}, func(c *Counts, n int64) { c.Zones = n }},
+	} {
+		var rows []row
+		if err := db.Model(src.model).
+			Select("tenant_id, COUNT(*) AS count").
+			Where("tenant_id IS NOT NULL").
+			Group("tenant_id").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		for _, r := range rows {
+			c := counts[r.TenantID]
+			src.add(&c, r.Count)
+			counts[r.TenantID] = c
+		}
+	}
+
+	return counts, nil
+}
+
+// Delete deletes the tenant t and its chat integrations. Tenants with users,
+// groups or zones are kept.
+func Delete(db *gorm.DB, t *models.Tenant) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.User{}, &models.Group{}, &models.ZoneTenant{}} {
+			var n int64
+			if err := tx.Model(model).Where("tenant_id = ?", t.ID).Count(&n).Error; err != nil {
+				return err
+			}
+
+			if n > 0 {
+				return ErrInUse
+			}
+		}
+
+		if err := tx.Where("tenant_id = ?", t.ID).Delete(&models.Integration{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(t).Error
+	})
+}
+
+// Scope limits a query on users, groups or integrations to the tenant tenantID. A nil
+// tenantID, the provider, sees every row.
+func Scope(tenantID *uint) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if tenantID == nil {
+			return tx
+		}
+
+		return tx.Where("tenant_id = ?", *tenantID)
+	}
+}
+
+// Same reports whether a and b are the same tenant, or both the provider.
+func Same(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return *a == *b
+}
+
+// Zones returns the zones of the tenant tenantID ordered by name.
+func Zones(db *gorm.DB, tenantID uint) ([]string, error) {
+	var zones []string
+
+	err := db.Model(&models.ZoneTenant{}).
+		Where("tenant_id = ?", tenantID).
+		Order("zone_name").
+		Pluck("zone_name", &zones).Error
+
+	return zones, err
+}
+
+// OfZone returns the ID of the tenant zone belongs to, or nil for a zone of
+// the provider.
+func OfZone(db *gorm.DB, zone string) (*uint, error) {
+	var assignment models.ZoneTenant
+
+	err := db.Where("zone_name = ?", zone).First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &assignment.TenantID, nil
+}
+
+// AssignZone assigns zone to the tenant tenantID, or back to the provider
+// for nil.
+func AssignZone(db *gorm.DB, zone string, tenantID *uint) error {
+	if tenantID == nil {
+		return db.Where("zone_name = ?", zone).Delete(&models.ZoneTenant{}).Error
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "zone_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tenant_id"}),
+	}).Create(&models.ZoneTenant{ZoneName: zone, TenantID: *tenantID}).Error
+}
+
+// ActivityScope limits a query on the activity log to the entries of the
+// tenant tenantID: those of its users and those of its zones. A nil tenantID,
+// the provider, sees every entry.
+func ActivityScope(tenantID *uint) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if tenantID == nil {
+			return tx
+		}
+
+		db := tx.Session(&gorm.Session{NewDB: true})
+		users := db.Model(&models.User{}).Select("id").Where("tenant_id = ?", *tenantID)
+		zones := db.Model(&models.ZoneTenant{}).Select("zone_name").Where("tenant_id = ?", *tenantID)
+
+		return tx.Where("user_id IN (?) OR (resource_type = ? AND resource_name IN (?))", users, "zone", zones)
+	}
+}
+
+// Parse returns the tenant of the form value raw: the ID of a tenant, or ""
+// and "0" for the provider. It returns ErrUnknown for any other value.
+func Parse(db *gorm.DB, raw string) (*uint, error) {
+	if raw == "" || raw == "0" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 0)
+	if err != nil {
+		return nil, ErrUnknown
+	}
+
+	t, err := Get(db, uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknown
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &t.ID, nil
+}
+
+// ID returns the tenant ID tenantID points to, or 0 for the provider.
+func ID(tenantID *uint) uint {
+	if tenantID == nil {
+		return 0
+	}
+
+	return *tenantID
+}
+
+// Choices returns the tenants an administrator of the tenant actor may put
+// users and groups in: all of them for the provider, none for a tenant, whose
+// administrators keep everything in their own tenant.
+func Choices(db *gorm.DB, actor *uint) ([]models.Tenant, error) {
+	if actor != nil {
+		return nil, nil
+	}
+
+	return List(db)
+}
+
+// Submitted returns the tenant of a user or group an administrator of the
+// tenant actor submitted with the tenant_id form value raw. Administrators of
+// a tenant always get their own tenant; for the provider an empty raw keeps
+// current.
+func Submitted(db *gorm.DB, actor *uint, raw string, current *uint) (*uint, error) {
+	if actor != nil {
+		return actor, nil
+	}
+
+	if raw == "" {
+		return current, nil
+	}
+
+	return Parse(db, raw)
+}
+
+// AssignCreated assigns a zone the user created to the tenant of the user.
+// A zone the provider creates drops the assignment an earlier zone of the
+// same name may have left.
+func AssignCreated(db *gorm.DB, zone string, creator *models.User) error {
+	return AssignZone(db, zone, creator.TenantID)
+}
@@ -0,0 +1,195 @@
+package tenant
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.Tenant{}, &models.User{}, &models.Group{},
+		&models.ZoneTenant{}, &models.ActivityLog{}, &models.TenantUsage{}, &models.UsageMonth{},
+		&models.Integration{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func createTenant(t *testing.T, db *gorm.DB, name string) *models.Tenant {
+	t.Helper()
+
+	tenant := &models.Tenant{Name: name}
+	if err := db.Create(tenant).Error; err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	return tenant
+}
+
+func createUser(t *testing.T, db *gorm.DB, name string, tenantID *uint) *models.User {
+	t.Helper()
+
+	user := &models.User{Username: name, Email: name + "@example.com", TenantID: tenantID}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return user
+}
+
+func TestAssignZone(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+	globex := createTenant(t, db, "globex")
+
+	if err := AssignZone(db, "example.com.", &acme.ID); err != nil {
+		t.Fatalf("AssignZone() error = %v", err)
+	}
+
+	if err := AssignZone(db, "example.com.", &globex.ID); err != nil {
+		t.Fatalf("AssignZone(move) error = %v", err)
+	}
+
+	owner, err := OfZone(db, "example.com.")
+	if err != nil || owner == nil || *owner != globex.ID {
+		t.Errorf("OfZone() = %v, %v, want %d", owner, err, globex.ID)
+	}
+
+	provider := createUser(t, db, "root", nil)
+	if err = AssignCreated(db, "example.com.", provider); err != nil {
+		t.Fatalf("AssignCreated(provider) error = %v", err)
+	}
+
+	if owner, err = OfZone(db, "example.com."); err != nil || owner != nil {
+		t.Errorf("OfZone() after the provider created the zone = %v, %v, want nil", owner, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+
+	if err := AssignZone(db, "example.com.", &acme.ID); err != nil {
+		t.Fatalf("AssignZone() error = %v", err)
+	}
+
+	if err := Delete(db, acme); !errors.Is(err, ErrInUse) {
+		t.Errorf("Delete(with zone) error = %v, want ErrInUse", err)
+	}
+
+	if err := AssignZone(db, "example.com.", nil); err != nil {
+		t.Fatalf("AssignZone(nil) error = %v", err)
+	}
+
+	db.Create(&models.Integration{Name: "acme-chat", Kind: models.IntegrationSlack,
+		WebhookURL: "https://hooks.example.com/acme", TenantID: &acme.ID})
+
+	if err := Delete(db, acme); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+
+	var left int64
+	if db.Model(&models.Integration{}).Count(&left); left != 0 {
+		t.Errorf("%d integrations left after deleting their tenant, want 0", left)
+	}
+}
+
+func TestCountAll(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+	createUser(t, db, "alice", &acme.ID)
+	createUser(t, db, "bob", &acme.ID)
+	createUser(t, db, "root", nil)
+
+	if err := AssignZone(db, "example.com.", &acme.ID); err != nil {
+		t.Fatalf("AssignZone() error = %v", err)
+	}
+
+	counts, err := CountAll(db)
+	if err != nil {
+		t.Fatalf("CountAll() error = %v", err)
+	}
+
+	if got, want := counts[acme.ID], (Counts{Users: 2, Zones: 1}); got != want {
+		t.Errorf("CountAll()[acme] = %+v, want %+v", got, want)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+	globex := createTenant(t, db, "globex")
+	alice := createUser(t, db, "alice", &acme.ID)
+	bob := createUser(t, db, "bob", &globex.ID)
+	createUser(t, db, "root", nil)
+
+	var users []models.User
+	if err := db.Scopes(Scope(&acme.ID)).Find(&users).Error; err != nil || len(users) != 1 || users[0].ID != alice.ID {
+		t.Errorf("Scope(acme) = %v, %v, want alice", users, err)
+	}
+
+	if err := db.Scopes(Scope(nil)).Find(&users).Error; err != nil || len(users) != 3 {
+		t.Errorf("Scope(nil) = %d users, %v, want 3", len(users), err)
+	}
+
+	if err := AssignZone(db, "acme.example.", &acme.ID); err != nil {
+		t.Fatalf("AssignZone() error = %v", err)
+	}
+
+	aliceID, bobID := uint64(alice.ID), uint64(bob.ID)
+	entries := []models.ActivityLog{
+		{UserID: &aliceID, Action: "login", ResourceType: "auth"},
+		{UserID: &bobID, Action: "login", ResourceType: "auth"},
+		{Action: "zone_updated", ResourceType: "zone", ResourceName: "acme.example."},
+		{Action: "zone_updated", ResourceType: "zone", ResourceName: "globex.example."},
+	}
+
+	if err := db.Create(&entries).Error; err != nil {
+		t.Fatalf("failed to create activity: %v", err)
+	}
+
+	var visible []models.ActivityLog
+	if err := db.Scopes(ActivityScope(&acme.ID)).Order("id").Find(&visible).Error; err != nil {
+		t.Fatalf("ActivityScope() error = %v", err)
+	}
+
+	if len(visible) != 2 || visible[0].ID != entries[0].ID || visible[1].ID != entries[2].ID {
+		t.Errorf("ActivityScope(acme) = %+v, want the entries of alice and acme.example.", visible)
+	}
+}
+
+func TestSubmitted(t *testing.T) {
+	db := newTestDB(t)
+	acme := createTenant(t, db, "acme")
+	globex := createTenant(t, db, "globex")
+
+	got, err := Submitted(db, &acme.ID, "0", nil)
+	if err != nil || got == nil || *got != acme.ID {
+		t.Errorf("Submitted(tenant actor) = %v, %v, want acme", got, err)
+	}
+
+	got, err = Submitted(db, nil, "", &globex.ID)
+	if err != nil || got == nil || *got != globex.ID {
+		t.Errorf("Submitted(empty) = %v, %v, want the current tenant", got, err)
+	}
+
+	if got, err = Submitted(db, nil, "0", &globex.ID); err != nil || got != nil {
+		t.Errorf("Submitted(0) = %v, %v, want the provider", got, err)
+	}
+
+	if _, err = Submitted(db, nil, "999", nil); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Submitted(unknown) error = %v, want ErrUnknown", err)
+	}
+}
@@ -0,0 +1,61 @@
+package tenant
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonestats"
+)
+
+// Usage is what a tenant uses of its quotas.
+type Usage struct {
+	Tenant *models.Tenant
+	Zones  []ZoneUsage
+	// Records is the number of records in the scanned zones.
+	Records int
+	// APIRequests is the number of API requests in the current minute.
+	APIRequests int
+	// Month is the usage recorded for the current month so far.
+	Month *models.TenantUsage
+}
+
+// ZoneUsage is the number of records of a zone of a tenant.
+type ZoneUsage struct {
+	Name    string
+	Records int
+	// Scanned is false until the zone statistics have scanned the zone;
+	// Records is 0 until then.
+	Scanned bool
+}
+
+// UsageOf returns the usage of t at now. Record counts come from the last
+// scan of the zone statistics, API requests from API and the usage of the
+// month from the last run of the Runner.
+func UsageOf(db *gorm.DB, t *models.Tenant, now time.Time) (*Usage, error) {
+	zones, err := Zones(db, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	month, err := MonthlyUsage(db, t.ID, Month(now))
+	if err != nil {
+		return nil, err
+	}
+
+	u := &Usage{Tenant: t, Zones: make([]ZoneUsage, 0, len(zones)), APIRequests: API.Requests(t.ID, now), Month: month}
+
+	for _, name := range zones {
+		zone := ZoneUsage{Name: name}
+		if entry, ok := zonestats.Default.Entry(name); ok && entry.Err == "" {
+			zone.Records = entry.Records
+			zone.Scanned = true
+		}
+
+		u.Records += zone.Records
+		u.Zones = append(u.Zones, zone)
+	}
+
+	return u, nil
+}
@@ -15,6 +15,7 @@ import (
 	"golang.org/x/mod/semver"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
 )
 
 const (
@@ -94,7 +95,8 @@ func (c *Checker) Run(ctx context.Context) {
 		return
 	}
 
-	c.checkOnce(ctx)
+	jobs.Scheduled(jobs.UpdateCheck, c.interval)
+	c.run(ctx)
 
 	ticker := time.NewTicker(c.interval)
 	defer ticker.Stop()
@@ -104,11 +106,16 @@ func (c *Checker) Run(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			c.checkOnce(ctx)
+			c.run(ctx)
 		}
 	}
 }
 
+// run performs one instrumented check.
+func (c *Checker) run(ctx context.Context) {
+	_ = jobs.Run(jobs.UpdateCheck, func() error { return c.checkOnce(ctx) })
+}
+
 // githubRelease is the subset of the GitHub release payload we consume.
 type githubRelease struct {
 	TagName string `json:"tag_name"`
@@ -116,12 +123,12 @@ type githubRelease struct {
 }
 
 // checkOnce queries the latest release and updates the cached Info. Errors are
-// logged and the previous snapshot is kept.
-func (c *Checker) checkOnce(ctx context.Context) {
+// logged and returned; the previous snapshot is kept.
+func (c *Checker) checkOnce(ctx context.Context) error {
 	latest, err := c.fetchLatest(ctx)
 	if err != nil {
 		log.Debug().Err(err).Str("repository", c.repository).Msg("updatecheck: failed to fetch latest release")
-		return
+		return err
 	}
 
 	available := semver.IsValid(c.current) && semver.IsValid(latest.TagName) &&
@@ -140,6 +147,8 @@ func (c *Checker) checkOnce(ctx context.Context) {
 		log.Info().Str("current", c.current).Str("latest", latest.TagName).
 			Msg("updatecheck: a newer version is available")
 	}
+
+	return nil
 }
 
 func (c *Checker) fetchLatest(ctx context.Context) (githubRelease, error) {
@@ -0,0 +1,78 @@
+// Package userzones keeps the zones each user starred as favorites and the
+// zones they opened most recently, so the dashboard can list them for quick
+// access.
+package userzones
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// RecentLimit is the number of recently opened zones kept per user.
+const RecentLimit = 10
+
+// Favorites returns the names of the zones userID starred, by name.
+func Favorites(db *gorm.DB, userID uint64) ([]string, error) {
+	var names []string
+
+	err := db.Model(&models.ZoneFavorite{}).
+		Where("user_id = ?", userID).
+		Order("zone_name").
+		Pluck("zone_name", &names).Error
+
+	return names, err
+}
+
+// SetFavorite stars zoneName for userID, or removes the star.
+func SetFavorite(db *gorm.DB, userID uint64, zoneName string, favorite bool) error {
+	if !favorite {
+		return db.Where("user_id = ? AND zone_name = ?", userID, zoneName).
+			Delete(&models.ZoneFavorite{}).Error
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ZoneFavorite{UserID: userID, ZoneName: zoneName}).Error
+}
+
+// RecordVisit notes that userID opened zoneName at now and forgets the visits
+// beyond RecentLimit.
+func RecordVisit(db *gorm.DB, userID uint64, zoneName string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "zone_name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"visited_at"}),
+		}).Create(&models.ZoneVisit{UserID: userID, ZoneName: zoneName, VisitedAt: now}).Error
+		if err != nil {
+			return err
+		}
+
+		var stale []uint64
+
+		err = tx.Model(&models.ZoneVisit{}).
+			Where("user_id = ?", userID).
+			Order("visited_at DESC, id DESC").
+			Offset(RecentLimit).
+			Pluck("id", &stale).Error
+		if err != nil || len(stale) == 0 {
+			return err
+		}
+
+		return tx.Delete(&models.ZoneVisit{}, stale).Error
+	})
+}
+
+// Recent returns the zones userID opened most recently, newest first.
+func Recent(db *gorm.DB, userID uint64) ([]models.ZoneVisit, error) {
+	var visits []models.ZoneVisit
+
+	err := db.Where("user_id = ?", userID).
+		Order("visited_at DESC, id DESC").
+		Limit(RecentLimit).
+		Find(&visits).Error
+
+	return visits, err
+}
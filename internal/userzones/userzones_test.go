@@ -0,0 +1,92 @@
+package userzones
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.ZoneFavorite{}, &models.ZoneVisit{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	for _, name := range []string{"alice", "bob"} {
+		if err = db.Create(&models.User{Username: name, Email: name + "@example.com"}).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	return db
+}
+
+func TestFavorites(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, zone := range []string{"b.example.", "a.example.", "b.example."} {
+		if err := SetFavorite(db, 1, zone, true); err != nil {
+			t.Fatalf("SetFavorite(%s) error = %v", zone, err)
+		}
+	}
+
+	if err := SetFavorite(db, 2, "c.example.", true); err != nil {
+		t.Fatalf("SetFavorite() error = %v", err)
+	}
+
+	if got, err := Favorites(db, 1); err != nil || !reflect.DeepEqual(got, []string{"a.example.", "b.example."}) {
+		t.Fatalf("Favorites() = %v, %v", got, err)
+	}
+
+	if err := SetFavorite(db, 1, "a.example.", false); err != nil {
+		t.Fatalf("SetFavorite(false) error = %v", err)
+	}
+
+	if got, _ := Favorites(db, 1); !reflect.DeepEqual(got, []string{"b.example."}) {
+		t.Errorf("Favorites() after removal = %v", got)
+	}
+}
+
+func TestRecordVisit(t *testing.T) {
+	db := newTestDB(t)
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	for i := range RecentLimit + 2 {
+		if err := RecordVisit(db, 1, fmt.Sprintf("z%02d.example.", i), start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("RecordVisit() error = %v", err)
+		}
+	}
+
+	// Opening an old zone again moves it to the top without a second row.
+	if err := RecordVisit(db, 1, "z05.example.", start.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordVisit() error = %v", err)
+	}
+
+	visits, err := Recent(db, 1)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+
+	if len(visits) != RecentLimit || visits[0].ZoneName != "z05.example." || visits[1].ZoneName != "z11.example." {
+		t.Fatalf("Recent() = %+v", visits)
+	}
+
+	var count int64
+	db.Model(&models.ZoneVisit{}).Where("user_id = ?", 1).Count(&count)
+
+	if count != RecentLimit {
+		t.Errorf("stored %d visits, want %d", count, RecentLimit)
+	}
+}
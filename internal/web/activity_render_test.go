@@ -0,0 +1,87 @@
+package web
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/avatar"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/activity"
+)
+
+func TestActivityDetail_LabelsChanged(t *testing.T) {
+	engine := newTemplateEngine(&config.Config{}, avatar.None{})
+	if err := engine.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	render := func(t *testing.T, diff *activitylog.LabelsDiff) string {
+		t.Helper()
+
+		entry := activity.EntryView{
+			ActivityLog: models.ActivityLog{
+				Action:       activitylog.ActionLabelsChanged,
+				ResourceType: activitylog.ResourceTypeZone,
+				ResourceName: "example.com.",
+			},
+			Labels: diff,
+		}
+
+		var buf bytes.Buffer
+		if err := engine.Render(&buf, activity.TemplateDetail, fiber.Map{
+			"Entry":         entry,
+			"hasPermission": func(string) bool { return false },
+		}); err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+
+		return buf.String()
+	}
+
+	t.Run("rrset labels", func(t *testing.T) {
+		out := render(t, &activitylog.LabelsDiff{
+			Name: "www.example.com.",
+			Type: "A",
+			Old:  map[string]string{"env": "staging", "owner": "web"},
+			New:  map[string]string{"env": "prod", "team": "ops"},
+		})
+
+		for _, want := range []string{
+			"labels changed",
+			"<code>www.example.com.</code>",
+			"<code>env</code>",
+			`text-decoration-line-through">staging<`,
+			`fw-semibold">prod<`,
+			"<code>owner</code>",
+			"<code>team</code>",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output does not contain %q", want)
+			}
+		}
+
+		if strings.Contains(out, "no labels changed") {
+			t.Error("output claims no labels changed")
+		}
+	})
+
+	t.Run("zone labels unchanged", func(t *testing.T) {
+		out := render(t, &activitylog.LabelsDiff{
+			Old: map[string]string{"env": "prod"},
+			New: map[string]string{"env": "prod"},
+		})
+
+		if !strings.Contains(out, "no labels changed") {
+			t.Error("output does not report that no labels changed")
+		}
+
+		if strings.Contains(out, "Open record in zone editor") {
+			t.Error("zone labels must not link to a record")
+		}
+	})
+}
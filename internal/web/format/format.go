@@ -0,0 +1,269 @@
+// Package format provides the locale-aware number, percentage and date helpers
+// exposed to templates. It deliberately supports a small, fixed set of locales
+// (see Locales) instead of pulling in full CLDR data: the UI only needs digit
+// grouping, the decimal mark and a short date layout.
+package format
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLocale is used when a user has no preference or an unknown one.
+	DefaultLocale = "en-US"
+
+	// nbsp keeps grouped digits and "12 %" together when the browser wraps text.
+	nbsp = "\u00a0"
+)
+
+// Locale describes how numbers and dates are written in one locale.
+type Locale struct {
+	// Tag is the BCP 47 language tag stored in the user's preference.
+	Tag string
+	// Name is the human-readable label shown in the locale picker.
+	Name string
+	// Group separates thousands, Decimal is the decimal mark.
+	Group, Decimal string
+	// PercentSpace is true when a no-break space precedes the % sign.
+	PercentSpace bool
+	// DateLayout and TimeLayout are Go reference layouts.
+	DateLayout, TimeLayout string
+}
+
+// Locales lists the supported locales in picker order.
+var Locales = []Locale{
+	{
+		Tag: "en-US", Name: "English (United States)",
+		Group: ",", Decimal: ".", PercentSpace: false,
+		DateLayout: "Jan 2, 2006", TimeLayout: "3:04 PM",
+	},
+	{
+		Tag: "en-GB", Name: "English (United Kingdom)",
+		Group: ",", Decimal: ".", PercentSpace: false,
+		DateLayout: "02/01/2006", TimeLayout: "15:04",
+	},
+	{
+		Tag: "de-DE", Name: "Deutsch",
+		Group: ".", Decimal: ",", PercentSpace: true,
+		DateLayout: "02.01.2006", TimeLayout: "15:04",
+	},
+	{
+		Tag: "fr-FR", Name: "Français",
+		Group: nbsp, Decimal: ",", PercentSpace: true,
+		DateLayout: "02/01/2006", TimeLayout: "15:04",
+	},
+	{
+		Tag: "es-ES", Name: "Español",
+		Group: ".", Decimal: ",", PercentSpace: true,
+		DateLayout: "02/01/2006", TimeLayout: "15:04",
+	},
+	{
+		Tag: "nl-NL", Name: "Nederlands",
+		Group: ".", Decimal: ",", PercentSpace: false,
+		DateLayout: "02-01-2006", TimeLayout: "15:04",
+	},
+	{
+		Tag: "sv-SE", Name: "Svenska",
+		Group: nbsp, Decimal: ",", PercentSpace: true,
+		DateLayout: "2006-01-02", TimeLayout: "15:04",
+	},
+	{
+		Tag: "ja-JP", Name: "日本語",
+		Group: ",", Decimal: ".", PercentSpace: false,
+		DateLayout: "2006/01/02", TimeLayout: "15:04",
+	},
+}
+
+// Lookup returns the locale for tag, matching case-insensitively and falling
+// back from a region-less tag ("de") to its first regional variant and from an
+// unknown tag to DefaultLocale.
+func Lookup(tag string) Locale {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+
+	for _, l := range Locales {
+		if strings.EqualFold(l.Tag, tag) {
+			return l
+		}
+	}
+
+	lang, _, _ := strings.Cut(tag, "-")
+	for _, l := range Locales {
+		if l2, _, _ := strings.Cut(l.Tag, "-"); lang != "" && strings.EqualFold(l2, lang) {
+			return l
+		}
+	}
+
+	return Locales[0]
+}
+
+// IsSupported reports whether tag exactly names one of Locales.
+func IsSupported(tag string) bool {
+	for _, l := range Locales {
+		if l.Tag == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Number formats an integer or float with the locale's digit grouping. Floats
+// are rounded to at most two decimals with trailing zeros removed. Unsupported
+// types render as an empty string.
+func Number(locale string, v any) string {
+	l := Lookup(locale)
+
+	switch n := v.(type) {
+	case int:
+		return groupInt(l, strconv.FormatInt(int64(n), 10))
+	case int32:
+		return groupInt(l, strconv.FormatInt(int64(n), 10))
+	case int64:
+		return groupInt(l, strconv.FormatInt(n, 10))
+	case uint:
+		return groupInt(l, strconv.FormatUint(uint64(n), 10))
+	case uint32:
+		return groupInt(l, strconv.FormatUint(uint64(n), 10))
+	case uint64:
+		return groupInt(l, strconv.FormatUint(n, 10))
+	case float32:
+		return decimal(l, float64(n), 2, true)
+	case float64:
+		return decimal(l, n, 2, true)
+	default:
+		return ""
+	}
+}
+
+// Percent formats ratio (0.125 = 12.5 %) with the given number of decimals.
+func Percent(locale string, ratio float64, decimals int) string {
+	l := Lookup(locale)
+
+	s := decimal(l, ratio*100, decimals, false) //nolint:mnd // ratio → percent
+	if l.PercentSpace {
+		return s + " %"
+	}
+
+	return s + "%"
+}
+
+// Date formats t as a short date in the locale's layout.
+func Date(locale string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(Lookup(locale).DateLayout)
+}
+
+// DateTime formats t as a short date followed by the time of day.
+func DateTime(locale string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	l := Lookup(locale)
+
+	return t.Format(l.DateLayout + " " + l.TimeLayout)
+}
+
+// RelativeTime describes t relative to now in compact units, e.g. "just now",
+// "5 min ago", "3 h ago", "2 d ago" or "in 3 h" for future times. Anything
+// older than a year is shown in years.
+func RelativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	d := now.Sub(t)
+
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	const (
+		day  = 24 * time.Hour
+		year = 365 * day
+	)
+
+	var s string
+
+	switch {
+	case d < 10*time.Second:
+		return "just now"
+	case d < time.Minute:
+		s = strconv.Itoa(int(d/time.Second)) + " s"
+	case d < time.Hour:
+		s = strconv.Itoa(int(d/time.Minute)) + " min"
+	case d < day:
+		s = strconv.Itoa(int(d/time.Hour)) + " h"
+	case d < year:
+		s = strconv.Itoa(int(d/day)) + " d"
+	default:
+		s = strconv.Itoa(int(d/year)) + " y"
+	}
+
+	if future {
+		return "in " + s
+	}
+
+	return s + " ago"
+}
+
+// decimal formats f with the given number of decimals, grouping the integer
+// part. When trim is set, trailing zeros (and a dangling decimal mark) are
+// dropped.
+func decimal(l Locale, f float64, decimals int, trim bool) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	s := strconv.FormatFloat(f, 'f', max(decimals, 0), 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+
+	if trim {
+		frac = strings.TrimRight(frac, "0")
+	}
+
+	out := groupInt(l, intPart)
+	if frac != "" {
+		out += l.Decimal + frac
+	}
+
+	return out
+}
+
+// groupInt inserts the locale's group separator every three digits of a
+// decimal integer string (with optional leading minus sign).
+func groupInt(l Locale, digits string) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	const groupSize = 3
+	if len(digits) <= groupSize {
+		return sign + digits
+	}
+
+	var b strings.Builder
+
+	head := len(digits) % groupSize
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+
+	for i := head; i < len(digits); i += groupSize {
+		if b.Len() > 0 {
+			b.WriteString(l.Group)
+		}
+
+		b.WriteString(digits[i : i+groupSize])
+	}
+
+	return sign + b.String()
+}
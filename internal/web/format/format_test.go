@@ -0,0 +1,133 @@
+package format
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		locale string
+		v      any
+		want   string
+	}{
+		{"en-US", 0, "0"},
+		{"en-US", 999, "999"},
+		{"en-US", 1000, "1,000"},
+		{"en-US", int64(1234567), "1,234,567"},
+		{"en-US", -1234567, "-1,234,567"},
+		{"en-US", uint64(12345), "12,345"},
+		{"de-DE", 1234567, "1.234.567"},
+		{"fr-FR", 1234567, "1\u00a0234\u00a0567"},
+		{"en-US", 1234.5, "1,234.5"},
+		{"de-DE", 1234.567, "1.234,57"},
+		{"en-US", 3.0, "3"},
+		{"", 1000, "1,000"},
+		{"xx-YY", 1000, "1,000"},
+		{"en-US", "not a number", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Number(tt.locale, tt.v); got != tt.want {
+			t.Errorf("Number(%q, %v) = %q, want %q", tt.locale, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		locale   string
+		ratio    float64
+		decimals int
+		want     string
+	}{
+		{"en-US", 0.125, 1, "12.5%"},
+		{"en-US", 1, 0, "100%"},
+		{"de-DE", 0.125, 1, "12,5\u00a0%"},
+		{"en-US", 12.5, 0, "1,250%"},
+		{"en-US", math.NaN(), 1, "NaN%"},
+	}
+
+	for _, tt := range tests {
+		if got := Percent(tt.locale, tt.ratio, tt.decimals); got != tt.want {
+			t.Errorf("Percent(%q, %v, %d) = %q, want %q", tt.locale, tt.ratio, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestDateAndDateTime(t *testing.T) {
+	ts := time.Date(2026, time.March, 7, 14, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale       string
+		wantDate     string
+		wantDateTime string
+	}{
+		{"en-US", "Mar 7, 2026", "Mar 7, 2026 2:05 PM"},
+		{"en-GB", "07/03/2026", "07/03/2026 14:05"},
+		{"de-DE", "07.03.2026", "07.03.2026 14:05"},
+		{"de", "07.03.2026", "07.03.2026 14:05"},
+		{"sv_SE", "2026-03-07", "2026-03-07 14:05"},
+	}
+
+	for _, tt := range tests {
+		if got := Date(tt.locale, ts); got != tt.wantDate {
+			t.Errorf("Date(%q) = %q, want %q", tt.locale, got, tt.wantDate)
+		}
+
+		if got := DateTime(tt.locale, ts); got != tt.wantDateTime {
+			t.Errorf("DateTime(%q) = %q, want %q", tt.locale, got, tt.wantDateTime)
+		}
+	}
+
+	if got := Date("en-US", time.Time{}); got != "" {
+		t.Errorf("Date(zero) = %q, want empty", got)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, time.March, 7, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{5 * time.Second, "just now"},
+		{42 * time.Second, "42 s ago"},
+		{5 * time.Minute, "5 min ago"},
+		{3*time.Hour + 59*time.Minute, "3 h ago"},
+		{49 * time.Hour, "2 d ago"},
+		{800 * 24 * time.Hour, "2 y ago"},
+		{-3 * time.Hour, "in 3 h"},
+	}
+
+	for _, tt := range tests {
+		if got := RelativeTime(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("RelativeTime(-%v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+
+	if got := RelativeTime(time.Time{}, now); got != "" {
+		t.Errorf("RelativeTime(zero) = %q, want empty", got)
+	}
+}
+
+func TestLookupAndIsSupported(t *testing.T) {
+	if got := Lookup("DE-de").Tag; got != "de-DE" {
+		t.Errorf("Lookup(DE-de) = %q, want de-DE", got)
+	}
+
+	if got := Lookup("en").Tag; got != "en-US" {
+		t.Errorf("Lookup(en) = %q, want en-US", got)
+	}
+
+	if got := Lookup("").Tag; got != DefaultLocale {
+		t.Errorf("Lookup(\"\") = %q, want %q", got, DefaultLocale)
+	}
+
+	if !IsSupported("ja-JP") || IsSupported("ja") {
+		t.Error("IsSupported should only accept exact tags")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/rs/zerolog/log"
@@ -14,7 +15,9 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
@@ -38,10 +41,22 @@ const (
 // can render structured data before/after without parsing JSON itself.
 type EntryView struct {
 	models.ActivityLog
+	// DisplayName is the acting user's current display name, falling back to
+	// the username stored with the entry (e.g. when the user has been deleted).
+	DisplayName string
+	// Email is the acting user's current email address, used for avatars.
+	Email string
 	// ZoneSettings is populated for zone_updated entries.
 	ZoneSettings *activitylog.ZoneSettingsDiff
-	// RecordsDiff is populated with record_changed entries.
+	// RecordsDiff is populated with record_changed and view_record_changed
+	// entries.
 	RecordsDiff *activitylog.RecordsDiff
+	// View is the view of view_record_changed entries.
+	View string
+	// Labels is populated for labels_changed entries.
+	Labels *activitylog.LabelsDiff
+	// Expiry is populated for expiry_set and expiry_cleared entries.
+	Expiry *activitylog.ExpiryDetails
 	// UndoDetails is populated for record_undone entries.
 	UndoDetails *activitylog.RecordUndoneDetails
 	// ZoneSnapshot is populated for zone_deleted entries.
@@ -103,6 +118,11 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 		auth.RequirePermission(authService, auth.PermAdminActivityLogUndo),
 		s.PostUndo,
 	)
+
+	app.Get(APIPath,
+		auth.RequirePermission(authService, auth.PermAdminActivityLog),
+		s.ListJSON,
+	)
 }
 
 // List renders the paginated activity log with optional filters.
@@ -138,7 +158,7 @@ func (s *Service) List(c fiber.Ctx) error {
 	}
 
 	filters := parseActivityFilters(c)
-	tx := buildActivityQuery(s.db, &filters)
+	tx := buildActivityQuery(s.scoped(c), &filters)
 
 	var totalCount int64
 	if err := tx.Count(&totalCount).Error; err != nil {
@@ -172,8 +192,10 @@ func (s *Service) List(c fiber.Ctx) error {
 	}
 
 	views := getActivityViews(entries)
-	actions := getDistinctActions(s.db)
-	zones := getDistinctZones(s.db)
+	attachUsers(s.db, views)
+
+	actions := getDistinctActions(s.scoped(c))
+	zones := getDistinctZones(s.scoped(c))
 
 	return c.Render(TemplateList, fiber.Map{
 		"Navigation":   nav,
@@ -211,11 +233,12 @@ func (s *Service) Get(c fiber.Ctx) error {
 	}
 
 	var entry models.ActivityLog
-	if err := s.db.First(&entry, uint64(id)).Error; err != nil {
+	if err := s.scoped(c).First(&entry, uint64(id)).Error; err != nil {
 		return c.Redirect().To(Path)
 	}
 
 	views := getActivityViews([]models.ActivityLog{entry})
+	attachUsers(s.db, views)
 
 	filters := parseActivityFilters(c)
 	page := fiber.Query[int](c, "page", 0)
@@ -240,6 +263,13 @@ func (s *Service) Get(c fiber.Ctx) error {
 	}, handler.BaseLayout)
 }
 
+// scoped returns the database limited to the activity log entries of the
+// current user's tenant.
+func (s *Service) scoped(c fiber.Ctx) *gorm.DB {
+	current, _ := c.Locals("CurrentUser").(models.User)
+	return s.db.Scopes(tenant.ActivityScope(current.TenantID))
+}
+
 // buildListQuery returns a URL-encoded query string capturing the current
 // filter + paging state, used to round-trip back to the list view from a detail page.
 func buildListQuery(filters *activityFilters, page, pageSize int) string {
@@ -290,7 +320,7 @@ func buildActivityQuery(db *gorm.DB, filters *activityFilters) *gorm.DB {
 	tx := db.Model(&models.ActivityLog{})
 
 	if filters.User != "" {
-		tx = tx.Where("username LIKE ?", "%"+filters.User+"%")
+		tx = tx.Scopes(like.Contains(filters.User, "username"))
 	}
 
 	if filters.Action != "" {
@@ -298,16 +328,16 @@ func buildActivityQuery(db *gorm.DB, filters *activityFilters) *gorm.DB {
 	}
 
 	if filters.Zone != "" {
-		tx = tx.Where("resource_type = ? AND resource_name LIKE ?", "zone", "%"+filters.Zone+"%")
+		tx = tx.Where("resource_type = ?", "zone").Scopes(like.Contains(filters.Zone, "resource_name"))
 	}
 
-	if filters.From != "" {
-		tx = tx.Where("created_at >= ?", filters.From)
+	if from, err := time.Parse(time.DateOnly, filters.From); err == nil {
+		tx = tx.Where("created_at >= ?", from)
 	}
 
-	if filters.To != "" {
+	if to, err := time.Parse(time.DateOnly, filters.To); err == nil {
 		// Include the full day by adding a day to the "to" date
-		tx = tx.Where("created_at < DATE_ADD(?, INTERVAL 1 DAY)", filters.To)
+		tx = tx.Where("created_at < ?", to.AddDate(0, 0, 1))
 	}
 
 	return tx
@@ -316,7 +346,7 @@ func buildActivityQuery(db *gorm.DB, filters *activityFilters) *gorm.DB {
 func getActivityViews(entries []models.ActivityLog) []EntryView {
 	views := make([]EntryView, len(entries))
 	for i := range entries {
-		views[i] = EntryView{ActivityLog: entries[i]}
+		views[i] = EntryView{ActivityLog: entries[i], DisplayName: entries[i].Username}
 		if entries[i].Details == "" {
 			continue
 		}
@@ -332,6 +362,22 @@ func getActivityViews(entries []models.ActivityLog) []EntryView {
 			if err := json.Unmarshal([]byte(entries[i].Details), &diff); err == nil {
 				views[i].RecordsDiff = &diff
 			}
+		case activitylog.ActionViewRecordChanged:
+			var diff activitylog.ViewRecordsDiff
+			if err := json.Unmarshal([]byte(entries[i].Details), &diff); err == nil {
+				views[i].RecordsDiff = &diff.RecordsDiff
+				views[i].View = diff.View
+			}
+		case activitylog.ActionLabelsChanged:
+			var diff activitylog.LabelsDiff
+			if err := json.Unmarshal([]byte(entries[i].Details), &diff); err == nil {
+				views[i].Labels = &diff
+			}
+		case activitylog.ActionExpirySet, activitylog.ActionExpiryCleared:
+			var details activitylog.ExpiryDetails
+			if err := json.Unmarshal([]byte(entries[i].Details), &details); err == nil {
+				views[i].Expiry = &details
+			}
 		case activitylog.ActionRecordUndone:
 			var ud activitylog.RecordUndoneDetails
 			if err := json.Unmarshal([]byte(entries[i].Details), &ud); err == nil {
@@ -353,6 +399,45 @@ func getActivityViews(entries []models.ActivityLog) []EntryView {
 	return views
 }
 
+// attachUsers resolves display names and emails of the acting users with a
+// single query. Entries whose user no longer exists keep the stored username.
+func attachUsers(db *gorm.DB, views []EntryView) {
+	ids := make([]uint64, 0, len(views))
+	for i := range views {
+		if views[i].UserID != nil {
+			ids = append(ids, *views[i].UserID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	var users []models.User
+	if err := db.Select("id", "username", "email", "display_name", "first_name", "last_name").
+		Where("id IN ?", ids).Find(&users).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load users for activity log entries")
+
+		return
+	}
+
+	byID := make(map[uint64]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+
+	for i := range views {
+		if views[i].UserID == nil {
+			continue
+		}
+
+		if u, ok := byID[*views[i].UserID]; ok {
+			views[i].DisplayName = u.FullName()
+			views[i].Email = u.Email
+		}
+	}
+}
+
 // buildPageLinks produces a windowed pager: first page, current ± window, last page,
 // with ellipsis placeholders in the gaps.
 func buildPageLinks(current, total int) []pageLink {
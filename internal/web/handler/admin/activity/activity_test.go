@@ -1,7 +1,11 @@
 package activity
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 )
 
 func TestBuildPageLinks(t *testing.T) {
@@ -197,3 +201,42 @@ func TestBuildPageLinks_NoAdjacentEllipses(t *testing.T) {
 		}
 	}
 }
+
+func TestGetActivityViews_LabelsChanged(t *testing.T) {
+	views := getActivityViews([]models.ActivityLog{{
+		Action:  activitylog.ActionLabelsChanged,
+		Details: `{"name":"www.example.com.","type":"A","old":{"env":"staging"},"new":{"env":"prod","team":"ops"}}`,
+	}})
+
+	labels := views[0].Labels
+	if labels == nil {
+		t.Fatal("Labels not decoded")
+	}
+
+	want := []activitylog.FieldDiff{
+		{Field: "env", Old: "staging", New: "prod"},
+		{Field: "team", New: "ops"},
+	}
+	if got := labels.Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetActivityViews_Expiry(t *testing.T) {
+	views := getActivityViews([]models.ActivityLog{
+		{
+			Action:  activitylog.ActionExpirySet,
+			Details: `{"name":"promo.example.com.","type":"CNAME","new":"2026-11-01","note":"campaign"}`,
+		},
+		{Action: activitylog.ActionExpiryCleared, Details: `{"old":"2026-11-01"}`},
+	})
+
+	want := &activitylog.ExpiryDetails{Name: "promo.example.com.", Type: "CNAME", New: "2026-11-01", Note: "campaign"}
+	if !reflect.DeepEqual(views[0].Expiry, want) {
+		t.Errorf("Expiry = %+v, want %+v", views[0].Expiry, want)
+	}
+
+	if views[1].Expiry == nil || views[1].Expiry.Old != "2026-11-01" || views[1].Expiry.Name != "" {
+		t.Errorf("Expiry = %+v, want the zone's expiry removed", views[1].Expiry)
+	}
+}
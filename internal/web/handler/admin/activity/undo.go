@@ -13,9 +13,10 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
-	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
 )
 
 const undoTimeout = 30 * time.Second
@@ -92,9 +93,11 @@ func (s *Service) undoRecordChanged(c fiber.Ctx, id int, entry *models.ActivityL
 		return c.Redirect().To(redirectBase + "&error=Failed+to+apply+undo+to+PowerDNS:+" + url.QueryEscape(err.Error()))
 	}
 
+	zoneindex.Default.RefreshZone(ctx, entry.ResourceName)
+
 	// Record a new activity log entry for the undo operation.
-	userID, username := currentUserFromSession(c)
-	activitylog.Record(&activitylog.Entry{
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
 		DB:           s.db,
 		UserID:       userID,
 		Username:     username,
@@ -106,7 +109,7 @@ func (s *Service) undoRecordChanged(c fiber.Ctx, id int, entry *models.ActivityL
 			OriginalUsername: entry.Username,
 		},
 		IPAddress: c.IP(),
-	})
+	}))
 
 	log.Info().Int("original_id", id).Str("zone", entry.ResourceName).Str("user", username).
 		Msg("record changes undone successfully")
@@ -194,9 +197,11 @@ func (s *Service) undoZoneDeleted(c fiber.Ctx, id int, entry *models.ActivityLog
 		return c.Redirect().To(redirectBase + "&error=Failed+to+recreate+zone:+" + url.QueryEscape(err.Error()))
 	}
 
+	zoneindex.Default.RefreshZone(ctx, zoneName)
+
 	// Record the undo action.
-	userID, username := currentUserFromSession(c)
-	activitylog.Record(&activitylog.Entry{
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
 		DB:           s.db,
 		UserID:       userID,
 		Username:     username,
@@ -208,7 +213,7 @@ func (s *Service) undoZoneDeleted(c fiber.Ctx, id int, entry *models.ActivityLog
 			OriginalUsername: entry.Username,
 		},
 		IPAddress: c.IP(),
-	})
+	}))
 
 	log.Info().Int("original_id", id).Str("zone", zoneName).Str("user", username).
 		Msg("zone deletion undone successfully")
@@ -279,24 +284,6 @@ func buildReverseRRSet(rec *activitylog.RecordEntryDiff) *pdnsapi.RRset {
 	return nil
 }
 
-// currentUserFromSession extracts the current user's ID and username from the
-// session cookie. Returns nil userID and empty username when no valid session exists.
-func currentUserFromSession(c fiber.Ctx) (*uint64, string) {
-	sid := c.Cookies("session")
-	if sid == "" {
-		return nil, ""
-	}
-
-	sd := new(session.Data)
-	if err := sd.Read(sid); err != nil || sd.User.ID == 0 {
-		return nil, ""
-	}
-
-	id := sd.User.ID
-
-	return &id, sd.User.Username
-}
-
 // buildQueryString preserves the existing filter/page query params so the
 // redirect lands back on the same filtered view.
 func buildQueryString(c fiber.Ctx) string {
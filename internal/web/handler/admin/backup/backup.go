@@ -0,0 +1,161 @@
+// Package backup implements the admin page that downloads a backup of the
+// application database and restores an uploaded one (see internal/backup).
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/backup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path to the backup page.
+	Path = handler.RootPath + "admin/backup"
+	// DownloadPath downloads a backup archive.
+	DownloadPath = Path + "/download"
+	// RestorePath restores an uploaded backup archive.
+	RestorePath = Path + "/restore"
+
+	// TemplateName is the name of the backup template.
+	TemplateName = "admin/backup/backup"
+
+	// archiveField is the form field of the uploaded archive.
+	archiveField = "archive"
+)
+
+// Service is the backup handler service.
+type Service struct {
+	handler.Service
+	db *gorm.DB
+}
+
+// Handler is the backup handler.
+var Handler = Service{}
+
+// Init initializes the backup handler.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermAdminBackup), s.Get)
+	app.Get(DownloadPath, auth.RequirePermission(authService, auth.PermAdminBackup), s.Download)
+	app.Post(RestorePath, auth.RequirePermission(authService, auth.PermAdminBackup), s.Restore)
+}
+
+// Get renders the backup page.
+func (s *Service) Get(c fiber.Ctx) error {
+	return s.render(c, fiber.StatusOK, fiber.Map{
+		"Success": c.Query("success"),
+		"Error":   c.Query("error"),
+	})
+}
+
+// Download sends a backup archive of the application database.
+func (s *Service) Download(c fiber.Ctx) error {
+	var b bytes.Buffer
+
+	counts, err := backup.Export(s.db, &b)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to export backup")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Backup failed", "Failed to export the database.", nil)
+	}
+
+	s.record(c, activitylog.ActionBackupExported, counts)
+
+	c.Attachment("gopowerdns-admin-backup-" + time.Now().UTC().Format("20060102-150405") + ".json")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+
+	return c.Send(b.Bytes())
+}
+
+// Restore replaces the application database with the uploaded archive.
+func (s *Service) Restore(c fiber.Ctx) error {
+	header, err := c.FormFile(archiveField)
+	if err != nil {
+		return s.render(c, fiber.StatusBadRequest, fiber.Map{"Error": "Choose a backup archive to restore."})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return s.render(c, fiber.StatusBadRequest, fiber.Map{"Error": "Failed to read the uploaded file."})
+	}
+	defer file.Close()
+
+	counts, err := backup.Restore(s.db, file)
+
+	switch {
+	case errors.Is(err, backup.ErrInvalidArchive), errors.Is(err, backup.ErrUnsupportedVersion):
+		return s.render(c, fiber.StatusBadRequest, fiber.Map{"Error": err.Error()})
+	case err != nil:
+		requestid.Logger(c.Context()).Error().Err(err).Str("file", header.Filename).Msg("failed to restore backup")
+
+		return s.render(c, fiber.StatusInternalServerError, fiber.Map{
+			"Error": "Restore failed; the database was left unchanged: " + err.Error(),
+		})
+	}
+
+	requestid.Logger(c.Context()).Info().Str("file", header.Filename).Msg("backup restored")
+
+	// Recorded after the restore, so that the entry is kept.
+	s.record(c, activitylog.ActionBackupRestored, counts)
+
+	rows := 0
+	for _, tc := range counts {
+		rows += tc.Rows
+	}
+
+	success := fmt.Sprintf("Restored %d rows in %d tables from %s.", rows, len(counts), header.Filename)
+
+	return c.Redirect().To(Path + "?success=" + url.QueryEscape(success))
+}
+
+// record writes an export or restore to the activity log.
+func (s *Service) record(c fiber.Ctx, action string, counts []backup.TableCount) {
+	rows := make(map[string]int, len(counts))
+	for _, tc := range counts {
+		rows[tc.Table] = tc.Rows
+	}
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeSystem,
+		ResourceName: "database",
+		Details:      map[string]any{"rows": rows},
+	}))
+}
+
+// render renders the backup page, merging any extra keys (e.g. Success/Error).
+func (s *Service) render(c fiber.Ctx, status int, extra fiber.Map) error {
+	nav := navigation.NewContext("Backup & Restore", "admin", "backup").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb("Backup & Restore", Path, true)
+
+	data := fiber.Map{"Navigation": nav}
+
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	return c.Status(status).Render(TemplateName, data, handler.BaseLayout)
+}
@@ -0,0 +1,386 @@
+// Package dhcpimport provides the admin pages that manage the DHCP lease
+// imports keeping A, AAAA and PTR records of leased addresses (see
+// internal/integrations/dhcplease).
+package dhcpimport
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/integrations/dhcplease"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathList is the path for the DHCP import list.
+	PathList = handler.RootPath + "admin/dhcp-imports"
+	// PathNew is the path for creating a DHCP import.
+	PathNew = PathList + "/new"
+	// PathEdit is the path for editing a DHCP import.
+	PathEdit = PathList + "/:id/edit"
+	// PathDelete is the path for deleting a DHCP import.
+	PathDelete = PathList + "/:id/delete"
+	// PathRun runs a DHCP import right away.
+	PathRun = PathList + "/:id/run"
+	// PathReport shows the last reconciliation report of a DHCP import.
+	PathReport = PathList + "/:id/report"
+
+	templateList   = "admin/dhcpimport/list"
+	templateForm   = "admin/dhcpimport/form"
+	templateReport = "admin/dhcpimport/report"
+
+	navSection    = "admin"
+	navSubsection = "dhcp-imports"
+
+	labelImports   = "DHCP Imports"
+	labelNewImport = "New DHCP Import"
+	labelEdit      = "Edit DHCP Import"
+	labelReport    = "Import Report"
+
+	errImportNotFound  = "DHCP import not found"
+	errInvalidFormData = "Invalid form data"
+
+	// maxTTL is the largest TTL accepted for imported records, one week.
+	maxTTL = 604800
+	// maxIntervalMinutes is the longest interval between imports, one day.
+	maxIntervalMinutes = 1440
+)
+
+// Service is the DHCP import handler service.
+type Service struct {
+	handler.Service
+	db *gorm.DB
+}
+
+// Handler is the DHCP import handler.
+var Handler = Service{}
+
+// form is the submitted DHCP import form.
+type form struct {
+	Name            string `form:"name"`
+	Kind            string `form:"kind"`
+	URL             string `form:"url"`
+	Username        string `form:"username"`
+	Password        string `form:"password"`
+	IPv6            bool   `form:"ipv6"`
+	LeaseFile       string `form:"lease_file"`
+	ForwardZone     string `form:"forward_zone"`
+	ReverseZones    string `form:"reverse_zones"`
+	TTL             uint32 `form:"ttl"`
+	IntervalMinutes uint   `form:"interval_minutes"`
+	ConflictPolicy  string `form:"conflict_policy"`
+	Enabled         bool   `form:"enabled"`
+}
+
+// Init initializes the DHCP import handler.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+
+	perm := auth.RequirePermission(authService, auth.PermAdminDHCPImports)
+
+	app.Get(PathList, perm, s.List)
+	app.Get(PathNew, perm, s.New)
+	app.Post(PathNew, perm, s.Create)
+	app.Get(PathEdit, perm, s.Edit)
+	app.Post(PathEdit, perm, s.Update)
+	app.Post(PathDelete, perm, s.Delete)
+	app.Post(PathRun, perm, s.Run)
+	app.Get(PathReport, perm, s.Report)
+}
+
+// List renders the DHCP import list.
+func (s *Service) List(c fiber.Ctx) error {
+	nav := navigation.NewContext(labelImports, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelImports, PathList, true)
+
+	var imports []models.DHCPImport
+	if err := s.db.Order(handler.OrderNameASC).Find(&imports).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list DHCP imports")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load DHCP imports", nil)
+	}
+
+	reports := make(map[uint]*dhcplease.Report, len(imports))
+	for i := range imports {
+		reports[imports[i].ID] = dhcplease.LastReport(&imports[i])
+	}
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Imports":    imports,
+		"Reports":    reports,
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// New renders the create DHCP import form.
+func (s *Service) New(c fiber.Ctx) error {
+	return s.renderForm(c, fiber.StatusOK, &models.DHCPImport{
+		Kind:            models.DHCPImportKea,
+		TTL:             300,
+		IntervalMinutes: 5,
+		ConflictPolicy:  models.DHCPConflictSkip,
+		Enabled:         true,
+	}, "")
+}
+
+// Create handles the create DHCP import form submission.
+func (s *Service) Create(c fiber.Ctx) error {
+	var in form
+	if err := c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	imp := &models.DHCPImport{}
+	if msg := apply(imp, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, imp, msg)
+	}
+
+	if err := s.db.Create(imp).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to create DHCP import")
+		return s.renderForm(c, fiber.StatusInternalServerError, imp, "Failed to create DHCP import: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("DHCP import "+imp.Name+" created."))
+}
+
+// Edit renders the edit DHCP import form.
+func (s *Service) Edit(c fiber.Ctx) error {
+	imp, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	return s.renderForm(c, fiber.StatusOK, imp, "")
+}
+
+// Update handles the edit DHCP import form submission. An empty password
+// keeps the stored one.
+func (s *Service) Update(c fiber.Ctx) error {
+	imp, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	var in form
+	if err = c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	if msg := apply(imp, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, imp, msg)
+	}
+
+	if err = s.db.Save(imp).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to update DHCP import")
+		return s.renderForm(c, fiber.StatusInternalServerError, imp, "Failed to update DHCP import: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("DHCP import "+imp.Name+" updated."))
+}
+
+// Delete handles DHCP import deletion. The records it created stay in the
+// zones.
+func (s *Service) Delete(c fiber.Ctx) error {
+	imp, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("import_id = ?", imp.ID).Delete(&models.DHCPImportRecord{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(imp).Error
+	})
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to delete DHCP import")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Delete Failed",
+			"Failed to delete DHCP import", nil)
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("DHCP import "+imp.Name+" deleted."))
+}
+
+// Run runs a DHCP import right away, whether enabled or not.
+func (s *Service) Run(c fiber.Ctx) error {
+	imp, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := dhcplease.Import(c.Context(), s.db, imp, time.Now())
+	if err != nil {
+		msg := "DHCP import " + imp.Name + " failed: " + err.Error()
+		return c.Redirect().To(PathList + "?error=" + url.QueryEscape(msg))
+	}
+
+	msg := fmt.Sprintf("DHCP import %s done: %d leases, %d records added, %d removed, %d conflicts.",
+		imp.Name, report.Leases, report.Added, report.Removed, len(report.Conflicts))
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape(msg))
+}
+
+// Report renders the last reconciliation report of a DHCP import and the
+// records it manages.
+func (s *Service) Report(c fiber.Ctx) error {
+	imp, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	var records []models.DHCPImportRecord
+	if err = s.db.Where("import_id = ?", imp.ID).Order("zone_name, name, type").Find(&records).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list DHCP import records")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load the records of the DHCP import", nil)
+	}
+
+	nav := navigation.NewContext(labelReport, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelImports, PathList, false).
+		AddBreadcrumb(imp.Name, "", true)
+
+	return c.Render(templateReport, fiber.Map{
+		"Navigation": nav,
+		"Import":     imp,
+		"Report":     dhcplease.LastReport(imp),
+		"Records":    records,
+	}, handler.BaseLayout)
+}
+
+// load returns the DHCP import of the :id parameter, or renders the error
+// page and returns its result as the error.
+func (s *Service) load(c fiber.Ctx) (*models.DHCPImport, error) {
+	var imp models.DHCPImport
+
+	err := s.db.First(&imp, fiber.Params[uint](c, "id")).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, errImportNotFound)
+	}
+
+	if err != nil {
+		return nil, handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load DHCP import", nil)
+	}
+
+	return &imp, nil
+}
+
+// apply validates the submitted form and copies it to imp. It returns the
+// message of the first invalid field, or "".
+func apply(imp *models.DHCPImport, in *form) string {
+	imp.Name = strings.TrimSpace(in.Name)
+	imp.Kind = models.DHCPImportKind(in.Kind)
+	imp.URL = strings.TrimSpace(in.URL)
+	imp.Username = strings.TrimSpace(in.Username)
+	imp.IPv6 = in.IPv6
+	imp.LeaseFile = strings.TrimSpace(in.LeaseFile)
+	imp.ForwardZone = zoneName(in.ForwardZone)
+	imp.TTL = in.TTL
+	imp.IntervalMinutes = in.IntervalMinutes
+	imp.ConflictPolicy = models.DHCPConflictPolicy(in.ConflictPolicy)
+	imp.Enabled = in.Enabled
+
+	if in.Password != "" {
+		imp.Password = in.Password
+	}
+
+	reverse := strings.Fields(in.ReverseZones)
+	for i, zone := range reverse {
+		reverse[i] = zoneName(zone)
+	}
+
+	imp.ReverseZones = strings.Join(reverse, "\n")
+
+	switch {
+	case imp.Name == "":
+		return "Name is required"
+	case imp.ForwardZone == ".":
+		return "Forward zone is required"
+	case imp.TTL == 0 || imp.TTL > maxTTL:
+		return "TTL must be between 1 and 604800 seconds"
+	case imp.IntervalMinutes == 0 || imp.IntervalMinutes > maxIntervalMinutes:
+		return "Interval must be between 1 and 1440 minutes"
+	case imp.ConflictPolicy != models.DHCPConflictSkip && imp.ConflictPolicy != models.DHCPConflictOverwrite:
+		return "Choose to skip or overwrite conflicting records"
+	}
+
+	for _, zone := range reverse {
+		if !strings.HasSuffix(zone, ".in-addr.arpa.") && !strings.HasSuffix(zone, ".ip6.arpa.") {
+			return "Reverse zone " + zone + " is not below in-addr.arpa or ip6.arpa"
+		}
+	}
+
+	switch imp.Kind {
+	case models.DHCPImportKea:
+		u, err := url.Parse(imp.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return "Control Agent URL must be an http or https URL"
+		}
+	case models.DHCPImportISC:
+		if !filepath.IsAbs(imp.LeaseFile) {
+			return "Lease file must be an absolute path"
+		}
+	default:
+		return "Choose Kea or ISC dhcpd"
+	}
+
+	return ""
+}
+
+// zoneName returns the zone name lower-case and fully qualified.
+func zoneName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name
+}
+
+// renderForm renders the DHCP import form; imp.ID is 0 for a new one.
+func (s *Service) renderForm(c fiber.Ctx, status int, imp *models.DHCPImport, errMsg string) error {
+	title := labelEdit
+	if imp.ID == 0 {
+		title = labelNewImport
+	}
+
+	nav := navigation.NewContext(title, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelImports, PathList, false).
+		AddBreadcrumb(title, "", true)
+
+	return c.Status(status).Render(templateForm, fiber.Map{
+		"Navigation":  nav,
+		"Import":      imp,
+		"IsCreate":    imp.ID == 0,
+		"HasPassword": imp.Password != "",
+		"Error":       errMsg,
+	}, handler.BaseLayout)
+}
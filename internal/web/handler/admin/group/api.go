@@ -0,0 +1,91 @@
+package group
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// APIPath is the JSON group list.
+const APIPath = handler.APIPathPrefix + "admin/groups"
+
+// apiColumns are the sort keys of the JSON group list.
+var apiColumns = handler.ListColumns{
+	"id":         "id",
+	"name":       "name",
+	"source":     "source",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// APIGroup is a row of the JSON group list.
+type APIGroup struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	ExternalID  string             `json:"external_id"`
+	Source      models.GroupSource `json:"source"`
+	Description string             `json:"description"`
+	Members     int64              `json:"members"`
+	// Role is the name of the mapped role, empty when the group is unmapped.
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListJSON returns a page of groups, searched like the group list and
+// optionally filtered by ?source=, a name ?prefix= and ?modified_since=.
+func (s *Service) ListJSON(c fiber.Ctx) error {
+	req, err := handler.ParseListRequest(c, apiColumns, "id", true)
+	if err != nil {
+		return handler.ListError(c, err)
+	}
+
+	source := c.Query("source")
+
+	var groups []models.Group
+
+	resp, err := req.Find(s.scoped(c), &models.Group{}, func(tx *gorm.DB) *gorm.DB {
+		if req.Search != "" {
+			tx = tx.Scopes(like.Contains(req.Search, "name", "external_id", "description"))
+		}
+
+		if source != "" {
+			tx = tx.Where("source = ?", source)
+		}
+
+		return tx.Scopes(req.Scope("name", "updated_at"))
+	}, &groups)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("query groups failed")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, ErrFailedLoadGroups, nil)
+	}
+
+	memberCounts, roleMappings := s.listDetails(groups)
+
+	rows := make([]APIGroup, len(groups))
+	for i, g := range groups {
+		rows[i] = APIGroup{
+			ID:          g.ID,
+			Name:        g.Name,
+			ExternalID:  g.ExternalID,
+			Source:      g.Source,
+			Description: g.Description,
+			Members:     memberCounts[g.ID],
+			Role:        roleMappings[g.ID],
+			CreatedAt:   g.CreatedAt,
+			UpdatedAt:   g.UpdatedAt,
+		}
+	}
+
+	if resp.Data, err = handler.SelectFields(req.Fields, rows); err != nil {
+		return handler.ListError(c, err)
+	}
+
+	return c.JSON(resp)
+}
@@ -12,7 +12,9 @@ import (
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
@@ -139,6 +141,10 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 		auth.RequirePermission(authService, auth.PermAdminGroups),
 		s.Delete,
 	)
+	app.Get(APIPath,
+		auth.RequirePermission(authService, auth.PermAdminGroups),
+		s.ListJSON,
+	)
 }
 
 // List shows groups with simple pagination and search.
@@ -163,12 +169,11 @@ func (s *Service) List(c fiber.Ctx) error {
 	var (
 		groups     []models.Group
 		totalCount int64
-		tx         = s.db.Model(&models.Group{})
+		tx         = s.scoped(c).Model(&models.Group{})
 	)
 
 	if search != "" {
-		like := "%" + search + "%"
-		tx = tx.Where("name ILIKE ? OR external_id ILIKE ? OR description ILIKE ?", like, like, like)
+		tx = tx.Scopes(like.Contains(search, "name", "external_id", "description"))
 	}
 
 	if err := tx.Count(&totalCount).Error; err != nil {
@@ -190,7 +195,7 @@ func (s *Service) List(c fiber.Ctx) error {
 	}
 
 	offset := (page - 1) * pageSize
-	if err := tx.Order("id DESC").Limit(pageSize).Offset(offset).Find(&groups).Error; err != nil {
+	if err := tx.Preload("Tenant").Order("id DESC").Limit(pageSize).Offset(offset).Find(&groups).Error; err != nil {
 		log.Error().Err(err).Msg("query groups failed")
 
 		return c.Status(fiber.StatusInternalServerError).Render(TemplateList, fiber.Map{
@@ -199,26 +204,13 @@ func (s *Service) List(c fiber.Ctx) error {
 		}, handler.BaseLayout)
 	}
 
-	// Load member counts and role mappings for each group
-	memberCounts := make(map[uint]int64)
-	roleMappings := make(map[uint]string) // group_id -> role_name
-
-	for _, g := range groups {
-		var count int64
-		if err := s.db.Model(&models.UserGroup{}).Where("group_id = ?", g.ID).Count(&count).Error; err == nil {
-			memberCounts[g.ID] = count
-		}
-
-		// Load role mapping
-		var mapping models.GroupMapping
-		if err := s.db.Preload("Role").Where("group_id = ?", g.ID).First(&mapping).Error; err == nil {
-			roleMappings[g.ID] = mapping.Role.Name
-		}
-	}
+	memberCounts, roleMappings := s.listDetails(groups)
+	tenants := s.tenants(c)
 
 	return c.Render(TemplateList, fiber.Map{
 		"Navigation":   nav,
 		"Groups":       groups,
+		"ShowTenants":  len(tenants) > 0,
 		"MemberCounts": memberCounts,
 		"RoleMappings": roleMappings,
 		"Search":       search,
@@ -233,6 +225,51 @@ func (s *Service) List(c fiber.Ctx) error {
 	}, handler.BaseLayout)
 }
 
+// listDetails returns the member count and mapped role name of groups, keyed
+// by group ID, with one query each instead of one per group. Failures are
+// logged and leave the maps empty.
+func (s *Service) listDetails(groups []models.Group) (map[uint]int64, map[uint]string) {
+	memberCounts := make(map[uint]int64, len(groups))
+	roleMappings := make(map[uint]string, len(groups)) // group_id -> role_name
+
+	if len(groups) == 0 {
+		return memberCounts, roleMappings
+	}
+
+	ids := make([]uint, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+	}
+
+	var counts []struct {
+		GroupID uint
+		Count   int64
+	}
+
+	if err := s.db.Model(&models.UserGroup{}).
+		Select("group_id, COUNT(*) AS count").
+		Where("group_id IN ?", ids).
+		Group("group_id").
+		Scan(&counts).Error; err != nil {
+		log.Error().Err(err).Msg("count group members failed")
+	}
+
+	for _, cnt := range counts {
+		memberCounts[cnt.GroupID] = cnt.Count
+	}
+
+	var mappings []models.GroupMapping
+	if err := s.db.Preload("Role").Where("group_id IN ?", ids).Find(&mappings).Error; err != nil {
+		log.Error().Err(err).Msg("load group mappings failed")
+	}
+
+	for _, m := range mappings {
+		roleMappings[m.GroupID] = m.Role.Name
+	}
+
+	return memberCounts, roleMappings
+}
+
 // New renders empty form.
 func (s *Service) New(c fiber.Ctx) error {
 	nav := navigation.NewContext(TitleNewGroup, NavSectionAdmin, NavEntityGroup).
@@ -242,7 +279,7 @@ func (s *Service) New(c fiber.Ctx) error {
 		AddBreadcrumb(BreadcrumbNewLbl, RouteNew, true)
 
 	var users []models.User
-	if err := s.db.Order(handler.OrderUsernameASC).Find(&users).Error; err != nil {
+	if err := s.scoped(c).Order(handler.OrderUsernameASC).Find(&users).Error; err != nil {
 		log.Error().Err(err).Msg("failed to load users")
 
 		return c.Status(fiber.StatusInternalServerError).Render(TemplateForm, fiber.Map{
@@ -270,9 +307,12 @@ func (s *Service) New(c fiber.Ctx) error {
 		"IsCreate":    true,
 		"Users":       users,
 		"Roles":       roles,
+		"Tenants":     s.tenants(c),
+		"TenantID":    uint(0),
 		"SelectedIDs": []uint64{},
 		"AllTags":     allTags,
 		"AssignedSet": map[uint]bool{},
+		"Zones":       "",
 	}, handler.BaseLayout)
 }
 
@@ -303,7 +343,12 @@ func (s *Service) Create(c fiber.Ctx) error {
 		}
 	}
 
-	if err := s.validator.Struct(input); err != nil {
+	zones, err := parseGroupZones(c)
+	if err == nil {
+		err = s.validator.Struct(input)
+	}
+
+	if err != nil {
 		log.Warn().Err(err).Msg("validation failed for create group")
 
 		nav := navigation.NewContext(TitleNewGroup, NavSectionAdmin, NavEntityGroup).
@@ -322,10 +367,19 @@ func (s *Service) Create(c fiber.Ctx) error {
 				Description: input.Description,
 			},
 			"IsCreate": true,
+			"Zones":    c.FormValue("zones"),
 		}, handler.BaseLayout)
 	}
 
+	tenantID, err := s.formTenant(c, nil)
+	if err != nil {
+		return handler.RenderError(c, fiber.StatusBadRequest, "Invalid Tenant", "Unknown tenant selected", nil)
+	}
+
+	input.UserIDs = s.members(tenantID, input.UserIDs)
+
 	g := &models.Group{
+		TenantID:    tenantID,
 		Name:        input.Name,
 		ExternalID:  input.ExternalID,
 		Source:      models.GroupSource(input.Source),
@@ -376,6 +430,7 @@ func (s *Service) Create(c fiber.Ctx) error {
 		userGroup := models.UserGroup{
 			UserID:  userID,
 			GroupID: g.ID,
+			Manual:  true,
 		}
 		if err := tx.Create(&userGroup).Error; err != nil {
 			tx.Rollback()
@@ -391,6 +446,7 @@ func (s *Service) Create(c fiber.Ctx) error {
 	}
 
 	syncGroupTags(s.db, g.ID, parseGroupTagIDs(c))
+	syncGroupZones(s.db, g.ID, zones)
 
 	return c.Redirect().To(Path)
 }
@@ -401,13 +457,13 @@ func (s *Service) Edit(c fiber.Ctx) error {
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		return c.Status(fiber.StatusBadRequest).SendString(ErrInvalidID)
+		return fiber.NewError(fiber.StatusBadRequest, ErrInvalidID)
 	}
 
 	var g models.Group
-	if err := s.db.First(&g, id).Error; err != nil {
+	if err := s.scoped(c).First(&g, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).SendString(ErrGroupNotFound)
+			return fiber.NewError(fiber.StatusNotFound, ErrGroupNotFound)
 		}
 
 		log.Error().Err(err).Msg("load group failed")
@@ -415,9 +471,9 @@ func (s *Service) Edit(c fiber.Ctx) error {
 		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", ErrFailedLoadGroup, nil)
 	}
 
-	// Load all users
+	// Load the users that may join the group
 	var users []models.User
-	if err := s.db.Order(handler.OrderUsernameASC).Find(&users).Error; err != nil {
+	if err := s.db.Scopes(tenant.Scope(g.TenantID)).Order(handler.OrderUsernameASC).Find(&users).Error; err != nil {
 		log.Error().Err(err).Msg("failed to load users")
 		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load users", nil)
 	}
@@ -473,10 +529,13 @@ func (s *Service) Edit(c fiber.Ctx) error {
 		"IsCreate":     false,
 		"Users":        users,
 		"Roles":        roles,
+		"Tenants":      s.tenants(c),
+		"TenantID":     tenant.ID(g.TenantID),
 		"MappedRoleID": mappedRoleID,
 		"SelectedIDs":  selectedIDs,
 		"AllTags":      allTags,
 		"AssignedSet":  tagAssignedSet,
+		"Zones":        loadGroupZones(s.db, g.ID),
 	}, handler.BaseLayout)
 }
 
@@ -486,13 +545,13 @@ func (s *Service) Update(c fiber.Ctx) error {
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		return c.Status(fiber.StatusBadRequest).SendString(ErrInvalidID)
+		return fiber.NewError(fiber.StatusBadRequest, ErrInvalidID)
 	}
 
 	var g models.Group
-	if err = s.db.First(&g, id).Error; err != nil {
+	if err = s.scoped(c).First(&g, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).SendString(ErrGroupNotFound)
+			return fiber.NewError(fiber.StatusNotFound, ErrGroupNotFound)
 		}
 
 		log.Error().Err(err).Msg("load group failed")
@@ -525,7 +584,12 @@ func (s *Service) Update(c fiber.Ctx) error {
 		}
 	}
 
-	if errValidator := s.validator.Struct(input); errValidator != nil {
+	zones, errValidator := parseGroupZones(c)
+	if errValidator == nil {
+		errValidator = s.validator.Struct(input)
+	}
+
+	if errValidator != nil {
 		log.Warn().Err(errValidator).Msg("validation failed for update group")
 
 		nav := navigation.NewContext(TitleEditGroup, NavSectionAdmin, NavEntityGroup).
@@ -545,9 +609,18 @@ func (s *Service) Update(c fiber.Ctx) error {
 			"Error":      ErrValidationPrefix + errValidator.Error(),
 			"Group":      g,
 			"IsCreate":   false,
+			"Zones":      c.FormValue("zones"),
 		}, handler.BaseLayout)
 	}
 
+	tenantID, err := s.formTenant(c, g.TenantID)
+	if err != nil {
+		return handler.RenderError(c, fiber.StatusBadRequest, "Invalid Tenant", "Unknown tenant selected", nil)
+	}
+
+	input.UserIDs = s.members(tenantID, input.UserIDs)
+
+	g.TenantID = tenantID
 	g.Name = input.Name
 	g.ExternalID = input.ExternalID
 	g.Source = models.GroupSource(input.Source)
@@ -593,6 +666,7 @@ func (s *Service) Update(c fiber.Ctx) error {
 	}
 
 	syncGroupTags(s.db, g.ID, parseGroupTagIDs(c))
+	syncGroupZones(s.db, g.ID, zones)
 
 	return c.Redirect().To(Path)
 }
@@ -603,13 +677,61 @@ func (s *Service) Delete(c fiber.Ctx) error {
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		return c.Status(fiber.StatusBadRequest).SendString(ErrInvalidID)
+		return fiber.NewError(fiber.StatusBadRequest, ErrInvalidID)
 	}
 
-	if err := s.db.Delete(&models.Group{}, id).Error; err != nil {
+	if err := s.scoped(c).Delete(&models.Group{}, id).Error; err != nil {
 		log.Error().Err(err).Msg("failed to delete group")
 		return handler.RenderError(c, fiber.StatusInternalServerError, "Delete Failed", ErrFailedDeleteGroup, nil)
 	}
 
 	return c.Redirect().To(Path)
 }
+
+// scoped returns the database limited to the groups, or users, of the current
+// user's tenant; administrators outside any tenant see all of them.
+func (s *Service) scoped(c fiber.Ctx) *gorm.DB {
+	current, _ := c.Locals("CurrentUser").(models.User)
+	return s.db.Scopes(tenant.Scope(current.TenantID))
+}
+
+// tenants returns the tenants offered in the group form; see tenant.Choices.
+func (s *Service) tenants(c fiber.Ctx) []models.Tenant {
+	current, _ := c.Locals("CurrentUser").(models.User)
+
+	tenants, err := tenant.Choices(s.db, current.TenantID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load tenants")
+	}
+
+	return tenants
+}
+
+// formTenant returns the tenant of a submitted group whose tenant was
+// current; see tenant.Submitted.
+func (s *Service) formTenant(c fiber.Ctx, current *uint) (*uint, error) {
+	actor, _ := c.Locals("CurrentUser").(models.User)
+	return tenant.Submitted(s.db, actor.TenantID, c.FormValue("tenant_id"), current)
+}
+
+// members returns the user IDs of ids that may join a group of the tenant
+// tenantID: users of that tenant, or any user for a group of the provider.
+func (s *Service) members(tenantID *uint, ids []string) []string {
+	if tenantID == nil || len(ids) == 0 {
+		return ids
+	}
+
+	var allowed []uint64
+	if err := s.db.Model(&models.User{}).Scopes(tenant.Scope(tenantID)).
+		Where("id IN ?", ids).Pluck("id", &allowed).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load the users of the tenant")
+		return nil
+	}
+
+	members := make([]string, len(allowed))
+	for i, id := range allowed {
+		members[i] = strconv.FormatUint(id, 10)
+	}
+
+	return members
+}
@@ -12,7 +12,22 @@ import (
 )
 
 // updateOrCreateGroupMembership updates or creates group memberships in the database.
+// Members an administrator adds are marked manual so group sync never removes
+// them; members that stay keep their existing flag.
 func (s *Service) updateOrCreateGroupMembership(c fiber.Ctx, tx *gorm.DB, groupID uint, input *formInput) error {
+	var existing []models.UserGroup
+	if err := tx.Where("group_id = ?", groupID).Find(&existing).Error; err != nil {
+		tx.Rollback()
+		log.Error().Err(err).Msg("failed to load existing group members")
+
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", "Failed to update group members", nil)
+	}
+
+	manual := make(map[uint64]bool, len(existing))
+	for _, ug := range existing {
+		manual[ug.UserID] = ug.Manual
+	}
+
 	// Delete existing group members
 	if err := tx.Where("group_id = ?", groupID).Delete(&models.UserGroup{}).Error; err != nil {
 		tx.Rollback()
@@ -21,6 +36,8 @@ func (s *Service) updateOrCreateGroupMembership(c fiber.Ctx, tx *gorm.DB, groupI
 		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", "Failed to update group members", nil)
 	}
 
+	var kept []uint64
+
 	// Create new user group memberships
 	for _, userIDStr := range input.UserIDs {
 		userID, err := strconv.ParseUint(userIDStr, 10, 64)
@@ -28,9 +45,12 @@ func (s *Service) updateOrCreateGroupMembership(c fiber.Ctx, tx *gorm.DB, groupI
 			continue // skip invalid IDs
 		}
 
+		wasManual, wasMember := manual[userID]
+
 		userGroup := models.UserGroup{
 			UserID:  userID,
 			GroupID: groupID,
+			Manual:  wasManual || !wasMember,
 		}
 		if err = tx.Create(&userGroup).Error; err != nil {
 			tx.Rollback()
@@ -38,6 +58,21 @@ func (s *Service) updateOrCreateGroupMembership(c fiber.Ctx, tx *gorm.DB, groupI
 
 			return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", "Failed to add users to group", nil)
 		}
+
+		kept = append(kept, userID)
+	}
+
+	// Removals queued by group sync are settled for members the edit removed.
+	pending := tx.Where("group_id = ?", groupID)
+	if len(kept) > 0 {
+		pending = pending.Where("user_id NOT IN ?", kept)
+	}
+
+	if err := pending.Delete(&models.PendingGroupRemoval{}).Error; err != nil {
+		tx.Rollback()
+		log.Error().Err(err).Msg("failed to delete pending group removals")
+
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", "Failed to update group members", nil)
 	}
 
 	if err := tx.Commit().Error; err != nil {
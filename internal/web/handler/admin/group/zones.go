@@ -0,0 +1,34 @@
+package group
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// parseGroupZones reads the zones textarea, one zone pattern per line, and
+// returns the canonical patterns; see auth.ZonePattern for the syntax.
+func parseGroupZones(c fiber.Ctx) ([]string, error) {
+	return auth.ParseZonePatterns(c.FormValue("zones"))
+}
+
+// loadGroupZones returns the zone patterns of the group, one per line, for the form.
+func loadGroupZones(db *gorm.DB, groupID uint) string {
+	var patterns []string
+	db.Model(&models.GroupZone{}).Where("group_id = ?", groupID).Order("pattern").Pluck("pattern", &patterns)
+
+	return strings.Join(patterns, "\n")
+}
+
+// syncGroupZones replaces the GroupZone entries for the given group with the provided patterns.
+func syncGroupZones(db *gorm.DB, groupID uint, patterns []string) {
+	db.Where("group_id = ?", groupID).Delete(&models.GroupZone{})
+
+	for _, pattern := range patterns {
+		db.Create(&models.GroupZone{GroupID: groupID, Pattern: pattern})
+	}
+}
@@ -0,0 +1,160 @@
+package groupmapping
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// Impact describes how one user's effective permissions would change.
+type Impact struct {
+	User    models.User
+	Added   []string
+	Removed []string
+}
+
+// computeImpact evaluates, without writing anything, how replacing the current
+// group → role mapping with proposed changes the effective permissions of the
+// members of every changed group. Effective permissions follow the same rule
+// as auth.Service.GetUserPermissions: the user's own role plus the roles mapped
+// to each of their groups.
+//
+// It returns the users whose permissions change, sorted by username, and the
+// number of affected group members whose permissions stay the same.
+func computeImpact(db *gorm.DB, current, proposed map[uint]uint) ([]Impact, int, error) {
+	var changed []uint
+
+	for groupID, roleID := range current {
+		if proposed[groupID] != roleID {
+			changed = append(changed, groupID)
+		}
+	}
+
+	for groupID, roleID := range proposed {
+		if _, ok := current[groupID]; !ok && roleID != 0 {
+			changed = append(changed, groupID)
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil, 0, nil
+	}
+
+	var userIDs []uint64
+	if err := db.Model(&models.UserGroup{}).
+		Where("group_id IN ?", changed).
+		Distinct("user_id").
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, 0, fmt.Errorf("load affected users: %w", err)
+	}
+
+	if len(userIDs) == 0 {
+		return nil, 0, nil
+	}
+
+	var users []models.User
+	if err := db.Where("id IN ?", userIDs).Order("username ASC").Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("load users: %w", err)
+	}
+
+	var memberships []models.UserGroup
+	if err := db.Where("user_id IN ?", userIDs).Find(&memberships).Error; err != nil {
+		return nil, 0, fmt.Errorf("load memberships: %w", err)
+	}
+
+	groupsOf := make(map[uint64][]uint, len(users))
+	for _, m := range memberships {
+		groupsOf[m.UserID] = append(groupsOf[m.UserID], m.GroupID)
+	}
+
+	rolePerms, err := loadRolePermissions(db)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		impacts   []Impact
+		unchanged int
+	)
+
+	for _, u := range users {
+		before := effectivePermissions(u.RoleID, groupsOf[u.ID], current, rolePerms)
+		after := effectivePermissions(u.RoleID, groupsOf[u.ID], proposed, rolePerms)
+
+		added, removed := diffPermissions(before, after)
+		if len(added) == 0 && len(removed) == 0 {
+			unchanged++
+			continue
+		}
+
+		impacts = append(impacts, Impact{User: u, Added: added, Removed: removed})
+	}
+
+	return impacts, unchanged, nil
+}
+
+// loadRolePermissions returns the permission names granted by each role.
+func loadRolePermissions(db *gorm.DB) (map[uint][]string, error) {
+	var rows []struct {
+		RoleID uint
+		Name   string
+	}
+
+	if err := db.Table("role_permissions").
+		Select("role_permissions.role_id, permissions.name").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("load role permissions: %w", err)
+	}
+
+	perms := make(map[uint][]string)
+	for _, r := range rows {
+		perms[r.RoleID] = append(perms[r.RoleID], r.Name)
+	}
+
+	return perms, nil
+}
+
+// effectivePermissions is the union of the permissions of roleID and of the
+// roles mapped to groupIDs.
+func effectivePermissions(
+	roleID uint, groupIDs []uint, mapping map[uint]uint, rolePerms map[uint][]string,
+) map[string]bool {
+	set := make(map[string]bool)
+
+	for _, p := range rolePerms[roleID] {
+		set[p] = true
+	}
+
+	for _, g := range groupIDs {
+		for _, p := range rolePerms[mapping[g]] {
+			set[p] = true
+		}
+	}
+
+	return set
+}
+
+// diffPermissions returns the sorted permissions only in after (added) and
+// only in before (removed).
+func diffPermissions(before, after map[string]bool) (added, removed []string) {
+	for p := range after {
+		if !before[p] {
+			added = append(added, p)
+		}
+	}
+
+	for p := range before {
+		if !after[p] {
+			removed = append(removed, p)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	return added, removed
+}
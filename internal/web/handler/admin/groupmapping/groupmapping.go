@@ -0,0 +1,407 @@
+// Package groupmapping provides the admin page for reviewing and bulk-editing
+// group → role mappings, with a dry-run that previews the permission impact.
+package groupmapping
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/authsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the base path for group mapping management.
+	Path = handler.RootPath + "admin/group-mappings"
+
+	// PathSyncPreview is the path of the group sync dry-run page.
+	PathSyncPreview = Path + "/sync-preview"
+
+	templateList = "admin/groupmapping/list"
+	templateSync = "admin/groupmapping/sync"
+
+	navSection = "admin"
+	navEntity  = "group-mappings"
+
+	labelGroupMappings = "Group Mappings"
+
+	// actionPreview renders the dry-run result instead of saving.
+	actionPreview = "preview"
+
+	errFailedLoadMappings = "Failed to load group mappings"
+	errFailedSaveMappings = "Failed to save group mappings"
+	errUnknownRole        = "Unknown role selected"
+)
+
+// errRoleNotFound is returned when a submitted role id does not exist.
+var errRoleNotFound = errors.New("role not found")
+
+// Service handles the group mapping admin page.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+	ldap        auth.ProviderCache[*auth.LDAPProvider]
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Row is one group on the mapping page with its current and selected role.
+type Row struct {
+	Group       models.Group
+	Members     int64
+	RoleID      uint
+	SelectedID  uint
+	HasMapping  bool
+	WillChange  bool
+	CurrentRole string
+}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermAdminGroupMappings), s.List)
+	app.Post(Path, auth.RequirePermission(authService, auth.PermAdminGroupMappings), s.Save)
+	app.Get(PathSyncPreview, auth.RequirePermission(authService, auth.PermAdminGroupMappings), s.SyncPreview)
+	app.Post(PathSyncPreview, auth.RequirePermission(authService, auth.PermAdminGroupMappings), s.RunSyncPreview)
+	app.Get(PathPending, auth.RequirePermission(authService, auth.PermAdminGroupMappings), s.Pending)
+	app.Post(PathPending+"/:id/approve", auth.RequirePermission(authService, auth.PermAdminGroupMappings), s.ApprovePending)
+	app.Post(PathPending+"/:id/reject", auth.RequirePermission(authService, auth.PermAdminGroupMappings), s.RejectPending)
+}
+
+// ldapProvider returns the LDAP provider of the current settings used to look
+// up directory groups, or nil when LDAP is disabled.
+func (s *Service) ldapProvider() *auth.LDAPProvider {
+	settings := authsettings.Current(s.cfg)
+
+	provider, err := s.ldap.Get(settings.Version, func() (*auth.LDAPProvider, error) {
+		provider, err := auth.NewLDAPProvider(auth.NewLDAPConfig(&settings.LDAP), s.db)
+		if err != nil && !errors.Is(err, auth.ErrLDAPDisabled) {
+			log.Warn().Err(err).Msg("LDAP group lookup unavailable for sync preview")
+		}
+
+		return provider, err
+	})
+	if err != nil {
+		return nil
+	}
+
+	return provider
+}
+
+// List shows every group with its mapped role.
+func (s *Service) List(c fiber.Ctx) error {
+	st, err := s.loadState()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load group mappings")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadMappings, nil)
+	}
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": s.nav(),
+		"Rows":       st.rows(st.current),
+		"Roles":      st.roles,
+		"Success":    c.Query("success"),
+	}, handler.BaseLayout)
+}
+
+// Save applies the submitted mappings, or renders a dry-run of their effect on
+// users' effective permissions when the preview action was requested.
+//
+// Every group row posts a role_<groupID> select (0 = unmapped). The bulk
+// toolbar additionally posts group_ids and bulk_role, which override the
+// per-row selection of the checked groups.
+func (s *Service) Save(c fiber.Ctx) error {
+	st, err := s.loadState()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load group mappings")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadMappings, nil)
+	}
+
+	proposed, err := st.parseProposed(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).Render(templateList, fiber.Map{
+			"Navigation": s.nav(),
+			"Rows":       st.rows(st.current),
+			"Roles":      st.roles,
+			"Error":      errUnknownRole,
+		}, handler.BaseLayout)
+	}
+
+	changes := st.changes(proposed)
+
+	if c.FormValue("action") == actionPreview {
+		impacts, unchanged, err := computeImpact(s.db, st.current, proposed)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to compute group mapping impact")
+			return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadMappings, nil)
+		}
+
+		return c.Render(templateList, fiber.Map{
+			"Navigation": s.nav(),
+			"Rows":       st.rows(proposed),
+			"Roles":      st.roles,
+			"Preview":    true,
+			"Changes":    changes,
+			"Impacts":    impacts,
+			"Unchanged":  unchanged,
+		}, handler.BaseLayout)
+	}
+
+	if len(changes) == 0 {
+		return c.Redirect().To(Path + "?success=" + url.QueryEscape("No mapping changes to save"))
+	}
+
+	if err := applyChanges(s.db, changes); err != nil {
+		log.Error().Err(err).Msg("failed to save group mappings")
+
+		return c.Status(fiber.StatusInternalServerError).Render(templateList, fiber.Map{
+			"Navigation": s.nav(),
+			"Rows":       st.rows(proposed),
+			"Roles":      st.roles,
+			"Error":      errFailedSaveMappings,
+		}, handler.BaseLayout)
+	}
+
+	log.Info().Int("changes", len(changes)).Msg("group mappings updated")
+
+	return c.Redirect().To(Path + "?success=" + url.QueryEscape(
+		"Saved "+strconv.Itoa(len(changes))+" mapping change(s)"))
+}
+
+func (s *Service) nav() *navigation.Context {
+	return navigation.NewContext(labelGroupMappings, navSection, navEntity).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb(labelGroupMappings, Path, true)
+}
+
+// state is a snapshot of groups, roles and the current group → role mapping.
+type state struct {
+	groups   []models.Group
+	members  map[uint]int64
+	roles    []models.Role
+	roleByID map[uint]models.Role
+	current  map[uint]uint
+}
+
+func (s *Service) loadState() (*state, error) {
+	st := &state{
+		members:  make(map[uint]int64),
+		roleByID: make(map[uint]models.Role),
+		current:  make(map[uint]uint),
+	}
+
+	if err := s.db.Order(handler.OrderNameASC).Find(&st.groups).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Order(handler.OrderNameASC).Find(&st.roles).Error; err != nil {
+		return nil, err
+	}
+
+	for _, r := range st.roles {
+		st.roleByID[r.ID] = r
+	}
+
+	var mappings []models.GroupMapping
+	if err := s.db.Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+
+	for _, m := range mappings {
+		st.current[m.GroupID] = m.RoleID
+	}
+
+	var counts []struct {
+		GroupID uint
+		Count   int64
+	}
+
+	if err := s.db.Model(&models.UserGroup{}).
+		Select("group_id, COUNT(*) AS count").
+		Group("group_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	for _, cnt := range counts {
+		st.members[cnt.GroupID] = cnt.Count
+	}
+
+	return st, nil
+}
+
+// rows builds the table rows with selected holding the role chosen per group.
+func (st *state) rows(selected map[uint]uint) []Row {
+	rows := make([]Row, 0, len(st.groups))
+
+	for _, g := range st.groups {
+		roleID, mapped := st.current[g.ID]
+		rows = append(rows, Row{
+			Group:       g,
+			Members:     st.members[g.ID],
+			RoleID:      roleID,
+			SelectedID:  selected[g.ID],
+			HasMapping:  mapped,
+			WillChange:  selected[g.ID] != roleID,
+			CurrentRole: st.roleByID[roleID].Name,
+		})
+	}
+
+	return rows
+}
+
+// parseProposed reads the submitted mapping for every known group.
+func (st *state) parseProposed(c fiber.Ctx) (map[uint]uint, error) {
+	proposed := make(map[uint]uint, len(st.groups))
+
+	for _, g := range st.groups {
+		raw := c.FormValue("role_" + strconv.FormatUint(uint64(g.ID), 10))
+		if raw == "" {
+			// Row not submitted: keep the current mapping.
+			if roleID, ok := st.current[g.ID]; ok {
+				proposed[g.ID] = roleID
+			}
+
+			continue
+		}
+
+		roleID, err := st.parseRoleID(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		if roleID != 0 {
+			proposed[g.ID] = roleID
+		}
+	}
+
+	bulk := c.FormValue("bulk_role")
+	if bulk == "" {
+		return proposed, nil
+	}
+
+	bulkRoleID, err := st.parseRoleID(bulk)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range c.Request().PostArgs().PeekMulti("group_ids") {
+		groupID, err := strconv.ParseUint(string(raw), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if !st.hasGroup(uint(groupID)) {
+			continue
+		}
+
+		if bulkRoleID == 0 {
+			delete(proposed, uint(groupID))
+		} else {
+			proposed[uint(groupID)] = bulkRoleID
+		}
+	}
+
+	return proposed, nil
+}
+
+func (st *state) parseRoleID(raw string) (uint, error) {
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, errRoleNotFound
+	}
+
+	if id == 0 {
+		return 0, nil
+	}
+
+	if _, ok := st.roleByID[uint(id)]; !ok {
+		return 0, errRoleNotFound
+	}
+
+	return uint(id), nil
+}
+
+func (st *state) hasGroup(id uint) bool {
+	for _, g := range st.groups {
+		if g.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Change describes a single group whose mapped role differs from the proposal.
+type Change struct {
+	Group    models.Group
+	FromID   uint
+	ToID     uint
+	FromRole string
+	ToRole   string
+}
+
+// changes lists the groups whose mapping differs between current and proposed.
+func (st *state) changes(proposed map[uint]uint) []Change {
+	var changes []Change
+
+	for _, g := range st.groups {
+		from, to := st.current[g.ID], proposed[g.ID]
+		if from == to {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Group:    g,
+			FromID:   from,
+			ToID:     to,
+			FromRole: st.roleByID[from].Name,
+			ToRole:   st.roleByID[to].Name,
+		})
+	}
+
+	return changes
+}
+
+// applyChanges replaces the mappings of all changed groups in one transaction.
+func applyChanges(db *gorm.DB, changes []Change) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, ch := range changes {
+			if err := tx.Where("group_id = ?", ch.Group.ID).Delete(&models.GroupMapping{}).Error; err != nil {
+				return err
+			}
+
+			if ch.ToID == 0 {
+				continue
+			}
+
+			if err := tx.Create(&models.GroupMapping{GroupID: ch.Group.ID, RoleID: ch.ToID}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,425 @@
+package groupmapping
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// captureViews is a minimal Fiber Views engine that records the last render.
+type captureViews struct {
+	mu       sync.Mutex
+	lastData any
+}
+
+func (v *captureViews) Load() error { return nil }
+
+func (v *captureViews) Render(w io.Writer, name string, data any, _ ...string) error {
+	v.mu.Lock()
+	v.lastData = data
+	v.mu.Unlock()
+
+	_, _ = io.WriteString(w, name)
+
+	return nil
+}
+
+// fixture holds the seeded rows used by the tests.
+type fixture struct {
+	viewer, operator models.Role
+	ops, dev         models.Group
+	alice, bob       models.User
+}
+
+// newTestService seeds two roles, two groups and two users:
+//
+//	viewer:   zone.read
+//	operator: zone.read, zone.update
+//	ops → operator (alice, bob), dev → unmapped (bob)
+func newTestService(t *testing.T) (*fiber.App, *Service, *captureViews, fixture) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(
+		&models.Role{}, &models.Permission{}, &models.RolePermission{},
+		&models.User{}, &models.Group{}, &models.GroupMapping{}, &models.UserGroup{}, &models.PendingGroupRemoval{},
+	); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	read := models.Permission{Name: "zone.read", Resource: "zone", Action: "read"}
+	update := models.Permission{Name: "zone.update", Resource: "zone", Action: "update"}
+	db.Create(&read)
+	db.Create(&update)
+
+	f := fixture{
+		viewer:   models.Role{Name: "viewer"},
+		operator: models.Role{Name: "operator"},
+		ops:      models.Group{Name: "ops", ExternalID: "ops", Source: models.GroupSourceLocal},
+		dev:      models.Group{Name: "dev", ExternalID: "dev", Source: models.GroupSourceLocal},
+	}
+	db.Create(&f.viewer)
+	db.Create(&f.operator)
+	db.Create(&models.RolePermission{RoleID: f.viewer.ID, PermissionID: read.ID})
+	db.Create(&models.RolePermission{RoleID: f.operator.ID, PermissionID: read.ID})
+	db.Create(&models.RolePermission{RoleID: f.operator.ID, PermissionID: update.ID})
+
+	db.Create(&f.ops)
+	db.Create(&f.dev)
+	db.Create(&models.GroupMapping{GroupID: f.ops.ID, RoleID: f.operator.ID})
+
+	f.alice = models.User{Username: "alice", Email: "alice@example.com", RoleID: f.viewer.ID}
+	f.bob = models.User{Username: "bob", Email: "bob@example.com", RoleID: f.viewer.ID}
+	db.Create(&f.alice)
+	db.Create(&f.bob)
+	db.Create(&models.UserGroup{UserID: f.alice.ID, GroupID: f.ops.ID})
+	db.Create(&models.UserGroup{UserID: f.bob.ID, GroupID: f.ops.ID})
+	db.Create(&models.UserGroup{UserID: f.bob.ID, GroupID: f.dev.ID})
+
+	views := &captureViews{}
+	app := fiber.New(fiber.Config{Views: views})
+	svc := &Service{cfg: &config.Config{}, db: db, authService: auth.NewService(db)}
+
+	// Register routes directly — bypasses permission middleware for unit tests.
+	app.Get(Path, svc.List)
+	app.Post(Path, svc.Save)
+	app.Post(PathSyncPreview, svc.RunSyncPreview)
+	app.Get(PathPending, svc.Pending)
+	app.Post(PathPending+"/:id/approve", svc.ApprovePending)
+	app.Post(PathPending+"/:id/reject", svc.RejectPending)
+
+	return app, svc, views, f
+}
+
+func roleField(g models.Group) string {
+	return "role_" + strconv.FormatUint(uint64(g.ID), 10)
+}
+
+func id(v uint) string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+func post(t *testing.T, app *fiber.App, form url.Values) *http.Response {
+	t.Helper()
+
+	return postTo(t, app, Path, form)
+}
+
+func postTo(t *testing.T, app *fiber.App, path string, form url.Values) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path,
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+
+	return resp
+}
+
+func mappingOf(t *testing.T, db *gorm.DB, g models.Group) uint {
+	t.Helper()
+
+	var m models.GroupMapping
+	if err := db.Where("group_id = ?", g.ID).Limit(1).Find(&m).Error; err != nil {
+		t.Fatalf("load mapping: %v", err)
+	}
+
+	return m.RoleID
+}
+
+func TestList(t *testing.T) {
+	app, _, views, f := newTestService(t)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, Path, http.NoBody)
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	m, _ := views.lastData.(fiber.Map)
+	rows, _ := m["Rows"].([]Row)
+
+	if len(rows) != 2 {
+		t.Fatalf("len(rows) = %d, want 2", len(rows))
+	}
+
+	// Ordered by name: dev, ops.
+	if rows[0].HasMapping || rows[0].Members != 1 {
+		t.Errorf("dev row = %+v", rows[0])
+	}
+
+	if rows[1].CurrentRole != f.operator.Name || rows[1].Members != 2 || rows[1].WillChange {
+		t.Errorf("ops row = %+v", rows[1])
+	}
+}
+
+func TestSave_PreviewDoesNotWrite(t *testing.T) {
+	app, svc, views, f := newTestService(t)
+
+	resp := post(t, app, url.Values{
+		"action":         {actionPreview},
+		roleField(f.ops): {id(f.viewer.ID)},
+		roleField(f.dev): {"0"},
+	})
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	if got := mappingOf(t, svc.db, f.ops); got != f.operator.ID {
+		t.Errorf("preview changed mapping to role %d", got)
+	}
+
+	m, _ := views.lastData.(fiber.Map)
+
+	changes, _ := m["Changes"].([]Change)
+	if len(changes) != 1 || changes[0].FromRole != "operator" || changes[0].ToRole != "viewer" {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	impacts, _ := m["Impacts"].([]Impact)
+	if len(impacts) != 2 {
+		t.Fatalf("len(impacts) = %d, want 2", len(impacts))
+	}
+
+	for _, im := range impacts {
+		if len(im.Added) != 0 || len(im.Removed) != 1 || im.Removed[0] != "zone.update" {
+			t.Errorf("%s impact = %+v", im.User.Username, im)
+		}
+	}
+}
+
+func TestSave_PreviewCountsUnchangedMembers(t *testing.T) {
+	app, _, views, f := newTestService(t)
+
+	// Mapping dev to operator adds nothing for bob, who already has it via ops.
+	resp := post(t, app, url.Values{
+		"action":         {actionPreview},
+		roleField(f.ops): {id(f.operator.ID)},
+		roleField(f.dev): {id(f.operator.ID)},
+	})
+	defer func() { _ = resp.Body.Close() }()
+
+	m, _ := views.lastData.(fiber.Map)
+
+	if impacts, _ := m["Impacts"].([]Impact); len(impacts) != 0 {
+		t.Errorf("impacts = %+v, want none", impacts)
+	}
+
+	if m["Unchanged"] != 1 {
+		t.Errorf("Unchanged = %v, want 1", m["Unchanged"])
+	}
+}
+
+func TestSave_ApplyBulk(t *testing.T) {
+	app, svc, _, f := newTestService(t)
+
+	resp := post(t, app, url.Values{
+		"action":         {"apply"},
+		roleField(f.ops): {id(f.operator.ID)},
+		roleField(f.dev): {"0"},
+		"group_ids":      {id(f.ops.ID), id(f.dev.ID)},
+		"bulk_role":      {id(f.viewer.ID)},
+	})
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusSeeOther && resp.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want redirect", resp.StatusCode)
+	}
+
+	if got := mappingOf(t, svc.db, f.ops); got != f.viewer.ID {
+		t.Errorf("ops mapped to %d, want viewer", got)
+	}
+
+	if got := mappingOf(t, svc.db, f.dev); got != f.viewer.ID {
+		t.Errorf("dev mapped to %d, want viewer", got)
+	}
+
+	// Bulk unmap.
+	resp = post(t, app, url.Values{
+		"action":    {"apply"},
+		"group_ids": {id(f.ops.ID), id(f.dev.ID)},
+		"bulk_role": {"0"},
+	})
+	defer func() { _ = resp.Body.Close() }()
+
+	var count int64
+	svc.db.Model(&models.GroupMapping{}).Count(&count)
+
+	if count != 0 {
+		t.Errorf("%d mappings left after bulk unmap", count)
+	}
+}
+
+func TestSave_UnknownRole(t *testing.T) {
+	app, svc, _, f := newTestService(t)
+
+	resp := post(t, app, url.Values{
+		"action":         {"apply"},
+		roleField(f.ops): {"9999"},
+	})
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+
+	if got := mappingOf(t, svc.db, f.ops); got != f.operator.ID {
+		t.Errorf("mapping changed to %d on invalid input", got)
+	}
+}
+
+func TestDiffPermissions(t *testing.T) {
+	added, removed := diffPermissions(
+		map[string]bool{"a": true, "b": true},
+		map[string]bool{"b": true, "d": true, "c": true},
+	)
+
+	if strings.Join(added, ",") != "c,d" || strings.Join(removed, ",") != "a" {
+		t.Errorf("added = %v, removed = %v", added, removed)
+	}
+}
+
+func TestRunSyncPreview_ManualGroups(t *testing.T) {
+	app, svc, views, f := newTestService(t)
+
+	svc.db.Model(&f.alice).Update("auth_source", models.AuthSourceOIDC)
+	svc.db.Model(&f.ops).Update("source", models.GroupSourceOIDC)
+
+	resp := postTo(t, app, PathSyncPreview, url.Values{
+		"user_id": {strconv.FormatUint(f.alice.ID, 10)},
+		"groups":  {"newcomers\n\n"},
+	})
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	m, _ := views.lastData.(fiber.Map)
+
+	results, _ := m["Results"].([]SyncResult)
+	if len(results) != 1 || results[0].Plan == nil {
+		t.Fatalf("results = %+v", results)
+	}
+
+	plan := results[0].Plan
+	if strings.Join(plan.NewGroups, ",") != "newcomers" || strings.Join(plan.Removed, ",") != "ops" ||
+		strings.Join(plan.RolesLost, ",") != "operator" {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	var groups int64
+	svc.db.Model(&models.Group{}).Count(&groups)
+
+	if groups != 2 {
+		t.Errorf("preview created groups: %d", groups)
+	}
+}
+
+func TestRunSyncPreview_OIDCRequiresGroups(t *testing.T) {
+	app, svc, _, f := newTestService(t)
+
+	svc.db.Model(&f.alice).Update("auth_source", models.AuthSourceOIDC)
+
+	for _, form := range []url.Values{
+		{"user_id": {strconv.FormatUint(f.alice.ID, 10)}},
+		{"user_id": {userAll}},
+	} {
+		resp := postTo(t, app, PathSyncPreview, form)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want 400", form, resp.StatusCode)
+		}
+	}
+}
+
+func TestPending_ApproveAndReject(t *testing.T) {
+	app, svc, views, f := newTestService(t)
+
+	approve := models.PendingGroupRemoval{UserID: f.alice.ID, GroupID: f.ops.ID}
+	reject := models.PendingGroupRemoval{UserID: f.bob.ID, GroupID: f.dev.ID}
+	svc.db.Create(&approve)
+	svc.db.Create(&reject)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, PathPending, http.NoBody)
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	m, _ := views.lastData.(fiber.Map)
+	if pending, _ := m["Pending"].([]models.PendingGroupRemoval); len(pending) != 2 {
+		t.Fatalf("len(Pending) = %d, want 2", len(pending))
+	}
+
+	resp = postTo(t, app, PathPending+"/"+strconv.FormatUint(approve.ID, 10)+"/approve", url.Values{})
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != fiber.StatusSeeOther && resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("approve status = %d", resp.StatusCode)
+	}
+
+	resp = postTo(t, app, PathPending+"/"+strconv.FormatUint(reject.ID, 10)+"/reject", url.Values{})
+	_ = resp.Body.Close()
+
+	var memberships []models.UserGroup
+	svc.db.Order("user_id, group_id").Find(&memberships)
+
+	if len(memberships) != 2 {
+		t.Fatalf("memberships = %+v, want bob in ops and dev", memberships)
+	}
+
+	for _, ug := range memberships {
+		if ug.UserID != f.bob.ID {
+			t.Errorf("unexpected membership %+v", ug)
+		}
+
+		if ug.GroupID == f.dev.ID && !ug.Manual {
+			t.Error("rejected removal should mark bob's dev membership manual")
+		}
+	}
+
+	var left int64
+	svc.db.Model(&models.PendingGroupRemoval{}).Count(&left)
+
+	if left != 0 {
+		t.Errorf("pending removals left = %d, want 0", left)
+	}
+
+	resp = postTo(t, app, PathPending+"/abc/approve", url.Values{})
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("invalid id status = %d, want 400", resp.StatusCode)
+	}
+}
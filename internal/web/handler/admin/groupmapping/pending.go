@@ -0,0 +1,76 @@
+package groupmapping
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathPending is the path of the pending group removals page.
+	PathPending = Path + "/pending-removals"
+
+	templatePending = "admin/groupmapping/pending"
+
+	labelPending = "Pending Removals"
+
+	errFailedLoadPending   = "Failed to load pending removals"
+	errFailedSettlePending = "Failed to update the pending removal"
+	errInvalidPendingID    = "Invalid pending removal ID"
+)
+
+// Pending lists the group removals queued by group sync under the approval
+// policy.
+func (s *Service) Pending(c fiber.Ctx) error {
+	pending, err := s.authService.ListPendingGroupRemovals()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load pending group removals")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadPending, nil)
+	}
+
+	return c.Render(templatePending, fiber.Map{
+		"Navigation": s.pendingNav(),
+		"Pending":    pending,
+		"Policy":     s.cfg.Auth.GroupSync.Policy,
+		"Success":    c.Query("success"),
+	}, handler.BaseLayout)
+}
+
+// ApprovePending removes the membership of a pending removal.
+func (s *Service) ApprovePending(c fiber.Ctx) error {
+	return s.settlePending(c, s.authService.ApprovePendingGroupRemoval, "Membership removed.")
+}
+
+// RejectPending keeps the membership of a pending removal and protects it from
+// later syncs.
+func (s *Service) RejectPending(c fiber.Ctx) error {
+	return s.settlePending(c, s.authService.RejectPendingGroupRemoval, "Membership kept and marked as manual.")
+}
+
+func (s *Service) settlePending(c fiber.Ctx, settle func(id uint64) error, success string) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || id == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidPendingID)
+	}
+
+	if err := settle(id); err != nil {
+		log.Error().Err(err).Uint64("id", id).Msg("failed to settle pending group removal")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSettlePending, nil)
+	}
+
+	return c.Redirect().To(PathPending + "?success=" + url.QueryEscape(success))
+}
+
+func (s *Service) pendingNav() *navigation.Context {
+	return navigation.NewContext(labelPending, navSection, navEntity).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb(labelGroupMappings, Path, false).
+		AddBreadcrumb(labelPending, PathPending, true)
+}
@@ -0,0 +1,231 @@
+package groupmapping
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	labelSyncPreview = "Simulate Group Sync"
+
+	// userAll selects every LDAP user for the preview.
+	userAll = "all"
+
+	errFailedLoadUsers   = "Failed to load users"
+	errNoUserSelected    = "Select a user to simulate"
+	errUserNotFound      = "User not found"
+	errNoSyncSource      = "Local users are not synchronized; choose LDAP or OIDC as source"
+	errGroupsRequired    = "Enter the user's external groups: OIDC groups are only known when the user logs in"
+	errLDAPLookupMissing = "Previewing all users requires LDAP to be enabled; OIDC groups are only known at login"
+)
+
+// SyncResult is the simulated group sync of one user.
+type SyncResult struct {
+	User   models.User
+	Source models.GroupSource
+	Groups []string
+	Plan   *auth.SyncPlan
+	Error  string
+}
+
+// SyncPreview renders the group sync simulation form.
+func (s *Service) SyncPreview(c fiber.Ctx) error {
+	users, err := s.syncUsers()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load users for sync preview")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadUsers, nil)
+	}
+
+	return c.Render(templateSync, fiber.Map{
+		"Navigation":    s.syncNav(),
+		"Users":         users,
+		"LDAPAvailable": s.ldapProvider() != nil,
+		"SelectedUser":  "",
+		"SelectedSrc":   "",
+		"GroupsInput":   "",
+	}, handler.BaseLayout)
+}
+
+// RunSyncPreview simulates SyncUserGroups for one user or all LDAP users and
+// renders what would change. Nothing is written.
+//
+// For a single user the external groups come from the groups field (one per
+// line) or, when it is empty and the source is LDAP, from the directory. OIDC
+// groups are only delivered at login, so they must be entered by hand.
+func (s *Service) RunSyncPreview(c fiber.Ctx) error {
+	users, err := s.syncUsers()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load users for sync preview")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadUsers, nil)
+	}
+
+	data := fiber.Map{
+		"Navigation":    s.syncNav(),
+		"Users":         users,
+		"LDAPAvailable": s.ldapProvider() != nil,
+		"SelectedUser":  c.FormValue("user_id"),
+		"SelectedSrc":   c.FormValue("source"),
+		"GroupsInput":   c.FormValue("groups"),
+	}
+
+	results, skipped, errMsg := s.simulate(c, users)
+	if errMsg != "" {
+		data["Error"] = errMsg
+		return c.Status(fiber.StatusBadRequest).Render(templateSync, data, handler.BaseLayout)
+	}
+
+	data["Results"] = results
+	data["Skipped"] = skipped
+
+	return c.Render(templateSync, data, handler.BaseLayout)
+}
+
+// simulate builds the results for the submitted selection. skipped counts the
+// OIDC users left out of an all-users preview.
+func (s *Service) simulate(c fiber.Ctx, users []models.User) (results []SyncResult, skipped int, errMsg string) {
+	selected := c.FormValue("user_id")
+	ldap := s.ldapProvider()
+
+	if selected == userAll {
+		if ldap == nil {
+			return nil, 0, errLDAPLookupMissing
+		}
+
+		for i := range users {
+			if users[i].AuthSource != models.AuthSourceLDAP {
+				if users[i].AuthSource == models.AuthSourceOIDC {
+					skipped++
+				}
+
+				continue
+			}
+
+			results = append(results, s.simulateUser(ldap, users[i], models.GroupSourceLDAP, nil))
+		}
+
+		return results, skipped, ""
+	}
+
+	id, err := strconv.ParseUint(selected, 10, 64)
+	if err != nil || id == 0 {
+		return nil, 0, errNoUserSelected
+	}
+
+	var user *models.User
+
+	for i := range users {
+		if users[i].ID == id {
+			user = &users[i]
+			break
+		}
+	}
+
+	if user == nil {
+		return nil, 0, errUserNotFound
+	}
+
+	source := syncSource(c.FormValue("source"), user.AuthSource)
+	if source == "" {
+		return nil, 0, errNoSyncSource
+	}
+
+	groups := parseGroupList(c.FormValue("groups"))
+	if len(groups) == 0 && (source != models.GroupSourceLDAP || ldap == nil) {
+		return nil, 0, errGroupsRequired
+	}
+
+	return []SyncResult{s.simulateUser(ldap, *user, source, groups)}, 0, ""
+}
+
+// simulateUser plans the sync of one user. A nil groups list is looked up in
+// the LDAP directory of ldap.
+func (s *Service) simulateUser(
+	ldap *auth.LDAPProvider,
+	user models.User,
+	source models.GroupSource,
+	groups []string,
+) SyncResult {
+	res := SyncResult{User: user, Source: source, Groups: groups}
+
+	if groups == nil {
+		looked, err := ldap.LookupUserGroups(user.Username)
+		if err != nil {
+			log.Warn().Err(err).Str("username", user.Username).Msg("sync preview: LDAP group lookup failed")
+
+			res.Error = "LDAP lookup failed: " + err.Error()
+
+			return res
+		}
+
+		res.Groups = looked
+	}
+
+	plan, err := s.authService.PlanUserGroupSync(user.ID, res.Groups, source)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("sync preview: planning failed")
+
+		res.Error = "Failed to plan sync"
+
+		return res
+	}
+
+	res.Plan = plan
+
+	return res
+}
+
+func (s *Service) syncUsers() ([]models.User, error) {
+	var users []models.User
+	err := s.db.Order(handler.OrderUsernameASC).Find(&users).Error
+
+	return users, err
+}
+
+func (s *Service) syncNav() *navigation.Context {
+	return navigation.NewContext(labelSyncPreview, navSection, navEntity).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb(labelGroupMappings, Path, false).
+		AddBreadcrumb(labelSyncPreview, PathSyncPreview, true)
+}
+
+// syncSource returns the group source to simulate: the explicitly chosen one,
+// or the one matching the user's authentication source. Local users without an
+// explicit choice yield "".
+func syncSource(chosen string, authSource models.AuthSource) models.GroupSource {
+	switch models.GroupSource(chosen) {
+	case models.GroupSourceLDAP, models.GroupSourceOIDC:
+		return models.GroupSource(chosen)
+	}
+
+	switch authSource {
+	case models.AuthSourceLDAP:
+		return models.GroupSourceLDAP
+	case models.AuthSourceOIDC:
+		return models.GroupSourceOIDC
+	default:
+		return ""
+	}
+}
+
+// parseGroupList splits one group per line, trimming blanks.
+func parseGroupList(raw string) []string {
+	var groups []string
+
+	for _, line := range strings.Split(raw, "\n") {
+		if g := strings.TrimSpace(line); g != "" {
+			groups = append(groups, g)
+		}
+	}
+
+	return groups
+}
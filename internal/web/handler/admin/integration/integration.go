@@ -0,0 +1,340 @@
+// Package integration provides the admin pages that manage the Slack,
+// Mattermost and Microsoft Teams channels notified of zone and record changes
+// and failed health checks (see internal/chatnotify). Administrators of a
+// tenant manage the integrations of their tenant only.
+package integration
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/chatnotify"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathList is the path for the integration list.
+	PathList = handler.RootPath + "admin/integrations"
+	// PathNew is the path for creating an integration.
+	PathNew = PathList + "/new"
+	// PathEdit is the path for editing an integration.
+	PathEdit = PathList + "/:id/edit"
+	// PathDelete is the path for deleting an integration.
+	PathDelete = PathList + "/:id/delete"
+	// PathTest sends a test message to an integration.
+	PathTest = PathList + "/:id/test"
+
+	templateList = "admin/integration/list"
+	templateForm = "admin/integration/form"
+
+	navSection    = "admin"
+	navSubsection = "integrations"
+
+	labelIntegrations    = "Integrations"
+	labelNewIntegration  = "New Integration"
+	labelEditIntegration = "Edit Integration"
+
+	errIntegrationNotFound = "Integration not found"
+	errInvalidFormData     = "Invalid form data"
+)
+
+// kinds are the integration kinds offered in the form.
+var kinds = []models.IntegrationKind{models.IntegrationSlack, models.IntegrationMattermost, models.IntegrationTeams}
+
+// Service is the integration handler service.
+type Service struct {
+	handler.Service
+	db       *gorm.DB
+	notifier *chatnotify.Notifier
+}
+
+// Handler is the integration handler.
+var Handler = Service{}
+
+// form is the submitted integration form.
+type form struct {
+	Name           string `form:"name"`
+	Kind           string `form:"kind"`
+	WebhookURL     string `form:"webhook_url"`
+	Channel        string `form:"channel"`
+	Zones          string `form:"zones"`
+	Enabled        bool   `form:"enabled"`
+	ZoneEvents     bool   `form:"zone_events"`
+	RecordEvents   bool   `form:"record_events"`
+	HealthEvents   bool   `form:"health_events"`
+	ZoneTemplate   string `form:"zone_template"`
+	RecordTemplate string `form:"record_template"`
+	HealthTemplate string `form:"health_template"`
+}
+
+// Init initializes the integration handler.
+func (s *Service) Init(
+	app *fiber.App,
+	cfg *config.Config,
+	db *gorm.DB,
+	authService *auth.Service,
+	notifier *chatnotify.Notifier,
+) {
+	if app == nil || cfg == nil || db == nil || notifier == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.notifier = notifier
+
+	perm := auth.RequirePermission(authService, auth.PermAdminIntegrations)
+
+	app.Get(PathList, perm, s.List)
+	app.Get(PathNew, perm, s.New)
+	app.Post(PathNew, perm, s.Create)
+	app.Get(PathEdit, perm, s.Edit)
+	app.Post(PathEdit, perm, s.Update)
+	app.Post(PathDelete, perm, s.Delete)
+	app.Post(PathTest, perm, s.Test)
+}
+
+// List renders the integration list.
+func (s *Service) List(c fiber.Ctx) error {
+	nav := navigation.NewContext(labelIntegrations, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelIntegrations, PathList, true)
+
+	var integrations []models.Integration
+	if err := s.scoped(c).Preload("Tenant").Order(handler.OrderNameASC).Find(&integrations).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list integrations")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load integrations", nil)
+	}
+
+	current, _ := c.Locals("CurrentUser").(models.User)
+
+	tenants, err := tenant.Choices(s.db, current.TenantID)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to load tenants")
+	}
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation":   nav,
+		"Integrations": integrations,
+		"ShowTenants":  len(tenants) > 0,
+		"Success":      c.Query("success"),
+		"Error":        c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// New renders the create integration form.
+func (s *Service) New(c fiber.Ctx) error {
+	return s.renderForm(c, fiber.StatusOK, &models.Integration{
+		Kind:         models.IntegrationSlack,
+		Enabled:      true,
+		ZoneEvents:   true,
+		RecordEvents: true,
+		HealthEvents: true,
+	}, "")
+}
+
+// Create handles the create integration form submission.
+func (s *Service) Create(c fiber.Ctx) error {
+	var in form
+	if err := c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	current, _ := c.Locals("CurrentUser").(models.User)
+
+	integration := &models.Integration{TenantID: current.TenantID}
+	apply(integration, &in)
+
+	if msg := validate(integration); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, integration, msg)
+	}
+
+	if err := s.db.Create(integration).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to create integration")
+		return s.renderForm(c, fiber.StatusInternalServerError, integration, "Failed to create integration: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Integration "+integration.Name+" created."))
+}
+
+// Edit renders the edit integration form.
+func (s *Service) Edit(c fiber.Ctx) error {
+	integration, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	return s.renderForm(c, fiber.StatusOK, integration, "")
+}
+
+// Update handles the edit integration form submission. An empty webhook URL
+// keeps the stored one.
+func (s *Service) Update(c fiber.Ctx) error {
+	integration, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	var in form
+	if err = c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	apply(integration, &in)
+
+	if msg := validate(integration); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, integration, msg)
+	}
+
+	if err = s.db.Save(integration).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to update integration")
+		return s.renderForm(c, fiber.StatusInternalServerError, integration, "Failed to update integration: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Integration "+integration.Name+" updated."))
+}
+
+// Delete handles integration deletion.
+func (s *Service) Delete(c fiber.Ctx) error {
+	integration, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	if err = s.db.Delete(integration).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to delete integration")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Delete Failed", "Failed to delete integration", nil)
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Integration "+integration.Name+" deleted."))
+}
+
+// Test sends an example message to an integration, whether enabled or not.
+func (s *Service) Test(c fiber.Ctx) error {
+	integration, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	if err = s.notifier.SendTest(c.Context(), integration); err != nil {
+		msg := "Test message to " + integration.Name + " failed: " + err.Error()
+		return c.Redirect().To(PathList + "?error=" + url.QueryEscape(msg))
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Test message sent to "+integration.Name+"."))
+}
+
+// load returns the integration of the :id parameter, or renders the error
+// page and returns its result as the error.
+func (s *Service) load(c fiber.Ctx) (*models.Integration, error) {
+	var integration models.Integration
+
+	err := s.scoped(c).First(&integration, fiber.Params[uint](c, "id")).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, errIntegrationNotFound)
+	}
+
+	if err != nil {
+		return nil, handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load integration", nil)
+	}
+
+	return &integration, nil
+}
+
+// scoped returns the database limited to the integrations of the current
+// user's tenant; administrators outside any tenant see every integration.
+func (s *Service) scoped(c fiber.Ctx) *gorm.DB {
+	current, _ := c.Locals("CurrentUser").(models.User)
+	return s.db.Scopes(tenant.Scope(current.TenantID))
+}
+
+// apply copies the submitted form to integration.
+func apply(integration *models.Integration, in *form) {
+	integration.Name = strings.TrimSpace(in.Name)
+	integration.Kind = models.IntegrationKind(in.Kind)
+	integration.Channel = strings.TrimSpace(in.Channel)
+	integration.Zones = strings.Join(strings.Fields(in.Zones), " ")
+	integration.Enabled = in.Enabled
+	integration.ZoneEvents = in.ZoneEvents
+	integration.RecordEvents = in.RecordEvents
+	integration.HealthEvents = in.HealthEvents
+	integration.ZoneTemplate = strings.TrimSpace(in.ZoneTemplate)
+	integration.RecordTemplate = strings.TrimSpace(in.RecordTemplate)
+	integration.HealthTemplate = strings.TrimSpace(in.HealthTemplate)
+
+	if u := strings.TrimSpace(in.WebhookURL); u != "" {
+		integration.WebhookURL = u
+	}
+}
+
+// validate returns the message of the first invalid field of integration,
+// or "".
+func validate(integration *models.Integration) string {
+	if integration.Name == "" {
+		return "Name is required"
+	}
+
+	if !slices.Contains(kinds, integration.Kind) {
+		return "Choose Slack, Mattermost or Microsoft Teams"
+	}
+
+	u, err := url.Parse(integration.WebhookURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "Webhook URL must be an http or https URL"
+	}
+
+	for event, src := range map[string]string{
+		chatnotify.EventZone:   integration.ZoneTemplate,
+		chatnotify.EventRecord: integration.RecordTemplate,
+		chatnotify.EventHealth: integration.HealthTemplate,
+	} {
+		if err = chatnotify.CheckTemplate(event, src); err != nil {
+			return "Invalid " + event + " template: " + err.Error()
+		}
+	}
+
+	return ""
+}
+
+// renderForm renders the integration form; integration.ID is 0 for a new one.
+func (s *Service) renderForm(c fiber.Ctx, status int, integration *models.Integration, errMsg string) error {
+	title := labelEditIntegration
+	if integration.ID == 0 {
+		title = labelNewIntegration
+	}
+
+	nav := navigation.NewContext(title, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelIntegrations, PathList, false).
+		AddBreadcrumb(title, "", true)
+
+	return c.Status(status).Render(templateForm, fiber.Map{
+		"Navigation":  nav,
+		"Integration": integration,
+		"IsCreate":    integration.ID == 0,
+		"HasWebhook":  integration.WebhookURL != "",
+		"Kinds":       kinds,
+		"Defaults": fiber.Map{
+			"Zone":   chatnotify.DefaultZoneTemplate,
+			"Record": chatnotify.DefaultRecordTemplate,
+			"Health": chatnotify.DefaultHealthTemplate,
+		},
+		"Error": errMsg,
+	}, handler.BaseLayout)
+}
@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// recordingViews keeps the data of the last render.
+type recordingViews struct {
+	data fiber.Map
+}
+
+func (*recordingViews) Load() error { return nil }
+
+func (v *recordingViews) Render(w io.Writer, name string, data any, _ ...string) error {
+	v.data, _ = data.(fiber.Map)
+	_, _ = io.WriteString(w, name)
+
+	return nil
+}
+
+func TestTenantIntegrations(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Tenant{}, &models.Integration{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	acme, globex := models.Tenant{Name: "acme"}, models.Tenant{Name: "globex"}
+	db.Create(&acme)
+	db.Create(&globex)
+
+	for _, in := range []models.Integration{
+		{Name: "provider", TenantID: nil},
+		{Name: "globex", TenantID: &globex.ID},
+	} {
+		in.Kind, in.WebhookURL = models.IntegrationSlack, "https://hooks.example.com/"+in.Name
+		db.Create(&in)
+	}
+
+	views := &recordingViews{}
+	app := fiber.New(fiber.Config{Views: views})
+	svc := &Service{db: db}
+
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", models.User{ID: 2, Username: "acme-admin", TenantID: &acme.ID})
+		return c.Next()
+	})
+	app.Get(PathList, svc.List)
+	app.Post(PathNew, svc.Create)
+	app.Get(PathEdit, svc.Edit)
+	app.Post(PathDelete, svc.Delete)
+
+	send := func(method, path string, form url.Values) int {
+		t.Helper()
+
+		req := httptest.NewRequestWithContext(context.Background(), method, path, strings.NewReader(form.Encode()))
+		if form != nil {
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+		}
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	if status := send(http.MethodPost, PathNew, url.Values{
+		"name": {"acme"}, "kind": {"slack"}, "webhook_url": {"https://hooks.example.com/acme"}, "enabled": {"true"},
+	}); status != http.StatusSeeOther && status != http.StatusFound {
+		t.Fatalf("create: status %d", status)
+	}
+
+	var created models.Integration
+	if err = db.Where("name = ?", "acme").First(&created).Error; err != nil || created.TenantID == nil ||
+		*created.TenantID != acme.ID {
+		t.Errorf("integration created by a tenant admin = %+v, %v, want tenant %d", created, err, acme.ID)
+	}
+
+	send(http.MethodGet, PathList, nil)
+
+	listed, _ := views.data["Integrations"].([]models.Integration)
+	if len(listed) != 1 || listed[0].Name != "acme" {
+		t.Errorf("tenant admin lists %+v, want only acme", listed)
+	}
+
+	for _, path := range []string{"/admin/integrations/1/edit", "/admin/integrations/2/edit"} {
+		if status := send(http.MethodGet, path, nil); status != http.StatusNotFound {
+			t.Errorf("GET %s as tenant admin: status %d, want 404", path, status)
+		}
+	}
+
+	if status := send(http.MethodPost, "/admin/integrations/2/delete", url.Values{}); status != http.StatusNotFound {
+		t.Errorf("delete an integration of another tenant: status %d, want 404", status)
+	}
+}
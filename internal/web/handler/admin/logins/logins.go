@@ -0,0 +1,166 @@
+// Package logins provides the admin report of sign-in attempts across all
+// users, with failed and suspicious logins highlighted. Administrators of a
+// tenant see the attempts of the users of their tenant only.
+package logins
+
+import (
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/loginhistory"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the route of the login report.
+	Path = handler.RootPath + "admin/logins"
+
+	// TemplateName is the template of the login report.
+	TemplateName = "admin/logins/list"
+
+	// pageSize is the number of attempts per page.
+	pageSize = 50
+
+	// summaryWindow is the period the summary counts cover.
+	summaryWindow = 24 * time.Hour
+
+	errMsgLoad = "Failed to load the login history"
+)
+
+// Service provides the login report.
+type Service struct {
+	handler.Service
+	cfg *config.Config
+	db  *gorm.DB
+}
+
+// Handler is the exported singleton instance.
+var Handler = Service{}
+
+// Init registers the handler routes. Login attempts are audit data, so the
+// report shares the permission of the activity log.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermAdminActivityLog), s.List)
+}
+
+// List renders a page of login attempts matching the filters.
+func (s *Service) List(c fiber.Ctx) error {
+	filter := loginhistory.Filter{
+		Username:   strings.TrimSpace(c.Query("user")),
+		Failed:     c.Query("failed") != "",
+		Suspicious: c.Query("suspicious") != "",
+	}
+
+	page := max(fiber.Query[int](c, "page", 1), 1)
+	scope := visible(c)
+
+	var total int64
+	if err := loginhistory.Query(s.db.Scopes(scope), &filter).Count(&total).Error; err != nil {
+		log.Error().Err(err).Msg("failed to count login events")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errMsgLoad, nil)
+	}
+
+	totalPages := max(int((total+pageSize-1)/pageSize), 1)
+	page = min(page, totalPages)
+
+	var events []models.LoginEvent
+
+	err := loginhistory.Query(s.db.Scopes(scope), &filter).Order("created_at DESC, id DESC").
+		Limit(pageSize).Offset((page - 1) * pageSize).Find(&events).Error
+	if err != nil {
+		log.Error().Err(err).Msg("failed to query login events")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errMsgLoad, nil)
+	}
+
+	since := time.Now().Add(-summaryWindow)
+
+	var failed, suspicious int64
+
+	s.db.Model(&models.LoginEvent{}).Scopes(scope).
+		Where("success = ? AND created_at > ?", false, since).Count(&failed)
+	s.db.Model(&models.LoginEvent{}).Scopes(scope).
+		Where("flags <> '' AND created_at > ?", since).Count(&suspicious)
+
+	nav := navigation.NewContext("Logins", "admin", "logins").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb("Logins", Path, true)
+
+	return c.Render(TemplateName, fiber.Map{
+		"Navigation":       nav,
+		"LoginEvents":      events,
+		"ShowUser":         true,
+		"Filter":           filter,
+		"Total":            total,
+		"Page":             page,
+		"TotalPages":       totalPages,
+		"PrevQuery":        pageQuery(&filter, page-1),
+		"NextQuery":        pageQuery(&filter, page+1),
+		"HasPrev":          page > 1,
+		"HasNext":          page < totalPages,
+		"FailedRecent":     failed,
+		"SuspiciousRecent": suspicious,
+	}, handler.BaseLayout)
+}
+
+// visible limits a query on login events to those the current user may see:
+// the attempts of the users of their tenant, or all of them outside any
+// tenant. Failed attempts on unknown usernames belong to no tenant.
+func visible(c fiber.Ctx) func(*gorm.DB) *gorm.DB {
+	current, _ := c.Locals("CurrentUser").(models.User)
+
+	return func(tx *gorm.DB) *gorm.DB {
+		if current.TenantID == nil {
+			return tx
+		}
+
+		users := tx.Session(&gorm.Session{NewDB: true}).Model(&models.User{}).
+			Select("id").Scopes(tenant.Scope(current.TenantID))
+
+		return tx.Where("login_events.user_id IN (?)", users)
+	}
+}
+
+// pageQuery returns the query string of page with the filters of f.
+func pageQuery(f *loginhistory.Filter, page int) template.URL {
+	v := url.Values{}
+
+	if f.Username != "" {
+		v.Set("user", f.Username)
+	}
+
+	if f.Failed {
+		v.Set("failed", "1")
+	}
+
+	if f.Suspicious {
+		v.Set("suspicious", "1")
+	}
+
+	if page > 1 {
+		v.Set("page", strconv.Itoa(page))
+	}
+
+	return template.URL(v.Encode()) //nolint:gosec // server-built via url.Values.Encode()
+}
@@ -0,0 +1,92 @@
+package logins
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// recordingViews keeps the data of the last render.
+type recordingViews struct {
+	data fiber.Map
+}
+
+func (*recordingViews) Load() error { return nil }
+
+func (v *recordingViews) Render(w io.Writer, name string, data any, _ ...string) error {
+	v.data, _ = data.(fiber.Map)
+	_, _ = io.WriteString(w, name)
+
+	return nil
+}
+
+func TestListScopedToTenant(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Tenant{}, &models.User{}, &models.LoginEvent{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	acme, globex := models.Tenant{Name: "acme"}, models.Tenant{Name: "globex"}
+	db.Create(&acme)
+	db.Create(&globex)
+
+	users := []models.User{
+		{Username: "root", Email: "root@example.com"},
+		{Username: "acme-admin", Email: "admin@acme.example", TenantID: &acme.ID},
+		{Username: "globex-admin", Email: "admin@globex.example", TenantID: &globex.ID},
+	}
+	for i := range users {
+		db.Create(&users[i])
+		db.Create(&models.LoginEvent{UserID: &users[i].ID, Username: users[i].Username, Provider: "local",
+			Reason: "invalid password", CreatedAt: time.Now()})
+	}
+
+	db.Create(&models.LoginEvent{Username: "nobody", Provider: "local", Reason: "unknown user", CreatedAt: time.Now()})
+
+	for _, tt := range []struct {
+		viewer models.User
+		want   int64
+	}{
+		{users[0], 4},
+		{users[1], 1},
+	} {
+		views := &recordingViews{}
+		app := fiber.New(fiber.Config{Views: views})
+		svc := &Service{db: db}
+
+		app.Get(Path, func(c fiber.Ctx) error {
+			c.Locals("CurrentUser", tt.viewer)
+			return c.Next()
+		}, svc.List)
+
+		resp, err := app.Test(httptest.NewRequestWithContext(context.Background(), http.MethodGet, Path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = resp.Body.Close()
+
+		events, _ := views.data["LoginEvents"].([]models.LoginEvent)
+		if views.data["Total"] != tt.want || int64(len(events)) != tt.want || views.data["FailedRecent"] != tt.want {
+			t.Errorf("%s sees %v attempts, %d listed, %v failed, want %d",
+				tt.viewer.Username, views.data["Total"], len(events), views.data["FailedRecent"], tt.want)
+		}
+
+		if tt.viewer.TenantID != nil && len(events) == 1 && events[0].Username != tt.viewer.Username {
+			t.Errorf("%s sees the attempt of %s", tt.viewer.Username, events[0].Username)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Package maintenance implements the admin page that toggles maintenance mode.
+// While it is on, users without the admin.maintenance permission get a 503
+// maintenance page, e.g. during a PowerDNS upgrade.
+package maintenance
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/maintenance"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path to the maintenance mode page.
+	Path = handler.RootPath + "admin/maintenance"
+
+	// TemplateName is the name of the maintenance mode template.
+	TemplateName = "admin/maintenance/maintenance"
+)
+
+var errMessageTooLong = errors.New("the message must be at most 500 characters")
+
+// Service is the maintenance mode handler service.
+type Service struct {
+	handler.Service
+	db    *gorm.DB
+	store *controller.Store
+}
+
+// Handler is the maintenance mode handler.
+var Handler = Service{}
+
+// Init initializes the maintenance mode handler. The shared store is created
+// in the web service, which also enforces maintenance mode.
+func (s *Service) Init(
+	app *fiber.App,
+	cfg *config.Config,
+	db *gorm.DB,
+	authService *auth.Service,
+	store *controller.Store,
+) {
+	if app == nil || cfg == nil || db == nil || store == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.store = store
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermAdminMaintenance), s.Get)
+	app.Post(Path, auth.RequirePermission(authService, auth.PermAdminMaintenance), s.Post)
+}
+
+// Get renders the maintenance mode page.
+func (s *Service) Get(c fiber.Ctx) error {
+	state := s.store.State()
+
+	return s.render(c, fiber.StatusOK, state.Message, fiber.Map{
+		"Success": c.Query("success"),
+		"Error":   c.Query("error"),
+	})
+}
+
+// Post turns maintenance mode on (form field action "enable") or off
+// (anything else). Enabling again while it is on updates the message.
+func (s *Service) Post(c fiber.Ctx) error {
+	current := s.store.State()
+	enable := c.FormValue("action") == "enable"
+
+	message := strings.TrimSpace(c.FormValue("message"))
+	if !enable {
+		message = current.Message
+	}
+
+	if utf8.RuneCountInString(message) > controller.MaxMessageLength {
+		return s.render(c, fiber.StatusBadRequest, message, fiber.Map{"Error": errMessageTooLong.Error()})
+	}
+
+	state := controller.State{Message: message}
+
+	if enable {
+		state.Enabled = true
+		state.Since = current.Since
+		state.By = current.By
+
+		if !current.Enabled {
+			_, state.By = auth.Actor(c)
+			state.Since = time.Now().UTC()
+		}
+	}
+
+	if err := s.store.Set(&state); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Bool("enabled", enable).Msg("failed to save maintenance mode")
+
+		return s.render(c, fiber.StatusInternalServerError, message, fiber.Map{"Error": "Failed to save maintenance mode"})
+	}
+
+	success := "Maintenance message updated."
+
+	switch {
+	case enable && !current.Enabled:
+		success = "Maintenance mode enabled. Only users with the admin.maintenance permission can use the application."
+		s.record(c, activitylog.ActionMaintenanceEnabled, message)
+	case !enable && current.Enabled:
+		success = "Maintenance mode disabled."
+		s.record(c, activitylog.ActionMaintenanceDisabled, message)
+	case !enable:
+		success = "Maintenance mode is already off."
+	}
+
+	return c.Redirect().To(Path + "?success=" + url.QueryEscape(success))
+}
+
+// record writes a maintenance mode toggle to the activity log.
+func (s *Service) record(c fiber.Ctx, action, message string) {
+	requestid.Logger(c.Context()).Info().Str("action", action).Msg("maintenance mode toggled")
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeSystem,
+		ResourceName: controller.SettingKey,
+		Details:      map[string]any{"message": message},
+	}))
+}
+
+// render renders the maintenance mode page with message in the form, merging
+// any extra keys (e.g. Success/Error).
+func (s *Service) render(c fiber.Ctx, status int, message string, extra fiber.Map) error {
+	nav := navigation.NewContext("Maintenance Mode", "admin", "maintenance").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb("Maintenance Mode", Path, true)
+
+	data := fiber.Map{
+		"Navigation":     nav,
+		"State":          s.store.State(),
+		"Message":        message,
+		"DefaultMessage": controller.DefaultMessage,
+		"MaxLength":      controller.MaxMessageLength,
+	}
+
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	return c.Status(status).Render(TemplateName, data, handler.BaseLayout)
+}
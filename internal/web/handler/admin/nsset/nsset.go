@@ -0,0 +1,435 @@
+// Package nsset provides the admin pages that manage nameserver sets, the
+// zones assigned to them and the migration of zones from one set to another
+// (see internal/nsset).
+package nsset
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/nsset"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathList is the path for the nameserver set list.
+	PathList = handler.RootPath + "admin/nameserver-sets"
+	// PathNew is the path for creating a nameserver set.
+	PathNew = PathList + "/new"
+	// PathEdit is the path for editing a nameserver set.
+	PathEdit = PathList + "/:id/edit"
+	// PathDelete is the path for deleting a nameserver set.
+	PathDelete = PathList + "/:id/delete"
+	// PathZones is the path for the zones of a nameserver set.
+	PathZones = PathList + "/:id/zones"
+	// PathAssign is the path for assigning zones to a nameserver set.
+	PathAssign = PathList + "/:id/assign"
+	// PathMigrate is the path for moving the zones of a set to another set.
+	PathMigrate = PathList + "/:id/migrate"
+	// PathApply is the path for applying a set to its pending and failed
+	// zones again.
+	PathApply = PathList + "/:id/apply"
+
+	templateList  = "admin/nsset/list"
+	templateForm  = "admin/nsset/form"
+	templateZones = "admin/nsset/zones"
+
+	navSection    = "admin"
+	navSubsection = "nameserver-sets"
+
+	labelNameserverSets = "Nameserver Sets"
+	labelNewSet         = "New Nameserver Set"
+	labelEditSet        = "Edit Nameserver Set"
+
+	errSetNotFound     = "Nameserver set not found"
+	errInvalidFormData = "Invalid form data"
+)
+
+// Service is the nameserver set handler service.
+type Service struct {
+	handler.Service
+	db  *gorm.DB
+	cfg *config.Config
+	// start applies a set to its pending zones; nsset.Start runs it in the
+	// background.
+	start func(db *gorm.DB, setID uint, actor activitylog.Entry)
+}
+
+// Handler is the nameserver set handler.
+var Handler = Service{}
+
+// form is the submitted nameserver set form.
+type form struct {
+	Name        string `form:"name"`
+	Description string `form:"description"`
+	Nameservers string `form:"nameservers"`
+}
+
+// Init initializes the nameserver set handler.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.cfg = cfg
+	s.start = nsset.Start
+
+	perm := auth.RequirePermission(authService, auth.PermAdminNameserverSets)
+
+	app.Get(PathList, perm, s.List)
+	app.Get(PathNew, perm, s.New)
+	app.Post(PathNew, perm, s.Create)
+	app.Get(PathEdit, perm, s.Edit)
+	app.Post(PathEdit, perm, s.Update)
+	app.Post(PathDelete, perm, s.Delete)
+	app.Get(PathZones, perm, s.Zones)
+	app.Post(PathAssign, perm, s.Assign)
+	app.Post(PathMigrate, perm, s.Migrate)
+	app.Post(PathApply, perm, s.Apply)
+}
+
+// List renders the nameserver set list.
+func (s *Service) List(c fiber.Ctx) error {
+	nav := navigation.NewContext(labelNameserverSets, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelNameserverSets, PathList, true)
+
+	sets, err := nsset.List(s.db)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list nameserver sets")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load nameserver sets", nil)
+	}
+
+	counts, err := nsset.ZoneCounts(s.db)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to count the zones of nameserver sets")
+	}
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Sets":       sets,
+		"ZoneCounts": counts,
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// New renders the create nameserver set form.
+func (s *Service) New(c fiber.Ctx) error {
+	return s.renderForm(c, fiber.StatusOK, &models.NameserverSet{}, "")
+}
+
+// Create handles the create nameserver set form submission.
+func (s *Service) Create(c fiber.Ctx) error {
+	var in form
+	if err := c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	set := &models.NameserverSet{}
+	if msg := apply(set, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, set, msg)
+	}
+
+	if err := s.db.Create(set).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to create nameserver set")
+		return s.renderForm(c, fiber.StatusInternalServerError, set, "Failed to create nameserver set: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Nameserver set "+set.Name+" created."))
+}
+
+// Edit renders the edit nameserver set form.
+func (s *Service) Edit(c fiber.Ctx) error {
+	set, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	return s.renderForm(c, fiber.StatusOK, set, "")
+}
+
+// Update handles the edit nameserver set form submission. When the
+// nameservers change, the set is applied to all its zones again in the
+// background.
+func (s *Service) Update(c fiber.Ctx) error {
+	set, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	var in form
+	if err = c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	previous := set.Nameservers
+
+	if msg := apply(set, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, set, msg)
+	}
+
+	if err = s.db.Save(set).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to update nameserver set")
+		return s.renderForm(c, fiber.StatusInternalServerError, set, "Failed to update nameserver set: "+err.Error())
+	}
+
+	msg := "Nameserver set " + set.Name + " updated."
+
+	if set.Nameservers != previous {
+		if err = nsset.MarkPending(s.db, set.ID); err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Uint("set_id", set.ID).Msg("failed to mark the zones of a nameserver set pending")
+			return redirectError(c, PathList, "The set was saved, but its zones could not be updated: "+err.Error())
+		}
+
+		s.start(s.db, set.ID, *auth.Attribute(c, &activitylog.Entry{}))
+
+		msg += " Its zones are being updated."
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape(msg))
+}
+
+// Delete handles nameserver set deletion. Sets still assigned to zones are
+// kept.
+func (s *Service) Delete(c fiber.Ctx) error {
+	set, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	if err = nsset.Delete(s.db, set); errors.Is(err, nsset.ErrInUse) {
+		return redirectError(c, PathList, "Nameserver set "+set.Name+
+			" is assigned to zones. Migrate them to another set first.")
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to delete nameserver set")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Delete Failed",
+			"Failed to delete nameserver set", nil)
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Nameserver set "+set.Name+" deleted."))
+}
+
+// Zones renders the zones of a nameserver set with the outcome of applying
+// the set to each.
+func (s *Service) Zones(c fiber.Ctx) error {
+	set, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	zones, err := nsset.Zones(s.db, set.ID)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list the zones of a nameserver set")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load the zones of the nameserver set", nil)
+	}
+
+	sets, err := nsset.List(s.db)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list nameserver sets")
+	}
+
+	pending, failed := 0, 0
+
+	for i := range zones {
+		switch {
+		case zones[i].Error != "":
+			failed++
+		case zones[i].Pending():
+			pending++
+		}
+	}
+
+	nav := navigation.NewContext(set.Name, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelNameserverSets, PathList, false).
+		AddBreadcrumb(set.Name, "", true)
+
+	return c.Render(templateZones, fiber.Map{
+		"Navigation": nav,
+		"Set":        set,
+		"Sets":       sets,
+		"Zones":      zones,
+		"Pending":    pending,
+		"Failed":     failed,
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// Assign assigns the submitted zones to the set and applies it to them in
+// the background. Zones that do not exist in PowerDNS are dropped when the
+// set is applied.
+func (s *Service) Assign(c fiber.Ctx) error {
+	set, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	back := zonesPath(set.ID)
+
+	var zones []string
+
+	for field := range strings.FieldsFuncSeq(strings.ToLower(c.FormValue("zones")), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		if !strings.HasSuffix(field, ".") {
+			field += "."
+		}
+
+		zones = append(zones, field)
+	}
+
+	if len(zones) == 0 {
+		return redirectError(c, back, "Name at least one zone.")
+	}
+
+	for _, zone := range zones {
+		if err = nsset.Assign(s.db, zone, set.ID); err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zone).Msg("failed to assign nameserver set")
+			return redirectError(c, back, "Failed to assign "+zone+": "+err.Error())
+		}
+	}
+
+	s.start(s.db, set.ID, *auth.Attribute(c, &activitylog.Entry{}))
+
+	return c.Redirect().To(back + "?success=" + url.QueryEscape(
+		strconv.Itoa(len(zones))+" zones assigned to "+set.Name+"; their NS records are being updated."))
+}
+
+// Migrate moves all zones of the set to the submitted target set and applies
+// the target set to them in the background.
+func (s *Service) Migrate(c fiber.Ctx) error {
+	set, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	back := zonesPath(set.ID)
+
+	targetID, err := strconv.ParseUint(c.FormValue("target_id"), 10, 0)
+	if err != nil || uint(targetID) == set.ID {
+		return redirectError(c, back, "Choose another nameserver set to migrate the zones to.")
+	}
+
+	target, err := nsset.Get(s.db, uint(targetID))
+	if err != nil {
+		return redirectError(c, back, "The target nameserver set no longer exists.")
+	}
+
+	moved, err := nsset.Migrate(s.db, set.ID, target.ID)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint("set_id", set.ID).Msg("failed to migrate nameserver set")
+		return redirectError(c, back, "Failed to migrate the zones: "+err.Error())
+	}
+
+	s.start(s.db, target.ID, *auth.Attribute(c, &activitylog.Entry{}))
+
+	return c.Redirect().To(zonesPath(target.ID) + "?success=" + url.QueryEscape(strconv.FormatInt(moved, 10)+
+		" zones moved from "+set.Name+" to "+target.Name+"; their NS records are being updated."))
+}
+
+// Apply applies the set again to its pending and failed zones in the
+// background.
+func (s *Service) Apply(c fiber.Ctx) error {
+	set, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	s.start(s.db, set.ID, *auth.Attribute(c, &activitylog.Entry{}))
+
+	return c.Redirect().To(zonesPath(set.ID) + "?success=" + url.QueryEscape(
+		"Applying "+set.Name+" to its pending and failed zones."))
+}
+
+// load returns the nameserver set of the :id parameter, or renders the error
+// page and returns its result as the error.
+func (s *Service) load(c fiber.Ctx) (*models.NameserverSet, error) {
+	set, err := nsset.Get(s.db, fiber.Params[uint](c, "id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, errSetNotFound)
+	}
+
+	if err != nil {
+		return nil, handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load nameserver set", nil)
+	}
+
+	return set, nil
+}
+
+// apply validates the submitted form and copies it to set. It returns the
+// message of the first invalid field, or "".
+func apply(set *models.NameserverSet, in *form) string {
+	set.Name = strings.ToLower(strings.TrimSpace(in.Name))
+	set.Description = strings.TrimSpace(in.Description)
+	set.Nameservers = in.Nameservers
+
+	if set.Name == "" || len(set.Name) > 64 || strings.ContainsFunc(set.Name, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_'
+	}) {
+		return "Name must be 1 to 64 letters, digits, dashes or underscores, e.g. anycast-eu"
+	}
+
+	nameservers, err := nsset.Parse(in.Nameservers)
+	if err != nil {
+		return "Nameservers: " + err.Error()
+	}
+
+	set.Nameservers = strings.Join(nameservers, "\n")
+
+	return ""
+}
+
+// renderForm renders the nameserver set form; set.ID is 0 for a new one.
+func (s *Service) renderForm(c fiber.Ctx, status int, set *models.NameserverSet, errMsg string) error {
+	title := labelEditSet
+	if set.ID == 0 {
+		title = labelNewSet
+	}
+
+	nav := navigation.NewContext(title, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelNameserverSets, PathList, false).
+		AddBreadcrumb(title, "", true)
+
+	return c.Status(status).Render(templateForm, fiber.Map{
+		"Navigation": nav,
+		"Set":        set,
+		"IsCreate":   set.ID == 0,
+		"Error":      errMsg,
+	}, handler.BaseLayout)
+}
+
+// zonesPath returns the path of the zones of the set id.
+func zonesPath(id uint) string {
+	return strings.Replace(PathZones, ":id", strconv.FormatUint(uint64(id), 10), 1)
+}
+
+// redirectError redirects to back with msg as the error message.
+func redirectError(c fiber.Ctx, back, msg string) error {
+	return c.Redirect().To(back + "?error=" + url.QueryEscape(msg))
+}
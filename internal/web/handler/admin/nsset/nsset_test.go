@@ -0,0 +1,151 @@
+package nsset
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/nsset"
+)
+
+// recordingViews renders the template name and keeps the data of the last
+// render.
+type recordingViews struct {
+	data fiber.Map
+}
+
+func (*recordingViews) Load() error { return nil }
+
+func (v *recordingViews) Render(w io.Writer, name string, data any, _ ...string) error {
+	v.data, _ = data.(fiber.Map)
+	_, _ = io.WriteString(w, name)
+
+	return nil
+}
+
+func newTestService(t *testing.T) (*fiber.App, *gorm.DB, *[]uint) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.NameserverSet{}, &models.ZoneNameserverSet{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	var started []uint
+
+	svc := &Service{db: db, start: func(_ *gorm.DB, setID uint, _ activitylog.Entry) {
+		started = append(started, setID)
+	}}
+
+	app := fiber.New(fiber.Config{Views: &recordingViews{}})
+	app.Post(PathNew, svc.Create)
+	app.Post(PathEdit, svc.Update)
+	app.Post(PathDelete, svc.Delete)
+	app.Post(PathMigrate, svc.Migrate)
+
+	return app, db, &started
+}
+
+func post(t *testing.T, app *fiber.App, path string, form url.Values) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp
+}
+
+func TestCreateAndUpdate(t *testing.T) {
+	app, db, started := newTestService(t)
+
+	resp := post(t, app, PathNew, url.Values{"name": {"Bad Name"}, "nameservers": {"ns1.example.net"}})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("create with an invalid name: status %d, want 400", resp.StatusCode)
+	}
+
+	resp = post(t, app, PathNew, url.Values{"name": {"Anycast-EU"}, "nameservers": {"NS1.example.net\nns2.example.net."}})
+	if resp.StatusCode != http.StatusSeeOther && resp.StatusCode != http.StatusFound {
+		t.Fatalf("create: status %d", resp.StatusCode)
+	}
+
+	set, err := nsset.Get(db, 1)
+	if err != nil || set.Name != "anycast-eu" || set.Nameservers != "ns1.example.net.\nns2.example.net." {
+		t.Fatalf("created set = %+v, %v", set, err)
+	}
+
+	// Saving the same nameservers leaves the zones alone.
+	post(t, app, "/admin/nameserver-sets/1/edit", url.Values{"name": {"anycast-eu"}, "description": {"EU"},
+		"nameservers": {"ns1.example.net. ns2.example.net."}})
+
+	if len(*started) != 0 {
+		t.Errorf("started = %v after an unchanged update, want none", *started)
+	}
+
+	post(t, app, "/admin/nameserver-sets/1/edit", url.Values{"name": {"anycast-eu"}, "nameservers": {"ns3.example.net"}})
+
+	if len(*started) != 1 || (*started)[0] != 1 {
+		t.Errorf("started = %v after changing the nameservers, want set 1", *started)
+	}
+}
+
+func TestMigrateAndDelete(t *testing.T) {
+	app, db, started := newTestService(t)
+
+	legacy := &models.NameserverSet{Name: "legacy", Nameservers: "ns1.example.net."}
+	anycast := &models.NameserverSet{Name: "anycast-eu", Nameservers: "a.anycast.example."}
+	db.Create(legacy)
+	db.Create(anycast)
+
+	if err := nsset.Assign(db, "example.com.", legacy.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := post(t, app, "/admin/nameserver-sets/1/delete", nil)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "error=") {
+		t.Errorf("delete of an assigned set redirected to %q, want an error", loc)
+	}
+
+	resp = post(t, app, "/admin/nameserver-sets/1/migrate", url.Values{"target_id": {"1"}})
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "error=") {
+		t.Errorf("migrate to the same set redirected to %q, want an error", loc)
+	}
+
+	resp = post(t, app, "/admin/nameserver-sets/1/migrate", url.Values{"target_id": {"2"}})
+	if loc := resp.Header.Get("Location"); !strings.HasPrefix(loc, "/admin/nameserver-sets/2/zones?success=") {
+		t.Errorf("migrate redirected to %q, want the zones of the target set", loc)
+	}
+
+	if assignment, _ := nsset.Assigned(db, "example.com."); assignment == nil || assignment.SetID != anycast.ID {
+		t.Errorf("assignment after migrate = %+v, want set %d", assignment, anycast.ID)
+	}
+
+	if len(*started) != 1 || (*started)[0] != anycast.ID {
+		t.Errorf("started = %v, want the target set", *started)
+	}
+
+	resp = post(t, app, "/admin/nameserver-sets/1/delete", nil)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "success=") {
+		t.Errorf("delete of an unused set redirected to %q, want success", loc)
+	}
+}
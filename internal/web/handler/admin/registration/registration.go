@@ -0,0 +1,231 @@
+// Package registration provides the review queue for self-registered local
+// accounts: user administrators approve a registration with a role, or deny
+// it, and the registrant is notified by email.
+package registration
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/mailer"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the base path of the review queue.
+	Path = handler.RootPath + "admin/registrations"
+
+	// TemplateList is the template of the review queue.
+	TemplateList = "admin/registration/list"
+
+	labelRegistrations = "Registrations"
+
+	errFailedLoadRegistrations = "Failed to load registrations"
+	errInvalidRegistrationID   = "Invalid registration ID"
+)
+
+// Service handles the registration review queue.
+type Service struct {
+	handler.Service
+	cfg       *config.Config
+	db        *gorm.DB
+	localAuth *auth.LocalProvider
+	mailer    mailer.Sender
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.localAuth = auth.NewLocalProvider(db)
+
+	if cfg.Mail.Enabled() {
+		s.mailer = mailer.New(&cfg.Mail)
+	}
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermAdminUsers), s.List)
+	app.Post(Path+"/:id/approve", auth.RequirePermission(authService, auth.PermAdminUsers), s.Approve)
+	app.Post(Path+"/:id/deny", auth.RequirePermission(authService, auth.PermAdminUsers), s.Deny)
+}
+
+// List renders the pending registrations with a role selection each,
+// preselected with the configured registration role.
+func (s *Service) List(c fiber.Ctx) error {
+	return s.renderList(c, fiber.StatusOK, "")
+}
+
+// Approve activates a pending account with the selected role and notifies
+// the registrant. Administrators of a tenant only approve the registrations
+// of their tenant, with the roles they may grant.
+func (s *Service) Approve(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return s.renderList(c, fiber.StatusBadRequest, errInvalidRegistrationID)
+	}
+
+	reviewer := currentUser(c)
+
+	var role models.Role
+	if err := s.db.Scopes(auth.GrantableRoles(reviewer.TenantID)).
+		Where("id = ?", c.FormValue("role_id")).First(&role).Error; err != nil {
+		return s.renderList(c, fiber.StatusBadRequest, "Select the role of the new user.")
+	}
+
+	user, err := s.localAuth.ApproveRegistration(id, role.ID, reviewer.TenantID)
+	if errors.Is(err, auth.ErrRegistrationNotPending) {
+		return s.renderList(c, fiber.StatusConflict, "This registration has already been reviewed.")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", id).Msg("failed to approve registration")
+		return s.renderList(c, fiber.StatusInternalServerError, "Failed to approve the registration.")
+	}
+
+	s.record(c, activitylog.ActionUserApproved, user, map[string]any{"user_id": user.ID, "role": role.Name})
+
+	s.notify(c, user, true)
+
+	return c.Redirect().To(Path + "?success=" + url.QueryEscape("Registration of "+user.Username+" approved."))
+}
+
+// Deny deletes a pending account and notifies the registrant.
+func (s *Service) Deny(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return s.renderList(c, fiber.StatusBadRequest, errInvalidRegistrationID)
+	}
+
+	user, err := s.localAuth.DenyRegistration(id, currentUser(c).TenantID)
+	if errors.Is(err, auth.ErrRegistrationNotPending) {
+		return s.renderList(c, fiber.StatusConflict, "This registration has already been reviewed.")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", id).Msg("failed to deny registration")
+		return s.renderList(c, fiber.StatusInternalServerError, "Failed to deny the registration.")
+	}
+
+	s.record(c, activitylog.ActionUserDenied, user, map[string]any{"user_id": user.ID, "email": user.Email})
+
+	s.notify(c, user, false)
+
+	return c.Redirect().To(Path + "?success=" + url.QueryEscape("Registration of "+user.Username+" denied."))
+}
+
+// renderList renders the review queue with an optional error.
+func (s *Service) renderList(c fiber.Ctx, status int, errorMsg string) error {
+	reviewer := currentUser(c)
+
+	users, err := s.localAuth.PendingRegistrations(reviewer.TenantID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load registrations")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadRegistrations, nil)
+	}
+
+	var roles []models.Role
+	if err := s.db.Scopes(auth.GrantableRoles(reviewer.TenantID)).Order("name ASC").Find(&roles).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load roles")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadRegistrations, nil)
+	}
+
+	nav := navigation.NewContext(labelRegistrations, "admin", "user").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb("Users", "/admin/user", false).
+		AddBreadcrumb(labelRegistrations, Path, true)
+
+	return c.Status(status).Render(TemplateList, fiber.Map{
+		"Navigation": nav,
+		"Users":      users,
+		"Roles":      roles,
+		"Enabled":    s.cfg.Auth.LocalDB.Registration,
+		"Success":    c.Query("success"),
+		"Error":      errorMsg,
+	}, handler.BaseLayout)
+}
+
+// record writes an activity log entry for a review of user.
+func (s *Service) record(c fiber.Ctx, action string, user *models.User, details map[string]any) {
+	reviewer := currentUser(c)
+	reviewerID := reviewer.ID
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &reviewerID,
+		Username:     reviewer.Username,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeUser,
+		ResourceName: user.Username,
+		Details:      details,
+		IPAddress:    c.IP(),
+	}))
+}
+
+// currentUser returns the reviewer of the request.
+func currentUser(c fiber.Ctx) models.User {
+	user, _ := c.Locals("CurrentUser").(models.User)
+
+	return user
+}
+
+// notify emails the registrant the outcome of the review in the background.
+func (s *Service) notify(c fiber.Ctx, user *models.User, approved bool) {
+	if s.mailer == nil || user.Email == "" {
+		return
+	}
+
+	brand, ok := c.Locals("Brand").(config.Branding)
+	if !ok {
+		brand = s.cfg.Branding.Resolve(s.cfg.Title)
+	}
+
+	var subject, body string
+
+	if approved {
+		subject = brand.Name + ": account approved"
+		body = fmt.Sprintf(`Hello %s,
+
+your account %q was approved. You can sign in at:
+
+%s
+`, user.FullName(), user.Username, strings.TrimRight(s.cfg.Webserver.URL, "/")+"/login")
+	} else {
+		subject = brand.Name + ": registration denied"
+		body = fmt.Sprintf(`Hello %s,
+
+your registration of the account %q was denied by an administrator.
+`, user.FullName(), user.Username)
+	}
+
+	to := user.Email
+	jobs.Go(jobs.Mail, func() error {
+		err := s.mailer.Send(to, subject, body)
+		if err != nil {
+			log.Error().Err(err).Str("to", to).Msg("failed to send registration email")
+		}
+
+		return err
+	})
+}
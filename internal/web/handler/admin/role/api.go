@@ -0,0 +1,125 @@
+package role
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// APIPath is the JSON role list.
+const APIPath = handler.APIPathPrefix + "admin/roles"
+
+// apiColumns are the sort keys of the JSON role list.
+var apiColumns = handler.ListColumns{
+	"id":         "id",
+	"name":       "name",
+	"system":     "is_system",
+	"updated_at": "updated_at",
+}
+
+// APIRole is a row of the JSON role list.
+type APIRole struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	System      bool      `json:"system"`
+	Permissions int64     `json:"permissions"`
+	Users       int64     `json:"users"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ListJSON returns a page of roles with their permission and user counts,
+// searched by name and description and optionally filtered by a name
+// ?prefix= and ?modified_since=.
+func (s *Service) ListJSON(c fiber.Ctx) error {
+	req, err := handler.ParseListRequest(c, apiColumns, "name", false)
+	if err != nil {
+		return handler.ListError(c, err)
+	}
+
+	var roles []models.Role
+
+	resp, err := req.Find(s.db, &models.Role{}, func(tx *gorm.DB) *gorm.DB {
+		if req.Search != "" {
+			tx = tx.Scopes(like.Contains(req.Search, "name", "description"))
+		}
+
+		return tx.Scopes(req.Scope("name", "updated_at"))
+	}, &roles)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("query roles failed")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, "failed to load roles", nil)
+	}
+
+	permCounts, userCounts := s.counts(roles)
+
+	rows := make([]APIRole, len(roles))
+	for i, r := range roles {
+		rows[i] = APIRole{
+			ID:          r.ID,
+			Name:        r.Name,
+			Description: r.Description,
+			System:      r.IsSystem,
+			Permissions: permCounts[r.ID],
+			Users:       userCounts[r.ID],
+			UpdatedAt:   r.UpdatedAt,
+		}
+	}
+
+	if resp.Data, err = handler.SelectFields(req.Fields, rows); err != nil {
+		return handler.ListError(c, err)
+	}
+
+	return c.JSON(resp)
+}
+
+// counts returns the permission and user counts of roles, keyed by role ID,
+// with one query each. Failures are logged and leave the maps empty.
+func (s *Service) counts(roles []models.Role) (map[uint]int64, map[uint]int64) {
+	permCounts := make(map[uint]int64, len(roles))
+	userCounts := make(map[uint]int64, len(roles))
+
+	if len(roles) == 0 {
+		return permCounts, userCounts
+	}
+
+	ids := make([]uint, len(roles))
+	for i, r := range roles {
+		ids[i] = r.ID
+	}
+
+	for _, q := range []struct {
+		model  any
+		counts map[uint]int64
+	}{
+		{&models.RolePermission{}, permCounts},
+		{&models.User{}, userCounts},
+	} {
+		var rows []struct {
+			RoleID uint
+			Count  int64
+		}
+
+		if err := s.db.Model(q.model).
+			Select("role_id, COUNT(*) AS count").
+			Where("role_id IN ?", ids).
+			Group("role_id").
+			Scan(&rows).Error; err != nil {
+			log.Error().Err(err).Msg("count role members failed")
+			continue
+		}
+
+		for _, row := range rows {
+			q.counts[row.RoleID] = row.Count
+		}
+	}
+
+	return permCounts, userCounts
+}
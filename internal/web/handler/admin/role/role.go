@@ -4,6 +4,7 @@ package role
 import (
 	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
@@ -80,10 +81,20 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 		auth.RequirePermission(authService, auth.PermAdminRoles),
 		s.Delete,
 	)
+	app.Get(APIPath,
+		auth.RequirePermission(authService, auth.PermAdminRoles),
+		s.ListJSON,
+	)
 }
 
 // List shows all roles with their permission counts and user counts.
 func (s *Service) List(c fiber.Ctx) error {
+	return s.renderList(c, fiber.StatusOK, "")
+}
+
+// renderList renders the role list with the given status and an optional
+// error banner, so rejected deletes keep showing the roles table.
+func (s *Service) renderList(c fiber.Ctx, status int, errMsg string) error {
 	nav := navigation.NewContext("Roles", "admin", "role").
 		AddBreadcrumb("Home", dashboard.Path, false).
 		AddBreadcrumb("Admin", "#", false).
@@ -99,27 +110,14 @@ func (s *Service) List(c fiber.Ctx) error {
 		}, handler.BaseLayout)
 	}
 
-	// Load permission counts and user counts per role.
-	permCounts := make(map[uint]int64)
-	userCounts := make(map[uint]int64)
-
-	for _, r := range roles {
-		var pc int64
-		if err := s.db.Model(&models.RolePermission{}).Where("role_id = ?", r.ID).Count(&pc).Error; err == nil {
-			permCounts[r.ID] = pc
-		}
+	permCounts, userCounts := s.counts(roles)
 
-		var uc int64
-		if err := s.db.Model(&models.User{}).Where("role_id = ?", r.ID).Count(&uc).Error; err == nil {
-			userCounts[r.ID] = uc
-		}
-	}
-
-	return c.Render(TemplateList, fiber.Map{
+	return c.Status(status).Render(TemplateList, fiber.Map{
 		"Navigation": nav,
 		"Roles":      roles,
 		"PermCounts": permCounts,
 		"UserCounts": userCounts,
+		"Error":      errMsg,
 	}, handler.BaseLayout)
 }
 
@@ -158,8 +156,10 @@ func (s *Service) Create(c fiber.Ctx) error {
 		AddBreadcrumb("New", Path+"/new", true)
 
 	var in struct {
-		Name        string `form:"name"        validate:"required,min=1,max=100"`
-		Description string `form:"description" validate:"max=255"`
+		Name        string `form:"name"         validate:"required,min=1,max=100"`
+		Description string `form:"description"  validate:"max=255"`
+		RecordTypes string `form:"record_types" validate:"max=255"`
+		Zones       string `form:"zones"        validate:"max=2048"`
 	}
 
 	if err := c.Bind().Body(&in); err != nil {
@@ -170,13 +170,20 @@ func (s *Service) Create(c fiber.Ctx) error {
 		}, handler.BaseLayout)
 	}
 
-	if err := s.validator.Struct(in); err != nil {
+	zones, err := auth.ParseZonePatterns(in.Zones)
+	if err == nil {
+		err = s.validator.Struct(in)
+	}
+
+	if err != nil {
 		permissions, _ := s.loadPermissions() //nolint:errcheck // best-effort; permissions may be empty on DB error
 
 		return c.Status(fiber.StatusBadRequest).Render(TemplateForm, fiber.Map{
-			"Navigation":       nav,
-			"Error":            "Validation failed: " + err.Error(),
-			"Role":             models.Role{Name: in.Name, Description: in.Description},
+			"Navigation": nav,
+			"Error":      "Validation failed: " + err.Error(),
+			"Role": models.Role{
+				Name: in.Name, Description: in.Description, RecordTypes: in.RecordTypes, Zones: in.Zones,
+			},
 			"IsCreate":         true,
 			"Permissions":      permissions,
 			"PermissionGroups": groupPermissions(permissions),
@@ -187,6 +194,8 @@ func (s *Service) Create(c fiber.Ctx) error {
 	role := models.Role{
 		Name:        in.Name,
 		Description: in.Description,
+		RecordTypes: models.NormalizeRecordTypes(in.RecordTypes),
+		Zones:       strings.Join(zones, "\n"),
 	}
 
 	tx := s.db.Begin()
@@ -310,8 +319,10 @@ func (s *Service) Update(c fiber.Ctx) error {
 	}
 
 	var in struct {
-		Name        string `form:"name"        validate:"required,min=1,max=100"`
-		Description string `form:"description" validate:"max=255"`
+		Name        string `form:"name"         validate:"required,min=1,max=100"`
+		Description string `form:"description"  validate:"max=255"`
+		RecordTypes string `form:"record_types" validate:"max=255"`
+		Zones       string `form:"zones"        validate:"max=2048"`
 	}
 
 	if err := c.Bind().Body(&in); err != nil {
@@ -323,9 +334,16 @@ func (s *Service) Update(c fiber.Ctx) error {
 		}, handler.BaseLayout)
 	}
 
-	if err := s.validator.Struct(in); err != nil {
+	zones, err := auth.ParseZonePatterns(in.Zones)
+	if err == nil {
+		err = s.validator.Struct(in)
+	}
+
+	if err != nil {
 		permissions, _ := s.loadPermissions() //nolint:errcheck // best-effort; permissions may be empty on DB error
 
+		role.Zones = in.Zones
+
 		return c.Status(fiber.StatusBadRequest).Render(TemplateForm, fiber.Map{
 			"Navigation":       nav,
 			"Error":            "Validation failed: " + err.Error(),
@@ -343,13 +361,16 @@ func (s *Service) Update(c fiber.Ctx) error {
 	}
 
 	role.Description = in.Description
+	role.RecordTypes = models.NormalizeRecordTypes(in.RecordTypes)
+	role.Zones = strings.Join(zones, "\n")
 
 	selectedPerms := s.parseSelectedPermIDs(c)
 
 	return s.commitRoleUpdate(c, nav, &role, selectedPerms)
 }
 
-// Delete removes a role.
+// Delete removes a custom role together with its permission assignments and
+// group mappings. System roles and roles still assigned to users are kept.
 func (s *Service) Delete(c fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil || id <= 0 {
@@ -357,54 +378,41 @@ func (s *Service) Delete(c fiber.Ctx) error {
 	}
 
 	var role models.Role
-	if err := s.db.First(&role, id).Error; err != nil {
+	if err = s.db.First(&role, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Redirect().To(Path)
 		}
 
-		return c.Status(fiber.StatusInternalServerError).Render(TemplateList, fiber.Map{
-			"Error": "Failed to load role",
-		}, handler.BaseLayout)
+		return s.renderList(c, fiber.StatusInternalServerError, "Failed to load role")
 	}
 
 	if role.IsSystem {
-		nav := navigation.NewContext("Roles", "admin", "role").
-			AddBreadcrumb("Home", dashboard.Path, false).
-			AddBreadcrumb("Admin", "#", false).
-			AddBreadcrumb("Roles", Path, true)
-
-		return c.Status(fiber.StatusForbidden).Render(TemplateList, fiber.Map{
-			"Navigation": nav,
-			"Error":      "Cannot delete system roles",
-		}, handler.BaseLayout)
+		return s.renderList(c, fiber.StatusForbidden, "Cannot delete system roles")
 	}
 
 	// Prevent deletion if users are still assigned to this role.
 	var userCount int64
-	if err := s.db.Model(&models.User{}).Where("role_id = ?", id).Count(&userCount).Error; err == nil && userCount > 0 {
-		nav := navigation.NewContext("Roles", "admin", "role").
-			AddBreadcrumb("Home", dashboard.Path, false).
-			AddBreadcrumb("Admin", "#", false).
-			AddBreadcrumb("Roles", Path, true)
-
-		return c.Status(fiber.StatusBadRequest).Render(TemplateList, fiber.Map{
-			"Navigation": nav,
-			"Error":      "Cannot delete role: it is still assigned to users",
-		}, handler.BaseLayout)
+	if err = s.db.Model(&models.User{}).Where("role_id = ?", id).Count(&userCount).Error; err == nil && userCount > 0 {
+		return s.renderList(c, fiber.StatusBadRequest, "Cannot delete role: it is still assigned to users")
 	}
 
-	if err := s.db.Delete(&models.Role{}, id).Error; err != nil {
-		log.Error().Err(err).Msg("failed to delete role")
+	// Remove dependents explicitly: SQLite only enforces ON DELETE CASCADE when
+	// foreign keys are enabled on the connection.
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", id).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
 
-		nav := navigation.NewContext("Roles", "admin", "role").
-			AddBreadcrumb("Home", dashboard.Path, false).
-			AddBreadcrumb("Admin", "#", false).
-			AddBreadcrumb("Roles", Path, true)
+		if err := tx.Where("role_id = ?", id).Delete(&models.GroupMapping{}).Error; err != nil {
+			return err
+		}
 
-		return c.Status(fiber.StatusInternalServerError).Render(TemplateList, fiber.Map{
-			"Navigation": nav,
-			"Error":      "Failed to delete role",
-		}, handler.BaseLayout)
+		return tx.Delete(&models.Role{}, id).Error
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete role")
+
+		return s.renderList(c, fiber.StatusInternalServerError, "Failed to delete role")
 	}
 
 	return c.Redirect().To(Path)
@@ -499,7 +507,13 @@ func (s *Service) renderDemoAdminBlock(c fiber.Ctx, nav *navigation.Context, rol
 type PermissionGroup struct {
 	Resource    string
 	Icon        string
-	Permissions []models.Permission
+	Permissions []PermissionEntry
+}
+
+// PermissionEntry is a permission together with the routes it guards.
+type PermissionEntry struct {
+	models.Permission
+	Routes []auth.GuardedRoute
 }
 
 // loadPermissions returns all permissions ordered by resource then action.
@@ -513,26 +527,31 @@ func (s *Service) loadPermissions() ([]models.Permission, error) {
 }
 
 // groupPermissions groups a flat permission list by resource, with a Bootstrap-
-// icon class chosen per resource name.
+// icon class chosen per resource name, and attaches the routes each permission
+// guards.
 func groupPermissions(perms []models.Permission) []PermissionGroup {
+	routes := auth.PermissionRoutes()
+
 	icons := map[string]string{
 		"admin":     "bi-shield-lock",
 		"zone":      "bi-globe",
 		"dashboard": "bi-speedometer2",
 		"server":    "bi-server",
+		"profile":   "bi-person-circle",
+		"tools":     "bi-tools",
 	}
 
 	// Preserve insertion order using a slice of keys.
 	var keys []string
 
-	byResource := make(map[string][]models.Permission)
+	byResource := make(map[string][]PermissionEntry)
 
 	for _, p := range perms {
 		if _, seen := byResource[p.Resource]; !seen {
 			keys = append(keys, p.Resource)
 		}
 
-		byResource[p.Resource] = append(byResource[p.Resource], p)
+		byResource[p.Resource] = append(byResource[p.Resource], PermissionEntry{Permission: p, Routes: routes[p.Name]})
 	}
 
 	groups := make([]PermissionGroup, 0, len(keys))
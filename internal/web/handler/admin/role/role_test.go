@@ -0,0 +1,262 @@
+package role
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// captureViews is a minimal Fiber Views engine that records the last render.
+type captureViews struct {
+	mu       sync.Mutex
+	lastData any
+}
+
+func (v *captureViews) Load() error { return nil }
+
+func (v *captureViews) Render(w io.Writer, name string, data any, _ ...string) error {
+	v.mu.Lock()
+	v.lastData = data
+	v.mu.Unlock()
+
+	_, _ = io.WriteString(w, name)
+
+	return nil
+}
+
+func newTestService(t *testing.T) (*fiber.App, *Service, *captureViews) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(
+		&models.Role{}, &models.Permission{}, &models.RolePermission{},
+		&models.User{}, &models.Group{}, &models.GroupMapping{},
+	); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	views := &captureViews{}
+	app := fiber.New(fiber.Config{Views: views})
+	svc := &Service{cfg: &config.Config{}, db: db}
+
+	// Register routes directly — bypasses permission middleware for unit tests.
+	app.Get(Path, svc.List)
+	app.Post(Path+"/:id/delete", svc.Delete)
+	app.Get(APIPath, svc.ListJSON)
+
+	return app, svc, views
+}
+
+func postDelete(t *testing.T, app *fiber.App, id uint) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		Path+"/"+strconv.FormatUint(uint64(id), 10)+"/delete", http.NoBody)
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+
+	return resp
+}
+
+func TestDelete_SystemRoleIsProtected(t *testing.T) {
+	app, svc, views := newTestService(t)
+
+	role := models.Role{Name: "viewer", IsSystem: true}
+	svc.db.Create(&role)
+
+	resp := postDelete(t, app, role.ID)
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", resp.StatusCode)
+	}
+
+	var count int64
+	svc.db.Model(&models.Role{}).Where("id = ?", role.ID).Count(&count)
+
+	if count != 1 {
+		t.Error("system role was deleted")
+	}
+
+	m, ok := views.lastData.(fiber.Map)
+	if !ok {
+		t.Fatalf("render data is %T, want fiber.Map", views.lastData)
+	}
+
+	if roles, _ := m["Roles"].([]models.Role); len(roles) != 1 {
+		t.Errorf("error page should still list roles, got %v", m["Roles"])
+	}
+}
+
+func TestDelete_RoleInUse(t *testing.T) {
+	app, svc, _ := newTestService(t)
+
+	role := models.Role{Name: "dns-operators"}
+	svc.db.Create(&role)
+	svc.db.Create(&models.User{Username: "jdoe", Email: "jdoe@example.com", RoleID: role.ID})
+
+	resp := postDelete(t, app, role.ID)
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestDelete_CustomRoleRemovesDependents(t *testing.T) {
+	app, svc, _ := newTestService(t)
+
+	role := models.Role{Name: "dns-operators"}
+	svc.db.Create(&role)
+
+	perm := models.Permission{Name: "zone.read", Resource: "zone", Action: "read"}
+	svc.db.Create(&perm)
+	svc.db.Create(&models.RolePermission{RoleID: role.ID, PermissionID: perm.ID})
+
+	group := models.Group{Name: "ops", ExternalID: "ops", Source: models.GroupSourceLocal}
+	svc.db.Create(&group)
+	svc.db.Create(&models.GroupMapping{GroupID: group.ID, RoleID: role.ID})
+
+	resp := postDelete(t, app, role.ID)
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusSeeOther && resp.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want redirect", resp.StatusCode)
+	}
+
+	for _, m := range []any{&models.Role{}, &models.RolePermission{}, &models.GroupMapping{}} {
+		var count int64
+		svc.db.Model(m).Count(&count)
+
+		if count != 0 {
+			t.Errorf("%T: %d rows left after delete", m, count)
+		}
+	}
+}
+
+func TestGroupPermissions(t *testing.T) {
+	groups := groupPermissions([]models.Permission{
+		{Name: "admin.roles", Resource: "admin"},
+		{Name: "zone.read", Resource: "zone"},
+		{Name: "admin.users", Resource: "admin"},
+		{Name: "custom.thing", Resource: "custom"},
+	})
+
+	if len(groups) != 3 {
+		t.Fatalf("len(groups) = %d, want 3", len(groups))
+	}
+
+	if groups[0].Resource != "admin" || len(groups[0].Permissions) != 2 || groups[0].Icon != "bi-shield-lock" {
+		t.Errorf("groups[0] = %+v", groups[0])
+	}
+
+	if groups[2].Icon != "bi-key" {
+		t.Errorf("unknown resource icon = %q, want bi-key", groups[2].Icon)
+	}
+}
+
+func TestListJSON(t *testing.T) {
+	app, svc, _ := newTestService(t)
+
+	perm := models.Permission{Name: "zone.read", Resource: "zone", Action: "read"}
+	svc.db.Create(&perm)
+
+	for _, name := range []string{"operators", "admin", "DNS-Viewers"} {
+		role := models.Role{Name: name, Description: name + " role"}
+		svc.db.Create(&role)
+		svc.db.Create(&models.RolePermission{RoleID: role.ID, PermissionID: perm.ID})
+		svc.db.Create(&models.User{Username: "u-" + name, Email: name + "@example.com", RoleID: role.ID})
+	}
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		APIPath+"?search=dns&sort=name&order=desc", http.NoBody)
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var got struct {
+		handler.ListResponse
+		Data []APIRole `json:"data"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if got.RecordsTotal != 3 || got.RecordsFiltered != 1 || len(got.Data) != 1 {
+		t.Fatalf("response = %+v", got)
+	}
+
+	if r := got.Data[0]; r.Name != "DNS-Viewers" || r.Permissions != 1 || r.Users != 1 {
+		t.Errorf("row = %+v", r)
+	}
+
+	req = httptest.NewRequestWithContext(context.Background(), http.MethodGet, APIPath+"?sort=zones", http.NoBody)
+
+	resp, err = app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown sort: status = %d, want 400", resp.StatusCode)
+	}
+
+	req = httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		APIPath+"?prefix=OP&fields=name,users&modified_since=2000-01-01", http.NoBody)
+
+	resp, err = app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var sparse struct {
+		Data []map[string]any `json:"data"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&sparse); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(sparse.Data) != 1 || len(sparse.Data[0]) != 2 || sparse.Data[0]["name"] != "operators" {
+		t.Errorf("sparse rows = %v, want the name and users of operators", sparse.Data)
+	}
+
+	req = httptest.NewRequestWithContext(context.Background(), http.MethodGet, APIPath+"?fields=password", http.NoBody)
+
+	resp, err = app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown field: status = %d, want 400", resp.StatusCode)
+	}
+}
@@ -0,0 +1,252 @@
+// Package app implements the admin GUI for the runtime application settings:
+// overrides of the log level, session lifetime and local login options that
+// take effect without a restart. The log level and sampling can also be read
+// and changed through a JSON endpoint, e.g. by the loglevel command.
+package app
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/appsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/authsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path to the application settings page.
+	Path = handler.AppSettingsPath
+
+	// APIPath is the path of the logging endpoint.
+	APIPath = handler.APIPathPrefix + "admin/logging"
+
+	// TemplateName is the name of the application settings template.
+	TemplateName = "admin/settings/app"
+
+	// minSessionExpiry is the shortest accepted session lifetime.
+	minSessionExpiry = 5 * time.Minute
+
+	// minResetTokenTTL is the shortest accepted password reset link lifetime.
+	minResetTokenTTL = time.Minute
+)
+
+// LogLevels are the log levels offered on the form.
+var LogLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+var (
+	errInvalidLogLevel      = errors.New("log level must be one of trace, debug, info, warn or error")
+	errInvalidLogSampling   = errors.New("debug log sampling must be a whole number, 0 keeps every message")
+	errInvalidSessionExpiry = errors.New("session expiry must be a duration of at least 5m, e.g. 12h")
+	errInvalidIdleTimeout   = errors.New("idle timeout must be 0 (off) or a duration of at least 5m, e.g. 1h")
+	errInvalidResetTokenTTL = errors.New("reset link lifetime must be a duration of at least 1m, e.g. 1h")
+	errNoLoginMethod        = errors.New("local login can only be disabled when LDAP or OIDC login is enabled")
+)
+
+// Service is the application settings handler service.
+type Service struct {
+	handler.Service
+	cfg   *config.Config
+	db    *gorm.DB
+	store *controller.Store
+}
+
+// Handler is the application settings handler.
+var Handler = Service{}
+
+// Init initializes the application settings handler. The shared store is
+// created in the web service, which also applies the settings.
+func (s *Service) Init(
+	app *fiber.App,
+	cfg *config.Config,
+	db *gorm.DB,
+	authService *auth.Service,
+	store *controller.Store,
+) {
+	if app == nil || cfg == nil || db == nil || store == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.cfg = cfg
+	s.store = store
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermAdminSettings), s.Get)
+	app.Post(Path, auth.RequirePermission(authService, auth.PermAdminSettings), s.Post)
+	app.Get(APIPath, auth.RequirePermission(authService, auth.PermAdminSettings), s.GetLogging)
+	app.Post(APIPath, auth.RequirePermission(authService, auth.PermAdminSettings), s.PostLogging)
+}
+
+// Get renders the application settings form.
+func (s *Service) Get(c fiber.Ctx) error {
+	return c.Render(TemplateName, s.viewData(nil, nil), handler.BaseLayout)
+}
+
+// Post handles the application settings form submission. Fields whose
+// override box is unchecked fall back to the config files.
+func (s *Service) Post(c fiber.Ctx) error {
+	o, err := s.parseForm(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).
+			Render(TemplateName, s.viewData(o, fiber.Map{"Error": err.Error()}), handler.BaseLayout)
+	}
+
+	if err := o.Save(s.db); err != nil {
+		log.Error().Err(err).Msg("failed to save application settings")
+
+		return c.Status(fiber.StatusInternalServerError).
+			Render(TemplateName, s.viewData(o, fiber.Map{"Error": "Failed to save settings"}), handler.BaseLayout)
+	}
+
+	if err := s.store.Reload(); err != nil {
+		log.Error().Err(err).Msg("failed to reload application settings after save")
+	}
+
+	log.Info().Msg("application settings saved successfully")
+
+	return c.Render(TemplateName, s.viewData(nil, fiber.Map{"Success": "Settings saved successfully"}), handler.BaseLayout)
+}
+
+// parseForm reads and validates the submitted overrides. On error the parsed
+// overrides are returned as well so the form can be re-rendered.
+func (s *Service) parseForm(c fiber.Ctx) (*controller.Overrides, error) {
+	var (
+		o    controller.Overrides
+		errs []error
+	)
+
+	if c.FormValue("override_log_level") != "" {
+		level := strings.ToLower(strings.TrimSpace(c.FormValue("log_level")))
+		o.LogLevel = &level
+
+		errs = append(errs, validateLogLevel(level))
+	}
+
+	if c.FormValue("override_log_sampling") != "" {
+		n, err := parseLogSampling(c.FormValue("log_sampling"))
+		o.LogSampling = &n
+
+		errs = append(errs, err)
+	}
+
+	if c.FormValue("override_session_expiry") != "" {
+		d, err := parseDuration(c.FormValue("session_expiry"), minSessionExpiry, errInvalidSessionExpiry)
+		o.SessionExpiry = &d
+
+		errs = append(errs, err)
+	}
+
+	if c.FormValue("override_session_idle_timeout") != "" {
+		d, err := parseIdleTimeout(c.FormValue("session_idle_timeout"))
+		o.SessionIdleTimeout = &d
+
+		errs = append(errs, err)
+	}
+
+	if c.FormValue("override_local_login") != "" {
+		enabled := c.FormValue("local_login") == "on"
+		o.LocalLogin = &enabled
+	}
+
+	if c.FormValue("override_password_reset") != "" {
+		enabled := c.FormValue("password_reset") == "on"
+		o.PasswordReset = &enabled
+	}
+
+	if c.FormValue("override_reset_token_ttl") != "" {
+		d, err := parseDuration(c.FormValue("reset_token_ttl"), minResetTokenTTL, errInvalidResetTokenTTL)
+		o.ResetTokenTTL = &d
+
+		errs = append(errs, err)
+	}
+
+	resolved := o.Apply(s.store.Base())
+	providers := authsettings.Current(s.cfg)
+
+	if !resolved.LocalLogin && !providers.LDAP.Enabled && !providers.OIDC.Enabled {
+		errs = append(errs, errNoLoginMethod)
+	}
+
+	return &o, errors.Join(errs...)
+}
+
+// validateLogLevel checks that level is one of LogLevels.
+func validateLogLevel(level string) error {
+	if !slices.Contains(LogLevels, level) {
+		return errInvalidLogLevel
+	}
+
+	return nil
+}
+
+// parseLogSampling parses the debug log sampling, a non-negative number.
+func parseLogSampling(value string) (uint32, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, errInvalidLogSampling
+	}
+
+	return uint32(n), nil
+}
+
+// parseDuration parses a Go duration of at least lowest.
+func parseDuration(value string, lowest time.Duration, errInvalid error) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || d < lowest {
+		return d, errInvalid
+	}
+
+	return d, nil
+}
+
+// parseIdleTimeout parses the session idle timeout: 0 turns it off, any other
+// value must be at least minSessionExpiry.
+func parseIdleTimeout(value string) (time.Duration, error) {
+	if v := strings.TrimSpace(value); v == "0" || v == "off" {
+		return 0, nil
+	}
+
+	return parseDuration(value, minSessionExpiry, errInvalidIdleTimeout)
+}
+
+// viewData builds the template payload, merging any extra keys (e.g. Success/Error).
+func (s *Service) viewData(overrides *controller.Overrides, extra fiber.Map) fiber.Map {
+	if overrides == nil {
+		o := s.store.Overrides()
+		overrides = &o
+	}
+
+	base := s.store.Base()
+
+	nav := navigation.NewContext("Application", "settings", "app").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Settings", "", false).
+		AddBreadcrumb("Application", Path, true)
+
+	data := fiber.Map{
+		"Navigation": nav,
+		"Overrides":  overrides,
+		"Base":       base,
+		"Form":       overrides.Apply(base),
+		"Effective":  s.store.Settings(),
+		"LogLevels":  LogLevels,
+		"Watching":   !s.cfg.ConfigWatch.Disabled && s.cfg.Path != "",
+	}
+
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	return data
+}
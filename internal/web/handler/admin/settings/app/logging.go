@@ -0,0 +1,98 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// Logging is the JSON body returned by the logging endpoint.
+type Logging struct {
+	// Level and Sampling are in effect.
+	Level    string `json:"level"`
+	Sampling uint32 `json:"sampling"`
+	// ConfigLevel and ConfigSampling are the values of the config files,
+	// used again after a reset.
+	ConfigLevel    string `json:"config_level"`
+	ConfigSampling uint32 `json:"config_sampling"`
+	// Overridden reports whether the level or the sampling is overridden.
+	Overridden bool `json:"overridden"`
+}
+
+// LoggingChange is the JSON body accepted by the logging endpoint. Omitted
+// fields keep their value; Reset drops both overrides before the others are
+// applied.
+type LoggingChange struct {
+	Level    *string `json:"level"`
+	Sampling *uint32 `json:"sampling"`
+	Reset    bool    `json:"reset"`
+}
+
+// GetLogging returns the log level and sampling.
+func (s *Service) GetLogging(c fiber.Ctx) error {
+	return c.JSON(s.logging())
+}
+
+// PostLogging overrides the log level and sampling like the settings form;
+// the change applies to all replicas without a restart.
+func (s *Service) PostLogging(c fiber.Ctx) error {
+	var in LoggingChange
+	if err := c.Bind().JSON(&in); err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "invalid JSON body", nil)
+	}
+
+	o := s.store.Overrides()
+
+	if in.Reset {
+		o.LogLevel, o.LogSampling = nil, nil
+	}
+
+	if in.Level != nil {
+		level := strings.ToLower(strings.TrimSpace(*in.Level))
+		if err := validateLogLevel(level); err != nil {
+			return handler.JSONError(c, fiber.StatusUnprocessableEntity, handler.CodeValidation, err.Error(),
+				fiber.Map{"field": "level"})
+		}
+
+		o.LogLevel = &level
+	}
+
+	if in.Sampling != nil {
+		o.LogSampling = in.Sampling
+	}
+
+	logger := requestid.Logger(c.Context())
+
+	if err := o.Save(s.db); err != nil {
+		logger.Error().Err(err).Msg("failed to save log settings")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, "failed to save settings", nil)
+	}
+
+	if err := s.store.Reload(); err != nil {
+		logger.Error().Err(err).Msg("failed to reload application settings after save")
+	}
+
+	_, username := auth.Actor(c)
+	got := s.logging()
+
+	logger.Warn().Str("user", username).Str("level", got.Level).Uint32("sampling", got.Sampling).
+		Msg("log settings changed")
+
+	return c.JSON(got)
+}
+
+func (s *Service) logging() Logging {
+	base, effective, o := s.store.Base(), s.store.Settings(), s.store.Overrides()
+
+	return Logging{
+		Level:          effective.LogLevel,
+		Sampling:       effective.LogSampling,
+		ConfigLevel:    base.LogLevel,
+		ConfigSampling: base.LogSampling,
+		Overridden:     o.LogLevel != nil || o.LogSampling != nil,
+	}
+}
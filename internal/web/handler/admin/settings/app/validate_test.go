@@ -0,0 +1,79 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateLogLevel(t *testing.T) {
+	for _, level := range LogLevels {
+		if err := validateLogLevel(level); err != nil {
+			t.Errorf("validateLogLevel(%q) = %v, want nil", level, err)
+		}
+	}
+
+	for _, level := range []string{"", "verbose", "panic", "disabled"} {
+		if err := validateLogLevel(level); !errors.Is(err, errInvalidLogLevel) {
+			t.Errorf("validateLogLevel(%q) = %v, want errInvalidLogLevel", level, err)
+		}
+	}
+}
+
+func TestParseLogSampling(t *testing.T) {
+	for value, want := range map[string]uint32{"0": 0, " 100 ": 100} {
+		if got, err := parseLogSampling(value); err != nil || got != want {
+			t.Errorf("parseLogSampling(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "-1", "1.5", "often", "4294967296"} {
+		if _, err := parseLogSampling(value); !errors.Is(err, errInvalidLogSampling) {
+			t.Errorf("parseLogSampling(%q) = %v, want errInvalidLogSampling", value, err)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"12h", 12 * time.Hour, false},
+		{" 30m ", 30 * time.Minute, false},
+		{"5m", 5 * time.Minute, false},
+		{"4m59s", 0, true},
+		{"", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value, minSessionExpiry, errInvalidSessionExpiry)
+		if tt.wantErr {
+			if !errors.Is(err, errInvalidSessionExpiry) {
+				t.Errorf("parseDuration(%q) error = %v, want errInvalidSessionExpiry", tt.value, err)
+			}
+
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseIdleTimeout(t *testing.T) {
+	for value, want := range map[string]time.Duration{"0": 0, " off ": 0, "1h": time.Hour, "5m": 5 * time.Minute} {
+		if got, err := parseIdleTimeout(value); err != nil || got != want {
+			t.Errorf("parseIdleTimeout(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "30s", "-1h"} {
+		if _, err := parseIdleTimeout(value); !errors.Is(err, errInvalidIdleTimeout) {
+			t.Errorf("parseIdleTimeout(%q) error = %v, want errInvalidIdleTimeout", value, err)
+		}
+	}
+}
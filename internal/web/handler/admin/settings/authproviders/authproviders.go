@@ -0,0 +1,338 @@
+// Package authproviders implements the admin GUI for the LDAP and OIDC login
+// providers. A provider saved here replaces the [auth.ldap] or [auth.oidc]
+// section of the config file and takes effect without a restart; the form
+// can test unsaved settings against the directory or identity provider.
+package authproviders
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/appsettings"
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/authsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path to the authentication settings page.
+	Path = handler.AuthSettingsPath
+
+	// PathTestLDAP tests the LDAP settings of the form.
+	PathTestLDAP = Path + "/test/ldap"
+
+	// PathTestOIDC tests the OIDC settings of the form.
+	PathTestOIDC = Path + "/test/oidc"
+
+	// TemplateName is the name of the authentication settings template.
+	TemplateName = "admin/settings/auth"
+
+	// discoveryTimeout bounds the OIDC discovery request of a test.
+	discoveryTimeout = 10 * time.Second
+)
+
+var (
+	errInvalidPort     = errors.New("LDAP port must be a number between 1 and 65535")
+	errInvalidTimeout  = errors.New("LDAP timeout must be a number of seconds")
+	errNoLoginMethod   = errors.New("LDAP and OIDC can only both be disabled while local login is enabled")
+	errMissingPassword = errors.New("enter the password of the test user")
+)
+
+// Service is the authentication settings handler service.
+type Service struct {
+	handler.Service
+	cfg   *config.Config
+	db    *gorm.DB
+	store *controller.Store
+}
+
+// Handler is the authentication settings handler.
+var Handler = Service{}
+
+// Init initializes the authentication settings handler. The shared store is
+// created in the web service, whose login handlers follow it.
+func (s *Service) Init(
+	app *fiber.App,
+	cfg *config.Config,
+	db *gorm.DB,
+	authService *auth.Service,
+	store *controller.Store,
+) {
+	if app == nil || cfg == nil || db == nil || store == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.cfg = cfg
+	s.store = store
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermAdminSettings), s.Get)
+	app.Post(Path, auth.RequirePermission(authService, auth.PermAdminSettings), s.Post)
+	app.Post(PathTestLDAP, auth.RequirePermission(authService, auth.PermAdminSettings), s.TestLDAP)
+	app.Post(PathTestOIDC, auth.RequirePermission(authService, auth.PermAdminSettings), s.TestOIDC)
+}
+
+// Get renders the authentication settings form.
+func (s *Service) Get(c fiber.Ctx) error {
+	return c.Render(TemplateName, s.viewData(nil, nil), handler.BaseLayout)
+}
+
+// Post handles the authentication settings form submission. A provider whose
+// override box is unchecked falls back to the config files.
+func (s *Service) Post(c fiber.Ctx) error {
+	o, err := s.parseForm(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).
+			Render(TemplateName, s.viewData(o, fiber.Map{"Error": err.Error()}), handler.BaseLayout)
+	}
+
+	if err := o.Save(s.db); err != nil {
+		log.Error().Err(err).Msg("failed to save authentication settings")
+
+		return c.Status(fiber.StatusInternalServerError).
+			Render(TemplateName, s.viewData(o, fiber.Map{"Error": "Failed to save settings"}), handler.BaseLayout)
+	}
+
+	if err := s.store.Reload(); err != nil {
+		log.Error().Err(err).Msg("failed to reload authentication settings after save")
+	}
+
+	effective := s.store.Settings()
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		Action:       activitylog.ActionAuthSettingsChanged,
+		ResourceType: activitylog.ResourceTypeAuth,
+		ResourceName: controller.SettingKey,
+		Details: map[string]any{
+			"ldap_override": o.LDAP != nil,
+			"ldap_enabled":  effective.LDAP.Enabled,
+			"oidc_override": o.OIDC != nil,
+			"oidc_enabled":  effective.OIDC.Enabled,
+		},
+	}))
+
+	log.Info().Msg("authentication settings saved successfully")
+
+	return c.Render(TemplateName, s.viewData(nil, fiber.Map{"Success": "Settings saved successfully"}), handler.BaseLayout)
+}
+
+// TestLDAP connects to the directory of the submitted LDAP settings and binds
+// with the service account. With a test username it signs in as that user
+// instead and returns the user's groups. Nothing is saved.
+func (s *Service) TestLDAP(c fiber.Ctx) error {
+	settings, err := parseLDAP(c, s.store.Settings().LDAP)
+	settings.Enabled = true
+
+	if err == nil {
+		err = settings.Validate()
+	}
+
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, err.Error(), nil)
+	}
+
+	provider, err := auth.NewLDAPProvider(auth.NewLDAPConfig(&settings), s.db)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, err.Error(), nil)
+	}
+
+	username := strings.TrimSpace(c.FormValue("ldap_test_username"))
+	if username == "" {
+		if err := provider.TestConnection(); err != nil {
+			return handler.JSONError(c, fiber.StatusBadGateway, handler.CodeUpstream, err.Error(), nil)
+		}
+
+		return c.JSON(fiber.Map{"ok": true, "message": "Connected to " + settings.Host + "."})
+	}
+
+	password := c.FormValue("ldap_test_password")
+	if password == "" {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, errMissingPassword.Error(), nil)
+	}
+
+	groups, err := provider.TestLogin(username, password)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadGateway, handler.CodeUpstream, err.Error(), nil)
+	}
+
+	return c.JSON(fiber.Map{"ok": true, "message": "Signed in as " + username + ".", "groups": groups})
+}
+
+// TestOIDC fetches the discovery document of the submitted OIDC settings.
+// Nothing is saved.
+func (s *Service) TestOIDC(c fiber.Ctx) error {
+	settings := parseOIDC(c, s.store.Settings().OIDC)
+	settings.Enabled = true
+
+	if err := settings.Validate(); err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, err.Error(), nil)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), discoveryTimeout)
+	defer cancel()
+
+	if _, err := auth.NewOIDCProvider(ctx, auth.NewOIDCConfig(&settings), s.db); err != nil {
+		return handler.JSONError(c, fiber.StatusBadGateway, handler.CodeUpstream, err.Error(), nil)
+	}
+
+	return c.JSON(fiber.Map{"ok": true, "message": "Discovered " + settings.ProviderURL + "."})
+}
+
+// parseForm reads and validates the submitted providers. On error the parsed
+// overrides are returned as well so the form can be re-rendered.
+func (s *Service) parseForm(c fiber.Ctx) (*controller.Overrides, error) {
+	var (
+		o       controller.Overrides
+		errs    []error
+		current = s.store.Settings()
+	)
+
+	if c.FormValue("override_ldap") != "" {
+		ldap, err := parseLDAP(c, current.LDAP)
+		o.LDAP = &ldap
+
+		if err == nil {
+			err = ldap.Validate()
+		}
+
+		errs = append(errs, err)
+	}
+
+	if c.FormValue("override_oidc") != "" {
+		oidc := parseOIDC(c, current.OIDC)
+		o.OIDC = &oidc
+
+		errs = append(errs, oidc.Validate())
+	}
+
+	resolved := o.Apply(s.store.Base())
+	if !resolved.LDAP.Enabled && !resolved.OIDC.Enabled && !appsettings.Current(s.cfg).LocalLogin {
+		errs = append(errs, errNoLoginMethod)
+	}
+
+	return &o, errors.Join(errs...)
+}
+
+// parseLDAP reads the LDAP fields of the form. A blank bind password keeps the
+// one of current.
+func parseLDAP(c fiber.Ctx, current config.LDAPAuth) (config.LDAPAuth, error) {
+	var errs []error
+
+	ldap := config.LDAPAuth{
+		Enabled:         c.FormValue("ldap_enabled") == "on",
+		Host:            strings.TrimSpace(c.FormValue("ldap_host")),
+		UseSSL:          c.FormValue("ldap_use_ssl") == "on",
+		UseTLS:          c.FormValue("ldap_use_tls") == "on",
+		SkipVerify:      c.FormValue("ldap_skip_verify") == "on",
+		BindDN:          strings.TrimSpace(c.FormValue("ldap_bind_dn")),
+		BindPassword:    c.FormValue("ldap_bind_password"),
+		BaseDN:          strings.TrimSpace(c.FormValue("ldap_base_dn")),
+		UserFilter:      strings.TrimSpace(c.FormValue("ldap_user_filter")),
+		GroupBaseDN:     strings.TrimSpace(c.FormValue("ldap_group_base_dn")),
+		GroupFilter:     strings.TrimSpace(c.FormValue("ldap_group_filter")),
+		GroupMemberAttr: strings.TrimSpace(c.FormValue("ldap_group_member_attr")),
+		UsernameAttr:    strings.TrimSpace(c.FormValue("ldap_username_attr")),
+		EmailAttr:       strings.TrimSpace(c.FormValue("ldap_email_attr")),
+		FirstNameAttr:   strings.TrimSpace(c.FormValue("ldap_first_name_attr")),
+		LastNameAttr:    strings.TrimSpace(c.FormValue("ldap_last_name_attr")),
+		GroupNameAttr:   strings.TrimSpace(c.FormValue("ldap_group_name_attr")),
+		SearchAttrs:     splitList(c.FormValue("ldap_search_attrs")),
+	}
+
+	if ldap.BindPassword == "" {
+		ldap.BindPassword = current.BindPassword
+	}
+
+	if raw := strings.TrimSpace(c.FormValue("ldap_port")); raw != "" {
+		port, err := strconv.Atoi(raw)
+		if err != nil || port < 1 || port > 65535 {
+			errs = append(errs, errInvalidPort)
+		}
+
+		ldap.Port = port
+	}
+
+	if raw := strings.TrimSpace(c.FormValue("ldap_timeout")); raw != "" {
+		timeout, err := strconv.Atoi(raw)
+		if err != nil || timeout < 0 {
+			errs = append(errs, errInvalidTimeout)
+		}
+
+		ldap.Timeout = timeout
+	}
+
+	return ldap, errors.Join(errs...)
+}
+
+// parseOIDC reads the OIDC fields of the form. A blank client secret keeps the
+// one of current.
+func parseOIDC(c fiber.Ctx, current config.OIDCAuth) config.OIDCAuth {
+	oidc := config.OIDCAuth{
+		Enabled:      c.FormValue("oidc_enabled") == "on",
+		ProviderURL:  strings.TrimSpace(c.FormValue("oidc_provider_url")),
+		ClientID:     strings.TrimSpace(c.FormValue("oidc_client_id")),
+		ClientSecret: c.FormValue("oidc_client_secret"),
+		RedirectURL:  strings.TrimSpace(c.FormValue("oidc_redirect_url")),
+		Scopes:       splitList(c.FormValue("oidc_scopes")),
+		GroupsClaim:  strings.TrimSpace(c.FormValue("oidc_groups_claim")),
+		AcceptBearer: c.FormValue("oidc_accept_bearer") == "on",
+	}
+
+	if oidc.ClientSecret == "" {
+		oidc.ClientSecret = current.ClientSecret
+	}
+
+	return oidc
+}
+
+// splitList splits a list separated by commas or whitespace.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// viewData builds the template payload, merging any extra keys (e.g. Success/Error).
+func (s *Service) viewData(overrides *controller.Overrides, extra fiber.Map) fiber.Map {
+	if overrides == nil {
+		o := s.store.Overrides()
+		overrides = &o
+	}
+
+	base := s.store.Base()
+
+	nav := navigation.NewContext("Authentication", "settings", "auth").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Settings", "", false).
+		AddBreadcrumb("Authentication", Path, true)
+
+	data := fiber.Map{
+		"Navigation": nav,
+		"Overrides":  overrides,
+		"Base":       base,
+		"Form":       overrides.Apply(base),
+		"Effective":  s.store.Settings(),
+		"TestLDAP":   PathTestLDAP,
+		"TestOIDC":   PathTestOIDC,
+		"Watching":   !s.cfg.ConfigWatch.Disabled && s.cfg.Path != "",
+	}
+
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	return data
+}
@@ -0,0 +1,114 @@
+package authproviders
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+// submit posts form to a route that runs parse with the request.
+func submit(t *testing.T, form url.Values, parse func(c fiber.Ctx)) {
+	t.Helper()
+
+	app := fiber.New()
+	app.Post("/", func(c fiber.Ctx) error {
+		parse(c)
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	_ = resp.Body.Close()
+}
+
+func TestParseLDAP(t *testing.T) {
+	current := config.LDAPAuth{BindPassword: "saved-secret"}
+
+	var (
+		got config.LDAPAuth
+		err error
+	)
+
+	submit(t, url.Values{
+		"ldap_enabled":      {"on"},
+		"ldap_host":         {" ldap.example.com "},
+		"ldap_port":         {"636"},
+		"ldap_use_ssl":      {"on"},
+		"ldap_base_dn":      {"dc=example,dc=com"},
+		"ldap_search_attrs": {"memberOf, uid\tcn"},
+	}, func(c fiber.Ctx) { got, err = parseLDAP(c, current) })
+
+	if err != nil {
+		t.Fatalf("parseLDAP() error = %v", err)
+	}
+
+	if got.Host != "ldap.example.com" || got.Port != 636 || !got.UseSSL || got.UseTLS {
+		t.Errorf("parseLDAP() = %+v", got)
+	}
+
+	if got.BindPassword != "saved-secret" {
+		t.Errorf("blank bind password = %q, want the saved one", got.BindPassword)
+	}
+
+	if !slices.Equal(got.SearchAttrs, []string{"memberOf", "uid", "cn"}) {
+		t.Errorf("search attrs = %v", got.SearchAttrs)
+	}
+
+	submit(t, url.Values{
+		"ldap_port":          {"70000"},
+		"ldap_timeout":       {"soon"},
+		"ldap_bind_password": {"new-secret"},
+	}, func(c fiber.Ctx) { got, err = parseLDAP(c, current) })
+
+	if !errors.Is(err, errInvalidPort) || !errors.Is(err, errInvalidTimeout) {
+		t.Errorf("parseLDAP() error = %v, want errInvalidPort and errInvalidTimeout", err)
+	}
+
+	if got.BindPassword != "new-secret" {
+		t.Errorf("bind password = %q, want new-secret", got.BindPassword)
+	}
+}
+
+func TestParseOIDC(t *testing.T) {
+	var got config.OIDCAuth
+
+	submit(t, url.Values{
+		"oidc_enabled":       {"on"},
+		"oidc_provider_url":  {"https://sso.example.com"},
+		"oidc_client_id":     {"dns"},
+		"oidc_redirect_url":  {"https://dns.example.com/auth/oidc/callback"},
+		"oidc_scopes":        {"openid profile,groups"},
+		"oidc_accept_bearer": {"on"},
+	}, func(c fiber.Ctx) { got = parseOIDC(c, config.OIDCAuth{ClientSecret: "saved-secret"}) })
+
+	if got.ClientSecret != "saved-secret" {
+		t.Errorf("blank client secret = %q, want the saved one", got.ClientSecret)
+	}
+
+	if !slices.Equal(got.Scopes, []string{"openid", "profile", "groups"}) || !got.AcceptBearer {
+		t.Errorf("parseOIDC() = %+v", got)
+	}
+
+	if err := got.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+
+	got.RedirectURL = ""
+	if err := got.Validate(); !errors.Is(err, config.ErrOIDCMissingRedirectURL) {
+		t.Errorf("Validate() = %v, want ErrOIDCMissingRedirectURL", err)
+	}
+}
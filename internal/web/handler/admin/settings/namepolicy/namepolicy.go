@@ -0,0 +1,144 @@
+package namepolicy
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the URL path for the name policies settings page.
+	Path = handler.RootPath + "admin/settings/name-policies"
+
+	// TemplateName is the template used for this page.
+	TemplateName = "admin/settings/name-policies"
+)
+
+// Service is the name policies settings handler.
+type Service struct {
+	handler.Service
+	db *gorm.DB
+}
+
+// Handler is the singleton handler instance.
+var Handler = Service{}
+
+// Init registers the routes.
+func (s *Service) Init(app *fiber.App, _ *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+
+	app.Get(Path,
+		auth.RequirePermission(authService, auth.PermAdminZoneRecords),
+		s.Get,
+	)
+	app.Post(Path,
+		auth.RequirePermission(authService, auth.PermAdminZoneRecords),
+		s.Post,
+	)
+}
+
+func newNav() *navigation.Context {
+	return navigation.NewContext("Name Policies", "settings", "name-policies").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Settings", "#", false).
+		AddBreadcrumb("Name Policies", Path, true)
+}
+
+// Get renders the name policies settings page.
+func (s *Service) Get(c fiber.Ctx) error {
+	return s.render(c, LoadRules(s.db), "", "")
+}
+
+// render renders the page with rules, and an Error or Success message when
+// kind is set.
+func (s *Service) render(c fiber.Ctx, rules []Rule, kind, message string) error {
+	data := fiber.Map{
+		"Navigation": newNav(),
+		"Rules":      rules,
+		"Operations": Operations,
+	}
+	if kind != "" {
+		data[kind] = message
+	}
+
+	return c.Render(TemplateName, data, handler.BaseLayout)
+}
+
+// Post handles add and delete actions.
+func (s *Service) Post(c fiber.Ctx) error {
+	settings := &Settings{}
+	if err := settings.Load(s.db); err != nil {
+		settings.Rules = nil
+	}
+
+	switch c.FormValue("action") {
+	case "add":
+		rule := Rule{
+			Pattern:    c.FormValue("pattern"),
+			Types:      strings.FieldsFunc(c.FormValue("types"), isTypeSeparator),
+			Operations: formValues(c, "operations"),
+			Reason:     c.FormValue("reason"),
+		}
+
+		if err := rule.Normalize(); err != nil {
+			return s.render(c, settings.Rules, "Error", "Invalid name policy: "+err.Error()+".")
+		}
+
+		for _, r := range settings.Rules {
+			rule.ID = max(rule.ID, r.ID)
+		}
+
+		rule.ID++
+		settings.Rules = append(settings.Rules, rule)
+
+	case "delete":
+		id, err := strconv.ParseUint(c.FormValue("id"), 10, 64)
+		if err != nil {
+			return c.Redirect().To(Path)
+		}
+
+		settings.Rules = slices.DeleteFunc(settings.Rules, func(r Rule) bool { return r.ID == id })
+
+	default:
+		return c.Redirect().To(Path)
+	}
+
+	if err := settings.Save(s.db); err != nil {
+		log.Error().Err(err).Msg("failed to save name policies")
+
+		return s.render(c, settings.Rules, "Error", "Failed to save settings.")
+	}
+
+	return s.render(c, settings.Rules, "Success", "Name policies saved.")
+}
+
+// isTypeSeparator splits the record types of the form at commas and spaces.
+func isTypeSeparator(r rune) bool {
+	return r == ',' || r == ' '
+}
+
+// formValues returns all values of the form field key, e.g. of checkboxes.
+func formValues(c fiber.Ctx, key string) []string {
+	var values []string
+
+	for _, v := range c.Request().PostArgs().PeekMulti(key) {
+		values = append(values, string(v))
+	}
+
+	return values
+}
@@ -0,0 +1,144 @@
+// Package namepolicy provides the name policies of the zone editor: rules,
+// set by administrators, that block creating, changing or deleting the
+// records of certain names, e.g. autodiscover or the NS records of the zone
+// apex, for users without the zone.policy_override permission.
+package namepolicy
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
+)
+
+// SettingKey is the database key for the name policy settings.
+const SettingKey = "zone_name_policies"
+
+// Operations on an RRset a rule can block.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Operations lists the operations in the order they are shown.
+var Operations = []string{OpCreate, OpUpdate, OpDelete}
+
+var (
+	errEmptyPattern = errors.New("enter a name or pattern")
+	errBadPattern   = errors.New("the pattern is not valid")
+	errNoOperation  = errors.New("select at least one operation to block")
+)
+
+// Rule blocks Operations on the RRsets whose name matches Pattern.
+type Rule struct {
+	// ID identifies the rule on the settings page.
+	ID uint64 `json:"id"`
+	// Pattern matches the name relative to the zone, lower-cased: "@" for the
+	// apex, a name such as "autodiscover", or a shell pattern such as
+	// "_dmarc*" where * also matches dots.
+	Pattern string `json:"pattern"`
+	// Types limits the rule to these record types; empty for all types.
+	Types []string `json:"types,omitempty"`
+	// Operations are the blocked operations, see OpCreate.
+	Operations []string `json:"operations"`
+	// Reason is shown to users the rule blocks.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Settings holds the configured rules.
+type Settings struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Load loads the name policies from the database.
+func (s *Settings) Load(db *gorm.DB) error {
+	return setting.GetJSON(db, SettingKey, s)
+}
+
+// Save persists the name policies to the database.
+func (s *Settings) Save(db *gorm.DB) error {
+	return setting.SetJSON(db, SettingKey, s)
+}
+
+// LoadRules returns the configured rules, none when the setting does not
+// exist yet or cannot be read.
+func LoadRules(db *gorm.DB) []Rule {
+	var s Settings
+	if err := s.Load(db); err != nil {
+		return nil
+	}
+
+	return s.Rules
+}
+
+// Normalize lower-cases the pattern, upper-cases the types and orders the
+// operations of r, and checks that the rule is complete.
+func (r *Rule) Normalize() error {
+	r.Pattern = strings.ToLower(strings.TrimSpace(r.Pattern))
+	if r.Pattern == "" {
+		return errEmptyPattern
+	}
+
+	if _, err := path.Match(r.Pattern, ""); err != nil {
+		return errBadPattern
+	}
+
+	types := make([]string, 0, len(r.Types))
+	for _, t := range r.Types {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+
+	slices.Sort(types)
+	r.Types = slices.Compact(types)
+
+	ops := make([]string, 0, len(Operations))
+	for _, op := range Operations {
+		if slices.Contains(r.Operations, op) {
+			ops = append(ops, op)
+		}
+	}
+
+	if len(ops) == 0 {
+		return errNoOperation
+	}
+
+	r.Operations = ops
+	r.Reason = strings.TrimSpace(r.Reason)
+
+	return nil
+}
+
+// Matches reports whether r blocks op on the RRset of rrType named name,
+// relative to the zone ("@" for the apex).
+func (r *Rule) Matches(name, rrType, op string) bool {
+	if !slices.Contains(r.Operations, op) {
+		return false
+	}
+
+	if len(r.Types) > 0 && !slices.Contains(r.Types, strings.ToUpper(rrType)) {
+		return false
+	}
+
+	// Names never contain "/", so * of path.Match also matches dots.
+	ok, err := path.Match(r.Pattern, strings.ToLower(name))
+
+	return err == nil && ok
+}
+
+// Find returns the first rule of rules that blocks op on the RRset, or nil.
+func Find(rules []Rule, name, rrType, op string) *Rule {
+	for i := range rules {
+		if rules[i].Matches(name, rrType, op) {
+			return &rules[i]
+		}
+	}
+
+	return nil
+}
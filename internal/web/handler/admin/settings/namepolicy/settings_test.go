@@ -0,0 +1,57 @@
+package namepolicy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuleNormalize(t *testing.T) {
+	r := Rule{Pattern: " _DMARC* ", Types: []string{"txt", " ", "TXT", "cname"},
+		Operations: []string{OpDelete, "rename", OpCreate}, Reason: " mail team "}
+	if err := r.Normalize(); err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+
+	want := Rule{Pattern: "_dmarc*", Types: []string{"CNAME", "TXT"},
+		Operations: []string{OpCreate, OpDelete}, Reason: "mail team"}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("Normalize() = %+v, want %+v", r, want)
+	}
+
+	for _, bad := range []Rule{
+		{Pattern: " ", Operations: []string{OpCreate}},
+		{Pattern: "[a", Operations: []string{OpCreate}},
+		{Pattern: "www"},
+	} {
+		if err := bad.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) error = nil", bad)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	rules := []Rule{
+		{Pattern: "autodiscover", Operations: []string{OpCreate}},
+		{Pattern: "_dmarc*", Types: []string{"TXT"}, Operations: Operations},
+		{Pattern: "@", Types: []string{"NS"}, Operations: []string{OpDelete}},
+	}
+
+	tests := []struct {
+		name, rrType, op string
+		want             *Rule
+	}{
+		{"Autodiscover", "CNAME", OpCreate, &rules[0]},
+		{"autodiscover", "CNAME", OpUpdate, nil},
+		{"_dmarc.mail", "txt", OpUpdate, &rules[1]},
+		{"_dmarc", "CNAME", OpCreate, nil},
+		{"@", "NS", OpDelete, &rules[2]},
+		{"@", "NS", OpUpdate, nil},
+		{"sub", "NS", OpDelete, nil},
+	}
+
+	for _, tt := range tests {
+		if got := Find(rules, tt.name, tt.rrType, tt.op); got != tt.want {
+			t.Errorf("Find(%s, %s, %s) = %+v, want %+v", tt.name, tt.rrType, tt.op, got, tt.want)
+		}
+	}
+}
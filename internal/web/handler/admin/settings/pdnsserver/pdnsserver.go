@@ -16,6 +16,7 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
 )
 
 const (
@@ -152,6 +153,8 @@ func (s *Service) Post(c fiber.Ctx) error {
 	log.Info().
 		Str("api_server_url", settings.APIServerURL).
 		Str("version", settings.VHost).
+		Bool("log_api_traffic", settings.LogAPITraffic).
+		Bool("read_only", settings.ReadOnly).
 		Msg("PDNS server settings saved successfully")
 
 	// Re-initialize PowerDNS engine with new settings asynchronously to avoid blocking the request
@@ -161,6 +164,9 @@ func (s *Service) Post(c fiber.Ctx) error {
 			return
 		}
 
+		// The zones of the previous server must not be served any longer.
+		zoneindex.Default.Invalidate()
+
 		// Test PowerDNS API connection with new settings (non-blocking, log-only)
 		if err := powerdns.Engine.Test(); err != nil {
 			log.Error().Err(err).Msg("failed to connect to PowerDNS API with new settings")
@@ -0,0 +1,283 @@
+// Package pdnsviews provides the settings pages that manage the PowerDNS
+// views: further PowerDNS servers serving their own variant of zones for
+// split-horizon setups (see internal/splithorizon).
+package pdnsviews
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/splithorizon"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathList is the path for the view list.
+	PathList = handler.RootPath + "admin/settings/pdns-views"
+	// PathNew is the path for creating a view.
+	PathNew = PathList + "/new"
+	// PathEdit is the path for editing a view.
+	PathEdit = PathList + "/:id/edit"
+	// PathDelete is the path for deleting a view.
+	PathDelete = PathList + "/:id/delete"
+	// PathTest tests the connection to a view.
+	PathTest = PathList + "/:id/test"
+
+	templateList = "admin/settings/pdns-views"
+	templateForm = "admin/settings/pdns-view"
+
+	navSection    = "settings"
+	navSubsection = "pdns-views"
+
+	labelViews   = "PowerDNS Views"
+	labelNewView = "New PowerDNS View"
+	labelEdit    = "Edit PowerDNS View"
+
+	errViewNotFound    = "PowerDNS view not found"
+	errInvalidFormData = "Invalid form data"
+)
+
+// viewName matches valid view names; they appear in URLs of the zone editor.
+var viewName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+
+// Service is the PowerDNS view handler service.
+type Service struct {
+	handler.Service
+	db *gorm.DB
+}
+
+// Handler is the PowerDNS view handler.
+var Handler = Service{}
+
+// form is the submitted view form.
+type form struct {
+	Name         string `form:"name"`
+	Description  string `form:"description"`
+	APIServerURL string `form:"api_server_url"`
+	APIKey       string `form:"api_key"`
+	VHost        string `form:"vhost"`
+	ReadOnly     bool   `form:"read_only"`
+}
+
+// Init initializes the PowerDNS view handler.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+
+	perm := auth.RequirePermission(authService, auth.PermAdminPDNSServer)
+
+	app.Get(PathList, perm, s.List)
+	app.Get(PathNew, perm, s.New)
+	app.Post(PathNew, perm, s.Create)
+	app.Get(PathEdit, perm, s.Edit)
+	app.Post(PathEdit, perm, s.Update)
+	app.Post(PathDelete, perm, s.Delete)
+	app.Post(PathTest, perm, s.Test)
+}
+
+// List renders the view list.
+func (s *Service) List(c fiber.Ctx) error {
+	nav := navigation.NewContext(labelViews, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Settings", "", false).
+		AddBreadcrumb(labelViews, PathList, true)
+
+	var views []models.PDNSView
+	if err := s.db.Order(handler.OrderNameASC).Find(&views).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list PowerDNS views")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load PowerDNS views", nil)
+	}
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Views":      views,
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// New renders the create view form.
+func (s *Service) New(c fiber.Ctx) error {
+	return s.renderForm(c, fiber.StatusOK, &models.PDNSView{VHost: "localhost"}, "")
+}
+
+// Create handles the create view form submission.
+func (s *Service) Create(c fiber.Ctx) error {
+	var in form
+	if err := c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	view := &models.PDNSView{}
+	if msg := apply(view, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, view, msg)
+	}
+
+	if err := s.db.Create(view).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to create PowerDNS view")
+		return s.renderForm(c, fiber.StatusInternalServerError, view, "Failed to create PowerDNS view: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("PowerDNS view "+view.Name+" created."))
+}
+
+// Edit renders the edit view form.
+func (s *Service) Edit(c fiber.Ctx) error {
+	view, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	return s.renderForm(c, fiber.StatusOK, view, "")
+}
+
+// Update handles the edit view form submission. An empty API key keeps the
+// stored one.
+func (s *Service) Update(c fiber.Ctx) error {
+	view, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	var in form
+	if err = c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	if msg := apply(view, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, view, msg)
+	}
+
+	if err = s.db.Save(view).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to update PowerDNS view")
+		return s.renderForm(c, fiber.StatusInternalServerError, view, "Failed to update PowerDNS view: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("PowerDNS view "+view.Name+" updated."))
+}
+
+// Delete handles view deletion. The zones on the server stay untouched.
+func (s *Service) Delete(c fiber.Ctx) error {
+	view, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	if err = s.db.Delete(view).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to delete PowerDNS view")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Delete Failed",
+			"Failed to delete PowerDNS view", nil)
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("PowerDNS view "+view.Name+" deleted."))
+}
+
+// Test checks that the PowerDNS API of a view answers.
+func (s *Service) Test(c fiber.Ctx) error {
+	view, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	server, err := powerdns.Probe(c.Context(), splithorizon.Settings(view))
+	if err != nil {
+		msg := "PowerDNS view " + view.Name + " is not reachable: " + err.Error()
+		return c.Redirect().To(PathList + "?error=" + url.QueryEscape(msg))
+	}
+
+	msg := "PowerDNS view " + view.Name + " answered"
+	if server.Version != nil {
+		msg += ", running PowerDNS " + *server.Version
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape(msg+"."))
+}
+
+// load returns the view of the :id parameter, or renders the error page and
+// returns its result as the error.
+func (s *Service) load(c fiber.Ctx) (*models.PDNSView, error) {
+	var view models.PDNSView
+
+	err := s.db.First(&view, fiber.Params[uint](c, "id")).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, errViewNotFound)
+	}
+
+	if err != nil {
+		return nil, handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load PowerDNS view", nil)
+	}
+
+	return &view, nil
+}
+
+// apply validates the submitted form and copies it to view. It returns the
+// message of the first invalid field, or "".
+func apply(view *models.PDNSView, in *form) string {
+	view.Name = strings.ToLower(strings.TrimSpace(in.Name))
+	view.Description = strings.TrimSpace(in.Description)
+	view.APIServerURL = strings.TrimSpace(in.APIServerURL)
+	view.VHost = strings.TrimSpace(in.VHost)
+	view.ReadOnly = in.ReadOnly
+
+	if in.APIKey != "" {
+		view.APIKey = in.APIKey
+	}
+
+	if view.VHost == "" {
+		view.VHost = "localhost"
+	}
+
+	u, err := url.Parse(view.APIServerURL)
+
+	switch {
+	case !viewName.MatchString(view.Name):
+		return "Name must be lower-case letters, digits, dashes and underscores"
+	case err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "":
+		return "PowerDNS API URL must be an http or https URL"
+	case len(view.APIKey) < 8:
+		return "API key must have at least 8 characters"
+	}
+
+	return ""
+}
+
+// renderForm renders the view form; view.ID is 0 for a new one.
+func (s *Service) renderForm(c fiber.Ctx, status int, view *models.PDNSView, errMsg string) error {
+	title := labelEdit
+	if view.ID == 0 {
+		title = labelNewView
+	}
+
+	nav := navigation.NewContext(title, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Settings", "", false).
+		AddBreadcrumb(labelViews, PathList, false).
+		AddBreadcrumb(title, "", true)
+
+	return c.Status(status).Render(templateForm, fiber.Map{
+		"Navigation": nav,
+		"View":       view,
+		"IsCreate":   view.ID == 0,
+		"HasAPIKey":  view.ID != 0 && view.APIKey != "",
+		"Error":      errMsg,
+	}, handler.BaseLayout)
+}
@@ -0,0 +1,142 @@
+package ttl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
+)
+
+// DefaultsSettingKey is the database key for the global record defaults.
+const DefaultsSettingKey = "zone_record_defaults"
+
+var (
+	errInvalidDefaultTTL = errors.New("the default TTL must be a positive number of seconds")
+	errInvalidMXPriority = errors.New("the MX priority must be a number between 0 and 65535")
+
+	nameserverRegex = regexp.MustCompile(`^([a-z0-9_]([a-z0-9-]{0,61}[a-z0-9])?\.)+$`)
+)
+
+// RecordDefaults are the values the zone editor prefills when a record is
+// added: the TTL, the nameservers offered for NS records and the priority of
+// MX records. Unset fields are zero.
+type RecordDefaults struct {
+	TTL         uint32   `json:"ttl,omitempty"`
+	Nameservers []string `json:"nameservers,omitempty"`
+	MXPriority  *uint16  `json:"mx_priority,omitempty"`
+}
+
+// Load loads the global record defaults from the database.
+func (d *RecordDefaults) Load(db *gorm.DB) error {
+	return setting.GetJSON(db, DefaultsSettingKey, d)
+}
+
+// Save persists the global record defaults to the database.
+func (d *RecordDefaults) Save(db *gorm.DB) error {
+	return setting.SetJSON(db, DefaultsSettingKey, d)
+}
+
+// LoadDefaults returns the global record defaults, none when the setting
+// does not exist yet or cannot be read.
+func LoadDefaults(db *gorm.DB) RecordDefaults {
+	var d RecordDefaults
+	if err := d.Load(db); err != nil {
+		return RecordDefaults{}
+	}
+
+	return d
+}
+
+// Or returns d with its unset fields taken from fallback, e.g. the defaults
+// of a zone over the global ones.
+func (d RecordDefaults) Or(fallback RecordDefaults) RecordDefaults {
+	if d.TTL == 0 {
+		d.TTL = fallback.TTL
+	}
+
+	if len(d.Nameservers) == 0 {
+		d.Nameservers = fallback.Nameservers
+	}
+
+	if d.MXPriority == nil {
+		d.MXPriority = fallback.MXPriority
+	}
+
+	return d
+}
+
+// IsZero reports whether no default is set.
+func (d RecordDefaults) IsZero() bool {
+	return d.TTL == 0 && len(d.Nameservers) == 0 && d.MXPriority == nil
+}
+
+// TTLText returns the TTL for a form field, empty when unset.
+func (d RecordDefaults) TTLText() string {
+	if d.TTL == 0 {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(d.TTL), 10)
+}
+
+// NameserversText returns the nameservers for a form field.
+func (d RecordDefaults) NameserversText() string {
+	return strings.Join(d.Nameservers, ", ")
+}
+
+// MXPriorityText returns the MX priority for a form field, empty when unset.
+func (d RecordDefaults) MXPriorityText() string {
+	if d.MXPriority == nil {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(*d.MXPriority), 10)
+}
+
+// ParseDefaults parses the fields of a record defaults form. Empty fields
+// are unset; nameservers are separated by commas or white space and returned
+// lower-cased with a trailing dot.
+func ParseDefaults(ttl, nameservers, mxPriority string) (RecordDefaults, error) {
+	var d RecordDefaults
+
+	if ttl = strings.TrimSpace(ttl); ttl != "" {
+		n, err := strconv.ParseUint(ttl, 10, 32)
+		if err != nil || n == 0 {
+			return RecordDefaults{}, errInvalidDefaultTTL
+		}
+
+		d.TTL = uint32(n)
+	}
+
+	for field := range strings.FieldsFuncSeq(strings.ToLower(nameservers), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		ns := field
+		if !strings.HasSuffix(ns, ".") {
+			ns += "."
+		}
+
+		if len(ns) > 254 || !nameserverRegex.MatchString(ns) {
+			return RecordDefaults{}, fmt.Errorf("nameserver %q is not a valid hostname", field)
+		}
+
+		d.Nameservers = append(d.Nameservers, ns)
+	}
+
+	if mxPriority = strings.TrimSpace(mxPriority); mxPriority != "" {
+		n, err := strconv.ParseUint(mxPriority, 10, 16)
+		if err != nil {
+			return RecordDefaults{}, errInvalidMXPriority
+		}
+
+		priority := uint16(n)
+		d.MXPriority = &priority
+	}
+
+	return d, nil
+}
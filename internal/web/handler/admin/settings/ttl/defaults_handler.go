@@ -0,0 +1,25 @@
+package ttl
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// postDefaults saves the global record defaults, which apply to the zones
+// without defaults of their own.
+func (s *Service) postDefaults(c fiber.Ctx) error {
+	presets := LoadWithDefaults(s.db)
+
+	defaults, err := ParseDefaults(c.FormValue("default_ttl"), c.FormValue("nameservers"), c.FormValue("mx_priority"))
+	if err != nil {
+		return s.render(c, presets, "Error", "Invalid record defaults: "+err.Error()+".")
+	}
+
+	if err = defaults.Save(s.db); err != nil {
+		log.Error().Err(err).Msg("failed to save record defaults")
+
+		return s.render(c, presets, "Error", "Failed to save settings.")
+	}
+
+	return s.render(c, presets, "Success", "Record defaults saved.")
+}
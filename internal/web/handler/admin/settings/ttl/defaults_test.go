@@ -0,0 +1,42 @@
+package ttl
+
+import "testing"
+
+func TestParseDefaults(t *testing.T) {
+	d, err := ParseDefaults("300", "NS1.example.net, ns2.example.net.\nns3.example.net", "0")
+	if err != nil {
+		t.Fatalf("ParseDefaults() error = %v", err)
+	}
+
+	if d.TTL != 300 || d.MXPriority == nil || *d.MXPriority != 0 {
+		t.Errorf("ParseDefaults() = %+v", d)
+	}
+
+	if got := d.NameserversText(); got != "ns1.example.net., ns2.example.net., ns3.example.net." {
+		t.Errorf("nameservers = %q", got)
+	}
+
+	if d, err = ParseDefaults("", "", ""); err != nil || !d.IsZero() {
+		t.Errorf("ParseDefaults() of empty fields = %+v, %v", d, err)
+	}
+
+	for _, fields := range [][3]string{{"0", "", ""}, {"x", "", ""}, {"", "ns1..example.net", ""}, {"", "", "70000"}} {
+		if _, err = ParseDefaults(fields[0], fields[1], fields[2]); err == nil {
+			t.Errorf("ParseDefaults(%q) accepted", fields)
+		}
+	}
+}
+
+func TestRecordDefaultsOr(t *testing.T) {
+	priority := uint16(20)
+	global := RecordDefaults{TTL: 3600, Nameservers: []string{"ns1.example.net."}, MXPriority: &priority}
+
+	got := RecordDefaults{TTL: 300}.Or(global)
+	if got.TTL != 300 || len(got.Nameservers) != 1 || got.MXPriorityText() != "20" {
+		t.Errorf("Or() = %+v, want the zone TTL over the global nameservers and MX priority", got)
+	}
+
+	if got = (RecordDefaults{}).Or(RecordDefaults{}); !got.IsZero() || got.TTLText() != "" || got.MXPriorityText() != "" {
+		t.Errorf("Or() of unset defaults = %+v", got)
+	}
+}
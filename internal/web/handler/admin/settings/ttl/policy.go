@@ -0,0 +1,118 @@
+package ttl
+
+import (
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
+)
+
+// PolicySettingKey is the database key for TTL policy settings.
+const PolicySettingKey = "zone_ttl_policies"
+
+// Policy bounds the TTL of the records of Type in Zone. An empty Zone
+// applies to all zones and an empty Type to all record types; a zero Min or
+// Max sets no bound.
+type Policy struct {
+	Zone string `json:"zone,omitempty"`
+	Type string `json:"type,omitempty"`
+	Min  uint32 `json:"min,omitempty"`
+	Max  uint32 `json:"max,omitempty"`
+}
+
+// PolicySettings holds the configured TTL policies.
+type PolicySettings struct {
+	Policies []Policy `json:"policies"`
+}
+
+// Load loads the TTL policies from the database.
+func (s *PolicySettings) Load(db *gorm.DB) error {
+	return setting.GetJSON(db, PolicySettingKey, s)
+}
+
+// Save persists the TTL policies to the database.
+func (s *PolicySettings) Save(db *gorm.DB) error {
+	return setting.SetJSON(db, PolicySettingKey, s)
+}
+
+// LoadPolicies returns the configured TTL policies, none when the setting
+// does not exist yet or cannot be read.
+func LoadPolicies(db *gorm.DB) []Policy {
+	var s PolicySettings
+	if err := s.Load(db); err != nil {
+		return nil
+	}
+
+	return s.Policies
+}
+
+// specificity ranks p: a policy of a zone beats a global one, and one of a
+// record type beats one of all types.
+func (p *Policy) specificity() int {
+	rank := 0
+	if p.Zone != "" {
+		rank += 2
+	}
+
+	if p.Type != "" {
+		rank++
+	}
+
+	return rank
+}
+
+// Effective returns the policy of policies that applies to the records of
+// rrType in zoneName, or nil when none does. Only the most specific policy
+// applies; its bounds are not combined with those of less specific ones.
+func Effective(policies []Policy, zoneName, rrType string) *Policy {
+	var best *Policy
+
+	for i := range policies {
+		p := &policies[i]
+		if (p.Zone != "" && p.Zone != zoneName) || (p.Type != "" && p.Type != rrType) {
+			continue
+		}
+
+		if best == nil || p.specificity() > best.specificity() {
+			best = p
+		}
+	}
+
+	return best
+}
+
+// ForZone returns the policies that apply to zoneName, its own and the
+// global ones.
+func ForZone(policies []Policy, zoneName string) []Policy {
+	out := make([]Policy, 0, len(policies))
+
+	for i := range policies {
+		if policies[i].Zone == "" || policies[i].Zone == zoneName {
+			out = append(out, policies[i])
+		}
+	}
+
+	return out
+}
+
+// Allows reports whether ttl is within the bounds of p.
+func (p *Policy) Allows(ttl uint32) bool {
+	return (p.Min == 0 || ttl >= p.Min) && (p.Max == 0 || ttl <= p.Max)
+}
+
+// Bounds describes the TTLs p allows, e.g. "between 300 and 86400 seconds".
+func (p *Policy) Bounds() string {
+	lowest, highest := strconv.FormatUint(uint64(p.Min), 10), strconv.FormatUint(uint64(p.Max), 10)
+
+	switch {
+	case p.Min != 0 && p.Max != 0:
+		return "between " + lowest + " and " + highest + " seconds"
+	case p.Min != 0:
+		return "at least " + lowest + " seconds"
+	case p.Max != 0:
+		return "at most " + highest + " seconds"
+	default:
+		return "any number of seconds"
+	}
+}
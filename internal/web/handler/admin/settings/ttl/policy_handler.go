@@ -0,0 +1,121 @@
+package ttl
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	errInvalidMinTTL  = errors.New("minimum TTL must be a number of seconds")
+	errInvalidMaxTTL  = errors.New("maximum TTL must be a number of seconds")
+	errNoTTLBound     = errors.New("set a minimum TTL, a maximum TTL or both")
+	errTTLBoundsOrder = errors.New("minimum TTL must not exceed the maximum TTL")
+)
+
+// postPolicy adds or deletes a TTL policy. A policy is identified by its zone
+// and record type; adding one for a zone and type that has one already
+// replaces it.
+func (s *Service) postPolicy(c fiber.Ctx, action string) error {
+	presets := LoadWithDefaults(s.db)
+
+	settings := &PolicySettings{}
+	if err := settings.Load(s.db); err != nil {
+		settings.Policies = nil
+	}
+
+	policy := Policy{
+		Zone: normalizePolicyZone(c.FormValue("zone")),
+		Type: strings.ToUpper(strings.TrimSpace(c.FormValue("type"))),
+	}
+
+	if !validPolicyType(policy.Type) {
+		return s.render(c, presets, "Error", "The record type may only contain letters and digits.")
+	}
+
+	kept := settings.Policies[:0]
+	for _, p := range settings.Policies {
+		if p.Zone != policy.Zone || p.Type != policy.Type {
+			kept = append(kept, p)
+		}
+	}
+
+	settings.Policies = kept
+
+	if action == "add_policy" {
+		var err error
+		if policy.Min, policy.Max, err = parsePolicyBounds(c.FormValue("min"), c.FormValue("max")); err != nil {
+			return s.render(c, presets, "Error", "Invalid TTL policy: "+err.Error()+".")
+		}
+
+		settings.Policies = append(settings.Policies, policy)
+	}
+
+	if err := settings.Save(s.db); err != nil {
+		log.Error().Err(err).Msg("failed to save TTL policies")
+
+		return s.render(c, presets, "Error", "Failed to save settings.")
+	}
+
+	return s.render(c, presets, "Success", "TTL policies saved.")
+}
+
+// normalizePolicyZone returns zone as a canonical zone name with trailing
+// dot, or empty for all zones.
+func normalizePolicyZone(zone string) string {
+	zone = strings.ToLower(strings.TrimSpace(zone))
+	if zone == "" || strings.HasSuffix(zone, ".") {
+		return zone
+	}
+
+	return zone + "."
+}
+
+// validPolicyType reports whether rrType, already upper-cased, is empty for
+// all types or looks like a record type.
+func validPolicyType(rrType string) bool {
+	for _, r := range rrType {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parsePolicyBounds parses the minimum and maximum TTL of a policy; empty
+// is no bound, but at least one must be set.
+func parsePolicyBounds(minValue, maxValue string) (uint32, uint32, error) {
+	parse := func(value string) (uint32, bool) {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return 0, true
+		}
+
+		n, err := strconv.ParseUint(value, 10, 32)
+
+		return uint32(n), err == nil
+	}
+
+	lowest, ok := parse(minValue)
+	if !ok {
+		return 0, 0, errInvalidMinTTL
+	}
+
+	highest, ok := parse(maxValue)
+	if !ok {
+		return 0, 0, errInvalidMaxTTL
+	}
+
+	switch {
+	case lowest == 0 && highest == 0:
+		return 0, 0, errNoTTLBound
+	case highest != 0 && lowest > highest:
+		return 0, 0, errTTLBoundsOrder
+	}
+
+	return lowest, highest, nil
+}
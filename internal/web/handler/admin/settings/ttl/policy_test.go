@@ -0,0 +1,66 @@
+package ttl
+
+import "testing"
+
+func TestEffective(t *testing.T) {
+	policies := []Policy{
+		{Min: 300, Max: 86400},
+		{Type: "MX", Min: 3600},
+		{Zone: "example.com.", Max: 3600},
+		{Zone: "example.com.", Type: "TXT", Min: 60},
+	}
+
+	tests := []struct {
+		zone, rrType string
+		want         *Policy
+	}{
+		{"other.example.", "A", &policies[0]},
+		{"other.example.", "MX", &policies[1]},
+		{"example.com.", "MX", &policies[2]},
+		{"example.com.", "TXT", &policies[3]},
+	}
+
+	for _, tt := range tests {
+		if got := Effective(policies, tt.zone, tt.rrType); got != tt.want {
+			t.Errorf("Effective(%s, %s) = %+v, want %+v", tt.zone, tt.rrType, got, tt.want)
+		}
+	}
+
+	if got := Effective(policies[1:2], "example.com.", "A"); got != nil {
+		t.Errorf("Effective() = %+v, want nil", got)
+	}
+
+	if got := ForZone(policies, "other.example."); len(got) != 2 {
+		t.Errorf("ForZone() = %+v, want the global policies", got)
+	}
+}
+
+func TestPolicyAllows(t *testing.T) {
+	p := Policy{Min: 300, Max: 86400}
+
+	for ttl, want := range map[uint32]bool{5: false, 300: true, 3600: true, 86400: true, 604800: false} {
+		if got := p.Allows(ttl); got != want {
+			t.Errorf("Allows(%d) = %v, want %v", ttl, got, want)
+		}
+	}
+
+	if got := (&Policy{Max: 60}).Allows(604800); got {
+		t.Error("Allows(604800) with a maximum of 60 = true")
+	}
+
+	if got := p.Bounds(); got != "between 300 and 86400 seconds" {
+		t.Errorf("Bounds() = %q", got)
+	}
+}
+
+func TestParsePolicyBounds(t *testing.T) {
+	if lowest, highest, err := parsePolicyBounds("300", ""); err != nil || lowest != 300 || highest != 0 {
+		t.Errorf("parsePolicyBounds(300, '') = %d, %d, %v", lowest, highest, err)
+	}
+
+	for _, bounds := range [][2]string{{"", ""}, {"600", "300"}, {"-1", ""}, {"", "x"}} {
+		if _, _, err := parsePolicyBounds(bounds[0], bounds[1]); err == nil {
+			t.Errorf("parsePolicyBounds(%q, %q) accepted", bounds[0], bounds[1])
+		}
+	}
+}
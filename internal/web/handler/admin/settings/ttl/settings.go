@@ -2,7 +2,6 @@
 package ttl
 
 import (
-	"encoding/json"
 	"errors"
 
 	"gorm.io/gorm"
@@ -42,24 +41,12 @@ type Settings struct {
 
 // Load loads TTL settings from the database.
 func (s *Settings) Load(db *gorm.DB) error {
-	entry, err := setting.Get(db, SettingKey)
-	if err != nil {
-		return err
-	}
-
-	return json.Unmarshal(entry.Value, s)
+	return setting.GetJSON(db, SettingKey, s)
 }
 
 // Save persists TTL settings to the database.
 func (s *Settings) Save(db *gorm.DB) error {
-	data, err := json.Marshal(s)
-	if err != nil {
-		return err
-	}
-
-	_, err = setting.Set(db, SettingKey, data)
-
-	return err
+	return setting.SetJSON(db, SettingKey, s)
 }
 
 // DefaultPresets returns the built-in TTL preset list.
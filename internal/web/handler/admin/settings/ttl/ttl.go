@@ -60,18 +60,36 @@ func newNav() *navigation.Context {
 
 // Get renders the TTL presets settings page.
 func (s *Service) Get(c fiber.Ctx) error {
-	presets := LoadWithDefaults(s.db)
+	return s.render(c, LoadWithDefaults(s.db), "", "")
+}
 
-	return c.Render(TemplateName, fiber.Map{
+// render renders the page with presets and the TTL policies, and an Error
+// or Success message when kind is set.
+func (s *Service) render(c fiber.Ctx, presets []Preset, kind, message string) error {
+	data := fiber.Map{
 		"Navigation": newNav(),
 		"Presets":    presets,
-	}, handler.BaseLayout)
+		"Policies":   LoadPolicies(s.db),
+		"Defaults":   LoadDefaults(s.db),
+	}
+	if kind != "" {
+		data[kind] = message
+	}
+
+	return c.Render(TemplateName, data, handler.BaseLayout)
 }
 
-// Post handles add and delete actions.
+// Post handles add and delete actions of presets and TTL policies, and
+// saves the record defaults.
 func (s *Service) Post(c fiber.Ctx) error {
 	action := c.FormValue("action")
-	nav := newNav()
+	if action == "add_policy" || action == "delete_policy" {
+		return s.postPolicy(c, action)
+	}
+
+	if action == "save_defaults" {
+		return s.postDefaults(c)
+	}
 
 	settings := &Settings{}
 	if err := settings.Load(s.db); err != nil {
@@ -84,30 +102,18 @@ func (s *Service) Post(c fiber.Ctx) error {
 		label := strings.TrimSpace(c.FormValue("label"))
 
 		if secondsStr == "" || label == "" {
-			return c.Render(TemplateName, fiber.Map{
-				"Navigation": nav,
-				"Presets":    settings.Presets,
-				"Error":      "Both seconds and label are required.",
-			}, handler.BaseLayout)
+			return s.render(c, settings.Presets, "Error", "Both seconds and label are required.")
 		}
 
 		sec, err := strconv.ParseUint(secondsStr, 10, 32)
 		if err != nil || sec == 0 {
-			return c.Render(TemplateName, fiber.Map{
-				"Navigation": nav,
-				"Presets":    settings.Presets,
-				"Error":      "Seconds must be a positive integer.",
-			}, handler.BaseLayout)
+			return s.render(c, settings.Presets, "Error", "Seconds must be a positive integer.")
 		}
 
 		// Reject duplicate seconds values.
 		for _, p := range settings.Presets {
 			if uint64(p.Seconds) == sec {
-				return c.Render(TemplateName, fiber.Map{
-					"Navigation": nav,
-					"Presets":    settings.Presets,
-					"Error":      "A preset with that TTL value already exists.",
-				}, handler.BaseLayout)
+				return s.render(c, settings.Presets, "Error", "A preset with that TTL value already exists.")
 			}
 		}
 
@@ -137,16 +143,8 @@ func (s *Service) Post(c fiber.Ctx) error {
 	if err := settings.Save(s.db); err != nil {
 		log.Error().Err(err).Msg("failed to save TTL presets")
 
-		return c.Render(TemplateName, fiber.Map{
-			"Navigation": nav,
-			"Presets":    settings.Presets,
-			"Error":      "Failed to save settings.",
-		}, handler.BaseLayout)
+		return s.render(c, settings.Presets, "Error", "Failed to save settings.")
 	}
 
-	return c.Render(TemplateName, fiber.Map{
-		"Navigation": nav,
-		"Presets":    settings.Presets,
-		"Success":    "TTL presets saved.",
-	}, handler.BaseLayout)
+	return s.render(c, settings.Presets, "Success", "TTL presets saved.")
 }
@@ -1,8 +1,6 @@
 package zone
 
 import (
-	"encoding/json"
-
 	"gorm.io/gorm"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
@@ -21,26 +19,10 @@ type RecordSettings struct {
 
 // Load loads the zone record settings from the database.
 func (r *RecordSettings) Load(db *gorm.DB) error {
-	// Retrieve the setting from the database
-	s, err := setting.Get(db, SettingKeyZoneRecords)
-	if err != nil {
-		return err
-	}
-
-	// Unmarshal the JSON blob into the struct
-	return json.Unmarshal(s.Value, r)
+	return setting.GetJSON(db, SettingKeyZoneRecords, r)
 }
 
 // Save saves the zone record settings to the database.
 func (r *RecordSettings) Save(db *gorm.DB) error {
-	// Marshal the struct to JSON
-	data, err := json.Marshal(r)
-	if err != nil {
-		return err
-	}
-
-	// Save or update the setting in the database
-	_, err = setting.Set(db, SettingKeyZoneRecords, data)
-
-	return err
+	return setting.SetJSON(db, SettingKeyZoneRecords, r)
 }
@@ -0,0 +1,402 @@
+// Package snapshot implements the admin pages that list the scheduled
+// snapshots (see internal/snapshot), take one on demand, and restore single
+// zones or the application data from a snapshot.
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/backup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/snapshot"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonesnapshot"
+)
+
+const (
+	// Path is the path to the snapshot list; POST takes a snapshot.
+	Path = handler.RootPath + "admin/snapshots"
+	// DetailPath shows the zones of a snapshot.
+	DetailPath = Path + "/:name"
+	// DownloadPath downloads a snapshot archive.
+	DownloadPath = DetailPath + "/download"
+	// ZonePath downloads (GET) or restores (POST) one zone of a snapshot.
+	ZonePath = DetailPath + "/zone"
+	// AppPath restores the application data of a snapshot.
+	AppPath = DetailPath + "/app"
+
+	// ListTemplateName is the name of the snapshot list template.
+	ListTemplateName = "admin/snapshot/list"
+	// DetailTemplateName is the name of the snapshot detail template.
+	DetailTemplateName = "admin/snapshot/detail"
+)
+
+// Service is the snapshot handler service.
+type Service struct {
+	handler.Service
+	db    *gorm.DB
+	cfg   *config.Snapshots
+	store snapshot.Store
+	// zoneSnapshots controls the zone snapshot taken before a zone restore.
+	zoneSnapshots config.ZoneSnapshots
+}
+
+// Handler is the snapshot handler.
+var Handler = Service{}
+
+// Init initializes the snapshot handler. store is nil when no snapshot
+// storage is configured; the pages then explain how to configure one.
+func (s *Service) Init(
+	app *fiber.App,
+	cfg *config.Config,
+	db *gorm.DB,
+	authService *auth.Service,
+	store snapshot.Store,
+) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.cfg = &cfg.Snapshots
+	s.store = store
+	s.zoneSnapshots = cfg.ZoneSnapshots
+
+	perm := auth.RequirePermission(authService, auth.PermAdminSnapshots)
+
+	app.Get(Path, perm, s.List)
+	app.Post(Path, perm, s.Take)
+	app.Get(DetailPath, perm, s.Detail)
+	app.Get(DownloadPath, perm, s.Download)
+	app.Get(ZonePath, perm, s.DownloadZone)
+	app.Post(ZonePath, perm, s.RestoreZone)
+	app.Post(AppPath, perm, s.RestoreApp)
+}
+
+// List renders the stored snapshots.
+func (s *Service) List(c fiber.Ctx) error {
+	data := fiber.Map{
+		"Configured": s.store != nil,
+		"Config":     s.cfg,
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}
+
+	if s.store != nil {
+		objects, err := s.store.List(c.Context())
+		if err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list snapshots")
+
+			data["Error"] = "Failed to list the snapshots: " + err.Error()
+		}
+
+		data["Snapshots"] = objects
+	}
+
+	return s.render(c, ListTemplateName, navigation.NewContext("Snapshots", "admin", "snapshots").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb("Snapshots", Path, true), data)
+}
+
+// Take takes a snapshot now and applies the retention policy.
+func (s *Service) Take(c fiber.Ctx) error {
+	if s.store == nil {
+		return c.Redirect().To(Path)
+	}
+
+	now := time.Now()
+	name := snapshot.Name(now)
+
+	manifest, err := snapshot.Take(c.Context(), s.db, s.store, name)
+	if manifest == nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("snapshot", name).Msg("failed to take snapshot")
+
+		return c.Redirect().To(Path + "?error=" + url.QueryEscape("Failed to take a snapshot: "+err.Error()))
+	}
+
+	if pruneErr := snapshot.Prune(c.Context(), s.store, s.cfg, now); pruneErr != nil {
+		requestid.Logger(c.Context()).Warn().Err(pruneErr).Msg("failed to remove expired snapshots")
+	}
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		Action:       activitylog.ActionSnapshotTaken,
+		ResourceType: activitylog.ResourceTypeSystem,
+		ResourceName: name,
+		Details:      map[string]any{"zones": len(manifest.Zones), "failed_zones": manifest.Errors},
+	}))
+
+	if err != nil {
+		msg := fmt.Sprintf("Snapshot %s taken, but %d zone(s) could not be exported.", name, len(manifest.Errors))
+		return c.Redirect().To(Path + "?error=" + url.QueryEscape(msg))
+	}
+
+	msg := fmt.Sprintf("Snapshot %s taken with %d zone(s).", name, len(manifest.Zones))
+
+	return c.Redirect().To(Path + "?success=" + url.QueryEscape(msg))
+}
+
+// Detail renders the manifest of a snapshot with its zones.
+func (s *Service) Detail(c fiber.Ctx) error {
+	name := c.Params("name")
+
+	manifest, err := s.manifest(c, name)
+	if err != nil {
+		return err
+	}
+
+	return s.render(c, DetailTemplateName, navigation.NewContext("Snapshot", "admin", "snapshots").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb("Snapshots", Path, false).
+		AddBreadcrumb(name, "", true), fiber.Map{
+		"Name":     name,
+		"Manifest": manifest,
+		"Failed":   sortedKeys(manifest.Errors),
+		"Success":  c.Query("success"),
+		"Error":    c.Query("error"),
+	})
+}
+
+// Download sends a snapshot archive.
+func (s *Service) Download(c fiber.Ctx) error {
+	name := c.Params("name")
+
+	if s.store == nil {
+		return s.notFound(c)
+	}
+
+	rc, err := s.store.Open(c.Context(), name)
+	if errors.Is(err, snapshot.ErrNotFound) {
+		return s.notFound(c)
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("snapshot", name).Msg("failed to open snapshot")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Snapshot unavailable", err.Error(), nil)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("snapshot", name).Msg("failed to read snapshot")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Snapshot unavailable", err.Error(), nil)
+	}
+
+	c.Attachment(name)
+	c.Set(fiber.HeaderContentType, "application/gzip")
+
+	return c.Send(data)
+}
+
+// DownloadZone sends one zone of a snapshot in BIND format.
+func (s *Service) DownloadZone(c fiber.Ctx) error {
+	name, zone := c.Params("name"), c.Query("zone")
+
+	data, err := s.zoneFile(c, name, zone)
+	if err != nil {
+		return s.entryError(c, name, err)
+	}
+
+	c.Attachment(strings.TrimSuffix(zone, ".") + ".zone")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+
+	return c.Send(data)
+}
+
+// RestoreZone restores one zone to its content in a snapshot.
+func (s *Service) RestoreZone(c fiber.Ctx) error {
+	name, zone := c.Params("name"), c.FormValue("zone")
+	back := Path + "/" + url.PathEscape(name)
+
+	data, err := s.zoneFile(c, name, zone)
+	if err != nil {
+		return s.entryError(c, name, err)
+	}
+
+	rrsets, err := snapshot.ParseZoneFile(bytes.NewReader(data))
+	if err != nil {
+		return c.Redirect().To(back + "?error=" + url.QueryEscape(err.Error()))
+	}
+
+	// Keep the current content restorable; a deleted zone has none.
+	userID, _ := auth.Actor(c)
+	if _, err = zonesnapshot.Take(c.Context(), s.db, s.zoneSnapshots, zone, zonesnapshot.ReasonRestore, userID); err != nil {
+		requestid.Logger(c.Context()).Warn().Err(err).Str("zone", zone).Msg("failed to take zone snapshot before the restore")
+	}
+
+	if err = snapshot.RestoreZone(c.Context(), zone, rrsets); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("snapshot", name).Str("zone", zone).
+			Msg("failed to restore zone from snapshot")
+
+		return c.Redirect().To(back + "?error=" + url.QueryEscape("Failed to restore "+zone+": "+err.Error()))
+	}
+
+	requestid.Logger(c.Context()).Info().Str("snapshot", name).Str("zone", zone).Msg("zone restored from snapshot")
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		Action:       activitylog.ActionSnapshotZoneRestored,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zone,
+		Details:      map[string]any{"snapshot": name, "rrsets": len(rrsets)},
+	}))
+
+	msg := fmt.Sprintf("Restored %s with %d RRsets from %s.", zone, len(rrsets), name)
+
+	return c.Redirect().To(back + "?success=" + url.QueryEscape(msg))
+}
+
+// RestoreApp replaces the application database with the archive in a
+// snapshot.
+func (s *Service) RestoreApp(c fiber.Ctx) error {
+	name := c.Params("name")
+	back := Path + "/" + url.PathEscape(name)
+
+	if s.store == nil {
+		return s.notFound(c)
+	}
+
+	var counts []backup.TableCount
+
+	err := snapshot.ReadEntry(c.Context(), s.store, name, snapshot.AppEntry, func(r io.Reader) error {
+		var err error
+
+		counts, err = backup.Restore(s.db, r)
+
+		return err
+	})
+	if errors.Is(err, snapshot.ErrNotFound) || errors.Is(err, snapshot.ErrEntryNotFound) {
+		return s.entryError(c, name, err)
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("snapshot", name).Msg("failed to restore application data")
+
+		msg := "Restore failed; the database was left unchanged: " + err.Error()
+
+		return c.Redirect().To(back + "?error=" + url.QueryEscape(msg))
+	}
+
+	requestid.Logger(c.Context()).Info().Str("snapshot", name).Msg("application data restored from snapshot")
+
+	rows := make(map[string]int, len(counts))
+	total := 0
+
+	for _, tc := range counts {
+		rows[tc.Table] = tc.Rows
+		total += tc.Rows
+	}
+
+	// Recorded after the restore, so that the entry is kept.
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		Action:       activitylog.ActionBackupRestored,
+		ResourceType: activitylog.ResourceTypeSystem,
+		ResourceName: "database",
+		Details:      map[string]any{"snapshot": name, "rows": rows},
+	}))
+
+	msg := fmt.Sprintf("Restored %d rows in %d tables from %s.", total, len(counts), name)
+
+	return c.Redirect().To(back + "?success=" + url.QueryEscape(msg))
+}
+
+// manifest returns the manifest of the snapshot name, or renders the error
+// page and returns its result as the error.
+func (s *Service) manifest(c fiber.Ctx, name string) (*snapshot.Manifest, error) {
+	if s.store == nil {
+		return nil, s.notFound(c)
+	}
+
+	manifest, err := snapshot.ReadManifest(c.Context(), s.store, name)
+	if err != nil {
+		return nil, s.entryError(c, name, err)
+	}
+
+	return manifest, nil
+}
+
+// zoneFile returns the content of zone in the snapshot name.
+func (s *Service) zoneFile(c fiber.Ctx, name, zone string) ([]byte, error) {
+	if s.store == nil {
+		return nil, snapshot.ErrNotFound
+	}
+
+	if zone == "" {
+		return nil, snapshot.ErrEntryNotFound
+	}
+
+	var data []byte
+
+	err := snapshot.ReadEntry(c.Context(), s.store, name, snapshot.ZoneEntry(zone), func(r io.Reader) error {
+		var err error
+
+		data, err = io.ReadAll(r)
+
+		return err
+	})
+
+	return data, err
+}
+
+// entryError renders the error page for a failure to read a snapshot.
+func (s *Service) entryError(c fiber.Ctx, name string, err error) error {
+	switch {
+	case errors.Is(err, snapshot.ErrNotFound):
+		return s.notFound(c)
+	case errors.Is(err, snapshot.ErrEntryNotFound):
+		return handler.RenderError(c, fiber.StatusNotFound, "Not in snapshot",
+			"The snapshot "+name+" does not contain this entry.", s.backAction())
+	default:
+		requestid.Logger(c.Context()).Error().Err(err).Str("snapshot", name).Msg("failed to read snapshot")
+
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Snapshot unavailable",
+			"Failed to read the snapshot "+name+": "+err.Error(), s.backAction())
+	}
+}
+
+func (s *Service) notFound(c fiber.Ctx) error {
+	return handler.RenderError(c, fiber.StatusNotFound, "Snapshot not found",
+		"The snapshot does not exist or has been removed by the retention policy.", s.backAction())
+}
+
+func (s *Service) backAction() *handler.ErrorAction {
+	return &handler.ErrorAction{Label: "Snapshots", URL: Path, Icon: "bi-clock-history"}
+}
+
+// render renders tpl with the navigation nav and data.
+func (s *Service) render(c fiber.Ctx, tpl string, nav *navigation.Context, data fiber.Map) error {
+	data["Navigation"] = nav
+
+	return c.Render(tpl, data, handler.BaseLayout)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,97 @@
+package system
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/servermonitor"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathAvailability is the availability history of the PowerDNS servers.
+	PathAvailability = Path + "/availability"
+
+	// TemplateAvailability is the template of the availability history.
+	TemplateAvailability = "admin/system/availability"
+
+	// maxFailureRows is the number of failed checks listed.
+	maxFailureRows = 50
+)
+
+// availabilityPeriods are the periods the availability is shown for.
+var availabilityPeriods = []struct {
+	Title  string
+	Period time.Duration
+}{
+	{"24 hours", 24 * time.Hour},
+	{"7 days", 7 * 24 * time.Hour},
+	{"30 days", 30 * 24 * time.Hour},
+}
+
+// ServerAvailability is a server on the availability page.
+type ServerAvailability struct {
+	Status servermonitor.Status
+	// Periods is the availability in each of availabilityPeriods.
+	Periods []servermonitor.Availability
+}
+
+// Availability renders the current status of the primary PowerDNS server and
+// of the views, their availability over the last day, week and month and the
+// last failed checks, as recorded by servermonitor.Default.
+func (s *Service) Availability(c fiber.Ctx) error {
+	nav := navigation.NewContext("PowerDNS Availability", "admin", "system").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb("System Information", Path, false).
+		AddBreadcrumb("PowerDNS Availability", PathAvailability, true)
+
+	statuses := servermonitor.Default.Statuses()
+	servers := make([]ServerAvailability, len(statuses))
+
+	for i := range statuses {
+		servers[i] = ServerAvailability{
+			Status:  statuses[i],
+			Periods: make([]servermonitor.Availability, len(availabilityPeriods)),
+		}
+	}
+
+	var loadErr string
+
+	now := time.Now()
+
+	for p, period := range availabilityPeriods {
+		byServer, err := servermonitor.Availabilities(s.db, now.Add(-period.Period))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to load server availability")
+
+			loadErr = "Failed to load the availability history: " + err.Error()
+
+			break
+		}
+
+		for i := range servers {
+			servers[i].Periods[p] = byServer[servers[i].Status.Server]
+		}
+	}
+
+	failures, err := servermonitor.Failures(s.db, maxFailureRows)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load failed server checks")
+
+		loadErr = "Failed to load the availability history: " + err.Error()
+	}
+
+	return c.Render(TemplateAvailability, fiber.Map{
+		"Navigation": nav,
+		"Monitor":    s.cfg.Monitor,
+		"Periods":    availabilityPeriods,
+		"Servers":    servers,
+		"Failures":   failures,
+		"Error":      loadErr,
+	}, handler.BaseLayout)
+}
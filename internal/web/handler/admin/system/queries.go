@@ -0,0 +1,63 @@
+package system
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/querylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathQueries is the slow query report.
+	PathQueries = handler.RootPath + "admin/debug/queries"
+	// PathQueriesReset clears the slow query report.
+	PathQueriesReset = PathQueries + "/reset"
+
+	// TemplateQueries is the template of the slow query report.
+	TemplateQueries = "admin/system/queries"
+
+	// maxStatementRows is the number of statements listed.
+	maxStatementRows = 50
+)
+
+// Queries renders the slow queries and the most frequent statements recorded
+// by querylog.Default. ?sort=total orders the statements by total duration.
+func (s *Service) Queries(c fiber.Ctx) error {
+	nav := navigation.NewContext("Database Queries", "admin", "system").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb("System Information", Path, false).
+		AddBreadcrumb("Database Queries", PathQueries, true)
+
+	order, sort := querylog.ByCount, "count"
+	if c.Query("sort") == "total" {
+		order, sort = querylog.ByTotal, "total"
+	}
+
+	statements := querylog.Default.Statements(order)
+	total := len(statements)
+
+	if len(statements) > maxStatementRows {
+		statements = statements[:maxStatementRows]
+	}
+
+	return c.Render(TemplateQueries, fiber.Map{
+		"Navigation":      nav,
+		"Threshold":       querylog.Default.Threshold(),
+		"Since":           querylog.Default.Since(),
+		"Slow":            querylog.Default.Slow(),
+		"Statements":      statements,
+		"StatementsTotal": total,
+		"Sort":            sort,
+		"Success":         c.Query("success"),
+	}, handler.BaseLayout)
+}
+
+// ResetQueries clears the slow queries and statement counts.
+func (s *Service) ResetQueries(c fiber.Ctx) error {
+	querylog.Default.Reset()
+
+	return c.Redirect().To(PathQueries + "?success=Query+statistics+cleared.")
+}
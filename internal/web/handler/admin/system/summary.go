@@ -0,0 +1,110 @@
+package system
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// masked replaces the value of a secret that is set.
+const masked = "********"
+
+// secretWords mark string settings whose value must not be shown.
+var secretWords = []string{"password", "secret", "token", "key", "salt"}
+
+// ConfigEntry is one setting of the configuration summary.
+type ConfigEntry struct {
+	Key    string
+	Value  string
+	Secret bool
+}
+
+// summarizeConfig flattens cfg into dotted keys as used in main.toml. String
+// values of secret settings are masked; maps are summarized by size.
+func summarizeConfig(cfg any) []ConfigEntry {
+	var entries []ConfigEntry
+
+	flatten(reflect.ValueOf(cfg), "", &entries)
+
+	return entries
+}
+
+func flatten(v reflect.Value, prefix string, entries *[]ConfigEntry) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	t := v.Type()
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := prefix + settingName(field)
+		value := v.Field(i)
+
+		switch {
+		case value.Kind() == reflect.Struct && field.Type != reflect.TypeFor[time.Time]():
+			flatten(value, key+".", entries)
+		case value.Kind() == reflect.Map:
+			*entries = append(*entries, ConfigEntry{Key: key, Value: fmt.Sprintf("%d entries", value.Len())})
+		case value.Kind() == reflect.String && isSecret(field.Name):
+			entry := ConfigEntry{Key: key, Secret: true}
+			if value.String() != "" {
+				entry.Value = masked
+			}
+
+			*entries = append(*entries, entry)
+		default:
+			*entries = append(*entries, ConfigEntry{Key: key, Value: formatValue(value)})
+		}
+	}
+}
+
+// settingName returns the main.toml name of a field.
+func settingName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ","); name != "" && name != "-" {
+		return name
+	}
+
+	return strings.ToLower(field.Name)
+}
+
+func isSecret(fieldName string) bool {
+	name := strings.ToLower(fieldName)
+
+	for _, word := range secretWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range v.Len() {
+			items[i] = formatValue(v.Index(i))
+		}
+
+		return strings.Join(items, ", ")
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+
+		return formatValue(v.Elem())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,64 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+func TestSummarizeConfigMasksSecrets(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DB.Password = "db-pass"
+	cfg.DB.Host = "db.example.com"
+	cfg.Webserver.CookieEncryptionKey = "cookie-key"
+	cfg.Auth.OIDC.ClientSecret = ""
+	cfg.Auth.LocalDB.ResetTokenTTL = time.Hour
+	cfg.Metrics.Token = "metrics-token"
+	cfg.Record = config.Record{"A": {}, "AAAA": {}}
+
+	entries := map[string]ConfigEntry{}
+	for _, e := range summarizeConfig(cfg) {
+		entries[e.Key] = e
+	}
+
+	for key, want := range map[string]string{
+		"db.password":                   masked,
+		"webserver.cookieencryptionkey": masked,
+		"metrics.token":                 masked,
+		"auth.oidc.client_secret":       "",
+		"db.host":                       "db.example.com",
+		"auth.localdb.reset_token_ttl":  "1h0m0s",
+		"record":                        "2 entries",
+	} {
+		got, ok := entries[key]
+		if !ok {
+			t.Errorf("%s missing from summary", key)
+			continue
+		}
+
+		if got.Value != want {
+			t.Errorf("%s = %q, want %q", key, got.Value, want)
+		}
+	}
+
+	for _, e := range entries {
+		for _, secret := range []string{"db-pass", "cookie-key", "metrics-token"} {
+			if e.Value == secret {
+				t.Errorf("%s shows secret value", e.Key)
+			}
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[uint64]string{
+		512:             "512 B",
+		2048:            "2.0 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Package system provides the admin system information page: build, runtime,
+// database, session store and PowerDNS versions plus a summary of the
+// configuration with secrets masked. It collects what support needs when
+// triaging an issue.
+package system
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/version"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/health"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the system information page.
+	Path = handler.RootPath + "admin/system"
+
+	// TemplateName is the template of the system information page.
+	TemplateName = "admin/system/info"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// started approximates the process start time.
+var started = time.Now()
+
+// Service is the system information handler service.
+type Service struct {
+	handler.Service
+	cfg *config.Config
+	db  *gorm.DB
+}
+
+// Handler is the system information handler.
+var Handler = Service{}
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildTime string
+	Modified  bool
+	GoVersion string
+}
+
+// RuntimeInfo describes the Go runtime of the process.
+type RuntimeInfo struct {
+	OS         string
+	Arch       string
+	NumCPU     int
+	GOMAXPROCS int
+	Goroutines int
+	HeapAlloc  string
+	Sys        string
+	NumGC      uint32
+	Started    time.Time
+	Uptime     string
+}
+
+// DatabaseInfo describes the application database.
+type DatabaseInfo struct {
+	Dialect       string
+	ServerVersion string
+	Tables        int
+	// Schema explains how the schema version is tracked.
+	Schema string
+}
+
+// PowerDNSServer is a server reported by the PowerDNS API.
+type PowerDNSServer struct {
+	ID         string
+	DaemonType string
+	Version    string
+}
+
+// Link is a quick link on the page.
+type Link struct {
+	Title string
+	URL   string
+}
+
+// Info is the data of the system information page.
+type Info struct {
+	Build         BuildInfo
+	Runtime       RuntimeInfo
+	Database      DatabaseInfo
+	SessionStore  string
+	PowerDNSURL   string
+	PowerDNS      []PowerDNSServer
+	PowerDNSError string
+	Config        []ConfigEntry
+	Links         []Link
+}
+
+// Init initializes the system information handler.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+
+	app.Get(Path,
+		auth.RequirePermission(authService, auth.PermAdminSystem),
+		s.Get,
+	)
+
+	app.Get(PathAvailability, auth.RequirePermission(authService, auth.PermAdminSystem), s.Availability)
+	app.Get(PathQueries, auth.RequirePermission(authService, auth.PermAdminSystem), s.Queries)
+	app.Post(PathQueriesReset, auth.RequirePermission(authService, auth.PermAdminSystem), s.ResetQueries)
+}
+
+// Get renders the system information page.
+func (s *Service) Get(c fiber.Ctx) error {
+	nav := navigation.NewContext("System Information", "admin", "system").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb("System Information", Path, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return c.Render(TemplateName, fiber.Map{
+		"Navigation": nav,
+		"Info":       s.collect(ctx),
+	}, handler.BaseLayout)
+}
+
+// collect gathers the page data. Failing lookups are shown on the page rather
+// than failing it.
+func (s *Service) collect(ctx context.Context) Info {
+	info := Info{
+		Build:        buildInfo(),
+		Runtime:      runtimeInfo(),
+		Database:     s.databaseInfo(ctx),
+		SessionStore: sessionStore(s.cfg.DB.GormEngine),
+		Config:       summarizeConfig(s.cfg),
+		Links: []Link{
+			{Title: "Health check", URL: health.Path},
+			{Title: "PowerDNS availability", URL: PathAvailability},
+			{Title: "Database queries", URL: PathQueries},
+		},
+	}
+
+	if s.cfg.Metrics.Enabled {
+		info.Links = append(info.Links, Link{Title: "Prometheus metrics", URL: s.cfg.Metrics.Path})
+	}
+
+	if powerdns.Engine.Client == nil {
+		info.PowerDNSError = powerdns.ErrMsgClientNotInitialized
+		return info
+	}
+
+	info.PowerDNSURL = powerdns.Engine.BaseURL
+
+	servers, err := powerdns.Engine.Servers.List(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list PowerDNS servers")
+
+		info.PowerDNSError = "Failed to query the PowerDNS API: " + err.Error()
+
+		return info
+	}
+
+	for i := range servers {
+		info.PowerDNS = append(info.PowerDNS, PowerDNSServer{
+			ID:         deref(servers[i].ID),
+			DaemonType: deref(servers[i].DaemonType),
+			Version:    deref(servers[i].Version),
+		})
+	}
+
+	return info
+}
+
+func buildInfo() BuildInfo {
+	info := BuildInfo{Version: version.Get(), GoVersion: runtime.Version()}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.time":
+			info.BuildTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+
+	return info
+}
+
+func runtimeInfo() RuntimeInfo {
+	var mem runtime.MemStats
+
+	runtime.ReadMemStats(&mem)
+
+	return RuntimeInfo{
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  formatBytes(mem.HeapAlloc),
+		Sys:        formatBytes(mem.Sys),
+		NumGC:      mem.NumGC,
+		Started:    started,
+		Uptime:     time.Since(started).Truncate(time.Second).String(),
+	}
+}
+
+// serverVersionQueries select the server version per GORM dialect.
+var serverVersionQueries = map[string]string{
+	"sqlite":   "SELECT sqlite_version()",
+	"mysql":    "SELECT VERSION()",
+	"postgres": "SHOW server_version",
+}
+
+func (s *Service) databaseInfo(ctx context.Context) DatabaseInfo {
+	db := s.db.WithContext(ctx)
+
+	info := DatabaseInfo{
+		Dialect: db.Dialector.Name(),
+		Schema:  "Migrated automatically at startup (GORM AutoMigrate); matches application version " + version.Get(),
+	}
+
+	if query, ok := serverVersionQueries[info.Dialect]; ok {
+		if err := db.Raw(query).Scan(&info.ServerVersion).Error; err != nil {
+			log.Debug().Err(err).Msg("failed to query database server version")
+		}
+	}
+
+	if tables, err := db.Migrator().GetTables(); err == nil {
+		info.Tables = len(tables)
+	} else {
+		log.Debug().Err(err).Msg("failed to list database tables")
+	}
+
+	return info
+}
+
+// sessionStore describes the session storage opened for the database engine.
+func sessionStore(engine string) string {
+	switch engine {
+	case "sqlite":
+		return "SQLite (separate sessions database file)"
+	case "postgres":
+		return "PostgreSQL (sessions table)"
+	default:
+		return "MySQL (sessions table)"
+	}
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
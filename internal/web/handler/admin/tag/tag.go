@@ -116,7 +116,7 @@ func (s *Service) Create(c fiber.Ctx) error {
 	}
 
 	if err := c.Bind().Body(&in); err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString(errInvalidFormData)
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
 	}
 
 	if in.Name == "" {
@@ -154,7 +154,7 @@ func (s *Service) Edit(c fiber.Ctx) error {
 	var tag models.Tag
 	if err := s.db.First(&tag, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).SendString(errTagNotFound)
+			return fiber.NewError(fiber.StatusNotFound, errTagNotFound)
 		}
 
 		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadTag, nil)
@@ -180,7 +180,7 @@ func (s *Service) Update(c fiber.Ctx) error {
 	var tag models.Tag
 	if err := s.db.First(&tag, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).SendString(errTagNotFound)
+			return fiber.NewError(fiber.StatusNotFound, errTagNotFound)
 		}
 
 		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadTag, nil)
@@ -198,7 +198,7 @@ func (s *Service) Update(c fiber.Ctx) error {
 	}
 
 	if err := c.Bind().Body(&in); err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString(errInvalidFormData)
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
 	}
 
 	if in.Name == "" {
@@ -233,13 +233,13 @@ func (s *Service) Delete(c fiber.Ctx) error {
 
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString(errInvalidTagID)
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidTagID)
 	}
 
 	var tag models.Tag
 	if err = s.db.First(&tag, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).SendString(errTagNotFound)
+			return fiber.NewError(fiber.StatusNotFound, errTagNotFound)
 		}
 
 		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadTag, nil)
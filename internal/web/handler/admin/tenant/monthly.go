@@ -0,0 +1,129 @@
+package tenant
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathMonthly is the path for the monthly usage of the tenants.
+	PathMonthly = PathList + "/monthly"
+	// PathMonthlyExport is the path of the export of the usage of a month;
+	// .csv or .json follows.
+	PathMonthlyExport = PathMonthly + "/export"
+
+	templateMonthly = "admin/tenant/monthly"
+
+	labelMonthlyUsage = "Monthly Usage"
+)
+
+// Monthly renders the usage of the tenants in the month of the query, the
+// current month by default.
+func (s *Service) Monthly(c fiber.Ctx) error {
+	month, err := queryMonth(c)
+	if err != nil {
+		return err
+	}
+
+	months, err := tenant.Months(s.db)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list the months of tenant usage")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load the months of tenant usage", nil)
+	}
+
+	summary, err := tenant.MonthlySummary(s.db, month)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("month", month).Msg("failed to load the tenant usage of a month")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load the tenant usage of the month", nil)
+	}
+
+	var sentAt *time.Time
+
+	for i := range months {
+		if months[i].Month == month {
+			sentAt = months[i].SentAt
+		}
+	}
+
+	nav := navigation.NewContext(labelMonthlyUsage, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelTenants, PathList, false).
+		AddBreadcrumb(labelMonthlyUsage, "", true)
+
+	return c.Render(templateMonthly, fiber.Map{
+		"Navigation": nav,
+		"Months":     months,
+		"Summary":    summary,
+		"Current":    month == tenant.Month(time.Now()),
+		"SentAt":     sentAt,
+		"Webhook":    s.cfg.Usage.WebhookURL != "",
+	}, handler.BaseLayout)
+}
+
+// ExportCSV downloads the usage of the tenants in the month of the query as
+// CSV.
+func (s *Service) ExportCSV(c fiber.Ctx) error {
+	summary, err := s.exportSummary(c)
+	if err != nil {
+		return err
+	}
+
+	c.Attachment("tenant-usage-" + summary.Month + ".csv")
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+
+	return summary.WriteCSV(c.Response().BodyWriter())
+}
+
+// ExportJSON downloads the usage of the tenants in the month of the query as
+// JSON, the body of the webhook request of the month.
+func (s *Service) ExportJSON(c fiber.Ctx) error {
+	summary, err := s.exportSummary(c)
+	if err != nil {
+		return err
+	}
+
+	c.Attachment("tenant-usage-" + summary.Month + ".json")
+
+	return c.JSON(summary)
+}
+
+// exportSummary returns the usage of the tenants in the month of the query.
+func (s *Service) exportSummary(c fiber.Ctx) (*tenant.Summary, error) {
+	month, err := queryMonth(c)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := tenant.MonthlySummary(s.db, month)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("month", month).Msg("failed to load the tenant usage of a month")
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to load the tenant usage of the month")
+	}
+
+	return summary, nil
+}
+
+// queryMonth returns the month of the query, the current month when it has
+// none.
+func queryMonth(c fiber.Ctx) (string, error) {
+	month := c.Query("month")
+	if month == "" {
+		return tenant.Month(time.Now()), nil
+	}
+
+	if !tenant.ValidMonth(month) {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Invalid month, e.g. 2026-10")
+	}
+
+	return month, nil
+}
@@ -0,0 +1,379 @@
+// Package tenant provides the admin pages on which the provider manages
+// tenants and the zones assigned to them (see internal/tenant).
+package tenant
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathList is the path for the tenant list.
+	PathList = handler.RootPath + "admin/tenants"
+	// PathNew is the path for creating a tenant.
+	PathNew = PathList + "/new"
+	// PathEdit is the path for editing a tenant.
+	PathEdit = PathList + "/:id/edit"
+	// PathDelete is the path for deleting a tenant.
+	PathDelete = PathList + "/:id/delete"
+	// PathZones is the path for the zones of a tenant.
+	PathZones = PathList + "/:id/zones"
+	// PathAssign is the path for assigning zones to a tenant.
+	PathAssign = PathList + "/:id/assign"
+	// PathRelease is the path for giving a zone of a tenant back to the
+	// provider.
+	PathRelease = PathList + "/:id/release"
+
+	templateList  = "admin/tenant/list"
+	templateForm  = "admin/tenant/form"
+	templateZones = "admin/tenant/zones"
+
+	navSection    = "admin"
+	navSubsection = "tenants"
+
+	labelTenants    = "Tenants"
+	labelNewTenant  = "New Tenant"
+	labelEditTenant = "Edit Tenant"
+
+	errTenantNotFound  = "Tenant not found"
+	errInvalidFormData = "Invalid form data"
+)
+
+// Service is the tenant handler service.
+type Service struct {
+	handler.Service
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// Handler is the tenant handler.
+var Handler = Service{}
+
+// form is the submitted tenant form.
+type form struct {
+	Name              string `form:"name"`
+	Description       string `form:"description"`
+	MaxZones          int    `form:"max_zones"`
+	MaxRecordsPerZone int    `form:"max_records_per_zone"`
+	MaxAPIRequests    int    `form:"max_api_requests"`
+}
+
+// Init initializes the tenant handler.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.cfg = cfg
+
+	perm := auth.RequirePermission(authService, auth.PermAdminTenants)
+
+	app.Get(PathList, perm, s.List)
+	app.Get(PathNew, perm, s.New)
+	app.Post(PathNew, perm, s.Create)
+	app.Get(PathEdit, perm, s.Edit)
+	app.Post(PathEdit, perm, s.Update)
+	app.Post(PathDelete, perm, s.Delete)
+	app.Get(PathZones, perm, s.Zones)
+	app.Post(PathAssign, perm, s.Assign)
+	app.Post(PathRelease, perm, s.Release)
+	app.Get(PathUsage, perm, s.Usage)
+	app.Get(PathMonthly, perm, s.Monthly)
+	app.Get(PathMonthlyExport+".csv", perm, s.ExportCSV)
+	app.Get(PathMonthlyExport+".json", perm, s.ExportJSON)
+
+	app.Get(PathOwnUsage, auth.RequirePermission(authService, auth.PermTenantUsage), s.OwnUsage)
+}
+
+// List renders the tenant list.
+func (s *Service) List(c fiber.Ctx) error {
+	nav := navigation.NewContext(labelTenants, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelTenants, PathList, true)
+
+	tenants, err := tenant.List(s.db)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list tenants")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load tenants", nil)
+	}
+
+	counts, err := tenant.CountAll(s.db)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to count the resources of tenants")
+	}
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Tenants":    tenants,
+		"Counts":     counts,
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// New renders the create tenant form.
+func (s *Service) New(c fiber.Ctx) error {
+	return s.renderForm(c, fiber.StatusOK, &models.Tenant{}, "")
+}
+
+// Create handles the create tenant form submission.
+func (s *Service) Create(c fiber.Ctx) error {
+	var in form
+	if err := c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	t := &models.Tenant{}
+	if msg := apply(t, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, t, msg)
+	}
+
+	if err := s.db.Create(t).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to create tenant")
+		return s.renderForm(c, fiber.StatusInternalServerError, t, "Failed to create tenant: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Tenant "+t.Name+" created."))
+}
+
+// Edit renders the edit tenant form.
+func (s *Service) Edit(c fiber.Ctx) error {
+	t, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	return s.renderForm(c, fiber.StatusOK, t, "")
+}
+
+// Update handles the edit tenant form submission.
+func (s *Service) Update(c fiber.Ctx) error {
+	t, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	var in form
+	if err = c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	if msg := apply(t, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, t, msg)
+	}
+
+	if err = s.db.Save(t).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to update tenant")
+		return s.renderForm(c, fiber.StatusInternalServerError, t, "Failed to update tenant: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Tenant "+t.Name+" updated."))
+}
+
+// Delete handles tenant deletion. Tenants that still have users, groups or
+// zones are kept.
+func (s *Service) Delete(c fiber.Ctx) error {
+	t, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	if err = tenant.Delete(s.db, t); errors.Is(err, tenant.ErrInUse) {
+		return redirectError(c, PathList, "Tenant "+t.Name+
+			" still has users, groups or zones. Move or delete them first.")
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to delete tenant")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Delete Failed",
+			"Failed to delete tenant", nil)
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("Tenant "+t.Name+" deleted."))
+}
+
+// Zones renders the zones assigned to a tenant.
+func (s *Service) Zones(c fiber.Ctx) error {
+	t, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	zones, err := tenant.Zones(s.db, t.ID)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list the zones of a tenant")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load the zones of the tenant", nil)
+	}
+
+	nav := navigation.NewContext(t.Name, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelTenants, PathList, false).
+		AddBreadcrumb(t.Name, "", true)
+
+	return c.Render(templateZones, fiber.Map{
+		"Navigation": nav,
+		"Tenant":     t,
+		"Zones":      zones,
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// Assign assigns the submitted zones to the tenant. Zones of another tenant
+// move to this one.
+func (s *Service) Assign(c fiber.Ctx) error {
+	t, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	back := zonesPath(t.ID)
+
+	var zones []string
+
+	for field := range strings.FieldsFuncSeq(strings.ToLower(c.FormValue("zones")), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		if !strings.HasSuffix(field, ".") {
+			field += "."
+		}
+
+		zones = append(zones, field)
+	}
+
+	if len(zones) == 0 {
+		return redirectError(c, back, "Name at least one zone.")
+	}
+
+	for _, zone := range zones {
+		if err = tenant.AssignZone(s.db, zone, &t.ID); err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zone).Msg("failed to assign zone to tenant")
+			return redirectError(c, back, "Failed to assign "+zone+": "+err.Error())
+		}
+	}
+
+	return c.Redirect().To(back + "?success=" + url.QueryEscape(
+		strconv.Itoa(len(zones))+" zones assigned to "+t.Name+"."))
+}
+
+// Release gives the submitted zone of the tenant back to the provider.
+func (s *Service) Release(c fiber.Ctx) error {
+	t, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	back := zonesPath(t.ID)
+	zone := c.FormValue("zone")
+
+	owner, err := tenant.OfZone(s.db, zone)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zone).Msg("failed to load the tenant of a zone")
+		return redirectError(c, back, "Failed to release "+zone+": "+err.Error())
+	}
+
+	if owner == nil || *owner != t.ID {
+		return redirectError(c, back, "Zone "+zone+" is not assigned to "+t.Name+".")
+	}
+
+	if err = tenant.AssignZone(s.db, zone, nil); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zone).Msg("failed to release zone of tenant")
+		return redirectError(c, back, "Failed to release "+zone+": "+err.Error())
+	}
+
+	return c.Redirect().To(back + "?success=" + url.QueryEscape("Zone "+zone+" released to the provider."))
+}
+
+// load returns the tenant of the :id parameter, or renders the error page and
+// returns its result as the error.
+func (s *Service) load(c fiber.Ctx) (*models.Tenant, error) {
+	t, err := tenant.Get(s.db, fiber.Params[uint](c, "id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, errTenantNotFound)
+	}
+
+	if err != nil {
+		return nil, handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load tenant", nil)
+	}
+
+	return t, nil
+}
+
+// apply validates the submitted form and copies it to t. It returns the
+// message of the first invalid field, or "".
+func apply(t *models.Tenant, in *form) string {
+	t.Name = strings.ToLower(strings.TrimSpace(in.Name))
+	t.Description = strings.TrimSpace(in.Description)
+	t.MaxZones = in.MaxZones
+	t.MaxRecordsPerZone = in.MaxRecordsPerZone
+	t.MaxAPIRequests = in.MaxAPIRequests
+
+	if t.Name == "" || len(t.Name) > 64 || strings.ContainsFunc(t.Name, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_'
+	}) {
+		return "Name must be 1 to 64 letters, digits, dashes or underscores, e.g. acme-hosting"
+	}
+
+	if len(t.Description) > 255 {
+		return "Description must be at most 255 characters"
+	}
+
+	if t.MaxZones < 0 || t.MaxRecordsPerZone < 0 || t.MaxAPIRequests < 0 {
+		return "Quotas must be 0, for unlimited, or more"
+	}
+
+	return ""
+}
+
+// renderForm renders the tenant form; t.ID is 0 for a new one.
+func (s *Service) renderForm(c fiber.Ctx, status int, t *models.Tenant, errMsg string) error {
+	title := labelEditTenant
+	if t.ID == 0 {
+		title = labelNewTenant
+	}
+
+	nav := navigation.NewContext(title, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelTenants, PathList, false).
+		AddBreadcrumb(title, "", true)
+
+	return c.Status(status).Render(templateForm, fiber.Map{
+		"Navigation": nav,
+		"Tenant":     t,
+		"IsCreate":   t.ID == 0,
+		"Error":      errMsg,
+	}, handler.BaseLayout)
+}
+
+// zonesPath returns the path of the zones of the tenant id.
+func zonesPath(id uint) string {
+	return strings.Replace(PathZones, ":id", strconv.FormatUint(uint64(id), 10), 1)
+}
+
+// redirectError redirects to back with msg as the error message.
+func redirectError(c fiber.Ctx, back, msg string) error {
+	return c.Redirect().To(back + "?error=" + url.QueryEscape(msg))
+}
@@ -0,0 +1,210 @@
+package tenant
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+)
+
+// recordingViews renders the template name and keeps the data of the last
+// render.
+type recordingViews struct {
+	data fiber.Map
+}
+
+func (*recordingViews) Load() error { return nil }
+
+func (v *recordingViews) Render(w io.Writer, name string, data any, _ ...string) error {
+	v.data, _ = data.(fiber.Map)
+	_, _ = io.WriteString(w, name)
+
+	return nil
+}
+
+func newTestService(t *testing.T) (*fiber.App, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.Tenant{}, &models.User{}, &models.Group{},
+		&models.ZoneTenant{}, &models.TenantUsage{}, &models.UsageMonth{}, &models.Integration{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	svc := &Service{db: db}
+
+	app := fiber.New(fiber.Config{Views: &recordingViews{}})
+	app.Post(PathNew, svc.Create)
+	app.Post(PathEdit, svc.Update)
+	app.Post(PathDelete, svc.Delete)
+	app.Post(PathAssign, svc.Assign)
+	app.Post(PathRelease, svc.Release)
+	app.Get(PathMonthlyExport+".csv", svc.ExportCSV)
+	app.Get(PathMonthlyExport+".json", svc.ExportJSON)
+
+	return app, db
+}
+
+func post(t *testing.T, app *fiber.App, path string, form url.Values) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp
+}
+
+func TestCreateAndUpdate(t *testing.T) {
+	app, db := newTestService(t)
+
+	resp := post(t, app, PathNew, url.Values{"name": {"ACME Hosting"}})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("create with an invalid name: status %d, want 400", resp.StatusCode)
+	}
+
+	resp = post(t, app, PathNew, url.Values{"name": {"ACME-Hosting"}, "description": {" ACME Ltd. "}})
+	if resp.StatusCode != http.StatusSeeOther && resp.StatusCode != http.StatusFound {
+		t.Fatalf("create: status %d", resp.StatusCode)
+	}
+
+	got, err := tenant.Get(db, 1)
+	if err != nil || got.Name != "acme-hosting" || got.Description != "ACME Ltd." {
+		t.Fatalf("created tenant = %+v, %v", got, err)
+	}
+
+	post(t, app, "/admin/tenants/1/edit", url.Values{"name": {"acme"}})
+
+	if got, err = tenant.Get(db, 1); err != nil || got.Name != "acme" {
+		t.Errorf("updated tenant = %+v, %v", got, err)
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	app, db := newTestService(t)
+
+	resp := post(t, app, PathNew, url.Values{"name": {"acme"}, "max_zones": {"-1"}})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("create with a negative quota: status %d, want 400", resp.StatusCode)
+	}
+
+	post(t, app, PathNew, url.Values{
+		"name": {"acme"}, "max_zones": {"10"}, "max_records_per_zone": {"500"}, "max_api_requests": {"60"},
+	})
+
+	got, err := tenant.Get(db, 1)
+	if err != nil || got.MaxZones != 10 || got.MaxRecordsPerZone != 500 || got.MaxAPIRequests != 60 {
+		t.Fatalf("created tenant = %+v, %v", got, err)
+	}
+
+	post(t, app, "/admin/tenants/1/edit", url.Values{"name": {"acme"}, "max_zones": {"0"}})
+
+	if got, err = tenant.Get(db, 1); err != nil || got.MaxZones != 0 || got.MaxAPIRequests != 0 {
+		t.Errorf("updated tenant = %+v, %v", got, err)
+	}
+}
+
+func TestZonesAndDelete(t *testing.T) {
+	app, db := newTestService(t)
+
+	acme := &models.Tenant{Name: "acme"}
+	globex := &models.Tenant{Name: "globex"}
+	db.Create(acme)
+	db.Create(globex)
+
+	resp := post(t, app, "/admin/tenants/1/assign", url.Values{"zones": {"Example.com\nexample.org."}})
+	if loc := resp.Header.Get("Location"); !strings.HasPrefix(loc, "/admin/tenants/1/zones?success=") {
+		t.Errorf("assign redirected to %q, want the zones of the tenant", loc)
+	}
+
+	if zones, _ := tenant.Zones(db, acme.ID); strings.Join(zones, " ") != "example.com. example.org." {
+		t.Errorf("zones of acme = %v", zones)
+	}
+
+	resp = post(t, app, "/admin/tenants/1/delete", nil)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "error=") {
+		t.Errorf("delete of a tenant with zones redirected to %q, want an error", loc)
+	}
+
+	resp = post(t, app, "/admin/tenants/2/release", url.Values{"zone": {"example.com."}})
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "error=") {
+		t.Errorf("release from another tenant redirected to %q, want an error", loc)
+	}
+
+	for _, zone := range []string{"example.com.", "example.org."} {
+		post(t, app, "/admin/tenants/1/release", url.Values{"zone": {zone}})
+	}
+
+	if owner, _ := tenant.OfZone(db, "example.com."); owner != nil {
+		t.Errorf("tenant of example.com. after release = %d, want the provider", *owner)
+	}
+
+	resp = post(t, app, "/admin/tenants/1/delete", nil)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "success=") {
+		t.Errorf("delete redirected to %q, want success", loc)
+	}
+}
+
+func TestMonthlyExport(t *testing.T) {
+	app, db := newTestService(t)
+
+	acme := &models.Tenant{Name: "acme"}
+	db.Create(acme)
+
+	if err := tenant.RecordUsage(db, acme, "2026-10", 2, 30, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) (*http.Response, string) {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, http.NoBody)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		return resp, string(body)
+	}
+
+	resp, body := get(PathMonthlyExport + ".csv?month=2026-10")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "2026-10,1,acme,2,30,100") {
+		t.Errorf("CSV export = %d %q", resp.StatusCode, body)
+	}
+
+	if cd := resp.Header.Get("Content-Disposition"); !strings.Contains(cd, "tenant-usage-2026-10.csv") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	resp, body = get(PathMonthlyExport + ".json?month=2026-10")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"api_requests":100`) ||
+		!strings.Contains(body, `"event":"tenant_usage.monthly"`) {
+		t.Errorf("JSON export = %d %q", resp.StatusCode, body)
+	}
+
+	if resp, _ = get(PathMonthlyExport + ".csv?month=october"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("export of an invalid month: status %d, want 400", resp.StatusCode)
+	}
+}
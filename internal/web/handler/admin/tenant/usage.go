@@ -0,0 +1,83 @@
+package tenant
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathUsage is the path for the usage of a tenant.
+	PathUsage = PathList + "/:id/usage"
+	// PathOwnUsage is the path on which users of a tenant see the usage of
+	// their tenant.
+	PathOwnUsage = handler.RootPath + "tenant/usage"
+
+	templateUsage = "admin/tenant/usage"
+
+	labelUsage = "Usage"
+)
+
+// Usage renders the quotas and usage of a tenant.
+func (s *Service) Usage(c fiber.Ctx) error {
+	t, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	nav := navigation.NewContext(t.Name+" "+labelUsage, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelTenants, PathList, false).
+		AddBreadcrumb(t.Name, zonesPath(t.ID), false).
+		AddBreadcrumb(labelUsage, "", true)
+
+	return s.renderUsage(c, nav, t, true)
+}
+
+// OwnUsage renders the quotas and usage of the tenant of the current user.
+// Users of the provider have no quotas and are sent to the dashboard.
+func (s *Service) OwnUsage(c fiber.Ctx) error {
+	user, _ := c.Locals("CurrentUser").(models.User)
+	if user.TenantID == nil {
+		return c.Redirect().To(dashboard.Path + "?error=" + url.QueryEscape("Only users of a tenant have quotas."))
+	}
+
+	t, err := tenant.Get(s.db, *user.TenantID)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to load the tenant of the user")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load tenant", nil)
+	}
+
+	nav := navigation.NewContext("Tenant "+labelUsage, "tenant", "usage").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Tenant "+labelUsage, "", true)
+
+	return s.renderUsage(c, nav, t, false)
+}
+
+// renderUsage renders the usage of t; admin links the zones to the tenant
+// administration.
+func (s *Service) renderUsage(c fiber.Ctx, nav *navigation.Context, t *models.Tenant, admin bool) error {
+	usage, err := tenant.UsageOf(s.db, t, time.Now())
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to load the usage of a tenant")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load the usage of the tenant", nil)
+	}
+
+	return c.Render(templateUsage, fiber.Map{
+		"Navigation": nav,
+		"Usage":      usage,
+		"Admin":      admin,
+	}, handler.BaseLayout)
+}
@@ -0,0 +1,325 @@
+// Package tsigkey provides the admin pages that manage the TSIG keys signing
+// RFC 2136 dynamic updates sent to the update gateway (see
+// internal/dnsupdate).
+package tsigkey
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsupdate"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathList is the path for the TSIG key list.
+	PathList = handler.RootPath + "admin/tsig-keys"
+	// PathNew is the path for creating a TSIG key.
+	PathNew = PathList + "/new"
+	// PathEdit is the path for editing a TSIG key.
+	PathEdit = PathList + "/:id/edit"
+	// PathDelete is the path for deleting a TSIG key.
+	PathDelete = PathList + "/:id/delete"
+
+	templateList = "admin/tsigkey/list"
+	templateForm = "admin/tsigkey/form"
+
+	navSection    = "admin"
+	navSubsection = "tsig-keys"
+
+	labelTSIGKeys   = "TSIG Keys"
+	labelNewTSIGKey = "New TSIG Key"
+	labelEditKey    = "Edit TSIG Key"
+
+	errKeyNotFound     = "TSIG key not found"
+	errInvalidFormData = "Invalid form data"
+
+	// secretSize is the length of generated secrets in bytes, the output
+	// size of HMAC-SHA256.
+	secretSize = 32
+
+	// defaultRecordTypes are the record types offered for new keys, those a
+	// DHCP server updates.
+	defaultRecordTypes = "A,AAAA,PTR,TXT,DHCID"
+)
+
+// Service is the TSIG key handler service.
+type Service struct {
+	handler.Service
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// Handler is the TSIG key handler.
+var Handler = Service{}
+
+// form is the submitted TSIG key form.
+type form struct {
+	Name        string `form:"name"`
+	Algorithm   string `form:"algorithm"`
+	Secret      string `form:"secret"`
+	Zones       string `form:"zones"`
+	RecordTypes string `form:"record_types"`
+	Enabled     bool   `form:"enabled"`
+}
+
+// Init initializes the TSIG key handler.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.cfg = cfg
+
+	perm := auth.RequirePermission(authService, auth.PermAdminTSIGKeys)
+
+	app.Get(PathList, perm, s.List)
+	app.Get(PathNew, perm, s.New)
+	app.Post(PathNew, perm, s.Create)
+	app.Get(PathEdit, perm, s.Edit)
+	app.Post(PathEdit, perm, s.Update)
+	app.Post(PathDelete, perm, s.Delete)
+}
+
+// List renders the TSIG key list.
+func (s *Service) List(c fiber.Ctx) error {
+	return s.renderList(c, nil)
+}
+
+// New renders the create TSIG key form.
+func (s *Service) New(c fiber.Ctx) error {
+	return s.renderForm(c, fiber.StatusOK, &models.TSIGKey{
+		Algorithm:   dnsupdate.AlgorithmHMACSHA256,
+		RecordTypes: defaultRecordTypes,
+		Enabled:     true,
+	}, "")
+}
+
+// Create handles the create TSIG key form submission. Without a pasted
+// secret, a random one is generated. The list then shows the secret once.
+func (s *Service) Create(c fiber.Ctx) error {
+	var in form
+	if err := c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	key := &models.TSIGKey{}
+	if msg := apply(key, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, key, msg)
+	}
+
+	if key.Secret == "" {
+		secret := make([]byte, secretSize)
+		_, _ = rand.Read(secret)
+		key.Secret = base64.StdEncoding.EncodeToString(secret)
+	}
+
+	if err := s.db.Create(key).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to create TSIG key")
+		return s.renderForm(c, fiber.StatusInternalServerError, key, "Failed to create TSIG key: "+err.Error())
+	}
+
+	return s.renderList(c, key)
+}
+
+// Edit renders the edit TSIG key form.
+func (s *Service) Edit(c fiber.Ctx) error {
+	key, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	return s.renderForm(c, fiber.StatusOK, key, "")
+}
+
+// Update handles the edit TSIG key form submission. An empty secret keeps
+// the stored one.
+func (s *Service) Update(c fiber.Ctx) error {
+	key, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	var in form
+	if err = c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, errInvalidFormData)
+	}
+
+	if msg := apply(key, &in); msg != "" {
+		return s.renderForm(c, fiber.StatusBadRequest, key, msg)
+	}
+
+	if err = s.db.Save(key).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to update TSIG key")
+		return s.renderForm(c, fiber.StatusInternalServerError, key, "Failed to update TSIG key: "+err.Error())
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("TSIG key "+key.Name+" updated."))
+}
+
+// Delete handles TSIG key deletion.
+func (s *Service) Delete(c fiber.Ctx) error {
+	key, err := s.load(c)
+	if err != nil {
+		return err
+	}
+
+	if err = s.db.Delete(key).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to delete TSIG key")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Delete Failed",
+			"Failed to delete TSIG key", nil)
+	}
+
+	return c.Redirect().To(PathList + "?success=" + url.QueryEscape("TSIG key "+key.Name+" deleted."))
+}
+
+// load returns the TSIG key of the :id parameter, or renders the error page
+// and returns its result as the error.
+func (s *Service) load(c fiber.Ctx) (*models.TSIGKey, error) {
+	var key models.TSIGKey
+
+	err := s.db.First(&key, fiber.Params[uint](c, "id")).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, errKeyNotFound)
+	}
+
+	if err != nil {
+		return nil, handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load TSIG key", nil)
+	}
+
+	return &key, nil
+}
+
+// apply validates the submitted form and copies it to key. It returns the
+// message of the first invalid field, or "".
+func apply(key *models.TSIGKey, in *form) string {
+	key.Name = strings.ToLower(strings.TrimSpace(in.Name))
+	if key.Name != "" && !strings.HasSuffix(key.Name, ".") {
+		key.Name += "."
+	}
+
+	key.Algorithm = in.Algorithm
+	key.Zones = in.Zones
+	key.RecordTypes = in.RecordTypes
+	key.Enabled = in.Enabled
+
+	if !validName(key.Name) {
+		return "Name must be a domain name, e.g. dhcp-updater"
+	}
+
+	if !slices.Contains(dnsupdate.Algorithms, key.Algorithm) {
+		return "Choose one of the offered algorithms"
+	}
+
+	if secret := strings.Join(strings.Fields(in.Secret), ""); secret != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(secret); err != nil || len(decoded) == 0 {
+			return "Secret must be base64-encoded, as in the key files of BIND and ISC dhcpd"
+		}
+
+		key.Secret = secret
+	}
+
+	zones, err := auth.ParseZonePatterns(in.Zones)
+	if err != nil {
+		return err.Error()
+	}
+
+	key.Zones = strings.Join(zones, "\n")
+
+	key.RecordTypes = models.NormalizeRecordTypes(in.RecordTypes)
+	if key.RecordTypes == "" {
+		return "Name at least one record type the key may change"
+	}
+
+	return ""
+}
+
+// validName reports whether name is a fully qualified domain name.
+func validName(name string) bool {
+	if len(name) > 255 {
+		return false
+	}
+
+	for label := range strings.SplitSeq(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// renderList renders the TSIG key list; created is the key just created,
+// whose secret is shown once, or nil.
+func (s *Service) renderList(c fiber.Ctx, created *models.TSIGKey) error {
+	nav := navigation.NewContext(labelTSIGKeys, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelTSIGKeys, PathList, true)
+
+	var keys []models.TSIGKey
+	if err := s.db.Order(handler.OrderNameASC).Find(&keys).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list TSIG keys")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load TSIG keys", nil)
+	}
+
+	success := c.Query("success")
+	if created != nil {
+		success = "TSIG key " + created.Name + " created."
+	}
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Keys":       keys,
+		"Created":    created,
+		"Listen":     s.cfg.DNSUpdate.Listen,
+		"Success":    success,
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// renderForm renders the TSIG key form; key.ID is 0 for a new one.
+func (s *Service) renderForm(c fiber.Ctx, status int, key *models.TSIGKey, errMsg string) error {
+	title := labelEditKey
+	if key.ID == 0 {
+		title = labelNewTSIGKey
+	}
+
+	nav := navigation.NewContext(title, navSection, navSubsection).
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Administration", "", false).
+		AddBreadcrumb(labelTSIGKeys, PathList, false).
+		AddBreadcrumb(title, "", true)
+
+	return c.Status(status).Render(templateForm, fiber.Map{
+		"Navigation": nav,
+		"Key":        key,
+		"IsCreate":   key.ID == 0,
+		"Algorithms": dnsupdate.Algorithms,
+		"Error":      errMsg,
+	}, handler.BaseLayout)
+}
@@ -0,0 +1,112 @@
+package user
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// APIPath is the JSON user list.
+const APIPath = handler.APIPathPrefix + "admin/users"
+
+// apiColumns are the sort keys of the JSON user list.
+var apiColumns = handler.ListColumns{
+	"id":           "id",
+	"username":     "username",
+	"email":        "email",
+	"display_name": "display_name",
+	"auth_source":  "auth_source",
+	"active":       "active",
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+}
+
+// APIUser is a row of the JSON user list.
+type APIUser struct {
+	ID             uint64            `json:"id"`
+	Username       string            `json:"username"`
+	Email          string            `json:"email"`
+	DisplayName    string            `json:"display_name"`
+	FirstName      string            `json:"first_name"`
+	LastName       string            `json:"last_name"`
+	AuthSource     models.AuthSource `json:"auth_source"`
+	RoleID         uint              `json:"role_id"`
+	Role           string            `json:"role"`
+	Active         bool              `json:"active"`
+	Pending        bool              `json:"pending"`
+	ServiceAccount bool              `json:"service_account"`
+	TOTPEnabled    bool              `json:"totp_enabled"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
+}
+
+// ListJSON returns a page of users, searched like the user list and
+// optionally filtered by ?active=true|false, ?auth_source=, a username
+// ?prefix= and ?modified_since=.
+func (s *Service) ListJSON(c fiber.Ctx) error {
+	req, err := handler.ParseListRequest(c, apiColumns, "id", true)
+	if err != nil {
+		return handler.ListError(c, err)
+	}
+
+	active, authSource := c.Query("active"), c.Query("auth_source")
+
+	var users []models.User
+
+	resp, err := req.Find(s.scoped(c).Preload("Role"), &models.User{}, func(tx *gorm.DB) *gorm.DB {
+		if req.Search != "" {
+			tx = tx.Scopes(like.Contains(req.Search,
+				"username", "email", "external_id", "display_name", "first_name", "last_name"))
+		}
+
+		if active != "" {
+			tx = tx.Where("active = ?", active == "true")
+		}
+
+		if authSource != "" {
+			tx = tx.Where("auth_source = ?", authSource)
+		}
+
+		return tx.Scopes(req.Scope("username", "updated_at"))
+	}, &users)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("query users failed")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, "failed to load users", nil)
+	}
+
+	rows := make([]APIUser, len(users))
+	for i := range users {
+		u := &users[i]
+		rows[i] = APIUser{
+			ID:             u.ID,
+			Username:       u.Username,
+			Email:          u.Email,
+			DisplayName:    u.DisplayName,
+			FirstName:      u.FirstName,
+			LastName:       u.LastName,
+			AuthSource:     u.AuthSource,
+			RoleID:         u.RoleID,
+			Role:           u.Role.Name,
+			Active:         u.Active,
+			Pending:        u.Pending,
+			ServiceAccount: u.ServiceAccount,
+			TOTPEnabled:    u.TOTPEnabled,
+			CreatedAt:      u.CreatedAt,
+			UpdatedAt:      u.UpdatedAt,
+			DeletedAt:      u.DeletedAt,
+		}
+	}
+
+	if resp.Data, err = handler.SelectFields(req.Fields, rows); err != nil {
+		return handler.ListError(c, err)
+	}
+
+	return c.JSON(resp)
+}
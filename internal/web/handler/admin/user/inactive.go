@@ -0,0 +1,136 @@
+package user
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/inactiveusers"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathInactive lists the users who have not logged in recently.
+	PathInactive = inactiveusers.ReportPath
+
+	// TemplateInactive is the template for the inactive user list.
+	TemplateInactive = "admin/user/inactive"
+
+	// maxInactiveDays bounds the days query parameter (ten years).
+	maxInactiveDays = 3650
+)
+
+// Inactive lists the users who have not logged in for the configured number of
+// days, or for the days query parameter.
+func (s *Service) Inactive(c fiber.Ctx) error {
+	days := s.inactiveDays(c.Query("days"))
+
+	candidates, err := s.inactiveCandidates(c, days)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find inactive users")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load users", nil)
+	}
+
+	nav := navigation.NewContext("Inactive Users", "admin", "user").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb("Users", Path, false).
+		AddBreadcrumb("Inactive", PathInactive, true)
+
+	return c.Render(TemplateInactive, fiber.Map{
+		"Navigation":     nav,
+		"Candidates":     candidates,
+		"Days":           days,
+		"Schedule":       s.cfg.InactiveUsers.Interval,
+		"AutoDeactivate": s.cfg.InactiveUsers.AutoDeactivate,
+		"Success":        c.Query("success"),
+	}, handler.BaseLayout)
+}
+
+// DeactivateInactive deactivates the selected inactive users. Selected users
+// that are not (or no longer) inactive are skipped.
+func (s *Service) DeactivateInactive(c fiber.Ctx) error {
+	days := s.inactiveDays(c.FormValue("days"))
+
+	candidates, err := s.inactiveCandidates(c, days)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find inactive users")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load users", nil)
+	}
+
+	selectedIDs := parseUintIDs(c, "user_ids")
+
+	ids := make([]uint64, len(selectedIDs))
+	for i, id := range selectedIDs {
+		ids[i] = uint64(id)
+	}
+
+	selected := inactiveusers.Select(candidates, ids)
+	if len(selected) == 0 {
+		return handler.RenderError(c, fiber.StatusBadRequest, "Nothing Selected",
+			"Select at least one inactive user to deactivate.", nil)
+	}
+
+	var actor *models.User
+	if current, ok := currentUser(c); ok {
+		actor = &current
+	}
+
+	if err = inactiveusers.Deactivate(s.db, selected, actor, c.IP()); err != nil {
+		log.Error().Err(err).Msg("failed to deactivate inactive users")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", "Failed to deactivate users", nil)
+	}
+
+	return c.Redirect().To(PathInactive + "?days=" + strconv.Itoa(days) + "&success=" +
+		url.QueryEscape(strconv.Itoa(len(selected))+" users deactivated."))
+}
+
+// inactiveDays parses raw as a number of days, falling back to the configured
+// default when it is missing or out of range.
+func (s *Service) inactiveDays(raw string) int {
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 1 || days > maxInactiveDays {
+		return s.cfg.InactiveUsers.Days
+	}
+
+	return days
+}
+
+// inactiveCandidates returns the users inactive for days, without the user
+// making the request and, for administrators of a tenant, without the users
+// of other tenants.
+func (s *Service) inactiveCandidates(c fiber.Ctx, days int) ([]inactiveusers.Candidate, error) {
+	candidates, err := inactiveusers.Find(s.db, inactiveusers.Cutoff(time.Now(), days))
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok := currentUser(c)
+	if !ok {
+		return candidates, nil
+	}
+
+	filtered := candidates[:0]
+
+	for i := range candidates {
+		if candidates[i].User.ID != current.ID &&
+			(current.TenantID == nil || tenant.Same(candidates[i].User.TenantID, current.TenantID)) {
+			filtered = append(filtered, candidates[i])
+		}
+	}
+
+	return filtered, nil
+}
+
+// currentUser returns the user making the request.
+func currentUser(c fiber.Ctx) (models.User, bool) {
+	current, ok := c.Locals("CurrentUser").(models.User)
+	return current, ok && current.ID != 0
+}
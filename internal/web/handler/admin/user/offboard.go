@@ -0,0 +1,162 @@
+package user
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/offboarding"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+// TemplateOffboard is the template for offboarding a user and its report.
+const TemplateOffboard = "admin/user/offboard"
+
+// offboardPath returns the offboarding page of the user id.
+func offboardPath(id uint64) string {
+	return Path + "/" + strconv.FormatUint(id, 10) + "/offboard"
+}
+
+// Offboard shows what the user owns and asks whether to deactivate or delete
+// the account and to whom its resources go.
+func (s *Service) Offboard(c fiber.Ctx) error {
+	user, ok, err := s.offboardUser(c)
+	if !ok {
+		return err
+	}
+
+	return s.renderOffboard(c, fiber.StatusOK, &user, nil, "")
+}
+
+// DoOffboard deactivates or deletes the user, transfers its resources when a
+// user to transfer them to is selected, and shows the offboarding report.
+func (s *Service) DoOffboard(c fiber.Ctx) error {
+	user, ok, err := s.offboardUser(c)
+	if !ok {
+		return err
+	}
+
+	mode := offboarding.Mode(c.FormValue("mode"))
+	if mode != offboarding.ModeDeactivate && mode != offboarding.ModeDelete {
+		return s.renderOffboard(c, fiber.StatusBadRequest, &user, nil, "Choose whether to deactivate or delete the user.")
+	}
+
+	if msg := s.offboardBlocked(c, &user, mode); msg != "" {
+		return s.renderOffboard(c, fiber.StatusForbidden, &user, nil, msg)
+	}
+
+	var target *models.User
+
+	if raw := c.FormValue("transfer_to"); raw != "" && raw != "0" {
+		var t models.User
+		if err := s.scoped(c).Where("id = ?", raw).First(&t).Error; err != nil {
+			return s.renderOffboard(c, fiber.StatusBadRequest, &user, nil, "Unknown user selected for the transfer.")
+		}
+
+		target = &t
+	}
+
+	var actor *models.User
+	if current, ok := currentUser(c); ok {
+		actor = &current
+	}
+
+	report, err := offboarding.Offboard(s.db, &user, mode, target, actor, c.IP())
+	if errors.Is(err, offboarding.ErrTransferToSelf) || errors.Is(err, offboarding.ErrTransferTargetInactive) {
+		return s.renderOffboard(c, fiber.StatusBadRequest, &user, nil, "Select another, active user for the transfer.")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to offboard user")
+		return s.renderOffboard(c, fiber.StatusInternalServerError, &user, nil, "Failed to offboard the user.")
+	}
+
+	return s.renderOffboard(c, fiber.StatusOK, &user, report, "")
+}
+
+// offboardUser loads the user of the id parameter. When ok is false the
+// response has been handled and err is to be returned.
+func (s *Service) offboardUser(c fiber.Ctx) (user models.User, ok bool, err error) {
+	id, convErr := strconv.Atoi(c.Params("id"))
+	if convErr != nil || id <= 0 {
+		return user, false, c.Redirect().To(Path)
+	}
+
+	if err := s.scoped(c).Preload("Role").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, false, c.Redirect().To(Path)
+		}
+
+		log.Error().Err(err).Int("user_id", id).Msg("failed to load user")
+
+		return user, false, handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load user.", nil)
+	}
+
+	return user, true, nil
+}
+
+// offboardBlocked returns why user cannot be offboarded in mode, or "".
+func (s *Service) offboardBlocked(c fiber.Ctx, user *models.User, mode offboarding.Mode) string {
+	if current, ok := currentUser(c); ok && current.ID == user.ID {
+		return "You cannot offboard your own account."
+	}
+
+	if mode == offboarding.ModeDelete && user.Role.Name == "admin" {
+		return "Cannot delete admin users."
+	}
+
+	if isLastActiveAdmin(s.db, user, user.RoleID, false) {
+		return "Cannot deactivate the last active admin."
+	}
+
+	return ""
+}
+
+// renderOffboard renders the offboarding form, or the report once done.
+func (s *Service) renderOffboard(
+	c fiber.Ctx,
+	status int,
+	user *models.User,
+	report *offboarding.Report,
+	errorMsg string,
+) error {
+	nav := navigation.NewContext("Offboard User", "admin", "user").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb("Users", Path, false).
+		AddBreadcrumb(user.Username, offboardPath(user.ID), true)
+
+	data := fiber.Map{
+		"Navigation": nav,
+		"User":       user,
+		"Report":     report,
+		"Error":      errorMsg,
+	}
+
+	if report == nil {
+		resources, err := offboarding.Collect(s.db, user.ID)
+		if err != nil {
+			log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to collect user resources")
+			return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load user.", nil)
+		}
+
+		var targets []models.User
+		if err := s.scoped(c).Where("active = ? AND id <> ?", true, user.ID).
+			Order("username ASC").Find(&targets).Error; err != nil {
+			log.Error().Err(err).Msg("failed to load users")
+			return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load users.", nil)
+		}
+
+		data["Resources"] = resources
+		data["Targets"] = targets
+		data["CanDelete"] = user.Role.Name != "admin"
+	}
+
+	return c.Status(status).Render(TemplateOffboard, data, handler.BaseLayout)
+}
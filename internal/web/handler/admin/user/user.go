@@ -4,6 +4,7 @@ package user
 import (
 	"errors"
 	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
@@ -12,11 +13,13 @@ import (
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/offboarding"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
-	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
 )
 
 const (
@@ -33,6 +36,8 @@ const (
 
 	// adminUsername is the reserved admin account username.
 	adminUsername = "admin"
+
+	errRoleNotGrantable = "You may not give users the selected role"
 )
 
 // Service provides CRUD operations for users.
@@ -40,6 +45,7 @@ type Service struct {
 	handler.Service
 	cfg       *config.Config
 	db        *gorm.DB
+	localAuth *auth.LocalProvider
 	validator *validator.Validate
 }
 
@@ -55,16 +61,23 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 
 	s.db = db
 	s.cfg = cfg
+	s.localAuth = auth.NewLocalProvider(db)
+	s.localAuth.SetPasswordPolicy(cfg.Auth.LocalDB.PasswordPolicy)
 	s.validator = validator.New()
 
 	// Routes
 	app.Get(Path, auth.RequirePermission(authService, auth.PermAdminUsers), s.List)
 	app.Get(Path+"/new", auth.RequirePermission(authService, auth.PermAdminUsers), s.New)
+	app.Get(PathInactive, auth.RequirePermission(authService, auth.PermAdminUsers), s.Inactive)
+	app.Post(PathInactive, auth.RequirePermission(authService, auth.PermAdminUsers), s.DeactivateInactive)
 	app.Post(Path, auth.RequirePermission(authService, auth.PermAdminUsers), s.Create)
 	app.Get(Path+"/:id/edit", auth.RequirePermission(authService, auth.PermAdminUsers), s.Edit)
 	app.Post(Path+"/:id", auth.RequirePermission(authService, auth.PermAdminUsers), s.Update)
 	app.Post(Path+"/:id/delete", auth.RequirePermission(authService, auth.PermAdminUsers), s.Delete)
+	app.Get(Path+"/:id/offboard", auth.RequirePermission(authService, auth.PermAdminUsers), s.Offboard)
+	app.Post(Path+"/:id/offboard", auth.RequirePermission(authService, auth.PermAdminUsers), s.DoOffboard)
 	app.Post(Path+"/:id/disable-totp", auth.RequirePermission(authService, auth.PermAdminUsers), s.DisableTOTP)
+	app.Get(APIPath, auth.RequirePermission(authService, auth.PermAdminUsers), s.ListJSON)
 }
 
 // listViewData and formViewData were initially planned as typed data holders, but this project uses
@@ -92,18 +105,12 @@ func (s *Service) List(c fiber.Ctx) error {
 	var (
 		users      []models.User
 		totalCount int64
-		tx         = s.db.Model(&models.User{})
+		tx         = s.scoped(c).Model(&models.User{})
 	)
 
 	if search != "" {
-		like := "%" + search + "%"
-		tx = tx.Where(
-			"username ILIKE ? OR email ILIKE ? OR external_id ILIKE ? OR display_name ILIKE ?",
-			like,
-			like,
-			like,
-			like,
-		)
+		tx = tx.Scopes(like.Contains(search,
+			"username", "email", "external_id", "display_name", "first_name", "last_name"))
 	}
 
 	if err := tx.Count(&totalCount).Error; err != nil {
@@ -126,7 +133,7 @@ func (s *Service) List(c fiber.Ctx) error {
 	}
 
 	offset := (page - 1) * pageSize
-	if err := tx.Preload("Role").Order("id DESC").Limit(pageSize).Offset(offset).Find(&users).Error; err != nil {
+	if err := tx.Preload("Role").Preload("Tenant").Order("id DESC").Limit(pageSize).Offset(offset).Find(&users).Error; err != nil {
 		log.Error().Err(err).Msg("query users failed")
 
 		return c.Status(fiber.StatusInternalServerError).Render(TemplateList, fiber.Map{
@@ -136,20 +143,15 @@ func (s *Service) List(c fiber.Ctx) error {
 		}, handler.BaseLayout)
 	}
 
-	// Get current user ID from the session
-	var currentUserID uint64
-
-	if sessionID := c.Cookies("session"); sessionID != "" {
-		sessionData := new(session.Data)
-		if err := sessionData.Read(sessionID); err == nil {
-			currentUserID = sessionData.User.ID
-		}
-	}
+	// Get current user ID
+	current, _ := currentUser(c)
+	currentUserID := current.ID
 
 	return c.Render(TemplateList, fiber.Map{
 		"Navigation":    nav,
 		"Users":         users,
 		"CurrentUserID": currentUserID,
+		"ShowTenants":   len(s.tenants(c)) > 0,
 		"Search":        search,
 		"Page":          page,
 		"PageSize":      pageSize,
@@ -171,7 +173,7 @@ func (s *Service) New(c fiber.Ctx) error {
 		AddBreadcrumb("New", Path+"/new", true)
 
 	var roles []models.Role
-	if err := s.db.Order(handler.OrderNameASC).Find(&roles).Error; err != nil {
+	if err := s.grantable(c).Order(handler.OrderNameASC).Find(&roles).Error; err != nil {
 		log.Error().Err(err).Msg("failed to load roles")
 
 		return c.Status(fiber.StatusInternalServerError).Render(TemplateForm, fiber.Map{
@@ -184,27 +186,35 @@ func (s *Service) New(c fiber.Ctx) error {
 	s.db.Order("name asc").Find(&allTags)
 
 	return c.Render(TemplateForm, fiber.Map{
-		"Navigation":  nav,
-		"User":        models.User{AuthSource: models.AuthSourceLocal, Active: true},
-		"IsCreate":    true,
-		"Roles":       roles,
-		"AllTags":     allTags,
-		"AssignedSet": map[uint]bool{},
+		"Navigation":           nav,
+		"User":                 models.User{AuthSource: models.AuthSourceLocal, Active: true, MustChangePassword: true},
+		"IsCreate":             true,
+		"Roles":                roles,
+		"Tenants":              s.tenants(c),
+		"TenantID":             uint(0),
+		"AllTags":              allTags,
+		"AssignedSet":          map[uint]bool{},
+		"PasswordRequirements": s.localAuth.PasswordRequirements(),
 	}, handler.BaseLayout)
 }
 
 // Create creates a new user.
 func (s *Service) Create(c fiber.Ctx) error {
 	var in struct {
-		Username     string `form:"username"      validate:"required,min=3,max=100"`
-		Email        string `form:"email"         validate:"required,email,max=255"`
-		DisplayName  string `form:"displayname"   validate:"max=255"`
-		AuthSource   string `form:"source"        validate:"required,oneof=local oidc ldap"`
-		ExternalID   string `form:"external_id"`
-		Password     string `form:"password"`
-		Active       bool   `form:"active"`
-		RoleID       uint   `form:"role_id"`
-		TOTPRequired bool   `form:"totp_required"`
+		Username       string `form:"username"      validate:"required,min=3,max=100"`
+		Email          string `form:"email"         validate:"required,email,max=255"`
+		DisplayName    string `form:"displayname"   validate:"max=255"`
+		FirstName      string `form:"firstname"     validate:"max=100"`
+		LastName       string `form:"lastname"      validate:"max=100"`
+		AuthSource     string `form:"source"        validate:"required,oneof=local oidc ldap"`
+		ExternalID     string `form:"external_id"`
+		Password       string `form:"password"`
+		Active         bool   `form:"active"`
+		RoleID         uint   `form:"role_id"`
+		TOTPRequired   bool   `form:"totp_required"`
+		ServiceAccount bool   `form:"service_account"`
+		// MustChangePassword asks the user to replace the initial password.
+		MustChangePassword bool `form:"must_change_password"`
 	}
 
 	if err := c.Bind().Body(&in); err != nil {
@@ -237,15 +247,32 @@ func (s *Service) Create(c fiber.Ctx) error {
 		}, handler.BaseLayout)
 	}
 
+	tenantID, err := s.formTenant(c, nil)
+	if err != nil {
+		nav := navigation.NewContext("Users", "admin", "user").
+			AddBreadcrumb("Home", dashboard.Path, false).
+			AddBreadcrumb("Admin", "#", false).
+			AddBreadcrumb("Users", Path, true)
+
+		return c.Status(fiber.StatusBadRequest).Render(TemplateList, fiber.Map{
+			"Navigation": nav,
+			"Error":      "Unknown tenant selected",
+		}, handler.BaseLayout)
+	}
+
 	user := models.User{
-		Username:     in.Username,
-		Email:        in.Email,
-		DisplayName:  in.DisplayName,
-		AuthSource:   models.AuthSource(in.AuthSource),
-		ExternalID:   in.ExternalID,
-		Active:       in.Active,
-		RoleID:       in.RoleID,
-		TOTPRequired: in.TOTPRequired,
+		TenantID:       tenantID,
+		Username:       in.Username,
+		Email:          in.Email,
+		DisplayName:    in.DisplayName,
+		FirstName:      in.FirstName,
+		LastName:       in.LastName,
+		AuthSource:     models.AuthSource(in.AuthSource),
+		ExternalID:     in.ExternalID,
+		Active:         in.Active,
+		RoleID:         in.RoleID,
+		TOTPRequired:   in.TOTPRequired,
+		ServiceAccount: in.ServiceAccount,
 	}
 	if user.RoleID == 0 {
 		var userRole models.Role
@@ -254,8 +281,35 @@ func (s *Service) Create(c fiber.Ctx) error {
 		}
 	}
 
+	if user.RoleID != 0 && !s.canGrant(c, user.RoleID) {
+		nav := navigation.NewContext("Users", "admin", "user").
+			AddBreadcrumb("Home", dashboard.Path, false).
+			AddBreadcrumb("Admin", "#", false).
+			AddBreadcrumb("Users", Path, true)
+
+		return c.Status(fiber.StatusBadRequest).Render(TemplateList, fiber.Map{
+			"Navigation": nav,
+			"Error":      errRoleNotGrantable,
+		}, handler.BaseLayout)
+	}
+
 	if in.AuthSource == string(models.AuthSourceLocal) && in.Password != "" {
+		if err := s.localAuth.ValidatePassword(in.Password); err != nil {
+			nav := navigation.NewContext("Users", "admin", "user").
+				AddBreadcrumb("Home", dashboard.Path, false).
+				AddBreadcrumb("Admin", "#", false).
+				AddBreadcrumb("Users", Path, true)
+
+			return c.Status(fiber.StatusBadRequest).Render(TemplateList, fiber.Map{
+				"Navigation": nav,
+				"Error":      "Password must have " + s.localAuth.PasswordRequirements(),
+			}, handler.BaseLayout)
+		}
+
+		now := time.Now()
 		user.Password = models.HashPassword(in.Password)
+		user.PasswordChangedAt = &now
+		user.MustChangePassword = in.MustChangePassword
 	}
 
 	if err := s.db.Create(&user).Error; err != nil {
@@ -284,7 +338,7 @@ func (s *Service) Edit(c fiber.Ctx) error {
 	}
 
 	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := s.scoped(c).First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Redirect().To(Path)
 		}
@@ -295,7 +349,7 @@ func (s *Service) Edit(c fiber.Ctx) error {
 	}
 
 	var roles []models.Role
-	if err := s.db.Order(handler.OrderNameASC).Find(&roles).Error; err != nil {
+	if err := s.grantable(c).Order(handler.OrderNameASC).Find(&roles).Error; err != nil {
 		log.Error().Err(err).Msg("failed to load roles")
 
 		return c.Status(fiber.StatusInternalServerError).Render(TemplateForm, fiber.Map{
@@ -321,13 +375,16 @@ func (s *Service) Edit(c fiber.Ctx) error {
 	}
 
 	return c.Render(TemplateForm, fiber.Map{
-		"Navigation":      nav,
-		"User":            user,
-		"IsCreate":        false,
-		"Roles":           roles,
-		"AllTags":         allTags,
-		"AssignedSet":     assignedSet,
-		"DemoAdminLocked": s.cfg.Demo && user.Username == adminUsername,
+		"Navigation":           nav,
+		"User":                 user,
+		"IsCreate":             false,
+		"Roles":                roles,
+		"Tenants":              s.tenants(c),
+		"TenantID":             tenant.ID(user.TenantID),
+		"AllTags":              allTags,
+		"AssignedSet":          assignedSet,
+		"DemoAdminLocked":      s.cfg.Demo && user.Username == adminUsername,
+		"PasswordRequirements": s.localAuth.PasswordRequirements(),
 	}, handler.BaseLayout)
 }
 
@@ -339,14 +396,20 @@ func (s *Service) Update(c fiber.Ctx) error {
 	}
 
 	var in struct {
-		Username     string `form:"username"      validate:"required,min=3,max=100"`
-		Email        string `form:"email"         validate:"required,email,max=255"`
-		DisplayName  string `form:"displayname"   validate:"max=255"`
-		AuthSource   string `form:"source"        validate:"required,oneof=local oidc ldap"`
-		Password     string `form:"password"`
-		Active       bool   `form:"active"`
-		RoleID       uint   `form:"role_id"`
-		TOTPRequired bool   `form:"totp_required"`
+		Username       string `form:"username"      validate:"required,min=3,max=100"`
+		Email          string `form:"email"         validate:"required,email,max=255"`
+		DisplayName    string `form:"displayname"   validate:"max=255"`
+		FirstName      string `form:"firstname"     validate:"max=100"`
+		LastName       string `form:"lastname"      validate:"max=100"`
+		AuthSource     string `form:"source"        validate:"required,oneof=local oidc ldap"`
+		Password       string `form:"password"`
+		Active         bool   `form:"active"`
+		RoleID         uint   `form:"role_id"`
+		TOTPRequired   bool   `form:"totp_required"`
+		ServiceAccount bool   `form:"service_account"`
+		// MustChangePassword asks the user to change the password at the next
+		// login.
+		MustChangePassword bool `form:"must_change_password"`
 	}
 	if err = c.Bind().Body(&in); err != nil {
 		return c.Status(fiber.StatusBadRequest).Render(TemplateForm, fiber.Map{
@@ -365,7 +428,7 @@ func (s *Service) Update(c fiber.Ctx) error {
 	}
 
 	var user models.User
-	if err = s.db.First(&user, id).Error; err != nil {
+	if err = s.scoped(c).First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Redirect().To(Path)
 		}
@@ -377,7 +440,7 @@ func (s *Service) Update(c fiber.Ctx) error {
 
 	// Load roles for error re-renders.
 	var roles []models.Role
-	if err = s.db.Order(handler.OrderNameASC).Find(&roles).Error; err != nil {
+	if err = s.grantable(c).Order(handler.OrderNameASC).Find(&roles).Error; err != nil {
 		log.Error().Err(err).Msg("failed to load roles for update render")
 	}
 
@@ -389,11 +452,14 @@ func (s *Service) Update(c fiber.Ctx) error {
 
 	renderUpdateErr := func(msg string) error {
 		return c.Status(fiber.StatusBadRequest).Render(TemplateForm, fiber.Map{
-			"Navigation": editNav,
-			"Error":      msg,
-			"User":       user,
-			"IsCreate":   false,
-			"Roles":      roles,
+			"Navigation":           editNav,
+			"Error":                msg,
+			"User":                 user,
+			"IsCreate":             false,
+			"Roles":                roles,
+			"Tenants":              s.tenants(c),
+			"TenantID":             tenant.ID(user.TenantID),
+			"PasswordRequirements": s.localAuth.PasswordRequirements(),
 		}, handler.BaseLayout)
 	}
 
@@ -415,16 +481,42 @@ func (s *Service) Update(c fiber.Ctx) error {
 		return renderUpdateErr("You cannot deactivate your own account")
 	}
 
+	if in.RoleID != user.RoleID && !s.canGrant(c, in.RoleID) {
+		return renderUpdateErr(errRoleNotGrantable)
+	}
+
+	if in.Password != "" {
+		if err = s.localAuth.ValidatePassword(in.Password); err != nil {
+			return renderUpdateErr("Password must have " + s.localAuth.PasswordRequirements())
+		}
+	}
+
+	tenantID, err := s.formTenant(c, user.TenantID)
+	if err != nil {
+		return renderUpdateErr("Unknown tenant selected")
+	}
+
+	user.TenantID = tenantID
+
 	user.Username = in.Username
 	user.Email = in.Email
 	user.DisplayName = in.DisplayName
+	user.FirstName = in.FirstName
+	user.LastName = in.LastName
 	user.AuthSource = models.AuthSource(in.AuthSource)
 	user.Active = in.Active
 	user.RoleID = in.RoleID
+	// Activating a self-registered account approves the registration.
+	user.Pending = user.Pending && !in.Active
 	user.TOTPRequired = in.TOTPRequired
+	user.ServiceAccount = in.ServiceAccount
+
+	user.MustChangePassword = in.AuthSource == string(models.AuthSourceLocal) && in.MustChangePassword
 
 	if in.AuthSource == string(models.AuthSourceLocal) && in.Password != "" {
+		now := time.Now()
 		user.Password = models.HashPassword(in.Password)
+		user.PasswordChangedAt = &now
 	}
 
 	if err = s.db.Save(&user).Error; err != nil {
@@ -461,27 +553,18 @@ func isLastActiveAdmin(db *gorm.DB, user *models.User, newRoleID uint, newActive
 	return otherActiveAdmins == 0
 }
 
-// isSelfDeactivation reports whether the current session user is deactivating their own account.
+// isSelfDeactivation reports whether the current user is deactivating their own account.
 func isSelfDeactivation(c fiber.Ctx, id int, newActive bool) bool {
 	if newActive {
 		return false
 	}
 
-	sessionID := c.Cookies("session")
-	if sessionID == "" {
+	current, ok := currentUser(c)
+	if !ok || id <= 0 {
 		return false
 	}
 
-	current := new(session.Data)
-	if err := current.Read(sessionID); err != nil {
-		return false
-	}
-
-	if id <= 0 {
-		return false
-	}
-
-	return current.User.ID == uint64(id)
+	return current.ID == uint64(id)
 }
 
 // Delete removes a user.
@@ -493,7 +576,7 @@ func (s *Service) Delete(c fiber.Ctx) error {
 
 	// Load the user to check if they can be deleted
 	var user models.User
-	if err := s.db.Preload("Role").First(&user, id).Error; err != nil {
+	if err := s.scoped(c).Preload("Role").First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Redirect().To(Path)
 		}
@@ -523,22 +606,28 @@ func (s *Service) Delete(c fiber.Ctx) error {
 	}
 
 	// Prevent a user (including admin) from deleting themselves
-	// Read current session and compare target id with logged-in user id
-	if sessionID := c.Cookies("session"); sessionID != "" {
-		current := new(session.Data)
-		if errSess := current.Read(sessionID); errSess == nil {
-			if current.User.ID == uint64(id) {
-				nav := navigation.NewContext("Users", "admin", "user").
-					AddBreadcrumb("Home", dashboard.Path, false).
-					AddBreadcrumb("Admin", "#", false).
-					AddBreadcrumb("Users", Path, true)
-
-				return c.Status(fiber.StatusBadRequest).Render(TemplateList, fiber.Map{
-					"Navigation": nav,
-					"Error":      "You cannot delete your own account.",
-				}, handler.BaseLayout)
-			}
-		}
+	if current, ok := currentUser(c); ok && current.ID == uint64(id) {
+		nav := navigation.NewContext("Users", "admin", "user").
+			AddBreadcrumb("Home", dashboard.Path, false).
+			AddBreadcrumb("Admin", "#", false).
+			AddBreadcrumb("Users", Path, true)
+
+		return c.Status(fiber.StatusBadRequest).Render(TemplateList, fiber.Map{
+			"Navigation": nav,
+			"Error":      "You cannot delete your own account.",
+		}, handler.BaseLayout)
+	}
+
+	// Zones, API keys and schedules would silently go with the user; let the
+	// administrator decide what happens to them first.
+	resources, err := offboarding.Collect(s.db, user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to collect user resources")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load user.", nil)
+	}
+
+	if !resources.Empty() {
+		return c.Redirect().To(offboardPath(user.ID))
 	}
 
 	if err := s.db.Delete(&models.User{}, id).Error; err != nil {
@@ -564,7 +653,7 @@ func (s *Service) DisableTOTP(c fiber.Ctx) error {
 	}
 
 	var user models.User
-	if err = s.db.First(&user, id).Error; err != nil {
+	if err = s.scoped(c).First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Redirect().To(Path)
 		}
@@ -596,6 +685,50 @@ func (s *Service) DisableTOTP(c fiber.Ctx) error {
 	return c.Redirect().To(editPath)
 }
 
+// scoped returns the database limited to the users of the current user's
+// tenant; administrators outside any tenant see every user.
+func (s *Service) scoped(c fiber.Ctx) *gorm.DB {
+	current, _ := currentUser(c)
+	return s.db.Scopes(tenant.Scope(current.TenantID))
+}
+
+// grantable returns the database limited to the roles the current user may
+// give users; see auth.GrantableRoles.
+func (s *Service) grantable(c fiber.Ctx) *gorm.DB {
+	current, _ := currentUser(c)
+	return s.db.Scopes(auth.GrantableRoles(current.TenantID))
+}
+
+// canGrant reports whether the current user may give users the role roleID.
+func (s *Service) canGrant(c fiber.Ctx, roleID uint) bool {
+	var count int64
+	if err := s.grantable(c).Model(&models.Role{}).Where("id = ?", roleID).Count(&count).Error; err != nil {
+		log.Error().Err(err).Uint("role_id", roleID).Msg("failed to check the role")
+		return false
+	}
+
+	return count > 0
+}
+
+// tenants returns the tenants offered in the user form; see tenant.Choices.
+func (s *Service) tenants(c fiber.Ctx) []models.Tenant {
+	current, _ := currentUser(c)
+
+	tenants, err := tenant.Choices(s.db, current.TenantID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load tenants")
+	}
+
+	return tenants
+}
+
+// formTenant returns the tenant of a submitted user whose tenant was current;
+// see tenant.Submitted.
+func (s *Service) formTenant(c fiber.Ctx, current *uint) (*uint, error) {
+	actor, _ := currentUser(c)
+	return tenant.Submitted(s.db, actor.TenantID, c.FormValue("tenant_id"), current)
+}
+
 // parseUintIDs reads a multi-value form field and returns a slice of uint values.
 func parseUintIDs(c fiber.Ctx, field string) []uint {
 	vals := c.Request().PostArgs().PeekMulti(field)
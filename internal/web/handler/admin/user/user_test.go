@@ -19,6 +19,7 @@ import (
 	"github.com/onsi/gomega"
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 	websess "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
@@ -110,7 +111,8 @@ func newTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("open in-memory sqlite: %v", err)
 	}
 
-	if err := db.AutoMigrate(&models.User{}, &models.Role{}, &models.Tag{}, &models.UserTag{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Role{}, &models.Tag{}, &models.UserTag{},
+		&models.ActivityLog{}, &models.ZoneOwnership{}, &models.APIKey{}, &models.RecordSchedule{}); err != nil {
 		t.Fatalf("automigrate: %v", err)
 	}
 
@@ -122,9 +124,23 @@ func newTestConfig() *config.Config {
 		Webserver: config.Webserver{
 			Session: config.Session{ExpiryTime: time.Minute},
 		},
+		InactiveUsers: config.InactiveUsers{Days: 90},
+		Auth: config.Auth{
+			LocalDB: config.LocalDBAuth{
+				PasswordPolicy: config.PasswordPolicy{MinLength: config.DefaultPasswordMinLength},
+			},
+		},
 	}
 }
 
+// newLocalProvider returns the local auth provider of a Service for cfg.
+func newLocalProvider(db *gorm.DB, cfg *config.Config) *auth.LocalProvider {
+	p := auth.NewLocalProvider(db)
+	p.SetPasswordPolicy(cfg.Auth.LocalDB.PasswordPolicy)
+
+	return p
+}
+
 // newTestApp builds a Fiber app with Service routes registered directly,
 // without the permission middleware, so tests don't need a valid session.
 func newTestApp(t *testing.T, db *gorm.DB) *fiber.App {
@@ -136,6 +152,7 @@ func newTestApp(t *testing.T, db *gorm.DB) *fiber.App {
 	s := &Service{
 		cfg:       cfg,
 		db:        db,
+		localAuth: newLocalProvider(db, cfg),
 		validator: validator.New(),
 	}
 
@@ -161,11 +178,17 @@ func newSessionApp(t *testing.T, db *gorm.DB) (*Service, *fiber.App) {
 	s := &Service{
 		cfg:       cfg,
 		db:        db,
+		localAuth: newLocalProvider(db, cfg),
 		validator: validator.New(),
 	}
 
+	app.Use(auth.Authenticate(nil))
+	app.Get(PathInactive, s.Inactive)
+	app.Post(PathInactive, s.DeactivateInactive)
 	app.Post(Path+"/:id", s.Update)
 	app.Post(Path+"/:id/delete", s.Delete)
+	app.Get(Path+"/:id/offboard", s.Offboard)
+	app.Post(Path+"/:id/offboard", s.DoOffboard)
 
 	return s, app
 }
@@ -209,7 +232,7 @@ func writeSession(t *testing.T, cfg *config.Config, u *models.User) string {
 	sid := "test-session-" + u.Username
 	sessData := &websess.Data{User: *u}
 
-	if err := sessData.Write(sid, cfg.Webserver.Session.ExpiryTime); err != nil {
+	if err := sessData.Write(sid); err != nil {
 		t.Fatalf("write session: %v", err)
 	}
 
@@ -320,6 +343,66 @@ func TestCreate_Success(t *testing.T) {
 	g.Expect(db.Where("username = ?", "alice").First(&u).Error).To(gomega.Succeed())
 }
 
+func TestCreate_LocalUserMustChangePassword(t *testing.T) {
+	g := gomega.NewWithT(t)
+	db := newTestDB(t)
+
+	initSessionStore()
+
+	role := createRole(t, db, "user")
+	app := newTestApp(t, db)
+
+	form := url.Values{
+		"username":             {"dave"},
+		"email":                {"dave@example.com"},
+		"source":               {"local"},
+		"password":             {"initial-pw"},
+		"active":               {"true"},
+		"role_id":              {roleID(&role)},
+		"must_change_password": {"true"},
+	}
+
+	resp := doPost(t, app, Path, form)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusSeeOther))
+
+	var u models.User
+	g.Expect(db.Where("username = ?", "dave").First(&u).Error).To(gomega.Succeed())
+	g.Expect(u.MustChangePassword).To(gomega.BeTrue())
+	g.Expect(u.PasswordChangedAt).NotTo(gomega.BeNil())
+}
+
+func TestCreate_PasswordBelowPolicy_ReturnsBadRequest(t *testing.T) {
+	g := gomega.NewWithT(t)
+	db := newTestDB(t)
+
+	initSessionStore()
+
+	role := createRole(t, db, "user")
+	app := newTestApp(t, db)
+
+	form := url.Values{
+		"username": {"erin"},
+		"email":    {"erin@example.com"},
+		"source":   {"local"},
+		"password": {"short"},
+		"active":   {"true"},
+		"role_id":  {roleID(&role)},
+	}
+
+	resp := doPost(t, app, Path, form)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusBadRequest))
+
+	var count int64
+	db.Model(&models.User{}).Where("username = ?", "erin").Count(&count)
+	g.Expect(count).To(gomega.BeZero())
+}
+
 func TestCreate_MissingRequiredFields_ReturnsBadRequest(t *testing.T) {
 	g := gomega.NewWithT(t)
 	db := newTestDB(t)
@@ -684,3 +767,120 @@ func TestDisableTOTP_NoopWhenNotEnabled(t *testing.T) {
 
 	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusSeeOther))
 }
+
+// --- Inactive ---
+
+func TestDeactivateInactive_OnlySelectedCandidates(t *testing.T) {
+	g := gomega.NewWithT(t)
+	db := newTestDB(t)
+
+	initSessionStore()
+
+	adminRole := createRole(t, db, "admin")
+	userRole := createRole(t, db, "user")
+	longAgo := func(u *models.User) { u.CreatedAt = time.Now().AddDate(-1, 0, 0) }
+
+	root := createUser(t, db, "root", adminRole.ID, longAgo)
+	stale := createUser(t, db, "stale", userRole.ID, longAgo)
+	bot := createUser(t, db, "bot", userRole.ID, longAgo, func(u *models.User) { u.ServiceAccount = true })
+	fresh := createUser(t, db, "fresh", userRole.ID)
+	s, app := newSessionApp(t, db)
+	sid := writeSession(t, s.cfg, &root)
+
+	resp := doGet(t, app, PathInactive)
+	_ = resp.Body.Close()
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+
+	form := url.Values{"user_ids": {
+		strconv.FormatUint(root.ID, 10),
+		strconv.FormatUint(stale.ID, 10),
+		strconv.FormatUint(bot.ID, 10),
+		strconv.FormatUint(fresh.ID, 10),
+	}}
+
+	resp = doPost(t, app, PathInactive, form, http.Cookie{Name: "session", Value: sid})
+	_ = resp.Body.Close()
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusSeeOther))
+
+	var active []string
+	db.Model(&models.User{}).Where("active = ?", true).Order("username").Pluck("username", &active)
+	g.Expect(active).To(gomega.Equal([]string{"bot", "fresh", "root"}))
+
+	var entry models.ActivityLog
+	g.Expect(db.Where("action = ?", "user_deactivated").First(&entry).Error).To(gomega.Succeed())
+	g.Expect(entry.ResourceName).To(gomega.Equal("stale"))
+	g.Expect(entry.Username).To(gomega.Equal("root"))
+
+	// Nothing left to deactivate among the selection.
+	resp = doPost(t, app, PathInactive, form, http.Cookie{Name: "session", Value: sid})
+	_ = resp.Body.Close()
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusBadRequest))
+}
+
+func TestDelete_RedirectsToOffboardWhenOwningResources(t *testing.T) {
+	g := gomega.NewWithT(t)
+	db := newTestDB(t)
+
+	initSessionStore()
+
+	role := createRole(t, db, "user")
+	u := createUser(t, db, "frank", role.ID)
+	g.Expect(db.Create(&models.ZoneOwnership{ZoneName: "example.org.", UserID: u.ID}).Error).To(gomega.Succeed())
+
+	app := newTestApp(t, db)
+
+	resp := doPost(t, app, fmt.Sprintf("%s/%d/delete", Path, u.ID), nil)
+	_ = resp.Body.Close()
+
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusSeeOther))
+	g.Expect(resp.Header.Get("Location")).To(gomega.Equal(offboardPath(u.ID)))
+
+	var count int64
+	db.Model(&models.User{}).Where("id = ?", u.ID).Count(&count)
+	g.Expect(count).To(gomega.Equal(int64(1)))
+}
+
+func TestOffboard_DeleteWithTransfer(t *testing.T) {
+	g := gomega.NewWithT(t)
+	db := newTestDB(t)
+
+	initSessionStore()
+
+	adminRole := createRole(t, db, "admin")
+	userRole := createRole(t, db, "user")
+	root := createUser(t, db, "root", adminRole.ID)
+	leaver := createUser(t, db, "leaver", userRole.ID)
+	heir := createUser(t, db, "heir", userRole.ID)
+	g.Expect(db.Create(&models.ZoneOwnership{ZoneName: "example.org.", UserID: leaver.ID}).Error).To(gomega.Succeed())
+
+	s, app := newSessionApp(t, db)
+	sid := writeSession(t, s.cfg, &root)
+	cookie := http.Cookie{Name: "session", Value: sid}
+
+	resp := doGet(t, app, offboardPath(leaver.ID))
+	_ = resp.Body.Close()
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+
+	// Admins cannot offboard themselves.
+	resp = doPost(t, app, offboardPath(root.ID), url.Values{"mode": {"deactivate"}}, cookie)
+	_ = resp.Body.Close()
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusForbidden))
+
+	form := url.Values{"mode": {"delete"}, "transfer_to": {strconv.FormatUint(heir.ID, 10)}}
+	resp = doPost(t, app, offboardPath(leaver.ID), form, cookie)
+	_ = resp.Body.Close()
+	g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+
+	var grant models.ZoneOwnership
+	g.Expect(db.Where("zone_name = ?", "example.org.").First(&grant).Error).To(gomega.Succeed())
+	g.Expect(grant.UserID).To(gomega.Equal(heir.ID))
+
+	var count int64
+	db.Model(&models.User{}).Where("id = ?", leaver.ID).Count(&count)
+	g.Expect(count).To(gomega.BeZero())
+
+	var entry models.ActivityLog
+	g.Expect(db.Where("action = ?", "user_offboarded").First(&entry).Error).To(gomega.Succeed())
+	g.Expect(entry.ResourceName).To(gomega.Equal("leaver"))
+	g.Expect(entry.Username).To(gomega.Equal("root"))
+}
@@ -0,0 +1,78 @@
+package zonetag
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// InheritedTag is a tag a zone receives from one of its parent zones.
+type InheritedTag struct {
+	Tag  models.Tag
+	From string
+}
+
+// inheritance indexes the inheriting zone tags so the tags a zone receives
+// from its parents can be resolved without further queries.
+type inheritance struct {
+	byZone  map[string][]models.ZoneTag
+	blocked map[string]bool
+}
+
+// newInheritance builds the index from zoneTags; tags without Inherit are
+// ignored. The tags' Tag association should be preloaded.
+func newInheritance(zoneTags []models.ZoneTag, blocked map[string]bool) *inheritance {
+	inh := &inheritance{
+		byZone:  make(map[string][]models.ZoneTag),
+		blocked: blocked,
+	}
+
+	for i := range zoneTags {
+		if zoneTags[i].Inherit {
+			inh.byZone[zoneTags[i].ZoneID] = append(inh.byZone[zoneTags[i].ZoneID], zoneTags[i])
+		}
+	}
+
+	return inh
+}
+
+// inheritedTags returns the tags zone inherits, nearest parent first. A tag
+// inherited from several parents is reported once, from the nearest one.
+func (inh *inheritance) inheritedTags(zone string) []InheritedTag {
+	var (
+		out  []InheritedTag
+		seen = make(map[uint]bool)
+	)
+
+	for _, parent := range auth.ParentZones(zone, inh.blocked) {
+		for _, zt := range inh.byZone[parent] {
+			if seen[zt.TagID] {
+				continue
+			}
+
+			seen[zt.TagID] = true
+
+			out = append(out, InheritedTag{Tag: zt.Tag, From: parent})
+		}
+	}
+
+	return out
+}
+
+// loadBlockedZones returns the zones that opted out of tag inheritance.
+func (s *Service) loadBlockedZones() map[string]bool {
+	var zones []string
+	if err := s.db.Model(&models.ZoneAccessOption{}).
+		Where("block_inheritance = ?", true).
+		Pluck("zone_id", &zones).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load zone access options")
+	}
+
+	blocked := make(map[string]bool, len(zones))
+	for _, z := range zones {
+		blocked[z] = true
+	}
+
+	return blocked
+}
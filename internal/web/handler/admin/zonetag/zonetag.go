@@ -18,6 +18,7 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
 )
 
 const (
@@ -69,7 +70,7 @@ func (s *Service) List(c fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	apiZones, err := powerdns.Engine.Zones.List(ctx)
+	apiZones, err := zoneindex.Default.List(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to fetch zones for zone-tag list")
 
@@ -92,9 +93,13 @@ func (s *Service) List(c fiber.Ctx) error {
 		tagCountByZone[zoneTags[i].ZoneID]++
 	}
 
+	inh := newInheritance(zoneTags, s.loadBlockedZones())
+
 	type ZoneRow struct {
-		Name     string
-		TagCount int
+		Name           string
+		TagCount       int
+		InheritedCount int
+		Blocked        bool
 	}
 
 	rows := make([]ZoneRow, 0, len(apiZones))
@@ -105,8 +110,10 @@ func (s *Service) List(c fiber.Ctx) error {
 
 		name := *apiZones[i].Name
 		rows = append(rows, ZoneRow{
-			Name:     name,
-			TagCount: tagCountByZone[name],
+			Name:           name,
+			TagCount:       tagCountByZone[name],
+			InheritedCount: len(inh.inheritedTags(name)),
+			Blocked:        inh.blocked[name],
 		})
 	}
 
@@ -146,7 +153,7 @@ func (s *Service) List(c fiber.Ctx) error {
 func (s *Service) Edit(c fiber.Ctx) error {
 	zoneName := c.Params("zoneName")
 	if zoneName == "" {
-		return c.Status(fiber.StatusBadRequest).SendString("Zone name required")
+		return fiber.NewError(fiber.StatusBadRequest, "Zone name required")
 	}
 
 	nav := navigation.NewContext("Zone Tags", "admin", "zone-tags").
@@ -162,15 +169,26 @@ func (s *Service) Edit(c fiber.Ctx) error {
 	s.db.Where("zone_id = ?", zoneName).Find(&assigned)
 
 	assignedSet := make(map[uint]bool)
+	inheritSet := make(map[uint]bool)
+
 	for i := range assigned {
 		assignedSet[assigned[i].TagID] = true
+		inheritSet[assigned[i].TagID] = assigned[i].Inherit
 	}
 
+	var inheriting []models.ZoneTag
+	s.db.Preload("Tag").Where("inherit = ?", true).Find(&inheriting)
+
+	blocked := s.loadBlockedZones()
+
 	return c.Render(templateForm, fiber.Map{
-		"Navigation":  nav,
-		"ZoneName":    zoneName,
-		"AllTags":     allTags,
-		"AssignedSet": assignedSet,
+		"Navigation":       nav,
+		"ZoneName":         zoneName,
+		"AllTags":          allTags,
+		"AssignedSet":      assignedSet,
+		"InheritSet":       inheritSet,
+		"BlockInheritance": blocked[zoneName],
+		"InheritedTags":    newInheritance(inheriting, blocked).inheritedTags(zoneName),
 	}, handler.BaseLayout)
 }
 
@@ -178,11 +196,21 @@ func (s *Service) Edit(c fiber.Ctx) error {
 func (s *Service) Update(c fiber.Ctx) error {
 	zoneName := c.Params("zoneName")
 	if zoneName == "" {
-		return c.Status(fiber.StatusBadRequest).SendString("Zone name required")
+		return fiber.NewError(fiber.StatusBadRequest, "Zone name required")
 	}
 
-	// Parse selected tag IDs
+	// Parse selected tag IDs and the subset that sub-zones inherit
 	selectedIDs := parseTagIDs(c)
+	inheritIDs := make(map[uint]bool)
+
+	for _, id := range parseUintValues(c.Request().PostArgs().PeekMulti("inherit_ids")) {
+		inheritIDs[id] = true
+	}
+
+	option := models.ZoneAccessOption{
+		ZoneID:           zoneName,
+		BlockInheritance: c.FormValue("block_inheritance") != "",
+	}
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		// Delete existing zone-tag associations
@@ -193,14 +221,24 @@ func (s *Service) Update(c fiber.Ctx) error {
 		// Insert new associations
 		for _, tagID := range selectedIDs {
 			zt := models.ZoneTag{
-				ZoneID: zoneName,
-				TagID:  tagID,
+				ZoneID:  zoneName,
+				TagID:   tagID,
+				Inherit: inheritIDs[tagID],
 			}
 			if err := tx.Create(&zt).Error; err != nil {
 				return err
 			}
 		}
 
+		// Only keep an options row while it differs from the default.
+		if err := tx.Where("zone_id = ?", zoneName).Delete(&models.ZoneAccessOption{}).Error; err != nil {
+			return err
+		}
+
+		if option.BlockInheritance {
+			return tx.Create(&option).Error
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -213,8 +251,11 @@ func (s *Service) Update(c fiber.Ctx) error {
 
 // parseTagIDs parses the multi-value tag_ids form field.
 func parseTagIDs(c fiber.Ctx) []uint {
-	vals := c.Request().PostArgs().PeekMulti("tag_ids")
+	return parseUintValues(c.Request().PostArgs().PeekMulti("tag_ids"))
+}
 
+// parseUintValues parses positive decimal IDs, dropping anything else.
+func parseUintValues(vals [][]byte) []uint {
 	result := make([]uint, 0, len(vals))
 	for _, v := range vals {
 		n := 0
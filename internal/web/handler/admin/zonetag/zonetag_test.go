@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -61,7 +62,7 @@ func newTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("failed to open in-memory sqlite: %v", err)
 	}
 
-	if err := db.AutoMigrate(&models.Tag{}, &models.ZoneTag{}); err != nil {
+	if err := db.AutoMigrate(&models.Tag{}, &models.ZoneTag{}, &models.ZoneAccessOption{}); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
 
@@ -278,3 +279,76 @@ func TestParseTagIDs_Empty(t *testing.T) {
 		t.Errorf("expected empty slice, got %v", got)
 	}
 }
+
+// TestUpdate_SavesInheritanceOptions checks that the inherit flags and the
+// block-inheritance option are persisted, and that Edit reports tags a
+// sub-zone inherits from its parent.
+func TestUpdate_SavesInheritanceOptions(t *testing.T) {
+	app, views := newTestApp(t)
+	svc := newTestService(t, app)
+
+	prod := models.Tag{Name: "production"}
+	staging := models.Tag{Name: "staging"}
+	svc.db.Create(&prod)
+	svc.db.Create(&staging)
+
+	body := "tag_ids=" + strconv.FormatUint(uint64(prod.ID), 10) +
+		"&tag_ids=" + strconv.FormatUint(uint64(staging.ID), 10) +
+		"&inherit_ids=" + strconv.FormatUint(uint64(prod.ID), 10)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/admin/zone-tag/example.com./edit", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	var zoneTags []models.ZoneTag
+	svc.db.Where("zone_id = ?", "example.com.").Order("tag_id").Find(&zoneTags)
+
+	if len(zoneTags) != 2 || !zoneTags[0].Inherit || zoneTags[1].Inherit {
+		t.Fatalf("unexpected zone tags: %+v", zoneTags)
+	}
+
+	resp = doGet(t, app, "/admin/zone-tag/dev.example.com./edit")
+	_ = resp.Body.Close()
+
+	views.mu.Lock()
+	m, _ := views.lastData.(fiber.Map)
+	views.mu.Unlock()
+
+	inherited, _ := m["InheritedTags"].([]InheritedTag)
+	if len(inherited) != 1 || inherited[0].Tag.Name != "production" || inherited[0].From != "example.com." {
+		t.Errorf("InheritedTags = %+v", inherited)
+	}
+
+	// Blocking inheritance on the sub-zone hides the parent's tags.
+	req = httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		"/admin/zone-tag/dev.example.com./edit", strings.NewReader("block_inheritance=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err = app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	resp = doGet(t, app, "/admin/zone-tag/dev.example.com./edit")
+	_ = resp.Body.Close()
+
+	views.mu.Lock()
+	m, _ = views.lastData.(fiber.Map)
+	views.mu.Unlock()
+
+	if m["BlockInheritance"] != true {
+		t.Error("expected BlockInheritance to be true")
+	}
+
+	if inherited, _ := m["InheritedTags"].([]InheritedTag); len(inherited) != 0 {
+		t.Errorf("expected no inherited tags once blocked, got %+v", inherited)
+	}
+}
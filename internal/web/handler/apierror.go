@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+// APIPathPrefix is the path prefix of the JSON endpoints.
+const APIPathPrefix = RootPath + "api/"
+
+// Machine-readable error codes of the API error envelope.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodePreconditionFailed = "precondition_failed"
+	CodeValidation         = "validation_failed"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeInternal           = "internal_error"
+	CodeUpstream           = "upstream_error"
+	CodeUnavailable        = "service_unavailable"
+)
+
+// APIError is the JSON body of every failed API request. Success is always
+// false; it is kept so clients checking it need no changes.
+type APIError struct {
+	Success   bool   `json:"success"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// JSONError responds with status and an APIError. details is optional
+// structured context, e.g. the offending field or record type.
+func JSONError(c fiber.Ctx, status int, code, message string, details any) error {
+	return c.Status(status).JSON(APIError{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestid.FromContext(c.Context()),
+	})
+}
+
+// CodeForStatus returns the default error code for an HTTP status.
+func CodeForStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return CodeBadRequest
+	case fiber.StatusUnauthorized:
+		return CodeUnauthorized
+	case fiber.StatusForbidden:
+		return CodeForbidden
+	case fiber.StatusNotFound, fiber.StatusMethodNotAllowed:
+		return CodeNotFound
+	case fiber.StatusConflict, fiber.StatusPreconditionFailed:
+		return CodeConflict
+	case fiber.StatusUnprocessableEntity:
+		return CodeValidation
+	case fiber.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case fiber.StatusBadGateway, fiber.StatusGatewayTimeout:
+		return CodeUpstream
+	case fiber.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+
+	if status >= fiber.StatusInternalServerError {
+		return CodeInternal
+	}
+
+	return CodeBadRequest
+}
+
+// IsAPIRequest reports whether the request expects a JSON response: it
+// targets an /api/ path, sends a JSON body, or prefers JSON over HTML.
+func IsAPIRequest(c fiber.Ctx) bool {
+	if strings.HasPrefix(c.Path(), APIPathPrefix) {
+		return true
+	}
+
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		return true
+	}
+
+	return c.Accepts(fiber.MIMETextHTML, fiber.MIMEApplicationJSON) == fiber.MIMEApplicationJSON
+}
+
+// ErrorHandler is the application's Fiber error handler. Errors returned by
+// handlers and middleware are answered with an APIError for API requests and
+// with the error page otherwise. Messages of unexpected (non-fiber) errors are
+// logged but not sent to the client.
+func ErrorHandler(c fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	message := http.StatusText(status)
+
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		status = fe.Code
+		message = fe.Message
+	} else {
+		requestid.Logger(c.Context()).Error().Err(err).Str("method", c.Method()).Str("path", c.Path()).
+			Msg("unhandled request error")
+	}
+
+	if IsAPIRequest(c) {
+		return JSONError(c, status, CodeForStatus(status), message, nil)
+	}
+
+	if renderErr := RenderError(c, status, http.StatusText(status), message, nil); renderErr != nil {
+		requestid.Logger(c.Context()).Error().Err(renderErr).Msg("failed to render error page")
+
+		return c.Status(status).SendString(message)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/requestid"
+)
+
+func newErrorApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(requestid.New())
+
+	app.Get("/api/thing", func(fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusForbidden, "no access")
+	})
+	app.Get("/zone/x", func(fiber.Ctx) error {
+		return errors.New("database is on fire")
+	})
+	app.Post("/zone/x/records", func(c fiber.Ctx) error {
+		return JSONError(c, fiber.StatusBadRequest, CodeValidation, "bad record", fiber.Map{"record_type": "SOA"})
+	})
+
+	return app
+}
+
+func doRequest(t *testing.T, app *fiber.App, req *http.Request) (*http.Response, []byte) {
+	t.Helper()
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	return resp, body
+}
+
+func decodeAPIError(t *testing.T, body []byte) APIError {
+	t.Helper()
+
+	var out APIError
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatalf("body is not an APIError: %v: %s", err, body)
+	}
+
+	return out
+}
+
+func TestErrorHandler_APIPathGetsJSON(t *testing.T) {
+	app := newErrorApp()
+
+	req := httptest.NewRequestWithContext(context.Background(), fiber.MethodGet, "/api/thing", http.NoBody)
+	resp, body := doRequest(t, app, req)
+
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("status = %d, want 403", resp.StatusCode)
+	}
+
+	got := decodeAPIError(t, body)
+	if got.Success || got.Code != CodeForbidden || got.Message != "no access" {
+		t.Errorf("APIError = %+v", got)
+	}
+
+	if got.RequestID == "" || got.RequestID != resp.Header.Get(fiber.HeaderXRequestID) {
+		t.Errorf("RequestID = %q, header %q", got.RequestID, resp.Header.Get(fiber.HeaderXRequestID))
+	}
+}
+
+func TestErrorHandler_HidesUnexpectedErrors(t *testing.T) {
+	app := newErrorApp()
+
+	req := httptest.NewRequestWithContext(context.Background(), fiber.MethodGet, "/zone/x", http.NoBody)
+	req.Header.Set(fiber.HeaderAccept, fiber.MIMEApplicationJSON)
+	resp, body := doRequest(t, app, req)
+
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", resp.StatusCode)
+	}
+
+	got := decodeAPIError(t, body)
+	if got.Code != CodeInternal || strings.Contains(got.Message, "fire") {
+		t.Errorf("APIError = %+v", got)
+	}
+}
+
+func TestErrorHandler_HTMLRequestFallsBackToText(t *testing.T) {
+	// The test app has no views, so rendering the error page fails and the
+	// handler falls back to the plain message.
+	app := newErrorApp()
+
+	req := httptest.NewRequestWithContext(context.Background(), fiber.MethodGet, "/zone/x", http.NoBody)
+	req.Header.Set(fiber.HeaderAccept, "text/html,application/xhtml+xml,*/*;q=0.8")
+	resp, body := doRequest(t, app, req)
+
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", resp.StatusCode)
+	}
+
+	if strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		t.Errorf("HTML request got JSON: %s", body)
+	}
+}
+
+func TestJSONError_Details(t *testing.T) {
+	app := newErrorApp()
+
+	req := httptest.NewRequestWithContext(context.Background(), fiber.MethodPost, "/zone/x/records", strings.NewReader("{}"))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, body := doRequest(t, app, req)
+
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+
+	if !strings.Contains(string(body), `"details":{"record_type":"SOA"}`) {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]string{
+		fiber.StatusBadRequest:          CodeBadRequest,
+		fiber.StatusNotFound:            CodeNotFound,
+		fiber.StatusUnprocessableEntity: CodeValidation,
+		fiber.StatusTeapot:              CodeBadRequest,
+		fiber.StatusBadGateway:          CodeUpstream,
+		fiber.StatusInternalServerError: CodeInternal,
+		fiber.StatusNotImplemented:      CodeInternal,
+	}
+
+	for status, want := range tests {
+		if got := CodeForStatus(status); got != want {
+			t.Errorf("CodeForStatus(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
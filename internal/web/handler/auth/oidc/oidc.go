@@ -3,7 +3,6 @@ package oidc
 import (
 	"context"
 	"errors"
-	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -13,7 +12,10 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/appsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/authsettings"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/loginhistory"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
@@ -28,25 +30,30 @@ const (
 
 	// LogoutPath is the path for OIDC logout.
 	LogoutPath = handler.RootPath + "auth/oidc/logout"
+
+	// stateTTL is how long a login state token stays valid.
+	stateTTL = 5 * time.Minute
+
+	// discoveryTimeout bounds the request for the provider's discovery
+	// document.
+	discoveryTimeout = 10 * time.Second
 )
 
 // Service is the OIDC handler service.
 type Service struct {
 	handler.Service
-	cfg          *config.Config
-	db           *gorm.DB
-	oidcProvider *auth.OIDCProvider
-	authService  *auth.Service
-	stateMu      sync.Mutex
-	stateStore   map[string]time.Time // in-memory state store protected by stateMu
+	cfg         *config.Config
+	db          *gorm.DB
+	providers   auth.ProviderCache[*auth.OIDCProvider]
+	authService *auth.Service
 }
 
 // Handler is the OIDC handler.
-var Handler = Service{
-	stateStore: make(map[string]time.Time),
-}
+var Handler = Service{}
 
-// Init initializes the OIDC handler.
+// Init initializes the OIDC handler. The routes are registered even while
+// OIDC is disabled, as it can be enabled at runtime under Settings →
+// Authentication.
 func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB) {
 	if app == nil || cfg == nil || db == nil {
 		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
@@ -56,66 +63,88 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB) {
 	s.db = db
 	s.cfg = cfg
 	s.authService = auth.NewService(db)
+	s.authService.SetGroupSyncPolicy(cfg.Auth.GroupSync.Policy)
 
-	// Initialize OIDC provider if enabled
-	if cfg.Auth.OIDC.Enabled {
-		oidcConfig := auth.OIDCConfig{
-			Enabled:      cfg.Auth.OIDC.Enabled,
-			ProviderURL:  cfg.Auth.OIDC.ProviderURL,
-			ClientID:     cfg.Auth.OIDC.ClientID,
-			ClientSecret: cfg.Auth.OIDC.ClientSecret,
-			RedirectURL:  cfg.Auth.OIDC.RedirectURL,
-			Scopes:       cfg.Auth.OIDC.Scopes,
-			GroupsClaim:  cfg.Auth.OIDC.GroupsClaim,
-		}
+	// Discover the provider now rather than on the first login.
+	s.Provider()
 
-		ctx := context.Background()
+	// Register routes
+	app.Get(LoginPath, s.Login)
+	app.Get(CallbackPath, s.Callback)
+	app.Get(LogoutPath, s.Logout)
+}
 
-		oidcProvider, err := auth.NewOIDCProvider(ctx, &oidcConfig, db)
-		if err != nil {
-			if errors.Is(err, auth.ErrOIDCDisabled) {
-				log.Info().Msg("OIDC authentication is disabled by configuration")
-			} else {
-				log.Warn().Err(err).Msg("Failed to initialize OIDC provider - OIDC authentication will be disabled")
-			}
+// Provider returns the OIDC provider of the current settings, or nil when
+// OIDC is disabled or the provider could not be initialized. The provider is
+// discovered again once the settings change.
+func (s *Service) Provider() *auth.OIDCProvider {
+	settings := authsettings.Current(s.cfg)
 
-			return // Don't fail, just disable OIDC
-		}
+	provider, err := s.providers.Get(settings.Version, func() (*auth.OIDCProvider, error) {
+		return newProvider(&settings.OIDC, s.db)
+	})
+	if err != nil {
+		return nil
+	}
 
-		s.oidcProvider = oidcProvider
+	return provider
+}
 
-		log.Info().Msg("OIDC authentication provider initialized")
+// BearerProvider returns the OIDC provider when its ID tokens are accepted as
+// bearer tokens, or nil.
+func (s *Service) BearerProvider() *auth.OIDCProvider {
+	if !authsettings.Current(s.cfg).OIDC.AcceptBearer {
+		return nil
+	}
 
-		// Register routes
-		app.Get(LoginPath, s.Login)
-		app.Get(CallbackPath, s.Callback)
-		app.Get(LogoutPath, s.Logout)
+	return s.Provider()
+}
+
+// newProvider discovers the provider of cfg. Failures are logged and leave
+// OIDC disabled until the settings change.
+func newProvider(cfg *config.OIDCAuth, db *gorm.DB) (*auth.OIDCProvider, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
+	provider, err := auth.NewOIDCProvider(ctx, auth.NewOIDCConfig(cfg), db)
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCDisabled) {
+			log.Info().Msg("OIDC authentication is disabled by configuration")
+		} else {
+			log.Warn().Err(err).Msg("Failed to initialize OIDC provider - OIDC authentication will be disabled")
+		}
 
-		// Start state cleanup goroutine
-		go s.cleanupStates()
+		return nil, err
 	}
+
+	log.Info().Msg("OIDC authentication provider initialized")
+
+	return provider, nil
 }
 
 // Login initiates the OIDC login flow.
 func (s *Service) Login(c fiber.Ctx) error {
-	if s.oidcProvider == nil {
-		return c.Status(fiber.StatusServiceUnavailable).SendString("OIDC authentication is not available")
+	provider := s.Provider()
+	if provider == nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "OIDC authentication is not available")
 	}
 
 	// Generate state token for CSRF protection
 	state, err := auth.GenerateStateToken()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate state token")
-		return c.Status(fiber.StatusInternalServerError).SendString("Internal server error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
-	// Store state with expiration (5 minutes)
-	s.stateMu.Lock()
-	s.stateStore[state] = time.Now().Add(5 * time.Minute)
-	s.stateMu.Unlock()
+	// Store state in the shared session storage so the callback may be served
+	// by another replica or after a restart.
+	if err = session.WriteOIDCState(state, stateTTL); err != nil {
+		log.Error().Err(err).Msg("Failed to store state token")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+	}
 
 	// Get authorization URL
-	authURL := s.oidcProvider.GetAuthURL(state)
+	authURL := provider.GetAuthURL(state)
 
 	// Redirect to OIDC provider
 	return c.Redirect().To(authURL)
@@ -123,8 +152,9 @@ func (s *Service) Login(c fiber.Ctx) error {
 
 // Callback handles the OIDC callback.
 func (s *Service) Callback(c fiber.Ctx) error {
-	if s.oidcProvider == nil {
-		return c.Status(fiber.StatusServiceUnavailable).SendString("OIDC authentication is not available")
+	provider := s.Provider()
+	if provider == nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "OIDC authentication is not available")
 	}
 
 	// Get code and state from query parameters
@@ -133,29 +163,25 @@ func (s *Service) Callback(c fiber.Ctx) error {
 
 	if code == "" || state == "" {
 		log.Error().Msg("Missing code or state in OIDC callback")
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid callback parameters")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid callback parameters")
 	}
 
-	// Verify state
-	s.stateMu.Lock()
-	expiration, exists := s.stateStore[state]
-	delete(s.stateStore, state)
-	s.stateMu.Unlock()
-
-	if !exists {
-		log.Error().Str("state", state).Msg("Invalid state token")
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid state token")
+	// Verify state (single use: the token is removed from storage on lookup)
+	expiration, err := session.ConsumeOIDCState(state)
+	if err != nil {
+		log.Error().Err(err).Str("state", state).Msg("Invalid state token")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid state token")
 	}
 
 	if time.Now().After(expiration) {
 		log.Error().Str("state", state).Msg("Expired state token")
-		return c.Status(fiber.StatusBadRequest).SendString("Expired state token")
+		return fiber.NewError(fiber.StatusBadRequest, "Expired state token")
 	}
 
 	// Handle callback
 	ctx := context.Background()
 
-	authenticatedUser, groups, err := s.oidcProvider.HandleCallback(ctx, code)
+	authenticatedUser, groups, err := provider.HandleCallback(ctx, code)
 	if err != nil {
 		log.Error().Err(err).Msg("OIDC authentication failed")
 		activitylog.Record(&activitylog.Entry{
@@ -165,8 +191,9 @@ func (s *Service) Callback(c fiber.Ctx) error {
 			Details:      map[string]any{"auth_type": "oidc", "reason": err.Error()},
 			IPAddress:    c.IP(),
 		})
+		loginhistory.Record(loginhistory.FromRequest(c, s.db).Failed("", loginhistory.ProviderOIDC, err))
 
-		return c.Status(fiber.StatusUnauthorized).SendString("Authentication failed")
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication failed")
 	}
 
 	// Sync OIDC groups
@@ -180,23 +207,25 @@ func (s *Service) Callback(c fiber.Ctx) error {
 	sessionID, errSession := session.GenerateSessionID()
 	if errSession != nil {
 		log.Error().Err(errSession).Msg("Failed to generate session ID")
-		return c.Status(fiber.StatusInternalServerError).SendString("Internal server error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
+	expiry := appsettings.Current(s.cfg).SessionExpiry
+
 	userSession := &session.Data{
 		User: *authenticatedUser,
 	}
 
-	if err = userSession.Write(sessionID, s.cfg.Webserver.Session.ExpiryTime); err != nil {
+	if err = userSession.Write(sessionID); err != nil {
 		log.Error().Err(err).Msg("Failed to write session")
-		return c.Status(fiber.StatusInternalServerError).SendString("Internal server error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
 	// Set login cookie
 	cookieSettings := &fiber.Cookie{
 		Name:     "session",
 		Value:    sessionID,
-		MaxAge:   int(s.cfg.Webserver.Session.ExpiryTime.Seconds()),
+		MaxAge:   int(expiry.Seconds()),
 		Secure:   true,
 		HTTPOnly: true,
 		SameSite: "Lax",
@@ -220,6 +249,7 @@ func (s *Service) Callback(c fiber.Ctx) error {
 		Details:      map[string]any{"auth_type": "oidc"},
 		IPAddress:    c.IP(),
 	})
+	loginhistory.Record(loginhistory.FromRequest(c, s.db).Succeeded(authenticatedUser, loginhistory.ProviderOIDC))
 
 	return c.Redirect().To(dashboard.Path)
 }
@@ -256,9 +286,9 @@ func (s *Service) Logout(c fiber.Ctx) error {
 		SameSite: "Lax",
 	})
 
-	if s.oidcProvider != nil {
+	if provider := s.Provider(); provider != nil {
 		postLogoutRedirectURI := s.cfg.Webserver.URL
-		logoutURL := s.oidcProvider.GetLogoutURL("", postLogoutRedirectURI)
+		logoutURL := provider.GetLogoutURL("", postLogoutRedirectURI)
 
 		if logoutURL != "" {
 			return c.Redirect().To(logoutURL)
@@ -267,23 +297,3 @@ func (s *Service) Logout(c fiber.Ctx) error {
 
 	return c.Redirect().To("/login")
 }
-
-// cleanupStates periodically removes expired state tokens.
-func (s *Service) cleanupStates() {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		now := time.Now()
-
-		s.stateMu.Lock()
-
-		for state, expiration := range s.stateStore {
-			if now.After(expiration) {
-				delete(s.stateStore, state)
-			}
-		}
-
-		s.stateMu.Unlock()
-	}
-}
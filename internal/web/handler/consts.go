@@ -24,4 +24,10 @@ const (
 
 	// BrandingSettingsPath is the path to the branding settings page.
 	BrandingSettingsPath = RootPath + "admin/settings/branding"
+
+	// AppSettingsPath is the path to the application settings page.
+	AppSettingsPath = RootPath + "admin/settings/app"
+
+	// AuthSettingsPath is the path to the LDAP and OIDC provider settings page.
+	AuthSettingsPath = RootPath + "admin/settings/auth"
 )
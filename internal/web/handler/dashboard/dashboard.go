@@ -15,10 +15,13 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonestats"
 )
 
 const (
@@ -43,15 +46,27 @@ const (
 	TabReverseV6 = "reverse-ipv6"
 
 	desc = "desc"
+
+	// modifiedLimit is the number of recently modified zones shown.
+	modifiedLimit = 5
 )
 
 // Zone represents a DNS zone for template rendering.
 type Zone struct {
-	Name    string
-	Kind    string
-	Serial  uint32
-	DNSSec  bool
-	Masters []string
+	Name           string
+	Kind           string
+	Serial         uint32
+	NotifiedSerial uint32
+	DNSSec         bool
+	Masters        []string
+	// LastCheck is when a Slave zone last checked its primaries; only set
+	// for the zones of the current page.
+	LastCheck time.Time
+	// Favorite is whether the current user starred the zone; only set for
+	// the zones of the current page.
+	Favorite bool
+	// Labels are the labels of the zone.
+	Labels labels.Labels
 }
 
 // QueryParams holds the query and pagination parameters.
@@ -60,6 +75,8 @@ type QueryParams struct {
 	PageSize    int
 	SearchQuery string
 	FilterKind  string
+	// FilterLabel keeps the zones with a label, written as key or key=value.
+	FilterLabel string
 	SortField   string
 	SortOrder   string
 }
@@ -77,6 +94,7 @@ type TabData struct {
 	NextPage    int
 	SearchQuery string
 	FilterKind  string
+	FilterLabel string
 	SortField   string
 	SortOrder   string
 }
@@ -87,6 +105,20 @@ type Data struct {
 	ForwardTab   TabData
 	ReverseV4Tab TabData
 	ReverseV6Tab TabData
+	// Favorites are the zones the current user starred.
+	Favorites []string
+	// Recent are the zones the current user opened last, newest first.
+	Recent []RecentZone
+	// Stats summarizes the zones the current user has access to.
+	Stats zonestats.Summary
+	// Modified are the zones changed last through this application.
+	Modified []zonestats.Modified
+	// Expiring are the zones and RRsets whose expiry date is near or passed.
+	Expiring []Expiring
+	// Query is the URL query of the current view, see stateQuery.
+	Query string
+	// Views are the views the current user saved.
+	Views []View
 }
 
 // Service is the dashboard handler service.
@@ -116,6 +148,26 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 		auth.RequirePermission(authService, auth.PermDashboardView),
 		s.Get,
 	)
+	app.Get(ExportPath+".csv",
+		auth.RequirePermission(authService, auth.PermDashboardView),
+		s.ExportCSV,
+	)
+	app.Get(ExportPath+".xlsx",
+		auth.RequirePermission(authService, auth.PermDashboardView),
+		s.ExportXLSX,
+	)
+	app.Post(FavoritePath+"/:name",
+		auth.RequirePermission(authService, auth.PermDashboardView),
+		s.ToggleFavorite,
+	)
+	app.Post(ViewPath,
+		auth.RequirePermission(authService, auth.PermDashboardView),
+		s.SaveView,
+	)
+	app.Post(ViewPath+"/:id/delete",
+		auth.RequirePermission(authService, auth.PermDashboardView),
+		s.DeleteView,
+	)
 }
 
 // Get handles the dashboard page rendering.
@@ -150,6 +202,10 @@ func (s *Service) Get(c fiber.Ctx) error {
 	hasSearch := queryArgs.Has("search")
 	hasKind := queryArgs.Has("kind")
 
+	if target := missingFilters(c, sessData.DashboardFilters.Search, sessData.DashboardFilters.Kind); target != "" {
+		return c.Redirect().To(target)
+	}
+
 	if hasSearch {
 		sessData.DashboardFilters.Search = c.Query("search")
 	}
@@ -158,8 +214,10 @@ func (s *Service) Get(c fiber.Ctx) error {
 		sessData.DashboardFilters.Kind = c.Query("kind")
 	}
 
-	if hasSearch || hasKind {
-		if err := sessData.Write(sessionID, s.cfg.Webserver.Session.ExpiryTime); err != nil {
+	// Requests authenticated by an API key or bearer token have no session
+	// to remember the filters in.
+	if (hasSearch || hasKind) && sessionID != "" {
+		if err := sessData.Write(sessionID); err != nil {
 			log.Debug().Err(err).Msg("dashboard: could not write session data for filters")
 		}
 	}
@@ -174,7 +232,7 @@ func (s *Service) Get(c fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	apiZones, err := powerdns.Engine.Zones.List(ctx)
+	apiZones, err := zoneindex.Default.List(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to fetch zones from PowerDNS")
 
@@ -190,16 +248,37 @@ func (s *Service) Get(c fiber.Ctx) error {
 	forwardZones, reverseV4Zones, reverseV6Zones := categorizeZones(apiZones)
 	forwardZones, reverseV4Zones, reverseV6Zones = s.applyZoneAccessFilter(c, forwardZones, reverseV4Zones, reverseV6Zones)
 
+	errMsg := c.Query("error")
+
 	zones := selectTabZones(activeTab, forwardZones, reverseV4Zones, reverseV6Zones)
 	zones = s.filterTabZones(ctx, zones, activeTab, &params)
+
+	zones, err = s.labelZones(zones, params.FilterLabel)
+	if err != nil {
+		log.Debug().Err(err).Str("label", params.FilterLabel).Msg("dashboard: invalid label filter")
+
+		errMsg = "Invalid label filter: " + err.Error()
+	}
+
 	sortZones(zones, params.SortField, params.SortOrder)
 
+	available := zoneNames(forwardZones, reverseV4Zones, reverseV6Zones)
+	favorites, recent := s.loadQuickAccess(c, available)
+
 	paginatedZones, totalPages, actualPage := paginateZones(zones, params.Page, params.PageSize)
+	fillLastChecks(ctx, paginatedZones)
+	markFavorites(paginatedZones, favorites)
 	params.Page = actualPage
 	tabData := buildTabData(paginatedZones, totalPages, &params)
 	tabData.TotalItems = len(zones)
 
 	data := assembleDashboardData(activeTab, &tabData, forwardZones, reverseV4Zones, reverseV6Zones)
+	data.Favorites = favorites
+	data.Recent = recent
+	data.Stats, data.Modified = s.loadStats(apiZones, available)
+	data.Expiring = s.loadExpiring(available, time.Now())
+	data.Query = stateQuery(activeTab, &params)
+	data.Views = s.loadViews(c, data.Query)
 
 	log.Debug().
 		Int("total_zones", len(apiZones)).
@@ -211,16 +290,38 @@ func (s *Service) Get(c fiber.Ctx) error {
 		Int("page_size", params.PageSize).
 		Str("search", params.SearchQuery).
 		Str("filter_kind", params.FilterKind).
+		Str("filter_label", params.FilterLabel).
 		Str("sort_field", params.SortField).
 		Str("sort_order", params.SortOrder).
 		Msg("Dashboard zones retrieved successfully")
 
 	return c.Render(TemplateName, fiber.Map{
 		"Navigation": nav,
-		"Data":       data,
+		"Data":       &data,
+		"Success":    c.Query("success"),
+		"Error":      errMsg,
 	}, handler.BaseLayout)
 }
 
+// loadStats returns the statistics of the zones of apiZones in available,
+// which holds the zones the user may see, and the zones changed last.
+func (s *Service) loadStats(apiZones []pdnsapi.Zone, available map[string]bool) (zonestats.Summary, []zonestats.Modified) {
+	visible := make([]pdnsapi.Zone, 0, len(available))
+
+	for i := range apiZones {
+		if available[pdnsapi.StringValue(apiZones[i].Name)] {
+			visible = append(visible, apiZones[i])
+		}
+	}
+
+	modified, err := zonestats.RecentlyModified(s.db, func(name string) bool { return available[name] }, modifiedLimit)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load recently modified zones")
+	}
+
+	return zonestats.Default.Summarize(visible), modified
+}
+
 // resolveActiveTab returns the requested tab or falls back to TabForward if invalid.
 func resolveActiveTab(c fiber.Ctx) string {
 	tab := c.Query("tab", TabForward)
@@ -239,6 +340,7 @@ func parseQueryParams(c fiber.Ctx, defaultPageSize int, defaultSearch, defaultKi
 		PageSize:    fiber.Query[int](c, "pageSize", defaultPageSize),
 		SearchQuery: c.Query("search", defaultSearch),
 		FilterKind:  c.Query("kind", defaultKind),
+		FilterLabel: c.Query("label"),
 		SortField:   c.Query("sort", "name"),
 		SortOrder:   c.Query("order", "asc"),
 	}
@@ -254,20 +356,25 @@ func parseQueryParams(c fiber.Ctx, defaultPageSize int, defaultSearch, defaultKi
 	return params
 }
 
-// applyZoneAccessFilter restricts zones to those accessible by the current user.
-// Admin users (nil accessible map) see all zones.
+// applyZoneAccessFilter restricts zones to those accessible by the current
+// request; see auth.ZoneFilter. Unrestricted users see all zones, and no zone
+// is shown when the access cannot be loaded.
 func (s *Service) applyZoneAccessFilter(c fiber.Ctx, fwd, v4, v6 []Zone) ([]Zone, []Zone, []Zone) {
 	currentUser, ok := c.Locals("CurrentUser").(models.User)
 	if !ok || currentUser.ID == 0 {
 		return fwd, v4, v6
 	}
 
-	accessible, err := s.authService.GetAccessibleZoneIDs(currentUser.ID)
-	if err != nil || accessible == nil {
+	allowed, err := auth.ZoneFilter(c, s.authService)
+	if err != nil {
+		allowed = func(string) bool { return false }
+	}
+
+	if allowed == nil {
 		return fwd, v4, v6
 	}
 
-	return filterByAccess(fwd, accessible), filterByAccess(v4, accessible), filterByAccess(v6, accessible)
+	return filterByAccess(fwd, allowed), filterByAccess(v4, allowed), filterByAccess(v6, allowed)
 }
 
 // filterTabZones applies the active tab's search and kind filters. Reverse tabs
@@ -349,6 +456,10 @@ func categorizeZones(apiZones []pdnsapi.Zone) (forward, reverseV4, reverseV6 []Z
 			zone.Serial = *apiZone.Serial
 		}
 
+		if apiZone.NotifiedSerial != nil {
+			zone.NotifiedSerial = *apiZone.NotifiedSerial
+		}
+
 		switch {
 		case strings.HasSuffix(zone.Name, ".in-addr.arpa."):
 			reverseV4 = append(reverseV4, zone)
@@ -362,6 +473,36 @@ func categorizeZones(apiZones []pdnsapi.Zone) (forward, reverseV4, reverseV6 []Z
 	return forward, reverseV4, reverseV6
 }
 
+// fillLastChecks sets LastCheck on the Slave zones of zones. The zone list of
+// the client library lacks last_check, so it is only fetched when the page
+// holds a Slave zone.
+func fillLastChecks(ctx context.Context, zones []Zone) {
+	hasSlave := false
+
+	for i := range zones {
+		if zones[i].Kind == string(pdnsapi.SlaveZoneKind) {
+			hasSlave = true
+			break
+		}
+	}
+
+	if !hasSlave {
+		return
+	}
+
+	statuses, err := powerdns.Engine.ZoneTransferStatuses(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to fetch zone transfer status")
+		return
+	}
+
+	for i := range zones {
+		if status, ok := statuses[zones[i].Name]; ok {
+			zones[i].LastCheck = status.LastCheck
+		}
+	}
+}
+
 // filterZones applies search and kind filters to zones.
 func filterZones(zones []Zone, searchQuery, filterKind string) []Zone {
 	// Apply search filter
@@ -393,6 +534,35 @@ func filterZones(zones []Zone, searchQuery, filterKind string) []Zone {
 	return zones
 }
 
+// labelZones sets the labels of zones and keeps those with the label of
+// selector, see labels.ParseSelector. An invalid selector is returned as an
+// error together with the unfiltered zones.
+func (s *Service) labelZones(zones []Zone, selector string) ([]Zone, error) {
+	byZone, err := labels.Zones(s.db)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load zone labels")
+	}
+
+	sel, selErr := labels.ParseSelector(selector)
+
+	return filterLabels(zones, byZone, sel), selErr
+}
+
+// filterLabels sets the labels of zones from byZone and keeps the zones sel
+// matches.
+func filterLabels(zones []Zone, byZone map[string]labels.Labels, sel labels.Selector) []Zone {
+	filtered := make([]Zone, 0, len(zones))
+
+	for _, zone := range zones {
+		zone.Labels = byZone[zone.Name]
+		if sel.Matches(zone.Labels) {
+			filtered = append(filtered, zone)
+		}
+	}
+
+	return filtered
+}
+
 // sortZones sorts zones by the specified field and order.
 func sortZones(zones []Zone, sortField, sortOrder string) {
 	switch sortField {
@@ -458,11 +628,11 @@ func paginateZones(zones []Zone, page, pageSize int) (paginatedZones []Zone, tot
 }
 
 // buildTabData creates TabData with pagination information.
-// filterByAccess removes zones whose Name is not in the accessible set.
-func filterByAccess(zones []Zone, accessible map[string]bool) []Zone {
+// filterByAccess removes the zones allowed does not admit.
+func filterByAccess(zones []Zone, allowed func(string) bool) []Zone {
 	out := make([]Zone, 0, len(zones))
 	for _, z := range zones {
-		if accessible[z.Name] {
+		if allowed(z.Name) {
 			out = append(out, z)
 		}
 	}
@@ -483,6 +653,7 @@ func buildTabData(zones []Zone, totalPages int, params *QueryParams) TabData {
 		NextPage:    params.Page + 1,
 		SearchQuery: params.SearchQuery,
 		FilterKind:  params.FilterKind,
+		FilterLabel: params.FilterLabel,
 		SortField:   params.SortField,
 		SortOrder:   params.SortOrder,
 	}
@@ -0,0 +1,57 @@
+package dashboard
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/expiry"
+)
+
+// expiringLimit is the number of expiring zones and RRsets shown.
+const expiringLimit = 10
+
+// Expiring is a zone or RRset whose expiry date is near or has passed.
+type Expiring struct {
+	ZoneName string
+	// Name and Type identify the RRset; both are empty for the zone.
+	Name      string
+	Type      string
+	ExpiresOn string
+	Note      string
+	Overdue   bool
+}
+
+// loadExpiring returns the zones and RRsets in available, which holds the
+// zones the user may see, that expire within the reminder period of the
+// expiry configuration, overdue ones first.
+func (s *Service) loadExpiring(available map[string]bool, now time.Time) []Expiring {
+	expiries, err := expiry.Upcoming(s.db, now.Add(s.cfg.Expiry.NotifyBefore),
+		func(name string) bool { return available[name] }, expiringLimit)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load expiring zones and records")
+		return nil
+	}
+
+	return expiringViews(expiries, now)
+}
+
+// expiringViews converts expiries for the dashboard.
+func expiringViews(expiries []models.Expiry, now time.Time) []Expiring {
+	today := now.UTC().Truncate(24 * time.Hour)
+
+	out := make([]Expiring, 0, len(expiries))
+	for i := range expiries {
+		out = append(out, Expiring{
+			ZoneName:  expiries[i].ZoneName,
+			Name:      expiries[i].Name,
+			Type:      expiries[i].Type,
+			ExpiresOn: expiries[i].ExpiresAt.UTC().Format(time.DateOnly),
+			Note:      expiries[i].Note,
+			Overdue:   expiries[i].ExpiresAt.Before(today),
+		})
+	}
+
+	return out
+}
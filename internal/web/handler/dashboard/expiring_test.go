@@ -0,0 +1,26 @@
+package dashboard
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestExpiringViews(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	got := expiringViews([]models.Expiry{
+		{ZoneName: "a.example.", ExpiresAt: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), Note: "campaign"},
+		{ZoneName: "a.example.", Name: "promo.a.example.", Type: "CNAME", ExpiresAt: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+	}, now)
+
+	want := []Expiring{
+		{ZoneName: "a.example.", ExpiresOn: "2026-10-16", Note: "campaign", Overdue: true},
+		{ZoneName: "a.example.", Name: "promo.a.example.", Type: "CNAME", ExpiresOn: "2026-10-17"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expiringViews() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/xlsx"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonestats"
+)
+
+// ExportPath is the path of the zone list export; .csv or .xlsx follows.
+const ExportPath = Path + "/export"
+
+// exportColumns are the columns of the zone list export. The record and
+// health check counts come from the last zone scan and are empty for zones
+// not scanned yet.
+var exportColumns = []string{
+	"name", "kind", "serial", "notified_serial", "dnssec", "records", "errors", "warnings", "labels",
+}
+
+// ExportURL returns the URL of the export of the zones of the view as format,
+// csv or xlsx.
+func (d *Data) ExportURL(format string) string {
+	return ExportPath + "." + format + "?" + d.Query
+}
+
+// ExportCSV downloads the zones of the dashboard view in the query as CSV.
+func (s *Service) ExportCSV(c fiber.Ctx) error {
+	activeTab, zones, err := s.exportZones(c)
+	if err != nil {
+		return err
+	}
+
+	c.Attachment("zones-" + activeTab + ".csv")
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		if err := writeZonesCSV(w, zones, zonestats.Default.Entry); err != nil {
+			log.Error().Err(err).Msg("failed to write zone list CSV")
+		}
+	})
+}
+
+// ExportXLSX downloads the zones of the dashboard view in the query as an
+// Excel workbook.
+func (s *Service) ExportXLSX(c fiber.Ctx) error {
+	activeTab, zones, err := s.exportZones(c)
+	if err != nil {
+		return err
+	}
+
+	c.Attachment("zones-" + activeTab + ".xlsx")
+	c.Set(fiber.HeaderContentType, xlsx.ContentType)
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		if err := writeZonesXLSX(w, zones, zonestats.Default.Entry); err != nil {
+			log.Error().Err(err).Msg("failed to write zone list workbook")
+		}
+	})
+}
+
+// exportZones returns the tab and the zones of the dashboard view in the
+// query: every zone of the tab the current user has access to that matches
+// the search, kind and label, in the sort order of the view. Unlike the dashboard,
+// it does not page the zones or fall back to the filters of the session.
+func (s *Service) exportZones(c fiber.Ctx) (string, []Zone, error) {
+	activeTab := resolveActiveTab(c)
+	params := parseQueryParams(c, DefaultPageSize, "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	apiZones, err := zoneindex.Default.List(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to fetch zones from PowerDNS")
+
+		msg := "Failed to fetch zones: " + err.Error()
+		if powerdns.IsServerUnreachable(err) {
+			msg = powerdns.ErrMsgServerUnreachable
+		}
+
+		return "", nil, fiber.NewError(fiber.StatusInternalServerError, msg)
+	}
+
+	fwd, v4, v6 := categorizeZones(apiZones)
+	fwd, v4, v6 = s.applyZoneAccessFilter(c, fwd, v4, v6)
+
+	zones := selectTabZones(activeTab, fwd, v4, v6)
+	zones = s.filterTabZones(ctx, zones, activeTab, &params)
+
+	zones, err = s.labelZones(zones, params.FilterLabel)
+	if err != nil {
+		return "", nil, fiber.NewError(fiber.StatusBadRequest, "Invalid label filter: "+err.Error())
+	}
+
+	sortZones(zones, params.SortField, params.SortOrder)
+
+	return activeTab, zones, nil
+}
+
+// exportRow returns the cells of zone in the order of exportColumns, with
+// the counts of its scan entry; nil cells are empty.
+func exportRow(zone *Zone, entry func(name string) (zonestats.Entry, bool)) []any {
+	row := []any{zone.Name, zone.Kind, zone.Serial, zone.NotifiedSerial, zone.DNSSec, nil, nil, nil, zone.Labels.String()}
+
+	if e, ok := entry(zone.Name); ok && e.Err == "" {
+		row[5], row[6], row[7] = e.Records, e.Errors, e.Warnings
+	}
+
+	return row
+}
+
+// writeZonesCSV writes zones as CSV with exportColumns.
+func writeZonesCSV(w io.Writer, zones []Zone, entry func(name string) (zonestats.Entry, bool)) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(exportColumns); err != nil {
+		return err
+	}
+
+	for i := range zones {
+		row := exportRow(&zones[i], entry)
+
+		record := make([]string, len(row))
+		for j, v := range row {
+			if v != nil {
+				record[j] = fmt.Sprint(v)
+			}
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// writeZonesXLSX writes zones as a workbook with exportColumns.
+func writeZonesXLSX(w io.Writer, zones []Zone, entry func(name string) (zonestats.Entry, bool)) error {
+	xw := xlsx.NewWriter(w, "Zones")
+
+	if err := xw.WriteHeader(exportColumns); err != nil {
+		return err
+	}
+
+	for i := range zones {
+		if err := xw.Write(exportRow(&zones[i], entry)); err != nil {
+			return err
+		}
+	}
+
+	return xw.Close()
+}
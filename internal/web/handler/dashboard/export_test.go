@@ -0,0 +1,71 @@
+package dashboard
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonestats"
+)
+
+func TestWriteZonesCSV(t *testing.T) {
+	zones := []Zone{
+		{
+			Name: "example.com.", Kind: "Native", Serial: 2026101701, NotifiedSerial: 2026101701, DNSSec: true,
+			Labels: labels.Labels{"owner": "web-team", "ticket": "JIRA-123"},
+		},
+		{Name: "new.example.", Kind: "Master", Serial: 1},
+		{Name: "broken.example.", Kind: "Slave"},
+	}
+
+	entries := map[string]zonestats.Entry{
+		"example.com.":    {Records: 12, Warnings: 1},
+		"broken.example.": {Err: "timeout"},
+	}
+
+	entry := func(name string) (zonestats.Entry, bool) {
+		e, ok := entries[name]
+		return e, ok
+	}
+
+	var b strings.Builder
+	if err := writeZonesCSV(&b, zones, entry); err != nil {
+		t.Fatalf("writeZonesCSV: %v", err)
+	}
+
+	want := "name,kind,serial,notified_serial,dnssec,records,errors,warnings,labels\n" +
+		"example.com.,Native,2026101701,2026101701,true,12,0,1,\"owner=web-team, ticket=JIRA-123\"\n" +
+		"new.example.,Master,1,0,false,,,,\n" +
+		"broken.example.,Slave,0,0,false,,,,\n"
+
+	if b.String() != want {
+		t.Errorf("writeZonesCSV =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestFilterLabels(t *testing.T) {
+	zones := []Zone{{Name: "a.example."}, {Name: "b.example."}, {Name: "c.example."}}
+	byZone := map[string]labels.Labels{
+		"a.example.": {"owner": "web-team"},
+		"b.example.": {"owner": "db-team"},
+	}
+
+	sel, err := labels.ParseSelector("owner=web-team")
+	if err != nil {
+		t.Fatalf("ParseSelector: %v", err)
+	}
+
+	got := filterLabels(zones, byZone, sel)
+	if len(got) != 1 || got[0].Name != "a.example." || got[0].Labels["owner"] != "web-team" {
+		t.Errorf("filterLabels(owner=web-team) = %+v", got)
+	}
+
+	sel, _ = labels.ParseSelector("owner")
+	if got := filterLabels(zones, byZone, sel); len(got) != 2 {
+		t.Errorf("filterLabels(owner) kept %d zones, want 2", len(got))
+	}
+
+	if got := filterLabels(zones, byZone, labels.Selector{}); len(got) != 3 || got[1].Labels["owner"] != "db-team" {
+		t.Errorf("filterLabels() without selector = %+v", got)
+	}
+}
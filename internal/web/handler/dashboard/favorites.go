@@ -0,0 +1,110 @@
+package dashboard
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/userzones"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// FavoritePath is the path that stars or unstars a zone; the zone name follows.
+const FavoritePath = handler.RootPath + "zone/favorite"
+
+// RecentZone is a zone the user opened recently.
+type RecentZone struct {
+	Name      string
+	VisitedAt time.Time
+}
+
+// ToggleFavorite stars the zone for the current user, or removes the star
+// when the form field favorite is 0, and redirects back to the dashboard.
+func (s *Service) ToggleFavorite(c fiber.Ctx) error {
+	zoneName := c.Params("name")
+	if !strings.HasSuffix(zoneName, ".") {
+		zoneName += "."
+	}
+
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Not logged in")
+	}
+
+	if !auth.CanAccessZone(c, s.authService, zoneName) {
+		return fiber.NewError(fiber.StatusForbidden, "Access to this zone is not permitted")
+	}
+
+	favorite := c.FormValue("favorite") != "0"
+
+	if err := userzones.SetFavorite(s.db, user.ID, zoneName, favorite); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to update favorite zone")
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update favorites")
+	}
+
+	return c.Redirect().Back(Path)
+}
+
+// loadQuickAccess returns the favorite and recently opened zones of the
+// current user, limited to the zones in available, which holds the zones the
+// user may see.
+func (s *Service) loadQuickAccess(c fiber.Ctx, available map[string]bool) ([]string, []RecentZone) {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return nil, nil
+	}
+
+	favorites, err := userzones.Favorites(s.db, user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load favorite zones")
+	}
+
+	visits, err := userzones.Recent(s.db, user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load recent zones")
+	}
+
+	return quickAccess(favorites, visits, available)
+}
+
+// quickAccess drops the favorites and visits of zones not in available, e.g.
+// deleted zones or zones the user lost access to.
+func quickAccess(favorites []string, visits []models.ZoneVisit, available map[string]bool) ([]string, []RecentZone) {
+	favorites = slices.DeleteFunc(favorites, func(name string) bool { return !available[name] })
+
+	recent := make([]RecentZone, 0, len(visits))
+
+	for _, v := range visits {
+		if available[v.ZoneName] {
+			recent = append(recent, RecentZone{Name: v.ZoneName, VisitedAt: v.VisitedAt})
+		}
+	}
+
+	return favorites, recent
+}
+
+// markFavorites sets Zone.Favorite on the zones in favorites.
+func markFavorites(zones []Zone, favorites []string) {
+	for i := range zones {
+		zones[i].Favorite = slices.Contains(favorites, zones[i].Name)
+	}
+}
+
+// zoneNames returns the set of the names of the given zone lists.
+func zoneNames(lists ...[]Zone) map[string]bool {
+	names := map[string]bool{}
+
+	for _, zones := range lists {
+		for i := range zones {
+			names[zones[i].Name] = true
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,38 @@
+package dashboard
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestQuickAccess(t *testing.T) {
+	available := zoneNames([]Zone{{Name: "a.example."}, {Name: "b.example."}}, []Zone{{Name: "2.0.192.in-addr.arpa."}})
+	visited := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	favorites, recent := quickAccess(
+		[]string{"a.example.", "gone.example."},
+		[]models.ZoneVisit{
+			{ZoneName: "2.0.192.in-addr.arpa.", VisitedAt: visited},
+			{ZoneName: "hidden.example.", VisitedAt: visited},
+		},
+		available,
+	)
+
+	if !reflect.DeepEqual(favorites, []string{"a.example."}) {
+		t.Errorf("favorites = %v", favorites)
+	}
+
+	if want := []RecentZone{{Name: "2.0.192.in-addr.arpa.", VisitedAt: visited}}; !reflect.DeepEqual(recent, want) {
+		t.Errorf("recent = %+v, want %+v", recent, want)
+	}
+
+	zones := []Zone{{Name: "a.example."}, {Name: "b.example."}}
+	markFavorites(zones, favorites)
+
+	if !zones[0].Favorite || zones[1].Favorite {
+		t.Errorf("markFavorites() = %+v", zones)
+	}
+}
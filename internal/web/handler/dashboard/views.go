@@ -0,0 +1,224 @@
+package dashboard
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+const (
+	// ViewPath is the path that saves a dashboard view; POST ViewPath/:id/delete
+	// deletes one.
+	ViewPath = Path + "/views"
+
+	// MaxViews is the number of views a user can save.
+	MaxViews = 50
+
+	maxViewName = 100
+)
+
+var (
+	errViewName  = errors.New("enter a name of at most 100 characters for the view")
+	errViewLimit = errors.New("you cannot save more than 50 views; delete one first")
+)
+
+// viewParams are the query parameters a view keeps. The page is left out so
+// a view always opens on its first page.
+var viewParams = []string{"tab", "search", "kind", "label", "sort", "order", "pageSize"}
+
+// View is a saved dashboard view.
+type View struct {
+	ID    uint64
+	Name  string
+	Query string
+	// Active is whether the dashboard currently shows this view.
+	Active bool
+}
+
+// SaveView saves the dashboard query of the form field query under the name of
+// the form field name, replacing a view of the same name, and opens it.
+func (s *Service) SaveView(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Not logged in")
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	query := viewQuery(c.FormValue("query"))
+
+	if err := s.saveView(user.ID, name, query); err != nil {
+		if errors.Is(err, errViewName) || errors.Is(err, errViewLimit) {
+			return c.Redirect().To(Path + "?" + withMessage(query, "error", err.Error()))
+		}
+
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("user_id", user.ID).Msg("failed to save dashboard view")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save the view")
+	}
+
+	return c.Redirect().To(Path + "?" + withMessage(query, "success", "View "+name+" saved."))
+}
+
+// DeleteView deletes a view of the current user and redirects back.
+func (s *Service) DeleteView(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Not logged in")
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid view ID")
+	}
+
+	if err = s.db.Where("id = ? AND user_id = ?", id, user.ID).Delete(&models.DashboardView{}).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("view_id", id).Msg("failed to delete dashboard view")
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete the view")
+	}
+
+	return c.Redirect().Back(Path)
+}
+
+// saveView stores query as the view name of userID.
+func (s *Service) saveView(userID uint64, name, query string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxViewName {
+		return errViewName
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.DashboardView{}).
+			Where("user_id = ? AND name <> ?", userID, name).
+			Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count >= MaxViews {
+			return errViewLimit
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"query", "updated_at"}),
+		}).Create(&models.DashboardView{UserID: userID, Name: name, Query: query}).Error
+	})
+}
+
+// loadViews returns the saved views of the current user by name, marking the
+// one matching the query current.
+func (s *Service) loadViews(c fiber.Ctx, current string) []View {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return nil
+	}
+
+	var saved []models.DashboardView
+	if err := s.db.Where("user_id = ?", user.ID).Order("name").Find(&saved).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load dashboard views")
+		return nil
+	}
+
+	views := make([]View, 0, len(saved))
+	for i := range saved {
+		views = append(views, View{
+			ID:     saved[i].ID,
+			Name:   saved[i].Name,
+			Query:  saved[i].Query,
+			Active: saved[i].Query == current,
+		})
+	}
+
+	return views
+}
+
+// stateQuery returns the view query of the dashboard state.
+func stateQuery(activeTab string, params *QueryParams) string {
+	values := url.Values{}
+	values.Set("tab", activeTab)
+	values.Set("search", params.SearchQuery)
+	values.Set("kind", params.FilterKind)
+	values.Set("label", params.FilterLabel)
+	values.Set("sort", params.SortField)
+	values.Set("order", params.SortOrder)
+	values.Set("pageSize", strconv.Itoa(params.PageSize))
+
+	return encodeView(values)
+}
+
+// viewQuery returns the view parameters of the URL query raw.
+func viewQuery(raw string) string {
+	values, err := url.ParseQuery(strings.TrimPrefix(raw, "?"))
+	if err != nil {
+		return ""
+	}
+
+	return encodeView(values)
+}
+
+// encodeView encodes the view parameters of values in a fixed order. search
+// and kind are always included, empty when unset, so that opening the view
+// clears the filters remembered in the session.
+func encodeView(values url.Values) string {
+	var b strings.Builder
+
+	for _, key := range viewParams {
+		v := values.Get(key)
+		if v == "" && key != "search" && key != "kind" {
+			continue
+		}
+
+		if b.Len() > 0 {
+			b.WriteByte('&')
+		}
+
+		b.WriteString(key + "=" + url.QueryEscape(v))
+	}
+
+	return b.String()
+}
+
+// withMessage appends a success or error message to query.
+func withMessage(query, key, msg string) string {
+	if query != "" {
+		query += "&"
+	}
+
+	return query + key + "=" + url.QueryEscape(msg)
+}
+
+// missingFilters returns the URL the dashboard redirects to when a search or
+// kind remembered in the session is applied but not part of the request URL,
+// so that the address bar always reflects the view. It is empty when no
+// redirect is needed.
+func missingFilters(c fiber.Ctx, search, kind string) string {
+	args := c.Request().URI().QueryArgs()
+
+	values, err := url.ParseQuery(string(args.QueryString()))
+	if err != nil {
+		return ""
+	}
+
+	missing := false
+
+	for key, remembered := range map[string]string{"search": search, "kind": kind} {
+		if remembered != "" && !args.Has(key) {
+			values.Set(key, remembered)
+			missing = true
+		}
+	}
+
+	if !missing {
+		return ""
+	}
+
+	return Path + "?" + values.Encode()
+}
@@ -0,0 +1,111 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestViewQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"", "search=&kind="},
+		{"?tab=reverse-ipv4&page=3&search=10.0&pageSize=50", "tab=reverse-ipv4&search=10.0&kind=&pageSize=50"},
+		{"kind=Slave&sort=serial&order=desc&success=saved", "search=&kind=Slave&sort=serial&order=desc"},
+		{"search=a+b%26c", "search=a+b%26c&kind="},
+		{"%zz", ""},
+	}
+
+	for _, tt := range tests {
+		if got := viewQuery(tt.raw); got != tt.want {
+			t.Errorf("viewQuery(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+
+	params := QueryParams{Page: 2, PageSize: 25, SearchQuery: "example", SortField: "name", SortOrder: "asc"}
+	if got, want := stateQuery(TabForward, &params), "tab=forward&search=example&kind=&sort=name&order=asc&pageSize=25"; got != want {
+		t.Errorf("stateQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestSaveView(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.DashboardView{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	db.Create(&models.User{Username: "alice", Email: "alice@example.com"})
+
+	s := &Service{db: db}
+
+	if err = s.saveView(1, "", "tab=forward"); !errors.Is(err, errViewName) {
+		t.Errorf("saveView(empty name) error = %v, want %v", err, errViewName)
+	}
+
+	for i := range MaxViews {
+		if err = s.saveView(1, fmt.Sprintf("view %d", i), "tab=forward"); err != nil {
+			t.Fatalf("saveView() error = %v", err)
+		}
+	}
+
+	if err = s.saveView(1, "one too many", "tab=forward"); !errors.Is(err, errViewLimit) {
+		t.Errorf("saveView() beyond limit error = %v, want %v", err, errViewLimit)
+	}
+
+	// Saving under an existing name replaces the view, even at the limit.
+	if err = s.saveView(1, "view 0", "tab=reverse-ipv6"); err != nil {
+		t.Fatalf("saveView(existing) error = %v", err)
+	}
+
+	var view models.DashboardView
+	db.Where("user_id = ? AND name = ?", 1, "view 0").First(&view)
+
+	if view.Query != "tab=reverse-ipv6" {
+		t.Errorf("replaced view query = %q", view.Query)
+	}
+}
+
+func TestMissingFilters(t *testing.T) {
+	tests := []struct {
+		target       string
+		search, kind string
+		want         string
+	}{
+		{"/dashboard?tab=forward", "", "", ""},
+		{"/dashboard?tab=forward", "example", "", "/dashboard?search=example&tab=forward"},
+		{"/dashboard?kind=Slave", "example", "Master", "/dashboard?kind=Slave&search=example"},
+		{"/dashboard?search=&kind=", "example", "Master", ""},
+	}
+
+	for _, tt := range tests {
+		app := fiber.New()
+
+		var got string
+
+		app.Get(Path, func(c fiber.Ctx) error {
+			got = missingFilters(c, tt.search, tt.kind)
+			return nil
+		})
+
+		if _, err := app.Test(httptest.NewRequest(fiber.MethodGet, tt.target, nil)); err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+
+		if got != tt.want {
+			t.Errorf("missingFilters(%s, %q, %q) = %q, want %q", tt.target, tt.search, tt.kind, got, tt.want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gofiber/fiber/v3"
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/maintenance"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
 )
 
@@ -71,6 +72,21 @@ func (h *Handler) Check(c fiber.Ctx) error {
 		checks["powerdns"] = "ok"
 	}
 
+	// Maintenance mode only locks out users; the service itself stays healthy
+	// so that load balancers keep routing to it.
+	if maintenance.Current().Enabled {
+		checks["maintenance"] = "enabled"
+	} else {
+		checks["maintenance"] = "off"
+	}
+
+	// Read-only mode only rejects changes; zones are still served.
+	if powerdns.ReadOnly() {
+		checks["readonly"] = "enabled"
+	} else {
+		checks["readonly"] = "off"
+	}
+
 	s := status{Checks: checks}
 
 	if healthy {
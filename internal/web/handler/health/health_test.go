@@ -13,6 +13,9 @@ import (
 	"github.com/gofiber/fiber/v3"
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/maintenance"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
 )
 
 func newTestDB(t *testing.T) *gorm.DB {
@@ -137,3 +140,41 @@ func TestCheck_PDNSNotConfigured(t *testing.T) {
 		t.Errorf("expected powerdns=not_configured, got %q", s.Checks["powerdns"])
 	}
 }
+
+// TestCheck_Maintenance verifies that maintenance mode is reported but keeps
+// the endpoint at 200.
+func TestCheck_Maintenance(t *testing.T) {
+	var alive atomic.Bool
+	alive.Store(true)
+
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&models.Setting{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	store, err := maintenance.NewStore(db)
+	if err != nil {
+		t.Fatalf("maintenance store: %v", err)
+	}
+
+	if err := store.Set(&maintenance.State{Enabled: true}); err != nil {
+		t.Fatalf("enable maintenance: %v", err)
+	}
+
+	maintenance.SetDefault(store)
+	t.Cleanup(func() { maintenance.SetDefault(nil) })
+
+	resp := doGet(t, newTestApp(db, &alive))
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 during maintenance, got %d", resp.StatusCode)
+	}
+
+	s := decodeStatus(t, resp)
+
+	if s.Checks["maintenance"] != "enabled" {
+		t.Errorf("expected maintenance=enabled, got %q", s.Checks["maintenance"])
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ZoneEditPathPrefix is the path prefix of the zone edit page; the zone name
+// (canonical, with trailing dot) follows it directly.
+const ZoneEditPathPrefix = RootPath + "zone/edit/"
+
+// ZoneEditURL returns the zone edit page URL for the given zone.
+func ZoneEditURL(zone string) string {
+	return ZoneEditPathPrefix + canonicalName(zone)
+}
+
+// RecordURL returns a deep link to a single RRset on the zone edit page, e.g.
+// /zone/edit/example.com.?record=www.example.com.&type=A#www-A.
+//
+// The query parameters are authoritative (they survive login redirects and
+// mail clients that strip fragments); the fragment is a readable anchor that
+// the page understands on its own as well. An empty rrType links to every
+// RRset with the given name.
+func RecordURL(zone, name, rrType string) string {
+	zone = canonicalName(zone)
+	name = canonicalName(name)
+
+	q := url.Values{}
+	q.Set("record", name)
+
+	if rrType != "" {
+		q.Set("type", strings.ToUpper(rrType))
+	}
+
+	return ZoneEditPathPrefix + zone + "?" + q.Encode() + "#" + url.PathEscape(RecordAnchor(zone, name, rrType))
+}
+
+// RecordAnchor returns the short fragment identifier for an RRset relative to
+// its zone: "www-A" for www.example.com./A, "@-MX" for the apex and just the
+// relative name when rrType is empty.
+func RecordAnchor(zone, name, rrType string) string {
+	zone = canonicalName(zone)
+	name = canonicalName(name)
+
+	rel := name
+
+	switch {
+	case strings.EqualFold(name, zone):
+		rel = "@"
+	case strings.HasSuffix(strings.ToLower(name), "."+strings.ToLower(zone)):
+		rel = name[:len(name)-len(zone)-1]
+	}
+
+	if rrType == "" {
+		return rel
+	}
+
+	return rel + "-" + strings.ToUpper(rrType)
+}
+
+// canonicalName appends the trailing dot to a DNS name when it is missing.
+func canonicalName(name string) string {
+	name = strings.TrimSpace(name)
+	if name != "" && !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name
+}
@@ -0,0 +1,45 @@
+package handler
+
+import "testing"
+
+func TestRecordAnchor(t *testing.T) {
+	tests := []struct {
+		name, zone, record, rrType, want string
+	}{
+		{"relative name", "example.com.", "www.example.com.", "A", "www-A"},
+		{"apex", "example.com", "example.com.", "mx", "@-MX"},
+		{"no type", "example.com.", "mail.example.com", "", "mail"},
+		{"foreign name", "example.com.", "www.example.org.", "CNAME", "www.example.org.-CNAME"},
+		{"case insensitive", "Example.COM.", "WWW.example.com.", "A", "WWW-A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecordAnchor(tt.zone, tt.record, tt.rrType); got != tt.want {
+				t.Errorf("RecordAnchor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordURL(t *testing.T) {
+	got := RecordURL("example.com", "www.example.com", "a")
+	want := "/zone/edit/example.com.?record=www.example.com.&type=A#www-A"
+
+	if got != want {
+		t.Errorf("RecordURL() = %q, want %q", got, want)
+	}
+
+	got = RecordURL("example.com.", "example.com.", "")
+	want = "/zone/edit/example.com.?record=example.com.#@"
+
+	if got != want {
+		t.Errorf("RecordURL() without type = %q, want %q", got, want)
+	}
+}
+
+func TestZoneEditURL(t *testing.T) {
+	if got := ZoneEditURL("example.com"); got != "/zone/edit/example.com." {
+		t.Errorf("ZoneEditURL() = %q", got)
+	}
+}
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
+)
+
+// Paging limits of the JSON list endpoints.
+const (
+	// DefaultListLimit is the number of rows returned when none is asked for.
+	DefaultListLimit = 25
+	// MaxListLimit is the largest number of rows returned at once.
+	MaxListLimit = 500
+)
+
+// Errors of ParseListRequest.
+var (
+	ErrListSort  = errors.New("unknown sort column")
+	ErrListOrder = errors.New("order must be asc or desc")
+	ErrListLimit = errors.New("invalid page size")
+	ErrListSince = errors.New("modified_since must be a date or an RFC 3339 time")
+	ErrListField = errors.New("unknown field")
+)
+
+// ListColumns maps the names of the sortable fields of a JSON list to their
+// SQL columns. Only these names are accepted as sort keys, so user input
+// never reaches the ORDER BY clause.
+type ListColumns map[string]string
+
+// ListRequest is the paging, sorting and search of a JSON list request.
+type ListRequest struct {
+	// Draw is the DataTables request counter, echoed in the response.
+	Draw   int
+	Offset int
+	Limit  int
+	Search string
+	// Sort is the SQL column to order by; Desc reverses the order.
+	Sort string
+	Desc bool
+	// Prefix keeps the rows whose name starts with it; Since those changed
+	// at or after it. Both are applied by Scope.
+	Prefix string
+	Since  time.Time
+	// Fields are the fields of the rows to return, all when empty; see
+	// SelectFields.
+	Fields []string
+}
+
+// ParseListRequest reads the paging, sorting, search, filters and fields of
+// a JSON list request. Both the plain parameters page, page_size (or offset
+// and limit), search, sort and order and the DataTables server-side
+// parameters draw, start, length, search[value], order[0][column],
+// order[0][dir] and columns[n][data] are accepted, as are prefix,
+// modified_since and a comma-separated list of fields. Without a sort key the
+// rows are ordered by defaultSort, which must be a key of columns, descending
+// when defaultDesc is set.
+func ParseListRequest(c fiber.Ctx, columns ListColumns, defaultSort string, defaultDesc bool) (ListRequest, error) {
+	r := ListRequest{
+		Draw:   fiber.Query[int](c, "draw", 0),
+		Limit:  DefaultListLimit,
+		Search: strings.TrimSpace(c.Query("search", c.Query("search[value]"))),
+	}
+
+	if v := c.Query("length", c.Query("limit", c.Query("page_size"))); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxListLimit {
+			return r, fmt.Errorf("%w: %q (1 to %d)", ErrListLimit, v, MaxListLimit)
+		}
+
+		r.Limit = limit
+	}
+
+	if v := c.Query("start", c.Query("offset")); v != "" {
+		offset, _ := strconv.Atoi(v)
+		r.Offset = max(offset, 0)
+	} else {
+		r.Offset = (max(fiber.Query[int](c, "page", 1), 1) - 1) * r.Limit
+	}
+
+	sort, order := c.Query("sort"), c.Query("order")
+
+	if v := c.Query("order[0][column]"); v != "" {
+		sort = c.Query("columns[" + v + "][data]")
+		order = c.Query("order[0][dir]")
+	}
+
+	if sort == "" {
+		sort = defaultSort
+
+		if order == "" && defaultDesc {
+			order = "desc"
+		}
+	}
+
+	column, ok := columns[sort]
+	if !ok {
+		return r, fmt.Errorf("%w: %q", ErrListSort, sort)
+	}
+
+	switch strings.ToLower(order) {
+	case "", "asc":
+	case "desc":
+		r.Desc = true
+	default:
+		return r, fmt.Errorf("%w: %q", ErrListOrder, order)
+	}
+
+	r.Sort = column
+
+	r.Prefix = strings.TrimSpace(c.Query("prefix"))
+
+	if v := c.Query("modified_since"); v != "" {
+		since, err := parseSince(v)
+		if err != nil {
+			return r, fmt.Errorf("%w: %q", ErrListSince, v)
+		}
+
+		r.Since = since
+	}
+
+	for field := range strings.SplitSeq(c.Query("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			r.Fields = append(r.Fields, field)
+		}
+	}
+
+	return r, nil
+}
+
+// parseSince parses modified_since, an RFC 3339 time or a date, which is the
+// start of the day in UTC.
+func parseSince(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.DateOnly, v)
+}
+
+// Scope returns the scope of the Prefix and Since filters of r: the rows
+// whose nameColumn starts with Prefix, ignoring case, and whose
+// modifiedColumn is not before Since.
+func (r *ListRequest) Scope(nameColumn, modifiedColumn string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if r.Prefix != "" {
+			tx = tx.Scopes(like.StartsWith(r.Prefix, nameColumn))
+		}
+
+		if !r.Since.IsZero() {
+			tx = tx.Where(modifiedColumn+" >= ?", r.Since)
+		}
+
+		return tx
+	}
+}
+
+// Page adds the order, offset and limit of r to tx.
+func (r *ListRequest) Page(tx *gorm.DB) *gorm.DB {
+	order := r.Sort
+	if r.Desc {
+		order += " DESC"
+	}
+
+	return tx.Order(order).Offset(r.Offset).Limit(r.Limit)
+}
+
+// Find loads the page of r from the rows of model matching filter into dest,
+// a pointer to a slice, and returns the response without its data. filter
+// adds the search and filters of the request; it may be nil.
+func (r *ListRequest) Find(db *gorm.DB, model any, filter func(*gorm.DB) *gorm.DB, dest any) (*ListResponse, error) {
+	if filter == nil {
+		filter = func(tx *gorm.DB) *gorm.DB { return tx }
+	}
+
+	// Each query below starts from a copy of db, e.g. with its preloads.
+	db = db.Session(&gorm.Session{})
+	resp := &ListResponse{Draw: r.Draw, Offset: r.Offset, Limit: r.Limit}
+
+	if err := db.Model(model).Count(&resp.RecordsTotal).Error; err != nil {
+		return nil, err
+	}
+
+	if err := filter(db.Model(model)).Count(&resp.RecordsFiltered).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.Page(filter(db.Model(model))).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListResponse is the JSON body of the list endpoints. The field names of the
+// counts are those DataTables expects, so a table can use an endpoint as its
+// server-side source unchanged.
+type ListResponse struct {
+	Draw int `json:"draw,omitempty"`
+	// RecordsTotal counts all rows, RecordsFiltered those matching the
+	// search and filters.
+	RecordsTotal    int64 `json:"recordsTotal"`
+	RecordsFiltered int64 `json:"recordsFiltered"`
+	Offset          int   `json:"offset"`
+	Limit           int   `json:"limit"`
+	Data            any   `json:"data"`
+}
+
+// SelectFields returns rows, a slice of structs, with only the JSON fields
+// named in fields, or rows unchanged when fields is empty. Fields a row
+// leaves out with omitempty stay absent. An unknown field is an
+// ErrListField.
+func SelectFields(fields []string, rows any) (any, error) {
+	if len(fields) == 0 {
+		return rows, nil
+	}
+
+	known := jsonFields(reflect.TypeOf(rows).Elem())
+	for _, field := range fields {
+		if !known[field] {
+			return nil, fmt.Errorf("%w: %q", ErrListField, field)
+		}
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []map[string]json.RawMessage
+	if err = json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make([]map[string]json.RawMessage, len(all))
+
+	for i, row := range all {
+		selected[i] = make(map[string]json.RawMessage, len(fields))
+
+		for _, field := range fields {
+			if value, ok := row[field]; ok {
+				selected[i][field] = value
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// jsonFields returns the names of the JSON fields of the struct type t.
+func jsonFields(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		switch name {
+		case "-":
+		case "":
+			names[field.Name] = true
+		default:
+			names[name] = true
+		}
+	}
+
+	return names
+}
+
+// ListError responds to a request ParseListRequest rejected.
+func ListError(c fiber.Ctx, err error) error {
+	return JSONError(c, fiber.StatusBadRequest, CodeBadRequest, err.Error(), nil)
+}
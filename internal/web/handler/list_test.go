@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestParseListRequest(t *testing.T) {
+	columns := ListColumns{"id": "id", "name": "users.name"}
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  ListRequest
+		err   error
+	}{
+		{"defaults", nil, ListRequest{Limit: DefaultListLimit, Sort: "id", Desc: true}, nil},
+		{
+			"plain",
+			url.Values{"page": {"3"}, "page_size": {"10"}, "search": {" bob "}, "sort": {"name"}, "order": {"asc"}},
+			ListRequest{Offset: 20, Limit: 10, Search: "bob", Sort: "users.name"},
+			nil,
+		},
+		{
+			"datatables",
+			url.Values{
+				"draw": {"4"}, "start": {"50"}, "length": {"50"}, "search[value]": {"ann"},
+				"order[0][column]": {"1"}, "order[0][dir]": {"desc"},
+				"columns[0][data]": {"id"}, "columns[1][data]": {"name"},
+			},
+			ListRequest{Draw: 4, Offset: 50, Limit: 50, Search: "ann", Sort: "users.name", Desc: true},
+			nil,
+		},
+		{
+			"offset and limit",
+			url.Values{
+				"offset": {"40"}, "limit": {"20"}, "prefix": {" adm "}, "modified_since": {"2026-03-01"},
+				"fields": {"id, username,"},
+			},
+			ListRequest{
+				Offset: 40, Limit: 20, Sort: "id", Desc: true, Prefix: "adm",
+				Since: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Fields: []string{"id", "username"},
+			},
+			nil,
+		},
+		{
+			"modified since a time",
+			url.Values{"modified_since": {"2026-03-01T12:00:00+02:00"}},
+			ListRequest{Limit: DefaultListLimit, Sort: "id", Desc: true, Since: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)},
+			nil,
+		},
+		{"unknown sort", url.Values{"sort": {"password"}}, ListRequest{}, ErrListSort},
+		{"bad order", url.Values{"sort": {"id"}, "order": {"sideways"}}, ListRequest{}, ErrListOrder},
+		{"limit too large", url.Values{"page_size": {"100000"}}, ListRequest{}, ErrListLimit},
+		{"bad modified since", url.Values{"modified_since": {"yesterday"}}, ListRequest{}, ErrListSince},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got ListRequest
+				err error
+			)
+
+			app := fiber.New()
+			app.Get("/list", func(c fiber.Ctx) error {
+				got, err = ParseListRequest(c, columns, "id", true)
+				return nil
+			})
+
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+				"/list?"+tt.query.Encode(), http.NoBody)
+			if _, testErr := app.Test(req); testErr != nil {
+				t.Fatalf("app.Test() error = %v", testErr)
+			}
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil || !got.Since.Equal(tt.want.Since) {
+				t.Fatalf("ParseListRequest() = %+v, %v; want %+v", got, err, tt.want)
+			}
+
+			got.Since = tt.want.Since
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseListRequest() = %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	type row struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Email    string `json:"email,omitempty"`
+		Password string `json:"-"`
+	}
+
+	rows := []row{{ID: 1, Name: "alice", Email: "alice@example.com"}, {ID: 2, Name: "bob"}}
+
+	if got, err := SelectFields(nil, rows); err != nil || !reflect.DeepEqual(got, rows) {
+		t.Errorf("SelectFields(nil) = %v, %v; want the rows unchanged", got, err)
+	}
+
+	got, err := SelectFields([]string{"email", "id"}, rows)
+	if err != nil {
+		t.Fatalf("SelectFields() error = %v", err)
+	}
+
+	data, _ := json.Marshal(got)
+	if want := `[{"email":"alice@example.com","id":1},{"id":2}]`; string(data) != want {
+		t.Errorf("SelectFields() = %s, want %s", data, want)
+	}
+
+	for _, fields := range [][]string{{"password"}, {"Password"}, {"id", "role"}} {
+		if _, err = SelectFields(fields, rows); !errors.Is(err, ErrListField) {
+			t.Errorf("SelectFields(%q) error = %v, want %v", fields, err, ErrListField)
+		}
+	}
+}
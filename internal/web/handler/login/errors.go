@@ -30,6 +30,10 @@ var (
 	// are not valid for the selected authentication method.
 	ErrInvalidCredentials = errors.New("invalid username or password")
 
+	// ErrAccountPending is returned when the credentials of a self-registered
+	// account are valid but an administrator has not approved it yet.
+	ErrAccountPending = errors.New("your account is awaiting approval by an administrator")
+
 	// ErrInternalServerError is returned for unexpected failures during the login
 	// process.
 	ErrInternalServerError = errors.New("internal server error")
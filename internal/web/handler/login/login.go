@@ -3,6 +3,7 @@ package login
 import (
 	"errors"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
@@ -10,7 +11,11 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/appsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/authsettings"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/loginhistory"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/mailer"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/version"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
@@ -31,8 +36,10 @@ type Service struct {
 	cfg         *config.Config
 	db          *gorm.DB
 	localAuth   *auth.LocalProvider
-	ldapAuth    *auth.LDAPProvider
+	ldapAuth    auth.ProviderCache[*auth.LDAPProvider]
 	authService *auth.Service
+	mailer      mailer.Sender
+	validator   *validator.Validate
 }
 
 // Handler is the login handler.
@@ -50,72 +57,70 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB) {
 
 	// Initialize auth providers
 	s.localAuth = auth.NewLocalProvider(db)
+	s.localAuth.SetPasswordPolicy(cfg.Auth.LocalDB.PasswordPolicy)
 	s.authService = auth.NewService(db)
+	s.authService.SetGroupSyncPolicy(cfg.Auth.GroupSync.Policy)
+	s.validator = validator.New()
 
 	// Initialize LDAP provider if enabled
-	s.initLDAP()
+	s.ldapProvider()
+
+	if cfg.Mail.Enabled() {
+		s.mailer = mailer.New(&cfg.Mail)
+	}
 
 	// register routes
 	app.Route(Path, func(router fiber.Router) {
 		router.Get(handler.RootPath, s.Get)
 		router.Post(handler.RootPath, s.Post)
 	})
+
+	app.Get(PathForgot, s.GetForgot)
+	app.Post(PathForgot, s.PostForgot)
+	app.Get(PathReset, s.GetReset)
+	app.Post(PathReset, s.PostReset)
+	app.Get(PathRegister, s.GetRegister)
+	app.Post(PathRegister, s.PostRegister)
 }
 
-// initLDAP initializes the LDAP auth provider when enabled, using guard clauses to reduce nesting.
-func (s *Service) initLDAP() {
-	if !s.cfg.Auth.LDAP.Enabled {
-		return
-	}
+// ldapProvider returns the LDAP auth provider of the current settings, or nil
+// when LDAP is disabled or the provider could not be created. The provider is
+// created again once the settings change.
+func (s *Service) ldapProvider() *auth.LDAPProvider {
+	settings := authsettings.Current(s.cfg)
 
-	ldapCfg := s.cfg.Auth.LDAP
-	ldapConfig := auth.LDAPConfig{
-		Enabled:          ldapCfg.Enabled,
-		Host:             ldapCfg.Host,
-		Port:             ldapCfg.Port,
-		UseSSL:           ldapCfg.UseSSL,
-		UseTLS:           ldapCfg.UseTLS,
-		SkipVerify:       ldapCfg.SkipVerify,
-		BindDN:           ldapCfg.BindDN,
-		BindPassword:     ldapCfg.BindPassword,
-		BaseDN:           ldapCfg.BaseDN,
-		UserFilter:       ldapCfg.UserFilter,
-		GroupBaseDN:      ldapCfg.GroupBaseDN,
-		GroupFilter:      ldapCfg.GroupFilter,
-		GroupMemberAttr:  ldapCfg.GroupMemberAttr,
-		UsernameAttr:     ldapCfg.UsernameAttr,
-		EmailAttr:        ldapCfg.EmailAttr,
-		FirstNameAttr:    ldapCfg.FirstNameAttr,
-		LastNameAttr:     ldapCfg.LastNameAttr,
-		GroupNameAttr:    ldapCfg.GroupNameAttr,
-		Timeout:          ldapCfg.Timeout,
-		SearchAttributes: ldapCfg.SearchAttrs,
-	}
+	provider, err := s.ldapAuth.Get(settings.Version, func() (*auth.LDAPProvider, error) {
+		ldapProvider, err := auth.NewLDAPProvider(auth.NewLDAPConfig(&settings.LDAP), s.db)
 
-	ldapProvider, err := auth.NewLDAPProvider(&ldapConfig, s.db)
-	if err != nil {
-		if errors.Is(err, auth.ErrLDAPDisabled) {
+		switch {
+		case errors.Is(err, auth.ErrLDAPDisabled):
 			log.Info().Msg("LDAP authentication is disabled by configuration")
-			return
+		case err != nil:
+			log.Warn().Err(err).Msg("Failed to initialize LDAP provider - LDAP authentication will be disabled")
+		default:
+			log.Info().Msg("LDAP authentication provider initialized")
 		}
 
-		log.Warn().Err(err).Msg("Failed to initialize LDAP provider - LDAP authentication will be disabled")
-
-		return
+		return ldapProvider, err
+	})
+	if err != nil {
+		return nil
 	}
 
-	s.ldapAuth = ldapProvider
-
-	log.Info().Msg("LDAP authentication provider initialized")
+	return provider
 }
 
 // Get handles the login page rendering.
 func (s *Service) Get(c fiber.Ctx) error {
 	return c.Render(TemplateName, fiber.Map{
-		"local_db_enabled": s.cfg.Auth.LocalDB.Enabled,
-		"ldap_enabled":     s.cfg.Auth.LDAP.Enabled,
-		"oidc_enabled":     s.cfg.Auth.OIDC.Enabled,
-		"version":          version.Get(),
+		"local_db_enabled":       appsettings.Current(s.cfg).LocalLogin,
+		"ldap_enabled":           authsettings.Current(s.cfg).LDAP.Enabled,
+		"oidc_enabled":           authsettings.Current(s.cfg).OIDC.Enabled,
+		"password_reset_enabled": s.resetEnabled(),
+		"password_reset_done":    c.Query(queryPasswordReset) != "",
+		"registration_enabled":   s.registrationEnabled(),
+		"registered":             c.Query(queryRegistered) != "",
+		"version":                version.Get(),
 	})
 }
 
@@ -151,6 +156,7 @@ func (s *Service) Post(c fiber.Ctx) error {
 				IPAddress:    c.IP(),
 			},
 		)
+		loginhistory.Record(loginhistory.FromRequest(c, s.db).Failed(form.Username, authType, err))
 
 		return s.renderError(c, form.Username, form.AuthType, err.Error())
 	}
@@ -169,6 +175,7 @@ func (s *Service) Post(c fiber.Ctx) error {
 			IPAddress:    c.IP(),
 		},
 	)
+	loginhistory.Record(loginhistory.FromRequest(c, s.db).Succeeded(authenticatedUser, authType))
 
 	// TOTP only applies to local accounts
 	if authenticatedUser.AuthSource == models.AuthSourceLocal && authenticatedUser.TOTPEnabled {
@@ -198,13 +205,15 @@ func (s *Service) Post(c fiber.Ctx) error {
 // renderError renders the login page with an error message, preserving the submitted username and auth type.
 func (s *Service) renderError(c fiber.Ctx, username, authType, errorMsg string) error {
 	return c.Render(TemplateName, fiber.Map{
-		"local_db_enabled": s.cfg.Auth.LocalDB.Enabled,
-		"ldap_enabled":     s.cfg.Auth.LDAP.Enabled,
-		"oidc_enabled":     s.cfg.Auth.OIDC.Enabled,
-		"error":            errorMsg,
-		"username":         username,
-		"auth_type":        authType,
-		"version":          version.Get(),
+		"local_db_enabled":       appsettings.Current(s.cfg).LocalLogin,
+		"ldap_enabled":           authsettings.Current(s.cfg).LDAP.Enabled,
+		"oidc_enabled":           authsettings.Current(s.cfg).OIDC.Enabled,
+		"password_reset_enabled": s.resetEnabled(),
+		"registration_enabled":   s.registrationEnabled(),
+		"error":                  errorMsg,
+		"username":               username,
+		"auth_type":              authType,
+		"version":                version.Get(),
 	})
 }
 
@@ -213,11 +222,11 @@ func (s *Service) renderError(c fiber.Ctx, username, authType, errorMsg string)
 // or when an unsupported method is requested.
 func (s *Service) pickAuthType(requested string) (string, error) {
 	if requested == "" {
-		if s.cfg.Auth.LocalDB.Enabled {
+		if appsettings.Current(s.cfg).LocalLogin {
 			return "local", nil
 		}
 
-		if s.cfg.Auth.LDAP.Enabled {
+		if authsettings.Current(s.cfg).LDAP.Enabled {
 			return "ldap", nil
 		}
 
@@ -226,13 +235,13 @@ func (s *Service) pickAuthType(requested string) (string, error) {
 
 	switch requested {
 	case "local":
-		if !s.cfg.Auth.LocalDB.Enabled {
+		if !appsettings.Current(s.cfg).LocalLogin {
 			return "", ErrLocalAuthDisabled
 		}
 
 		return "local", nil
 	case "ldap":
-		if !s.cfg.Auth.LDAP.Enabled || s.ldapAuth == nil {
+		if !authsettings.Current(s.cfg).LDAP.Enabled || s.ldapProvider() == nil {
 			return "", ErrLDAPAuthDisabled
 		}
 
@@ -248,6 +257,10 @@ func (s *Service) authenticate(authType, username, password string) (*models.Use
 	switch authType {
 	case "local":
 		user, err := s.localAuth.Authenticate(username, password)
+		if errors.Is(err, auth.ErrUserPendingApproval) {
+			return nil, ErrAccountPending
+		}
+
 		if err != nil {
 			log.Error().Err(err).Str("username", username).Msg("Local authentication failed")
 			return nil, ErrInvalidCredentials
@@ -255,7 +268,12 @@ func (s *Service) authenticate(authType, username, password string) (*models.Use
 
 		return user, nil
 	case "ldap":
-		user, groups, err := s.ldapAuth.Authenticate(username, password)
+		ldapProvider := s.ldapProvider()
+		if ldapProvider == nil {
+			return nil, ErrLDAPAuthDisabled
+		}
+
+		user, groups, err := ldapProvider.Authenticate(username, password)
 		if err != nil {
 			log.Error().Err(err).Str("username", username).Msg("LDAP authentication failed")
 			return nil, ErrInvalidCredentials
@@ -271,6 +289,18 @@ func (s *Service) authenticate(authType, username, password string) (*models.Use
 	}
 }
 
+// SignIn starts a session for user without asking for credentials, e.g. for
+// the administrator account created by the setup wizard.
+func (s *Service) SignIn(c fiber.Ctx, user *models.User) error {
+	return s.createSessionAndSetCookie(c, user)
+}
+
+// passwordChangeRequired reports whether the session of user is restricted to
+// changing the password. Passwords cannot be changed in demo mode.
+func (s *Service) passwordChangeRequired(user *models.User) bool {
+	return !s.cfg.Demo && s.localAuth.PasswordChangeRequired(user)
+}
+
 // createSessionAndSetCookie creates a user session, writes it to the store,
 // and sets the corresponding session cookie on the response.
 func (s *Service) createSessionAndSetCookie(c fiber.Ctx, user *models.User) error {
@@ -280,8 +310,10 @@ func (s *Service) createSessionAndSetCookie(c fiber.Ctx, user *models.User) erro
 		return err
 	}
 
-	userSession := &session.Data{User: *user}
-	if err := userSession.Write(sessionID, s.cfg.Webserver.Session.ExpiryTime); err != nil {
+	expiry := appsettings.Current(s.cfg).SessionExpiry
+
+	userSession := &session.Data{User: *user, PasswordChange: s.passwordChangeRequired(user)}
+	if err := userSession.Write(sessionID); err != nil {
 		log.Error().Err(err).Msg("failed to write session")
 		return err
 	}
@@ -289,7 +321,7 @@ func (s *Service) createSessionAndSetCookie(c fiber.Ctx, user *models.User) erro
 	cookieSettings := &fiber.Cookie{
 		Name:     "session",
 		Value:    sessionID,
-		MaxAge:   int(s.cfg.Webserver.Session.ExpiryTime.Seconds()),
+		MaxAge:   int(expiry.Seconds()),
 		Secure:   true,
 		HTTPOnly: true,
 		SameSite: "Lax",
@@ -311,8 +343,10 @@ func (s *Service) createPendingSessionAndSetCookie(c fiber.Ctx, user *models.Use
 		return err
 	}
 
-	userSession := &session.Data{User: *user, TOTPPending: true}
-	if err := userSession.Write(sessionID, s.cfg.Webserver.Session.ExpiryTime); err != nil {
+	expiry := appsettings.Current(s.cfg).SessionExpiry
+
+	userSession := &session.Data{User: *user, TOTPPending: true, PasswordChange: s.passwordChangeRequired(user)}
+	if err := userSession.Write(sessionID); err != nil {
 		log.Error().Err(err).Msg("failed to write pending session")
 		return err
 	}
@@ -320,7 +354,7 @@ func (s *Service) createPendingSessionAndSetCookie(c fiber.Ctx, user *models.Use
 	cookieSettings := &fiber.Cookie{
 		Name:     "session",
 		Value:    sessionID,
-		MaxAge:   int(s.cfg.Webserver.Session.ExpiryTime.Seconds()),
+		MaxAge:   int(expiry.Seconds()),
 		Secure:   true,
 		HTTPOnly: true,
 		SameSite: "Lax",
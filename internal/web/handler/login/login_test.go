@@ -164,8 +164,9 @@ func TestPickAuthType_DefaultsAndErrors(t *testing.T) {
 		t.Fatalf("expected ErrLDAPAuthDisabled, got %v", err)
 	}
 
-	// Provide a non-nil ldapAuth and keep Enabled → selecting ldap should succeed
-	s.ldapAuth = &auth.LDAPProvider{}
+	// Drop the provider cached while LDAP was disabled and keep Enabled → the
+	// provider is created again and selecting ldap should succeed
+	s.ldapAuth = auth.ProviderCache[*auth.LDAPProvider]{}
 	if at, err = s.pickAuthType("ldap"); err != nil || at != "ldap" {
 		t.Fatalf("expected ldap, got at=%q err=%v", at, err)
 	}
@@ -0,0 +1,174 @@
+package login
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/appsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/version"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/registration"
+)
+
+const (
+	// PathRegister is the path of the self-registration form. The auth
+	// middleware treats it like the login page.
+	PathRegister = handler.RootPath + "register"
+
+	// TemplateRegister is the name of the registration template.
+	TemplateRegister = "login/register"
+
+	// queryRegistered is set on the login page after a registration.
+	queryRegistered = "registered"
+)
+
+// RegisterForm is the submitted registration form.
+type RegisterForm struct {
+	Username    string `form:"username"         validate:"required,min=3,max=100"`
+	Email       string `form:"email"            validate:"required,email,max=255"`
+	DisplayName string `form:"displayname"      validate:"max=255"`
+	Password    string `form:"password"`
+	Confirm     string `form:"confirm_password"`
+}
+
+// registrationEnabled reports whether visitors may register local accounts.
+func (s *Service) registrationEnabled() bool {
+	return s.cfg.Auth.LocalDB.Registration && appsettings.Current(s.cfg).LocalLogin
+}
+
+// GetRegister renders the registration form.
+func (s *Service) GetRegister(c fiber.Ctx) error {
+	if !s.registrationEnabled() {
+		return fiber.ErrNotFound
+	}
+
+	return s.renderRegister(c, fiber.StatusOK, &RegisterForm{}, "")
+}
+
+// PostRegister creates a pending local account and asks the user
+// administrators to review it.
+func (s *Service) PostRegister(c fiber.Ctx) error {
+	if !s.registrationEnabled() {
+		return fiber.ErrNotFound
+	}
+
+	form := new(RegisterForm)
+	if err := c.Bind().Body(form); err != nil {
+		return s.renderRegister(c, fiber.StatusBadRequest, form, ErrInvalidFormData.Error())
+	}
+
+	form.Username = strings.TrimSpace(form.Username)
+	form.Email = strings.TrimSpace(form.Email)
+	form.DisplayName = strings.TrimSpace(form.DisplayName)
+
+	if err := s.validator.Struct(form); err != nil {
+		return s.renderRegister(c, fiber.StatusBadRequest, form,
+			"Enter a username of at least 3 characters and a valid email address.")
+	}
+
+	if err := s.localAuth.ValidatePassword(form.Password); err != nil {
+		return s.renderRegister(c, fiber.StatusBadRequest, form,
+			"The password must have "+s.localAuth.PasswordRequirements()+".")
+	}
+
+	if form.Password != form.Confirm {
+		return s.renderRegister(c, fiber.StatusBadRequest, form, "The passwords do not match.")
+	}
+
+	var role models.Role
+	if err := s.db.Where(models.WhereNameIs, s.cfg.Auth.LocalDB.RegistrationRole).First(&role).Error; err != nil {
+		log.Error().Err(err).Str("role", s.cfg.Auth.LocalDB.RegistrationRole).Msg("registration role not found")
+		return s.renderRegister(c, fiber.StatusInternalServerError, form, ErrInternalServerError.Error())
+	}
+
+	user, err := s.localAuth.Register(form.Username, form.Email, form.Password, form.DisplayName, role.ID)
+	if errors.Is(err, auth.ErrUserNameOrEmailExists) {
+		return s.renderRegister(c, fiber.StatusConflict, form, "The username or email address is already taken.")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Str("username", form.Username).Msg("failed to register user")
+		return s.renderRegister(c, fiber.StatusInternalServerError, form, ErrInternalServerError.Error())
+	}
+
+	userID := user.ID
+	activitylog.Record(&activitylog.Entry{
+		DB:           s.db,
+		UserID:       &userID,
+		Username:     user.Username,
+		Action:       activitylog.ActionUserRegistered,
+		ResourceType: activitylog.ResourceTypeUser,
+		ResourceName: user.Username,
+		Details:      map[string]any{"email": user.Email},
+		IPAddress:    c.IP(),
+	})
+
+	s.notifyRegistrationReviewers(c, user)
+
+	return c.Redirect().To(Path + "?" + queryRegistered + "=1")
+}
+
+// renderRegister renders the registration form with an optional error.
+func (s *Service) renderRegister(c fiber.Ctx, status int, form *RegisterForm, errorMsg string) error {
+	return c.Status(status).Render(TemplateRegister, fiber.Map{
+		"version":      version.Get(),
+		"error":        errorMsg,
+		"username":     form.Username,
+		"email":        form.Email,
+		"displayname":  form.DisplayName,
+		"requirements": s.localAuth.PasswordRequirements(),
+		"min_length":   s.localAuth.PasswordPolicy().MinLength,
+	})
+}
+
+// notifyRegistrationReviewers emails every user administrator about a new
+// registration.
+func (s *Service) notifyRegistrationReviewers(c fiber.Ctx, user *models.User) {
+	if s.mailer == nil {
+		return
+	}
+
+	reviewers, err := s.authService.GetUsersWithPermission(auth.PermAdminUsers)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load registration reviewers")
+		return
+	}
+
+	brand, ok := c.Locals("Brand").(config.Branding)
+	if !ok {
+		brand = s.cfg.Branding.Resolve(s.cfg.Title)
+	}
+
+	subject := brand.Name + ": new registration from " + user.Username
+	body := fmt.Sprintf(`%s registered the account %q (%s).
+
+Approve or deny the registration at:
+
+%s
+`, user.FullName(), user.Username, user.Email, strings.TrimRight(s.cfg.Webserver.URL, "/")+registration.Path)
+
+	for i := range reviewers {
+		to := reviewers[i].Email
+		if to == "" {
+			continue
+		}
+
+		jobs.Go(jobs.Mail, func() error {
+			err := s.mailer.Send(to, subject, body)
+			if err != nil {
+				log.Error().Err(err).Str("to", to).Msg("failed to send registration email")
+			}
+
+			return err
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package login
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func newRegisterService(t *testing.T, enabled bool) *fiber.App {
+	t.Helper()
+
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&models.Role{}, &models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if err := db.Create(&models.Role{Name: "user"}).Error; err != nil {
+		t.Fatalf("failed to create role: %v", err)
+	}
+
+	cfg := newTestConfig()
+	cfg.Auth.LocalDB.Registration = enabled
+	cfg.Auth.LocalDB.RegistrationRole = "user"
+	cfg.Auth.LocalDB.PasswordPolicy.MinLength = 8
+
+	app := newTestApp()
+
+	initSessionStore()
+
+	var s Service
+	s.Init(app, cfg, db)
+
+	return app
+}
+
+func TestRegister_Disabled(t *testing.T) {
+	app := newRegisterService(t, false)
+
+	if resp, _ := performGet(t, app, PathRegister); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET status = %d, want 404", resp.StatusCode)
+	}
+
+	resp := performPost(t, app, PathRegister, url.Values{"username": {"dave"}})
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("POST status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestRegister_PendingUntilApproved(t *testing.T) {
+	app := newRegisterService(t, true)
+
+	if resp, _ := performGet(t, app, PathRegister); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET status = %d, want 200", resp.StatusCode)
+	}
+
+	form := url.Values{
+		"username":         {"dave"},
+		"email":            {"dave@example.com"},
+		"password":         {"short"},
+		"confirm_password": {"short"},
+	}
+
+	resp := performPost(t, app, PathRegister, form)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("weak password status = %d, want 400", resp.StatusCode)
+	}
+
+	form.Set("password", "long-enough")
+	form.Set("confirm_password", "long-enough")
+
+	resp = performPost(t, app, PathRegister, form)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != Path+"?registered=1" {
+		t.Fatalf("register status = %d location = %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	resp = performPost(t, app, PathRegister, form)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate status = %d, want 409", resp.StatusCode)
+	}
+
+	resp = performPost(t, app, Path+"/", url.Values{
+		"username": {"dave"}, "password": {"long-enough"}, "auth_type": {"local"},
+	})
+
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if string(body) != ErrAccountPending.Error() {
+		t.Fatalf("login body = %q, want %q", body, ErrAccountPending.Error())
+	}
+}
@@ -0,0 +1,219 @@
+package login
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/appsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/version"
+)
+
+const (
+	// PathForgot is the path of the "Forgot password" form. It lives below the
+	// login path so the auth middleware lets anonymous users reach it.
+	PathForgot = Path + "/forgot"
+
+	// PathReset is the path of the form that sets a new password; the emailed
+	// link carries the token as query parameter.
+	PathReset = Path + "/reset"
+
+	// TemplateForgot is the name of the "Forgot password" template.
+	TemplateForgot = "login/forgot"
+
+	// TemplateReset is the name of the password reset template.
+	TemplateReset = "login/reset"
+
+	// queryPasswordReset is set on the login page after a successful reset.
+	queryPasswordReset = "reset"
+)
+
+// resetEnabled reports whether the password reset flow is offered.
+func (s *Service) resetEnabled() bool {
+	rs := appsettings.Current(s.cfg)
+
+	return rs.LocalLogin && rs.PasswordReset && s.mailer != nil
+}
+
+// resetTokenTTL returns how long reset links stay valid.
+func (s *Service) resetTokenTTL() time.Duration {
+	if ttl := appsettings.Current(s.cfg).ResetTokenTTL; ttl > 0 {
+		return ttl
+	}
+
+	return config.DefaultResetTokenTTL
+}
+
+// GetForgot renders the "Forgot password" form.
+func (s *Service) GetForgot(c fiber.Ctx) error {
+	if !s.resetEnabled() {
+		return fiber.ErrNotFound
+	}
+
+	return c.Render(TemplateForgot, fiber.Map{"version": version.Get()})
+}
+
+// PostForgot emails a reset link to the local account with the submitted
+// username or email address. The response is the same whether or not such an
+// account exists, so the form cannot be used to probe for accounts.
+func (s *Service) PostForgot(c fiber.Ctx) error {
+	if !s.resetEnabled() {
+		return fiber.ErrNotFound
+	}
+
+	identifier := strings.TrimSpace(c.FormValue("login"))
+	if identifier == "" {
+		return c.Status(fiber.StatusBadRequest).Render(TemplateForgot, fiber.Map{
+			"version": version.Get(),
+			"error":   "Enter your username or email address.",
+		})
+	}
+
+	token, user, err := s.localAuth.CreatePasswordResetToken(identifier, s.resetTokenTTL())
+
+	switch {
+	case err == nil:
+		userID := user.ID
+		activitylog.Record(&activitylog.Entry{
+			DB:           s.db,
+			UserID:       &userID,
+			Username:     user.Username,
+			Action:       activitylog.ActionPasswordResetSent,
+			ResourceType: activitylog.ResourceTypeAuth,
+			IPAddress:    c.IP(),
+		})
+
+		subject, body := s.resetMail(c, user, token)
+
+		// Sent in the background: a slow SMTP server must not reveal through
+		// the response time that the account exists.
+		to := user.Email
+		jobs.Go(jobs.Mail, func() error {
+			err := s.mailer.Send(to, subject, body)
+			if err != nil {
+				log.Error().Err(err).Str("username", user.Username).Msg("failed to send password reset email")
+			}
+
+			return err
+		})
+	case errors.Is(err, auth.ErrUserNotFound), errors.Is(err, auth.ErrMultipleUsersFound):
+		log.Info().Str("login", identifier).Err(err).Msg("password reset requested for unknown account")
+	default:
+		log.Error().Err(err).Msg("failed to create password reset token")
+	}
+
+	return c.Render(TemplateForgot, fiber.Map{
+		"version": version.Get(),
+		"sent":    true,
+	})
+}
+
+// GetReset renders the new-password form for a valid reset link.
+func (s *Service) GetReset(c fiber.Ctx) error {
+	if !s.resetEnabled() {
+		return fiber.ErrNotFound
+	}
+
+	token := c.Query("token")
+	if _, err := s.localAuth.ValidatePasswordResetToken(token); err != nil {
+		return s.renderResetInvalid(c, err)
+	}
+
+	return c.Render(TemplateReset, fiber.Map{
+		"version":      version.Get(),
+		"token":        token,
+		"requirements": s.localAuth.PasswordRequirements(),
+		"min_length":   s.localAuth.PasswordPolicy().MinLength,
+	})
+}
+
+// PostReset sets the new password and redeems the token.
+func (s *Service) PostReset(c fiber.Ctx) error {
+	if !s.resetEnabled() {
+		return fiber.ErrNotFound
+	}
+
+	token := c.FormValue("token")
+	password := c.FormValue("password")
+
+	renderErr := func(msg string) error {
+		return c.Status(fiber.StatusBadRequest).Render(TemplateReset, fiber.Map{
+			"version":      version.Get(),
+			"token":        token,
+			"error":        msg,
+			"requirements": s.localAuth.PasswordRequirements(),
+			"min_length":   s.localAuth.PasswordPolicy().MinLength,
+		})
+	}
+
+	if err := s.localAuth.ValidatePassword(password); err != nil {
+		return renderErr("The password must have " + s.localAuth.PasswordRequirements() + ".")
+	}
+
+	if password != c.FormValue("confirm_password") {
+		return renderErr("The passwords do not match.")
+	}
+
+	user, err := s.localAuth.ResetPasswordWithToken(token, password)
+	if err != nil {
+		return s.renderResetInvalid(c, err)
+	}
+
+	userID := user.ID
+	activitylog.Record(&activitylog.Entry{
+		DB:           s.db,
+		UserID:       &userID,
+		Username:     user.Username,
+		Action:       activitylog.ActionPasswordReset,
+		ResourceType: activitylog.ResourceTypeAuth,
+		IPAddress:    c.IP(),
+	})
+
+	return c.Redirect().To(Path + "?" + queryPasswordReset + "=1")
+}
+
+// renderResetInvalid renders the reset page for an unusable link.
+func (s *Service) renderResetInvalid(c fiber.Ctx, err error) error {
+	if !errors.Is(err, auth.ErrInvalidResetToken) {
+		log.Error().Err(err).Msg("failed to check password reset token")
+	}
+
+	return c.Status(fiber.StatusBadRequest).Render(TemplateReset, fiber.Map{
+		"version": version.Get(),
+		"invalid": true,
+	})
+}
+
+// resetMail returns the subject and body of the reset email.
+func (s *Service) resetMail(c fiber.Ctx, user *models.User, token string) (subject, body string) {
+	brand, ok := c.Locals("Brand").(config.Branding)
+	if !ok {
+		brand = s.cfg.Branding.Resolve(s.cfg.Title)
+	}
+
+	link := strings.TrimRight(s.cfg.Webserver.URL, "/") + PathReset + "?token=" + url.QueryEscape(token)
+
+	subject = brand.Name + ": password reset"
+	body = fmt.Sprintf(`Hello %s,
+
+someone asked to reset the password of the account %q.
+Open the link below within %s to choose a new password:
+
+%s
+
+The link can be used once. If you did not ask for a reset, ignore this
+email; your password stays unchanged.
+`, user.FullName(), user.Username, s.resetTokenTTL(), link)
+
+	return subject, body
+}
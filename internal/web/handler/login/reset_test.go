@@ -0,0 +1,169 @@
+package login
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// sentMail is one message captured by stubMailer.
+type sentMail struct {
+	to, subject, body string
+}
+
+// stubMailer records sent messages on a channel, since PostForgot sends in
+// the background.
+type stubMailer struct {
+	sent chan sentMail
+}
+
+func (m *stubMailer) Send(to, subject, body string) error {
+	m.sent <- sentMail{to: to, subject: subject, body: body}
+	return nil
+}
+
+func newResetService(t *testing.T) (*fiber.App, *Service, *stubMailer) {
+	t.Helper()
+
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&models.PasswordResetToken{}, &models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	cfg := newTestConfig()
+	cfg.Title = "Test DNS"
+	cfg.Auth.LocalDB.PasswordReset = true
+	cfg.Mail = config.Mail{Host: "smtp.example.com", Port: 25, From: "dns@example.com"}
+
+	app := newTestApp()
+
+	initSessionStore()
+
+	var s Service
+	s.Init(app, cfg, db)
+
+	m := &stubMailer{sent: make(chan sentMail, 1)}
+	s.mailer = m
+
+	if _, err := auth.NewLocalProvider(db).CreateUser("carol", "carol@example.com", "old-password", "Carol", 0); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return app, &s, m
+}
+
+func performGet(t *testing.T, app *fiber.App, target string) (*http.Response, string) {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, target, http.NoBody)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	return resp, string(body)
+}
+
+func TestPasswordReset_Flow(t *testing.T) {
+	app, s, m := newResetService(t)
+
+	resp := performPost(t, app, PathForgot, url.Values{"login": {"carol@example.com"}})
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("forgot status = %d, want 200", resp.StatusCode)
+	}
+
+	var mail sentMail
+	select {
+	case mail = <-m.sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reset email sent")
+	}
+
+	if mail.to != "carol@example.com" || !strings.Contains(mail.subject, "Test DNS") {
+		t.Errorf("mail = %+v", mail)
+	}
+
+	prefix := "http://localhost" + PathReset + "?token="
+
+	idx := strings.Index(mail.body, prefix)
+	if idx < 0 {
+		t.Fatalf("reset link missing from body:\n%s", mail.body)
+	}
+
+	token := strings.Fields(mail.body[idx+len(prefix):])[0]
+
+	if resp, body := performGet(t, app, PathReset+"?token="+token); resp.StatusCode != http.StatusOK || body != TemplateReset {
+		t.Fatalf("reset form status = %d body = %q", resp.StatusCode, body)
+	}
+
+	// Mismatched confirmation keeps the token usable.
+	resp = performPost(t, app, PathReset, url.Values{
+		"token": {token}, "password": {"brand-new-pw"}, "confirm_password": {"other-pw-123"},
+	})
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("mismatch status = %d, want 400", resp.StatusCode)
+	}
+
+	resp = performPost(t, app, PathReset, url.Values{
+		"token": {token}, "password": {"brand-new-pw"}, "confirm_password": {"brand-new-pw"},
+	})
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != Path+"?reset=1" {
+		t.Fatalf("reset status = %d location = %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if _, err := s.localAuth.Authenticate("carol", "brand-new-pw"); err != nil {
+		t.Errorf("new password rejected: %v", err)
+	}
+
+	// The link is single-use.
+	if resp, _ := performGet(t, app, PathReset+"?token="+token); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("reused link status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestPasswordReset_UnknownAccountLooksTheSame(t *testing.T) {
+	app, _, m := newResetService(t)
+
+	resp := performPost(t, app, PathForgot, url.Values{"login": {"nobody"}})
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	select {
+	case mail := <-m.sent:
+		t.Fatalf("unexpected email %+v", mail)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPasswordReset_Disabled(t *testing.T) {
+	app, s, _ := newResetService(t)
+	s.cfg.Auth.LocalDB.PasswordReset = false
+
+	if resp, _ := performGet(t, app, PathForgot); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,48 @@
+// Package metrics exposes the Prometheus metrics of the application, such as
+// the background job series of package jobs and the log statement counters.
+package metrics
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+// Handler serves the metrics endpoint.
+type Handler struct {
+	cfg     config.Metrics
+	handler fiber.Handler
+}
+
+// New creates a metrics handler serving the default Prometheus registry.
+func New(cfg config.Metrics) *Handler {
+	return &Handler{cfg: cfg, handler: adaptor.HTTPHandler(promhttp.Handler())}
+}
+
+// Register registers the metrics endpoint on the given router when enabled. Like
+// the health endpoint it must be registered before any auth middleware; access
+// is controlled by the optional bearer token instead of a session.
+func (h *Handler) Register(app *fiber.App) {
+	if !h.cfg.Enabled {
+		return
+	}
+
+	app.Get(h.cfg.Path, h.Serve)
+}
+
+// Serve writes the metrics in the Prometheus exposition format.
+func (h *Handler) Serve(c fiber.Ctx) error {
+	if h.cfg.Token != "" {
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.Token)) != 1 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+	}
+
+	return h.handler(c)
+}
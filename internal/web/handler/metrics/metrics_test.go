@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+)
+
+func doGet(t *testing.T, app *fiber.App, path, authorization string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, http.NoBody)
+	if authorization != "" {
+		req.Header.Set(fiber.HeaderAuthorization, authorization)
+	}
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+
+	return resp
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	app := fiber.New()
+	New(config.Metrics{Path: "/metrics"}).Register(app)
+
+	resp := doGet(t, app, "/metrics", "")
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestMetricsServesJobSeries(t *testing.T) {
+	_ = jobs.Run("metrics_test", func() error { return nil })
+
+	app := fiber.New()
+	New(config.Metrics{Enabled: true, Path: "/metrics"}).Register(app)
+
+	resp := doGet(t, app, "/metrics", "")
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(body), `background_job_last_success_timestamp_seconds{job="metrics_test"}`) {
+		t.Errorf("job series missing from metrics:\n%s", body)
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	app := fiber.New()
+	New(config.Metrics{Enabled: true, Path: "/metrics", Token: "s3cret"}).Register(app)
+
+	tests := []struct {
+		authorization string
+		want          int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusUnauthorized},
+		{"Bearer s3cret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		resp := doGet(t, app, "/metrics", tt.authorization)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.want {
+			t.Errorf("Authorization %q: status = %d, want %d", tt.authorization, resp.StatusCode, tt.want)
+		}
+	}
+}
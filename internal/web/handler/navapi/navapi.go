@@ -0,0 +1,76 @@
+// Package navapi serves the main menu and breadcrumbs as JSON, filtered by the
+// permissions of the current user, for frontends that do not use the server-
+// rendered templates.
+package navapi
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+// Path is the path of the navigation endpoint.
+const Path = handler.RootPath + "api/navigation"
+
+// Service serves the navigation endpoint.
+type Service struct {
+	handler.Service
+	authService *auth.Service
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Response is the JSON body of the navigation endpoint.
+type Response struct {
+	// Navigation is the context of the page given by the path query parameter;
+	// null when the path is missing or matches no menu entry the user may see.
+	Navigation *navigation.Context `json:"navigation"`
+	// Sections is the main menu with the entries the user may see.
+	Sections []navigation.MenuSection `json:"sections"`
+}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.authService = authService
+
+	app.Get(Path, s.Get)
+}
+
+// Get returns the menu of the current user. With ?path=/admin/user the
+// response also carries the page title, active entry and breadcrumbs of that
+// page.
+func (s *Service) Get(c fiber.Ctx) error {
+	permissions, err := auth.GetUserPermissionsFromContext(c, s.authService)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load permissions for navigation")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, "failed to load permissions", nil)
+	}
+
+	granted := make(map[string]bool, len(permissions))
+	for _, perm := range permissions {
+		granted[perm] = true
+	}
+
+	hasPermission := func(perm string) bool { return granted[perm] }
+
+	var nav *navigation.Context
+	if path := c.Query("path"); path != "" {
+		nav = navigation.ContextForPath(path, hasPermission)
+	}
+
+	return c.JSON(Response{
+		Navigation: nav,
+		Sections:   navigation.Menu(hasPermission, nav),
+	})
+}
@@ -0,0 +1,101 @@
+package pdnscompat
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	zoneedit "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/edit"
+)
+
+// etag returns the entity tag of the zone revision revision.
+func etag(revision string) string {
+	return `"` + revision + `"`
+}
+
+// entityTags returns the entity tags of an If-Match or If-None-Match header.
+func entityTags(header string) []string {
+	var tags []string
+
+	for tag := range strings.SplitSeq(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// ifMatchRevisions returns the zone revisions the If-Match header of c
+// accepts, or nil when it accepts any, i.e. is missing or "*". ok is false
+// when the header accepts no revision, e.g. as it only has weak entity tags,
+// which never match for a change.
+func ifMatchRevisions(c fiber.Ctx) (revisions []string, ok bool) {
+	tags := entityTags(c.Get(fiber.HeaderIfMatch))
+	if len(tags) == 0 || slices.Contains(tags, "*") {
+		return nil, true
+	}
+
+	for _, tag := range tags {
+		if len(tag) > 2 && strings.HasPrefix(tag, `"`) && strings.HasSuffix(tag, `"`) {
+			revisions = append(revisions, tag[1:len(tag)-1])
+		}
+	}
+
+	return revisions, len(revisions) > 0
+}
+
+// noneMatch reports whether the If-None-Match header of c names none of the
+// entity tag tag, compared weakly as for a read.
+func noneMatch(c fiber.Ctx, tag string) bool {
+	for _, t := range entityTags(c.Get(fiber.HeaderIfNoneMatch)) {
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return false
+		}
+	}
+
+	return true
+}
+
+// completeZone reports whether a zone read with query has all its RRsets,
+// so its revision can be sent as the ETag.
+func completeZone(query url.Values) bool {
+	return query.Get("rrsets") != "false" && query.Get("include_disabled") != "false" &&
+		!query.Has("rrset_name") && !query.Has("rrset_type")
+}
+
+// checkRevision refuses a change of the zone of the request with 412 unless
+// the zone is at one of revisions.
+func checkRevision(c fiber.Ctx, revisions []string) error {
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName(c))
+	if err != nil {
+		return &zoneedit.ChangeError{
+			Status:  fiber.StatusInternalServerError,
+			Code:    handler.CodeUpstream,
+			Message: "Failed to fetch zone: " + err.Error(),
+			Err:     err,
+		}
+	}
+
+	if !slices.Contains(revisions, zoneedit.ZoneRevision(zone)) {
+		return &zoneedit.ChangeError{
+			Status:  fiber.StatusPreconditionFailed,
+			Code:    handler.CodePreconditionFailed,
+			Message: zoneedit.ErrMsgPreconditionFailed,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,591 @@
+// Package pdnscompat serves the zone endpoints of the PowerDNS HTTP API below
+// the same paths, /api/v1/servers/:server_id/zones..., so tools written for
+// PowerDNS, such as the Terraform PowerDNS provider and octoDNS, can use
+// GoPowerDNS-Admin instead of the PowerDNS server.
+//
+// Clients authenticate with a personal API key in the X-API-Key header, as
+// they would against PowerDNS. Unlike the PowerDNS API, every request is
+// checked against the permissions and zone access of the key's owner, record
+// changes go through the checks of the zone editor, and changes are recorded
+// in the activity log. Responses of PowerDNS are passed on unchanged; errors
+// use the PowerDNS format, {"error": "..."}. A zone read carries the revision
+// of its RRsets as the ETag, and record changes honor If-Match, so clients
+// can read, modify and write a zone without overwriting concurrent changes.
+package pdnscompat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	zoneedit "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/edit"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// PathServers lists the PowerDNS servers, which is the one configured.
+	PathServers = handler.APIPathPrefix + "v1/servers"
+
+	// PathServer is the server; :server_id is the configured vhost.
+	PathServer = PathServers + "/:server_id"
+
+	// PathZones lists and creates zones.
+	PathZones = PathServer + "/zones"
+
+	// PathZone reads, changes and deletes a zone.
+	PathZone = PathZones + "/:zone_id"
+
+	// defaultTimeout is the timeout of the requests to PowerDNS.
+	defaultTimeout = 30 * time.Second
+
+	errMsgZoneAccess = "Access to this zone is not permitted"
+)
+
+// Service serves the PowerDNS-compatible API.
+type Service struct {
+	handler.Service
+	db          *gorm.DB
+	authService *auth.Service
+	editor      *zoneedit.Service
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes. Record changes and zone deletions are made by the
+// zone editor, so zoneedit.Handler must be initialized as well.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.authService = authService
+	s.editor = &zoneedit.Handler
+
+	app.Use(PathServers, respondErrors)
+
+	app.Get(PathServers, s.ListServers)
+	app.Get(PathServer, requireServer, s.GetServer)
+	app.Get(PathZones,
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneList),
+		s.ListZones,
+	)
+	app.Post(PathZones,
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneCreate),
+		s.CreateZone,
+	)
+	app.Get(PathZone,
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneRead),
+		s.GetZone,
+	)
+	app.Get(PathZone+"/export",
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneRead),
+		s.ExportZone,
+	)
+	app.Put(PathZone,
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.UpdateZone,
+	)
+	app.Patch(PathZone,
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PatchZone,
+	)
+	app.Delete(PathZone,
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneDelete),
+		s.DeleteZone,
+	)
+	app.Put(PathZone+"/notify",
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.transfer(activitylog.ActionZoneNotified),
+	)
+	app.Put(PathZone+"/axfr-retrieve",
+		requireServer,
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.transfer(activitylog.ActionZoneRetrieved),
+	)
+}
+
+// respondErrors answers the errors of the route guards with the error body of
+// the PowerDNS API, which its clients show to the user.
+func respondErrors(c fiber.Ctx) error {
+	err := c.Next()
+
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return respondError(c, fe.Code, fe.Message)
+	}
+
+	return err
+}
+
+// respondError responds with status and the error body of the PowerDNS API.
+func respondError(c fiber.Ctx, status int, msg string) error {
+	return c.Status(status).JSON(fiber.Map{"error": msg})
+}
+
+// requireServer answers requests for other servers than the configured one
+// with 404, as PowerDNS does.
+func requireServer(c fiber.Ctx) error {
+	if powerdns.Engine.Client == nil {
+		return respondError(c, fiber.StatusServiceUnavailable, powerdns.ErrMsgClientNotInitialized)
+	}
+
+	if c.Params("server_id") != powerdns.Engine.VHost {
+		return respondError(c, fiber.StatusNotFound, "Not Found")
+	}
+
+	return c.Next()
+}
+
+// ListServers lists the configured PowerDNS server.
+func (s *Service) ListServers(c fiber.Ctx) error {
+	resp, err := forward(c, fiber.MethodGet, "", nil)
+	if err != nil {
+		return respondForwardError(c, err)
+	}
+
+	if resp.StatusCode == fiber.StatusOK {
+		resp.Body = append(append([]byte("["), resp.Body...), ']')
+	}
+
+	return respond(c, resp)
+}
+
+// GetServer returns the configured PowerDNS server.
+func (s *Service) GetServer(c fiber.Ctx) error {
+	resp, err := forward(c, fiber.MethodGet, "", nil)
+	if err != nil {
+		return respondForwardError(c, err)
+	}
+
+	return respond(c, resp)
+}
+
+// ListZones lists the zones the user may access. Unlike PowerDNS, it takes a
+// label query parameter that keeps the zones with a label, written as key or
+// key=value; see labels.ParseSelector.
+func (s *Service) ListZones(c fiber.Ctx) error {
+	query, err := requestQuery(c)
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, "Invalid query: "+err.Error())
+	}
+
+	sel, err := labels.ParseSelector(query.Get("label"))
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, "Invalid label filter: "+err.Error())
+	}
+
+	query.Del("label")
+
+	resp, err := forwardQuery(c, fiber.MethodGet, "zones", query, nil)
+	if err != nil {
+		return respondForwardError(c, err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		return respond(c, resp)
+	}
+
+	allowed, err := auth.ZoneFilter(c, s.authService)
+	if err != nil {
+		return respondError(c, fiber.StatusInternalServerError, "Failed to load zone access")
+	}
+
+	if !sel.IsZero() {
+		byZone, err := labels.Zones(s.db)
+		if err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Msg("failed to load zone labels")
+
+			return respondError(c, fiber.StatusInternalServerError, "Failed to load zone labels")
+		}
+
+		accessible := allowed
+		allowed = func(name string) bool {
+			return (accessible == nil || accessible(name)) && sel.Matches(byZone[name])
+		}
+	}
+
+	if allowed != nil {
+		if resp.Body, err = filterZones(resp.Body, allowed); err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Msg("failed to decode zone list")
+
+			return respondError(c, fiber.StatusBadGateway, "Invalid zone list from PowerDNS")
+		}
+	}
+
+	return respond(c, resp)
+}
+
+// CreateZone creates a zone.
+func (s *Service) CreateZone(c fiber.Ctx) error {
+	var zone struct {
+		Name string `json:"name"`
+		Kind string `json:"kind"`
+	}
+
+	if err := json.Unmarshal(c.Body(), &zone); err != nil || zone.Name == "" {
+		return respondError(c, fiber.StatusUnprocessableEntity, "Zone name is required")
+	}
+
+	user, hasUser := c.Locals("CurrentUser").(models.User)
+	if hasUser {
+		if err := tenant.CheckZones(s.db, user.TenantID, 1); errors.Is(err, tenant.ErrQuota) {
+			return respondError(c, fiber.StatusForbidden, err.Error())
+		} else if err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Msg("failed to check zone quota")
+
+			return respondError(c, fiber.StatusInternalServerError, "Failed to check zone quota")
+		}
+	}
+
+	resp, err := forward(c, fiber.MethodPost, "zones", c.Body())
+	if err != nil {
+		return respondForwardError(c, err)
+	}
+
+	if resp.StatusCode == fiber.StatusCreated {
+		zoneName := canonicalZone(zone.Name)
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		zoneindex.Default.RefreshZone(ctx, zoneName)
+		cancel()
+
+		if hasUser {
+			if err = tenant.AssignCreated(s.db, zoneName, &user); err != nil {
+				requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to assign zone to tenant")
+			}
+		}
+
+		s.record(c, activitylog.ActionZoneCreated, zoneName, map[string]any{"kind": zone.Kind})
+	}
+
+	return respond(c, resp)
+}
+
+// GetZone returns a zone with its RRsets. Unless the RRsets are filtered,
+// the revision of the zone is sent as the ETag, which PATCH and DELETE
+// accept in If-Match; a matching If-None-Match is answered with 304.
+func (s *Service) GetZone(c fiber.Ctx) error {
+	if !s.canAccessZone(c) {
+		return respondError(c, fiber.StatusForbidden, errMsgZoneAccess)
+	}
+
+	query, err := requestQuery(c)
+	if err != nil {
+		return respondForwardError(c, err)
+	}
+
+	resp, err := forwardQuery(c, fiber.MethodGet, zonePath(c), query, nil)
+	if err != nil {
+		return respondForwardError(c, err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK || !completeZone(query) {
+		return respond(c, resp)
+	}
+
+	var zone pdnsapi.Zone
+	if err = json.Unmarshal(resp.Body, &zone); err != nil {
+		return respond(c, resp)
+	}
+
+	tag := etag(zoneedit.ZoneRevision(&zone))
+	c.Set(fiber.HeaderETag, tag)
+
+	if !noneMatch(c, tag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return respond(c, resp)
+}
+
+// ExportZone returns a zone in the zone file format.
+func (s *Service) ExportZone(c fiber.Ctx) error {
+	return s.getZone(c, zonePath(c)+"/export")
+}
+
+// getZone passes on the response to pathFragment below the zone of the
+// request when the user may access the zone.
+func (s *Service) getZone(c fiber.Ctx, pathFragment string) error {
+	if !s.canAccessZone(c) {
+		return respondError(c, fiber.StatusForbidden, errMsgZoneAccess)
+	}
+
+	resp, err := forward(c, fiber.MethodGet, pathFragment, nil)
+	if err != nil {
+		return respondForwardError(c, err)
+	}
+
+	return respond(c, resp)
+}
+
+// UpdateZone changes the properties of a zone, e.g. its kind or primaries.
+func (s *Service) UpdateZone(c fiber.Ctx) error {
+	if !s.canAccessZone(c) {
+		return respondError(c, fiber.StatusForbidden, errMsgZoneAccess)
+	}
+
+	zoneName := zoneName(c)
+
+	deletion, err := zonedeletion.Pending(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load zone deletion")
+
+		return respondError(c, fiber.StatusInternalServerError, "Failed to load zone deletion")
+	}
+
+	if deletion != nil {
+		return respondError(c, fiber.StatusConflict, "Zone is deleted and waits for its purge")
+	}
+
+	var changes map[string]any
+	if err = json.Unmarshal(c.Body(), &changes); err != nil {
+		return respondError(c, fiber.StatusBadRequest, "Invalid JSON")
+	}
+
+	resp, err := forward(c, fiber.MethodPut, zonePath(c), c.Body())
+	if err != nil {
+		return respondForwardError(c, err)
+	}
+
+	if resp.StatusCode < fiber.StatusMultipleChoices {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		zoneindex.Default.RefreshZone(ctx, zoneName)
+		cancel()
+
+		s.record(c, activitylog.ActionZoneUpdated, zoneName, changes)
+	}
+
+	return respond(c, resp)
+}
+
+// PatchZone changes the RRsets of a zone like the zone editor: the record
+// types and LUA records are checked against the settings and the user's
+// roles, and the change is recorded with its diff. With If-Match, the zone
+// must still be at the revision of the ETag.
+func (s *Service) PatchZone(c fiber.Ctx) error {
+	if !s.canAccessZone(c) {
+		return respondError(c, fiber.StatusForbidden, errMsgZoneAccess)
+	}
+
+	revisions, ok := ifMatchRevisions(c)
+	if !ok {
+		return respondError(c, fiber.StatusPreconditionFailed, zoneedit.ErrMsgPreconditionFailed)
+	}
+
+	var patch pdnsapi.RRsets
+	if err := json.Unmarshal(c.Body(), &patch); err != nil {
+		return respondError(c, fiber.StatusBadRequest, "Invalid JSON")
+	}
+
+	if err := s.editor.PatchRRsets(c, zoneName(c), revisions, patch.Sets); err != nil {
+		return respondChangeError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteZone deletes a zone like the zone editor, which keeps it for the
+// configured grace period with soft delete. With If-Match, the zone must
+// still be at the revision of the ETag.
+func (s *Service) DeleteZone(c fiber.Ctx) error {
+	if !s.canAccessZone(c) {
+		return respondError(c, fiber.StatusForbidden, errMsgZoneAccess)
+	}
+
+	revisions, ok := ifMatchRevisions(c)
+	if !ok {
+		return respondError(c, fiber.StatusPreconditionFailed, zoneedit.ErrMsgPreconditionFailed)
+	}
+
+	if err := checkRevision(c, revisions); err != nil {
+		return respondChangeError(c, err)
+	}
+
+	if _, err := s.editor.DeleteZone(c, zoneName(c)); err != nil {
+		return respondChangeError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// transfer returns the handler of the notify and axfr-retrieve endpoints,
+// which records action.
+func (s *Service) transfer(action string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !s.canAccessZone(c) {
+			return respondError(c, fiber.StatusForbidden, errMsgZoneAccess)
+		}
+
+		resp, err := forward(c, fiber.MethodPut, zonePath(c)+"/"+pathSuffix(c), nil)
+		if err != nil {
+			return respondForwardError(c, err)
+		}
+
+		if resp.StatusCode == fiber.StatusOK {
+			var result struct {
+				Result string `json:"result"`
+			}
+
+			_ = json.Unmarshal(resp.Body, &result)
+
+			s.record(c, action, zoneName(c), map[string]string{"result": result.Result})
+		}
+
+		return respond(c, resp)
+	}
+}
+
+// record adds a change of zoneName to the activity log.
+func (s *Service) record(c fiber.Ctx, action, zoneName string, details any) {
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      details,
+		IPAddress:    c.IP(),
+	}))
+}
+
+// canAccessZone reports whether the user may access the zone of the request.
+func (s *Service) canAccessZone(c fiber.Ctx) bool {
+	return auth.CanAccessZone(c, s.authService, zoneName(c))
+}
+
+// filterZones drops the zones allowed rejects from the zone list body.
+// Fields of the zones are kept as PowerDNS sent them.
+func filterZones(body []byte, allowed func(string) bool) ([]byte, error) {
+	var zones []json.RawMessage
+	if err := json.Unmarshal(body, &zones); err != nil {
+		return nil, err
+	}
+
+	kept := make([]json.RawMessage, 0, len(zones))
+
+	for _, raw := range zones {
+		var zone struct {
+			Name string `json:"name"`
+		}
+
+		if err := json.Unmarshal(raw, &zone); err != nil {
+			return nil, err
+		}
+
+		if allowed(canonicalZone(zone.Name)) {
+			kept = append(kept, raw)
+		}
+	}
+
+	return json.Marshal(kept)
+}
+
+// forward sends the request to PowerDNS with the query of c.
+func forward(c fiber.Ctx, method, pathFragment string, body []byte) (*powerdns.Response, error) {
+	query, err := requestQuery(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return forwardQuery(c, method, pathFragment, query, body)
+}
+
+// forwardQuery sends the request to PowerDNS with query.
+func forwardQuery(c fiber.Ctx, method, pathFragment string, query url.Values, body []byte) (*powerdns.Response, error) {
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	return powerdns.Engine.Forward(ctx, method, pathFragment, query, body)
+}
+
+// requestQuery returns the query of c.
+func requestQuery(c fiber.Ctx) (url.Values, error) {
+	return url.ParseQuery(string(c.Request().URI().QueryString()))
+}
+
+// respond passes the response of PowerDNS on to the client.
+func respond(c fiber.Ctx, resp *powerdns.Response) error {
+	if resp.ContentType != "" {
+		c.Set(fiber.HeaderContentType, resp.ContentType)
+	}
+
+	return c.Status(resp.StatusCode).Send(resp.Body)
+}
+
+// respondForwardError responds to a request PowerDNS could not be asked.
+func respondForwardError(c fiber.Ctx, err error) error {
+	requestid.Logger(c.Context()).Error().Err(err).Str("path", c.Path()).Msg("failed to forward request to PowerDNS")
+
+	if errors.Is(err, powerdns.ErrClientNotInitialized) {
+		return respondError(c, fiber.StatusServiceUnavailable, powerdns.ErrMsgClientNotInitialized)
+	}
+
+	return respondError(c, fiber.StatusBadGateway, "Failed to reach PowerDNS: "+err.Error())
+}
+
+// respondChangeError responds to a change refused by the zone editor, with
+// the status of PowerDNS when it refused the change.
+func respondChangeError(c fiber.Ctx, err error) error {
+	var pdnsErr *pdnsapi.Error
+	if errors.As(err, &pdnsErr) && pdnsErr.StatusCode != 0 {
+		return respondError(c, pdnsErr.StatusCode, pdnsErr.Message)
+	}
+
+	var changeErr *zoneedit.ChangeError
+	if errors.As(err, &changeErr) {
+		return respondError(c, changeErr.Status, changeErr.Message)
+	}
+
+	return respondError(c, fiber.StatusInternalServerError, err.Error())
+}
+
+// zoneName returns the canonical name of the zone of the request.
+func zoneName(c fiber.Ctx) string {
+	return canonicalZone(c.Params("zone_id"))
+}
+
+// zonePath returns the path fragment of the zone of the request.
+func zonePath(c fiber.Ctx) string {
+	return "zones/" + c.Params("zone_id")
+}
+
+// pathSuffix returns the last segment of the request path, e.g. "notify".
+func pathSuffix(c fiber.Ctx) string {
+	return c.Path()[strings.LastIndex(c.Path(), "/")+1:]
+}
+
+// canonicalZone returns zone lower-cased with a single trailing dot.
+func canonicalZone(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(zone, ".")) + "."
+}
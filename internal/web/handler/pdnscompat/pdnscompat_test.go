@@ -0,0 +1,416 @@
+package pdnscompat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/pdnsserver"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	zonesettings "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/zone"
+	zoneedit "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/edit"
+)
+
+const testZone = `{"name":"example.com.","kind":"Native","rrsets":[{"name":"example.com.","type":"NS","ttl":3600,` +
+	`"records":[{"content":"ns1.example.com.","disabled":false}],"comments":[]}]}`
+
+// fakePowerDNS serves example.com. and other.org. and keeps the bodies of
+// the PATCH requests.
+type fakePowerDNS struct {
+	mu      sync.Mutex
+	patches []string
+}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /api/v1/servers/localhost":
+		_, _ = io.WriteString(w, `{"id":"localhost","version":"4.9.0"}`)
+	case "GET /api/v1/servers/localhost/zones":
+		_, _ = io.WriteString(w, `[{"name":"example.com.","kind":"Native"},{"name":"other.org.","kind":"Native"}]`)
+	case "GET /api/v1/servers/localhost/zones/example.com.":
+		_, _ = io.WriteString(w, testZone)
+	case "PATCH /api/v1/servers/localhost/zones/example.com.":
+		body, _ := io.ReadAll(r.Body)
+
+		f.mu.Lock()
+		f.patches = append(f.patches, string(body))
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"Not Found"}`)
+	}
+}
+
+type compatFixture struct {
+	app  *fiber.App
+	db   *gorm.DB
+	pdns *fakePowerDNS
+	key  string
+	// newKey issues another API key of the user.
+	newKey func(scope auth.APIKeyScope) (string, error)
+}
+
+// newCompatFixture serves the API for a user whose role is limited to
+// example.com and holds the given permissions, and returns an API key of
+// the user.
+func newCompatFixture(t *testing.T, permissions ...string) *compatFixture {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Setting{}, &models.Role{}, &models.Permission{}, &models.RolePermission{},
+		&models.User{}, &models.Group{}, &models.GroupMapping{}, &models.UserGroup{}, &models.APIKey{},
+		&models.Tag{}, &models.ZoneTag{}, &models.UserTag{}, &models.GroupTag{}, &models.GroupZone{},
+		&models.ZoneOwnership{}, &models.ZoneAccessOption{}, &models.ZoneDeletion{}, &models.ActivityLog{},
+		&models.Label{}, &models.Tenant{}, &models.ZoneTenant{},
+	); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	role := models.Role{Name: "user", Zones: "example.com"}
+	db.Create(&role)
+
+	for _, name := range permissions {
+		perm := models.Permission{Name: name, Resource: "zone", Action: name}
+		db.Create(&perm)
+		db.Create(&models.RolePermission{RoleID: role.ID, PermissionID: perm.ID})
+	}
+
+	user := models.User{Username: "terraform", RoleID: role.ID, Active: true}
+	db.Create(&user)
+
+	records := &zonesettings.RecordSettings{Records: config.Record{
+		"A":   config.RecordTypeSettings{Forward: true},
+		"LUA": config.RecordTypeSettings{Forward: true},
+	}}
+	if err = records.Save(db); err != nil {
+		t.Fatalf("failed to save record settings: %v", err)
+	}
+
+	f := &compatFixture{db: db, pdns: &fakePowerDNS{}}
+
+	srv := httptest.NewServer(f.pdns)
+	t.Cleanup(srv.Close)
+
+	settings := &pdnsserver.Settings{APIServerURL: srv.URL, APIKey: "secret", VHost: "localhost"}
+	if err = settings.Save(db); err != nil {
+		t.Fatalf("failed to save PowerDNS settings: %v", err)
+	}
+
+	prev := powerdns.Engine
+	t.Cleanup(func() { powerdns.Engine = prev })
+
+	if err = powerdns.Open(db); err != nil {
+		t.Fatalf("failed to open PowerDNS client: %v", err)
+	}
+
+	authService := auth.NewService(db)
+	cfg := &config.Config{}
+
+	f.newKey = func(scope auth.APIKeyScope) (string, error) {
+		token, _, err := authService.CreateAPIKey(user.ID, "terraform", scope, nil)
+		return token, err
+	}
+
+	if f.key, err = f.newKey(auth.APIKeyScope{}); err != nil {
+		t.Fatalf("failed to create API key: %v", err)
+	}
+
+	f.app = fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	f.app.Use(auth.Authenticate(authService))
+	zoneedit.Handler.Init(fiber.New(), cfg, db, authService)
+	(&Service{}).Init(f.app, cfg, db, authService)
+
+	return f
+}
+
+func (f *compatFixture) do(t *testing.T, method, target, body string) (*http.Response, string) {
+	t.Helper()
+
+	return f.doHeader(t, method, target, body, nil)
+}
+
+// doHeader is do with the additional request headers header.
+func (f *compatFixture) doHeader(t *testing.T, method, target, body string, header http.Header) (*http.Response, string) {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), method, target, strings.NewReader(body))
+	req.Header.Set(auth.APIKeyHeader, f.key)
+	req.Header.Set("Content-Type", "application/json")
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := f.app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	return resp, string(data)
+}
+
+func TestListZones_FiltersByZoneAccess(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneList)
+
+	resp, body := f.do(t, http.MethodGet, PathServers+"/localhost/zones", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", resp.StatusCode, body)
+	}
+
+	var zones []struct {
+		Name string `json:"name"`
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal([]byte(body), &zones); err != nil {
+		t.Fatalf("failed to decode %s: %v", body, err)
+	}
+
+	if len(zones) != 1 || zones[0].Name != "example.com." || zones[0].Kind != "Native" {
+		t.Errorf("zones = %+v, want only example.com.", zones)
+	}
+}
+
+func TestListZones_FiltersByLabel(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneList)
+
+	if _, err := labels.Set(f.db, "example.com.", labels.Target{}, labels.Labels{"owner": "web"}); err != nil {
+		t.Fatalf("failed to set labels: %v", err)
+	}
+
+	tests := []struct {
+		label string
+		want  int
+	}{
+		{"owner", 1},
+		{"owner=web", 1},
+		{"owner=dns", 0},
+		{"team", 0},
+	}
+
+	for _, tt := range tests {
+		resp, body := f.do(t, http.MethodGet, PathServers+"/localhost/zones?label="+tt.label, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("label %q: status = %d, body = %s", tt.label, resp.StatusCode, body)
+		}
+
+		var zones []json.RawMessage
+		if err := json.Unmarshal([]byte(body), &zones); err != nil {
+			t.Fatalf("failed to decode %s: %v", body, err)
+		}
+
+		if len(zones) != tt.want {
+			t.Errorf("label %q: got %d zones, want %d", tt.label, len(zones), tt.want)
+		}
+	}
+
+	if resp, _ := f.do(t, http.MethodGet, PathServers+"/localhost/zones?label=Bad%20Key", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid label: status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestGetZone_Access(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneRead)
+
+	if resp, body := f.do(t, http.MethodGet, PathServers+"/localhost/zones/example.com.", ""); resp.StatusCode !=
+		http.StatusOK || body != testZone {
+		t.Errorf("GET example.com. = %d %s", resp.StatusCode, body)
+	}
+
+	resp, body := f.do(t, http.MethodGet, PathServers+"/localhost/zones/other.org.", "")
+	if resp.StatusCode != http.StatusForbidden || body != `{"error":"`+errMsgZoneAccess+`"}` {
+		t.Errorf("GET other.org. = %d %s", resp.StatusCode, body)
+	}
+
+	if resp, _ = f.do(t, http.MethodGet, PathServers+"/other/zones/example.com.", ""); resp.StatusCode !=
+		http.StatusNotFound {
+		t.Errorf("status for an unknown server = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestDeleteZone_RequiresPermission(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneRead)
+
+	resp, body := f.do(t, http.MethodDelete, PathServers+"/localhost/zones/example.com.", "")
+	if resp.StatusCode != http.StatusForbidden || !strings.HasPrefix(body, `{"error":`) {
+		t.Errorf("DELETE = %d %s, want 403 in the PowerDNS error format", resp.StatusCode, body)
+	}
+}
+
+func TestPatchZone_RecordsActivity(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneUpdate)
+
+	resp, body := f.do(t, http.MethodPatch, PathServers+"/localhost/zones/example.com.",
+		`{"rrsets":[{"name":"www.example.com.","type":"A","ttl":300,"changetype":"REPLACE",`+
+			`"records":[{"content":"192.0.2.1","disabled":false}]}]}`)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("PATCH = %d %s, want 204", resp.StatusCode, body)
+	}
+
+	if len(f.pdns.patches) != 1 || !strings.Contains(f.pdns.patches[0], `"192.0.2.1"`) {
+		t.Errorf("patches sent to PowerDNS = %v", f.pdns.patches)
+	}
+
+	var entry models.ActivityLog
+	if err := f.db.Where("action = ?", activitylog.ActionRecordChanged).First(&entry).Error; err != nil {
+		t.Fatalf("no record change in the activity log: %v", err)
+	}
+
+	if entry.ResourceName != "example.com." || entry.Username != "terraform" ||
+		!strings.Contains(entry.Details, "www.example.com.") {
+		t.Errorf("activity = %+v", entry)
+	}
+}
+
+func TestPatchZone_RecordQuota(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneUpdate)
+
+	acme := &models.Tenant{Name: "acme", MaxRecordsPerZone: 1}
+	f.db.Create(acme)
+	f.db.Create(&models.ZoneTenant{ZoneName: "example.com.", TenantID: acme.ID})
+
+	resp, body := f.do(t, http.MethodPatch, PathServers+"/localhost/zones/example.com.",
+		`{"rrsets":[{"name":"www.example.com.","type":"A","ttl":300,"changetype":"REPLACE",`+
+			`"records":[{"content":"192.0.2.1","disabled":false}]}]}`)
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(body, "quota exceeded") {
+		t.Errorf("PATCH over the record quota = %d %s, want 403", resp.StatusCode, body)
+	}
+
+	if len(f.pdns.patches) != 0 {
+		t.Errorf("patches sent to PowerDNS = %v, want none", f.pdns.patches)
+	}
+}
+
+func TestPatchZone_RejectsLUAWithoutPermission(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneUpdate)
+
+	resp, body := f.do(t, http.MethodPatch, PathServers+"/localhost/zones/example.com.",
+		`{"rrsets":[{"name":"lb.example.com.","type":"LUA","ttl":300,"changetype":"REPLACE",`+
+			`"records":[{"content":"A \"pickrandom({'192.0.2.1'})\"","disabled":false}]}]}`)
+	if resp.StatusCode != http.StatusForbidden || !strings.HasPrefix(body, `{"error":`) {
+		t.Errorf("PATCH = %d %s, want 403", resp.StatusCode, body)
+	}
+
+	if len(f.pdns.patches) != 0 {
+		t.Errorf("patches sent to PowerDNS = %v, want none", f.pdns.patches)
+	}
+}
+
+func TestPatchZone_KeyLimits(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneUpdate, auth.PermZoneRead)
+
+	var err error
+	if f.key, err = f.newKey(auth.APIKeyScope{Zones: []string{"k8s.example.com"}}); err != nil {
+		t.Fatalf("failed to create API key: %v", err)
+	}
+
+	// The role grants example.com., the key only k8s.example.com.
+	if resp, body := f.do(t, http.MethodGet, PathServers+"/localhost/zones/example.com.", ""); resp.StatusCode !=
+		http.StatusForbidden {
+		t.Errorf("GET with a key for other zones = %d %s, want 403", resp.StatusCode, body)
+	}
+
+	if f.key, err = f.newKey(auth.APIKeyScope{RecordTypes: []string{"TXT"}}); err != nil {
+		t.Fatalf("failed to create API key: %v", err)
+	}
+
+	resp, body := f.do(t, http.MethodPatch, PathServers+"/localhost/zones/example.com.",
+		`{"rrsets":[{"name":"www.example.com.","type":"A","ttl":300,"changetype":"REPLACE",`+
+			`"records":[{"content":"192.0.2.1","disabled":false}]}]}`)
+	if resp.StatusCode != http.StatusForbidden || !strings.HasPrefix(body, `{"error":`) {
+		t.Errorf("PATCH of an A record with a TXT key = %d %s, want 403", resp.StatusCode, body)
+	}
+
+	if len(f.pdns.patches) != 0 {
+		t.Errorf("patches sent to PowerDNS = %v, want none", f.pdns.patches)
+	}
+}
+
+func TestGetZone_ETag(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneRead)
+	target := PathServers + "/localhost/zones/example.com."
+
+	resp, body := f.do(t, http.MethodGet, target, "")
+	tag := resp.Header.Get(fiber.HeaderETag)
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(tag, `"`) || body != testZone {
+		t.Fatalf("GET = %d, ETag %q, body %s", resp.StatusCode, tag, body)
+	}
+
+	resp, body = f.doHeader(t, http.MethodGet, target, "", http.Header{"If-None-Match": {`"stale", ` + tag}})
+	if resp.StatusCode != http.StatusNotModified || body != "" {
+		t.Errorf("GET with a matching If-None-Match = %d %s, want 304", resp.StatusCode, body)
+	}
+
+	if resp, _ = f.doHeader(t, http.MethodGet, target, "", http.Header{"If-None-Match": {`"stale"`}}); resp.StatusCode !=
+		http.StatusOK {
+		t.Errorf("GET with another If-None-Match = %d, want 200", resp.StatusCode)
+	}
+
+	if resp, _ = f.do(t, http.MethodGet, target+"?rrset_name=www.example.com.", ""); resp.Header.Get(fiber.HeaderETag) != "" {
+		t.Errorf("GET of filtered RRsets has ETag %q, want none", resp.Header.Get(fiber.HeaderETag))
+	}
+}
+
+func TestPatchZone_IfMatch(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneRead, auth.PermZoneUpdate)
+	target := PathServers + "/localhost/zones/example.com."
+	patch := `{"rrsets":[{"name":"www.example.com.","type":"A","ttl":300,"changetype":"REPLACE",` +
+		`"records":[{"content":"192.0.2.1","disabled":false}]}]}`
+
+	resp, _ := f.do(t, http.MethodGet, target, "")
+	tag := resp.Header.Get(fiber.HeaderETag)
+
+	for _, ifMatch := range []string{`"0123456789abcdef"`, "W/" + tag} {
+		resp, body := f.doHeader(t, http.MethodPatch, target, patch, http.Header{"If-Match": {ifMatch}})
+		if resp.StatusCode != http.StatusPreconditionFailed || !strings.HasPrefix(body, `{"error":`) {
+			t.Errorf("PATCH with If-Match %s = %d %s, want 412", ifMatch, resp.StatusCode, body)
+		}
+	}
+
+	if len(f.pdns.patches) != 0 {
+		t.Fatalf("patches sent to PowerDNS = %v, want none", f.pdns.patches)
+	}
+
+	for _, ifMatch := range []string{tag, `"stale", ` + tag, "*"} {
+		resp, body := f.doHeader(t, http.MethodPatch, target, patch, http.Header{"If-Match": {ifMatch}})
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("PATCH with If-Match %s = %d %s, want 204", ifMatch, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestDeleteZone_IfMatch(t *testing.T) {
+	f := newCompatFixture(t, auth.PermZoneDelete)
+
+	resp, body := f.doHeader(t, http.MethodDelete, PathServers+"/localhost/zones/example.com.", "",
+		http.Header{"If-Match": {`"0123456789abcdef"`}})
+	if resp.StatusCode != http.StatusPreconditionFailed || !strings.HasPrefix(body, `{"error":`) {
+		t.Errorf("DELETE with a stale If-Match = %d %s, want 412", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,260 @@
+// Package profileapikeys lets users issue and revoke personal API keys from
+// their profile, for scripts and automation that cannot use a browser session.
+package profileapikeys
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the route of the API keys page.
+	Path = handler.RootPath + "profile/api-keys"
+
+	// Template is the template name of the API keys page.
+	Template = "profile/apikeys"
+
+	// maxNameLen is the longest key name accepted (see models.APIKey).
+	maxNameLen = 100
+
+	errMsgLoadKeys   = "Failed to load your API keys"
+	errMsgNameNeeded = "Enter a name for the key"
+	errMsgNameLength = "The name must be at most 100 characters"
+	errMsgExpiry     = "Choose a valid expiry"
+	errMsgZones      = "Invalid zone pattern"
+	errMsgCreate     = "Failed to create the API key"
+)
+
+// expiryDays are the lifetimes offered for new keys; 0 never expires.
+var expiryDays = []int{30, 90, 365, 0}
+
+// Service handles the API keys page.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+	now         func() time.Time
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes. Keys are managed from a browser session only, so a
+// key cannot issue or revoke keys itself.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+	s.now = time.Now
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermProfileAPIKeys), auth.RequireSession(), s.List)
+	app.Post(Path, auth.RequirePermission(authService, auth.PermProfileAPIKeys), auth.RequireSession(), s.Create)
+	app.Post(Path+"/:id/revoke", auth.RequirePermission(authService, auth.PermProfileAPIKeys), auth.RequireSession(),
+		s.Revoke)
+}
+
+// List renders the keys of the current user.
+func (s *Service) List(c fiber.Ctx) error {
+	return s.render(c, fiber.StatusOK, fiber.Map{
+		"Success": c.Query("success"),
+		"Error":   c.Query("error"),
+	})
+}
+
+// Create issues a key and shows its token once.
+func (s *Service) Create(c fiber.Ctx) error {
+	user, _ := c.Locals("CurrentUser").(models.User)
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	recordTypes := models.NormalizeRecordTypes(c.FormValue("record_types"))
+	form := fiber.Map{"Name": name, "Zones": c.FormValue("zones"), "RecordTypes": recordTypes}
+
+	switch {
+	case name == "":
+		form["Error"] = errMsgNameNeeded
+	case len(name) > maxNameLen:
+		form["Error"] = errMsgNameLength
+	}
+
+	days, err := strconv.Atoi(c.FormValue("expires", "0"))
+	if err != nil || days < 0 {
+		form["Error"] = errMsgExpiry
+	}
+
+	zones, err := auth.ParseZonePatterns(c.FormValue("zones"))
+	if err != nil {
+		form["Error"] = errMsgZones + ": " + err.Error()
+	}
+
+	if form["Error"] != nil {
+		return s.render(c, fiber.StatusBadRequest, form)
+	}
+
+	var expiresAt *time.Time
+
+	if days > 0 {
+		t := s.now().AddDate(0, 0, days)
+		expiresAt = &t
+	}
+
+	scope := auth.APIKeyScope{Zones: zones}
+	for _, p := range c.Request().PostArgs().PeekMulti("permissions") {
+		scope.Permissions = append(scope.Permissions, string(p))
+	}
+
+	if recordTypes != "" {
+		scope.RecordTypes = strings.Split(recordTypes, ",")
+	}
+
+	token, key, err := s.authService.CreateAPIKey(user.ID, name, scope, expiresAt)
+	if errors.Is(err, auth.ErrAPIKeyPermission) {
+		form["Error"] = "You cannot grant a key a permission you do not have"
+		return s.render(c, fiber.StatusBadRequest, form)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to create api key")
+
+		form["Error"] = errMsgCreate
+
+		return s.render(c, fiber.StatusInternalServerError, form)
+	}
+
+	details := map[string]any{"api_key_id": key.ID, "name": key.Name, "prefix": key.Prefix}
+	if len(scope.Permissions) > 0 {
+		details["permissions"] = scope.Permissions
+	}
+
+	if len(scope.Zones) > 0 {
+		details["zones"] = scope.Zones
+	}
+
+	if len(scope.RecordTypes) > 0 {
+		details["record_types"] = scope.RecordTypes
+	}
+
+	if expiresAt != nil {
+		details["expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	s.record(c, activitylog.ActionAPIKeyCreated, user, details)
+
+	return s.render(c, fiber.StatusOK, fiber.Map{
+		"Success":  "API key created. Copy it now; it is not shown again.",
+		"NewToken": token,
+	})
+}
+
+// Revoke revokes a key of the current user; requests using it fail from now on.
+func (s *Service) Revoke(c fiber.Ctx) error {
+	user, _ := c.Locals("CurrentUser").(models.User)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return handler.RenderError(c, fiber.StatusBadRequest, "API Keys", "Invalid API key ID", nil)
+	}
+
+	key, err := s.authService.RevokeAPIKey(user.ID, id)
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		return handler.RenderError(c, fiber.StatusNotFound, "API Keys", "API key not found",
+			&handler.ErrorAction{Label: "Back to API keys", URL: Path, Icon: "bi-arrow-left"})
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("api_key_id", id).Msg("failed to revoke api key")
+		return c.Redirect().To(Path + "?error=" + url.QueryEscape("Failed to revoke the API key"))
+	}
+
+	s.record(c, activitylog.ActionAPIKeyRevoked, user,
+		map[string]any{"api_key_id": key.ID, "name": key.Name, "prefix": key.Prefix})
+
+	return c.Redirect().To(Path + "?success=" + url.QueryEscape("API key "+key.Name+" revoked"))
+}
+
+// render shows the page with the keys and permissions of the current user,
+// merged with data.
+func (s *Service) render(c fiber.Ctx, status int, data fiber.Map) error {
+	user, _ := c.Locals("CurrentUser").(models.User)
+
+	keys, err := s.authService.ListAPIKeys(user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load api keys")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errMsgLoadKeys, nil)
+	}
+
+	permissions, err := s.authService.GetUserPermissions(user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load permissions")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errMsgLoadKeys, nil)
+	}
+
+	views := make([]KeyView, len(keys))
+	for i := range keys {
+		views[i] = KeyView{APIKey: keys[i], Scopes: auth.APIKeyPermissions(&keys[i]), Status: keyStatus(&keys[i], s.now())}
+	}
+
+	nav := navigation.NewContext("API Keys", "profile", "api-keys").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Profile", handler.RootPath+"profile", false).
+		AddBreadcrumb("API Keys", Path, true)
+
+	data["Navigation"] = nav
+	data["Keys"] = views
+	data["Permissions"] = permissions
+	data["ExpiryDays"] = expiryDays
+
+	return c.Status(status).Render(Template, data, handler.BaseLayout)
+}
+
+// record writes action on a key of user to the activity log.
+func (s *Service) record(c fiber.Ctx, action string, user models.User, details map[string]any) {
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeUser,
+		ResourceName: user.Username,
+		Details:      details,
+	}))
+}
+
+// KeyView is an API key as shown on the page.
+type KeyView struct {
+	models.APIKey
+	// Scopes are the permissions the key is limited to; nil for all.
+	Scopes []string
+	// Status is "active", "expired" or "revoked".
+	Status string
+}
+
+// keyStatus tells whether key still authenticates requests at now.
+func keyStatus(key *models.APIKey, now time.Time) string {
+	switch {
+	case key.RevokedAt != nil:
+		return "revoked"
+	case key.ExpiresAt != nil && !now.Before(*key.ExpiresAt):
+		return "expired"
+	default:
+		return "active"
+	}
+}
@@ -2,6 +2,9 @@
 package profile
 
 import (
+	"errors"
+	"net/url"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
 	"github.com/rs/zerolog/log"
@@ -10,6 +13,7 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/format"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
@@ -20,8 +24,15 @@ const (
 	// Path is the route for the profile page.
 	Path = handler.RootPath + "profile"
 
+	// PasswordPath is the route of the password change; users whose password
+	// must be changed are sent here after logging in.
+	PasswordPath = Path + "/password"
+
 	// Template is the template name for the profile page.
 	Template = "profile/profile"
+
+	// PasswordTemplate is the template name for the forced password change.
+	PasswordTemplate = "profile/password"
 )
 
 // Service handles profile view and password change.
@@ -29,6 +40,7 @@ type Service struct {
 	handler.Service
 	cfg       *config.Config
 	db        *gorm.DB
+	localAuth *auth.LocalProvider
 	validator *validator.Validate
 }
 
@@ -44,10 +56,13 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, _ *auth.
 
 	s.cfg = cfg
 	s.db = db
+	s.localAuth = auth.NewLocalProvider(db)
+	s.localAuth.SetPasswordPolicy(cfg.Auth.LocalDB.PasswordPolicy)
 	s.validator = validator.New()
 
 	app.Get(Path, s.View)
-	app.Post(Path+"/password", s.ChangePassword)
+	app.Get(PasswordPath, s.PasswordPage)
+	app.Post(PasswordPath, s.ChangePassword)
 	app.Post(Path+"/preferences", s.SavePreferences)
 }
 
@@ -59,14 +74,48 @@ func (s *Service) View(c fiber.Ctx) error {
 	}
 
 	return c.Render(Template, fiber.Map{
-		"Navigation": profileNav(),
-		"User":       user,
-		"Groups":     s.loadGroupMemberships(user.ID),
-		"IsDemo":     s.cfg.Demo,
+		"Navigation":           profileNav(),
+		"User":                 user,
+		"Groups":               s.loadGroupMemberships(user.ID),
+		"IsDemo":               s.cfg.Demo,
+		"Locales":              format.Locales,
+		"PasswordRequirements": s.localAuth.PasswordRequirements(),
+		"PasswordMinLength":    s.localAuth.PasswordPolicy().MinLength,
+	}, handler.BaseLayout)
+}
+
+// PasswordPage renders the password change of users who must change their
+// password before continuing; everyone else uses the form on the profile page.
+func (s *Service) PasswordPage(c fiber.Ctx) error {
+	if !passwordChangeDue(c) {
+		return c.Redirect().To(Path)
+	}
+
+	return s.renderPasswordPage(c, fiber.StatusOK, "")
+}
+
+// renderPasswordPage renders the forced password change with msg as error.
+func (s *Service) renderPasswordPage(c fiber.Ctx, status int, msg string) error {
+	nav := navigation.NewContext("Change Password", "profile", "profile").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Change Password", PasswordPath, true)
+
+	return c.Status(status).Render(PasswordTemplate, fiber.Map{
+		"Navigation":           nav,
+		"Error":                msg,
+		"PasswordRequirements": s.localAuth.PasswordRequirements(),
+		"PasswordMinLength":    s.localAuth.PasswordPolicy().MinLength,
 	}, handler.BaseLayout)
 }
 
-// ChangePassword handles a password change request. Only available for local users.
+// passwordChangeDue reports whether the session must change its password.
+func passwordChangeDue(c fiber.Ctx) bool {
+	p := auth.PrincipalFrom(c)
+	return p != nil && p.PasswordChange
+}
+
+// ChangePassword handles a password change request. Only available for local
+// users. A password change that was due is cleared from the session.
 func (s *Service) ChangePassword(c fiber.Ctx) error {
 	user, ok := s.currentUser(c)
 	if !ok {
@@ -83,21 +132,29 @@ func (s *Service) ChangePassword(c fiber.Ctx) error {
 		return c.Redirect().To(Path)
 	}
 
+	forced := passwordChangeDue(c)
 	groups := s.loadGroupMemberships(user.ID)
 
 	renderErr := func(msg string) error {
+		if forced {
+			return s.renderPasswordPage(c, fiber.StatusBadRequest, msg)
+		}
+
 		return c.Status(fiber.StatusBadRequest).Render(Template, fiber.Map{
-			"Navigation": profileNav(),
-			"User":       user,
-			"Groups":     groups,
-			"IsDemo":     s.cfg.Demo,
-			"Error":      msg,
+			"Navigation":           profileNav(),
+			"User":                 user,
+			"Groups":               groups,
+			"IsDemo":               s.cfg.Demo,
+			"Locales":              format.Locales,
+			"PasswordRequirements": s.localAuth.PasswordRequirements(),
+			"PasswordMinLength":    s.localAuth.PasswordPolicy().MinLength,
+			"Error":                msg,
 		}, handler.BaseLayout)
 	}
 
 	var in struct {
 		CurrentPassword string `form:"current_password" validate:"required"`
-		NewPassword     string `form:"new_password"     validate:"required,min=8"`
+		NewPassword     string `form:"new_password"     validate:"required"`
 		ConfirmPassword string `form:"confirm_password" validate:"required"`
 	}
 
@@ -106,32 +163,62 @@ func (s *Service) ChangePassword(c fiber.Ctx) error {
 	}
 
 	if err := s.validator.Struct(in); err != nil {
-		return renderErr("New password must be at least 8 characters")
+		return renderErr("Please fill in all password fields")
 	}
 
 	if in.NewPassword != in.ConfirmPassword {
 		return renderErr("New passwords do not match")
 	}
 
-	if !user.VerifyPassword(in.CurrentPassword) {
-		return renderErr("Current password is incorrect")
-	}
+	err := s.localAuth.ChangePassword(user.ID, in.CurrentPassword, in.NewPassword)
 
-	user.Password = models.HashPassword(in.NewPassword)
-	if err := s.db.Save(&user).Error; err != nil {
+	switch {
+	case errors.Is(err, auth.ErrInvalidOldPassword):
+		return renderErr("Current password is incorrect")
+	case errors.Is(err, auth.ErrPasswordPolicy):
+		return renderErr("New password must have " + s.localAuth.PasswordRequirements())
+	case errors.Is(err, auth.ErrPasswordUnchanged):
+		return renderErr("New password must differ from the current password")
+	case err != nil:
 		log.Error().Err(err).Msg("failed to update password")
 		return renderErr("Failed to update password")
 	}
 
+	if forced {
+		s.clearPasswordChange(c)
+
+		return c.Redirect().To(dashboard.Path + "?success=" + url.QueryEscape("Password updated successfully"))
+	}
+
 	return c.Render(Template, fiber.Map{
-		"Navigation": profileNav(),
-		"User":       user,
-		"Groups":     groups,
-		"IsDemo":     s.cfg.Demo,
-		"Success":    "Password updated successfully",
+		"Navigation":           profileNav(),
+		"User":                 user,
+		"Groups":               groups,
+		"IsDemo":               s.cfg.Demo,
+		"Locales":              format.Locales,
+		"PasswordRequirements": s.localAuth.PasswordRequirements(),
+		"PasswordMinLength":    s.localAuth.PasswordPolicy().MinLength,
+		"Success":              "Password updated successfully",
 	}, handler.BaseLayout)
 }
 
+// clearPasswordChange lifts the password change restriction of the session.
+func (s *Service) clearPasswordChange(c fiber.Ctx) {
+	sessionID := c.Cookies("session")
+
+	sessData := new(session.Data)
+	if err := sessData.Read(sessionID); err != nil {
+		return
+	}
+
+	sessData.PasswordChange = false
+	sessData.User.MustChangePassword = false
+
+	if err := sessData.Write(sessionID); err != nil {
+		log.Error().Err(err).Msg("failed to update session after password change")
+	}
+}
+
 // GroupMembership pairs a group with its mapped role (nil when no mapping exists).
 type GroupMembership struct {
 	Group      models.Group
@@ -160,37 +247,35 @@ func (s *Service) loadGroupMemberships(userID uint64) []GroupMembership {
 
 // currentUser loads a fresh copy of the logged-in user from the DB.
 func (s *Service) currentUser(c fiber.Ctx) (models.User, bool) {
-	sessionID := c.Cookies("session")
-	if sessionID == "" {
-		return models.User{}, false
-	}
-
-	sessData := new(session.Data)
-	if err := sessData.Read(sessionID); err != nil || sessData.User.ID == 0 {
+	current, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || current.ID == 0 {
 		return models.User{}, false
 	}
 
 	var user models.User
-	if err := s.db.Preload("Role").First(&user, sessData.User.ID).Error; err != nil {
+	if err := s.db.Preload("Role").First(&user, current.ID).Error; err != nil {
 		return models.User{}, false
 	}
 
 	return user, true
 }
 
-// SavePreferences updates UI preferences (e.g. page sizes) for the current user.
+// SavePreferences updates UI preferences (e.g. page sizes, locale) for the current user.
+// It accepts JSON (from in-page widgets, answered with JSON) and regular form
+// posts from the profile page (answered with a redirect back to the profile).
 func (s *Service) SavePreferences(c fiber.Ctx) error {
 	user, ok := s.currentUser(c)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		return handler.JSONError(c, fiber.StatusUnauthorized, handler.CodeUnauthorized, "unauthorized", nil)
 	}
 
 	var body struct {
-		ZoneEditPageSize    *int `json:"zone_edit_page_size"`
-		ActivityLogPageSize *int `json:"activity_log_page_size"`
+		ZoneEditPageSize    *int    `json:"zone_edit_page_size"`
+		ActivityLogPageSize *int    `json:"activity_log_page_size"`
+		Locale              *string `json:"locale"                 form:"locale"`
 	}
 	if err := c.Bind().Body(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "invalid body", nil)
 	}
 
 	if body.ZoneEditPageSize != nil {
@@ -207,9 +292,34 @@ func (s *Service) SavePreferences(c fiber.Ctx) error {
 		}
 	}
 
+	if body.Locale != nil && (*body.Locale == "" || format.IsSupported(*body.Locale)) {
+		s.db.Model(&models.User{}).Where("id = ?", user.ID).Update("locale", *body.Locale)
+		s.refreshSessionLocale(c, *body.Locale)
+	}
+
+	if !c.Is("json") {
+		return c.Redirect().To(Path)
+	}
+
 	return c.JSON(fiber.Map{"ok": true})
 }
 
+// refreshSessionLocale updates the locale of the user cached in the session so
+// templates pick up the new preference without a fresh login.
+func (s *Service) refreshSessionLocale(c fiber.Ctx, locale string) {
+	sessionID := c.Cookies("session")
+
+	sessData := new(session.Data)
+	if err := sessData.Read(sessionID); err != nil {
+		return
+	}
+
+	sessData.User.Locale = locale
+	if err := sessData.Write(sessionID); err != nil {
+		log.Error().Err(err).Msg("failed to update session locale")
+	}
+}
+
 func profileNav() *navigation.Context {
 	return navigation.NewContext("Profile", "profile", "profile").
 		AddBreadcrumb("Home", dashboard.Path, false).
@@ -0,0 +1,88 @@
+// Package profilesecurity shows users their own login history, so they can
+// spot sign-ins they do not recognize.
+package profilesecurity
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/loginhistory"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the route of the security page.
+	Path = handler.RootPath + "profile/security"
+
+	// Template is the template name of the security page.
+	Template = "profile/security"
+
+	// limit is the number of login attempts shown.
+	limit = 50
+)
+
+// Service handles the security page.
+type Service struct {
+	handler.Service
+	cfg *config.Config
+	db  *gorm.DB
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes. No permission required — any authenticated user may
+// see their own logins.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, _ *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+
+	app.Get(Path, s.View)
+}
+
+// View renders the latest login attempts of the current user.
+func (s *Service) View(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return c.Redirect().To("/login")
+	}
+
+	events, err := loginhistory.ForUser(s.db, user.ID, limit)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load login history")
+
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load your login history", nil)
+	}
+
+	var suspicious int
+
+	for i := range events {
+		if events[i].Suspicious() {
+			suspicious++
+		}
+	}
+
+	nav := navigation.NewContext("Security", "profile", "security").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Profile", handler.RootPath+"profile", false).
+		AddBreadcrumb("Security", Path, true)
+
+	return c.Render(Template, fiber.Map{
+		"Navigation":  nav,
+		"LoginEvents": events,
+		"Suspicious":  suspicious,
+		"Limit":       limit,
+	}, handler.BaseLayout)
+}
@@ -82,7 +82,7 @@ func (s *Service) SetupGet(c fiber.Ctx) error {
 		tempSecret = key.Secret()
 
 		sessData.TOTPTempSecret = tempSecret
-		if err := sessData.Write(sessionID, s.cfg.Webserver.Session.ExpiryTime); err != nil {
+		if err := sessData.Write(sessionID); err != nil {
 			log.Error().Err(err).Msg("failed to write temp TOTP secret to session")
 			return c.Redirect().To("/profile")
 		}
@@ -162,7 +162,7 @@ func (s *Service) SetupPost(c fiber.Ctx) error {
 	sessData.User.TOTPEnabled = true
 
 	sessData.User.TOTPSecret = confirmedSecret
-	if err := sessData.Write(sessionID, s.cfg.Webserver.Session.ExpiryTime); err != nil {
+	if err := sessData.Write(sessionID); err != nil {
 		log.Error().Err(err).Msg("failed to update session after TOTP setup")
 	}
 
@@ -201,7 +201,7 @@ func (s *Service) Disable(c fiber.Ctx) error {
 	sessData.User.TOTPEnabled = false
 
 	sessData.User.TOTPSecret = ""
-	if err := sessData.Write(sessionID, s.cfg.Webserver.Session.ExpiryTime); err != nil {
+	if err := sessData.Write(sessionID); err != nil {
 		log.Error().Err(err).Msg("failed to update session after TOTP disable")
 	}
 
@@ -0,0 +1,403 @@
+// Package search provides the global search: the search bar in the page
+// header and the search page find zones, records, users, groups and roles in
+// one go. Each category is only searched when the user holds the permission of
+// the matching list page, and zones and records are limited to the zones the
+// user has access to.
+package search
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/like"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	admingroup "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/group"
+	adminrole "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/role"
+	adminuser "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/user"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// Path is the path of the search page.
+	Path = handler.RootPath + "search"
+
+	// APIPath is the path of the search endpoint behind the search bar.
+	APIPath = handler.APIPathPrefix + "search"
+
+	// MinQueryLength is the number of characters a query needs.
+	MinQueryLength = 2
+
+	// barLimit and pageLimit are the number of results per category shown
+	// in the search bar and on the search page.
+	barLimit  = 5
+	pageLimit = 25
+
+	// maxRecordMatches caps the records PowerDNS returns per search; matches
+	// in zones the user cannot access are dropped afterwards.
+	maxRecordMatches = 500
+
+	searchTimeout = 10 * time.Second
+
+	templateSearch = "search/search"
+
+	labelSearch = "Search"
+)
+
+// Category names, in the order results are returned.
+const (
+	CategoryZones   = "zones"
+	CategoryRecords = "records"
+	CategoryUsers   = "users"
+	CategoryGroups  = "groups"
+	CategoryRoles   = "roles"
+)
+
+// Result is a single search hit.
+type Result struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+	URL    string `json:"url"`
+}
+
+// Category holds the results of one kind of object.
+type Category struct {
+	Name    string   `json:"name"`
+	Label   string   `json:"label"`
+	Icon    string   `json:"icon"`
+	Results []Result `json:"results"`
+	// More is set when the category had more results than returned.
+	More bool `json:"more"`
+	// Error is set when the category could not be searched.
+	Error string `json:"error,omitempty"`
+}
+
+// Response is the JSON body of the search endpoint.
+type Response struct {
+	Query      string     `json:"query"`
+	Categories []Category `json:"categories"`
+}
+
+// Service handles the global search.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+
+	guard := auth.RequireAnyPermission(authService,
+		auth.PermZoneRead, auth.PermAdminUsers, auth.PermAdminGroups, auth.PermAdminRoles)
+
+	app.Get(Path, guard, s.Page)
+	app.Get(APIPath, guard, s.Search)
+}
+
+// Page renders the results of ?q= on a page of their own.
+func (s *Service) Page(c fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+
+	var categories []Category
+	if utf8.RuneCountInString(query) >= MinQueryLength {
+		categories = s.search(c, query, pageLimit)
+	}
+
+	nav := navigation.NewContext(labelSearch, "search", "search").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelSearch, Path, true)
+
+	return c.Render(templateSearch, fiber.Map{
+		"Navigation":     nav,
+		"Query":          query,
+		"Categories":     categories,
+		"MinQueryLength": MinQueryLength,
+	}, handler.BaseLayout)
+}
+
+// Search returns the results of ?q=, up to ?limit= (default 5, at most 25)
+// per category.
+func (s *Service) Search(c fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(query) < MinQueryLength {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation,
+			"The query needs at least "+strconv.Itoa(MinQueryLength)+" characters", nil)
+	}
+
+	limit := fiber.Query[int](c, "limit", barLimit)
+	if limit < 1 || limit > pageLimit {
+		limit = barLimit
+	}
+
+	return c.JSON(Response{Query: query, Categories: s.search(c, query, limit)})
+}
+
+// search runs query against every category the current user may see and
+// returns the categories with results.
+func (s *Service) search(c fiber.Ctx, query string, limit int) []Category {
+	ctx, cancel := context.WithTimeout(c.Context(), searchTimeout)
+	defer cancel()
+
+	logger := requestid.Logger(c.Context())
+	categories := make([]Category, 0, 5)
+	user, _ := c.Locals("CurrentUser").(models.User)
+
+	if auth.HasPermissionInContext(c, s.authService, auth.PermZoneRead) {
+		// When the access cannot be loaded no zone is allowed.
+		allowed, err := auth.ZoneFilter(c, s.authService)
+		if err != nil {
+			allowed = func(string) bool { return false }
+		}
+
+		zones, err := zoneindex.Default.List(ctx)
+
+		zoneCategory := searchZones(zones, allowed, query, limit)
+		if err != nil {
+			logger.Warn().Err(err).Msg("search: failed to list zones")
+			zoneCategory.Error = "Zones could not be listed"
+		}
+
+		categories = append(categories, zoneCategory, searchRecords(ctx, allowed, query, limit))
+	}
+
+	for _, cat := range []struct {
+		permission string
+		search     func(db *gorm.DB, tenantID *uint, query string, limit int) (Category, error)
+	}{
+		{auth.PermAdminUsers, searchUsers},
+		{auth.PermAdminGroups, searchGroups},
+		{auth.PermAdminRoles, searchRoles},
+	} {
+		if !auth.HasPermissionInContext(c, s.authService, cat.permission) {
+			continue
+		}
+
+		category, err := cat.search(s.db, user.TenantID, query, limit)
+		if err != nil {
+			logger.Error().Err(err).Str("category", category.Name).Msg("search: query failed")
+			category.Error = "Search failed"
+		}
+
+		categories = append(categories, category)
+	}
+
+	kept := categories[:0]
+
+	for i := range categories {
+		if len(categories[i].Results) > 0 || categories[i].Error != "" {
+			kept = append(kept, categories[i])
+		}
+	}
+
+	return kept
+}
+
+// searchZones returns the zones allowed, nil for all, whose name contains
+// query.
+func searchZones(zones []pdnsapi.Zone, allowed func(string) bool, query string, limit int) Category {
+	category := Category{Name: CategoryZones, Label: "Zones", Icon: "bi-globe2", Results: []Result{}}
+	q := strings.ToLower(query)
+
+	for i := range zones {
+		name := pdnsapi.StringValue(zones[i].Name)
+		if !strings.Contains(strings.ToLower(name), q) || (allowed != nil && !allowed(name)) {
+			continue
+		}
+
+		if len(category.Results) == limit {
+			category.More = true
+			break
+		}
+
+		var kind string
+		if zones[i].Kind != nil {
+			kind = string(*zones[i].Kind)
+		}
+
+		category.Results = append(category.Results, Result{Title: name, Detail: kind, URL: handler.ZoneEditURL(name)})
+	}
+
+	return category
+}
+
+// searchRecords returns the records in zones allowed, nil for all, whose name
+// or content contains query, using the search API of PowerDNS.
+func searchRecords(ctx context.Context, allowed func(string) bool, query string, limit int) Category {
+	category := Category{Name: CategoryRecords, Label: "Records", Icon: "bi-list-ul", Results: []Result{}}
+
+	if powerdns.Engine.Client == nil {
+		category.Error = powerdns.ErrMsgClientNotInitialized
+		return category
+	}
+
+	matches, err := powerdns.Engine.Search.Data(ctx, "*"+query+"*", maxRecordMatches, pdnsapi.SearchObjectTypeRecord)
+	if err != nil {
+		log.Warn().Err(err).Str("query", query).Msg("search: PowerDNS record search failed")
+		category.Error = "PowerDNS search failed"
+
+		return category
+	}
+
+	category.Results, category.More = records(matches, allowed, limit)
+
+	return category
+}
+
+// records converts the record matches of PowerDNS in zones allowed, nil for
+// all.
+func records(matches []pdnsapi.SearchResult, allowed func(string) bool, limit int) ([]Result, bool) {
+	results := []Result{}
+
+	for i := range matches {
+		m := &matches[i]
+		zone := pdnsapi.StringValue(m.Zone)
+
+		if pdnsapi.StringValue(m.ObjectType) != string(pdnsapi.SearchObjectTypeRecord) || (allowed != nil && !allowed(zone)) {
+			continue
+		}
+
+		if len(results) == limit {
+			return results, true
+		}
+
+		name, rrType := pdnsapi.StringValue(m.Name), pdnsapi.StringValue(m.Type)
+		results = append(results, Result{
+			Title:  name + " " + rrType,
+			Detail: pdnsapi.StringValue(m.Content),
+			URL:    handler.RecordURL(zone, name, rrType),
+		})
+	}
+
+	return results, false
+}
+
+// searchUsers returns the users of the tenant tenantID whose login, name or
+// email contains query; see tenant.Scope.
+func searchUsers(db *gorm.DB, tenantID *uint, query string, limit int) (Category, error) {
+	category := Category{Name: CategoryUsers, Label: "Users", Icon: "bi-person", Results: []Result{}}
+
+	var users []models.User
+
+	err := db.Scopes(tenant.Scope(tenantID)).Where("deleted_at IS NULL").
+		Scopes(like.Contains(query, "username", "email", "display_name", "first_name", "last_name")).
+		Order("username").
+		Limit(limit + 1).
+		Find(&users).Error
+	if err != nil {
+		return category, err
+	}
+
+	for i := range users {
+		if len(category.Results) == limit {
+			category.More = true
+			break
+		}
+
+		u := &users[i]
+
+		detail := u.Email
+		if name := u.FullName(); name != u.Username {
+			detail = name + " · " + u.Email
+		}
+
+		category.Results = append(category.Results, Result{
+			Title:  u.Username,
+			Detail: detail,
+			URL:    adminuser.Path + "/" + strconv.FormatUint(u.ID, 10) + "/edit",
+		})
+	}
+
+	return category, nil
+}
+
+// searchGroups returns the groups of the tenant tenantID whose name or
+// description contains query; see tenant.Scope.
+func searchGroups(db *gorm.DB, tenantID *uint, query string, limit int) (Category, error) {
+	category := Category{Name: CategoryGroups, Label: "Groups", Icon: "bi-people", Results: []Result{}}
+
+	var groups []models.Group
+
+	err := db.Scopes(tenant.Scope(tenantID), like.Contains(query, "name", "description")).
+		Order("name").
+		Limit(limit + 1).
+		Find(&groups).Error
+	if err != nil {
+		return category, err
+	}
+
+	for i := range groups {
+		if len(category.Results) == limit {
+			category.More = true
+			break
+		}
+
+		g := &groups[i]
+		category.Results = append(category.Results, Result{
+			Title:  g.Name,
+			Detail: g.Description,
+			URL:    admingroup.Path + "/" + strconv.FormatUint(uint64(g.ID), 10) + "/edit",
+		})
+	}
+
+	return category, nil
+}
+
+// searchRoles returns the roles whose name or description contains query.
+// Roles are shared by all tenants.
+func searchRoles(db *gorm.DB, _ *uint, query string, limit int) (Category, error) {
+	category := Category{Name: CategoryRoles, Label: "Roles", Icon: "bi-shield-lock", Results: []Result{}}
+
+	var roles []models.Role
+
+	err := db.Scopes(like.Contains(query, "name", "description")).
+		Order("name").
+		Limit(limit + 1).
+		Find(&roles).Error
+	if err != nil {
+		return category, err
+	}
+
+	for i := range roles {
+		if len(category.Results) == limit {
+			category.More = true
+			break
+		}
+
+		r := &roles[i]
+		category.Results = append(category.Results, Result{
+			Title:  r.Name,
+			Detail: r.Description,
+			URL:    adminrole.Path + "/" + strconv.FormatUint(uint64(r.ID), 10) + "/edit",
+		})
+	}
+
+	return category, nil
+}
@@ -0,0 +1,163 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func titles(results []Result) []string {
+	out := make([]string, len(results))
+	for i := range results {
+		out[i] = results[i].Title
+	}
+
+	return out
+}
+
+func TestSearchZones(t *testing.T) {
+	native, slave := pdnsapi.NativeZoneKind, pdnsapi.SlaveZoneKind
+	zones := []pdnsapi.Zone{
+		{Name: pdnsapi.String("example.com."), Kind: &native},
+		{Name: pdnsapi.String("Example.net."), Kind: &slave},
+		{Name: pdnsapi.String("other.org.")},
+		{Name: pdnsapi.String("secret-example.org."), Kind: &native},
+	}
+	access := &auth.ZoneAccess{Direct: map[string]bool{"example.com.": true, "Example.net.": true}}
+
+	got := searchZones(zones, access.Allows, "EXAMPLE", 5)
+	if !reflect.DeepEqual(titles(got.Results), []string{"example.com.", "Example.net."}) || got.More {
+		t.Fatalf("searchZones() = %+v", got)
+	}
+
+	if got.Results[1].Detail != "Slave" || got.Results[0].URL != "/zone/edit/example.com." {
+		t.Errorf("result = %+v", got.Results)
+	}
+
+	if got = searchZones(zones, nil, "example", 2); len(got.Results) != 2 || !got.More {
+		t.Errorf("searchZones(limit 2) = %+v", got)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	record, comment := string(pdnsapi.SearchObjectTypeRecord), string(pdnsapi.SearchObjectTypeComment)
+	matches := []pdnsapi.SearchResult{
+		{ObjectType: &record, Zone: pdnsapi.String("example.com."), Name: pdnsapi.String("www.example.com."),
+			Type: pdnsapi.String("A"), Content: pdnsapi.String("192.0.2.1")},
+		{ObjectType: &comment, Zone: pdnsapi.String("example.com."), Name: pdnsapi.String("www.example.com.")},
+		{ObjectType: &record, Zone: pdnsapi.String("hidden.org."), Name: pdnsapi.String("www.hidden.org."),
+			Type: pdnsapi.String("A"), Content: pdnsapi.String("192.0.2.2")},
+		{ObjectType: &record, Zone: pdnsapi.String("example.com."), Name: pdnsapi.String("mail.example.com."),
+			Type: pdnsapi.String("MX"), Content: pdnsapi.String("10 mx.example.com.")},
+	}
+	access := &auth.ZoneAccess{Direct: map[string]bool{"example.com.": true}}
+
+	results, more := records(matches, access.Allows, 5)
+	if !reflect.DeepEqual(titles(results), []string{"www.example.com. A", "mail.example.com. MX"}) || more {
+		t.Fatalf("records() = %+v, %v", results, more)
+	}
+
+	if results[0].Detail != "192.0.2.1" || results[0].URL == "" {
+		t.Errorf("result = %+v", results[0])
+	}
+
+	if results, more = records(matches, access.Allows, 1); len(results) != 1 || !more {
+		t.Errorf("records(limit 1) = %+v, %v", results, more)
+	}
+}
+
+func TestSearchDatabase(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.Group{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	db.Create(&models.Role{Name: "admin", Description: "Full access"})
+	db.Create(&models.Role{Name: "dns_operator", Description: "Edits records"})
+	db.Create(&models.User{Username: "alice", Email: "alice@example.com", DisplayName: "Alice Admin", RoleID: 1})
+	db.Create(&models.User{Username: "bob", Email: "bob@example.org", RoleID: 2})
+	db.Create(&models.Group{Name: "DNS Team", Source: models.GroupSourceLocal, ExternalID: "dns"})
+	db.Create(&models.Group{Name: "Ops", Description: "100% on call", Source: models.GroupSourceLocal, ExternalID: "ops"})
+
+	users, err := searchUsers(db, nil, "ADMIN", 5)
+	if err != nil || !reflect.DeepEqual(titles(users.Results), []string{"alice"}) {
+		t.Fatalf("searchUsers() = %+v, %v", users, err)
+	}
+
+	if users.Results[0].Detail != "Alice Admin · alice@example.com" || users.Results[0].URL != "/admin/user/1/edit" {
+		t.Errorf("user result = %+v", users.Results[0])
+	}
+
+	if users, _ = searchUsers(db, nil, "example", 1); len(users.Results) != 1 || !users.More {
+		t.Errorf("searchUsers(limit 1) = %+v", users)
+	}
+
+	// LIKE wildcards in the query match literally.
+	groups, err := searchGroups(db, nil, "0%", 5)
+	if err != nil || !reflect.DeepEqual(titles(groups.Results), []string{"Ops"}) {
+		t.Errorf("searchGroups(0%%) = %+v, %v", groups, err)
+	}
+
+	if groups, _ = searchGroups(db, nil, "_", 5); len(groups.Results) != 0 {
+		t.Errorf("searchGroups(_) = %+v", groups)
+	}
+
+	roles, err := searchRoles(db, nil, "dns_", 5)
+	if err != nil || !reflect.DeepEqual(titles(roles.Results), []string{"dns_operator"}) {
+		t.Errorf("searchRoles() = %+v, %v", roles, err)
+	}
+}
+
+func TestSearchDatabase_Tenants(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Tenant{}, &models.Role{}, &models.User{}, &models.Group{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	acme, globex := models.Tenant{Name: "acme"}, models.Tenant{Name: "globex"}
+	db.Create(&acme)
+	db.Create(&globex)
+
+	db.Create(&models.User{Username: "ops-root", Email: "root@example.com"})
+	db.Create(&models.User{Username: "ops-acme", Email: "ops@acme.example", TenantID: &acme.ID})
+	db.Create(&models.User{Username: "ops-globex", Email: "ops@globex.example", TenantID: &globex.ID})
+	db.Create(&models.Group{Name: "ops", Source: models.GroupSourceLocal, ExternalID: "ops"})
+	db.Create(&models.Group{Name: "ops-acme", Source: models.GroupSourceLocal, ExternalID: "ops-acme",
+		TenantID: &acme.ID})
+	db.Create(&models.Group{Name: "ops-globex", Source: models.GroupSourceLocal, ExternalID: "ops-globex",
+		TenantID: &globex.ID})
+
+	for _, tt := range []struct {
+		tenantID   *uint
+		wantUsers  []string
+		wantGroups []string
+	}{
+		{nil, []string{"ops-acme", "ops-globex", "ops-root"}, []string{"ops", "ops-acme", "ops-globex"}},
+		{&acme.ID, []string{"ops-acme"}, []string{"ops-acme"}},
+		{&globex.ID, []string{"ops-globex"}, []string{"ops-globex"}},
+	} {
+		users, err := searchUsers(db, tt.tenantID, "ops", 5)
+		if err != nil || !reflect.DeepEqual(titles(users.Results), tt.wantUsers) {
+			t.Errorf("searchUsers(tenant %v) = %+v, %v, want %v", tt.tenantID, users, err, tt.wantUsers)
+		}
+
+		groups, err := searchGroups(db, tt.tenantID, "ops", 5)
+		if err != nil || !reflect.DeepEqual(titles(groups.Results), tt.wantGroups) {
+			t.Errorf("searchGroups(tenant %v) = %+v, %v, want %v", tt.tenantID, groups, err, tt.wantGroups)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+// Package setup implements the first-run setup wizard. It replaces the default
+// admin/changeme account: the administrator picks their own credentials, then
+// connects PowerDNS with a live connection test and chooses the login methods.
+//
+// The wizard runs while setup is pending (see package db/controller/setup):
+//
+//  1. Path creates the first administrator account. It needs the setup token
+//     from the startup log and signs the new administrator in.
+//  2. PowerDNSPath configures the PowerDNS API connection; PowerDNSTestPath
+//     tests unsaved settings.
+//  3. AuthPath selects the login methods and completes setup.
+package setup
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/appsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/authsettings"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/pdnsserver"
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/version"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/login"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// Path is the first step of the setup wizard: the administrator account.
+	Path = handler.RootPath + "setup"
+
+	// PowerDNSPath is the PowerDNS connection step.
+	PowerDNSPath = Path + "/powerdns"
+
+	// PowerDNSTestPath tests the PowerDNS connection settings of the form.
+	PowerDNSTestPath = PowerDNSPath + "/test"
+
+	// AuthPath is the last step: the login methods.
+	AuthPath = Path + "/auth"
+
+	// TemplateName is the name of the setup wizard template.
+	TemplateName = "setup/setup"
+
+	// defaultVHost is the virtual host PowerDNS serves its API on.
+	defaultVHost = "localhost"
+)
+
+// Steps of the wizard, as passed to the template.
+const (
+	stepAccount  = "account"
+	stepPowerDNS = "powerdns"
+	stepAuth     = "auth"
+)
+
+var (
+	errInvalidToken   = errors.New("the setup token is not valid; copy it from the log of the application")
+	errInvalidAccount = errors.New("enter a username of 3 to 100 characters and a valid email address")
+	errPasswordPolicy = errors.New("the password does not meet the password policy")
+	errPasswordMatch  = errors.New("the passwords do not match")
+	errAccountExists  = errors.New("the administrator account has already been created")
+	errCreateAccount  = errors.New("failed to create the administrator account")
+	errInvalidPDNS    = errors.New("enter the API URL, an API key of at least 8 characters and the virtual host")
+	errNoLoginMethod  = errors.New("local login can only be disabled when LDAP or OIDC login is enabled")
+)
+
+// Service is the setup wizard handler service.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	store       *controller.Store
+	appSettings *appsettings.Store
+	localAuth   *auth.LocalProvider
+	validator   *validator.Validate
+}
+
+// Handler is the setup wizard handler.
+var Handler = Service{}
+
+// Account is the form of the administrator account step.
+type Account struct {
+	Token       string `form:"token"`
+	Username    string `form:"username"    validate:"required,min=3,max=100"`
+	Email       string `form:"email"       validate:"required,email,max=255"`
+	DisplayName string `form:"displayname" validate:"max=255"`
+	Password    string `form:"password"`
+	Confirm     string `form:"confirm_password"`
+}
+
+// Init initializes the setup wizard handler. The stores are shared with the
+// web service, whose middleware sends every request here while setup is
+// pending.
+func (s *Service) Init(
+	app *fiber.App,
+	cfg *config.Config,
+	db *gorm.DB,
+	authService *auth.Service,
+	store *controller.Store,
+	appSettings *appsettings.Store,
+) {
+	if app == nil || cfg == nil || db == nil || store == nil || appSettings == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.store = store
+	s.appSettings = appSettings
+	s.localAuth = auth.NewLocalProvider(db)
+	s.localAuth.SetPasswordPolicy(cfg.Auth.LocalDB.PasswordPolicy)
+	s.validator = validator.New()
+
+	app.Get(Path, s.GetAccount)
+	app.Post(Path, s.PostAccount)
+
+	app.Get(PowerDNSPath, s.pending, auth.RequirePermission(authService, auth.PermAdminPDNSServer), s.GetPowerDNS)
+	app.Post(PowerDNSPath, s.pending, auth.RequirePermission(authService, auth.PermAdminPDNSServer), s.PostPowerDNS)
+	app.Post(PowerDNSTestPath, s.pending, auth.RequirePermission(authService, auth.PermAdminPDNSServer), s.TestPowerDNS)
+
+	app.Get(AuthPath, s.pending, auth.RequirePermission(authService, auth.PermAdminSettings), s.GetAuth)
+	app.Post(AuthPath, s.pending, auth.RequirePermission(authService, auth.PermAdminSettings), s.PostAuth)
+}
+
+// pending sends requests to the dashboard once setup is done.
+func (s *Service) pending(c fiber.Ctx) error {
+	if !s.store.Pending() {
+		return c.Redirect().To(handler.DashboardPath)
+	}
+
+	return c.Next()
+}
+
+// GetAccount renders the administrator account step, or continues with the
+// next step when the account exists.
+func (s *Service) GetAccount(c fiber.Ctx) error {
+	if !s.store.Pending() {
+		return c.Redirect().To(handler.DashboardPath)
+	}
+
+	exists, err := s.store.HasUsers()
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("setup: failed to count users")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Setup", "Failed to read the users", nil)
+	}
+
+	if exists {
+		return c.Redirect().To(PowerDNSPath)
+	}
+
+	return s.render(c, fiber.StatusOK, stepAccount, fiber.Map{"Account": Account{}})
+}
+
+// PostAccount creates the first administrator account and signs it in.
+func (s *Service) PostAccount(c fiber.Ctx) error {
+	if !s.store.Pending() {
+		return c.Redirect().To(handler.DashboardPath)
+	}
+
+	var in Account
+	if err := c.Bind().Body(&in); err != nil {
+		return s.render(c, fiber.StatusBadRequest, stepAccount, fiber.Map{"Account": in, "Error": "Invalid form data"})
+	}
+
+	in.Token = strings.TrimSpace(in.Token)
+	in.Username = strings.TrimSpace(in.Username)
+	in.Email = strings.TrimSpace(in.Email)
+	in.DisplayName = strings.TrimSpace(in.DisplayName)
+
+	user, err := s.createAdmin(&in)
+	if errors.Is(err, errAccountExists) {
+		return c.Redirect().To(PowerDNSPath)
+	}
+
+	if err != nil {
+		in.Password, in.Confirm = "", ""
+		status := fiber.StatusBadRequest
+
+		if !isFormError(err) {
+			requestid.Logger(c.Context()).Error().Err(err).Msg("setup: failed to create the administrator account")
+
+			status = fiber.StatusInternalServerError
+			err = errCreateAccount
+		}
+
+		return s.render(c, status, stepAccount, fiber.Map{"Account": in, "Error": err.Error()})
+	}
+
+	userID := user.ID
+	activitylog.Record(&activitylog.Entry{
+		DB:           s.db,
+		UserID:       &userID,
+		Username:     user.Username,
+		Action:       activitylog.ActionSetupAccountCreated,
+		ResourceType: activitylog.ResourceTypeUser,
+		ResourceName: user.Username,
+		IPAddress:    c.IP(),
+	})
+
+	requestid.Logger(c.Context()).Info().Str("username", user.Username).Msg("setup: administrator account created")
+
+	if err := login.Handler.SignIn(c, user); err != nil {
+		return c.Redirect().To(login.Path)
+	}
+
+	return c.Redirect().To(PowerDNSPath)
+}
+
+// createAdmin validates in and creates the administrator account, unless a
+// user exists already.
+func (s *Service) createAdmin(in *Account) (*models.User, error) {
+	if !s.store.CheckToken(in.Token) {
+		return nil, errInvalidToken
+	}
+
+	if err := s.validator.Struct(in); err != nil {
+		return nil, errInvalidAccount
+	}
+
+	if err := s.localAuth.ValidatePassword(in.Password); err != nil {
+		return nil, errPasswordPolicy
+	}
+
+	if in.Password != in.Confirm {
+		return nil, errPasswordMatch
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Username:          in.Username,
+		Email:             in.Email,
+		DisplayName:       in.DisplayName,
+		Password:          models.HashPassword(in.Password),
+		PasswordChangedAt: &now,
+		Active:            true,
+		AuthSource:        models.AuthSourceLocal,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return errAccountExists
+		}
+
+		var adminRole models.Role
+		if err := tx.Where(models.WhereNameIs, "admin").First(&adminRole).Error; err != nil {
+			return err
+		}
+
+		user.RoleID = adminRole.ID
+
+		return tx.Create(user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetPowerDNS renders the PowerDNS connection step, filled with the saved or
+// bootstrapped settings.
+func (s *Service) GetPowerDNS(c fiber.Ctx) error {
+	settings := &pdnsserver.Settings{}
+	if err := settings.Load(s.db); err != nil {
+		settings = &pdnsserver.Settings{VHost: defaultVHost}
+	}
+
+	return s.render(c, fiber.StatusOK, stepPowerDNS, fiber.Map{"Settings": settings})
+}
+
+// PostPowerDNS tests and saves the PowerDNS connection, then continues with
+// the login methods. A failed test keeps the form open unless the form field
+// force is set.
+func (s *Service) PostPowerDNS(c fiber.Ctx) error {
+	settings, err := s.parsePowerDNS(c)
+	if err != nil {
+		return s.render(c, fiber.StatusBadRequest, stepPowerDNS, fiber.Map{"Settings": settings, "Error": err.Error()})
+	}
+
+	if _, err = powerdns.Probe(c.Context(), settings); err != nil && c.FormValue("force") == "" {
+		return s.render(c, fiber.StatusBadRequest, stepPowerDNS, fiber.Map{
+			"Settings":   settings,
+			"Error":      "PowerDNS did not answer: " + err.Error(),
+			"OfferForce": true,
+		})
+	}
+
+	if err = settings.Save(s.db); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("setup: failed to save PowerDNS server settings")
+
+		return s.render(c, fiber.StatusInternalServerError, stepPowerDNS, fiber.Map{
+			"Settings": settings,
+			"Error":    "Failed to save the settings",
+		})
+	}
+
+	if err = powerdns.Open(s.db); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("setup: failed to initialize PowerDNS engine")
+	}
+
+	// The zones of a previously configured server must not be served any longer.
+	zoneindex.Default.Invalidate()
+
+	requestid.Logger(c.Context()).Info().Str("api_server_url", settings.APIServerURL).
+		Msg("setup: PowerDNS server settings saved")
+
+	return c.Redirect().To(AuthPath + "?success=" + url.QueryEscape("PowerDNS connection saved."))
+}
+
+// TestPowerDNS tests the PowerDNS connection settings of the form without
+// saving them.
+func (s *Service) TestPowerDNS(c fiber.Ctx) error {
+	settings, err := s.parsePowerDNS(c)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, err.Error(), nil)
+	}
+
+	server, err := powerdns.Probe(c.Context(), settings)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadGateway, handler.CodeUpstream, err.Error(), nil)
+	}
+
+	result := fiber.Map{"ok": true}
+	if server.DaemonType != nil {
+		result["daemon_type"] = *server.DaemonType
+	}
+
+	if server.Version != nil {
+		result["version"] = *server.Version
+	}
+
+	return c.JSON(result)
+}
+
+// parsePowerDNS reads and validates the PowerDNS connection form. On error
+// the parsed settings are returned as well so the form can be re-rendered.
+func (s *Service) parsePowerDNS(c fiber.Ctx) (*pdnsserver.Settings, error) {
+	settings := &pdnsserver.Settings{}
+	if err := c.Bind().Body(settings); err != nil {
+		return settings, errInvalidPDNS
+	}
+
+	settings.APIServerURL = strings.TrimSpace(settings.APIServerURL)
+	settings.VHost = strings.TrimSpace(settings.VHost)
+
+	if err := s.validator.Struct(settings); err != nil {
+		return settings, errInvalidPDNS
+	}
+
+	return settings, nil
+}
+
+// GetAuth renders the login methods step.
+func (s *Service) GetAuth(c fiber.Ctx) error {
+	return s.render(c, fiber.StatusOK, stepAuth, fiber.Map{
+		"Success":   c.Query("success"),
+		"Effective": s.appSettings.Settings(),
+	})
+}
+
+// PostAuth saves the login methods and completes setup. Settings that match
+// the config files are not stored as overrides.
+func (s *Service) PostAuth(c fiber.Ctx) error {
+	localLogin := c.FormValue("local_login") == "on"
+	passwordReset := c.FormValue("password_reset") == "on"
+
+	providers := authsettings.Current(s.cfg)
+
+	if !localLogin && !providers.LDAP.Enabled && !providers.OIDC.Enabled {
+		effective := s.appSettings.Settings()
+		effective.PasswordReset = passwordReset
+
+		return s.render(c, fiber.StatusBadRequest, stepAuth, fiber.Map{
+			"Effective": effective,
+			"Error":     errNoLoginMethod.Error(),
+		})
+	}
+
+	o := s.appSettings.Overrides()
+	base := s.appSettings.Base()
+	o.LocalLogin = override(base.LocalLogin, localLogin)
+	o.PasswordReset = override(base.PasswordReset, passwordReset)
+
+	if err := o.Save(s.db); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("setup: failed to save login methods")
+
+		return s.render(c, fiber.StatusInternalServerError, stepAuth, fiber.Map{
+			"Effective": s.appSettings.Settings(),
+			"Error":     "Failed to save the login methods",
+		})
+	}
+
+	if err := s.appSettings.Reload(); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("setup: failed to reload application settings")
+	}
+
+	if err := s.store.Complete(); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("setup: failed to complete setup")
+
+		return s.render(c, fiber.StatusInternalServerError, stepAuth, fiber.Map{
+			"Effective": s.appSettings.Settings(),
+			"Error":     "Failed to complete setup",
+		})
+	}
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		Action:       activitylog.ActionSetupCompleted,
+		ResourceType: activitylog.ResourceTypeSystem,
+		ResourceName: controller.SettingKey,
+		Details: map[string]any{
+			"local_login":    localLogin,
+			"password_reset": passwordReset,
+			"ldap":           providers.LDAP.Enabled,
+			"oidc":           providers.OIDC.Enabled,
+		},
+	}))
+
+	requestid.Logger(c.Context()).Info().Msg("setup: completed")
+
+	return c.Redirect().To(handler.DashboardPath + "?success=" + url.QueryEscape("Setup complete. Welcome!"))
+}
+
+// override returns nil when value matches the config file, so that the
+// setting keeps following it, and an override of value otherwise.
+func override(base, value bool) *bool {
+	if base == value {
+		return nil
+	}
+
+	return &value
+}
+
+// isFormError reports whether err is a mistake in the account form.
+func isFormError(err error) bool {
+	return errors.Is(err, errInvalidToken) ||
+		errors.Is(err, errInvalidAccount) ||
+		errors.Is(err, errPasswordPolicy) ||
+		errors.Is(err, errPasswordMatch)
+}
+
+// render renders step of the wizard, merging any extra keys (e.g. Error).
+func (s *Service) render(c fiber.Ctx, status int, step string, extra fiber.Map) error {
+	data := fiber.Map{
+		"Step":                 step,
+		"Version":              version.Get(),
+		"LDAPEnabled":          authsettings.Current(s.cfg).LDAP.Enabled,
+		"OIDCEnabled":          authsettings.Current(s.cfg).OIDC.Enabled,
+		"MailEnabled":          s.cfg.Mail.Enabled(),
+		"PasswordRequirements": s.localAuth.PasswordRequirements(),
+		"PasswordMinLength":    s.localAuth.PasswordPolicy().MinLength,
+		"PowerDNSPath":         PowerDNSPath,
+		"PowerDNSTest":         PowerDNSTestPath,
+		"AuthPath":             AuthPath,
+	}
+
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	return c.Status(status).Render(TemplateName, data)
+}
@@ -0,0 +1,109 @@
+package setup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func newTestService(t *testing.T) (*Service, string) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Setting{}, &models.Role{}, &models.User{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if err = db.Create(&models.Role{Name: "admin"}).Error; err != nil {
+		t.Fatalf("failed to create admin role: %v", err)
+	}
+
+	store, err := controller.NewStore(db)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	token, err := store.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	localAuth := auth.NewLocalProvider(db)
+	localAuth.SetPasswordPolicy(config.PasswordPolicy{MinLength: config.DefaultPasswordMinLength})
+
+	return &Service{db: db, store: store, localAuth: localAuth, validator: validator.New()}, token
+}
+
+func TestCreateAdmin(t *testing.T) {
+	s, token := newTestService(t)
+
+	valid := func() *Account {
+		return &Account{
+			Token:    token,
+			Username: "alice",
+			Email:    "alice@example.com",
+			Password: "correct horse",
+			Confirm:  "correct horse",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Account)
+		want   error
+	}{
+		{"wrong token", func(a *Account) { a.Token = "guess" }, errInvalidToken},
+		{"missing email", func(a *Account) { a.Email = "" }, errInvalidAccount},
+		{"short password", func(a *Account) { a.Password, a.Confirm = "short", "short" }, errPasswordPolicy},
+		{"mismatch", func(a *Account) { a.Confirm = "battery staple" }, errPasswordMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := valid()
+			tt.modify(in)
+
+			if _, err := s.createAdmin(in); !errors.Is(err, tt.want) {
+				t.Fatalf("createAdmin() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	user, err := s.createAdmin(valid())
+	if err != nil {
+		t.Fatalf("createAdmin() error = %v", err)
+	}
+
+	if user.AuthSource != models.AuthSourceLocal || !user.Active || !user.VerifyPassword("correct horse") {
+		t.Errorf("createAdmin() = %+v, want an active local user with the chosen password", user)
+	}
+
+	second := valid()
+	second.Username, second.Email = "mallory", "mallory@example.com"
+
+	if _, err := s.createAdmin(second); !errors.Is(err, errAccountExists) {
+		t.Fatalf("second createAdmin() error = %v, want %v", err, errAccountExists)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	if got := override(true, true); got != nil {
+		t.Errorf("override(true, true) = %v, want nil", *got)
+	}
+
+	if got := override(true, false); got == nil || *got {
+		t.Errorf("override(true, false) = %v, want false", got)
+	}
+}
@@ -0,0 +1,282 @@
+// Package querytool provides the DNS query tester, a dig-like page and API
+// endpoint that sends a single query to PowerDNS or another server and shows
+// the full response.
+package querytool
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/dns/dnsmessage"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path of the query tester page.
+	Path = handler.RootPath + "tools/query"
+
+	// APIPath is the path of the query endpoint.
+	APIPath = handler.APIPathPrefix + "tools/query"
+
+	// ServerAuthoritative selects the PowerDNS server as query target.
+	ServerAuthoritative = "authoritative"
+
+	templateQuery = "tools/query"
+
+	labelQuery = "DNS Query"
+)
+
+var (
+	errNameRequired    = errors.New("name is required")
+	errNoAuthoritative = errors.New("the PowerDNS server address is unknown; set dnscheck.authoritative")
+)
+
+// QueryRequest is the JSON body of the query endpoint.
+type QueryRequest struct {
+	Name string `json:"name"`
+	// Type is a record type mnemonic such as AAAA or TYPE65; default A.
+	Type string `json:"type"`
+	// Server is ServerAuthoritative (the default) or a host[:port].
+	Server    string `json:"server"`
+	Recursion bool   `json:"recursion"`
+	DNSSEC    bool   `json:"dnssec"`
+}
+
+// Record is a resource record of a response section.
+type Record struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Class string `json:"class"`
+	TTL   uint32 `json:"ttl"`
+	Data  string `json:"data"`
+}
+
+// Question is the question of a response.
+type Question struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// QueryResponse is the response of the queried server.
+type QueryResponse struct {
+	Success  bool   `json:"success"`
+	Server   string `json:"server"`
+	Protocol string `json:"protocol"`
+	// RTT is the round-trip time in milliseconds.
+	RTT        float64    `json:"rtt_ms"`
+	Size       int        `json:"size"`
+	ID         uint16     `json:"id"`
+	RCode      string     `json:"rcode"`
+	Flags      []string   `json:"flags"`
+	Question   []Question `json:"question"`
+	Answer     []Record   `json:"answer"`
+	Authority  []Record   `json:"authority"`
+	Additional []Record   `json:"additional"`
+}
+
+// Service handles the query tester.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermToolsQuery), s.Page)
+	app.Post(APIPath, auth.RequirePermission(authService, auth.PermToolsQuery), s.Query)
+}
+
+// Page renders the query form; the page sends queries to the API endpoint.
+// ?name= and ?type= prefill the form.
+func (s *Service) Page(c fiber.Ctx) error {
+	nav := navigation.NewContext(labelQuery, "tools", "query").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelQuery, Path, true)
+
+	return c.Render(templateQuery, fiber.Map{
+		"Navigation":    nav,
+		"Name":          c.Query("name"),
+		"Type":          c.Query("type", "A"),
+		"Authoritative": s.authoritative(),
+		"Resolvers":     s.cfg.DNSCheck.Resolvers,
+	}, handler.BaseLayout)
+}
+
+// Query sends the query of the request body and returns the response.
+func (s *Service) Query(c fiber.Ctx) error {
+	var request QueryRequest
+	if err := c.Bind().Body(&request); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to parse DNS query request")
+
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid request data", nil)
+	}
+
+	query, server, err := s.parse(&request)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, err.Error(), nil)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), s.cfg.DNSCheck.Timeout)
+	defer cancel()
+
+	resp, err := dnsclient.Exchange(ctx, server, query)
+	if err != nil {
+		requestid.Logger(c.Context()).Warn().Err(err).Str("server", server).Str("name", query.Name).
+			Msg("DNS query failed")
+
+		return handler.JSONError(c, fiber.StatusBadGateway, handler.CodeUpstream,
+			"Query to "+server+" failed: "+err.Error(), fiber.Map{"server": server})
+	}
+
+	return c.JSON(newQueryResponse(resp))
+}
+
+// parse validates request and returns the query and the server to send it to.
+func (s *Service) parse(request *QueryRequest) (dnsclient.Query, string, error) {
+	query := dnsclient.Query{
+		Name:      strings.TrimSpace(request.Name),
+		Type:      dnsmessage.TypeA,
+		Recursion: request.Recursion,
+		DNSSEC:    request.DNSSEC,
+	}
+
+	if query.Name == "" {
+		return query, "", errNameRequired
+	}
+
+	if request.Type != "" {
+		t, err := dnsclient.ParseType(request.Type)
+		if err != nil {
+			return query, "", err
+		}
+
+		query.Type = t
+	}
+
+	if request.Server == "" || request.Server == ServerAuthoritative {
+		server := s.authoritative()
+		if server == "" {
+			return query, "", errNoAuthoritative
+		}
+
+		return query, server, nil
+	}
+
+	server, err := dnsclient.Server(request.Server)
+
+	return query, server, err
+}
+
+// authoritative returns the address of PowerDNS: dnscheck.authoritative, or
+// the host of the PowerDNS API on port 53.
+func (s *Service) authoritative() string {
+	if s.cfg.DNSCheck.Authoritative != "" {
+		return s.cfg.DNSCheck.Authoritative
+	}
+
+	return powerdns.Engine.DNSServer()
+}
+
+// newQueryResponse converts resp for the JSON response.
+func newQueryResponse(resp *dnsclient.Response) QueryResponse {
+	msg := &resp.Message
+
+	out := QueryResponse{
+		Success:    true,
+		Server:     resp.Server,
+		Protocol:   resp.Protocol,
+		RTT:        float64(resp.RTT.Microseconds()) / 1000,
+		Size:       resp.Size,
+		ID:         msg.ID,
+		RCode:      dnsclient.RCodeName(msg.RCode),
+		Flags:      flags(&msg.Header),
+		Question:   make([]Question, 0, len(msg.Questions)),
+		Answer:     records(msg.Answers),
+		Authority:  records(msg.Authorities),
+		Additional: records(msg.Additionals),
+	}
+
+	for _, q := range msg.Questions {
+		out.Question = append(out.Question, Question{Name: q.Name.String(), Type: dnsclient.TypeName(q.Type)})
+	}
+
+	return out
+}
+
+// flags returns the set header flags in dig notation.
+func flags(h *dnsmessage.Header) []string {
+	out := []string{}
+
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{h.Response, "qr"},
+		{h.Authoritative, "aa"},
+		{h.Truncated, "tc"},
+		{h.RecursionDesired, "rd"},
+		{h.RecursionAvailable, "ra"},
+		{h.AuthenticData, "ad"},
+		{h.CheckingDisabled, "cd"},
+	} {
+		if f.set {
+			out = append(out, f.name)
+		}
+	}
+
+	return out
+}
+
+// records converts the resources of a section. OPT records carry the EDNS
+// payload size and flags in the class and TTL, which their data shows
+// instead.
+func records(rrs []dnsmessage.Resource) []Record {
+	out := make([]Record, 0, len(rrs))
+
+	for i := range rrs {
+		rr := &rrs[i]
+		record := Record{
+			Name: rr.Header.Name.String(),
+			Type: dnsclient.TypeName(rr.Header.Type),
+			Data: dnsclient.Data(rr),
+		}
+
+		switch {
+		case rr.Header.Type == dnsmessage.TypeOPT:
+		case rr.Header.Class == dnsmessage.ClassINET:
+			record.Class, record.TTL = "IN", rr.Header.TTL
+		default:
+			record.Class, record.TTL = strings.TrimPrefix(rr.Header.Class.String(), "Class"), rr.Header.TTL
+		}
+
+		out = append(out, record)
+	}
+
+	return out
+}
@@ -0,0 +1,87 @@
+package querytool
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+)
+
+func TestParse(t *testing.T) {
+	s := &Service{cfg: &config.Config{DNSCheck: config.DNSCheck{Authoritative: "192.0.2.53:53"}}}
+
+	tests := []struct {
+		request    QueryRequest
+		wantServer string
+		wantType   dnsmessage.Type
+		wantErr    bool
+	}{
+		{QueryRequest{Name: "example.com"}, "192.0.2.53:53", dnsmessage.TypeA, false},
+		{QueryRequest{Name: "example.com", Type: "mx", Server: "9.9.9.9"}, "9.9.9.9:53", dnsmessage.TypeMX, false},
+		{QueryRequest{Name: "example.com", Server: ServerAuthoritative, Type: "TYPE65"}, "192.0.2.53:53", 65, false},
+		{QueryRequest{Name: " "}, "", 0, true},
+		{QueryRequest{Name: "example.com", Type: "BOGUS"}, "", 0, true},
+		{QueryRequest{Name: "example.com", Server: ":53"}, "", 0, true},
+	}
+
+	for _, tc := range tests {
+		query, server, err := s.parse(&tc.request)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parse(%+v) error = %v, want error %v", tc.request, err, tc.wantErr)
+			continue
+		}
+
+		if !tc.wantErr && (server != tc.wantServer || query.Type != tc.wantType) {
+			t.Errorf("parse(%+v) = %v %q, want %v %q", tc.request, query.Type, server, tc.wantType, tc.wantServer)
+		}
+	}
+}
+
+func TestNewQueryResponse(t *testing.T) {
+	name := dnsmessage.MustNewName("example.com.")
+
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(1232, dnsmessage.RCodeSuccess, true); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := &dnsclient.Response{
+		Server: "192.0.2.53:53", Protocol: "udp", RTT: 1500 * time.Microsecond, Size: 80,
+		Message: dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 7, Response: true, Authoritative: true, RCode: dnsmessage.RCodeNameError},
+			Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+			Authorities: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600},
+				Body: &dnsmessage.SOAResource{
+					NS: dnsmessage.MustNewName("ns1.example.com."), MBox: dnsmessage.MustNewName("hostmaster.example.com."),
+					Serial: 1, Refresh: 2, Retry: 3, Expire: 4, MinTTL: 5,
+				},
+			}},
+			Additionals: []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}},
+		},
+	}
+
+	got := newQueryResponse(resp)
+
+	if got.RCode != "NXDOMAIN" || got.RTT != 1.5 || !reflect.DeepEqual(got.Flags, []string{"qr", "aa"}) {
+		t.Errorf("header = %s %v %v", got.RCode, got.RTT, got.Flags)
+	}
+
+	wantSOA := Record{Name: "example.com.", Type: "SOA", Class: "IN", TTL: 3600,
+		Data: "ns1.example.com. hostmaster.example.com. 1 2 3 4 5"}
+	if len(got.Authority) != 1 || got.Authority[0] != wantSOA {
+		t.Errorf("authority = %+v, want %+v", got.Authority, wantSOA)
+	}
+
+	if len(got.Additional) != 1 || got.Additional[0].Class != "" || got.Additional[0].Data != "udp=1232 do=true" {
+		t.Errorf("additional = %+v", got.Additional)
+	}
+
+	if len(got.Answer) != 0 || got.Answer == nil {
+		t.Errorf("answer = %#v, want empty slice", got.Answer)
+	}
+}
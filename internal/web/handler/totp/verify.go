@@ -2,12 +2,15 @@
 package totp
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v3"
 	"github.com/pquerna/otp/totp"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/loginhistory"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
@@ -19,6 +22,9 @@ const (
 	VerifyTemplate = "totp/verify"
 )
 
+// errInvalidCode is recorded in the login history for a wrong code.
+var errInvalidCode = errors.New("invalid TOTP code")
+
 // Service handles TOTP verification.
 type Service struct {
 	handler.Service
@@ -63,6 +69,10 @@ func (s *Service) Post(c fiber.Ctx) error {
 	if !totp.Validate(form.Code, sessData.User.TOTPSecret) {
 		log.Warn().Uint64("user_id", sessData.User.ID).Msg("invalid TOTP code")
 
+		attempt := loginhistory.FromRequest(c, s.db).Failed(sessData.User.Username, loginhistory.ProviderTOTP, errInvalidCode)
+		attempt.UserID = &sessData.User.ID
+		loginhistory.Record(attempt)
+
 		return c.Status(fiber.StatusUnauthorized).Render(VerifyTemplate, fiber.Map{
 			"Error": "Invalid code. Please try again.",
 		})
@@ -70,7 +80,7 @@ func (s *Service) Post(c fiber.Ctx) error {
 
 	// Upgrade session: clear pending flag
 	sessData.TOTPPending = false
-	if err := sessData.Write(sessionID, s.cfg.Webserver.Session.ExpiryTime); err != nil {
+	if err := sessData.Write(sessionID); err != nil {
 		log.Error().Err(err).Msg("failed to upgrade session after TOTP")
 		return c.Redirect().To("/login")
 	}
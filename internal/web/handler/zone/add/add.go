@@ -4,21 +4,23 @@ package zoneadd
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
-	pdnsapi "github.com/joeig/go-powerdns/v3"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
-	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
 )
 
 const (
@@ -37,9 +39,11 @@ const (
 // Service is the add zone handler service.
 type Service struct {
 	handler.Service
-	cfg       *config.Config
-	db        *gorm.DB
-	validator *validator.Validate
+	cfg         *config.Config
+	db          *gorm.DB
+	validator   *validator.Validate
+	authService *auth.Service
+	batches     batchStore
 }
 
 // Handler is the add zone handler.
@@ -55,6 +59,7 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 	s.db = db
 	s.cfg = cfg
 	s.validator = validator.New()
+	s.authService = authService
 
 	// register routes with permission checks
 	app.Get(Path,
@@ -65,6 +70,30 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 		auth.RequirePermission(authService, auth.PermZoneCreate),
 		s.Post,
 	)
+	app.Get(PathReverse,
+		auth.RequirePermission(authService, auth.PermZoneCreate),
+		s.GetReverse,
+	)
+	app.Post(PathReverse,
+		auth.RequirePermission(authService, auth.PermZoneCreate),
+		s.PostReverse,
+	)
+	app.Get(PathBatch,
+		auth.RequirePermission(authService, auth.PermZoneCreate),
+		s.GetBatch,
+	)
+	app.Post(PathBatch,
+		auth.RequirePermission(authService, auth.PermZoneCreate),
+		s.PostBatch,
+	)
+	app.Get(PathBatch+"/:id",
+		auth.RequirePermission(authService, auth.PermZoneCreate),
+		s.GetBatchProgress,
+	)
+	app.Get(PathBatch+"/:id/status",
+		auth.RequirePermission(authService, auth.PermZoneCreate),
+		s.GetBatchStatus,
+	)
 }
 
 // Get handles the add zone page rendering.
@@ -79,6 +108,7 @@ func (s *Service) Get(c fiber.Ctx) error {
 	return c.Render(TemplateName, fiber.Map{
 		"Navigation": nav,
 		"Form":       &ZoneForm{},
+		"NSSets":     s.nameserverSets(c),
 	}, handler.BaseLayout)
 }
 
@@ -90,14 +120,17 @@ func (s *Service) Post(c fiber.Ctx) error {
 		AddBreadcrumb("Dashboard", dashboard.Path, false).
 		AddBreadcrumb(PageTitle, Path, true)
 
+	sets := s.nameserverSets(c)
+
 	// Parse form data
 	form := &ZoneForm{}
 	if err := c.Bind().Body(form); err != nil {
-		log.Error().Err(err).Msg("failed to parse add zone form")
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to parse add zone form")
 
 		return c.Status(fiber.StatusBadRequest).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
 			"Form":       form,
+			"NSSets":     sets,
 			"Error":      "Invalid form data",
 		}, handler.BaseLayout)
 	}
@@ -112,6 +145,7 @@ func (s *Service) Post(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
 			"Form":       form,
+			"NSSets":     sets,
 			"Error":      err.Error(),
 		}, handler.BaseLayout)
 	}
@@ -126,34 +160,64 @@ func (s *Service) Post(c fiber.Ctx) error {
 			errorMessages[i] = "Field '" + ve.Field() + "' failed validation tag '" + ve.Tag() + "'"
 		}
 
-		log.Error().Err(err).Msg("validation failed for add zone")
+		requestid.Logger(c.Context()).Error().Err(err).Msg("validation failed for add zone")
 
 		return c.Status(fiber.StatusBadRequest).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
 			"Form":       form,
+			"NSSets":     sets,
 			"Error":      errorMessages,
 		}, handler.BaseLayout)
 	}
 
-	// Create zone via PowerDNS API
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
+	// A primary zone of a nameserver set gets the nameservers of the set.
+	if form.NameserverSet != 0 && form.Kind != ZoneKindSlave {
+		i := slices.IndexFunc(sets, func(set models.NameserverSet) bool { return set.ID == form.NameserverSet })
+		if i < 0 {
+			return c.Status(fiber.StatusBadRequest).Render(TemplateName, fiber.Map{
+				"Navigation": nav,
+				"Form":       form,
+				"NSSets":     sets,
+				"Error":      "The nameserver set no longer exists.",
+			}, handler.BaseLayout)
+		}
 
-	if err := createZone(ctx, form); err != nil {
-		var pdnsErr *pdnsapi.Error
+		form.Nameservers = sets[i].NameserverList()
+	}
 
-		isConflict := (errors.As(err, &pdnsErr) && pdnsErr.StatusCode == fiber.StatusConflict) ||
-			err.Error() == "Conflict"
+	// Zones of a tenant count against its zone quota.
+	user, _ := c.Locals("CurrentUser").(models.User)
+	if err := tenant.CheckZones(s.db, user.TenantID, 1); err != nil {
+		status := fiber.StatusForbidden
+		if !errors.Is(err, tenant.ErrQuota) {
+			requestid.Logger(c.Context()).Error().Err(err).Msg("failed to check the zone quota")
 
-		if isConflict {
+			status = fiber.StatusInternalServerError
+		}
+
+		return c.Status(status).Render(TemplateName, fiber.Map{
+			"Navigation": nav,
+			"Form":       form,
+			"NSSets":     sets,
+			"Error":      "Cannot create the zone: " + err.Error(),
+		}, handler.BaseLayout)
+	}
+
+	// Create zone via PowerDNS API
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	if err := CreateZone(ctx, form); err != nil {
+		if IsConflict(err) {
 			return c.Status(fiber.StatusConflict).Render(TemplateName, fiber.Map{
 				"Navigation":   nav,
 				"Form":         form,
+				"NSSets":       sets,
 				"ConflictZone": form.Name,
 			}, handler.BaseLayout)
 		}
 
-		log.Error().
+		requestid.Logger(c.Context()).Error().
 			Err(err).
 			Str("zone_name", form.Name).
 			Str("zone_kind", string(form.Kind)).
@@ -162,42 +226,37 @@ func (s *Service) Post(c fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
 			"Form":       form,
+			"NSSets":     sets,
 			"Error":      "Failed to create zone: " + err.Error(),
 		}, handler.BaseLayout)
 	}
 
-	log.Info().
+	requestid.Logger(c.Context()).Info().
 		Str("zone_name", form.Name).
 		Str("zone_kind", string(form.Kind)).
 		Str("soa_edit_api", string(form.SOAEditAPI)).
 		Msg("Zone created successfully")
 
-	// Record activity: zone created
-	var (
-		userID   *uint64
-		username string
-	)
-
-	if sid := c.Cookies("session"); sid != "" {
-		sd := new(session.Data)
-		if err := sd.Read(sid); err == nil && sd.User.ID > 0 {
-			id := sd.User.ID
-			userID = &id
-			username = sd.User.Username
-		}
+	if err := tenant.AssignCreated(s.db, form.Name, &user); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", form.Name).Msg("failed to assign zone to tenant")
 	}
 
-	activitylog.Record(
-		&activitylog.Entry{
-			DB: s.db, UserID: userID,
-			Username:     username,
-			Action:       activitylog.ActionZoneCreated,
-			ResourceType: activitylog.ResourceTypeZone,
-			ResourceName: form.Name,
-			Details:      map[string]any{"kind": string(form.Kind), "soa_edit_api": string(form.SOAEditAPI)},
-			IPAddress:    c.IP(),
-		},
-	)
+	// Record activity: zone created
+	userID, username := auth.Actor(c)
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB: s.db, UserID: userID,
+		Username:     username,
+		Action:       activitylog.ActionZoneCreated,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: form.Name,
+		Details:      map[string]any{"kind": string(form.Kind), "soa_edit_api": string(form.SOAEditAPI)},
+		IPAddress:    c.IP(),
+	}))
+
+	if len(form.Nameservers) > 0 && form.NameserverSet != 0 {
+		s.assignNameserverSet(ctx, c, form.Name, form.NameserverSet)
+	}
 
 	// Redirect to the dashboard with a success message
 	return c.Redirect().To(dashboard.Path + "?success=Zone created successfully")
@@ -0,0 +1,682 @@
+package zoneadd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathBatch is the path of the bulk add page.
+	PathBatch = Path + "/batch"
+
+	// TemplateBatch is the template of the bulk add page.
+	TemplateBatch = "zone/batch"
+
+	// TemplateBatchProgress is the template showing the progress of a batch.
+	TemplateBatchProgress = "zone/batch-progress"
+
+	// PageTitleBatch is the title of the bulk add page.
+	PageTitleBatch = "Add Zones in Bulk"
+
+	// maxBatchZones caps the zones of one batch.
+	maxBatchZones = 500
+
+	// batchWorkers is the number of zones created concurrently.
+	batchWorkers = 4
+
+	// batchRetention is how long a finished batch can still be viewed.
+	batchRetention = time.Hour
+
+	// maxBatchErrors caps the list errors shown at once.
+	maxBatchErrors = 10
+)
+
+// BatchForm is the submitted bulk add form. Zones holds one zone per line as
+// "name[,kind[,template]]"; the other fields are the defaults of all lines.
+type BatchForm struct {
+	Zones       string     `form:"zones"`
+	Kind        ZoneKind   `form:"kind"`
+	SOAEditAPI  SOAEditAPI `form:"soa_edit_api"`
+	Masters     string     `form:"masters"`
+	Nameservers string     `form:"nameservers"`
+	Template    string     `form:"template"`
+}
+
+// BatchItem is a zone of a batch and the outcome of creating it.
+type BatchItem struct {
+	Line int
+	Name string
+	Kind ZoneKind
+	// Template is an existing zone whose records are copied into the new zone.
+	Template string
+	// Status is pending, created, exists or failed.
+	Status  string
+	Message string
+}
+
+// BatchProgress is a snapshot of a batch, served to the progress page.
+type BatchProgress struct {
+	Total    int
+	Done     int
+	Created  int
+	Exists   int
+	Failed   int
+	Finished bool
+	Items    []BatchItem
+}
+
+// Batch is a list of zones created in the background.
+type Batch struct {
+	ID     string
+	UserID uint64
+	// TenantID is the tenant of the user; the zones created belong to it.
+	TenantID *uint
+
+	// actor attributes the zones created in the activity log.
+	actor       activitylog.Entry
+	soaEditAPI  SOAEditAPI
+	masters     string
+	nameservers []string
+
+	mu       sync.Mutex
+	items    []BatchItem
+	finished bool
+	ended    time.Time
+}
+
+// Progress returns a snapshot of the batch.
+func (b *Batch) Progress() BatchProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p := BatchProgress{Total: len(b.items), Finished: b.finished, Items: make([]BatchItem, len(b.items))}
+	copy(p.Items, b.items)
+
+	for i := range b.items {
+		switch b.items[i].Status {
+		case statusCreated:
+			p.Created++
+		case statusExists:
+			p.Exists++
+		case statusFailed:
+			p.Failed++
+		default:
+			continue
+		}
+
+		p.Done++
+	}
+
+	return p
+}
+
+func (b *Batch) item(i int) BatchItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.items[i]
+}
+
+func (b *Batch) settle(i int, status, msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.items[i].Status = status
+	b.items[i].Message = msg
+}
+
+func (b *Batch) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.finished = true
+	b.ended = time.Now()
+}
+
+// expired reports whether the batch finished more than batchRetention ago.
+func (b *Batch) expired(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.finished && now.Sub(b.ended) > batchRetention
+}
+
+// batchStore keeps the batches of this instance in memory; its zero value is
+// ready to use.
+type batchStore struct {
+	mu      sync.Mutex
+	batches map[string]*Batch
+}
+
+// add stores b and drops expired batches.
+func (st *batchStore) add(b *Batch) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.batches == nil {
+		st.batches = make(map[string]*Batch)
+	}
+
+	now := time.Now()
+	for id, other := range st.batches {
+		if other.expired(now) {
+			delete(st.batches, id)
+		}
+	}
+
+	st.batches[b.ID] = b
+}
+
+func (st *batchStore) get(id string) *Batch {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.batches[id]
+}
+
+// GetBatch renders the bulk add form.
+func (s *Service) GetBatch(c fiber.Ctx) error {
+	form := &BatchForm{
+		Kind:        ZoneKindNative,
+		SOAEditAPI:  SOAEditAPIDefault,
+		Nameservers: strings.Join(s.cfg.ZoneRequest.Nameservers, "\n"),
+	}
+
+	return s.renderBatch(c, fiber.StatusOK, form, nil)
+}
+
+// PostBatch validates the list of zones and starts creating them in the
+// background, then redirects to the progress page of the batch.
+func (s *Service) PostBatch(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok {
+		return c.Redirect().To(handler.RootPath)
+	}
+
+	form := &BatchForm{}
+	if err := c.Bind().Body(form); err != nil {
+		return s.renderBatch(c, fiber.StatusBadRequest, form, []string{"Invalid form data"})
+	}
+
+	items, nameservers, errs := parseBatch(form)
+	if len(errs) > 0 {
+		return s.renderBatch(c, fiber.StatusBadRequest, form, errs)
+	}
+
+	if errs = s.checkTemplates(c, items); len(errs) > 0 {
+		return s.renderBatch(c, fiber.StatusForbidden, form, errs)
+	}
+
+	if powerdns.Engine.Client == nil {
+		return s.renderBatch(c, fiber.StatusServiceUnavailable, form, []string{powerdns.ErrMsgClientNotInitialized})
+	}
+
+	batch := &Batch{
+		ID:          newBatchID(),
+		UserID:      user.ID,
+		TenantID:    user.TenantID,
+		actor:       *auth.Attribute(c, &activitylog.Entry{}),
+		soaEditAPI:  form.SOAEditAPI,
+		masters:     form.Masters,
+		nameservers: nameservers,
+		items:       items,
+	}
+	s.batches.add(batch)
+
+	jobs.Go(jobs.ZoneBatch, func() error {
+		return s.runBatch(context.Background(), batch)
+	})
+
+	return c.Redirect().To(PathBatch + "/" + batch.ID)
+}
+
+// GetBatchProgress renders the progress page of a batch.
+func (s *Service) GetBatchProgress(c fiber.Ctx) error {
+	batch := s.ownBatch(c)
+	if batch == nil {
+		return handler.RenderError(c, fiber.StatusNotFound, "Batch not found",
+			"The batch does not exist or has expired.", nil)
+	}
+
+	nav := navigation.NewContext(PageTitleBatch, "zones", "add").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(PageTitle, Path, false).
+		AddBreadcrumb(PageTitleBatch, PathBatch, true)
+
+	progressJSON, err := json.Marshal(batch.Progress())
+	if err != nil {
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Batch unavailable", err.Error(), nil)
+	}
+
+	return c.Render(TemplateBatchProgress, fiber.Map{
+		"Navigation":   nav,
+		"Batch":        batch,
+		"ProgressJSON": template.JS(progressJSON), //nolint:gosec // safe: json.Marshal escapes HTML chars
+	}, handler.BaseLayout)
+}
+
+// GetBatchStatus returns the progress of a batch as JSON for polling.
+func (s *Service) GetBatchStatus(c fiber.Ctx) error {
+	batch := s.ownBatch(c)
+	if batch == nil {
+		return handler.JSONError(c, fiber.StatusNotFound, handler.CodeNotFound, "batch not found", nil)
+	}
+
+	return c.JSON(batch.Progress())
+}
+
+// ownBatch returns the batch of the :id parameter if the current user
+// started it.
+func (s *Service) ownBatch(c fiber.Ctx) *Batch {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok {
+		return nil
+	}
+
+	batch := s.batches.get(c.Params("id"))
+	if batch == nil || batch.UserID != user.ID {
+		return nil
+	}
+
+	return batch
+}
+
+// checkTemplates returns an error for every template zone the request cannot
+// access.
+func (s *Service) checkTemplates(c fiber.Ctx, items []BatchItem) []string {
+	allowed, err := auth.ZoneFilter(c, s.authService)
+	if err != nil {
+		return []string{"Failed to check access to the template zones."}
+	}
+
+	if allowed == nil {
+		return nil
+	}
+
+	var errs []string
+
+	seen := make(map[string]bool)
+
+	for i := range items {
+		tpl := items[i].Template
+		if tpl == "" || seen[tpl] {
+			continue
+		}
+
+		seen[tpl] = true
+
+		if !allowed(tpl) {
+			errs = append(errs, fmt.Sprintf("You do not have access to the template zone %s.", tpl))
+		}
+	}
+
+	return errs
+}
+
+// runBatch creates the zones of batch with batchWorkers workers. It returns
+// an error if any zone failed.
+func (s *Service) runBatch(ctx context.Context, batch *Batch) error {
+	defer batch.finish()
+
+	templates := loadTemplates(ctx, batch)
+
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range batchWorkers {
+		wg.Go(func() {
+			for i := range next {
+				s.createBatchZone(ctx, batch, i, templates)
+			}
+		})
+	}
+
+	for i := range batch.items {
+		next <- i
+	}
+
+	close(next)
+	wg.Wait()
+
+	if p := batch.Progress(); p.Failed > 0 {
+		return fmt.Errorf("%d of %d zones failed", p.Failed, p.Total)
+	}
+
+	return nil
+}
+
+// templateZone holds the records of a template zone, or why they could not be
+// loaded.
+type templateZone struct {
+	rrsets []pdnsapi.RRset
+	err    error
+}
+
+// loadTemplates fetches every template zone of batch once.
+func loadTemplates(ctx context.Context, batch *Batch) map[string]templateZone {
+	templates := make(map[string]templateZone)
+
+	for i := range batch.items {
+		name := batch.items[i].Template
+		if _, ok := templates[name]; ok || name == "" {
+			continue
+		}
+
+		tctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+
+		var tz templateZone
+
+		if powerdns.Engine.Client == nil {
+			tz.err = errors.New(powerdns.ErrMsgClientNotInitialized)
+		} else if zone, err := powerdns.Engine.Zones.Get(tctx, name); err != nil {
+			tz.err = err
+		} else {
+			tz.rrsets = zone.RRsets
+		}
+
+		cancel()
+
+		templates[name] = tz
+	}
+
+	return templates
+}
+
+// createBatchZone creates the zone of item i and copies the records of its
+// template.
+func (s *Service) createBatchZone(ctx context.Context, batch *Batch, i int, templates map[string]templateZone) {
+	item := batch.item(i)
+
+	tpl := templates[item.Template]
+	if tpl.err != nil {
+		batch.settle(i, statusFailed, fmt.Sprintf("Template zone %s could not be loaded: %v", item.Template, tpl.err))
+		return
+	}
+
+	if err := tenant.CheckZones(s.db, batch.TenantID, 1); err != nil {
+		batch.settle(i, statusFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	form := &ZoneForm{
+		ZoneType:    ZoneTypeForward,
+		Name:        item.Name,
+		Kind:        item.Kind,
+		SOAEditAPI:  batch.soaEditAPI,
+		Masters:     batch.masters,
+		Nameservers: batch.nameservers,
+	}
+
+	if err := CreateZone(ctx, form); err != nil {
+		if IsConflict(err) {
+			batch.settle(i, statusExists, "The zone already exists and was left unchanged.")
+			return
+		}
+
+		log.Error().Err(err).Str("zone_name", item.Name).Str("batch", batch.ID).Msg("failed to create zone")
+		batch.settle(i, statusFailed, err.Error())
+
+		return
+	}
+
+	if err := tenant.AssignZone(s.db, item.Name, batch.TenantID); err != nil {
+		log.Error().Err(err).Str("zone_name", item.Name).Msg("failed to assign zone to tenant")
+	}
+
+	msg := ""
+
+	if sets := templateRRsets(tpl.rrsets, item.Template, item.Name, len(batch.nameservers) > 0); len(sets) > 0 {
+		// The new zone holds its SOA and nameservers besides the template records.
+		if err := tenant.CheckRecords(s.db, item.Name, 0, 1+len(batch.nameservers)+tenant.RecordCount(sets)); err != nil {
+			msg = fmt.Sprintf("The zone was created, but the records of %s were not copied: %v", item.Template, err)
+		} else if err = powerdns.Engine.Records.Patch(ctx, item.Name, &pdnsapi.RRsets{Sets: sets}); err != nil {
+			log.Error().Err(err).Str("zone_name", item.Name).Msg("failed to copy template records")
+
+			msg = fmt.Sprintf("The zone was created, but the records of %s could not be copied: %v", item.Template, err)
+		}
+	}
+
+	batch.settle(i, statusCreated, msg)
+
+	details := map[string]any{"kind": string(item.Kind), "soa_edit_api": string(batch.soaEditAPI), "batch": batch.ID}
+	if item.Template != "" {
+		details["template"] = item.Template
+	}
+
+	entry := batch.actor
+	entry.DB = s.db
+	entry.Action = activitylog.ActionZoneCreated
+	entry.ResourceType = activitylog.ResourceTypeZone
+	entry.ResourceName = item.Name
+	entry.Details = details
+
+	activitylog.Record(&entry)
+}
+
+// templateRRsets returns the RRsets of a template zone renamed into zone.
+// The SOA is skipped, and so are the apex NS records when the new zone gets
+// its own nameservers.
+func templateRRsets(rrsets []pdnsapi.RRset, template, zone string, ownNS bool) []pdnsapi.RRset {
+	var sets []pdnsapi.RRset
+
+	for _, rr := range rrsets {
+		if rr.Name == nil || rr.Type == nil || len(rr.Records) == 0 {
+			continue
+		}
+
+		name := *rr.Name
+		apex := name == template
+
+		if *rr.Type == pdnsapi.RRTypeSOA || (apex && ownNS && *rr.Type == pdnsapi.RRTypeNS) {
+			continue
+		}
+
+		switch {
+		case apex:
+			name = zone
+		case strings.HasSuffix(name, "."+template):
+			name = strings.TrimSuffix(name, template) + zone
+		default:
+			continue
+		}
+
+		changeType := pdnsapi.ChangeTypeReplace
+		sets = append(sets, pdnsapi.RRset{
+			Name:       pdnsapi.String(name),
+			Type:       rr.Type,
+			TTL:        rr.TTL,
+			ChangeType: &changeType,
+			Records:    rr.Records,
+		})
+	}
+
+	return sets
+}
+
+// parseBatch parses the zone list of form and returns the zones to create
+// and the nameservers, or the errors found in the list.
+func parseBatch(form *BatchForm) ([]BatchItem, []string, []string) {
+	nameservers, msg := parseNameservers(form.Nameservers)
+	if msg != "" {
+		return nil, nil, []string{msg}
+	}
+
+	defaultKind, ok := parseKind(string(form.Kind))
+	if !ok {
+		return nil, nil, []string{"Select a zone type."}
+	}
+
+	switch form.SOAEditAPI {
+	case SOAEditAPIDefault, SOAEditAPIIncrease, SOAEditAPIEpoch, SOAEditAPIOff:
+	default:
+		return nil, nil, []string{"Select a SOA-EDIT-API mode."}
+	}
+
+	defaultTemplate := ""
+	if t := strings.ToLower(strings.TrimSpace(form.Template)); t != "" {
+		defaultTemplate = fqdn(t)
+	}
+
+	r := csv.NewReader(strings.NewReader(form.Zones))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	var (
+		items []BatchItem
+		errs  []string
+	)
+
+	seen := make(map[string]int)
+
+	for len(errs) < maxBatchErrors {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			errs = append(errs, err.Error())
+			break
+		}
+
+		line, _ := r.FieldPos(0)
+
+		if len(items) == 0 && len(errs) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue // header
+		}
+
+		item, msg := parseBatchLine(record, defaultKind, defaultTemplate)
+		item.Line = line
+
+		switch {
+		case msg != "":
+			errs = append(errs, fmt.Sprintf("Line %d: %s", line, msg))
+		case seen[item.Name] > 0:
+			errs = append(errs, fmt.Sprintf("Line %d: %s is already listed on line %d.", line, item.Name, seen[item.Name]))
+		case item.Kind == ZoneKindSlave && strings.TrimSpace(form.Masters) == "":
+			errs = append(errs, fmt.Sprintf("Line %d: Slave zones need the masters below.", line))
+		default:
+			seen[item.Name] = line
+			items = append(items, item)
+		}
+	}
+
+	switch {
+	case len(errs) > 0:
+		return nil, nil, errs
+	case len(items) == 0:
+		return nil, nil, []string{"Enter at least one zone."}
+	case len(items) > maxBatchZones:
+		return nil, nil, []string{fmt.Sprintf("Enter at most %d zones at once.", maxBatchZones)}
+	}
+
+	return items, nameservers, nil
+}
+
+// parseBatchLine parses the fields "name[,kind[,template]]" of one line.
+func parseBatchLine(record []string, defaultKind ZoneKind, defaultTemplate string) (BatchItem, string) {
+	item := BatchItem{
+		Name:     fqdn(strings.ToLower(strings.TrimSpace(record[0]))),
+		Kind:     defaultKind,
+		Template: defaultTemplate,
+		Status:   statusPending,
+	}
+
+	if len(record) > 3 {
+		return item, "Expected name, kind and template."
+	}
+
+	if !validHostname(item.Name) {
+		return item, fmt.Sprintf("%q is not a valid zone name.", record[0])
+	}
+
+	if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+		kind, ok := parseKind(record[1])
+		if !ok {
+			return item, fmt.Sprintf("Unknown zone type %q; use Native, Master or Slave.", record[1])
+		}
+
+		item.Kind = kind
+	}
+
+	if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+		item.Template = fqdn(strings.ToLower(strings.TrimSpace(record[2])))
+	}
+
+	switch {
+	case item.Template == "":
+	case !validHostname(item.Template):
+		return item, fmt.Sprintf("%q is not a valid template zone.", record[2])
+	case item.Template == item.Name:
+		return item, "A zone cannot be its own template."
+	case item.Kind == ZoneKindSlave:
+		return item, "Slave zones are transferred from their masters and cannot use a template."
+	}
+
+	return item, ""
+}
+
+// parseKind parses a zone kind case-insensitively; Primary and Secondary are
+// accepted as aliases of Master and Slave.
+func parseKind(s string) (ZoneKind, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "native":
+		return ZoneKindNative, true
+	case "master", "primary":
+		return ZoneKindMaster, true
+	case "slave", "secondary":
+		return ZoneKindSlave, true
+	default:
+		return "", false
+	}
+}
+
+func newBatchID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func (s *Service) renderBatch(c fiber.Ctx, status int, form *BatchForm, errs []string) error {
+	nav := navigation.NewContext(PageTitleBatch, "zones", "add").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(PageTitle, Path, false).
+		AddBreadcrumb(PageTitleBatch, PathBatch, true)
+
+	return c.Status(status).Render(TemplateBatch, fiber.Map{
+		"Navigation": nav,
+		"Form":       form,
+		"Error":      errs,
+		"MaxZones":   maxBatchZones,
+	}, handler.BaseLayout)
+}
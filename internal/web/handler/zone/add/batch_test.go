@@ -0,0 +1,207 @@
+package zoneadd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+func batchForm(zones string) *BatchForm {
+	return &BatchForm{Zones: zones, Kind: ZoneKindNative, SOAEditAPI: SOAEditAPIDefault, Nameservers: "ns1.example.net"}
+}
+
+func TestParseBatch(t *testing.T) {
+	form := batchForm("name,kind,template\n# comment\nExample.com\n\nexample.net,primary\n" +
+		"example.org,,tpl.example\n\"example.info\",Native,\n")
+
+	items, nameservers, errs := parseBatch(form)
+	if len(errs) > 0 {
+		t.Fatalf("parseBatch() errors = %v", errs)
+	}
+
+	want := []BatchItem{
+		{Line: 3, Name: "example.com.", Kind: ZoneKindNative, Status: statusPending},
+		{Line: 5, Name: "example.net.", Kind: ZoneKindMaster, Status: statusPending},
+		{Line: 6, Name: "example.org.", Kind: ZoneKindNative, Template: "tpl.example.", Status: statusPending},
+		{Line: 7, Name: "example.info.", Kind: ZoneKindNative, Status: statusPending},
+	}
+
+	if len(items) != len(want) {
+		t.Fatalf("parseBatch() = %+v, want %+v", items, want)
+	}
+
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, items[i], want[i])
+		}
+	}
+
+	if len(nameservers) != 1 || nameservers[0] != "ns1.example.net." {
+		t.Errorf("nameservers = %v", nameservers)
+	}
+}
+
+func TestParseBatch_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		zones string
+		want  string
+	}{
+		{"empty", "# nothing\n", "at least one zone"},
+		{"invalid name", "example.com\nexa mple.com", "Line 2: "},
+		{"duplicate", "example.com\nEXAMPLE.com.", "already listed on line 1"},
+		{"unknown kind", "example.com,forward", "Unknown zone type"},
+		{"too many fields", "example.com,Native,tpl.example,extra", "Expected name"},
+		{"own template", "example.com,,example.com", "own template"},
+		{"slave without masters", "example.com,Slave", "need the masters"},
+		{"slave with template", "example.com,Slave,tpl.example", "cannot use a template"},
+		{"csv error", "\"example.com", "parse error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := batchForm(tt.zones)
+			if tt.name == "slave with template" {
+				form.Masters = "192.0.2.1"
+			}
+
+			_, _, errs := parseBatch(form)
+			if len(errs) == 0 || !strings.Contains(strings.Join(errs, "\n"), tt.want) {
+				t.Errorf("parseBatch() errors = %v, want %q", errs, tt.want)
+			}
+		})
+	}
+
+	if _, _, errs := parseBatch(batchForm(strings.Repeat("x.example.\n", 2))); len(errs) != 1 {
+		t.Errorf("duplicate errors = %v, want one", errs)
+	}
+
+	many := make([]string, maxBatchZones+1)
+	for i := range many {
+		many[i] = "zone" + strconv.Itoa(i) + ".example."
+	}
+
+	if _, _, errs := parseBatch(batchForm(strings.Join(many, "\n"))); len(errs) != 1 || !strings.Contains(errs[0], "at most") {
+		t.Errorf("oversized batch errors = %v", errs)
+	}
+}
+
+func TestTemplateRRsets(t *testing.T) {
+	rr := func(name string, rrType pdnsapi.RRType, content string) pdnsapi.RRset {
+		return pdnsapi.RRset{
+			Name:    pdnsapi.String(name),
+			Type:    pdnsapi.RRTypePtr(rrType),
+			TTL:     pdnsapi.Uint32(300),
+			Records: []pdnsapi.Record{{Content: pdnsapi.String(content)}},
+		}
+	}
+
+	rrsets := []pdnsapi.RRset{
+		rr("tpl.example.", pdnsapi.RRTypeSOA, "ns1. hostmaster. 1 2 3 4 5"),
+		rr("tpl.example.", pdnsapi.RRTypeNS, "ns1.tpl.example."),
+		rr("tpl.example.", pdnsapi.RRTypeMX, "10 mail.example.net."),
+		rr("www.tpl.example.", pdnsapi.RRTypeA, "192.0.2.1"),
+		rr("other.example.", pdnsapi.RRTypeA, "192.0.2.2"),
+	}
+
+	sets := templateRRsets(rrsets, "tpl.example.", "new.example.", true)
+	if len(sets) != 2 || *sets[0].Name != "new.example." || *sets[1].Name != "www.new.example." {
+		t.Fatalf("templateRRsets() = %+v, want the MX and www A records renamed", sets)
+	}
+
+	if *sets[0].ChangeType != pdnsapi.ChangeTypeReplace || *sets[1].TTL != 300 {
+		t.Errorf("templateRRsets() = %+v, want REPLACE with the template TTL", sets[1])
+	}
+
+	if sets := templateRRsets(rrsets, "tpl.example.", "new.example.", false); len(sets) != 3 {
+		t.Errorf("templateRRsets() without nameservers = %d sets, want the apex NS copied", len(sets))
+	}
+}
+
+func TestRunBatch_ClientNotInitialized(t *testing.T) {
+	if powerdns.Engine.Client != nil {
+		t.Skip("PowerDNS client is initialized")
+	}
+
+	svc := &Service{cfg: newTestConfig(), db: newTestDB(t)}
+	batch := &Batch{ID: "b1", soaEditAPI: SOAEditAPIDefault, items: []BatchItem{
+		{Line: 1, Name: "a.example.", Kind: ZoneKindNative, Status: statusPending},
+		{Line: 2, Name: "b.example.", Kind: ZoneKindNative, Template: "tpl.example.", Status: statusPending},
+	}}
+
+	if err := svc.runBatch(context.Background(), batch); err == nil {
+		t.Error("runBatch() error = nil, want failed zones reported")
+	}
+
+	p := batch.Progress()
+	if !p.Finished || p.Done != 2 || p.Failed != 2 {
+		t.Fatalf("progress = %+v, want both zones failed", p)
+	}
+
+	if !strings.Contains(p.Items[1].Message, "Template zone tpl.example.") {
+		t.Errorf("template failure message = %q", p.Items[1].Message)
+	}
+}
+
+func TestBatchPages(t *testing.T) {
+	svc := &Service{cfg: newTestConfig(), db: newTestDB(t)}
+	current := models.User{ID: 1, Username: "alice"}
+
+	app := newTestApp()
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", current)
+		return c.Next()
+	})
+	app.Post(PathBatch, svc.PostBatch)
+	app.Get(PathBatch+"/:id", svc.GetBatchProgress)
+	app.Get(PathBatch+"/:id/status", svc.GetBatchStatus)
+
+	form := url.Values{"zones": {"bad zone"}, "kind": {"Native"}, "soa_edit_api": {"DEFAULT"}}
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, PathBatch,
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "Line 1:") {
+		t.Errorf("invalid list: status %d, body %q", resp.StatusCode, body)
+	}
+
+	batch := &Batch{ID: "b1", UserID: 1, items: []BatchItem{{Line: 1, Name: "a.example.", Status: statusCreated}}}
+	batch.finish()
+	svc.batches.add(batch)
+
+	resp = doGet(t, app, PathBatch+"/b1/status")
+	defer func() { _ = resp.Body.Close() }()
+
+	var p BatchProgress
+	if err = json.NewDecoder(resp.Body).Decode(&p); err != nil || p.Created != 1 || !p.Finished {
+		t.Errorf("status = %+v, %v", p, err)
+	}
+
+	current = models.User{ID: 2, Username: "bob"}
+
+	for _, path := range []string{PathBatch + "/b1", PathBatch + "/b1/status", PathBatch + "/nope/status"} {
+		if resp := doGet(t, app, path); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s as another user: status %d, want 404", path, resp.StatusCode)
+		}
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
 )
 
 // resolveZoneName sets form.Name based on the zone type.
@@ -39,8 +43,8 @@ func resolveZoneName(form *ZoneForm) error {
 	return nil
 }
 
-// createZone creates the zone in PowerDNS according to form.Kind.
-func createZone(ctx context.Context, form *ZoneForm) error {
+// CreateZone creates the zone in PowerDNS according to form.Kind.
+func CreateZone(ctx context.Context, form *ZoneForm) error {
 	// Validate kind before making any API calls.
 	switch form.Kind {
 	case ZoneKindNative, ZoneKindMaster, ZoneKindSlave:
@@ -55,21 +59,19 @@ func createZone(ctx context.Context, form *ZoneForm) error {
 
 	soaEditAPIStr := string(form.SOAEditAPI)
 
+	var err error
+
 	switch form.Kind {
 	case ZoneKindNative:
-		_, err := powerdns.Engine.Zones.AddNative(
+		_, err = powerdns.Engine.Zones.AddNative(
 			ctx, form.Name,
-			false, "", false, "", soaEditAPIStr, false, nil,
+			false, "", false, "", soaEditAPIStr, false, form.Nameservers,
 		)
-
-		return err
 	case ZoneKindMaster:
-		_, err := powerdns.Engine.Zones.AddMaster(
+		_, err = powerdns.Engine.Zones.AddMaster(
 			ctx, form.Name,
-			false, "", false, "", soaEditAPIStr, false, nil,
+			false, "", false, "", soaEditAPIStr, false, form.Nameservers,
 		)
-
-		return err
 	case ZoneKindSlave:
 		var masters []string
 
@@ -79,10 +81,22 @@ func createZone(ctx context.Context, form *ZoneForm) error {
 			}
 		}
 
-		_, err := powerdns.Engine.Zones.AddSlave(ctx, form.Name, masters)
+		_, err = powerdns.Engine.Zones.AddSlave(ctx, form.Name, masters)
+	}
 
+	if err != nil {
 		return err
 	}
 
-	return fmt.Errorf("unknown zone kind: %s", form.Kind)
+	zoneindex.Default.RefreshZone(ctx, form.Name)
+
+	return nil
+}
+
+// IsConflict reports whether err is PowerDNS refusing to create a zone that
+// already exists.
+func IsConflict(err error) bool {
+	var pdnsErr *pdnsapi.Error
+
+	return (errors.As(err, &pdnsErr) && pdnsErr.StatusCode == fiber.StatusConflict) || err.Error() == "Conflict"
 }
@@ -73,7 +73,7 @@ func TestResolveZoneName_ReverseIPv6_Invalid(t *testing.T) {
 func TestCreateZone_UnknownKind(t *testing.T) {
 	form := &ZoneForm{Kind: ZoneKind("Unknown"), Name: "test.", SOAEditAPI: SOAEditAPIDefault}
 
-	err := createZone(context.Background(), form)
+	err := CreateZone(context.Background(), form)
 	if err == nil {
 		t.Fatal("expected error for unknown zone kind, got nil")
 	}
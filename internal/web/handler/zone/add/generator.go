@@ -0,0 +1,320 @@
+package zoneadd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// PathReverse is the path of the reverse zone generator.
+	PathReverse = Path + "/reverse"
+
+	// TemplateReverse is the template of the reverse zone generator.
+	TemplateReverse = "zone/reverse"
+
+	// PageTitleReverse is the title of the reverse zone generator.
+	PageTitleReverse = "Generate Reverse Zones"
+
+	actionCreate = "create"
+
+	// Outcomes of creating a zone in one of the bulk tools.
+	statusPending = "pending"
+	statusCreated = "created"
+	statusExists  = "exists"
+	statusFailed  = "failed"
+)
+
+// hostnameRegex matches a lower-case hostname with trailing dot.
+var hostnameRegex = regexp.MustCompile(`^([a-z0-9_]([a-z0-9-]{0,61}[a-z0-9])?\.)+$`)
+
+// ReverseForm is the submitted reverse zone generator form.
+type ReverseForm struct {
+	Network     string     `form:"network"`
+	Nameservers string     `form:"nameservers"`
+	Hostmaster  string     `form:"hostmaster"`
+	Kind        ZoneKind   `form:"kind"`
+	SOAEditAPI  SOAEditAPI `form:"soa_edit_api"`
+	// Delegate adds the RFC 2317 delegation records of classless zones to
+	// their parent zone.
+	Delegate bool `form:"delegate"`
+	// Action is "create" to create the zones; anything else previews them.
+	Action string `form:"action"`
+}
+
+// ReverseResult is a generated zone with the outcome of creating it.
+type ReverseResult struct {
+	ReverseZone
+	// Delegation are the records the parent of a classless zone needs.
+	Delegation []DelegationRecord
+	// Status is created, exists or failed; empty in a preview.
+	Status string
+	// Message describes a failure or a step that needs manual work.
+	Message string
+	// Delegated is set when the delegation was added to the parent zone.
+	Delegated bool
+}
+
+// GetReverse renders the reverse zone generator.
+func (s *Service) GetReverse(c fiber.Ctx) error {
+	form := &ReverseForm{
+		Kind:        ZoneKindNative,
+		SOAEditAPI:  SOAEditAPIDefault,
+		Nameservers: strings.Join(s.cfg.ZoneRequest.Nameservers, "\n"),
+		Delegate:    true,
+	}
+
+	return s.renderReverse(c, fiber.StatusOK, form, nil, "")
+}
+
+// PostReverse previews or creates the reverse zones of a network. Every zone
+// gets the given nameservers and an SOA naming the first of them as primary.
+func (s *Service) PostReverse(c fiber.Ctx) error {
+	form := &ReverseForm{}
+	if err := c.Bind().Body(form); err != nil {
+		return s.renderReverse(c, fiber.StatusBadRequest, form, nil, "Invalid form data")
+	}
+
+	zones, nameservers, msg := validateReverseForm(form)
+	if msg != "" {
+		return s.renderReverse(c, fiber.StatusBadRequest, form, nil, msg)
+	}
+
+	results := make([]ReverseResult, len(zones))
+	for i := range zones {
+		results[i] = ReverseResult{ReverseZone: zones[i], Delegation: ClasslessDelegation(&zones[i], nameservers)}
+	}
+
+	if form.Action != actionCreate {
+		return s.renderReverse(c, fiber.StatusOK, form, results, "")
+	}
+
+	if powerdns.Engine.Client == nil {
+		return s.renderReverse(c, fiber.StatusServiceUnavailable, form, results, powerdns.ErrMsgClientNotInitialized)
+	}
+
+	userID, username := auth.Actor(c)
+	serial := time.Now().Format("20060102") + "01"
+
+	for i := range results {
+		r := &results[i]
+		s.createReverseZone(c, form, r, nameservers, serial)
+
+		if r.Status != statusCreated {
+			continue
+		}
+
+		activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+			DB:           s.db,
+			UserID:       userID,
+			Username:     username,
+			Action:       activitylog.ActionZoneCreated,
+			ResourceType: activitylog.ResourceTypeZone,
+			ResourceName: r.Name,
+			Details: map[string]any{
+				"kind":         string(form.Kind),
+				"soa_edit_api": string(form.SOAEditAPI),
+				"network":      r.Network,
+			},
+			IPAddress: c.IP(),
+		}))
+
+		if r.Delegated {
+			activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+				DB:           s.db,
+				UserID:       userID,
+				Username:     username,
+				Action:       activitylog.ActionZoneUpdated,
+				ResourceType: activitylog.ResourceTypeZone,
+				ResourceName: r.Parent,
+				Details:      map[string]any{"delegated": r.Name, "records": len(r.Delegation)},
+				IPAddress:    c.IP(),
+			}))
+		}
+	}
+
+	return s.renderReverse(c, fiber.StatusOK, form, results, "")
+}
+
+// createReverseZone creates the zone of r, sets its SOA and, when requested,
+// delegates a classless zone from its parent. The outcome is stored in r.
+func (s *Service) createReverseZone(c fiber.Ctx, form *ReverseForm, r *ReverseResult, nameservers []string,
+	serial string,
+) {
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zoneForm := &ZoneForm{
+		ZoneType:    r.ZoneType(),
+		Name:        r.Name,
+		Kind:        form.Kind,
+		SOAEditAPI:  form.SOAEditAPI,
+		Nameservers: nameservers,
+	}
+
+	user, _ := c.Locals("CurrentUser").(models.User)
+	if err := tenant.CheckZones(s.db, user.TenantID, 1); err != nil {
+		r.Status = statusFailed
+		r.Message = err.Error()
+
+		return
+	}
+
+	if err := CreateZone(ctx, zoneForm); err != nil {
+		if IsConflict(err) {
+			r.Status = statusExists
+			r.Message = "The zone already exists and was left unchanged."
+
+			return
+		}
+
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", r.Name).Msg("failed to create reverse zone")
+
+		r.Status = statusFailed
+		r.Message = err.Error()
+
+		return
+	}
+
+	r.Status = statusCreated
+
+	if err := tenant.AssignCreated(s.db, r.Name, &user); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", r.Name).Msg("failed to assign zone to tenant")
+	}
+
+	changeType := pdnsapi.ChangeTypeReplace
+	soa := pdnsapi.RRset{
+		Name:       pdnsapi.String(r.Name),
+		Type:       pdnsapi.RRTypePtr(pdnsapi.RRTypeSOA),
+		TTL:        pdnsapi.Uint32(delegationTTL),
+		ChangeType: &changeType,
+		Records: []pdnsapi.Record{{
+			Content:  pdnsapi.String(soaContent(r.Name, nameservers[0], form.Hostmaster, serial)),
+			Disabled: pdnsapi.Bool(false),
+		}},
+	}
+
+	if err := powerdns.Engine.Records.Patch(ctx, r.Name, &pdnsapi.RRsets{Sets: []pdnsapi.RRset{soa}}); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", r.Name).Msg("failed to set SOA of reverse zone")
+
+		r.Message = "The zone was created, but its SOA could not be set: " + err.Error()
+	}
+
+	if !form.Delegate || len(r.Delegation) == 0 {
+		return
+	}
+
+	err := powerdns.Engine.Records.Patch(ctx, r.Parent, &pdnsapi.RRsets{Sets: delegationRRsets(r.Delegation)})
+	if err != nil {
+		requestid.Logger(c.Context()).Warn().Err(err).Str("zone_name", r.Parent).Msg("failed to delegate classless zone")
+
+		r.Message = fmt.Sprintf("The delegation could not be added to %s (%v); add the records below manually.",
+			r.Parent, err)
+
+		return
+	}
+
+	r.Delegated = true
+
+	zoneindex.Default.RefreshZone(ctx, r.Parent)
+}
+
+// validateReverseForm normalizes form and returns the zones to create and the
+// nameservers, or a message for the user.
+func validateReverseForm(form *ReverseForm) ([]ReverseZone, []string, string) {
+	zones, err := ReverseZones(form.Network)
+	if err != nil {
+		return nil, nil, err.Error()
+	}
+
+	nameservers, msg := parseNameservers(form.Nameservers)
+	if msg != "" {
+		return nil, nil, msg
+	}
+
+	form.Hostmaster = strings.TrimSpace(form.Hostmaster)
+
+	switch {
+	case len(nameservers) == 0:
+		return nil, nil, "Enter at least one nameserver."
+	case form.Kind != ZoneKindNative && form.Kind != ZoneKindMaster:
+		return nil, nil, "Reverse zones are created as Native or Primary zones."
+	case form.SOAEditAPI != SOAEditAPIDefault && form.SOAEditAPI != SOAEditAPIIncrease &&
+		form.SOAEditAPI != SOAEditAPIEpoch && form.SOAEditAPI != SOAEditAPIOff:
+		return nil, nil, "Select a SOA-EDIT-API mode."
+	case form.Hostmaster != "" && !strings.Contains(form.Hostmaster, "@"):
+		return nil, nil, "The hostmaster must be an email address."
+	}
+
+	form.Nameservers = strings.Join(nameservers, "\n")
+
+	return zones, nameservers, ""
+}
+
+// parseNameservers splits a list of hostnames separated by commas or white
+// space and returns them lower-cased with a trailing dot, or a message naming
+// the first invalid one.
+func parseNameservers(list string) ([]string, string) {
+	var nameservers []string
+
+	for field := range strings.FieldsFuncSeq(strings.ToLower(list), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		ns := fqdn(field)
+		if !validHostname(ns) {
+			return nil, fmt.Sprintf("Nameserver %q is not a valid hostname.", field)
+		}
+
+		nameservers = append(nameservers, ns)
+	}
+
+	return nameservers, ""
+}
+
+// fqdn returns name with a trailing dot.
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+
+	return name + "."
+}
+
+// validHostname reports whether name is a lower-case hostname with trailing
+// dot.
+func validHostname(name string) bool {
+	return len(name) <= 254 && hostnameRegex.MatchString(name)
+}
+
+func (s *Service) renderReverse(c fiber.Ctx, status int, form *ReverseForm, results []ReverseResult,
+	msg string,
+) error {
+	nav := navigation.NewContext(PageTitleReverse, "zones", "add").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(PageTitle, Path, false).
+		AddBreadcrumb(PageTitleReverse, PathReverse, true)
+
+	return c.Status(status).Render(TemplateReverse, fiber.Map{
+		"Navigation": nav,
+		"Form":       form,
+		"Results":    results,
+		"Created":    form.Action == actionCreate && results != nil && msg == "",
+		"Error":      msg,
+	}, handler.BaseLayout)
+}
@@ -0,0 +1,37 @@
+package zoneadd
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/nsset"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+// nameserverSets returns the nameserver sets offered for new zones.
+func (s *Service) nameserverSets(c fiber.Ctx) []models.NameserverSet {
+	sets, err := nsset.List(s.db)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list nameserver sets")
+	}
+
+	return sets
+}
+
+// assignNameserverSet assigns the zone just created with the nameservers of
+// the set setID to the set. Applying the set only confirms the NS records.
+// A failure is logged; the zone is created either way.
+func (s *Service) assignNameserverSet(ctx context.Context, c fiber.Ctx, zone string, setID uint) {
+	err := nsset.Assign(s.db, zone, setID)
+	if err == nil {
+		err = nsset.ApplyZone(ctx, s.db, zone, *auth.Attribute(c, &activitylog.Entry{}))
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zone).Msg("failed to assign nameserver set")
+	}
+}
@@ -0,0 +1,239 @@
+package zoneadd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+// delegationTTL is the TTL of the records delegating a classless zone.
+const delegationTTL = 3600
+
+// errHostPrefix is returned for networks that hold a single address.
+var errHostPrefix = errors.New("the prefix covers a single address; enter a network")
+
+// ReverseZone is a reverse zone covering a network or part of it.
+type ReverseZone struct {
+	// Name is the zone name, e.g. "20.10.in-addr.arpa.".
+	Name string
+	// Network is the network the zone covers in CIDR notation.
+	Network string
+	// IPv6 is set for ip6.arpa zones.
+	IPv6 bool
+	// Parent is the zone delegating a classless (RFC 2317) zone; empty for
+	// zones on an octet or nibble boundary.
+	Parent string
+	// First and Last are the last octets of the addresses of a classless zone.
+	First, Last int
+}
+
+// ZoneType returns the zone type the zone is created as.
+func (z *ReverseZone) ZoneType() ZoneType {
+	if z.IPv6 {
+		return ZoneTypeReverseIPv6
+	}
+
+	return ZoneTypeReverseIPv4
+}
+
+// DelegationRecord is a record the parent zone needs to delegate a classless
+// zone.
+type DelegationRecord struct {
+	Name    string
+	Type    string
+	Content string
+}
+
+// ReverseZones returns the reverse zones covering the network in CIDR
+// notation. Prefixes between octet (IPv4) or nibble (IPv6) boundaries are
+// split into the zones of the next longer boundary, so 10.20.0.0/20 yields
+// sixteen /24 zones. IPv4 networks longer than /24 yield one classless zone
+// named after RFC 2317, e.g. "64-127.2.0.192.in-addr.arpa." for 192.0.2.64/26.
+func ReverseZones(network string) ([]ReverseZone, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(network))
+	if err != nil {
+		return nil, fmt.Errorf("invalid network %q: enter a prefix in CIDR notation, e.g. 10.20.0.0/16", network)
+	}
+
+	prefix = prefix.Masked()
+
+	if prefix.Addr().Is4() {
+		return reverseIPv4Zones(prefix)
+	}
+
+	return reverseIPv6Zones(prefix)
+}
+
+func reverseIPv4Zones(prefix netip.Prefix) ([]ReverseZone, error) {
+	bits := prefix.Bits()
+
+	switch {
+	case bits == 32:
+		return nil, errHostPrefix
+	case bits > 24:
+		return []ReverseZone{classlessZone(prefix)}, nil
+	}
+
+	// At most 256 zones, for a /0 split into /8 zones.
+	boundary := max((bits+7)/8*8, 8)
+	count := 1 << (boundary - bits)
+
+	a := prefix.Addr().As4()
+	base := binary.BigEndian.Uint32(a[:])
+	step := uint32(1) << (32 - boundary)
+
+	zones := make([]ReverseZone, 0, count)
+
+	for i := range uint32(count) {
+		var octets [4]byte
+		binary.BigEndian.PutUint32(octets[:], base+i*step)
+
+		labels := make([]string, 0, boundary/8)
+		for j := boundary/8 - 1; j >= 0; j-- {
+			labels = append(labels, strconv.Itoa(int(octets[j])))
+		}
+
+		zones = append(zones, ReverseZone{
+			Name:    strings.Join(labels, ".") + ".in-addr.arpa.",
+			Network: netip.PrefixFrom(netip.AddrFrom4(octets), boundary).String(),
+		})
+	}
+
+	return zones, nil
+}
+
+// classlessZone returns the RFC 2317 zone of an IPv4 prefix longer than /24.
+func classlessZone(prefix netip.Prefix) ReverseZone {
+	a := prefix.Addr().As4()
+	first := int(a[3])
+	last := first + 1<<(32-prefix.Bits()) - 1
+	parent := fmt.Sprintf("%d.%d.%d.in-addr.arpa.", a[2], a[1], a[0])
+
+	return ReverseZone{
+		Name:    fmt.Sprintf("%d-%d.%s", first, last, parent),
+		Network: prefix.String(),
+		Parent:  parent,
+		First:   first,
+		Last:    last,
+	}
+}
+
+func reverseIPv6Zones(prefix netip.Prefix) ([]ReverseZone, error) {
+	bits := prefix.Bits()
+	if bits > 124 {
+		return nil, errHostPrefix
+	}
+
+	boundary := max((bits+3)/4*4, 4)
+	count := 1 << (boundary - bits)
+	nibbles := boundary / 4
+	a := prefix.Addr().As16()
+
+	zones := make([]ReverseZone, 0, count)
+
+	// The zones differ only in their last nibble, which the prefix covers
+	// partially.
+	for i := range count {
+		addr := a
+		last := nibbles - 1
+
+		shift := 4
+		if last%2 == 1 {
+			shift = 0
+		}
+
+		addr[last/2] += byte(i << shift)
+
+		labels := make([]string, 0, nibbles)
+		for j := last; j >= 0; j-- {
+			b := addr[j/2]
+			if j%2 == 0 {
+				b >>= 4
+			}
+
+			labels = append(labels, strconv.FormatUint(uint64(b&lowerNibbleMask), 16))
+		}
+
+		zones = append(zones, ReverseZone{
+			Name:    strings.Join(labels, ".") + ".ip6.arpa.",
+			Network: netip.PrefixFrom(netip.AddrFrom16(addr), boundary).String(),
+			IPv6:    true,
+		})
+	}
+
+	return zones, nil
+}
+
+// ClasslessDelegation returns the records the parent zone needs to delegate
+// the classless zone z to nameservers: the NS records of the zone and a CNAME
+// for every address pointing into it. It returns nil for other zones.
+func ClasslessDelegation(z *ReverseZone, nameservers []string) []DelegationRecord {
+	if z.Parent == "" {
+		return nil
+	}
+
+	records := make([]DelegationRecord, 0, len(nameservers)+z.Last-z.First+1)
+	for _, ns := range nameservers {
+		records = append(records, DelegationRecord{Name: z.Name, Type: "NS", Content: ns})
+	}
+
+	for n := z.First; n <= z.Last; n++ {
+		octet := strconv.Itoa(n)
+		records = append(records, DelegationRecord{
+			Name:    octet + "." + z.Parent,
+			Type:    "CNAME",
+			Content: octet + "." + z.Name,
+		})
+	}
+
+	return records
+}
+
+// delegationRRsets groups records into RRsets replacing the existing ones.
+func delegationRRsets(records []DelegationRecord) []pdnsapi.RRset {
+	var sets []pdnsapi.RRset
+
+	index := make(map[string]int)
+
+	for _, r := range records {
+		key := r.Name + " " + r.Type
+
+		i, ok := index[key]
+		if !ok {
+			changeType := pdnsapi.ChangeTypeReplace
+			i = len(sets)
+			index[key] = i
+			sets = append(sets, pdnsapi.RRset{
+				Name:       pdnsapi.String(r.Name),
+				Type:       pdnsapi.RRTypePtr(pdnsapi.RRType(r.Type)),
+				TTL:        pdnsapi.Uint32(delegationTTL),
+				ChangeType: &changeType,
+			})
+		}
+
+		sets[i].Records = append(sets[i].Records, pdnsapi.Record{
+			Content:  pdnsapi.String(r.Content),
+			Disabled: pdnsapi.Bool(false),
+		})
+	}
+
+	return sets
+}
+
+// soaContent returns the SOA record content of a generated zone: the first
+// nameserver as primary, hostmaster as the responsible mailbox (default
+// hostmaster.<zone>) and common timer values.
+func soaContent(zone, primary, hostmaster, serial string) string {
+	rname := "hostmaster." + zone
+
+	if local, domain, ok := strings.Cut(hostmaster, "@"); ok {
+		rname = strings.ReplaceAll(local, ".", `\.`) + "." + strings.TrimSuffix(domain, ".") + "."
+	}
+
+	return fmt.Sprintf("%s %s %s 10800 3600 604800 3600", primary, rname, serial)
+}
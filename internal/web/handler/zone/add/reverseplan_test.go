@@ -0,0 +1,140 @@
+package zoneadd
+
+import (
+	"errors"
+	"testing"
+)
+
+func zoneNames(zones []ReverseZone) []string {
+	names := make([]string, len(zones))
+	for i := range zones {
+		names[i] = zones[i].Name
+	}
+
+	return names
+}
+
+func TestReverseZones(t *testing.T) {
+	tests := []struct {
+		network string
+		count   int
+		first   string
+		last    string
+		net     string
+	}{
+		{"10.20.0.0/16", 1, "20.10.in-addr.arpa.", "20.10.in-addr.arpa.", "10.20.0.0/16"},
+		{"10.20.0.0/20", 16, "0.20.10.in-addr.arpa.", "15.20.10.in-addr.arpa.", "10.20.0.0/24"},
+		{"10.20.5.7/23", 2, "4.20.10.in-addr.arpa.", "5.20.10.in-addr.arpa.", "10.20.4.0/24"},
+		{"10.0.0.0/7", 2, "10.in-addr.arpa.", "11.in-addr.arpa.", "10.0.0.0/8"},
+		{"192.0.2.64/26", 1, "64-127.2.0.192.in-addr.arpa.", "64-127.2.0.192.in-addr.arpa.", "192.0.2.64/26"},
+		{"2001:db8::/32", 1, "8.b.d.0.1.0.0.2.ip6.arpa.", "8.b.d.0.1.0.0.2.ip6.arpa.", "2001:db8::/32"},
+		{"2001:db8:4::/46", 4, "4.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "7.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "2001:db8:4::/48"},
+		{"2001:db8:f0::/45", 8, "0.f.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "7.f.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "2001:db8:f0::/48"},
+		{"2001:db8:0:4::/62", 4, "4.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "7.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "2001:db8:0:4::/64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			zones, err := ReverseZones(tt.network)
+			if err != nil {
+				t.Fatalf("ReverseZones() error = %v", err)
+			}
+
+			names := zoneNames(zones)
+			if len(names) != tt.count || names[0] != tt.first || names[len(names)-1] != tt.last {
+				t.Errorf("ReverseZones() = %v, want %d zones %s … %s", names, tt.count, tt.first, tt.last)
+			}
+
+			if zones[0].Network != tt.net {
+				t.Errorf("first zone network = %s, want %s", zones[0].Network, tt.net)
+			}
+		})
+	}
+}
+
+func TestReverseZonesErrors(t *testing.T) {
+	for _, network := range []string{"10.20.0.0", "bogus/8", "192.0.2.1/32", "2001:db8::1/128"} {
+		if _, err := ReverseZones(network); err == nil {
+			t.Errorf("ReverseZones(%q) succeeded, want an error", network)
+		}
+	}
+
+	if zones, err := ReverseZones("0.0.0.0/0"); err != nil || len(zones) != 256 {
+		t.Errorf("ReverseZones(0.0.0.0/0) = %d zones, %v, want 256", len(zones), err)
+	}
+
+	if _, err := ReverseZones("192.0.2.0/32"); !errors.Is(err, errHostPrefix) {
+		t.Errorf("ReverseZones(/32) error = %v, want errHostPrefix", err)
+	}
+}
+
+func TestClasslessDelegation(t *testing.T) {
+	zones, err := ReverseZones("192.0.2.64/30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := ClasslessDelegation(&zones[0], []string{"ns1.example.net.", "ns2.example.net."})
+	if len(records) != 6 {
+		t.Fatalf("got %d records, want 2 NS and 4 CNAME: %+v", len(records), records)
+	}
+
+	if r := records[0]; r.Name != "64-67.2.0.192.in-addr.arpa." || r.Type != "NS" || r.Content != "ns1.example.net." {
+		t.Errorf("NS record = %+v", r)
+	}
+
+	if r := records[5]; r.Name != "67.2.0.192.in-addr.arpa." || r.Type != "CNAME" ||
+		r.Content != "67.64-67.2.0.192.in-addr.arpa." {
+		t.Errorf("CNAME record = %+v", r)
+	}
+
+	if sets := delegationRRsets(records); len(sets) != 5 || len(sets[0].Records) != 2 {
+		t.Errorf("delegationRRsets() = %d sets, want 5 with 2 NS records first", len(sets))
+	}
+
+	full, _ := ReverseZones("192.0.2.0/24")
+	if records := ClasslessDelegation(&full[0], []string{"ns1.example.net."}); records != nil {
+		t.Errorf("delegation of an octet-aligned zone = %+v", records)
+	}
+}
+
+func TestSOAContent(t *testing.T) {
+	zone := "2.0.192.in-addr.arpa."
+
+	if got := soaContent(zone, "ns1.example.net.", "", "2026060101"); got !=
+		"ns1.example.net. hostmaster.2.0.192.in-addr.arpa. 2026060101 10800 3600 604800 3600" {
+		t.Errorf("default SOA = %q", got)
+	}
+
+	if got := soaContent(zone, "ns1.example.net.", "dns.admin@example.net", "1"); got !=
+		`ns1.example.net. dns\.admin.example.net. 1 10800 3600 604800 3600` {
+		t.Errorf("SOA with hostmaster = %q", got)
+	}
+}
+
+func TestValidateReverseForm(t *testing.T) {
+	form := &ReverseForm{
+		Network: "10.0.0.0/24", Nameservers: "NS1.example.net, ns2.example.net.",
+		Kind: ZoneKindNative, SOAEditAPI: SOAEditAPIDefault,
+	}
+
+	zones, nameservers, msg := validateReverseForm(form)
+	if msg != "" || len(zones) != 1 || len(nameservers) != 2 || nameservers[0] != "ns1.example.net." {
+		t.Fatalf("validateReverseForm() = %v, %v, %q", zones, nameservers, msg)
+	}
+
+	for name, mutate := range map[string]func(f *ReverseForm){
+		"no nameservers":  func(f *ReverseForm) { f.Nameservers = "" },
+		"bad nameserver":  func(f *ReverseForm) { f.Nameservers = "ns/1" },
+		"slave kind":      func(f *ReverseForm) { f.Kind = ZoneKindSlave },
+		"bad hostmaster":  func(f *ReverseForm) { f.Hostmaster = "hostmaster" },
+		"invalid network": func(f *ReverseForm) { f.Network = "10.0.0.0/33" },
+	} {
+		f := *form
+		mutate(&f)
+
+		if _, _, msg := validateReverseForm(&f); msg == "" {
+			t.Errorf("%s: validateReverseForm() accepted %+v", name, f)
+		}
+	}
+}
@@ -53,4 +53,10 @@ type ZoneForm struct {
 	Kind           ZoneKind   `form:"kind"            validate:"required,oneof=Native Master Slave"`
 	SOAEditAPI     SOAEditAPI `form:"soa_edit_api"    validate:"required,oneof=DEFAULT INCREASE EPOCH OFF"`
 	Masters        string     `form:"masters"` // Comma-separated list for Slave zones
+	// Nameservers are the NS records of a new Native or Master zone. The add
+	// form leaves them empty; zones created from a request use the requested ones.
+	Nameservers []string `form:"-"`
+	// NameserverSet is the ID of the nameserver set the zone is assigned to,
+	// or 0. Its nameservers become the NS records of the zone.
+	NameserverSet uint `form:"nameserver_set"`
 }
@@ -0,0 +1,494 @@
+// Package zoneclaim provides the zone ownership claim workflow: a user claims
+// an existing zone, proves control of the domain with a TXT record or through
+// the hostmaster mailbox, and an administrator approves the claim, which
+// grants the user access to the zone.
+package zoneclaim
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/mailer"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// Path is the path of the claim form.
+	Path = handler.RootPath + "zone/claim"
+
+	// PathMine lists the claims of the current user.
+	PathMine = handler.RootPath + "zone/claims"
+
+	// PathAdmin is the base path of the review queue.
+	PathAdmin = handler.RootPath + "admin/zone-claims"
+
+	// ChallengeLabel is prepended to the zone name to form the name of the
+	// TXT record that proves control of the zone.
+	ChallengeLabel = "_gopowerdns-admin-challenge"
+
+	templateNew    = "zone/claim/new"
+	templateMine   = "zone/claim/mine"
+	templateList   = "admin/zoneclaim/list"
+	templateReview = "admin/zoneclaim/review"
+
+	labelClaimZone  = "Claim Zone"
+	labelMyClaims   = "My Zone Claims"
+	labelZoneClaims = "Zone Claims"
+
+	lookupTimeout = 10 * time.Second
+
+	errFailedLoadClaims = "Failed to load zone claims"
+	errFailedSaveClaim  = "Failed to save the zone claim"
+	errInvalidClaimID   = "Invalid zone claim ID"
+	errClaimNotFound    = "Zone claim not found"
+	errNotSignedIn      = "You must be signed in to claim a zone."
+)
+
+// Service handles the zone claim pages.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+	mailer      mailer.Sender
+	// zoneExists reports whether a zone exists in PowerDNS; replaced in tests.
+	zoneExists func(ctx context.Context, name string) (bool, error)
+	// lookupTXT resolves TXT records in public DNS; replaced in tests.
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Form is the submitted zone claim form.
+type Form struct {
+	Name   string `form:"name"`
+	Method string `form:"method"`
+}
+
+// ClaimView is a claim with the details the claimant needs to prove control.
+type ClaimView struct {
+	models.ZoneClaim
+	// Challenge is the name of the TXT record to publish.
+	Challenge string
+	// Hostmaster is the address the confirmation link is mailed to.
+	Hostmaster string
+}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+	s.zoneExists = indexedZoneExists
+	s.lookupTXT = net.DefaultResolver.LookupTXT
+
+	if cfg.Mail.Enabled() {
+		s.mailer = mailer.New(&cfg.Mail)
+	}
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermZoneClaim), s.New)
+	app.Post(Path, auth.RequirePermission(authService, auth.PermZoneClaim), s.Submit)
+	app.Get(PathMine, auth.RequirePermission(authService, auth.PermZoneClaim), s.Mine)
+	app.Post(PathMine+"/:id/verify", auth.RequirePermission(authService, auth.PermZoneClaim), s.Verify)
+	app.Get(PathMine+"/:id/confirm", auth.RequirePermission(authService, auth.PermZoneClaim), s.Confirm)
+
+	app.Get(PathAdmin, auth.RequirePermission(authService, auth.PermAdminZoneClaims), s.List)
+	app.Get(PathAdmin+"/:id", auth.RequirePermission(authService, auth.PermAdminZoneClaims), s.Review)
+	app.Post(PathAdmin+"/:id/approve", auth.RequirePermission(authService, auth.PermAdminZoneClaims), s.Approve)
+	app.Post(PathAdmin+"/:id/reject", auth.RequirePermission(authService, auth.PermAdminZoneClaims), s.Reject)
+	app.Post(PathAdmin+"/:id/revoke", auth.RequirePermission(authService, auth.PermAdminZoneClaims), s.Revoke)
+}
+
+// New renders the claim form.
+func (s *Service) New(c fiber.Ctx) error {
+	if _, ok := c.Locals("CurrentUser").(models.User); !ok {
+		return handler.RenderError(c, fiber.StatusUnauthorized, "Unauthorized", errNotSignedIn, nil)
+	}
+
+	return s.renderNew(c, fiber.StatusOK, &Form{Method: string(models.ZoneClaimTXT)}, "")
+}
+
+// Submit stores a new claim. For the email method the confirmation link is
+// mailed to the hostmaster address of the zone right away.
+func (s *Service) Submit(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok {
+		return handler.RenderError(c, fiber.StatusUnauthorized, "Unauthorized", errNotSignedIn, nil)
+	}
+
+	form := &Form{}
+	if err := c.Bind().Body(form); err != nil {
+		return s.renderNew(c, fiber.StatusBadRequest, form, "Invalid form data")
+	}
+
+	claim, msg := s.buildClaim(c.Context(), form, &user)
+	if msg != "" {
+		return s.renderNew(c, fiber.StatusBadRequest, form, msg)
+	}
+
+	if err := s.db.Create(claim).Error; err != nil {
+		log.Error().Err(err).Str("zone", claim.ZoneName).Msg("failed to store zone claim")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveClaim, nil)
+	}
+
+	userID := user.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &userID,
+		Username:     user.Username,
+		Action:       activitylog.ActionZoneClaimed,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: claim.ZoneName,
+		Details:      map[string]any{"claim_id": claim.ID, "method": claim.Method},
+		IPAddress:    c.IP(),
+	}))
+
+	success := "Claim for " + claim.ZoneName + " submitted. Publish the TXT record below and verify it."
+	if claim.Method == models.ZoneClaimEmail {
+		s.sendChallenge(c, claim, &user)
+
+		success = "Claim for " + claim.ZoneName + " submitted. Follow the link sent to " +
+			hostmaster(claim.ZoneName) + "."
+	}
+
+	return c.Redirect().To(PathMine + "?success=" + url.QueryEscape(success))
+}
+
+// Mine lists the claims of the current user with the verification
+// instructions of the pending ones.
+func (s *Service) Mine(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok {
+		return handler.RenderError(c, fiber.StatusUnauthorized, "Unauthorized", errNotSignedIn, nil)
+	}
+
+	var claims []models.ZoneClaim
+	if err := s.db.Where("requester_id = ?", user.ID).
+		Order("created_at DESC").
+		Find(&claims).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load zone claims")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadClaims, nil)
+	}
+
+	views := make([]ClaimView, 0, len(claims))
+	for i := range claims {
+		views = append(views, newClaimView(&claims[i]))
+	}
+
+	nav := navigation.NewContext(labelMyClaims, "zones", "claims").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelMyClaims, PathMine, true)
+
+	return c.Render(templateMine, fiber.Map{
+		"Navigation": nav,
+		"Claims":     views,
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// Verify checks the TXT record of a pending TXT claim, or mails the
+// confirmation link of a pending email claim again.
+func (s *Service) Verify(c fiber.Ctx) error {
+	user, claim, status, msg := s.loadOwnClaim(c)
+	if claim == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	if claim.Status != models.ZoneClaimPending {
+		return redirectMine(c, "", "The claim for "+claim.ZoneName+" is already "+string(claim.Status)+".")
+	}
+
+	if claim.Method == models.ZoneClaimEmail {
+		if s.mailer == nil {
+			return redirectMine(c, "", "Email is not configured; submit a new claim using a TXT record.")
+		}
+
+		s.sendChallenge(c, claim, user)
+
+		return redirectMine(c, "Confirmation link sent to "+hostmaster(claim.ZoneName)+".", "")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), lookupTimeout)
+	defer cancel()
+
+	values, err := s.lookupTXT(ctx, challengeName(claim.ZoneName))
+	if err != nil {
+		log.Debug().Err(err).Str("zone", claim.ZoneName).Msg("zone claim TXT lookup failed")
+	}
+
+	if !containsToken(values, claim.Token) {
+		return redirectMine(c, "", "The TXT record "+challengeName(claim.ZoneName)+
+			" with the claim token was not found. DNS changes can take a while to propagate; try again later.")
+	}
+
+	return s.markVerified(c, claim, user)
+}
+
+// Confirm verifies a pending email claim from the link mailed to the
+// hostmaster address. The link only works for the claimant.
+func (s *Service) Confirm(c fiber.Ctx) error {
+	user, claim, status, msg := s.loadOwnClaim(c)
+	if claim == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	if claim.Method != models.ZoneClaimEmail ||
+		subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(claim.Token)) != 1 {
+		return handler.RenderError(c, fiber.StatusBadRequest, "Invalid Link",
+			"The confirmation link is invalid.", nil)
+	}
+
+	if claim.Status != models.ZoneClaimPending {
+		return redirectMine(c, "", "The claim for "+claim.ZoneName+" is already "+string(claim.Status)+".")
+	}
+
+	return s.markVerified(c, claim, user)
+}
+
+// markVerified moves a pending claim to verified and notifies the reviewers.
+func (s *Service) markVerified(c fiber.Ctx, claim *models.ZoneClaim, user *models.User) error {
+	now := time.Now()
+
+	res := s.db.Model(&models.ZoneClaim{}).
+		Where("id = ? AND status = ?", claim.ID, models.ZoneClaimPending).
+		Updates(map[string]any{"status": models.ZoneClaimVerified, "verified_at": now})
+	if res.Error != nil {
+		log.Error().Err(res.Error).Uint64("claim_id", claim.ID).Msg("failed to verify zone claim")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveClaim, nil)
+	}
+
+	if res.RowsAffected == 0 {
+		return redirectMine(c, "", "The claim for "+claim.ZoneName+" is no longer pending.")
+	}
+
+	claim.Status = models.ZoneClaimVerified
+	claim.VerifiedAt = &now
+
+	userID := user.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &userID,
+		Username:     user.Username,
+		Action:       activitylog.ActionZoneClaimVerified,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: claim.ZoneName,
+		Details:      map[string]any{"claim_id": claim.ID, "method": claim.Method},
+		IPAddress:    c.IP(),
+	}))
+
+	s.notifyReviewers(c, claim, user)
+
+	return redirectMine(c, "Control of "+claim.ZoneName+" verified. An administrator will review the claim.", "")
+}
+
+// buildClaim validates form and returns the claim to store, or a message for
+// the user.
+func (s *Service) buildClaim(ctx context.Context, form *Form, user *models.User) (*models.ZoneClaim, string) {
+	name := strings.ToLower(strings.TrimSpace(form.Name))
+	if name == "" {
+		return nil, "Enter the zone name."
+	}
+
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	method := models.ZoneClaimMethod(form.Method)
+
+	switch method {
+	case models.ZoneClaimTXT:
+	case models.ZoneClaimEmail:
+		if s.mailer == nil {
+			return nil, "Email is not configured; prove control with a TXT record."
+		}
+	default:
+		return nil, "Select how to prove control of the zone."
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	exists, err := s.zoneExists(lookupCtx, name)
+	if err != nil {
+		log.Error().Err(err).Str("zone", name).Msg("failed to look up claimed zone")
+		return nil, "Failed to look up the zone in PowerDNS."
+	}
+
+	if !exists {
+		return nil, name + " does not exist. Request a new zone instead."
+	}
+
+	access, err := s.authService.GetZoneAccess(user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to resolve zone access")
+		return nil, errFailedSaveClaim
+	}
+
+	if access.Allows(name) {
+		return nil, "You already have access to " + name + "."
+	}
+
+	var open int64
+	if err = s.db.Model(&models.ZoneClaim{}).
+		Where("zone_name = ? AND requester_id = ? AND status IN ?", name, user.ID,
+			[]models.ZoneClaimStatus{models.ZoneClaimPending, models.ZoneClaimVerified}).
+		Count(&open).Error; err != nil {
+		log.Error().Err(err).Msg("failed to check for open zone claims")
+		return nil, errFailedSaveClaim
+	}
+
+	if open > 0 {
+		return nil, "You already have an open claim for " + name + "."
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to generate zone claim token")
+		return nil, errFailedSaveClaim
+	}
+
+	return &models.ZoneClaim{
+		ZoneName:    name,
+		RequesterID: user.ID,
+		Method:      method,
+		Token:       token,
+		Status:      models.ZoneClaimPending,
+	}, ""
+}
+
+// loadOwnClaim loads the claim of the :id parameter, which must belong to the
+// current user. When the claim is nil the returned status and message
+// describe the failure.
+func (s *Service) loadOwnClaim(c fiber.Ctx) (*models.User, *models.ZoneClaim, int, string) {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok {
+		return nil, nil, fiber.StatusUnauthorized, errNotSignedIn
+	}
+
+	claim, status, msg := s.loadClaim(c)
+	if claim == nil {
+		return nil, nil, status, msg
+	}
+
+	if claim.RequesterID != user.ID {
+		return nil, nil, fiber.StatusNotFound, errClaimNotFound
+	}
+
+	return &user, claim, fiber.StatusOK, ""
+}
+
+func (s *Service) renderNew(c fiber.Ctx, status int, form *Form, msg string) error {
+	nav := navigation.NewContext(labelClaimZone, "zones", "claim").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelMyClaims, PathMine, false).
+		AddBreadcrumb(labelClaimZone, Path, true)
+
+	return c.Status(status).Render(templateNew, fiber.Map{
+		"Navigation":     nav,
+		"Form":           form,
+		"Error":          msg,
+		"EmailAvailable": s.mailer != nil,
+		"ChallengeLabel": ChallengeLabel,
+	}, handler.BaseLayout)
+}
+
+func newClaimView(claim *models.ZoneClaim) ClaimView {
+	return ClaimView{
+		ZoneClaim:  *claim,
+		Challenge:  challengeName(claim.ZoneName),
+		Hostmaster: hostmaster(claim.ZoneName),
+	}
+}
+
+// indexedZoneExists looks zone up in the cached zone list.
+func indexedZoneExists(ctx context.Context, name string) (bool, error) {
+	zones, err := zoneindex.Default.List(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range zones {
+		if zones[i].Name != nil && strings.EqualFold(*zones[i].Name, name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// challengeName returns the name of the TXT record proving control of zone.
+func challengeName(zone string) string {
+	return ChallengeLabel + "." + zone
+}
+
+// hostmaster returns the hostmaster address of zone.
+func hostmaster(zone string) string {
+	return "hostmaster@" + strings.TrimSuffix(zone, ".")
+}
+
+// containsToken reports whether one of the TXT values is token. Resolvers
+// return long values split into strings already joined.
+func containsToken(values []string, token string) bool {
+	for _, v := range values {
+		if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(v)), []byte(token)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newToken returns a random claim token.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// redirectMine redirects to the claim list with a success or error message.
+func redirectMine(c fiber.Ctx, success, msg string) error {
+	q := url.Values{}
+	if success != "" {
+		q.Set("success", success)
+	}
+
+	if msg != "" {
+		q.Set("error", msg)
+	}
+
+	return c.Redirect().To(PathMine + "?" + q.Encode())
+}
+
+// parseID returns the :id route parameter.
+func parseID(c fiber.Ctx) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	return id, err == nil && id > 0
+}
@@ -0,0 +1,347 @@
+package zoneclaim
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// noOpViews renders the template name so tests can tell pages apart.
+type noOpViews struct{}
+
+func (noOpViews) Load() error { return nil }
+
+func (noOpViews) Render(w io.Writer, name string, _ any, _ ...string) error {
+	_, _ = io.WriteString(w, name)
+	return nil
+}
+
+// mailbox keeps the subjects of the claim mails by recipient. Claims mail
+// the hostmaster of the zone, the reviewers and the claimant, in the
+// background.
+type mailbox struct {
+	mu      sync.Mutex
+	subject map[string][]string
+}
+
+func (m *mailbox) Send(to, subject, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subject[to] = append(m.subject[to], subject)
+
+	return nil
+}
+
+// await returns the subject of the next mail to to.
+func (m *mailbox) await(t *testing.T, to string) string {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		m.mu.Lock()
+		if subjects := m.subject[to]; len(subjects) > 0 {
+			m.subject[to] = subjects[1:]
+			m.mu.Unlock()
+
+			return subjects[0]
+		}
+		m.mu.Unlock()
+	}
+
+	t.Fatalf("no mail to %s", to)
+
+	return ""
+}
+
+// claims is a claim service with a claimant, alice, who reaches
+// web.example.com. through the "web" tag, and a reviewer, root, who holds
+// admin.zone.claims. PowerDNS serves example.com. and web.example.com.;
+// public DNS answers with the TXT records in txt.
+type claims struct {
+	app  *fiber.App
+	svc  *Service
+	db   *gorm.DB
+	mail *mailbox
+	txt  map[string][]string
+
+	claimant models.User
+	reviewer models.User
+	actor    models.User
+}
+
+func newClaims(t *testing.T) *claims {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(
+		&models.Role{}, &models.Permission{}, &models.RolePermission{},
+		&models.User{}, &models.Group{}, &models.GroupMapping{}, &models.UserGroup{},
+		&models.Tag{}, &models.UserTag{}, &models.GroupTag{}, &models.GroupZone{},
+		&models.ZoneTag{}, &models.ZoneAccessOption{},
+		&models.ZoneClaim{}, &models.ZoneOwnership{}, &models.ActivityLog{},
+	); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	userRole, reviewerRole := models.Role{Name: "user"}, models.Role{Name: "admin"}
+	perm := models.Permission{Name: auth.PermAdminZoneClaims, Resource: "admin", Action: "zone.claims"}
+	db.Create(&userRole)
+	db.Create(&reviewerRole)
+	db.Create(&perm)
+	db.Create(&models.RolePermission{RoleID: reviewerRole.ID, PermissionID: perm.ID})
+
+	cl := &claims{
+		db:       db,
+		mail:     &mailbox{subject: map[string][]string{}},
+		txt:      map[string][]string{},
+		claimant: models.User{Username: "alice", Email: "alice@example.com", RoleID: userRole.ID, Active: true},
+		reviewer: models.User{Username: "root", Email: "root@example.com", RoleID: reviewerRole.ID, Active: true},
+	}
+	db.Create(&cl.claimant)
+	db.Create(&cl.reviewer)
+
+	web := models.Tag{Name: "web"}
+	db.Create(&web)
+	db.Create(&models.UserTag{UserID: cl.claimant.ID, TagID: web.ID})
+	db.Create(&models.ZoneTag{ZoneID: "web.example.com.", TagID: web.ID})
+
+	cfg := &config.Config{}
+	cfg.Webserver.URL = "https://dns.example.com"
+
+	served := map[string]bool{"example.com.": true, "web.example.com.": true}
+	cl.svc = &Service{
+		cfg:         cfg,
+		db:          db,
+		authService: auth.NewService(db),
+		mailer:      cl.mail,
+		zoneExists:  func(_ context.Context, name string) (bool, error) { return served[name], nil },
+		lookupTXT:   func(_ context.Context, name string) ([]string, error) { return cl.txt[name], nil },
+	}
+
+	cl.app = fiber.New(fiber.Config{Views: noOpViews{}})
+	cl.app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", cl.actor)
+		return c.Next()
+	})
+	cl.app.Post(Path, cl.svc.Submit)
+	cl.app.Post(PathMine+"/:id/verify", cl.svc.Verify)
+	cl.app.Get(PathMine+"/:id/confirm", cl.svc.Confirm)
+	cl.app.Post(PathAdmin+"/:id/approve", cl.svc.Approve)
+	cl.app.Post(PathAdmin+"/:id/reject", cl.svc.Reject)
+	cl.app.Post(PathAdmin+"/:id/revoke", cl.svc.Revoke)
+
+	return cl
+}
+
+// send makes a request as actor and returns the response status.
+func (cl *claims) send(t *testing.T, actor models.User, method, target string, form url.Values) int {
+	t.Helper()
+
+	cl.actor = actor
+
+	req := httptest.NewRequestWithContext(context.Background(), method, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := cl.app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+// claim submits a claim of the claimant to zone and returns it.
+func (cl *claims) claim(t *testing.T, zone, method string) models.ZoneClaim {
+	t.Helper()
+
+	if status := cl.send(t, cl.claimant, http.MethodPost, Path,
+		url.Values{"name": {zone}, "method": {method}}); status != http.StatusSeeOther {
+		t.Fatalf("claim of %s: status %d, want 303", zone, status)
+	}
+
+	var claim models.ZoneClaim
+	if err := cl.db.Order("id DESC").First(&claim).Error; err != nil {
+		t.Fatalf("claim not stored: %v", err)
+	}
+
+	return claim
+}
+
+// statusOf returns the current status of the claim id.
+func (cl *claims) statusOf(t *testing.T, id uint64) models.ZoneClaimStatus {
+	t.Helper()
+
+	var claim models.ZoneClaim
+	if err := cl.db.First(&claim, id).Error; err != nil {
+		t.Fatalf("claim %d not found: %v", id, err)
+	}
+
+	return claim.Status
+}
+
+// owns reports whether the claimant reaches zone.
+func (cl *claims) owns(t *testing.T, zone string) bool {
+	t.Helper()
+
+	access, err := cl.svc.authService.GetZoneAccess(cl.claimant.ID)
+	if err != nil {
+		t.Fatalf("GetZoneAccess() error = %v", err)
+	}
+
+	return access.Allows(zone)
+}
+
+// mine and review return the paths of the claimant and reviewer actions on
+// the claim id.
+func mine(id uint64, action string) string {
+	return PathMine + "/" + strconv.FormatUint(id, 10) + "/" + action
+}
+
+func review(id uint64, action string) string {
+	return PathAdmin + "/" + strconv.FormatUint(id, 10) + "/" + action
+}
+
+func TestSubmit_Validation(t *testing.T) {
+	cl := newClaims(t)
+
+	for name, form := range map[string]url.Values{
+		"missing name":       {"method": {"txt"}},
+		"unknown method":     {"name": {"example.com"}, "method": {"phone"}},
+		"unknown zone":       {"name": {"example.org"}, "method": {"txt"}},
+		"already accessible": {"name": {"web.example.com"}, "method": {"txt"}},
+	} {
+		if status := cl.send(t, cl.claimant, http.MethodPost, Path, form); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, status)
+		}
+	}
+
+	cl.claim(t, "Example.com", "txt")
+
+	if status := cl.send(t, cl.claimant, http.MethodPost, Path,
+		url.Values{"name": {"example.com"}, "method": {"txt"}}); status != http.StatusBadRequest {
+		t.Errorf("second open claim: status %d, want 400", status)
+	}
+}
+
+func TestTXTClaim_VerifyApproveRevoke(t *testing.T) {
+	cl := newClaims(t)
+
+	claim := cl.claim(t, "example.com", "txt")
+	if claim.ZoneName != "example.com." || claim.Token == "" || claim.Status != models.ZoneClaimPending {
+		t.Fatalf("claim = %+v", claim)
+	}
+
+	if status := cl.send(t, cl.reviewer, http.MethodPost, review(claim.ID, "approve"), nil); status !=
+		http.StatusConflict {
+		t.Errorf("approve an unverified claim: status %d, want 409", status)
+	}
+
+	cl.send(t, cl.claimant, http.MethodPost, mine(claim.ID, "verify"), nil)
+
+	if got := cl.statusOf(t, claim.ID); got != models.ZoneClaimPending {
+		t.Fatalf("status without the TXT record = %s, want pending", got)
+	}
+
+	cl.txt[ChallengeLabel+".example.com."] = []string{"unrelated", claim.Token}
+	cl.send(t, cl.claimant, http.MethodPost, mine(claim.ID, "verify"), nil)
+
+	if got := cl.statusOf(t, claim.ID); got != models.ZoneClaimVerified {
+		t.Fatalf("status with the TXT record = %s, want verified", got)
+	}
+
+	cl.mail.await(t, cl.reviewer.Email)
+
+	if status := cl.send(t, cl.reviewer, http.MethodPost, review(claim.ID, "approve"), nil); status !=
+		http.StatusSeeOther {
+		t.Fatalf("approve: status %d, want 303", status)
+	}
+
+	if !cl.owns(t, "example.com.") {
+		t.Error("approved claim does not grant access")
+	}
+
+	if subject := cl.mail.await(t, cl.claimant.Email); !strings.Contains(subject, "approved") {
+		t.Errorf("mail to the claimant = %q", subject)
+	}
+
+	if status := cl.send(t, cl.reviewer, http.MethodPost, review(claim.ID, "revoke"), nil); status !=
+		http.StatusSeeOther {
+		t.Fatalf("revoke: status %d, want 303", status)
+	}
+
+	if cl.owns(t, "example.com.") {
+		t.Error("revoked claim still grants access")
+	}
+
+	if got := cl.statusOf(t, claim.ID); got != models.ZoneClaimRevoked {
+		t.Errorf("status after revoke = %s, want revoked", got)
+	}
+}
+
+func TestEmailClaim_Confirm(t *testing.T) {
+	cl := newClaims(t)
+
+	claim := cl.claim(t, "example.com", "email")
+	cl.mail.await(t, "hostmaster@example.com")
+
+	if status := cl.send(t, cl.claimant, http.MethodGet, mine(claim.ID, "confirm?token=wrong"), nil); status !=
+		http.StatusBadRequest {
+		t.Errorf("wrong token: status %d, want 400", status)
+	}
+
+	// Only the claimant can confirm.
+	if status := cl.send(t, cl.reviewer, http.MethodGet, mine(claim.ID, "confirm?token="+claim.Token), nil); status !=
+		http.StatusNotFound {
+		t.Errorf("confirm of another user's claim: status %d, want 404", status)
+	}
+
+	cl.send(t, cl.claimant, http.MethodGet, mine(claim.ID, "confirm?token="+claim.Token), nil)
+
+	if got := cl.statusOf(t, claim.ID); got != models.ZoneClaimVerified {
+		t.Errorf("status = %s, want verified", got)
+	}
+}
+
+func TestReject_RequiresReason(t *testing.T) {
+	cl := newClaims(t)
+	claim := cl.claim(t, "example.com", "txt")
+
+	if status := cl.send(t, cl.reviewer, http.MethodPost, review(claim.ID, "reject"), nil); status !=
+		http.StatusBadRequest {
+		t.Errorf("reject without a reason: status %d, want 400", status)
+	}
+
+	if status := cl.send(t, cl.reviewer, http.MethodPost, review(claim.ID, "reject"),
+		url.Values{"comment": {"Not yours"}}); status != http.StatusSeeOther {
+		t.Fatalf("reject: status %d, want 303", status)
+	}
+
+	if got := cl.statusOf(t, claim.ID); got != models.ZoneClaimRejected {
+		t.Errorf("status = %s, want rejected", got)
+	}
+
+	if cl.owns(t, "example.com.") {
+		t.Error("rejected claim grants access")
+	}
+}
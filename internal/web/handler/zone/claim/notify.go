@@ -0,0 +1,138 @@
+package zoneclaim
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+)
+
+// sendChallenge mails the confirmation link of an email claim to the
+// hostmaster address of the zone.
+func (s *Service) sendChallenge(c fiber.Ctx, claim *models.ZoneClaim, requester *models.User) {
+	if s.mailer == nil {
+		return
+	}
+
+	link := s.link(fmt.Sprintf("%s/%d/confirm?token=%s", PathMine, claim.ID, url.QueryEscape(claim.Token)))
+
+	subject := s.brandName(c) + ": confirm ownership of " + claim.ZoneName
+	body := fmt.Sprintf(`%s (%s) claims ownership of the zone %s.
+
+To confirm the claim, open the following link while signed in as %s:
+
+%s
+
+If you do not know about this claim, ignore this message.
+`, requester.FullName(), requester.Username, claim.ZoneName, requester.Username, link)
+
+	s.send(hostmaster(claim.ZoneName), subject, body)
+}
+
+// notifyReviewers emails every user allowed to review zone claims about a
+// verified claim.
+func (s *Service) notifyReviewers(c fiber.Ctx, claim *models.ZoneClaim, requester *models.User) {
+	if s.mailer == nil {
+		return
+	}
+
+	reviewers, err := s.authService.GetUsersWithPermission(auth.PermAdminZoneClaims)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load zone claim reviewers")
+		return
+	}
+
+	subject := s.brandName(c) + ": zone claim for " + claim.ZoneName
+	body := fmt.Sprintf(`%s proved control of the zone %s (%s) and claims ownership.
+
+Review the claim at:
+
+%s
+`, requester.FullName(), claim.ZoneName, methodLabel(claim.Method), s.link(fmt.Sprintf("%s/%d", PathAdmin, claim.ID)))
+
+	for i := range reviewers {
+		if reviewers[i].Email == "" || reviewers[i].ID == requester.ID {
+			continue
+		}
+
+		s.send(reviewers[i].Email, subject, body)
+	}
+}
+
+// notifyRequester emails the claimant the outcome of the review.
+func (s *Service) notifyRequester(c fiber.Ctx, claim *models.ZoneClaim) {
+	if s.mailer == nil || claim.Requester.Email == "" {
+		return
+	}
+
+	var subject, body string
+
+	switch claim.Status {
+	case models.ZoneClaimApproved:
+		subject = s.brandName(c) + ": zone claim for " + claim.ZoneName + " approved"
+		body = fmt.Sprintf(`Hello %s,
+
+your claim for the zone %s was approved. You can now manage the zone.
+
+%s
+`, claim.Requester.FullName(), claim.ZoneName, s.link("/zone/edit/"+claim.ZoneName))
+	case models.ZoneClaimRejected:
+		subject = s.brandName(c) + ": zone claim for " + claim.ZoneName + " rejected"
+		body = fmt.Sprintf(`Hello %s,
+
+your claim for the zone %s was rejected.
+
+Reason:
+%s
+`, claim.Requester.FullName(), claim.ZoneName, claim.ReviewComment)
+	default:
+		return
+	}
+
+	if claim.Status == models.ZoneClaimApproved && claim.ReviewComment != "" {
+		body += "\nComment from the reviewer:\n" + claim.ReviewComment + "\n"
+	}
+
+	s.send(claim.Requester.Email, subject, body)
+}
+
+// methodLabel describes how control of a zone was proven.
+func methodLabel(method models.ZoneClaimMethod) string {
+	if method == models.ZoneClaimEmail {
+		return "hostmaster email"
+	}
+
+	return "TXT record"
+}
+
+// send delivers a message in the background so a slow SMTP server does not
+// delay the response.
+func (s *Service) send(to, subject, body string) {
+	jobs.Go(jobs.Mail, func() error {
+		err := s.mailer.Send(to, subject, body)
+		if err != nil {
+			log.Error().Err(err).Str("to", to).Msg("failed to send zone claim email")
+		}
+
+		return err
+	})
+}
+
+func (s *Service) brandName(c fiber.Ctx) string {
+	if brand, ok := c.Locals("Brand").(config.Branding); ok {
+		return brand.Name
+	}
+
+	return s.cfg.Branding.Resolve(s.cfg.Title).Name
+}
+
+func (s *Service) link(path string) string {
+	return strings.TrimRight(s.cfg.Webserver.URL, "/") + path
+}
@@ -0,0 +1,276 @@
+package zoneclaim
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const statusAll = "all"
+
+// errAlreadyReviewed is returned when a claim was settled concurrently.
+var errAlreadyReviewed = errors.New("zone claim was already reviewed")
+
+// List renders the review queue. The status query parameter selects verified
+// (default), pending, approved, rejected, revoked or all claims.
+func (s *Service) List(c fiber.Ctx) error {
+	status := c.Query("status", string(models.ZoneClaimVerified))
+
+	query := s.db.Preload("Requester").Order("created_at DESC")
+	if status != statusAll {
+		query = query.Where("status = ?", status)
+	}
+
+	var claims []models.ZoneClaim
+	if err := query.Find(&claims).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load zone claims")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadClaims, nil)
+	}
+
+	nav := navigation.NewContext(labelZoneClaims, "admin", "zone-claims").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb(labelZoneClaims, PathAdmin, true)
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Claims":     claims,
+		"Status":     status,
+		"Success":    c.Query("success"),
+	}, handler.BaseLayout)
+}
+
+// Review renders a single claim with the forms applicable to its status.
+func (s *Service) Review(c fiber.Ctx) error {
+	claim, status, msg := s.loadClaim(c)
+	if claim == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	return s.renderReview(c, fiber.StatusOK, claim, "")
+}
+
+// Approve grants the claimant ownership of a verified claim's zone and
+// notifies the claimant.
+func (s *Service) Approve(c fiber.Ctx) error {
+	claim, status, msg := s.loadClaim(c)
+	if claim == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	if claim.Status != models.ZoneClaimVerified {
+		return s.renderReview(c, fiber.StatusConflict, claim, "Only verified claims can be approved.")
+	}
+
+	reviewer, _ := c.Locals("CurrentUser").(models.User)
+	comment := strings.TrimSpace(c.FormValue("comment"))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := settle(tx, claim, models.ZoneClaimVerified, models.ZoneClaimApproved, &reviewer, comment); err != nil {
+			return err
+		}
+
+		claimID := claim.ID
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.ZoneOwnership{ZoneName: claim.ZoneName, UserID: claim.RequesterID, ClaimID: &claimID}).Error
+	})
+	if errors.Is(err, errAlreadyReviewed) {
+		return s.renderReview(c, fiber.StatusConflict, claim, "This claim has already been reviewed.")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("claim_id", claim.ID).Msg("failed to approve zone claim")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveClaim, nil)
+	}
+
+	s.recordReview(c, &reviewer, claim, activitylog.ActionZoneClaimApproved, comment)
+	s.notifyRequester(c, claim)
+
+	return c.Redirect().To(PathAdmin + "?success=" +
+		url.QueryEscape(claim.Requester.Username+" now owns "+claim.ZoneName+"."))
+}
+
+// Reject declines a pending or verified claim and notifies the claimant.
+func (s *Service) Reject(c fiber.Ctx) error {
+	claim, status, msg := s.loadClaim(c)
+	if claim == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	if !claim.Open() {
+		return s.renderReview(c, fiber.StatusConflict, claim, "This claim has already been reviewed.")
+	}
+
+	comment := strings.TrimSpace(c.FormValue("comment"))
+	if comment == "" {
+		return s.renderReview(c, fiber.StatusBadRequest, claim, "Tell the claimant why the claim is rejected.")
+	}
+
+	reviewer, _ := c.Locals("CurrentUser").(models.User)
+
+	err := settle(s.db, claim, claim.Status, models.ZoneClaimRejected, &reviewer, comment)
+	if errors.Is(err, errAlreadyReviewed) {
+		return s.renderReview(c, fiber.StatusConflict, claim, "This claim has already been reviewed.")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("claim_id", claim.ID).Msg("failed to reject zone claim")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveClaim, nil)
+	}
+
+	s.recordReview(c, &reviewer, claim, activitylog.ActionZoneClaimRejected, comment)
+	s.notifyRequester(c, claim)
+
+	return c.Redirect().To(PathAdmin + "?success=" + url.QueryEscape("Claim for "+claim.ZoneName+" rejected."))
+}
+
+// Revoke removes the ownership grant of an approved claim.
+func (s *Service) Revoke(c fiber.Ctx) error {
+	claim, status, msg := s.loadClaim(c)
+	if claim == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	if claim.Status != models.ZoneClaimApproved {
+		return s.renderReview(c, fiber.StatusConflict, claim, "Only approved claims can be revoked.")
+	}
+
+	reviewer, _ := c.Locals("CurrentUser").(models.User)
+	comment := strings.TrimSpace(c.FormValue("comment"))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := settle(tx, claim, models.ZoneClaimApproved, models.ZoneClaimRevoked, &reviewer, comment); err != nil {
+			return err
+		}
+
+		return tx.Where("zone_name = ? AND user_id = ?", claim.ZoneName, claim.RequesterID).
+			Delete(&models.ZoneOwnership{}).Error
+	})
+	if errors.Is(err, errAlreadyReviewed) {
+		return s.renderReview(c, fiber.StatusConflict, claim, "This claim has already been revoked.")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("claim_id", claim.ID).Msg("failed to revoke zone ownership")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveClaim, nil)
+	}
+
+	s.recordReview(c, &reviewer, claim, activitylog.ActionZoneOwnershipRevoked, comment)
+
+	return c.Redirect().To(PathAdmin + "?success=" +
+		url.QueryEscape("Ownership of "+claim.ZoneName+" revoked from "+claim.Requester.Username+"."))
+}
+
+// settle moves a claim from status from to status to. The update is
+// conditional so a claim cannot be reviewed twice concurrently.
+func settle(db *gorm.DB, claim *models.ZoneClaim, from, to models.ZoneClaimStatus, reviewer *models.User,
+	comment string,
+) error {
+	now := time.Now()
+
+	var reviewerID *uint64
+	if reviewer.ID != 0 {
+		id := reviewer.ID
+		reviewerID = &id
+	}
+
+	res := db.Model(&models.ZoneClaim{}).
+		Where("id = ? AND status = ?", claim.ID, from).
+		Updates(map[string]any{
+			"status":         to,
+			"reviewer_id":    reviewerID,
+			"review_comment": comment,
+			"reviewed_at":    now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return errAlreadyReviewed
+	}
+
+	claim.Status = to
+	claim.ReviewerID = reviewerID
+	claim.ReviewComment = comment
+	claim.ReviewedAt = &now
+
+	return nil
+}
+
+// recordReview writes the activity log entry of a review decision.
+func (s *Service) recordReview(c fiber.Ctx, reviewer *models.User, claim *models.ZoneClaim, action, comment string) {
+	reviewerID := reviewer.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &reviewerID,
+		Username:     reviewer.Username,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: claim.ZoneName,
+		Details: map[string]any{
+			"claim_id":  claim.ID,
+			"requester": claim.Requester.Username,
+			"method":    claim.Method,
+			"comment":   comment,
+		},
+		IPAddress: c.IP(),
+	}))
+}
+
+// loadClaim loads the claim of the :id parameter. When it is nil the returned
+// status and message describe the failure.
+func (s *Service) loadClaim(c fiber.Ctx) (*models.ZoneClaim, int, string) {
+	id, ok := parseID(c)
+	if !ok {
+		return nil, fiber.StatusBadRequest, errInvalidClaimID
+	}
+
+	var claim models.ZoneClaim
+
+	err := s.db.Preload("Requester").Preload("Reviewer").First(&claim, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, errClaimNotFound
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("claim_id", id).Msg("failed to load zone claim")
+		return nil, fiber.StatusInternalServerError, errFailedLoadClaims
+	}
+
+	return &claim, fiber.StatusOK, ""
+}
+
+// renderLoadError renders the error page for a failed loadClaim.
+func renderLoadError(c fiber.Ctx, status int, msg string) error {
+	return handler.RenderError(c, status, http.StatusText(status), msg, nil)
+}
+
+func (s *Service) renderReview(c fiber.Ctx, status int, claim *models.ZoneClaim, msg string) error {
+	nav := navigation.NewContext(claim.ZoneName, "admin", "zone-claims").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb(labelZoneClaims, PathAdmin, false).
+		AddBreadcrumb(claim.ZoneName, "", true)
+
+	return c.Status(status).Render(templateReview, fiber.Map{
+		"Navigation": nav,
+		"Claim":      newClaimView(claim),
+		"Error":      msg,
+	}, handler.BaseLayout)
+}
@@ -0,0 +1,232 @@
+// Package zonedelegation provides the subdomain report of a zone, which lists
+// the subdomains the zone delegates to other nameservers with the outcome of
+// their last delegation check.
+package zonedelegation
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/delegation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path of the subdomain report; the zone name follows.
+	Path = handler.RootPath + "zone/subdomains"
+
+	// PathCheck checks the delegations of a zone; the zone name follows. It
+	// is apart from Path so that rate limits can target it.
+	PathCheck = handler.RootPath + "zone/delegation-check"
+
+	templateReport = "zone/subdomains"
+
+	labelReport = "Subdomains"
+
+	// reportTimeout bounds fetching the zone.
+	reportTimeout = 30 * time.Second
+)
+
+// Row is a delegation of the zone with its last check, if any.
+type Row struct {
+	delegation.Delegation
+	Check   *models.DelegationCheck
+	Servers []delegation.Server
+}
+
+// Service handles the subdomain report.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+	runner      *delegation.Runner
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes. runner checks the delegations on request.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service,
+	runner *delegation.Runner,
+) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+	s.runner = runner
+
+	perm := auth.RequirePermission(authService, auth.PermZoneRead)
+
+	app.Get(Path+"/:name", perm, s.Report)
+	app.Post(PathCheck+"/:name", perm, s.Check)
+}
+
+// Report lists the delegations of the zone in PowerDNS with the outcome of
+// their last check.
+func (s *Service) Report(c fiber.Ctx) error {
+	zoneName, err := s.zoneName(c)
+	if err != nil {
+		return err
+	}
+
+	if powerdns.Engine.Client == nil {
+		return handler.RenderError(c, fiber.StatusInternalServerError,
+			"PowerDNS Unreachable", powerdns.ErrMsgClientNotInitialized, handler.PDNSServerSettingsAction)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), reportTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).
+			Msg("failed to fetch zone for subdomain report")
+
+		msg := "Failed to fetch zone: " + err.Error()
+		if powerdns.IsServerUnreachable(err) {
+			msg = powerdns.ErrMsgServerUnreachable
+		}
+
+		return handler.RenderError(c, fiber.StatusInternalServerError,
+			"PowerDNS Unreachable", msg, handler.PDNSServerSettingsAction)
+	}
+
+	var checks []models.DelegationCheck
+	if err = s.db.Where("zone_name = ?", zoneName).Find(&checks).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to load delegation checks")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load the delegation checks", nil)
+	}
+
+	rows := buildRows(delegation.Delegations(zone), checks)
+
+	editPath := handler.ZoneEditURL(zoneName)
+
+	nav := navigation.NewContext(labelReport, "zones", "subdomains").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(zoneName, editPath, false).
+		AddBreadcrumb(labelReport, "", true)
+
+	return c.Render(templateReport, fiber.Map{
+		"Navigation":     nav,
+		"Zone":           zoneName,
+		"EditPath":       editPath,
+		"Rows":           rows,
+		"Problems":       problems(rows),
+		"MaxDelegations": delegation.MaxDelegations,
+		"Recheck":        describeInterval(s.cfg.DNSCheck.DelegationInterval),
+		"Success":        c.Query("success"),
+		"Error":          c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// Check checks every delegation of the zone now and returns to the report.
+func (s *Service) Check(c fiber.Ctx) error {
+	zoneName, err := s.zoneName(c)
+	if err != nil {
+		return err
+	}
+
+	reportPath := Path + "/" + zoneName
+
+	if err = s.runner.CheckZone(c.Context(), zoneName, nil); err != nil {
+		return c.Redirect().To(reportPath + "?error=" + url.QueryEscape("Check failed: "+err.Error()))
+	}
+
+	return c.Redirect().To(reportPath + "?success=" + url.QueryEscape("Delegations checked."))
+}
+
+// zoneName returns the canonical zone name of the :name parameter, or an
+// error when the current user may not access the zone.
+func (s *Service) zoneName(c fiber.Ctx) (string, error) {
+	zoneName := c.Params("name")
+	if !strings.HasSuffix(zoneName, ".") {
+		zoneName += "."
+	}
+
+	if !auth.CanAccessZone(c, s.authService, zoneName) {
+		return "", fiber.NewError(fiber.StatusForbidden, "Access to this zone is not permitted")
+	}
+
+	return zoneName, nil
+}
+
+// buildRows joins the delegations of a zone with their stored checks. A
+// check of other nameservers than the delegation has now is stale and left
+// out.
+func buildRows(delegations []delegation.Delegation, checks []models.DelegationCheck) []Row {
+	byName := make(map[string]*models.DelegationCheck, len(checks))
+	for i := range checks {
+		byName[checks[i].Name] = &checks[i]
+	}
+
+	rows := make([]Row, 0, len(delegations))
+
+	for _, d := range delegations {
+		row := Row{Delegation: d}
+
+		if check := byName[d.Name]; check != nil && strings.Join(check.NameserverList(), " ") ==
+			strings.Join(d.Nameservers, " ") {
+			row.Check = check
+			row.Servers = delegation.Servers(check)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// problems counts the rows whose last check was not OK.
+func problems(rows []Row) int {
+	n := 0
+
+	for i := range rows {
+		if rows[i].Check != nil && rows[i].Check.Status != models.DelegationOK {
+			n++
+		}
+	}
+
+	return n
+}
+
+// describeInterval describes the re-check interval, e.g. "6 hours", or
+// returns "" when the re-check is disabled.
+func describeInterval(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return ""
+	case d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d/time.Minute), "minute")
+	}
+}
+
+// plural returns n with unit, in the plural unless n is 1.
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+
+	return strconv.Itoa(n) + " " + unit + "s"
+}
@@ -0,0 +1,189 @@
+// Package zonedeleted provides the page listing the zones deleted in
+// soft-delete mode, from which they can be restored or purged right away.
+package zonedeleted
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+)
+
+const (
+	// Path is the path of the deleted zones page.
+	Path = handler.RootPath + "zone/deleted"
+
+	templateList = "zone/deleted"
+
+	labelDeletedZones = "Deleted Zones"
+
+	errFailedLoadDeletions = "Failed to load the deleted zones"
+	errInvalidDeletionID   = "Invalid zone deletion ID"
+	errDeletionNotFound    = "Zone deletion not found"
+)
+
+// Service handles the deleted zones page.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+	now         func() time.Time
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+	s.now = time.Now
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermZoneDelete), s.List)
+	app.Post(Path+"/:id/restore", auth.RequirePermission(authService, auth.PermZoneDelete), s.Restore)
+	app.Post(Path+"/:id/purge", auth.RequirePermission(authService, auth.PermZoneDelete), s.Purge)
+}
+
+// List renders the deleted zones the current user has access to.
+func (s *Service) List(c fiber.Ctx) error {
+	deletions, err := zonedeletion.Open(s.db)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load zone deletions")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadDeletions, nil)
+	}
+
+	allowed, err := auth.ZoneFilter(c, s.authService)
+	if err != nil {
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadDeletions, nil)
+	}
+
+	visible := deletions[:0]
+
+	for i := range deletions {
+		if allowed == nil || allowed(deletions[i].ZoneName) {
+			visible = append(visible, deletions[i])
+		}
+	}
+
+	nav := navigation.NewContext(labelDeletedZones, "zones", "deleted").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelDeletedZones, Path, true)
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Deletions":  visible,
+		"SoftDelete": s.cfg.ZoneDeletion.SoftDelete(),
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// Restore reverts a deletion; the zone is live again right away.
+func (s *Service) Restore(c fiber.Ctx) error {
+	deletion, status, msg := s.loadDeletion(c)
+	if deletion == nil {
+		return handler.RenderError(c, status, "Zone Deletion", msg, nil)
+	}
+
+	if _, err := zonedeletion.Restore(s.db, deletion.ID, s.now()); err != nil {
+		return s.redirectError(c, "restore", deletion, err)
+	}
+
+	s.record(c, activitylog.ActionZoneDeletionRestored, deletion)
+
+	return c.Redirect().To("/zone/edit/" + deletion.ZoneName + "?success=" + url.QueryEscape("Zone restored"))
+}
+
+// Purge removes a deleted zone from PowerDNS without waiting for the end of
+// its grace period. It also retries failed purges.
+func (s *Service) Purge(c fiber.Ctx) error {
+	deletion, status, msg := s.loadDeletion(c)
+	if deletion == nil {
+		return handler.RenderError(c, status, "Zone Deletion", msg, nil)
+	}
+
+	ctx := context.WithoutCancel(c.Context())
+	if _, err := zonedeletion.PurgeNow(ctx, s.db, deletion.ID, s.now()); err != nil {
+		return s.redirectError(c, "purge", deletion, err)
+	}
+
+	return c.Redirect().To(Path + "?success=" + url.QueryEscape(deletion.ZoneName+" purged from PowerDNS."))
+}
+
+// loadDeletion returns the deletion named by the id route parameter, or nil
+// with the status and message to show. Deletions of zones the user may not
+// access are reported as not found.
+func (s *Service) loadDeletion(c fiber.Ctx) (*models.ZoneDeletion, int, string) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, fiber.StatusBadRequest, errInvalidDeletionID
+	}
+
+	deletion, err := zonedeletion.Get(s.db, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, errDeletionNotFound
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("deletion_id", id).Msg("failed to load zone deletion")
+		return nil, fiber.StatusInternalServerError, errFailedLoadDeletions
+	}
+
+	if !auth.CanAccessZone(c, s.authService, deletion.ZoneName) {
+		return nil, fiber.StatusNotFound, errDeletionNotFound
+	}
+
+	return deletion, 0, ""
+}
+
+// redirectError returns to the list with the reason action failed.
+func (s *Service) redirectError(c fiber.Ctx, action string, deletion *models.ZoneDeletion, err error) error {
+	msg := "Failed to " + action + " " + deletion.ZoneName + ": " + err.Error()
+	if errors.Is(err, zonedeletion.ErrNotOpen) {
+		msg = deletion.ZoneName + " was already purged or restored."
+	}
+
+	return c.Redirect().To(Path + "?error=" + url.QueryEscape(msg))
+}
+
+// record writes action on deletion to the activity log.
+func (s *Service) record(c fiber.Ctx, action string, deletion *models.ZoneDeletion) {
+	user, _ := c.Locals("CurrentUser").(models.User)
+
+	var userID *uint64
+	if user.ID != 0 {
+		userID = &user.ID
+	}
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     user.Username,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: deletion.ZoneName,
+		Details:      map[string]any{"deletion_id": deletion.ID},
+		IPAddress:    c.IP(),
+	}))
+}
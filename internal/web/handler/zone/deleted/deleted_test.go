@@ -0,0 +1,155 @@
+package zonedeleted
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// noOpViews renders the template name so tests can tell pages apart.
+type noOpViews struct{}
+
+func (noOpViews) Load() error { return nil }
+
+func (noOpViews) Render(w io.Writer, name string, _ any, _ ...string) error {
+	_, _ = io.WriteString(w, name)
+	return nil
+}
+
+func newTestService(t *testing.T) (*fiber.App, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.ZoneDeletion{}, &models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	svc := &Service{
+		cfg: &config.Config{ZoneDeletion: config.ZoneDeletion{GracePeriod: time.Hour}},
+		db:  db,
+		now: func() time.Time { return now },
+	}
+
+	app := fiber.New(fiber.Config{Views: noOpViews{}})
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", models.User{ID: 1, Username: "alice"})
+		return c.Next()
+	})
+	app.Get(Path, svc.List)
+	app.Post(Path+"/:id/restore", svc.Restore)
+	app.Post(Path+"/:id/purge", svc.Purge)
+
+	return app, db
+}
+
+func post(t *testing.T, app *fiber.App, path string) *http.Response {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, http.NoBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp
+}
+
+func TestRestore(t *testing.T) {
+	app, db := newTestService(t)
+
+	d, err := zonedeletion.Schedule(db, "example.com.", nil, now.Add(time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := post(t, app, "/zone/deleted/1/restore")
+	if resp.StatusCode != http.StatusSeeOther && resp.StatusCode != http.StatusFound {
+		t.Fatalf("restore: status %d", resp.StatusCode)
+	}
+
+	if loc := resp.Header.Get("Location"); !strings.HasPrefix(loc, "/zone/edit/example.com.?success=") {
+		t.Errorf("restore redirect = %q, want the zone editor", loc)
+	}
+
+	if got, _ := zonedeletion.Get(db, d.ID); got.Status != models.ZoneDeletionRestored {
+		t.Errorf("status = %s, want restored", got.Status)
+	}
+
+	var entries int64
+
+	db.Model(&models.ActivityLog{}).Where("action = ?", activitylog.ActionZoneDeletionRestored).Count(&entries)
+
+	if entries != 1 {
+		t.Errorf("activity entries = %d, want 1", entries)
+	}
+
+	// Restoring again reports that the deletion is closed.
+	resp = post(t, app, "/zone/deleted/1/restore")
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "error=") {
+		t.Errorf("second restore redirect = %q, want an error", loc)
+	}
+
+	for path, want := range map[string]int{
+		"/zone/deleted/abc/restore": http.StatusBadRequest,
+		"/zone/deleted/9/restore":   http.StatusNotFound,
+		"/zone/deleted/9/purge":     http.StatusNotFound,
+	} {
+		if resp := post(t, app, path); resp.StatusCode != want {
+			t.Errorf("POST %s: status %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestPurgeFailure(t *testing.T) {
+	if powerdns.Engine.Client != nil {
+		t.Skip("PowerDNS client is initialized")
+	}
+
+	app, db := newTestService(t)
+
+	d, err := zonedeletion.Schedule(db, "example.com.", nil, now.Add(time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := post(t, app, "/zone/deleted/1/purge")
+	if loc := resp.Header.Get("Location"); !strings.HasPrefix(loc, Path+"?error=") {
+		t.Errorf("purge redirect = %q, want the list with an error", loc)
+	}
+
+	if got, _ := zonedeletion.Get(db, d.ID); got.Status != models.ZoneDeletionFailed {
+		t.Errorf("status = %s, want failed", got.Status)
+	}
+
+	resp, err = app.Test(httptest.NewRequestWithContext(context.Background(), http.MethodGet, Path, http.NoBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(body) != templateList {
+		t.Errorf("list: status %d, body %q", resp.StatusCode, body)
+	}
+}
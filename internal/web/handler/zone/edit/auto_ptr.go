@@ -2,10 +2,10 @@ package zoneedit
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -16,6 +16,8 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
 	settingctrl "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setting"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	ttlsettings "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/ttl"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
 )
 
 const (
@@ -27,6 +29,10 @@ const (
 // ZoneSettings holds per-zone application settings stored in the database.
 type ZoneSettings struct {
 	AutoPTR bool `json:"auto_ptr"`
+	// Protected blocks the deletion of the zone until it is unlocked.
+	Protected bool `json:"protected"`
+	// Defaults are prefilled when records are added, over the global ones.
+	Defaults ttlsettings.RecordDefaults `json:"record_defaults"`
 }
 
 // allZoneSettings is the top-level structure stored under zoneSettingsKey.
@@ -34,14 +40,9 @@ type allZoneSettings map[string]ZoneSettings
 
 // loadZoneSettings returns the stored settings for the given zone, or defaults.
 func loadZoneSettings(db *gorm.DB, zoneName string) ZoneSettings {
-	row, err := settingctrl.Get(db, zoneSettingsKey)
-	if err != nil {
-		return ZoneSettings{}
-	}
-
 	var all allZoneSettings
 
-	if err := json.Unmarshal(row.Value, &all); err != nil {
+	if err := settingctrl.GetJSON(db, zoneSettingsKey, &all); err != nil {
 		return ZoneSettings{}
 	}
 
@@ -52,29 +53,19 @@ func loadZoneSettings(db *gorm.DB, zoneName string) ZoneSettings {
 func saveZoneSettings(db *gorm.DB, zoneName string, settings ZoneSettings) error {
 	var all allZoneSettings
 
-	row, err := settingctrl.Get(db, zoneSettingsKey)
-	if err != nil && !errors.Is(err, settingctrl.ErrSettingNotFound) {
+	err := settingctrl.GetJSON(db, zoneSettingsKey, &all)
+	if err != nil && !errors.Is(err, settingctrl.ErrSettingNotFound) &&
+		!errors.Is(err, settingctrl.ErrSettingInvalidValue) {
 		return fmt.Errorf("load zone settings: %w", err)
 	}
 
-	if err == nil {
-		if jsonErr := json.Unmarshal(row.Value, &all); jsonErr != nil {
-			all = allZoneSettings{}
-		}
-	} else {
+	if err != nil || all == nil {
 		all = allZoneSettings{}
 	}
 
 	all[zoneName] = settings
 
-	data, err := json.Marshal(all)
-	if err != nil {
-		return fmt.Errorf("marshal zone settings: %w", err)
-	}
-
-	_, err = settingctrl.Set(db, zoneSettingsKey, data)
-
-	return err
+	return settingctrl.SetJSON(db, zoneSettingsKey, all)
 }
 
 // ipv4PTRName converts an IPv4 address string to its PTR record name.
@@ -145,7 +136,7 @@ func findBestReverseZoneFromList(ptrName string, reverseZones []string) string {
 // findBestReverseZone returns the name of the most-specific reverse zone in
 // PowerDNS that contains ptrName, or "" if none exists.
 func findBestReverseZone(ctx context.Context, ptrName string) (string, error) {
-	zones, err := powerdns.Engine.Zones.List(ctx)
+	zones, err := zoneindex.Default.List(ctx)
 	if err != nil {
 		return "", fmt.Errorf("list zones: %w", err)
 	}
@@ -265,13 +256,13 @@ func ipsFromCurrentZone(currentZone *pdnsapi.Zone, fqdn, rrType string) []string
 
 // applyAutoPTR creates, updates, or deletes PTR records in the appropriate
 // reverse zones for every A/AAAA change in the given list. It returns the IPs
-// for which no reverse zone could be found (PTR creation skipped).
+// for which no reverse zone could be found (PTR creation skipped). The PTR
+// changes are logged on behalf of actor, which only carries the attribution.
 func (s *Service) applyAutoPTR(
 	ctx context.Context,
 	currentZone *pdnsapi.Zone,
 	changes []RecordChange,
-	userID *uint64,
-	username, ipAddress string,
+	actor *activitylog.Entry,
 ) []string {
 	// Cache fetched reverse zones to avoid redundant API calls when multiple
 	// IPs share the same reverse zone.
@@ -329,13 +320,44 @@ func (s *Service) applyAutoPTR(
 		// Delete PTR records for IPs that are being removed.
 		for ip := range oldIPSet {
 			if !newIPSet[ip] {
-				s.deleteAutoPTR(ctx, fetchReverseZone, fqdn, ip, change.Type, userID, username, ipAddress)
+				s.deleteAutoPTR(ctx, fetchReverseZone, fqdn, ip, change.Type, actor)
 			}
 		}
 
 		// Create/replace PTR records for all IPs in the new state.
 		for ip := range newIPSet {
-			if !s.createAutoPTR(ctx, fqdn, ip, change.Type, change.TTL, userID, username, ipAddress) {
+			if !s.createAutoPTR(ctx, fqdn, ip, change.Type, change.TTL, actor) {
+				noReverseZoneIPs = append(noReverseZoneIPs, ip)
+			}
+		}
+	}
+
+	return noReverseZoneIPs
+}
+
+// applyRequestedPTRs creates the PTR records the changes ask for in their PTR
+// field, for zones without Auto-PTR. Addresses that are not among the records
+// of their change are ignored. It returns the addresses for which no reverse
+// zone could be found.
+func (s *Service) applyRequestedPTRs(ctx context.Context, changes []RecordChange, actor *activitylog.Entry) []string {
+	var noReverseZoneIPs []string
+
+	for _, change := range changes {
+		if change.Type != "A" && change.Type != rrTypeAAAA {
+			continue
+		}
+
+		fqdn := change.Name
+		if !strings.HasSuffix(fqdn, ".") {
+			fqdn += "."
+		}
+
+		for _, ip := range change.PTR {
+			if !slices.ContainsFunc(change.Records, func(r Record) bool { return r.Content == ip }) {
+				continue
+			}
+
+			if !s.createAutoPTR(ctx, fqdn, ip, change.Type, change.TTL, actor) {
 				noReverseZoneIPs = append(noReverseZoneIPs, ip)
 			}
 		}
@@ -351,8 +373,7 @@ func (s *Service) deleteAutoPTR(
 	ctx context.Context,
 	fetchReverseZone func(string) *pdnsapi.Zone,
 	fqdn, ip, rrType string,
-	userID *uint64,
-	username, ipAddress string,
+	actor *activitylog.Entry,
 ) {
 	ptrName, err := ptrNameForIP(ip, rrType)
 	if err != nil {
@@ -393,7 +414,7 @@ func (s *Service) deleteAutoPTR(
 		return
 	}
 
-	s.patchPTR(ctx, reverseZone, ptrName, currentPTR, 0, true, userID, username, ipAddress)
+	s.patchPTR(ctx, reverseZone, ptrName, currentPTR, 0, true, actor)
 }
 
 // createAutoPTR creates or replaces the PTR record for ip pointing to fqdn.
@@ -403,8 +424,7 @@ func (s *Service) createAutoPTR(
 	ctx context.Context,
 	fqdn, ip, rrType string,
 	ttl uint32,
-	userID *uint64,
-	username, ipAddress string,
+	actor *activitylog.Entry,
 ) bool {
 	ptrName, err := ptrNameForIP(ip, rrType)
 	if err != nil {
@@ -426,7 +446,7 @@ func (s *Service) createAutoPTR(
 		return false
 	}
 
-	s.patchPTR(ctx, reverseZone, ptrName, fqdn, ttl, false, userID, username, ipAddress)
+	s.patchPTR(ctx, reverseZone, ptrName, fqdn, ttl, false, actor)
 
 	return true
 }
@@ -467,8 +487,7 @@ func (s *Service) patchPTR(
 	reverseZone, ptrName, fqdn string,
 	ttl uint32,
 	del bool,
-	userID *uint64,
-	username, ipAddress string,
+	actor *activitylog.Entry,
 ) {
 	rrType := pdnsapi.RRType("PTR")
 
@@ -510,6 +529,8 @@ func (s *Service) patchPTR(
 		Bool("delete", del).
 		Msg("auto-PTR: PTR record patched")
 
+	zoneindex.Default.RefreshZone(ctx, reverseZone)
+
 	// Build a minimal diff describing the auto-PTR change.
 	action := "added"
 
@@ -535,14 +556,12 @@ func (s *Service) patchPTR(
 		},
 	}
 
-	activitylog.Record(&activitylog.Entry{
-		DB:           s.db,
-		UserID:       userID,
-		Username:     username,
-		Action:       activitylog.ActionRecordChanged,
-		ResourceType: activitylog.ResourceTypeZone,
-		ResourceName: reverseZone,
-		Details:      diff,
-		IPAddress:    ipAddress,
-	})
+	entry := *actor
+	entry.DB = s.db
+	entry.Action = activitylog.ActionRecordChanged
+	entry.ResourceType = activitylog.ResourceTypeZone
+	entry.ResourceName = reverseZone
+	entry.Details = diff
+
+	activitylog.Record(&entry)
 }
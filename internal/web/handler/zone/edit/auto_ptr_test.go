@@ -9,6 +9,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	ttlsettings "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/ttl"
 )
 
 // ── helpers ───────────────────────────────────────────────────────────────────
@@ -357,6 +358,20 @@ func TestZoneSettings_UpdateExisting(t *testing.T) {
 	}
 }
 
+func TestZoneSettings_RecordDefaults(t *testing.T) {
+	db := newTestDB(t)
+
+	settings := ZoneSettings{AutoPTR: true, Defaults: ttlsettings.RecordDefaults{TTL: 300, Nameservers: []string{"ns1.example.net."}}}
+	if err := saveZoneSettings(db, "example.com.", settings); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	got := loadZoneSettings(db, "example.com.")
+	if got.Defaults.TTL != 300 || got.Defaults.NameserversText() != "ns1.example.net." {
+		t.Fatalf("Defaults = %+v, want the saved record defaults", got.Defaults)
+	}
+}
+
 func TestZoneSettings_NilDBReturnsDefaults(t *testing.T) {
 	s := loadZoneSettings(nil, "example.com.")
 
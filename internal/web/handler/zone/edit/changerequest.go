@@ -0,0 +1,553 @@
+package zoneedit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// PathChanges is the base path of the change request pages.
+	PathChanges = handler.RootPath + "zone/changes"
+
+	templateChangeList   = "zone/changes/list"
+	templateChangeReview = "zone/changes/review"
+
+	labelChangeRequests = "Change Requests"
+
+	// maxSummaryItems is the number of RRsets named in a change summary.
+	maxSummaryItems = 5
+
+	changeStatusAll = "all"
+
+	errFailedLoadChanges  = "Failed to load change requests"
+	errFailedSaveChange   = "Failed to save the change request"
+	errInvalidChangeID    = "Invalid change request ID"
+	errChangeNotFound     = "Change request not found"
+	errMsgAlreadyReviewed = "This change request has already been reviewed."
+)
+
+// errChangeAlreadyReviewed is returned when a change request was settled
+// concurrently.
+var errChangeAlreadyReviewed = errors.New("change request was already reviewed")
+
+// NeedsApproval reports whether the record changes of the user are stored as
+// change requests: the user holds zone.propose but not zone.approve.
+func (s *Service) NeedsApproval(c fiber.Ctx) bool {
+	if s.authService == nil {
+		return false
+	}
+
+	return auth.HasPermissionInContext(c, s.authService, auth.PermZonePropose) &&
+		!auth.HasPermissionInContext(c, s.authService, auth.PermZoneApprove)
+}
+
+// proposeChanges stores changes to zoneName as a change request instead of
+// applying them and notifies the approvers. rrSets are the RRsets the changes
+// would patch.
+func (s *Service) proposeChanges(c fiber.Ctx, zoneName string, changes []RecordChange, rrSets []pdnsapi.RRset) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return handler.JSONError(c, fiber.StatusUnauthorized, handler.CodeUnauthorized, "Unauthorized", nil)
+	}
+
+	if len(rrSets) == 0 {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, "There are no changes to propose", nil)
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, errFailedSaveChange, nil)
+	}
+
+	cr := &models.ChangeRequest{
+		ZoneName:    zoneName,
+		Changes:     string(data),
+		Summary:     summarizeChanges(rrSets, zoneName),
+		RequesterID: user.ID,
+		Status:      models.ChangeRequestPending,
+	}
+
+	if err = s.db.Create(cr).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to store change request")
+
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, errFailedSaveChange, nil)
+	}
+
+	cr.Requester = user
+
+	userID := user.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &userID,
+		Username:     user.Username,
+		Action:       activitylog.ActionChangeRequested,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      map[string]any{"request_id": cr.ID, "summary": cr.Summary},
+		IPAddress:    c.IP(),
+	}))
+
+	s.notifyApprovers(c, cr)
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"pending":    true,
+		"message":    "Changes submitted for approval",
+		"request_id": cr.ID,
+	})
+}
+
+// summarizeChanges names the RRsets of a records update, e.g.
+// "www A, @ MX and 3 more".
+func summarizeChanges(rrSets []pdnsapi.RRset, zoneName string) string {
+	items := make([]string, 0, min(len(rrSets), maxSummaryItems))
+
+	for i := range rrSets {
+		if i == maxSummaryItems {
+			return strings.Join(items, ", ") + fmt.Sprintf(" and %d more", len(rrSets)-maxSummaryItems)
+		}
+
+		items = append(items, getDisplayNameForZone(pdnsapi.StringValue(rrSets[i].Name), zoneName)+" "+
+			string(*rrSets[i].Type))
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// ListChanges lists change requests. Approvers see the requests of every zone
+// they may access, other users their own. The status query parameter selects
+// pending (default), approved, rejected or all requests; zone limits the list
+// to one zone.
+func (s *Service) ListChanges(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 {
+		return handler.RenderError(c, fiber.StatusUnauthorized, "Unauthorized", "You must be signed in.", nil)
+	}
+
+	status := c.Query("status", string(models.ChangeRequestPending))
+	zone := c.Query("zone")
+
+	query := s.db.Preload("Requester").Preload("Reviewer").Order("created_at DESC")
+	if status != changeStatusAll {
+		query = query.Where("status = ?", status)
+	}
+
+	if zone != "" {
+		query = query.Where("zone_name = ?", normalizeZoneName(zone))
+	}
+
+	canApprove := s.canApprove(c)
+	if !canApprove {
+		query = query.Where("requester_id = ?", user.ID)
+	}
+
+	var requests []models.ChangeRequest
+	if err := query.Find(&requests).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to load change requests")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadChanges, nil)
+	}
+
+	if canApprove {
+		allowed, err := auth.ZoneFilter(c, s.authService)
+		if err != nil {
+			return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadChanges, nil)
+		}
+
+		visible := requests[:0]
+
+		for i := range requests {
+			if allowed == nil || allowed(requests[i].ZoneName) {
+				visible = append(visible, requests[i])
+			}
+		}
+
+		requests = visible
+	}
+
+	nav := navigation.NewContext(labelChangeRequests, "zones", "changes").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelChangeRequests, PathChanges, true)
+
+	return c.Render(templateChangeList, fiber.Map{
+		"Navigation": nav,
+		"Requests":   requests,
+		"Status":     status,
+		"Zone":       zone,
+		"CanApprove": canApprove,
+		"Success":    c.Query("success"),
+	}, handler.BaseLayout)
+}
+
+// ReviewChange renders a change request with its changes. A pending request
+// is compared with the current zone and offers the approve and reject forms
+// to approvers.
+func (s *Service) ReviewChange(c fiber.Ctx) error {
+	cr, status, msg := s.loadChangeRequest(c)
+	if cr == nil {
+		return renderChangeLoadError(c, status, msg)
+	}
+
+	return s.renderChangeReview(c, fiber.StatusOK, cr, "")
+}
+
+// ApproveChange applies a pending change request and notifies the requester.
+// Changes to RRsets that were changed since the request was made are only
+// applied when the approver confirms it with the force field.
+func (s *Service) ApproveChange(c fiber.Ctx) error {
+	cr, status, msg := s.loadChangeRequest(c)
+	if cr == nil {
+		return renderChangeLoadError(c, status, msg)
+	}
+
+	reviewer, _ := c.Locals("CurrentUser").(models.User)
+
+	switch {
+	case cr.Status != models.ChangeRequestPending:
+		return s.renderChangeReview(c, fiber.StatusConflict, cr, errMsgAlreadyReviewed)
+	case cr.RequesterID == reviewer.ID:
+		return s.renderChangeReview(c, fiber.StatusForbidden, cr, "Changes must be approved by someone else.")
+	case s.pendingDeletion(c, cr.ZoneName) != nil:
+		return s.renderChangeReview(c, fiber.StatusConflict, cr, errMsgZoneDeleted)
+	case powerdns.Engine.Client == nil:
+		return s.renderChangeReview(c, fiber.StatusServiceUnavailable, cr, powerdns.ErrMsgClientNotInitialized)
+	}
+
+	changes, err := decodeChanges(cr)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("request_id", cr.ID).Msg("failed to decode change request")
+		return s.renderChangeReview(c, fiber.StatusInternalServerError, cr, "The stored changes are invalid.")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	currentZone, err := powerdns.Engine.Zones.Get(ctx, cr.ZoneName)
+	if err != nil {
+		return s.renderChangeReview(c, fiber.StatusBadGateway, cr, "Failed to fetch zone: "+err.Error())
+	}
+
+	if c.FormValue("force") == "" && len(findConflicts(changes, currentZone)) > 0 {
+		return s.renderChangeReview(c, fiber.StatusConflict, cr,
+			"Records were changed since the request was made. Check the changes and confirm to overwrite them.")
+	}
+
+	// Comments are attributed to the user who wrote them.
+	rrSets := buildRRSetsFromChanges(changes, currentZone, cr.Requester.Username, time.Now())
+
+	if err = s.checkRecordQuota(cr.ZoneName, currentZone, rrSets); err != nil {
+		return s.renderChangeReview(c, fiber.StatusForbidden, cr, err.Error())
+	}
+
+	comment := strings.TrimSpace(c.FormValue("comment"))
+
+	err = settleChange(s.db, cr, models.ChangeRequestApproved, &reviewer, comment)
+	if errors.Is(err, errChangeAlreadyReviewed) {
+		return s.renderChangeReview(c, fiber.StatusConflict, cr, errMsgAlreadyReviewed)
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("request_id", cr.ID).Msg("failed to approve change request")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveChange, nil)
+	}
+
+	ptrNoReverseZone, err := s.patchRecords(ctx, c, cr.ZoneName, currentZone, changes, rrSets, false)
+	if err != nil {
+		if errReopen := reopenChange(s.db, cr); errReopen != nil {
+			requestid.Logger(c.Context()).Error().Err(errReopen).Uint64("request_id", cr.ID).
+				Msg("failed to reopen change request")
+		}
+
+		return s.renderChangeReview(c, fiber.StatusBadGateway, cr, "Failed to update records: "+err.Error())
+	}
+
+	reviewerID := reviewer.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &reviewerID,
+		Username:     reviewer.Username,
+		Action:       activitylog.ActionChangeApproved,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: cr.ZoneName,
+		Details: map[string]any{
+			"request_id": cr.ID,
+			"requester":  cr.Requester.Username,
+			"summary":    cr.Summary,
+			"comment":    comment,
+		},
+		IPAddress: c.IP(),
+	}))
+
+	s.notifyChangeRequester(c, cr)
+
+	msg = "Changes to " + cr.ZoneName + " applied."
+	if len(ptrNoReverseZone) > 0 {
+		msg += " No reverse zone found for the PTR records of " + strings.Join(ptrNoReverseZone, ", ") + "."
+	}
+
+	return c.Redirect().To(PathChanges + "?success=" + url.QueryEscape(msg))
+}
+
+// RejectChange declines a pending change request and notifies the requester.
+func (s *Service) RejectChange(c fiber.Ctx) error {
+	cr, status, msg := s.loadChangeRequest(c)
+	if cr == nil {
+		return renderChangeLoadError(c, status, msg)
+	}
+
+	comment := strings.TrimSpace(c.FormValue("comment"))
+	if comment == "" {
+		return s.renderChangeReview(c, fiber.StatusBadRequest, cr, "Tell the requester why the changes are rejected.")
+	}
+
+	reviewer, _ := c.Locals("CurrentUser").(models.User)
+
+	err := settleChange(s.db, cr, models.ChangeRequestRejected, &reviewer, comment)
+	if errors.Is(err, errChangeAlreadyReviewed) {
+		return s.renderChangeReview(c, fiber.StatusConflict, cr, errMsgAlreadyReviewed)
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("request_id", cr.ID).Msg("failed to reject change request")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveChange, nil)
+	}
+
+	reviewerID := reviewer.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &reviewerID,
+		Username:     reviewer.Username,
+		Action:       activitylog.ActionChangeRejected,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: cr.ZoneName,
+		Details: map[string]any{
+			"request_id": cr.ID,
+			"requester":  cr.Requester.Username,
+			"summary":    cr.Summary,
+			"comment":    comment,
+		},
+		IPAddress: c.IP(),
+	}))
+
+	s.notifyChangeRequester(c, cr)
+
+	return c.Redirect().To(PathChanges + "?success=" + url.QueryEscape("Changes to "+cr.ZoneName+" rejected."))
+}
+
+// settleChange moves a pending change request to status. The update is
+// conditional so a request cannot be approved and rejected concurrently.
+func settleChange(db *gorm.DB, cr *models.ChangeRequest, status models.ChangeRequestStatus, reviewer *models.User,
+	comment string,
+) error {
+	now := time.Now()
+
+	var reviewerID *uint64
+	if reviewer.ID != 0 {
+		id := reviewer.ID
+		reviewerID = &id
+	}
+
+	res := db.Model(&models.ChangeRequest{}).
+		Where("id = ? AND status = ?", cr.ID, models.ChangeRequestPending).
+		Updates(map[string]any{
+			"status":         status,
+			"reviewer_id":    reviewerID,
+			"review_comment": comment,
+			"reviewed_at":    now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return errChangeAlreadyReviewed
+	}
+
+	cr.Status = status
+	cr.ReviewerID = reviewerID
+	cr.ReviewComment = comment
+	cr.ReviewedAt = &now
+
+	return nil
+}
+
+// reopenChange returns an approved change request whose changes could not be
+// applied to the pending ones.
+func reopenChange(db *gorm.DB, cr *models.ChangeRequest) error {
+	err := db.Model(&models.ChangeRequest{}).
+		Where("id = ?", cr.ID).
+		Updates(map[string]any{
+			"status":         models.ChangeRequestPending,
+			"reviewer_id":    nil,
+			"review_comment": "",
+			"reviewed_at":    nil,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	cr.Status = models.ChangeRequestPending
+	cr.ReviewerID = nil
+	cr.ReviewComment = ""
+	cr.ReviewedAt = nil
+
+	return nil
+}
+
+// decodeChanges returns the proposed changes of cr.
+func decodeChanges(cr *models.ChangeRequest) ([]RecordChange, error) {
+	var changes []RecordChange
+	if err := json.Unmarshal([]byte(cr.Changes), &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// pendingChangeCount returns the number of pending change requests of
+// zoneName the user may review, or their own for other users.
+func (s *Service) pendingChangeCount(c fiber.Ctx, zoneName string) int64 {
+	query := s.db.Model(&models.ChangeRequest{}).
+		Where("zone_name = ? AND status = ?", zoneName, models.ChangeRequestPending)
+
+	if !s.canApprove(c) {
+		user, _ := c.Locals("CurrentUser").(models.User)
+		query = query.Where("requester_id = ?", user.ID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to count change requests")
+	}
+
+	return count
+}
+
+// canApprove reports whether the user may review change requests.
+func (s *Service) canApprove(c fiber.Ctx) bool {
+	return s.authService == nil || auth.HasPermissionInContext(c, s.authService, auth.PermZoneApprove)
+}
+
+// loadChangeRequest loads the change request of the :id parameter if the
+// user may see it: approvers the requests of the zones they may access,
+// other users their own. When it is nil the returned status and message
+// describe the failure.
+func (s *Service) loadChangeRequest(c fiber.Ctx) (*models.ChangeRequest, int, string) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || id == 0 {
+		return nil, fiber.StatusBadRequest, errInvalidChangeID
+	}
+
+	var cr models.ChangeRequest
+
+	err = s.db.Preload("Requester").Preload("Reviewer").First(&cr, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, errChangeNotFound
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("request_id", id).Msg("failed to load change request")
+		return nil, fiber.StatusInternalServerError, errFailedLoadChanges
+	}
+
+	user, _ := c.Locals("CurrentUser").(models.User)
+
+	if !s.canAccessZone(c, cr.ZoneName) || (cr.RequesterID != user.ID && !s.canApprove(c)) {
+		return nil, fiber.StatusNotFound, errChangeNotFound
+	}
+
+	return &cr, fiber.StatusOK, ""
+}
+
+// renderChangeLoadError renders the error page for a failed loadChangeRequest.
+func renderChangeLoadError(c fiber.Ctx, status int, msg string) error {
+	return handler.RenderError(c, status, http.StatusText(status), msg, nil)
+}
+
+// changeReviewView holds what the review page shows of a change request.
+type changeReviewView struct {
+	// Changes compares a pending request with the current zone.
+	Changes []RRsetPreview
+	// Conflicts are the RRsets changed since the request was made.
+	Conflicts []RRsetRef
+	// Proposed lists the changes of a reviewed request, or of a pending one
+	// when the zone could not be loaded.
+	Proposed []RecordChange
+	// Error explains why a pending request could not be compared.
+	Error string
+}
+
+// loadChangeReview compares a pending change request with the current zone.
+func (s *Service) loadChangeReview(c fiber.Ctx, cr *models.ChangeRequest) *changeReviewView {
+	view := &changeReviewView{}
+
+	changes, err := decodeChanges(cr)
+	if err != nil {
+		view.Error = "The stored changes are invalid."
+		return view
+	}
+
+	view.Proposed = changes
+
+	if cr.Status != models.ChangeRequestPending {
+		return view
+	}
+
+	if powerdns.Engine.Client == nil {
+		view.Error = powerdns.ErrMsgClientNotInitialized
+		return view
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, cr.ZoneName)
+	if err != nil {
+		view.Error = "Failed to fetch zone: " + err.Error()
+		return view
+	}
+
+	rrSets := buildRRSetsFromChanges(changes, zone, cr.Requester.Username, time.Now())
+	view.Changes = previewRecordsUpdate(zone, rrSets, cr.ZoneName)
+	view.Conflicts = findConflicts(changes, zone)
+	view.Proposed = nil
+
+	return view
+}
+
+func (s *Service) renderChangeReview(c fiber.Ctx, status int, cr *models.ChangeRequest, msg string) error {
+	user, _ := c.Locals("CurrentUser").(models.User)
+	title := fmt.Sprintf("Change #%d", cr.ID)
+
+	nav := navigation.NewContext(title, "zones", "changes").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelChangeRequests, PathChanges, false).
+		AddBreadcrumb(title, "", true)
+
+	return c.Status(status).Render(templateChangeReview, fiber.Map{
+		"Navigation": nav,
+		"Request":    cr,
+		"Review":     s.loadChangeReview(c, cr),
+		"CanReview":  cr.Status == models.ChangeRequestPending && s.canApprove(c) && cr.RequesterID != user.ID,
+		"Error":      msg,
+	}, handler.BaseLayout)
+}
@@ -0,0 +1,214 @@
+package zoneedit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+// templateViews renders the template name so tests can tell pages apart.
+type templateViews struct{}
+
+func (templateViews) Load() error { return nil }
+
+func (templateViews) Render(w io.Writer, name string, _ any, _ ...string) error {
+	_, _ = io.WriteString(w, name)
+	return nil
+}
+
+type changeFixture struct {
+	app       *fiber.App
+	db        *gorm.DB
+	requester models.User
+	approver  models.User
+	current   *models.User
+}
+
+// newChangeFixture seeds a requester and an approver. Requests are made as
+// the approver unless current is changed.
+func newChangeFixture(t *testing.T) *changeFixture {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.ChangeRequest{}, &models.ZoneDeletion{},
+		&models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	f := &changeFixture{db: db}
+
+	role := models.Role{Name: "user"}
+	db.Create(&role)
+
+	f.requester = models.User{Username: "alice", RoleID: role.ID, Active: true}
+	f.approver = models.User{Username: "bob", RoleID: role.ID, Active: true}
+	db.Create(&f.requester)
+	db.Create(&f.approver)
+	f.current = &f.approver
+
+	svc := &Service{cfg: &config.Config{}, db: db}
+
+	f.app = fiber.New(fiber.Config{Views: templateViews{}})
+	f.app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", *f.current)
+		return c.Next()
+	})
+	f.app.Post(PathChanges+"/:id/approve", svc.ApproveChange)
+	f.app.Post(PathChanges+"/:id/reject", svc.RejectChange)
+
+	return f
+}
+
+func (f *changeFixture) propose(t *testing.T) *models.ChangeRequest {
+	t.Helper()
+
+	cr := &models.ChangeRequest{
+		ZoneName:    "example.com.",
+		Changes:     `[{"changed":true,"name":"www.example.com.","type":"A","ttl":300,"records":[{"content":"192.0.2.1"}]}]`,
+		Summary:     "www A",
+		RequesterID: f.requester.ID,
+		Status:      models.ChangeRequestPending,
+	}
+	if err := f.db.Create(cr).Error; err != nil {
+		t.Fatalf("failed to store change request: %v", err)
+	}
+
+	return cr
+}
+
+func (f *changeFixture) post(t *testing.T, target string, form url.Values) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, target,
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp
+}
+
+func (f *changeFixture) status(t *testing.T, id uint64) models.ChangeRequestStatus {
+	t.Helper()
+
+	var stored models.ChangeRequest
+	if err := f.db.First(&stored, id).Error; err != nil {
+		t.Fatalf("failed to load change request: %v", err)
+	}
+
+	return stored.Status
+}
+
+func TestApproveChange_RequiresAnotherUser(t *testing.T) {
+	powerdns.Engine.Client = nil
+
+	f := newChangeFixture(t)
+	cr := f.propose(t)
+	f.current = &f.requester
+
+	resp := f.post(t, PathChanges+"/"+strconv.FormatUint(cr.ID, 10)+"/approve", nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status of approving an own change = %d, want 403", resp.StatusCode)
+	}
+
+	if got := f.status(t, cr.ID); got != models.ChangeRequestPending {
+		t.Errorf("status = %q, want pending", got)
+	}
+}
+
+func TestRejectChange_RequiresReason(t *testing.T) {
+	f := newChangeFixture(t)
+	cr := f.propose(t)
+	target := PathChanges + "/" + strconv.FormatUint(cr.ID, 10) + "/reject"
+
+	if resp := f.post(t, target, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status without reason = %d, want 400", resp.StatusCode)
+	}
+
+	if resp := f.post(t, target, url.Values{"comment": {"Wrong address"}}); resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("reject status = %d, want 303", resp.StatusCode)
+	}
+
+	var stored models.ChangeRequest
+	f.db.First(&stored, cr.ID)
+
+	if stored.Status != models.ChangeRequestRejected || stored.ReviewComment != "Wrong address" ||
+		stored.ReviewerID == nil || *stored.ReviewerID != f.approver.ID {
+		t.Errorf("stored = %+v", stored)
+	}
+
+	if resp := f.post(t, target, url.Values{"comment": {"Again"}}); resp.StatusCode != http.StatusConflict {
+		t.Errorf("status of rejecting twice = %d, want 409", resp.StatusCode)
+	}
+}
+
+func TestSettleChange(t *testing.T) {
+	f := newChangeFixture(t)
+	cr := f.propose(t)
+
+	if err := settleChange(f.db, cr, models.ChangeRequestApproved, &f.approver, ""); err != nil {
+		t.Fatalf("settleChange: %v", err)
+	}
+
+	stale := &models.ChangeRequest{ID: cr.ID}
+	if err := settleChange(f.db, stale, models.ChangeRequestRejected, &f.approver, "no"); !errors.Is(err,
+		errChangeAlreadyReviewed) {
+		t.Errorf("settling a reviewed request: err = %v, want errChangeAlreadyReviewed", err)
+	}
+
+	if err := reopenChange(f.db, cr); err != nil {
+		t.Fatalf("reopenChange: %v", err)
+	}
+
+	if got := f.status(t, cr.ID); got != models.ChangeRequestPending || cr.ReviewerID != nil {
+		t.Errorf("reopened status = %q, reviewer = %v", got, cr.ReviewerID)
+	}
+}
+
+func TestSummarizeChanges(t *testing.T) {
+	rrSet := func(name string, rrType pdnsapi.RRType) pdnsapi.RRset {
+		return pdnsapi.RRset{Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(rrType)}
+	}
+
+	rrSets := []pdnsapi.RRset{
+		rrSet("example.com.", pdnsapi.RRTypeMX),
+		rrSet("www.example.com.", pdnsapi.RRTypeA),
+	}
+
+	if got, want := summarizeChanges(rrSets, "example.com."), "@ MX, www A"; got != want {
+		t.Errorf("summarizeChanges = %q, want %q", got, want)
+	}
+
+	for i := range 5 {
+		rrSets = append(rrSets, rrSet("host"+strconv.Itoa(i)+".example.com.", pdnsapi.RRTypeAAAA))
+	}
+
+	want := "@ MX, www A, host0 AAAA, host1 AAAA, host2 AAAA and 2 more"
+	if got := summarizeChanges(rrSets, "example.com."); got != want {
+		t.Errorf("summarizeChanges = %q, want %q", got, want)
+	}
+}
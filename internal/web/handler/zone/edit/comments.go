@@ -0,0 +1,125 @@
+package zoneedit
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+// maxCommentHistory is the number of comments kept per RRset. When a new
+// comment exceeds it, the oldest ones are dropped.
+const maxCommentHistory = 20
+
+// CommentEntry is one comment of an RRset's comment history.
+type CommentEntry struct {
+	Content    string `json:"content"`
+	Account    string `json:"account"`     // Username of the author
+	ModifiedAt int64  `json:"modified_at"` // Unix time
+}
+
+// sortedComments returns the comments of rrSet oldest first, leaving out the
+// empty placeholders earlier versions stored on every RRset.
+func sortedComments(rrSet *pdnsapi.RRset) []pdnsapi.Comment {
+	if rrSet == nil {
+		return nil
+	}
+
+	comments := make([]pdnsapi.Comment, 0, len(rrSet.Comments))
+
+	for _, comment := range rrSet.Comments {
+		if pdnsapi.StringValue(comment.Content) == "" && pdnsapi.StringValue(comment.Account) == "" {
+			continue
+		}
+
+		comments = append(comments, comment)
+	}
+
+	slices.SortStableFunc(comments, func(a, b pdnsapi.Comment) int {
+		return cmp.Compare(pdnsapi.Uint64Value(a.ModifiedAt), pdnsapi.Uint64Value(b.ModifiedAt))
+	})
+
+	return comments
+}
+
+// extractCommentFromRRSet returns the latest comment of an RRset.
+func extractCommentFromRRSet(rrSet *pdnsapi.RRset) string {
+	comments := sortedComments(rrSet)
+	if len(comments) == 0 {
+		return ""
+	}
+
+	return pdnsapi.StringValue(comments[len(comments)-1].Content)
+}
+
+// extractCommentHistory returns the comments of an RRset newest first.
+func extractCommentHistory(rrSet *pdnsapi.RRset) []CommentEntry {
+	comments := sortedComments(rrSet)
+	history := make([]CommentEntry, 0, len(comments))
+
+	for _, comment := range slices.Backward(comments) {
+		history = append(history, CommentEntry{
+			Content:    pdnsapi.StringValue(comment.Content),
+			Account:    pdnsapi.StringValue(comment.Account),
+			ModifiedAt: int64(pdnsapi.Uint64Value(comment.ModifiedAt)),
+		})
+	}
+
+	return history
+}
+
+// commentsForChange returns the comments to store with an RRset whose comment
+// is set to comment. The history of current is kept; a changed comment is
+// appended with account as its author.
+func commentsForChange(current *pdnsapi.RRset, comment, account string, now time.Time) []pdnsapi.Comment {
+	comments := sortedComments(current)
+
+	latest := ""
+	if len(comments) > 0 {
+		latest = pdnsapi.StringValue(comments[len(comments)-1].Content)
+	}
+
+	if comment == latest {
+		return comments
+	}
+
+	comments = append(comments, pdnsapi.Comment{
+		Content:    pdnsapi.String(comment),
+		Account:    pdnsapi.String(account),
+		ModifiedAt: pdnsapi.Uint64(uint64(now.Unix())),
+	})
+
+	if len(comments) > maxCommentHistory {
+		comments = comments[len(comments)-maxCommentHistory:]
+	}
+
+	return comments
+}
+
+// rrSetIndex maps the lower-cased name and type ("name/TYPE") of the RRsets
+// of zone to the RRset.
+func rrSetIndex(zone *pdnsapi.Zone) map[string]*pdnsapi.RRset {
+	if zone == nil {
+		return nil
+	}
+
+	index := make(map[string]*pdnsapi.RRset, len(zone.RRsets))
+
+	for i := range zone.RRsets {
+		rrSet := &zone.RRsets[i]
+		if rrSet.Name == nil || rrSet.Type == nil {
+			continue
+		}
+
+		index[rrSetKey(*rrSet.Name, string(*rrSet.Type))] = rrSet
+	}
+
+	return index
+}
+
+// rrSetKey returns the rrSetIndex key of an RRset.
+func rrSetKey(name, rrType string) string {
+	return strings.ToLower(name) + "/" + rrType
+}
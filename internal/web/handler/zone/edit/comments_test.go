@@ -0,0 +1,97 @@
+package zoneedit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func comment(content, account string, at uint64) pdnsapi.Comment {
+	return pdnsapi.Comment{Content: pdnsapi.String(content), Account: pdnsapi.String(account), ModifiedAt: pdnsapi.Uint64(at)}
+}
+
+func TestCommentHistory(t *testing.T) {
+	rrSet := &pdnsapi.RRset{Comments: []pdnsapi.Comment{
+		comment("second", "bob", 200),
+		comment("", "", 50), // placeholder written by earlier versions
+		comment("first", "alice", 100),
+	}}
+
+	if got := extractCommentFromRRSet(rrSet); got != "second" {
+		t.Errorf("extractCommentFromRRSet() = %q, want second", got)
+	}
+
+	history := extractCommentHistory(rrSet)
+	if len(history) != 2 || history[0].Account != "bob" || history[1].Content != "first" || history[1].ModifiedAt != 100 {
+		t.Errorf("extractCommentHistory() = %+v", history)
+	}
+
+	if got := extractCommentFromRRSet(&pdnsapi.RRset{Comments: []pdnsapi.Comment{comment("", "", 1)}}); got != "" {
+		t.Errorf("placeholder comment = %q, want empty", got)
+	}
+}
+
+func TestCommentsForChange(t *testing.T) {
+	now := time.Unix(300, 0)
+	current := &pdnsapi.RRset{Comments: []pdnsapi.Comment{comment("first", "alice", 100)}}
+
+	if got := commentsForChange(current, "first", "bob", now); len(got) != 1 || *got[0].Account != "alice" {
+		t.Errorf("unchanged comment rewritten: %+v", got)
+	}
+
+	got := commentsForChange(current, "second", "bob", now)
+	if len(got) != 2 || *got[1].Content != "second" || *got[1].Account != "bob" || *got[1].ModifiedAt != 300 {
+		t.Errorf("changed comment not appended: %+v", got)
+	}
+
+	if got := commentsForChange(current, "", "bob", now); len(got) != 2 || *got[1].Content != "" {
+		t.Errorf("cleared comment not recorded: %+v", got)
+	}
+
+	if got := commentsForChange(nil, "", "bob", now); len(got) != 0 {
+		t.Errorf("empty comment on new RRset stored: %+v", got)
+	}
+}
+
+func TestCommentsForChangeTrimsHistory(t *testing.T) {
+	current := &pdnsapi.RRset{}
+	for i := range maxCommentHistory {
+		current.Comments = append(current.Comments, comment(fmt.Sprint(i), "alice", uint64(i)))
+	}
+
+	got := commentsForChange(current, "new", "bob", time.Unix(1000, 0))
+	if len(got) != maxCommentHistory || *got[0].Content != "1" || *got[len(got)-1].Content != "new" {
+		t.Errorf("history not trimmed to the newest %d: first %q, last %q", maxCommentHistory,
+			*got[0].Content, *got[len(got)-1].Content)
+	}
+}
+
+func TestBuildRRSetsFromChangesKeepsComments(t *testing.T) {
+	zone := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{{
+		Name: pdnsapi.String("www.example.com."), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeA),
+		Comments: []pdnsapi.Comment{comment("web", "alice", 100)},
+	}}}
+
+	changes := []RecordChange{
+		{
+			Existed: true, Changed: true, Name: "WWW.example.com.", Type: "A", TTL: 300, Comment: "web",
+			Records: []Record{{Content: "192.0.2.1"}},
+		},
+		{Existed: true, Changed: true, Name: "old.example.com.", Type: "A"},
+	}
+
+	rrSets := buildRRSetsFromChanges(changes, zone, "bob", time.Unix(300, 0))
+	if len(rrSets) != 2 {
+		t.Fatalf("got %d RRsets, want 2", len(rrSets))
+	}
+
+	if c := rrSets[0].Comments; len(c) != 1 || *c[0].Account != "alice" {
+		t.Errorf("replace lost the comment history: %+v", c)
+	}
+
+	if c := rrSets[1].Comments; c != nil {
+		t.Errorf("delete carries comments: %+v", c)
+	}
+}
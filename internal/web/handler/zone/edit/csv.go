@@ -0,0 +1,455 @@
+package zoneedit
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+const (
+	// csvImportField is the multipart field holding the uploaded CSV file.
+	csvImportField = "file"
+
+	// csvApex is the name column value of the zone apex.
+	csvApex = "@"
+
+	// csvLabelsColumn is the optional last column of the import, holding the
+	// labels of the RRset. The export always writes it.
+	csvLabelsColumn = "labels"
+)
+
+// csvColumns are the columns the import requires.
+var csvColumns = []string{"name", "type", "ttl", "content", "disabled", "comment"}
+
+// exportColumns is the column layout of the export: csvColumns and the labels
+// column, so an exported file imports as it is.
+var exportColumns = slices.Concat(csvColumns, []string{csvLabelsColumn})
+
+var errCSVNoRecords = errors.New("the file contains no records")
+
+// writeRecordsCSV writes records in the import format. Names are relative to
+// the zone, with @ for the apex.
+func writeRecordsCSV(w io.Writer, records []RecordData) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(exportColumns); err != nil {
+		return err
+	}
+
+	for i := range records {
+		rec := &records[i]
+
+		if err := cw.Write([]string{
+			rec.DisplayName,
+			rec.Type,
+			strconv.FormatUint(uint64(rec.TTL), 10),
+			rec.Content,
+			strconv.FormatBool(rec.Disabled),
+			rec.Comment,
+			rec.Labels.String(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// readRecordsCSV parses a record CSV into one change per RRset, in the order
+// the RRsets first appear. All rows of an RRset must share the TTL and, when
+// set, the comment and labels. With the labels column it also returns the
+// labels of every RRset in the file, empty for those without; otherwise the
+// labels are nil.
+func readRecordsCSV(r io.Reader, zoneName string) ([]RecordChange, map[labels.Target]labels.Labels, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errCSVNoRecords
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !validCSVHeader(header) {
+		return nil, nil, fmt.Errorf("unexpected header %q, want %q or %q", strings.Join(header, ","),
+			strings.Join(csvColumns, ","), strings.Join(exportColumns, ","))
+	}
+
+	var (
+		changes  []RecordChange
+		index    = map[string]int{}
+		imported map[labels.Target]labels.Labels
+	)
+
+	hasLabels := len(header) == len(exportColumns)
+	if hasLabels {
+		imported = map[labels.Target]labels.Labels{}
+	}
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		change, rec, err := parseCSVRow(row, zoneName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var rowLabels labels.Labels
+
+		if hasLabels {
+			if rowLabels, err = labels.Parse(row[len(csvColumns)]); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+
+		key := change.Name + "/" + change.Type
+		target := labels.Target{Name: change.Name, Type: change.Type}
+
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(changes)
+			changes = append(changes, change)
+
+			if hasLabels {
+				imported[target] = rowLabels
+			}
+
+			continue
+		}
+
+		existing := &changes[i]
+		if existing.TTL != change.TTL {
+			return nil, nil, fmt.Errorf("line %d: TTL %d differs from %d for %s %s", line, change.TTL, existing.TTL,
+				change.Name, change.Type)
+		}
+
+		if change.Comment != "" && existing.Comment != "" && change.Comment != existing.Comment {
+			return nil, nil, fmt.Errorf("line %d: conflicting comments for %s %s", line, change.Name, change.Type)
+		}
+
+		if existing.Comment == "" {
+			existing.Comment = change.Comment
+		}
+
+		if len(rowLabels) > 0 {
+			if len(imported[target]) > 0 && !maps.Equal(imported[target], rowLabels) {
+				return nil, nil, fmt.Errorf("line %d: conflicting labels for %s %s", line, change.Name, change.Type)
+			}
+
+			imported[target] = rowLabels
+		}
+
+		existing.Records = append(existing.Records, rec)
+	}
+
+	if len(changes) == 0 {
+		return nil, nil, errCSVNoRecords
+	}
+
+	return changes, imported, nil
+}
+
+// validCSVHeader reports whether header names the columns of csvColumns,
+// optionally followed by the labels column. The reader then requires every
+// row to have as many fields as the header.
+func validCSVHeader(header []string) bool {
+	want := csvColumns
+	if len(header) == len(exportColumns) {
+		want = exportColumns
+	}
+
+	if len(header) != len(want) {
+		return false
+	}
+
+	for i, col := range want {
+		if !strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")), col) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseCSVRow parses one CSV row into a single-record change.
+func parseCSVRow(row []string, zoneName string) (RecordChange, Record, error) {
+	name, err := csvRecordName(strings.TrimSpace(row[0]), zoneName)
+	if err != nil {
+		return RecordChange{}, Record{}, err
+	}
+
+	rrType := strings.ToUpper(strings.TrimSpace(row[1]))
+	if rrType == "" {
+		return RecordChange{}, Record{}, errors.New("type is required")
+	}
+
+	if isDNSSECManaged(rrType) {
+		return RecordChange{}, Record{}, fmt.Errorf("%s records are managed by DNSSEC", rrType)
+	}
+
+	ttl, err := strconv.ParseUint(strings.TrimSpace(row[2]), 10, 32)
+	if err != nil {
+		return RecordChange{}, Record{}, fmt.Errorf("invalid TTL %q", row[2])
+	}
+
+	content := strings.TrimSpace(row[3])
+	if content == "" {
+		return RecordChange{}, Record{}, errors.New("content is required")
+	}
+
+	disabled := false
+
+	if raw := strings.TrimSpace(row[4]); raw != "" {
+		if disabled, err = strconv.ParseBool(raw); err != nil {
+			return RecordChange{}, Record{}, fmt.Errorf("invalid disabled flag %q", row[4])
+		}
+	}
+
+	rec := Record{Content: content, Disabled: disabled}
+
+	return RecordChange{
+		Changed: true,
+		Name:    name,
+		Type:    rrType,
+		TTL:     uint32(ttl),
+		Records: []Record{rec},
+		Comment: strings.TrimSpace(row[5]),
+	}, rec, nil
+}
+
+// csvRecordName turns a name column value into a fully qualified name inside
+// zoneName. Relative names are appended to the zone; names with a trailing dot
+// must belong to it.
+func csvRecordName(name, zoneName string) (string, error) {
+	switch {
+	case name == "":
+		return "", errors.New("name is required")
+	case name == csvApex:
+		return zoneName, nil
+	case strings.HasSuffix(name, "."):
+		lower := strings.ToLower(name)
+		zone := strings.ToLower(zoneName)
+
+		if lower != zone && !strings.HasSuffix(lower, "."+zone) {
+			return "", fmt.Errorf("%s is not in zone %s", name, zoneName)
+		}
+
+		return name, nil
+	default:
+		return name + "." + zoneName, nil
+	}
+}
+
+// markImportChanges sets Existed for RRsets present in the zone and clears
+// Changed for RRsets identical to the current ones, so unchanged rows are
+// neither written nor logged.
+func markImportChanges(zone *pdnsapi.Zone, changes []RecordChange) {
+	current := rrSetIndex(zone)
+
+	for i := range changes {
+		change := &changes[i]
+
+		rrSet, ok := current[rrSetKey(change.Name, change.Type)]
+		if !ok {
+			continue
+		}
+
+		change.Existed = true
+		change.Changed = !sameRRSet(rrSet, change)
+	}
+}
+
+// sameRRSet reports whether change would leave rrSet as it is.
+func sameRRSet(rrSet *pdnsapi.RRset, change *RecordChange) bool {
+	if rrSet.TTL == nil || *rrSet.TTL != change.TTL || extractCommentFromRRSet(rrSet) != change.Comment ||
+		len(rrSet.Records) != len(change.Records) {
+		return false
+	}
+
+	existing := make(map[string]bool, len(rrSet.Records))
+
+	for _, rec := range rrSet.Records {
+		if rec.Content != nil {
+			existing[*rec.Content] = rec.Disabled != nil && *rec.Disabled
+		}
+	}
+
+	for _, rec := range change.Records {
+		disabled, ok := existing[ensureQuotedContent(change.Type, rec.Content)]
+		if !ok || disabled != rec.Disabled {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ExportCSV downloads the records of a zone in the CSV import format. The
+// query parameters of the RRsets endpoint select and order the records, see
+// exportRecords.
+func (s *Service) ExportCSV(c fiber.Ctx) error {
+	zoneName, records, err := s.exportRecords(c)
+	if err != nil {
+		return err
+	}
+
+	logger := requestid.Logger(c.Context())
+
+	c.Attachment(strings.TrimSuffix(zoneName, ".") + ".csv")
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		if err := writeRecordsCSV(w, records); err != nil {
+			logger.Error().Err(err).Str("zone_name", zoneName).Msg("failed to write records CSV")
+		}
+	})
+}
+
+// ImportCSV applies an uploaded record CSV to a zone. Each RRset in the file
+// replaces the RRset of the same name and type; RRsets not in the file are
+// left untouched. With the labels column, the labels of the RRsets in the file
+// are replaced too, once the records were saved or proposed.
+func (s *Service) ImportCSV(c fiber.Ctx) error {
+	zoneName := c.Params("name")
+	if zoneName == "" {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	zoneName = normalizeZoneName(zoneName)
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	changes, imported, err := readUploadedCSV(c, zoneName)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, "Invalid CSV: "+err.Error(), nil)
+	}
+
+	if powerdns.Engine.Client == nil {
+		requestid.Logger(c.Context()).Error().Msg(powerdns.ErrMsgClientNotInitialized)
+
+		return handler.JSONError(c, fiber.StatusServiceUnavailable, handler.CodeUnavailable,
+			powerdns.ErrMsgClientNotInitialized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeUpstream,
+			fmt.Sprintf("failed to fetch zone: %v", err), nil)
+	}
+
+	markImportChanges(zone, changes)
+
+	request := RecordsUpdateRequest{Import: true}
+
+	for i := range changes {
+		if changes[i].Changed {
+			request.Changes = append(request.Changes, changes[i])
+		}
+	}
+
+	if len(request.Changes) == 0 {
+		message := "No changes to import"
+		if n := s.importLabels(c, zoneName, imported); n > 0 {
+			message = fmt.Sprintf("Updated the labels of %d RRsets", n)
+		}
+
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": message,
+		})
+	}
+
+	if err = s.applyRecordsUpdate(c, zoneName, &request); err != nil {
+		return err
+	}
+
+	if c.Response().StatusCode() == fiber.StatusOK {
+		s.importLabels(c, zoneName, imported)
+	}
+
+	return nil
+}
+
+// importLabels replaces the labels of the RRsets in imported that differ
+// from the stored ones and returns how many it changed. Failures are logged.
+func (s *Service) importLabels(c fiber.Ctx, zoneName string, imported map[labels.Target]labels.Labels) int {
+	if len(imported) == 0 {
+		return 0
+	}
+
+	current, err := labels.Records(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load record labels")
+		return 0
+	}
+
+	changed := 0
+
+	for target, l := range imported {
+		if maps.Equal(current[target], l) {
+			continue
+		}
+
+		if err := s.setLabels(c, zoneName, target, l); err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).
+				Str("name", target.Name).Str("type", target.Type).Msg("failed to import labels")
+
+			continue
+		}
+
+		changed++
+	}
+
+	return changed
+}
+
+// readUploadedCSV reads and parses the uploaded CSV file.
+func readUploadedCSV(c fiber.Ctx, zoneName string) ([]RecordChange, map[labels.Target]labels.Labels, error) {
+	header, err := c.FormFile(csvImportField)
+	if err != nil {
+		return nil, nil, errors.New("no file uploaded")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return readRecordsCSV(file, zoneName)
+}
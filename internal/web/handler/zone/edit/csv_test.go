@@ -0,0 +1,168 @@
+package zoneedit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+)
+
+func TestRecordsCSVRoundTrip(t *testing.T) {
+	const zone = "example.com."
+
+	ttl := uint32(3600)
+	rrSets := []pdnsapi.RRset{
+		{
+			Name: pdnsapi.String("example.com."), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeTXT), TTL: &ttl,
+			Records: []pdnsapi.Record{
+				{Content: pdnsapi.String(`"v=spf1 -all"`), Disabled: pdnsapi.Bool(false)},
+				{Content: pdnsapi.String(`"a, \"quoted\" value"`), Disabled: pdnsapi.Bool(true)},
+			},
+			Comments: []pdnsapi.Comment{{Content: pdnsapi.String("mail, policy")}},
+		},
+		{
+			Name: pdnsapi.String("www.example.com."), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeA), TTL: &ttl,
+			Records: []pdnsapi.Record{{Content: pdnsapi.String("192.0.2.1"), Disabled: pdnsapi.Bool(false)}},
+		},
+	}
+
+	records := extractRecordsFromRRSets(rrSets, zone, getDisplayNameForZone)
+	for i := range records {
+		if records[i].Type == "A" {
+			records[i].Labels = labels.Labels{"owner": "web-team", "ticket": "JIRA-123"}
+		}
+	}
+
+	var b strings.Builder
+	if err := writeRecordsCSV(&b, records); err != nil {
+		t.Fatalf("writeRecordsCSV() error: %v", err)
+	}
+
+	if !strings.HasPrefix(b.String(), "name,type,ttl,content,disabled,comment,labels\n@,TXT,3600,") ||
+		!strings.HasSuffix(b.String(), "\nwww,A,3600,192.0.2.1,false,,\"owner=web-team, ticket=JIRA-123\"\n") {
+		t.Errorf("unexpected CSV:\n%s", b.String())
+	}
+
+	// The export is in the import format, labels included.
+	changes, imported, err := readRecordsCSV(strings.NewReader(b.String()), zone)
+	if err != nil {
+		t.Fatalf("readRecordsCSV() error: %v", err)
+	}
+
+	wantLabels := map[labels.Target]labels.Labels{
+		{Name: "example.com.", Type: "TXT"}:   {},
+		{Name: "www.example.com.", Type: "A"}: {"owner": "web-team", "ticket": "JIRA-123"},
+	}
+
+	if !reflect.DeepEqual(imported, wantLabels) {
+		t.Errorf("readRecordsCSV() labels = %v, want %v", imported, wantLabels)
+	}
+
+	want := []RecordChange{
+		{
+			Changed: true, Name: "example.com.", Type: "TXT", TTL: 3600, Comment: "mail, policy",
+			Records: []Record{{Content: `"v=spf1 -all"`}, {Content: `"a, \"quoted\" value"`, Disabled: true}},
+		},
+		{
+			Changed: true, Name: "www.example.com.", Type: "A", TTL: 3600,
+			Records: []Record{{Content: "192.0.2.1"}},
+		},
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("readRecordsCSV() = %+v, want %+v", changes, want)
+	}
+
+	markImportChanges(&pdnsapi.Zone{RRsets: rrSets}, changes)
+
+	for _, change := range changes {
+		if !change.Existed || change.Changed {
+			t.Errorf("%s %s: existed=%v changed=%v, want unchanged existing RRset",
+				change.Name, change.Type, change.Existed, change.Changed)
+		}
+	}
+}
+
+func TestReadRecordsCSV_Names(t *testing.T) {
+	input := "Name,Type,TTL,Content,Disabled,Comment\n" +
+		"mail,mx,300,10 mx.example.com.,,\n" +
+		"ftp.example.com.,CNAME,300,www.example.com.,0,\n"
+
+	changes, imported, err := readRecordsCSV(strings.NewReader(input), "example.com.")
+	if err != nil {
+		t.Fatalf("readRecordsCSV() error: %v", err)
+	}
+
+	if imported != nil {
+		t.Errorf("readRecordsCSV() labels = %v, want nil without the labels column", imported)
+	}
+
+	if len(changes) != 2 || changes[0].Name != "mail.example.com." || changes[0].Type != "MX" ||
+		changes[1].Name != "ftp.example.com." {
+		t.Errorf("readRecordsCSV() = %+v", changes)
+	}
+}
+
+func TestReadRecordsCSV_Invalid(t *testing.T) {
+	const (
+		header       = "name,type,ttl,content,disabled,comment\n"
+		labelsHeader = "name,type,ttl,content,disabled,comment,labels\n"
+	)
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty file", input: ""},
+		{name: "header only", input: header},
+		{name: "wrong header", input: "name,type,content,ttl,disabled,comment\nwww,A,300,192.0.2.1,false,\n"},
+		{name: "missing column", input: header + "www,A,300,192.0.2.1,false\n"},
+		{name: "bad TTL", input: header + "www,A,5m,192.0.2.1,false,\n"},
+		{name: "bad disabled flag", input: header + "www,A,300,192.0.2.1,maybe,\n"},
+		{name: "empty content", input: header + "www,A,300,,false,\n"},
+		{name: "name outside zone", input: header + "www.example.org.,A,300,192.0.2.1,false,\n"},
+		{name: "DNSSEC type", input: header + "@,RRSIG,300,x,false,\n"},
+		{name: "TTL mismatch", input: header + "www,A,300,192.0.2.1,false,\nwww,A,600,192.0.2.2,false,\n"},
+		{name: "comment mismatch", input: header + "www,A,300,192.0.2.1,false,a\nwww,A,300,192.0.2.2,false,b\n"},
+		{name: "invalid label", input: labelsHeader + "www,A,300,192.0.2.1,false,,Owner!=x\n"},
+		{
+			name:  "labels mismatch",
+			input: labelsHeader + "www,A,300,192.0.2.1,false,,owner=a\nwww,A,300,192.0.2.2,false,,owner=b\n",
+		},
+		{name: "labels column missing", input: labelsHeader + "www,A,300,192.0.2.1,false,\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := readRecordsCSV(strings.NewReader(tc.input), "example.com."); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestMarkImportChanges(t *testing.T) {
+	ttl := uint32(300)
+	zone := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{{
+		Name: pdnsapi.String("www.example.com."), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeA), TTL: &ttl,
+		Records: []pdnsapi.Record{{Content: pdnsapi.String("192.0.2.1"), Disabled: pdnsapi.Bool(false)}},
+	}}}
+
+	changes := []RecordChange{
+		{Changed: true, Name: "www.example.com.", Type: "A", TTL: 300, Records: []Record{{Content: "192.0.2.1", Disabled: true}}},
+		{Changed: true, Name: "new.example.com.", Type: "A", TTL: 300, Records: []Record{{Content: "192.0.2.2"}}},
+	}
+
+	markImportChanges(zone, changes)
+
+	if !changes[0].Existed || !changes[0].Changed {
+		t.Errorf("disabled flag change not detected: %+v", changes[0])
+	}
+
+	if changes[1].Existed || !changes[1].Changed {
+		t.Errorf("new RRset: %+v", changes[1])
+	}
+}
@@ -0,0 +1,134 @@
+package zoneedit
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+)
+
+const (
+	// errMsgZoneDeleted is returned when changing a zone that waits for its purge.
+	errMsgZoneDeleted = "This zone is deleted and waits for its purge. Restore it to make changes."
+
+	// errMsgZoneProtected is returned when deleting a protected zone.
+	errMsgZoneProtected = "This zone is protected against deletion. Unlock it in the zone settings first."
+
+	// errMsgAlreadyDeleted is returned when deleting a zone that waits for its purge.
+	errMsgAlreadyDeleted = "Zone is already deleted"
+)
+
+// pendingDeletion returns the open soft delete of zoneName, or nil. Errors are
+// logged and yield nil, so the editor still loads.
+func (s *Service) pendingDeletion(c fiber.Ctx, zoneName string) *models.ZoneDeletion {
+	deletion, err := zonedeletion.Pending(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load zone deletion")
+	}
+
+	return deletion
+}
+
+// softDelete freezes zoneName and schedules its purge after the configured
+// grace period instead of deleting it right away. snapshot is the zone as
+// fetched before, or nil with snapErr set.
+func (s *Service) softDelete(c fiber.Ctx, zoneName string, snapshot *activitylog.ZoneSnapshot,
+	snapErr error,
+) (*models.ZoneDeletion, error) {
+	if snapErr != nil {
+		requestid.Logger(c.Context()).Error().Err(snapErr).Str("zone_name", zoneName).
+			Msg("failed to fetch zone for soft delete")
+
+		return nil, &ChangeError{
+			Status:  fiber.StatusInternalServerError,
+			Code:    handler.CodeUpstream,
+			Message: "Failed to fetch zone: " + snapErr.Error(),
+			Err:     snapErr,
+		}
+	}
+
+	userID, username := auth.Actor(c)
+	purgeAt := time.Now().Add(s.cfg.ZoneDeletion.GracePeriod)
+
+	deletion, err := zonedeletion.Schedule(s.db, zoneName, snapshot, purgeAt, userID)
+	if errors.Is(err, zonedeletion.ErrAlreadyDeleted) {
+		return nil, &ChangeError{Status: fiber.StatusConflict, Code: handler.CodeConflict, Message: errMsgAlreadyDeleted}
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to schedule zone deletion")
+
+		return nil, &ChangeError{
+			Status:  fiber.StatusInternalServerError,
+			Code:    handler.CodeInternal,
+			Message: "Failed to delete zone",
+		}
+	}
+
+	requestid.Logger(c.Context()).Info().
+		Str("zone_name", zoneName).
+		Time("purge_at", purgeAt).
+		Msg("Zone scheduled for deletion")
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionZoneDeletionScheduled,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details: map[string]any{
+			"deletion_id": deletion.ID,
+			"purge_at":    purgeAt.UTC().Format(time.RFC3339),
+		},
+		IPAddress: c.IP(),
+	}))
+
+	return deletion, nil
+}
+
+// DescribeDuration spells out d in days, hours and minutes, e.g. "1 day 12
+// hours", for the delete confirmation and the trash page.
+func DescribeDuration(d time.Duration) string {
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	var parts []string
+
+	for _, u := range units {
+		n := int(d / u.size)
+		if n == 0 {
+			continue
+		}
+
+		d -= time.Duration(n) * u.size
+
+		part := strconv.Itoa(n) + " " + u.name
+		if n > 1 {
+			part += "s"
+		}
+
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 0 {
+		return "less than a minute"
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,119 @@
+package zoneedit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+)
+
+func newDeletionTestApp(t *testing.T) (*fiber.App, *gorm.DB) {
+	t.Helper()
+
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&models.Role{}, &models.User{}, &models.ZoneDeletion{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	svc := &Service{db: db, cfg: &config.Config{ZoneDeletion: config.ZoneDeletion{GracePeriod: time.Hour}}}
+
+	app := fiber.New(fiber.Config{Views: &noopViews{}})
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", models.User{ID: 1, Username: "alice"})
+		return c.Next()
+	})
+	app.Post(Path+"/delete", svc.Delete)
+	app.Post(Path+"/records", svc.PostRecords)
+	app.Post(Path+"/schedules", svc.PostSchedule)
+
+	return app, db
+}
+
+func postJSON(t *testing.T, app *fiber.App, path, body string) (int, handler.APIError) {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var apiErr handler.APIError
+	_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+
+	return resp.StatusCode, apiErr
+}
+
+func TestDelete_ProtectedZone(t *testing.T) {
+	app, db := newDeletionTestApp(t)
+
+	if err := saveZoneSettings(db, "example.com.", ZoneSettings{Protected: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, apiErr := postJSON(t, app, "/zone/edit/example.com/delete", "")
+	if status != http.StatusConflict || apiErr.Code != handler.CodeConflict ||
+		!strings.Contains(apiErr.Message, "protected") {
+		t.Errorf("delete protected zone: status %d, %+v", status, apiErr)
+	}
+}
+
+func TestDeletedZoneIsFrozen(t *testing.T) {
+	app, db := newDeletionTestApp(t)
+
+	if _, err := zonedeletion.Schedule(db, "example.com.", nil, time.Now().Add(time.Hour), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		body string
+		want string
+	}{
+		{"/zone/edit/example.com/delete", "", "already deleted"},
+		{"/zone/edit/example.com/records", `{"changes":[]}`, "waits for its purge"},
+		{"/zone/edit/example.com/schedules", `{}`, "waits for its purge"},
+	}
+
+	for _, tt := range tests {
+		status, apiErr := postJSON(t, app, tt.path, tt.body)
+		if status != http.StatusConflict || !strings.Contains(apiErr.Message, tt.want) {
+			t.Errorf("POST %s: status %d, %+v; want 409 %q", tt.path, status, apiErr, tt.want)
+		}
+	}
+
+	// Other zones are not affected.
+	if status, _ := postJSON(t, app, "/zone/edit/other.com/records", `{"changes":[]}`); status == http.StatusConflict {
+		t.Error("POST records of another zone: status 409")
+	}
+}
+
+func TestDescribeDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		30 * 24 * time.Hour:        "30 days",
+		36 * time.Hour:             "1 day 12 hours",
+		time.Hour + 90*time.Second: "1 hour 1 minute",
+		10 * time.Second:           "less than a minute",
+	}
+
+	for d, want := range tests {
+		if got := DescribeDuration(d); got != want {
+			t.Errorf("DescribeDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
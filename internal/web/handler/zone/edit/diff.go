@@ -60,6 +60,24 @@ func buildZoneSettingsDiff(
 		})
 	}
 
+	if oldSettings.Protected != form.Protected {
+		diff.Fields = append(diff.Fields, activitylog.FieldDiff{
+			Field: "protected",
+			Old:   strconv.FormatBool(oldSettings.Protected),
+			New:   strconv.FormatBool(form.Protected),
+		})
+	}
+
+	for _, f := range []activitylog.FieldDiff{
+		{Field: "default_ttl", Old: oldSettings.Defaults.TTLText(), New: form.DefaultTTL},
+		{Field: "default_nameservers", Old: oldSettings.Defaults.NameserversText(), New: form.DefaultNameservers},
+		{Field: "default_mx_priority", Old: oldSettings.Defaults.MXPriorityText(), New: form.DefaultMXPriority},
+	} {
+		if f.Old != f.New {
+			diff.Fields = append(diff.Fields, f)
+		}
+	}
+
 	return diff
 }
 
@@ -151,41 +169,6 @@ func extractOldRRSetData(oldRRSet *pdnsapi.RRset, exists bool) ([]string, uint32
 	return oldContents, oldTTL
 }
 
-// buildZoneSnapshot captures the full state of a zone for activity log storage.
-func buildZoneSnapshot(zone *pdnsapi.Zone) *activitylog.ZoneSnapshot {
-	snapshot := &activitylog.ZoneSnapshot{}
-
-	if zone.Kind != nil {
-		snapshot.Kind = string(*zone.Kind)
-	}
-
-	snapshot.SOAEditAPI = string(getSOAEditAPIFromZone(zone))
-	snapshot.Masters = zone.Masters
-
-	for _, rr := range zone.RRsets {
-		if rr.Name == nil || rr.Type == nil || rr.TTL == nil {
-			continue
-		}
-
-		var records []string
-
-		for _, r := range rr.Records {
-			if r.Content != nil {
-				records = append(records, *r.Content)
-			}
-		}
-
-		snapshot.RRsets = append(snapshot.RRsets, activitylog.RRsetSnapshot{
-			Name:    *rr.Name,
-			Type:    string(*rr.Type),
-			TTL:     *rr.TTL,
-			Records: records,
-		})
-	}
-
-	return snapshot
-}
-
 func determineRecordChangeAction(change *RecordChange, hasOld bool) string {
 	switch {
 	case !change.Existed || !hasOld:
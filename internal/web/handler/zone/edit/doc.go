@@ -9,19 +9,41 @@
 //   - URI record content normalization per RFC 7553 (priority, weight, target).
 //   - Record comments and enabled/disabled state handling.
 //   - Filtering of allowed record types based on application settings.
+//   - LUA records, gated by the zone.lua permission and checked for
+//     well-formed content; see validateLUARecords.
+//   - Name and TTL policies set by administrators, which users with the
+//     zone.policy_override and zone.ttl_override permissions may bypass;
+//     see validateNamePolicies and validateTTLPolicies.
+//   - Zone health issues found by package zonecheck.
+//   - An email security panel evaluating the SPF, DMARC and DKIM records with
+//     package emailsec, whose guided forms compose records for the editor.
 //   - Persists changes via the shared PowerDNS engine and API client.
 //
 // Primary entrypoints
 //   - (*Service).Get: renders the edit form for a given zone.
 //   - (*Service).Post: updates general zone properties (kind, SOA-EDIT-API, masters).
 //   - (*Service).PostRecords: applies record (RRset) changes.
+//   - (*Service).PostMetadata: updates zone metadata (ALSO-NOTIFY, AXFR access, SOA-EDIT).
+//   - (*Service).ExportCSV / (*Service).ImportCSV: download and upload records as CSV.
+//   - (*Service).PostTTL: previews or applies a TTL change across several RRsets.
+//   - (*Service).GetEmailSecurity / (*Service).PostEmailSecurity: evaluate the
+//     email security records and compose SPF, DMARC and DKIM records.
+//   - (*Service).PostRetrieve / (*Service).PostNotify: trigger an AXFR or a NOTIFY.
+//   - (*Service).ListChanges / (*Service).ApproveChange / (*Service).RejectChange:
+//     review the record changes of users with zone.propose but not zone.approve,
+//     which applyRecordsUpdate stores as change requests instead of applying.
+//   - (*Service).PatchRRsets / (*Service).DeleteZone: apply a PowerDNS RRsets
+//     patch or delete a zone with the checks of the editor, for the
+//     PowerDNS-compatible API of package pdnscompat. ZoneRevision is the ETag
+//     that API sends; PatchRRsets refuses a patch of a zone at another revision.
 //
 // Conventions and helpers
 //   - Zone names are treated as fully-qualified (with a trailing dot); see normalizeZoneName.
 //   - Display names for records omit the zone suffix; see getDisplayNameForZone.
 //   - SOA-EDIT-API values are extracted with safe defaults; see getSOAEditAPIFromZone.
 //   - Quoted string validation and normalization for record content is provided by
-//     isQuotedStringSequence and ensureQuotedContent.
+//     isQuotedStringSequence and ensureQuotedContent; quoteTXT splits TXT data
+//     into character strings of at most 255 bytes.
 //
 // Templates & routing
 //   - The package uses the "zone/edit" template for rendering the edit page.
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"net/url"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -21,12 +23,21 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/emailsec"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/mailer"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/recordtrash"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/userzones"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/namepolicy"
 	ttlsettings "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/ttl"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
-	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonecheck"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonesnapshot"
 )
 
 // uriRecordRe matches the RFC 7553 content format for URI records:
@@ -80,8 +91,15 @@ type ZoneForm struct {
 	Name       string     `form:"name"`
 	Kind       string     `form:"kind"         validate:"required,oneof=Native Master Slave"`
 	SOAEditAPI SOAEditAPI `form:"soa_edit_api" validate:"required,oneof=DEFAULT INCREASE EPOCH OFF"`
-	Masters    string     `form:"masters"`  // Comma-separated list for Slave zones
-	AutoPTR    bool       `form:"auto_ptr"` // Automatically create PTR records for A/AAAA changes
+	Masters    string     `form:"masters"`   // Comma-separated list for Slave zones
+	AutoPTR    bool       `form:"auto_ptr"`  // Automatically create PTR records for A/AAAA changes
+	Protected  bool       `form:"protected"` // Block deletion of the zone
+	// Record defaults of the zone; empty fields fall back to the global ones.
+	DefaultTTL         string `form:"default_ttl"`
+	DefaultNameservers string `form:"default_nameservers"`
+	DefaultMXPriority  string `form:"default_mx_priority"`
+	// NameserverSet is the ID of the nameserver set of the zone, or 0.
+	NameserverSet uint `form:"nameserver_set"`
 }
 
 // RecordData represents a single DNS record for display.
@@ -93,6 +111,12 @@ type RecordData struct {
 	Content     string `json:"content"`
 	Disabled    bool   `json:"disabled"`
 	Comment     string `json:"comment"` // Record comment
+	// History is the comment history of the RRset, newest first.
+	History []CommentEntry `json:"comment_history,omitempty"`
+	// Revision fingerprints the RRset; see rrsetRevision.
+	Revision string `json:"revision,omitempty"`
+	// Labels are the labels of the RRset.
+	Labels labels.Labels `json:"labels,omitempty"`
 }
 
 // RecordChange represents a change to be applied to records.
@@ -104,6 +128,13 @@ type RecordChange struct {
 	TTL     uint32   `json:"ttl"`
 	Records []Record `json:"records"`
 	Comment string   `json:"comment"` // Comment for the RRset
+	// Base is the revision of the RRset the change was made against, empty
+	// for an RRset that did not exist. When set, the change is rejected if
+	// the RRset has changed since.
+	Base *string `json:"base,omitempty"`
+	// PTR lists addresses of Records to create PTR records for in zones
+	// without Auto-PTR.
+	PTR []string `json:"ptr,omitempty"`
 }
 
 // Record represents a single record entry.
@@ -115,6 +146,15 @@ type Record struct {
 // RecordsUpdateRequest represents the request for updating records.
 type RecordsUpdateRequest struct {
 	Changes []RecordChange `json:"changes"`
+	// Force applies the changes even to RRsets changed since their base
+	// revision.
+	Force bool `json:"force"`
+	// Preview validates the changes and responds with what they would change
+	// without applying them.
+	Preview bool `json:"preview"`
+	// Import marks the changes of a CSV import, which are saved as a zone
+	// snapshot first.
+	Import bool `json:"-"`
 }
 
 // RecordTypeOption represents a record type option for the dropdown.
@@ -132,6 +172,7 @@ type Service struct {
 	db          *gorm.DB
 	validator   *validator.Validate
 	authService *auth.Service
+	mailer      mailer.Sender
 }
 
 // Handler is the edit zone handler.
@@ -149,6 +190,10 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 	s.validator = validator.New()
 	s.authService = authService
 
+	if cfg.Mail.Enabled() {
+		s.mailer = mailer.New(&cfg.Mail)
+	}
+
 	// register routes with permission checks
 	app.Get(Path,
 		auth.RequirePermission(authService, auth.PermZoneUpdate),
@@ -158,27 +203,120 @@ func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authServ
 		auth.RequirePermission(authService, auth.PermZoneUpdate),
 		s.Post,
 	)
+	app.Post(Path+"/metadata",
+		auth.RequirePermission(authService, auth.PermZoneMetadata),
+		s.PostMetadata,
+	)
+	app.Get(Path+"/rrsets",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.GetRRsets,
+	)
+	app.Get(Path+"/labels",
+		auth.RequirePermission(authService, auth.PermZoneRead),
+		s.GetLabels,
+	)
+	app.Post(Path+"/labels",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostLabels,
+	)
+	app.Get(Path+"/expiries",
+		auth.RequirePermission(authService, auth.PermZoneRead),
+		s.GetExpiries,
+	)
+	app.Post(Path+"/expiry",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostExpiry,
+	)
+	app.Get(Path+"/email-security",
+		auth.RequirePermission(authService, auth.PermZoneRead),
+		s.GetEmailSecurity,
+	)
+	app.Post(Path+"/email-security",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostEmailSecurity,
+	)
 	app.Post(Path+"/records",
 		auth.RequirePermission(authService, auth.PermZoneUpdate),
 		s.PostRecords,
 	)
+	app.Post(Path+"/records/preview",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostRecordsPreview,
+	)
+	app.Get(Path+"/export.csv",
+		auth.RequirePermission(authService, auth.PermZoneRead),
+		s.ExportCSV,
+	)
+	app.Get(Path+"/export.xlsx",
+		auth.RequirePermission(authService, auth.PermZoneRead),
+		s.ExportXLSX,
+	)
+	app.Post(Path+"/import",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.ImportCSV,
+	)
+	app.Post(Path+"/ttl",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostTTL,
+	)
+	app.Post(Path+"/axfr-retrieve",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostRetrieve,
+	)
+	app.Post(Path+"/reverse-zones",
+		auth.RequirePermission(authService, auth.PermZoneCreate),
+		s.PostReverseZones,
+	)
+	app.Post(Path+"/notify",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostNotify,
+	)
 	app.Post(Path+"/delete",
 		auth.RequirePermission(authService, auth.PermZoneDelete),
 		s.Delete,
 	)
+	app.Get(Path+"/schedules",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.GetSchedules,
+	)
+	app.Post(Path+"/schedules",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostSchedule,
+	)
+	app.Post(Path+"/schedules/:id/cancel",
+		auth.RequirePermission(authService, auth.PermZoneUpdate),
+		s.PostScheduleCancel,
+	)
+
+	app.Get(PathChanges,
+		auth.RequireAnyPermission(authService, auth.PermZoneApprove, auth.PermZonePropose),
+		s.ListChanges,
+	)
+	app.Get(PathChanges+"/:id",
+		auth.RequireAnyPermission(authService, auth.PermZoneApprove, auth.PermZonePropose),
+		s.ReviewChange,
+	)
+	app.Post(PathChanges+"/:id/approve",
+		auth.RequirePermission(authService, auth.PermZoneApprove),
+		s.ApproveChange,
+	)
+	app.Post(PathChanges+"/:id/reject",
+		auth.RequirePermission(authService, auth.PermZoneApprove),
+		s.RejectChange,
+	)
 }
 
 // Get handles the edit zone page rendering.
 func (s *Service) Get(c fiber.Ctx) error {
 	zoneName := c.Params("name")
 	if zoneName == "" {
-		return c.Status(fiber.StatusBadRequest).SendString(ErrMsgZoneNameRequired)
+		return fiber.NewError(fiber.StatusBadRequest, ErrMsgZoneNameRequired)
 	}
 
 	zoneName = normalizeZoneName(zoneName)
 
 	if !s.canAccessZone(c, zoneName) {
-		return c.Status(fiber.StatusForbidden).SendString("Access to this zone is not permitted")
+		return fiber.NewError(fiber.StatusForbidden, "Access to this zone is not permitted")
 	}
 
 	// Create navigation context
@@ -197,6 +335,12 @@ func (s *Service) Get(c fiber.Ctx) error {
 		return nil
 	}
 
+	if user, ok := c.Locals("CurrentUser").(models.User); ok && user.ID != 0 {
+		if err = userzones.RecordVisit(s.db, user.ID, zoneName, time.Now()); err != nil {
+			requestid.Logger(c.Context()).Warn().Err(err).Str("zone_name", zoneName).Msg("failed to record zone visit")
+		}
+	}
+
 	// Extract SOA-EDIT-API and masters
 	soaEditAPI := getSOAEditAPIFromZone(zone)
 	masters := strings.Join(zone.Masters, ", ")
@@ -206,7 +350,7 @@ func (s *Service) Get(c fiber.Ctx) error {
 
 	// Collect reverse and forward zone names for cross-zone hints and the
 	// Auto-PTR checkbox warning. A single zone list call serves both purposes.
-	listCtx, listCancel := context.WithTimeout(context.Background(), defaultTimeout)
+	listCtx, listCancel := context.WithTimeout(c.Context(), defaultTimeout)
 	defer listCancel()
 
 	reverseZoneNames, forwardZoneNames := buildZoneLists(listCtx)
@@ -218,16 +362,39 @@ func (s *Service) Get(c fiber.Ctx) error {
 		SOAEditAPI: soaEditAPI,
 		Masters:    masters,
 		AutoPTR:    zoneSettings.AutoPTR,
+		Protected:  zoneSettings.Protected,
+
+		DefaultTTL:         zoneSettings.Defaults.TTLText(),
+		DefaultNameservers: zoneSettings.Defaults.NameserversText(),
+		DefaultMXPriority:  zoneSettings.Defaults.MXPriorityText(),
 	}
 
+	nameserverSets, nameserverSet := s.nameserverSets(c, zoneName)
+	form.NameserverSet = nameserverSet
+
 	// Extract records from RRsets
 	records := extractRecordsFromRRSets(zone.RRsets, zoneName, getDisplayNameForZone)
+	s.attachLabels(c, zoneName, records)
+
+	zoneLabels, err := labels.Zone(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load zone labels")
+	}
 
 	// Check DNSSEC status
 	dnssecEnabled := zone.DNSsec != nil && *zone.DNSsec
 
-	// Load allowed record types from settings
-	allowedRecordTypes := s.loadAllowedRecordTypes(zoneIsReverse(*zone.Name))
+	// Load allowed record types from settings, narrowed to the types the
+	// user's roles may modify.
+	editableTypes := s.editableRecordTypes(c)
+	allowedRecordTypes := filterEditableRecordTypes(s.loadAllowedRecordTypes(zoneIsReverse(*zone.Name)), editableTypes)
+
+	canEditLUA := s.canEditLUA(c)
+	if !canEditLUA {
+		allowedRecordTypes = slices.DeleteFunc(allowedRecordTypes, func(o RecordTypeOption) bool {
+			return o.Type == recordTypeLUA
+		})
+	}
 
 	// Sort record types alphabetically by type
 	sort.Slice(allowedRecordTypes, func(i, j int) bool {
@@ -259,24 +426,71 @@ func (s *Service) Get(c fiber.Ctx) error {
 	// json.Marshal escapes </>, & by default — safe to embed in a <script> tag.
 	// Build a map of PTR name → reverse zone name for A/AAAA records that
 	// already have a matching PTR record, so the UI can show a hint badge.
-	existingPTRs := buildExistingPTRsMap(listCtx, records, reverseZoneNames)
-
-	initJSON, err := json.Marshal(map[string]interface{}{
-		"zoneName":     *zone.Name,
-		"records":      records,
-		"allowedTypes": allowedRecordTypes,
-		"pageSize":     recordsPageSize,
-		"ttlPresets":   ttlPresets,
-		"reverseZones": reverseZoneNames,
-		"forwardZones": forwardZoneNames,
-		"existingPTRs": existingPTRs,
-	})
+	//
+	// Large zones embed only the first page of records; the page loads the
+	// others from GetRRsets as the user pages, filters and searches.
+	pageRecords := records
+	lazy := len(groupRRsets(records)) > LazyRRsetThreshold
+
+	var firstPage RRsetPage
+	if lazy {
+		firstPage = pageRRsets(records, &RRsetQuery{Page: 1, PageSize: recordsPageSize})
+		pageRecords = firstPage.Records
+	}
+
+	existingPTRs := buildExistingPTRsMap(listCtx, pageRecords, reverseZoneNames)
+
+	initData := map[string]interface{}{
+		"zoneName":      *zone.Name,
+		"records":       pageRecords,
+		"allowedTypes":  allowedRecordTypes,
+		"pageSize":      recordsPageSize,
+		"ttlPresets":    ttlPresets,
+		"reverseZones":  reverseZoneNames,
+		"forwardZones":  forwardZoneNames,
+		"existingPTRs":  existingPTRs,
+		"autoPTR":       zoneSettings.AutoPTR && !zoneIsReverse(zoneName),
+		"canAddZones":   auth.HasPermissionInContext(c, s.authService, auth.PermZoneCreate),
+		"editableTypes": sortedTypeList(editableTypes),
+		"canEditLua":    canEditLUA,
+		"luaTypes":      luaResultTypes,
+		"schedules":     s.loadScheduleViews(c, zoneName),
+		"zoneLabels":    zoneLabels,
+		"expiries":      s.loadExpiryViews(c, zoneName),
+		"ttlPolicies":   ttlsettings.ForZone(ttlsettings.LoadPolicies(s.db), zoneName),
+		"defaults":      zoneSettings.Defaults.Or(ttlsettings.LoadDefaults(s.db)),
+		"ttlOverride":   s.canOverrideTTL(c),
+		"namePolicies":  namepolicy.LoadRules(s.db),
+		"nameOverride":  s.canOverrideNamePolicies(c),
+	}
+
+	if lazy {
+		initData["lazy"] = true
+		initData["rrsets"] = firstPage.RRsets
+		initData["total"] = firstPage.Total
+		initData["types"] = recordTypes(records)
+	}
+
+	initJSON, err := json.Marshal(initData)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to marshal zone init data")
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to marshal zone init data")
 
 		initJSON = []byte(`{"zoneName":"","records":[],"allowedTypes":[],"pageSize":25}`)
 	}
 
+	// Zone metadata is only loaded for users who may change it.
+	var metadata *metadataView
+
+	if auth.HasPermissionInContext(c, s.authService, auth.PermZoneMetadata) {
+		metadata = loadMetadataView(listCtx, zoneName)
+		metadata.Error = c.Query("metadataError")
+	}
+
+	transfer := loadTransferView(listCtx, zoneName, *zone.Kind)
+	if transfer != nil {
+		transfer.Error = c.Query("transferError")
+	}
+
 	// Render form with existing zone data
 	return c.Render(TemplateName, fiber.Map{
 		"Navigation":         nav,
@@ -288,16 +502,47 @@ func (s *Service) Get(c fiber.Ctx) error {
 		"RecordsPageSize":    recordsPageSize,
 		"InitDataJSON":       template.JS(initJSON), //nolint:gosec // safe: json.Marshal escapes HTML chars
 		"Success":            c.Query("success"),
+		"Error":              c.Query("error"),
 		"IsReverse":          zoneIsReverse(zoneName),
 		"ReverseZoneNames":   reverseZoneNames,
+		"Metadata":           metadata,
+		"Transfer":           transfer,
+		"Tab":                c.Query("tab"),
+		"Deletion":           s.pendingDeletion(c, zoneName),
+		"NeedsApproval":      s.NeedsApproval(c),
+		"PendingChanges":     s.pendingChangeCount(c, zoneName),
+		"TrashCount":         s.trashCount(c, zoneName),
+		"CanDelete":          auth.HasPermissionInContext(c, s.authService, auth.PermZoneDelete),
+		"SoftDelete":         s.cfg.ZoneDeletion.SoftDelete(),
+		"GracePeriod":        DescribeDuration(s.cfg.ZoneDeletion.GracePeriod),
+		"Health":             zonecheck.Check(zone, time.Now()),
+		"SPFMaxLookups":      emailsec.MaxLookups,
+		"LameDelegations":    s.lameDelegations(c, zoneName),
+		"NameserverSets":     nameserverSets,
+		"CanAssignNSSet":     auth.HasPermissionInContext(c, s.authService, auth.PermAdminNameserverSets),
 	}, handler.BaseLayout)
 }
 
+// lameDelegations returns the delegations of the zone whose last check found
+// lame nameservers.
+func (s *Service) lameDelegations(c fiber.Ctx, zoneName string) []models.DelegationCheck {
+	var checks []models.DelegationCheck
+
+	err := s.db.Where("zone_name = ? AND status <> ?", zoneName, models.DelegationOK).
+		Order("name").Find(&checks).Error
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).
+			Msg("failed to load delegation checks")
+	}
+
+	return checks
+}
+
 // Post handles the edit zone form submission.
 func (s *Service) Post(c fiber.Ctx) error {
 	zoneName := c.Params("name")
 	if zoneName == "" {
-		return c.Status(fiber.StatusBadRequest).SendString(ErrMsgZoneNameRequired)
+		return fiber.NewError(fiber.StatusBadRequest, ErrMsgZoneNameRequired)
 	}
 
 	// Ensure the zone name ends with a dot
@@ -306,7 +551,7 @@ func (s *Service) Post(c fiber.Ctx) error {
 	}
 
 	if !s.canAccessZone(c, zoneName) {
-		return c.Status(fiber.StatusForbidden).SendString("Access to this zone is not permitted")
+		return fiber.NewError(fiber.StatusForbidden, "Access to this zone is not permitted")
 	}
 
 	// Create navigation context
@@ -314,10 +559,15 @@ func (s *Service) Post(c fiber.Ctx) error {
 		AddBreadcrumb("Dashboard", dashboard.Path, false).
 		AddBreadcrumb(PageTitle, "", true)
 
+	if s.pendingDeletion(c, zoneName) != nil {
+		return handler.RenderError(c, fiber.StatusConflict, "Zone Deleted", errMsgZoneDeleted,
+			&handler.ErrorAction{Label: "Back to Zone", URL: "/zone/edit/" + zoneName, Icon: "bi-arrow-left"})
+	}
+
 	// Parse form data
 	form := &ZoneForm{}
 	if err := c.Bind().Body(form); err != nil {
-		log.Error().Err(err).Msg("failed to parse edit zone form")
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to parse edit zone form")
 
 		return c.Status(fiber.StatusBadRequest).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
@@ -336,7 +586,7 @@ func (s *Service) Post(c fiber.Ctx) error {
 			errorMessages[i] = "Field '" + ve.Field() + "' failed validation tag '" + ve.Tag() + "'"
 		}
 
-		log.Error().Err(err).Msg("validation failed for edit zone")
+		requestid.Logger(c.Context()).Error().Err(err).Msg("validation failed for edit zone")
 
 		return c.Status(fiber.StatusBadRequest).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
@@ -345,9 +595,18 @@ func (s *Service) Post(c fiber.Ctx) error {
 		}, handler.BaseLayout)
 	}
 
+	defaults, err := ttlsettings.ParseDefaults(form.DefaultTTL, form.DefaultNameservers, form.DefaultMXPriority)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).Render(TemplateName, fiber.Map{
+			"Navigation": nav,
+			"Form":       form,
+			"Error":      "Invalid record defaults: " + err.Error(),
+		}, handler.BaseLayout)
+	}
+
 	// Check if the PowerDNS client is initialized
 	if powerdns.Engine.Client == nil {
-		log.Error().Msg(powerdns.ErrMsgClientNotInitialized)
+		requestid.Logger(c.Context()).Error().Msg(powerdns.ErrMsgClientNotInitialized)
 
 		return c.Status(fiber.StatusInternalServerError).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
@@ -357,7 +616,7 @@ func (s *Service) Post(c fiber.Ctx) error {
 	}
 
 	// Update zone via PowerDNS API
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
 	defer cancel()
 
 	// Fetch the current zone state before the update so we can compute a diff.
@@ -394,7 +653,7 @@ func (s *Service) Post(c fiber.Ctx) error {
 
 	err = powerdns.Engine.Zones.Change(ctx, zoneName, &zoneUpdate)
 	if err != nil {
-		log.Error().
+		requestid.Logger(c.Context()).Error().
 			Err(err).
 			Str("zone_name", zoneName).
 			Msg("failed to update zone")
@@ -406,12 +665,14 @@ func (s *Service) Post(c fiber.Ctx) error {
 		}, handler.BaseLayout)
 	}
 
-	log.Info().
+	requestid.Logger(c.Context()).Info().
 		Str("zone_name", zoneName).
 		Str("zone_kind", form.Kind).
 		Str("soa_edit_api", string(form.SOAEditAPI)).
 		Msg("Zone updated successfully")
 
+	zoneindex.Default.RefreshZone(ctx, zoneName)
+
 	// Load old per-zone settings before overwriting (needed for diff).
 	oldZoneSettings := loadZoneSettings(s.db, zoneName)
 
@@ -419,26 +680,56 @@ func (s *Service) Post(c fiber.Ctx) error {
 	// of what was submitted.
 	autoPTR := form.AutoPTR && !zoneIsReverse(zoneName) && form.Kind != "Slave"
 
+	// Only users who may delete the zone may lock or unlock it.
+	protected := oldZoneSettings.Protected
+	if auth.HasPermissionInContext(c, s.authService, auth.PermZoneDelete) {
+		protected = form.Protected
+	}
+
 	// Persist per-zone application settings.
-	if saveErr := saveZoneSettings(s.db, zoneName, ZoneSettings{AutoPTR: autoPTR}); saveErr != nil {
-		log.Warn().Err(saveErr).Str("zone_name", zoneName).Msg("failed to save zone settings")
+	newZoneSettings := ZoneSettings{AutoPTR: autoPTR, Protected: protected, Defaults: defaults}
+	if saveErr := saveZoneSettings(s.db, zoneName, newZoneSettings); saveErr != nil {
+		requestid.Logger(c.Context()).Warn().Err(saveErr).Str("zone_name", zoneName).Msg("failed to save zone settings")
 	}
 
 	form.AutoPTR = autoPTR // keep form consistent for diff
+	form.Protected = protected
+	form.DefaultTTL = defaults.TTLText()
+	form.DefaultNameservers = defaults.NameserversText()
+	form.DefaultMXPriority = defaults.MXPriorityText()
+
+	settingsDiff := buildZoneSettingsDiff(currentZone, form, oldZoneSettings)
+
+	// Only users who manage nameserver sets may assign one to the zone.
+	var nsSetErr error
+
+	if auth.HasPermissionInContext(c, s.authService, auth.PermAdminNameserverSets) {
+		var nsSetDiff *activitylog.FieldDiff
+
+		nsSetDiff, nsSetErr = s.assignNameserverSet(ctx, c, zoneName, form.NameserverSet)
+		if nsSetDiff != nil {
+			settingsDiff.Fields = append(settingsDiff.Fields, *nsSetDiff)
+		}
+	}
 
 	// Record activity: zone updated (include before/after diff)
-	userID, username := currentUserFromSession(c)
-	activitylog.Record(
-		&activitylog.Entry{
-			DB:           s.db,
-			UserID:       userID,
-			Username:     username,
-			Action:       activitylog.ActionZoneUpdated,
-			ResourceType: activitylog.ResourceTypeZone, ResourceName: zoneName,
-			Details:   buildZoneSettingsDiff(currentZone, form, oldZoneSettings),
-			IPAddress: c.IP(),
-		},
-	)
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionZoneUpdated,
+		ResourceType: activitylog.ResourceTypeZone, ResourceName: zoneName,
+		Details:   settingsDiff,
+		IPAddress: c.IP(),
+	}))
+
+	if nsSetErr != nil {
+		requestid.Logger(c.Context()).Error().Err(nsSetErr).Str("zone_name", zoneName).Msg("failed to apply nameserver set")
+
+		return c.Redirect().To("/zone/edit/" + zoneName + "?error=" +
+			url.QueryEscape("Zone updated, but the nameserver set could not be applied: "+nsSetErr.Error()))
+	}
 
 	// Redirect back to the zone edit page with success message
 	return c.Redirect().To("/zone/edit/" + zoneName + "?success=Zone updated successfully")
@@ -446,12 +737,15 @@ func (s *Service) Post(c fiber.Ctx) error {
 
 // PostRecords handles the record updates for a zone.
 func (s *Service) PostRecords(c fiber.Ctx) error {
+	return s.postRecords(c, false)
+}
+
+// postRecords parses a records update and applies it, or previews it when
+// preview or the Preview field of the request is set.
+func (s *Service) postRecords(c fiber.Ctx, preview bool) error {
 	zoneName := c.Params("name")
 	if zoneName == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"message": ErrMsgZoneNameRequired,
-		})
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
 	}
 
 	// Ensure the zone name ends with a dot
@@ -460,118 +754,166 @@ func (s *Service) PostRecords(c fiber.Ctx) error {
 	}
 
 	if !s.canAccessZone(c, zoneName) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"success": false,
-			"message": "Access to this zone is not permitted",
-		})
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
 	}
 
 	// Parse JSON request
 	var request RecordsUpdateRequest
 	if err := c.Bind().Body(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse records update request")
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to parse records update request")
 
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"message": "Invalid request data",
-		})
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid request data", nil)
 	}
 
-	// ensure only allowed record types are being modified
-	if errValidateRecordTypes := s.validateRecordsUpdateAreValidTypes(
-		c,
-		zoneName,
-		&request,
-		zoneIsReverse(zoneName)); errValidateRecordTypes != nil {
-		return errValidateRecordTypes
+	request.Preview = request.Preview || preview
+
+	return s.applyRecordsUpdate(c, zoneName, &request)
+}
+
+// applyRecordsUpdate validates and applies the record changes of request to
+// zoneName and responds with the JSON result. With Preview set it responds
+// with the changes instead of applying them.
+func (s *Service) applyRecordsUpdate(c fiber.Ctx, zoneName string, request *RecordsUpdateRequest) error {
+	if err := s.ValidateChanges(c, zoneName, request.Changes); err != nil {
+		return respondChangeError(c, err)
 	}
 
 	// Check if the PowerDNS client is initialized
 	if powerdns.Engine.Client == nil {
-		log.Error().Msg(powerdns.ErrMsgClientNotInitialized)
+		requestid.Logger(c.Context()).Error().Msg(powerdns.ErrMsgClientNotInitialized)
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"message": powerdns.ErrMsgClientNotInitialized,
-		})
+		return handler.JSONError(c, fiber.StatusServiceUnavailable, handler.CodeUnavailable,
+			powerdns.ErrMsgClientNotInitialized, nil)
 	}
 
 	// Build RRsets for PowerDNS API
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
 	defer cancel()
 
 	// Fetch the current zone state before patching so we can diff old vs. new.
 	currentZone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"message": fmt.Sprintf("failed to fetch zone: %v", err),
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeUpstream,
+			fmt.Sprintf("failed to fetch zone: %v", err), nil)
+	}
+
+	// Refuse to overwrite RRsets changed by someone else since the page was
+	// loaded, unless the user chose to.
+	if !request.Force {
+		if conflicts := findConflicts(request.Changes, currentZone); len(conflicts) > 0 {
+			return handler.JSONError(c, fiber.StatusConflict, handler.CodeConflict, errMsgConflict,
+				fiber.Map{"conflicts": conflicts})
+		}
+	}
+
+	_, username := auth.Actor(c)
+
+	rrSets := buildRRSetsFromChanges(request.Changes, currentZone, username, time.Now())
+
+	if err = s.checkRecordQuota(zoneName, currentZone, rrSets); err != nil {
+		return respondChangeError(c, err)
+	}
+
+	if request.Preview {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"changes": previewRecordsUpdate(currentZone, rrSets, zoneName),
+			// PTR records in reverse zones are updated after the records.
+			"auto_ptr": !zoneIsReverse(zoneName) && loadZoneSettings(s.db, zoneName).AutoPTR,
+			// Saving stores the changes for approval instead.
+			"approval": s.NeedsApproval(c),
 		})
 	}
 
-	rrSets := buildRRSetsFromChanges(request.Changes)
+	// Changes of users under review wait for an approver.
+	if s.NeedsApproval(c) {
+		return s.proposeChanges(c, zoneName, request.Changes, rrSets)
+	}
+
+	ptrNoReverseZone, err := s.patchRecords(ctx, c, zoneName, currentZone, request.Changes, rrSets, request.Import)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeUpstream,
+			"Failed to update records: "+err.Error(), nil)
+	}
 
-	// Update records via PowerDNS API
-	err = powerdns.Engine.Records.Patch(ctx, zoneName, &pdnsapi.RRsets{
+	return c.JSON(fiber.Map{
+		"success":             true,
+		"message":             "Records updated successfully",
+		"ptr_no_reverse_zone": ptrNoReverseZone,
+	})
+}
+
+// patchRecords applies rrSets, built from changes, to zoneName, creates the
+// PTR records of zones with auto-PTR and records the change in the activity
+// log. currentZone is the zone before the change; it is saved as a zone
+// snapshot first for a CSV import, when imported, or a large change. It returns the addresses
+// without a reverse zone for their PTR record.
+func (s *Service) patchRecords(ctx context.Context, c fiber.Ctx, zoneName string, currentZone *pdnsapi.Zone,
+	changes []RecordChange, rrSets []pdnsapi.RRset, imported bool,
+) ([]string, error) {
+	if reason := zonesnapshot.PatchReason(s.cfg.ZoneSnapshots, len(rrSets), imported); reason != "" {
+		s.saveSnapshot(c, zoneName, reason, zonesnapshot.FromZone(currentZone))
+	}
+
+	err := powerdns.Engine.Records.Patch(ctx, zoneName, &pdnsapi.RRsets{
 		Sets: rrSets,
 	})
 	if err != nil {
-		log.Error().
+		requestid.Logger(c.Context()).Error().
 			Err(err).
 			Str("zone_name", zoneName).
 			Msg("failed to update zone records")
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"message": "Failed to update records: " + err.Error(),
-		})
+		return nil, err
 	}
 
-	log.Info().
+	requestid.Logger(c.Context()).Info().
 		Str("zone_name", zoneName).
-		Int("changes_count", len(request.Changes)).
+		Int("changes_count", len(changes)).
 		Msg("Zone records updated successfully")
 
-	userID, username := currentUserFromSession(c)
+	// The serial changed; keep the zone lists current.
+	zoneindex.Default.RefreshZone(ctx, zoneName)
+
+	userID, username := auth.Actor(c)
+
+	s.trashDeleted(c, zoneName, recordtrash.Deleted(currentZone, rrSets))
 
-	// Auto-create PTR records if enabled for this zone (forward zones only).
+	// Auto-create PTR records if enabled for this zone (forward zones only);
+	// otherwise create those the changes ask for.
 	var ptrNoReverseZone []string
 
 	if !zoneIsReverse(zoneName) {
+		actor := auth.Attribute(c, &activitylog.Entry{UserID: userID, Username: username})
+
 		if zs := loadZoneSettings(s.db, zoneName); zs.AutoPTR {
-			ptrNoReverseZone = s.applyAutoPTR(ctx, currentZone, request.Changes, userID, username, c.IP())
+			ptrNoReverseZone = s.applyAutoPTR(ctx, currentZone, changes, actor)
+		} else {
+			ptrNoReverseZone = s.applyRequestedPTRs(ctx, changes, actor)
 		}
 	}
 
 	// Record activity: record changed (include per-RRset before/after diff)
-	activitylog.Record(
-		&activitylog.Entry{
-			DB:           s.db,
-			UserID:       userID,
-			Username:     username,
-			Action:       activitylog.ActionRecordChanged,
-			ResourceType: activitylog.ResourceTypeZone,
-			ResourceName: zoneName,
-			Details:      buildRecordsDiff(currentZone, request.Changes),
-			IPAddress:    c.IP(),
-		},
-	)
-
-	return c.JSON(fiber.Map{
-		"success":               true,
-		"message":               "Records updated successfully",
-		"ptr_no_reverse_zone":   ptrNoReverseZone,
-	})
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionRecordChanged,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      buildRecordsDiff(currentZone, changes),
+		IPAddress:    c.IP(),
+	}))
+
+	return ptrNoReverseZone, nil
 }
 
 // Delete handles the zone deletion.
 func (s *Service) Delete(c fiber.Ctx) error {
 	zoneName := c.Params("name")
 	if zoneName == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"message": ErrMsgZoneNameRequired,
-		})
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
 	}
 
 	// Ensure zone name ends with a dot
@@ -580,80 +922,117 @@ func (s *Service) Delete(c fiber.Ctx) error {
 	}
 
 	if !s.canAccessZone(c, zoneName) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"success": false,
-			"message": "Access to this zone is not permitted",
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	deletion, err := s.DeleteZone(c, zoneName)
+	if err != nil {
+		return respondChangeError(c, err)
+	}
+
+	if deletion != nil {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "Zone deleted; it is purged from PowerDNS on " +
+				deletion.PurgeAt.UTC().Format("2006-01-02 15:04 MST") + " unless restored",
 		})
 	}
 
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Zone deleted successfully",
+	})
+}
+
+// DeleteZone deletes zoneName from PowerDNS and records it in the activity
+// log. With soft delete configured the zone is only scheduled for its purge
+// and the returned deletion is set. The caller checks the zone access.
+func (s *Service) DeleteZone(c fiber.Ctx, zoneName string) (*models.ZoneDeletion, error) {
+	if loadZoneSettings(s.db, zoneName).Protected {
+		return nil, &ChangeError{Status: fiber.StatusConflict, Code: handler.CodeConflict, Message: errMsgZoneProtected}
+	}
+
+	if s.pendingDeletion(c, zoneName) != nil {
+		return nil, &ChangeError{Status: fiber.StatusConflict, Code: handler.CodeConflict, Message: errMsgAlreadyDeleted}
+	}
+
 	// Check if PowerDNS client is initialized
 	if powerdns.Engine.Client == nil {
-		log.Error().Msg(powerdns.ErrMsgClientNotInitialized)
+		requestid.Logger(c.Context()).Error().Msg(powerdns.ErrMsgClientNotInitialized)
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"message": powerdns.ErrMsgClientNotInitialized,
-		})
+		return nil, &ChangeError{
+			Status:  fiber.StatusServiceUnavailable,
+			Code:    handler.CodeUnavailable,
+			Message: powerdns.ErrMsgClientNotInitialized,
+		}
 	}
 
 	// Fetch zone snapshot before deletion for potential undo.
 	var snapshot *activitylog.ZoneSnapshot
 
-	snapCtx, snapCancel := context.WithTimeout(context.Background(), defaultTimeout)
+	snapCtx, snapCancel := context.WithTimeout(c.Context(), defaultTimeout)
 	zone, snapErr := powerdns.Engine.Zones.Get(snapCtx, zoneName)
 
 	snapCancel()
 
 	if snapErr == nil && zone != nil {
-		snapshot = buildZoneSnapshot(zone)
+		snapshot = zonesnapshot.FromZone(zone)
+		s.saveSnapshot(c, zoneName, zonesnapshot.ReasonDelete, snapshot)
+	}
+
+	if s.cfg.ZoneDeletion.SoftDelete() {
+		return s.softDelete(c, zoneName, snapshot, snapErr)
 	}
 
 	// Delete zone via PowerDNS API
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
 	defer cancel()
 
 	err := powerdns.Engine.Zones.Delete(ctx, zoneName)
 	if err != nil {
-		log.Error().
+		requestid.Logger(c.Context()).Error().
 			Err(err).
 			Str("zone_name", zoneName).
 			Msg("failed to delete zone")
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"message": "Failed to delete zone: " + err.Error(),
-		})
+		return nil, &ChangeError{
+			Status:  fiber.StatusInternalServerError,
+			Code:    handler.CodeUpstream,
+			Message: "Failed to delete zone: " + err.Error(),
+			Err:     err,
+		}
 	}
 
-	log.Info().
+	requestid.Logger(c.Context()).Info().
 		Str("zone_name", zoneName).
 		Msg("Zone deleted successfully")
 
-	// Record activity: zone deleted (include snapshot for potential undo)
-	userID, username := currentUserFromSession(c)
-	activitylog.Record(
-		&activitylog.Entry{
-			DB:           s.db,
-			UserID:       userID,
-			Username:     username,
-			Action:       activitylog.ActionZoneDeleted,
-			ResourceType: activitylog.ResourceTypeZone,
-			ResourceName: zoneName,
-			Details:      snapshot,
-			IPAddress:    c.IP(),
-		},
-	)
+	zoneindex.Default.Remove(zoneName)
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "Zone deleted successfully",
-	})
+	// Record activity: zone deleted (include snapshot for potential undo)
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionZoneDeleted,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      snapshot,
+		IPAddress:    c.IP(),
+	}))
+
+	return nil, nil
 }
 
 // buildRRSetsFromChanges converts RecordChange entries into PowerDNS RRset patch operations,
-// skipping unchanged entries unless they represent a deletion.
-func buildRRSetsFromChanges(changes []RecordChange) []pdnsapi.RRset {
+// skipping unchanged entries unless they represent a deletion. The comment
+// history of the RRsets in current is kept; changed comments are attributed
+// to account.
+func buildRRSetsFromChanges(changes []RecordChange, current *pdnsapi.Zone, account string, now time.Time) []pdnsapi.RRset {
 	rrSets := make([]pdnsapi.RRset, 0, len(changes))
+	currentRRSets := rrSetIndex(current)
 
 	for _, change := range changes {
 		// Always process a deletion of an existing RRset (existed=true, no records),
@@ -689,21 +1068,20 @@ func buildRRSetsFromChanges(changes []RecordChange) []pdnsapi.RRset {
 			changeType = pdnsapi.ChangeTypeReplace
 		}
 
-		// Always include comment to allow clearing.
-		commentContent := change.Comment
-		commentAccount := ""
-		comments := []pdnsapi.Comment{
-			{Content: &commentContent, Account: &commentAccount},
-		}
-
-		rrSets = append(rrSets, pdnsapi.RRset{
+		rrSet := pdnsapi.RRset{
 			Name:       &name,
 			Type:       &rrType,
 			TTL:        &ttl,
 			ChangeType: &changeType,
 			Records:    records,
-			Comments:   comments,
-		})
+		}
+
+		// Send the comments even when unchanged: a REPLACE drops the ones left out.
+		if changeType == pdnsapi.ChangeTypeReplace {
+			rrSet.Comments = commentsForChange(currentRRSets[rrSetKey(name, change.Type)], change.Comment, account, now)
+		}
+
+		rrSets = append(rrSets, rrSet)
 	}
 
 	return rrSets
@@ -712,7 +1090,7 @@ func buildRRSetsFromChanges(changes []RecordChange) []pdnsapi.RRset {
 // getZoneOrRender validates PDNS client availability and fetches the zone; renders errors when needed.
 func (s *Service) getZoneOrRender(c fiber.Ctx, nav *navigation.Context, zoneName string) (*pdnsapi.Zone, error) {
 	if powerdns.Engine.Client == nil {
-		log.Error().Msg(powerdns.ErrMsgClientNotInitialized)
+		requestid.Logger(c.Context()).Error().Msg(powerdns.ErrMsgClientNotInitialized)
 
 		return nil, c.Status(fiber.StatusInternalServerError).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
@@ -720,12 +1098,12 @@ func (s *Service) getZoneOrRender(c fiber.Ctx, nav *navigation.Context, zoneName
 		}, handler.BaseLayout)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
 	defer cancel()
 
 	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
 	if err != nil {
-		log.Error().Err(err).Str("zone_name", zoneName).Msg("failed to fetch zone")
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to fetch zone")
 
 		return nil, c.Status(fiber.StatusNotFound).Render(TemplateName, fiber.Map{
 			"Navigation": nav,
@@ -736,31 +1114,17 @@ func (s *Service) getZoneOrRender(c fiber.Ctx, nav *navigation.Context, zoneName
 	return zone, nil
 }
 
-// canAccessZone returns false when zone-tag restrictions are in effect and the
-// given zone is not in the user's accessible set. Returns true for admin users
-// and for any user with no tag assignments (unrestricted).
+// canAccessZone returns false when zone restrictions (tags or zone patterns)
+// are in effect and the given zone is not in the user's accessible set.
+// Returns true for admin users and for any unrestricted user.
 func (s *Service) canAccessZone(c fiber.Ctx, zoneName string) bool {
-	user, ok := c.Locals("CurrentUser").(models.User)
-	if !ok || user.ID == 0 {
-		return false
-	}
-
-	if s.authService == nil {
-		return true
-	}
-
-	accessible, err := s.authService.GetAccessibleZoneIDs(user.ID)
-	if err != nil || accessible == nil {
-		return true
-	}
-
-	return accessible[zoneName]
+	return auth.CanAccessZone(c, s.authService, zoneName)
 }
 
-// buildZoneLists queries the PowerDNS zone list and splits the results into
+// buildZoneLists reads the zone index and splits the results into
 // reverse (in-addr.arpa / ip6.arpa) and forward zone name slices.
 func buildZoneLists(ctx context.Context) (reverseZones, forwardZones []string) {
-	zones, err := powerdns.Engine.Zones.List(ctx)
+	zones, err := zoneindex.Default.List(ctx)
 	if err != nil {
 		return
 	}
@@ -797,22 +1161,3 @@ func parseMasters(mastersStr string) ([]string, error) {
 
 	return masters, nil
 }
-
-// currentUserFromSession extracts the current user's ID and username from the
-// session cookie. Returns nil userID and an empty username when no valid session
-// is present.
-func currentUserFromSession(c fiber.Ctx) (*uint64, string) {
-	sid := c.Cookies("session")
-	if sid == "" {
-		return nil, ""
-	}
-
-	sd := new(session.Data)
-	if err := sd.Read(sid); err != nil || sd.User.ID == 0 {
-		return nil, ""
-	}
-
-	id := sd.User.ID
-
-	return &id, sd.User.Username
-}
@@ -1,6 +1,11 @@
 package zoneedit
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+)
 
 func TestEnsureQuotedContent_TXT(t *testing.T) {
 	tests := []struct {
@@ -69,3 +74,45 @@ func TestEnsureQuotedContent_URI(t *testing.T) {
 		})
 	}
 }
+
+func TestEnsureQuotedContent_LongTXT(t *testing.T) {
+	key := strings.Repeat("A", 300)
+
+	if got, want := ensureQuotedContent("TXT", "v=DKIM1; p="+key),
+		`"v=DKIM1; p=`+key[:244]+`" "`+key[244:]+`"`; got != want {
+		t.Errorf("unquoted: want %q, got %q", want, got)
+	}
+
+	// Strings that fit keep their boundaries; long ones are split.
+	if got, want := ensureQuotedContent("TXT", `"v=DKIM1; " "p=`+key+`"`),
+		`"v=DKIM1; " "p=`+key[:253]+`" "`+key[253:]+`"`; got != want {
+		t.Errorf("quoted: want %q, got %q", want, got)
+	}
+
+	// Escapes count as the byte they stand for, and characters are not split.
+	text := strings.Repeat(`\"`, 200) + strings.Repeat("é", 100)
+	if got, want := ensureQuotedContent("TXT", `"`+text+`"`),
+		`"`+strings.Repeat(`\"`, 200)+strings.Repeat("é", 27)+`" "`+strings.Repeat("é", 73)+`"`; got != want {
+		t.Errorf("escaped: want %q, got %q", want, got)
+	}
+}
+
+func TestQuoteTXT(t *testing.T) {
+	tests := []struct{ in, out string }{
+		{``, `""`},
+		{`C:\temp "x"`, `"C:\\temp \"x\""`},
+		{"tab\there\x7f", `"tab\009here\127"`},
+		{"caf\xe9", `"caf\233"`},
+		{"grüße", `"grüße"`},
+	}
+
+	for _, tc := range tests {
+		if got := quoteTXT(tc.in); got != tc.out {
+			t.Errorf("quoteTXT(%q) = %q, want %q", tc.in, got, tc.out)
+		}
+
+		if got := dnsclient.Unquote(quoteTXT(tc.in)); got != tc.in {
+			t.Errorf("Unquote(quoteTXT(%q)) = %q", tc.in, got)
+		}
+	}
+}
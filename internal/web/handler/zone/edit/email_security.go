@@ -0,0 +1,119 @@
+package zoneedit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/emailsec"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// Kinds of email security records a ComposeRequest builds.
+const (
+	composeSPF   = "spf"
+	composeDMARC = "dmarc"
+	composeDKIM  = "dkim"
+)
+
+var errEmailSecurityKind = errors.New("choose an SPF, DMARC or DKIM record")
+
+// ComposeRequest asks for the TXT record of one of the guided forms of the
+// email security panel; Kind selects the form.
+type ComposeRequest struct {
+	Kind  string              `json:"kind"`
+	SPF   *emailsec.SPFForm   `json:"spf"`
+	DMARC *emailsec.DMARCForm `json:"dmarc"`
+	DKIM  *emailsec.DKIMForm  `json:"dkim"`
+}
+
+// GetEmailSecurity evaluates the SPF, DMARC and DKIM records of a zone as
+// PowerDNS serves them; staged changes are not included.
+func (s *Service) GetEmailSecurity(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+	if zoneName == "." {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	if powerdns.Engine.Client == nil {
+		requestid.Logger(c.Context()).Error().Msg(powerdns.ErrMsgClientNotInitialized)
+
+		return handler.JSONError(c, fiber.StatusServiceUnavailable, handler.CodeUnavailable,
+			powerdns.ErrMsgClientNotInitialized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeUpstream,
+			fmt.Sprintf("failed to fetch zone: %v", err), nil)
+	}
+
+	return c.JSON(fiber.Map{
+		"report": emailsec.Check(zone),
+	})
+}
+
+// PostEmailSecurity composes the TXT record of a guided form of the email
+// security panel. Nothing is saved: the editor stages the record like any
+// other, so saving it goes through the usual checks.
+func (s *Service) PostEmailSecurity(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+	if zoneName == "." {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	var request ComposeRequest
+	if err := c.Bind().Body(&request); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to parse email security request")
+
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid request data", nil)
+	}
+
+	name, text, err := composeEmailRecord(zoneName, &request)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, err.Error(), nil)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"name":    name,
+		"type":    "TXT",
+		"text":    text,
+	})
+}
+
+// composeEmailRecord returns the name and text of the TXT record request
+// asks for in zoneName.
+func composeEmailRecord(zoneName string, request *ComposeRequest) (string, string, error) {
+	switch {
+	case request.Kind == composeSPF && request.SPF != nil:
+		text, err := emailsec.ComposeSPF(request.SPF)
+		return zoneName, text, err
+	case request.Kind == composeDMARC && request.DMARC != nil:
+		text, err := emailsec.ComposeDMARC(request.DMARC)
+		return "_dmarc." + zoneName, text, err
+	case request.Kind == composeDKIM && request.DKIM != nil:
+		text, err := emailsec.ComposeDKIM(request.DKIM)
+		return strings.ToLower(request.DKIM.Selector) + "._domainkey." + zoneName, text, err
+	default:
+		return "", "", errEmailSecurityKind
+	}
+}
@@ -0,0 +1,28 @@
+package zoneedit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/emailsec"
+)
+
+func TestComposeEmailRecord(t *testing.T) {
+	name, text, err := composeEmailRecord("example.com.", &ComposeRequest{
+		Kind: composeDMARC, DMARC: &emailsec.DMARCForm{Policy: "reject", Percent: 100},
+	})
+	if err != nil || name != "_dmarc.example.com." || text != "v=DMARC1; p=reject" {
+		t.Errorf("composeEmailRecord(dmarc) = %q, %q, %v", name, text, err)
+	}
+
+	name, _, err = composeEmailRecord("example.com.", &ComposeRequest{
+		Kind: composeSPF, SPF: &emailsec.SPFForm{MX: true, All: "-"},
+	})
+	if err != nil || name != "example.com." {
+		t.Errorf("composeEmailRecord(spf) = %q, %v", name, err)
+	}
+
+	if _, _, err = composeEmailRecord("example.com.", &ComposeRequest{Kind: composeDKIM}); !errors.Is(err, errEmailSecurityKind) {
+		t.Errorf("composeEmailRecord() without a form: err = %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package zoneedit
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/expiry"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// ExpiryRequest sets the expiry date of a zone, or of one of its RRsets when
+// Name and Type are set. An empty ExpiresOn removes it.
+type ExpiryRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// ExpiresOn is the date as YYYY-MM-DD.
+	ExpiresOn string `json:"expires_on"`
+	Note      string `json:"note"`
+}
+
+// ExpiryView is an expiry date as shown in the zone editor.
+type ExpiryView struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	ExpiresOn string `json:"expires_on"`
+	Note      string `json:"note"`
+	CreatedBy string `json:"created_by"`
+	// Overdue is set once the date has passed.
+	Overdue bool `json:"overdue"`
+}
+
+// newExpiryView converts an expiry for the zone editor.
+func newExpiryView(e *models.Expiry, today time.Time) ExpiryView {
+	v := ExpiryView{
+		Name:      e.Name,
+		Type:      e.Type,
+		ExpiresOn: e.ExpiresAt.UTC().Format(time.DateOnly),
+		Note:      e.Note,
+		Overdue:   e.ExpiresAt.Before(today),
+	}
+	if e.CreatedBy != nil {
+		v.CreatedBy = e.CreatedBy.FullName()
+	}
+
+	return v
+}
+
+// loadExpiryViews returns the expiries of zoneName for the zone editor. A
+// failure is logged and shows the zone without expiries.
+func (s *Service) loadExpiryViews(c fiber.Ctx, zoneName string) []ExpiryView {
+	expiries, err := expiry.Zone(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load expiries")
+		return []ExpiryView{}
+	}
+
+	today := today()
+
+	views := make([]ExpiryView, 0, len(expiries))
+	for i := range expiries {
+		views = append(views, newExpiryView(&expiries[i], today))
+	}
+
+	return views
+}
+
+// today returns the start of the current day in UTC, which expiry dates are
+// stored in.
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// GetExpiries returns the expiry dates of a zone and of its RRsets.
+func (s *Service) GetExpiries(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+	if zoneName == "." {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	return c.JSON(fiber.Map{
+		"expiries": s.loadExpiryViews(c, zoneName),
+	})
+}
+
+// PostExpiry sets or removes the expiry date of a zone or of one of its
+// RRsets. Like labels, expiry dates are kept by the application only, so
+// they take effect at once, also for users whose record changes need
+// approval.
+func (s *Service) PostExpiry(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+	if zoneName == "." {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	var request ExpiryRequest
+	if err := c.Bind().Body(&request); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to parse expiry request")
+
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid request data", nil)
+	}
+
+	target := labels.Target{Type: strings.ToUpper(strings.TrimSpace(request.Type))}
+	if request.Name != "" {
+		target.Name = normalizeZoneName(request.Name)
+	}
+
+	if (target.Name == "") != (target.Type == "") {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation,
+			"Set both name and type for the expiry of an RRset, or neither for the zone", nil)
+	}
+
+	note := strings.TrimSpace(request.Note)
+	if utf8.RuneCountInString(note) > expiry.MaxNoteLength {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation,
+			"The note is too long", nil)
+	}
+
+	var expiresAt time.Time
+
+	if request.ExpiresOn != "" {
+		var err error
+
+		expiresAt, err = time.Parse(time.DateOnly, strings.TrimSpace(request.ExpiresOn))
+		if err != nil {
+			return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation,
+				"Invalid expiry date, use YYYY-MM-DD", nil)
+		}
+
+		if expiresAt.Before(today()) {
+			return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation,
+				"The expiry date is in the past", nil)
+		}
+
+		if ferr := s.checkTarget(c, zoneName, target); ferr != nil {
+			return handler.JSONError(c, ferr.Code, handler.CodeForStatus(ferr.Code), ferr.Message, nil)
+		}
+	}
+
+	details, err := s.setExpiry(c, zoneName, target, expiresAt, note)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to save expiry")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, "Failed to save expiry", nil)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"expires_on": details.New,
+		"note":       details.Note,
+	})
+}
+
+// setExpiry sets the expiry of target in zoneName, or removes it when
+// expiresAt is zero, and records the change in the activity log.
+func (s *Service) setExpiry(
+	c fiber.Ctx, zoneName string, target labels.Target, expiresAt time.Time, note string,
+) (*activitylog.ExpiryDetails, error) {
+	details := &activitylog.ExpiryDetails{Name: target.Name, Type: target.Type}
+
+	var (
+		old    *models.Expiry
+		err    error
+		action = activitylog.ActionExpirySet
+	)
+
+	userID, username := auth.Actor(c)
+
+	if expiresAt.IsZero() {
+		action = activitylog.ActionExpiryCleared
+		old, err = expiry.Clear(s.db, zoneName, target.Name, target.Type)
+	} else {
+		details.New, details.Note = expiresAt.Format(time.DateOnly), note
+		old, err = expiry.Set(s.db, &models.Expiry{
+			ZoneName:    zoneName,
+			Name:        target.Name,
+			Type:        target.Type,
+			ExpiresAt:   expiresAt,
+			Note:        note,
+			CreatedByID: userID,
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if old != nil {
+		details.Old = old.ExpiresAt.UTC().Format(time.DateOnly)
+		if expiresAt.IsZero() {
+			details.Note = old.Note
+		}
+	}
+
+	if details.Old == details.New && (old == nil || old.Note == note) {
+		return details, nil
+	}
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      details,
+		IPAddress:    c.IP(),
+	}))
+
+	return details, nil
+}
@@ -0,0 +1,71 @@
+package zoneedit
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFilterRecords(t *testing.T) {
+	records := []RecordData{
+		{Name: "www.example.com.", DisplayName: "www", Type: "A", TTL: 300, Content: "192.0.2.1"},
+		{Name: "www.example.com.", DisplayName: "www", Type: "A", TTL: 300, Content: "192.0.2.2"},
+		{Name: "example.com.", DisplayName: "@", Type: "MX", TTL: 3600, Content: "10 mail.example.com."},
+		{Name: "mail.example.com.", DisplayName: "mail", Type: "A", TTL: 600, Content: "192.0.2.3"},
+	}
+
+	got := filterRecords(records, &RRsetQuery{Type: "A", Sort: rrsetSortTTL, Desc: true})
+
+	var contents []string
+	for _, r := range got {
+		contents = append(contents, r.Content)
+	}
+
+	// Both records of the www RRset are kept, although only one matches.
+	if want := "192.0.2.3,192.0.2.1,192.0.2.2"; strings.Join(contents, ",") != want {
+		t.Errorf("filterRecords = %v, want %s", contents, want)
+	}
+
+	if got := filterRecords(records, &RRsetQuery{Search: ".2.2"}); len(got) != 2 {
+		t.Errorf("filterRecords(search) = %+v, want the www RRset", got)
+	}
+
+	if got := filterRecords(records, &RRsetQuery{}); len(got) != len(records) || got[0].DisplayName != "@" {
+		t.Errorf("filterRecords(all) = %+v, want every record, apex first", got)
+	}
+}
+
+func TestWriteRecordsXLSX(t *testing.T) {
+	var buf bytes.Buffer
+
+	records := []RecordData{{DisplayName: "www", Type: "TXT", TTL: 300, Content: `"a & b"`, Comment: "web"}}
+	if err := writeRecordsXLSX(&buf, "example.com", records); err != nil {
+		t.Fatalf("writeRecordsXLSX: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+
+	f, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatalf("open sheet: %v", err)
+	}
+	defer f.Close()
+
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read sheet: %v", err)
+	}
+
+	for _, want := range []string{
+		">comment</t>", `<c r="C2"><v>300</v></c>`, "&#34;a &amp; b&#34;", `<c r="E2" t="b"><v>0</v>`,
+	} {
+		if !strings.Contains(string(sheet), want) {
+			t.Errorf("sheet lacks %s:\n%s", want, sheet)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package zoneedit
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// LabelsRequest replaces the labels of a zone, or of one of its RRsets when
+// Name and Type are set.
+type LabelsRequest struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+}
+
+// RRsetLabels are the labels of one RRset.
+type RRsetLabels struct {
+	labels.Target
+	Labels labels.Labels `json:"labels"`
+}
+
+// attachLabels sets the labels of records from those stored for their
+// RRsets. A failure is logged and leaves the records without labels.
+func (s *Service) attachLabels(c fiber.Ctx, zoneName string, records []RecordData) {
+	byTarget, err := labels.Records(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load record labels")
+		return
+	}
+
+	for i := range records {
+		records[i].Labels = byTarget[labels.Target{Name: records[i].Name, Type: records[i].Type}]
+	}
+}
+
+// GetLabels returns the labels of a zone and of its RRsets.
+func (s *Service) GetLabels(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+	if zoneName == "." {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	zoneLabels, err := labels.Zone(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load zone labels")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, "Failed to load labels", nil)
+	}
+
+	byTarget, err := labels.Records(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load record labels")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, "Failed to load labels", nil)
+	}
+
+	records := make([]RRsetLabels, 0, len(byTarget))
+	for target, l := range byTarget {
+		records = append(records, RRsetLabels{Target: target, Labels: l})
+	}
+
+	slices.SortFunc(records, func(a, b RRsetLabels) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Type, b.Type))
+	})
+
+	return c.JSON(fiber.Map{
+		"zone":    zoneLabels,
+		"records": records,
+	})
+}
+
+// PostLabels replaces the labels of a zone or of one of its RRsets. Labels
+// are kept by the application only, so they take effect at once, also for
+// users whose record changes need approval.
+func (s *Service) PostLabels(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+	if zoneName == "." {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	var request LabelsRequest
+	if err := c.Bind().Body(&request); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to parse labels request")
+
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid request data", nil)
+	}
+
+	next, err := labels.Normalize(request.Labels)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, err.Error(), nil)
+	}
+
+	target := labels.Target{Type: strings.ToUpper(strings.TrimSpace(request.Type))}
+	if request.Name != "" {
+		target.Name = normalizeZoneName(request.Name)
+	}
+
+	if (target.Name == "") != (target.Type == "") {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation,
+			"Set both name and type for the labels of an RRset, or neither for the zone", nil)
+	}
+
+	if ferr := s.checkTarget(c, zoneName, target); ferr != nil {
+		return handler.JSONError(c, ferr.Code, handler.CodeForStatus(ferr.Code), ferr.Message, nil)
+	}
+
+	if err := s.setLabels(c, zoneName, target, next); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to save labels")
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, "Failed to save labels", nil)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"labels":  next,
+	})
+}
+
+// setLabels replaces the labels of target in zoneName and records the
+// change in the activity log.
+func (s *Service) setLabels(c fiber.Ctx, zoneName string, target labels.Target, next labels.Labels) error {
+	old, err := labels.Set(s.db, zoneName, target, next)
+	if err != nil {
+		return err
+	}
+
+	if maps.Equal(old, next) {
+		return nil
+	}
+
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionLabelsChanged,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      &activitylog.LabelsDiff{Name: target.Name, Type: target.Type, Old: old, New: next},
+		IPAddress:    c.IP(),
+	}))
+
+	return nil
+}
+
+// checkTarget checks that the RRset of target, which labels or an expiry are
+// set on, exists in the zone, and returns the error to respond with when it
+// does not.
+func (s *Service) checkTarget(c fiber.Ctx, zoneName string, target labels.Target) *fiber.Error {
+	if target.IsZone() {
+		return nil
+	}
+
+	if target.Name != zoneName && !strings.HasSuffix(target.Name, "."+zoneName) {
+		return fiber.NewError(fiber.StatusBadRequest, "Record "+target.Name+" is not in zone "+zoneName)
+	}
+
+	if powerdns.Engine.Client == nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, powerdns.ErrMsgClientNotInitialized)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to fetch zone")
+		return fiber.NewError(fiber.StatusNotFound, "Zone not found: "+zoneName)
+	}
+
+	if _, ok := rrSetIndex(zone)[rrSetKey(target.Name, target.Type)]; !ok {
+		return fiber.NewError(fiber.StatusNotFound, "No "+target.Type+" records named "+target.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package zoneedit
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// recordTypeLUA is the PowerDNS record type whose content is Lua code
+// evaluated on every query.
+const recordTypeLUA = "LUA"
+
+// luaResultTypes are the record types a LUA record may answer with.
+var luaResultTypes = []string{"A", "AAAA", "CNAME", "LOC", "MX", "NAPTR", "NS", "PTR", "SPF", "SRV", "TXT"}
+
+var (
+	errLUAFormat       = errors.New(`LUA content must be a record type followed by quoted Lua code, e.g. A "ifportup(443, {'192.0.2.1'})"`)
+	errLUAEmptyCode    = errors.New("LUA code must not be empty")
+	errLUAUnterminated = errors.New("unterminated string in Lua code")
+	errLUAUnbalanced   = errors.New("unbalanced brackets in Lua code")
+)
+
+// parseLUA splits the content of a LUA record into the record type it answers
+// with and its Lua code. The code may be split into several quoted strings,
+// which PowerDNS concatenates.
+func parseLUA(content string) (string, string, error) {
+	rrType, quoted, ok := strings.Cut(strings.TrimSpace(content), " ")
+	if !ok || !isQuotedStringSequence(quoted) {
+		return "", "", errLUAFormat
+	}
+
+	rrType = strings.ToUpper(rrType)
+	if !slices.Contains(luaResultTypes, rrType) {
+		return "", "", fmt.Errorf("LUA records cannot answer with %s records", rrType)
+	}
+
+	var code strings.Builder
+
+	for _, m := range quotedStringRE.FindAllStringSubmatch(quoted, -1) {
+		code.WriteString(dnsclient.Unquote(m[0]))
+	}
+
+	return rrType, code.String(), nil
+}
+
+// checkLuaSyntax catches the common mistakes in Lua code before PowerDNS
+// does: empty code, unterminated strings and unbalanced brackets. It is no
+// full parser; PowerDNS still reports other errors when the record is queried.
+func checkLuaSyntax(code string) error {
+	if strings.TrimSpace(code) == "" {
+		return errLUAEmptyCode
+	}
+
+	var stack []byte
+
+	closing := map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+
+		switch {
+		case ch == '-' && strings.HasPrefix(code[i:], "--"):
+			// Comments: a long comment ends at its closing bracket, a
+			// line comment at the end of the line.
+			if level, ok := longBracket(code[i+2:]); ok {
+				end := strings.Index(code[i+2:], "]"+strings.Repeat("=", level)+"]")
+				if end < 0 {
+					return errLUAUnterminated
+				}
+
+				i += 2 + end + level + 1
+
+				continue
+			}
+
+			if end := strings.IndexByte(code[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(code)
+			}
+		case ch == '"' || ch == '\'':
+			end := shortStringEnd(code, i)
+			if end < 0 {
+				return errLUAUnterminated
+			}
+
+			i = end
+		case ch == '[':
+			if level, ok := longBracket(code[i:]); ok {
+				end := strings.Index(code[i:], "]"+strings.Repeat("=", level)+"]")
+				if end < 0 {
+					return errLUAUnterminated
+				}
+
+				i += end + level + 1
+
+				continue
+			}
+
+			stack = append(stack, ch)
+		case ch == '(' || ch == '{':
+			stack = append(stack, ch)
+		case closing[ch] != 0:
+			if len(stack) == 0 || stack[len(stack)-1] != closing[ch] {
+				return errLUAUnbalanced
+			}
+
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if len(stack) > 0 {
+		return errLUAUnbalanced
+	}
+
+	return nil
+}
+
+// longBracket reports whether s starts a Lua long bracket ([[ or [=[ ...)
+// and returns its level, the number of equal signs.
+func longBracket(s string) (int, bool) {
+	if !strings.HasPrefix(s, "[") {
+		return 0, false
+	}
+
+	level := 0
+	for level+1 < len(s) && s[level+1] == '=' {
+		level++
+	}
+
+	return level, level+1 < len(s) && s[level+1] == '['
+}
+
+// shortStringEnd returns the index of the quote closing the string that
+// starts at code[start], or -1 when the string is not closed on its line.
+func shortStringEnd(code string, start int) int {
+	quote := code[start]
+
+	for i := start + 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '\n':
+			return -1
+		case quote:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// canEditLUA reports whether the user may change LUA records.
+func (s *Service) canEditLUA(c fiber.Ctx) bool {
+	return auth.HasPermissionInContext(c, s.authService, auth.PermZoneLUA)
+}
+
+// validateLUARecords rejects changes to LUA records by users without the
+// zone.lua permission and LUA records whose content is malformed.
+func (s *Service) validateLUARecords(c fiber.Ctx, zoneName string, changes []RecordChange) error {
+	for _, change := range changes {
+		if !strings.EqualFold(change.Type, recordTypeLUA) {
+			continue
+		}
+
+		if !s.canEditLUA(c) {
+			log.Warn().Str("zone_name", zoneName).Msg("attempt to modify LUA records without permission")
+
+			return &ChangeError{
+				Status:  fiber.StatusForbidden,
+				Code:    handler.CodeForbidden,
+				Message: "You are not permitted to modify LUA records",
+				Details: fiber.Map{"record_type": recordTypeLUA},
+			}
+		}
+
+		for _, record := range change.Records {
+			_, code, err := parseLUA(record.Content)
+			if err == nil {
+				err = checkLuaSyntax(code)
+			}
+
+			if err != nil {
+				return &ChangeError{
+					Status:  fiber.StatusBadRequest,
+					Code:    handler.CodeValidation,
+					Message: "Invalid LUA record " + change.Name + ": " + err.Error(),
+					Details: fiber.Map{"record_type": recordTypeLUA, "name": change.Name, "content": record.Content},
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package zoneedit
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseLUA(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		wantType string
+		wantCode string
+		wantErr  bool
+	}{
+		{"ifportup", `A "ifportup(443, {'192.0.2.1', '192.0.2.2'})"`, "A", "ifportup(443, {'192.0.2.1', '192.0.2.2'})", false},
+		{"lower case type", `aaaa "pickrandom({'2001:db8::1'})"`, "AAAA", "pickrandom({'2001:db8::1'})", false},
+		{"escaped quote", `TXT "\"hello\""`, "TXT", `"hello"`, false},
+		{"split strings", `CNAME "if continent('EU') then return 'eu.example.' " "else return 'us.example.' end"`,
+			"CNAME", "if continent('EU') then return 'eu.example.' else return 'us.example.' end", false},
+		{"no type", `"ifportup(443, {'192.0.2.1'})"`, "", "", true},
+		{"unquoted code", `A ifportup(443, {'192.0.2.1'})`, "", "", true},
+		{"unsupported type", `SOA "return 'x'"`, "", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rrType, code, err := parseLUA(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseLUA(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			}
+
+			if rrType != tc.wantType || code != tc.wantCode {
+				t.Errorf("parseLUA(%q) = %q, %q; want %q, %q", tc.in, rrType, code, tc.wantType, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestCheckLuaSyntax(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{"call", "ifportup(443, {'192.0.2.1', '192.0.2.2'})", nil},
+		{"statements", ";if country('NL') then return {'192.0.2.1'} end return {'192.0.2.2'}", nil},
+		{"brackets in strings", `ifurlup('https://example.com/(', {{'192.0.2.1'}})`, nil},
+		{"long string", "return [[a ) b]]", nil},
+		{"long string with level", "return [==[a ]] b]==]", nil},
+		{"line comment", "return '192.0.2.1' -- (unbalanced in a comment", nil},
+		{"long comment", "--[[ ( ]] return '192.0.2.1'", nil},
+		{"index", "return t[k[1]]", nil},
+		{"empty", "  ", errLUAEmptyCode},
+		{"unterminated string", "return 'abc", errLUAUnterminated},
+		{"unterminated long string", "return [[abc", errLUAUnterminated},
+		{"missing paren", "ifportup(443, {'192.0.2.1'}", errLUAUnbalanced},
+		{"mismatched", "ifportup(443, {'192.0.2.1')}", errLUAUnbalanced},
+		{"stray close", "return 1)", errLUAUnbalanced},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkLuaSyntax(tc.code); !errors.Is(err, tc.want) {
+				t.Fatalf("checkLuaSyntax(%q) = %v, want %v", tc.code, err, tc.want)
+			}
+		})
+	}
+}
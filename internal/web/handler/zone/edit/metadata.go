@@ -0,0 +1,317 @@
+package zoneedit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+// allowAXFRAutoNS is the ALLOW-AXFR-FROM keyword that allows the zone's own
+// nameservers.
+const allowAXFRAutoNS = "AUTO-NS"
+
+// soaEditValues are the values PowerDNS accepts for the SOA-EDIT metadata.
+var soaEditValues = []string{"INCREMENT-WEEKS", "INCEPTION-EPOCH", "INCEPTION-INCREMENT", "EPOCH", "NONE"}
+
+// MetadataForm is the submitted zone metadata form. List fields are comma or
+// newline separated.
+type MetadataForm struct {
+	AlsoNotify    string `form:"also_notify"`
+	AllowAXFRFrom string `form:"allow_axfr_from"`
+	SOAEdit       string `form:"soa_edit"`
+}
+
+// metadataView is the data of the metadata tab.
+type metadataView struct {
+	AlsoNotify    string
+	AllowAXFRFrom string
+	SOAEdit       string
+	TSIGKeys      []string
+	SelectedTSIG  map[string]bool
+	SOAEditValues []string
+	// LoadFailed hides the form when the metadata could not be loaded.
+	LoadFailed bool
+	Error      string
+}
+
+// loadMetadataView loads the metadata tab data for zoneName. Failures are
+// shown on the tab instead of failing the whole page.
+func loadMetadataView(ctx context.Context, zoneName string) *metadataView {
+	view := &metadataView{SOAEditValues: soaEditValues, SelectedTSIG: map[string]bool{}}
+
+	md, err := powerdns.Engine.ZoneMetadata(ctx, zoneName)
+	if err != nil {
+		log.Error().Err(err).Str("zone_name", zoneName).Msg("failed to load zone metadata")
+
+		view.LoadFailed = true
+
+		return view
+	}
+
+	keys, err := powerdns.Engine.TSIGKeyNames(ctx)
+	if err != nil {
+		// Older PowerDNS versions or a disabled TSIG API; show the selected
+		// keys only.
+		log.Warn().Err(err).Msg("failed to list TSIG keys")
+	}
+
+	view.AlsoNotify = md.String(pdnsapi.MetadataAlsoNotify)
+	view.AllowAXFRFrom = md.String(pdnsapi.MetadataAllowAXFRFrom)
+	view.TSIGKeys = mergeKeyNames(keys, md.Get(pdnsapi.MetadataTSIGAllowAXFR))
+
+	if soaEdit := md.Get(pdnsapi.MetadataSOAEdit); len(soaEdit) > 0 {
+		view.SOAEdit = soaEdit[0]
+	}
+
+	for _, name := range md.Get(pdnsapi.MetadataTSIGAllowAXFR) {
+		view.SelectedTSIG[name] = true
+	}
+
+	return view
+}
+
+// PostMetadata updates the managed metadata kinds of a zone.
+func (s *Service) PostMetadata(c fiber.Ctx) error {
+	zoneName := c.Params("name")
+	if zoneName == "" {
+		return fiber.NewError(fiber.StatusBadRequest, ErrMsgZoneNameRequired)
+	}
+
+	zoneName = normalizeZoneName(zoneName)
+
+	if !s.canAccessZone(c, zoneName) {
+		return fiber.NewError(fiber.StatusForbidden, "Access to this zone is not permitted")
+	}
+
+	if s.pendingDeletion(c, zoneName) != nil {
+		return redirectMetadata(c, zoneName, "", errMsgZoneDeleted)
+	}
+
+	form := &MetadataForm{}
+	if err := c.Bind().Body(form); err != nil {
+		return redirectMetadata(c, zoneName, "", "Invalid form data")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	current, err := powerdns.Engine.ZoneMetadata(ctx, zoneName)
+	if err != nil {
+		log.Error().Err(err).Str("zone_name", zoneName).Msg("failed to load zone metadata")
+		return redirectMetadata(c, zoneName, "", "Failed to load zone metadata: "+err.Error())
+	}
+
+	keys, err := powerdns.Engine.TSIGKeyNames(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to list TSIG keys")
+	}
+
+	// Keys already assigned stay selectable even when they cannot be listed.
+	keys = mergeKeyNames(keys, current.Get(pdnsapi.MetadataTSIGAllowAXFR))
+
+	next, err := parseMetadataForm(form, c.Request().PostArgs().PeekMulti("tsig_allow_axfr"), keys)
+	if err != nil {
+		return redirectMetadata(c, zoneName, "", err.Error())
+	}
+
+	changed, err := powerdns.Engine.SetZoneMetadata(ctx, zoneName, current, next)
+	if len(changed) > 0 {
+		s.recordMetadataChange(c, zoneName, current, next, changed)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Str("zone_name", zoneName).Msg("failed to update zone metadata")
+		return redirectMetadata(c, zoneName, "", "Failed to update zone metadata: "+err.Error())
+	}
+
+	if len(changed) == 0 {
+		return redirectMetadata(c, zoneName, "No metadata changes.", "")
+	}
+
+	return redirectMetadata(c, zoneName, "Zone metadata updated successfully", "")
+}
+
+func (s *Service) recordMetadataChange(c fiber.Ctx, zoneName string, current, next powerdns.Metadata,
+	changed []pdnsapi.MetadataKind,
+) {
+	diff := &activitylog.ZoneSettingsDiff{}
+	for _, kind := range changed {
+		diff.Fields = append(diff.Fields, activitylog.FieldDiff{
+			Field: string(kind), Old: current.String(kind), New: next.String(kind),
+		})
+	}
+
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionZoneUpdated,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      diff,
+		IPAddress:    c.IP(),
+	}))
+}
+
+// redirectMetadata returns to the metadata tab of the zone edit page with a
+// success or error message.
+func redirectMetadata(c fiber.Ctx, zoneName, success, errMsg string) error {
+	query := url.Values{"tab": {"metadata"}}
+	if success != "" {
+		query.Set("success", success)
+	}
+
+	if errMsg != "" {
+		query.Set("metadataError", errMsg)
+	}
+
+	return c.Redirect().To("/zone/edit/" + zoneName + "?" + query.Encode())
+}
+
+// parseMetadataForm validates the form and returns the metadata to store.
+// tsigKeys are the selected TSIG-ALLOW-AXFR keys and knownKeys the keys that
+// may be selected.
+func parseMetadataForm(form *MetadataForm, tsigKeys [][]byte, knownKeys []string) (powerdns.Metadata, error) {
+	md := powerdns.Metadata{}
+
+	alsoNotify, err := parseAlsoNotify(form.AlsoNotify)
+	if err != nil {
+		return nil, err
+	}
+
+	allowAXFR, err := parseAllowAXFRFrom(form.AllowAXFRFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	var tsig []string
+
+	for _, raw := range tsigKeys {
+		name := strings.TrimSpace(string(raw))
+		if name == "" || containsString(tsig, name) {
+			continue
+		}
+
+		if !containsString(knownKeys, name) {
+			return nil, fmt.Errorf("unknown TSIG key %q", name)
+		}
+
+		tsig = append(tsig, name)
+	}
+
+	soaEdit := strings.ToUpper(strings.TrimSpace(form.SOAEdit))
+	if soaEdit != "" && !containsString(soaEditValues, soaEdit) {
+		return nil, fmt.Errorf("unsupported SOA-EDIT value %q", form.SOAEdit)
+	}
+
+	setIfAny(md, pdnsapi.MetadataAlsoNotify, alsoNotify)
+	setIfAny(md, pdnsapi.MetadataAllowAXFRFrom, allowAXFR)
+	setIfAny(md, pdnsapi.MetadataTSIGAllowAXFR, tsig)
+
+	if soaEdit != "" {
+		md[pdnsapi.MetadataSOAEdit] = []string{soaEdit}
+	}
+
+	return md, nil
+}
+
+// parseAlsoNotify accepts IP addresses with an optional port
+// (192.0.2.1, 192.0.2.1:5300, [2001:db8::1]:5300).
+func parseAlsoNotify(raw string) ([]string, error) {
+	var result []string
+
+	for _, entry := range splitList(raw) {
+		host := entry
+
+		if h, port, err := net.SplitHostPort(entry); err == nil {
+			if n, perr := strconv.Atoi(port); perr != nil || n < 1 || n > 65535 {
+				return nil, fmt.Errorf("ALSO-NOTIFY: invalid port in %q", entry)
+			}
+
+			host = h
+		}
+
+		if net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("ALSO-NOTIFY: %q is not an IP address", entry)
+		}
+
+		if !containsString(result, entry) {
+			result = append(result, entry)
+		}
+	}
+
+	return result, nil
+}
+
+// parseAllowAXFRFrom accepts IP addresses, networks in CIDR notation and
+// AUTO-NS.
+func parseAllowAXFRFrom(raw string) ([]string, error) {
+	var result []string
+
+	for _, entry := range splitList(raw) {
+		switch {
+		case strings.EqualFold(entry, allowAXFRAutoNS):
+			entry = allowAXFRAutoNS
+		case strings.Contains(entry, "/"):
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return nil, fmt.Errorf("ALLOW-AXFR-FROM: %q is not a valid network", entry)
+			}
+		case net.ParseIP(entry) == nil:
+			return nil, fmt.Errorf("ALLOW-AXFR-FROM: %q is not an IP address or network", entry)
+		}
+
+		if !containsString(result, entry) {
+			result = append(result, entry)
+		}
+	}
+
+	return result, nil
+}
+
+// splitList splits a comma, whitespace or newline separated list.
+func splitList(raw string) []string {
+	return strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+}
+
+// mergeKeyNames returns keys plus the names of extra that are missing from it.
+func mergeKeyNames(keys, extra []string) []string {
+	merged := append([]string(nil), keys...)
+
+	for _, name := range extra {
+		if !containsString(merged, name) {
+			merged = append(merged, name)
+		}
+	}
+
+	return merged
+}
+
+func setIfAny(md powerdns.Metadata, kind pdnsapi.MetadataKind, values []string) {
+	if len(values) > 0 {
+		md[kind] = values
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,73 @@
+package zoneedit
+
+import (
+	"reflect"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func TestParseMetadataForm(t *testing.T) {
+	form := &MetadataForm{
+		AlsoNotify:    "192.0.2.10, [2001:db8::10]:5300\n192.0.2.10",
+		AllowAXFRFrom: "192.0.2.0/24 auto-ns",
+		SOAEdit:       "inception-epoch",
+	}
+
+	md, err := parseMetadataForm(form, [][]byte{[]byte("xfr-key."), []byte("xfr-key.")}, []string{"xfr-key."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[pdnsapi.MetadataKind][]string{
+		pdnsapi.MetadataAlsoNotify:    {"192.0.2.10", "[2001:db8::10]:5300"},
+		pdnsapi.MetadataAllowAXFRFrom: {"192.0.2.0/24", "AUTO-NS"},
+		pdnsapi.MetadataTSIGAllowAXFR: {"xfr-key."},
+		pdnsapi.MetadataSOAEdit:       {"INCEPTION-EPOCH"},
+	}
+
+	for kind, values := range want {
+		if got := md.Get(kind); !reflect.DeepEqual(got, values) {
+			t.Errorf("%s = %v, want %v", kind, got, values)
+		}
+	}
+}
+
+func TestParseMetadataForm_EmptyClearsKinds(t *testing.T) {
+	md, err := parseMetadataForm(&MetadataForm{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(md) != 0 {
+		t.Errorf("metadata = %v, want empty", md)
+	}
+}
+
+func TestParseMetadataForm_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		form MetadataForm
+		tsig string
+	}{
+		{name: "hostname in ALSO-NOTIFY", form: MetadataForm{AlsoNotify: "ns1.example.com"}},
+		{name: "bad port in ALSO-NOTIFY", form: MetadataForm{AlsoNotify: "192.0.2.1:99999"}},
+		{name: "bad network in ALLOW-AXFR-FROM", form: MetadataForm{AllowAXFRFrom: "192.0.2.0/33"}},
+		{name: "hostname in ALLOW-AXFR-FROM", form: MetadataForm{AllowAXFRFrom: "example.com"}},
+		{name: "unknown SOA-EDIT", form: MetadataForm{SOAEdit: "INCREASE"}},
+		{name: "unknown TSIG key", tsig: "other."},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var tsig [][]byte
+			if tc.tsig != "" {
+				tsig = [][]byte{[]byte(tc.tsig)}
+			}
+
+			if _, err := parseMetadataForm(&tc.form, tsig, []string{"xfr-key."}); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package zoneedit
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/namepolicy"
+)
+
+// canOverrideNamePolicies reports whether the user may change records that
+// the name policies block.
+func (s *Service) canOverrideNamePolicies(c fiber.Ctx) bool {
+	return auth.HasPermissionInContext(c, s.authService, auth.PermZonePolicyOverride)
+}
+
+// validateNamePolicies rejects changes that the name policies block, unless
+// the user has the zone.policy_override permission.
+func (s *Service) validateNamePolicies(c fiber.Ctx, zoneName string, changes []RecordChange) error {
+	rules := namepolicy.LoadRules(s.db)
+	if len(rules) == 0 || s.canOverrideNamePolicies(c) {
+		return nil
+	}
+
+	err := checkNamePolicies(rules, zoneName, changes)
+	if err != nil {
+		log.Warn().Str("zone_name", zoneName).Err(err).Msg("record change blocked by a name policy")
+	}
+
+	return err
+}
+
+// changeOperation returns the operation of a changed RRset: creating it,
+// updating it or deleting it.
+func changeOperation(change *RecordChange) string {
+	switch {
+	case len(change.Records) == 0:
+		return namepolicy.OpDelete
+	case change.Existed:
+		return namepolicy.OpUpdate
+	default:
+		return namepolicy.OpCreate
+	}
+}
+
+// checkNamePolicies returns a *ChangeError for the first changed RRset of
+// changes that a rule of rules blocks.
+func checkNamePolicies(rules []namepolicy.Rule, zoneName string, changes []RecordChange) error {
+	for i := range changes {
+		change := &changes[i]
+		if !change.Changed {
+			continue
+		}
+
+		op := changeOperation(change)
+		rrType := strings.ToUpper(change.Type)
+
+		rule := namepolicy.Find(rules, getDisplayNameForZone(change.Name, zoneName), rrType, op)
+		if rule == nil {
+			continue
+		}
+
+		message := "A name policy does not permit you to " + op + " the " + rrType + " records of " + change.Name
+		if rule.Reason != "" {
+			message += ": " + rule.Reason
+		}
+
+		return &ChangeError{
+			Status:  fiber.StatusForbidden,
+			Code:    handler.CodeForbidden,
+			Message: message,
+			Details: fiber.Map{"name": change.Name, "record_type": rrType, "operation": op, "pattern": rule.Pattern},
+		}
+	}
+
+	return nil
+}
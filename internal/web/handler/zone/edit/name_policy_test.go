@@ -0,0 +1,47 @@
+package zoneedit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/namepolicy"
+)
+
+func TestCheckNamePolicies(t *testing.T) {
+	rules := []namepolicy.Rule{
+		{Pattern: "autodiscover", Operations: []string{namepolicy.OpCreate}, Reason: "use the mail settings"},
+		{Pattern: "@", Types: []string{"NS"}, Operations: []string{namepolicy.OpDelete}},
+	}
+
+	var changeErr *ChangeError
+
+	created := RecordChange{Changed: true, Name: "autodiscover.example.com.", Type: "CNAME",
+		Records: []Record{{Content: "mail.example.com."}}}
+
+	err := checkNamePolicies(rules, "example.com.", []RecordChange{created})
+	if !errors.As(err, &changeErr) || changeErr.Code != handler.CodeForbidden ||
+		changeErr.Message != "A name policy does not permit you to create the CNAME records of "+
+			"autodiscover.example.com.: use the mail settings" {
+		t.Errorf("creating autodiscover: err = %v", err)
+	}
+
+	deleted := RecordChange{Existed: true, Changed: true, Name: "example.com.", Type: "NS"}
+	if err = checkNamePolicies(rules, "example.com.", []RecordChange{deleted}); !errors.As(err, &changeErr) ||
+		changeErr.Details.(fiber.Map)["operation"] != namepolicy.OpDelete {
+		t.Errorf("deleting the apex NS records: err = %v", err)
+	}
+
+	updated := created
+	updated.Existed = true
+	apexNS := RecordChange{Existed: true, Changed: true, Name: "example.com.", Type: "NS",
+		Records: []Record{{Content: "ns1.example.com."}}}
+	unchanged := deleted
+	unchanged.Changed = false
+
+	if err = checkNamePolicies(rules, "example.com.", []RecordChange{updated, apexNS, unchanged}); err != nil {
+		t.Errorf("changes the policies permit: %v", err)
+	}
+}
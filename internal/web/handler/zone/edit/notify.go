@@ -0,0 +1,108 @@
+package zoneedit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+)
+
+// notifyApprovers emails every user allowed to approve changes to the zone
+// of cr about the new change request.
+func (s *Service) notifyApprovers(c fiber.Ctx, cr *models.ChangeRequest) {
+	if s.mailer == nil {
+		return
+	}
+
+	approvers, err := s.authService.GetUsersWithPermission(auth.PermZoneApprove)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load change request approvers")
+		return
+	}
+
+	subject := s.brandName(c) + ": changes to " + cr.ZoneName + " need approval"
+	body := fmt.Sprintf(`%s proposed changes to the zone %s:
+
+%s
+
+Review the changes at:
+
+%s
+`, cr.Requester.FullName(), cr.ZoneName, cr.Summary, s.link(fmt.Sprintf("%s/%d", PathChanges, cr.ID)))
+
+	for i := range approvers {
+		if approvers[i].Email == "" || approvers[i].ID == cr.RequesterID {
+			continue
+		}
+
+		access, err := s.authService.GetZoneAccess(approvers[i].ID)
+		if err != nil || !access.Allows(cr.ZoneName) {
+			continue
+		}
+
+		s.send(approvers[i].Email, subject, body)
+	}
+}
+
+// notifyChangeRequester emails the requester the outcome of the review.
+func (s *Service) notifyChangeRequester(c fiber.Ctx, cr *models.ChangeRequest) {
+	if s.mailer == nil || cr.Requester.Email == "" {
+		return
+	}
+
+	var outcome string
+
+	switch cr.Status {
+	case models.ChangeRequestApproved:
+		outcome = "approved and applied"
+	case models.ChangeRequestRejected:
+		outcome = "rejected"
+	default:
+		return
+	}
+
+	subject := s.brandName(c) + ": changes to " + cr.ZoneName + " " + outcome
+	body := fmt.Sprintf(`Hello %s,
+
+your changes to the zone %s (%s) were %s.
+`, cr.Requester.FullName(), cr.ZoneName, cr.Summary, outcome)
+
+	if cr.ReviewComment != "" {
+		body += "\nComment from the reviewer:\n" + cr.ReviewComment + "\n"
+	}
+
+	body += "\n" + s.link(fmt.Sprintf("%s/%d", PathChanges, cr.ID)) + "\n"
+
+	s.send(cr.Requester.Email, subject, body)
+}
+
+// send delivers a message in the background so a slow SMTP server does not
+// delay the response.
+func (s *Service) send(to, subject, body string) {
+	jobs.Go(jobs.Mail, func() error {
+		err := s.mailer.Send(to, subject, body)
+		if err != nil {
+			log.Error().Err(err).Str("to", to).Msg("failed to send change request email")
+		}
+
+		return err
+	})
+}
+
+func (s *Service) brandName(c fiber.Ctx) string {
+	if brand, ok := c.Locals("Brand").(config.Branding); ok {
+		return brand.Name
+	}
+
+	return s.cfg.Branding.Resolve(s.cfg.Title).Name
+}
+
+func (s *Service) link(path string) string {
+	return strings.TrimRight(s.cfg.Webserver.URL, "/") + path
+}
@@ -0,0 +1,79 @@
+package zoneedit
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/nsset"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+// nameserverSets returns the nameserver sets offered in the zone settings
+// and the ID of the set assigned to zoneName, or 0.
+func (s *Service) nameserverSets(c fiber.Ctx, zoneName string) ([]models.NameserverSet, uint) {
+	sets, err := nsset.List(s.db)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to list nameserver sets")
+	}
+
+	assignment, err := nsset.Assigned(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load the nameserver set of the zone")
+	}
+
+	if assignment == nil {
+		return sets, 0
+	}
+
+	return sets, assignment.SetID
+}
+
+// assignNameserverSet assigns zoneName to the set setID, or to none for 0,
+// and applies the set to the apex NS records. It returns the change for the
+// zone settings diff, nil when the set stays the same, and the error of
+// assigning or applying the set.
+func (s *Service) assignNameserverSet(ctx context.Context, c fiber.Ctx, zoneName string, setID uint) (
+	*activitylog.FieldDiff, error,
+) {
+	previous, err := nsset.Assigned(s.db, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &activitylog.FieldDiff{Field: "nameserver_set"}
+
+	if previous != nil {
+		if previous.SetID == setID {
+			return nil, nil
+		}
+
+		if previous.Set != nil {
+			diff.Old = previous.Set.Name
+		}
+	} else if setID == 0 {
+		return nil, nil
+	}
+
+	if setID != 0 {
+		set, getErr := nsset.Get(s.db, setID)
+		if getErr != nil {
+			return nil, getErr
+		}
+
+		diff.New = set.Name
+	}
+
+	if err = nsset.Assign(s.db, zoneName, setID); err != nil {
+		return nil, err
+	}
+
+	if setID == 0 {
+		return diff, nil
+	}
+
+	return diff, nsset.ApplyZone(ctx, s.db, zoneName, *auth.Attribute(c, &activitylog.Entry{}))
+}
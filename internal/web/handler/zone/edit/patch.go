@@ -0,0 +1,260 @@
+package zoneedit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// errMsgApprovalRequired is returned by PatchRRsets to users whose record
+// changes need approval.
+const errMsgApprovalRequired = "Your record changes need approval; submit them in the zone editor"
+
+// ChangeError is a zone change refused by the checks of the editor or failed
+// in PowerDNS. Status and Code are the HTTP status and APIError code to
+// report it with.
+type ChangeError struct {
+	Status  int
+	Code    string
+	Message string
+	// Details is optional structured context, e.g. the offending record type.
+	Details any
+	// Err is the PowerDNS error behind an upstream failure, or nil.
+	Err error
+}
+
+// Error implements error.
+func (e *ChangeError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the PowerDNS error behind e.
+func (e *ChangeError) Unwrap() error {
+	return e.Err
+}
+
+// respondChangeError responds with err as an APIError. Errors other than a
+// ChangeError are reported as internal errors.
+func respondChangeError(c fiber.Ctx, err error) error {
+	var changeErr *ChangeError
+	if !errors.As(err, &changeErr) {
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal, err.Error(), nil)
+	}
+
+	return handler.JSONError(c, changeErr.Status, changeErr.Code, changeErr.Message, changeErr.Details)
+}
+
+// checkRecordQuota returns a *ChangeError when rrSets, a patch of current,
+// take zoneName over the record quota of its tenant.
+func (s *Service) checkRecordQuota(zoneName string, current *pdnsapi.Zone, rrSets []pdnsapi.RRset) error {
+	before, after := tenant.PatchedRecords(current, rrSets)
+
+	err := tenant.CheckRecords(s.db, zoneName, before, after)
+	if errors.Is(err, tenant.ErrQuota) {
+		return &ChangeError{Status: fiber.StatusForbidden, Code: handler.CodeForbidden, Message: err.Error()}
+	}
+
+	return err
+}
+
+// ValidateChanges runs the checks of the editor on changes to zoneName: the
+// zone does not wait for its purge, the settings and the user's roles allow
+// the record types, LUA records are permitted and well-formed, the name
+// policies do not block the changes and the TTL policies allow the TTLs. The
+// error is a *ChangeError.
+func (s *Service) ValidateChanges(c fiber.Ctx, zoneName string, changes []RecordChange) error {
+	if s.pendingDeletion(c, zoneName) != nil {
+		return &ChangeError{Status: fiber.StatusConflict, Code: handler.CodeConflict, Message: errMsgZoneDeleted}
+	}
+
+	// ensure only allowed record types are being modified
+	if err := s.validateRecordsUpdateAreValidTypes(zoneName, changes, zoneIsReverse(zoneName)); err != nil {
+		return err
+	}
+
+	// ensure the user's roles permit modifying these record types
+	if err := s.validateRecordTypePermissions(c, zoneName, changes); err != nil {
+		return err
+	}
+
+	// LUA records run code on the PowerDNS server; they need their own
+	// permission and well-formed content.
+	if err := s.validateLUARecords(c, zoneName, changes); err != nil {
+		return err
+	}
+
+	if err := s.validateNamePolicies(c, zoneName, changes); err != nil {
+		return err
+	}
+
+	return s.validateTTLPolicies(c, zoneName, changes)
+}
+
+// PatchRRsets applies rrSets, the RRsets of a PowerDNS API patch, to zoneName
+// like a change saved in the editor: after the checks of ValidateChanges,
+// with auto-PTR and recorded in the activity log. The caller checks the zone
+// access. Users whose changes need approval are refused, as a change request
+// has no answer in the PowerDNS API. Unless revisions is empty, the zone must
+// still be at one of them, as returned by ZoneRevision. The error is a
+// *ChangeError.
+func (s *Service) PatchRRsets(c fiber.Ctx, zoneName string, revisions []string, rrSets []pdnsapi.RRset) error {
+	if powerdns.Engine.Client == nil {
+		requestid.Logger(c.Context()).Error().Msg(powerdns.ErrMsgClientNotInitialized)
+
+		return &ChangeError{
+			Status:  fiber.StatusServiceUnavailable,
+			Code:    handler.CodeUnavailable,
+			Message: powerdns.ErrMsgClientNotInitialized,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	currentZone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return &ChangeError{
+			Status:  fiber.StatusInternalServerError,
+			Code:    handler.CodeUpstream,
+			Message: "Failed to fetch zone: " + err.Error(),
+			Err:     err,
+		}
+	}
+
+	if len(revisions) > 0 && !slices.Contains(revisions, ZoneRevision(currentZone)) {
+		return &ChangeError{
+			Status:  fiber.StatusPreconditionFailed,
+			Code:    handler.CodePreconditionFailed,
+			Message: ErrMsgPreconditionFailed,
+		}
+	}
+
+	changes, err := changesFromRRsets(currentZone, rrSets)
+	if err != nil {
+		return err
+	}
+
+	if err = s.ValidateChanges(c, zoneName, changes); err != nil {
+		return err
+	}
+
+	if s.NeedsApproval(c) {
+		return &ChangeError{Status: fiber.StatusForbidden, Code: handler.CodeForbidden, Message: errMsgApprovalRequired}
+	}
+
+	_, username := auth.Actor(c)
+
+	patch := buildRRSetsFromChanges(changes, currentZone, username, time.Now())
+	if err = s.checkRecordQuota(zoneName, currentZone, patch); err != nil {
+		return err
+	}
+
+	if _, err = s.patchRecords(ctx, c, zoneName, currentZone, changes, patch, false); err != nil {
+		return &ChangeError{
+			Status:  fiber.StatusInternalServerError,
+			Code:    handler.CodeUpstream,
+			Message: "Failed to update records: " + err.Error(),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// ValidateRRsets runs the checks of ValidateChanges on rrSets, a PowerDNS
+// API patch of current, for changes PatchRRsets does not apply, e.g. to the
+// variant of the zone on a view. The error is a *ChangeError.
+func (s *Service) ValidateRRsets(c fiber.Ctx, zoneName string, current *pdnsapi.Zone, rrSets []pdnsapi.RRset) error {
+	changes, err := changesFromRRsets(current, rrSets)
+	if err != nil {
+		return err
+	}
+
+	return s.ValidateChanges(c, zoneName, changes)
+}
+
+// changesFromRRsets converts the RRsets of a PowerDNS API patch into the
+// changes the editor sends. An RRset without comments keeps its comment.
+// Deleting an RRset the zone does not have is a no-op and left out.
+func changesFromRRsets(current *pdnsapi.Zone, rrSets []pdnsapi.RRset) ([]RecordChange, error) {
+	currentRRSets := rrSetIndex(current)
+	changes := make([]RecordChange, 0, len(rrSets))
+
+	for i := range rrSets {
+		rrSet := &rrSets[i]
+		if rrSet.Name == nil || rrSet.Type == nil || rrSet.ChangeType == nil {
+			return nil, &ChangeError{
+				Status:  fiber.StatusUnprocessableEntity,
+				Code:    handler.CodeValidation,
+				Message: "RRset needs a name, type and changetype",
+			}
+		}
+
+		name := *rrSet.Name
+		rrType := strings.ToUpper(string(*rrSet.Type))
+		existing := currentRRSets[rrSetKey(name, rrType)]
+
+		change := RecordChange{
+			Existed: existing != nil,
+			Changed: true,
+			Name:    name,
+			Type:    rrType,
+		}
+
+		switch *rrSet.ChangeType {
+		case pdnsapi.ChangeTypeDelete:
+			if existing == nil {
+				continue
+			}
+		case pdnsapi.ChangeTypeReplace:
+			switch {
+			case rrSet.TTL != nil:
+				change.TTL = *rrSet.TTL
+			case existing != nil && existing.TTL != nil:
+				change.TTL = *existing.TTL
+			default:
+				return nil, &ChangeError{
+					Status:  fiber.StatusUnprocessableEntity,
+					Code:    handler.CodeValidation,
+					Message: "RRset " + name + " IN " + rrType + ": TTL is required",
+					Details: fiber.Map{"name": name, "record_type": rrType},
+				}
+			}
+
+			for _, record := range rrSet.Records {
+				change.Records = append(change.Records, Record{
+					Content:  pdnsapi.StringValue(record.Content),
+					Disabled: pdnsapi.BoolValue(record.Disabled),
+				})
+			}
+
+			if len(rrSet.Comments) > 0 {
+				change.Comment = extractCommentFromRRSet(rrSet)
+			} else {
+				change.Comment = extractCommentFromRRSet(existing)
+			}
+		default:
+			return nil, &ChangeError{
+				Status:  fiber.StatusUnprocessableEntity,
+				Code:    handler.CodeValidation,
+				Message: "Changetype " + string(*rrSet.ChangeType) + " is not supported",
+				Details: fiber.Map{"name": name, "record_type": rrType},
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
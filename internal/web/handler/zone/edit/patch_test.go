@@ -0,0 +1,85 @@
+package zoneedit
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// TestPostRecords_StopsAtValidation checks that a refused change is answered
+// with the validation error and not applied.
+func TestPostRecords_StopsAtValidation(t *testing.T) {
+	app, _ := newDeletionTestApp(t)
+
+	status, apiErr := postJSON(t, app, "/zone/edit/example.com/records",
+		`{"changes":[{"changed":true,"name":"www.example.com.","type":"A","ttl":300,"records":[{"content":"192.0.2.1"}]}]}`)
+	if status != http.StatusBadRequest || apiErr.Code != handler.CodeBadRequest {
+		t.Errorf("status %d, %+v; want 400 for a record type not allowed by the settings", status, apiErr)
+	}
+}
+
+func TestChangesFromRRsets(t *testing.T) {
+	rrSet := func(name string, rrType pdnsapi.RRType, changeType pdnsapi.ChangeType, ttl *uint32,
+		contents ...string,
+	) pdnsapi.RRset {
+		rrSet := pdnsapi.RRset{
+			Name:       pdnsapi.String(name),
+			Type:       pdnsapi.RRTypePtr(rrType),
+			ChangeType: pdnsapi.ChangeTypePtr(changeType),
+			TTL:        ttl,
+		}
+		for _, content := range contents {
+			rrSet.Records = append(rrSet.Records, pdnsapi.Record{Content: pdnsapi.String(content)})
+		}
+
+		return rrSet
+	}
+
+	current := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{{
+		Name:     pdnsapi.String("www.example.com."),
+		Type:     pdnsapi.RRTypePtr(pdnsapi.RRTypeA),
+		TTL:      pdnsapi.Uint32(300),
+		Comments: []pdnsapi.Comment{{Content: pdnsapi.String("web"), ModifiedAt: pdnsapi.Uint64(1)}},
+	}}}
+
+	changes, err := changesFromRRsets(current, []pdnsapi.RRset{
+		rrSet("www.example.com.", pdnsapi.RRTypeA, pdnsapi.ChangeTypeReplace, nil, "192.0.2.2"),
+		rrSet("mail.example.com.", pdnsapi.RRTypeA, pdnsapi.ChangeTypeReplace, pdnsapi.Uint32(60), "192.0.2.3"),
+		rrSet("old.example.com.", pdnsapi.RRTypeA, pdnsapi.ChangeTypeDelete, nil),
+		rrSet("www.example.com.", pdnsapi.RRTypeA, pdnsapi.ChangeTypeDelete, nil),
+	})
+	if err != nil {
+		t.Fatalf("changesFromRRsets: %v", err)
+	}
+
+	want := []RecordChange{
+		{Existed: true, Changed: true, Name: "www.example.com.", Type: "A", TTL: 300,
+			Records: []Record{{Content: "192.0.2.2"}}, Comment: "web"},
+		{Changed: true, Name: "mail.example.com.", Type: "A", TTL: 60, Records: []Record{{Content: "192.0.2.3"}}},
+		{Existed: true, Changed: true, Name: "www.example.com.", Type: "A"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changesFromRRsets = %+v, want %+v", changes, want)
+	}
+
+	var changeErr *ChangeError
+
+	_, err = changesFromRRsets(current, []pdnsapi.RRset{
+		rrSet("new.example.com.", pdnsapi.RRTypeA, pdnsapi.ChangeTypeReplace, nil, "192.0.2.4"),
+	})
+	if !errors.As(err, &changeErr) || changeErr.Status != http.StatusUnprocessableEntity {
+		t.Errorf("new RRset without TTL: err = %v, want 422", err)
+	}
+
+	_, err = changesFromRRsets(current, []pdnsapi.RRset{
+		rrSet("www.example.com.", pdnsapi.RRTypeA, pdnsapi.ChangeType("EXTEND"), nil, "192.0.2.4"),
+	})
+	if !errors.As(err, &changeErr) || changeErr.Status != http.StatusUnprocessableEntity {
+		t.Errorf("unknown changetype: err = %v, want 422", err)
+	}
+}
@@ -0,0 +1,130 @@
+package zoneedit
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+// Actions of an RRsetPreview.
+const (
+	previewAdded     = "added"
+	previewModified  = "modified"
+	previewDeleted   = "deleted"
+	previewUnchanged = "unchanged"
+)
+
+// RRsetPreview describes how a records update would change one RRset.
+type RRsetPreview struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+	// Action is added, modified, deleted or unchanged.
+	Action string `json:"action"`
+	OldTTL uint32 `json:"old_ttl"`
+	NewTTL uint32 `json:"new_ttl"`
+	// Added and Removed are the records the update adds and removes, with
+	// their contents as sent to PowerDNS. A record that is enabled or
+	// disabled is in both. Kept counts the records left as they are.
+	Added      []Record `json:"added"`
+	Removed    []Record `json:"removed"`
+	Kept       int      `json:"kept"`
+	OldComment string   `json:"old_comment"`
+	NewComment string   `json:"new_comment"`
+}
+
+// previewRecordsUpdate compares the RRsets a records update would patch with
+// their current state in zone, sorted by name and type.
+func previewRecordsUpdate(zone *pdnsapi.Zone, rrSets []pdnsapi.RRset, zoneName string) []RRsetPreview {
+	current := rrSetIndex(zone)
+	previews := make([]RRsetPreview, 0, len(rrSets))
+
+	for i := range rrSets {
+		rrSet := &rrSets[i]
+		name, rrType := pdnsapi.StringValue(rrSet.Name), string(*rrSet.Type)
+		old := current[rrSetKey(name, rrType)]
+
+		p := RRsetPreview{
+			Name:        name,
+			DisplayName: getDisplayNameForZone(name, zoneName),
+			Type:        rrType,
+			Added:       []Record{},
+			Removed:     []Record{},
+		}
+
+		var oldRecords, newRecords []Record
+
+		if old != nil {
+			p.OldTTL = pdnsapi.Uint32Value(old.TTL)
+			p.OldComment = extractCommentFromRRSet(old)
+			oldRecords = previewRecords(old.Records)
+		}
+
+		if *rrSet.ChangeType != pdnsapi.ChangeTypeDelete {
+			p.NewTTL = pdnsapi.Uint32Value(rrSet.TTL)
+			p.NewComment = extractCommentFromRRSet(rrSet)
+			newRecords = previewRecords(rrSet.Records)
+		}
+
+		for _, r := range newRecords {
+			if slices.Contains(oldRecords, r) {
+				p.Kept++
+			} else {
+				p.Added = append(p.Added, r)
+			}
+		}
+
+		for _, r := range oldRecords {
+			if !slices.Contains(newRecords, r) {
+				p.Removed = append(p.Removed, r)
+			}
+		}
+
+		switch {
+		case old == nil && len(newRecords) == 0:
+			p.Action = previewUnchanged
+		case old == nil:
+			p.Action = previewAdded
+		case len(newRecords) == 0:
+			p.Action = previewDeleted
+		case len(p.Added) > 0 || len(p.Removed) > 0 || p.OldTTL != p.NewTTL || p.OldComment != p.NewComment:
+			p.Action = previewModified
+		default:
+			p.Action = previewUnchanged
+		}
+
+		previews = append(previews, p)
+	}
+
+	sort.Slice(previews, func(i, j int) bool {
+		if previews[i].Name != previews[j].Name {
+			return previews[i].Name < previews[j].Name
+		}
+
+		return previews[i].Type < previews[j].Type
+	})
+
+	return previews
+}
+
+// previewRecords converts PowerDNS records for an RRsetPreview.
+func previewRecords(records []pdnsapi.Record) []Record {
+	out := make([]Record, 0, len(records))
+
+	for _, rec := range records {
+		out = append(out, Record{
+			Content:  pdnsapi.StringValue(rec.Content),
+			Disabled: pdnsapi.BoolValue(rec.Disabled),
+		})
+	}
+
+	return out
+}
+
+// PostRecordsPreview validates a records update like PostRecords and
+// responds with the changes it would make, without applying them.
+func (s *Service) PostRecordsPreview(c fiber.Ctx) error {
+	return s.postRecords(c, true)
+}
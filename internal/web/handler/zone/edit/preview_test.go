@@ -0,0 +1,65 @@
+package zoneedit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func TestPreviewRecordsUpdate(t *testing.T) {
+	rrSet := func(name string, rrType pdnsapi.RRType, ttl uint32, contents ...string) pdnsapi.RRset {
+		records := make([]pdnsapi.Record, 0, len(contents))
+		for _, content := range contents {
+			records = append(records, pdnsapi.Record{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)})
+		}
+
+		return pdnsapi.RRset{Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(rrType), TTL: pdnsapi.Uint32(ttl), Records: records}
+	}
+
+	zone := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		rrSet("www.example.com.", pdnsapi.RRTypeA, 300, "192.0.2.1", "192.0.2.2"),
+		rrSet("example.com.", pdnsapi.RRTypeTXT, 3600, `"v=spf1 -all"`),
+		rrSet("old.example.com.", pdnsapi.RRTypeA, 300, "192.0.2.9"),
+	}}
+
+	changes := []RecordChange{
+		{
+			Existed: true, Changed: true, Name: "www.example.com.", Type: "A", TTL: 600,
+			Records: []Record{{Content: "192.0.2.1"}, {Content: "192.0.2.2", Disabled: true}, {Content: "192.0.2.3"}},
+		},
+		// The content is quoted as sent to PowerDNS, so nothing changes.
+		{Existed: true, Changed: true, Name: "example.com.", Type: "TXT", TTL: 3600, Records: []Record{{Content: "v=spf1 -all"}}},
+		{Existed: true, Changed: true, Name: "old.example.com.", Type: "A", TTL: 300},
+		{Changed: true, Name: "new.example.com", Type: "AAAA", TTL: 300, Comment: "new", Records: []Record{{Content: "2001:db8::1"}}},
+	}
+
+	rrSets := buildRRSetsFromChanges(changes, zone, "admin", time.Unix(1700000000, 0))
+	got := previewRecordsUpdate(zone, rrSets, "example.com.")
+
+	want := []RRsetPreview{
+		{
+			Name: "example.com.", DisplayName: "@", Type: "TXT", Action: previewUnchanged,
+			OldTTL: 3600, NewTTL: 3600, Added: []Record{}, Removed: []Record{}, Kept: 1,
+		},
+		{
+			Name: "new.example.com.", DisplayName: "new", Type: "AAAA", Action: previewAdded, NewTTL: 300,
+			Added: []Record{{Content: "2001:db8::1"}}, Removed: []Record{}, NewComment: "new",
+		},
+		{
+			Name: "old.example.com.", DisplayName: "old", Type: "A", Action: previewDeleted, OldTTL: 300,
+			Added: []Record{}, Removed: []Record{{Content: "192.0.2.9"}},
+		},
+		{
+			Name: "www.example.com.", DisplayName: "www", Type: "A", Action: previewModified, OldTTL: 300, NewTTL: 600,
+			Added:   []Record{{Content: "192.0.2.2", Disabled: true}, {Content: "192.0.2.3"}},
+			Removed: []Record{{Content: "192.0.2.2"}},
+			Kept:    1,
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("previewRecordsUpdate =\n%+v\nwant\n%+v", got, want)
+	}
+}
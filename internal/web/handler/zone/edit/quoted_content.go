@@ -7,9 +7,10 @@ import (
 
 // ensureQuotedContent ensures that DNS record content is correctly wrapped in
 // double quotes for RR types that require it (TXT, SPF). If the content is
-// already a valid sequence of quoted strings, it's returned unchanged. If not,
-// embedded quotes are escaped and the whole content is wrapped in quotes.
-// For other RR types, content is returned unchanged.
+// already a valid sequence of quoted strings, it's returned unchanged, except
+// that strings longer than 255 bytes are split. If not, it is encoded with
+// quoteTXT. URI targets are quoted as well; other RR types are returned
+// unchanged.
 func ensureQuotedContent(rrType, content string) string {
 	if content == "" {
 		return content
@@ -24,12 +25,10 @@ func ensureQuotedContent(rrType, content string) string {
 		}
 
 		if isQuotedStringSequence(s) {
-			return s
+			return splitLongStrings(s)
 		}
-		// Escape embedded quotes and wrap
-		s = strings.ReplaceAll(s, `"`, `\"`)
 
-		return `"` + s + `"`
+		return quoteTXT(s)
 
 	case "URI":
 		// RFC 7553: priority (uint16) weight (uint16) "target-uri"
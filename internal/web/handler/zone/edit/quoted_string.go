@@ -1,13 +1,91 @@
 package zoneedit
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsclient"
+)
+
+// maxCharacterString is the length limit of a single character string of a
+// TXT record in bytes, see RFC 1035 section 3.3.
+const maxCharacterString = 255
 
 // quotedStringSequenceRE matches one or more RFC-1035-style quoted strings
 // separated by whitespace. Each quoted string allows escaping via backslash
 // (e.g., \" for a literal quote).
 var quotedStringSequenceRE = regexp.MustCompile(`^\s*"([^"\\]|\\.)*"(?:\s+"([^"\\]|\\.)*")*\s*$`)
 
+// quotedStringRE matches a single quoted string of a quoted string sequence.
+var quotedStringRE = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+
 // isQuotedStringSequence returns true if s consists of one or more
 // RFC-1035-style quoted strings separated by whitespace: "..." "..."
 // It supports escaping of \" inside a quoted string. Simplified check.
 func isQuotedStringSequence(s string) bool { return quotedStringSequenceRE.MatchString(s) }
+
+// quoteTXT encodes text as a sequence of quoted strings of at most
+// maxCharacterString bytes each, escaping quotes and backslashes and writing
+// control characters as \DDD. Strings are split between UTF-8 characters, so
+// long values such as DKIM keys fit into one TXT record.
+func quoteTXT(text string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	length := 0
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if length+size > maxCharacterString {
+			b.WriteString(`" "`)
+
+			length = 0
+		}
+
+		switch c := text[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c == 0x7f || r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, `\%03d`, c)
+		default:
+			b.WriteString(text[i : i+size])
+		}
+
+		length += size
+		i += size
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// splitLongStrings re-encodes the strings of the quoted string sequence s
+// that are longer than maxCharacterString bytes with quoteTXT. s is returned
+// unchanged when all of them fit.
+func splitLongStrings(s string) string {
+	matches := quotedStringRE.FindAllStringSubmatch(s, -1)
+
+	tooLong := false
+	parts := make([]string, 0, len(matches))
+
+	for _, m := range matches {
+		if text := dnsclient.Unquote(m[0]); len(text) > maxCharacterString {
+			tooLong = true
+
+			parts = append(parts, quoteTXT(text))
+		} else {
+			parts = append(parts, m[0])
+		}
+	}
+
+	if !tooLong {
+		return s
+	}
+
+	return strings.Join(parts, " ")
+}
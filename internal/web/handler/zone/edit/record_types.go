@@ -1,9 +1,15 @@
 package zoneedit
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/gofiber/fiber/v3"
 	"github.com/rs/zerolog/log"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	zonesettings "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/zone"
 )
 
@@ -36,11 +42,7 @@ func (s *Service) loadAllowedRecordTypes(reverse bool) []RecordTypeOption {
 }
 
 // validateRecordsUpdateAreValidTypes checks if all provided record types are allowed.
-func (s *Service) validateRecordsUpdateAreValidTypes(
-	c fiber.Ctx,
-	zoneName string,
-	request *RecordsUpdateRequest,
-	reverse bool) error {
+func (s *Service) validateRecordsUpdateAreValidTypes(zoneName string, changes []RecordChange, reverse bool) error {
 	allowedTypes := s.loadAllowedRecordTypes(reverse)
 
 	allowedTypesMap := make(map[string]bool, len(allowedTypes))
@@ -48,7 +50,7 @@ func (s *Service) validateRecordsUpdateAreValidTypes(
 		allowedTypesMap[at.Type] = true
 	}
 
-	for _, change := range request.Changes {
+	for _, change := range changes {
 		if !allowedTypesMap[change.Type] {
 			// Allow editing records that already exist even if their type is not in
 			// the allowed-types list (e.g. SOA records managed outside the admin UI).
@@ -59,12 +61,96 @@ func (s *Service) validateRecordsUpdateAreValidTypes(
 			log.Warn().Str("zone_name", zoneName).Str("record_type", change.Type).
 				Msg("attempt to modify disallowed record type")
 
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"success": false,
-				"message": "Modification of record type " + change.Type + " is not allowed",
-			})
+			return &ChangeError{
+				Status:  fiber.StatusBadRequest,
+				Code:    handler.CodeBadRequest,
+				Message: "Modification of record type " + change.Type + " is not allowed",
+				Details: fiber.Map{"record_type": change.Type},
+			}
 		}
 	}
 
 	return nil
 }
+
+// editableRecordTypes returns the record types the current user's roles allow
+// them to modify, or nil when they are unrestricted.
+func (s *Service) editableRecordTypes(c fiber.Ctx) map[string]bool {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok || user.ID == 0 || s.authService == nil {
+		return nil
+	}
+
+	editable, err := s.authService.GetEditableRecordTypes(user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to resolve editable record types")
+
+		return map[string]bool{}
+	}
+
+	// An API key may be limited to fewer record types than its user.
+	return auth.PrincipalFrom(c).LimitRecordTypes(editable)
+}
+
+// filterEditableRecordTypes drops the record types the user may not modify
+// from the dropdown options. A nil editable set leaves options untouched.
+func filterEditableRecordTypes(options []RecordTypeOption, editable map[string]bool) []RecordTypeOption {
+	if editable == nil {
+		return options
+	}
+
+	filtered := make([]RecordTypeOption, 0, len(options))
+
+	for _, o := range options {
+		if editable[o.Type] {
+			filtered = append(filtered, o)
+		}
+	}
+
+	return filtered
+}
+
+// validateRecordTypePermissions rejects changes to record types that the
+// user's roles do not allow. Unlike validateRecordsUpdateAreValidTypes this
+// also applies to RRsets that already existed (e.g. NS or SOA).
+func (s *Service) validateRecordTypePermissions(c fiber.Ctx, zoneName string, changes []RecordChange) error {
+	editable := s.editableRecordTypes(c)
+	if editable == nil {
+		return nil
+	}
+
+	for _, change := range changes {
+		if editable[strings.ToUpper(change.Type)] {
+			continue
+		}
+
+		log.Warn().Str("zone_name", zoneName).Str("record_type", change.Type).
+			Msg("role does not permit modifying record type")
+
+		return &ChangeError{
+			Status:  fiber.StatusForbidden,
+			Code:    handler.CodeForbidden,
+			Message: "Your role does not permit modifying " + change.Type + " records",
+			Details: fiber.Map{"record_type": change.Type},
+		}
+	}
+
+	return nil
+}
+
+// sortedTypeList returns the keys of an editable-types set in sorted order, or
+// nil for an unrestricted (nil) set so the client can tell the two apart.
+func sortedTypeList(types map[string]bool) []string {
+	if types == nil {
+		return nil
+	}
+
+	list := make([]string, 0, len(types))
+	for t := range types {
+		list = append(list, t)
+	}
+
+	sort.Strings(list)
+
+	return list
+}
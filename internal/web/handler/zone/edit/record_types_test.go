@@ -0,0 +1,36 @@
+package zoneedit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterEditableRecordTypes(t *testing.T) {
+	options := []RecordTypeOption{{Type: "A"}, {Type: "MX"}, {Type: "NS"}, {Type: "TXT"}}
+
+	if got := filterEditableRecordTypes(options, nil); !reflect.DeepEqual(got, options) {
+		t.Errorf("nil set should leave options untouched, got %v", got)
+	}
+
+	got := filterEditableRecordTypes(options, map[string]bool{"A": true, "TXT": true})
+	want := []RecordTypeOption{{Type: "A"}, {Type: "TXT"}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterEditableRecordTypes() = %v, want %v", got, want)
+	}
+
+	if got := filterEditableRecordTypes(options, map[string]bool{}); len(got) != 0 {
+		t.Errorf("empty set should allow nothing, got %v", got)
+	}
+}
+
+func TestSortedTypeList(t *testing.T) {
+	if got := sortedTypeList(nil); got != nil {
+		t.Errorf("sortedTypeList(nil) = %v, want nil", got)
+	}
+
+	got := sortedTypeList(map[string]bool{"TXT": true, "A": true, "CNAME": true})
+	if want := []string{"A", "CNAME", "TXT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sortedTypeList() = %v, want %v", got, want)
+	}
+}
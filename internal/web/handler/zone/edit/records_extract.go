@@ -51,8 +51,10 @@ func extractRecordsFromRRSets(
 			continue
 		}
 
-		// Get comment from RRset (if any)
+		// Get comment and comment history from RRset (if any)
 		comment := extractCommentFromRRSet(&rrSet)
+		history := extractCommentHistory(&rrSet)
+		revision := rrsetRevision(&rrSet)
 
 		// Process each record in the RRset
 		for _, rec := range rrSet.Records {
@@ -63,6 +65,8 @@ func extractRecordsFromRRSets(
 				Content:     "",
 				Disabled:    false,
 				Comment:     comment,
+				History:     history,
+				Revision:    revision,
 			}
 
 			if rrSet.TTL != nil {
@@ -83,12 +87,3 @@ func extractRecordsFromRRSets(
 
 	return records
 }
-
-// extractCommentFromRRSet extracts the comment from an RRset.
-func extractCommentFromRRSet(rrSet *pdnsapi.RRset) string {
-	if rrSet != nil && len(rrSet.Comments) > 0 && rrSet.Comments[0].Content != nil {
-		return *rrSet.Comments[0].Content
-	}
-
-	return ""
-}
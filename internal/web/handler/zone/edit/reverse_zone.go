@@ -0,0 +1,306 @@
+package zoneedit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	zoneadd "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/add"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// minReversePrefix and maxReversePrefix bound the prefix length of the
+	// ip6.arpa zones PostReverseZones creates.
+	minReversePrefix = 16
+	maxReversePrefix = 124
+
+	// maxReverseAddresses bounds the addresses of one PostReverseZones request.
+	maxReverseAddresses = 100
+
+	// reverseDelegationTTL is the TTL of the NS records delegating a new
+	// reverse zone from its parent.
+	reverseDelegationTTL = 3600
+)
+
+// ReverseZonesRequest asks for the PTR records of IPv6 addresses of a zone,
+// creating the reverse zones they lack.
+type ReverseZonesRequest struct {
+	// Addresses are contents of AAAA records of the zone.
+	Addresses []string `json:"addresses"`
+	// Prefix is the prefix length of the reverse zones to create, on a
+	// nibble boundary, e.g. 64 for the zone of the /64 of an address.
+	Prefix int `json:"prefix"`
+}
+
+// PostReverseZones creates the PTR records of the requested IPv6 addresses.
+// An address without a reverse zone gets a new ip6.arpa zone covering its
+// network of the requested prefix length, with the nameservers and kind of
+// the forward zone. The new zone is delegated from the closest enclosing
+// reverse zone, if there is one, so that it joins the reverse tree.
+func (s *Service) PostReverseZones(c fiber.Ctx) error {
+	zoneName := c.Params("name")
+	if zoneName == "" {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	if !strings.HasSuffix(zoneName, ".") {
+		zoneName += "."
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	if zoneIsReverse(zoneName) {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest,
+			"Reverse zones are created for the addresses of forward zones", nil)
+	}
+
+	if s.NeedsApproval(c) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden, errMsgApprovalRequired, nil)
+	}
+
+	var request ReverseZonesRequest
+	if err := c.Bind().Body(&request); err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid request data", nil)
+	}
+
+	switch {
+	case request.Prefix%4 != 0 || request.Prefix < minReversePrefix || request.Prefix > maxReversePrefix:
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, fmt.Sprintf(
+			"The prefix length must be a multiple of 4 from %d to %d", minReversePrefix, maxReversePrefix), nil)
+	case len(request.Addresses) == 0 || len(request.Addresses) > maxReverseAddresses:
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation,
+			fmt.Sprintf("Send from 1 to %d addresses", maxReverseAddresses), nil)
+	}
+
+	if powerdns.Engine.Client == nil {
+		return handler.JSONError(c, fiber.StatusServiceUnavailable, handler.CodeUnavailable,
+			powerdns.ErrMsgClientNotInitialized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	forward, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeUpstream,
+			fmt.Sprintf("failed to fetch zone: %v", err), nil)
+	}
+
+	nameservers := apexNameservers(forward, zoneName)
+	if len(nameservers) == 0 {
+		return handler.JSONError(c, fiber.StatusConflict, handler.CodeConflict,
+			"The zone has no NS records to give its reverse zones", nil)
+	}
+
+	kind := zoneadd.ZoneKindNative
+	if forward.Kind != nil && *forward.Kind == pdnsapi.MasterZoneKind {
+		kind = zoneadd.ZoneKindMaster
+	}
+
+	userID, username := auth.Actor(c)
+	actor := auth.Attribute(c, &activitylog.Entry{UserID: userID, Username: username})
+
+	var (
+		created, delegated, skipped []string
+		ptrs                        int
+	)
+
+	for _, content := range request.Addresses {
+		addr, parseErr := netip.ParseAddr(strings.TrimSpace(content))
+		if parseErr != nil || !addr.Is6() || addr.Is4In6() {
+			skipped = append(skipped, content+" (not an IPv6 address)")
+			continue
+		}
+
+		owner, ttl := aaaaOwner(forward, addr)
+		if owner == "" {
+			skipped = append(skipped, content+" (no AAAA record in the zone)")
+			continue
+		}
+
+		ptrName, _ := ipv6PTRName(addr.String())
+
+		best, findErr := findBestReverseZone(ctx, ptrName)
+		if findErr != nil {
+			return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeUpstream, findErr.Error(), nil)
+		}
+
+		reverseZone, _ := zoneadd.ReverseIPv6Zone(netip.PrefixFrom(addr, request.Prefix).Masked().String())
+
+		// A zone at least as specific as the requested one covers the address
+		// already; otherwise best, if any, is the parent of the new zone.
+		if len(best) < len(reverseZone) {
+			isNew, ok, createErr := s.createReverseZone(ctx, c, reverseZone, best, kind, nameservers)
+			if errors.Is(createErr, tenant.ErrQuota) {
+				return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+					fmt.Sprintf("Cannot create reverse zone %s: %v", reverseZone, createErr), nil)
+			}
+
+			if createErr != nil {
+				requestid.Logger(c.Context()).Error().Err(createErr).Str("zone_name", reverseZone).
+					Msg("failed to create reverse zone")
+
+				return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeUpstream,
+					fmt.Sprintf("Failed to create reverse zone %s: %v", reverseZone, createErr), nil)
+			}
+
+			if isNew {
+				created = append(created, reverseZone)
+			}
+
+			if ok {
+				delegated = append(delegated, reverseZone)
+			}
+		}
+
+		if s.createAutoPTR(ctx, owner, content, rrTypeAAAA, ttl, actor) {
+			ptrs++
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"message":   fmt.Sprintf("Created %d reverse zone(s) and %d PTR record(s)", len(created), ptrs),
+		"created":   created,
+		"delegated": delegated,
+		"skipped":   skipped,
+	})
+}
+
+// createReverseZone creates the ip6.arpa zone reverseZone with nameservers
+// and records it in the activity log. When parent is set and accessible, the
+// zone is delegated from it; delegated reports whether that worked. A zone
+// that exists already in PowerDNS is left unchanged and created is false.
+func (s *Service) createReverseZone(ctx context.Context, c fiber.Ctx, reverseZone, parent string,
+	kind zoneadd.ZoneKind, nameservers []string,
+) (created, delegated bool, err error) {
+	form := &zoneadd.ZoneForm{
+		ZoneType:    zoneadd.ZoneTypeReverseIPv6,
+		Name:        reverseZone,
+		Kind:        kind,
+		SOAEditAPI:  zoneadd.SOAEditAPIDefault,
+		Nameservers: nameservers,
+	}
+
+	user, _ := c.Locals("CurrentUser").(models.User)
+	if err = tenant.CheckZones(s.db, user.TenantID, 1); err != nil {
+		return false, false, err
+	}
+
+	if err = zoneadd.CreateZone(ctx, form); err != nil {
+		if zoneadd.IsConflict(err) {
+			return false, false, nil
+		}
+
+		return false, false, err
+	}
+
+	if err = tenant.AssignCreated(s.db, reverseZone, &user); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", reverseZone).Msg("failed to assign zone to tenant")
+	}
+
+	userID, username := auth.Actor(c)
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionZoneCreated,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: reverseZone,
+		Details:      map[string]any{"kind": string(kind), "soa_edit_api": string(form.SOAEditAPI)},
+		IPAddress:    c.IP(),
+	}))
+
+	if parent == "" || !s.canAccessZone(c, parent) {
+		return true, false, nil
+	}
+
+	records := make([]pdnsapi.Record, 0, len(nameservers))
+	for _, ns := range nameservers {
+		records = append(records, pdnsapi.Record{Content: pdnsapi.String(ns), Disabled: pdnsapi.Bool(false)})
+	}
+
+	delegation := pdnsapi.RRset{
+		Name:       pdnsapi.String(reverseZone),
+		Type:       pdnsapi.RRTypePtr(pdnsapi.RRTypeNS),
+		TTL:        pdnsapi.Uint32(reverseDelegationTTL),
+		ChangeType: pdnsapi.ChangeTypePtr(pdnsapi.ChangeTypeReplace),
+		Records:    records,
+	}
+
+	err = powerdns.Engine.Records.Patch(ctx, parent, &pdnsapi.RRsets{Sets: []pdnsapi.RRset{delegation}})
+	if err != nil {
+		requestid.Logger(c.Context()).Warn().Err(err).Str("zone_name", parent).Msg("failed to delegate reverse zone")
+
+		return true, false, nil
+	}
+
+	zoneindex.Default.RefreshZone(ctx, parent)
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionZoneUpdated,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: parent,
+		Details:      map[string]any{"delegated": reverseZone, "records": len(records)},
+		IPAddress:    c.IP(),
+	}))
+
+	return true, true, nil
+}
+
+// apexNameservers returns the enabled NS records at the apex of zone.
+func apexNameservers(zone *pdnsapi.Zone, zoneName string) []string {
+	var nameservers []string
+
+	for _, rr := range zone.RRsets {
+		if rr.Name == nil || rr.Type == nil || *rr.Type != pdnsapi.RRTypeNS || !strings.EqualFold(*rr.Name, zoneName) {
+			continue
+		}
+
+		for _, r := range rr.Records {
+			if r.Content != nil && !pdnsapi.BoolValue(r.Disabled) {
+				nameservers = append(nameservers, *r.Content)
+			}
+		}
+	}
+
+	return nameservers
+}
+
+// aaaaOwner returns the name and TTL of the first AAAA RRset of zone holding
+// addr, or "" if there is none.
+func aaaaOwner(zone *pdnsapi.Zone, addr netip.Addr) (string, uint32) {
+	for _, rr := range zone.RRsets {
+		if rr.Name == nil || rr.Type == nil || *rr.Type != pdnsapi.RRTypeAAAA {
+			continue
+		}
+
+		for _, r := range rr.Records {
+			if a, err := netip.ParseAddr(pdnsapi.StringValue(r.Content)); err == nil && a == addr {
+				return strings.ToLower(*rr.Name), pdnsapi.Uint32Value(rr.TTL)
+			}
+		}
+	}
+
+	return "", 0
+}
@@ -0,0 +1,79 @@
+package zoneedit
+
+import (
+	"context"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func TestApexNameservers(t *testing.T) {
+	zone := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		{
+			Name: strPtr("Example.com."), Type: rrType("NS"),
+			Records: []pdnsapi.Record{
+				{Content: strPtr("ns1.example.com.")},
+				{Content: strPtr("ns2.example.com."), Disabled: pdnsapi.Bool(true)},
+				{Content: strPtr("ns3.example.net."), Disabled: pdnsapi.Bool(false)},
+			},
+		},
+		{
+			Name: strPtr("sub.example.com."), Type: rrType("NS"),
+			Records: []pdnsapi.Record{{Content: strPtr("ns.sub.example.com.")}},
+		},
+	}}
+
+	got := apexNameservers(zone, "example.com.")
+	want := []string{"ns1.example.com.", "ns3.example.net."}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apexNameservers = %v, want %v", got, want)
+	}
+}
+
+func TestAAAAOwner(t *testing.T) {
+	zone := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		{
+			Name: strPtr("a.example.com."), Type: rrType("A"), TTL: pdnsapi.Uint32(60),
+			Records: []pdnsapi.Record{{Content: strPtr("192.0.2.1")}},
+		},
+		{
+			Name: strPtr("WWW.example.com."), Type: rrType("AAAA"), TTL: pdnsapi.Uint32(300),
+			Records: []pdnsapi.Record{{Content: strPtr("2001:DB8:0:0::1")}},
+		},
+	}}
+
+	name, ttl := aaaaOwner(zone, netip.MustParseAddr("2001:db8::1"))
+	if name != "www.example.com." || ttl != 300 {
+		t.Errorf("aaaaOwner = %q, %d, want www.example.com., 300", name, ttl)
+	}
+
+	if name, _ := aaaaOwner(zone, netip.MustParseAddr("2001:db8::2")); name != "" {
+		t.Errorf("aaaaOwner of an unknown address = %q, want none", name)
+	}
+}
+
+func TestApplyRequestedPTRs_IgnoresOtherAddresses(t *testing.T) {
+	s := &Service{}
+
+	// Neither change needs a reverse zone lookup: the address is not a record
+	// of its change, or the type has no PTR records.
+	changes := []RecordChange{
+		{
+			Name: "www.example.com.", Type: "AAAA", Changed: true,
+			Records: []Record{{Content: "2001:db8::1"}},
+			PTR:     []string{"2001:db8::2"},
+		},
+		{
+			Name: "www.example.com.", Type: "TXT", Changed: true,
+			Records: []Record{{Content: `"2001:db8::1"`}},
+			PTR:     []string{`"2001:db8::1"`},
+		},
+	}
+
+	if got := s.applyRequestedPTRs(context.Background(), changes, nil); len(got) != 0 {
+		t.Errorf("applyRequestedPTRs = %v, want none", got)
+	}
+}
@@ -0,0 +1,92 @@
+package zoneedit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+// errMsgConflict is the message of a records update rejected because RRsets
+// it changes were changed by someone else after the page was loaded.
+const errMsgConflict = "Records were changed by someone else since you loaded the zone"
+
+// ErrMsgPreconditionFailed is the message of a change refused because the
+// zone is no longer at the revision the client based it on.
+const ErrMsgPreconditionFailed = "Zone was changed since the revision in If-Match"
+
+// rrsetRevision returns a fingerprint of the TTL, records and comments of an
+// RRset. The zone edit page sends it back with each change as the revision
+// the change was based on; it is empty for an RRset that does not exist.
+func rrsetRevision(rrSet *pdnsapi.RRset) string {
+	if rrSet == nil {
+		return ""
+	}
+
+	lines := make([]string, 0, len(rrSet.Records)+len(rrSet.Comments))
+
+	for _, rec := range rrSet.Records {
+		lines = append(lines, "r\x00"+pdnsapi.StringValue(rec.Content)+"\x00"+
+			strconv.FormatBool(pdnsapi.BoolValue(rec.Disabled)))
+	}
+
+	for _, comment := range rrSet.Comments {
+		lines = append(lines, "c\x00"+pdnsapi.StringValue(comment.Content)+"\x00"+
+			pdnsapi.StringValue(comment.Account)+"\x00"+strconv.FormatUint(pdnsapi.Uint64Value(comment.ModifiedAt), 10))
+	}
+
+	// PowerDNS does not guarantee the order of records and comments.
+	slices.Sort(lines)
+
+	sum := sha256.Sum256([]byte(strconv.FormatUint(uint64(pdnsapi.Uint32Value(rrSet.TTL)), 10) + "\n" +
+		strings.Join(lines, "\n")))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// findConflicts returns the RRsets changed in zone since the revision each
+// change of changes was based on. Changes without a base revision, such as
+// those of the bulk TTL change and the CSV import, are not checked.
+func findConflicts(changes []RecordChange, zone *pdnsapi.Zone) []RRsetRef {
+	current := rrSetIndex(zone)
+
+	var conflicts []RRsetRef
+
+	for _, change := range changes {
+		if change.Base == nil {
+			continue
+		}
+
+		name := change.Name
+		if !strings.HasSuffix(name, ".") {
+			name += "."
+		}
+
+		if rrsetRevision(current[rrSetKey(name, change.Type)]) != *change.Base {
+			conflicts = append(conflicts, RRsetRef{Name: name, Type: change.Type})
+		}
+	}
+
+	return conflicts
+}
+
+// ZoneRevision returns a fingerprint of the RRsets of zone, which changes
+// with every change of a record, TTL or comment. The PowerDNS-compatible API
+// sends it as the ETag of the zone.
+func ZoneRevision(zone *pdnsapi.Zone) string {
+	index := rrSetIndex(zone)
+
+	lines := make([]string, 0, len(index))
+	for key, rrSet := range index {
+		lines = append(lines, key+"\x00"+rrsetRevision(rrSet))
+	}
+
+	slices.Sort(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,123 @@
+package zoneedit
+
+import (
+	"reflect"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func TestRRsetRevision(t *testing.T) {
+	rrSet := func(ttl uint32, contents ...string) *pdnsapi.RRset {
+		records := make([]pdnsapi.Record, 0, len(contents))
+		for _, content := range contents {
+			records = append(records, pdnsapi.Record{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)})
+		}
+
+		return &pdnsapi.RRset{
+			Name: pdnsapi.String("www.example.com."), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeA),
+			TTL: pdnsapi.Uint32(ttl), Records: records,
+		}
+	}
+
+	base := rrsetRevision(rrSet(300, "192.0.2.1", "192.0.2.2"))
+
+	if base == "" {
+		t.Fatal("revision of an RRset is empty")
+	}
+
+	if got := rrsetRevision(nil); got != "" {
+		t.Errorf("revision of a missing RRset = %q, want empty", got)
+	}
+
+	if got := rrsetRevision(rrSet(300, "192.0.2.2", "192.0.2.1")); got != base {
+		t.Errorf("record order changed the revision: %q != %q", got, base)
+	}
+
+	disabled := rrSet(300, "192.0.2.1", "192.0.2.2")
+	disabled.Records[1].Disabled = pdnsapi.Bool(true)
+
+	commented := rrSet(300, "192.0.2.1", "192.0.2.2")
+	commented.Comments = []pdnsapi.Comment{{Content: pdnsapi.String("web"), Account: pdnsapi.String("admin")}}
+
+	for name, changed := range map[string]*pdnsapi.RRset{
+		"ttl":      rrSet(600, "192.0.2.1", "192.0.2.2"),
+		"records":  rrSet(300, "192.0.2.1"),
+		"disabled": disabled,
+		"comment":  commented,
+	} {
+		if rrsetRevision(changed) == base {
+			t.Errorf("changing the %s kept the revision", name)
+		}
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	www := pdnsapi.RRset{
+		Name: pdnsapi.String("www.example.com."), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeA), TTL: pdnsapi.Uint32(300),
+		Records: []pdnsapi.Record{{Content: pdnsapi.String("192.0.2.1"), Disabled: pdnsapi.Bool(false)}},
+	}
+	mail := pdnsapi.RRset{
+		Name: pdnsapi.String("mail.example.com."), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeA), TTL: pdnsapi.Uint32(300),
+		Records: []pdnsapi.Record{{Content: pdnsapi.String("192.0.2.2"), Disabled: pdnsapi.Bool(false)}},
+	}
+	zone := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{www, mail}}
+
+	current := rrsetRevision(&www)
+	stale := "0000000000000000"
+	none := ""
+
+	changes := []RecordChange{
+		// Unchanged since it was loaded.
+		{Name: "www.example.com.", Type: "A", Base: &current},
+		// Changed since it was loaded.
+		{Name: "mail.example.com", Type: "A", Base: &stale},
+		// Created since the page was loaded.
+		{Name: "mail.example.com.", Type: "A", Base: &none},
+		// New and still missing.
+		{Name: "ftp.example.com.", Type: "A", Base: &none},
+		// Deleted since it was loaded.
+		{Name: "old.example.com.", Type: "A", Base: &stale},
+		// Not checked.
+		{Name: "mail.example.com.", Type: "A"},
+	}
+
+	want := []RRsetRef{
+		{Name: "mail.example.com.", Type: "A"},
+		{Name: "mail.example.com.", Type: "A"},
+		{Name: "old.example.com.", Type: "A"},
+	}
+
+	if got := findConflicts(changes, zone); !reflect.DeepEqual(got, want) {
+		t.Errorf("findConflicts = %v, want %v", got, want)
+	}
+}
+
+func TestZoneRevision(t *testing.T) {
+	rrSet := func(name, content string) pdnsapi.RRset {
+		return pdnsapi.RRset{
+			Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(pdnsapi.RRTypeA), TTL: pdnsapi.Uint32(300),
+			Records: []pdnsapi.Record{{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)}},
+		}
+	}
+
+	base := ZoneRevision(&pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		rrSet("www.example.com.", "192.0.2.1"), rrSet("mail.example.com.", "192.0.2.2"),
+	}})
+
+	if got := ZoneRevision(&pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		rrSet("mail.example.com.", "192.0.2.2"), rrSet("WWW.example.com.", "192.0.2.1"),
+	}}); got != base {
+		t.Errorf("RRset order changed the revision: %q != %q", got, base)
+	}
+
+	for name, zone := range map[string]*pdnsapi.Zone{
+		"record": {RRsets: []pdnsapi.RRset{rrSet("www.example.com.", "192.0.2.1"), rrSet("mail.example.com.", "192.0.2.3")}},
+		"rrset":  {RRsets: []pdnsapi.RRset{rrSet("www.example.com.", "192.0.2.1")}},
+		"name":   {RRsets: []pdnsapi.RRset{rrSet("www.example.com.", "192.0.2.1"), rrSet("mx.example.com.", "192.0.2.2")}},
+	} {
+		if ZoneRevision(zone) == base {
+			t.Errorf("changing the %s kept the revision", name)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package zoneedit
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/labels"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+const (
+	// LazyRRsetThreshold is the number of RRsets from which the zone edit
+	// page loads its records page by page from the RRsets endpoint instead of
+	// embedding them all.
+	LazyRRsetThreshold = 1000
+
+	// maxRecordsPageSize is the largest page of the RRsets endpoint.
+	maxRecordsPageSize = 100
+
+	rrsetSortName = "name"
+	rrsetSortType = "type"
+	rrsetSortTTL  = "ttl"
+)
+
+// RRsetQuery filters, sorts and pages the RRsets of a zone like the records
+// table of the zone edit page.
+type RRsetQuery struct {
+	// Type keeps the RRsets of one type; empty keeps all.
+	Type string
+	// Search keeps the RRsets with a record whose name, data or comment
+	// contains it, ignoring case.
+	Search string
+	// Name keeps the RRsets with exactly this name.
+	Name string
+	// Label keeps the RRsets with this label.
+	Label labels.Selector
+	// Sort is name, type or ttl; Desc reverses it. The apex stays first when
+	// sorting by name.
+	Sort string
+	Desc bool
+	// Page is 1-based and clamped to the last page.
+	Page     int
+	PageSize int
+	// Focus and FocusType select the page holding the first RRset with this
+	// name, and type when set, instead of Page.
+	Focus     string
+	FocusType string
+}
+
+// RRsetPage is the JSON body of the RRsets endpoint.
+type RRsetPage struct {
+	// Records are all records of the RRsets on the page, so that the page
+	// can send complete RRsets to PostRecords.
+	Records []RecordData `json:"records"`
+	// RRsets and Total count the matching RRsets and their records.
+	RRsets   int `json:"rrsets"`
+	Total    int `json:"total"`
+	Page     int `json:"page"`
+	PageSize int `json:"page_size"`
+	// ExistingPTRs maps the PTR names of the A and AAAA records of the page
+	// to the reverse zone holding them.
+	ExistingPTRs map[string]string `json:"existing_ptrs"`
+}
+
+// rrsetGroup is the records of one RRset.
+type rrsetGroup struct {
+	records []RecordData
+}
+
+func (g *rrsetGroup) first() *RecordData { return &g.records[0] }
+
+// groupRRsets groups records, which extractRecordsFromRRSets returns RRset
+// by RRset, into their RRsets.
+func groupRRsets(records []RecordData) []rrsetGroup {
+	var groups []rrsetGroup
+
+	for i := 0; i < len(records); {
+		j := i + 1
+		for j < len(records) && records[j].Name == records[i].Name && records[j].Type == records[i].Type {
+			j++
+		}
+
+		groups = append(groups, rrsetGroup{records: records[i:j]})
+		i = j
+	}
+
+	return groups
+}
+
+// matches reports whether the RRset passes the filters of q.
+func (q *RRsetQuery) matches(g *rrsetGroup) bool {
+	r := g.first()
+
+	if q.Type != "" && r.Type != q.Type {
+		return false
+	}
+
+	if q.Name != "" && r.Name != q.Name {
+		return false
+	}
+
+	if !q.Label.Matches(r.Labels) {
+		return false
+	}
+
+	if q.Search == "" {
+		return true
+	}
+
+	search := strings.ToLower(q.Search)
+
+	return slices.ContainsFunc(g.records, func(r RecordData) bool {
+		return strings.Contains(strings.ToLower(r.DisplayName), search) ||
+			strings.Contains(strings.ToLower(r.Content), search) ||
+			strings.Contains(strings.ToLower(r.Comment), search)
+	})
+}
+
+// compare orders two RRsets by the sort of q.
+func (q *RRsetQuery) compare(a, b *rrsetGroup) int {
+	ra, rb := a.first(), b.first()
+
+	byName := func() int {
+		return cmp.Compare(strings.ToLower(ra.DisplayName), strings.ToLower(rb.DisplayName))
+	}
+
+	var c int
+
+	switch q.Sort {
+	case rrsetSortType:
+		c = cmp.Or(cmp.Compare(ra.Type, rb.Type), byName())
+	case rrsetSortTTL:
+		c = cmp.Or(cmp.Compare(ra.TTL, rb.TTL), byName())
+	default:
+		// The zone apex sorts first regardless of the direction.
+		if apexA, apexB := ra.DisplayName == "@", rb.DisplayName == "@"; apexA != apexB {
+			if apexA {
+				return -1
+			}
+
+			return 1
+		}
+
+		c = cmp.Or(byName(), cmp.Compare(ra.Type, rb.Type))
+	}
+
+	if q.Desc {
+		return -c
+	}
+
+	return c
+}
+
+// matchRRsets returns the RRsets of records passing the filters of q, in
+// the order of q, and the number of their records.
+func matchRRsets(records []RecordData, q *RRsetQuery) ([]rrsetGroup, int) {
+	var matched []rrsetGroup
+
+	total := 0
+
+	for _, g := range groupRRsets(records) {
+		if q.matches(&g) {
+			matched = append(matched, g)
+			total += len(g.records)
+		}
+	}
+
+	slices.SortStableFunc(matched, func(a, b rrsetGroup) int { return q.compare(&a, &b) })
+
+	return matched, total
+}
+
+// pageRRsets filters, sorts and pages records by RRset.
+func pageRRsets(records []RecordData, q *RRsetQuery) RRsetPage {
+	matched, total := matchRRsets(records, q)
+
+	size := q.PageSize
+	if size < 1 {
+		size = DefaultRecordsPageSize
+	}
+
+	pages := max((len(matched)+size-1)/size, 1)
+	page := min(max(q.Page, 1), pages)
+
+	if q.Focus != "" {
+		if i := slices.IndexFunc(matched, func(g rrsetGroup) bool {
+			return g.first().Name == q.Focus && (q.FocusType == "" || g.first().Type == q.FocusType)
+		}); i >= 0 {
+			page = i/size + 1
+		}
+	}
+
+	out := RRsetPage{Records: []RecordData{}, RRsets: len(matched), Total: total, Page: page, PageSize: size}
+
+	for _, g := range matched[min((page-1)*size, len(matched)):min(page*size, len(matched))] {
+		out.Records = append(out.Records, g.records...)
+	}
+
+	return out
+}
+
+// parseRRsetQuery reads the query of the RRsets endpoint. It fails only on
+// an invalid label selector.
+func parseRRsetQuery(c fiber.Ctx) (RRsetQuery, error) {
+	label, err := labels.ParseSelector(c.Query("label"))
+	if err != nil {
+		return RRsetQuery{}, err
+	}
+
+	q := RRsetQuery{
+		Type:      strings.ToUpper(c.Query("type")),
+		Search:    strings.TrimSpace(c.Query("search")),
+		Name:      c.Query("name"),
+		Label:     label,
+		Sort:      c.Query("sort", rrsetSortName),
+		Desc:      c.Query("order") == "desc",
+		Page:      fiber.Query[int](c, "page", 1),
+		PageSize:  fiber.Query[int](c, "page_size", DefaultRecordsPageSize),
+		Focus:     c.Query("focus"),
+		FocusType: strings.ToUpper(c.Query("focus_type")),
+	}
+
+	if q.PageSize < 1 || q.PageSize > maxRecordsPageSize {
+		q.PageSize = DefaultRecordsPageSize
+	}
+
+	return q, nil
+}
+
+// GetRRsets returns one page of the records of a zone, filtered and sorted
+// on the server. The zone edit page uses it for zones with more than
+// LazyRRsetThreshold RRsets.
+func (s *Service) GetRRsets(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+	if zoneName == "." {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	q, err := parseRRsetQuery(c)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, err.Error(), nil)
+	}
+
+	if powerdns.Engine.Client == nil {
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal,
+			powerdns.ErrMsgClientNotInitialized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to fetch zone")
+		return handler.JSONError(c, fiber.StatusNotFound, handler.CodeNotFound, "Zone not found: "+zoneName, nil)
+	}
+
+	records := extractRecordsFromRRSets(zone.RRsets, zoneName, getDisplayNameForZone)
+	s.attachLabels(c, zoneName, records)
+
+	page := pageRRsets(records, &q)
+
+	reverseZoneNames, _ := buildZoneLists(ctx)
+	page.ExistingPTRs = buildExistingPTRsMap(ctx, page.Records, reverseZoneNames)
+
+	return c.JSON(page)
+}
+
+// recordTypes returns the sorted types of records.
+func recordTypes(records []RecordData) []string {
+	types := make([]string, 0, len(records))
+	for i := range records {
+		types = append(types, records[i].Type)
+	}
+
+	slices.Sort(types)
+
+	return slices.Compact(types)
+}
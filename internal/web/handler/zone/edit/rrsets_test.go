@@ -0,0 +1,187 @@
+package zoneedit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+func TestPageRRsets(t *testing.T) {
+	rec := func(name, display, rrType string, ttl uint32, content string) RecordData {
+		return RecordData{Name: name, DisplayName: display, Type: rrType, TTL: ttl, Content: content}
+	}
+
+	records := []RecordData{
+		rec("www.example.com.", "www", "A", 300, "192.0.2.1"),
+		rec("www.example.com.", "www", "A", 300, "192.0.2.2"),
+		rec("example.com.", "@", "MX", 3600, "10 mail.example.com."),
+		rec("mail.example.com.", "mail", "A", 600, "192.0.2.3"),
+		rec("example.com.", "@", "NS", 3600, "ns1.example.net."),
+		rec("api.example.com.", "api", "CNAME", 60, "www.example.com."),
+	}
+
+	// rrsets lists the RRsets of a page as "display/type".
+	rrsets := func(p RRsetPage) []string {
+		var out []string
+		for _, g := range groupRRsets(p.Records) {
+			out = append(out, g.first().DisplayName+"/"+g.first().Type)
+		}
+
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		query     RRsetQuery
+		want      []string
+		wantPage  int
+		wantSets  int
+		wantTotal int
+	}{
+		{
+			name:     "name sort keeps the apex first",
+			query:    RRsetQuery{Page: 1, PageSize: 10},
+			want:     []string{"@/MX", "@/NS", "api/CNAME", "mail/A", "www/A"},
+			wantPage: 1, wantSets: 5, wantTotal: 6,
+		},
+		{
+			name:     "descending name sort keeps the apex first",
+			query:    RRsetQuery{Desc: true, Page: 1, PageSize: 10},
+			want:     []string{"@/NS", "@/MX", "www/A", "mail/A", "api/CNAME"},
+			wantPage: 1, wantSets: 5, wantTotal: 6,
+		},
+		{
+			name:     "pages whole RRsets",
+			query:    RRsetQuery{Page: 3, PageSize: 2},
+			want:     []string{"www/A"},
+			wantPage: 3, wantSets: 5, wantTotal: 6,
+		},
+		{
+			name:     "clamps the page",
+			query:    RRsetQuery{Page: 9, PageSize: 4},
+			want:     []string{"www/A"},
+			wantPage: 2, wantSets: 5, wantTotal: 6,
+		},
+		{
+			name:     "type filter and ttl sort",
+			query:    RRsetQuery{Type: "A", Sort: rrsetSortTTL, Page: 1, PageSize: 10},
+			want:     []string{"www/A", "mail/A"},
+			wantPage: 1, wantSets: 2, wantTotal: 3,
+		},
+		{
+			name:     "search returns the whole RRset",
+			query:    RRsetQuery{Search: "192.0.2.2", Page: 1, PageSize: 10},
+			want:     []string{"www/A"},
+			wantPage: 1, wantSets: 1, wantTotal: 2,
+		},
+		{
+			name:     "search ignores case and matches content",
+			query:    RRsetQuery{Search: "MAIL", Sort: rrsetSortType, Page: 1, PageSize: 10},
+			want:     []string{"mail/A", "@/MX"},
+			wantPage: 1, wantSets: 2, wantTotal: 2,
+		},
+		{
+			name:     "exact name",
+			query:    RRsetQuery{Name: "example.com.", Page: 1, PageSize: 1},
+			want:     []string{"@/MX"},
+			wantPage: 1, wantSets: 2, wantTotal: 2,
+		},
+		{
+			name:     "focus selects the page of the RRset",
+			query:    RRsetQuery{Focus: "www.example.com.", FocusType: "A", Page: 1, PageSize: 2},
+			want:     []string{"www/A"},
+			wantPage: 3, wantSets: 5, wantTotal: 6,
+		},
+		{
+			name:     "unknown focus keeps the page",
+			query:    RRsetQuery{Focus: "nope.example.com.", Page: 2, PageSize: 2},
+			want:     []string{"api/CNAME", "mail/A"},
+			wantPage: 2, wantSets: 5, wantTotal: 6,
+		},
+		{
+			name:     "no match",
+			query:    RRsetQuery{Type: "AAAA", Page: 1, PageSize: 10},
+			wantPage: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pageRRsets(records, &tt.query)
+
+			if g := rrsets(got); !reflect.DeepEqual(g, tt.want) {
+				t.Errorf("rrsets = %v, want %v", g, tt.want)
+			}
+
+			if got.Page != tt.wantPage || got.RRsets != tt.wantSets || got.Total != tt.wantTotal {
+				t.Errorf("page/rrsets/total = %d/%d/%d, want %d/%d/%d",
+					got.Page, got.RRsets, got.Total, tt.wantPage, tt.wantSets, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestPageRRsets_PagesLargeZones(t *testing.T) {
+	records := make([]RecordData, 0, 2*LazyRRsetThreshold)
+	for i := range LazyRRsetThreshold {
+		name := fmt.Sprintf("host%04d", i)
+		records = append(records,
+			RecordData{Name: name + ".example.com.", DisplayName: name, Type: "A", Content: "192.0.2.1"},
+			RecordData{Name: name + ".example.com.", DisplayName: name, Type: "A", Content: "192.0.2.2"},
+		)
+	}
+
+	got := pageRRsets(records, &RRsetQuery{Page: 2, PageSize: 25})
+
+	if got.RRsets != LazyRRsetThreshold || got.Total != len(records) || len(got.Records) != 50 {
+		t.Fatalf("rrsets/total/records = %d/%d/%d", got.RRsets, got.Total, len(got.Records))
+	}
+
+	if got.Records[0].DisplayName != "host0025" {
+		t.Errorf("first record = %q, want host0025", got.Records[0].DisplayName)
+	}
+}
+
+func TestRecordTypes(t *testing.T) {
+	got := recordTypes([]RecordData{{Type: "TXT"}, {Type: "A"}, {Type: "TXT"}, {Type: "MX"}, {Type: "A"}})
+	if want := []string{"A", "MX", "TXT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recordTypes = %v, want %v", got, want)
+	}
+}
+
+func TestGetRRsets_ReturnsErrorWhenPDNSClientNil(t *testing.T) {
+	powerdns.Engine.Client = nil
+
+	app := fiber.New()
+	svc := &Service{}
+
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", models.User{ID: 1})
+		return c.Next()
+	})
+	app.Get("/zones/:name/rrsets", svc.GetRRsets)
+
+	req := httptest.NewRequestWithContext(context.Background(), fiber.MethodGet, "/zones/example.com/rrsets", http.NoBody)
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test returned error: %v", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("expected status 500 when PDNS client is nil, got %d", resp.StatusCode)
+	}
+}
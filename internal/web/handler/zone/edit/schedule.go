@@ -0,0 +1,263 @@
+package zoneedit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/recordschedule"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// ScheduleView is a pending record schedule as sent to the zone editor.
+type ScheduleView struct {
+	ID        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Content   string    `json:"content"`
+	Action    string    `json:"action"`
+	RunAt     time.Time `json:"run_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// ScheduleRequest is the body of a request to schedule a record change.
+type ScheduleRequest struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Content string    `json:"content"`
+	Action  string    `json:"action"`
+	RunAt   time.Time `json:"run_at"`
+}
+
+// newScheduleView converts a schedule for the zone editor.
+func newScheduleView(s *models.RecordSchedule) ScheduleView {
+	view := ScheduleView{
+		ID:      s.ID,
+		Name:    s.Name,
+		Type:    s.Type,
+		Content: s.Content,
+		Action:  string(s.Action),
+		RunAt:   s.RunAt,
+	}
+
+	if s.CreatedBy != nil {
+		view.CreatedBy = s.CreatedBy.Username
+	}
+
+	return view
+}
+
+// loadScheduleViews returns the pending schedules of zoneName. Errors are
+// logged and yield no schedules, so the editor still loads.
+func (s *Service) loadScheduleViews(c fiber.Ctx, zoneName string) []ScheduleView {
+	schedules, err := recordschedule.Pending(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load record schedules")
+	}
+
+	views := make([]ScheduleView, 0, len(schedules))
+	for i := range schedules {
+		views = append(views, newScheduleView(&schedules[i]))
+	}
+
+	return views
+}
+
+// GetSchedules lists the pending record schedules of a zone.
+func (s *Service) GetSchedules(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"schedules": s.loadScheduleViews(c, zoneName),
+	})
+}
+
+// PostSchedule schedules enabling or disabling an existing record.
+func (s *Service) PostSchedule(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	if s.pendingDeletion(c, zoneName) != nil {
+		return handler.JSONError(c, fiber.StatusConflict, handler.CodeConflict, errMsgZoneDeleted, nil)
+	}
+
+	// A schedule would apply the change without approval.
+	if s.NeedsApproval(c) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Your record changes need approval and cannot be scheduled", nil)
+	}
+
+	var req ScheduleRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid request data", nil)
+	}
+
+	req.Name = normalizeZoneName(req.Name)
+	req.Type = strings.ToUpper(req.Type)
+
+	if msg := validateScheduleRequest(&req, time.Now()); msg != "" {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, msg, nil)
+	}
+
+	if editable := s.editableRecordTypes(c); editable != nil && !editable[req.Type] {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Your role does not permit modifying "+req.Type+" records",
+			fiber.Map{"record_type": req.Type})
+	}
+
+	if req.Type == recordTypeLUA && !s.canEditLUA(c) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"You are not permitted to modify LUA records", fiber.Map{"record_type": req.Type})
+	}
+
+	if powerdns.Engine.Client == nil {
+		return handler.JSONError(c, fiber.StatusServiceUnavailable, handler.CodeUnavailable,
+			powerdns.ErrMsgClientNotInitialized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadGateway, handler.CodeUpstream, "failed to fetch zone: "+err.Error(), nil)
+	}
+
+	if _, ok := recordschedule.Find(zone, req.Name, req.Type, req.Content); !ok {
+		return handler.JSONError(c, fiber.StatusNotFound, handler.CodeNotFound,
+			"The record does not exist in PowerDNS; save pending changes first", nil)
+	}
+
+	userID, username := auth.Actor(c)
+
+	schedule := &models.RecordSchedule{
+		ZoneName:    zoneName,
+		Name:        req.Name,
+		Type:        req.Type,
+		Content:     req.Content,
+		Action:      models.RecordScheduleAction(req.Action),
+		RunAt:       req.RunAt,
+		Status:      models.RecordSchedulePending,
+		CreatedByID: userID,
+	}
+
+	if err = s.db.Create(schedule).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to create record schedule")
+
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal,
+			"Failed to save the schedule", nil)
+	}
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionRecordScheduled,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      scheduleDetails(schedule),
+		IPAddress:    c.IP(),
+	}))
+
+	view := newScheduleView(schedule)
+	view.CreatedBy = username
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"message":  "Schedule saved",
+		"schedule": view,
+	})
+}
+
+// PostScheduleCancel cancels a pending record schedule.
+func (s *Service) PostScheduleCancel(c fiber.Ctx) error {
+	zoneName := normalizeZoneName(c.Params("name"))
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid schedule ID", nil)
+	}
+
+	schedule, err := recordschedule.Cancel(s.db, zoneName, id)
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return handler.JSONError(c, fiber.StatusNotFound, handler.CodeNotFound, "Schedule not found", nil)
+	case errors.Is(err, recordschedule.ErrNotPending):
+		return handler.JSONError(c, fiber.StatusConflict, handler.CodeConflict,
+			"The schedule already ran or was canceled", nil)
+	case err != nil:
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("schedule_id", id).Msg("failed to cancel record schedule")
+
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeInternal,
+			"Failed to cancel the schedule", nil)
+	}
+
+	userID, username := auth.Actor(c)
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionRecordScheduleCanceled,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      scheduleDetails(schedule),
+		IPAddress:    c.IP(),
+	}))
+
+	return c.JSON(fiber.Map{"success": true, "message": "Schedule canceled"})
+}
+
+// validateScheduleRequest returns a user-facing message when req is invalid.
+func validateScheduleRequest(req *ScheduleRequest, now time.Time) string {
+	switch {
+	case req.Name == "." || req.Type == "" || req.Content == "":
+		return "Name, type and content of the record are required"
+	case req.Type == "SOA" || isDNSSECManaged(req.Type):
+		return req.Type + " records cannot be scheduled"
+	case req.Action != string(models.RecordScheduleEnable) && req.Action != string(models.RecordScheduleDisable):
+		return "Action must be enable or disable"
+	case !req.RunAt.After(now):
+		return "The time must be in the future"
+	}
+
+	return ""
+}
+
+// scheduleDetails returns the activity log details of a schedule.
+func scheduleDetails(s *models.RecordSchedule) map[string]any {
+	return map[string]any{
+		"schedule_id": s.ID,
+		"name":        s.Name,
+		"type":        s.Type,
+		"content":     s.Content,
+		"action":      s.Action,
+		"run_at":      s.RunAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,34 @@
+package zoneedit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateScheduleRequest(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := ScheduleRequest{
+		Name: "www.example.com.", Type: "A", Content: "192.0.2.1", Action: "disable", RunAt: now.Add(time.Hour),
+	}
+
+	if msg := validateScheduleRequest(&valid, now); msg != "" {
+		t.Fatalf("valid request rejected: %s", msg)
+	}
+
+	tests := map[string]func(r *ScheduleRequest){
+		"missing content": func(r *ScheduleRequest) { r.Content = "" },
+		"soa":             func(r *ScheduleRequest) { r.Type = "SOA" },
+		"dnssec type":     func(r *ScheduleRequest) { r.Type = "RRSIG" },
+		"unknown action":  func(r *ScheduleRequest) { r.Action = "delete" },
+		"past time":       func(r *ScheduleRequest) { r.RunAt = now.Add(-time.Minute) },
+	}
+
+	for name, mutate := range tests {
+		req := valid
+		mutate(&req)
+
+		if msg := validateScheduleRequest(&req, now); msg == "" {
+			t.Errorf("%s: request accepted", name)
+		}
+	}
+}
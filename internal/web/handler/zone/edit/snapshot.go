@@ -0,0 +1,24 @@
+package zoneedit
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonesnapshot"
+)
+
+// saveSnapshot saves snapshot, the content of zoneName before the operation
+// of reason, as a zone snapshot. A failure is logged but does not stop the
+// operation.
+func (s *Service) saveSnapshot(c fiber.Ctx, zoneName, reason string, snapshot *activitylog.ZoneSnapshot) {
+	userID, _ := auth.Actor(c)
+
+	if _, err := zonesnapshot.Save(s.db, s.cfg.ZoneSnapshots, zoneName, reason, snapshot, userID); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).
+			Str("zone_name", zoneName).
+			Str("reason", reason).
+			Msg("failed to save zone snapshot")
+	}
+}
@@ -0,0 +1,116 @@
+package zoneedit
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+// transferView is the data of the zone transfer card.
+type transferView struct {
+	Status powerdns.TransferStatus
+	// CanRetrieve allows an AXFR from the primary (secondary zones only).
+	CanRetrieve bool
+	// LoadFailed hides the serials when the status could not be loaded.
+	LoadFailed bool
+	Error      string
+}
+
+// loadTransferView loads the replication state of primary and secondary
+// zones. It returns nil for other zone kinds.
+func loadTransferView(ctx context.Context, zoneName string, kind pdnsapi.ZoneKind) *transferView {
+	if kind != pdnsapi.MasterZoneKind && kind != pdnsapi.SlaveZoneKind {
+		return nil
+	}
+
+	view := &transferView{CanRetrieve: kind == pdnsapi.SlaveZoneKind}
+
+	status, err := powerdns.Engine.ZoneTransferStatus(ctx, zoneName)
+	if err != nil {
+		log.Error().Err(err).Str("zone_name", zoneName).Msg("failed to load zone transfer status")
+
+		view.LoadFailed = true
+
+		return view
+	}
+
+	view.Status = status
+
+	return view
+}
+
+// PostRetrieve asks PowerDNS to retrieve a secondary zone from its primary.
+func (s *Service) PostRetrieve(c fiber.Ctx) error {
+	return s.triggerTransfer(c, activitylog.ActionZoneRetrieved, "AXFR retrieval", powerdns.Engine.RetrieveZone)
+}
+
+// PostNotify asks PowerDNS to send a NOTIFY for the zone to its secondaries.
+func (s *Service) PostNotify(c fiber.Ctx) error {
+	return s.triggerTransfer(c, activitylog.ActionZoneNotified, "NOTIFY", powerdns.Engine.NotifyZone)
+}
+
+// triggerTransfer runs trigger for the zone, records action and redirects
+// back to the edit page with PowerDNS' result message.
+func (s *Service) triggerTransfer(c fiber.Ctx, action, what string,
+	trigger func(context.Context, string) (string, error),
+) error {
+	zoneName := c.Params("name")
+	if zoneName == "" {
+		return fiber.NewError(fiber.StatusBadRequest, ErrMsgZoneNameRequired)
+	}
+
+	zoneName = normalizeZoneName(zoneName)
+
+	if !s.canAccessZone(c, zoneName) {
+		return fiber.NewError(fiber.StatusForbidden, "Access to this zone is not permitted")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	result, err := trigger(ctx, zoneName)
+	if err != nil {
+		log.Error().Err(err).Str("zone_name", zoneName).Str("action", action).Msg("zone transfer trigger failed")
+		return redirectTransfer(c, zoneName, "", what+" failed: "+err.Error())
+	}
+
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       action,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      map[string]string{"result": result},
+		IPAddress:    c.IP(),
+	}))
+
+	if result == "" {
+		result = what + " requested"
+	}
+
+	return redirectTransfer(c, zoneName, result, "")
+}
+
+// redirectTransfer returns to the zone edit page with a success or error
+// message for the zone transfer card.
+func redirectTransfer(c fiber.Ctx, zoneName, success, errMsg string) error {
+	query := url.Values{}
+	if success != "" {
+		query.Set("success", success)
+	}
+
+	if errMsg != "" {
+		query.Set("transferError", errMsg)
+	}
+
+	return c.Redirect().To("/zone/edit/" + zoneName + "?" + query.Encode())
+}
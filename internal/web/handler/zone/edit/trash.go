@@ -0,0 +1,36 @@
+package zoneedit
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/recordtrash"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+// trashDeleted puts rrSets, just deleted from zoneName, into the trash of
+// the zone. A failure is logged but does not fail the change, which is
+// already applied.
+func (s *Service) trashDeleted(c fiber.Ctx, zoneName string, rrSets []pdnsapi.RRset) {
+	userID, _ := auth.Actor(c)
+
+	if err := recordtrash.Add(s.db, s.cfg.RecordTrash, zoneName, rrSets, userID, time.Now()); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).
+			Str("zone_name", zoneName).
+			Int("rrsets", len(rrSets)).
+			Msg("failed to move deleted RRsets to the trash")
+	}
+}
+
+// trashCount returns the number of deleted RRsets in the trash of zoneName.
+func (s *Service) trashCount(c fiber.Ctx, zoneName string) int64 {
+	count, err := recordtrash.Count(s.db, zoneName, time.Now())
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to count trash entries")
+	}
+
+	return count
+}
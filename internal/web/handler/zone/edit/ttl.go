@@ -0,0 +1,237 @@
+package zoneedit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	ttlsettings "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/ttl"
+)
+
+// maxTTL is the largest TTL allowed by RFC 2181.
+const maxTTL = 1<<31 - 1
+
+var errTTLNoSelection = errors.New("select records or record types to change")
+
+// RRsetRef identifies an RRset by name and type.
+type RRsetRef struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TTLUpdateRequest is a bulk TTL change. It applies to the RRsets listed in
+// RRsets and to every RRset of the listed Types. With Preview set nothing is
+// changed; the response lists the RRsets that would be.
+type TTLUpdateRequest struct {
+	TTL     uint32     `json:"ttl"`
+	Types   []string   `json:"types"`
+	RRsets  []RRsetRef `json:"rrsets"`
+	Preview bool       `json:"preview"`
+}
+
+// TTLChange describes the TTL change of one RRset.
+type TTLChange struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+	OldTTL      uint32 `json:"old_ttl"`
+	NewTTL      uint32 `json:"new_ttl"`
+	Records     int    `json:"records"`
+}
+
+// selectTTLChanges returns the changes setting the TTL of the RRsets of zone
+// selected by request, sorted by name and type. RRsets that already have the
+// TTL and DNSSEC-managed RRsets are left out.
+func selectTTLChanges(zone *pdnsapi.Zone, request *TTLUpdateRequest) []RecordChange {
+	selected := make(map[string]bool, len(request.RRsets))
+
+	for _, ref := range request.RRsets {
+		selected[rrSetKey(normalizeZoneName(ref.Name), strings.ToUpper(ref.Type))] = true
+	}
+
+	types := make(map[string]bool, len(request.Types))
+
+	for _, t := range request.Types {
+		types[strings.ToUpper(t)] = true
+	}
+
+	var changes []RecordChange
+
+	for key, rrSet := range rrSetIndex(zone) {
+		rrType := string(*rrSet.Type)
+		if !selected[key] && !types[rrType] || isDNSSECManaged(rrType) {
+			continue
+		}
+
+		if rrSet.TTL != nil && *rrSet.TTL == request.TTL {
+			continue
+		}
+
+		records := make([]Record, 0, len(rrSet.Records))
+
+		for _, rec := range rrSet.Records {
+			records = append(records, Record{
+				Content:  pdnsapi.StringValue(rec.Content),
+				Disabled: pdnsapi.BoolValue(rec.Disabled),
+			})
+		}
+
+		changes = append(changes, RecordChange{
+			Existed: true,
+			Changed: true,
+			Name:    *rrSet.Name,
+			Type:    rrType,
+			TTL:     request.TTL,
+			Records: records,
+			Comment: extractCommentFromRRSet(rrSet),
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+
+		return changes[i].Type < changes[j].Type
+	})
+
+	return changes
+}
+
+// PostTTL changes the TTL of several RRsets in one PATCH, or previews the
+// change.
+func (s *Service) PostTTL(c fiber.Ctx) error {
+	zoneName := c.Params("name")
+	if zoneName == "" {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, ErrMsgZoneNameRequired, nil)
+	}
+
+	zoneName = normalizeZoneName(zoneName)
+
+	if !s.canAccessZone(c, zoneName) {
+		return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+			"Access to this zone is not permitted", nil)
+	}
+
+	var request TTLUpdateRequest
+	if err := c.Bind().Body(&request); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to parse TTL update request")
+
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeBadRequest, "Invalid request data", nil)
+	}
+
+	if len(request.Types) == 0 && len(request.RRsets) == 0 {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation, errTTLNoSelection.Error(), nil)
+	}
+
+	if request.TTL > maxTTL {
+		return handler.JSONError(c, fiber.StatusBadRequest, handler.CodeValidation,
+			"TTL must not exceed "+strconv.Itoa(maxTTL)+" seconds", fiber.Map{"ttl": request.TTL})
+	}
+
+	if powerdns.Engine.Client == nil {
+		requestid.Logger(c.Context()).Error().Msg(powerdns.ErrMsgClientNotInitialized)
+
+		return handler.JSONError(c, fiber.StatusServiceUnavailable, handler.CodeUnavailable,
+			powerdns.ErrMsgClientNotInitialized, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), defaultTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return handler.JSONError(c, fiber.StatusInternalServerError, handler.CodeUpstream,
+			fmt.Sprintf("failed to fetch zone: %v", err), nil)
+	}
+
+	changes := selectTTLChanges(zone, &request)
+
+	if request.Preview {
+		current := rrSetIndex(zone)
+		preview := make([]TTLChange, 0, len(changes))
+
+		for _, change := range changes {
+			preview = append(preview, TTLChange{
+				Name:        change.Name,
+				DisplayName: getDisplayNameForZone(change.Name, zoneName),
+				Type:        change.Type,
+				OldTTL:      pdnsapi.Uint32Value(current[rrSetKey(change.Name, change.Type)].TTL),
+				NewTTL:      change.TTL,
+				Records:     len(change.Records),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"success": true,
+			"changes": preview,
+		})
+	}
+
+	if len(changes) == 0 {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "No RRsets need a TTL change",
+		})
+	}
+
+	return s.applyRecordsUpdate(c, zoneName, &RecordsUpdateRequest{Changes: changes})
+}
+
+// canOverrideTTL reports whether the user may set TTLs outside the TTL
+// policies.
+func (s *Service) canOverrideTTL(c fiber.Ctx) bool {
+	return auth.HasPermissionInContext(c, s.authService, auth.PermZoneTTLOverride)
+}
+
+// validateTTLPolicies rejects changes whose TTL the TTL policies do not
+// allow, unless the user has the zone.ttl_override permission.
+func (s *Service) validateTTLPolicies(c fiber.Ctx, zoneName string, changes []RecordChange) error {
+	policies := ttlsettings.LoadPolicies(s.db)
+	if len(policies) == 0 || s.canOverrideTTL(c) {
+		return nil
+	}
+
+	return checkTTLPolicies(policies, zoneName, changes)
+}
+
+// checkTTLPolicies returns a *ChangeError for the first changed RRset of
+// changes whose TTL is outside the policy that applies to it. Deletions have
+// no TTL to check.
+func checkTTLPolicies(policies []ttlsettings.Policy, zoneName string, changes []RecordChange) error {
+	for _, change := range changes {
+		if !change.Changed || len(change.Records) == 0 {
+			continue
+		}
+
+		rrType := strings.ToUpper(change.Type)
+
+		policy := ttlsettings.Effective(policies, zoneName, rrType)
+		if policy == nil || policy.Allows(change.TTL) {
+			continue
+		}
+
+		return &ChangeError{
+			Status: fiber.StatusBadRequest,
+			Code:   handler.CodeValidation,
+			Message: fmt.Sprintf("The TTL of %s %s must be %s, not %d",
+				rrType, change.Name, policy.Bounds(), change.TTL),
+			Details: fiber.Map{
+				"name": change.Name, "record_type": rrType, "ttl": change.TTL,
+				"min": policy.Min, "max": policy.Max,
+			},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package zoneedit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	ttlsettings "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/ttl"
+)
+
+func TestSelectTTLChanges(t *testing.T) {
+	rrSet := func(name string, rrType pdnsapi.RRType, ttl uint32, contents ...string) pdnsapi.RRset {
+		records := make([]pdnsapi.Record, 0, len(contents))
+		for _, content := range contents {
+			records = append(records, pdnsapi.Record{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)})
+		}
+
+		return pdnsapi.RRset{Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(rrType), TTL: pdnsapi.Uint32(ttl), Records: records}
+	}
+
+	zone := &pdnsapi.Zone{RRsets: []pdnsapi.RRset{
+		rrSet("www.example.com.", pdnsapi.RRTypeA, 3600, "192.0.2.1", "192.0.2.2"),
+		rrSet("mail.example.com.", pdnsapi.RRTypeA, 300, "192.0.2.3"),
+		rrSet("example.com.", pdnsapi.RRTypeMX, 3600, "10 mail.example.com."),
+		rrSet("example.com.", pdnsapi.RRTypeTXT, 3600, `"v=spf1 -all"`),
+		rrSet("example.com.", pdnsapi.RRTypeRRSIG, 3600, "A 13 2 3600 20261101000000 20261001000000 1 example.com. AAAA"),
+	}}
+	zone.RRsets[3].Comments = []pdnsapi.Comment{{Content: pdnsapi.String("spf")}}
+
+	changes := selectTTLChanges(zone, &TTLUpdateRequest{
+		TTL:    300,
+		Types:  []string{"a", "RRSIG"},
+		RRsets: []RRsetRef{{Name: "Example.com", Type: "txt"}},
+	})
+
+	want := []RecordChange{
+		{
+			Existed: true, Changed: true, Name: "example.com.", Type: "TXT", TTL: 300, Comment: "spf",
+			Records: []Record{{Content: `"v=spf1 -all"`}},
+		},
+		{
+			Existed: true, Changed: true, Name: "www.example.com.", Type: "A", TTL: 300,
+			Records: []Record{{Content: "192.0.2.1"}, {Content: "192.0.2.2"}},
+		},
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("selectTTLChanges() = %+v, want %+v", changes, want)
+	}
+
+	if changes := selectTTLChanges(zone, &TTLUpdateRequest{TTL: 300, Types: []string{"NS"}}); len(changes) != 0 {
+		t.Errorf("selectTTLChanges() for absent type = %+v, want none", changes)
+	}
+}
+
+func TestCheckTTLPolicies(t *testing.T) {
+	policies := []ttlsettings.Policy{{Min: 300, Max: 86400}, {Type: "TXT", Min: 60}}
+	change := func(rrType string, ttl uint32) RecordChange {
+		return RecordChange{Changed: true, Name: "www.example.com.", Type: rrType, TTL: ttl,
+			Records: []Record{{Content: "x"}}}
+	}
+
+	if err := checkTTLPolicies(policies, "example.com.", []RecordChange{change("A", 3600), change("txt", 60)}); err != nil {
+		t.Errorf("TTLs within the policies: %v", err)
+	}
+
+	err := checkTTLPolicies(policies, "example.com.", []RecordChange{change("A", 5)})
+
+	var changeErr *ChangeError
+	if !errors.As(err, &changeErr) || changeErr.Code != handler.CodeValidation ||
+		changeErr.Message != "The TTL of A www.example.com. must be between 300 and 86400 seconds, not 5" {
+		t.Errorf("TTL below the policy: err = %v", err)
+	}
+
+	unchanged := change("A", 5)
+	unchanged.Changed = false
+	deleted := change("A", 0)
+	deleted.Records = nil
+
+	if err = checkTTLPolicies(policies, "example.com.", []RecordChange{unchanged, deleted}); err != nil {
+		t.Errorf("unchanged and deleted RRsets: %v", err)
+	}
+}
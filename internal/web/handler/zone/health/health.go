@@ -0,0 +1,208 @@
+// Package zonehealth provides the zone health report, which runs the checks of
+// package zonecheck on every zone the user has access to.
+package zonehealth
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonecheck"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// Path is the path of the zone health report.
+	Path = handler.RootPath + "zone/health"
+
+	templateReport = "zone/health"
+
+	labelZoneHealth = "Zone Health"
+
+	// checkWorkers is the number of zones fetched from PowerDNS at a time.
+	checkWorkers = 4
+
+	// reportTimeout bounds fetching and checking all zones.
+	reportTimeout = 2 * time.Minute
+)
+
+// Row is the health of one zone.
+type Row struct {
+	Zone   string
+	Report zonecheck.Report
+	// Error is why the zone could not be checked.
+	Error string
+}
+
+// Summary counts the zones of a report.
+type Summary struct {
+	Checked      int
+	WithErrors   int
+	WithWarnings int
+	Healthy      int
+	Failed       int
+}
+
+// Service handles the zone health report.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+	now         func() time.Time
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+	s.now = time.Now
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermZoneRead), s.Report)
+}
+
+// Report checks the zones the current user has access to and renders those
+// with issues, or all of them with ?all=1.
+func (s *Service) Report(c fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), reportTimeout)
+	defer cancel()
+
+	zones, err := zoneindex.Default.List(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to fetch zones from PowerDNS")
+
+		msg := "Failed to fetch zones: " + err.Error()
+		if powerdns.IsServerUnreachable(err) {
+			msg = powerdns.ErrMsgServerUnreachable
+		}
+
+		return handler.RenderError(c, fiber.StatusInternalServerError,
+			"PowerDNS Unreachable", msg, handler.PDNSServerSettingsAction)
+	}
+
+	allowed, err := auth.ZoneFilter(c, s.authService)
+	if err != nil {
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", "Failed to load zone access", nil)
+	}
+
+	names := make([]string, 0, len(zones))
+
+	for i := range zones {
+		if name := pdnsapi.StringValue(zones[i].Name); allowed == nil || allowed(name) {
+			names = append(names, name)
+		}
+	}
+
+	rows := s.checkZones(ctx, names)
+	summary := summarize(rows)
+
+	showAll := fiber.Query[bool](c, "all")
+	if !showAll {
+		rows = slices.DeleteFunc(rows, func(r Row) bool {
+			return r.Error == "" && len(r.Report.Issues) == 0
+		})
+	}
+
+	nav := navigation.NewContext(labelZoneHealth, "zones", "health").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelZoneHealth, Path, true)
+
+	return c.Render(templateReport, fiber.Map{
+		"Navigation": nav,
+		"Rows":       rows,
+		"Summary":    summary,
+		"ShowAll":    showAll,
+	}, handler.BaseLayout)
+}
+
+// checkZones fetches and checks the named zones with checkWorkers workers.
+// Zones with errors come first, then zones with warnings, each by name.
+func (s *Service) checkZones(ctx context.Context, names []string) []Row {
+	rows := make([]Row, len(names))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range checkWorkers {
+		wg.Go(func() {
+			for i := range next {
+				rows[i] = s.checkZone(ctx, names[i])
+			}
+		})
+	}
+
+	for i := range names {
+		next <- i
+	}
+
+	close(next)
+	wg.Wait()
+
+	slices.SortFunc(rows, func(a, b Row) int {
+		return cmp.Or(
+			cmp.Compare(b.Report.Errors, a.Report.Errors),
+			cmp.Compare(b.Report.Warnings, a.Report.Warnings),
+			strings.Compare(a.Zone, b.Zone),
+		)
+	})
+
+	return rows
+}
+
+// checkZone fetches and checks one zone.
+func (s *Service) checkZone(ctx context.Context, name string) Row {
+	if powerdns.Engine.Client == nil {
+		return Row{Zone: name, Error: powerdns.ErrMsgClientNotInitialized}
+	}
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, name)
+	if err != nil {
+		log.Warn().Err(err).Str("zone_name", name).Msg("failed to fetch zone for health check")
+		return Row{Zone: name, Error: err.Error()}
+	}
+
+	return Row{Zone: name, Report: zonecheck.Check(zone, s.now())}
+}
+
+// summarize counts the zones of rows by health.
+func summarize(rows []Row) Summary {
+	summary := Summary{Checked: len(rows)}
+
+	for i := range rows {
+		switch r := &rows[i]; {
+		case r.Error != "":
+			summary.Failed++
+		case r.Report.Errors > 0:
+			summary.WithErrors++
+		case r.Report.Warnings > 0:
+			summary.WithWarnings++
+		default:
+			summary.Healthy++
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,21 @@
+package zonehealth
+
+import (
+	"testing"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonecheck"
+)
+
+func TestSummarize(t *testing.T) {
+	rows := []Row{
+		{Zone: "a.example.", Report: zonecheck.Report{Errors: 1, Warnings: 2}},
+		{Zone: "b.example.", Report: zonecheck.Report{Warnings: 1}},
+		{Zone: "c.example."},
+		{Zone: "d.example.", Error: "timeout"},
+	}
+
+	want := Summary{Checked: 4, WithErrors: 1, WithWarnings: 1, Healthy: 1, Failed: 1}
+	if got := summarize(rows); got != want {
+		t.Errorf("summarize() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package zonerequest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+)
+
+// notifyReviewers emails every user allowed to review zone requests about a
+// new request.
+func (s *Service) notifyReviewers(c fiber.Ctx, req *models.ZoneRequest, requester *models.User) {
+	if s.mailer == nil {
+		return
+	}
+
+	reviewers, err := s.authService.GetUsersWithPermission(auth.PermAdminZoneRequests)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load zone request reviewers")
+		return
+	}
+
+	nameservers := "template default"
+	if ns := req.NameserverList(); len(ns) > 0 {
+		nameservers = strings.Join(ns, ", ")
+	}
+
+	subject := s.brandName(c) + ": zone request for " + req.Name
+	body := fmt.Sprintf(`%s requested the zone %s.
+
+Purpose:
+%s
+
+Nameservers: %s
+
+Review the request at:
+
+%s
+`, requester.FullName(), req.Name, req.Purpose, nameservers, s.link(fmt.Sprintf("%s/%d", PathAdmin, req.ID)))
+
+	for i := range reviewers {
+		if reviewers[i].Email == "" || reviewers[i].ID == requester.ID {
+			continue
+		}
+
+		s.send(reviewers[i].Email, subject, body)
+	}
+}
+
+// notifyRequester emails the requester the outcome of the review.
+func (s *Service) notifyRequester(c fiber.Ctx, req *models.ZoneRequest) {
+	if s.mailer == nil || req.Requester.Email == "" {
+		return
+	}
+
+	var subject, body string
+
+	switch req.Status {
+	case models.ZoneRequestApproved:
+		subject = s.brandName(c) + ": zone " + req.Name + " created"
+		body = fmt.Sprintf(`Hello %s,
+
+your request for the zone %s was approved and the zone has been created.
+
+%s
+`, req.Requester.FullName(), req.Name, s.link(PathMine))
+	case models.ZoneRequestRejected:
+		subject = s.brandName(c) + ": zone request for " + req.Name + " rejected"
+		body = fmt.Sprintf(`Hello %s,
+
+your request for the zone %s was rejected.
+
+Reason:
+%s
+`, req.Requester.FullName(), req.Name, req.ReviewComment)
+	default:
+		return
+	}
+
+	if req.Status == models.ZoneRequestApproved && req.ReviewComment != "" {
+		body += "\nComment from the reviewer:\n" + req.ReviewComment + "\n"
+	}
+
+	s.send(req.Requester.Email, subject, body)
+}
+
+// send delivers a message in the background so a slow SMTP server does not
+// delay the response.
+func (s *Service) send(to, subject, body string) {
+	jobs.Go(jobs.Mail, func() error {
+		err := s.mailer.Send(to, subject, body)
+		if err != nil {
+			log.Error().Err(err).Str("to", to).Msg("failed to send zone request email")
+		}
+
+		return err
+	})
+}
+
+func (s *Service) brandName(c fiber.Ctx) string {
+	if brand, ok := c.Locals("Brand").(config.Branding); ok {
+		return brand.Name
+	}
+
+	return s.cfg.Branding.Resolve(s.cfg.Title).Name
+}
+
+func (s *Service) link(path string) string {
+	return strings.TrimRight(s.cfg.Webserver.URL, "/") + path
+}
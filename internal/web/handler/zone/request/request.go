@@ -0,0 +1,329 @@
+// Package zonerequest provides the self-service zone request workflow: users
+// without the zone.create permission submit a request, administrators approve
+// or reject it, and the requester is notified by email.
+package zonerequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/mailer"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	zoneadd "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/add"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path of the zone request form.
+	Path = handler.RootPath + "zone/request"
+
+	// PathMine lists the zone requests of the current user.
+	PathMine = handler.RootPath + "zone/requests"
+
+	// PathAdmin is the base path of the review queue.
+	PathAdmin = handler.RootPath + "admin/zone-requests"
+
+	templateNew    = "zone/request/new"
+	templateMine   = "zone/request/mine"
+	templateList   = "admin/zonerequest/list"
+	templateReview = "admin/zonerequest/review"
+
+	labelRequestZone  = "Request Zone"
+	labelMyRequests   = "My Zone Requests"
+	labelZoneRequests = "Zone Requests"
+
+	maxPurposeLen = 2000
+
+	errFailedLoadRequests = "Failed to load zone requests"
+	errFailedSaveRequest  = "Failed to save the zone request"
+	errInvalidRequestID   = "Invalid zone request ID"
+	errRequestNotFound    = "Zone request not found"
+	errNotSignedIn        = "You must be signed in to request a zone."
+)
+
+// zoneNameRegex matches a lower-case DNS name with trailing dot and at least
+// two labels.
+var zoneNameRegex = regexp.MustCompile(`^([a-z0-9_]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.$`)
+
+// Service handles the zone request pages.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+	mailer      mailer.Sender
+	// createZone creates the approved zone in PowerDNS; replaced in tests.
+	createZone func(ctx context.Context, form *zoneadd.ZoneForm) error
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Form is the submitted zone request form.
+type Form struct {
+	Name        string `form:"name"`
+	Purpose     string `form:"purpose"`
+	Nameservers string `form:"nameservers"`
+	TagID       uint   `form:"tag_id"`
+}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+	s.createZone = zoneadd.CreateZone
+
+	if cfg.Mail.Enabled() {
+		s.mailer = mailer.New(&cfg.Mail)
+	}
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermZoneRequest), s.New)
+	app.Post(Path, auth.RequirePermission(authService, auth.PermZoneRequest), s.Submit)
+	app.Get(PathMine, auth.RequirePermission(authService, auth.PermZoneRequest), s.Mine)
+
+	app.Get(PathAdmin, auth.RequirePermission(authService, auth.PermAdminZoneRequests), s.List)
+	app.Get(PathAdmin+"/:id", auth.RequirePermission(authService, auth.PermAdminZoneRequests), s.Review)
+	app.Post(PathAdmin+"/:id/approve", auth.RequirePermission(authService, auth.PermAdminZoneRequests), s.Approve)
+	app.Post(PathAdmin+"/:id/reject", auth.RequirePermission(authService, auth.PermAdminZoneRequests), s.Reject)
+}
+
+// New renders the zone request form.
+func (s *Service) New(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok {
+		return handler.RenderError(c, fiber.StatusUnauthorized, "Unauthorized", errNotSignedIn, nil)
+	}
+
+	tags, err := s.authService.GetUserTags(user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load user tags")
+	}
+
+	form := &Form{}
+	if len(tags) == 1 {
+		form.TagID = tags[0].ID
+	}
+
+	return s.renderNew(c, fiber.StatusOK, form, tags, "")
+}
+
+// Submit stores a new zone request and notifies the reviewers.
+func (s *Service) Submit(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok {
+		return handler.RenderError(c, fiber.StatusUnauthorized, "Unauthorized", errNotSignedIn, nil)
+	}
+
+	form := &Form{}
+	if err := c.Bind().Body(form); err != nil {
+		return s.renderNew(c, fiber.StatusBadRequest, form, nil, "Invalid form data")
+	}
+
+	tags, err := s.authService.GetUserTags(user.ID)
+	if err != nil {
+		log.Error().Err(err).Uint64("user_id", user.ID).Msg("failed to load user tags")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedSaveRequest, nil)
+	}
+
+	req, msg := s.buildRequest(form, tags)
+	if msg != "" {
+		return s.renderNew(c, fiber.StatusBadRequest, form, tags, msg)
+	}
+
+	req.RequesterID = user.ID
+
+	if err := s.db.Create(req).Error; err != nil {
+		log.Error().Err(err).Str("zone", req.Name).Msg("failed to store zone request")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveRequest, nil)
+	}
+
+	userID := user.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &userID,
+		Username:     user.Username,
+		Action:       activitylog.ActionZoneRequested,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: req.Name,
+		Details:      map[string]any{"request_id": req.ID, "purpose": req.Purpose},
+		IPAddress:    c.IP(),
+	}))
+
+	s.notifyReviewers(c, req, &user)
+
+	return c.Redirect().To(PathMine + "?success=" + url.QueryEscape("Zone request for "+req.Name+" submitted."))
+}
+
+// Mine lists the zone requests of the current user.
+func (s *Service) Mine(c fiber.Ctx) error {
+	user, ok := c.Locals("CurrentUser").(models.User)
+	if !ok {
+		return handler.RenderError(c, fiber.StatusUnauthorized, "Unauthorized", errNotSignedIn, nil)
+	}
+
+	var requests []models.ZoneRequest
+	if err := s.db.Preload("Tag").
+		Where("requester_id = ?", user.ID).
+		Order("created_at DESC").
+		Find(&requests).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load zone requests")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadRequests, nil)
+	}
+
+	nav := navigation.NewContext(labelMyRequests, "zones", "requests").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelMyRequests, PathMine, true)
+
+	return c.Render(templateMine, fiber.Map{
+		"Navigation": nav,
+		"Requests":   requests,
+		"Success":    c.Query("success"),
+	}, handler.BaseLayout)
+}
+
+// buildRequest validates form and returns the request to store, or a message
+// for the user.
+func (s *Service) buildRequest(form *Form, tags []models.Tag) (*models.ZoneRequest, string) {
+	name, err := normalizeZoneName(form.Name)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	nameservers, err := parseNameservers(form.Nameservers)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	purpose := strings.TrimSpace(form.Purpose)
+
+	switch {
+	case purpose == "":
+		return nil, "Describe what the zone is for."
+	case len(purpose) > maxPurposeLen:
+		return nil, fmt.Sprintf("The purpose must be at most %d characters.", maxPurposeLen)
+	}
+
+	var tagID *uint
+
+	if form.TagID != 0 {
+		if !containsTag(tags, form.TagID) {
+			return nil, "Select one of your teams."
+		}
+
+		id := form.TagID
+		tagID = &id
+	}
+
+	var pending int64
+	if err := s.db.Model(&models.ZoneRequest{}).
+		Where("name = ? AND status = ?", name, models.ZoneRequestPending).
+		Count(&pending).Error; err != nil {
+		log.Error().Err(err).Msg("failed to check for pending zone requests")
+		return nil, errFailedSaveRequest
+	}
+
+	if pending > 0 {
+		return nil, "A request for " + name + " is already waiting for review."
+	}
+
+	return &models.ZoneRequest{
+		Name:        name,
+		Purpose:     purpose,
+		Nameservers: strings.Join(nameservers, "\n"),
+		TagID:       tagID,
+		Status:      models.ZoneRequestPending,
+	}, ""
+}
+
+func (s *Service) renderNew(c fiber.Ctx, status int, form *Form, tags []models.Tag, msg string) error {
+	nav := navigation.NewContext(labelRequestZone, "zones", "request").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(labelMyRequests, PathMine, false).
+		AddBreadcrumb(labelRequestZone, Path, true)
+
+	return c.Status(status).Render(templateNew, fiber.Map{
+		"Navigation":         nav,
+		"Form":               form,
+		"Tags":               tags,
+		"Error":              msg,
+		"DefaultNameservers": s.cfg.ZoneRequest.Nameservers,
+	}, handler.BaseLayout)
+}
+
+// normalizeZoneName lower-cases name, adds the trailing dot and checks that
+// the result is a valid zone name.
+func normalizeZoneName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", errors.New("enter the zone name")
+	}
+
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	if len(name) > 254 || !zoneNameRegex.MatchString(name) {
+		return "", fmt.Errorf("%q is not a valid zone name", name)
+	}
+
+	return name, nil
+}
+
+// parseNameservers splits a comma or newline separated list of hostnames.
+func parseNameservers(raw string) ([]string, error) {
+	var nameservers []string
+
+	seen := make(map[string]bool)
+
+	for field := range strings.FieldsFuncSeq(raw, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		ns, err := normalizeZoneName(field)
+		if err != nil {
+			return nil, fmt.Errorf("nameserver %q is not a valid hostname", field)
+		}
+
+		if !seen[ns] {
+			seen[ns] = true
+			nameservers = append(nameservers, ns)
+		}
+	}
+
+	return nameservers, nil
+}
+
+func containsTag(tags []models.Tag, id uint) bool {
+	for i := range tags {
+		if tags[i].ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseID returns the :id route parameter.
+func parseID(c fiber.Ctx) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	return id, err == nil && id > 0
+}
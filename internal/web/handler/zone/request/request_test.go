@@ -0,0 +1,313 @@
+package zonerequest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	zoneadd "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/add"
+)
+
+// noOpViews renders the template name so tests can tell pages apart.
+type noOpViews struct{}
+
+func (noOpViews) Load() error { return nil }
+
+func (noOpViews) Render(w io.Writer, name string, _ any, _ ...string) error {
+	_, _ = io.WriteString(w, name)
+	return nil
+}
+
+// stubMailer collects sent messages; sends happen in the background.
+type stubMailer struct {
+	sent chan string
+}
+
+func (m *stubMailer) Send(to, subject, _ string) error {
+	m.sent <- to + ": " + subject
+	return nil
+}
+
+func (m *stubMailer) next(t *testing.T) string {
+	t.Helper()
+
+	select {
+	case msg := <-m.sent:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email sent")
+		return ""
+	}
+}
+
+type fixture struct {
+	app       *fiber.App
+	svc       *Service
+	db        *gorm.DB
+	mail      *stubMailer
+	requester models.User
+	admin     models.User
+	team      models.Tag
+	other     models.Tag
+
+	mu      sync.Mutex
+	created []*zoneadd.ZoneForm
+	current *models.User
+}
+
+// newFixture seeds a requester tagged "web" and an admin holding
+// admin.zone.requests. Requests are made as the requester until asAdmin.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(
+		&models.Role{}, &models.Permission{}, &models.RolePermission{},
+		&models.User{}, &models.Group{}, &models.GroupMapping{}, &models.UserGroup{},
+		&models.Tag{}, &models.UserTag{}, &models.GroupTag{}, &models.GroupZone{}, &models.ZoneTag{},
+		&models.ZoneRequest{}, &models.ActivityLog{}, &models.Tenant{}, &models.ZoneTenant{},
+	); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	f := &fixture{db: db, mail: &stubMailer{sent: make(chan string, 10)}}
+
+	userRole := models.Role{Name: "user"}
+	adminRole := models.Role{Name: "admin"}
+	review := models.Permission{Name: auth.PermAdminZoneRequests, Resource: "admin", Action: "zone.requests"}
+	db.Create(&userRole)
+	db.Create(&adminRole)
+	db.Create(&review)
+	db.Create(&models.RolePermission{RoleID: adminRole.ID, PermissionID: review.ID})
+
+	f.requester = models.User{Username: "alice", Email: "alice@example.com", RoleID: userRole.ID, Active: true}
+	f.admin = models.User{Username: "root", Email: "root@example.com", RoleID: adminRole.ID, Active: true}
+	f.team = models.Tag{Name: "web"}
+	f.other = models.Tag{Name: "other"}
+	db.Create(&f.requester)
+	db.Create(&f.admin)
+	db.Create(&f.team)
+	db.Create(&f.other)
+	db.Create(&models.UserTag{UserID: f.requester.ID, TagID: f.team.ID})
+
+	cfg := &config.Config{
+		ZoneRequest: config.ZoneRequest{Kind: "Native", SOAEditAPI: "DEFAULT", Nameservers: []string{"ns1.example.net."}},
+	}
+	cfg.Webserver.URL = "https://dns.example.com"
+
+	f.svc = &Service{
+		cfg:         cfg,
+		db:          db,
+		authService: auth.NewService(db),
+		mailer:      f.mail,
+		createZone: func(_ context.Context, form *zoneadd.ZoneForm) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+
+			for _, prev := range f.created {
+				if prev.Name == form.Name {
+					return errors.New("Conflict")
+				}
+			}
+
+			f.created = append(f.created, form)
+
+			return nil
+		},
+	}
+	f.current = &f.requester
+
+	f.app = fiber.New(fiber.Config{Views: noOpViews{}})
+	f.app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", *f.current)
+		return c.Next()
+	})
+	f.app.Post(Path, f.svc.Submit)
+	f.app.Post(PathAdmin+"/:id/approve", f.svc.Approve)
+	f.app.Post(PathAdmin+"/:id/reject", f.svc.Reject)
+
+	return f
+}
+
+func (f *fixture) post(t *testing.T, target string, form url.Values) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, target,
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp
+}
+
+func (f *fixture) submit(t *testing.T, name string) *models.ZoneRequest {
+	t.Helper()
+
+	resp := f.post(t, Path, url.Values{
+		"name":        {name},
+		"purpose":     {"Marketing site"},
+		"nameservers": {"ns1.example.org, ns2.example.org"},
+		"tag_id":      {strconv.FormatUint(uint64(f.team.ID), 10)},
+	})
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("submit status = %d, want 303", resp.StatusCode)
+	}
+
+	var req models.ZoneRequest
+	if err := f.db.Order("id DESC").First(&req).Error; err != nil {
+		t.Fatalf("request not stored: %v", err)
+	}
+
+	return &req
+}
+
+func TestSubmit_StoresRequestAndNotifiesReviewers(t *testing.T) {
+	f := newFixture(t)
+
+	req := f.submit(t, "Shop.Example.com")
+
+	if req.Name != "shop.example.com." || req.Status != models.ZoneRequestPending {
+		t.Errorf("request = %+v", req)
+	}
+
+	if got := req.NameserverList(); len(got) != 2 || got[0] != "ns1.example.org." {
+		t.Errorf("nameservers = %v", got)
+	}
+
+	if msg := f.mail.next(t); !strings.HasPrefix(msg, "root@example.com: ") {
+		t.Errorf("reviewer mail = %q", msg)
+	}
+
+	// A second request for the same zone is refused while the first is pending.
+	if resp := f.post(t, Path, url.Values{"name": {"shop.example.com"}, "purpose": {"again"}}); resp.StatusCode !=
+		http.StatusBadRequest {
+		t.Errorf("duplicate status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestSubmit_Validation(t *testing.T) {
+	f := newFixture(t)
+
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"missing purpose", url.Values{"name": {"a.example.com"}}},
+		{"invalid name", url.Values{"name": {"bad name"}, "purpose": {"x"}}},
+		{"single label", url.Values{"name": {"localhost"}, "purpose": {"x"}}},
+		{"invalid nameserver", url.Values{"name": {"a.example.com"}, "purpose": {"x"}, "nameservers": {"ns/1"}}},
+		{"foreign team", url.Values{
+			"name": {"a.example.com"}, "purpose": {"x"}, "tag_id": {strconv.FormatUint(uint64(f.other.ID), 10)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := f.post(t, Path, tt.form); resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestApprove_CreatesZoneAndGrantsTeam(t *testing.T) {
+	f := newFixture(t)
+	req := f.submit(t, "shop.example.com")
+	f.mail.next(t)
+
+	f.current = &f.admin
+
+	resp := f.post(t, PathAdmin+"/"+strconv.FormatUint(req.ID, 10)+"/approve",
+		url.Values{"tag_id": {strconv.FormatUint(uint64(f.team.ID), 10)}})
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("approve status = %d, want 303", resp.StatusCode)
+	}
+
+	if len(f.created) != 1 {
+		t.Fatalf("created %d zones, want 1", len(f.created))
+	}
+
+	form := f.created[0]
+	if form.Name != "shop.example.com." || form.Kind != zoneadd.ZoneKindNative || len(form.Nameservers) != 2 {
+		t.Errorf("zone form = %+v", form)
+	}
+
+	var zt models.ZoneTag
+	if err := f.db.Where("zone_id = ? AND tag_id = ?", "shop.example.com.", f.team.ID).First(&zt).Error; err != nil {
+		t.Errorf("team tag not assigned: %v", err)
+	}
+
+	var stored models.ZoneRequest
+	f.db.First(&stored, req.ID)
+
+	if stored.Status != models.ZoneRequestApproved || stored.ReviewerID == nil || *stored.ReviewerID != f.admin.ID {
+		t.Errorf("stored = %+v", stored)
+	}
+
+	if msg := f.mail.next(t); !strings.HasPrefix(msg, "alice@example.com: ") {
+		t.Errorf("requester mail = %q", msg)
+	}
+
+	// Approving again is refused.
+	if resp := f.post(t, PathAdmin+"/"+strconv.FormatUint(req.ID, 10)+"/approve", nil); resp.StatusCode !=
+		http.StatusConflict {
+		t.Errorf("second approve status = %d, want 409", resp.StatusCode)
+	}
+}
+
+func TestReject_RequiresReason(t *testing.T) {
+	f := newFixture(t)
+	req := f.submit(t, "shop.example.com")
+	f.mail.next(t)
+
+	f.current = &f.admin
+	target := PathAdmin + "/" + strconv.FormatUint(req.ID, 10) + "/reject"
+
+	if resp := f.post(t, target, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status without reason = %d, want 400", resp.StatusCode)
+	}
+
+	if resp := f.post(t, target, url.Values{"comment": {"Use example.org"}}); resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("reject status = %d, want 303", resp.StatusCode)
+	}
+
+	var stored models.ZoneRequest
+	f.db.First(&stored, req.ID)
+
+	if stored.Status != models.ZoneRequestRejected || stored.ReviewComment != "Use example.org" {
+		t.Errorf("stored = %+v", stored)
+	}
+
+	if len(f.created) != 0 {
+		t.Errorf("rejected request created a zone")
+	}
+
+	if msg := f.mail.next(t); !strings.Contains(msg, "rejected") {
+		t.Errorf("requester mail = %q", msg)
+	}
+}
@@ -0,0 +1,331 @@
+package zonerequest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	zoneadd "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/add"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	createTimeout = 30 * time.Second
+
+	statusAll = "all"
+)
+
+// errAlreadyReviewed is returned when a request was settled concurrently.
+var errAlreadyReviewed = errors.New("zone request was already reviewed")
+
+// List renders the review queue. The status query parameter selects pending
+// (default), approved, rejected or all requests.
+func (s *Service) List(c fiber.Ctx) error {
+	status := c.Query("status", string(models.ZoneRequestPending))
+
+	query := s.db.Preload("Requester").Preload("Tag").Order("created_at DESC")
+	if status != statusAll {
+		query = query.Where("status = ?", status)
+	}
+
+	var requests []models.ZoneRequest
+	if err := query.Find(&requests).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load zone requests")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadRequests, nil)
+	}
+
+	nav := navigation.NewContext(labelZoneRequests, "admin", "zone-requests").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb(labelZoneRequests, PathAdmin, true)
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Requests":   requests,
+		"Status":     status,
+		"Success":    c.Query("success"),
+	}, handler.BaseLayout)
+}
+
+// Review renders a single request with the approve and reject forms.
+func (s *Service) Review(c fiber.Ctx) error {
+	req, status, msg := s.loadRequest(c)
+	if req == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	return s.renderReview(c, fiber.StatusOK, req, "")
+}
+
+// Approve creates the requested zone from the configured template, assigns it
+// to the selected team tag and notifies the requester.
+func (s *Service) Approve(c fiber.Ctx) error {
+	req, status, msg := s.loadRequest(c)
+	if req == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	if req.Status != models.ZoneRequestPending {
+		return s.renderReview(c, fiber.StatusConflict, req, "This request has already been reviewed.")
+	}
+
+	// The reviewer may assign the zone to a different team than requested.
+	var tagID *uint
+
+	if raw := c.FormValue("tag_id"); raw != "" && raw != "0" {
+		var tag models.Tag
+		if err := s.db.Where("id = ?", raw).First(&tag).Error; err != nil {
+			return s.renderReview(c, fiber.StatusBadRequest, req, "Unknown team selected.")
+		}
+
+		tagID = &tag.ID
+	}
+
+	// The zone counts against the zone quota of the tenant of the requester.
+	if err := tenant.CheckZones(s.db, req.Requester.TenantID, 1); err != nil {
+		status := fiber.StatusForbidden
+		if !errors.Is(err, tenant.ErrQuota) {
+			log.Error().Err(err).Uint64("request_id", req.ID).Msg("failed to check the zone quota")
+
+			status = fiber.StatusInternalServerError
+		}
+
+		return s.renderReview(c, status, req, "Cannot create the zone: "+err.Error())
+	}
+
+	form := s.zoneForm(req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), createTimeout)
+	defer cancel()
+
+	if err := s.createZone(ctx, form); err != nil {
+		var pdnsErr *pdnsapi.Error
+		if (errors.As(err, &pdnsErr) && pdnsErr.StatusCode == fiber.StatusConflict) || err.Error() == "Conflict" {
+			return s.renderReview(c, fiber.StatusConflict, req,
+				req.Name+" already exists in PowerDNS. Reject the request or delete the existing zone first.")
+		}
+
+		log.Error().Err(err).Str("zone_name", req.Name).Msg("failed to create requested zone")
+
+		return s.renderReview(c, fiber.StatusInternalServerError, req, "Failed to create zone: "+err.Error())
+	}
+
+	reviewer, _ := c.Locals("CurrentUser").(models.User)
+	comment := strings.TrimSpace(c.FormValue("comment"))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := settle(tx, req, models.ZoneRequestApproved, &reviewer, comment); err != nil {
+			return err
+		}
+
+		// The zone belongs to the tenant of the requester.
+		if err := tenant.AssignCreated(tx, req.Name, &req.Requester); err != nil {
+			return err
+		}
+
+		req.TagID = tagID
+		if err := tx.Model(req).Update("tag_id", tagID).Error; err != nil {
+			return err
+		}
+
+		if tagID == nil {
+			return nil
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.ZoneTag{ZoneID: req.Name, TagID: *tagID}).Error
+	})
+	if err != nil {
+		// The zone exists at this point; only the bookkeeping failed.
+		log.Error().Err(err).Uint64("request_id", req.ID).Msg("failed to record zone request approval")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed",
+			"The zone was created but the request could not be updated.", nil)
+	}
+
+	reviewerID := reviewer.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &reviewerID,
+		Username:     reviewer.Username,
+		Action:       activitylog.ActionZoneCreated,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: req.Name,
+		Details: map[string]any{
+			"kind":         string(form.Kind),
+			"soa_edit_api": string(form.SOAEditAPI),
+			"request_id":   req.ID,
+			"requester":    req.Requester.Username,
+		},
+		IPAddress: c.IP(),
+	}))
+
+	s.notifyRequester(c, req)
+
+	return c.Redirect().To(PathAdmin + "?success=" + url.QueryEscape("Zone "+req.Name+" created."))
+}
+
+// Reject declines the request and notifies the requester.
+func (s *Service) Reject(c fiber.Ctx) error {
+	req, status, msg := s.loadRequest(c)
+	if req == nil {
+		return renderLoadError(c, status, msg)
+	}
+
+	comment := strings.TrimSpace(c.FormValue("comment"))
+	if comment == "" {
+		return s.renderReview(c, fiber.StatusBadRequest, req, "Tell the requester why the request is rejected.")
+	}
+
+	reviewer, _ := c.Locals("CurrentUser").(models.User)
+
+	err := settle(s.db, req, models.ZoneRequestRejected, &reviewer, comment)
+	if errors.Is(err, errAlreadyReviewed) {
+		return s.renderReview(c, fiber.StatusConflict, req, "This request has already been reviewed.")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("request_id", req.ID).Msg("failed to reject zone request")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Save Failed", errFailedSaveRequest, nil)
+	}
+
+	reviewerID := reviewer.ID
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       &reviewerID,
+		Username:     reviewer.Username,
+		Action:       activitylog.ActionZoneRequestRejected,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: req.Name,
+		Details:      map[string]any{"request_id": req.ID, "requester": req.Requester.Username, "comment": comment},
+		IPAddress:    c.IP(),
+	}))
+
+	s.notifyRequester(c, req)
+
+	return c.Redirect().To(PathAdmin + "?success=" + url.QueryEscape("Request for "+req.Name+" rejected."))
+}
+
+// settle moves a pending request to status. The update is conditional so a
+// request cannot be approved and rejected concurrently.
+func settle(db *gorm.DB, req *models.ZoneRequest, status models.ZoneRequestStatus, reviewer *models.User,
+	comment string,
+) error {
+	now := time.Now()
+
+	var reviewerID *uint64
+	if reviewer.ID != 0 {
+		id := reviewer.ID
+		reviewerID = &id
+	}
+
+	res := db.Model(&models.ZoneRequest{}).
+		Where("id = ? AND status = ?", req.ID, models.ZoneRequestPending).
+		Updates(map[string]any{
+			"status":         status,
+			"reviewer_id":    reviewerID,
+			"review_comment": comment,
+			"reviewed_at":    now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return errAlreadyReviewed
+	}
+
+	req.Status = status
+	req.ReviewerID = reviewerID
+	req.ReviewComment = comment
+	req.ReviewedAt = &now
+
+	return nil
+}
+
+// zoneForm builds the zone creation form for an approved request from the
+// [zonerequest] template.
+func (s *Service) zoneForm(req *models.ZoneRequest) *zoneadd.ZoneForm {
+	nameservers := req.NameserverList()
+	if len(nameservers) == 0 {
+		nameservers = s.cfg.ZoneRequest.Nameservers
+	}
+
+	return &zoneadd.ZoneForm{
+		ZoneType:    zoneadd.ZoneTypeForward,
+		Name:        req.Name,
+		Kind:        zoneadd.ZoneKind(s.cfg.ZoneRequest.Kind),
+		SOAEditAPI:  zoneadd.SOAEditAPI(s.cfg.ZoneRequest.SOAEditAPI),
+		Nameservers: nameservers,
+	}
+}
+
+// loadRequest loads the request of the :id parameter. When it is nil the
+// returned status and message describe the failure.
+func (s *Service) loadRequest(c fiber.Ctx) (*models.ZoneRequest, int, string) {
+	id, ok := parseID(c)
+	if !ok {
+		return nil, fiber.StatusBadRequest, errInvalidRequestID
+	}
+
+	var req models.ZoneRequest
+
+	err := s.db.Preload("Requester").Preload("Tag").Preload("Reviewer").First(&req, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, errRequestNotFound
+	}
+
+	if err != nil {
+		log.Error().Err(err).Uint64("request_id", id).Msg("failed to load zone request")
+		return nil, fiber.StatusInternalServerError, errFailedLoadRequests
+	}
+
+	return &req, fiber.StatusOK, ""
+}
+
+// renderLoadError renders the error page for a failed loadRequest.
+func renderLoadError(c fiber.Ctx, status int, msg string) error {
+	return handler.RenderError(c, status, http.StatusText(status), msg, nil)
+}
+
+func (s *Service) renderReview(c fiber.Ctx, status int, req *models.ZoneRequest, msg string) error {
+	var tags []models.Tag
+	if err := s.db.Order(handler.OrderNameASC).Find(&tags).Error; err != nil {
+		log.Error().Err(err).Msg("failed to load tags")
+	}
+
+	var selectedTagID uint
+	if req.TagID != nil {
+		selectedTagID = *req.TagID
+	}
+
+	nav := navigation.NewContext(req.Name, "admin", "zone-requests").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb("Admin", "#", false).
+		AddBreadcrumb(labelZoneRequests, PathAdmin, false).
+		AddBreadcrumb(req.Name, "", true)
+
+	return c.Status(status).Render(templateReview, fiber.Map{
+		"Navigation":    nav,
+		"Request":       req,
+		"Tags":          tags,
+		"SelectedTagID": selectedTagID,
+		"Template":      s.zoneForm(req),
+		"Nameservers":   req.NameserverList(),
+		"Error":         msg,
+	}, handler.BaseLayout)
+}
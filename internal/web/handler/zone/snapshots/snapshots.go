@@ -0,0 +1,364 @@
+// Package zonesnapshots provides the zone snapshot pages: the snapshots
+// taken of a zone before it was deleted or changed in bulk, what restoring
+// one would change, and the restore itself.
+package zonesnapshots
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonesnapshot"
+)
+
+const (
+	// Path is the path of the snapshot list; ?zone= limits it to one zone
+	// and POST takes a snapshot of that zone.
+	Path = handler.RootPath + "zone/snapshots"
+	// DetailPath shows a snapshot and what restoring it would change.
+	DetailPath = Path + "/:id"
+	// RestorePath restores the zone from a snapshot.
+	RestorePath = DetailPath + "/restore"
+
+	templateList   = "zone/snapshots"
+	templateDetail = "zone/snapshot"
+
+	labelSnapshots = "Zone Snapshots"
+
+	// requestTimeout bounds fetching and restoring the zone.
+	requestTimeout = 30 * time.Second
+
+	errFailedLoadSnapshots = "Failed to load the zone snapshots"
+	errInvalidSnapshotID   = "Invalid snapshot ID"
+	errSnapshotNotFound    = "Snapshot not found"
+)
+
+// Service handles the zone snapshot pages.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Change is an RRset a restore would replace or delete.
+type Change struct {
+	Name   string
+	Type   string
+	Delete bool
+}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+
+	app.Get(Path, auth.RequirePermission(authService, auth.PermZoneRead), s.List)
+	app.Post(Path, auth.RequirePermission(authService, auth.PermZoneUpdate), s.Take)
+	app.Get(DetailPath, auth.RequirePermission(authService, auth.PermZoneRead), s.Detail)
+	app.Post(RestorePath, auth.RequirePermission(authService, auth.PermZoneUpdate), s.Restore)
+}
+
+// List renders the snapshots of the zone parameter, or of every zone the
+// current user has access to.
+func (s *Service) List(c fiber.Ctx) error {
+	zoneName := canonicalZone(c.Query("zone"))
+
+	allowed, err := auth.ZoneFilter(c, s.authService)
+	if err != nil {
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadSnapshots, nil)
+	}
+
+	if zoneName != "" && allowed != nil && !allowed(zoneName) {
+		return handler.RenderError(c, fiber.StatusForbidden, labelSnapshots, "Access to this zone is not permitted", nil)
+	}
+
+	snapshots, err := zonesnapshot.List(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to load zone snapshots")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadSnapshots, nil)
+	}
+
+	visible := snapshots[:0]
+
+	for i := range snapshots {
+		if allowed == nil || allowed(snapshots[i].ZoneName) {
+			visible = append(visible, snapshots[i])
+		}
+	}
+
+	nav := navigation.NewContext(labelSnapshots, "zones", "snapshots").
+		AddBreadcrumb("Home", dashboard.Path, false)
+
+	if zoneName != "" {
+		nav.AddBreadcrumb(zoneName, handler.ZoneEditURL(zoneName), false)
+	}
+
+	nav.AddBreadcrumb(labelSnapshots, "", true)
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Zone":       zoneName,
+		"Snapshots":  visible,
+		"Enabled":    s.cfg.ZoneSnapshots.Enabled(),
+		"Keep":       s.cfg.ZoneSnapshots.Keep,
+		"CanEdit":    auth.HasPermissionInContext(c, s.authService, auth.PermZoneUpdate),
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// Take snapshots the zone of the zone form field, e.g. before changing it by
+// hand.
+func (s *Service) Take(c fiber.Ctx) error {
+	zoneName := canonicalZone(c.FormValue("zone"))
+	if zoneName == "" || !auth.CanAccessZone(c, s.authService, zoneName) {
+		return handler.RenderError(c, fiber.StatusForbidden, labelSnapshots, "Access to this zone is not permitted", nil)
+	}
+
+	back := Path + "?zone=" + url.QueryEscape(zoneName)
+
+	if !s.cfg.ZoneSnapshots.Enabled() {
+		return c.Redirect().To(back + "&error=" + url.QueryEscape("Zone snapshots are disabled"))
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), requestTimeout)
+	defer cancel()
+
+	userID, username := auth.Actor(c)
+
+	snapshot, err := zonesnapshot.Take(ctx, s.db, s.cfg.ZoneSnapshots, zoneName, zonesnapshot.ReasonManual, userID)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to take zone snapshot")
+		return c.Redirect().To(back + "&error=" + url.QueryEscape("Failed to take a snapshot: "+err.Error()))
+	}
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionSnapshotTaken,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details:      map[string]any{"zone_snapshot_id": snapshot.ID, "rrsets": snapshot.RRsetCount},
+		IPAddress:    c.IP(),
+	}))
+
+	return c.Redirect().To(back + "&success=" + url.QueryEscape("Snapshot taken"))
+}
+
+// Detail renders a snapshot: its RRsets and the RRsets restoring it would
+// replace or delete.
+func (s *Service) Detail(c fiber.Ctx) error {
+	snapshot, status, msg := s.loadSnapshot(c)
+	if snapshot == nil {
+		return handler.RenderError(c, status, labelSnapshots, msg, nil)
+	}
+
+	content, err := zonesnapshot.Decode(snapshot)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("snapshot_id", snapshot.ID).Msg("failed to decode zone snapshot")
+		return handler.RenderError(c, fiber.StatusInternalServerError, labelSnapshots, "The snapshot is damaged", nil)
+	}
+
+	listPath := Path + "?zone=" + url.QueryEscape(snapshot.ZoneName)
+
+	nav := navigation.NewContext(labelSnapshots, "zones", "snapshots").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(snapshot.ZoneName, handler.ZoneEditURL(snapshot.ZoneName), false).
+		AddBreadcrumb(labelSnapshots, listPath, false).
+		AddBreadcrumb("#"+strconv.FormatUint(snapshot.ID, 10), "", true)
+
+	data := fiber.Map{
+		"Navigation": nav,
+		"Snapshot":   snapshot,
+		"Content":    content,
+		"ListPath":   listPath,
+		"CanEdit":    auth.HasPermissionInContext(c, s.authService, auth.PermZoneUpdate),
+		"Error":      c.Query("error"),
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), requestTimeout)
+	defer cancel()
+
+	changes, missing, err := s.preview(ctx, snapshot.ZoneName, content)
+	if err != nil {
+		requestid.Logger(c.Context()).Warn().Err(err).Str("zone_name", snapshot.ZoneName).Msg("failed to fetch zone")
+		data["PreviewError"] = err.Error()
+	}
+
+	data["Changes"] = changes
+	data["Missing"] = missing
+
+	return c.Render(templateDetail, data, handler.BaseLayout)
+}
+
+// Restore makes the zone contain the RRsets of a snapshot again, after
+// taking a snapshot of its current content so that the restore can be
+// reverted.
+func (s *Service) Restore(c fiber.Ctx) error {
+	snapshot, status, msg := s.loadSnapshot(c)
+	if snapshot == nil {
+		return handler.RenderError(c, status, labelSnapshots, msg, nil)
+	}
+
+	zoneName := snapshot.ZoneName
+	back := Path + "/" + strconv.FormatUint(snapshot.ID, 10)
+
+	deletion, err := zonedeletion.Pending(s.db, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load zone deletion")
+		return c.Redirect().To(back + "?error=" + url.QueryEscape("Failed to load the zone deletion"))
+	}
+
+	if deletion != nil {
+		return c.Redirect().To(back + "?error=" +
+			url.QueryEscape("The zone is deleted; restore it from Deleted Zones first"))
+	}
+
+	content, err := zonesnapshot.Decode(snapshot)
+	if err != nil {
+		return c.Redirect().To(back + "?error=" + url.QueryEscape("The snapshot is damaged"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Context()), requestTimeout)
+	defer cancel()
+
+	userID, username := auth.Actor(c)
+
+	// The zone may no longer exist; then there is nothing to keep.
+	if _, err = zonesnapshot.Take(ctx, s.db, s.cfg.ZoneSnapshots, zoneName, zonesnapshot.ReasonRestore, userID); err != nil &&
+		!isNotFound(err) {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to take zone snapshot")
+		return c.Redirect().To(back + "?error=" + url.QueryEscape("Failed to snapshot the zone before the restore: "+err.Error()))
+	}
+
+	created, err := zonesnapshot.Restore(ctx, zoneName, content)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to restore zone snapshot")
+		return c.Redirect().To(back + "?error=" + url.QueryEscape("Failed to restore the snapshot: "+err.Error()))
+	}
+
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionSnapshotZoneRestored,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details: map[string]any{
+			"zone_snapshot_id": snapshot.ID,
+			"reason":           snapshot.Reason,
+			"taken_at":         snapshot.CreatedAt,
+			"created":          created,
+		},
+		IPAddress: c.IP(),
+	}))
+
+	msg = "Zone restored from the snapshot of " + snapshot.CreatedAt.UTC().Format("2006-01-02 15:04 MST")
+	if created {
+		msg = "Zone re-created from the snapshot of " + snapshot.CreatedAt.UTC().Format("2006-01-02 15:04 MST")
+	}
+
+	return c.Redirect().To(handler.ZoneEditURL(zoneName) + "?success=" + url.QueryEscape(msg))
+}
+
+// preview returns the RRsets restoring content into zoneName would replace
+// or delete, or missing when the zone no longer exists.
+func (s *Service) preview(
+	ctx context.Context, zoneName string, content *activitylog.ZoneSnapshot,
+) (changes []Change, missing bool, err error) {
+	if powerdns.Engine.Client == nil {
+		return nil, false, powerdns.ErrClientNotInitialized
+	}
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if isNotFound(err) {
+		return nil, true, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, rr := range zonesnapshot.Changes(zone.RRsets, content) {
+		changes = append(changes, Change{
+			Name:   pdnsapi.StringValue(rr.Name),
+			Type:   string(*rr.Type),
+			Delete: *rr.ChangeType == pdnsapi.ChangeTypeDelete,
+		})
+	}
+
+	return changes, false, nil
+}
+
+// loadSnapshot returns the snapshot named by the id route parameter, or nil
+// with the status and message to show. Snapshots of zones the user may not
+// access are reported as not found.
+func (s *Service) loadSnapshot(c fiber.Ctx) (*models.ZoneSnapshot, int, string) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, fiber.StatusBadRequest, errInvalidSnapshotID
+	}
+
+	snapshot, err := zonesnapshot.Get(s.db, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.StatusNotFound, errSnapshotNotFound
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("snapshot_id", id).Msg("failed to load zone snapshot")
+		return nil, fiber.StatusInternalServerError, errFailedLoadSnapshots
+	}
+
+	if !auth.CanAccessZone(c, s.authService, snapshot.ZoneName) {
+		return nil, fiber.StatusNotFound, errSnapshotNotFound
+	}
+
+	return snapshot, 0, ""
+}
+
+// isNotFound reports whether err is a 404 of the PowerDNS API.
+func isNotFound(err error) bool {
+	var apiErr *pdnsapi.Error
+
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// canonicalZone returns zone in lower case with a trailing dot, or "".
+func canonicalZone(zone string) string {
+	zone = strings.ToLower(strings.TrimSpace(zone))
+	if zone == "" || strings.HasSuffix(zone, ".") {
+		return zone
+	}
+
+	return zone + "."
+}
@@ -0,0 +1,196 @@
+package zonesnapshots
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonesnapshot"
+)
+
+// recordingViews renders the template name and keeps the data of the last
+// render.
+type recordingViews struct {
+	data fiber.Map
+}
+
+func (*recordingViews) Load() error { return nil }
+
+func (v *recordingViews) Render(w io.Writer, name string, data any, _ ...string) error {
+	v.data, _ = data.(fiber.Map)
+	_, _ = io.WriteString(w, name)
+
+	return nil
+}
+
+func newTestService(t *testing.T) (*fiber.App, *gorm.DB, *recordingViews) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.RolePermission{}, &models.User{},
+		&models.Group{}, &models.GroupMapping{}, &models.UserGroup{}, &models.APIKey{}, &models.Tag{},
+		&models.ZoneTag{}, &models.UserTag{}, &models.GroupTag{}, &models.GroupZone{}, &models.ZoneOwnership{},
+		&models.ZoneAccessOption{}, &models.Tenant{}, &models.ZoneTenant{}, &models.ZoneSnapshot{},
+		&models.ZoneDeletion{}, &models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	admin := models.Role{Name: "admin"}
+	db.Create(&admin)
+	db.Create(&models.User{ID: 1, Username: "alice", Email: "alice@example.com", RoleID: admin.ID, Active: true})
+
+	authService := auth.NewService(db)
+	svc := &Service{
+		cfg:         &config.Config{ZoneSnapshots: config.ZoneSnapshots{Keep: 5, LargePatch: 10}},
+		db:          db,
+		authService: authService,
+	}
+	views := &recordingViews{}
+
+	app := fiber.New(fiber.Config{Views: views})
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", models.User{ID: 1, Username: "alice"})
+		return c.Next()
+	})
+	app.Use(auth.Authenticate(authService))
+	app.Get(Path, svc.List)
+	app.Get(DetailPath, svc.Detail)
+	app.Post(RestorePath, svc.Restore)
+
+	return app, db, views
+}
+
+func send(t *testing.T, app *fiber.App, method, path string) *http.Response {
+	t.Helper()
+
+	return sendWithKey(t, app, method, path, "")
+}
+
+// sendWithKey sends a request authenticated by the API key token, if any.
+func sendWithKey(t *testing.T, app *fiber.App, method, path, token string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), method, path, http.NoBody)
+	if token != "" {
+		req.Header.Set(auth.APIKeyHeader, token)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp
+}
+
+func TestList(t *testing.T) {
+	app, db, views := newTestService(t)
+	cfg := config.ZoneSnapshots{Keep: 5}
+
+	for _, zone := range []string{"example.com.", "example.com.", "example.org."} {
+		if _, err := zonesnapshot.Save(db, cfg, zone, zonesnapshot.ReasonDelete, &activitylog.ZoneSnapshot{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for query, want := range map[string]int{"": 3, "?zone=example.com": 2, "?zone=example.org.": 1} {
+		if resp := send(t, app, http.MethodGet, Path+query); resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d", query, resp.StatusCode)
+		}
+
+		if got := len(views.data["Snapshots"].([]models.ZoneSnapshot)); got != want {
+			t.Errorf("GET %s: %d snapshots, want %d", query, got, want)
+		}
+	}
+}
+
+func TestRestoreRefusesDeletedZone(t *testing.T) {
+	app, db, _ := newTestService(t)
+
+	s, err := zonesnapshot.Save(db, config.ZoneSnapshots{Keep: 5}, "example.com.", zonesnapshot.ReasonDelete,
+		&activitylog.ZoneSnapshot{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = zonedeletion.Schedule(db, "example.com.", nil, time.Now().Add(time.Hour), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := send(t, app, http.MethodPost, "/zone/snapshots/1/restore")
+	if loc := resp.Header.Get("Location"); !strings.HasPrefix(loc, "/zone/snapshots/1?error=") {
+		t.Errorf("restore of snapshot %d redirect = %q, want an error", s.ID, loc)
+	}
+
+	for path, want := range map[string]int{
+		"/zone/snapshots/abc":       http.StatusBadRequest,
+		"/zone/snapshots/9":         http.StatusNotFound,
+		"/zone/snapshots/9/restore": http.StatusNotFound,
+	} {
+		method := http.MethodGet
+		if strings.HasSuffix(path, "/restore") {
+			method = http.MethodPost
+		}
+
+		if resp := send(t, app, method, path); resp.StatusCode != want {
+			t.Errorf("%s %s: status %d, want %d", method, path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestZoneScopedKey(t *testing.T) {
+	app, db, views := newTestService(t)
+	cfg := config.ZoneSnapshots{Keep: 5}
+
+	token, _, err := auth.NewService(db).CreateAPIKey(1, "k8s", auth.APIKeyScope{Zones: []string{"example.org"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, zone := range []string{"example.com.", "example.org."} {
+		if _, err = zonesnapshot.Save(db, cfg, zone, zonesnapshot.ReasonDelete, &activitylog.ZoneSnapshot{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if resp := sendWithKey(t, app, http.MethodGet, Path, token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", Path, resp.StatusCode)
+	}
+
+	if got := views.data["Snapshots"].([]models.ZoneSnapshot); len(got) != 1 || got[0].ZoneName != "example.org." {
+		t.Errorf("snapshots listed to a key for example.org. = %+v", got)
+	}
+
+	for method, path := range map[string]string{
+		http.MethodGet:  Path + "?zone=example.com.",
+		http.MethodPost: "/zone/snapshots/1/restore",
+	} {
+		if resp := sendWithKey(t, app, method, path, token); resp.StatusCode != http.StatusForbidden &&
+			resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s %s with a key for example.org.: status %d, want 403 or 404", method, path, resp.StatusCode)
+		}
+	}
+
+	if resp := sendWithKey(t, app, http.MethodGet, "/zone/snapshots/2", token); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET the snapshot of example.org.: status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,190 @@
+// Package zonetrash provides the trash page of a zone: the RRsets deleted
+// from it within the [recordtrash] retention, each of which can be restored
+// with one click.
+package zonetrash
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/recordtrash"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	zoneedit "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/edit"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the prefix of the trash page of a zone, /zone/trash/:name.
+	Path = handler.RootPath + "zone/trash"
+
+	templateList = "zone/trash"
+
+	labelTrash = "Trash"
+
+	// requestTimeout bounds fetching the zone before a restore.
+	requestTimeout = 30 * time.Second
+
+	errFailedLoadTrash = "Failed to load the trash"
+	errInvalidEntryID  = "Invalid trash entry ID"
+	errEntryNotFound   = "Deleted RRset not found; it may have expired"
+	errAccessDenied    = "Access to this zone is not permitted"
+)
+
+// Service handles the zone trash page.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+	editor      *zoneedit.Service
+	now         func() time.Time
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Entry is a deleted RRset as listed on the trash page.
+type Entry struct {
+	models.DeletedRRset
+	Contents []string
+}
+
+// Init registers routes. Restores go through the checks of the zone editor,
+// so zoneedit.Handler must be initialized as well.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+	s.editor = &zoneedit.Handler
+	s.now = time.Now
+
+	app.Get(Path+"/:name", auth.RequirePermission(authService, auth.PermZoneRead), s.List)
+	app.Post(Path+"/:name/:id/restore", auth.RequirePermission(authService, auth.PermZoneUpdate), s.Restore)
+}
+
+// List renders the unexpired deleted RRsets of the zone.
+func (s *Service) List(c fiber.Ctx) error {
+	zoneName := canonicalZone(c.Params("name"))
+	if !auth.CanAccessZone(c, s.authService, zoneName) {
+		return handler.RenderError(c, fiber.StatusForbidden, labelTrash, errAccessDenied, nil)
+	}
+
+	deleted, err := recordtrash.List(s.db, zoneName, s.now())
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to load the zone trash")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error", errFailedLoadTrash, nil)
+	}
+
+	entries := make([]Entry, 0, len(deleted))
+	for i := range deleted {
+		entries = append(entries, Entry{DeletedRRset: deleted[i], Contents: recordtrash.Records(&deleted[i])})
+	}
+
+	nav := navigation.NewContext(labelTrash, "zones", "trash").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(zoneName, handler.ZoneEditURL(zoneName), false).
+		AddBreadcrumb(labelTrash, "", true)
+
+	return c.Render(templateList, fiber.Map{
+		"Navigation": nav,
+		"Zone":       zoneName,
+		"Path":       Path + "/" + zoneName,
+		"Entries":    entries,
+		"Enabled":    s.cfg.RecordTrash.Enabled(),
+		"Retention":  zoneedit.DescribeDuration(s.cfg.RecordTrash.Retention),
+		"CanEdit":    auth.HasPermissionInContext(c, s.authService, auth.PermZoneUpdate),
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}, handler.BaseLayout)
+}
+
+// Restore puts a deleted RRset back into the zone, with its records and
+// comments, like a change saved in the zone editor. An RRset of the same
+// name and type added since is never overwritten.
+func (s *Service) Restore(c fiber.Ctx) error {
+	zoneName := canonicalZone(c.Params("name"))
+	if !auth.CanAccessZone(c, s.authService, zoneName) {
+		return handler.RenderError(c, fiber.StatusForbidden, labelTrash, errAccessDenied, nil)
+	}
+
+	back := Path + "/" + zoneName
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return handler.RenderError(c, fiber.StatusBadRequest, labelTrash, errInvalidEntryID, nil)
+	}
+
+	entry, err := recordtrash.Get(s.db, zoneName, id, s.now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return redirectError(c, back, errEntryNotFound)
+	}
+
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("entry_id", id).Msg("failed to load trash entry")
+		return redirectError(c, back, errFailedLoadTrash)
+	}
+
+	if powerdns.Engine.Client == nil {
+		return redirectError(c, back, powerdns.ErrMsgClientNotInitialized)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), requestTimeout)
+	defer cancel()
+
+	current, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return redirectError(c, back, "Failed to fetch zone: "+err.Error())
+	}
+
+	rrSet, err := recordtrash.Restore(current, entry)
+	if err != nil {
+		return redirectError(c, back, entry.Name+" IN "+entry.Type+": "+err.Error())
+	}
+
+	// The revision makes the restore fail rather than overwrite an RRset
+	// added in the meantime.
+	err = s.editor.PatchRRsets(c, zoneName, []string{zoneedit.ZoneRevision(current)}, []pdnsapi.RRset{rrSet})
+	if err != nil {
+		return redirectError(c, back, err.Error())
+	}
+
+	if err = recordtrash.Remove(s.db, entry.ID); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Uint64("entry_id", entry.ID).Msg("failed to remove restored trash entry")
+	}
+
+	return c.Redirect().To(back + "?success=" + url.QueryEscape("Restored "+entry.Name+" IN "+entry.Type))
+}
+
+func redirectError(c fiber.Ctx, back, msg string) error {
+	return c.Redirect().To(back + "?error=" + url.QueryEscape(msg))
+}
+
+// canonicalZone returns zone in lower case with a trailing dot.
+func canonicalZone(zone string) string {
+	zone = strings.ToLower(strings.TrimSpace(zone))
+	if strings.HasSuffix(zone, ".") {
+		return zone
+	}
+
+	return zone + "."
+}
@@ -0,0 +1,171 @@
+package zonetrash
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/recordtrash"
+)
+
+// recordingViews renders the template name and keeps the data of the last
+// render.
+type recordingViews struct {
+	data fiber.Map
+}
+
+func (*recordingViews) Load() error { return nil }
+
+func (v *recordingViews) Render(w io.Writer, name string, data any, _ ...string) error {
+	v.data, _ = data.(fiber.Map)
+	_, _ = io.WriteString(w, name)
+
+	return nil
+}
+
+func newTestService(t *testing.T) (*fiber.App, *gorm.DB, *recordingViews) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.RolePermission{}, &models.User{},
+		&models.Group{}, &models.GroupMapping{}, &models.UserGroup{}, &models.APIKey{}, &models.Tag{},
+		&models.ZoneTag{}, &models.UserTag{}, &models.GroupTag{}, &models.GroupZone{}, &models.ZoneOwnership{},
+		&models.ZoneAccessOption{}, &models.Tenant{}, &models.ZoneTenant{}, &models.DeletedRRset{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	admin := models.Role{Name: "admin"}
+	db.Create(&admin)
+	db.Create(&models.User{ID: 1, Username: "alice", Email: "alice@example.com", RoleID: admin.ID, Active: true})
+
+	authService := auth.NewService(db)
+	svc := &Service{
+		cfg:         &config.Config{RecordTrash: config.RecordTrash{Retention: time.Hour}},
+		db:          db,
+		authService: authService,
+		now:         time.Now,
+	}
+	views := &recordingViews{}
+
+	app := fiber.New(fiber.Config{Views: views})
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("CurrentUser", models.User{ID: 1, Username: "alice"})
+		return c.Next()
+	})
+	app.Use(auth.Authenticate(authService))
+	app.Get(Path+"/:name", svc.List)
+	app.Post(Path+"/:name/:id/restore", svc.Restore)
+
+	return app, db, views
+}
+
+func send(t *testing.T, app *fiber.App, method, path string) *http.Response {
+	t.Helper()
+
+	return sendWithKey(t, app, method, path, "")
+}
+
+// sendWithKey sends a request authenticated by the API key token, if any.
+func sendWithKey(t *testing.T, app *fiber.App, method, path, token string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(context.Background(), method, path, http.NoBody)
+	if token != "" {
+		req.Header.Set(auth.APIKeyHeader, token)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp
+}
+
+func trash(t *testing.T, db *gorm.DB, zone, name string) {
+	t.Helper()
+
+	rrType := pdnsapi.RRTypeA
+	ttl := uint32(300)
+	content := "192.0.2.2"
+	rrSet := pdnsapi.RRset{Name: &name, Type: &rrType, TTL: &ttl, Records: []pdnsapi.Record{{Content: &content}}}
+
+	err := recordtrash.Add(db, config.RecordTrash{Retention: time.Hour}, zone, []pdnsapi.RRset{rrSet}, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestList(t *testing.T) {
+	app, db, views := newTestService(t)
+
+	trash(t, db, "example.com.", "www.example.com.")
+	trash(t, db, "example.org.", "www.example.org.")
+
+	if resp := send(t, app, http.MethodGet, Path+"/example.com"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET: status %d", resp.StatusCode)
+	}
+
+	entries := views.data["Entries"].([]Entry)
+	if len(entries) != 1 || entries[0].Name != "www.example.com." || len(entries[0].Contents) != 1 {
+		t.Errorf("entries = %+v, want the www.example.com. A RRset", entries)
+	}
+
+	if got := views.data["Retention"]; got != "1 hour" {
+		t.Errorf("retention = %v, want 1 hour", got)
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	app, db, _ := newTestService(t)
+
+	trash(t, db, "example.org.", "www.example.org.")
+
+	if resp := send(t, app, http.MethodPost, Path+"/example.com./abc/restore"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("restore of an invalid ID: status %d, want 400", resp.StatusCode)
+	}
+
+	// Entries of other zones are not found.
+	resp := send(t, app, http.MethodPost, Path+"/example.com./1/restore")
+	if loc := resp.Header.Get("Location"); !strings.HasPrefix(loc, Path+"/example.com.?error=") {
+		t.Errorf("restore of another zone's entry redirect = %q, want an error", loc)
+	}
+}
+
+func TestZoneScopedKey(t *testing.T) {
+	app, db, _ := newTestService(t)
+
+	trash(t, db, "example.com.", "www.example.com.")
+
+	token, _, err := auth.NewService(db).CreateAPIKey(1, "k8s", auth.APIKeyScope{Zones: []string{"example.org"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := sendWithKey(t, app, http.MethodGet, Path+"/example.com.", token); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("GET the trash of example.com. with a key for example.org.: status %d, want 403", resp.StatusCode)
+	}
+
+	resp := sendWithKey(t, app, http.MethodPost, Path+"/example.com./1/restore", token)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("restore in example.com. with a key for example.org.: status %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,424 @@
+// Package zonevariants provides the split-horizon page of a zone: its
+// variant on the primary PowerDNS server and on a view side by side, with
+// actions to copy or edit an RRset on either side.
+package zonevariants
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/splithorizon"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	zoneedit "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/edit"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path of the variants page; the zone name follows.
+	Path = handler.RootPath + "zone/variants"
+
+	templateReport = "zone/variants"
+
+	labelReport = "Views"
+
+	// requestTimeout bounds fetching and changing the variants of the zone.
+	requestTimeout = 30 * time.Second
+
+	// maxTTL is the largest TTL accepted, one week.
+	maxTTL = 604800
+)
+
+// Service handles the variants page.
+type Service struct {
+	handler.Service
+	db          *gorm.DB
+	authService *auth.Service
+	editor      *zoneedit.Service
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// copyForm is a submitted copy of an RRset to the other side.
+type copyForm struct {
+	View string `form:"view"`
+	Name string `form:"rrname"`
+	Type string `form:"type"`
+	// To is the side receiving the RRset.
+	To string `form:"to"`
+}
+
+// saveForm is a submitted RRset of one side; no records delete it.
+type saveForm struct {
+	View    string `form:"view"`
+	Side    string `form:"side"`
+	Name    string `form:"rrname"`
+	Type    string `form:"type"`
+	TTL     uint32 `form:"ttl"`
+	Records string `form:"records"`
+}
+
+// Init registers routes. Changes of the primary zone go through the checks
+// of the zone editor, so zoneedit.Handler must be initialized as well.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.db = db
+	s.authService = authService
+	s.editor = &zoneedit.Handler
+
+	app.Get(Path+"/:name", auth.RequirePermission(authService, auth.PermZoneRead), s.Report)
+	app.Post(Path+"/:name/copy", auth.RequirePermission(authService, auth.PermZoneUpdate), s.Copy)
+	app.Post(Path+"/:name/save", auth.RequirePermission(authService, auth.PermZoneUpdate), s.Save)
+}
+
+// Report shows the variants of the zone on the primary server and the view
+// of the view parameter, by default the first one.
+func (s *Service) Report(c fiber.Ctx) error {
+	zoneName, err := s.zoneName(c)
+	if err != nil {
+		return err
+	}
+
+	var views []models.PDNSView
+	if err = s.db.Order(handler.OrderNameASC).Find(&views).Error; err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Msg("failed to load PowerDNS views")
+		return handler.RenderError(c, fiber.StatusInternalServerError, "Database Error",
+			"Failed to load the PowerDNS views", nil)
+	}
+
+	editPath := handler.ZoneEditURL(zoneName)
+
+	nav := navigation.NewContext(labelReport, "zones", "variants").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(zoneName, editPath, false).
+		AddBreadcrumb(labelReport, "", true)
+
+	data := fiber.Map{
+		"Navigation": nav,
+		"Zone":       zoneName,
+		"EditPath":   editPath,
+		"Views":      views,
+		"CanEdit":    auth.HasPermissionInContext(c, s.authService, auth.PermZoneUpdate),
+		"CanManage":  auth.HasPermissionInContext(c, s.authService, auth.PermAdminPDNSServer),
+		"Success":    c.Query("success"),
+		"Error":      c.Query("error"),
+	}
+
+	if len(views) == 0 {
+		return c.Render(templateReport, data, handler.BaseLayout)
+	}
+
+	view := &views[0]
+
+	for i := range views {
+		if views[i].Name == c.Query("view") {
+			view = &views[i]
+		}
+	}
+
+	data["View"] = view
+
+	ctx, cancel := context.WithTimeout(c.Context(), requestTimeout)
+	defer cancel()
+
+	primary, err := s.primaryZone(ctx, zoneName)
+	if err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Msg("failed to fetch zone")
+
+		msg := "Failed to fetch zone: " + err.Error()
+		if powerdns.IsServerUnreachable(err) {
+			msg = powerdns.ErrMsgServerUnreachable
+		}
+
+		return handler.RenderError(c, fiber.StatusInternalServerError,
+			"PowerDNS Unreachable", msg, handler.PDNSServerSettingsAction)
+	}
+
+	variant, err := splithorizon.Client(view).Zones.Get(ctx, zoneName)
+
+	switch {
+	case isNotFound(err):
+		data["ViewMissing"] = true
+	case err != nil:
+		requestid.Logger(c.Context()).Warn().Err(err).Str("zone_name", zoneName).Str("view", view.Name).
+			Msg("failed to fetch zone from view")
+
+		data["ViewError"] = err.Error()
+
+		return c.Render(templateReport, data, handler.BaseLayout)
+	}
+
+	rows := splithorizon.Diff(primary, variant)
+
+	data["Rows"] = rows
+	data["Summary"] = splithorizon.Summarize(rows)
+
+	return c.Render(templateReport, data, handler.BaseLayout)
+}
+
+// Copy sets an RRset of one side to that of the other side, deleting it when
+// the other side does not have it.
+func (s *Service) Copy(c fiber.Ctx) error {
+	zoneName, err := s.zoneName(c)
+	if err != nil {
+		return err
+	}
+
+	var in copyForm
+	if err = c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid form data")
+	}
+
+	view, err := s.loadView(in.View)
+	if err != nil {
+		return err
+	}
+
+	to := splithorizon.Side(in.To)
+	if to != splithorizon.SidePrimary && to != splithorizon.SideView {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid side")
+	}
+
+	back := reportPath(zoneName, view)
+
+	ctx, cancel := context.WithTimeout(c.Context(), requestTimeout)
+	defer cancel()
+
+	primary, err := s.primaryZone(ctx, zoneName)
+	if err != nil {
+		return redirectError(c, back, "Failed to fetch zone: "+err.Error())
+	}
+
+	variant, err := splithorizon.Client(view).Zones.Get(ctx, zoneName)
+	if err != nil {
+		return redirectError(c, back, "Failed to fetch zone from view "+view.Name+": "+err.Error())
+	}
+
+	row := splithorizon.Find(splithorizon.Diff(primary, variant), strings.ToLower(in.Name), in.Type)
+	if row == nil {
+		return redirectError(c, back, "RRset "+in.Name+" "+in.Type+" not found")
+	}
+
+	if err = s.apply(c, zoneName, view, to, row.Name, row.Type, row.Side(to.Other())); err != nil {
+		return redirectError(c, back, err.Error())
+	}
+
+	return redirectSuccess(c, back, "Copied "+row.Name+" "+row.Type+" to "+sideLabel(to, view)+".")
+}
+
+// Save sets an RRset of one side to the submitted records, deleting it when
+// there are none.
+func (s *Service) Save(c fiber.Ctx) error {
+	zoneName, err := s.zoneName(c)
+	if err != nil {
+		return err
+	}
+
+	var in saveForm
+	if err = c.Bind().Body(&in); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid form data")
+	}
+
+	view, err := s.loadView(in.View)
+	if err != nil {
+		return err
+	}
+
+	back := reportPath(zoneName, view)
+
+	side := splithorizon.Side(in.Side)
+	if side != splithorizon.SidePrimary && side != splithorizon.SideView {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid side")
+	}
+
+	name, ok := recordName(in.Name, zoneName)
+	if !ok {
+		return redirectError(c, back, "Name "+in.Name+" is not in zone "+zoneName)
+	}
+
+	rrType := strings.ToUpper(strings.TrimSpace(in.Type))
+	if rrType == "" || !splithorizon.Comparable(rrType) {
+		return redirectError(c, back, "Record type "+rrType+" cannot be edited here")
+	}
+
+	var rrSet *splithorizon.RRset
+
+	if records := splithorizon.ParseRecords(in.Records); len(records) > 0 {
+		if in.TTL == 0 || in.TTL > maxTTL {
+			return redirectError(c, back, "TTL must be between 1 and 604800 seconds")
+		}
+
+		rrSet = &splithorizon.RRset{TTL: in.TTL, Records: records}
+	}
+
+	if err = s.apply(c, zoneName, view, side, name, rrType, rrSet); err != nil {
+		return redirectError(c, back, err.Error())
+	}
+
+	return redirectSuccess(c, back, "Saved "+name+" "+rrType+" on "+sideLabel(side, view)+".")
+}
+
+// apply sets the RRset of name and rrType on side to rrSet, or deletes it when
+// rrSet is nil. Changes of the primary zone go through the zone editor;
+// those of the view get its checks and are recorded in the activity log.
+func (s *Service) apply(c fiber.Ctx, zoneName string, view *models.PDNSView, side splithorizon.Side,
+	name, rrType string, rrSet *splithorizon.RRset,
+) error {
+	patch := []pdnsapi.RRset{splithorizon.Patch(name, rrType, rrSet)}
+
+	if side == splithorizon.SidePrimary {
+		return s.editor.PatchRRsets(c, zoneName, nil, patch)
+	}
+
+	if s.editor.NeedsApproval(c) {
+		return errors.New("your record changes need approval; views cannot be changed")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), requestTimeout)
+	defer cancel()
+
+	client := splithorizon.Client(view)
+
+	current, err := client.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return errors.New("failed to fetch zone from view " + view.Name + ": " + err.Error())
+	}
+
+	if err = s.editor.ValidateRRsets(c, zoneName, current, patch); err != nil {
+		return err
+	}
+
+	var before *splithorizon.RRset
+	if row := splithorizon.Find(splithorizon.Diff(nil, current), name, rrType); row != nil {
+		before = row.View
+	}
+
+	if err = client.Records.Patch(ctx, zoneName, &pdnsapi.RRsets{Sets: patch}); err != nil {
+		requestid.Logger(c.Context()).Error().Err(err).Str("zone_name", zoneName).Str("view", view.Name).
+			Msg("failed to update records of view")
+
+		return errors.New("failed to update records of view " + view.Name + ": " + err.Error())
+	}
+
+	userID, username := auth.Actor(c)
+	activitylog.Record(auth.Attribute(c, &activitylog.Entry{
+		DB:           s.db,
+		UserID:       userID,
+		Username:     username,
+		Action:       activitylog.ActionViewRecordChanged,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: zoneName,
+		Details: activitylog.ViewRecordsDiff{
+			View: view.Name,
+			RecordsDiff: activitylog.RecordsDiff{
+				Records: []activitylog.RecordEntryDiff{splithorizon.Change(name, rrType, before, rrSet)},
+			},
+		},
+	}))
+
+	return nil
+}
+
+// primaryZone fetches zoneName from the primary PowerDNS server.
+func (s *Service) primaryZone(ctx context.Context, zoneName string) (*pdnsapi.Zone, error) {
+	if powerdns.Engine.Client == nil {
+		return nil, powerdns.ErrClientNotInitialized
+	}
+
+	return powerdns.Engine.Zones.Get(ctx, zoneName)
+}
+
+// loadView returns the view named name.
+func (s *Service) loadView(name string) (*models.PDNSView, error) {
+	var view models.PDNSView
+
+	err := s.db.Where("name = ?", name).First(&view).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "PowerDNS view not found")
+	}
+
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to load PowerDNS view")
+	}
+
+	return &view, nil
+}
+
+// zoneName returns the canonical zone name of the :name parameter, or an
+// error when the current user may not access the zone.
+func (s *Service) zoneName(c fiber.Ctx) (string, error) {
+	zoneName := c.Params("name")
+	if !strings.HasSuffix(zoneName, ".") {
+		zoneName += "."
+	}
+
+	if !auth.CanAccessZone(c, s.authService, zoneName) {
+		return "", fiber.NewError(fiber.StatusForbidden, "Access to this zone is not permitted")
+	}
+
+	return zoneName, nil
+}
+
+// recordName returns name as a canonical name in zoneName: "@" is the apex
+// and names without trailing dot are relative to it. ok is false for names
+// outside of the zone.
+func recordName(name, zoneName string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	switch {
+	case name == "" || name == "@":
+		return zoneName, true
+	case !strings.HasSuffix(name, "."):
+		return name + "." + zoneName, true
+	default:
+		return name, name == zoneName || strings.HasSuffix(name, "."+zoneName)
+	}
+}
+
+// isNotFound reports whether err is a 404 of the PowerDNS API.
+func isNotFound(err error) bool {
+	var apiErr *pdnsapi.Error
+
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// sideLabel names side for messages.
+func sideLabel(side splithorizon.Side, view *models.PDNSView) string {
+	if side == splithorizon.SidePrimary {
+		return "the primary server"
+	}
+
+	return "view " + view.Name
+}
+
+func reportPath(zoneName string, view *models.PDNSView) string {
+	return Path + "/" + zoneName + "?view=" + url.QueryEscape(view.Name)
+}
+
+func redirectError(c fiber.Ctx, path, msg string) error {
+	return c.Redirect().To(path + "&error=" + url.QueryEscape(msg))
+}
+
+func redirectSuccess(c fiber.Ctx, path, msg string) error {
+	return c.Redirect().To(path + "&success=" + url.QueryEscape(msg))
+}
@@ -0,0 +1,174 @@
+// Package zoneverify provides the resolution check of a zone, which queries
+// the configured resolvers for the records of the zone and compares their
+// answers with PowerDNS.
+package zoneverify
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsverify"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/dashboard"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// Path is the path of the resolution check; the zone name follows.
+	Path = handler.RootPath + "zone/verify"
+
+	templateVerify = "zone/verify"
+
+	labelVerify = "Verify Resolution"
+
+	// verifyTimeout bounds fetching the zone and all queries.
+	verifyTimeout = 2 * time.Minute
+)
+
+// Summary counts the RRsets of a check.
+type Summary struct {
+	Checked  int
+	OK       int
+	Problems int
+	Skipped  int
+}
+
+// Service handles the resolution check.
+type Service struct {
+	handler.Service
+	cfg         *config.Config
+	db          *gorm.DB
+	authService *auth.Service
+}
+
+// Handler is the exported instance.
+var Handler = Service{}
+
+// Init registers routes.
+func (s *Service) Init(app *fiber.App, cfg *config.Config, db *gorm.DB, authService *auth.Service) {
+	if app == nil || cfg == nil || db == nil {
+		log.Fatal().Msg(handler.ErrNilACDFatalLogMsg)
+		return
+	}
+
+	s.cfg = cfg
+	s.db = db
+	s.authService = authService
+
+	app.Get(Path+"/:name", auth.RequirePermission(authService, auth.PermZoneRead), s.Verify)
+}
+
+// Verify queries the selected resolvers, all configured ones by default, for
+// the records of the zone and renders the answers per RRset. With
+// ?problems=1 only RRsets that did not match everywhere are shown.
+func (s *Service) Verify(c fiber.Ctx) error {
+	zoneName := c.Params("name")
+	if !strings.HasSuffix(zoneName, ".") {
+		zoneName += "."
+	}
+
+	if !s.canAccessZone(c, zoneName) {
+		return fiber.NewError(fiber.StatusForbidden, "Access to this zone is not permitted")
+	}
+
+	if powerdns.Engine.Client == nil {
+		return handler.RenderError(c, fiber.StatusInternalServerError,
+			"PowerDNS Unreachable", powerdns.ErrMsgClientNotInitialized, handler.PDNSServerSettingsAction)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), verifyTimeout)
+	defer cancel()
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		log.Error().Err(err).Str("zone_name", zoneName).Msg("failed to fetch zone for resolution check")
+
+		msg := "Failed to fetch zone: " + err.Error()
+		if powerdns.IsServerUnreachable(err) {
+			msg = powerdns.ErrMsgServerUnreachable
+		}
+
+		return handler.RenderError(c, fiber.StatusInternalServerError,
+			"PowerDNS Unreachable", msg, handler.PDNSServerSettingsAction)
+	}
+
+	resolvers := s.selectedResolvers(c)
+
+	report := dnsverify.New(resolvers, s.cfg.DNSCheck.Timeout).Verify(ctx, zone)
+	summary := summarize(report)
+
+	onlyProblems := fiber.Query[bool](c, "problems")
+	if onlyProblems {
+		report.Results = slices.DeleteFunc(report.Results, func(r dnsverify.Result) bool {
+			return r.OK()
+		})
+	}
+
+	editPath := handler.ZoneEditURL(zoneName)
+
+	nav := navigation.NewContext(labelVerify, "zones", "verify").
+		AddBreadcrumb("Home", dashboard.Path, false).
+		AddBreadcrumb(zoneName, editPath, false).
+		AddBreadcrumb(labelVerify, "", true)
+
+	return c.Render(templateVerify, fiber.Map{
+		"Navigation":   nav,
+		"Zone":         zoneName,
+		"EditPath":     editPath,
+		"Resolvers":    s.cfg.DNSCheck.Resolvers,
+		"Selected":     resolvers,
+		"Results":      report.Results,
+		"Summary":      summary,
+		"OnlyProblems": onlyProblems,
+		"MaxRRsets":    dnsverify.MaxRRsets,
+	}, handler.BaseLayout)
+}
+
+// selectedResolvers returns the configured resolvers picked with ?resolver=,
+// or all of them when none is picked. Other resolvers are ignored, so users
+// cannot send queries to arbitrary servers.
+func (s *Service) selectedResolvers(c fiber.Ctx) []string {
+	var selected []string
+
+	for _, raw := range c.Request().URI().QueryArgs().PeekMulti("resolver") {
+		if resolver := string(raw); slices.Contains(s.cfg.DNSCheck.Resolvers, resolver) &&
+			!slices.Contains(selected, resolver) {
+			selected = append(selected, resolver)
+		}
+	}
+
+	if len(selected) == 0 {
+		return s.cfg.DNSCheck.Resolvers
+	}
+
+	return selected
+}
+
+// summarize counts the RRsets of report by outcome.
+func summarize(report dnsverify.Report) Summary {
+	summary := Summary{Checked: len(report.Results), Skipped: report.Skipped}
+
+	for i := range report.Results {
+		if report.Results[i].OK() {
+			summary.OK++
+		} else {
+			summary.Problems++
+		}
+	}
+
+	return summary
+}
+
+// canAccessZone reports whether the current user may see zoneName.
+func (s *Service) canAccessZone(c fiber.Ctx, zoneName string) bool {
+	return auth.CanAccessZone(c, s.authService, zoneName)
+}
@@ -0,0 +1,23 @@
+package zoneverify
+
+import (
+	"testing"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsverify"
+)
+
+func TestSummarize(t *testing.T) {
+	report := dnsverify.Report{
+		Results: []dnsverify.Result{
+			{Name: "a.example.", Answers: []dnsverify.Answer{{Status: dnsverify.StatusMatch}}},
+			{Name: "b.example.", Answers: []dnsverify.Answer{{Status: dnsverify.StatusMatch}, {Status: dnsverify.StatusDiffers}}},
+			{Name: "c.example.", Answers: []dnsverify.Answer{{Status: dnsverify.StatusError}}},
+		},
+		Skipped: 2,
+	}
+
+	want := Summary{Checked: 3, OK: 1, Problems: 2, Skipped: 2}
+	if got := summarize(report); got != want {
+		t.Errorf("summarize() = %+v, want %+v", got, want)
+	}
+}
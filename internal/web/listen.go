@@ -0,0 +1,155 @@
+package web
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+// unixSocketMode is the file mode of Unix sockets: the owner and the group,
+// e.g. that of the reverse proxy, may connect.
+const unixSocketMode = 0o770
+
+// listen opens listeners and merges them into one net.Listener, so that
+// Fiber prepares its routes once and shuts all of them down together.
+// tlsConfig serves the listeners with TLS set.
+func listen(listeners []config.Listener, tlsConfig *tls.Config) (net.Listener, error) {
+	opened := make([]net.Listener, 0, len(listeners))
+
+	for _, l := range listeners {
+		ln, err := open(l.Address)
+		if err != nil {
+			for _, o := range opened {
+				_ = o.Close()
+			}
+
+			return nil, err
+		}
+
+		if l.TLS {
+			ln = tls.NewListener(ln, tlsConfig)
+		}
+
+		log.Info().Str("addr", l.Address).Bool("tls", l.TLS).Msg("web service listening")
+
+		opened = append(opened, ln)
+	}
+
+	if len(opened) == 1 {
+		return opened[0], nil
+	}
+
+	return newMultiListener(opened), nil
+}
+
+// open listens on a TCP address or, with the unix: prefix, on a Unix socket.
+// A socket file left behind by an earlier run is replaced.
+func open(address string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, config.UnixSocketPrefix)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Chmod(path, unixSocketMode); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+// multiListener accepts the connections of several listeners.
+type multiListener struct {
+	listeners []net.Listener
+	conns     chan net.Conn
+	errs      chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newMultiListener(listeners []net.Listener) *multiListener {
+	m := &multiListener{
+		listeners: listeners,
+		conns:     make(chan net.Conn),
+		errs:      make(chan error),
+		done:      make(chan struct{}),
+	}
+
+	for _, ln := range listeners {
+		go m.serve(ln)
+	}
+
+	return m
+}
+
+// serve forwards the connections and errors of ln to Accept until the
+// listener is closed.
+func (m *multiListener) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case m.errs <- err:
+				// The server stops on errors that are not temporary.
+				continue
+			case <-m.done:
+				return
+			}
+		}
+
+		select {
+		case m.conns <- conn:
+		case <-m.done:
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
+// Accept returns the next connection of any of the listeners.
+func (m *multiListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-m.conns:
+		return conn, nil
+	case err := <-m.errs:
+		return nil, err
+	case <-m.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes all listeners.
+func (m *multiListener) Close() error {
+	var errs []error
+
+	m.closeOnce.Do(func() {
+		close(m.done)
+
+		for _, ln := range m.listeners {
+			errs = append(errs, ln.Close())
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// Addr returns the address of the first listener.
+func (m *multiListener) Addr() net.Addr {
+	return m.listeners[0].Addr()
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+func TestListen_TCPAndUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "web.sock")
+
+	// A socket file left behind by an earlier run is replaced.
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := listen([]config.Listener{
+		{Address: "127.0.0.1:0"},
+		{Address: config.UnixSocketPrefix + socket},
+	}, nil)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+
+	tcpAddr := ln.(*multiListener).listeners[0].Addr().String()
+
+	for _, target := range [][2]string{{"tcp", tcpAddr}, {"unix", socket}} {
+		conn, err := net.Dial(target[0], target[1])
+		if err != nil {
+			t.Fatalf("dial %s: %v", target[1], err)
+		}
+
+		accepted, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("Accept() for %s error = %v", target[1], err)
+		}
+
+		if got := accepted.LocalAddr().Network(); got != target[0] {
+			t.Errorf("accepted %s connection, want %s", got, target[0])
+		}
+
+		_ = accepted.Close()
+		_ = conn.Close()
+	}
+
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != unixSocketMode {
+		t.Errorf("socket mode = %v, %v; want %v", info.Mode().Perm(), err, os.FileMode(unixSocketMode))
+	}
+
+	if err = ln.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	if _, err = ln.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept() after Close() error = %v, want net.ErrClosed", err)
+	}
+}
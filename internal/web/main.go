@@ -2,37 +2,81 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"html/template"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/helmet"
 	"github.com/gofiber/fiber/v3/middleware/static"
 	"github.com/gofiber/template/html/v3"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/acme"
 	"golang.org/x/crypto/acme/autocert"
 	"gorm.io/gorm"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/acmedns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/avatar"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/cache"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/chatnotify"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	appsettingsctrl "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/appsettings"
+	authsettingsctrl "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/authsettings"
 	brandingctrl "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/branding"
+	maintenancectrl "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/maintenance"
+	setupctrl "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/delegation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/dnsupdate"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/expiry"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/inactiveusers"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/integrations/dhcplease"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/logger"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/recordschedule"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/recordtrash"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/servermonitor"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/snapshot"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/updatecheck"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/version"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/format"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/activity"
+	backuphandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/backup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/dhcpimport"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/group"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/groupmapping"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/integration"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/logins"
+	maintenancehandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/maintenance"
+	nssethandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/nsset"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/registration"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/role"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/server/configuration"
+	appsettingshandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/app"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/authproviders"
 	brandinghandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/branding"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/namepolicy"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/pdnsserver"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/pdnsviews"
 	ttlsettings "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/ttl"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/settings/zone"
+	snapshothandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/snapshot"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/system"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/tag"
+	tenanthandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/tenant"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/tsigkey"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/user"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/admin/zonetag"
 	oidchandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/auth/oidc"
@@ -40,14 +84,42 @@ import (
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/health"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/login"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/logout"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/metrics"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/navapi"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/pdnscompat"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/profile"
+	profileapikeys "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/profile/apikeys"
+	profilesecurity "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/profile/security"
 	profiletotp "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/profile/totp"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/search"
+	setuphandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/setup"
+	querytool "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/tools/query"
 	totphandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/totp"
 	zoneadd "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/add"
+	zoneclaim "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/claim"
+	zonedelegation "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/delegation"
+	zonedeleted "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/deleted"
 	zoneedit "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/edit"
+	zonehealth "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/health"
+	zonerequest "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/request"
+	zonesnapshots "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/snapshots"
+	zonetrash "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/trash"
+	zonevariants "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/variants"
+	zoneverify "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/zone/verify"
 	accesslogmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/accesslog"
 	authmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/auth"
+	maintenancemiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/maintenance"
 	pdnsmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/pdns"
+	ratelimitmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/ratelimit"
+	readonlymiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/readonly"
+	requestidmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/requestid"
+	securityheadersmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/securityheaders"
+	setupmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/setup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonedeletion"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonestats"
 )
 
 // Service represents the web service.
@@ -58,56 +130,25 @@ type Service struct {
 	alive        atomic.Bool
 	db           *gorm.DB
 	authService  *auth.Service
+	audit        *logger.AuditWriter
 }
 
-// Start starts the web service on the given address.
-func (s *Service) Start(addr string) error {
-	var doneFiber = make(chan bool)
+// Start starts the web service on the given listeners.
+func (s *Service) Start(listeners []config.Listener) error {
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
 
-	go func() {
-		listenCfg := fiber.ListenConfig{}
-
-		switch {
-		case s.cfg.Webserver.ACMEEnabled:
-			m := &autocert.Manager{
-				Prompt:     autocert.AcceptTOS,
-				HostPolicy: autocert.HostWhitelist(s.cfg.Webserver.ACMEDomain),
-				Cache:      autocert.DirCache(s.cfg.Webserver.ACMECacheDir),
-				Email:      s.cfg.Webserver.ACMEEmail,
-			}
+	ln, err := listen(listeners, tlsConfig)
+	if err != nil {
+		return err
+	}
 
-			// Start HTTP-01 challenge listener on port 80.
-			go func() {
-				challengeAddr := listenHost(addr) + ":80"
-				log.Info().Str("addr", challengeAddr).Msg("ACME HTTP-01 challenge listener starting")
-
-				srv := &http.Server{ //nolint:gosec // port 80 is intentional for ACME HTTP-01 challenges
-					Addr:    challengeAddr,
-					Handler: m.HTTPHandler(nil),
-				}
-				if err := srv.ListenAndServe(); err != nil {
-					log.Error().Err(err).Msg("ACME HTTP-01 challenge listener stopped")
-				}
-			}()
-
-			log.Info().
-				Str("domain", s.cfg.Webserver.ACMEDomain).
-				Str("cache", s.cfg.Webserver.ACMECacheDir).
-				Msg("ACME/Let's Encrypt enabled")
-
-			listenCfg.AutoCertManager = m
-
-		case s.cfg.Webserver.TLSEnabled():
-			log.Info().
-				Str("cert", s.cfg.Webserver.TLSCertFile).
-				Str("key", s.cfg.Webserver.TLSKeyFile).
-				Msg("TLS enabled")
-
-			listenCfg.CertFile = s.cfg.Webserver.TLSCertFile
-			listenCfg.CertKeyFile = s.cfg.Webserver.TLSKeyFile
-		}
+	var doneFiber = make(chan bool)
 
-		if err := s.App.Listen(addr, listenCfg); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	go func() {
+		if err := s.App.Listener(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatal().Msgf("fiber listen error: %v", err)
 		}
 
@@ -119,15 +160,78 @@ func (s *Service) Start(addr string) error {
 	return nil
 }
 
-// listenHost extracts the host part from an addr string like ":8443" or "0.0.0.0:8443".
-func listenHost(addr string) string {
-	for i := len(addr) - 1; i >= 0; i-- {
-		if addr[i] == ':' {
-			return addr[:i]
+// tlsConfig returns the TLS settings of the HTTPS listeners, or nil when
+// neither a certificate nor ACME is configured.
+func (s *Service) tlsConfig() (*tls.Config, error) {
+	switch {
+	case s.cfg.Webserver.ACMEEnabled && s.cfg.Webserver.ACMEChallenge == config.ACMEChallengeDNS01:
+		// The certificate is issued in the background; handshakes fail
+		// until the first one is available.
+		m := acmedns.New(&s.cfg.Webserver, acmedns.PowerDNSSolver{})
+		go m.Run(context.Background())
+
+		log.Info().
+			Str("domain", s.cfg.Webserver.ACMEDomain).
+			Str("cache", s.cfg.Webserver.ACMECacheDir).
+			Msg("ACME/Let's Encrypt enabled with DNS-01 challenge")
+
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: m.GetCertificate,
+		}, nil
+
+	case s.cfg.Webserver.ACMEEnabled:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(s.cfg.Webserver.ACMEDomain),
+			Cache:      autocert.DirCache(s.cfg.Webserver.ACMECacheDir),
+			Email:      s.cfg.Webserver.ACMEEmail,
+			Client:     &acme.Client{DirectoryURL: s.cfg.Webserver.ACMEDirectoryURL},
+		}
+
+		// Start HTTP-01 challenge listener on port 80.
+		go func() {
+			challengeAddr := net.JoinHostPort(s.cfg.Webserver.BindAddress, "80")
+			log.Info().Str("addr", challengeAddr).Msg("ACME HTTP-01 challenge listener starting")
+
+			srv := &http.Server{ //nolint:gosec // port 80 is intentional for ACME HTTP-01 challenges
+				Addr:    challengeAddr,
+				Handler: m.HTTPHandler(nil),
+			}
+			if err := srv.ListenAndServe(); err != nil {
+				log.Error().Err(err).Msg("ACME HTTP-01 challenge listener stopped")
+			}
+		}()
+
+		log.Info().
+			Str("domain", s.cfg.Webserver.ACMEDomain).
+			Str("cache", s.cfg.Webserver.ACMECacheDir).
+			Msg("ACME/Let's Encrypt enabled")
+
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: m.GetCertificate,
+			NextProtos:     []string{"http/1.1", acme.ALPNProto},
+		}, nil
+
+	case s.cfg.Webserver.TLSEnabled():
+		log.Info().
+			Str("cert", s.cfg.Webserver.TLSCertFile).
+			Str("key", s.cfg.Webserver.TLSKeyFile).
+			Msg("TLS enabled")
+
+		cert, err := tls.LoadX509KeyPair(s.cfg.Webserver.TLSCertFile, s.cfg.Webserver.TLSKeyFile)
+		if err != nil {
+			return nil, err
 		}
+
+		return &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}, nil
 	}
 
-	return addr
+	return nil, nil //nolint:nilnil // no TLS configured
 }
 
 // WaitShutdown waits for graceful shutdown of tweety.
@@ -165,19 +269,17 @@ func (s *Service) WaitShutdown() {
 	}()
 
 	<-serverShutdown
-	log.Info().Msg("http server was stopped ... good bye...")
-}
 
-// New creates a new web service with the given configuration.
-func New(cfg *config.Config, db *gorm.DB) *Service {
-	if cfg == nil {
-		panic("config cannot be nil")
+	if s.audit != nil {
+		s.audit.Close()
 	}
 
-	if db == nil {
-		panic("db cannot be nil")
-	}
+	log.Info().Msg("http server was stopped ... good bye...")
+}
 
+// newTemplateEngine returns the template engine with the helper functions of
+// the templates. In dev mode it reads the templates from the local filesystem.
+func newTemplateEngine(cfg *config.Config, avatars avatar.Provider) *html.Engine {
 	httpFS := http.FS(templateEmbedFS{embeddedTemplates})
 	templateEngine := html.NewFileSystem(httpFS, ".gohtml")
 
@@ -204,6 +306,42 @@ func New(cfg *config.Config, db *gorm.DB) *Service {
 	templateEngine.AddFunc("sub", func(a, b int) int {
 		return a - b
 	})
+	templateEngine.AddFunc("zoneURL", handler.ZoneEditURL)
+	templateEngine.AddFunc("mainMenu", navigation.Menu)
+	templateEngine.AddFunc("recordURL", handler.RecordURL)
+
+	// locale-aware formatting; templates pass .CurrentUser.Locale
+	templateEngine.AddFunc("formatNumber", format.Number)
+	templateEngine.AddFunc("formatPercent", format.Percent)
+	templateEngine.AddFunc("formatDate", format.Date)
+	templateEngine.AddFunc("formatDateTime", format.DateTime)
+	templateEngine.AddFunc("timeAgo", func(t time.Time) string {
+		return format.RelativeTime(t, time.Now())
+	})
+
+	templateEngine.AddFunc("avatarURL", func(name, email string) template.URL {
+		// Provider URLs are built server-side (https or a generated SVG data: URI);
+		// mark them safe so html/template does not filter the data: scheme.
+		return template.URL(avatars.URL(name, email)) //nolint:gosec // URL is generated, not user input
+	})
+
+	return templateEngine
+}
+
+// New creates a new web service with the given configuration.
+func New(cfg *config.Config, db *gorm.DB) *Service {
+	if cfg == nil {
+		panic("config cannot be nil")
+	}
+
+	if db == nil {
+		panic("db cannot be nil")
+	}
+
+	// avatars: initials are rendered inline; external providers (Gravatar) need
+	// their origin allowed in img-src.
+	avatars := avatar.New(cfg.Avatar)
+	templateEngine := newTemplateEngine(cfg, avatars)
 
 	// create fiber app
 	rp := cfg.Webserver.ReverseProxy
@@ -221,10 +359,18 @@ func New(cfg *config.Config, db *gorm.DB) *Service {
 			PassLocalsToViews: true,
 			ProxyHeader:       rp.ProxyHeader,
 			TrustProxy:        rp.Enabled,
-			TrustProxyConfig:  fiber.TrustProxyConfig{Proxies: rp.TrustedIPs},
+			TrustProxyConfig:  fiber.TrustProxyConfig{Proxies: rp.TrustedIPs, UnixSocket: true},
+			// JSON envelope for API requests, error page for everything else
+			ErrorHandler: handler.ErrorHandler,
 		},
 	)
 
+	// remember which permission guards which route (shown in the role editor)
+	auth.RegisterRouteHooks(app)
+
+	// request ID (or the client's X-Request-ID) on every response and log line
+	app.Use(requestidmiddleware.New())
+
 	// serve embedded static files
 	staticFS, err := fs.Sub(embeddedStaticFiles, "static")
 	if err != nil {
@@ -241,26 +387,71 @@ func New(cfg *config.Config, db *gorm.DB) *Service {
 	// access log
 	app.Use(accesslogmiddleware.New())
 
-	// security headers
-	app.Use(helmet.New(helmet.Config{
-		// COEP is disabled — SharedArrayBuffer is not used, and frame-ancestors 'none'
-		// in the CSP already prevents cross-origin embedding attacks.
-		CrossOriginEmbedderPolicy: "unsafe-none",
-		XFrameOptions:             "DENY",
-		ReferrerPolicy:            "strict-origin-when-cross-origin",
-		// Alpine.js v3 evaluates x-data/x-on expressions via new Function(),
-		// requiring 'unsafe-eval'. Inline <style> in maincss.gohtml requires
-		// 'unsafe-inline' for styles.
-		ContentSecurityPolicy: "default-src 'self'; " +
-			"script-src 'self' 'unsafe-eval'; " +
-			"style-src 'self' 'unsafe-inline'; " +
-			"img-src 'self' data:; " +
-			"font-src 'self' data:; " +
-			"connect-src 'self'; " +
-			"frame-ancestors 'none'; " +
-			"base-uri 'self'; " +
-			"form-action 'self'",
-	}))
+	// security headers; [webserver.securityheaders] disabled leaves them to a
+	// reverse proxy
+	if !cfg.Webserver.SecurityHeaders.Disabled {
+		app.Use(securityheadersmiddleware.New(&cfg.Webserver.SecurityHeaders, avatars.Origin()))
+	}
+
+	// Cache settings and permission sets; writes to the underlying tables
+	// invalidate them, on other replicas too when the relay is enabled.
+	if !cfg.Cache.Disabled {
+		cache.SetDefault(cache.NewMemory(), cfg.Cache.TTL)
+	}
+
+	if err := cache.RegisterInvalidation(db, eventbus.Default); err != nil {
+		log.Error().Err(err).Msg("failed to register cache invalidation")
+	}
+
+	zoneindex.Default.Follow(eventbus.Default)
+
+	if cfg.Cache.SyncInterval > 0 {
+		go eventbus.Default.Relay(context.Background(), db, cfg.Cache.SyncInterval)
+	}
+
+	// Runtime settings: the TOML values with the overrides saved under
+	// Settings → Application on top. Config file changes are picked up by the
+	// watcher; only these settings apply without a restart.
+	appSettings, err := appsettingsctrl.NewStore(db, cfg.RuntimeSettings())
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load application settings; using configured values")
+	}
+
+	applyLogging := func(rs config.RuntimeSettings) {
+		if err := logger.SetLevel(rs.LogLevel); err != nil {
+			log.Error().Err(err).Msg("failed to apply log level")
+		}
+
+		logger.SetSampling(rs.LogSampling)
+	}
+
+	applyLogging(appSettings.Settings())
+	appSettings.OnChange(applyLogging)
+	appSettings.Follow(eventbus.Default)
+	appsettingsctrl.SetDefault(appSettings)
+	session.SetLifetime(func() session.Lifetime {
+		rs := appSettings.Settings()
+
+		return session.Lifetime{Absolute: rs.SessionExpiry, Idle: rs.SessionIdleTimeout}
+	})
+
+	// LDAP and OIDC providers: the TOML sections, each replaced by the one
+	// saved under Settings → Authentication. The login handlers rebuild their
+	// providers when these change.
+	authSettings, err := authsettingsctrl.NewStore(db, authsettingsctrl.FromConfig(cfg))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load authentication settings; using configured providers")
+	}
+
+	authSettings.Follow(eventbus.Default)
+	authsettingsctrl.SetDefault(authSettings)
+
+	if !cfg.ConfigWatch.Disabled && cfg.Path != "" {
+		go config.Watch(context.Background(), cfg.Path, cfg.ConfigWatch.Interval, func(c config.Config) {
+			appSettings.SetBase(c.RuntimeSettings())
+			authSettings.SetBase(authsettingsctrl.FromConfig(&c))
+		})
+	}
 
 	// expose version and branding to all templates via PassLocalsToViews.
 	// The branding store resolves DB overrides (set via the admin GUI) on top of
@@ -270,29 +461,173 @@ func New(cfg *config.Config, db *gorm.DB) *Service {
 		log.Error().Err(err).Msg("failed to load branding settings; using configured defaults")
 	}
 
+	// Maintenance mode, toggled under Administration → Maintenance Mode and
+	// shared by all replicas through the settings table.
+	maintenanceStore, err := maintenancectrl.NewStore(db)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load maintenance mode state; assuming off")
+	}
+
+	maintenanceStore.Follow(eventbus.Default)
+	maintenancectrl.SetDefault(maintenanceStore)
+
+	// First-run setup wizard: pending on a fresh installation until the
+	// administrator account, PowerDNS and the login methods are configured.
+	setupStore, err := setupctrl.NewStore(db)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load setup state")
+	}
+
+	setupStore.Follow(eventbus.Default)
+	logSetupToken(cfg, setupStore)
+
 	// Periodically check GitHub for newer releases; the footer shows a hint to
 	// admins when one is available. Fails soft and is a no-op when disabled or
 	// on a dev build.
 	updateChecker := updatecheck.New(cfg.Update, version.Version())
 	go updateChecker.Run(context.Background())
 
+	// Re-run the inactive user check at [inactiveusers] interval, mailing a
+	// report and optionally deactivating the users. No-op without an interval.
+	go inactiveusers.NewRunner(cfg, db).Run(context.Background())
+
+	// Keep the zone index behind the dashboard and zone pickers current.
+	zoneindex.Default.Configure(cfg.ZoneIndex.Interval)
+	go zoneindex.Default.Run(context.Background())
+
+	// Count the records and check the health of changed zones for the
+	// dashboard statistics, at the zone index interval.
+	zonestats.Default.Configure(cfg.ZoneIndex.Interval)
+	go zonestats.Default.Run(context.Background())
+
+	// Enable and disable records at the times scheduled in the zone editor.
+	go recordschedule.NewRunner(db).Run(context.Background())
+
+	// Remind the owners of zones and records that expire within [expiry]
+	// notifybefore. No-op without [mail].
+	go expiry.NewRunner(cfg, db).Run(context.Background())
+
+	// Purge soft-deleted zones once their grace period has passed.
+	go zonedeletion.NewRunner(db).Run(context.Background())
+
+	// Purge deleted RRsets from the zone trash once [recordtrash] retention
+	// has passed.
+	go recordtrash.NewRunner(db).Run(context.Background())
+
+	// Import the leases of the DHCP servers configured under Admin → DHCP
+	// Imports into A, AAAA and PTR records.
+	go dhcplease.NewRunner(db).Run(context.Background())
+
+	// Check the nameservers of delegated subdomains when their NS records
+	// change, and again every [dnscheck] delegation_interval.
+	delegationRunner := delegation.NewRunner(db, &cfg.DNSCheck)
+	delegationRunner.Follow(eventbus.Default)
+
+	go delegationRunner.Run(context.Background())
+
+	// Check every [monitor] interval that the API of PowerDNS and of each view
+	// answers, for the header, the availability page and the alerts.
+	servermonitor.Default.Configure(db, cfg.Monitor)
+	go servermonitor.Default.Run(context.Background())
+
+	// Accept RFC 2136 dynamic updates signed with the TSIG keys of the admin,
+	// e.g. from DHCP servers. Disabled without a listen address.
+	if cfg.DNSUpdate.Listen != "" {
+		go func() {
+			if err := dnsupdate.New(&cfg.DNSUpdate, db).Run(context.Background()); err != nil {
+				log.Error().Err(err).Msg("dynamic update listener stopped")
+			}
+		}()
+	}
+
+	// Snapshot all zones and the application data to the [snapshots] storage.
+	// Without a storage the admin page explains how to configure one.
+	var snapshotStore snapshot.Store
+
+	if cfg.Snapshots.Enabled() {
+		var err error
+
+		if snapshotStore, err = snapshot.NewStore(&cfg.Snapshots); err != nil {
+			log.Error().Err(err).Msg("failed to open snapshot storage")
+		}
+	}
+
+	go snapshot.NewRunner(&cfg.Snapshots, db, snapshotStore).Run(context.Background())
+
+	// Record the monthly usage of the tenants every [usage] interval and post
+	// the summaries of past months to the [usage] webhook.
+	go tenant.NewRunner(&cfg.Usage, db).Run(context.Background())
+
+	// Post zone and record changes and failing health checks to the chat
+	// channels configured under Admin -> Integrations.
+	chatNotifier := chatnotify.New(db, func(zone string) string {
+		if cfg.Webserver.URL == "" {
+			return ""
+		}
+
+		return strings.TrimRight(cfg.Webserver.URL, "/") + handler.ZoneEditURL(zone)
+	})
+	chatNotifier.Follow(eventbus.Default)
+	zonestats.Default.PublishTo(eventbus.Default)
+	servermonitor.Default.PublishTo(eventbus.Default)
+
+	// Stream the activity log to the SIEM file or syslog endpoint of
+	// [log.audit].
+	var auditWriter *logger.AuditWriter
+
+	if cfg.Log.Audit.Enabled() {
+		var err error
+
+		if auditWriter, err = logger.NewAuditWriter(&cfg.Log.Audit, version.Get()); err != nil {
+			log.Error().Err(err).Msg("failed to open the audit stream")
+		} else {
+			activitylog.Stream(eventbus.Default, db, auditWriter)
+		}
+	}
+
 	app.Use(func(c fiber.Ctx) error {
 		c.Locals("AppVersion", version.Get())
 		c.Locals("Brand", brandingStore.Brand())
 		c.Locals("Update", updateChecker.Info())
+		c.Locals("PowerDNSUnavailable", powerdns.Unavailable())
+		c.Locals("PowerDNSDown", servermonitor.Default.Down())
+		c.Locals("PowerDNSReadOnly", powerdns.ReadOnly())
+		c.Locals("PowerDNSReadOnlyForced", powerdns.ReadOnlyForced())
 
 		return c.Next()
 	})
 
-	// basic auth middleware
-	app.Use(authmiddleware.Middleware)
-
 	// Initialize auth service
 	authService := auth.NewService(db)
+	authService.SetGroupSyncPolicy(cfg.Auth.GroupSync.Policy)
+
+	// Send everything to the setup wizard until it has been completed.
+	app.Use(setupmiddleware.New(setupStore))
+
+	// Resolve the principal from the session cookie, an API key or an OIDC
+	// bearer token, then send unauthenticated requests to the login page.
+	app.Use(auth.Authenticate(authService))
+
+	// Throttle clients per API key, user or address.
+	if cfg.RateLimit.Enabled {
+		app.Use(ratelimitmiddleware.New(cfg.RateLimit))
+	}
+
+	// Hold the API keys of each tenant to the API request quota of the tenant.
+	app.Use(ratelimitmiddleware.Tenants(db))
+
+	app.Use(authmiddleware.Middleware)
 
 	// Add permissions to fiber.Locals middleware (after auth)
 	app.Use(auth.AddPermissionsToLocals(authService))
 
+	// Answer users without admin.maintenance with 503 while maintenance mode
+	// is on.
+	app.Use(maintenancemiddleware.New(maintenanceStore, authService))
+
+	// Reject changes to zones and records while PowerDNS is read-only.
+	app.Use(readonlymiddleware.New(powerdns.ReadOnly))
+
 	// Redirect to PowerDNS settings when the client is not yet configured.
 	// Must be registered before route handlers so it intercepts their paths.
 	app.Use("/dashboard", pdnsmiddleware.RequireClient)
@@ -305,32 +640,69 @@ func New(cfg *config.Config, db *gorm.DB) *Service {
 		App:         app,
 		db:          db,
 		authService: authService,
+		audit:       auditWriter,
 	}
 
 	service.alive.Store(true)
 	health.New(db, &service.alive).Register(app)
+	metrics.New(cfg.Metrics).Register(app)
 
 	// init handlers (they register their own routes with permission checks)
 	login.Handler.Init(app, cfg, db)
 	logout.Handler.Init(app, cfg, db)
 	oidchandler.Handler.Init(app, cfg, db)
+
+	authService.SetOIDCProvider(oidchandler.Handler.BearerProvider)
+
 	dashboard.Handler.Init(app, cfg, db, authService)
 	pdnsserver.Handler.Init(app, cfg, db, authService)
+	pdnsviews.Handler.Init(app, cfg, db, authService)
 	brandinghandler.Handler.Init(app, cfg, db, authService, brandingStore)
+	appsettingshandler.Handler.Init(app, cfg, db, authService, appSettings)
+	authproviders.Handler.Init(app, cfg, db, authService, authSettings)
+	maintenancehandler.Handler.Init(app, cfg, db, authService, maintenanceStore)
+	backuphandler.Handler.Init(app, cfg, db, authService)
+	snapshothandler.Handler.Init(app, cfg, db, authService, snapshotStore)
+	integration.Handler.Init(app, cfg, db, authService, chatNotifier)
+	tsigkey.Handler.Init(app, cfg, db, authService)
+	dhcpimport.Handler.Init(app, cfg, db, authService)
+	nssethandler.Handler.Init(app, cfg, db, authService)
+	tenanthandler.Handler.Init(app, cfg, db, authService)
+	setuphandler.Handler.Init(app, cfg, db, authService, setupStore, appSettings)
 	ttlsettings.Handler.Init(app, cfg, db, authService)
+	namepolicy.Handler.Init(app, cfg, db, authService)
 	zone.Handler.Init(app, cfg, db, authService)
 	zoneadd.Handler.Init(app, cfg, db, authService)
 	zoneedit.Handler.Init(app, cfg, db, authService)
+	zonerequest.Handler.Init(app, cfg, db, authService)
+	zoneclaim.Handler.Init(app, cfg, db, authService)
+	zonedeleted.Handler.Init(app, cfg, db, authService)
+	zonesnapshots.Handler.Init(app, cfg, db, authService)
+	zonetrash.Handler.Init(app, cfg, db, authService)
+	zonehealth.Handler.Init(app, cfg, db, authService)
+	zoneverify.Handler.Init(app, cfg, db, authService)
+	zonedelegation.Handler.Init(app, cfg, db, authService, delegationRunner)
+	zonevariants.Handler.Init(app, cfg, db, authService)
+	querytool.Handler.Init(app, cfg, db, authService)
+	search.Handler.Init(app, cfg, db, authService)
+	navapi.Handler.Init(app, cfg, db, authService)
 	configuration.Handler.Init(app, cfg, db, authService)
+	system.Handler.Init(app, cfg, db, authService)
 	group.Handler.Init(app, cfg, db, authService)
+	groupmapping.Handler.Init(app, cfg, db, authService)
 	role.Handler.Init(app, cfg, db, authService)
 	user.Handler.Init(app, cfg, db, authService)
+	registration.Handler.Init(app, cfg, db, authService)
 	activity.Handler.Init(app, cfg, db, authService)
+	logins.Handler.Init(app, cfg, db, authService)
 	profile.Handler.Init(app, cfg, db, authService)
 	totphandler.Handler.Init(app, cfg, db)
 	profiletotp.Handler.Init(app, cfg, db, authService)
+	profileapikeys.Handler.Init(app, cfg, db, authService)
+	profilesecurity.Handler.Init(app, cfg, db, authService)
 	tag.Handler.Init(app, cfg, db, authService)
 	zonetag.Handler.Init(app, cfg, db, authService)
+	pdnscompat.Handler.Init(app, cfg, db, authService)
 
 	// redirect root to dashboard
 	app.Get("/", func(c fiber.Ctx) error {
@@ -339,3 +711,24 @@ func New(cfg *config.Config, db *gorm.DB) *Service {
 
 	return service
 }
+
+// logSetupToken writes the setup token to the log while no administrator
+// account exists, so that only someone with access to the log can create it.
+func logSetupToken(cfg *config.Config, store *setupctrl.Store) {
+	if !store.Pending() {
+		return
+	}
+
+	if exists, err := store.HasUsers(); err != nil || exists {
+		return
+	}
+
+	token, err := store.Token()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to create the setup token")
+		return
+	}
+
+	log.Warn().Str("url", strings.TrimRight(cfg.Webserver.URL, "/")+setuphandler.Path).Str("setup_token", token).
+		Msg("setup required: open the setup page and enter the setup token to create the administrator account")
+}
@@ -1,12 +1,13 @@
 // Package accesslog provides a Fiber middleware that logs each HTTP request
-// using zerolog at Info level.
+// using zerolog at Info level, tagged with the request ID.
 package accesslog
 
 import (
 	"time"
 
 	"github.com/gofiber/fiber/v3"
-	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
 )
 
 // New returns a Fiber middleware that logs method, path, status, latency,
@@ -18,7 +19,7 @@ func New() fiber.Handler {
 		err := c.Next()
 
 		if c.Path() != "/health" {
-			log.Info().
+			requestid.Logger(c.Context()).Info().
 				Str("method", c.Method()).
 				Str("path", c.Path()).
 				Int("status", c.Response().StatusCode()).
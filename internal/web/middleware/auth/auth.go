@@ -5,18 +5,18 @@ import (
 
 	"github.com/gofiber/fiber/v3"
 
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
 	oidchandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/auth/oidc"
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/login"
-	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/session"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/profile"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/setup"
 )
 
-// Middleware is a Fiber middleware that checks for user authentication.
+// Middleware is a Fiber middleware that checks for user authentication. It
+// relies on auth.Authenticate having resolved the principal of the request.
 func Middleware(c fiber.Ctx) error {
-	var (
-		isLoginPage   = IsLoginPage(c)
-		isLogoutPage  = IsLogoutPage(c)
-		sessDataValid bool
-	)
+	isLoginPage := IsLoginPage(c)
 
 	originalURL := strings.ToLower(c.OriginalURL())
 	if strings.HasPrefix(originalURL, "/static") ||
@@ -25,45 +25,36 @@ func Middleware(c fiber.Ctx) error {
 		return c.Next()
 	}
 
-	// Allow logout and OIDC flow pages without authentication
-	if isLogoutPage || isOIDCPage(c) {
+	// Allow logout and OIDC flow pages without authentication, and the first
+	// setup step, which creates the account to log in with.
+	if IsLogoutPage(c) || isOIDCPage(c) || c.Path() == setup.Path {
 		return c.Next()
 	}
 
-	// get session cookie
-	loginCookie := c.Cookies("session")
-
-	// if no session cookie, redirect to login page
-	if loginCookie == "" && !isLoginPage {
-		return c.Redirect().To(login.Path)
-	}
-
-	// check session validity
-	sessData := new(session.Data)
-	if err := sessData.Read(loginCookie); err != nil {
+	p := auth.PrincipalFrom(c)
+	if p == nil {
 		// If we're already on the login page, don't redirect (would cause loop)
 		if isLoginPage {
 			return c.Next()
 		}
 
-		return c.Redirect().To(login.Path)
+		return unauthenticated(c)
 	}
 
-	// valid data in session
-	if sessData.User.ID > 0 {
-		sessDataValid = true
-		// Add the current user to locals for template access
-		c.Locals("CurrentUser", sessData.User)
+	// API keys and bearer tokens carry no second factor to wait for and
+	// never need the login page.
+	if p.Method != auth.MethodSession {
+		return c.Next()
 	}
 
-	if sessDataValid && isLoginPage {
+	if isLoginPage {
 		return c.Redirect().To("/dashboard")
 	}
 
 	// If TOTP is pending, restrict to TOTP-related pages only
-	if sessData.TOTPPending {
+	if p.TOTPPending {
 		if !isTOTPAllowedPage(c) {
-			if sessData.User.TOTPEnabled {
+			if p.User.TOTPEnabled {
 				return c.Redirect().To("/auth/totp/verify")
 			}
 
@@ -71,9 +62,30 @@ func Middleware(c fiber.Ctx) error {
 		}
 	}
 
+	// A password that must be changed restricts the session to the password
+	// change page.
+	if p.PasswordChange && !isPasswordChangeAllowedPage(c) {
+		if handler.IsAPIRequest(c) {
+			return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden,
+				"Password change required", nil)
+		}
+
+		return c.Redirect().To(profile.PasswordPath)
+	}
+
 	return c.Next()
 }
 
+// unauthenticated answers API requests with 401 and sends everything else to
+// the login page.
+func unauthenticated(c fiber.Ctx) error {
+	if handler.IsAPIRequest(c) {
+		return fiber.ErrUnauthorized
+	}
+
+	return c.Redirect().To(login.Path)
+}
+
 // isTOTPAllowedPage returns true if the request path is accessible during a pending TOTP challenge.
 func isTOTPAllowedPage(c fiber.Ctx) bool {
 	url := strings.ToLower(c.OriginalURL())
@@ -85,10 +97,24 @@ func isTOTPAllowedPage(c fiber.Ctx) bool {
 		strings.HasPrefix(url, "/branding")
 }
 
-// IsLoginPage checks if the current request is for the login page.
+// isPasswordChangeAllowedPage returns true if the request path is accessible
+// while the password must be changed.
+func isPasswordChangeAllowedPage(c fiber.Ctx) bool {
+	url := strings.ToLower(c.OriginalURL())
+
+	return strings.HasPrefix(url, profile.PasswordPath) ||
+		strings.HasPrefix(url, "/logout") ||
+		strings.HasPrefix(url, "/static") ||
+		strings.HasPrefix(url, "/branding")
+}
+
+// IsLoginPage checks if the current request is for the login page or the
+// registration page next to it.
 func IsLoginPage(c fiber.Ctx) bool {
 	originalURL := strings.ToLower(c.OriginalURL())
-	return strings.HasPrefix(originalURL, login.Path)
+
+	return strings.HasPrefix(originalURL, login.Path) ||
+		strings.HasPrefix(originalURL, login.PathRegister)
 }
 
 // IsLogoutPage checks if the current request is for the logout page.
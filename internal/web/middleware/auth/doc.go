@@ -1,19 +1,20 @@
 // Package auth provides authentication middleware for the web application.
 //
-// The middleware handles session validation, user authentication checks,
-// and automatic redirection for unauthenticated requests. It also adds
-// the current user to the request context for use in handlers and templates.
+// The middleware decides what an unauthenticated request may see, based on
+// the principal auth.Authenticate resolved from the session cookie, an API
+// key or an OIDC bearer token, and redirects unauthenticated requests.
 //
 // The middleware performs the following tasks:
-//   - Validates session cookies and redirects to login if invalid
-//   - Adds current user information to fiber.Locals for template access
+//   - Redirects requests without a principal to login (401 for API requests)
+//   - Holds sessions with a pending second factor on the TOTP pages
 //   - Allows public access to login and logout pages
 //   - Prevents redirect loops on authentication pages
 //
 // Usage:
 //
+//	app.Use(auth.Authenticate(authService))
 //	app.Use(authmiddleware.Middleware)
 //
-// The middleware expects sessions to be managed by the session package
-// and will redirect unauthenticated users to the login handler path.
+// The middleware expects auth.Authenticate to run first and will redirect
+// unauthenticated users to the login handler path.
 package auth
@@ -0,0 +1,78 @@
+// Package maintenance provides the middleware that enforces maintenance mode:
+// while it is enabled, requests of users without the admin.maintenance
+// permission are answered with 503 Service Unavailable.
+package maintenance
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/maintenance"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	authmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/navigation"
+)
+
+const (
+	// TemplateName is the name of the maintenance page template.
+	TemplateName = "errors/maintenance"
+
+	// RetryAfter is the Retry-After header value, in seconds, of the
+	// maintenance responses.
+	RetryAfter = 300
+)
+
+// New returns the maintenance mode middleware. It must run after
+// auth.AddPermissionsToLocals so the principal of the request is known.
+//
+// While maintenance mode is on the state is exposed to templates as the
+// Maintenance local. Static assets, the health endpoint and the sign-in pages
+// (login, logout, OIDC and TOTP) stay reachable so an administrator can still
+// sign in.
+func New(store *controller.Store, authService *auth.Service) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		state := store.State()
+		if !state.Enabled {
+			return c.Next()
+		}
+
+		c.Locals("Maintenance", state)
+
+		if exempt(c) || auth.HasPermissionInContext(c, authService, auth.PermAdminMaintenance) {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(RetryAfter))
+
+		if handler.IsAPIRequest(c) {
+			return handler.JSONError(c, fiber.StatusServiceUnavailable, handler.CodeUnavailable,
+				state.DisplayMessage(), nil)
+		}
+
+		nav := navigation.NewContext("Maintenance", "", "").
+			AddBreadcrumb("Home", handler.DashboardPath, false).
+			AddBreadcrumb("Maintenance", "", true)
+
+		return c.Status(fiber.StatusServiceUnavailable).Render(TemplateName, fiber.Map{
+			"Navigation": nav,
+			"State":      state,
+		}, handler.BaseLayout)
+	}
+}
+
+// exempt reports whether the request is served during maintenance regardless
+// of the user.
+func exempt(c fiber.Ctx) bool {
+	originalURL := strings.ToLower(c.OriginalURL())
+	if strings.HasPrefix(originalURL, "/static") ||
+		strings.HasPrefix(originalURL, "/branding") ||
+		strings.HasPrefix(originalURL, "/health") ||
+		strings.HasPrefix(originalURL, "/auth/") {
+		return true
+	}
+
+	return authmiddleware.IsLoginPage(c) || authmiddleware.IsLogoutPage(c)
+}
@@ -0,0 +1,77 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/maintenance"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+func TestNew(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Setting{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	store, err := controller.NewStore(db)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(New(store, auth.NewService(db)))
+
+	ok := func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/api/v1/zones", ok)
+	app.Get("/health", ok)
+	app.Get("/login", ok)
+	app.Get("/auth/totp/verify", ok)
+
+	get := func(path string) *http.Response {
+		t.Helper()
+
+		resp, err := app.Test(httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, http.NoBody))
+		if err != nil {
+			t.Fatalf("app.Test(%s) error = %v", path, err)
+		}
+
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		return resp
+	}
+
+	if resp := get("/api/v1/zones"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("maintenance off: status = %d, want 200", resp.StatusCode)
+	}
+
+	if err = store.Set(&controller.State{Enabled: true}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	resp := get("/api/v1/zones")
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("maintenance on: status = %d, want 503", resp.StatusCode)
+	}
+
+	if got := resp.Header.Get(fiber.HeaderRetryAfter); got != "300" {
+		t.Errorf("Retry-After = %q, want 300", got)
+	}
+
+	for _, path := range []string{"/health", "/login", "/auth/totp/verify"} {
+		if resp := get(path); resp.StatusCode != http.StatusOK {
+			t.Errorf("maintenance on: %s status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package ratelimit provides a Fiber middleware that throttles clients with
+// the token buckets of package ratelimit, to protect PowerDNS and the database
+// from runaway scripts.
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/ratelimit"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/tenant"
+)
+
+const (
+	// globalRoute is the route label of requests rejected by the global limit.
+	globalRoute = "global"
+	// tenantRoute is the route label of requests rejected by the API request
+	// quota of a tenant.
+	tenantRoute = "tenant"
+)
+
+var rejected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_rate_limited_requests_total",
+	Help: "Number of requests rejected by the rate limiter, by route limit.",
+}, []string{"route"})
+
+// route is a limit on the requests to paths starting with prefix.
+type route struct {
+	prefix  string
+	limiter *ratelimit.Limiter
+}
+
+// limits are the global limit and the route limits of the middleware.
+type limits struct {
+	global *ratelimit.Limiter
+	routes []route
+}
+
+// New returns the rate limiting middleware for cfg. Register it after
+// auth.Authenticate, so that clients are told apart by API key and user
+// rather than by address. Static files and the health check are not limited.
+func New(cfg config.RateLimit) fiber.Handler {
+	l := &limits{global: ratelimit.New(cfg.Rate, cfg.Burst), routes: make([]route, len(cfg.Routes))}
+	for i, r := range cfg.Routes {
+		l.routes[i] = route{prefix: r.Path, limiter: ratelimit.New(r.Rate, r.Burst)}
+	}
+
+	return func(c fiber.Ctx) error {
+		path := c.Path()
+		if exempt(path) {
+			return c.Next()
+		}
+
+		key := clientKey(c)
+
+		if limit, wait, ok := l.allow(path, key, time.Now()); !ok {
+			return reject(c, limit, key, wait)
+		}
+
+		return c.Next()
+	}
+}
+
+// Tenants returns the middleware enforcing the API request quotas of tenants
+// (see tenant.API). It limits the requests of users of a tenant authenticated
+// by an API key or a bearer token, not those of browser sessions. Register it
+// after auth.Authenticate; it applies whether or not [ratelimit] is enabled.
+func Tenants(db *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		if p == nil || p.User.TenantID == nil || p.Method == auth.MethodSession || exempt(c.Path()) {
+			return c.Next()
+		}
+
+		t, err := tenant.Quota(db, *p.User.TenantID)
+		if err != nil {
+			requestid.Logger(c.Context()).Error().Err(err).Uint("tenant_id", *p.User.TenantID).
+				Msg("failed to load the quotas of a tenant")
+
+			return c.Next()
+		}
+
+		if ok, wait := tenant.API.Allow(t.ID, t.MaxAPIRequests, time.Now()); !ok {
+			return reject(c, tenantRoute, clientKey(c), wait)
+		}
+
+		return c.Next()
+	}
+}
+
+// allow takes a token for a request of key to path at t. The first matching
+// route limit applies on top of the global one; a request either limit
+// rejects takes no token from the other. When the request is rejected allow
+// returns the label of the limit and how long the client has to wait.
+func (l *limits) allow(path, key string, t time.Time) (string, time.Duration, bool) {
+	var matched *route
+
+	for i := range l.routes {
+		if strings.HasPrefix(path, l.routes[i].prefix) {
+			matched = &l.routes[i]
+			break
+		}
+	}
+
+	if matched != nil {
+		if ok, wait := matched.limiter.Allow(key, t); !ok {
+			return matched.prefix, wait, false
+		}
+	}
+
+	if ok, wait := l.global.Allow(key, t); !ok {
+		if matched != nil {
+			matched.limiter.Refund(key)
+		}
+
+		return globalRoute, wait, false
+	}
+
+	return "", 0, true
+}
+
+// exempt reports whether requests to path are never limited.
+func exempt(path string) bool {
+	return strings.HasPrefix(path, "/static") ||
+		strings.HasPrefix(path, "/branding") ||
+		strings.HasPrefix(path, "/health")
+}
+
+// clientKey returns the bucket key of the request: its API key, its user or,
+// for anonymous requests, the client address.
+func clientKey(c fiber.Ctx) string {
+	p := auth.PrincipalFrom(c)
+	if p == nil {
+		return "ip:" + c.IP()
+	}
+
+	if p.APIKeyID != nil {
+		return "key:" + strconv.FormatUint(*p.APIKeyID, 10)
+	}
+
+	return "user:" + strconv.FormatUint(p.User.ID, 10)
+}
+
+// reject answers 429 with a Retry-After header of wait, rounded up to
+// whole seconds.
+func reject(c fiber.Ctx, routeLabel, key string, wait time.Duration) error {
+	retryAfter := max(1, int(math.Ceil(wait.Seconds())))
+
+	rejected.WithLabelValues(routeLabel).Inc()
+	requestid.Logger(c.Context()).Warn().
+		Str("client", key).
+		Str("path", c.Path()).
+		Str("limit", routeLabel).
+		Int("retry_after", retryAfter).
+		Msg("rate limit exceeded")
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+
+	return fiber.NewError(fiber.StatusTooManyRequests,
+		"Too many requests, retry in "+strconv.Itoa(retryAfter)+"s")
+}
@@ -0,0 +1,182 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/ratelimit"
+)
+
+// testGet returns a function that sends a GET request for path to app.
+func testGet(t *testing.T, app *fiber.App) func(path string) *http.Response {
+	t.Helper()
+
+	return func(path string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequestWithContext(context.Background(), fiber.MethodGet, path, http.NoBody)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+
+		_ = resp.Body.Close()
+
+		return resp
+	}
+}
+
+func TestNew(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(config.RateLimit{
+		Rate:   0.001,
+		Burst:  3,
+		Routes: []config.RateLimitRoute{{Path: "/api/", Rate: 0.001, Burst: 1}},
+	}))
+
+	ok := func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/api/zones", ok)
+	app.Get("/zones", ok)
+	app.Get("/health", ok)
+
+	get := testGet(t, app)
+
+	if resp := get("/api/zones"); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("first API request status = %d, want 200", resp.StatusCode)
+	}
+
+	resp := get("/api/zones")
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("second API request status = %d, want 429", resp.StatusCode)
+	}
+
+	if resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Error("429 without Retry-After header")
+	}
+
+	// The route limit is separate from the global one, which the API request
+	// used one token of.
+	for i := range 2 {
+		if resp := get("/zones"); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("page request %d status = %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+
+	if resp := get("/zones"); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("page request over global burst status = %d, want 429", resp.StatusCode)
+	}
+
+	if resp := get("/health"); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("health check status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestLimitsAllow_GlobalRejectionKeepsRouteBudget(t *testing.T) {
+	l := &limits{
+		global: ratelimit.New(1, 1),
+		routes: []route{{prefix: "/api/", limiter: ratelimit.New(0.001, 2)}},
+	}
+	now := time.Unix(1_700_000_000, 0)
+
+	if _, _, ok := l.allow("/api/zones", "a", now); !ok {
+		t.Fatal("first request rejected")
+	}
+
+	// The global limit rejects these; they must not take the last route token.
+	for i := range 3 {
+		limit, _, ok := l.allow("/api/zones", "a", now)
+		if ok || limit != globalRoute {
+			t.Fatalf("request %d over global burst: ok = %v, limit = %q, want rejected by %q",
+				i+2, ok, limit, globalRoute)
+		}
+	}
+
+	if _, _, ok := l.allow("/api/zones", "a", now.Add(time.Second)); !ok {
+		t.Fatal("request after global refill rejected; the route budget was used up by rejected requests")
+	}
+
+	if limit, _, ok := l.allow("/api/zones", "a", now.Add(2*time.Second)); ok || limit != "/api/" {
+		t.Errorf("third accepted request: ok = %v, limit = %q, want rejected by /api/", ok, limit)
+	}
+}
+
+// TestTenants checks that the API keys of the users of a tenant share the
+// request quota of the tenant, and that other tenants keep theirs.
+func TestTenants(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.RolePermission{}, &models.Tenant{},
+		&models.User{}, &models.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+
+	role := models.Role{Name: "user"}
+	db.Create(&role)
+
+	authService := auth.NewService(db)
+	keys := make(map[string]string)
+
+	for _, name := range []string{"acme", "globex"} {
+		tn := models.Tenant{Name: name, MaxAPIRequests: 2}
+		db.Create(&tn)
+
+		for _, user := range []string{"alice", "bob"} {
+			u := models.User{Username: name + "-" + user, Email: user + "@" + name + ".example", RoleID: role.ID,
+				TenantID: &tn.ID, Active: true}
+			db.Create(&u)
+
+			if keys[u.Username], _, err = authService.CreateAPIKey(u.ID, "ci", auth.APIKeyScope{}, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	app := fiber.New()
+	app.Use(auth.Authenticate(authService), Tenants(db))
+	app.Get("/api/zones", func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	get := func(user string) int {
+		t.Helper()
+
+		req := httptest.NewRequestWithContext(context.Background(), fiber.MethodGet, "/api/zones", http.NoBody)
+		req.Header.Set(auth.APIKeyHeader, keys[user])
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+
+		_ = resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	for _, tt := range []struct {
+		user string
+		want int
+	}{
+		{"acme-alice", fiber.StatusOK},
+		{"acme-bob", fiber.StatusOK},
+		{"acme-alice", fiber.StatusTooManyRequests},
+		{"acme-bob", fiber.StatusTooManyRequests},
+		{"globex-alice", fiber.StatusOK},
+	} {
+		if got := get(tt.user); got != tt.want {
+			t.Errorf("request with the key of %s: status %d, want %d", tt.user, got, tt.want)
+		}
+	}
+}
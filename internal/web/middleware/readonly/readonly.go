@@ -0,0 +1,88 @@
+// Package readonly provides the middleware that enforces read-only mode:
+// while the primary PowerDNS server is read-only, requests that change zones
+// or records are answered with 403 Forbidden. Zones and records still render.
+//
+// The middleware only sees the routes of the web UI and the API; changes sent
+// to PowerDNS otherwise, e.g. by scheduled record changes, DHCP imports or
+// dynamic updates, and changes to read-only views are rejected by the
+// PowerDNS client with powerdns.ErrReadOnly.
+package readonly
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+)
+
+// guardedPrefixes are the paths whose changes reach PowerDNS: the zone
+// pages, the PowerDNS-compatible API, undoing activity log entries and
+// restoring snapshots and backups.
+var guardedPrefixes = []string{
+	"/zone/",
+	"/api/v1/",
+	"/admin/activity/",
+	"/admin/snapshots/",
+	"/admin/backup/restore",
+}
+
+// exemptPrefixes are the paths below guardedPrefixes that leave PowerDNS
+// unchanged: zone claims and requests, which are approved separately, and
+// the delegation check.
+var exemptPrefixes = []string{
+	"/zone/claim",
+	"/zone/request",
+	"/zone/delegation-check",
+}
+
+// New returns the read-only mode middleware. readOnly reports whether the
+// mode is on, e.g. powerdns.ReadOnly.
+func New(readOnly func() bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !readOnly() {
+			return c.Next()
+		}
+
+		if safeMethod(c.Method()) || !Guarded(c.Path()) {
+			return c.Next()
+		}
+
+		if handler.IsAPIRequest(c) || strings.HasPrefix(c.Path(), "/api/") {
+			return handler.JSONError(c, fiber.StatusForbidden, handler.CodeForbidden, powerdns.ErrMsgReadOnly, nil)
+		}
+
+		return handler.RenderError(c, fiber.StatusForbidden, "Read-only mode",
+			"PowerDNS is in read-only mode: zones and records can be viewed but not changed.", nil)
+	}
+}
+
+// Guarded reports whether a change request to path is rejected in
+// read-only mode. Previews, e.g. of record changes, are allowed.
+func Guarded(path string) bool {
+	path = strings.ToLower(path)
+
+	if strings.HasSuffix(path, "/preview") {
+		return false
+	}
+
+	for _, prefix := range exemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+
+	for _, prefix := range guardedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// safeMethod reports whether method only reads.
+func safeMethod(method string) bool {
+	return method == fiber.MethodGet || method == fiber.MethodHead || method == fiber.MethodOptions
+}
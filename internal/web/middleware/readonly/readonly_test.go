@@ -0,0 +1,77 @@
+package readonly
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestGuarded(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/zone/edit/example.com./records":         true,
+		"/zone/add":                               true,
+		"/api/v1/servers/localhost/zones/example": true,
+		"/admin/activity/12/undo":                 true,
+		"/admin/snapshots/2026-05-01/zone":        true,
+		"/zone/edit/example.com./records/preview": false,
+		"/zone/claim":                             false,
+		"/zone/requests":                          false,
+		"/zone/delegation-check/example.com.":     false,
+		"/admin/snapshots":                        false,
+		"/profile":                                false,
+		"/logout":                                 false,
+	} {
+		if got := Guarded(path); got != want {
+			t.Errorf("Guarded(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	readOnly := true
+
+	app := fiber.New()
+	app.Use(New(func() bool { return readOnly }))
+
+	ok := func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/zone/edit/:name", ok)
+	app.Patch("/api/v1/servers/localhost/zones/:name", ok)
+	app.Post("/profile", ok)
+
+	send := func(method, path string) int {
+		t.Helper()
+
+		req := httptest.NewRequestWithContext(context.Background(), method, path, http.NoBody)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+
+		_ = resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	for _, tt := range []struct {
+		method, path string
+		want         int
+	}{
+		{fiber.MethodGet, "/zone/edit/example.com.", fiber.StatusOK},
+		{fiber.MethodPatch, "/api/v1/servers/localhost/zones/example.com.", fiber.StatusForbidden},
+		{fiber.MethodPost, "/profile", fiber.StatusOK},
+	} {
+		if got := send(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	readOnly = false
+
+	if got := send(fiber.MethodPatch, "/api/v1/servers/localhost/zones/example.com."); got != fiber.StatusOK {
+		t.Errorf("PATCH without read-only mode status = %d, want 200", got)
+	}
+}
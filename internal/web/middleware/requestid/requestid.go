@@ -0,0 +1,26 @@
+// Package requestid provides a Fiber middleware that assigns every request an
+// ID, or keeps the one sent in X-Request-ID by a client or proxy, and returns
+// it in the response.
+package requestid
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+// New returns the request ID middleware. Register it first so that all later
+// middleware and handlers can log with requestid.Logger(c.Context()).
+func New() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id := c.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Set(requestid.Header, id)
+		c.SetContext(requestid.NewContext(c.Context(), id))
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package requestid
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/requestid"
+)
+
+func TestNew(t *testing.T) {
+	app := fiber.New()
+	app.Use(New())
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendString(requestid.FromContext(c.Context()))
+	})
+
+	tests := []struct {
+		name, header string
+		keep         bool
+	}{
+		{"generated", "", false},
+		{"client id kept", "proxy-42", true},
+		{"invalid id replaced", "two words", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequestWithContext(context.Background(), fiber.MethodGet, "/", http.NoBody)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+
+			defer func() { _ = resp.Body.Close() }()
+
+			got := resp.Header.Get(requestid.Header)
+			if !requestid.Valid(got) {
+				t.Fatalf("response request ID %q is invalid", got)
+			}
+
+			if (got == tt.header) != tt.keep {
+				t.Errorf("response request ID = %q, client sent %q", got, tt.header)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil || string(body) != got {
+				t.Errorf("context request ID = %q, header %q", body, got)
+			}
+		})
+	}
+}
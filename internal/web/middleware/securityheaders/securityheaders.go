@@ -0,0 +1,129 @@
+// Package securityheaders provides the Fiber middleware that sets the
+// Content-Security-Policy, HSTS and the other hardening headers on every
+// response. The defaults fit the bundled UI; deployments adjust them under
+// [webserver.securityheaders].
+package securityheaders
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/helmet"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+// directive is one Content-Security-Policy directive.
+type directive struct {
+	name  string
+	value string
+}
+
+// defaultPolicy is the policy the UI needs. Alpine.js v3 evaluates
+// x-data/x-on expressions via new Function(), requiring 'unsafe-eval'. Inline
+// <style> in maincss.gohtml requires 'unsafe-inline' for styles.
+var defaultPolicy = []directive{
+	{"default-src", "'self'"},
+	{"script-src", "'self' 'unsafe-eval'"},
+	{"style-src", "'self' 'unsafe-inline'"},
+	{"img-src", "'self' data:"},
+	{"font-src", "'self' data:"},
+	{"connect-src", "'self'"},
+	{"frame-ancestors", "'none'"},
+	{"base-uri", "'self'"},
+	{"form-action", "'self'"},
+	{"object-src", "'none'"},
+}
+
+// New returns the middleware for cfg, a validated config. imgOrigins are
+// added to img-src, e.g. the origin of an external avatar provider.
+func New(cfg *config.SecurityHeaders, imgOrigins ...string) fiber.Handler {
+	headers := helmet.New(helmet.Config{
+		// COEP is disabled — SharedArrayBuffer is not used, and frame-ancestors
+		// in the CSP already prevents cross-origin embedding attacks.
+		CrossOriginEmbedderPolicy: "unsafe-none",
+		XFrameOptions:             cfg.FrameOptions,
+		ReferrerPolicy:            cfg.ReferrerPolicy,
+		PermissionPolicy:          cfg.PermissionsPolicy,
+		ContentSecurityPolicy:     Policy(cfg, imgOrigins...),
+		CSPReportOnly:             cfg.CSPReportOnly,
+	})
+
+	hsts := strictTransportSecurity(cfg)
+
+	return func(c fiber.Ctx) error {
+		// helmet compares c.Protocol(), the HTTP version, with "https", so it
+		// never sends HSTS; the scheme also covers requests a trusted proxy
+		// forwards with X-Forwarded-Proto: https.
+		if hsts != "" && c.Scheme() == "https" {
+			c.Set(fiber.HeaderStrictTransportSecurity, hsts)
+		}
+
+		return headers(c)
+	}
+}
+
+// strictTransportSecurity returns the Strict-Transport-Security header value
+// for cfg, or "" when HSTS is disabled.
+func strictTransportSecurity(cfg *config.SecurityHeaders) string {
+	if cfg.HSTSMaxAge <= 0 {
+		return ""
+	}
+
+	value := "max-age=" + strconv.Itoa(cfg.HSTSMaxAge)
+
+	if cfg.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+
+	if cfg.HSTSPreload {
+		value += "; preload"
+	}
+
+	return value
+}
+
+// Policy returns the Content-Security-Policy for cfg: the default policy with
+// imgOrigins allowed in img-src and the directives of cfg.CSP applied.
+// Directives the defaults lack are appended in name order.
+func Policy(cfg *config.SecurityHeaders, imgOrigins ...string) string {
+	values := make(map[string]string, len(defaultPolicy)+len(cfg.CSP))
+	order := make([]string, 0, len(defaultPolicy)+len(cfg.CSP))
+
+	for _, d := range defaultPolicy {
+		values[d.name] = d.value
+		order = append(order, d.name)
+	}
+
+	for _, origin := range imgOrigins {
+		if origin != "" {
+			values["img-src"] += " " + origin
+		}
+	}
+
+	// Embedding by the application's own pages must be allowed by both headers.
+	if cfg.FrameOptions == config.FrameOptionsSameOrigin {
+		values["frame-ancestors"] = "'self'"
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.CSP)) {
+		if _, ok := values[name]; !ok {
+			order = append(order, name)
+		}
+
+		values[name] = strings.TrimSpace(cfg.CSP[name])
+	}
+
+	parts := make([]string, 0, len(order))
+
+	for _, name := range order {
+		if value := values[name]; value != "" {
+			parts = append(parts, name+" "+value)
+		}
+	}
+
+	return strings.Join(parts, "; ")
+}
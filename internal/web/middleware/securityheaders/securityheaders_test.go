@@ -0,0 +1,113 @@
+package securityheaders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+)
+
+// defaults returns the settings of an empty, validated config section.
+func defaults() config.SecurityHeaders {
+	return config.SecurityHeaders{
+		FrameOptions:      config.FrameOptionsDeny,
+		ReferrerPolicy:    "strict-origin-when-cross-origin",
+		PermissionsPolicy: "camera=()",
+		HSTSMaxAge:        31536000,
+	}
+}
+
+// get requests / through the middleware for cfg. The app trusts every proxy
+// so that X-Forwarded-Proto: https marks the request as HTTPS.
+func get(t *testing.T, cfg *config.SecurityHeaders, https bool) http.Header {
+	t.Helper()
+
+	app := fiber.New(fiber.Config{
+		TrustProxy:       true,
+		TrustProxyConfig: fiber.TrustProxyConfig{Proxies: []string{"0.0.0.0/0"}},
+	})
+	app.Use(New(cfg, "https://www.gravatar.com"))
+	app.Get("/", func(c fiber.Ctx) error { return c.SendString("ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	if https {
+		req.Header.Set(fiber.HeaderXForwardedProto, "https")
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	return resp.Header
+}
+
+func TestNew_Defaults(t *testing.T) {
+	cfg := defaults()
+	h := get(t, &cfg, false)
+
+	for name, want := range map[string]string{
+		fiber.HeaderXFrameOptions:       "DENY",
+		fiber.HeaderReferrerPolicy:      "strict-origin-when-cross-origin",
+		fiber.HeaderXContentTypeOptions: "nosniff",
+		fiber.HeaderPermissionsPolicy:   "camera=()",
+	} {
+		if got := h.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	csp := h.Get(fiber.HeaderContentSecurityPolicy)
+	if !strings.Contains(csp, "img-src 'self' data: https://www.gravatar.com;") ||
+		!strings.Contains(csp, "frame-ancestors 'none'") {
+		t.Errorf("CSP = %q", csp)
+	}
+
+	if got := h.Get(fiber.HeaderStrictTransportSecurity); got != "" {
+		t.Errorf("HSTS over plain HTTP = %q, want none", got)
+	}
+
+	if got := get(t, &cfg, true).Get(fiber.HeaderStrictTransportSecurity); got != "max-age=31536000" {
+		t.Errorf("HSTS = %q, want max-age=31536000", got)
+	}
+
+	cfg.HSTSMaxAge = -1
+	if got := get(t, &cfg, true).Get(fiber.HeaderStrictTransportSecurity); got != "" {
+		t.Errorf("disabled HSTS = %q, want none", got)
+	}
+}
+
+func TestNew_ReportOnly(t *testing.T) {
+	cfg := defaults()
+	cfg.CSPReportOnly = true
+
+	h := get(t, &cfg, false)
+	if h.Get(fiber.HeaderContentSecurityPolicy) != "" || h.Get(fiber.HeaderContentSecurityPolicyReportOnly) == "" {
+		t.Errorf("report-only policy sent as %v", h)
+	}
+}
+
+func TestPolicy_Overrides(t *testing.T) {
+	cfg := defaults()
+	cfg.FrameOptions = config.FrameOptionsSameOrigin
+	cfg.CSP = map[string]string{
+		"img-src":    "'self' https://cdn.example.com",
+		"object-src": "",
+		"report-uri": "/csp-report",
+	}
+
+	got := Policy(&cfg, "https://www.gravatar.com")
+
+	want := "default-src 'self'; script-src 'self' 'unsafe-eval'; style-src 'self' 'unsafe-inline'; " +
+		"img-src 'self' https://cdn.example.com; font-src 'self' data:; connect-src 'self'; " +
+		"frame-ancestors 'self'; base-uri 'self'; form-action 'self'; report-uri /csp-report"
+	if got != want {
+		t.Errorf("Policy() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+// Package setup provides the middleware that sends every request to the
+// first-run setup wizard until it has been completed.
+package setup
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler"
+	setuphandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/setup"
+	authmiddleware "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/middleware/auth"
+)
+
+// New returns the setup middleware. While setup is pending, pages redirect to
+// the wizard and API requests are answered with 503. Static assets, the health
+// endpoint, the wizard itself and the login and logout pages stay reachable.
+// It must run before the auth middleware, which would send the visitor to a
+// login page nobody can use yet.
+func New(store *controller.Store) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !store.Pending() || exempt(c) {
+			return c.Next()
+		}
+
+		if handler.IsAPIRequest(c) {
+			return handler.JSONError(c, fiber.StatusServiceUnavailable, handler.CodeUnavailable,
+				"Setup has not been completed yet", nil)
+		}
+
+		return c.Redirect().To(setuphandler.Path)
+	}
+}
+
+// exempt reports whether the request is served while setup is pending.
+func exempt(c fiber.Ctx) bool {
+	originalURL := strings.ToLower(c.OriginalURL())
+
+	return strings.HasPrefix(originalURL, "/static") ||
+		strings.HasPrefix(originalURL, "/branding") ||
+		strings.HasPrefix(originalURL, "/health") ||
+		strings.HasPrefix(originalURL, setuphandler.Path) ||
+		authmiddleware.IsLoginPage(c) ||
+		authmiddleware.IsLogoutPage(c)
+}
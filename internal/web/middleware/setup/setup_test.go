@@ -0,0 +1,79 @@
+package setup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+
+	controller "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/setup"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	setuphandler "github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/web/handler/setup"
+)
+
+func TestNew(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Setting{}, &models.Role{}, &models.User{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	store, err := controller.NewStore(db)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(New(store))
+
+	ok := func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/dashboard", ok)
+	app.Get("/api/v1/zones", ok)
+	app.Get("/health", ok)
+	app.Get(setuphandler.Path, ok)
+	app.Get(setuphandler.PowerDNSPath, ok)
+
+	get := func(path string) *http.Response {
+		t.Helper()
+
+		resp, err := app.Test(httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, http.NoBody))
+		if err != nil {
+			t.Fatalf("app.Test(%s) error = %v", path, err)
+		}
+
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		return resp
+	}
+
+	resp := get("/dashboard")
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get(fiber.HeaderLocation) != setuphandler.Path {
+		t.Fatalf("setup pending: /dashboard = %d %q, want redirect to %s",
+			resp.StatusCode, resp.Header.Get(fiber.HeaderLocation), setuphandler.Path)
+	}
+
+	if resp := get("/api/v1/zones"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("setup pending: /api/v1/zones status = %d, want 503", resp.StatusCode)
+	}
+
+	for _, path := range []string{"/health", setuphandler.Path, setuphandler.PowerDNSPath} {
+		if resp := get(path); resp.StatusCode != http.StatusOK {
+			t.Errorf("setup pending: %s status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+
+	if err = store.Complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if resp := get("/dashboard"); resp.StatusCode != http.StatusOK {
+		t.Errorf("setup done: /dashboard status = %d, want 200", resp.StatusCode)
+	}
+}
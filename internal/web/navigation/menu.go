@@ -0,0 +1,324 @@
+package navigation
+
+import (
+	"strings"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/auth"
+)
+
+// MenuItem is an entry of the main menu. The sidebar template and the JSON
+// navigation API both render the menu returned by Menu, so the permission
+// rules live in one place.
+type MenuItem struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Icon  string `json:"icon"`
+	// Section is the Context.ActiveSection of the item's pages; a submenu is
+	// open while its section is active.
+	Section string `json:"section"`
+	// Pages are the Context.ActivePage values that highlight the item.
+	Pages []string `json:"pages,omitempty"`
+	// AnyOf lists the permissions of which the user needs at least one; an
+	// empty list shows the item to every user. Submenus are shown when at
+	// least one child is.
+	AnyOf []string `json:"-"`
+	// NoneOf hides the item from users holding any of these permissions.
+	NoneOf   []string   `json:"-"`
+	Children []MenuItem `json:"children,omitempty"`
+	Active   bool       `json:"active"`
+}
+
+// MenuSection is a headed group of menu items.
+type MenuSection struct {
+	Title string     `json:"title"`
+	Items []MenuItem `json:"items"`
+}
+
+// mainMenu is the full main menu before permission filtering.
+var mainMenu = []MenuSection{
+	{
+		Title: "Zone Management",
+		Items: []MenuItem{
+			{
+				Title: "Dashboard", URL: "/dashboard", Icon: "bi-speedometer",
+				Section: "dashboard", Pages: []string{"dashboard"}, AnyOf: []string{auth.PermDashboardView},
+			},
+			{
+				Title: "Add Zone", URL: "/zone/add", Icon: "bi-plus-square",
+				Section: "zones", Pages: []string{"add"}, AnyOf: []string{auth.PermZoneCreate},
+			},
+			{
+				Title: "Request Zone", URL: "/zone/requests", Icon: "bi-envelope-plus",
+				Section: "zones", Pages: []string{"request", "requests"},
+				AnyOf: []string{auth.PermZoneRequest}, NoneOf: []string{auth.PermZoneCreate},
+			},
+			{
+				Title: "Claim Zone", URL: "/zone/claims", Icon: "bi-patch-check",
+				Section: "zones", Pages: []string{"claim", "claims"}, AnyOf: []string{auth.PermZoneClaim},
+			},
+			{
+				Title: "Zone Health", URL: "/zone/health", Icon: "bi-heart-pulse",
+				Section: "zones", Pages: []string{"health"}, AnyOf: []string{auth.PermZoneRead},
+			},
+			{
+				Title: "Deleted Zones", URL: "/zone/deleted", Icon: "bi-trash",
+				Section: "zones", Pages: []string{"deleted"}, AnyOf: []string{auth.PermZoneDelete},
+			},
+			{
+				Title: "Zone Snapshots", URL: "/zone/snapshots", Icon: "bi-clock-history",
+				Section: "zones", Pages: []string{"snapshots"}, AnyOf: []string{auth.PermZoneRead},
+			},
+			{
+				Title: "Change Requests", URL: "/zone/changes", Icon: "bi-check2-square",
+				Section: "zones", Pages: []string{"changes"}, AnyOf: []string{auth.PermZoneApprove, auth.PermZonePropose},
+			},
+			{
+				Title: "DNS Query", URL: "/tools/query", Icon: "bi-search",
+				Section: "tools", Pages: []string{"query"}, AnyOf: []string{auth.PermToolsQuery},
+			},
+		},
+	},
+	{
+		Title: "Administration",
+		Items: []MenuItem{
+			{
+				Title: "Zone Requests", URL: "/admin/zone-requests", Icon: "bi-inbox",
+				Section: "admin", Pages: []string{"zone-requests"}, AnyOf: []string{auth.PermAdminZoneRequests},
+			},
+			{
+				Title: "Zone Claims", URL: "/admin/zone-claims", Icon: "bi-patch-question",
+				Section: "admin", Pages: []string{"zone-claims"}, AnyOf: []string{auth.PermAdminZoneClaims},
+			},
+			{
+				Title: "Activity", URL: "/admin/activity", Icon: "bi-activity",
+				Section: "admin", Pages: []string{"activity"}, AnyOf: []string{auth.PermAdminActivityLog},
+			},
+			{
+				Title: "Logins", URL: "/admin/logins", Icon: "bi-door-open",
+				Section: "admin", Pages: []string{"logins"}, AnyOf: []string{auth.PermAdminActivityLog},
+			},
+			{
+				Title: "Server Configuration", URL: "/admin/server/configuration", Icon: "bi-tools",
+				Section: "server", Pages: []string{"configuration"}, AnyOf: []string{auth.PermAdminServerConfig},
+			},
+			{
+				Title: "System Information", URL: "/admin/system", Icon: "bi-info-circle",
+				Section: "admin", Pages: []string{"system"}, AnyOf: []string{auth.PermAdminSystem},
+			},
+			{
+				Title: "Maintenance Mode", URL: "/admin/maintenance", Icon: "bi-cone-striped",
+				Section: "admin", Pages: []string{"maintenance"}, AnyOf: []string{auth.PermAdminMaintenance},
+			},
+			{
+				Title: "Backup & Restore", URL: "/admin/backup", Icon: "bi-database-down",
+				Section: "admin", Pages: []string{"backup"}, AnyOf: []string{auth.PermAdminBackup},
+			},
+			{
+				Title: "Snapshots", URL: "/admin/snapshots", Icon: "bi-clock-history",
+				Section: "admin", Pages: []string{"snapshots"}, AnyOf: []string{auth.PermAdminSnapshots},
+			},
+			{
+				Title: "Integrations", URL: "/admin/integrations", Icon: "bi-chat-dots",
+				Section: "admin", Pages: []string{"integrations"}, AnyOf: []string{auth.PermAdminIntegrations},
+			},
+			{
+				Title: "TSIG Keys", URL: "/admin/tsig-keys", Icon: "bi-key",
+				Section: "admin", Pages: []string{"tsig-keys"}, AnyOf: []string{auth.PermAdminTSIGKeys},
+			},
+			{
+				Title: "DHCP Imports", URL: "/admin/dhcp-imports", Icon: "bi-hdd-network",
+				Section: "admin", Pages: []string{"dhcp-imports"}, AnyOf: []string{auth.PermAdminDHCPImports},
+			},
+			{
+				Title: "Nameserver Sets", URL: "/admin/nameserver-sets", Icon: "bi-hdd-stack",
+				Section: "admin", Pages: []string{"nameserver-sets"}, AnyOf: []string{auth.PermAdminNameserverSets},
+			},
+			{
+				Title: "Tenants", URL: "/admin/tenants", Icon: "bi-buildings",
+				Section: "admin", Pages: []string{"tenants"}, AnyOf: []string{auth.PermAdminTenants},
+			},
+			{
+				Title: "Tenant Usage", URL: "/tenant/usage", Icon: "bi-speedometer2",
+				Section: "tenant", Pages: []string{"usage"},
+				AnyOf: []string{auth.PermTenantUsage}, NoneOf: []string{auth.PermAdminTenants},
+			},
+			{
+				Title: "Roles", URL: "/admin/role", Icon: "bi-shield-lock",
+				Section: "admin", Pages: []string{"role"}, AnyOf: []string{auth.PermAdminRoles},
+			},
+			{
+				Title: "Groups", URL: "/admin/group", Icon: "bi-people",
+				Section: "admin", Pages: []string{"group"}, AnyOf: []string{auth.PermAdminGroups},
+			},
+			{
+				Title: "Group Mappings", URL: "/admin/group-mappings", Icon: "bi-diagram-3",
+				Section: "admin", Pages: []string{"group-mappings"}, AnyOf: []string{auth.PermAdminGroupMappings},
+			},
+			{
+				Title: "Users", URL: "/admin/user", Icon: "bi-person-vcard",
+				Section: "admin", Pages: []string{"user"}, AnyOf: []string{auth.PermAdminUsers},
+			},
+			{
+				Title: "Tags", URL: "/admin/tag", Icon: "bi-tags",
+				Section: "admin", Pages: []string{"tags"}, AnyOf: []string{auth.PermAdminTags},
+			},
+			{
+				Title: "Zone Tags", URL: "/admin/zone-tag", Icon: "bi-diagram-3",
+				Section: "admin", Pages: []string{"zone-tags"}, AnyOf: []string{auth.PermAdminZoneTags},
+			},
+			{
+				Title: "Settings", URL: "#", Icon: "bi-gear", Section: "settings",
+				Children: []MenuItem{
+					{
+						Title: "Zone Records", URL: "/admin/settings/zone-records", Icon: "bi-card-list",
+						Section: "settings", Pages: []string{"zone-records"}, AnyOf: []string{auth.PermAdminZoneRecords},
+					},
+					{
+						Title: "Name Policies", URL: "/admin/settings/name-policies", Icon: "bi-sign-stop",
+						Section: "settings", Pages: []string{"name-policies"}, AnyOf: []string{auth.PermAdminZoneRecords},
+					},
+					{
+						Title: "PDNS Server", URL: "/admin/settings/pdns-server", Icon: "bi-server",
+						Section: "settings", Pages: []string{"pdns-server"}, AnyOf: []string{auth.PermAdminPDNSServer},
+					},
+					{
+						Title: "PDNS Views", URL: "/admin/settings/pdns-views", Icon: "bi-layers",
+						Section: "settings", Pages: []string{"pdns-views"}, AnyOf: []string{auth.PermAdminPDNSServer},
+					},
+					{
+						Title: "TTL Presets", URL: "/admin/settings/ttl-presets", Icon: "bi-clock",
+						Section: "settings", Pages: []string{"ttl-presets"}, AnyOf: []string{auth.PermAdminTTLPresets},
+					},
+					{
+						Title: "Branding", URL: "/admin/settings/branding", Icon: "bi-palette",
+						Section: "settings", Pages: []string{"branding"}, AnyOf: []string{auth.PermAdminBranding},
+					},
+					{
+						Title: "Application", URL: "/admin/settings/app", Icon: "bi-sliders",
+						Section: "settings", Pages: []string{"app"}, AnyOf: []string{auth.PermAdminSettings},
+					},
+					{
+						Title: "Authentication", URL: "/admin/settings/auth", Icon: "bi-person-lock",
+						Section: "settings", Pages: []string{"auth"}, AnyOf: []string{auth.PermAdminSettings},
+					},
+				},
+			},
+		},
+	},
+}
+
+// Menu returns the main menu filtered by hasPermission, with the entries of
+// the current page (nav may be nil) marked active. Empty sections and submenus
+// are dropped.
+func Menu(hasPermission func(string) bool, nav *Context) []MenuSection {
+	sections := make([]MenuSection, 0, len(mainMenu))
+
+	for _, section := range mainMenu {
+		items := filterItems(section.Items, hasPermission, nav)
+		if len(items) > 0 {
+			sections = append(sections, MenuSection{Title: section.Title, Items: items})
+		}
+	}
+
+	return sections
+}
+
+func filterItems(items []MenuItem, hasPermission func(string) bool, nav *Context) []MenuItem {
+	var visible []MenuItem
+
+	for _, item := range items {
+		if !item.allowed(hasPermission) {
+			continue
+		}
+
+		if len(item.Children) > 0 {
+			item.Children = filterItems(item.Children, hasPermission, nav)
+			if len(item.Children) == 0 {
+				continue
+			}
+
+			item.Active = nav != nil && nav.ActiveSection == item.Section
+		} else {
+			item.Active = nav != nil && contains(item.Pages, nav.ActivePage)
+		}
+
+		visible = append(visible, item)
+	}
+
+	return visible
+}
+
+func (m *MenuItem) allowed(hasPermission func(string) bool) bool {
+	for _, perm := range m.NoneOf {
+		if hasPermission(perm) {
+			return false
+		}
+	}
+
+	if len(m.AnyOf) == 0 {
+		return true
+	}
+
+	for _, perm := range m.AnyOf {
+		if hasPermission(perm) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContextForPath builds the navigation context of the visible menu entry
+// whose URL is the longest prefix of path, with breadcrumbs Home → submenu →
+// entry. It returns nil when no entry the user may see matches.
+func ContextForPath(path string, hasPermission func(string) bool) *Context {
+	var (
+		best   *MenuItem
+		parent *MenuItem
+	)
+
+	var walk func(items []MenuItem, up *MenuItem)
+	walk = func(items []MenuItem, up *MenuItem) {
+		for i := range items {
+			item := &items[i]
+			if len(item.Children) > 0 {
+				walk(item.Children, item)
+				continue
+			}
+
+			if path != item.URL && !strings.HasPrefix(path, item.URL+"/") {
+				continue
+			}
+
+			if best == nil || len(item.URL) > len(best.URL) {
+				best, parent = item, up
+			}
+		}
+	}
+
+	sections := Menu(hasPermission, nil)
+	for i := range sections {
+		walk(sections[i].Items, nil)
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	nav := NewContext(best.Title, best.Section, best.Pages[0]).AddBreadcrumb("Home", "/dashboard", false)
+	if parent != nil {
+		nav.AddBreadcrumb(parent.Title, parent.URL, false)
+	}
+
+	return nav.AddBreadcrumb(best.Title, best.URL, true)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package navigation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func grant(perms ...string) func(string) bool {
+	set := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+
+	return func(p string) bool { return set[p] }
+}
+
+func titles(items []MenuItem) []string {
+	out := make([]string, len(items))
+	for i := range items {
+		out[i] = items[i].Title
+	}
+
+	return out
+}
+
+func TestMenu_FiltersByPermission(t *testing.T) {
+	sections := Menu(grant("dashboard.view", "zone.request"), nil)
+
+	require.Len(t, sections, 1, "the Administration section is dropped when empty")
+	assert.Equal(t, "Zone Management", sections[0].Title)
+	assert.Equal(t, []string{"Dashboard", "Request Zone"}, titles(sections[0].Items))
+
+	// zone.create replaces the request link with Add Zone.
+	sections = Menu(grant("dashboard.view", "zone.request", "zone.create"), nil)
+	assert.Equal(t, []string{"Dashboard", "Add Zone"}, titles(sections[0].Items))
+
+	sections = Menu(grant("dashboard.view", "zone.claim"), nil)
+	assert.Equal(t, []string{"Dashboard", "Claim Zone"}, titles(sections[0].Items))
+
+	sections = Menu(grant("dashboard.view", "zone.delete"), nil)
+	assert.Equal(t, []string{"Dashboard", "Deleted Zones"}, titles(sections[0].Items))
+}
+
+func TestMenu_SubmenuAndActive(t *testing.T) {
+	nav := NewContext("TTL Presets", "settings", "ttl-presets")
+	sections := Menu(grant("admin.ttl.presets", "admin.users"), nav)
+
+	require.Len(t, sections, 1)
+
+	items := sections[0].Items
+	require.Equal(t, []string{"Users", "Settings"}, titles(items))
+	assert.False(t, items[0].Active)
+	assert.True(t, items[1].Active, "submenu of the active section is open")
+	require.Equal(t, []string{"TTL Presets"}, titles(items[1].Children))
+	assert.True(t, items[1].Children[0].Active)
+
+	// The shared definition is not modified by filtering.
+	assert.Len(t, mainMenu[1].Items[len(mainMenu[1].Items)-1].Children, 8)
+}
+
+func TestContextForPath(t *testing.T) {
+	has := grant("admin.users", "admin.branding")
+
+	nav := ContextForPath("/admin/user/5/edit", has)
+	require.NotNil(t, nav)
+	assert.Equal(t, "Users", nav.PageTitle)
+	assert.True(t, nav.IsActive("admin", "user"))
+	assert.Equal(t, []BreadcrumbItem{
+		{Title: "Home", URL: "/dashboard"},
+		{Title: "Users", URL: "/admin/user", Active: true},
+	}, nav.Breadcrumbs)
+
+	nav = ContextForPath("/admin/settings/branding", has)
+	require.NotNil(t, nav)
+	assert.Equal(t, []string{"Home", "Settings", "Branding"},
+		[]string{nav.Breadcrumbs[0].Title, nav.Breadcrumbs[1].Title, nav.Breadcrumbs[2].Title})
+
+	assert.Nil(t, ContextForPath("/admin/role", has), "entries the user may not see do not match")
+	assert.Nil(t, ContextForPath("/admin/users-export", has), "prefix matches stop at path segments")
+}
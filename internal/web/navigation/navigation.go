@@ -3,17 +3,17 @@ package navigation
 
 // BreadcrumbItem represents a single breadcrumb link.
 type BreadcrumbItem struct {
-	Title  string
-	URL    string
-	Active bool
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Active bool   `json:"active"`
 }
 
 // Context represents the navigation context for a page.
 type Context struct {
-	ActiveSection string
-	ActivePage    string
-	Breadcrumbs   []BreadcrumbItem
-	PageTitle     string
+	ActiveSection string           `json:"active_section"`
+	ActivePage    string           `json:"active_page"`
+	Breadcrumbs   []BreadcrumbItem `json:"breadcrumbs"`
+	PageTitle     string           `json:"page_title"`
 }
 
 // NewContext creates a new navigation context.
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
@@ -16,9 +17,32 @@ type StorageBackend interface {
 	Delete(key string) error
 }
 
+// renewInterval is the shortest time between two renewals of a session, so
+// that active sessions are not written to the storage on every request.
+const renewInterval = time.Minute
+
+// ErrExpired is returned for a session that has outlived its lifetime.
+var ErrExpired = errors.New("session expired")
+
+// DefaultLifetime is the session lifetime until SetLifetime is called.
+var DefaultLifetime = Lifetime{Absolute: 24 * time.Hour}
+
 // store is the global session storage backend.
 var store StorageBackend
 
+// lifetime returns the current session lifetime; set by SetLifetime.
+var lifetime = func() Lifetime { return DefaultLifetime }
+
+// Lifetime bounds how long a session lives. A zero duration disables the
+// respective limit.
+type Lifetime struct {
+	// Absolute is the longest a session lives after login, however active.
+	Absolute time.Duration
+	// Idle ends a session that has not been used for this long. Every request
+	// extends it, up to Absolute.
+	Idle time.Duration
+}
+
 // DashboardFilters holds the user's last-used dashboard filter state.
 type DashboardFilters struct {
 	Search string `json:"search,omitempty"`
@@ -30,27 +54,101 @@ type Data struct {
 	User             models.User
 	TOTPPending      bool   // password verified, TOTP code still required
 	TOTPTempSecret   string // temporary secret during setup, not yet confirmed
+	PasswordChange   bool   // the password must be changed before continuing
 	DashboardFilters DashboardFilters
+	CreatedAt        time.Time // login time; the absolute lifetime counts from here
+	LastSeen         time.Time // last renewal; the idle timeout counts from here
+}
+
+// ExpiresAt returns when the session ends under l, or the zero time when it
+// never does.
+func (s *Data) ExpiresAt(l Lifetime) time.Time {
+	var end time.Time
+
+	if l.Absolute > 0 {
+		end = s.CreatedAt.Add(l.Absolute)
+	}
+
+	if l.Idle > 0 {
+		if idle := s.LastSeen.Add(l.Idle); end.IsZero() || idle.Before(end) {
+			end = idle
+		}
+	}
+
+	return end
 }
 
-// Write writes the session data for the given session ID with an expiration duration.
-func (s *Data) Write(sessionID string, exp time.Duration) error {
+// Write writes the session data for the given session ID. A new session
+// starts now. The entry expires together with the session under the current
+// lifetime; ErrExpired is returned, and the entry removed, when that has
+// already happened.
+func (s *Data) Write(sessionID string) error {
+	now := time.Now()
+
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = now
+	}
+
+	if s.LastSeen.IsZero() {
+		s.LastSeen = now
+	}
+
+	var ttl time.Duration
+
+	if end := s.ExpiresAt(lifetime()); !end.IsZero() {
+		if ttl = end.Sub(now); ttl <= 0 {
+			_ = store.Delete(sessionID) //nolint:errcheck // best-effort cleanup
+
+			return ErrExpired
+		}
+	}
+
 	out, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
 
-	return store.Set(sessionID, out, exp)
+	return store.Set(sessionID, out, ttl)
 }
 
-// Read reads the session data for the given session ID.
+// Read reads the session data for the given session ID. It returns
+// ErrExpired, and removes the entry, for a session past its lifetime that the
+// storage backend has not evicted yet.
 func (s *Data) Read(sessionID string) error {
 	byteData, err := store.Get(sessionID)
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(byteData, s)
+	if err = json.Unmarshal(byteData, s); err != nil {
+		return err
+	}
+
+	// Sessions written before CreatedAt existed are renewed instead.
+	if s.CreatedAt.IsZero() {
+		return nil
+	}
+
+	if end := s.ExpiresAt(lifetime()); !end.IsZero() && !time.Now().Before(end) {
+		_ = store.Delete(sessionID) //nolint:errcheck // best-effort cleanup
+
+		return ErrExpired
+	}
+
+	return nil
+}
+
+// Renew records a request on the session. With an idle timeout it restarts
+// the idle period, at most once per renewInterval to spare the storage. It
+// returns ErrExpired when the session has outlived its absolute lifetime.
+func (s *Data) Renew(sessionID string) error {
+	if !s.CreatedAt.IsZero() && (lifetime().Idle <= 0 || time.Since(s.LastSeen) < renewInterval) {
+		return nil
+	}
+
+	s.LastSeen = time.Now()
+
+	return s.Write(sessionID)
 }
 
 // DeleteSession deletes the session with the given session ID from the store.
@@ -67,6 +165,17 @@ func Init(s StorageBackend) {
 	store = s
 }
 
+// SetLifetime makes f the source of the session lifetime. It is called
+// whenever a session is read or written, so the lifetime may follow settings
+// changes.
+func SetLifetime(f func() Lifetime) {
+	if f == nil {
+		panic("lifetime is nil")
+	}
+
+	lifetime = f
+}
+
 // GenerateSessionID generates a new secure random session ID.
 func GenerateSessionID() (string, error) {
 	// 32 bytes = 256 bits
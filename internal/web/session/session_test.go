@@ -0,0 +1,105 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// useLifetime installs l and a fresh store for the duration of the test.
+func useLifetime(t *testing.T, l Lifetime) *memStorage {
+	t.Helper()
+
+	prev := lifetime
+	t.Cleanup(func() { lifetime = prev })
+	SetLifetime(func() Lifetime { return l })
+
+	m := &memStorage{data: make(map[string][]byte)}
+	Init(m)
+
+	return m
+}
+
+func TestRead_AbsoluteLifetime(t *testing.T) {
+	m := useLifetime(t, Lifetime{Absolute: time.Hour})
+
+	s := Data{CreatedAt: time.Now().Add(-59 * time.Minute)}
+	if err := s.Write("live"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if err := new(Data).Read("live"); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	s.CreatedAt = time.Now().Add(-2 * time.Hour)
+	if err := s.Write("old"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Write() of an expired session error = %v, want ErrExpired", err)
+	}
+
+	// A backend that keeps entries past their TTL must not revive them.
+	s.CreatedAt = time.Now().Add(-59 * time.Minute)
+	if err := s.Write("stale"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	SetLifetime(func() Lifetime { return Lifetime{Absolute: time.Minute} })
+
+	if err := new(Data).Read("stale"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Read() error = %v, want ErrExpired", err)
+	}
+
+	if _, ok := m.data["stale"]; ok {
+		t.Error("expired session was not deleted")
+	}
+}
+
+func TestRenew_IdleTimeout(t *testing.T) {
+	useLifetime(t, Lifetime{Absolute: 24 * time.Hour, Idle: 30 * time.Minute})
+
+	s := Data{LastSeen: time.Now().Add(-20 * time.Minute)}
+	if err := s.Write("id"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var got Data
+	if err := got.Read("id"); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if err := got.Renew("id"); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+
+	if time.Since(got.LastSeen) > time.Minute {
+		t.Errorf("LastSeen = %v, want now", got.LastSeen)
+	}
+
+	if end := got.ExpiresAt(lifetime()); time.Until(end) < 29*time.Minute {
+		t.Errorf("ExpiresAt() = %v, want about 30m from now", end)
+	}
+
+	idle := Data{CreatedAt: time.Now().Add(-time.Hour), LastSeen: time.Now().Add(-31 * time.Minute)}
+	if err := idle.Write("idle"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Write() of an idle session error = %v, want ErrExpired", err)
+	}
+}
+
+func TestRenew_WithoutIdleTimeoutSkipsWrites(t *testing.T) {
+	m := useLifetime(t, Lifetime{Absolute: time.Hour})
+
+	s := Data{CreatedAt: time.Now(), LastSeen: time.Now().Add(-time.Hour)}
+	if err := s.Renew("id"); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+
+	if _, ok := m.data["id"]; ok {
+		t.Error("Renew() wrote the session without an idle timeout")
+	}
+
+	// Sessions from before CreatedAt existed get one.
+	var legacy Data
+	if err := legacy.Renew("legacy"); err != nil || legacy.CreatedAt.IsZero() {
+		t.Errorf("Renew() of a legacy session = %v, CreatedAt %v", err, legacy.CreatedAt)
+	}
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+// oidcStatePrefix namespaces OIDC state tokens inside the shared session storage
+// so they can never collide with session IDs.
+const oidcStatePrefix = "oidc_state:"
+
+// ErrStateNotFound is returned when an OIDC state token is unknown, was already
+// consumed, or has been expired by the storage backend.
+var ErrStateNotFound = errors.New("state token not found")
+
+// WriteOIDCState stores an OIDC login state token in the shared session storage.
+// Using the session backend (instead of process memory) lets the callback be
+// served by any replica behind a load balancer and survives restarts.
+func WriteOIDCState(state string, exp time.Duration) error {
+	expiresAt, err := time.Now().Add(exp).MarshalText()
+	if err != nil {
+		return err
+	}
+
+	return store.Set(oidcStatePrefix+state, expiresAt, exp)
+}
+
+// GetDeleter is implemented by storage backends that can remove an entry and
+// return its value in one atomic step.
+type GetDeleter interface {
+	// GetDelete removes the entry for key and returns its value, or nil if it
+	// is missing or expired. Of several concurrent calls for one key, at most
+	// one returns the value.
+	GetDelete(key string) ([]byte, error)
+}
+
+// ConsumeOIDCState looks up an OIDC state token and deletes it, so each token
+// can be used only once. It returns the time the token expires at; callers
+// should still compare it against the current time because not every storage
+// backend evicts expired keys eagerly.
+//
+// The token is only guaranteed to be single use, also between replicas
+// handling the same callback at once, when the storage backend implements
+// GetDeleter.
+func ConsumeOIDCState(state string) (time.Time, error) {
+	raw, err := takeEntry(oidcStatePrefix + state)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(raw) == 0 {
+		return time.Time{}, ErrStateNotFound
+	}
+
+	var expiresAt time.Time
+	if err = expiresAt.UnmarshalText(raw); err != nil {
+		return time.Time{}, err
+	}
+
+	return expiresAt, nil
+}
+
+// takeEntry removes the entry for key and returns its value, atomically when
+// the storage backend supports it.
+func takeEntry(key string) ([]byte, error) {
+	if gd, ok := store.(GetDeleter); ok {
+		return gd.GetDelete(key)
+	}
+
+	raw, err := store.Get(key)
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+
+	if err = store.Delete(key); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
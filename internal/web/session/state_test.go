@@ -0,0 +1,133 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStorage is a minimal in-memory StorageBackend for tests.
+type memStorage struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (m *memStorage) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.data[key], nil
+}
+
+func (m *memStorage) Set(key string, val []byte, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[key] = val
+
+	return nil
+}
+
+func (m *memStorage) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.data, key)
+
+	return nil
+}
+
+func (m *memStorage) GetDelete(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	val := m.data[key]
+	delete(m.data, key)
+
+	return val, nil
+}
+
+func TestOIDCState_RoundTripIsSingleUse(t *testing.T) {
+	Init(&memStorage{data: make(map[string][]byte)})
+
+	if err := WriteOIDCState("abc", time.Minute); err != nil {
+		t.Fatalf("WriteOIDCState() error = %v", err)
+	}
+
+	expiresAt, err := ConsumeOIDCState("abc")
+	if err != nil {
+		t.Fatalf("ConsumeOIDCState() error = %v", err)
+	}
+
+	if !expiresAt.After(time.Now()) {
+		t.Errorf("expiresAt = %v, want a time in the future", expiresAt)
+	}
+
+	if _, err = ConsumeOIDCState("abc"); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("second ConsumeOIDCState() error = %v, want ErrStateNotFound", err)
+	}
+}
+
+func TestOIDCState_ConcurrentConsumesSucceedOnce(t *testing.T) {
+	Init(&memStorage{data: make(map[string][]byte)})
+
+	if err := WriteOIDCState("abc", time.Minute); err != nil {
+		t.Fatalf("WriteOIDCState() error = %v", err)
+	}
+
+	const consumers = 16
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		successes int
+	)
+
+	start := make(chan struct{})
+
+	for range consumers {
+		wg.Go(func() {
+			<-start
+
+			_, err := ConsumeOIDCState("abc")
+
+			switch {
+			case err == nil:
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			case !errors.Is(err, ErrStateNotFound):
+				t.Errorf("ConsumeOIDCState() error = %v", err)
+			}
+		})
+	}
+
+	close(start)
+	wg.Wait()
+
+	if successes != 1 {
+		t.Errorf("%d of %d concurrent consumes succeeded, want exactly 1", successes, consumers)
+	}
+}
+
+func TestOIDCState_UnknownToken(t *testing.T) {
+	Init(&memStorage{data: make(map[string][]byte)})
+
+	if _, err := ConsumeOIDCState("missing"); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("ConsumeOIDCState() error = %v, want ErrStateNotFound", err)
+	}
+}
+
+func TestOIDCState_DoesNotCollideWithSessions(t *testing.T) {
+	mem := &memStorage{data: make(map[string][]byte)}
+	Init(mem)
+
+	if err := WriteOIDCState("same-id", time.Minute); err != nil {
+		t.Fatalf("WriteOIDCState() error = %v", err)
+	}
+
+	if _, ok := mem.data["same-id"]; ok {
+		t.Error("state token was stored under the bare key; expected a prefixed key")
+	}
+}
@@ -0,0 +1,54 @@
+// Authentication settings: the "Test" buttons post the unsaved form to the
+// test endpoint of the provider and show the outcome. "Test connection" of
+// LDAP leaves out the test user, so only the service account is checked.
+(function() {
+    const form = document.getElementById('auth-settings');
+    if (!form) {
+        return;
+    }
+
+    function show(result, kind, text) {
+        const alert = document.createElement('div');
+        alert.className = 'alert alert-' + kind + ' mb-0 py-2';
+        alert.textContent = text;
+        result.replaceChildren(alert);
+    }
+
+    form.querySelectorAll('[data-auth-test]').forEach((button) => {
+        const provider = button.dataset.authTest;
+        const result = document.getElementById(provider + '-test-result');
+        const url = provider === 'ldap' ? form.dataset.testLdapUrl : form.dataset.testOidcUrl;
+        const login = button.hasAttribute('data-auth-test-login');
+
+        button.addEventListener('click', async () => {
+            const data = new FormData(form);
+            if (!login) {
+                data.delete('ldap_test_username');
+                data.delete('ldap_test_password');
+            }
+            button.disabled = true;
+            show(result, 'secondary', login ? 'Signing in…' : 'Testing the connection…');
+            try {
+                const res = await fetch(url, {
+                    method: 'POST',
+                    headers: { Accept: 'application/json' },
+                    body: new URLSearchParams(data),
+                });
+                const body = await res.json();
+                if (res.ok) {
+                    let text = body.message;
+                    if (Array.isArray(body.groups)) {
+                        text += ' Groups: ' + (body.groups.length ? body.groups.join(', ') : 'none') + '.';
+                    }
+                    show(result, 'success', text);
+                } else {
+                    show(result, 'danger', body.message || 'The test failed.');
+                }
+            } catch (_e) {
+                show(result, 'danger', 'The test failed.');
+            } finally {
+                button.disabled = false;
+            }
+        });
+    });
+})();
@@ -0,0 +1,7 @@
+document.getElementById('copy-dashboard-link')?.addEventListener('click', function() {
+    // The dashboard URL always carries the full filter state.
+    navigator.clipboard.writeText(window.location.href).then(() => {
+        this.innerHTML = '<i class="bi bi-clipboard-check me-1"></i> Copied';
+        setTimeout(() => { this.innerHTML = '<i class="bi bi-link-45deg me-1"></i> Copy link'; }, 2000);
+    });
+});
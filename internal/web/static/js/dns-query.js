@@ -0,0 +1,71 @@
+// biome-ignore lint/correctness/noUnusedVariables: used by Alpine x-data="dnsQuery()" in templates/tools/query.gohtml
+function dnsQuery() {
+    const el = document.getElementById('dns-query-data');
+    const data = el ? JSON.parse(el.textContent) : { name: '', type: 'A' };
+    return {
+        form: {
+            name: data.name,
+            type: data.type,
+            server: 'authoritative',
+            custom: '',
+            recursion: false,
+            dnssec: false,
+        },
+        running: false,
+        error: '',
+        result: null,
+        sections: [
+            ['answer', 'Answer'],
+            ['authority', 'Authority'],
+            ['additional', 'Additional'],
+        ],
+        onServerChange() {
+            // Resolvers only resolve names when asked to; PowerDNS ignores RD.
+            this.form.recursion = this.form.server !== 'authoritative';
+        },
+        rcodeBadge(rcode) {
+            switch (rcode) {
+                case 'NOERROR':
+                    return 'text-bg-success';
+                case 'NXDOMAIN':
+                    return 'text-bg-warning';
+                default:
+                    return 'text-bg-danger';
+            }
+        },
+        async run() {
+            const server = this.form.server === 'custom' ? this.form.custom.trim() : this.form.server;
+            if (!this.form.name.trim() || !server) {
+                return;
+            }
+            this.running = true;
+            this.error = '';
+            try {
+                const res = await fetch('/api/tools/query', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json', Accept: 'application/json' },
+                    body: JSON.stringify({
+                        name: this.form.name.trim(),
+                        type: this.form.type.trim(),
+                        server,
+                        recursion: this.form.recursion,
+                        dnssec: this.form.dnssec,
+                    }),
+                });
+                let body;
+                try { body = await res.json(); } catch (_) { body = {}; }
+                if (res.ok && body.success) {
+                    this.result = body;
+                } else {
+                    this.result = null;
+                    this.error = body.message || `HTTP ${res.status}`;
+                }
+            } catch (_e) {
+                this.result = null;
+                this.error = 'The query could not be sent.';
+            } finally {
+                this.running = false;
+            }
+        },
+    };
+}
@@ -0,0 +1,169 @@
+// Global search bar in the page header. Results come from /api/search and are
+// shown in a dropdown grouped by category. "/" or Ctrl+K focuses the bar, the
+// arrow keys move through the results, Enter opens the selected result or the
+// full search page, and Escape closes the dropdown.
+(function() {
+    const form = document.getElementById('global-search');
+    const input = document.getElementById('global-search-input');
+    const menu = document.getElementById('global-search-results');
+    if (!form || !input || !menu) {
+        return;
+    }
+
+    const minLength = 2;
+    let timer = null;
+    let controller = null;
+    let active = -1;
+
+    function items() {
+        return Array.from(menu.querySelectorAll('a.dropdown-item'));
+    }
+
+    function open(show) {
+        menu.classList.toggle('show', show);
+        input.setAttribute('aria-expanded', show ? 'true' : 'false');
+        if (!show) {
+            select(-1);
+        }
+    }
+
+    function select(index) {
+        const links = items();
+        links.forEach((a, i) => a.classList.toggle('active', i === index));
+        active = index;
+        if (index >= 0 && links[index]) {
+            input.setAttribute('aria-activedescendant', links[index].id);
+            links[index].scrollIntoView({ block: 'nearest' });
+        } else {
+            input.removeAttribute('aria-activedescendant');
+        }
+    }
+
+    function element(tag, className, text) {
+        const el = document.createElement(tag);
+        if (className) {
+            el.className = className;
+        }
+        if (text) {
+            el.textContent = text;
+        }
+        return el;
+    }
+
+    function render(query, categories) {
+        menu.replaceChildren();
+        let n = 0;
+        categories.forEach((cat) => {
+            const header = element('h6', 'dropdown-header');
+            header.append(element('i', 'bi ' + cat.icon + ' me-1'), cat.label);
+            menu.append(header);
+            if (cat.error) {
+                menu.append(element('span', 'dropdown-item-text small text-warning', cat.error));
+            }
+            cat.results.forEach((r) => {
+                const a = element('a', 'dropdown-item d-flex flex-column');
+                a.href = r.url;
+                a.id = 'global-search-result-' + n++;
+                a.setAttribute('role', 'option');
+                a.append(element('span', 'font-monospace text-truncate', r.title));
+                if (r.detail) {
+                    a.append(element('small', 'text-muted text-truncate', r.detail));
+                }
+                menu.append(a);
+            });
+        });
+        if (n === 0) {
+            menu.append(element('span', 'dropdown-item-text text-muted', 'Nothing found.'));
+        }
+        menu.append(element('div', 'dropdown-divider'));
+        const all = element('a', 'dropdown-item small', 'Show all results');
+        all.href = '/search?q=' + encodeURIComponent(query);
+        all.id = 'global-search-result-' + n;
+        all.setAttribute('role', 'option');
+        menu.append(all);
+        select(-1);
+        open(true);
+    }
+
+    async function search() {
+        const query = input.value.trim();
+        if (controller) {
+            controller.abort();
+        }
+        if (query.length < minLength) {
+            open(false);
+            return;
+        }
+        controller = new AbortController();
+        try {
+            const res = await fetch('/api/search?q=' + encodeURIComponent(query), {
+                headers: { Accept: 'application/json' },
+                signal: controller.signal,
+            });
+            if (!res.ok) {
+                open(false);
+                return;
+            }
+            const body = await res.json();
+            if (input.value.trim() === query) {
+                render(query, body.categories || []);
+            }
+        } catch (_e) {
+            // Aborted by a newer query or the request failed; keep the old results.
+        }
+    }
+
+    input.addEventListener('input', () => {
+        clearTimeout(timer);
+        timer = setTimeout(search, 250);
+    });
+
+    input.addEventListener('keydown', (e) => {
+        const links = items();
+        switch (e.key) {
+            case 'ArrowDown':
+                e.preventDefault();
+                if (!menu.classList.contains('show')) {
+                    search();
+                    return;
+                }
+                select(links.length ? (active + 1) % links.length : -1);
+                break;
+            case 'ArrowUp':
+                e.preventDefault();
+                select(links.length ? (active - 1 + links.length) % links.length : -1);
+                break;
+            case 'Enter':
+                if (active >= 0 && links[active]) {
+                    e.preventDefault();
+                    window.location.href = links[active].href;
+                }
+                break;
+            case 'Escape':
+                open(false);
+                break;
+        }
+    });
+
+    form.addEventListener('submit', (e) => {
+        if (input.value.trim().length < minLength) {
+            e.preventDefault();
+        }
+    });
+
+    document.addEventListener('click', (e) => {
+        if (!form.contains(e.target)) {
+            open(false);
+        }
+    });
+
+    document.addEventListener('keydown', (e) => {
+        const target = e.target;
+        const editing = target.isContentEditable || ['INPUT', 'TEXTAREA', 'SELECT'].includes(target.tagName);
+        if ((e.key === '/' && !editing) || (e.key === 'k' && (e.ctrlKey || e.metaKey))) {
+            e.preventDefault();
+            input.focus();
+            input.select();
+        }
+    });
+})();
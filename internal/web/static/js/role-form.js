@@ -38,4 +38,23 @@
         checks().forEach(c => { c.checked = false; });
         toggles().forEach(t => { t.checked = false; t.indeterminate = false; });
     });
+
+    // Permission filter: hide permissions (and empty groups) that do not match
+    // the search text in name, description or guarded routes.
+    const search = document.getElementById('perm-search');
+    search?.addEventListener('input', function () {
+        const q = search.value.trim().toLowerCase();
+        let visible = 0;
+        document.querySelectorAll('.perm-group').forEach(function (group) {
+            let groupVisible = 0;
+            group.querySelectorAll('.perm-item').forEach(function (item) {
+                const match = q === '' || item.dataset.search.toLowerCase().includes(q);
+                item.classList.toggle('d-none', !match);
+                if (match) groupVisible++;
+            });
+            group.classList.toggle('d-none', groupVisible === 0);
+            visible += groupVisible;
+        });
+        document.getElementById('perm-search-empty')?.classList.toggle('d-none', visible > 0);
+    });
 }());
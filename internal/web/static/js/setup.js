@@ -0,0 +1,43 @@
+// PowerDNS step of the setup wizard: "Test connection" posts the form to the
+// test endpoint and shows whether PowerDNS answered, without saving anything.
+(function() {
+    const form = document.getElementById('setup-powerdns');
+    const button = document.getElementById('setup-powerdns-test');
+    const result = document.getElementById('setup-powerdns-result');
+    if (!form || !button || !result) {
+        return;
+    }
+
+    function show(kind, text) {
+        const alert = document.createElement('div');
+        alert.className = 'alert alert-' + kind + ' mb-0 py-2';
+        alert.textContent = text;
+        result.replaceChildren(alert);
+    }
+
+    button.addEventListener('click', async () => {
+        if (!form.reportValidity()) {
+            return;
+        }
+        button.disabled = true;
+        show('secondary', 'Testing the connection…');
+        try {
+            const res = await fetch(form.dataset.testUrl, {
+                method: 'POST',
+                headers: { Accept: 'application/json' },
+                body: new URLSearchParams(new FormData(form)),
+            });
+            const body = await res.json();
+            if (res.ok) {
+                const server = [body.daemon_type, body.version].filter(Boolean).join(' ');
+                show('success', 'Connected' + (server ? ' to ' + server : '') + '.');
+            } else {
+                show('danger', body.message || 'The connection test failed.');
+            }
+        } catch (_e) {
+            show('danger', 'The connection test failed.');
+        } finally {
+            button.disabled = false;
+        }
+    });
+})();
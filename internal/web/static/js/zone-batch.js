@@ -0,0 +1,47 @@
+// biome-ignore lint/correctness/noUnusedVariables: used by Alpine x-data="zoneBatch()" in templates/zone/batch-progress.gohtml
+function zoneBatch(id) {
+    const el = document.getElementById('zone-batch-data');
+    const progress = el ? JSON.parse(el.textContent) : { Total: 0, Done: 0, Created: 0, Exists: 0, Failed: 0, Items: [] };
+    return {
+        progress,
+        error: '',
+        percent(n) {
+            return this.progress.Total ? (n * 100) / this.progress.Total : 0;
+        },
+        badge(status) {
+            switch (status) {
+                case 'created':
+                    return 'text-bg-success';
+                case 'exists':
+                    return 'text-bg-secondary';
+                case 'failed':
+                    return 'text-bg-danger';
+                default:
+                    return 'text-bg-light border';
+            }
+        },
+        async poll() {
+            try {
+                const resp = await fetch(`/zone/add/batch/${encodeURIComponent(id)}/status`, {
+                    headers: { Accept: 'application/json' },
+                });
+                if (!resp.ok) {
+                    this.error = resp.status === 404 ? 'The batch has expired.' : `Failed to load the progress (HTTP ${resp.status}).`;
+                    return;
+                }
+                this.progress = await resp.json();
+                this.error = '';
+            } catch (_e) {
+                this.error = 'Failed to load the progress; retrying…';
+            }
+            if (!this.progress.Finished) {
+                setTimeout(() => this.poll(), 1000);
+            }
+        },
+        init() {
+            if (!this.progress.Finished) {
+                setTimeout(() => this.poll(), 1000);
+            }
+        },
+    };
+}
@@ -161,41 +161,141 @@ function composeMX(fields) {
 
 /**
  * Parse a TXT record content string (zone-file quoted format) into plain text.
- * Handles multiple quoted segments, which DNS concatenates without separator.
+ * Handles multiple quoted segments, which DNS concatenates without separator,
+ * and the escapes \" \\ and \DDD, whose bytes are decoded as UTF-8.
  * E.g. `"v=spf1" " include:example.com"` → `"v=spf1 include:example.com"`
  */
 function parseTXT(content) {
     if (!content) return '';
     const trimmed = content.trim();
-    const parts = [];
-    const re = /"((?:[^"\\]|\\.)*)"/g;
-    let match = re.exec(trimmed);
-    while (match !== null) {
-        parts.push(match[1].replace(/\\"/g, '"').replace(/\\\\/g, '\\'));
-        match = re.exec(trimmed);
-    }
-    if (parts.length > 0) return parts.join('');
+    const parts = [...trimmed.matchAll(/"((?:[^"\\]|\\.)*)"/g)].map(m => m[1]);
     // Not in quoted format — return as-is (best-effort).
-    return trimmed;
+    if (parts.length === 0) return trimmed;
+
+    const encoder = new TextEncoder();
+    const bytes = [];
+    const escaped = parts.join('');
+    for (let i = 0; i < escaped.length; i++) {
+        if (escaped[i] === '\\' && /^\d{3}$/.test(escaped.slice(i + 1, i + 4))) {
+            bytes.push(Number(escaped.slice(i + 1, i + 4)) & 0xff);
+            i += 3;
+            continue;
+        }
+        if (escaped[i] === '\\' && i + 1 < escaped.length) i++;
+        const ch = String.fromCodePoint(escaped.codePointAt(i));
+        bytes.push(...encoder.encode(ch));
+        i += ch.length - 1;
+    }
+    return new TextDecoder().decode(new Uint8Array(bytes));
 }
 
 /**
- * Compose plain text back into zone-file TXT content.
- * Strings longer than 255 chars are split into 255-char quoted chunks (DNS limit per string).
+ * Compose plain text back into zone-file TXT content, the inverse of parseTXT.
+ * Quotes and backslashes are escaped and control characters written as \DDD.
+ * Text longer than 255 bytes is split into several quoted strings (the DNS
+ * limit per string) between characters, so long DKIM keys fit one record.
  */
 function composeTXT(text) {
     if (text == null) return null;
-    text = String(text);
-    if (text.length === 0) return '""';
-    function escapeChunk(s) {
-        return '"' + s.replace(/\\/g, '\\\\').replace(/"/g, '\\"') + '"';
-    }
-    if (text.length <= 255) return escapeChunk(text);
+    const encoder = new TextEncoder();
     const chunks = [];
-    for (let i = 0; i < text.length; i += 255) {
-        chunks.push(escapeChunk(text.slice(i, i + 255)));
+    let chunk = '';
+    let length = 0;
+    for (const ch of String(text)) {
+        const size = encoder.encode(ch).length;
+        if (length + size > 255) {
+            chunks.push(chunk);
+            chunk = '';
+            length = 0;
+        }
+        const code = ch.codePointAt(0);
+        if (ch === '"' || ch === '\\') chunk += '\\' + ch;
+        else if (code < 0x20 || code === 0x7f) chunk += '\\' + String(code).padStart(3, '0');
+        else chunk += ch;
+        length += size;
     }
-    return chunks.join(' ');
+    chunks.push(chunk);
+    return chunks.map(c => '"' + c + '"').join(' ');
+}
+
+/**
+ * Split LUA record content into the record type it answers with and its Lua
+ * code. E.g. `A "ifportup(443, {'192.0.2.1'})"` → { type: 'A', code: "ifportup(…)" }
+ */
+function parseLUA(content) {
+    if (!content) return null;
+    const match = /^\s*([A-Za-z0-9]+)\s+(.*)$/s.exec(content);
+    if (!match) return null;
+    return { type: match[1].toUpperCase(), code: parseTXT(match[2]) };
+}
+
+/** Compose LUA record content; long code is split into quoted chunks like TXT. */
+function composeLUA(type, code) {
+    if (!type || !code || !code.trim()) return null;
+    return `${type} ${composeTXT(code)}`;
+}
+
+const LUA_KEYWORDS = new Set([
+    'and', 'break', 'do', 'else', 'elseif', 'end', 'false', 'for', 'function', 'goto', 'if', 'in',
+    'local', 'nil', 'not', 'or', 'repeat', 'return', 'then', 'true', 'until', 'while',
+]);
+
+// Functions PowerDNS provides to LUA records.
+const LUA_PDNS_FUNCTIONS = new Set([
+    'ifportup', 'ifurlup', 'ifurlextup', 'pickrandom', 'pickrandomsample', 'pickhashed', 'pickwrandom',
+    'pickwhashed', 'picknamehashed', 'pickclosest', 'pickchashed', 'closestMagic', 'latlon', 'latlonloc',
+    'country', 'countryCode', 'continent', 'continentCode', 'region', 'regionCode', 'asnum', 'netmask',
+    'view', 'all', 'include', 'createReverse', 'createForward', 'createReverse6', 'createForward6',
+    'filterForward', 'dblookup',
+]);
+
+/**
+ * Convert a name policy pattern to an anchored RegExp: * matches any
+ * characters, ? one, and [...] a class, with ^ negating it as in Go.
+ */
+function globToRegExp(pattern) {
+    let re = '';
+    for (let i = 0; i < pattern.length; i++) {
+        const ch = pattern[i];
+        if (ch === '*') re += '.*';
+        else if (ch === '?') re += '.';
+        else if (ch === '[') {
+            const end = pattern.indexOf(']', i + 1);
+            if (end === -1) return /$^/;
+            re += '[' + pattern.slice(i + 1, end) + ']';
+            i = end;
+        } else if (ch === '\\' && i + 1 < pattern.length) {
+            re += pattern[++i].replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+        } else re += ch.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+    }
+    return new RegExp('^' + re + '$');
+}
+
+function escapeHTML(s) {
+    return s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;').replace(/'/g, '&#39;');
+}
+
+/**
+ * Return Lua code as HTML with keywords, PowerDNS functions, strings, numbers
+ * and comments highlighted. All code is HTML-escaped.
+ */
+function highlightLua(code) {
+    const token = /(--\[(=*)\[[\s\S]*?\]\2\]|--[^\n]*)|("(?:[^"\\\n]|\\.)*"?|'(?:[^'\\\n]|\\.)*'?|\[(=*)\[[\s\S]*?\]\4\])|(\b\d+(?:\.\d+)?\b)|([A-Za-z_][A-Za-z0-9_]*)/g;
+    let html = '';
+    let last = 0;
+    for (const m of (code || '').matchAll(token)) {
+        html += escapeHTML(code.slice(last, m.index));
+        last = m.index + m[0].length;
+        const text = escapeHTML(m[0]);
+        if (m[1]) html += `<span class="text-secondary fst-italic">${text}</span>`;
+        else if (m[3]) html += `<span class="text-success">${text}</span>`;
+        else if (m[5]) html += `<span class="text-warning-emphasis">${text}</span>`;
+        else if (LUA_KEYWORDS.has(m[0])) html += `<span class="text-primary fw-semibold">${text}</span>`;
+        else if (LUA_PDNS_FUNCTIONS.has(m[0])) html += `<span class="text-info-emphasis fw-semibold">${text}</span>`;
+        else html += text;
+    }
+    return html + escapeHTML((code || '').slice(last));
 }
 
 function canonicalizeContent(type, content) {
@@ -230,21 +330,40 @@ function ipv4PTRName(ip) {
 /**
  * Expand a possibly-abbreviated IPv6 address to its full nibble-reversed PTR
  * name.  "2001:db8::1" → "1.0.0.0…8.b.d.0.1.0.0.2.ip6.arpa."
+ * A trailing embedded IPv4 address ("64:ff9b::192.0.2.1") fills the last two
+ * groups.
  */
 function ipv6PTRName(ip) {
     try {
-        const halves = ip.split('::');
+        let addr = ip.trim().toLowerCase();
+        const v4 = addr.match(/^(.*:)(\d{1,3})\.(\d{1,3})\.(\d{1,3})\.(\d{1,3})$/);
+        if (v4) {
+            const o = v4.slice(2).map(Number);
+            if (o.some(n => n > 255)) return null;
+            addr = v4[1] + ((o[0] << 8) | o[1]).toString(16) + ':' + ((o[2] << 8) | o[3]).toString(16);
+        }
+        const halves = addr.split('::');
+        if (halves.length > 2) return null;
         const left  = halves[0] ? halves[0].split(':') : [];
         const right = halves.length > 1 && halves[1] ? halves[1].split(':') : [];
         const fill = 8 - left.length - right.length;
-        if (fill < 0) return null;
+        if (fill < 0 || (halves.length === 1 && fill !== 0)) return null;
         const groups = [...left, ...Array(fill).fill('0'), ...right];
-        if (groups.length !== 8) return null;
+        if (groups.length !== 8 || groups.some(g => !/^[0-9a-f]{1,4}$/.test(g))) return null;
         const nibbles = groups.map(g => g.padStart(4, '0')).join('');
         return nibbles.split('').reverse().join('.') + '.ip6.arpa.';
     } catch { return null; }
 }
 
+/**
+ * Return the ip6.arpa zone of the network of `prefix` bits around the PTR
+ * name `ptr`.  ("…8.b.d.0.1.0.0.2.ip6.arpa.", 32) → "8.b.d.0.1.0.0.2.ip6.arpa."
+ */
+function ipv6ReverseZone(ptr, prefix) {
+    const labels = ptr.split('.');
+    return labels.slice(32 - prefix / 4).join('.');
+}
+
 /**
  * Return the PTR record name for an IP, or null if the type is unsupported.
  */
@@ -254,6 +373,20 @@ function ptrNameForIP(ip, rrType) {
     return null;
 }
 
+/**
+ * Return the most-specific reverse zone from `reverseZones` that owns the PTR
+ * name `ptr`, or null if none match.
+ */
+function findReverseZone(ptr, reverseZones) {
+    let best = null;
+    for (const zn of reverseZones) {
+        if (ptr === zn || ptr.endsWith('.' + zn)) {
+            if (!best || zn.length > best.length) best = zn;
+        }
+    }
+    return best;
+}
+
 /**
  * Return the most-specific forward zone from `forwardZones` that owns
  * `hostname`, or null if none match.
@@ -281,14 +414,55 @@ function zoneEditor(initData) {
         allowedTypes: initData.allowedTypes || [],
         records:      (initData.records || []).map(r => ({ ...r })),
         ttlPresets:   initData.ttlPresets   || [],
+        // TTL policies of the zone and the global ones; see ttlPolicyFor.
+        ttlPolicies:  initData.ttlPolicies  || [],
+        // Values prefilled in new records: those of the zone over the global
+        // ones (ttl, nameservers, mx_priority; unset ones are missing).
+        recordDefaults: initData.defaults   || {},
+        // Whether the user may set TTLs the policies do not allow.
+        canOverrideTTL: !!initData.ttlOverride,
+        // Name policies, and whether the user may change what they block.
+        namePolicies: initData.namePolicies || [],
+        canOverrideNames: !!initData.nameOverride,
         reverseZones: initData.reverseZones || [],
         forwardZones: initData.forwardZones || [],
         existingPTRs: initData.existingPTRs || {},
-
-        // Set once in init() from the server-provided snapshot — never mutated.
-        _originalKeys: {},  // { 'name|type': true }
+        // Auto-PTR updates the PTR records of all A/AAAA changes; otherwise
+        // the record modal offers a PTR record per AAAA record.
+        autoPTR:      !!initData.autoPTR,
+        canAddZones:  !!initData.canAddZones,
+        // Record types the user's roles may modify; null = unrestricted.
+        editableTypes: initData.editableTypes || null,
+        // Pending enable/disable schedules of the zone's records.
+        schedules:    initData.schedules    || [],
+        // Whether the user may modify LUA records, and the types they answer with.
+        canEditLua:   !!initData.canEditLua,
+        luaTypes:     initData.luaTypes     || ['A', 'AAAA', 'CNAME', 'TXT'],
+        // Labels of the zone itself; RRset labels come with the records.
+        zoneLabels:   initData.zoneLabels   || {},
+        // Expiry dates of the zone (empty name and type) and of its RRsets.
+        expiries:     initData.expiries     || [],
+
+        // Set in init() from the server-provided snapshot; in lazy mode the
+        // keys of every RRset loaded later are added. The values are the RRset
+        // revisions pending changes are based on.
+        _originalKeys: {},  // { 'name|type': revision }
         _initialTypeSet: {}, // { 'A': true, ... } — for "new type" badge
 
+        // ── Lazy loading ──────────────────────────────────────────────────────
+        // Set for large zones: the server filters, sorts and pages whole
+        // RRsets, and records only holds the current page.
+        lazy:        !!initData.lazy,
+        rrsetTotal:  initData.rrsets || 0,  // RRsets matching the filters
+        recordTotal: initData.total  || 0,  // records of those RRsets
+        zoneTypes:   initData.types  || [], // all record types of the zone
+        isLoading:   false,
+        // Server records of every RRset loaded so far, keyed 'name|type'.
+        _rrsets:     {},
+        _loadedKey:  '',
+        _loadSeq:    0,
+        _loadTimer:  null,
+
         // ── Pending changes (plain object for Alpine reactivity) ──────────────
         // Keys: 'name|type'. Values: RecordChange-shaped objects.
         pendingChanges: {},
@@ -296,6 +470,7 @@ function zoneEditor(initData) {
         // ── Filter / sort / pagination ────────────────────────────────────────
         searchQuery:      '',
         activeTypeFilter: 'all',
+        labelFilter:      '',   // key or key=value
         sortField:        'name',
         sortAsc:          true,
         currentPage:      1,
@@ -303,10 +478,43 @@ function zoneEditor(initData) {
 
         // ── Save state ────────────────────────────────────────────────────────
         isSaving: false,
+        // Server preview of the pending changes shown before saving; force
+        // is set once the user chose to overwrite conflicting changes.
+        review: { changes: null, autoPTR: false, approval: false, force: false, isLoading: false },
 
         // ── Hash-navigation highlight ─────────────────────────────────────────
         _highlightEl: null,
 
+        // ── Schedule modal ────────────────────────────────────────────────────
+        scheduleForm: { record: null, action: 'disable', runAt: '', isSaving: false },
+
+        // ── Labels modal ──────────────────────────────────────────────────────
+        // record is null for the labels of the zone itself.
+        labelsForm: { record: null, text: '', isSaving: false },
+
+        // ── Expiry modal ──────────────────────────────────────────────────────
+        // record is null for the expiry of the zone itself.
+        expiryForm: { record: null, expiresOn: '', note: '', exists: false, isSaving: false },
+
+        // ── Email security modal ──────────────────────────────────────────────
+        // report is the evaluation of the served records; tab is the guided
+        // form shown: 'spf', 'dmarc' or 'dkim'. Lists are one entry per line.
+        emailForm: {
+            report: null, isLoading: false, isComposing: false, tab: 'spf',
+            spf:   { mx: true, a: false, ip4: '', ip6: '', includes: '', all: '~' },
+            dmarc: { policy: 'none', subdomain_policy: '', percent: 100, rua: '', ruf: '', adkim: 'r', aspf: 'r' },
+            dkim:  { selector: '', key_type: 'rsa', public_key: '', testing: false },
+        },
+
+        // ── Reverse zone modal ────────────────────────────────────────────────
+        // IPv6 addresses saved without a reverse zone for their PTR record.
+        reverseForm: { addresses: [], prefix: 64, isSaving: false },
+
+        // ── Bulk TTL modal ────────────────────────────────────────────────────
+        // Selected RRsets, keyed 'name|type'.
+        selectedRRsets: {},
+        ttlForm: { scope: 'selected', types: [], ttl: 3600, ttlPreset: 'custom', preview: null, isSaving: false },
+
         // ── Record modal ──────────────────────────────────────────────────────
         recordForm: {
             isEditing:       false,
@@ -320,12 +528,19 @@ function zoneEditor(initData) {
             ttlPreset:       'custom',
             content:         '',
             comment:         '',
+            commentHistory:  [],
             disabled:        false,
             // MX-specific
             mxPriority: '10',
             mxHostname: '',
             // TXT-specific
             txtText: '',
+            // LUA-specific
+            luaType:      'A',
+            luaCode:      '',
+            luaConfirmed: false,
+            // Create the PTR record of an AAAA record without Auto-PTR.
+            ptr: false,
         },
 
         // ── SOA modal ─────────────────────────────────────────────────────────
@@ -349,39 +564,33 @@ function zoneEditor(initData) {
 
         init() {
             // Build immutable snapshots from the initial server-rendered state.
-            this._originalKeys    = Object.fromEntries(this.records.map(r => [r.name + '|' + r.type, true]));
-            this._initialTypeSet  = Object.fromEntries(this.records.map(r => [r.type, true]));
+            this._originalKeys    = Object.fromEntries(this.records.map(r => [r.name + '|' + r.type, r.revision || '']));
+            this._initialTypeSet  = Object.fromEntries(
+                (this.lazy ? this.zoneTypes : this.records.map(r => r.type)).map(t => [t, true]));
 
             // Reset page to 1 whenever search or type filter changes.
             this.$watch('searchQuery',      () => { this.currentPage = 1; });
             this.$watch('activeTypeFilter', () => { this.currentPage = 1; });
+            this.$watch('labelFilter',      () => { this.currentPage = 1; });
+
+            // Large zones fetch the page whenever the filters, sort or page change.
+            if (this.lazy) {
+                this._rememberRRsets(this.records);
+                this._loadedKey = this._pageKey();
+                ['searchQuery', 'activeTypeFilter', 'labelFilter', 'sortField', 'sortAsc', 'currentPage', 'pageSize']
+                    .forEach(prop => this.$watch(prop, () => this._scheduleLoad()));
+            }
 
             // Restore the page the user was on before a save-triggered reload.
             this._restorePage();
 
-            // If the URL contains a hash, navigate to and highlight that record.
-            const hash = window.location.hash;
-            if (hash) {
-                const targetName = decodeURIComponent(hash.slice(1));
-                const idx = this.records.findIndex(r => r.name === targetName);
-                if (idx >= 0) {
-                    // Clear filters so the record is visible, then jump to its page.
-                    this.activeTypeFilter = 'all';
-                    this.searchQuery = '';
-                    this.currentPage = Math.floor(idx / this.pageSize) + 1;
-                    this.$nextTick(() => {
-                        const el = document.getElementById('rec-' + CSS.escape(targetName));
-                        if (el) {
-                            el.scrollIntoView({ behavior: 'smooth', block: 'center' });
-                            el.classList.add('table-info');
-                            this._highlightEl = el;
-                        }
-                    });
-                }
-            }
+            // Deep links (?record=…&type=… or #www-A / #www.example.com.) jump
+            // to and highlight the referenced RRset.
+            const target = this._deepLinkTarget();
+            if (target) this.focusRecord(target.name, target.type);
 
             // Fix Bootstrap aria-hidden focus-trap warning: blur any focused descendant on hide.
-            ['recordModal', 'soaModal'].forEach(id => {
+            ['recordModal', 'soaModal', 'ttlModal', 'reviewModal', 'labelsModal', 'expiryModal'].forEach(id => {
                 const modal = document.getElementById(id);
                 if (modal) {
                     modal.addEventListener('hide.bs.modal', () => {
@@ -425,7 +634,7 @@ function zoneEditor(initData) {
 
         /** All record types currently present in the table, in a consistent order. */
         get availableTypes() {
-            const types = new Set(this.records.map(r => r.type));
+            const types = new Set([...(this.lazy ? this.zoneTypes : []), ...this.records.map(r => r.type)]);
             const priority = ['SOA', 'NS', 'A', 'AAAA', 'CNAME', 'MX', 'TXT', 'SRV', 'CAA', 'PTR'];
             return [...types].sort((a, b) => {
                 const ia = priority.indexOf(a), ib = priority.indexOf(b);
@@ -444,6 +653,10 @@ function zoneEditor(initData) {
                 list = list.filter(r => r.type === this.activeTypeFilter);
             }
 
+            if (this.labelFilter.trim()) {
+                list = list.filter(r => this.matchesLabel(r.labels));
+            }
+
             if (this.searchQuery) {
                 const q = this.searchQuery.toLowerCase();
                 list = list.filter(r =>
@@ -474,11 +687,19 @@ function zoneEditor(initData) {
             });
         },
 
+        /** Number of records matching the search and type filter. */
+        get recordCount() {
+            return this.lazy ? this.recordTotal : this.filteredRecords.length;
+        },
+
         get totalPages() {
-            return Math.max(1, Math.ceil(this.filteredRecords.length / this.pageSize));
+            const count = this.lazy ? this.rrsetTotal : this.filteredRecords.length;
+            return Math.max(1, Math.ceil(count / this.pageSize));
         },
 
         get paginatedRecords() {
+            // The server already filtered, sorted and paged the records.
+            if (this.lazy) return this.records;
             const page  = Math.min(this.currentPage, this.totalPages);
             const start = (page - 1) * this.pageSize;
             return this.filteredRecords.slice(start, start + this.pageSize);
@@ -492,6 +713,32 @@ function zoneEditor(initData) {
                 : 'Record data (e.g., IP address, hostname, text). For CNAME, MX, NS, PTR, SRV records a trailing dot is added automatically.';
         },
 
+        /** Highlighted preview of the Lua code in the record modal. */
+        get luaHighlighted() {
+            return highlightLua(this.recordForm.luaCode);
+        },
+
+        /** The ip6.arpa PTR name of the AAAA record in the record modal, or null. */
+        get recordPTRName() {
+            const content = (this.recordForm.content || '').trim();
+            if (this.recordForm.type !== 'AAAA' || !isValidIPv6(content)) return null;
+            return ipv6PTRName(content);
+        },
+
+        /** The reverse zone holding recordPTRName, or null. */
+        get recordReverseZone() {
+            const ptr = this.recordPTRName;
+            return ptr ? findReverseZone(ptr, this.reverseZones) : null;
+        },
+
+        /** The zones the reverse zone modal creates, one per address. */
+        get reverseZonePreview() {
+            return this.reverseForm.addresses.map(address => {
+                const ptr = ipv6PTRName(address);
+                return { address, zone: ptr ? ipv6ReverseZone(ptr, Number(this.reverseForm.prefix)) : '' };
+            });
+        },
+
         // ── Helpers ───────────────────────────────────────────────────────────
 
         recordId(r) {
@@ -499,25 +746,62 @@ function zoneEditor(initData) {
         },
 
         /**
-         * For A/AAAA records: return the reverse zone edit URL with a hash
-         * pointing to the PTR record line, or null if no PTR exists.
+         * For A/AAAA records: return a deep link into the reverse zone
+         * pointing at the PTR RRset, or null if no PTR exists.
          */
         ptrZoneLink(record) {
             if (record.type !== 'A' && record.type !== 'AAAA') return null;
             const ptr = ptrNameForIP(record.content, record.type);
             if (!ptr) return null;
             const zone = this.existingPTRs[ptr];
-            return zone ? '/zone/edit/' + zone + '#' + encodeURIComponent(ptr) : null;
+            return zone ? '/zone/edit/' + zone + '?' + new URLSearchParams({ record: ptr, type: 'PTR' }) : null;
         },
 
         /**
-         * For PTR records: return the forward zone edit URL with a hash
-         * pointing to the A/AAAA record line, or null if no matching zone.
+         * For PTR records: return a deep link into the forward zone
+         * pointing at the target name, or null if no matching zone.
          */
         forwardZoneLink(record) {
             if (record.type !== 'PTR') return null;
             const zone = findForwardZone(record.content, this.forwardZones);
-            return zone ? '/zone/edit/' + zone + '#' + encodeURIComponent(record.content) : null;
+            return zone ? '/zone/edit/' + zone + '?' + new URLSearchParams({ record: record.content }) : null;
+        },
+
+        /** DOM id of a record row; shared by all rows of the same RRset. */
+        recordAnchor(r) {
+            return 'rec-' + r.name + '-' + r.type;
+        },
+
+        /** Shareable deep link to the RRset of the given record. */
+        recordLink(r) {
+            const params = new URLSearchParams({ record: r.name, type: r.type });
+            return '/zone/edit/' + this.zoneName + '?' + params.toString() +
+                '#' + encodeURIComponent(this.getDisplayName(r.name) + '-' + r.type);
+        },
+
+        /** Copy the absolute deep link of a record's RRset to the clipboard. */
+        copyRecordLink(r) {
+            const url = window.location.origin + this.recordLink(r);
+            if (!navigator.clipboard) {
+                showToast(url, 'info', 10000);
+                return;
+            }
+            navigator.clipboard.writeText(url)
+                .then(() => showToast('Link copied to clipboard.', 'success'))
+                .catch(() => showToast(url, 'info', 10000));
+        },
+
+        /** Author and time of a comment history entry. */
+        commentMeta(entry) {
+            const when = entry.modified_at ? new Date(entry.modified_at * 1000).toLocaleString() : '';
+            return [entry.account || 'unknown', when].filter(Boolean).join(' · ');
+        },
+
+        /** Tooltip of the comment cell: the comment and who last changed it. */
+        commentTitle(r) {
+            const latest = (r.comment_history || [])[0];
+            if (!r.comment || !latest) return r.comment || '';
+            return r.comment + '\n— ' + this.commentMeta(latest);
         },
 
         recordRowClass(r) {
@@ -552,7 +836,9 @@ function zoneEditor(initData) {
 
         /** Returns { content, disabled } pairs for all records matching name+type, optionally excluding one content value. */
         collectRRsetRecords(name, type, excludeContent) {
-            return this.records
+            // Large zones keep the server records of every RRset loaded so far.
+            const records = this.lazy ? (this._rrsets[name + '|' + type] || []) : this.records;
+            return records
                 .filter(r => r.name === name && r.type === type &&
                     (excludeContent === undefined || r.content !== excludeContent))
                 .map(r => ({ content: r.content, disabled: r.disabled }));
@@ -584,6 +870,663 @@ function zoneEditor(initData) {
             });
         },
 
+        // ── Lazy loading (large zones) ────────────────────────────────────────
+
+        /** Identifies the page the filters, sort and page currently select. */
+        _pageKey() {
+            return JSON.stringify([this.searchQuery, this.activeTypeFilter, this.labelFilter.trim(), this.sortField,
+                this.sortAsc, this.currentPage, this.pageSize]);
+        },
+
+        /** Loads the selected page shortly after the last change, e.g. while typing a search. */
+        _scheduleLoad() {
+            clearTimeout(this._loadTimer);
+            this._loadTimer = setTimeout(() => this.loadPage(), 250);
+        },
+
+        /** Fetches RRsets from the server; returns null after showing an error. */
+        async _fetchRRsets(params) {
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/rrsets?${params}`);
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+                if (res.ok) return data;
+                showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+            } catch (err) {
+                showToast('Error loading records: ' + err.message, 'danger');
+            }
+            return null;
+        },
+
+        /** Keeps the server records of loaded RRsets for collectRRsetRecords. */
+        _rememberRRsets(records) {
+            const sets = {};
+            records.forEach(r => (sets[r.name + '|' + r.type] ||= []).push({ ...r }));
+            Object.entries(sets).forEach(([key, recs]) => {
+                this._rrsets[key] = recs;
+                this._originalKeys[key] = recs[0].revision || '';
+            });
+        },
+
+        /**
+         * Loads the page the filters, sort and page select. focus ({ name, type })
+         * loads the page holding that RRset instead. Returns whether the page
+         * was replaced.
+         */
+        async loadPage(focus) {
+            const key = this._pageKey();
+            if (!focus && key === this._loadedKey) return false;
+
+            const params = new URLSearchParams({
+                page: this.currentPage, page_size: this.pageSize,
+                sort: this.sortField, order: this.sortAsc ? 'asc' : 'desc',
+            });
+            if (this.activeTypeFilter !== 'all') params.set('type', this.activeTypeFilter);
+            if (this.searchQuery) params.set('search', this.searchQuery);
+            if (this.labelFilter.trim()) params.set('label', this.labelFilter.trim());
+            if (focus) {
+                params.set('focus', focus.name);
+                if (focus.type) params.set('focus_type', focus.type);
+            }
+
+            const seq = ++this._loadSeq;
+            this.isLoading = true;
+            const data = await this._fetchRRsets(params);
+            // A newer request supersedes this one.
+            if (seq !== this._loadSeq) return false;
+            this.isLoading = false;
+            if (!data) return false;
+
+            this.currentPage = data.page;
+            this._loadedKey  = this._pageKey();
+            this.rrsetTotal  = data.rrsets;
+            this.recordTotal = data.total;
+            this.existingPTRs = { ...this.existingPTRs, ...(data.existing_ptrs || {}) };
+            this._rememberRRsets(data.records);
+            this.records = this._withPendingChanges(data.records);
+            return true;
+        },
+
+        /** Loads a single RRset, if it exists, so that changes to it keep its other records. */
+        async _loadRRset(name, type) {
+            const data = await this._fetchRRsets(new URLSearchParams({ name, type, page_size: 1 }));
+            if (!data) return false;
+            this._rememberRRsets(data.records);
+            return true;
+        },
+
+        /**
+         * Shows the pending changes on a loaded page: changed RRsets replace
+         * their server records and new RRsets are listed first.
+         */
+        _withPendingChanges(records) {
+            const pendingRecords = change => change.records.map(r => ({
+                name: change.name, display_name: this.getDisplayName(change.name), type: change.type,
+                ttl: change.ttl, content: r.content, disabled: r.disabled, comment: change.comment,
+            }));
+
+            const out = [];
+            const seen = {};
+            records.forEach(r => {
+                const key = r.name + '|' + r.type;
+                const change = this.pendingChanges[key];
+                if (!change) {
+                    out.push({ ...r });
+                } else if (!seen[key]) {
+                    seen[key] = true;
+                    out.push(...pendingRecords(change));
+                }
+            });
+
+            const added = Object.values(this.pendingChanges)
+                .filter(c => !c.existed && (this.activeTypeFilter === 'all' || c.type === this.activeTypeFilter))
+                .flatMap(pendingRecords);
+            return [...added, ...out];
+        },
+
+        // ── Record schedules ──────────────────────────────────────────────────
+
+        /** Pending schedules of a single record. */
+        recordSchedules(record) {
+            return this.schedules.filter(s =>
+                s.name === record.name && s.type === record.type && s.content === record.content);
+        },
+
+        scheduleLabel(s) {
+            const label = (s.action === 'enable' ? 'Enable' : 'Disable') + ' on ' + new Date(s.run_at).toLocaleString();
+            return s.created_by ? label + ' (' + s.created_by + ')' : label;
+        },
+
+        openScheduleModal(record) {
+            if ((record.name + '|' + record.type) in this.pendingChanges) {
+                showToast('Save or discard the pending changes of this record first.', 'warning');
+                return;
+            }
+            // Default to one hour from now, in the browser's time zone.
+            const at = new Date(Date.now() + 3600 * 1000);
+            at.setSeconds(0, 0);
+            const local = new Date(at.getTime() - at.getTimezoneOffset() * 60000).toISOString().slice(0, 16);
+            this.scheduleForm = {
+                record,
+                action:   record.disabled ? 'enable' : 'disable',
+                runAt:    local,
+                isSaving: false,
+            };
+            this._showModal('scheduleModal');
+        },
+
+        async saveSchedule() {
+            const sf = this.scheduleForm;
+            const runAt = new Date(sf.runAt);
+            if (!sf.runAt || isNaN(runAt.getTime()) || runAt <= new Date()) {
+                showToast('Choose a time in the future.', 'warning');
+                return;
+            }
+            sf.isSaving = true;
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/schedules`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        name: sf.record.name, type: sf.record.type, content: sf.record.content,
+                        action: sf.action, run_at: runAt.toISOString(),
+                    }),
+                });
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+                if (res.ok && data.success) {
+                    this.schedules = [...this.schedules, data.schedule]
+                        .sort((a, b) => new Date(a.run_at) - new Date(b.run_at));
+                    showToast('Schedule saved.', 'success');
+                } else {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                }
+            } catch (err) {
+                showToast('Error saving schedule: ' + err.message, 'danger');
+            } finally {
+                sf.isSaving = false;
+            }
+        },
+
+        async cancelSchedule(schedule) {
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/schedules/${schedule.id}/cancel`, { method: 'POST' });
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+                if (res.ok && data.success) {
+                    this.schedules = this.schedules.filter(s => s.id !== schedule.id);
+                    showToast('Schedule canceled.', 'success');
+                } else {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                }
+            } catch (err) {
+                showToast('Error canceling schedule: ' + err.message, 'danger');
+            }
+        },
+
+        // ── Labels ────────────────────────────────────────────────────────────
+
+        /** Labels as sorted [key, value] pairs. */
+        labelEntries(labels) {
+            return Object.entries(labels || {}).sort(([a], [b]) => a.localeCompare(b));
+        },
+
+        /** A label as written in the filter and the labels modal: key=value, or the key alone. */
+        labelText(key, value) {
+            return value ? key + '=' + value : key;
+        },
+
+        /** Whether labels has the label of labelFilter, written as key or key=value. */
+        matchesLabel(labels) {
+            const filter = this.labelFilter.trim();
+            if (!filter) return true;
+            const eq = filter.indexOf('=');
+            const key = (eq === -1 ? filter : filter.slice(0, eq)).trim().toLowerCase();
+            if (!labels || !(key in labels)) return false;
+            return eq === -1 || labels[key] === filter.slice(eq + 1).trim();
+        },
+
+        /** Show the records with a label. */
+        filterByLabel(key, value) {
+            this.labelFilter = this.labelText(key, value);
+        },
+
+        /** Opens the labels modal for the RRset of record, or for the zone when record is null. */
+        openLabelsModal(record) {
+            const labels = record ? record.labels : this.zoneLabels;
+            this.labelsForm = {
+                record,
+                text:     this.labelEntries(labels).map(([k, v]) => this.labelText(k, v)).join('\n'),
+                isSaving: false,
+            };
+            this._showModal('labelsModal');
+        },
+
+        async saveLabels() {
+            const lf = this.labelsForm;
+            // One label per line or comma; the server validates keys and values.
+            const labels = {};
+            for (const item of lf.text.split(/[,\n]/)) {
+                if (!item.trim()) continue;
+                const eq = item.indexOf('=');
+                const key = (eq === -1 ? item : item.slice(0, eq)).trim();
+                labels[key] = eq === -1 ? '' : item.slice(eq + 1).trim();
+            }
+
+            lf.isSaving = true;
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/labels`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        name: lf.record ? lf.record.name : '',
+                        type: lf.record ? lf.record.type : '',
+                        labels,
+                    }),
+                });
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+                if (res.ok && data.success) {
+                    this._applyLabels(lf.record, data.labels || {});
+                    this._hideModal('labelsModal');
+                    showToast('Labels saved.', 'success');
+                } else {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                }
+            } catch (err) {
+                showToast('Error saving labels: ' + err.message, 'danger');
+            } finally {
+                lf.isSaving = false;
+            }
+        },
+
+        /** Shows saved labels on the records of the RRset of record, or on the zone. */
+        _applyLabels(record, labels) {
+            if (!record) {
+                this.zoneLabels = labels;
+                return;
+            }
+            const key = record.name + '|' + record.type;
+            const apply = list => list.forEach(r => {
+                if (r.name + '|' + r.type === key) r.labels = { ...labels };
+            });
+            apply(this.records);
+            if (this._rrsets[key]) apply(this._rrsets[key]);
+        },
+
+        // ── Expiry dates ──────────────────────────────────────────────────────
+
+        /** The expiry of the RRset of record, or of the zone when record is null. */
+        recordExpiry(record) {
+            const name = record ? record.name : '';
+            const type = record ? record.type : '';
+            return this.expiries.find(e => e.name === name && e.type === type) || null;
+        },
+
+        /** Tooltip of an expiry: its date, note and who set it. */
+        expiryTitle(e) {
+            let title = (e.overdue ? 'Expired on ' : 'Expires on ') + e.expires_on;
+            if (e.note) title += ': ' + e.note;
+            if (e.created_by) title += ' (' + e.created_by + ')';
+            return title;
+        },
+
+        /** Opens the expiry modal for the RRset of record, or for the zone when record is null. */
+        openExpiryModal(record) {
+            const e = this.recordExpiry(record);
+            this.expiryForm = {
+                record,
+                expiresOn: e ? e.expires_on : '',
+                note:      e ? e.note : '',
+                exists:    !!e,
+                isSaving:  false,
+            };
+            this._showModal('expiryModal');
+        },
+
+        /** Saves the date of the expiry modal; clear removes the expiry. */
+        async saveExpiry(clear) {
+            const ef = this.expiryForm;
+            if (!clear && !ef.expiresOn) {
+                showToast('Choose a date.', 'warning');
+                return;
+            }
+
+            ef.isSaving = true;
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/expiry`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        name:       ef.record ? ef.record.name : '',
+                        type:       ef.record ? ef.record.type : '',
+                        expires_on: clear ? '' : ef.expiresOn,
+                        note:       clear ? '' : ef.note,
+                    }),
+                });
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+                if (res.ok && data.success) {
+                    await this._reloadExpiries();
+                    this._hideModal('expiryModal');
+                    showToast(clear ? 'Expiry date removed.' : 'Expiry date saved.', 'success');
+                } else {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                }
+            } catch (err) {
+                showToast('Error saving expiry date: ' + err.message, 'danger');
+            } finally {
+                ef.isSaving = false;
+            }
+        },
+
+        async _reloadExpiries() {
+            const res = await fetch(`/zone/edit/${this.zoneName}/expiries`);
+            if (!res.ok) return;
+            const data = await res.json();
+            this.expiries = data.expiries || [];
+        },
+
+        // ── Email security ────────────────────────────────────────────────────
+
+        /** Opens the email security modal and evaluates the records PowerDNS serves. */
+        async openEmailSecurity() {
+            const ef = this.emailForm;
+            ef.report = null;
+            ef.isLoading = true;
+            this._showModal('emailSecurityModal');
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/email-security`);
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+                if (res.ok) {
+                    ef.report = data.report;
+                    this._prefillEmailForms(data.report);
+                } else {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                }
+            } catch (err) {
+                showToast('Error checking email security: ' + err.message, 'danger');
+            } finally {
+                ef.isLoading = false;
+            }
+        },
+
+        /** Starts the SPF and DMARC forms from the records the zone has. */
+        _prefillEmailForms(report) {
+            const spf = report.spf.record;
+            if (spf) {
+                const terms = spf.text.split(/\s+/).slice(1);
+                const has = re => terms.some(t => re.test(t));
+                const values = m => terms
+                    .filter(t => t.toLowerCase().startsWith(m + ':'))
+                    .map(t => t.slice(m.length + 1)).join('\n');
+                const all = terms.find(t => /^[-~?]all$/i.test(t));
+                this.emailForm.spf = {
+                    mx: has(/^\+?mx$/i), a: has(/^\+?a$/i),
+                    ip4: values('ip4'), ip6: values('ip6'), includes: values('include'),
+                    all: all ? all[0] : '~',
+                };
+            }
+
+            const tags = report.dmarc.tags || {};
+            if (report.dmarc.record) {
+                const addresses = v => (v || '').split(',')
+                    .map(a => a.trim().replace(/^mailto:/i, '')).filter(Boolean).join('\n');
+                this.emailForm.dmarc = {
+                    policy:           (tags.p || 'none').toLowerCase(),
+                    subdomain_policy: (tags.sp || '').toLowerCase(),
+                    percent:          tags.pct !== undefined ? Number(tags.pct) : 100,
+                    rua:   addresses(tags.rua),
+                    ruf:   addresses(tags.ruf),
+                    adkim: (tags.adkim || 'r').toLowerCase(),
+                    aspf:  (tags.aspf || 'r').toLowerCase(),
+                };
+            }
+        },
+
+        /** Badge class of the worst issue of an evaluated record. */
+        emailStatusClass(issues, missing) {
+            if ((issues || []).some(i => i.severity === 'error')) return 'text-bg-danger';
+            if (missing || (issues || []).length > 0) return 'text-bg-warning';
+            return 'text-bg-success';
+        },
+
+        /**
+         * Composes the record of the guided form on the current tab and opens
+         * it in the record modal, replacing the record it fixes.
+         */
+        async composeEmailRecord() {
+            if (!this.canEditType('TXT')) {
+                showToast('Your role does not permit changing TXT records.', 'danger');
+                return;
+            }
+
+            const ef = this.emailForm;
+            const list = s => (s || '').split(/[\s,]+/).filter(Boolean);
+            const body = { kind: ef.tab };
+            if (ef.tab === 'spf') {
+                body.spf = { ...ef.spf, ip4: list(ef.spf.ip4), ip6: list(ef.spf.ip6), includes: list(ef.spf.includes) };
+            } else if (ef.tab === 'dmarc') {
+                body.dmarc = { ...ef.dmarc, percent: Number(ef.dmarc.percent), rua: list(ef.dmarc.rua), ruf: list(ef.dmarc.ruf) };
+            } else {
+                body.dkim = { ...ef.dkim, selector: ef.dkim.selector.trim() };
+            }
+
+            ef.isComposing = true;
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/email-security`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body),
+                });
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+                if (!res.ok || !data.success) {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                    return;
+                }
+
+                const existing = this._emailRecordFor(ef.tab, data.name);
+                const el = document.getElementById('emailSecurityModal');
+                el.addEventListener('hidden.bs.modal',
+                    () => this._openTXTRecord(data.name, data.text, existing), { once: true });
+                this._hideModal('emailSecurityModal');
+            } catch (err) {
+                showToast('Error composing record: ' + err.message, 'danger');
+            } finally {
+                ef.isComposing = false;
+            }
+        },
+
+        /** The record a composed one replaces: the SPF or DMARC record, or the key of the selector. */
+        _emailRecordFor(kind, name) {
+            const report = this.emailForm.report;
+            if (!report) return null;
+            if (kind === 'spf')   return report.spf.record;
+            if (kind === 'dmarc') return report.dmarc.record;
+            return report.dkim.find(k => k.name === name)?.record || null;
+        },
+
+        /**
+         * Opens the record modal with a TXT record of text at name. It edits
+         * existing ({ content }) when the editor shows it, else adds a record.
+         */
+        async _openTXTRecord(name, text, existing) {
+            const matches = r => r.name === name && r.type === 'TXT' && r.content === existing.content;
+            let record = existing ? this.records.find(matches) : null;
+            if (existing && !record && this.lazy && await this.loadPage({ name, type: 'TXT' })) {
+                record = this.records.find(matches);
+            }
+
+            if (record) {
+                this.openEditRecord(record);
+            } else {
+                this.openAddRecord();
+                this.recordForm.name = this.getDisplayName(name);
+                this.recordForm.type = 'TXT';
+            }
+            this.recordForm.txtText = text;
+        },
+
+        // ── Bulk TTL change ───────────────────────────────────────────────────
+
+        get selectedCount() {
+            return Object.keys(this.selectedRRsets).length;
+        },
+
+        /** Whether every editable RRset on the current page is selected. */
+        get pageSelected() {
+            const page = this.paginatedRecords.filter(r => this.canEditType(r.type));
+            return page.length > 0 && page.every(r => this.isSelected(r));
+        },
+
+        /** Editable record types present in the zone, for the "all records of a type" scope. */
+        get ttlTypes() {
+            return this.availableTypes.filter(t => this.canEditType(t));
+        },
+
+        isSelected(record) {
+            return (record.name + '|' + record.type) in this.selectedRRsets;
+        },
+
+        /** Select or deselect the RRset of a record; TTLs apply to whole RRsets. */
+        toggleSelected(record) {
+            const key = record.name + '|' + record.type;
+            if (key in this.selectedRRsets) {
+                const { [key]: _, ...rest } = this.selectedRRsets;
+                this.selectedRRsets = rest;
+            } else {
+                this.selectedRRsets = { ...this.selectedRRsets, [key]: true };
+            }
+        },
+
+        togglePageSelected() {
+            const select = !this.pageSelected;
+            const updated = { ...this.selectedRRsets };
+            this.paginatedRecords.filter(r => this.canEditType(r.type)).forEach(r => {
+                const key = r.name + '|' + r.type;
+                if (select) updated[key] = true; else delete updated[key];
+            });
+            this.selectedRRsets = updated;
+        },
+
+        openTTLModal() {
+            if (this.pendingCount > 0) {
+                showToast('Save or discard the pending changes first.', 'warning');
+                return;
+            }
+            const ttl = this.ttlPresets.length > 0 ? this.ttlPresets[0].seconds : 3600;
+            this.ttlForm = {
+                scope:     this.selectedCount > 0 ? 'selected' : 'types',
+                types:     this.activeTypeFilter !== 'all' && this.canEditType(this.activeTypeFilter) ? [this.activeTypeFilter] : [],
+                ttl,
+                ttlPreset: this._ttlPresetFor(ttl),
+                preview:   null,
+                isSaving:  false,
+            };
+            this._showModal('ttlModal');
+        },
+
+        /** Sync ttlForm.ttl when the preset select changes. */
+        onBulkTTLPresetChange() {
+            if (this.ttlForm.ttlPreset !== 'custom') {
+                this.ttlForm.ttl = Number(this.ttlForm.ttlPreset);
+            }
+            this.ttlForm.preview = null;
+        },
+
+        /** Preview the bulk TTL change, or apply it once previewed. */
+        async submitTTL(preview) {
+            const tf = this.ttlForm;
+            const ttl = Number(tf.ttl);
+            if (!Number.isInteger(ttl) || ttl < 1) {
+                showToast('Enter a TTL of at least one second.', 'warning');
+                return;
+            }
+
+            const body = { ttl, preview };
+            if (tf.scope === 'selected') {
+                body.rrsets = Object.keys(this.selectedRRsets).map(key => {
+                    const i = key.lastIndexOf('|');
+                    return { name: key.slice(0, i), type: key.slice(i + 1) };
+                });
+            } else {
+                body.types = tf.types;
+            }
+            if ((body.rrsets || body.types).length === 0) {
+                showToast('Select records or record types first.', 'warning');
+                return;
+            }
+
+            tf.isSaving = true;
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/ttl`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body),
+                });
+
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+
+                if (!res.ok || !data.success) {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                } else if (preview) {
+                    tf.preview = data.changes || [];
+                } else {
+                    showToast(data.message || 'TTLs updated successfully!', 'success');
+                    this._rememberPage();
+                    this._hideModal('ttlModal');
+                    setTimeout(() => location.reload(), 1000);
+                    return;
+                }
+            } catch (err) {
+                showToast('Error changing TTLs: ' + err.message, 'danger');
+            }
+            tf.isSaving = false;
+        },
+
+        // ── Reverse zones ─────────────────────────────────────────────────────
+
+        /** Opens the reverse zone modal for addresses; the page reloads once it closes. */
+        _offerReverseZones(addresses) {
+            this.reverseForm = { addresses, prefix: 64, isSaving: false };
+            const el = document.getElementById('reverseModal');
+            if (!el) { location.reload(); return; }
+            el.addEventListener('hidden.bs.modal', () => location.reload(), { once: true });
+            this._showModal('reverseModal');
+        },
+
+        /** Creates the missing reverse zones of reverseForm.addresses and their PTR records. */
+        async createReverseZones() {
+            const rf = this.reverseForm;
+            rf.isSaving = true;
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/reverse-zones`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ addresses: rf.addresses, prefix: Number(rf.prefix) }),
+                });
+
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+
+                if (res.ok && data.success) {
+                    showToast(data.message, 'success');
+                    if (data.skipped && data.skipped.length > 0) {
+                        showToast('Skipped: ' + data.skipped.join(', '), 'warning', 10000);
+                    }
+                    this._hideModal('reverseModal');
+                } else {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                }
+            } catch (err) {
+                showToast('Error creating reverse zones: ' + err.message, 'danger');
+            }
+            rf.isSaving = false;
+        },
+
         // ── Bootstrap modal helpers ───────────────────────────────────────────
 
         _showModal(id) {
@@ -596,6 +1539,58 @@ function zoneEditor(initData) {
             if (el) bootstrap.Modal.getOrCreateInstance(el).hide();
         },
 
+        /**
+         * The TTL policy of records of type, the most specific of ttlPolicies
+         * like on the server: a zone's own beats a global one, and one of a
+         * record type beats one of all types. null when none applies.
+         */
+        ttlPolicyFor(type) {
+            let best = null, bestRank = -1;
+            for (const p of this.ttlPolicies) {
+                if (p.type && p.type !== type) continue;
+                const rank = (p.zone ? 2 : 0) + (p.type ? 1 : 0);
+                if (rank > bestRank) { best = p; bestRank = rank; }
+            }
+            return best;
+        },
+
+        /** Describes the TTLs a policy allows, e.g. "between 300 and 86400 seconds". */
+        ttlBounds(p) {
+            if (p.min && p.max) return `between ${p.min} and ${p.max} seconds`;
+            if (p.min) return `at least ${p.min} seconds`;
+            return `at most ${p.max} seconds`;
+        },
+
+        /** The policy violated by a TTL for records of type, or null. */
+        ttlViolation(type, ttl) {
+            const p = this.ttlPolicyFor(type);
+            if (!p || ((!p.min || ttl >= p.min) && (!p.max || ttl <= p.max))) return null;
+            return p;
+        },
+
+        /**
+         * The name policy blocking op ('create', 'update' or 'delete') on the
+         * RRset of type named name, or null. Patterns match the name relative
+         * to the zone like path.Match on the server, with * matching dots too.
+         */
+        namePolicyFor(name, type, op) {
+            if (this.canOverrideNames) return null;
+            const rel = this.getDisplayName(name).toLowerCase();
+            return this.namePolicies.find(p =>
+                p.operations.includes(op) &&
+                (!p.types || p.types.length === 0 || p.types.includes(type)) &&
+                globToRegExp(p.pattern).test(rel)) || null;
+        },
+
+        /** Shows why a name policy blocks a change; returns whether one does. */
+        _blockedByNamePolicy(name, type, op) {
+            const p = this.namePolicyFor(name, type, op);
+            if (!p) return false;
+            showToast(`A name policy does not permit you to ${op} the ${type} records of ${name}` +
+                (p.reason ? ': ' + p.reason : '.'), 'danger');
+            return true;
+        },
+
         /** Return the ttlPreset value ('custom' or a seconds string) for a given TTL number. */
         _ttlPresetFor(ttl) {
             const match = this.ttlPresets.find(p => p.seconds === ttl);
@@ -609,39 +1604,133 @@ function zoneEditor(initData) {
             }
         },
 
+        /** Prefill a new NS record with the first preferred nameserver. */
+        onRecordTypeChange() {
+            const ns = this.recordDefaults.nameservers || [];
+            if (this.recordForm.type === 'NS' && !this.recordForm.content && ns.length > 0) {
+                this.recordForm.content = ns[0];
+            }
+        },
+
         // ── Highlight helpers ─────────────────────────────────────────────────
 
         clearHighlight() {
             if (this._highlightEl) {
-                this._highlightEl.classList.remove('table-info');
+                this._highlightEl.forEach(el => el.classList.remove('table-info'));
                 this._highlightEl = null;
             }
         },
 
+        /**
+         * Resolve the RRset referenced by the page URL. Query parameters win over
+         * the fragment; the fragment is either a record name (relative or FQDN) or
+         * "<name>-<TYPE>" when the suffix is a record type present in the zone.
+         * Returns { name, type } with a canonical name, or null.
+         */
+        _deepLinkTarget() {
+            const params = new URLSearchParams(window.location.search);
+            if (params.get('record')) {
+                return {
+                    name: this.canonicalizeName(params.get('record')),
+                    type: (params.get('type') || '').toUpperCase(),
+                };
+            }
+
+            const hash = decodeURIComponent(window.location.hash.slice(1));
+            if (!hash) return null;
+
+            const dash = hash.lastIndexOf('-');
+            if (dash > 0) {
+                const type = hash.slice(dash + 1).toUpperCase();
+                if (this.availableTypes.includes(type)) {
+                    return { name: this.canonicalizeName(hash.slice(0, dash)), type };
+                }
+            }
+
+            return { name: this.canonicalizeName(hash), type: '' };
+        },
+
+        /**
+         * Clear filters, jump to the page holding the first record of the RRset
+         * (name + optional type) and highlight all of its rows.
+         */
+        focusRecord(name, type) {
+            if (this.lazy) {
+                this._focusLazy(name, type);
+                return;
+            }
+
+            const matches = r => r.name === name && (!type || r.type === type);
+            if (!this.records.some(matches)) return;
+
+            this.activeTypeFilter = 'all';
+            this.searchQuery = '';
+            this.labelFilter = '';
+            const idx = this.filteredRecords.findIndex(matches);
+            this.currentPage = Math.floor(idx / this.pageSize) + 1;
+
+            this._highlightRows(name, type);
+        },
+
+        /** focusRecord for large zones: the server finds the page of the RRset. */
+        async _focusLazy(name, type) {
+            this.activeTypeFilter = 'all';
+            this.searchQuery = '';
+            this.labelFilter = '';
+            // Let the filter watchers run, then drop the page load they scheduled.
+            await this.$nextTick();
+            clearTimeout(this._loadTimer);
+            if (await this.loadPage({ name, type })) this._highlightRows(name, type);
+        },
+
+        /** Scroll to and highlight the rows of an RRset once they are rendered. */
+        _highlightRows(name, type) {
+            this.$nextTick(() => {
+                this.clearHighlight();
+                const rows = [...document.querySelectorAll('#zone-editor tbody tr')]
+                    .filter(el => el.id === 'rec-' + name + '-' + el.dataset.type &&
+                        (!type || el.dataset.type === type));
+                if (rows.length === 0) return;
+                rows[0].scrollIntoView({ behavior: 'smooth', block: 'center' });
+                rows.forEach(el => el.classList.add('table-info'));
+                this._highlightEl = rows;
+            });
+        },
+
         // ── Open record modal (add) ───────────────────────────────────────────
 
         openAddRecord() {
             this.clearHighlight();
             const defaultType = this.allowedTypes.length > 0 ? this.allowedTypes[0].type : 'A';
-            const defaultTTL  = this.ttlPresets.length > 0 ? this.ttlPresets[0].seconds : 3600;
+            const defaultTTL  = this.recordDefaults.ttl ||
+                (this.ttlPresets.length > 0 ? this.ttlPresets[0].seconds : 3600);
+            const mxPriority  = this.recordDefaults.mx_priority != null ? String(this.recordDefaults.mx_priority) : '10';
             this.recordForm = {
                 isEditing: false,
                 originalId: '', originalName: '', originalType: '', originalContent: '',
                 name: '', type: defaultType,
                 ttl: defaultTTL, ttlPreset: this._ttlPresetFor(defaultTTL),
-                content: '', comment: '', disabled: false,
-                mxPriority: '10', mxHostname: '', txtText: '',
+                content: '', comment: '', commentHistory: [], disabled: false,
+                mxPriority, mxHostname: '', txtText: '',
+                luaType: 'A', luaCode: '', luaConfirmed: false, ptr: false,
             };
             this._showModal('recordModal');
         },
 
         // ── Open record modal (edit) ──────────────────────────────────────────
 
+        /** Whether the user's roles permit modifying records of the given type. */
+        canEditType(type) {
+            if (type === 'LUA' && !this.canEditLua) return false;
+            return this.editableTypes === null || this.editableTypes.includes(type);
+        },
+
         openEditRecord(record) {
             this.clearHighlight();
             if (record.type === 'SOA') { this.openSOAModal(record); return; }
             const mx  = record.type === 'MX'  ? parseMX(record.content)  : null;
             const txt = record.type === 'TXT' ? parseTXT(record.content) : '';
+            const lua = record.type === 'LUA' ? parseLUA(record.content) : null;
             this.recordForm = {
                 isEditing:       true,
                 originalId:      this.recordId(record),
@@ -654,10 +1743,15 @@ function zoneEditor(initData) {
                 ttlPreset:       this._ttlPresetFor(record.ttl),
                 content:         record.content,
                 comment:         record.comment || '',
+                commentHistory:  record.comment_history || [],
                 disabled:        record.disabled,
                 mxPriority: mx?.priority || '10',
                 mxHostname:  mx?.hostname  || '',
                 txtText:     txt,
+                luaType:      lua?.type || 'A',
+                luaCode:      lua?.code || '',
+                luaConfirmed: false,
+                ptr: !!this.pendingChanges[record.name + '|' + record.type]?.ptr?.includes(record.content),
             };
             this._showModal('recordModal');
         },
@@ -687,7 +1781,7 @@ function zoneEditor(initData) {
 
         // ── Save record modal ─────────────────────────────────────────────────
 
-        saveRecord() {
+        async saveRecord() {
             const form = document.getElementById('record-form');
             if (!form.checkValidity()) { form.reportValidity(); return; }
 
@@ -698,8 +1792,15 @@ function zoneEditor(initData) {
                 content = composeMX({ priority: rf.mxPriority, hostname: rf.mxHostname });
                 if (!content) { showToast('Please provide a valid priority (0–65535) and hostname.', 'danger'); return; }
             } else if (rf.type === 'TXT') {
-                content = composeTXT(rf.txtText);
+                // Unchanged text keeps the strings of the record as they are split.
+                content = rf.isEditing && rf.originalType === 'TXT' && parseTXT(rf.originalContent) === rf.txtText
+                    ? rf.originalContent
+                    : composeTXT(rf.txtText);
                 if (content === null) { showToast('Please provide valid TXT content.', 'danger'); return; }
+            } else if (rf.type === 'LUA') {
+                if (!rf.luaConfirmed) { showToast('Confirm that you understand LUA records run code on the PowerDNS server.', 'warning'); return; }
+                content = composeLUA(rf.luaType, rf.luaCode);
+                if (content === null) { showToast('Please provide the Lua code of the record.', 'danger'); return; }
             } else {
                 const rawContent = (rf.content || '').trim();
                 if (rf.type === 'A'    && !isValidIPv4(rawContent)) { showToast('Invalid IPv4 address for A record.', 'danger');    return; }
@@ -707,6 +1808,16 @@ function zoneEditor(initData) {
                 content = canonicalizeContent(rf.type, rawContent);
             }
 
+            const targetName = this.canonicalizeName(rf.name);
+            const op = (targetName + '|' + rf.type) in this._originalKeys ? 'update' : 'create';
+            if (this._blockedByNamePolicy(targetName, rf.type, op)) return;
+
+            const violated = this.ttlViolation(rf.type, Number(rf.ttl));
+            if (violated && !this.canOverrideTTL) {
+                showToast(`The TTL of ${rf.type} records must be ${this.ttlBounds(violated)}.`, 'danger');
+                return;
+            }
+
             const record = {
                 name:         this.canonicalizeName(rf.name),
                 type:         rf.type,
@@ -735,6 +1846,12 @@ function zoneEditor(initData) {
                 }
             }
 
+            // Large zones only load some RRsets: fetch the one the record joins
+            // so that the change keeps its other records.
+            const key = record.name + '|' + record.type;
+            if (this.lazy && !(key in this._originalKeys) && !(key in this.pendingChanges) &&
+                !(await this._loadRRset(record.name, record.type))) return;
+
             // Handle name/type change — update the old RRset in pendingChanges.
             if (rf.isEditing && (rf.originalName !== record.name || rf.originalType !== record.type)) {
                 const oldKey = rf.originalName + '|' + rf.originalType;
@@ -749,7 +1866,7 @@ function zoneEditor(initData) {
                         this.pendingChanges = { ...this.pendingChanges, [oldKey]: {
                             name: rf.originalName, type: rf.originalType,
                             ttl: oldTTL, comment: '', records: siblings,
-                            existed: true, changed: true,
+                            existed: true, changed: true, base: this._originalKeys[oldKey],
                         }};
                     }
                 } else if (oldKey in this.pendingChanges) {
@@ -765,7 +1882,6 @@ function zoneEditor(initData) {
             }
 
             // Upsert the new key in pendingChanges.
-            const key        = record.name + '|' + record.type;
             const existedInDB = key in this._originalKeys;
 
             if (!(key in this.pendingChanges)) {
@@ -774,6 +1890,7 @@ function zoneEditor(initData) {
                     name: record.name, type: record.type,
                     ttl: record.ttl, comment: record.comment,
                     records: existingRecs, existed: existedInDB, changed: true,
+                    base: this._originalKeys[key] || '',
                 }};
             }
 
@@ -800,6 +1917,11 @@ function zoneEditor(initData) {
                 change.records = [...change.records, { content: record.content, disabled: record.disabled }];
             }
 
+            // Addresses whose PTR record is created on save without Auto-PTR.
+            const ptr = (change.ptr || []).filter(a => a !== record.content && a !== rf.originalContent);
+            if (rf.ptr && !this.autoPTR && record.type === 'AAAA') ptr.push(record.content);
+            change.ptr = ptr;
+
             this.pendingChanges = { ...this.pendingChanges, [key]: change };
 
             // Update the records array (Alpine x-for re-renders on splice).
@@ -852,6 +1974,7 @@ function zoneEditor(initData) {
                     name: record.name, type: 'SOA',
                     ttl: record.ttl, comment: record.comment,
                     records: [], existed: existedInDB, changed: true,
+                    base: this._originalKeys[key] || '',
                 }};
             }
 
@@ -876,6 +1999,13 @@ function zoneEditor(initData) {
 
         async deleteRecord(record) {
             this.clearHighlight();
+            const rrsetKey = record.name + '|' + record.type;
+            if (rrsetKey in this._originalKeys) {
+                const remaining = rrsetKey in this.pendingChanges
+                    ? this.pendingChanges[rrsetKey].records.filter(r => r.content !== record.content)
+                    : this.collectRRsetRecords(record.name, record.type, record.content);
+                if (this._blockedByNamePolicy(record.name, record.type, remaining.length ? 'update' : 'delete')) return;
+            }
             const confirmed = await showConfirm('Are you sure you want to delete this record?', {
                 confirmText: 'Delete', confirmBtnClass: 'btn-danger',
             });
@@ -894,7 +2024,7 @@ function zoneEditor(initData) {
                     this.pendingChanges = { ...this.pendingChanges, [key]: {
                         name: record.name, type: record.type, ttl: record.ttl, comment: '',
                         records: this.collectRRsetRecords(record.name, record.type, record.content),
-                        existed: true, changed: true,
+                        existed: true, changed: true, base: this._originalKeys[key],
                     }};
                 }
             } else {
@@ -938,7 +2068,7 @@ function zoneEditor(initData) {
             } catch (_) { /* sessionStorage unavailable — non-fatal */ }
         },
 
-        async saveChanges() {
+        async saveChanges(force = false) {
             if (this.pendingCount === 0) { showToast('No changes to save', 'warning'); return; }
 
             this.isSaving = true;
@@ -946,23 +2076,35 @@ function zoneEditor(initData) {
                 const res = await fetch(`/zone/edit/${this.zoneName}/records`, {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify({ changes: Object.values(this.pendingChanges) }),
+                    body: JSON.stringify({ changes: Object.values(this.pendingChanges), force }),
                 });
 
                 let data;
                 try { data = await res.json(); } catch (_) { data = {}; }
 
                 if (res.ok && data.success) {
-                    showToast('Records saved successfully!', 'success');
+                    // Users whose changes need approval get a change request instead.
+                    showToast(data.pending ? data.message : 'Records saved successfully!', 'success');
                     // Remember the current page so the reload lands where the user was.
                     this._rememberPage();
                     let reloadDelay = 1000;
-                    if (data.ptr_no_reverse_zone && data.ptr_no_reverse_zone.length > 0) {
-                        const ips = data.ptr_no_reverse_zone.join(', ');
-                        showToast('Auto-PTR: no reverse zone found for ' + ips, 'warning', 10000);
+                    // Users who may create zones are offered the missing
+                    // ip6.arpa zones; other addresses are only reported.
+                    const noReverse = data.ptr_no_reverse_zone || [];
+                    const offer     = this.canAddZones ? noReverse.filter(ip => ip.includes(':')) : [];
+                    const others    = noReverse.filter(ip => !offer.includes(ip));
+                    if (others.length > 0) {
+                        showToast('Auto-PTR: no reverse zone found for ' + others.join(', '), 'warning', 10000);
                         reloadDelay = 5000;
                     }
+                    if (offer.length > 0) {
+                        this._offerReverseZones(offer);
+                        return;
+                    }
                     setTimeout(() => location.reload(), reloadDelay);
+                } else if (res.status === 409 && data.details?.conflicts) {
+                    this.isSaving = false;
+                    if (await this._resolveConflicts(data.details.conflicts)) await this.saveChanges(true);
                 } else {
                     showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
                     this.isSaving = false;
@@ -973,6 +2115,132 @@ function zoneEditor(initData) {
             }
         },
 
+        /** Shows what saving the pending changes would change, as computed by the server. */
+        async reviewChanges(force = false) {
+            if (this.pendingCount === 0) { showToast('No changes to save', 'warning'); return; }
+
+            const rv = this.review;
+            rv.isLoading = true;
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/records/preview`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ changes: Object.values(this.pendingChanges), force }),
+                });
+
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+
+                if (res.status === 409 && data.details?.conflicts) {
+                    rv.isLoading = false;
+                    if (await this._resolveConflicts(data.details.conflicts)) await this.reviewChanges(true);
+                    return;
+                }
+                if (res.ok && data.success) {
+                    rv.changes = data.changes || [];
+                    rv.autoPTR = !!data.auto_ptr;
+                    rv.approval = !!data.approval;
+                    rv.force   = force;
+                    this._showModal('reviewModal');
+                } else {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger');
+                }
+            } catch (err) {
+                showToast('Error previewing changes: ' + err.message, 'danger');
+            }
+            rv.isLoading = false;
+        },
+
+        /** Saves the reviewed changes. */
+        confirmReview() {
+            this._hideModal('reviewModal');
+            this.saveChanges(this.review.force);
+        },
+
+        /**
+         * Someone else changed RRsets the pending changes modify since they
+         * were loaded. Returns true if the user chose to overwrite those
+         * changes; otherwise loads them, dropping the user's changes to the
+         * conflicting RRsets and keeping the others for review.
+         */
+        async _resolveConflicts(conflicts) {
+            const names = conflicts.map(c => `${this.getDisplayName(c.name)} ${c.type}`).join(', ');
+            const overwrite = await showConfirm(
+                `These records were changed by someone else since you loaded the zone: ${names}. ` +
+                'Overwrite their changes with yours, or load their changes and drop yours to these records?',
+                { confirmText: 'Overwrite', cancelText: 'Load their changes' },
+            );
+            if (overwrite) return true;
+
+            for (const { name, type } of conflicts) {
+                const data = await this._fetchRRsets(new URLSearchParams({ name, type, page_size: 1 }));
+                if (!data) return false;
+
+                const key = name + '|' + type;
+                const { [key]: _, ...rest } = this.pendingChanges;
+                this.pendingChanges = rest;
+                delete this._originalKeys[key];
+                delete this._rrsets[key];
+                this._rememberRRsets(data.records);
+
+                // Put the current records where the stale ones were.
+                const idx = this.records.findIndex(r => r.name === name && r.type === type);
+                const records = this.records.filter(r => !(r.name === name && r.type === type));
+                records.splice(idx >= 0 ? idx : 0, 0, ...data.records);
+                this.records = records;
+            }
+            showToast('Loaded the current records. Your other changes are kept; review them and save again.', 'info', 10000);
+            return false;
+        },
+
+        // ── Export ────────────────────────────────────────────────────────────
+
+        /** URL of the record export as format (csv or xlsx), filtered and sorted like the table. */
+        exportURL(format) {
+            const params = new URLSearchParams({ sort: this.sortField, order: this.sortAsc ? 'asc' : 'desc' });
+            if (this.activeTypeFilter !== 'all') params.set('type', this.activeTypeFilter);
+            if (this.searchQuery) params.set('search', this.searchQuery);
+            if (this.labelFilter.trim()) params.set('label', this.labelFilter.trim());
+            return `/zone/edit/${this.zoneName}/export.${format}?${params}`;
+        },
+
+        // ── CSV import ────────────────────────────────────────────────────────
+
+        async importCSV(event) {
+            const file = event.target.files[0];
+            event.target.value = '';
+            if (!file) return;
+
+            const ok = await showConfirm(
+                `Import ${file.name}? Every RRset in the file replaces the RRset with the same name and type.`,
+                { confirmText: 'Import', confirmBtnClass: 'btn-primary' },
+            );
+            if (!ok) return;
+
+            const body = new FormData();
+            body.append('file', file);
+
+            this.isSaving = true;
+            try {
+                const res = await fetch(`/zone/edit/${this.zoneName}/import`, { method: 'POST', body });
+
+                let data;
+                try { data = await res.json(); } catch (_) { data = {}; }
+
+                if (res.ok && data.success) {
+                    showToast(data.message || 'Records imported successfully!', 'success');
+                    this._rememberPage();
+                    setTimeout(() => location.reload(), 1000);
+                } else {
+                    showToast('Error: ' + (data.message || `HTTP ${res.status}`), 'danger', 10000);
+                    this.isSaving = false;
+                }
+            } catch (err) {
+                showToast('Error importing records: ' + err.message, 'danger');
+                this.isSaving = false;
+            }
+        },
+
         // ── Discard all changes ───────────────────────────────────────────────
 
         async discardChanges() {
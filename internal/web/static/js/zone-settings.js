@@ -98,7 +98,7 @@ document.addEventListener('DOMContentLoaded', function() {
             .then(r => r.json())
             .then(data => {
                 if (data.success) {
-                    globalThis.location.href = '/dashboard?success=' + encodeURIComponent('Zone deleted successfully');
+                    globalThis.location.href = '/dashboard?success=' + encodeURIComponent(data.message);
                 } else {
                     alert('Error: ' + data.message);
                     confirmDeleteBtn.disabled = false;
@@ -0,0 +1,346 @@
+// Package xlsx writes spreadsheets in the Office Open XML format that Excel
+// and LibreOffice open: a workbook of one worksheet, written row by row so
+// that large exports are streamed rather than built in memory. Strings are
+// stored inline, so there is no shared string table to collect first.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// ContentType is the media type of the files Writer produces.
+	ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// MaxCellLength is the longest text a cell can hold; Writer cuts longer
+	// strings to it.
+	MaxCellLength = 32767
+
+	// maxSheetName is the longest worksheet name Excel accepts.
+	maxSheetName = 31
+
+	defaultSheetName = "Sheet1"
+
+	// styleHeader is the index of the bold cell style in stylesXML.
+	styleHeader = 1
+)
+
+// ErrClosed is returned when writing to a closed Writer.
+var ErrClosed = errors.New("xlsx: writer is closed")
+
+const contentTypesXML = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml"` +
+	` ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml"` +
+	` ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml"` +
+	` ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRelsXML = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1"` +
+	` Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"` +
+	` Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookRelsXML = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1"` +
+	` Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"` +
+	` Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2"` +
+	` Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"` +
+	` Target="styles.xml"/>` +
+	`</Relationships>`
+
+// stylesXML holds the default cell style and, at styleHeader, a bold one.
+const stylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill>` +
+	`<fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
+
+const workbookXML = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"` +
+	` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const (
+	sheetStartXML = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
+
+	// frozenHeaderXML keeps the first row in view while scrolling.
+	frozenHeaderXML = `<sheetViews><sheetView workbookViewId="0">` +
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+		`</sheetView></sheetViews>`
+
+	sheetEndXML = `</sheetData></worksheet>`
+)
+
+// Writer writes a workbook with one worksheet to an io.Writer. Rows are
+// written with WriteHeader and Write; Close finishes the file.
+type Writer struct {
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	name   string
+	row    int
+	closed bool
+}
+
+// NewWriter returns a Writer producing a workbook whose worksheet is named
+// sheetName. Characters Excel does not allow in sheet names are replaced.
+func NewWriter(w io.Writer, sheetName string) *Writer {
+	return &Writer{zw: zip.NewWriter(w), name: SheetName(sheetName)}
+}
+
+// WriteHeader writes a row of column titles in bold. When it is the first
+// row, the row stays visible while scrolling.
+func (w *Writer) WriteHeader(titles []string) error {
+	record := make([]any, len(titles))
+	for i, t := range titles {
+		record[i] = t
+	}
+
+	return w.writeRow(record, true)
+}
+
+// Write writes one row. Strings become text cells; integers, floats and
+// bools become number and boolean cells; nil leaves the cell empty. Other
+// values are written as text, formatted by fmt.Sprint.
+func (w *Writer) Write(record []any) error {
+	return w.writeRow(record, false)
+}
+
+// Close writes the end of the worksheet and the zip directory. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+
+	if err := w.start(false); err != nil {
+		return err
+	}
+
+	w.closed = true
+
+	if _, err := w.sheet.WriteString(sheetEndXML); err != nil {
+		return err
+	}
+
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+
+	return w.zw.Close()
+}
+
+// start writes the fixed parts of the workbook and opens the worksheet,
+// unless that happened already. frozen freezes the first row.
+func (w *Writer) start(frozen bool) error {
+	if w.sheet != nil {
+		return nil
+	}
+
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(w.name)); err != nil {
+		return err
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, name.String())},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	}
+
+	for _, p := range parts {
+		f, err := w.zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(f, p.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := w.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+
+	w.sheet = bufio.NewWriter(f)
+	w.sheet.WriteString(sheetStartXML)
+
+	if frozen {
+		w.sheet.WriteString(frozenHeaderXML)
+	}
+
+	_, err = w.sheet.WriteString("<sheetData>")
+
+	return err
+}
+
+// writeRow writes record as the next row, in bold when header is set.
+func (w *Writer) writeRow(record []any, header bool) error {
+	if w.closed {
+		return ErrClosed
+	}
+
+	if err := w.start(header && w.row == 0); err != nil {
+		return err
+	}
+
+	w.row++
+	row := strconv.Itoa(w.row)
+
+	// The bufio.Writer keeps its first error, which the last write returns.
+	b := w.sheet
+	b.WriteString(`<row r="` + row + `">`)
+
+	for i, v := range record {
+		if v == nil {
+			continue
+		}
+
+		b.WriteString(`<c r="` + ColumnName(i) + row + `"`)
+
+		if header {
+			b.WriteString(` s="` + strconv.Itoa(styleHeader) + `"`)
+		}
+
+		if num, ok := number(v); ok {
+			b.WriteString(`><v>` + num + `</v></c>`)
+			continue
+		}
+
+		if flag, ok := v.(bool); ok {
+			b.WriteString(` t="b"><v>` + boolValue(flag) + `</v></c>`)
+			continue
+		}
+
+		b.WriteString(` t="inlineStr"><is><t xml:space="preserve">`)
+
+		// Characters XML cannot hold, like most control characters, become
+		// U+FFFD.
+		if err := xml.EscapeText(b, []byte(truncate(text(v)))); err != nil {
+			return err
+		}
+
+		b.WriteString(`</t></is></c>`)
+	}
+
+	_, err := b.WriteString(`</row>`)
+
+	return err
+}
+
+// ColumnName returns the letters of the 0-based column i: A, B, ... Z, AA.
+func ColumnName(i int) string {
+	var name []byte
+
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = append([]byte{byte('A' + (i-1)%26)}, name...)
+	}
+
+	return string(name)
+}
+
+// SheetName returns name as a valid worksheet name: the characters []:*?/\
+// replaced by spaces, cut to 31 characters, and Sheet1 when empty.
+func SheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+
+		return r
+	}, name)
+
+	if utf8.RuneCountInString(name) > maxSheetName {
+		name = string([]rune(name)[:maxSheetName])
+	}
+
+	// Excel also rejects names starting or ending with an apostrophe.
+	name = strings.TrimSpace(strings.Trim(name, "'"))
+	if name == "" {
+		return defaultSheetName
+	}
+
+	return name
+}
+
+// number returns the cell value of an integer or float v.
+func number(v any) (string, bool) {
+	switch n := v.(type) {
+	case int:
+		return strconv.Itoa(n), true
+	case int32:
+		return strconv.FormatInt(int64(n), 10), true
+	case int64:
+		return strconv.FormatInt(n, 10), true
+	case uint:
+		return strconv.FormatUint(uint64(n), 10), true
+	case uint16:
+		return strconv.FormatUint(uint64(n), 10), true
+	case uint32:
+		return strconv.FormatUint(uint64(n), 10), true
+	case uint64:
+		return strconv.FormatUint(n, 10), true
+	case float32:
+		return strconv.FormatFloat(float64(n), 'g', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(n, 'g', -1, 64), true
+	}
+
+	return "", false
+}
+
+// boolValue returns the cell value of a boolean.
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
+
+// text returns the cell text of v.
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(v)
+}
+
+// truncate cuts s to at most MaxCellLength bytes on a character boundary.
+// Excel counts UTF-16 units, of which a string never has more than bytes.
+func truncate(s string) string {
+	if len(s) <= MaxCellLength {
+		return s
+	}
+
+	n := MaxCellLength
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
@@ -0,0 +1,148 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readPart returns the content of the part name of the workbook data.
+func readPart(t *testing.T, data []byte, name string) string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+
+	// Every part must be well-formed XML.
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		if _, err = dec.Token(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("%s is not well-formed: %v", name, err)
+		}
+	}
+
+	return string(content)
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf, "Zones: example/com")
+
+	if err := w.WriteHeader([]string{"name", "ttl", "disabled"}); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+
+	if err := w.Write([]any{"a<b> & \"c\"\x01", uint32(300), true}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := w.Write([]any{nil, 1.5, "x"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if err := w.Write([]any{"late"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		readPart(t, buf.Bytes(), name)
+	}
+
+	if wb := readPart(t, buf.Bytes(), "xl/workbook.xml"); !strings.Contains(wb, `<sheet name="Zones  example com"`) {
+		t.Errorf("workbook.xml = %s", wb)
+	}
+
+	sheet := readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+
+	for _, want := range []string{
+		`state="frozen"`,
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>`,
+		`<t xml:space="preserve">a&lt;b&gt; &amp; &#34;c&#34;` + "\uFFFD</t>",
+		`<c r="B2"><v>300</v></c>`,
+		`<c r="C2" t="b"><v>1</v></c>`,
+		`<row r="3"><c r="B3"><v>1.5</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet1.xml lacks %s:\n%s", want, sheet)
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf, "")
+	if err := w.Write([]any{"no header"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if sheet := readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml"); strings.Contains(sheet, "frozen") {
+		t.Errorf("sheet without header row has a frozen pane: %s", sheet)
+	}
+
+	if wb := readPart(t, buf.Bytes(), "xl/workbook.xml"); !strings.Contains(wb, `name="Sheet1"`) {
+		t.Errorf("workbook.xml = %s", wb)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := ColumnName(i); got != want {
+			t.Errorf("ColumnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := map[string]string{
+		"":                                     "Sheet1",
+		"'quoted'":                             "quoted",
+		"a[b]c":                                "a b c",
+		"records of a.very.long.zone.example.": "records of a.very.long.zone.exa",
+	}
+
+	for in, want := range tests {
+		if got := SheetName(in); got != want {
+			t.Errorf("SheetName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("a", MaxCellLength-1) + "é"
+	if got := truncate(long); got != strings.Repeat("a", MaxCellLength-1) {
+		t.Errorf("truncate cut inside a character: len %d", len(got))
+	}
+
+	if got := truncate("short"); got != "short" {
+		t.Errorf("truncate(short) = %q", got)
+	}
+}
@@ -0,0 +1,319 @@
+// Package zonecheck looks for common problems in the records of a zone: a
+// missing NS RRset at the apex, in-zone NS targets without address records,
+// CNAMEs next to other data or pointing nowhere, suspicious SOA serials and
+// duplicate records. Disabled records are ignored, as PowerDNS does not serve
+// them.
+package zonecheck
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+// Severity is how serious an issue is.
+type Severity string
+
+const (
+	// SeverityError marks problems that break resolution.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks likely mistakes.
+	SeverityWarning Severity = "warning"
+)
+
+// Check names, reported in Issue.Check.
+const (
+	CheckApexNS        = "apex-ns"
+	CheckGlue          = "glue"
+	CheckCNAMEConflict = "cname-conflict"
+	CheckDanglingCNAME = "dangling-cname"
+	CheckSOA           = "soa"
+	CheckDuplicate     = "duplicate"
+)
+
+// serialFuture is how far a SOA serial may run ahead of the current time
+// before it is reported.
+const serialFuture = 24 * time.Hour
+
+// minEpochSerial is the smallest serial taken for a Unix timestamp.
+const minEpochSerial = 1_000_000_000
+
+// cnameCompatible are the types allowed at a name next to a CNAME.
+var cnameCompatible = map[string]bool{"RRSIG": true, "NSEC": true, "NSEC3": true}
+
+// caseSensitive are the types whose content differs by case.
+var caseSensitive = map[string]bool{"TXT": true, "SPF": true, "LUA": true, "CAA": true, "URI": true}
+
+// Issue is a problem found in a zone.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Check    string   `json:"check"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Message  string   `json:"message"`
+}
+
+// Report is the result of checking a zone.
+type Report struct {
+	Issues   []Issue `json:"issues"`
+	Errors   int     `json:"errors"`
+	Warnings int     `json:"warnings"`
+}
+
+// zone is the checked zone with its enabled records by lower-cased name and
+// type.
+type zone struct {
+	name    string
+	records map[string]map[string][]string
+	issues  []Issue
+}
+
+// Check returns the issues of z, errors first, then by name and type. now is
+// the time SOA serials are compared against.
+func Check(z *pdnsapi.Zone, now time.Time) Report {
+	if z == nil {
+		return Report{}
+	}
+
+	c := &zone{
+		name:    strings.ToLower(pdnsapi.StringValue(z.Name)),
+		records: map[string]map[string][]string{},
+	}
+
+	for i := range z.RRsets {
+		c.add(&z.RRsets[i])
+	}
+
+	c.checkApexNS()
+	c.checkSOA(now)
+	c.checkGlue()
+	c.checkCNAMEs()
+
+	slices.SortStableFunc(c.issues, func(a, b Issue) int {
+		return cmp.Or(
+			cmp.Compare(rank(a.Severity), rank(b.Severity)),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Type, b.Type),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+
+	report := Report{Issues: c.issues}
+
+	for _, issue := range c.issues {
+		if issue.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+
+	return report
+}
+
+// rank orders severities, errors first.
+func rank(s Severity) int {
+	if s == SeverityError {
+		return 0
+	}
+
+	return 1
+}
+
+// add indexes the enabled records of rrSet and reports duplicates.
+func (c *zone) add(rrSet *pdnsapi.RRset) {
+	if rrSet.Name == nil || rrSet.Type == nil {
+		return
+	}
+
+	name := strings.ToLower(*rrSet.Name)
+	rrType := string(*rrSet.Type)
+	seen := map[string]bool{}
+
+	for _, rec := range rrSet.Records {
+		if pdnsapi.BoolValue(rec.Disabled) {
+			continue
+		}
+
+		content := strings.TrimSpace(pdnsapi.StringValue(rec.Content))
+
+		key := content
+		if !caseSensitive[rrType] {
+			key = strings.ToLower(content)
+		}
+
+		if seen[key] {
+			c.report(SeverityWarning, CheckDuplicate, name, rrType, "duplicate record "+content)
+			continue
+		}
+
+		seen[key] = true
+
+		if c.records[name] == nil {
+			c.records[name] = map[string][]string{}
+		}
+
+		c.records[name][rrType] = append(c.records[name][rrType], content)
+	}
+}
+
+func (c *zone) report(severity Severity, check, name, rrType, msg string) {
+	c.issues = append(c.issues, Issue{Severity: severity, Check: check, Name: name, Type: rrType, Message: msg})
+}
+
+// inZone reports whether name is the zone apex or below it.
+func (c *zone) inZone(name string) bool {
+	return name == c.name || strings.HasSuffix(name, "."+c.name)
+}
+
+// delegation returns the delegation point name lies at or below, or "".
+func (c *zone) delegation(name string) string {
+	for n := name; n != c.name && c.inZone(n); {
+		if len(c.records[n]["NS"]) > 0 {
+			return n
+		}
+
+		_, parent, ok := strings.Cut(n, ".")
+		if !ok {
+			break
+		}
+
+		n = parent
+	}
+
+	return ""
+}
+
+// exists reports whether there are records at name, directly or through a
+// wildcard.
+func (c *zone) exists(name string) bool {
+	if len(c.records[name]) > 0 {
+		return true
+	}
+
+	for n := name; c.inZone(n) && n != c.name; {
+		_, parent, _ := strings.Cut(n, ".")
+		if len(c.records["*."+parent]) > 0 {
+			return true
+		}
+
+		n = parent
+	}
+
+	return false
+}
+
+func (c *zone) checkApexNS() {
+	if len(c.records[c.name]["NS"]) == 0 {
+		c.report(SeverityError, CheckApexNS, c.name, "NS", "the zone apex has no NS records")
+	}
+}
+
+// checkSOA reports a missing SOA record and serials that are zero or lie in
+// the future, as date (YYYYMMDDnn) or Unix time serials.
+func (c *zone) checkSOA(now time.Time) {
+	soa := c.records[c.name]["SOA"]
+	if len(soa) == 0 {
+		c.report(SeverityError, CheckSOA, c.name, "SOA", "the zone has no SOA record")
+		return
+	}
+
+	fields := strings.Fields(soa[0])
+	if len(fields) != 7 {
+		c.report(SeverityError, CheckSOA, c.name, "SOA", "malformed SOA record "+soa[0])
+		return
+	}
+
+	serial, err := strconv.ParseUint(fields[2], 10, 32)
+	if err != nil {
+		c.report(SeverityError, CheckSOA, c.name, "SOA", "invalid SOA serial "+fields[2])
+		return
+	}
+
+	limit := now.Add(serialFuture)
+
+	switch date, isDate := serialDate(serial); {
+	case serial == 0:
+		c.report(SeverityWarning, CheckSOA, c.name, "SOA",
+			"the SOA serial is 0; secondaries may not pick up changes")
+	case isDate && date.After(limit):
+		c.report(SeverityWarning, CheckSOA, c.name, "SOA",
+			fmt.Sprintf("the SOA serial %d is dated %s, in the future", serial, date.Format(time.DateOnly)))
+	case !isDate && serial >= minEpochSerial && int64(serial) > limit.Unix():
+		c.report(SeverityWarning, CheckSOA, c.name, "SOA",
+			fmt.Sprintf("the SOA serial %d is a Unix time in the future", serial))
+	}
+}
+
+// serialDate returns the date of a YYYYMMDDnn serial.
+func serialDate(serial uint64) (time.Time, bool) {
+	s := strconv.FormatUint(serial, 10)
+	if len(s) != 10 {
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil || date.Year() < 1990 {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// checkGlue reports NS targets inside the zone without an A or AAAA record.
+// Targets at or below a delegation point need them as glue.
+func (c *zone) checkGlue() {
+	for name, types := range c.records {
+		for _, target := range types["NS"] {
+			target = strings.ToLower(target)
+			if !c.inZone(target) || len(c.records[target]["A"]) > 0 || len(c.records[target]["AAAA"]) > 0 {
+				continue
+			}
+
+			msg := "NS target " + target + " is in the zone but has no A or AAAA record"
+			if cut := c.delegation(target); cut != "" {
+				msg = "NS target " + target + " is below the delegation " + cut + " and needs glue A or AAAA records"
+			}
+
+			c.report(SeverityError, CheckGlue, name, "NS", msg)
+		}
+	}
+}
+
+// checkCNAMEs reports CNAMEs next to other data, CNAME RRsets with more than
+// one record and CNAMEs to names in the zone that do not exist.
+func (c *zone) checkCNAMEs() {
+	for name, types := range c.records {
+		targets := types["CNAME"]
+		if len(targets) == 0 {
+			continue
+		}
+
+		for rrType := range types {
+			if rrType != "CNAME" && !cnameCompatible[rrType] {
+				c.report(SeverityError, CheckCNAMEConflict, name, "CNAME",
+					"a CNAME cannot coexist with the "+rrType+" records at this name")
+			}
+		}
+
+		if len(targets) > 1 {
+			c.report(SeverityError, CheckCNAMEConflict, name, "CNAME", "a name can only have one CNAME record")
+		}
+
+		for _, target := range targets {
+			target = strings.ToLower(target)
+			if !c.inZone(target) || c.delegation(target) != "" || c.exists(target) {
+				continue
+			}
+
+			c.report(SeverityWarning, CheckDanglingCNAME, name, "CNAME",
+				"the CNAME target "+target+" does not exist in the zone")
+		}
+	}
+}
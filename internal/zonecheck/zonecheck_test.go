@@ -0,0 +1,117 @@
+package zonecheck
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+)
+
+func rrSet(name string, rrType pdnsapi.RRType, contents ...string) pdnsapi.RRset {
+	records := make([]pdnsapi.Record, 0, len(contents))
+	for _, content := range contents {
+		records = append(records, pdnsapi.Record{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)})
+	}
+
+	return pdnsapi.RRset{Name: pdnsapi.String(name), Type: pdnsapi.RRTypePtr(rrType), Records: records}
+}
+
+func testZone(rrSets ...pdnsapi.RRset) *pdnsapi.Zone {
+	base := []pdnsapi.RRset{
+		rrSet("example.com.", pdnsapi.RRTypeSOA, "ns1.example.com. hostmaster.example.com. 2026101701 10800 3600 604800 3600"),
+		rrSet("example.com.", pdnsapi.RRTypeNS, "ns1.example.com.", "ns.example.net."),
+		rrSet("ns1.example.com.", pdnsapi.RRTypeA, "192.0.2.53"),
+	}
+
+	return &pdnsapi.Zone{Name: pdnsapi.String("example.com."), RRsets: append(base, rrSets...)}
+}
+
+var now = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+func checks(issues []Issue) []string {
+	names := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		names = append(names, string(issue.Severity)+" "+issue.Check+" "+issue.Name)
+	}
+
+	return names
+}
+
+func TestCheckHealthyZone(t *testing.T) {
+	z := testZone(
+		rrSet("www.example.com.", pdnsapi.RRTypeCNAME, "web.example.com."),
+		rrSet("web.example.com.", pdnsapi.RRTypeA, "192.0.2.1"),
+		rrSet("app.example.com.", pdnsapi.RRTypeCNAME, "host.wild.example.com."),
+		rrSet("*.wild.example.com.", pdnsapi.RRTypeA, "192.0.2.2"),
+		rrSet("cdn.example.com.", pdnsapi.RRTypeCNAME, "example.cdn.example.net."),
+		rrSet("sub.example.com.", pdnsapi.RRTypeNS, "ns.sub.example.com."),
+		rrSet("ns.sub.example.com.", pdnsapi.RRTypeAAAA, "2001:db8::53"),
+		rrSet("x.example.com.", pdnsapi.RRTypeCNAME, "host.sub.example.com."),
+	)
+
+	if report := Check(z, now); len(report.Issues) != 0 {
+		t.Fatalf("Check() = %v, want no issues", checks(report.Issues))
+	}
+}
+
+func TestCheckProblems(t *testing.T) {
+	dup := rrSet("mail.example.com.", pdnsapi.RRTypeA, "192.0.2.25", "192.0.2.25", "192.0.2.26")
+	disabled := rrSet("off.example.com.", pdnsapi.RRTypeCNAME, "gone.example.com.")
+	disabled.Records[0].Disabled = pdnsapi.Bool(true)
+
+	z := &pdnsapi.Zone{Name: pdnsapi.String("example.com."), RRsets: []pdnsapi.RRset{
+		rrSet("example.com.", pdnsapi.RRTypeSOA, "ns1.example.com. hostmaster.example.com. 2027010101 10800 3600 604800 3600"),
+		rrSet("www.example.com.", pdnsapi.RRTypeCNAME, "gone.example.com."),
+		rrSet("www.example.com.", pdnsapi.RRTypeTXT, `"site"`),
+		rrSet("sub.example.com.", pdnsapi.RRTypeNS, "ns.sub.example.com.", "ns2.example.com."),
+		dup,
+		disabled,
+	}}
+
+	want := []string{
+		"error apex-ns example.com.",
+		"error glue sub.example.com.",
+		"error glue sub.example.com.",
+		"error cname-conflict www.example.com.",
+		"warning soa example.com.",
+		"warning duplicate mail.example.com.",
+		"warning dangling-cname www.example.com.",
+	}
+
+	report := Check(z, now)
+	if got := checks(report.Issues); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Check() = %v, want %v", got, want)
+	}
+
+	if report.Errors != 4 || report.Warnings != 3 {
+		t.Errorf("Check() counted %d errors and %d warnings, want 4 and 3", report.Errors, report.Warnings)
+	}
+}
+
+func TestCheckSOASerial(t *testing.T) {
+	tests := []struct {
+		serial string
+		want   bool
+	}{
+		{"2026101701", false},
+		{"2026101801", false},
+		{"2026102001", true},
+		{"1", false},
+		{"0", true},
+		{"1792238400", false},
+		{"1892238400", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.serial, func(t *testing.T) {
+			z := testZone()
+			z.RRsets[0] = rrSet("example.com.", pdnsapi.RRTypeSOA,
+				"ns1.example.com. hostmaster.example.com. "+tc.serial+" 10800 3600 604800 3600")
+
+			if got := len(Check(z, now).Issues) > 0; got != tc.want {
+				t.Errorf("serial %s reported = %v, want %v", tc.serial, got, tc.want)
+			}
+		})
+	}
+}
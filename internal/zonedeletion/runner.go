@@ -0,0 +1,88 @@
+package zonedeletion
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+)
+
+const (
+	// checkInterval is how often due deletions are looked for. Zones are
+	// purged up to this late.
+	checkInterval = time.Minute
+
+	// batchSize is the number of due deletions purged per check.
+	batchSize = 50
+
+	// purgeTimeout bounds the PowerDNS request of one purge.
+	purgeTimeout = 30 * time.Second
+)
+
+// Runner purges the zones whose grace period has passed.
+type Runner struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewRunner returns a Runner for the deletions in db.
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{db: db, now: time.Now}
+}
+
+// Run purges due zones every checkInterval until ctx is canceled.
+func (r *Runner) Run(ctx context.Context) {
+	jobs.Scheduled(jobs.ZoneDeletions, checkInterval)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = jobs.Run(jobs.ZoneDeletions, func() error { return r.runOnce(ctx) })
+		}
+	}
+}
+
+// runOnce purges the due zones. Each deletion is claimed first, so several
+// instances sharing a database purge a zone only once. The returned error
+// joins the errors of the purges that failed.
+func (r *Runner) runOnce(ctx context.Context) error {
+	deletions, err := due(r.db, r.now(), batchSize)
+	if err != nil {
+		log.Error().Err(err).Msg("zonedeletion: failed to load due deletions")
+		return err
+	}
+
+	var errs []error
+
+	for i := range deletions {
+		d := &deletions[i]
+
+		ok, err := claim(r.db, d.ID, d.Status)
+		if err != nil {
+			log.Error().Err(err).Uint64("deletion_id", d.ID).Msg("zonedeletion: failed to claim deletion")
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err = purge(ctx, r.db, d, r.now()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
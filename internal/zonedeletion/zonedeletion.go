@@ -0,0 +1,268 @@
+// Package zonedeletion implements the soft delete of zones. A deleted zone
+// stays in PowerDNS, frozen, until its grace period has passed; until then it
+// can be restored. A background Runner purges the zones that are due.
+package zonedeletion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+var (
+	// ErrNotOpen is returned when restoring or purging a deletion that was
+	// already purged or restored.
+	ErrNotOpen = errors.New("zone deletion is no longer open")
+	// ErrAlreadyDeleted is returned when deleting a zone that is already
+	// waiting for its purge.
+	ErrAlreadyDeleted = errors.New("zone is already deleted")
+)
+
+// Pending returns the open deletion of zoneName, or nil if the zone is not
+// deleted. Open deletions are pending or failed to purge.
+func Pending(db *gorm.DB, zoneName string) (*models.ZoneDeletion, error) {
+	var deletion models.ZoneDeletion
+
+	err := db.Preload("DeletedBy").
+		Where("zone_name = ? AND status IN ?", zoneName, openStatuses()).
+		Order("id DESC").
+		First(&deletion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &deletion, nil
+}
+
+// Open returns all open deletions, earliest purge first.
+func Open(db *gorm.DB) ([]models.ZoneDeletion, error) {
+	var deletions []models.ZoneDeletion
+
+	err := db.Preload("DeletedBy").
+		Where("status IN ?", openStatuses()).
+		Order("purge_at, id").
+		Find(&deletions).Error
+
+	return deletions, err
+}
+
+// Schedule records the soft delete of zoneName by userID. The zone is purged
+// from PowerDNS at purgeAt.
+func Schedule(
+	db *gorm.DB, zoneName string, snapshot *activitylog.ZoneSnapshot, purgeAt time.Time, userID *uint64,
+) (*models.ZoneDeletion, error) {
+	open, err := Pending(db, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	if open != nil {
+		return nil, ErrAlreadyDeleted
+	}
+
+	deletion := &models.ZoneDeletion{
+		ZoneName:    zoneName,
+		PurgeAt:     purgeAt,
+		Status:      models.ZoneDeletionPending,
+		DeletedByID: userID,
+	}
+
+	if snapshot != nil {
+		b, err := json.Marshal(snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		deletion.Snapshot = string(b)
+	}
+
+	if err := db.Create(deletion).Error; err != nil {
+		return nil, err
+	}
+
+	return deletion, nil
+}
+
+// Get returns the deletion id.
+func Get(db *gorm.DB, id uint64) (*models.ZoneDeletion, error) {
+	var deletion models.ZoneDeletion
+	if err := db.Preload("DeletedBy").First(&deletion, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &deletion, nil
+}
+
+// Restore reverts the open deletion id; the zone in PowerDNS was never
+// touched, so it simply stops being frozen.
+func Restore(db *gorm.DB, id uint64, now time.Time) (*models.ZoneDeletion, error) {
+	deletion, err := Get(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := db.Model(&models.ZoneDeletion{}).
+		Where("id = ? AND status IN ?", id, openStatuses()).
+		Updates(map[string]any{"status": models.ZoneDeletionRestored, "closed_at": now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, ErrNotOpen
+	}
+
+	deletion.Status = models.ZoneDeletionRestored
+	deletion.ClosedAt = &now
+
+	return deletion, nil
+}
+
+// PurgeNow purges the open deletion id right away instead of waiting for its
+// grace period, e.g. to retry a failed purge.
+func PurgeNow(ctx context.Context, db *gorm.DB, id uint64, now time.Time) (*models.ZoneDeletion, error) {
+	deletion, err := Get(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := claim(db, id, openStatuses()...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrNotOpen
+	}
+
+	return deletion, purge(ctx, db, deletion, now)
+}
+
+// Snapshot decodes the zone snapshot stored with deletion.
+func Snapshot(deletion *models.ZoneDeletion) (*activitylog.ZoneSnapshot, error) {
+	if deletion.Snapshot == "" {
+		return nil, nil
+	}
+
+	var snap activitylog.ZoneSnapshot
+	if err := json.Unmarshal([]byte(deletion.Snapshot), &snap); err != nil {
+		return nil, err
+	}
+
+	return &snap, nil
+}
+
+// purge removes the zone of a claimed deletion from PowerDNS, stores the
+// outcome and records it in the activity log on behalf of the user who
+// deleted the zone. The entry carries the snapshot, so the deletion can still
+// be undone from the activity log afterwards.
+func purge(ctx context.Context, db *gorm.DB, d *models.ZoneDeletion, now time.Time) error {
+	purgeCtx, cancel := context.WithTimeout(ctx, purgeTimeout)
+	defer cancel()
+
+	purgeErr := deleteZone(purgeCtx, d.ZoneName)
+
+	updates := map[string]any{"status": models.ZoneDeletionPurged, "closed_at": now, "error": ""}
+
+	if purgeErr != nil {
+		updates["status"] = models.ZoneDeletionFailed
+		updates["error"] = purgeErr.Error()
+
+		log.Error().Err(purgeErr).Uint64("deletion_id", d.ID).Str("zone_name", d.ZoneName).
+			Msg("zonedeletion: failed to purge zone")
+	} else {
+		log.Info().Uint64("deletion_id", d.ID).Str("zone_name", d.ZoneName).Msg("zonedeletion: zone purged")
+
+		zoneindex.Default.Remove(d.ZoneName)
+	}
+
+	if err := db.Model(d).Updates(updates).Error; err != nil {
+		log.Error().Err(err).Uint64("deletion_id", d.ID).Msg("zonedeletion: failed to store purge result")
+	}
+
+	if purgeErr != nil {
+		return purgeErr
+	}
+
+	username := "system"
+	if d.DeletedBy != nil {
+		username = d.DeletedBy.Username
+	}
+
+	var details any
+	if d.Snapshot != "" {
+		details = json.RawMessage(d.Snapshot)
+	}
+
+	activitylog.Record(&activitylog.Entry{
+		DB:           db,
+		UserID:       d.DeletedByID,
+		Username:     username,
+		Action:       activitylog.ActionZoneDeleted,
+		ResourceType: activitylog.ResourceTypeZone,
+		ResourceName: d.ZoneName,
+		Details:      details,
+	})
+
+	return nil
+}
+
+// deleteZone deletes zoneName from PowerDNS. A zone that is already gone
+// counts as deleted.
+func deleteZone(ctx context.Context, zoneName string) error {
+	if powerdns.Engine.Client == nil {
+		return powerdns.ErrClientNotInitialized
+	}
+
+	err := powerdns.Engine.Zones.Delete(ctx, zoneName)
+
+	var pdnsErr *pdnsapi.Error
+	if errors.As(err, &pdnsErr) && pdnsErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// due returns up to limit pending deletions whose grace period has passed,
+// earliest first.
+func due(db *gorm.DB, now time.Time, limit int) ([]models.ZoneDeletion, error) {
+	var deletions []models.ZoneDeletion
+
+	err := db.Preload("DeletedBy").
+		Where("status = ? AND purge_at <= ?", models.ZoneDeletionPending, now).
+		Order("purge_at, id").
+		Limit(limit).
+		Find(&deletions).Error
+
+	return deletions, err
+}
+
+// claim marks a deletion in one of the from statuses as running. It reports
+// false when another instance claimed it or a user restored it first.
+func claim(db *gorm.DB, id uint64, from ...models.ZoneDeletionStatus) (bool, error) {
+	res := db.Model(&models.ZoneDeletion{}).
+		Where("id = ? AND status IN ?", id, from).
+		Update("status", models.ZoneDeletionRunning)
+
+	return res.RowsAffected == 1, res.Error
+}
+
+func openStatuses() []models.ZoneDeletionStatus {
+	return []models.ZoneDeletionStatus{models.ZoneDeletionPending, models.ZoneDeletionFailed}
+}
@@ -0,0 +1,129 @@
+package zonedeletion
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.ZoneDeletion{}, &models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func TestScheduleAndRestore(t *testing.T) {
+	db := newTestDB(t)
+	snap := &activitylog.ZoneSnapshot{Kind: "Native", RRsets: []activitylog.RRsetSnapshot{
+		{Name: "www.example.com.", Type: "A", TTL: 300, Records: []string{"192.0.2.1"}},
+	}}
+
+	d, err := Schedule(db, "example.com.", snap, now.Add(time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	if _, err = Schedule(db, "example.com.", nil, now.Add(time.Hour), nil); !errors.Is(err, ErrAlreadyDeleted) {
+		t.Errorf("second Schedule() error = %v, want ErrAlreadyDeleted", err)
+	}
+
+	pending, err := Pending(db, "example.com.")
+	if err != nil || pending == nil || pending.ID != d.ID {
+		t.Fatalf("Pending() = %+v, %v", pending, err)
+	}
+
+	got, err := Snapshot(pending)
+	if err != nil || got == nil || len(got.RRsets) != 1 || got.RRsets[0].Records[0] != "192.0.2.1" {
+		t.Errorf("Snapshot() = %+v, %v", got, err)
+	}
+
+	if _, err = Restore(db, d.ID, now); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	if _, err = Restore(db, d.ID, now); !errors.Is(err, ErrNotOpen) {
+		t.Errorf("second Restore() error = %v, want ErrNotOpen", err)
+	}
+
+	if pending, _ = Pending(db, "example.com."); pending != nil {
+		t.Errorf("Pending() after restore = %+v, want nil", pending)
+	}
+
+	if _, err = Schedule(db, "example.com.", nil, now.Add(time.Hour), nil); err != nil {
+		t.Errorf("Schedule() after restore error = %v", err)
+	}
+}
+
+func TestRunOnceRecordsFailure(t *testing.T) {
+	db := newTestDB(t)
+
+	dueDeletion, err := Schedule(db, "due.example.", nil, now.Add(-time.Minute), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	future, err := Schedule(db, "future.example.", nil, now.Add(time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := powerdns.Engine.Client
+	powerdns.Engine.Client = nil
+
+	t.Cleanup(func() { powerdns.Engine.Client = prev })
+
+	r := &Runner{db: db, now: func() time.Time { return now }}
+
+	if err = r.runOnce(context.Background()); !errors.Is(err, powerdns.ErrClientNotInitialized) {
+		t.Fatalf("runOnce() error = %v, want ErrClientNotInitialized", err)
+	}
+
+	got, _ := Get(db, dueDeletion.ID)
+	if got.Status != models.ZoneDeletionFailed || got.Error == "" || got.ClosedAt == nil {
+		t.Errorf("due deletion = %+v, want failed", got)
+	}
+
+	// A failed purge keeps the zone deleted and is not retried on its own.
+	if pending, _ := Pending(db, "due.example."); pending == nil {
+		t.Error("Pending() after failed purge = nil, want the failed deletion")
+	}
+
+	if err = r.runOnce(context.Background()); err != nil {
+		t.Errorf("second runOnce() error = %v, want failed deletions skipped", err)
+	}
+
+	if got, _ = Get(db, future.ID); got.Status != models.ZoneDeletionPending {
+		t.Errorf("future deletion status = %s, want pending", got.Status)
+	}
+
+	if _, err = PurgeNow(context.Background(), db, dueDeletion.ID, now); !errors.Is(err, powerdns.ErrClientNotInitialized) {
+		t.Errorf("PurgeNow() error = %v, want the purge retried", err)
+	}
+
+	var entries int64
+
+	db.Model(&models.ActivityLog{}).Where("action = ?", activitylog.ActionZoneDeleted).Count(&entries)
+
+	if entries != 0 {
+		t.Errorf("activity entries = %d, want none for failed purges", entries)
+	}
+}
@@ -0,0 +1,297 @@
+// Package zoneindex keeps an in-memory index of the zones known to PowerDNS so
+// that zone lists (dashboard, zone pickers) do not fetch the full zone list on
+// every request.
+//
+// The index is refreshed in the background at a fixed interval and when it is
+// older than that interval on access. Handlers that create, change or delete
+// a zone update the entry of that zone right away with RefreshZone or Remove.
+// With Follow, those updates are published on the event bus and replayed on
+// the other replicas.
+package zoneindex
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+// DefaultInterval is the refresh interval used until Configure is called.
+const DefaultInterval = time.Minute
+
+// remoteRefreshTimeout bounds the fetch of a zone changed on another replica.
+const remoteRefreshTimeout = 10 * time.Second
+
+// Source fetches zones from PowerDNS.
+type Source interface {
+	List(ctx context.Context) ([]pdnsapi.Zone, error)
+	Get(ctx context.Context, name string) (*pdnsapi.Zone, error)
+}
+
+// engineSource reads from the shared PowerDNS engine, which is replaced when
+// the server settings change.
+type engineSource struct{}
+
+func (engineSource) List(ctx context.Context) ([]pdnsapi.Zone, error) {
+	if powerdns.Engine.Client == nil {
+		return nil, powerdns.ErrClientNotInitialized
+	}
+
+	return powerdns.Engine.Zones.List(ctx)
+}
+
+func (engineSource) Get(ctx context.Context, name string) (*pdnsapi.Zone, error) {
+	if powerdns.Engine.Client == nil {
+		return nil, powerdns.ErrClientNotInitialized
+	}
+
+	return powerdns.Engine.Zones.Get(ctx, name)
+}
+
+// Index is a cached list of zones without their RRsets.
+type Index struct {
+	source Source
+	now    func() time.Time
+
+	mu       sync.RWMutex
+	interval time.Duration
+	zones    map[string]pdnsapi.Zone
+	loaded   time.Time
+	bus      *eventbus.Bus
+
+	// refreshMu serializes full refreshes so concurrent requests on a stale
+	// index wait for a single List call.
+	refreshMu sync.Mutex
+}
+
+// Default is the index shared by the web handlers.
+var Default = New(engineSource{}, DefaultInterval)
+
+// New returns an empty index reading from source that is considered stale
+// after interval.
+func New(source Source, interval time.Duration) *Index {
+	return &Index{source: source, interval: interval, now: time.Now}
+}
+
+// Configure sets the refresh interval.
+func (i *Index) Configure(interval time.Duration) {
+	i.mu.Lock()
+	i.interval = interval
+	i.mu.Unlock()
+}
+
+// List returns all zones sorted by name, refreshing the index first when it is
+// empty or stale. When a refresh fails and an older index exists, the older
+// index is returned.
+func (i *Index) List(ctx context.Context) ([]pdnsapi.Zone, error) {
+	if !i.fresh() {
+		if err := i.refreshIfStale(ctx); err != nil {
+			i.mu.RLock()
+			loaded := i.zones != nil
+			i.mu.RUnlock()
+
+			if !loaded {
+				return nil, err
+			}
+
+			log.Warn().Err(err).Msg("zoneindex: refresh failed; serving stale zone list")
+		}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	zones := make([]pdnsapi.Zone, 0, len(i.zones))
+	for name := range i.zones {
+		zones = append(zones, i.zones[name])
+	}
+
+	sort.Slice(zones, func(a, b int) bool {
+		return *zones[a].Name < *zones[b].Name
+	})
+
+	return zones, nil
+}
+
+// Refresh replaces the index with the current zone list.
+func (i *Index) Refresh(ctx context.Context) error {
+	i.refreshMu.Lock()
+	defer i.refreshMu.Unlock()
+
+	return i.refresh(ctx)
+}
+
+func (i *Index) refreshIfStale(ctx context.Context) error {
+	i.refreshMu.Lock()
+	defer i.refreshMu.Unlock()
+
+	// Another request may have refreshed the index while this one waited.
+	if i.fresh() {
+		return nil
+	}
+
+	return i.refresh(ctx)
+}
+
+func (i *Index) refresh(ctx context.Context) error {
+	list, err := i.source.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	zones := make(map[string]pdnsapi.Zone, len(list))
+
+	for n := range list {
+		if list[n].Name == nil {
+			continue
+		}
+
+		zone := list[n]
+		zone.RRsets = nil
+		zones[key(*zone.Name)] = zone
+	}
+
+	i.mu.Lock()
+	i.zones = zones
+	i.loaded = i.now()
+	i.mu.Unlock()
+
+	return nil
+}
+
+// RefreshZone updates the entry of a single zone after it was created or
+// changed. A zone PowerDNS no longer knows is removed. When the zone cannot be
+// fetched the whole index is marked stale instead.
+func (i *Index) RefreshZone(ctx context.Context, name string) {
+	i.refreshZone(ctx, name)
+	i.publish(name)
+}
+
+func (i *Index) refreshZone(ctx context.Context, name string) {
+	zone, err := i.source.Get(ctx, name)
+	if err != nil {
+		var apiErr *pdnsapi.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			i.remove(name)
+			return
+		}
+
+		log.Debug().Err(err).Str("zone_name", name).Msg("zoneindex: failed to refresh zone")
+		i.Invalidate()
+
+		return
+	}
+
+	if zone == nil || zone.Name == nil {
+		return
+	}
+
+	entry := *zone
+	entry.RRsets = nil
+
+	i.mu.Lock()
+	if i.zones != nil {
+		i.zones[key(*entry.Name)] = entry
+	}
+	i.mu.Unlock()
+}
+
+// Remove drops a deleted zone from the index.
+func (i *Index) Remove(name string) {
+	i.remove(name)
+	i.publish(name)
+}
+
+func (i *Index) remove(name string) {
+	i.mu.Lock()
+	delete(i.zones, key(name))
+	i.mu.Unlock()
+}
+
+// Invalidate marks the index stale so the next List refreshes it, e.g. after
+// the PowerDNS server settings changed.
+func (i *Index) Invalidate() {
+	i.mu.Lock()
+	i.loaded = time.Time{}
+	i.mu.Unlock()
+}
+
+// Follow publishes the zone updates of this index on bus and applies the
+// updates published by other replicas.
+func (i *Index) Follow(bus *eventbus.Bus) {
+	i.mu.Lock()
+	i.bus = bus
+	i.mu.Unlock()
+
+	bus.Subscribe(eventbus.TopicZone, i.applyRemote)
+}
+
+// applyRemote refreshes a zone changed on another replica. Local events are
+// ignored; the index already reflects them.
+func (i *Index) applyRemote(e eventbus.Event) {
+	if !e.Remote || e.Key == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteRefreshTimeout)
+	defer cancel()
+
+	i.refreshZone(ctx, e.Key)
+}
+
+// Run refreshes the index at the configured interval until ctx is canceled.
+// Errors are logged; the previous index is kept.
+func (i *Index) Run(ctx context.Context) {
+	i.mu.RLock()
+	interval := i.interval
+	i.mu.RUnlock()
+
+	jobs.Scheduled(jobs.ZoneIndexRefresh, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := jobs.Run(jobs.ZoneIndexRefresh, func() error { return i.Refresh(ctx) }); err != nil {
+				log.Debug().Err(err).Msg("zoneindex: background refresh failed")
+			}
+		}
+	}
+}
+
+// publish announces a change of zone name to the other replicas.
+func (i *Index) publish(name string) {
+	i.mu.RLock()
+	bus := i.bus
+	i.mu.RUnlock()
+
+	if bus != nil {
+		bus.Publish(eventbus.TopicZone, key(name))
+	}
+}
+
+func (i *Index) fresh() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.zones != nil && !i.loaded.IsZero() && i.now().Sub(i.loaded) < i.interval
+}
+
+// key returns the canonical map key of a zone name.
+func key(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
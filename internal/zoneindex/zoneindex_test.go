@@ -0,0 +1,194 @@
+package zoneindex
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+type fakeSource struct {
+	zones map[string]uint32
+	lists int
+	err   error
+}
+
+func (f *fakeSource) List(context.Context) ([]pdnsapi.Zone, error) {
+	f.lists++
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	zones := make([]pdnsapi.Zone, 0, len(f.zones))
+	for name, serial := range f.zones {
+		zones = append(zones, f.zone(name, serial))
+	}
+
+	return zones, nil
+}
+
+func (f *fakeSource) Get(_ context.Context, name string) (*pdnsapi.Zone, error) {
+	serial, ok := f.zones[name]
+	if !ok {
+		return nil, &pdnsapi.Error{StatusCode: http.StatusNotFound, Message: "Not Found"}
+	}
+
+	zone := f.zone(name, serial)
+
+	return &zone, nil
+}
+
+func (f *fakeSource) zone(name string, serial uint32) pdnsapi.Zone {
+	rrType := pdnsapi.RRTypeA
+
+	return pdnsapi.Zone{
+		Name:   pdnsapi.String(name),
+		Serial: pdnsapi.Uint32(serial),
+		RRsets: []pdnsapi.RRset{{Name: pdnsapi.String(name), Type: &rrType}},
+	}
+}
+
+func names(zones []pdnsapi.Zone) []string {
+	result := make([]string, len(zones))
+	for i := range zones {
+		result[i] = *zones[i].Name
+	}
+
+	return result
+}
+
+func TestList_CachesUntilStale(t *testing.T) {
+	src := &fakeSource{zones: map[string]uint32{"b.example.": 1, "a.example.": 1}}
+	now := time.Unix(1000, 0)
+	idx := New(src, time.Minute)
+	idx.now = func() time.Time { return now }
+
+	zones, err := idx.List(t.Context())
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+
+	if got := names(zones); len(got) != 2 || got[0] != "a.example." || got[1] != "b.example." {
+		t.Errorf("List() = %v, want sorted zones", got)
+	}
+
+	if zones[0].RRsets != nil {
+		t.Error("RRsets should not be kept in the index")
+	}
+
+	src.zones["c.example."] = 1
+
+	if zones, _ = idx.List(t.Context()); len(zones) != 2 || src.lists != 1 {
+		t.Errorf("fresh index refetched: %d zones, %d list calls", len(zones), src.lists)
+	}
+
+	now = now.Add(time.Minute)
+
+	if zones, _ = idx.List(t.Context()); len(zones) != 3 || src.lists != 2 {
+		t.Errorf("stale index not refreshed: %d zones, %d list calls", len(zones), src.lists)
+	}
+}
+
+func TestList_ServesStaleIndexOnError(t *testing.T) {
+	src := &fakeSource{err: errors.New("unreachable")}
+	idx := New(src, time.Minute)
+
+	if _, err := idx.List(t.Context()); err == nil {
+		t.Fatal("expected an error without an index")
+	}
+
+	src.err = nil
+	src.zones = map[string]uint32{"a.example.": 1}
+
+	if err := idx.Refresh(t.Context()); err != nil {
+		t.Fatalf("Refresh() error: %v", err)
+	}
+
+	idx.Invalidate()
+
+	src.err = errors.New("unreachable")
+
+	zones, err := idx.List(t.Context())
+	if err != nil || len(zones) != 1 {
+		t.Errorf("List() = %v, %v; want the stale index", names(zones), err)
+	}
+}
+
+func TestRefreshZoneAndRemove(t *testing.T) {
+	src := &fakeSource{zones: map[string]uint32{"a.example.": 1}}
+	idx := New(src, time.Hour)
+
+	if err := idx.Refresh(t.Context()); err != nil {
+		t.Fatalf("Refresh() error: %v", err)
+	}
+
+	src.zones["a.example."] = 2
+	src.zones["new.example."] = 1
+
+	idx.RefreshZone(t.Context(), "a.example.")
+	idx.RefreshZone(t.Context(), "new.example.")
+
+	zones, _ := idx.List(t.Context())
+	if len(zones) != 2 || *zones[0].Serial != 2 || *zones[1].Name != "new.example." {
+		t.Errorf("after RefreshZone: %v", names(zones))
+	}
+
+	delete(src.zones, "a.example.")
+	idx.RefreshZone(t.Context(), "a.example.")
+	idx.Remove("NEW.example.")
+
+	if zones, _ = idx.List(t.Context()); len(zones) != 0 {
+		t.Errorf("after removal: %v", names(zones))
+	}
+
+	if src.lists != 1 {
+		t.Errorf("incremental updates triggered %d list calls, want 1", src.lists)
+	}
+}
+
+func TestFollowPublishesAndAppliesRemoteChanges(t *testing.T) {
+	src := &fakeSource{zones: map[string]uint32{"a.example.": 1}}
+	idx := New(src, time.Hour)
+
+	if _, err := idx.List(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := eventbus.New()
+
+	var published []eventbus.Event
+
+	bus.Subscribe(eventbus.TopicZone, func(e eventbus.Event) { published = append(published, e) })
+	idx.Follow(bus)
+
+	idx.Remove("a.example.")
+
+	if len(published) != 1 || published[0].Key != "a.example." {
+		t.Fatalf("published %+v, want the removed zone", published)
+	}
+
+	// A zone created on another replica is fetched without republishing.
+	src.zones["b.example."] = 2
+	published = nil
+
+	idx.applyRemote(eventbus.Event{Topic: eventbus.TopicZone, Key: "b.example.", Remote: true})
+
+	zones, err := idx.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(zones); len(got) != 1 || got[0] != "b.example." {
+		t.Errorf("List() = %v, want [b.example.]", got)
+	}
+
+	if len(published) != 0 {
+		t.Errorf("remote change was republished: %+v", published)
+	}
+}
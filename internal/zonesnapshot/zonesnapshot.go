@@ -0,0 +1,398 @@
+// Package zonesnapshot saves the RRsets of a zone before it is deleted or
+// changed in bulk, and restores zones from these snapshots. Unlike the
+// scheduled snapshots of internal/snapshot, which archive every zone in
+// BIND format, zone snapshots are kept in the database per zone: the newest
+// ones of each zone, as configured by [zonesnapshots].
+package zonesnapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+// Reasons a snapshot was taken for.
+const (
+	// ReasonDelete is a snapshot taken before the zone was deleted.
+	ReasonDelete = "delete"
+	// ReasonImport is a snapshot taken before a CSV import.
+	ReasonImport = "import"
+	// ReasonPatch is a snapshot taken before a change of many RRsets.
+	ReasonPatch = "patch"
+	// ReasonRestore is a snapshot taken before the zone was restored from
+	// another one, so that the restore can be reverted.
+	ReasonRestore = "restore"
+	// ReasonManual is a snapshot a user took.
+	ReasonManual = "manual"
+)
+
+// listLimit bounds the snapshots listed across all zones.
+const listLimit = 500
+
+// managedTypes are never restored or deleted by a restore: PowerDNS keeps
+// the SOA serial current and signs the zone itself.
+var managedTypes = map[string]bool{
+	"SOA":        true,
+	"RRSIG":      true,
+	"NSEC":       true,
+	"NSEC3":      true,
+	"NSEC3PARAM": true,
+	"DNSKEY":     true,
+}
+
+// restorable reports whether RRsets of rrType are restored into an existing
+// zone. PowerDNS-internal types such as TYPE65534 are not.
+func restorable(rrType string) bool {
+	return !managedTypes[rrType] && !strings.HasPrefix(rrType, "TYPE")
+}
+
+// PatchReason returns the reason to snapshot a zone before changing n of
+// its RRsets, by a CSV import when imported, or "" when no snapshot is due.
+func PatchReason(cfg config.ZoneSnapshots, n int, imported bool) string {
+	switch {
+	case !cfg.Enabled() || n == 0:
+		return ""
+	case imported:
+		return ReasonImport
+	case cfg.LargePatch > 0 && n >= cfg.LargePatch:
+		return ReasonPatch
+	default:
+		return ""
+	}
+}
+
+// FromZone captures the content of zone. Comments and whether records are
+// disabled are not part of it.
+func FromZone(zone *pdnsapi.Zone) *activitylog.ZoneSnapshot {
+	snapshot := &activitylog.ZoneSnapshot{}
+
+	if zone.Kind != nil {
+		snapshot.Kind = string(*zone.Kind)
+	}
+
+	snapshot.SOAEditAPI = pdnsapi.StringValue(zone.SOAEditAPI)
+	if snapshot.SOAEditAPI == "" {
+		snapshot.SOAEditAPI = "DEFAULT"
+	}
+
+	snapshot.Masters = zone.Masters
+
+	for _, rr := range zone.RRsets {
+		if rr.Name == nil || rr.Type == nil || rr.TTL == nil {
+			continue
+		}
+
+		var records []string
+
+		for _, r := range rr.Records {
+			if r.Content != nil {
+				records = append(records, *r.Content)
+			}
+		}
+
+		snapshot.RRsets = append(snapshot.RRsets, activitylog.RRsetSnapshot{
+			Name:    *rr.Name,
+			Type:    string(*rr.Type),
+			TTL:     *rr.TTL,
+			Records: records,
+		})
+	}
+
+	return snapshot
+}
+
+// Save stores snapshot, the content of zoneName before the operation of
+// reason by userID, and deletes the oldest snapshots of the zone beyond
+// cfg.Keep. It returns nil without storing anything when zone snapshots are
+// disabled.
+func Save(
+	db *gorm.DB, cfg config.ZoneSnapshots, zoneName, reason string, snapshot *activitylog.ZoneSnapshot, userID *uint64,
+) (*models.ZoneSnapshot, error) {
+	if !cfg.Enabled() || snapshot == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &models.ZoneSnapshot{
+		ZoneName:    zoneName,
+		Reason:      reason,
+		RRsetCount:  len(snapshot.RRsets),
+		Snapshot:    string(b),
+		CreatedByID: userID,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(s).Error; err != nil {
+			return err
+		}
+
+		return prune(tx, zoneName, cfg.Keep)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Take fetches zoneName from PowerDNS and saves it like Save.
+func Take(
+	ctx context.Context, db *gorm.DB, cfg config.ZoneSnapshots, zoneName, reason string, userID *uint64,
+) (*models.ZoneSnapshot, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	if powerdns.Engine.Client == nil {
+		return nil, powerdns.ErrClientNotInitialized
+	}
+
+	zone, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+	if err != nil {
+		return nil, fmt.Errorf("fetch zone: %w", err)
+	}
+
+	return Save(db, cfg, zoneName, reason, FromZone(zone), userID)
+}
+
+// prune deletes the snapshots of zoneName but the newest keep.
+func prune(db *gorm.DB, zoneName string, keep int) error {
+	var ids []uint64
+
+	err := db.Model(&models.ZoneSnapshot{}).
+		Where("zone_name = ?", zoneName).
+		Order("id DESC").
+		Offset(keep).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	return db.Delete(&models.ZoneSnapshot{}, ids).Error
+}
+
+// List returns the snapshots of zoneName, or of every zone when zoneName is
+// empty, newest first.
+func List(db *gorm.DB, zoneName string) ([]models.ZoneSnapshot, error) {
+	var snapshots []models.ZoneSnapshot
+
+	q := db.Preload("CreatedBy").Omit("snapshot").Order("id DESC")
+	if zoneName != "" {
+		q = q.Where("zone_name = ?", zoneName)
+	} else {
+		q = q.Limit(listLimit)
+	}
+
+	err := q.Find(&snapshots).Error
+
+	return snapshots, err
+}
+
+// Get returns the snapshot id.
+func Get(db *gorm.DB, id uint64) (*models.ZoneSnapshot, error) {
+	var snapshot models.ZoneSnapshot
+	if err := db.Preload("CreatedBy").First(&snapshot, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// Decode decodes the zone content stored with s.
+func Decode(s *models.ZoneSnapshot) (*activitylog.ZoneSnapshot, error) {
+	var snapshot activitylog.ZoneSnapshot
+	if err := json.Unmarshal([]byte(s.Snapshot), &snapshot); err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// Restore makes zoneName contain the RRsets of snapshot again: they replace
+// the current ones and RRsets not in the snapshot are deleted. The SOA and
+// DNSSEC records are left to PowerDNS. A zone that no longer exists is
+// created with the kind, primaries and records of the snapshot; created
+// reports this.
+func Restore(ctx context.Context, zoneName string, snapshot *activitylog.ZoneSnapshot) (created bool, err error) {
+	if powerdns.Engine.Client == nil {
+		return false, powerdns.ErrClientNotInitialized
+	}
+
+	current, err := powerdns.Engine.Zones.Get(ctx, zoneName)
+
+	var pdnsErr *pdnsapi.Error
+	if errors.As(err, &pdnsErr) && pdnsErr.StatusCode == http.StatusNotFound {
+		if _, err = powerdns.Engine.Zones.Add(ctx, newZone(zoneName, snapshot)); err != nil {
+			return false, fmt.Errorf("create zone: %w", err)
+		}
+
+		zoneindex.Default.RefreshZone(ctx, zoneName)
+
+		return true, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("fetch zone: %w", err)
+	}
+
+	changes := Changes(current.RRsets, snapshot)
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	if err = powerdns.Engine.Records.Patch(ctx, zoneName, &pdnsapi.RRsets{Sets: changes}); err != nil {
+		return false, err
+	}
+
+	zoneindex.Default.RefreshZone(ctx, zoneName)
+
+	return false, nil
+}
+
+// Changes returns the changes that turn the RRsets current into those of
+// snapshot. RRsets that already match are left out.
+func Changes(current []pdnsapi.RRset, snapshot *activitylog.ZoneSnapshot) []pdnsapi.RRset {
+	existing := make(map[string]*pdnsapi.RRset, len(current))
+
+	for i := range current {
+		rr := &current[i]
+		if rr.Name != nil && rr.Type != nil {
+			existing[key(*rr.Name, string(*rr.Type))] = rr
+		}
+	}
+
+	keep := make(map[string]bool, len(snapshot.RRsets))
+	changes := make([]pdnsapi.RRset, 0, len(snapshot.RRsets))
+
+	for _, rr := range snapshot.RRsets {
+		if !restorable(rr.Type) || len(rr.Records) == 0 {
+			continue
+		}
+
+		k := key(rr.Name, rr.Type)
+		keep[k] = true
+
+		if same(existing[k], &rr) {
+			continue
+		}
+
+		set := rrset(&rr)
+		replace := pdnsapi.ChangeTypeReplace
+		set.ChangeType = &replace
+
+		// Keep the comments of the RRset.
+		if cur := existing[k]; cur != nil {
+			set.Comments = cur.Comments
+		}
+
+		changes = append(changes, set)
+	}
+
+	for k, rr := range existing {
+		if keep[k] || !restorable(string(*rr.Type)) {
+			continue
+		}
+
+		remove := pdnsapi.ChangeTypeDelete
+		changes = append(changes, pdnsapi.RRset{Name: rr.Name, Type: rr.Type, ChangeType: &remove})
+	}
+
+	return changes
+}
+
+// newZone returns zoneName with the content of snapshot, for creating it.
+func newZone(zoneName string, snapshot *activitylog.ZoneSnapshot) *pdnsapi.Zone {
+	kind := pdnsapi.ZoneKind(snapshot.Kind)
+	if kind == "" {
+		kind = pdnsapi.NativeZoneKind
+	}
+
+	zone := &pdnsapi.Zone{Name: &zoneName, Kind: &kind, Masters: snapshot.Masters}
+
+	if snapshot.SOAEditAPI != "" {
+		soaEditAPI := snapshot.SOAEditAPI
+		zone.SOAEditAPI = &soaEditAPI
+	}
+
+	for _, rr := range snapshot.RRsets {
+		if len(rr.Records) == 0 || (rr.Type != "SOA" && !restorable(rr.Type)) {
+			continue
+		}
+
+		// No ChangeType: zone creation does not use changetype in rrsets.
+		zone.RRsets = append(zone.RRsets, rrset(&rr))
+	}
+
+	return zone
+}
+
+// rrset converts rr into an RRset of enabled records.
+func rrset(rr *activitylog.RRsetSnapshot) pdnsapi.RRset {
+	name := rr.Name
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	rrType := pdnsapi.RRType(rr.Type)
+	ttl := rr.TTL
+
+	records := make([]pdnsapi.Record, 0, len(rr.Records))
+
+	for _, content := range rr.Records {
+		disabled := false
+		records = append(records, pdnsapi.Record{Content: &content, Disabled: &disabled})
+	}
+
+	return pdnsapi.RRset{Name: &name, Type: &rrType, TTL: &ttl, Records: records}
+}
+
+// same reports whether current already holds the enabled records and TTL of
+// rr.
+func same(current *pdnsapi.RRset, rr *activitylog.RRsetSnapshot) bool {
+	if current == nil || pdnsapi.Uint32Value(current.TTL) != rr.TTL || len(current.Records) != len(rr.Records) {
+		return false
+	}
+
+	contents := make(map[string]bool, len(current.Records))
+
+	for _, r := range current.Records {
+		if pdnsapi.BoolValue(r.Disabled) {
+			return false
+		}
+
+		contents[pdnsapi.StringValue(r.Content)] = true
+	}
+
+	for _, content := range rr.Records {
+		if !contents[content] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func key(name, rrType string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name + "/" + strings.ToUpper(rrType)
+}
@@ -0,0 +1,232 @@
+package zonesnapshot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/config"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/controller/pdnsserver"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+)
+
+const testZone = "example.com."
+
+// fakePowerDNS serves example.com. with an SOA, a www A and a mail A RRset,
+// and keeps the bodies of the PATCH and zone creation requests.
+type fakePowerDNS struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	zone := strings.TrimPrefix(r.URL.Path, "/api/v1/servers/localhost/zones/")
+
+	switch {
+	case r.Method == http.MethodGet && zone == testZone:
+		_, _ = io.WriteString(w, `{"name":"example.com.","kind":"Native","rrsets":[`+
+			`{"name":"example.com.","type":"SOA","ttl":3600,"records":[{"content":"ns1.example.com. hostmaster.example.com. 5 10800 3600 604800 3600","disabled":false}]},`+
+			`{"name":"www.example.com.","type":"A","ttl":300,"records":[{"content":"192.0.2.2","disabled":false}]},`+
+			`{"name":"mail.example.com.","type":"A","ttl":300,"records":[{"content":"192.0.2.25","disabled":false}]}]}`)
+	case r.Method == http.MethodGet:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"Not Found"}`)
+	default:
+		body, _ := io.ReadAll(r.Body)
+
+		f.mu.Lock()
+		f.requests = append(f.requests, r.Method+" "+string(body))
+		f.mu.Unlock()
+
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.Setting{}, &models.User{}, &models.ZoneSnapshot{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func openFakePowerDNS(t *testing.T, db *gorm.DB) *fakePowerDNS {
+	t.Helper()
+
+	pdns := &fakePowerDNS{}
+
+	srv := httptest.NewServer(pdns)
+	t.Cleanup(srv.Close)
+
+	settings := &pdnsserver.Settings{APIServerURL: srv.URL, APIKey: "secret", VHost: "localhost"}
+	if err := settings.Save(db); err != nil {
+		t.Fatalf("failed to save PowerDNS settings: %v", err)
+	}
+
+	prev := powerdns.Engine
+	t.Cleanup(func() { powerdns.Engine = prev })
+
+	if err := powerdns.Open(db); err != nil {
+		t.Fatalf("failed to open PowerDNS client: %v", err)
+	}
+
+	return pdns
+}
+
+func TestPatchReason(t *testing.T) {
+	cfg := config.ZoneSnapshots{Keep: 5, LargePatch: 3}
+
+	for _, tt := range []struct {
+		cfg      config.ZoneSnapshots
+		n        int
+		imported bool
+		want     string
+	}{
+		{cfg, 2, false, ""},
+		{cfg, 3, false, ReasonPatch},
+		{cfg, 1, true, ReasonImport},
+		{cfg, 0, true, ""},
+		{config.ZoneSnapshots{Keep: 5, LargePatch: -1}, 100, false, ""},
+		{config.ZoneSnapshots{Keep: -1, LargePatch: 3}, 10, true, ""},
+	} {
+		if got := PatchReason(tt.cfg, tt.n, tt.imported); got != tt.want {
+			t.Errorf("PatchReason(%+v, %d, %v) = %q, want %q", tt.cfg, tt.n, tt.imported, got, tt.want)
+		}
+	}
+}
+
+func TestSaveKeepsTheNewest(t *testing.T) {
+	db := newTestDB(t)
+	cfg := config.ZoneSnapshots{Keep: 2, LargePatch: 10}
+
+	for i := range 3 {
+		snapshot := &activitylog.ZoneSnapshot{Kind: "Native", RRsets: make([]activitylog.RRsetSnapshot, i)}
+		if _, err := Save(db, cfg, testZone, ReasonPatch, snapshot, nil); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	if _, err := Save(db, cfg, "other.example.", ReasonDelete, &activitylog.ZoneSnapshot{}, nil); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	snapshots, err := List(db, testZone)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if len(snapshots) != 2 || snapshots[0].RRsetCount != 2 || snapshots[1].RRsetCount != 1 {
+		t.Errorf("List() = %+v, want the newest two", snapshots)
+	}
+
+	if all, _ := List(db, ""); len(all) != 3 {
+		t.Errorf("List() of all zones = %d snapshots, want 3", len(all))
+	}
+
+	// Disabled zone snapshots are not stored.
+	if s, err := Save(db, config.ZoneSnapshots{Keep: -1}, testZone, ReasonPatch, &activitylog.ZoneSnapshot{}, nil); s != nil || err != nil {
+		t.Errorf("Save() with zone snapshots disabled = %v, %v", s, err)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	ttl := uint32(300)
+	rrType := pdnsapi.RRTypeA
+	current := []pdnsapi.RRset{
+		{Name: pdnsapi.String("www.example.com."), Type: &rrType, TTL: &ttl,
+			Records: []pdnsapi.Record{{Content: pdnsapi.String("192.0.2.1"), Disabled: pdnsapi.Bool(false)}}},
+		{Name: pdnsapi.String("new.example.com."), Type: &rrType, TTL: &ttl,
+			Records: []pdnsapi.Record{{Content: pdnsapi.String("192.0.2.9"), Disabled: pdnsapi.Bool(false)}}},
+	}
+
+	snapshot := &activitylog.ZoneSnapshot{RRsets: []activitylog.RRsetSnapshot{
+		{Name: "example.com.", Type: "SOA", TTL: 3600, Records: []string{"ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"}},
+		{Name: "www.example.com.", Type: "A", TTL: 300, Records: []string{"192.0.2.1"}},
+		{Name: "mail.example.com.", Type: "A", TTL: 300, Records: []string{"192.0.2.25"}},
+	}}
+
+	changes := Changes(current, snapshot)
+	if len(changes) != 2 {
+		t.Fatalf("Changes() = %d changes, want 2", len(changes))
+	}
+
+	got := map[string]pdnsapi.ChangeType{}
+	for _, c := range changes {
+		got[*c.Name] = *c.ChangeType
+	}
+
+	if got["mail.example.com."] != pdnsapi.ChangeTypeReplace || got["new.example.com."] != pdnsapi.ChangeTypeDelete {
+		t.Errorf("Changes() = %v, want mail replaced and new deleted, SOA and www untouched", got)
+	}
+}
+
+func TestTakeAndRestore(t *testing.T) {
+	db := newTestDB(t)
+	pdns := openFakePowerDNS(t, db)
+	cfg := config.ZoneSnapshots{Keep: 5, LargePatch: 10}
+
+	s, err := Take(context.Background(), db, cfg, testZone, ReasonManual, nil)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+
+	snapshot, err := Decode(s)
+	if err != nil || len(snapshot.RRsets) != 3 || snapshot.Kind != "Native" {
+		t.Fatalf("Decode() = %+v, %v", snapshot, err)
+	}
+
+	// The zone changed since: www has another address and mail is gone.
+	snapshot.RRsets[1].Records = []string{"192.0.2.1"}
+	snapshot.RRsets = snapshot.RRsets[:2]
+
+	created, err := Restore(context.Background(), testZone, snapshot)
+	if err != nil || created {
+		t.Fatalf("Restore() = %v, %v", created, err)
+	}
+
+	// Restoring a deleted zone creates it.
+	created, err = Restore(context.Background(), "gone.example.", snapshot)
+	if err != nil || !created {
+		t.Fatalf("Restore() of a deleted zone = %v, %v", created, err)
+	}
+
+	if len(pdns.requests) != 2 {
+		t.Fatalf("requests = %q", pdns.requests)
+	}
+
+	patch := pdns.requests[0]
+	if !strings.HasPrefix(patch, "PATCH") || !strings.Contains(patch, "192.0.2.1") ||
+		!strings.Contains(patch, `"changetype":"DELETE"`) || strings.Contains(patch, "SOA") {
+		t.Errorf("patch = %s, want www replaced and mail deleted without the SOA", patch)
+	}
+
+	if create := pdns.requests[1]; !strings.HasPrefix(create, "POST") || !strings.Contains(create, "SOA") {
+		t.Errorf("zone creation = %s, want the records with the SOA", create)
+	}
+}
@@ -0,0 +1,69 @@
+package zonestats
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+)
+
+// modifiedWindow is the number of activity log entries searched for recently
+// modified zones, enough to skip the zones the user has no access to.
+const modifiedWindow = 200
+
+// modifyingActions are the activity log actions that change a zone.
+var modifyingActions = []string{
+	activitylog.ActionZoneCreated,
+	activitylog.ActionZoneUpdated,
+	activitylog.ActionRecordChanged,
+	activitylog.ActionRecordUndone,
+	activitylog.ActionRecordScheduleApplied,
+}
+
+// Modified is a zone changed recently through this application.
+type Modified struct {
+	Name       string
+	ModifiedAt time.Time
+	Username   string
+}
+
+// RecentlyModified returns up to limit zones most recently changed according
+// to the activity log, newest first, limited to the zones allow accepts.
+func RecentlyModified(db *gorm.DB, allow func(name string) bool, limit int) ([]Modified, error) {
+	var entries []models.ActivityLog
+
+	err := db.Select("resource_name", "username", "created_at").
+		Where("resource_type = ? AND action IN ?", activitylog.ResourceTypeZone, modifyingActions).
+		Order("created_at DESC, id DESC").
+		Limit(modifiedWindow).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	modified := make([]Modified, 0, limit)
+
+	for i := range entries {
+		name := entries[i].ResourceName
+		if !strings.HasSuffix(name, ".") {
+			name += "."
+		}
+
+		if seen[name] || !allow(name) {
+			continue
+		}
+
+		seen[name] = true
+
+		modified = append(modified, Modified{Name: name, ModifiedAt: entries[i].CreatedAt, Username: entries[i].Username})
+		if len(modified) == limit {
+			break
+		}
+	}
+
+	return modified, nil
+}
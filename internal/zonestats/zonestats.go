@@ -0,0 +1,370 @@
+// Package zonestats aggregates the dashboard statistics of the zones known to
+// PowerDNS: zones by kind, DNSSEC coverage, record counts and failing health
+// checks.
+//
+// Kinds and DNSSEC come from the zone index. Record counts and health need the
+// records of every zone, so a background scan fetches them and keeps the
+// result per zone. A zone is fetched again only when its serial changed or its
+// entry is older than maxAge, which keeps a scan of an unchanged server cheap.
+package zonestats
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/jobs"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/powerdns"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zonecheck"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/zoneindex"
+)
+
+const (
+	// FailingLimit is the number of failing zones listed in a Summary.
+	FailingLimit = 5
+
+	// scanWorkers is the number of zones fetched from PowerDNS at a time.
+	scanWorkers = 4
+
+	// maxAge is how long the entry of a zone is kept when its serial does
+	// not change, e.g. for zones without SOA-EDIT-API.
+	maxAge = time.Hour
+)
+
+// Lister lists the zones without their records, e.g. a *zoneindex.Index.
+type Lister interface {
+	List(ctx context.Context) ([]pdnsapi.Zone, error)
+}
+
+// Getter fetches a zone with its records.
+type Getter interface {
+	Get(ctx context.Context, name string) (*pdnsapi.Zone, error)
+}
+
+// engineGetter reads from the shared PowerDNS engine, which is replaced when
+// the server settings change.
+type engineGetter struct{}
+
+func (engineGetter) Get(ctx context.Context, name string) (*pdnsapi.Zone, error) {
+	if powerdns.Engine.Client == nil {
+		return nil, powerdns.ErrClientNotInitialized
+	}
+
+	return powerdns.Engine.Zones.Get(ctx, name)
+}
+
+// Entry is what the last scan learned about a zone.
+type Entry struct {
+	Serial   uint32
+	Records  int
+	Errors   int
+	Warnings int
+	// Err is why the zone could not be fetched.
+	Err       string
+	ScannedAt time.Time
+}
+
+// KindCount is the number of zones of a kind.
+type KindCount struct {
+	Kind  string
+	Count int
+}
+
+// FailingZone is a zone with health check errors.
+type FailingZone struct {
+	Name   string
+	Errors int
+}
+
+// Summary holds the statistics of a set of zones.
+type Summary struct {
+	Zones int
+	// Kinds counts the zones by kind, by kind name.
+	Kinds  []KindCount
+	DNSSEC int
+	// Scanned is the number of zones whose records were counted and checked.
+	// It is lower than Zones until the first scan finished and for zones
+	// created since the last one.
+	Scanned int
+	Records int
+	// Failing and Warnings count the zones with health check errors and the
+	// zones with warnings only.
+	Failing  int
+	Warnings int
+	// FailingZones are up to FailingLimit failing zones, most errors first.
+	FailingZones []FailingZone
+	// ScannedAt is when the last scan finished; zero before the first one.
+	ScannedAt time.Time
+}
+
+// DNSSECRatio returns the share of zones signed with DNSSEC.
+func (s Summary) DNSSECRatio() float64 {
+	if s.Zones == 0 {
+		return 0
+	}
+
+	return float64(s.DNSSEC) / float64(s.Zones)
+}
+
+// Service scans the zones and summarizes them.
+type Service struct {
+	zones  Lister
+	source Getter
+	now    func() time.Time
+
+	mu       sync.RWMutex
+	interval time.Duration
+	entries  map[string]Entry
+	scanned  time.Time
+	bus      *eventbus.Bus
+
+	// scanMu serializes scans.
+	scanMu sync.Mutex
+}
+
+// Default is the service shared by the web handlers.
+var Default = New(zoneindex.Default, engineGetter{}, zoneindex.DefaultInterval)
+
+// New returns a service listing zones from zones and fetching their records
+// from source, scanning every interval when run.
+func New(zones Lister, source Getter, interval time.Duration) *Service {
+	return &Service{zones: zones, source: source, interval: interval, now: time.Now}
+}
+
+// Configure sets the scan interval.
+func (s *Service) Configure(interval time.Duration) {
+	s.mu.Lock()
+	s.interval = interval
+	s.mu.Unlock()
+}
+
+// PublishTo publishes eventbus.TopicZoneHealth on bus for every zone whose
+// health checks start failing. Each replica scans on its own, so every
+// replica publishes the event locally.
+func (s *Service) PublishTo(bus *eventbus.Bus) {
+	s.mu.Lock()
+	s.bus = bus
+	s.mu.Unlock()
+}
+
+// Entry returns the result of the last scan of the zone name.
+func (s *Service) Entry(name string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.entries[name]
+
+	return e, ok
+}
+
+// Scan fetches the zones that are new, changed or not scanned within maxAge
+// and drops the entries of deleted zones. Zones that fail to load are kept
+// with Entry.Err set and retried on the next scan.
+func (s *Service) Scan(ctx context.Context) error {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	zones, err := s.zones.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+
+	s.mu.RLock()
+	previous, first, bus := s.entries, s.scanned.IsZero(), s.bus
+	s.mu.RUnlock()
+
+	entries := make(map[string]Entry, len(zones))
+	stale := make([]string, 0)
+
+	for i := range zones {
+		name := pdnsapi.StringValue(zones[i].Name)
+		if name == "" {
+			continue
+		}
+
+		e, ok := previous[name]
+		if ok && e.Err == "" && e.Serial == pdnsapi.Uint32Value(zones[i].Serial) && now.Sub(e.ScannedAt) < maxAge {
+			entries[name] = e
+			continue
+		}
+
+		stale = append(stale, name)
+	}
+
+	var failing []string
+
+	for i, e := range s.fetch(ctx, stale, now) {
+		entries[stale[i]] = e
+
+		if !first && e.Errors > 0 && startedFailing(previous, stale[i]) {
+			failing = append(failing, stale[i])
+		}
+	}
+
+	s.mu.Lock()
+	s.entries = entries
+	s.scanned = s.now()
+	s.mu.Unlock()
+
+	if bus != nil {
+		for _, name := range failing {
+			bus.Publish(eventbus.TopicZoneHealth, name)
+		}
+	}
+
+	log.Debug().Int("zones", len(entries)).Int("fetched", len(stale)).Msg("zonestats: scan finished")
+
+	return nil
+}
+
+// startedFailing reports whether the zone name was healthy or unknown in the
+// previous scan. A zone that could not be fetched is not reported again, so
+// that a transient error does not repeat the notification.
+func startedFailing(previous map[string]Entry, name string) bool {
+	e, ok := previous[name]
+
+	return !ok || (e.Err == "" && e.Errors == 0)
+}
+
+// fetch loads and checks the named zones with scanWorkers workers.
+func (s *Service) fetch(ctx context.Context, names []string, now time.Time) []Entry {
+	entries := make([]Entry, len(names))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range scanWorkers {
+		wg.Go(func() {
+			for i := range next {
+				entries[i] = s.scanZone(ctx, names[i], now)
+			}
+		})
+	}
+
+	for i := range names {
+		next <- i
+	}
+
+	close(next)
+	wg.Wait()
+
+	return entries
+}
+
+// scanZone fetches and checks one zone.
+func (s *Service) scanZone(ctx context.Context, name string, now time.Time) Entry {
+	zone, err := s.source.Get(ctx, name)
+	if err != nil {
+		log.Debug().Err(err).Str("zone_name", name).Msg("zonestats: failed to fetch zone")
+		return Entry{Err: err.Error(), ScannedAt: now}
+	}
+
+	entry := Entry{Serial: pdnsapi.Uint32Value(zone.Serial), ScannedAt: now}
+
+	for i := range zone.RRsets {
+		entry.Records += len(zone.RRsets[i].Records)
+	}
+
+	report := zonecheck.Check(zone, now)
+	entry.Errors, entry.Warnings = report.Errors, report.Warnings
+
+	return entry
+}
+
+// Summarize returns the statistics of zones, e.g. the zones of the index the
+// current user has access to.
+func (s *Service) Summarize(zones []pdnsapi.Zone) Summary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summary := Summary{ScannedAt: s.scanned}
+	kinds := map[string]int{}
+
+	for i := range zones {
+		zone := &zones[i]
+		if zone.Name == nil {
+			continue
+		}
+
+		summary.Zones++
+
+		if zone.Kind != nil {
+			kinds[string(*zone.Kind)]++
+		}
+
+		if pdnsapi.BoolValue(zone.DNSsec) {
+			summary.DNSSEC++
+		}
+
+		e, ok := s.entries[*zone.Name]
+		if !ok || e.Err != "" {
+			continue
+		}
+
+		summary.Scanned++
+		summary.Records += e.Records
+
+		switch {
+		case e.Errors > 0:
+			summary.Failing++
+			summary.FailingZones = append(summary.FailingZones, FailingZone{Name: *zone.Name, Errors: e.Errors})
+		case e.Warnings > 0:
+			summary.Warnings++
+		}
+	}
+
+	for kind, count := range kinds {
+		summary.Kinds = append(summary.Kinds, KindCount{Kind: kind, Count: count})
+	}
+
+	slices.SortFunc(summary.Kinds, func(a, b KindCount) int { return strings.Compare(a.Kind, b.Kind) })
+
+	slices.SortFunc(summary.FailingZones, func(a, b FailingZone) int {
+		return cmp.Or(cmp.Compare(b.Errors, a.Errors), strings.Compare(a.Name, b.Name))
+	})
+
+	if len(summary.FailingZones) > FailingLimit {
+		summary.FailingZones = summary.FailingZones[:FailingLimit]
+	}
+
+	return summary
+}
+
+// Run scans the zones right away and then at the configured interval until
+// ctx is canceled. Errors are logged; the previous entries are kept.
+func (s *Service) Run(ctx context.Context) {
+	s.mu.RLock()
+	interval := s.interval
+	s.mu.RUnlock()
+
+	jobs.Scheduled(jobs.ZoneStats, interval)
+
+	scan := func() {
+		if err := jobs.Run(jobs.ZoneStats, func() error { return s.Scan(ctx) }); err != nil {
+			log.Debug().Err(err).Msg("zonestats: background scan failed")
+		}
+	}
+
+	scan()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scan()
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package zonestats
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	pdnsapi "github.com/joeig/go-powerdns/v3"
+	"gorm.io/gorm"
+
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/activitylog"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/db/models"
+	"github.com/GoPowerDNS-Admin/GoPowerDNS-Admin/internal/eventbus"
+)
+
+type fakeZone struct {
+	kind   pdnsapi.ZoneKind
+	dnssec bool
+	serial uint32
+	// records is the number of A records below the apex.
+	records int
+	// broken omits the apex NS RRset, which is a health check error.
+	broken bool
+}
+
+type fakeSource struct {
+	zones map[string]*fakeZone
+	gets  map[string]int
+	err   error
+}
+
+func (f *fakeSource) List(context.Context) ([]pdnsapi.Zone, error) {
+	zones := make([]pdnsapi.Zone, 0, len(f.zones))
+
+	for name, z := range f.zones {
+		kind := z.kind
+		zones = append(zones, pdnsapi.Zone{
+			Name:   pdnsapi.String(name),
+			Kind:   &kind,
+			DNSsec: pdnsapi.Bool(z.dnssec),
+			Serial: pdnsapi.Uint32(z.serial),
+		})
+	}
+
+	return zones, nil
+}
+
+func (f *fakeSource) Get(_ context.Context, name string) (*pdnsapi.Zone, error) {
+	f.gets[name]++
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	z := f.zones[name]
+	zone := &pdnsapi.Zone{
+		Name:   pdnsapi.String(name),
+		Serial: pdnsapi.Uint32(z.serial),
+		RRsets: []pdnsapi.RRset{rrSet(name, pdnsapi.RRTypeSOA, "ns1.example.net. hostmaster.example.net. 1 10800 3600 604800 3600")},
+	}
+
+	if !z.broken {
+		zone.RRsets = append(zone.RRsets, rrSet(name, pdnsapi.RRTypeNS, "ns1.example.net."))
+	}
+
+	for range z.records {
+		zone.RRsets = append(zone.RRsets, rrSet("www."+name, pdnsapi.RRTypeA, "192.0.2.1"))
+	}
+
+	return zone, nil
+}
+
+func rrSet(name string, rrType pdnsapi.RRType, content string) pdnsapi.RRset {
+	return pdnsapi.RRset{
+		Name:    pdnsapi.String(name),
+		Type:    &rrType,
+		TTL:     pdnsapi.Uint32(3600),
+		Records: []pdnsapi.Record{{Content: pdnsapi.String(content), Disabled: pdnsapi.Bool(false)}},
+	}
+}
+
+func newTestService(src *fakeSource, now *time.Time) *Service {
+	s := New(src, src, time.Minute)
+	s.now = func() time.Time { return *now }
+
+	return s
+}
+
+func TestScan_Summarize(t *testing.T) {
+	src := &fakeSource{gets: map[string]int{}, zones: map[string]*fakeZone{
+		"a.example.": {kind: pdnsapi.NativeZoneKind, dnssec: true, serial: 1, records: 2},
+		"b.example.": {kind: pdnsapi.NativeZoneKind, serial: 1, records: 1, broken: true},
+		"c.example.": {kind: pdnsapi.SlaveZoneKind, serial: 1},
+	}}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	s := newTestService(src, &now)
+
+	zones, _ := src.List(t.Context())
+
+	if got := s.Summarize(zones); got.Zones != 3 || got.Scanned != 0 || !got.ScannedAt.IsZero() {
+		t.Fatalf("Summarize() before scan = %+v", got)
+	}
+
+	if err := s.Scan(t.Context()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	got := s.Summarize(zones)
+
+	// Every zone has a SOA record and all but the broken one an NS record.
+	if got.Zones != 3 || got.Scanned != 3 || got.Records != 8 || got.DNSSEC != 1 {
+		t.Errorf("Summarize() = %+v", got)
+	}
+
+	if len(got.Kinds) != 2 || got.Kinds[0] != (KindCount{"Native", 2}) || got.Kinds[1] != (KindCount{"Slave", 1}) {
+		t.Errorf("Kinds = %+v", got.Kinds)
+	}
+
+	if got.Failing != 1 || len(got.FailingZones) != 1 || got.FailingZones[0].Name != "b.example." {
+		t.Errorf("Failing = %d, FailingZones = %+v", got.Failing, got.FailingZones)
+	}
+
+	if r := got.DNSSECRatio(); r < 0.33 || r > 0.34 {
+		t.Errorf("DNSSECRatio() = %v", r)
+	}
+
+	if sub := s.Summarize(zones[:0]); sub.Zones != 0 || sub.DNSSECRatio() != 0 {
+		t.Errorf("Summarize(nil) = %+v", sub)
+	}
+}
+
+func TestScan_FetchesChangedZonesOnly(t *testing.T) {
+	src := &fakeSource{gets: map[string]int{}, zones: map[string]*fakeZone{
+		"a.example.": {serial: 1},
+		"b.example.": {serial: 1},
+	}}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	s := newTestService(src, &now)
+
+	for range 2 {
+		if err := s.Scan(t.Context()); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+	}
+
+	if src.gets["a.example."] != 1 || src.gets["b.example."] != 1 {
+		t.Fatalf("unchanged zones fetched again: %v", src.gets)
+	}
+
+	src.zones["a.example."].serial = 2
+	delete(src.zones, "b.example.")
+	src.zones["c.example."] = &fakeZone{serial: 1}
+
+	if err := s.Scan(t.Context()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if src.gets["a.example."] != 2 || src.gets["c.example."] != 1 {
+		t.Errorf("changed and new zones not fetched: %v", src.gets)
+	}
+
+	if _, ok := s.entries["b.example."]; ok {
+		t.Error("deleted zone kept")
+	}
+
+	now = now.Add(maxAge)
+
+	if err := s.Scan(t.Context()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if src.gets["a.example."] != 3 || src.gets["c.example."] != 2 {
+		t.Errorf("expired entries not fetched again: %v", src.gets)
+	}
+}
+
+func TestScan_RetriesFailedZones(t *testing.T) {
+	src := &fakeSource{gets: map[string]int{}, zones: map[string]*fakeZone{"a.example.": {serial: 1}}, err: errors.New("timeout")}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	s := newTestService(src, &now)
+
+	if err := s.Scan(t.Context()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	zones, _ := src.List(t.Context())
+	if got := s.Summarize(zones); got.Scanned != 0 {
+		t.Errorf("failed zone counted as scanned: %+v", got)
+	}
+
+	src.err = nil
+
+	if err := s.Scan(t.Context()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if got := s.Summarize(zones); got.Scanned != 1 || src.gets["a.example."] != 2 {
+		t.Errorf("failed zone not retried: %+v, gets %v", got, src.gets)
+	}
+}
+
+func TestScan_PublishesZonesStartingToFail(t *testing.T) {
+	src := &fakeSource{gets: map[string]int{}, zones: map[string]*fakeZone{
+		"a.example.": {serial: 1},
+		"b.example.": {serial: 1, broken: true},
+	}}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	s := newTestService(src, &now)
+
+	bus := eventbus.New()
+	s.PublishTo(bus)
+
+	var got []string
+
+	bus.Subscribe(eventbus.TopicZoneHealth, func(e eventbus.Event) { got = append(got, e.Key) })
+
+	scan := func() {
+		t.Helper()
+
+		if err := s.Scan(t.Context()); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+	}
+
+	// Zones failing at startup are not reported.
+	scan()
+
+	src.zones["a.example."] = &fakeZone{serial: 2, broken: true}
+	src.zones["b.example."] = &fakeZone{serial: 2, broken: true}
+	src.zones["c.example."] = &fakeZone{serial: 1, broken: true}
+	scan()
+
+	if len(got) != 2 || !slices.Contains(got, "a.example.") || !slices.Contains(got, "c.example.") {
+		t.Errorf("published %v, want the zones that started failing", got)
+	}
+
+	if e, ok := s.Entry("a.example."); !ok || e.Errors == 0 {
+		t.Errorf("Entry() = %+v, %v", e, ok)
+	}
+}
+
+func TestRecentlyModified(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite: %v", err)
+	}
+
+	if err = db.AutoMigrate(&models.ActivityLog{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	for i, e := range []struct{ action, name string }{
+		{activitylog.ActionZoneCreated, "a.example"},
+		{activitylog.ActionRecordChanged, "b.example."},
+		{activitylog.ActionZoneNotified, "c.example."},
+		{activitylog.ActionRecordChanged, "a.example."},
+		{activitylog.ActionZoneUpdated, "secret.example."},
+		{activitylog.ActionZoneUpdated, "d.example."},
+	} {
+		db.Create(&models.ActivityLog{
+			Username: "alice", Action: e.action, ResourceType: activitylog.ResourceTypeZone,
+			ResourceName: e.name, CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	allow := func(name string) bool { return name != "secret.example." }
+
+	got, err := RecentlyModified(db, allow, 2)
+	if err != nil {
+		t.Fatalf("RecentlyModified() error = %v", err)
+	}
+
+	if len(got) != 2 || got[0].Name != "d.example." || got[1].Name != "a.example." || got[1].Username != "alice" {
+		t.Fatalf("RecentlyModified() = %+v", got)
+	}
+
+	if got, _ = RecentlyModified(db, allow, 5); len(got) != 3 || got[2].Name != "b.example." {
+		t.Errorf("RecentlyModified(5) = %+v", got)
+	}
+}
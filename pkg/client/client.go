@@ -0,0 +1,242 @@
+// Package client is a Go client of the GoPowerDNS-Admin API: the zone
+// endpoints of its PowerDNS-compatible API and the admin lists. It
+// authenticates with a personal API key, so every request is checked against
+// the permissions and zone access of the key's user, like in the web UI.
+//
+//	c, err := client.New("https://pdns.example.com", os.Getenv("GPA_KEY"))
+//	if err != nil {
+//		return err
+//	}
+//
+//	err = c.UpsertRRset(ctx, "example.com.", client.RRset{
+//		Name:    "www.example.com.",
+//		Type:    "A",
+//		TTL:     300,
+//		Records: []client.Record{{Content: "192.0.2.1"}},
+//	})
+//
+// The package only depends on the standard library.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultServerID is the PowerDNS server ID of the zone endpoints, the
+	// vhost configured under Admin → PowerDNS Server.
+	DefaultServerID = "localhost"
+
+	// DefaultTimeout is the timeout of the default HTTP client.
+	DefaultTimeout = 30 * time.Second
+
+	// apiKeyHeader is the request header carrying the API key.
+	apiKeyHeader = "X-API-Key"
+)
+
+var errNoAPIKey = errors.New("client: an API key is required")
+
+// Client calls the API of a GoPowerDNS-Admin instance. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	serverID   string
+	httpClient *http.Client
+	userAgent  string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with, e.g. for a
+// custom transport or timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithServerID sets the PowerDNS server ID of the zone endpoints; it
+// defaults to DefaultServerID.
+func WithServerID(serverID string) Option {
+	return func(c *Client) { c.serverID = serverID }
+}
+
+// WithUserAgent sets the User-Agent header of the requests.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New returns a client of the instance at baseURL, e.g.
+// "https://pdns.example.com", authenticating with apiKey, a gpa_ key
+// created under Profile → API Keys.
+func New(baseURL, apiKey string, opts ...Option) (*Client, error) {
+	if apiKey == "" {
+		return nil, errNoAPIKey
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base URL %q: it needs an http or https scheme and a host", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		apiKey:     apiKey,
+		serverID:   DefaultServerID,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		userAgent:  "gopowerdns-admin-client",
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Error is a request the API answered with an error status.
+type Error struct {
+	StatusCode int
+	// Code is the machine-readable code of the admin endpoints, e.g.
+	// "bad_request"; the zone endpoints answer with a message only.
+	Code    string
+	Message string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("client: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	return fmt.Sprintf("client: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an Error with status 404.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsForbidden reports whether err is an Error with status 403, e.g. for a
+// zone or record type the key's user may not change.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsPreconditionFailed reports whether err is an Error with status 412: the
+// zone changed since the ETag passed with IfMatch.
+func IsPreconditionFailed(err error) bool {
+	return hasStatus(err, http.StatusPreconditionFailed)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// RequestOption adds a header to a single request.
+type RequestOption func(http.Header)
+
+// IfMatch makes a change only if the zone is still at etag, the ETag of
+// GetZone; otherwise the request fails with an Error IsPreconditionFailed
+// reports.
+func IfMatch(etag string) RequestOption {
+	return func(h http.Header) { h.Set("If-Match", etag) }
+}
+
+// do sends a request to path below the base URL with the JSON of body, if
+// not nil, and decodes the JSON response into out, if not nil. It returns
+// the response headers.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any,
+	opts ...RequestOption,
+) (http.Header, error) {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("client: failed to encode request: %w", err)
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to create request: %w", err)
+	}
+
+	req.Header.Set(apiKeyHeader, c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for _, opt := range opts {
+		opt(req.Header)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client: %s %s: %w", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, responseError(resp.StatusCode, data)
+	}
+
+	if out != nil && len(data) > 0 {
+		if s, ok := out.(*string); ok {
+			*s = string(data)
+		} else if err = json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("client: failed to decode response: %w", err)
+		}
+	}
+
+	return resp.Header, nil
+}
+
+// responseError returns the Error of a response with an error status. The
+// zone endpoints answer with {"error": ...}, the admin endpoints with
+// {"code": ..., "message": ...}.
+func responseError(status int, data []byte) *Error {
+	var body struct {
+		Error   string `json:"error"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	_ = json.Unmarshal(data, &body)
+
+	apiErr := &Error{StatusCode: status, Code: body.Code, Message: body.Message}
+	if apiErr.Message == "" {
+		apiErr.Message = body.Error
+	}
+
+	return apiErr
+}
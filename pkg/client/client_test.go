@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// request is a request received by the test server.
+type request struct {
+	Method, Path, Query, APIKey, IfMatch, Body string
+}
+
+// newTestClient returns a client of a server answering every request with
+// status and body, and the requests it received.
+func newTestClient(t *testing.T, status int, body string, header http.Header) (*Client, *[]request) {
+	t.Helper()
+
+	var requests []request
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{
+			Method: r.Method, Path: r.URL.EscapedPath(), Query: r.URL.RawQuery,
+			APIKey: r.Header.Get(apiKeyHeader), IfMatch: r.Header.Get("If-Match"), Body: string(data),
+		})
+
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL+"/", "gpa_test", WithServerID("ns"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return c, &requests
+}
+
+func TestNew(t *testing.T) {
+	for _, tt := range []struct{ baseURL, apiKey string }{
+		{"https://pdns.example.com", ""},
+		{"pdns.example.com", "gpa_test"},
+		{"ftp://pdns.example.com", "gpa_test"},
+	} {
+		if _, err := New(tt.baseURL, tt.apiKey); err == nil {
+			t.Errorf("New(%q, %q) succeeded, want an error", tt.baseURL, tt.apiKey)
+		}
+	}
+}
+
+func TestGetZone(t *testing.T) {
+	c, requests := newTestClient(t, http.StatusOK,
+		`{"name":"example.com.","kind":"Native","serial":2026030101,"rrsets":[{"name":"www.example.com.",`+
+			`"type":"A","ttl":300,"records":[{"content":"192.0.2.1","disabled":false}],"comments":[]}]}`,
+		http.Header{"Etag": {`"abc"`}})
+
+	zone, err := c.GetZone(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("GetZone() error = %v", err)
+	}
+
+	want := &Zone{
+		Name: "example.com.", Kind: KindNative, Serial: 2026030101, ETag: `"abc"`,
+		RRsets: []RRset{{
+			Name: "www.example.com.", Type: "A", TTL: 300,
+			Records: []Record{{Content: "192.0.2.1"}}, Comments: []Comment{},
+		}},
+	}
+	if !reflect.DeepEqual(zone, want) {
+		t.Errorf("GetZone() = %+v, want %+v", zone, want)
+	}
+
+	if r := (*requests)[0]; r.Method != http.MethodGet || r.Path != "/api/v1/servers/ns/zones/example.com." ||
+		r.APIKey != "gpa_test" {
+		t.Errorf("request = %+v", r)
+	}
+}
+
+func TestUpsertAndDeleteRRset(t *testing.T) {
+	c, requests := newTestClient(t, http.StatusNoContent, "", nil)
+	ctx := context.Background()
+
+	if err := c.UpsertRRset(ctx, "example.com.", RRset{
+		Name: "www.example.com.", Type: "A", TTL: 300, Records: []Record{{Content: "192.0.2.1"}},
+	}, IfMatch(`"abc"`)); err != nil {
+		t.Fatalf("UpsertRRset() error = %v", err)
+	}
+
+	if err := c.DeleteRRset(ctx, "example.com.", "old.example.com.", "TXT"); err != nil {
+		t.Fatalf("DeleteRRset() error = %v", err)
+	}
+
+	want := []request{
+		{
+			Method: http.MethodPatch, Path: "/api/v1/servers/ns/zones/example.com.", APIKey: "gpa_test", IfMatch: `"abc"`,
+			Body: `{"rrsets":[{"name":"www.example.com.","type":"A","ttl":300,"changetype":"REPLACE",` +
+				`"records":[{"content":"192.0.2.1","disabled":false}]}]}`,
+		},
+		{
+			Method: http.MethodPatch, Path: "/api/v1/servers/ns/zones/example.com.", APIKey: "gpa_test",
+			Body: `{"rrsets":[{"name":"old.example.com.","type":"TXT","changetype":"DELETE","records":[]}]}`,
+		},
+	}
+	if !reflect.DeepEqual(*requests, want) {
+		t.Errorf("requests = %+v, want %+v", *requests, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusPreconditionFailed,
+		`{"error":"Zone was changed since the revision in If-Match"}`, nil)
+
+	err := c.UpsertRRset(context.Background(), "example.com.", RRset{Name: "example.com.", Type: "TXT"})
+	if !IsPreconditionFailed(err) || IsNotFound(err) ||
+		err.Error() != "client: 412 Zone was changed since the revision in If-Match" {
+		t.Errorf("UpsertRRset() error = %v, want 412", err)
+	}
+
+	c, _ = newTestClient(t, http.StatusBadRequest, `{"success":false,"code":"bad_request","message":"unknown field"}`, nil)
+
+	_, err = c.ListUsers(context.Background(), nil)
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "bad_request" || apiErr.Message != "unknown field" {
+		t.Errorf("ListUsers() error = %#v", err)
+	}
+
+	if err = c.DeleteZone(context.Background(), ""); !errors.Is(err, errNoZoneName) {
+		t.Errorf("DeleteZone(\"\") error = %v, want %v", err, errNoZoneName)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	c, requests := newTestClient(t, http.StatusOK,
+		`{"recordsTotal":120,"recordsFiltered":1,"offset":0,"limit":25,"data":[{"id":7,"username":"jdoe"}]}`, nil)
+
+	page, err := c.ListUsers(context.Background(), &ListOptions{
+		Limit: 50, Offset: 100, Sort: "username", Prefix: "j", Fields: []string{"id", "username"},
+		ModifiedSince: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Filters: map[string]string{"active": "true"},
+	})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+
+	if page.Total != 120 || page.Filtered != 1 || len(page.Data) != 1 || page.Data[0].Username != "jdoe" {
+		t.Errorf("ListUsers() = %+v", page)
+	}
+
+	r := (*requests)[0]
+	want := "active=true&fields=id%2Cusername&limit=50&modified_since=2026-03-01T00%3A00%3A00Z&offset=100" +
+		"&order=asc&prefix=j&sort=username"
+
+	if r.Path != "/api/admin/users" || r.Query != want {
+		t.Errorf("request = %s?%s, want /api/admin/users?%s", r.Path, r.Query, want)
+	}
+}
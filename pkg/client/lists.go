@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListOptions pages, sorts and filters the admin lists. The zero value
+// returns the first 25 rows in the default order.
+type ListOptions struct {
+	Offset int
+	// Limit is the number of rows, at most 500; 0 returns 25.
+	Limit  int
+	Search string
+	// Sort is a field of the rows, e.g. "username"; Desc reverses the order.
+	Sort string
+	Desc bool
+	// Prefix keeps the rows whose name starts with it, ignoring case.
+	Prefix string
+	// ModifiedSince keeps the rows changed at or after it.
+	ModifiedSince time.Time
+	// Fields are the fields each row returns, all when empty; the others
+	// are left at their zero value.
+	Fields []string
+	// Filters are the filters of the list, e.g. "auth_source" of the users
+	// or "action" of the activity log.
+	Filters map[string]string
+}
+
+// query returns the query parameters of o.
+func (o *ListOptions) query() url.Values {
+	query := url.Values{}
+	if o == nil {
+		return query
+	}
+
+	for key, value := range o.Filters {
+		query.Set(key, value)
+	}
+
+	if o.Offset > 0 {
+		query.Set("offset", strconv.Itoa(o.Offset))
+	}
+
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+
+	if o.Search != "" {
+		query.Set("search", o.Search)
+	}
+
+	if o.Sort != "" {
+		query.Set("sort", o.Sort)
+
+		if o.Desc {
+			query.Set("order", "desc")
+		} else {
+			query.Set("order", "asc")
+		}
+	}
+
+	if o.Prefix != "" {
+		query.Set("prefix", o.Prefix)
+	}
+
+	if !o.ModifiedSince.IsZero() {
+		query.Set("modified_since", o.ModifiedSince.Format(time.RFC3339))
+	}
+
+	if len(o.Fields) > 0 {
+		query.Set("fields", strings.Join(o.Fields, ","))
+	}
+
+	return query
+}
+
+// Page is a page of an admin list.
+type Page[T any] struct {
+	// Total counts all rows, Filtered those matching the search and filters.
+	Total    int64 `json:"recordsTotal"`
+	Filtered int64 `json:"recordsFiltered"`
+	Offset   int   `json:"offset"`
+	Limit    int   `json:"limit"`
+	Data     []T   `json:"data"`
+}
+
+// User is a row of the user list.
+type User struct {
+	ID             uint64     `json:"id"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	DisplayName    string     `json:"display_name"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	AuthSource     string     `json:"auth_source"`
+	RoleID         uint       `json:"role_id"`
+	Role           string     `json:"role"`
+	Active         bool       `json:"active"`
+	Pending        bool       `json:"pending"`
+	ServiceAccount bool       `json:"service_account"`
+	TOTPEnabled    bool       `json:"totp_enabled"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Group is a row of the group list.
+type Group struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	ExternalID  string `json:"external_id"`
+	Source      string `json:"source"`
+	Description string `json:"description"`
+	Members     int64  `json:"members"`
+	// Role is the name of the mapped role, empty when the group is unmapped.
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Role is a row of the role list.
+type Role struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	System      bool      `json:"system"`
+	Permissions int64     `json:"permissions"`
+	Users       int64     `json:"users"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ActivityEntry is a row of the activity log.
+type ActivityEntry struct {
+	ID           uint64    `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UserID       *uint64   `json:"user_id,omitempty"`
+	Username     string    `json:"username"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type,omitempty"`
+	ResourceName string    `json:"resource_name,omitempty"`
+	IPAddress    string    `json:"ip,omitempty"`
+	AuthMethod   string    `json:"auth_method,omitempty"`
+	APIKeyID     *uint64   `json:"api_key_id,omitempty"`
+	// Details is the JSON context of the entry.
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+// ListUsers returns a page of the users; it needs admin.users.
+func (c *Client) ListUsers(ctx context.Context, opts *ListOptions) (*Page[User], error) {
+	return list[User](ctx, c, "api/admin/users", opts)
+}
+
+// ListGroups returns a page of the groups; it needs admin.groups.
+func (c *Client) ListGroups(ctx context.Context, opts *ListOptions) (*Page[Group], error) {
+	return list[Group](ctx, c, "api/admin/groups", opts)
+}
+
+// ListRoles returns a page of the roles; it needs admin.roles.
+func (c *Client) ListRoles(ctx context.Context, opts *ListOptions) (*Page[Role], error) {
+	return list[Role](ctx, c, "api/admin/roles", opts)
+}
+
+// ListActivity returns a page of the activity log; it needs
+// admin.activity.log.
+func (c *Client) ListActivity(ctx context.Context, opts *ListOptions) (*Page[ActivityEntry], error) {
+	return list[ActivityEntry](ctx, c, "api/admin/activity", opts)
+}
+
+// list returns the page of the admin list at path that opts asks for.
+func list[T any](ctx context.Context, c *Client, path string, opts *ListOptions) (*Page[T], error) {
+	var page Page[T]
+	if _, err := c.do(ctx, http.MethodGet, path, opts.query(), nil, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
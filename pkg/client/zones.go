@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Change types of an RRset in PatchRRsets.
+const (
+	ChangeTypeReplace = "REPLACE"
+	ChangeTypeDelete  = "DELETE"
+)
+
+// Zone kinds of CreateZone.
+const (
+	KindNative = "Native"
+	KindMaster = "Master"
+	KindSlave  = "Slave"
+)
+
+var errNoZoneName = errors.New("client: a zone name is required")
+
+// Zone is a zone as the PowerDNS API describes it. Names are fully
+// qualified, with a trailing dot.
+type Zone struct {
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind,omitempty"`
+	Serial      uint32   `json:"serial,omitempty"`
+	Masters     []string `json:"masters,omitempty"`
+	Nameservers []string `json:"nameservers,omitempty"`
+	Account     string   `json:"account,omitempty"`
+	DNSSEC      bool     `json:"dnssec,omitempty"`
+	RRsets      []RRset  `json:"rrsets,omitempty"`
+
+	// ETag is the revision of the RRsets of a zone read with GetZone; pass
+	// it to IfMatch to change the zone only if nobody changed it since.
+	ETag string `json:"-"`
+}
+
+// RRset is the records of one name and type.
+type RRset struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint32 `json:"ttl,omitempty"`
+	// ChangeType is set by UpsertRRset and DeleteRRset; PatchRRsets needs it.
+	ChangeType string    `json:"changetype,omitempty"`
+	Records    []Record  `json:"records"`
+	Comments   []Comment `json:"comments,omitempty"`
+}
+
+// Record is a record of an RRset.
+type Record struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Comment is a comment of an RRset.
+type Comment struct {
+	Content    string `json:"content"`
+	Account    string `json:"account"`
+	ModifiedAt int64  `json:"modified_at,omitempty"`
+}
+
+// ZoneListOptions filters ListZones.
+type ZoneListOptions struct {
+	// Label keeps the zones with a label, written as key or key=value.
+	Label string
+}
+
+// zonesPath returns the path of the zones of the server.
+func (c *Client) zonesPath() string {
+	return "api/v1/servers/" + url.PathEscape(c.serverID) + "/zones"
+}
+
+// zonePath returns the path of zone.
+func (c *Client) zonePath(zone string) string {
+	return c.zonesPath() + "/" + url.PathEscape(canonicalZone(zone))
+}
+
+// ListZones returns the zones the key's user may access, without their
+// RRsets. opts may be nil.
+func (c *Client) ListZones(ctx context.Context, opts *ZoneListOptions) ([]Zone, error) {
+	query := url.Values{}
+	if opts != nil && opts.Label != "" {
+		query.Set("label", opts.Label)
+	}
+
+	var zones []Zone
+	if _, err := c.do(ctx, http.MethodGet, c.zonesPath(), query, nil, &zones); err != nil {
+		return nil, err
+	}
+
+	return zones, nil
+}
+
+// GetZone returns zone with its RRsets and ETag.
+func (c *Client) GetZone(ctx context.Context, zone string) (*Zone, error) {
+	if zone == "" {
+		return nil, errNoZoneName
+	}
+
+	var z Zone
+
+	header, err := c.do(ctx, http.MethodGet, c.zonePath(zone), nil, nil, &z)
+	if err != nil {
+		return nil, err
+	}
+
+	z.ETag = header.Get("ETag")
+
+	return &z, nil
+}
+
+// ExportZone returns zone in the zone file format.
+func (c *Client) ExportZone(ctx context.Context, zone string) (string, error) {
+	if zone == "" {
+		return "", errNoZoneName
+	}
+
+	var text string
+	if _, err := c.do(ctx, http.MethodGet, c.zonePath(zone)+"/export", nil, nil, &text); err != nil {
+		return "", err
+	}
+
+	return text, nil
+}
+
+// CreateZone creates zone and returns it as created. Kind defaults to
+// Native; a zone without RRsets gets the nameservers of Nameservers.
+func (c *Client) CreateZone(ctx context.Context, zone *Zone) (*Zone, error) {
+	if zone == nil || zone.Name == "" {
+		return nil, errNoZoneName
+	}
+
+	body := *zone
+	body.Name = canonicalZone(zone.Name)
+
+	if body.Kind == "" {
+		body.Kind = KindNative
+	}
+
+	var created Zone
+	if _, err := c.do(ctx, http.MethodPost, c.zonesPath(), nil, &body, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// DeleteZone deletes zone. With soft delete configured, the instance keeps
+// it for its grace period.
+func (c *Client) DeleteZone(ctx context.Context, zone string, opts ...RequestOption) error {
+	if zone == "" {
+		return errNoZoneName
+	}
+
+	_, err := c.do(ctx, http.MethodDelete, c.zonePath(zone), nil, nil, nil, opts...)
+
+	return err
+}
+
+// PatchRRsets applies rrsets, each with its ChangeType, to zone in one
+// change. The change goes through the checks of the zone editor, e.g. the
+// allowed record types and the TTL policies.
+func (c *Client) PatchRRsets(ctx context.Context, zone string, rrsets []RRset, opts ...RequestOption) error {
+	if zone == "" {
+		return errNoZoneName
+	}
+
+	body := struct {
+		RRsets []RRset `json:"rrsets"`
+	}{RRsets: rrsets}
+
+	_, err := c.do(ctx, http.MethodPatch, c.zonePath(zone), nil, &body, nil, opts...)
+
+	return err
+}
+
+// UpsertRRset creates rrset in zone or replaces its records.
+func (c *Client) UpsertRRset(ctx context.Context, zone string, rrset RRset, opts ...RequestOption) error {
+	rrset.ChangeType = ChangeTypeReplace
+	if rrset.Records == nil {
+		rrset.Records = []Record{}
+	}
+
+	return c.PatchRRsets(ctx, zone, []RRset{rrset}, opts...)
+}
+
+// DeleteRRset deletes the RRset of name and rrType from zone. Deleting an
+// RRset the zone does not have succeeds.
+func (c *Client) DeleteRRset(ctx context.Context, zone, name, rrType string, opts ...RequestOption) error {
+	return c.PatchRRsets(ctx, zone, []RRset{{
+		Name: name, Type: rrType, ChangeType: ChangeTypeDelete, Records: []Record{},
+	}}, opts...)
+}
+
+// NotifyZone sends a NOTIFY for zone to its secondaries.
+func (c *Client) NotifyZone(ctx context.Context, zone string) error {
+	if zone == "" {
+		return errNoZoneName
+	}
+
+	_, err := c.do(ctx, http.MethodPut, c.zonePath(zone)+"/notify", nil, nil, nil)
+
+	return err
+}
+
+// canonicalZone returns zone with a trailing dot.
+func canonicalZone(zone string) string {
+	if zone == "" || zone[len(zone)-1] == '.' {
+		return zone
+	}
+
+	return zone + "."
+}